@@ -3,6 +3,8 @@
 
 package arbcompress
 
+import "errors"
+
 type Dictionary uint32
 
 const (
@@ -10,6 +12,8 @@ const (
 	StylusProgramDictionary
 )
 
+var ErrOutputWontFit = errors.New("output won't fit in maxsize")
+
 const LEVEL_WELL = 11
 const WINDOW_SIZE = 22 // BROTLI_DEFAULT_WINDOW
 
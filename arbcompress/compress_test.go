@@ -5,8 +5,11 @@ package arbcompress
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 
+	"github.com/klauspost/compress/zstd"
+
 	"github.com/offchainlabs/nitro/util/testhelpers"
 )
 
@@ -48,3 +51,23 @@ func TestArbCompress(t *testing.T) {
 	// test empty data:
 	testCompressDecompress(t, []byte{})
 }
+
+func TestDecompressZstd(t *testing.T) {
+	source := testhelpers.NewPseudoRandomDataSource(t, 0)
+	data := append(source.GetData(2500), bytes.Repeat([]byte("yadda "), 1000)...)
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed := encoder.EncodeAll(data, nil)
+	res, err := DecompressZstd(compressed, len(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(res, data) {
+		t.Fatal("results differ ", res, " vs. ", data)
+	}
+	if _, err := DecompressZstd(compressed, len(data)-1); !errors.Is(err, ErrOutputWontFit) {
+		t.Fatal("decompressed past the max size, got", err)
+	}
+}
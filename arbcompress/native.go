@@ -13,7 +13,6 @@ package arbcompress
 */
 import "C"
 import (
-	"errors"
 	"fmt"
 )
 
@@ -47,8 +46,6 @@ func Compress(input []byte, level uint32, dictionary Dictionary) ([]byte, error)
 	return output, nil
 }
 
-var ErrOutputWontFit = errors.New("output won't fit in maxsize")
-
 func Decompress(input []byte, maxSize int) ([]byte, error) {
 	return DecompressWithDictionary(input, maxSize, EmptyDictionary)
 }
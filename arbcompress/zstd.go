@@ -0,0 +1,36 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbcompress
+
+import (
+	"errors"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// DecompressZstd decompresses zstd-compressed input, failing if the result would be larger than maxSize.
+// It's pure go, so the same implementation runs natively and in the replay binary.
+func DecompressZstd(input []byte, maxSize int) ([]byte, error) {
+	decoder, err := zstd.NewReader(
+		nil,
+		zstd.WithDecoderConcurrency(1),
+		zstd.WithDecoderLowmem(true),
+		zstd.WithDecoderMaxMemory(uint64(maxSize)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer decoder.Close()
+	output, err := decoder.DecodeAll(input, nil)
+	if errors.Is(err, zstd.ErrDecoderSizeExceeded) || errors.Is(err, zstd.ErrWindowSizeExceeded) {
+		return nil, ErrOutputWontFit
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(output) > maxSize {
+		return nil, ErrOutputWontFit
+	}
+	return output, nil
+}
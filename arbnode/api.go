@@ -2,13 +2,19 @@ package arbnode
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
 	"github.com/offchainlabs/nitro/arbutil"
+	m "github.com/offchainlabs/nitro/broadcaster/message"
+	"github.com/offchainlabs/nitro/execution"
 	"github.com/offchainlabs/nitro/staker"
+	"github.com/offchainlabs/nitro/util/profiling"
 	"github.com/offchainlabs/nitro/validator"
 )
 
@@ -20,6 +26,213 @@ func (a *BlockValidatorAPI) LatestValidated(ctx context.Context) (*staker.Global
 	return a.val.ReadLastValidatedInfo()
 }
 
+type AssertionAPI struct {
+	staker *staker.Staker
+}
+
+func (a *AssertionAPI) GetAssertion(ctx context.Context, nodeNum hexutil.Uint64) (*staker.AssertionInfo, error) {
+	return a.staker.AssertionInfo(ctx, uint64(nodeNum))
+}
+
+func (a *AssertionAPI) GetAssertionStates(ctx context.Context, nodeNum hexutil.Uint64, start hexutil.Uint64, count hexutil.Uint64) ([]staker.AssertionBlockState, error) {
+	return a.staker.AssertionBlockStates(ctx, uint64(nodeNum), uint64(start), uint64(count))
+}
+
+func (a *AssertionAPI) LatestStakedAssertion(ctx context.Context) (*staker.AssertionInfo, error) {
+	return a.staker.LatestStakedAssertion(ctx)
+}
+
+type DelayedSequencerAPI struct {
+	delayedSequencer *DelayedSequencer
+}
+
+func (a *DelayedSequencerAPI) FinalityPolicy() DelayedSequencerFinalityPolicy {
+	return a.delayedSequencer.FinalityPolicy()
+}
+
+// DryRun returns the delayed messages the latest dry run found ready to be sequenced,
+// to compare against what the active sequencer sequences.
+func (a *DelayedSequencerAPI) DryRun() (*DelayedSequencerDryRun, error) {
+	dryRun := a.delayedSequencer.LastDryRun()
+	if dryRun == nil {
+		return nil, errors.New("delayed sequencer hasn't done a dry run")
+	}
+	return dryRun, nil
+}
+
+// DelayedSequencerAdminAPI lets operators intervene in delayed message sequencing.
+type DelayedSequencerAdminAPI struct {
+	delayedSequencer *DelayedSequencer
+}
+
+// ForceIncludeExpired sequences the delayed messages past the on-chain force inclusion window,
+// without waiting for them to be final.
+func (a *DelayedSequencerAdminAPI) ForceIncludeExpired(ctx context.Context) (*ForceInclusionResult, error) {
+	return a.delayedSequencer.ForceIncludeExpired(ctx)
+}
+
+// MessageQuarantineAdminAPI lets operators override the execution of messages that reproducibly break it.
+type MessageQuarantineAdminAPI struct {
+	streamer *TransactionStreamer
+}
+
+// QuarantineMessage records an override for the message at pos: "halt" stops execution before it,
+// while "empty-block" executes it as an invalid message, diverging from nodes without the override.
+func (a *MessageQuarantineAdminAPI) QuarantineMessage(ctx context.Context, pos hexutil.Uint64, action QuarantineAction, reason string) (*MessageQuarantine, error) {
+	return a.streamer.QuarantineMessage(arbutil.MessageIndex(pos), action, reason)
+}
+
+func (a *MessageQuarantineAdminAPI) ClearQuarantine(ctx context.Context, pos hexutil.Uint64) (bool, error) {
+	return a.streamer.ClearQuarantine(arbutil.MessageIndex(pos))
+}
+
+func (a *MessageQuarantineAdminAPI) ListQuarantined(ctx context.Context) ([]*MessageQuarantine, error) {
+	return a.streamer.QuarantinedMessages()
+}
+
+// SeqCoordinatorAdminAPI lets operators move the sequencer lockout between sequencers.
+type SeqCoordinatorAdminAPI struct {
+	coordinator *SeqCoordinator
+}
+
+// SequencerHandoff hands the lockout off from this sequencer to the target, given by its coordinator url,
+// once every message this sequencer sequenced is written and broadcast.
+func (a *SeqCoordinatorAdminAPI) SequencerHandoff(ctx context.Context, target string) (*SequencerHandoff, error) {
+	return a.coordinator.Handoff(ctx, target)
+}
+
+// ClearSequencerHandoff stops preferring the sequencer last handed off to over the priorities.
+func (a *SeqCoordinatorAdminAPI) ClearSequencerHandoff(ctx context.Context) error {
+	return a.coordinator.ClearHandoff(ctx)
+}
+
+const maxFeedMessagesPerCall = 1024
+
+// FeedMessagesAPI serves the streamer's messages as the feed broadcasts them, so a relay next to the node
+// can send clients the messages that are no longer in its backlog.
+type FeedMessagesAPI struct {
+	streamer *TransactionStreamer
+}
+
+// FeedMessages returns up to count messages starting at from, fewer if the node doesn't have them yet.
+func (a *FeedMessagesAPI) FeedMessages(ctx context.Context, from hexutil.Uint64, count hexutil.Uint64) ([]*m.BroadcastFeedMessage, error) {
+	msgCount, err := a.streamer.GetMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	until := min(arbutil.MessageIndex(from)+arbutil.MessageIndex(min(uint64(count), maxFeedMessagesPerCall)), msgCount)
+	messages := []*m.BroadcastFeedMessage{}
+	for pos := arbutil.MessageIndex(from); pos < until; pos++ {
+		msg, err := a.streamer.FeedMessage(pos)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+type ExportedMessage struct {
+	Pos        hexutil.Uint64                  `json:"pos"`
+	Message    *arbostypes.MessageWithMetadata `json:"message"`
+	Serialized hexutil.Bytes                   `json:"serialized"`
+	// The block hash the feed reported for the message, if any.
+	FeedBlockHash *common.Hash       `json:"feedBlockHash,omitempty"`
+	Quarantine    *MessageQuarantine `json:"quarantine,omitempty"`
+}
+
+// ExportMessage returns the message at pos, e.g. to reproduce a quarantined message's failure elsewhere.
+func (a *MessageQuarantineAdminAPI) ExportMessage(ctx context.Context, pos hexutil.Uint64) (*ExportedMessage, error) {
+	msg, err := a.streamer.getMessageWithMetadataAndBlockHash(arbutil.MessageIndex(pos))
+	if err != nil {
+		return nil, err
+	}
+	serialized, err := msg.MessageWithMeta.Message.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	quarantine, err := a.streamer.getQuarantine(arbutil.MessageIndex(pos))
+	if err != nil {
+		return nil, err
+	}
+	return &ExportedMessage{
+		Pos:           pos,
+		Message:       &msg.MessageWithMeta,
+		Serialized:    serialized,
+		FeedBlockHash: msg.BlockHash,
+		Quarantine:    quarantine,
+	}, nil
+}
+
+type DASCertificateAPI struct {
+	inspector       *DASCertificateInspector
+	inboxTracker    *InboxTracker
+	genesisBlockNum uint64
+}
+
+// BatchCertificate returns the data availability certificate of the batch, and which committee members signed it.
+func (a *DASCertificateAPI) BatchCertificate(ctx context.Context, batchNum hexutil.Uint64) (*BatchCertificate, error) {
+	return a.inspector.BatchCertificate(ctx, uint64(batchNum))
+}
+
+// VerifyBatchCertificate checks the batch's certificate signatures and expiry, and that its data can still be fetched.
+func (a *DASCertificateAPI) VerifyBatchCertificate(ctx context.Context, batchNum hexutil.Uint64) (*BatchCertificateVerification, error) {
+	return a.inspector.VerifyBatchCertificate(ctx, uint64(batchNum))
+}
+
+// BlockCertificate returns the certificate of the batch the block's message was posted in.
+// A transaction's certificate is that of the block in its receipt.
+func (a *DASCertificateAPI) BlockCertificate(ctx context.Context, blockNum hexutil.Uint64) (*BatchCertificate, error) {
+	if uint64(blockNum) <= a.genesisBlockNum {
+		return nil, fmt.Errorf("block %v isn't produced from a message (genesis is %v)", blockNum, a.genesisBlockNum)
+	}
+	pos := arbutil.BlockNumberToMessageCount(uint64(blockNum), a.genesisBlockNum) - 1
+	batchNum, found, err := a.inboxTracker.FindInboxBatchContainingMessage(pos)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("block %v hasn't been posted in a batch yet", blockNum)
+	}
+	return a.inspector.BatchCertificate(ctx, batchNum)
+}
+
+type DASMisbehaviorAPI struct {
+	monitor *DASMisbehaviorMonitor
+}
+
+// DasMisbehaviorEvidence returns evidence of committee members not serving data they signed for,
+// of batches from fromBatch on, with up to limit records (100 if unset).
+func (a *DASMisbehaviorAPI) DasMisbehaviorEvidence(ctx context.Context, fromBatch hexutil.Uint64, limit *hexutil.Uint64) ([]*DASMisbehaviorEvidence, error) {
+	maxRecords := 100
+	if limit != nil {
+		maxRecords = int(*limit)
+	}
+	return a.monitor.Evidence(uint64(fromBatch), maxRecords)
+}
+
+// DasMemberStats returns how often each checked committee member served data it signed for.
+func (a *DASMisbehaviorAPI) DasMemberStats(ctx context.Context) []DASMemberStats {
+	return a.monitor.MemberStats()
+}
+
+type BlockWitnessAPI struct {
+	server *BlockWitnessServer
+}
+
+// BlockWitness returns the witness of the block of the message at pos, for stateless followers.
+func (a *BlockWitnessAPI) BlockWitness(ctx context.Context, pos hexutil.Uint64) (*execution.BlockWitness, error) {
+	return a.server.BlockWitness(ctx, arbutil.MessageIndex(pos))
+}
+
+type ProfilingAPI struct{}
+
+// ComponentProfile captures a CPU profile for the given number of seconds and
+// summarizes it by component (the StopWaiter that launched each goroutine).
+func (a *ProfilingAPI) ComponentProfile(ctx context.Context, seconds hexutil.Uint64) (*profiling.ComponentProfile, error) {
+	return profiling.CaptureComponentProfile(ctx, time.Duration(seconds)*time.Second)
+}
+
 type BlockValidatorDebugAPI struct {
 	val *staker.StatelessBlockValidator
 }
@@ -54,3 +267,167 @@ func (a *BlockValidatorDebugAPI) ValidateMessageNumber(
 	result.Valid = valid
 	return result, err
 }
+
+type SoftConfirmationAPI struct {
+	monitor         *SoftConfirmationMonitor
+	genesisBlockNum uint64
+}
+
+// SoftConfirmationExposure returns how many messages are only soft confirmed and for how long the oldest has been.
+func (a *SoftConfirmationAPI) SoftConfirmationExposure(ctx context.Context) (*SoftConfirmationExposure, error) {
+	return a.monitor.Exposure()
+}
+
+// BlockSoftConfirmation returns when the block's message was soft confirmed and when it was included in a batch.
+// A transaction's exposure is that of the block in its receipt.
+func (a *SoftConfirmationAPI) BlockSoftConfirmation(ctx context.Context, blockNum hexutil.Uint64) (*MessageSoftConfirmation, error) {
+	if uint64(blockNum) <= a.genesisBlockNum {
+		return nil, fmt.Errorf("block %v isn't produced from a message (genesis is %v)", blockNum, a.genesisBlockNum)
+	}
+	pos := arbutil.BlockNumberToMessageCount(uint64(blockNum), a.genesisBlockNum) - 1
+	return a.monitor.MessageSoftConfirmation(ctx, pos)
+}
+
+type InclusionStatsAPI struct {
+	stats *InclusionStats
+}
+
+// DelayedInclusionStats returns how many delayed messages the sequencer included and how many were force included,
+// with their ages in parent chain blocks, who sent the force included ones, and the most recent of them.
+// If kind is given, only delayed messages of that L1 message kind are counted.
+func (a *InclusionStatsAPI) DelayedInclusionStats(ctx context.Context, kind *hexutil.Uint64) (*DelayedInclusionStats, error) {
+	if kind == nil {
+		return a.stats.Stats(nil), nil
+	}
+	if *kind > math.MaxUint8 {
+		return nil, fmt.Errorf("invalid L1 message kind %v", *kind)
+	}
+	k := uint8(*kind)
+	return a.stats.Stats(&k), nil
+}
+
+type InboxAPI struct {
+	tracker *InboxTracker
+}
+
+type InboxCounts struct {
+	DelayedCount hexutil.Uint64 `json:"delayedCount"`
+	// How many of the delayed messages have been read by a sequencer batch.
+	DelayedSequencedCount hexutil.Uint64 `json:"delayedSequencedCount"`
+	BatchCount            hexutil.Uint64 `json:"batchCount"`
+	BatchedMessageCount   hexutil.Uint64 `json:"batchedMessageCount"`
+	// The lowest batch whose metadata is still kept, if older batch metadata has been pruned.
+	FirstRetainedBatch *hexutil.Uint64 `json:"firstRetainedBatch,omitempty"`
+}
+
+// InboxCounts returns how many delayed messages and sequencer batches the node has read from the parent chain.
+func (a *InboxAPI) InboxCounts(ctx context.Context) (*InboxCounts, error) {
+	delayedCount, err := a.tracker.GetDelayedCount()
+	if err != nil {
+		return nil, err
+	}
+	batchCount, err := a.tracker.GetBatchCount()
+	if err != nil {
+		return nil, err
+	}
+	counts := &InboxCounts{
+		DelayedCount: hexutil.Uint64(delayedCount),
+		BatchCount:   hexutil.Uint64(batchCount),
+	}
+	if batchCount > 0 {
+		lastBatch, err := a.tracker.GetBatchMetadata(batchCount - 1)
+		if err != nil {
+			return nil, err
+		}
+		counts.DelayedSequencedCount = hexutil.Uint64(lastBatch.DelayedMessageCount)
+		counts.BatchedMessageCount = hexutil.Uint64(lastBatch.MessageCount)
+	}
+	firstRetained, err := a.tracker.GetFirstRetainedBatch()
+	if err != nil {
+		return nil, err
+	}
+	if firstRetained > 0 {
+		counts.FirstRetainedBatch = (*hexutil.Uint64)(&firstRetained)
+	}
+	return counts, nil
+}
+
+type InboxDelayedMessage struct {
+	Index            hexutil.Uint64                `json:"index"`
+	Message          *arbostypes.L1IncomingMessage `json:"message"`
+	Accumulator      common.Hash                   `json:"accumulator"`
+	ParentChainBlock hexutil.Uint64                `json:"parentChainBlock"`
+	// Whether a sequencer batch has read the message yet.
+	Sequenced bool `json:"sequenced"`
+}
+
+// DelayedMessage returns the delayed message at index along with the delayed inbox accumulator after it.
+func (a *InboxAPI) DelayedMessage(ctx context.Context, index hexutil.Uint64) (*InboxDelayedMessage, error) {
+	delayedCount, err := a.tracker.GetDelayedCount()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(index) >= delayedCount {
+		return nil, fmt.Errorf("delayed message %v not read yet, delayed count is %v", index, delayedCount)
+	}
+	msg, acc, parentChainBlock, err := a.tracker.GetDelayedMessageAccumulatorAndParentChainBlockNumber(ctx, uint64(index))
+	if err != nil {
+		return nil, err
+	}
+	sequenced := false
+	batchCount, err := a.tracker.GetBatchCount()
+	if err != nil {
+		return nil, err
+	}
+	if batchCount > 0 {
+		lastBatch, err := a.tracker.GetBatchMetadata(batchCount - 1)
+		if err != nil {
+			return nil, err
+		}
+		sequenced = uint64(index) < lastBatch.DelayedMessageCount
+	}
+	return &InboxDelayedMessage{
+		Index:            index,
+		Message:          msg,
+		Accumulator:      acc,
+		ParentChainBlock: hexutil.Uint64(parentChainBlock),
+		Sequenced:        sequenced,
+	}, nil
+}
+
+type InboxBatch struct {
+	Batch                hexutil.Uint64 `json:"batch"`
+	Accumulator          common.Hash    `json:"accumulator"`
+	MessageCount         hexutil.Uint64 `json:"messageCount"`
+	DelayedMessageCount  hexutil.Uint64 `json:"delayedMessageCount"`
+	ParentChainBlock     hexutil.Uint64 `json:"parentChainBlock"`
+	PreviousMessageCount hexutil.Uint64 `json:"previousMessageCount"`
+}
+
+// BatchContainingMessage returns the sequencer batch that posted the message at index, or nil if it hasn't been
+// posted yet.
+func (a *InboxAPI) BatchContainingMessage(ctx context.Context, index hexutil.Uint64) (*InboxBatch, error) {
+	batch, found, err := a.tracker.FindInboxBatchContainingMessage(arbutil.MessageIndex(index))
+	if err != nil || !found {
+		return nil, err
+	}
+	metadata, err := a.tracker.GetBatchMetadata(batch)
+	if err != nil {
+		return nil, err
+	}
+	var prevMessageCount arbutil.MessageIndex
+	if batch > 0 {
+		prevMessageCount, err = a.tracker.GetBatchMessageCount(batch - 1)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &InboxBatch{
+		Batch:                hexutil.Uint64(batch),
+		Accumulator:          metadata.Accumulator,
+		MessageCount:         hexutil.Uint64(metadata.MessageCount),
+		DelayedMessageCount:  hexutil.Uint64(metadata.DelayedMessageCount),
+		ParentChainBlock:     hexutil.Uint64(metadata.ParentChainBlock),
+		PreviousMessageCount: hexutil.Uint64(prevMessageCount),
+	}, nil
+}
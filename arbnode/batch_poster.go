@@ -10,6 +10,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"math/big"
 	"strings"
@@ -17,6 +18,7 @@ import (
 	"time"
 
 	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
 	"github.com/spf13/pflag"
 
 	"github.com/ethereum/go-ethereum"
@@ -108,20 +110,32 @@ type BatchPoster struct {
 	dapWriter          daprovider.Writer
 	dapReaders         []daprovider.Reader
 	dataPoster         *dataposter.DataPoster
+	shadow             *shadowBatchPoster   // nil unless shadowing another parent chain
+	failover           *parentChainFailover // nil unless fallback parent chain urls are configured
 	redisLock          *redislock.Simple
 	messagesPerBatch   *arbmath.MovingAverage[uint64]
-	non4844BatchCount  int // Count of consecutive non-4844 batches posted
+	non4844BatchCount  int  // Count of consecutive non-4844 batches posted
+	last4844           bool // Whether the last batch posted was a 4844 batch
+	lastBatchSize      int  // Compressed size of the last batch posted
 	// This is an atomic variable that should only be accessed atomically.
 	// An estimate of the number of batches we want to post but haven't yet.
 	// This doesn't include batches which we don't want to post yet due to the L1 bounds.
 	backlog         atomic.Uint64
 	lastHitL1Bounds time.Time // The last time we wanted to post a message but hit the L1 bounds
+	deferredSince   time.Time // When posting the batch being built was first deferred, if it was
+	deferredBaseFee *big.Int  // The parent chain base fee then
 
 	batchReverted        atomic.Bool // indicates whether data poster batch was reverted
 	nextRevertCheckBlock int64       // the last parent block scanned for reverting batches
 	postedFirstBatch     bool        // indicates if batch poster has posted the first batch
 
 	accessList func(SequencerInboxAccs, AfterDelayedMessagesRead int) types.AccessList
+
+	intents           *batchPostingIntentLog // nil unless the intent log is enabled
+	recoveringIntents map[uint64]bool        // nonces of intents from before a restart, only used by the reconciler
+	intentHold        atomic.Bool            // whether posting is held off until intents are reconciled
+
+	standby *batchPosterStandby // nil unless coordinating with standby batch posters
 }
 
 type l1BlockBound int
@@ -156,6 +170,8 @@ type BatchPosterConfig struct {
 	// Batch posting error delay.
 	ErrorDelay                     time.Duration               `koanf:"error-delay" reload:"hot"`
 	CompressionLevel               int                         `koanf:"compression-level" reload:"hot"`
+	CompressionFormat              string                      `koanf:"compression-format" reload:"hot"`
+	ZstdCompressionLevel           int                         `koanf:"zstd-compression-level" reload:"hot"`
 	DASRetentionPeriod             time.Duration               `koanf:"das-retention-period" reload:"hot"`
 	GasRefunderAddress             string                      `koanf:"gas-refunder-address" reload:"hot"`
 	DataPoster                     dataposter.DataPosterConfig `koanf:"data-poster" reload:"hot"`
@@ -164,7 +180,9 @@ type BatchPosterConfig struct {
 	ExtraBatchGas                  uint64                      `koanf:"extra-batch-gas" reload:"hot"`
 	Post4844Blobs                  bool                        `koanf:"post-4844-blobs" reload:"hot"`
 	IgnoreBlobPrice                bool                        `koanf:"ignore-blob-price" reload:"hot"`
+	BlobPriceHysteresisBips        arbmath.Bips                `koanf:"blob-price-hysteresis-bips" reload:"hot"`
 	ParentChainWallet              genericconf.WalletConfig    `koanf:"parent-chain-wallet"`
+	ParentChainFallbackUrls        []string                    `koanf:"parent-chain-fallback-urls"`
 	L1BlockBound                   string                      `koanf:"l1-block-bound" reload:"hot"`
 	L1BlockBoundBypass             time.Duration               `koanf:"l1-block-bound-bypass" reload:"hot"`
 	UseAccessLists                 bool                        `koanf:"use-access-lists" reload:"hot"`
@@ -172,6 +190,11 @@ type BatchPosterConfig struct {
 	Dangerous                      BatchPosterDangerousConfig  `koanf:"dangerous"`
 	ReorgResistanceMargin          time.Duration               `koanf:"reorg-resistance-margin" reload:"hot"`
 	CheckBatchCorrectness          bool                        `koanf:"check-batch-correctness"`
+	MaxPipelinedBatches            int                         `koanf:"max-pipelined-batches" reload:"hot"`
+	Shadow                         BatchPosterShadowConfig     `koanf:"shadow"`
+	Deferral                       BatchPosterDeferralConfig   `koanf:"deferral" reload:"hot"`
+	IntentLog                      BatchPosterIntentLogConfig  `koanf:"intent-log" reload:"hot"`
+	Standby                        BatchPosterStandbyConfig    `koanf:"standby" reload:"hot"`
 
 	gasRefunder  common.Address
 	l1BlockBound l1BlockBound
@@ -198,7 +221,28 @@ func (c *BatchPosterConfig) Validate() error {
 	} else {
 		return fmt.Errorf("invalid L1 block bound tag \"%v\" (see --help for options)", c.L1BlockBound)
 	}
-	return nil
+	if c.CompressionFormat != compressionFormatBrotli && c.CompressionFormat != compressionFormatZstd {
+		return fmt.Errorf("invalid compression format \"%v\" (see --help for options)", c.CompressionFormat)
+	}
+	if c.CompressionLevel < brotli.BestSpeed || c.CompressionLevel > brotli.BestCompression {
+		return fmt.Errorf("invalid compression level %v, must be between %v and %v", c.CompressionLevel, brotli.BestSpeed, brotli.BestCompression)
+	}
+	if c.ZstdCompressionLevel < 1 || c.ZstdCompressionLevel > 22 {
+		return fmt.Errorf("invalid zstd compression level %v, must be between 1 and 22", c.ZstdCompressionLevel)
+	}
+	if c.MaxPipelinedBatches < 1 {
+		return fmt.Errorf("invalid max pipelined batches %v, must be at least 1", c.MaxPipelinedBatches)
+	}
+	if err := c.Deferral.Validate(); err != nil {
+		return err
+	}
+	if c.Standby.Enable && (c.RedisUrl == "" || !c.RedisLock.Enable) {
+		return errors.New("the batch poster standby mode requires a redis-url and the redis-lock")
+	}
+	if err := c.Standby.Validate(); err != nil {
+		return err
+	}
+	return c.Shadow.Validate()
 }
 
 type BatchPosterConfigFetcher func() *BatchPosterConfig
@@ -212,12 +256,15 @@ func BatchPosterConfigAddOptions(prefix string, f *pflag.FlagSet) {
 	f.Bool(prefix+".wait-for-max-delay", DefaultBatchPosterConfig.WaitForMaxDelay, "wait for the max batch delay, even if the batch is full")
 	f.Duration(prefix+".poll-interval", DefaultBatchPosterConfig.PollInterval, "how long to wait after no batches are ready to be posted before checking again")
 	f.Duration(prefix+".error-delay", DefaultBatchPosterConfig.ErrorDelay, "how long to delay after error posting batch")
-	f.Int(prefix+".compression-level", DefaultBatchPosterConfig.CompressionLevel, "batch compression level")
+	f.Int(prefix+".compression-level", DefaultBatchPosterConfig.CompressionLevel, "batch compression level (0-11) when the compression format is brotli")
+	f.String(prefix+".compression-format", DefaultBatchPosterConfig.CompressionFormat, "batch compression format (\"brotli\", or \"zstd\" which every node reading the chain, and the replay binary of its wasm module root, must support)")
+	f.Int(prefix+".zstd-compression-level", DefaultBatchPosterConfig.ZstdCompressionLevel, "batch compression level (1-22) when the compression format is zstd")
 	f.Duration(prefix+".das-retention-period", DefaultBatchPosterConfig.DASRetentionPeriod, "In AnyTrust mode, the period which DASes are requested to retain the stored batches.")
 	f.String(prefix+".gas-refunder-address", DefaultBatchPosterConfig.GasRefunderAddress, "The gas refunder contract address (optional)")
 	f.Uint64(prefix+".extra-batch-gas", DefaultBatchPosterConfig.ExtraBatchGas, "use this much more gas than estimation says is necessary to post batches")
 	f.Bool(prefix+".post-4844-blobs", DefaultBatchPosterConfig.Post4844Blobs, "if the parent chain supports 4844 blobs and they're well priced, post EIP-4844 blobs")
 	f.Bool(prefix+".ignore-blob-price", DefaultBatchPosterConfig.IgnoreBlobPrice, "if the parent chain supports 4844 blobs and ignore-blob-price is true, post 4844 blobs even if it's not price efficient")
+	f.Uint64(prefix+".blob-price-hysteresis-bips", uint64(DefaultBatchPosterConfig.BlobPriceHysteresisBips), "how much cheaper (in basis points) blobs or calldata must be than the other to switch to them, so batches don't flap between the two when prices are close")
 	f.String(prefix+".redis-url", DefaultBatchPosterConfig.RedisUrl, "if non-empty, the Redis URL to store queued transactions in")
 	f.String(prefix+".l1-block-bound", DefaultBatchPosterConfig.L1BlockBound, "only post messages to batches when they're within the max future block/timestamp as of this L1 block tag (\"safe\", \"finalized\", \"latest\", or \"ignore\" to ignore this check)")
 	f.Duration(prefix+".l1-block-bound-bypass", DefaultBatchPosterConfig.L1BlockBoundBypass, "post batches even if not within the layer 1 future bounds if we're within this margin of the max delay")
@@ -225,9 +272,15 @@ func BatchPosterConfigAddOptions(prefix string, f *pflag.FlagSet) {
 	f.Uint64(prefix+".gas-estimate-base-fee-multiple-bips", uint64(DefaultBatchPosterConfig.GasEstimateBaseFeeMultipleBips), "for gas estimation, use this multiple of the basefee (measured in basis points) as the max fee per gas")
 	f.Duration(prefix+".reorg-resistance-margin", DefaultBatchPosterConfig.ReorgResistanceMargin, "do not post batch if its within this duration from layer 1 minimum bounds. Requires l1-block-bound option not be set to \"ignore\"")
 	f.Bool(prefix+".check-batch-correctness", DefaultBatchPosterConfig.CheckBatchCorrectness, "setting this to true will run the batch against an inbox multiplexer and verifies that it produces the correct set of messages")
+	f.Int(prefix+".max-pipelined-batches", DefaultBatchPosterConfig.MaxPipelinedBatches, "while there's a backlog, build and post up to this many batches at once with consecutive nonces (1 = post one batch at a time)")
 	redislock.AddConfigOptions(prefix+".redis-lock", f)
 	dataposter.DataPosterConfigAddOptions(prefix+".data-poster", f, dataposter.DefaultDataPosterConfig)
 	genericconf.WalletConfigAddOptions(prefix+".parent-chain-wallet", f, DefaultBatchPosterConfig.ParentChainWallet.Pathname)
+	f.StringSlice(prefix+".parent-chain-fallback-urls", DefaultBatchPosterConfig.ParentChainFallbackUrls, "parent chain RPC urls to fail over to when the parent chain connection fails, which are also checked before replacing a transaction by fee")
+	BatchPosterShadowConfigAddOptions(prefix+".shadow", f)
+	BatchPosterDeferralConfigAddOptions(prefix+".deferral", f)
+	BatchPosterIntentLogConfigAddOptions(prefix+".intent-log", f)
+	BatchPosterStandbyConfigAddOptions(prefix+".standby", f)
 }
 
 var DefaultBatchPosterConfig = BatchPosterConfig{
@@ -242,13 +295,17 @@ var DefaultBatchPosterConfig = BatchPosterConfig{
 	MaxDelay:                       time.Hour,
 	WaitForMaxDelay:                false,
 	CompressionLevel:               brotli.BestCompression,
+	CompressionFormat:              compressionFormatBrotli,
+	ZstdCompressionLevel:           19,
 	DASRetentionPeriod:             daprovider.DefaultDASRetentionPeriod,
 	GasRefunderAddress:             "",
 	ExtraBatchGas:                  50_000,
 	Post4844Blobs:                  false,
 	IgnoreBlobPrice:                false,
+	BlobPriceHysteresisBips:        arbmath.OneInBips / 10,
 	DataPoster:                     dataposter.DefaultDataPosterConfig,
 	ParentChainWallet:              DefaultBatchPosterL1WalletConfig,
+	ParentChainFallbackUrls:        []string{},
 	L1BlockBound:                   "",
 	L1BlockBoundBypass:             time.Hour,
 	UseAccessLists:                 true,
@@ -256,6 +313,11 @@ var DefaultBatchPosterConfig = BatchPosterConfig{
 	GasEstimateBaseFeeMultipleBips: arbmath.OneInBips * 3 / 2,
 	ReorgResistanceMargin:          10 * time.Minute,
 	CheckBatchCorrectness:          true,
+	MaxPipelinedBatches:            1,
+	Shadow:                         DefaultBatchPosterShadowConfig,
+	Deferral:                       DefaultBatchPosterDeferralConfig,
+	IntentLog:                      DefaultBatchPosterIntentLogConfig,
+	Standby:                        DefaultBatchPosterStandbyConfig,
 }
 
 var DefaultBatchPosterL1WalletConfig = genericconf.WalletConfig{
@@ -264,6 +326,7 @@ var DefaultBatchPosterL1WalletConfig = genericconf.WalletConfig{
 	PrivateKey:    genericconf.WalletConfigDefault.PrivateKey,
 	Account:       genericconf.WalletConfigDefault.Account,
 	OnlyCreateKey: genericconf.WalletConfigDefault.OnlyCreateKey,
+	KMS:           genericconf.WalletConfigDefault.KMS,
 }
 
 var TestBatchPosterConfig = BatchPosterConfig{
@@ -275,11 +338,14 @@ var TestBatchPosterConfig = BatchPosterConfig{
 	MaxDelay:                       0,
 	WaitForMaxDelay:                false,
 	CompressionLevel:               2,
+	CompressionFormat:              compressionFormatBrotli,
+	ZstdCompressionLevel:           3,
 	DASRetentionPeriod:             daprovider.DefaultDASRetentionPeriod,
 	GasRefunderAddress:             "",
 	ExtraBatchGas:                  10_000,
 	Post4844Blobs:                  true,
 	IgnoreBlobPrice:                false,
+	BlobPriceHysteresisBips:        arbmath.OneInBips / 10,
 	DataPoster:                     dataposter.TestDataPosterConfig,
 	ParentChainWallet:              DefaultBatchPosterL1WalletConfig,
 	L1BlockBound:                   "",
@@ -287,10 +353,16 @@ var TestBatchPosterConfig = BatchPosterConfig{
 	UseAccessLists:                 true,
 	GasEstimateBaseFeeMultipleBips: arbmath.OneInBips * 3 / 2,
 	CheckBatchCorrectness:          true,
+	MaxPipelinedBatches:            1,
+	Shadow:                         DefaultBatchPosterShadowConfig,
+	Deferral:                       DefaultBatchPosterDeferralConfig,
+	IntentLog:                      DefaultBatchPosterIntentLogConfig,
+	Standby:                        DefaultBatchPosterStandbyConfig,
 }
 
 type BatchPosterOpts struct {
 	DataPosterDB  ethdb.Database
+	IntentDB      ethdb.Database // nil to not keep an intent log
 	L1Reader      *headerreader.HeaderReader
 	Inbox         *InboxTracker
 	Streamer      *TransactionStreamer
@@ -305,6 +377,18 @@ type BatchPosterOpts struct {
 }
 
 func NewBatchPoster(ctx context.Context, opts *BatchPosterOpts) (*BatchPoster, error) {
+	var failover *parentChainFailover
+	if urls := opts.Config().ParentChainFallbackUrls; len(urls) > 0 {
+		var err error
+		failover, err = newParentChainFailover(ctx, opts.L1Reader, urls)
+		if err != nil {
+			return nil, err
+		}
+		// read and post through the failover from here on
+		optsCopy := *opts
+		optsCopy.L1Reader = failover.l1Reader
+		opts = &optsCopy
+	}
 	seqInbox, err := bridgegen.NewSequencerInbox(opts.DeployInfo.SequencerInbox, opts.L1Reader.Client())
 	if err != nil {
 		return nil, err
@@ -349,6 +433,7 @@ func NewBatchPoster(ctx context.Context, opts *BatchPosterOpts) (*BatchPoster, e
 		dapWriter:          opts.DAPWriter,
 		redisLock:          redisLock,
 		dapReaders:         opts.DAPReaders,
+		failover:           failover,
 	}
 	b.messagesPerBatch, err = arbmath.NewMovingAverage[uint64](20)
 	if err != nil {
@@ -368,6 +453,7 @@ func NewBatchPoster(ctx context.Context, opts *BatchPosterOpts) (*BatchPoster, e
 			ExtraBacklog:      b.GetBacklogEstimate,
 			RedisKey:          "data-poster.queue",
 			ParentChainID:     opts.ParentChainID,
+			CrossCheckClients: failover.crossCheckClients(),
 		})
 	if err != nil {
 		return nil, err
@@ -389,6 +475,24 @@ func NewBatchPoster(ctx context.Context, opts *BatchPosterOpts) (*BatchPoster, e
 			AfterDelayedMessagesRead: AfterDelayedMessagesRead,
 		})
 	}
+	if opts.IntentDB != nil && opts.Config().IntentLog.Enable {
+		b.intents = &batchPostingIntentLog{db: opts.IntentDB}
+		if err := b.loadIntents(); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Config().Standby.Enable {
+		b.standby, err = newBatchPosterStandby(redisClient, redisLock, func() *BatchPosterStandbyConfig { return &opts.Config().Standby }, &batchPosterStandbyMonitor{poster: b})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.Config().Shadow.Enable {
+		b.shadow, err = newShadowBatchPoster(ctx, b, func() *BatchPosterShadowConfig { return &opts.Config().Shadow })
+		if err != nil {
+			return nil, err
+		}
+	}
 	return b, nil
 }
 
@@ -695,9 +799,37 @@ func (b *BatchPoster) getBatchPosterPosition(ctx context.Context, blockNum *big.
 
 var errBatchAlreadyClosed = errors.New("batch segments already closed")
 
+const (
+	compressionFormatBrotli = "brotli"
+	compressionFormatZstd   = "zstd"
+)
+
+// the zstd level the compression level is capped at under a backlog, as brotli's is at its default
+const zstdDefaultCompressionLevel = 3
+
+// compressionRatioHistograms track how many times smaller each format compresses batches, in hundredths
+var compressionRatioHistograms = map[string]metrics.Histogram{
+	compressionFormatBrotli: metrics.NewRegisteredHistogram("arb/batchposter/compression/brotli/ratio", nil, metrics.NewBoundedHistogramSample()),
+	compressionFormatZstd:   metrics.NewRegisteredHistogram("arb/batchposter/compression/zstd/ratio", nil, metrics.NewBoundedHistogramSample()),
+}
+
+type batchCompressor interface {
+	io.Writer
+	Flush() error
+	Close() error
+}
+
+func newBatchCompressor(format string, w io.Writer, level int) (batchCompressor, error) {
+	if format == compressionFormatZstd {
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)), zstd.WithEncoderConcurrency(1))
+	}
+	return brotli.NewWriterLevel(w, level), nil
+}
+
 type batchSegments struct {
 	compressedBuffer      *bytes.Buffer
-	compressedWriter      *brotli.Writer
+	compressedWriter      batchCompressor
+	compressionFormat     string
 	rawSegments           [][]byte
 	timestamp             uint64
 	blockNum              uint64
@@ -711,6 +843,25 @@ type batchSegments struct {
 	isDone                bool
 }
 
+// preferBlobs returns whether to post a batch of about batchSize compressed bytes as blobs rather than calldata.
+// Blobs are paid for whole, so a small batch can cost more as a blob than as calldata even when blob gas is
+// cheaper per byte. To keep from flapping between the two while their prices are close, the other type must be
+// cheaper by the hysteresis to switch from the one the last batch used.
+func preferBlobs(header *types.Header, batchSize int, last4844 bool, hysteresis arbmath.Bips) bool {
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	size := big.NewInt(int64(batchSize))
+	blobsNeeded := arbmath.BigDiv(arbmath.BigSub(arbmath.BigAdd(size, usableBytesInBlob), common.Big1), usableBytesInBlob)
+	blobFee := eip4844.CalcBlobFee(eip4844.CalcExcessBlobGas(*header.ExcessBlobGas, *header.BlobGasUsed))
+	blobCost := arbmath.BigMul(arbmath.BigMul(blobFee, blobTxBlobGasPerBlob), blobsNeeded)
+	calldataCost := arbmath.BigMul(arbmath.BigMulByUint(header.BaseFee, params.TxDataNonZeroGasEIP2028), size)
+	if last4844 {
+		return !arbmath.BigLessThan(arbmath.BigMulByBips(calldataCost, arbmath.OneInBips+hysteresis), blobCost)
+	}
+	return arbmath.BigLessThan(arbmath.BigMulByBips(blobCost, arbmath.OneInBips+hysteresis), calldataCost)
+}
+
 type buildingBatch struct {
 	segments          *batchSegments
 	startMsgCount     arbutil.MessageIndex
@@ -720,7 +871,7 @@ type buildingBatch struct {
 	muxBackend        *simulatedMuxBackend
 }
 
-func newBatchSegments(firstDelayed uint64, config *BatchPosterConfig, backlog uint64, use4844 bool) *batchSegments {
+func newBatchSegments(firstDelayed uint64, config *BatchPosterConfig, backlog uint64, use4844 bool) (*batchSegments, error) {
 	maxSize := config.MaxSize
 	if use4844 {
 		maxSize = config.Max4844BatchSize
@@ -732,15 +883,20 @@ func newBatchSegments(firstDelayed uint64, config *BatchPosterConfig, backlog ui
 	}
 	compressedBuffer := bytes.NewBuffer(make([]byte, 0, maxSize*2))
 	compressionLevel := config.CompressionLevel
-	recompressionLevel := config.CompressionLevel
+	defaultCompressionLevel, fastCompressionLevel := brotli.DefaultCompression, 4
+	if config.CompressionFormat == compressionFormatZstd {
+		compressionLevel = config.ZstdCompressionLevel
+		defaultCompressionLevel, fastCompressionLevel = zstdDefaultCompressionLevel, 1
+	}
+	recompressionLevel := compressionLevel
 	if backlog > 20 {
-		compressionLevel = arbmath.MinInt(compressionLevel, brotli.DefaultCompression)
+		compressionLevel = arbmath.MinInt(compressionLevel, defaultCompressionLevel)
 	}
 	if backlog > 40 {
-		recompressionLevel = arbmath.MinInt(recompressionLevel, brotli.DefaultCompression)
+		recompressionLevel = arbmath.MinInt(recompressionLevel, defaultCompressionLevel)
 	}
 	if backlog > 60 {
-		compressionLevel = arbmath.MinInt(compressionLevel, 4)
+		compressionLevel = arbmath.MinInt(compressionLevel, fastCompressionLevel)
 	}
 	if recompressionLevel < compressionLevel {
 		// This should never be possible
@@ -751,19 +907,28 @@ func newBatchSegments(firstDelayed uint64, config *BatchPosterConfig, backlog ui
 		)
 		recompressionLevel = compressionLevel
 	}
+	compressedWriter, err := newBatchCompressor(config.CompressionFormat, compressedBuffer, compressionLevel)
+	if err != nil {
+		return nil, err
+	}
 	return &batchSegments{
 		compressedBuffer:   compressedBuffer,
-		compressedWriter:   brotli.NewWriterLevel(compressedBuffer, compressionLevel),
+		compressedWriter:   compressedWriter,
+		compressionFormat:  config.CompressionFormat,
 		sizeLimit:          maxSize,
 		recompressionLevel: recompressionLevel,
 		rawSegments:        make([][]byte, 0, 128),
 		delayedMsg:         firstDelayed,
-	}
+	}, nil
 }
 
 func (s *batchSegments) recompressAll() error {
 	s.compressedBuffer = bytes.NewBuffer(make([]byte, 0, s.sizeLimit*2))
-	s.compressedWriter = brotli.NewWriterLevel(s.compressedBuffer, s.recompressionLevel)
+	compressedWriter, err := newBatchCompressor(s.compressionFormat, s.compressedBuffer, s.recompressionLevel)
+	if err != nil {
+		return err
+	}
+	s.compressedWriter = compressedWriter
 	s.newUncompressedSize = 0
 	s.totalUncompressedSize = 0
 	for _, segment := range s.rawSegments {
@@ -941,8 +1106,14 @@ func (s *batchSegments) CloseAndGetBytes() ([]byte, error) {
 		return nil, err
 	}
 	compressedBytes := s.compressedBuffer.Bytes()
+	if len(compressedBytes) > 0 {
+		compressionRatioHistograms[s.compressionFormat].Update(int64(s.totalUncompressedSize * 100 / len(compressedBytes)))
+	}
 	fullMsg := make([]byte, 1, len(compressedBytes)+1)
 	fullMsg[0] = daprovider.BrotliMessageHeaderByte
+	if s.compressionFormat == compressionFormatZstd {
+		fullMsg[0] = daprovider.ZstdMessageHeaderByte
+	}
 	fullMsg = append(fullMsg, compressedBytes...)
 	return fullMsg, nil
 }
@@ -954,6 +1125,18 @@ func (b *BatchPoster) encodeAddBatch(
 	l2MessageData []byte,
 	delayedMsg uint64,
 	use4844 bool,
+) ([]byte, []kzg4844.Blob, error) {
+	return b.encodeAddBatchWithRefunder(seqNum, prevMsgNum, newMsgNum, l2MessageData, delayedMsg, use4844, b.config().gasRefunder)
+}
+
+func (b *BatchPoster) encodeAddBatchWithRefunder(
+	seqNum *big.Int,
+	prevMsgNum arbutil.MessageIndex,
+	newMsgNum arbutil.MessageIndex,
+	l2MessageData []byte,
+	delayedMsg uint64,
+	use4844 bool,
+	gasRefunder common.Address,
 ) ([]byte, []kzg4844.Blob, error) {
 	methodName := sequencerBatchPostMethodName
 	if use4844 {
@@ -975,7 +1158,7 @@ func (b *BatchPoster) encodeAddBatch(
 		calldata, err = method.Inputs.Pack(
 			seqNum,
 			new(big.Int).SetUint64(delayedMsg),
-			b.config().gasRefunder,
+			gasRefunder,
 			new(big.Int).SetUint64(uint64(prevMsgNum)),
 			new(big.Int).SetUint64(uint64(newMsgNum)),
 		)
@@ -984,7 +1167,7 @@ func (b *BatchPoster) encodeAddBatch(
 			seqNum,
 			l2MessageData,
 			new(big.Int).SetUint64(delayedMsg),
-			b.config().gasRefunder,
+			gasRefunder,
 			new(big.Int).SetUint64(uint64(prevMsgNum)),
 			new(big.Int).SetUint64(uint64(newMsgNum)),
 		)
@@ -1115,49 +1298,122 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 	if dbBatchCount > batchPosition.NextSeqNum {
 		return false, fmt.Errorf("attempting to post batch %v, but the local inbox tracker database already has %v batches", batchPosition.NextSeqNum, dbBatchCount)
 	}
-	if b.building == nil || b.building.startMsgCount != batchPosition.MessageCount {
-		latestHeader, err := b.l1Reader.LastHeader(ctx)
+	config := b.config()
+	batch, err := b.buildBatch(ctx, config, nonce, batchPositionBytes, batchPosition, nil)
+	if err != nil || batch == nil {
+		return false, err
+	}
+
+	// Batches are finalized in the background while the ones after them are built, and abandoned if one
+	// before them fails to post, so this context stops finalizing them once they won't be posted.
+	finalizeCtx, cancelFinalize := context.WithCancel(ctx)
+	defer cancelFinalize()
+	if err := b.startFinalizingBatch(finalizeCtx, config, batch, true); err != nil {
+		return false, err
+	}
+	pipeline := []*pipelinedBatch{batch}
+	for depth := b.pipelineDepth(config); len(pipeline) < depth; {
+		prev := pipeline[len(pipeline)-1]
+		next, err := b.buildBatch(ctx, config, prev.nonce+1, prev.newMeta, prev.nextPosition(), prev)
 		if err != nil {
-			return false, err
+			log.Warn("error building pipelined batch, posting the batches before it", "sequenceNumber", prev.position.NextSeqNum+1, "err", err)
+			break
 		}
+		if next == nil {
+			break
+		}
+		if err := b.startFinalizingBatch(finalizeCtx, config, next, false); err != nil {
+			log.Warn("error finalizing pipelined batch, posting the batches before it", "sequenceNumber", next.position.NextSeqNum, "err", err)
+			break
+		}
+		pipeline = append(pipeline, next)
+	}
+
+	for i, batch := range pipeline {
+		err := <-batch.finalized
+		if err == nil && b.batchReverted.Load() {
+			err = errors.New("batch was reverted")
+		}
+		if err == nil {
+			err = b.postBatch(ctx, config, batch)
+		}
+		if err != nil {
+			if i == 0 {
+				return false, err
+			}
+			// The nonces reserved for the rest of the pipeline were never handed to the data poster,
+			// so the batches from this one on are rebuilt on top of whatever it has queued.
+			b.building = nil
+			abandoned := len(pipeline) - i
+			batchPosterPipelineAbandonedCounter.Inc(int64(abandoned))
+			log.Warn(AbandonedPipelinedBatchesLogMsg, "sequenceNumber", batch.position.NextSeqNum, "abandoned", abandoned, "err", err)
+			return true, nil
+		}
+	}
+	batchPosterPipelineDepthGauge.Update(int64(len(pipeline)))
+	if len(pipeline) > 1 {
+		log.Info(PostedPipelinedBatchesLogMsg, "from", pipeline[0].position.NextSeqNum, "count", len(pipeline))
+	}
+	return true, nil
+}
+
+// buildBatch builds the batch following batchPosition, to post with the given nonce, returning nil if it isn't ready
+// to post yet. prev is the batch it follows in the pipeline, or nil if it follows what the data poster has queued.
+func (b *BatchPoster) buildBatch(ctx context.Context, config *BatchPosterConfig, nonce uint64, batchPositionBytes []byte, batchPosition batchPosterPosition, prev *pipelinedBatch) (*pipelinedBatch, error) {
+	if b.building == nil || b.building.startMsgCount != batchPosition.MessageCount {
 		var use4844 bool
-		config := b.config()
-		if config.Post4844Blobs && b.dapWriter == nil && latestHeader.ExcessBlobGas != nil && latestHeader.BlobGasUsed != nil {
-			arbOSVersion, err := b.arbOSVersionGetter.ArbOSVersionForMessageNumber(arbutil.MessageIndex(arbmath.SaturatingUSub(uint64(batchPosition.MessageCount), 1)))
+		if prev != nil {
+			// The parent chain's mempool won't hold blob and non-blob transactions from the same sender at once.
+			use4844 = prev.building.use4844
+		} else {
+			latestHeader, err := b.l1Reader.LastHeader(ctx)
 			if err != nil {
-				return false, err
+				return nil, err
 			}
-			if arbOSVersion >= 20 {
-				if config.IgnoreBlobPrice {
-					use4844 = true
-				} else {
-					backlog := b.backlog.Load()
-					// Logic to prevent switching from non-4844 batches to 4844 batches too often,
-					// so that blocks can be filled efficiently. The geth txpool rejects txs for
-					// accounts that already have the other type of txs in the pool with
-					// "address already reserved". This logic makes sure that, if there is a backlog,
-					// that enough non-4844 batches have been posted to fill a block before switching.
-					if backlog == 0 ||
-						b.non4844BatchCount == 0 ||
-						b.non4844BatchCount > 16 {
-						blobFeePerByte := eip4844.CalcBlobFee(eip4844.CalcExcessBlobGas(*latestHeader.ExcessBlobGas, *latestHeader.BlobGasUsed))
-						blobFeePerByte.Mul(blobFeePerByte, blobTxBlobGasPerBlob)
-						blobFeePerByte.Div(blobFeePerByte, usableBytesInBlob)
-
-						calldataFeePerByte := arbmath.BigMulByUint(latestHeader.BaseFee, 16)
-						use4844 = arbmath.BigLessThan(blobFeePerByte, calldataFeePerByte)
+			if config.Post4844Blobs && b.dapWriter == nil && latestHeader.ExcessBlobGas != nil && latestHeader.BlobGasUsed != nil {
+				arbOSVersion, err := b.arbOSVersionGetter.ArbOSVersionForMessageNumber(arbutil.MessageIndex(arbmath.SaturatingUSub(uint64(batchPosition.MessageCount), 1)))
+				if err != nil {
+					return nil, err
+				}
+				if arbOSVersion >= 20 {
+					if config.IgnoreBlobPrice {
+						use4844 = true
+					} else {
+						backlog := b.backlog.Load()
+						// Logic to prevent switching from non-4844 batches to 4844 batches too often,
+						// so that blocks can be filled efficiently. The geth txpool rejects txs for
+						// accounts that already have the other type of txs in the pool with
+						// "address already reserved". This logic makes sure that, if there is a backlog,
+						// that enough non-4844 batches have been posted to fill a block before switching.
+						if backlog == 0 ||
+							b.non4844BatchCount == 0 ||
+							b.non4844BatchCount > 16 {
+							// with a backlog the batch will be full, otherwise it'll likely be about as big as the last
+							batchSize := config.Max4844BatchSize
+							if backlog == 0 && b.lastBatchSize > 0 && b.lastBatchSize < batchSize {
+								batchSize = b.lastBatchSize
+							}
+							use4844 = preferBlobs(latestHeader, batchSize, b.last4844, config.BlobPriceHysteresisBips)
+							if use4844 != b.last4844 {
+								log.Info("BatchPoster: switching batch data type", "blobs", use4844, "estimatedSize", batchSize)
+							}
+						}
 					}
 				}
 			}
 		}
 
+		segments, err := newBatchSegments(batchPosition.DelayedMessageCount, config, b.GetBacklogEstimate(), use4844)
+		if err != nil {
+			return nil, err
+		}
 		b.building = &buildingBatch{
-			segments:      newBatchSegments(batchPosition.DelayedMessageCount, b.config(), b.GetBacklogEstimate(), use4844),
+			segments:      segments,
 			msgCount:      batchPosition.MessageCount,
 			startMsgCount: batchPosition.MessageCount,
 			use4844:       use4844,
 		}
-		if b.config().CheckBatchCorrectness {
+		if config.CheckBatchCorrectness {
 			b.building.muxBackend = &simulatedMuxBackend{
 				batchSeqNum: batchPosition.NextSeqNum,
 				allMsgs:     make(map[arbutil.MessageIndex]*arbostypes.MessageWithMetadata),
@@ -1166,24 +1422,23 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 	}
 	msgCount, err := b.streamer.GetMessageCount()
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 	if msgCount <= batchPosition.MessageCount {
 		// There's nothing after the newest batch, therefore batch posting was not required
-		return false, nil
+		return nil, nil
 	}
 	firstMsg, err := b.streamer.GetMessage(batchPosition.MessageCount)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 	firstMsgTime := time.Unix(int64(firstMsg.Message.Header.Timestamp), 0)
 
 	lastPotentialMsg, err := b.streamer.GetMessage(msgCount - 1)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	config := b.config()
 	forcePostBatch := config.MaxDelay <= 0 || time.Since(firstMsgTime) >= config.MaxDelay
 
 	var l1BoundMaxBlockNumber uint64 = math.MaxUint64
@@ -1216,7 +1471,7 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 			l1Bound, err = b.l1Reader.LatestFinalizedBlockHeader(ctx)
 		}
 		if err != nil {
-			return false, fmt.Errorf("error getting L1 bound block: %w", err)
+			return nil, fmt.Errorf("error getting L1 bound block: %w", err)
 		}
 
 		maxTimeVariationDelayBlocks, maxTimeVariationFutureBlocks, maxTimeVariationDelaySeconds, maxTimeVariationFutureSeconds, err := b.seqInbox.MaxTimeVariation(&bind.CallOpts{
@@ -1228,7 +1483,7 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 			log.Warn("error getting max time variation on L1 bound block; falling back on latest block", "err", err)
 			maxTimeVariationDelayBlocks, maxTimeVariationFutureBlocks, maxTimeVariationDelaySeconds, maxTimeVariationFutureSeconds, err = b.seqInbox.MaxTimeVariation(&bind.CallOpts{Context: ctx})
 			if err != nil {
-				return false, fmt.Errorf("error getting max time variation: %w", err)
+				return nil, fmt.Errorf("error getting max time variation: %w", err)
 			}
 		}
 
@@ -1238,7 +1493,7 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 
 		latestHeader, err := b.l1Reader.LastHeader(ctx)
 		if err != nil {
-			return false, err
+			return nil, err
 		}
 		latestBlockNumber := arbutil.ParentHeaderToL1BlockNumber(latestHeader)
 		l1BoundMinBlockNumber = arbmath.SaturatingUSub(latestBlockNumber, arbmath.BigToUintSaturating(maxTimeVariationDelayBlocks))
@@ -1286,7 +1541,7 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 		if err != nil {
 			// Clear our cache
 			b.building = nil
-			return false, fmt.Errorf("error adding message to batch: %w", err)
+			return nil, fmt.Errorf("error adding message to batch: %w", err)
 		}
 		if !success {
 			// this batch is full
@@ -1322,52 +1577,76 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 				"firstMsgBlockNumber", firstMsgBlockNumber,
 				"l1BoundMinBlockNumber", l1BoundMinBlockNumber,
 			)
-			return false, errors.New("batch is within reorg resistance margin from layer 1 minimum block or timestamp bounds")
+			return nil, errors.New("batch is within reorg resistance margin from layer 1 minimum block or timestamp bounds")
 		}
 	}
 
 	if !forcePostBatch || !b.building.haveUsefulMessage {
 		// the batch isn't full yet and we've posted a batch recently
 		// don't post anything for now
-		return false, nil
+		return nil, nil
+	}
+	if prev == nil {
+		if deferred, err := b.deferPosting(ctx, config, firstMsgTime); err != nil || deferred {
+			return nil, err
+		}
 	}
 
-	sequencerMsg, err := b.building.segments.CloseAndGetBytes()
+	building := b.building
+	b.building = nil // a closed batchSegments can't be reused
+	sequencerMsg, err := building.segments.CloseAndGetBytes()
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 	if sequencerMsg == nil {
 		log.Debug("BatchPoster: batch nil", "sequence nr.", batchPosition.NextSeqNum, "from", batchPosition.MessageCount, "prev delayed", batchPosition.DelayedMessageCount)
-		b.building = nil // a closed batchSegments can't be reused
-		return false, nil
+		return nil, nil
+	}
+	batch := &pipelinedBatch{
+		nonce:                    nonce,
+		position:                 batchPosition,
+		positionBytes:            batchPositionBytes,
+		building:                 building,
+		sequencerMsg:             sequencerMsg,
+		firstMsgTime:             firstMsgTime,
+		msgCount:                 msgCount,
+		lastPotentialDelayedRead: lastPotentialMsg.DelayedMessagesRead,
+		l1BoundMinTimestamp:      l1BoundMinTimestamp,
+		l1BoundMaxTimestamp:      l1BoundMaxTimestamp,
+		l1BoundMinBlockNumber:    l1BoundMinBlockNumber,
+		l1BoundMaxBlockNumber:    l1BoundMaxBlockNumber,
+	}
+	batch.newMeta, err = rlp.EncodeToBytes(batch.nextPosition())
+	if err != nil {
+		return nil, err
 	}
+	return batch, nil
+}
 
+// startFinalizingBatch stores the batch with the DA provider, if any, and encodes, estimates and checks its
+// transaction in the background. Only the head of the pipeline is checked against what the data poster has
+// queued, as each batch after it follows from the one before.
+func (b *BatchPoster) startFinalizingBatch(ctx context.Context, config *BatchPosterConfig, batch *pipelinedBatch, head bool) error {
 	if b.dapWriter != nil {
 		if !b.redisLock.AttemptLock(ctx) {
-			return false, errAttemptLockFailed
+			return errAttemptLockFailed
 		}
 
-		gotNonce, gotMeta, err := b.dataPoster.GetNextNonceAndMeta(ctx)
-		if err != nil {
-			batchPosterDAFailureCounter.Inc(1)
-			return false, err
-		}
-		if nonce != gotNonce || !bytes.Equal(batchPositionBytes, gotMeta) {
-			batchPosterDAFailureCounter.Inc(1)
-			return false, fmt.Errorf("%w: nonce changed from %d to %d while creating batch", storage.ErrStorageRace, nonce, gotNonce)
-		}
-		sequencerMsg, err = b.dapWriter.Store(ctx, sequencerMsg, uint64(time.Now().Add(config.DASRetentionPeriod).Unix()), config.DisableDapFallbackStoreDataOnChain)
-		if err != nil {
-			batchPosterDAFailureCounter.Inc(1)
-			return false, err
+		if head {
+			gotNonce, gotMeta, err := b.dataPoster.GetNextNonceAndMeta(ctx)
+			if err != nil {
+				batchPosterDAFailureCounter.Inc(1)
+				return err
+			}
+			if batch.nonce != gotNonce || !bytes.Equal(batch.positionBytes, gotMeta) {
+				batchPosterDAFailureCounter.Inc(1)
+				return fmt.Errorf("%w: nonce changed from %d to %d while creating batch", storage.ErrStorageRace, batch.nonce, gotNonce)
+			}
 		}
-
-		batchPosterDASuccessCounter.Inc(1)
-		batchPosterDALastSuccessfulActionGauge.Update(time.Now().Unix())
 	}
 
-	prevMessageCount := batchPosition.MessageCount
-	if b.config().Dangerous.AllowPostingFirstBatchWhenSequencerMessageCountMismatch && !b.postedFirstBatch {
+	batch.prevMessageCount = batch.position.MessageCount
+	if head && config.Dangerous.AllowPostingFirstBatchWhenSequencerMessageCountMismatch && !b.postedFirstBatch {
 		// AllowPostingFirstBatchWhenSequencerMessageCountMismatch can be used when the
 		// message count stored in batch poster's database gets out
 		// of sync with the sequencerReportedSubMessageCount stored in the parent chain.
@@ -1393,17 +1672,39 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 		// If prevMessageCount is set to zero, sequencer inbox's smart contract allows
 		// to post a batch even if sequencerReportedSubMessageCount is not equal
 		// to the provided prevMessageCount
-		prevMessageCount = 0
+		batch.prevMessageCount = 0
+	}
+
+	batch.finalized = make(chan error, 1)
+	go func() {
+		batch.finalized <- b.finalizeBatch(ctx, config, batch)
+	}()
+	return nil
+}
+
+func (b *BatchPoster) finalizeBatch(ctx context.Context, config *BatchPosterConfig, batch *pipelinedBatch) error {
+	building := batch.building
+	sequencerMsg := batch.sequencerMsg
+	if b.dapWriter != nil {
+		var err error
+		sequencerMsg, err = b.dapWriter.Store(ctx, sequencerMsg, uint64(time.Now().Add(config.DASRetentionPeriod).Unix()), config.DisableDapFallbackStoreDataOnChain)
+		if err != nil {
+			batchPosterDAFailureCounter.Inc(1)
+			return err
+		}
+
+		batchPosterDASuccessCounter.Inc(1)
+		batchPosterDALastSuccessfulActionGauge.Update(time.Now().Unix())
 	}
 
-	data, kzgBlobs, err := b.encodeAddBatch(new(big.Int).SetUint64(batchPosition.NextSeqNum), prevMessageCount, b.building.msgCount, sequencerMsg, b.building.segments.delayedMsg, b.building.use4844)
+	data, kzgBlobs, err := b.encodeAddBatch(new(big.Int).SetUint64(batch.position.NextSeqNum), batch.prevMessageCount, building.msgCount, sequencerMsg, building.segments.delayedMsg, building.use4844)
 	if err != nil {
-		return false, err
+		return err
 	}
 	if len(kzgBlobs)*params.BlobTxBlobGasPerBlob > params.MaxBlobGasPerBlock {
-		return false, fmt.Errorf("produced %v blobs for batch but a block can only hold %v (compressed batch was %v bytes long)", len(kzgBlobs), params.MaxBlobGasPerBlock/params.BlobTxBlobGasPerBlob, len(sequencerMsg))
+		return fmt.Errorf("produced %v blobs for batch but a block can only hold %v (compressed batch was %v bytes long)", len(kzgBlobs), params.MaxBlobGasPerBlock/params.BlobTxBlobGasPerBlob, len(sequencerMsg))
 	}
-	accessList := b.accessList(int(batchPosition.NextSeqNum), int(b.building.segments.delayedMsg))
+	accessList := b.accessList(int(batch.position.NextSeqNum), int(building.segments.delayedMsg))
 	// On restart, we may be trying to estimate gas for a batch whose successor has
 	// already made it into pending state, if not latest state.
 	// In that case, we might get a revert with `DelayedBackwards()`.
@@ -1411,87 +1712,115 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 	// In theory, this might reduce gas usage, but only by a factor that's already
 	// accounted for in `config.ExtraBatchGas`, as that same factor can appear if a user
 	// posts a new delayed message that we didn't see while gas estimating.
-	gasLimit, err := b.estimateGas(ctx, sequencerMsg, lastPotentialMsg.DelayedMessagesRead, data, kzgBlobs, nonce, accessList)
-	if err != nil {
-		return false, err
-	}
-	newMeta, err := rlp.EncodeToBytes(batchPosterPosition{
-		MessageCount:        b.building.msgCount,
-		DelayedMessageCount: b.building.segments.delayedMsg,
-		NextSeqNum:          batchPosition.NextSeqNum + 1,
-	})
+	gasLimit, err := b.estimateGas(ctx, sequencerMsg, batch.lastPotentialDelayedRead, data, kzgBlobs, batch.nonce, accessList)
 	if err != nil {
-		return false, err
+		return err
 	}
 
 	if config.CheckBatchCorrectness {
-		dapReaders := b.dapReaders
-		if b.building.use4844 {
+		// copied, as batches are checked concurrently
+		dapReaders := append([]daprovider.Reader{}, b.dapReaders...)
+		if building.use4844 {
 			dapReaders = append(dapReaders, daprovider.NewReaderForBlobReader(&simulatedBlobReader{kzgBlobs}))
 		}
-		seqMsg := binary.BigEndian.AppendUint64([]byte{}, l1BoundMinTimestamp)
-		seqMsg = binary.BigEndian.AppendUint64(seqMsg, l1BoundMaxTimestamp)
-		seqMsg = binary.BigEndian.AppendUint64(seqMsg, l1BoundMinBlockNumber)
-		seqMsg = binary.BigEndian.AppendUint64(seqMsg, l1BoundMaxBlockNumber)
-		seqMsg = binary.BigEndian.AppendUint64(seqMsg, b.building.segments.delayedMsg)
+		seqMsg := binary.BigEndian.AppendUint64([]byte{}, batch.l1BoundMinTimestamp)
+		seqMsg = binary.BigEndian.AppendUint64(seqMsg, batch.l1BoundMaxTimestamp)
+		seqMsg = binary.BigEndian.AppendUint64(seqMsg, batch.l1BoundMinBlockNumber)
+		seqMsg = binary.BigEndian.AppendUint64(seqMsg, batch.l1BoundMaxBlockNumber)
+		seqMsg = binary.BigEndian.AppendUint64(seqMsg, building.segments.delayedMsg)
 		seqMsg = append(seqMsg, sequencerMsg...)
-		b.building.muxBackend.seqMsg = seqMsg
-		b.building.muxBackend.delayedInboxStart = batchPosition.DelayedMessageCount
-		b.building.muxBackend.SetPositionWithinMessage(0)
-		simMux := arbstate.NewInboxMultiplexer(b.building.muxBackend, batchPosition.DelayedMessageCount, dapReaders, daprovider.KeysetValidate)
-		log.Info("Begin checking the correctness of batch against inbox multiplexer", "startMsgSeqNum", batchPosition.MessageCount, "endMsgSeqNum", b.building.msgCount-1)
-		for i := batchPosition.MessageCount; i < b.building.msgCount; i++ {
+		building.muxBackend.seqMsg = seqMsg
+		building.muxBackend.delayedInboxStart = batch.position.DelayedMessageCount
+		building.muxBackend.SetPositionWithinMessage(0)
+		simMux := arbstate.NewInboxMultiplexer(building.muxBackend, batch.position.DelayedMessageCount, dapReaders, daprovider.KeysetValidate)
+		log.Info("Begin checking the correctness of batch against inbox multiplexer", "startMsgSeqNum", batch.position.MessageCount, "endMsgSeqNum", building.msgCount-1)
+		for i := batch.position.MessageCount; i < building.msgCount; i++ {
 			msg, err := simMux.Pop(ctx)
 			if err != nil {
-				return false, fmt.Errorf("error getting message from simulated inbox multiplexer (Pop) when testing correctness of batch: %w", err)
+				return fmt.Errorf("error getting message from simulated inbox multiplexer (Pop) when testing correctness of batch: %w", err)
 			}
-			if msg.DelayedMessagesRead != b.building.muxBackend.allMsgs[i].DelayedMessagesRead {
-				b.building = nil
-				return false, fmt.Errorf("simulated inbox multiplexer failed to produce correct delayedMessagesRead field for msg with seqNum: %d. Got: %d, Want: %d", i, msg.DelayedMessagesRead, b.building.muxBackend.allMsgs[i].DelayedMessagesRead)
+			if msg.DelayedMessagesRead != building.muxBackend.allMsgs[i].DelayedMessagesRead {
+				return fmt.Errorf("simulated inbox multiplexer failed to produce correct delayedMessagesRead field for msg with seqNum: %d. Got: %d, Want: %d", i, msg.DelayedMessagesRead, building.muxBackend.allMsgs[i].DelayedMessagesRead)
 			}
-			if !msg.Message.Equals(b.building.muxBackend.allMsgs[i].Message) {
-				b.building = nil
-				return false, fmt.Errorf("simulated inbox multiplexer failed to produce correct message field for msg with seqNum: %d", i)
+			if !msg.Message.Equals(building.muxBackend.allMsgs[i].Message) {
+				return fmt.Errorf("simulated inbox multiplexer failed to produce correct message field for msg with seqNum: %d", i)
 			}
 		}
-		log.Debug("Successfully checked that the batch produces correct messages when ran through inbox multiplexer", "sequenceNumber", batchPosition.NextSeqNum)
+		log.Debug("Successfully checked that the batch produces correct messages when ran through inbox multiplexer", "sequenceNumber", batch.position.NextSeqNum)
 	}
 
+	batch.sequencerMsg = sequencerMsg
+	batch.data = data
+	batch.kzgBlobs = kzgBlobs
+	batch.accessList = accessList
+	batch.gasLimit = gasLimit
+	return nil
+}
+
+// postBatch hands the finalized batch to the data poster with the nonce reserved for it.
+func (b *BatchPoster) postBatch(ctx context.Context, config *BatchPosterConfig, batch *pipelinedBatch) error {
+	building := batch.building
+	batchPosition := batch.position
+	if b.standby != nil {
+		if err := b.standby.reserve(ctx, batch.nonce); err != nil {
+			return err
+		}
+	}
+	if err := b.recordIntent(batch); err != nil {
+		return err
+	}
 	tx, err := b.dataPoster.PostTransaction(ctx,
-		firstMsgTime,
-		nonce,
-		newMeta,
+		batch.firstMsgTime,
+		batch.nonce,
+		batch.newMeta,
 		b.seqInboxAddr,
-		data,
-		gasLimit,
+		batch.data,
+		batch.gasLimit,
 		new(big.Int),
-		kzgBlobs,
-		accessList,
+		batch.kzgBlobs,
+		batch.accessList,
 	)
 	if err != nil {
-		return false, err
+		return err
 	}
 	b.postedFirstBatch = true
+	b.notePosted(ctx, batch.gasLimit)
+	if b.shadow != nil {
+		if head, err := b.l1Reader.LastHeader(ctx); err != nil {
+			log.Warn("not replaying batch on shadow parent chain, couldn't read the parent chain head", "sequenceNumber", batchPosition.NextSeqNum, "err", err)
+		} else {
+			b.shadow.enqueue(shadowBatch{
+				seqNum:        batchPosition.NextSeqNum,
+				msgCount:      building.msgCount,
+				sequencerMsg:  batch.sequencerMsg,
+				use4844:       building.use4844,
+				postedAt:      time.Now(),
+				postedAtBlock: head.Number.Uint64(),
+			})
+		}
+	}
 	log.Info(
 		"BatchPoster: batch sent",
 		"sequenceNumber", batchPosition.NextSeqNum,
 		"from", batchPosition.MessageCount,
-		"to", b.building.msgCount,
+		"to", building.msgCount,
 		"prevDelayed", batchPosition.DelayedMessageCount,
-		"currentDelayed", b.building.segments.delayedMsg,
-		"totalSegments", len(b.building.segments.rawSegments),
-		"numBlobs", len(kzgBlobs),
+		"currentDelayed", building.segments.delayedMsg,
+		"totalSegments", len(building.segments.rawSegments),
+		"numBlobs", len(batch.kzgBlobs),
 	)
 
 	recentlyHitL1Bounds := time.Since(b.lastHitL1Bounds) < config.PollInterval*3
-	postedMessages := b.building.msgCount - batchPosition.MessageCount
+	postedMessages := building.msgCount - batchPosition.MessageCount
 	b.messagesPerBatch.Update(uint64(postedMessages))
-	if b.building.use4844 {
+	if building.use4844 {
 		b.non4844BatchCount = 0
 	} else {
 		b.non4844BatchCount++
 	}
-	unpostedMessages := msgCount - b.building.msgCount
+	b.last4844 = building.use4844
+	b.lastBatchSize = len(batch.sequencerMsg)
+	unpostedMessages := batch.msgCount - building.msgCount
 	messagesPerBatch := b.messagesPerBatch.Average()
 	if messagesPerBatch == 0 {
 		// This should be impossible because we always post at least one message in a batch.
@@ -1500,7 +1829,7 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 			"messagesPerBatch is somehow zero",
 			"postedMessages", postedMessages,
 			"buildingFrom", batchPosition.MessageCount,
-			"buildingTo", b.building.msgCount,
+			"buildingTo", building.msgCount,
 		)
 		messagesPerBatch = 1
 	}
@@ -1516,8 +1845,8 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 		logLevel(
 			"a large batch posting backlog exists",
 			"recentlyHitL1Bounds", recentlyHitL1Bounds,
-			"currentPosition", b.building.msgCount,
-			"messageCount", msgCount,
+			"currentPosition", building.msgCount,
+			"messageCount", batch.msgCount,
 			"messagesPerBatch", messagesPerBatch,
 			"postedMessages", postedMessages,
 			"unpostedMessages", unpostedMessages,
@@ -1530,18 +1859,16 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 		backlog = 0
 	}
 	b.backlog.Store(backlog)
-	b.building = nil
 
 	// If we aren't queueing up transactions, wait for the receipt before moving on to the next batch.
 	if config.DataPoster.UseNoOpStorage {
 		receipt, err := b.l1Reader.WaitForTxApproval(ctx, tx)
 		if err != nil {
-			return false, fmt.Errorf("error waiting for tx receipt: %w", err)
+			return fmt.Errorf("error waiting for tx receipt: %w", err)
 		}
 		log.Info("Got successful receipt from batch poster transaction", "txHash", tx.Hash(), "blockNumber", receipt.BlockNumber, "blockHash", receipt.BlockHash)
 	}
-
-	return true, nil
+	return nil
 }
 
 func (b *BatchPoster) GetBacklogEstimate() uint64 {
@@ -1549,11 +1876,28 @@ func (b *BatchPoster) GetBacklogEstimate() uint64 {
 }
 
 func (b *BatchPoster) Start(ctxIn context.Context) {
+	if b.failover != nil {
+		b.failover.l1Reader.Start(ctxIn)
+	}
 	b.dataPoster.Start(ctxIn)
 	b.redisLock.Start(ctxIn)
 	b.StopWaiter.Start(ctxIn, b)
 	b.LaunchThread(b.pollForReverts)
 	b.LaunchThread(b.pollForL1PriceData)
+	if b.shadow != nil {
+		b.shadow.Start(ctxIn)
+	}
+	if b.standby != nil {
+		b.standby.Start(ctxIn)
+	}
+	if b.intents != nil {
+		b.CallIteratively(func(ctx context.Context) time.Duration {
+			if err := b.reconcileIntents(ctx); err != nil {
+				log.Warn("error reconciling batch posting intents", "err", err)
+			}
+			return b.config().IntentLog.ReconcileInterval
+		})
+	}
 	commonEphemeralErrorHandler := util.NewEphemeralErrorHandler(time.Minute, "", 0)
 	exceedMaxMempoolSizeEphemeralErrorHandler := util.NewEphemeralErrorHandler(5*time.Minute, dataposter.ErrExceedsMaxMempoolSize.Error(), time.Minute)
 	storageRaceEphemeralErrorHandler := util.NewEphemeralErrorHandler(5*time.Minute, storage.ErrStorageRace.Error(), time.Minute)
@@ -1596,6 +1940,17 @@ func (b *BatchPoster) Start(ctxIn context.Context) {
 			resetAllEphemeralErrs()
 			return b.config().PollInterval
 		}
+		if b.standby != nil && !b.standby.Active() {
+			log.Debug("Not posting batches right now because another batch poster is active")
+			b.building = nil
+			resetAllEphemeralErrs()
+			return b.config().PollInterval
+		}
+		if b.intentHold.Load() {
+			log.Debug("Not posting batches right now because batches from before a restart may still be pending")
+			b.building = nil
+			return b.config().PollInterval
+		}
 		posted, err := b.maybePostSequencerBatch(ctx)
 		if err == nil {
 			resetAllEphemeralErrs()
@@ -1630,8 +1985,17 @@ func (b *BatchPoster) Start(ctxIn context.Context) {
 
 func (b *BatchPoster) StopAndWait() {
 	b.StopWaiter.StopAndWait()
+	if b.shadow != nil {
+		b.shadow.StopAndWait()
+	}
+	if b.standby != nil {
+		b.standby.StopAndWait()
+	}
 	b.dataPoster.StopAndWait()
 	b.redisLock.StopAndWait()
+	if b.failover != nil {
+		b.failover.stopAndClose()
+	}
 }
 
 type BoolRing struct {
@@ -0,0 +1,173 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/offchainlabs/nitro/util/arbmath"
+)
+
+var (
+	deferredPostingCounter = metrics.NewRegisteredCounter("arb/batchposter/deferral/deferred", nil)
+	deferralExpiredCounter = metrics.NewRegisteredCounter("arb/batchposter/deferral/expired", nil)
+	deferralSavingsCounter = metrics.NewRegisteredCounter("arb/batchposter/deferral/savings_gwei", nil)
+)
+
+// BatchPosterDeferralConfig has the batch poster hold off on posting batches during configured windows, or while
+// the parent chain base fee is above a ceiling. Batches are only deferred for so long, and never once they get
+// close to being force includable, as a delayed batch can't be force included ahead of.
+type BatchPosterDeferralConfig struct {
+	MaxBaseFeeGwei       float64       `koanf:"max-base-fee-gwei" reload:"hot"`
+	AvoidWindows         []string      `koanf:"avoid-windows" reload:"hot"`
+	MaxDeferral          time.Duration `koanf:"max-deferral" reload:"hot"`
+	ForceInclusionMargin time.Duration `koanf:"force-inclusion-margin" reload:"hot"`
+
+	avoidWindows []postingWindow
+}
+
+var DefaultBatchPosterDeferralConfig = BatchPosterDeferralConfig{
+	MaxBaseFeeGwei:       0,
+	AvoidWindows:         []string{},
+	MaxDeferral:          2 * time.Hour,
+	ForceInclusionMargin: 2 * time.Hour,
+}
+
+func BatchPosterDeferralConfigAddOptions(prefix string, f *pflag.FlagSet) {
+	f.Float64(prefix+".max-base-fee-gwei", DefaultBatchPosterDeferralConfig.MaxBaseFeeGwei, "defer posting batches while the parent chain base fee is above this (0 = no ceiling)")
+	f.StringSlice(prefix+".avoid-windows", DefaultBatchPosterDeferralConfig.AvoidWindows, "daily UTC windows to defer posting batches during, as HH:MM-HH:MM")
+	f.Duration(prefix+".max-deferral", DefaultBatchPosterDeferralConfig.MaxDeferral, "the longest to defer posting a batch before posting it anyway")
+	f.Duration(prefix+".force-inclusion-margin", DefaultBatchPosterDeferralConfig.ForceInclusionMargin, "never defer posting a batch whose first message is within this of becoming force includable")
+}
+
+func (c *BatchPosterDeferralConfig) Validate() error {
+	if c.MaxBaseFeeGwei < 0 {
+		return fmt.Errorf("invalid max base fee %v", c.MaxBaseFeeGwei)
+	}
+	c.avoidWindows = nil
+	for _, window := range c.AvoidWindows {
+		parsed, err := parsePostingWindow(window)
+		if err != nil {
+			return err
+		}
+		c.avoidWindows = append(c.avoidWindows, parsed)
+	}
+	return nil
+}
+
+func (c *BatchPosterDeferralConfig) enabled() bool {
+	return c.MaxBaseFeeGwei > 0 || len(c.avoidWindows) > 0
+}
+
+// postingWindow is a daily window, in minutes since midnight UTC, which wraps around midnight if end is before start.
+type postingWindow struct {
+	start, end int
+}
+
+func parsePostingWindow(window string) (postingWindow, error) {
+	parts := strings.Split(window, "-")
+	if len(parts) != 2 {
+		return postingWindow{}, fmt.Errorf("invalid posting window \"%v\", expected HH:MM-HH:MM", window)
+	}
+	var bounds [2]int
+	for i, part := range parts {
+		t, err := time.Parse("15:04", strings.TrimSpace(part))
+		if err != nil {
+			return postingWindow{}, fmt.Errorf("invalid posting window \"%v\": %w", window, err)
+		}
+		bounds[i] = t.Hour()*60 + t.Minute()
+	}
+	return postingWindow{start: bounds[0], end: bounds[1]}, nil
+}
+
+func (w postingWindow) contains(t time.Time) bool {
+	t = t.UTC()
+	minute := t.Hour()*60 + t.Minute()
+	if w.start <= w.end {
+		return minute >= w.start && minute < w.end
+	}
+	return minute >= w.start || minute < w.end
+}
+
+// deferralReason returns why posting should be deferred given the base fee, if it should.
+func (c *BatchPosterDeferralConfig) deferralReason(now time.Time, baseFee *big.Int) string {
+	for _, window := range c.avoidWindows {
+		if window.contains(now) {
+			return "posting window"
+		}
+	}
+	if c.MaxBaseFeeGwei > 0 && baseFee != nil && baseFee.Cmp(arbmath.FloatToBig(c.MaxBaseFeeGwei*params.GWei)) > 0 {
+		return "base fee ceiling"
+	}
+	return ""
+}
+
+// deferPosting returns whether to hold off on posting a batch whose first message is at firstMsgTime for now.
+func (b *BatchPoster) deferPosting(ctx context.Context, config *BatchPosterConfig, firstMsgTime time.Time) (bool, error) {
+	deferral := &config.Deferral
+	if !deferral.enabled() {
+		return false, nil
+	}
+	header, err := b.l1Reader.LastHeader(ctx)
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	reason := deferral.deferralReason(now, header.BaseFee)
+	if reason == "" {
+		return false, nil
+	}
+	if !b.deferredSince.IsZero() && now.Sub(b.deferredSince) >= deferral.MaxDeferral {
+		log.Warn("posting batch after deferring it for as long as allowed", "reason", reason, "deferredFor", now.Sub(b.deferredSince), "baseFee", header.BaseFee)
+		deferralExpiredCounter.Inc(1)
+		return false, nil
+	}
+	_, _, delaySeconds, _, err := b.seqInbox.MaxTimeVariation(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return false, fmt.Errorf("error getting max time variation: %w", err)
+	}
+	forceIncludableAt := firstMsgTime.Add(time.Duration(arbmath.BigToUintSaturating(delaySeconds)) * time.Second)
+	if now.Add(deferral.ForceInclusionMargin).After(forceIncludableAt) {
+		log.Warn("not deferring batch close to becoming force includable", "reason", reason, "forceIncludableAt", forceIncludableAt)
+		return false, nil
+	}
+	if b.deferredSince.IsZero() {
+		b.deferredSince = now
+		b.deferredBaseFee = header.BaseFee
+		log.Info("deferring batch posting", "reason", reason, "baseFee", header.BaseFee)
+	}
+	deferredPostingCounter.Inc(1)
+	return true, nil
+}
+
+// notePosted records the estimated savings of having deferred a batch posted with the gas limit, if it was.
+func (b *BatchPoster) notePosted(ctx context.Context, gasLimit uint64) {
+	if b.deferredSince.IsZero() {
+		return
+	}
+	deferredFor := time.Since(b.deferredSince)
+	deferredBaseFee := b.deferredBaseFee
+	b.deferredSince = time.Time{}
+	b.deferredBaseFee = nil
+	header, err := b.l1Reader.LastHeader(ctx)
+	if err != nil || header.BaseFee == nil || deferredBaseFee == nil {
+		return
+	}
+	savings := arbmath.BigMul(arbmath.BigSub(deferredBaseFee, header.BaseFee), arbmath.UintToBig(gasLimit))
+	log.Info("posted deferred batch", "deferredFor", deferredFor, "deferredBaseFee", deferredBaseFee, "baseFee", header.BaseFee, "estimatedSavings", savings)
+	if savings.Sign() > 0 {
+		deferralSavingsCounter.Inc(arbmath.BigDivByUint(savings, params.GWei).Int64())
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/solgen/go/precompilesgen"
+	"github.com/offchainlabs/nitro/util/headerreader"
+	"github.com/offchainlabs/nitro/util/rpcclient"
+)
+
+// parentChainFailover lets the batch poster keep posting when its parent chain connection fails, by reading
+// and sending through whichever of it and the configured fallback endpoints is working.
+type parentChainFailover struct {
+	l1Reader  *headerreader.HeaderReader
+	primary   arbutil.L1Interface
+	fallbacks []*rpcclient.RpcClient
+}
+
+func newParentChainFailover(ctx context.Context, primary *headerreader.HeaderReader, urls []string) (*parentChainFailover, error) {
+	chainID, err := primary.Client().ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f := &parentChainFailover{primary: primary.Client()}
+	endpoints := []rpc.ClientInterface{primary.Client().Client()}
+	for _, url := range urls {
+		config := rpcclient.DefaultClientConfig
+		config.URL = url
+		if err := config.Validate(); err != nil {
+			f.close()
+			return nil, err
+		}
+		client := rpcclient.NewRpcClient(func() *rpcclient.ClientConfig { return &config }, nil)
+		if err := client.Start(ctx); err != nil {
+			f.close()
+			return nil, fmt.Errorf("couldn't connect to fallback parent chain endpoint %v: %w", url, err)
+		}
+		f.fallbacks = append(f.fallbacks, client)
+		fallbackChainID, err := ethclient.NewClient(client).ChainID(ctx)
+		if err != nil {
+			f.close()
+			return nil, fmt.Errorf("couldn't read chain id from fallback parent chain endpoint %v: %w", url, err)
+		}
+		if fallbackChainID.Cmp(chainID) != 0 {
+			f.close()
+			return nil, fmt.Errorf("fallback parent chain endpoint %v serves chain %v instead of %v", url, fallbackChainID, chainID)
+		}
+		endpoints = append(endpoints, client)
+	}
+	client := ethclient.NewClient(rpcclient.NewFailoverClient(endpoints...))
+	arbSys, _ := precompilesgen.NewArbSys(types.ArbSysAddress, client)
+	f.l1Reader, err = headerreader.New(ctx, client, primary.Config, arbSys)
+	if err != nil {
+		f.close()
+		return nil, err
+	}
+	log.Info("batch poster failing over between parent chain endpoints", "fallbacks", len(urls))
+	return f, nil
+}
+
+// crossCheckClients returns a client for each endpoint, for the data poster to check each of them before
+// replacing a transaction by fee.
+func (f *parentChainFailover) crossCheckClients() []arbutil.L1Interface {
+	if f == nil {
+		return nil
+	}
+	clients := []arbutil.L1Interface{f.primary}
+	for _, fallback := range f.fallbacks {
+		clients = append(clients, ethclient.NewClient(fallback))
+	}
+	return clients
+}
+
+func (f *parentChainFailover) stopAndClose() {
+	f.l1Reader.StopAndWait()
+	f.close()
+}
+
+// close closes the fallback endpoints, leaving the primary one to its owner.
+func (f *parentChainFailover) close() {
+	for _, fallback := range f.fallbacks {
+		fallback.Close()
+	}
+}
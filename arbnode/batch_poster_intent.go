@@ -0,0 +1,281 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/offchainlabs/nitro/arbutil"
+)
+
+var (
+	intentRecordedCounter  = metrics.NewRegisteredCounter("arb/batchposter/intent/recorded", nil)
+	intentConfirmedCounter = metrics.NewRegisteredCounter("arb/batchposter/intent/confirmed", nil)
+	intentAbandonedCounter = metrics.NewRegisteredCounter("arb/batchposter/intent/abandoned", nil)
+	intentConflictCounter  = metrics.NewRegisteredCounter("arb/batchposter/intent/conflict", nil)
+	intentQueuedGauge      = metrics.NewRegisteredGauge("arb/batchposter/intent/queued", nil)
+	intentInFlightGauge    = metrics.NewRegisteredGauge("arb/batchposter/intent/inflight", nil)
+)
+
+// BatchPosterIntentLogConfig configures recording each batch the poster is about to post before handing it to the
+// data poster, so that after a crash it can tell which batches made it to the parent chain.
+type BatchPosterIntentLogConfig struct {
+	Enable            bool          `koanf:"enable"`
+	ReconcileInterval time.Duration `koanf:"reconcile-interval" reload:"hot"`
+	InFlightTimeout   time.Duration `koanf:"in-flight-timeout" reload:"hot"`
+}
+
+var DefaultBatchPosterIntentLogConfig = BatchPosterIntentLogConfig{
+	Enable:            true,
+	ReconcileInterval: time.Minute,
+	InFlightTimeout:   30 * time.Minute,
+}
+
+func BatchPosterIntentLogConfigAddOptions(prefix string, f *pflag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultBatchPosterIntentLogConfig.Enable, "record each batch before posting it, and on restart reconcile the records against the parent chain to avoid double posting or gaps")
+	f.Duration(prefix+".reconcile-interval", DefaultBatchPosterIntentLogConfig.ReconcileInterval, "how often to reconcile unresolved batch posting intents against the parent chain")
+	f.Duration(prefix+".in-flight-timeout", DefaultBatchPosterIntentLogConfig.InFlightTimeout, "how long to hold off posting while a batch the data poster lost track of is pending on the parent chain, before replacing it")
+}
+
+// batchPostingIntent records a batch about to be posted with a nonce.
+type batchPostingIntent struct {
+	Nonce                    uint64
+	SequenceNumber           uint64
+	FromMessage              arbutil.MessageIndex
+	ToMessage                arbutil.MessageIndex
+	AfterDelayedMessagesRead uint64
+	DataHash                 common.Hash // of the calldata and any blobs
+	Recorded                 uint64      // unix timestamp
+}
+
+type intentState int
+
+const (
+	// the nonce was used to post the batch
+	intentConfirmed intentState = iota
+	// the data poster has the transaction queued, and will see it through
+	intentQueued
+	// a transaction with the nonce is pending on the parent chain, but the data poster has lost track of it,
+	// so posting anything else with the nonce would race it
+	intentInFlight
+	// the transaction never reached the parent chain, and the batch will be rebuilt from the same position
+	intentAbandoned
+	// the nonce was used, but not to post the batch
+	intentConflict
+)
+
+func (s intentState) String() string {
+	switch s {
+	case intentConfirmed:
+		return "confirmed"
+	case intentQueued:
+		return "queued"
+	case intentInFlight:
+		return "in-flight"
+	case intentAbandoned:
+		return "abandoned"
+	case intentConflict:
+		return "conflict"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+// intentChainState is what intents are reconciled against.
+type intentChainState struct {
+	latestNonce  uint64 // the poster's nonce as of the latest parent chain block
+	batchCount   uint64 // the sequencer inbox's batch count as of the same block
+	pendingNonce uint64 // the poster's nonce including pending transactions
+}
+
+// state returns the intent's state, given whether the data poster has a transaction with its nonce queued.
+func (i *batchPostingIntent) state(chain intentChainState, queued bool) intentState {
+	if i.Nonce < chain.latestNonce {
+		if i.SequenceNumber < chain.batchCount {
+			return intentConfirmed
+		}
+		return intentConflict
+	}
+	if queued {
+		return intentQueued
+	}
+	if i.Nonce < chain.pendingNonce {
+		return intentInFlight
+	}
+	return intentAbandoned
+}
+
+// batchPostingIntentLog keeps intents in the database by nonce.
+type batchPostingIntentLog struct {
+	db ethdb.Database
+}
+
+func intentKey(nonce uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, nonce)
+}
+
+func (l *batchPostingIntentLog) record(intent *batchPostingIntent) error {
+	data, err := rlp.EncodeToBytes(intent)
+	if err != nil {
+		return err
+	}
+	return l.db.Put(intentKey(intent.Nonce), data)
+}
+
+func (l *batchPostingIntentLog) remove(nonce uint64) error {
+	return l.db.Delete(intentKey(nonce))
+}
+
+func (l *batchPostingIntentLog) all() ([]*batchPostingIntent, error) {
+	it := l.db.NewIterator(nil, nil)
+	defer it.Release()
+	var intents []*batchPostingIntent
+	for it.Next() {
+		var intent batchPostingIntent
+		if err := rlp.DecodeBytes(it.Value(), &intent); err != nil {
+			return nil, fmt.Errorf("decoding batch posting intent %x: %w", it.Key(), err)
+		}
+		intents = append(intents, &intent)
+	}
+	return intents, it.Error()
+}
+
+func batchDataHash(batch *pipelinedBatch) common.Hash {
+	parts := [][]byte{batch.data}
+	for i := range batch.kzgBlobs {
+		parts = append(parts, batch.kzgBlobs[i][:])
+	}
+	return crypto.Keccak256Hash(parts...)
+}
+
+// recordIntent records that the batch is about to be posted, before it's handed to the data poster.
+func (b *BatchPoster) recordIntent(batch *pipelinedBatch) error {
+	if b.intents == nil {
+		return nil
+	}
+	intent := &batchPostingIntent{
+		Nonce:                    batch.nonce,
+		SequenceNumber:           batch.position.NextSeqNum,
+		FromMessage:              batch.position.MessageCount,
+		ToMessage:                batch.building.msgCount,
+		AfterDelayedMessagesRead: batch.building.segments.delayedMsg,
+		DataHash:                 batchDataHash(batch),
+		Recorded:                 uint64(time.Now().Unix()),
+	}
+	if err := b.intents.record(intent); err != nil {
+		return fmt.Errorf("error recording batch posting intent: %w", err)
+	}
+	intentRecordedCounter.Inc(1)
+	return nil
+}
+
+// loadIntents notes the intents left from before a restart, holding off posting until they've been reconciled.
+func (b *BatchPoster) loadIntents() error {
+	intents, err := b.intents.all()
+	if err != nil {
+		return err
+	}
+	b.recoveringIntents = make(map[uint64]bool)
+	for _, intent := range intents {
+		b.recoveringIntents[intent.Nonce] = true
+	}
+	if len(intents) > 0 {
+		log.Info("reconciling batch posting intents from before restart", "count", len(intents), "firstNonce", intents[0].Nonce)
+		b.intentHold.Store(true)
+	}
+	return nil
+}
+
+// reconcileIntents resolves the recorded intents against the parent chain, holding off posting while a batch from
+// before a restart is pending there that the data poster doesn't know about.
+func (b *BatchPoster) reconcileIntents(ctx context.Context) error {
+	intents, err := b.intents.all()
+	if err != nil {
+		return err
+	}
+	if len(intents) == 0 {
+		intentQueuedGauge.Update(0)
+		intentInFlightGauge.Update(0)
+		b.intentHold.Store(false)
+		return nil
+	}
+	sender := b.dataPoster.Sender()
+	client := b.l1Reader.Client()
+	blockNum, err := client.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	var chain intentChainState
+	chain.latestNonce, err = client.NonceAt(ctx, sender, new(big.Int).SetUint64(blockNum))
+	if err != nil {
+		return err
+	}
+	batchCount, err := b.seqInbox.BatchCount(&bind.CallOpts{Context: ctx, BlockNumber: new(big.Int).SetUint64(blockNum)})
+	if err != nil {
+		return err
+	}
+	chain.batchCount = batchCount.Uint64()
+	chain.pendingNonce, err = client.PendingNonceAt(ctx, sender)
+	if err != nil {
+		return err
+	}
+
+	config := b.config().IntentLog
+	var queued, inFlight int64
+	for _, intent := range intents {
+		isQueued, err := b.dataPoster.Queued(ctx, intent.Nonce)
+		if err != nil {
+			return err
+		}
+		state := intent.state(chain, isQueued)
+		age := time.Since(time.Unix(int64(intent.Recorded), 0))
+		if state == intentInFlight && !b.recoveringIntents[intent.Nonce] {
+			// this process is posting it, and the data poster can't have lost track of it
+			state = intentQueued
+		}
+		if state == intentInFlight && age > config.InFlightTimeout {
+			log.Warn("giving up waiting on batch pending on the parent chain, it'll be replaced", "nonce", intent.Nonce, "sequenceNumber", intent.SequenceNumber, "dataHash", intent.DataHash, "age", age)
+			state = intentAbandoned
+		}
+		switch state {
+		case intentQueued:
+			queued++
+			continue
+		case intentInFlight:
+			inFlight++
+			log.Warn("holding off posting while a batch the data poster lost track of is pending on the parent chain", "nonce", intent.Nonce, "sequenceNumber", intent.SequenceNumber, "dataHash", intent.DataHash, "age", age)
+			continue
+		case intentConfirmed:
+			intentConfirmedCounter.Inc(1)
+		case intentAbandoned:
+			intentAbandonedCounter.Inc(1)
+			log.Info("batch posting intent never reached the parent chain, the batch will be posted again", "nonce", intent.Nonce, "sequenceNumber", intent.SequenceNumber, "from", intent.FromMessage, "to", intent.ToMessage)
+		case intentConflict:
+			intentConflictCounter.Inc(1)
+			log.Error("batch posting intent's nonce was used without posting the batch", "nonce", intent.Nonce, "sequenceNumber", intent.SequenceNumber, "batchCount", chain.batchCount, "dataHash", intent.DataHash)
+		}
+		if err := b.intents.remove(intent.Nonce); err != nil {
+			return err
+		}
+		delete(b.recoveringIntents, intent.Nonce)
+	}
+	intentQueuedGauge.Update(queued)
+	intentInFlightGauge.Update(inFlight)
+	b.intentHold.Store(inFlight > 0)
+	return nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+)
+
+func TestBatchPostingIntentState(t *testing.T) {
+	intent := &batchPostingIntent{Nonce: 10, SequenceNumber: 5}
+	for _, test := range []struct {
+		desc     string
+		chain    intentChainState
+		queued   bool
+		expected intentState
+	}{
+		{"mined, batch landed", intentChainState{latestNonce: 11, batchCount: 6, pendingNonce: 11}, false, intentConfirmed},
+		{"mined, batch didn't land", intentChainState{latestNonce: 11, batchCount: 5, pendingNonce: 11}, false, intentConflict},
+		{"queued in the data poster", intentChainState{latestNonce: 10, batchCount: 5, pendingNonce: 11}, true, intentQueued},
+		{"pending but not queued", intentChainState{latestNonce: 10, batchCount: 5, pendingNonce: 11}, false, intentInFlight},
+		{"never sent", intentChainState{latestNonce: 10, batchCount: 5, pendingNonce: 10}, false, intentAbandoned},
+	} {
+		if state := intent.state(test.chain, test.queued); state != test.expected {
+			t.Errorf("%v: got state %v, expected %v", test.desc, state, test.expected)
+		}
+	}
+}
+
+func TestBatchPostingIntentLog(t *testing.T) {
+	intents := &batchPostingIntentLog{db: rawdb.NewMemoryDatabase()}
+	for _, nonce := range []uint64{300, 2, 1} {
+		Require(t, intents.record(&batchPostingIntent{Nonce: nonce, SequenceNumber: nonce + 1, DataHash: common.Hash{byte(nonce)}}))
+	}
+	Require(t, intents.remove(2))
+	all, err := intents.all()
+	Require(t, err)
+	if len(all) != 2 || all[0].Nonce != 1 || all[1].Nonce != 300 {
+		t.Fatalf("unexpected intents %+v", all)
+	}
+	if all[1].SequenceNumber != 301 || all[1].DataHash != (common.Hash{byte(300 % 256)}) {
+		t.Fatalf("intent didn't round trip: %+v", all[1])
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/util/arbmath"
+)
+
+var (
+	batchPosterPipelineDepthGauge       = metrics.NewRegisteredGauge("arb/batchposter/pipeline/depth", nil)
+	batchPosterPipelineAbandonedCounter = metrics.NewRegisteredCounter("arb/batchposter/pipeline/abandoned", nil)
+)
+
+const (
+	PostedPipelinedBatchesLogMsg    = "BatchPoster: posted pipelined batches"
+	AbandonedPipelinedBatchesLogMsg = "abandoning pipelined batches after failing to post one"
+)
+
+// pipelinedBatch is a built batch along with the data poster nonce reserved for it. While there's a backlog,
+// several batches are built back to back, each following the one before it, and each is stored, estimated and
+// checked while the ones after it are built. They're then posted in nonce order, and if one fails, the rest are
+// abandoned without their nonces ever reaching the data poster, so its queue never has a gap.
+type pipelinedBatch struct {
+	nonce         uint64
+	position      batchPosterPosition
+	positionBytes []byte
+	newMeta       []byte
+	building      *buildingBatch
+	sequencerMsg  []byte
+	firstMsgTime  time.Time
+	// the streamer's message count, and how many delayed messages its last message had read, when it was built
+	msgCount                 arbutil.MessageIndex
+	lastPotentialDelayedRead uint64
+	prevMessageCount         arbutil.MessageIndex
+
+	l1BoundMinTimestamp   uint64
+	l1BoundMaxTimestamp   uint64
+	l1BoundMinBlockNumber uint64
+	l1BoundMaxBlockNumber uint64
+
+	// receives the result of finalizing the batch, after which the fields below are set
+	finalized  chan error
+	data       []byte
+	kzgBlobs   []kzg4844.Blob
+	accessList types.AccessList
+	gasLimit   uint64
+}
+
+// nextPosition returns the position of the batch following this one.
+func (p *pipelinedBatch) nextPosition() batchPosterPosition {
+	return batchPosterPosition{
+		MessageCount:        p.building.msgCount,
+		DelayedMessageCount: p.building.segments.delayedMsg,
+		NextSeqNum:          p.position.NextSeqNum + 1,
+	}
+}
+
+// pipelineDepth returns how many batches to build and post at once: just one unless there's a backlog to work
+// through, and never more than the data poster can have in the mempool.
+func (b *BatchPoster) pipelineDepth(config *BatchPosterConfig) int {
+	if config.MaxPipelinedBatches <= 1 || b.GetBacklogEstimate() == 0 {
+		return 1
+	}
+	depth := uint64(config.MaxPipelinedBatches)
+	if maxMempool := b.dataPoster.MaxMempoolTransactions(); maxMempool > 0 {
+		depth = arbmath.MinInt(depth, maxMempool)
+	}
+	return int(depth)
+}
@@ -0,0 +1,501 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/solgen/go/bridgegen"
+	"github.com/offchainlabs/nitro/util/arbmath"
+	"github.com/offchainlabs/nitro/util/blobs"
+	"github.com/offchainlabs/nitro/util/rpcclient"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+var (
+	shadowBatchSuccessCounter = metrics.NewRegisteredCounter("arb/batchposter/shadow/success", nil)
+	shadowBatchFailureCounter = metrics.NewRegisteredCounter("arb/batchposter/shadow/failure", nil)
+	shadowBatchDroppedCounter = metrics.NewRegisteredCounter("arb/batchposter/shadow/dropped", nil)
+	shadowBatchCostGauge      = metrics.NewRegisteredGaugeFloat64("arb/batchposter/shadow/cost/eth", nil)
+	primaryBatchCostGauge     = metrics.NewRegisteredGaugeFloat64("arb/batchposter/shadow/primarycost/eth", nil)
+	shadowBatchCostRatioGauge = metrics.NewRegisteredGauge("arb/batchposter/shadow/costratio/bips", nil)
+	shadowBatchGasGauge       = metrics.NewRegisteredGauge("arb/batchposter/shadow/gas", nil)
+	shadowInclusionTimer      = metrics.NewRegisteredTimer("arb/batchposter/shadow/inclusion", nil)
+	primaryInclusionTimer     = metrics.NewRegisteredTimer("arb/batchposter/shadow/primaryinclusion", nil)
+)
+
+const (
+	shadowModeEstimate = "estimate"
+	shadowModePost     = "post"
+)
+
+// BatchPosterShadowConfig configures replaying every posted batch against a second parent chain,
+// to rehearse moving the chain's settlement layer and compare the cost and timing of both parents.
+type BatchPosterShadowConfig struct {
+	Enable                bool                   `koanf:"enable"`
+	Mode                  string                 `koanf:"mode"`
+	ParentChainConnection rpcclient.ClientConfig `koanf:"parent-chain-connection"`
+	SequencerInbox        string                 `koanf:"sequencer-inbox"`
+	PrivateKey            string                 `koanf:"private-key"`
+	QueueSize             int                    `koanf:"queue-size"`
+	InclusionTimeout      time.Duration          `koanf:"inclusion-timeout" reload:"hot"`
+
+	sequencerInbox common.Address
+	privateKey     *ecdsa.PrivateKey
+}
+
+var DefaultBatchPosterShadowConfig = BatchPosterShadowConfig{
+	Enable:                false,
+	Mode:                  shadowModeEstimate,
+	ParentChainConnection: rpcclient.DefaultClientConfig,
+	QueueSize:             16,
+	InclusionTimeout:      10 * time.Minute,
+}
+
+func BatchPosterShadowConfigAddOptions(prefix string, f *pflag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultBatchPosterShadowConfig.Enable, "replay posted batches against a second parent chain to rehearse a settlement layer migration")
+	f.String(prefix+".mode", DefaultBatchPosterShadowConfig.Mode, "how batches are replayed against the shadow parent chain (\"estimate\" to only estimate their gas without any on-chain effect, or \"post\" to post them to a test sequencer inbox)")
+	rpcclient.RPCClientAddOptions(prefix+".parent-chain-connection", f, &DefaultBatchPosterShadowConfig.ParentChainConnection)
+	f.String(prefix+".sequencer-inbox", DefaultBatchPosterShadowConfig.SequencerInbox, "address of the sequencer inbox on the shadow parent chain")
+	f.String(prefix+".private-key", DefaultBatchPosterShadowConfig.PrivateKey, "private key of the wallet posting to the shadow sequencer inbox (required in post mode, used as the sender of estimates if set)")
+	f.Int(prefix+".queue-size", DefaultBatchPosterShadowConfig.QueueSize, "maximum number of posted batches waiting to be replayed; further batches aren't replayed until the queue drains")
+	f.Duration(prefix+".inclusion-timeout", DefaultBatchPosterShadowConfig.InclusionTimeout, "how long after a batch is posted to give up on replaying it and comparing it with the primary parent chain's")
+}
+
+func (c *BatchPosterShadowConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.Mode != shadowModeEstimate && c.Mode != shadowModePost {
+		return fmt.Errorf("invalid batch poster shadow mode \"%v\" (see --help for options)", c.Mode)
+	}
+	if !common.IsHexAddress(c.SequencerInbox) {
+		return fmt.Errorf("invalid shadow sequencer inbox address \"%v\"", c.SequencerInbox)
+	}
+	c.sequencerInbox = common.HexToAddress(c.SequencerInbox)
+	c.privateKey = nil
+	if c.PrivateKey != "" {
+		privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(c.PrivateKey, "0x"))
+		if err != nil {
+			return fmt.Errorf("invalid shadow batch poster private key: %w", err)
+		}
+		c.privateKey = privateKey
+	} else if c.Mode == shadowModePost {
+		return errors.New("shadow batch poster in post mode requires a private key")
+	}
+	if c.QueueSize <= 0 {
+		return errors.New("shadow batch poster queue size must be positive")
+	}
+	return c.ParentChainConnection.Validate()
+}
+
+// shadowBatch is a batch posted to the primary parent chain, waiting to be replayed.
+type shadowBatch struct {
+	seqNum        uint64
+	msgCount      arbutil.MessageIndex
+	sequencerMsg  []byte
+	use4844       bool
+	postedAt      time.Time
+	postedAtBlock uint64 // the primary parent chain's head when the batch was posted
+}
+
+// shadowPrimary finds batches posted to the primary parent chain.
+type shadowPrimary interface {
+	// batchReceipt returns the receipt of the transaction which posted the batch, or nil if it isn't included yet.
+	batchReceipt(ctx context.Context, seqNum uint64, fromBlock uint64) (*types.Receipt, error)
+}
+
+// seqInboxPrimary finds batches by the primary sequencer inbox's batch delivered events. The data poster may
+// replace a batch's transaction, so the transaction isn't known until the batch is included.
+type seqInboxPrimary struct {
+	client   arbutil.L1Interface
+	seqInbox common.Address
+}
+
+func (p *seqInboxPrimary) batchReceipt(ctx context.Context, seqNum uint64, fromBlock uint64) (*types.Receipt, error) {
+	logs, err := p.client.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		Addresses: []common.Address{p.seqInbox},
+		Topics:    [][]common.Hash{{batchDeliveredID}, {common.BigToHash(new(big.Int).SetUint64(seqNum))}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(logs) == 0 {
+		return nil, nil
+	}
+	return p.client.TransactionReceipt(ctx, logs[len(logs)-1].TxHash)
+}
+
+// shadowBatchPoster replays the batches posted by a BatchPoster against another parent chain.
+// Replaying never holds up posting to the primary parent chain: batches are queued, and dropped if
+// the shadow parent chain falls behind.
+type shadowBatchPoster struct {
+	stopwaiter.StopWaiter
+	poster    *BatchPoster
+	primary   shadowPrimary
+	config    func() *BatchPosterShadowConfig
+	rpcClient *rpcclient.RpcClient
+	client    *ethclient.Client
+	seqInbox  *bridgegen.SequencerInbox
+	chainID   *big.Int
+	sender    common.Address
+	queue     chan shadowBatch
+}
+
+func newShadowBatchPoster(ctx context.Context, poster *BatchPoster, config func() *BatchPosterShadowConfig) (*shadowBatchPoster, error) {
+	rpcClient := rpcclient.NewRpcClient(func() *rpcclient.ClientConfig { return &config().ParentChainConnection }, nil)
+	if err := rpcClient.Start(ctx); err != nil {
+		return nil, fmt.Errorf("couldn't connect to shadow parent chain: %w", err)
+	}
+	client := ethclient.NewClient(rpcClient)
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		rpcClient.Close()
+		return nil, fmt.Errorf("couldn't read shadow parent chain id: %w", err)
+	}
+	primaryChainID, err := poster.l1Reader.Client().ChainID(ctx)
+	if err != nil {
+		rpcClient.Close()
+		return nil, fmt.Errorf("couldn't read primary parent chain id: %w", err)
+	}
+	if err := checkShadowSeparation(chainID, primaryChainID, config().privateKey, poster.dataPoster.Sender()); err != nil {
+		rpcClient.Close()
+		return nil, err
+	}
+	seqInbox, err := bridgegen.NewSequencerInbox(config().sequencerInbox, client)
+	if err != nil {
+		rpcClient.Close()
+		return nil, err
+	}
+	// without a wallet of its own, estimate as the primary batch poster, which the shadow inbox is expected to allow
+	sender := poster.dataPoster.Sender()
+	if privateKey := config().privateKey; privateKey != nil {
+		sender = crypto.PubkeyToAddress(privateKey.PublicKey)
+	}
+	log.Info("batch poster shadowing parent chain", "chainId", chainID, "sequencerInbox", config().sequencerInbox, "mode", config().Mode, "sender", sender)
+	return &shadowBatchPoster{
+		poster:    poster,
+		primary:   &seqInboxPrimary{client: poster.l1Reader.Client(), seqInbox: poster.seqInboxAddr},
+		config:    config,
+		rpcClient: rpcClient,
+		client:    client,
+		seqInbox:  seqInbox,
+		chainID:   chainID,
+		sender:    sender,
+		queue:     make(chan shadowBatch, config().QueueSize),
+	}, nil
+}
+
+// checkShadowSeparation refuses a shadow parent chain whose transactions could be replayed on the primary parent
+// chain: one with the same chain id, such as a fork of it or the primary parent chain itself, or one posted to with
+// the primary batch poster's wallet, which would use up the data poster's nonces.
+func checkShadowSeparation(shadowChainID, primaryChainID *big.Int, privateKey *ecdsa.PrivateKey, primarySender common.Address) error {
+	if shadowChainID.Cmp(primaryChainID) == 0 {
+		return fmt.Errorf("shadow parent chain has the primary parent chain's chain id %v, so batches posted to it could be replayed on the primary parent chain", shadowChainID)
+	}
+	if privateKey != nil && crypto.PubkeyToAddress(privateKey.PublicKey) == primarySender {
+		return fmt.Errorf("shadow batch poster private key is the primary batch poster's wallet %v, which must not post to the shadow parent chain", primarySender)
+	}
+	return nil
+}
+
+func (s *shadowBatchPoster) enqueue(batch shadowBatch) {
+	select {
+	case s.queue <- batch:
+	default:
+		shadowBatchDroppedCounter.Inc(1)
+		log.Warn("shadow batch poster is falling behind, not replaying batch", "sequenceNumber", batch.seqNum)
+	}
+}
+
+func (s *shadowBatchPoster) Start(ctxIn context.Context) {
+	s.StopWaiter.Start(ctxIn, s)
+	s.LaunchThread(func(ctx context.Context) {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case batch := <-s.queue:
+				s.replayAndCompare(ctx, batch)
+			}
+		}
+	})
+}
+
+func (s *shadowBatchPoster) StopAndWait() {
+	s.StopWaiter.StopAndWait()
+	if s.rpcClient != nil {
+		s.rpcClient.Close()
+	}
+}
+
+// replayAndCompare replays the batch on the shadow parent chain, then compares it with the primary batch once
+// that's included. Everything about a batch must finish within the inclusion timeout of it being posted, so a
+// backlog of batches doesn't build up behind one that's stuck.
+func (s *shadowBatchPoster) replayAndCompare(ctx context.Context, batch shadowBatch) {
+	batchCtx, cancel := context.WithDeadline(ctx, batch.postedAt.Add(s.config().InclusionTimeout))
+	shadow, err := s.replay(batchCtx, batch)
+	if err != nil {
+		cancel()
+		if ctx.Err() == nil {
+			shadowBatchFailureCounter.Inc(1)
+			log.Warn("failed to replay batch on shadow parent chain", "sequenceNumber", batch.seqNum, "err", err)
+		}
+		return
+	}
+	// waiting for the primary batch doesn't hold up replaying the next one
+	s.LaunchThread(func(ctx context.Context) {
+		defer cancel()
+		if err := s.compare(batchCtx, batch, shadow); err != nil {
+			if ctx.Err() == nil {
+				shadowBatchFailureCounter.Inc(1)
+				log.Warn("failed to compare batch with shadow parent chain", "sequenceNumber", batch.seqNum, "err", err)
+			}
+			return
+		}
+		shadowBatchSuccessCounter.Inc(1)
+	})
+}
+
+// receiptCost is what the transaction of the receipt paid for its gas and blobs.
+func receiptCost(receipt *types.Receipt) *big.Int {
+	cost := arbmath.BigMulByUint(receipt.EffectiveGasPrice, receipt.GasUsed)
+	if receipt.BlobGasPrice != nil {
+		cost.Add(cost, arbmath.BigMulByUint(receipt.BlobGasPrice, receipt.BlobGasUsed))
+	}
+	return cost
+}
+
+// estimatedBatchCost is what posting a batch with the given gas and blobs would pay in the block after header,
+// assuming the gas estimate is all used and fees don't change.
+func estimatedBatchCost(header *types.Header, tipCap *big.Int, gas uint64, numBlobs int) *big.Int {
+	cost := arbmath.BigMulByUint(arbmath.BigAdd(header.BaseFee, tipCap), gas)
+	if numBlobs > 0 && header.ExcessBlobGas != nil {
+		blobFee := eip4844.CalcBlobFee(*header.ExcessBlobGas)
+		cost.Add(cost, arbmath.BigMulByUint(blobFee, uint64(numBlobs)*params.BlobTxBlobGasPerBlob))
+	}
+	return cost
+}
+
+// shadowReplay is the outcome of replaying a batch on the shadow parent chain.
+type shadowReplay struct {
+	gas              uint64
+	cost             *big.Int
+	numBlobs         int
+	estimateDuration time.Duration
+	included         time.Duration // zero unless the batch was posted
+}
+
+func (s *shadowBatchPoster) replay(ctx context.Context, batch shadowBatch) (*shadowReplay, error) {
+	config := s.config()
+	callOpts := &bind.CallOpts{Context: ctx}
+	// Don't read any new delayed messages, as the shadow bridge doesn't have the primary's delayed inbox.
+	delayedMsg, err := s.seqInbox.TotalDelayedMessagesRead(callOpts)
+	if err != nil {
+		return nil, fmt.Errorf("error getting shadow delayed messages read: %w", err)
+	}
+	seqNum := abi.MaxUint256
+	if config.Mode == shadowModePost {
+		seqNum, err = s.seqInbox.BatchCount(callOpts)
+		if err != nil {
+			return nil, fmt.Errorf("error getting shadow batch count: %w", err)
+		}
+	}
+	shadowHeader, err := s.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	// post blobs only if the shadow parent chain supports them
+	use4844 := batch.use4844 && shadowHeader.ExcessBlobGas != nil
+	// A previous message count of 0 disables the inbox's message count consistency check, as the shadow
+	// inbox's count won't match the primary's. When only estimating, the sequence number check is
+	// disabled too, like the primary batch poster's own estimates.
+	data, kzgBlobs, err := s.poster.encodeAddBatchWithRefunder(seqNum, 0, batch.msgCount, batch.sequencerMsg, arbmath.BigToUintSaturating(delayedMsg), use4844, common.Address{})
+	if err != nil {
+		return nil, err
+	}
+	_, blobHashes, err := blobs.ComputeCommitmentsAndHashes(kzgBlobs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute blob commitments: %w", err)
+	}
+	to := config.sequencerInbox
+	maxFeePerGas := arbmath.BigMulByBips(shadowHeader.BaseFee, s.poster.config().GasEstimateBaseFeeMultipleBips)
+	estimateStart := time.Now()
+	gas, err := estimateGas(s.rpcClient, ctx, estimateGasParams{
+		From:         s.sender,
+		To:           &to,
+		Data:         data,
+		MaxFeePerGas: (*hexutil.Big)(maxFeePerGas),
+		BlobHashes:   blobHashes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error estimating shadow batch gas: %w", err)
+	}
+	result := &shadowReplay{gas: gas, numBlobs: len(kzgBlobs), estimateDuration: time.Since(estimateStart)}
+	shadowBatchGasGauge.Update(int64(gas))
+	tipCap, err := s.client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if config.Mode != shadowModePost {
+		result.cost = estimatedBatchCost(shadowHeader, tipCap, gas, len(kzgBlobs))
+		return result, nil
+	}
+	receipt, err := s.post(ctx, shadowHeader, tipCap, data, kzgBlobs, blobHashes, gas)
+	if err != nil {
+		return nil, err
+	}
+	result.included = time.Since(batch.postedAt)
+	shadowInclusionTimer.Update(result.included)
+	result.cost = receiptCost(receipt)
+	return result, nil
+}
+
+// compare waits for the batch to be included on the primary parent chain, and reports how its cost and
+// inclusion time compare to the shadow parent chain's.
+func (s *shadowBatchPoster) compare(ctx context.Context, batch shadowBatch, shadow *shadowReplay) error {
+	primaryReceipt, primaryIncluded, err := s.waitForPrimaryInclusion(ctx, batch)
+	if err != nil {
+		return err
+	}
+	primaryCost := receiptCost(primaryReceipt)
+	primaryBatchCostGauge.Update(arbmath.BalancePerEther(primaryCost))
+	shadowBatchCostGauge.Update(arbmath.BalancePerEther(shadow.cost))
+	if primaryCost.Sign() > 0 {
+		shadowBatchCostRatioGauge.Update(arbmath.BigDiv(arbmath.BigMulByUint(shadow.cost, uint64(arbmath.OneInBips)), primaryCost).Int64())
+	}
+	log.Info(
+		"BatchPoster: batch replayed on shadow parent chain",
+		"mode", s.config().Mode,
+		"sequenceNumber", batch.seqNum,
+		"shadowGas", shadow.gas,
+		"primaryGasUsed", primaryReceipt.GasUsed,
+		"numBlobs", shadow.numBlobs,
+		"shadowCost", shadow.cost,
+		"primaryCost", primaryCost,
+		"estimateDuration", shadow.estimateDuration,
+		"shadowInclusion", shadow.included,
+		"primaryInclusion", primaryIncluded,
+	)
+	return nil
+}
+
+// post sends the replayed batch to the shadow sequencer inbox and waits for its receipt.
+func (s *shadowBatchPoster) post(ctx context.Context, header *types.Header, tipCap *big.Int, data []byte, kzgBlobs []kzg4844.Blob, blobHashes []common.Hash, gas uint64) (*types.Receipt, error) {
+	config := s.config()
+	nonce, err := s.client.PendingNonceAt(ctx, s.sender)
+	if err != nil {
+		return nil, err
+	}
+	// leave room for the base fee to double before the batch is included
+	feeCap := arbmath.BigAdd(arbmath.BigMulByUint(header.BaseFee, 2), tipCap)
+	gas += s.poster.config().ExtraBatchGas
+	to := config.sequencerInbox
+	var inner types.TxData
+	if len(kzgBlobs) > 0 {
+		commitments, _, err := blobs.ComputeCommitmentsAndHashes(kzgBlobs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute KZG commitments: %w", err)
+		}
+		proofs, err := blobs.ComputeBlobProofs(kzgBlobs, commitments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute KZG proofs: %w", err)
+		}
+		blobFeeCap := arbmath.BigMulByUint(eip4844.CalcBlobFee(*header.ExcessBlobGas), 2)
+		inner = &types.BlobTx{
+			ChainID:    uint256.MustFromBig(s.chainID),
+			Nonce:      nonce,
+			GasTipCap:  uint256.MustFromBig(tipCap),
+			GasFeeCap:  uint256.MustFromBig(feeCap),
+			Gas:        gas,
+			To:         to,
+			Value:      new(uint256.Int),
+			Data:       data,
+			BlobFeeCap: uint256.MustFromBig(blobFeeCap),
+			BlobHashes: blobHashes,
+			Sidecar: &types.BlobTxSidecar{
+				Blobs:       kzgBlobs,
+				Commitments: commitments,
+				Proofs:      proofs,
+			},
+		}
+	} else {
+		inner = &types.DynamicFeeTx{
+			ChainID:   s.chainID,
+			Nonce:     nonce,
+			GasTipCap: tipCap,
+			GasFeeCap: feeCap,
+			Gas:       gas,
+			To:        &to,
+			Data:      data,
+		}
+	}
+	tx, err := types.SignNewTx(config.privateKey, types.LatestSignerForChainID(s.chainID), inner)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.client.SendTransaction(ctx, tx); err != nil {
+		return nil, fmt.Errorf("error sending shadow batch: %w", err)
+	}
+	receipt, err := bind.WaitMined(ctx, s.client, tx)
+	if err != nil {
+		return nil, fmt.Errorf("error waiting for shadow batch %v: %w", tx.Hash(), err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, fmt.Errorf("shadow batch %v reverted", tx.Hash())
+	}
+	return receipt, nil
+}
+
+// shadowPollInterval is how often the primary parent chain is checked for a batch's inclusion.
+const shadowPollInterval = time.Second
+
+// waitForPrimaryInclusion returns the receipt of the batch on the primary parent chain, and how long it took to be
+// included, giving up when ctx is done.
+func (s *shadowBatchPoster) waitForPrimaryInclusion(ctx context.Context, batch shadowBatch) (*types.Receipt, time.Duration, error) {
+	for {
+		receipt, err := s.primary.batchReceipt(ctx, batch.seqNum, batch.postedAtBlock)
+		if err != nil && ctx.Err() == nil {
+			return nil, 0, err
+		}
+		if receipt != nil {
+			included := time.Since(batch.postedAt)
+			primaryInclusionTimer.Update(included)
+			return receipt, included, nil
+		}
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, 0, fmt.Errorf("batch %v wasn't included on the primary parent chain within %v", batch.seqNum, s.config().InclusionTimeout)
+			}
+			return nil, 0, ctx.Err()
+		case <-time.After(shadowPollInterval):
+		}
+	}
+}
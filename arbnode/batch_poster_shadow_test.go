@@ -0,0 +1,118 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+type testShadowPrimary struct {
+	calls      atomic.Int64
+	includedAt int64 // the call from which the batch is included, or 0 if it never is
+	seqNum     atomic.Uint64
+	fromBlock  atomic.Uint64
+}
+
+func (p *testShadowPrimary) batchReceipt(ctx context.Context, seqNum uint64, fromBlock uint64) (*types.Receipt, error) {
+	p.seqNum.Store(seqNum)
+	p.fromBlock.Store(fromBlock)
+	if call := p.calls.Add(1); p.includedAt == 0 || call < p.includedAt {
+		return nil, nil
+	}
+	return &types.Receipt{GasUsed: 100, EffectiveGasPrice: big.NewInt(10)}, nil
+}
+
+func TestShadowBatchCosts(t *testing.T) {
+	receipt := &types.Receipt{
+		GasUsed:           100,
+		EffectiveGasPrice: big.NewInt(10),
+		BlobGasUsed:       params.BlobTxBlobGasPerBlob,
+		BlobGasPrice:      big.NewInt(3),
+	}
+	if cost := receiptCost(receipt); cost.Cmp(big.NewInt(100*10+3*params.BlobTxBlobGasPerBlob)) != 0 {
+		Fail(t, "unexpected receipt cost", cost)
+	}
+	receipt.BlobGasPrice = nil
+	if cost := receiptCost(receipt); cost.Cmp(big.NewInt(100*10)) != 0 {
+		Fail(t, "unexpected cost of a receipt without blobs", cost)
+	}
+
+	// the estimate pays the tip too, and blobs at the minimum blob fee of 1 without excess blob gas
+	excessBlobGas := uint64(0)
+	header := &types.Header{BaseFee: big.NewInt(7), ExcessBlobGas: &excessBlobGas}
+	if cost := estimatedBatchCost(header, big.NewInt(3), 100, 2); cost.Cmp(big.NewInt(100*(7+3)+2*params.BlobTxBlobGasPerBlob)) != 0 {
+		Fail(t, "unexpected estimated cost", cost)
+	}
+	header.ExcessBlobGas = nil
+	if cost := estimatedBatchCost(header, big.NewInt(3), 100, 2); cost.Cmp(big.NewInt(100*(7+3))) != 0 {
+		Fail(t, "unexpected estimated cost without blob support", cost)
+	}
+}
+
+func TestShadowBatchPosterSeparation(t *testing.T) {
+	shadowKey, err := crypto.GenerateKey()
+	Require(t, err)
+	primaryKey, err := crypto.GenerateKey()
+	Require(t, err)
+	primarySender := crypto.PubkeyToAddress(primaryKey.PublicKey)
+	Require(t, checkShadowSeparation(big.NewInt(2), big.NewInt(1), shadowKey, primarySender))
+	Require(t, checkShadowSeparation(big.NewInt(2), big.NewInt(1), nil, primarySender))
+	if checkShadowSeparation(big.NewInt(1), big.NewInt(1), shadowKey, primarySender) == nil {
+		Fail(t, "accepted a shadow parent chain with the primary parent chain's chain id")
+	}
+	if checkShadowSeparation(big.NewInt(2), big.NewInt(1), primaryKey, primarySender) == nil {
+		Fail(t, "accepted the primary batch poster's wallet posting to the shadow parent chain")
+	}
+}
+
+func TestShadowBatchPosterQueue(t *testing.T) {
+	s := &shadowBatchPoster{queue: make(chan shadowBatch, 1)}
+	s.enqueue(shadowBatch{seqNum: 1})
+	s.enqueue(shadowBatch{seqNum: 2})
+	if len(s.queue) != 1 {
+		Fail(t, "expected the batch beyond the queue size to be dropped, queued", len(s.queue))
+	}
+	if batch := <-s.queue; batch.seqNum != 1 {
+		Fail(t, "unexpected queued batch", batch.seqNum)
+	}
+}
+
+func TestShadowBatchPosterWaitForPrimaryInclusion(t *testing.T) {
+	config := DefaultBatchPosterShadowConfig
+	primary := &testShadowPrimary{includedAt: 2}
+	s := &shadowBatchPoster{primary: primary, config: func() *BatchPosterShadowConfig { return &config }}
+	batch := shadowBatch{seqNum: 5, postedAt: time.Now(), postedAtBlock: 9}
+	receipt, included, err := s.waitForPrimaryInclusion(context.Background(), batch)
+	Require(t, err)
+	if receipt == nil || receipt.GasUsed != 100 {
+		Fail(t, "unexpected primary receipt", receipt)
+	}
+	if included < shadowPollInterval {
+		Fail(t, "inclusion time doesn't include the polls before it", included)
+	}
+	if primary.seqNum.Load() != 5 || primary.fromBlock.Load() != 9 {
+		Fail(t, "looked up the wrong batch", primary.seqNum.Load(), primary.fromBlock.Load())
+	}
+
+	// a batch that's never included is given up on when its context is done, not after the next poll
+	primary = &testShadowPrimary{}
+	s.primary = primary
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, _, err := s.waitForPrimaryInclusion(ctx, batch); err == nil {
+		Fail(t, "expected waiting for a batch that's never included to fail")
+	}
+	if elapsed := time.Since(start); elapsed >= shadowPollInterval {
+		Fail(t, "waited", elapsed, "for a batch past its deadline")
+	}
+}
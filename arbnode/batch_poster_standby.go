@@ -0,0 +1,420 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/arbnode/redislock"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+var (
+	standbyActiveGauge     = metrics.NewRegisteredGauge("arb/batchposter/standby/active", nil)
+	standbyTakeoverCounter = metrics.NewRegisteredCounter("arb/batchposter/standby/takeover", nil)
+	standbyStolenCounter   = metrics.NewRegisteredCounter("arb/batchposter/standby/stolen", nil)
+	standbyFencedCounter   = metrics.NewRegisteredCounter("arb/batchposter/standby/fenced", nil)
+)
+
+// BatchPosterStandbyConfig configures redundant batch posters sharing the batch poster's redis lock so exactly one
+// posts at a time. The poster holding the lock is the active one, and a standby takes the lock when it's released
+// or expires, or takes it over when the active poster stops getting its transactions confirmed. Each time a poster
+// acquires the lock it starts a new epoch, and every nonce is reserved under the poster's epoch before posting
+// with it, so a poster that lost the lock is fenced off from posting.
+type BatchPosterStandbyConfig struct {
+	Enable              bool          `koanf:"enable"`
+	KeyPrefix           string        `koanf:"key-prefix"`
+	ConfirmationTimeout time.Duration `koanf:"confirmation-timeout" reload:"hot"`
+}
+
+var DefaultBatchPosterStandbyConfig = BatchPosterStandbyConfig{
+	Enable:              false,
+	KeyPrefix:           "batch-poster.standby",
+	ConfirmationTimeout: 10 * time.Minute,
+}
+
+func BatchPosterStandbyConfigAddOptions(prefix string, f *pflag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultBatchPosterStandbyConfig.Enable, "coordinate with other batch posters through the redis-lock so exactly one posts at a time, taking over if the active one stops")
+	f.String(prefix+".key-prefix", DefaultBatchPosterStandbyConfig.KeyPrefix, "prefix of the redis keys batch posters fence each other off through")
+	f.Duration(prefix+".confirmation-timeout", DefaultBatchPosterStandbyConfig.ConfirmationTimeout, "take over from the active batch poster if its transactions go unconfirmed this long, and after taking over, how long to wait for the previous poster's transactions to confirm before replacing them")
+}
+
+func (c *BatchPosterStandbyConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.KeyPrefix == "" {
+		return errors.New("batch poster standby key prefix must not be empty")
+	}
+	return nil
+}
+
+var errFencedBatchPoster = errors.New("batch poster isn't the active poster")
+
+// standbyMonitor is what the standby coordinator needs to know about the poster's transactions.
+type standbyMonitor interface {
+	// confirmedNonce returns the poster's nonce as of the latest parent chain block.
+	confirmedNonce(ctx context.Context) (uint64, error)
+	// stalled returns whether the active poster's transactions have gone unconfirmed for the timeout.
+	stalled(ctx context.Context, timeout time.Duration) (bool, error)
+	// reset restarts the stall timeout, when a different poster becomes active.
+	reset()
+}
+
+type batchPosterStandby struct {
+	stopwaiter.StopWaiter
+	client  redis.UniversalClient
+	lock    *redislock.Simple
+	config  func() *BatchPosterStandbyConfig
+	monitor standbyMonitor
+
+	mutex        sync.Mutex
+	epoch        uint64    // the epoch this poster holds the lock under, or 0 if it doesn't
+	takenOver    time.Time // when the lock was taken, for waiting on the previous poster's transactions
+	handedOver   bool      // whether the previous poster's transactions have been confirmed or given up on
+	observedHeld string    // the lock holder other than this poster, as last observed
+	active       atomic.Bool
+}
+
+func newBatchPosterStandby(client redis.UniversalClient, lock *redislock.Simple, config func() *BatchPosterStandbyConfig, monitor standbyMonitor) (*batchPosterStandby, error) {
+	if client == nil {
+		return nil, errors.New("the batch poster standby mode requires a redis-url")
+	}
+	return &batchPosterStandby{
+		client:  client,
+		lock:    lock,
+		config:  config,
+		monitor: monitor,
+	}, nil
+}
+
+func (s *batchPosterStandby) key(name string) string {
+	return s.config().KeyPrefix + "." + name
+}
+
+func epochValue(id string, epoch uint64) string {
+	return id + "/" + strconv.FormatUint(epoch, 10)
+}
+
+// parseFence parses the fence, the epoch and highest nonce reserved under it, which are zero if it isn't set.
+func parseFence(value string) (uint64, uint64, error) {
+	if value == "" {
+		return 0, 0, nil
+	}
+	epoch, nonce, ok := strings.Cut(value, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid batch poster fence %q", value)
+	}
+	epochNum, err := strconv.ParseUint(epoch, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	nonceNum, err := strconv.ParseUint(nonce, 10, 64)
+	return epochNum, nonceNum, err
+}
+
+func getOrEmpty(ctx context.Context, getter redis.Cmdable, key string) (string, error) {
+	value, err := getter.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return value, err
+}
+
+// Active returns whether this poster held the lock under a handed over epoch as of the last update.
+// Posting is still fenced by reserve, which checks the lock in redis.
+func (s *batchPosterStandby) Active() bool {
+	return s.active.Load()
+}
+
+func (s *batchPosterStandby) updateActive() {
+	active := s.epoch != 0 && s.handedOver
+	s.active.Store(active)
+	if active {
+		standbyActiveGauge.Update(1)
+	} else {
+		standbyActiveGauge.Update(0)
+	}
+}
+
+func (s *batchPosterStandby) demote(reason string) {
+	if s.epoch != 0 {
+		log.Warn("batch poster standing by", "reason", reason, "epoch", s.epoch)
+	}
+	s.epoch = 0
+	s.handedOver = false
+	s.updateActive()
+}
+
+// startEpoch starts a new epoch for this poster, if it still holds the lock.
+func (s *batchPosterStandby) startEpoch(ctx context.Context) (bool, error) {
+	epoch, err := s.client.Incr(ctx, s.key("epoch")).Uint64()
+	if err != nil {
+		return false, err
+	}
+	started := false
+	err = s.client.Watch(ctx, func(tx *redis.Tx) error {
+		holder, err := getOrEmpty(ctx, tx, s.lock.Key())
+		if err != nil || holder != s.lock.MyId() {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, s.key("holder"), epochValue(s.lock.MyId(), epoch), 0)
+			return nil
+		})
+		if errors.Is(err, redis.TxFailedErr) {
+			return nil
+		}
+		started = err == nil
+		return err
+	}, s.lock.Key())
+	if err != nil || !started {
+		return false, err
+	}
+	s.epoch = epoch
+	s.takenOver = time.Now()
+	s.handedOver = false
+	standbyTakeoverCounter.Inc(1)
+	return true, nil
+}
+
+// checkHandover returns whether the previous poster's transactions have been confirmed, or given up on.
+func (s *batchPosterStandby) checkHandover(ctx context.Context) (bool, error) {
+	fence, err := getOrEmpty(ctx, s.client, s.key("fence"))
+	if err != nil {
+		return false, err
+	}
+	fenceEpoch, fenceNonce, err := parseFence(fence)
+	if err != nil {
+		return false, err
+	}
+	if fence == "" || fenceEpoch >= s.epoch {
+		return true, nil
+	}
+	confirmed, err := s.monitor.confirmedNonce(ctx)
+	if err != nil {
+		return false, err
+	}
+	if confirmed > fenceNonce {
+		log.Info("batch poster took over after the previous poster's transactions confirmed", "epoch", s.epoch, "previousEpoch", fenceEpoch, "nonce", confirmed)
+		return true, nil
+	}
+	if time.Since(s.takenOver) >= s.config().ConfirmationTimeout {
+		log.Warn("batch poster taking over without the previous poster's transactions confirming, they'll be replaced", "epoch", s.epoch, "previousEpoch", fenceEpoch, "confirmedNonce", confirmed, "previousNonce", fenceNonce)
+		return true, nil
+	}
+	log.Info("batch poster waiting on the previous poster's transactions before posting", "epoch", s.epoch, "confirmedNonce", confirmed, "previousNonce", fenceNonce)
+	return false, nil
+}
+
+func (s *batchPosterStandby) update(ctx context.Context) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer s.updateActive()
+
+	holder, err := s.lock.Holder(ctx)
+	if err != nil {
+		return err
+	}
+	if holder != "" && holder != s.lock.MyId() {
+		s.demote("another batch poster holds the lock")
+		if holder != s.observedHeld {
+			// a new active poster gets the full timeout to confirm its transactions
+			s.observedHeld = holder
+			s.monitor.reset()
+		}
+		stalled, err := s.monitor.stalled(ctx, s.config().ConfirmationTimeout)
+		if err != nil || !stalled {
+			return err
+		}
+		took, err := s.lock.TakeOver(ctx, holder)
+		if err != nil || !took {
+			return err
+		}
+		standbyStolenCounter.Inc(1)
+		log.Warn("batch poster took over the lock from an active poster whose transactions stopped confirming", "previous", holder)
+	} else {
+		// takes the lock if it's free, and keeps it if this poster holds it
+		locked, err := s.lock.TryLock(ctx)
+		if err != nil {
+			return err
+		}
+		if !locked {
+			s.demote("couldn't acquire the lock")
+			return nil
+		}
+	}
+	if s.epoch != 0 {
+		// the lock may have been held by another poster since this one last checked
+		current, err := getOrEmpty(ctx, s.client, s.key("holder"))
+		if err != nil {
+			return err
+		}
+		if current != epochValue(s.lock.MyId(), s.epoch) {
+			s.demote("another batch poster held the lock since")
+		}
+	}
+	if s.epoch == 0 {
+		started, err := s.startEpoch(ctx)
+		if err != nil || !started {
+			return err
+		}
+		log.Info("batch poster acquired the lock", "epoch", s.epoch)
+	}
+	if !s.handedOver {
+		s.handedOver, err = s.checkHandover(ctx)
+	}
+	return err
+}
+
+// reserve fences nonce for this poster's epoch, failing if another poster has taken over since.
+func (s *batchPosterStandby) reserve(ctx context.Context, nonce uint64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.active.Load() {
+		return errFencedBatchPoster
+	}
+	mine := epochValue(s.lock.MyId(), s.epoch)
+	var fenced error
+	err := s.client.Watch(ctx, func(tx *redis.Tx) error {
+		lockHolder, err := getOrEmpty(ctx, tx, s.lock.Key())
+		if err != nil {
+			return err
+		}
+		epochHolder, err := getOrEmpty(ctx, tx, s.key("holder"))
+		if err != nil {
+			return err
+		}
+		if lockHolder != s.lock.MyId() || epochHolder != mine {
+			fenced = fmt.Errorf("%w: the lock is held by %v under %v", errFencedBatchPoster, lockHolder, epochHolder)
+			return nil
+		}
+		fence, err := getOrEmpty(ctx, tx, s.key("fence"))
+		if err != nil {
+			return err
+		}
+		fenceEpoch, fenceNonce, err := parseFence(fence)
+		if err != nil {
+			return err
+		}
+		if fenceEpoch > s.epoch {
+			fenced = fmt.Errorf("%w: epoch %v has been fenced off by epoch %v", errFencedBatchPoster, s.epoch, fenceEpoch)
+			return nil
+		}
+		if fenceEpoch < s.epoch || nonce > fenceNonce {
+			fenceNonce = nonce
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, s.key("fence"), strconv.FormatUint(s.epoch, 10)+"/"+strconv.FormatUint(fenceNonce, 10), 0)
+			return nil
+		})
+		if errors.Is(err, redis.TxFailedErr) {
+			fenced = fmt.Errorf("%w: the lock or fence changed while reserving nonce %v", errFencedBatchPoster, nonce)
+			return nil
+		}
+		return err
+	}, s.lock.Key(), s.key("holder"), s.key("fence"))
+	if err != nil {
+		return err
+	}
+	if fenced != nil {
+		standbyFencedCounter.Inc(1)
+		s.demote(fenced.Error())
+		return fenced
+	}
+	return nil
+}
+
+// Start checks on the lock as often as the redis lock refreshes it. The lock itself is released by the
+// batch poster when stopping.
+func (s *batchPosterStandby) Start(ctxIn context.Context) {
+	s.StopWaiter.Start(ctxIn, s)
+	s.CallIteratively(func(ctx context.Context) time.Duration {
+		if err := s.update(ctx); err != nil {
+			log.Warn("error coordinating with standby batch posters", "err", err)
+		}
+		return s.lock.RefreshDuration()
+	})
+}
+
+func (s *batchPosterStandby) StopAndWait() {
+	s.StopWaiter.StopAndWait()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.demote("shutting down")
+}
+
+// batchPosterStandbyMonitor watches the poster's nonce on the parent chain to tell when the active poster stalls.
+type batchPosterStandbyMonitor struct {
+	poster      *BatchPoster
+	lastNonce   uint64
+	lastChanged time.Time
+}
+
+func (m *batchPosterStandbyMonitor) confirmedNonce(ctx context.Context) (uint64, error) {
+	return m.poster.l1Reader.Client().NonceAt(ctx, m.poster.dataPoster.Sender(), nil)
+}
+
+func (m *batchPosterStandbyMonitor) reset() {
+	m.lastChanged = time.Time{}
+}
+
+// stalled returns whether the poster's nonce hasn't advanced in the timeout, while it has pending transactions,
+// or while there have been messages to post for longer than the max delay.
+func (m *batchPosterStandbyMonitor) stalled(ctx context.Context, timeout time.Duration) (bool, error) {
+	latest, err := m.confirmedNonce(ctx)
+	if err != nil {
+		return false, err
+	}
+	if m.lastChanged.IsZero() || latest != m.lastNonce {
+		m.lastNonce = latest
+		m.lastChanged = time.Now()
+		return false, nil
+	}
+	unchanged := time.Since(m.lastChanged)
+	if unchanged < timeout {
+		return false, nil
+	}
+	pending, err := m.poster.l1Reader.Client().PendingNonceAt(ctx, m.poster.dataPoster.Sender())
+	if err != nil {
+		return false, err
+	}
+	if pending > latest {
+		return true, nil
+	}
+	if unchanged < timeout+m.poster.config().MaxDelay {
+		return false, nil
+	}
+	return m.poster.hasUnpostedMessages()
+}
+
+// hasUnpostedMessages returns whether there are messages not yet in a batch, as far as the inbox tracker knows.
+func (b *BatchPoster) hasUnpostedMessages() (bool, error) {
+	msgCount, err := b.streamer.GetMessageCount()
+	if err != nil {
+		return false, err
+	}
+	batchCount, err := b.inbox.GetBatchCount()
+	if err != nil || batchCount == 0 {
+		return msgCount > 0, err
+	}
+	postedCount, err := b.inbox.GetBatchMessageCount(batchCount - 1)
+	if err != nil {
+		return false, err
+	}
+	return msgCount > postedCount, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/offchainlabs/nitro/arbnode/redislock"
+	"github.com/offchainlabs/nitro/util/redisutil"
+)
+
+type testStandbyMonitor struct {
+	nonce     uint64
+	isStalled bool
+}
+
+func (m *testStandbyMonitor) confirmedNonce(context.Context) (uint64, error) {
+	return m.nonce, nil
+}
+
+func (m *testStandbyMonitor) stalled(context.Context, time.Duration) (bool, error) {
+	return m.isStalled, nil
+}
+
+func (m *testStandbyMonitor) reset() {}
+
+func TestBatchPosterStandby(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client, err := redisutil.RedisClientFromURL(redisutil.CreateTestRedis(ctx, t))
+	Require(t, err)
+	config := DefaultBatchPosterStandbyConfig
+	config.Enable = true
+	Require(t, config.Validate())
+	configFetcher := func() *BatchPosterStandbyConfig { return &config }
+	lockConfig := redislock.DefaultCfg
+	lockConfig.Key = batchPosterSimpleRedisLockKey
+	newStandby := func(monitor standbyMonitor) (*batchPosterStandby, *redislock.Simple) {
+		lock, err := redislock.NewSimple(client, func() *redislock.SimpleCfg { return &lockConfig }, func() bool { return true })
+		Require(t, err)
+		standby, err := newBatchPosterStandby(client, lock, configFetcher, monitor)
+		Require(t, err)
+		return standby, lock
+	}
+
+	monitorA := &testStandbyMonitor{}
+	a, lockA := newStandby(monitorA)
+	monitorB := &testStandbyMonitor{}
+	b, lockB := newStandby(monitorB)
+
+	Require(t, a.update(ctx))
+	Require(t, b.update(ctx))
+	if !a.Active() || b.Active() {
+		t.Fatalf("expected the first poster to take the free lock, active: %v %v", a.Active(), b.Active())
+	}
+	if !lockA.Locked() || lockB.Locked() {
+		t.Fatal("the active poster doesn't hold the batch poster's redis lock")
+	}
+	Require(t, a.reserve(ctx, 5))
+	if err := b.reserve(ctx, 5); !errors.Is(err, errFencedBatchPoster) {
+		t.Fatalf("standby reserved a nonce, err: %v", err)
+	}
+
+	// the standby takes over, but waits on the stalled poster's transactions before posting
+	monitorB.nonce = 3
+	monitorB.isStalled = true
+	Require(t, b.update(ctx))
+	if b.Active() {
+		t.Fatal("took over before the previous poster's transactions confirmed")
+	}
+	if err := a.reserve(ctx, 6); !errors.Is(err, errFencedBatchPoster) {
+		t.Fatalf("fenced off poster reserved a nonce, err: %v", err)
+	}
+	if a.Active() {
+		t.Fatal("fenced off poster still active")
+	}
+	monitorB.nonce = 6
+	Require(t, b.update(ctx))
+	if !b.Active() {
+		t.Fatal("didn't become active after the previous poster's transactions confirmed")
+	}
+	Require(t, b.reserve(ctx, 6))
+	Require(t, a.update(ctx))
+	if a.Active() {
+		t.Fatal("poster that lost the lock became active")
+	}
+
+	// releasing the lock hands over to the other poster
+	lockB.Release(ctx)
+	monitorA.nonce = 7
+	Require(t, a.update(ctx))
+	if !a.Active() {
+		t.Fatal("didn't take over the released lock")
+	}
+	Require(t, a.reserve(ctx, 7))
+	// the poster that released the lock is fenced off even before it notices
+	if err := b.reserve(ctx, 7); !errors.Is(err, errFencedBatchPoster) {
+		t.Fatalf("poster that released the lock reserved a nonce, err: %v", err)
+	}
+
+	// a poster that lost the lock and got it back starts a new epoch
+	lockA.Release(ctx)
+	monitorB.nonce = 8
+	Require(t, b.update(ctx))
+	if !b.Active() {
+		t.Fatal("didn't take the lock released by the other poster")
+	}
+	lockB.Release(ctx)
+	monitorA.nonce = 8
+	Require(t, a.update(ctx))
+	if !a.Active() || a.epoch != 5 {
+		t.Fatal("poster that got the lock back didn't start a new epoch", a.epoch)
+	}
+}
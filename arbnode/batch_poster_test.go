@@ -0,0 +1,162 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/offchainlabs/nitro/arbcompress"
+	"github.com/offchainlabs/nitro/arbstate"
+	"github.com/offchainlabs/nitro/arbstate/daprovider"
+	"github.com/offchainlabs/nitro/util/arbmath"
+)
+
+func TestPreferBlobs(t *testing.T) {
+	var zero uint64
+	// one wei per blob gas and per gas, so a blob costs as much as 8192 bytes of calldata
+	header := &types.Header{
+		BaseFee:       big.NewInt(1),
+		ExcessBlobGas: &zero,
+		BlobGasUsed:   &zero,
+	}
+	hysteresis := arbmath.OneInBips / 10
+	fullBlob := int(usableBytesInBlob.Int64())
+	for _, test := range []struct {
+		size     int
+		last4844 bool
+		expected bool
+	}{
+		{fullBlob, false, true},
+		{1000, false, false},
+		{1000, true, false},
+		// close to parity, the last type is kept
+		{8192, false, false},
+		{8192, true, true},
+		// clearly cheaper, the type switches
+		{9500, false, true},
+		{7000, true, false},
+	} {
+		if preferBlobs(header, test.size, test.last4844, hysteresis) != test.expected {
+			t.Errorf("size %v after blobs %v: expected blobs %v", test.size, test.last4844, test.expected)
+		}
+	}
+
+	// a blob fee spike makes calldata cheaper even for full blobs
+	excess := uint64(100_000_000)
+	header.ExcessBlobGas = &excess
+	header.BaseFee = big.NewInt(1_000_000_000)
+	if preferBlobs(header, fullBlob, true, hysteresis) {
+		Fail(t, "kept posting blobs through a blob fee spike")
+	}
+}
+
+func TestBatchSegmentsCompressionFormats(t *testing.T) {
+	messages := makeStreamerTestMessages(10, 100)
+	var decompressed [][]byte
+	for _, format := range []string{compressionFormatBrotli, compressionFormatZstd} {
+		config := TestBatchPosterConfig
+		config.CompressionFormat = format
+		Require(t, config.Validate())
+		segments, err := newBatchSegments(1, &config, 0, false)
+		Require(t, err)
+		for i := range messages {
+			success, err := segments.AddMessage(&messages[i].MessageWithMeta)
+			Require(t, err)
+			if !success {
+				Fail(t, format, "batch full after", i, "messages")
+			}
+		}
+		batch, err := segments.CloseAndGetBytes()
+		Require(t, err)
+		var data []byte
+		switch format {
+		case compressionFormatZstd:
+			if !daprovider.IsZstdMessageHeaderByte(batch[0]) {
+				Fail(t, "zstd batch has header byte", batch[0])
+			}
+			data, err = arbcompress.DecompressZstd(batch[1:], arbstate.MaxDecompressedLen)
+		default:
+			if !daprovider.IsBrotliMessageHeaderByte(batch[0]) {
+				Fail(t, "brotli batch has header byte", batch[0])
+			}
+			data, err = arbcompress.Decompress(batch[1:], arbstate.MaxDecompressedLen)
+		}
+		Require(t, err)
+		decompressed = append(decompressed, data)
+	}
+	if !bytes.Equal(decompressed[0], decompressed[1]) {
+		Fail(t, "batch contents differ between compression formats")
+	}
+
+	config := TestBatchPosterConfig
+	config.CompressionFormat = "lz4"
+	if config.Validate() == nil {
+		Fail(t, "accepted an unknown compression format")
+	}
+}
+
+func TestBatchPosterDeferralReason(t *testing.T) {
+	config := DefaultBatchPosterDeferralConfig
+	config.MaxBaseFeeGwei = 50
+	config.AvoidWindows = []string{"14:00-16:30", "23:00-01:00"}
+	Require(t, config.Validate())
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 1, 1, hour, minute, 0, 0, time.UTC)
+	}
+	cheap := big.NewInt(10 * params.GWei)
+	expensive := big.NewInt(60 * params.GWei)
+	for _, tc := range []struct {
+		now     time.Time
+		baseFee *big.Int
+		reason  string
+	}{
+		{at(12, 0), cheap, ""},
+		{at(12, 0), expensive, "base fee ceiling"},
+		{at(15, 0), cheap, "posting window"},
+		{at(16, 30), cheap, ""},
+		{at(23, 30), cheap, "posting window"},
+		{at(0, 59), cheap, "posting window"},
+		{at(1, 0), cheap, ""},
+	} {
+		if reason := config.deferralReason(tc.now, tc.baseFee); reason != tc.reason {
+			Fail(t, "at", tc.now, "with base fee", tc.baseFee, "got deferral reason", reason, "expected", tc.reason)
+		}
+	}
+	config.AvoidWindows = []string{"25:00-26:00"}
+	if config.Validate() == nil {
+		Fail(t, "accepted an invalid posting window")
+	}
+}
+
+func TestBatchPosterConfigCompressionLevels(t *testing.T) {
+	for _, test := range []struct {
+		format      string
+		level       int
+		zstdLevel   int
+		expectValid bool
+	}{
+		{compressionFormatBrotli, 0, 3, true},
+		{compressionFormatBrotli, 11, 3, true},
+		{compressionFormatBrotli, 12, 3, false},
+		{compressionFormatBrotli, -1, 3, false},
+		{compressionFormatZstd, 2, 1, true},
+		{compressionFormatZstd, 2, 22, true},
+		{compressionFormatZstd, 2, 0, false},
+		{compressionFormatZstd, 2, 23, false},
+	} {
+		config := TestBatchPosterConfig
+		config.CompressionFormat = test.format
+		config.CompressionLevel = test.level
+		config.ZstdCompressionLevel = test.zstdLevel
+		if err := config.Validate(); (err == nil) != test.expectValid {
+			Fail(t, test.format, "compression levels", test.level, test.zstdLevel, "validated with", err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/execution"
+	"github.com/offchainlabs/nitro/util/containers"
+)
+
+var (
+	blockWitnessRecordedCounter = metrics.NewRegisteredCounter("arb/blockwitness/recorded", nil)
+	blockWitnessCachedCounter   = metrics.NewRegisteredCounter("arb/blockwitness/cached", nil)
+)
+
+type BlockWitnessConfig struct {
+	Enable    bool `koanf:"enable"`
+	CacheSize int  `koanf:"cache-size"`
+}
+
+var DefaultBlockWitnessConfig = BlockWitnessConfig{
+	Enable:    false,
+	CacheSize: 64,
+}
+
+func BlockWitnessConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultBlockWitnessConfig.Enable, "serve block witnesses over arb_blockWitness, for stateless followers to apply blocks with (requires the state of recent blocks)")
+	f.Int(prefix+".cache-size", DefaultBlockWitnessConfig.CacheSize, "number of recent block witnesses to keep, so followers fetching the same blocks don't each have them recorded again")
+}
+
+func (c *BlockWitnessConfig) Validate() error {
+	if c.CacheSize < 0 {
+		return errors.New("block witness cache size can't be negative")
+	}
+	return nil
+}
+
+// BlockWitnessServer records block witnesses from the execution client.
+type BlockWitnessServer struct {
+	streamer *TransactionStreamer
+	recorder execution.ExecutionRecorder
+
+	cacheMutex sync.Mutex
+	cache      *containers.LruCache[arbutil.MessageIndex, *execution.BlockWitness]
+}
+
+func NewBlockWitnessServer(streamer *TransactionStreamer, recorder execution.ExecutionRecorder, config *BlockWitnessConfig) *BlockWitnessServer {
+	return &BlockWitnessServer{
+		streamer: streamer,
+		recorder: recorder,
+		cache:    containers.NewLruCache[arbutil.MessageIndex, *execution.BlockWitness](config.CacheSize),
+	}
+}
+
+func (s *BlockWitnessServer) BlockWitness(ctx context.Context, pos arbutil.MessageIndex) (*execution.BlockWitness, error) {
+	s.cacheMutex.Lock()
+	witness, ok := s.cache.Get(pos)
+	s.cacheMutex.Unlock()
+	if ok {
+		blockWitnessCachedCounter.Inc(1)
+		return witness, nil
+	}
+	msg, err := s.streamer.GetMessage(pos)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.recorder.RecordBlockCreation(ctx, pos, msg)
+	if err != nil {
+		return nil, err
+	}
+	preimages := make([]hexutil.Bytes, 0, len(result.Preimages))
+	for _, preimage := range result.Preimages {
+		preimages = append(preimages, preimage)
+	}
+	witness = &execution.BlockWitness{
+		Pos:       pos,
+		BlockHash: result.BlockHash,
+		Message:   msg,
+		Preimages: preimages,
+	}
+	if len(result.UserWasms) > 0 {
+		witness.StylusArch = runtime.GOARCH
+		witness.UserWasms = make(map[common.Hash]execution.WitnessWasm, len(result.UserWasms))
+		for moduleHash, wasm := range result.UserWasms {
+			witness.UserWasms[moduleHash] = execution.WitnessWasm{Asm: wasm.Asm, Module: wasm.Module}
+		}
+	}
+	blockWitnessRecordedCounter.Inc(1)
+	s.cacheMutex.Lock()
+	s.cache.Add(pos, witness)
+	s.cacheMutex.Unlock()
+	return witness, nil
+}
@@ -0,0 +1,239 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/staker"
+	"github.com/offchainlabs/nitro/util/dbutil"
+	"github.com/offchainlabs/nitro/util/headerreader"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+	"github.com/offchainlabs/nitro/util/webhook"
+)
+
+// Bridge events emitted from the parent chain.
+const (
+	BridgeEventDepositSeen           = "deposit.seen"
+	BridgeEventAssertionConfirmed    = "assertion.confirmed"
+	BridgeEventWithdrawalsExecutable = "withdrawals.executable"
+)
+
+var (
+	bridgeEventsDelayedCountKey  = []byte("_bridgeEventsDelayedCount")  // contains the number of delayed messages notified
+	bridgeEventsConfirmedNodeKey = []byte("_bridgeEventsConfirmedNode") // contains the number of the latest confirmed assertion notified
+)
+
+type BridgeEventsConfig struct {
+	Enable       bool           `koanf:"enable"`
+	PollInterval time.Duration  `koanf:"poll-interval" reload:"hot"`
+	Webhook      webhook.Config `koanf:"webhook" reload:"hot"`
+}
+
+type BridgeEventsConfigFetcher func() *BridgeEventsConfig
+
+var DefaultBridgeEventsConfig = BridgeEventsConfig{
+	Enable:       false,
+	PollInterval: 10 * time.Second,
+	Webhook:      webhook.DefaultConfig,
+}
+
+func BridgeEventsConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultBridgeEventsConfig.Enable, "send webhook notifications of deposits seen on the parent chain, and of assertions being confirmed making withdrawals executable")
+	f.Duration(prefix+".poll-interval", DefaultBridgeEventsConfig.PollInterval, "how often to check for new delayed messages and confirmations")
+	webhook.ConfigAddOptions(prefix+".webhook", f)
+}
+
+func (c *BridgeEventsConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	return c.Webhook.Validate()
+}
+
+type DepositSeenEvent struct {
+	SequenceNumber   hexutil.Uint64 `json:"sequenceNumber"`
+	RequestId        *common.Hash   `json:"requestId"`
+	Kind             uint8          `json:"kind"`
+	Sender           common.Address `json:"sender"`
+	ParentChainBlock hexutil.Uint64 `json:"parentChainBlock"`
+	Timestamp        hexutil.Uint64 `json:"timestamp"`
+}
+
+// AssertionConfirmedEvent is sent for the latest confirmed assertion, which covers any assertions
+// confirmed before it since the previous poll.
+type AssertionConfirmedEvent struct {
+	NodeNum                  hexutil.Uint64 `json:"nodeNum"`
+	BlockHash                common.Hash    `json:"blockHash"`
+	SendRoot                 common.Hash    `json:"sendRoot"`
+	ParentChainBlockProposed hexutil.Uint64 `json:"parentChainBlockProposed"`
+}
+
+// BridgeEventWatcher sends webhook notifications of the parent chain side of bridge messages' lifecycle:
+// messages entering the delayed inbox, and assertions being confirmed, which makes the withdrawals
+// up to their send root executable on the parent chain.
+type BridgeEventWatcher struct {
+	stopwaiter.StopWaiter
+	db              ethdb.Database
+	inbox           *InboxTracker
+	latestConfirmed func(context.Context) (*staker.NodeInfo, error)
+	config          BridgeEventsConfigFetcher
+	notifier        *webhook.Notifier
+
+	delayedCount  uint64
+	confirmedNode uint64
+	// set until the latest confirmed assertion to start notifying after is known
+	startAtLatest bool
+}
+
+func readBridgeEventsProgress(db ethdb.Database, key []byte) (uint64, bool, error) {
+	data, err := db.Get(key)
+	if dbutil.IsErrNotFound(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	if len(data) != 8 {
+		return 0, false, fmt.Errorf("invalid bridge events progress %v", hexutil.Bytes(data))
+	}
+	return binary.BigEndian.Uint64(data), true, nil
+}
+
+func NewBridgeEventWatcher(db ethdb.Database, inbox *InboxTracker, l1Reader *headerreader.HeaderReader, rollupAddress common.Address, config BridgeEventsConfigFetcher) (*BridgeEventWatcher, error) {
+	rollup, err := staker.NewRollupWatcher(rollupAddress, l1Reader.Client(), bind.CallOpts{})
+	if err != nil {
+		return nil, err
+	}
+	return newBridgeEventWatcher(db, inbox, func(ctx context.Context) (*staker.NodeInfo, error) {
+		return latestConfirmedAssertion(ctx, rollup)
+	}, config)
+}
+
+func newBridgeEventWatcher(db ethdb.Database, inbox *InboxTracker, latestConfirmed func(context.Context) (*staker.NodeInfo, error), config BridgeEventsConfigFetcher) (*BridgeEventWatcher, error) {
+	w := &BridgeEventWatcher{
+		db:              db,
+		inbox:           inbox,
+		latestConfirmed: latestConfirmed,
+		config:          config,
+		notifier:        webhook.NewNotifier(func() *webhook.Config { return &config().Webhook }),
+	}
+	// without stored progress, start from the current state rather than notifying the whole history
+	var found bool
+	var err error
+	w.delayedCount, found, err = readBridgeEventsProgress(db, bridgeEventsDelayedCountKey)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		if w.delayedCount, err = inbox.GetDelayedCount(); err != nil {
+			return nil, err
+		}
+	}
+	w.confirmedNode, found, err = readBridgeEventsProgress(db, bridgeEventsConfirmedNodeKey)
+	if err != nil {
+		return nil, err
+	}
+	w.startAtLatest = !found
+	return w, nil
+}
+
+func (w *BridgeEventWatcher) Start(ctxIn context.Context) {
+	w.StopWaiter.Start(ctxIn, w)
+	w.notifier.Start(w.GetContext())
+	w.CallIteratively(func(ctx context.Context) time.Duration {
+		if err := w.update(ctx); err != nil && ctx.Err() == nil {
+			log.Warn("failed to update bridge events", "err", err)
+		}
+		return w.config().PollInterval
+	})
+}
+
+func (w *BridgeEventWatcher) StopAndWait() {
+	w.StopWaiter.StopAndWait()
+	w.notifier.StopAndWait()
+}
+
+func (w *BridgeEventWatcher) writeProgress(key []byte, value uint64) error {
+	return w.db.Put(key, binary.BigEndian.AppendUint64(nil, value))
+}
+
+func (w *BridgeEventWatcher) update(ctx context.Context) error {
+	if err := w.updateDeposits(ctx); err != nil {
+		return err
+	}
+	return w.updateConfirmations(ctx)
+}
+
+func (w *BridgeEventWatcher) updateDeposits(ctx context.Context) error {
+	delayedCount, err := w.inbox.GetDelayedCount()
+	if err != nil {
+		return err
+	}
+	if delayedCount < w.delayedCount {
+		// the delayed inbox was reorged, its new messages will be notified again
+		w.delayedCount = delayedCount
+	}
+	for ; w.delayedCount < delayedCount; w.delayedCount++ {
+		msg, _, parentChainBlock, err := w.inbox.GetDelayedMessageAccumulatorAndParentChainBlockNumber(ctx, w.delayedCount)
+		if err != nil {
+			return err
+		}
+		switch msg.Header.Kind {
+		case arbostypes.L1MessageType_Initialize, arbostypes.L1MessageType_BatchPostingReport:
+			// not bridge messages
+			continue
+		}
+		w.notifier.Notify(BridgeEventDepositSeen, &DepositSeenEvent{
+			SequenceNumber:   hexutil.Uint64(w.delayedCount),
+			RequestId:        msg.Header.RequestId,
+			Kind:             msg.Header.Kind,
+			Sender:           msg.Header.Poster,
+			ParentChainBlock: hexutil.Uint64(parentChainBlock),
+			Timestamp:        hexutil.Uint64(msg.Header.Timestamp),
+		})
+	}
+	return w.writeProgress(bridgeEventsDelayedCountKey, w.delayedCount)
+}
+
+func (w *BridgeEventWatcher) updateConfirmations(ctx context.Context) error {
+	info, err := w.latestConfirmed(ctx)
+	if err != nil {
+		return err
+	}
+	var latest uint64
+	if info != nil {
+		latest = info.NodeNum
+	}
+	if w.startAtLatest {
+		// without stored progress, start after the current latest confirmed assertion
+		w.startAtLatest = false
+	} else if latest <= w.confirmedNode {
+		return nil
+	} else {
+		globalState := info.AfterState().GlobalState
+		event := &AssertionConfirmedEvent{
+			NodeNum:                  hexutil.Uint64(info.NodeNum),
+			BlockHash:                globalState.BlockHash,
+			SendRoot:                 globalState.SendRoot,
+			ParentChainBlockProposed: hexutil.Uint64(info.ParentChainBlockProposed),
+		}
+		w.notifier.Notify(BridgeEventAssertionConfirmed, event)
+		w.notifier.Notify(BridgeEventWithdrawalsExecutable, event)
+	}
+	w.confirmedNode = latest
+	return w.writeProgress(bridgeEventsConfirmedNodeKey, w.confirmedNode)
+}
@@ -0,0 +1,164 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/staker"
+	"github.com/offchainlabs/nitro/validator"
+)
+
+type bridgeEventNotification struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// newTestBridgeEventWatcher returns a started watcher reading confirmations from latestConfirmed, and a
+// channel receiving the notifications it sends.
+func newTestBridgeEventWatcher(t *testing.T, db ethdb.Database, inbox *InboxTracker, latestConfirmed func(context.Context) (*staker.NodeInfo, error)) (*BridgeEventWatcher, chan bridgeEventNotification) {
+	t.Helper()
+	received := make(chan bridgeEventNotification, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification bridgeEventNotification
+		if err := json.NewDecoder(r.Body).Decode(&notification); err != nil {
+			t.Error(err)
+			return
+		}
+		received <- notification
+	}))
+	t.Cleanup(server.Close)
+	config := DefaultBridgeEventsConfig
+	config.Enable = true
+	config.Webhook.URLs = []string{server.URL}
+	watcher, err := newBridgeEventWatcher(db, inbox, latestConfirmed, func() *BridgeEventsConfig { return &config })
+	Require(t, err)
+	watcher.notifier.Start(context.Background())
+	t.Cleanup(watcher.notifier.StopAndWait)
+	return watcher, received
+}
+
+func expectBridgeEvent(t *testing.T, received chan bridgeEventNotification, eventType string, data interface{}) {
+	t.Helper()
+	select {
+	case notification := <-received:
+		if notification.Type != eventType {
+			Fail(t, "got", notification.Type, "notification, expected", eventType)
+		}
+		Require(t, json.Unmarshal(notification.Data, data))
+	case <-time.After(5 * time.Second):
+		Fail(t, "timed out waiting for a", eventType, "notification")
+	}
+}
+
+func expectNoBridgeEvents(t *testing.T, received chan bridgeEventNotification) {
+	t.Helper()
+	select {
+	case notification := <-received:
+		Fail(t, "unexpected", notification.Type, "notification", string(notification.Data))
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func testConfirmedNode(nodeNum uint64) *staker.NodeInfo {
+	return &staker.NodeInfo{
+		NodeNum:                  nodeNum,
+		ParentChainBlockProposed: nodeNum * 10,
+		Assertion: &staker.Assertion{
+			AfterState: &validator.ExecutionState{
+				GlobalState: validator.GoGlobalState{
+					BlockHash: common.BigToHash(common.Big1),
+					SendRoot:  common.BigToHash(common.Big2),
+				},
+			},
+		},
+	}
+}
+
+func TestBridgeEventsConfirmations(t *testing.T) {
+	ctx := context.Background()
+	_, streamer, db, _ := NewTransactionStreamerForTest(t, common.Address{})
+	inbox, err := NewInboxTracker(db, streamer, nil, DefaultSnapSyncConfig)
+	Require(t, err)
+	latest := testConfirmedNode(3)
+	latestConfirmed := func(context.Context) (*staker.NodeInfo, error) { return latest, nil }
+
+	// without stored progress, the assertions already confirmed aren't notified
+	watcher, received := newTestBridgeEventWatcher(t, db, inbox, latestConfirmed)
+	Require(t, watcher.updateConfirmations(ctx))
+	expectNoBridgeEvents(t, received)
+
+	latest = testConfirmedNode(5)
+	Require(t, watcher.updateConfirmations(ctx))
+	for _, eventType := range []string{BridgeEventAssertionConfirmed, BridgeEventWithdrawalsExecutable} {
+		var event AssertionConfirmedEvent
+		expectBridgeEvent(t, received, eventType, &event)
+		if event.NodeNum != 5 || event.ParentChainBlockProposed != 50 || event.SendRoot != common.BigToHash(common.Big2) {
+			Fail(t, "unexpected", eventType, "event", event)
+		}
+	}
+	Require(t, watcher.updateConfirmations(ctx))
+	expectNoBridgeEvents(t, received)
+
+	// a restarted watcher resumes after the last assertion notified
+	watcher, received = newTestBridgeEventWatcher(t, db, inbox, latestConfirmed)
+	Require(t, watcher.updateConfirmations(ctx))
+	expectNoBridgeEvents(t, received)
+	latest = testConfirmedNode(6)
+	Require(t, watcher.updateConfirmations(ctx))
+	var event AssertionConfirmedEvent
+	expectBridgeEvent(t, received, BridgeEventAssertionConfirmed, &event)
+	if event.NodeNum != 6 {
+		Fail(t, "expected assertion 6 confirmed, got", event.NodeNum)
+	}
+}
+
+func TestBridgeEventsDeposits(t *testing.T) {
+	ctx := context.Background()
+	_, streamer, db, _ := NewTransactionStreamerForTest(t, common.Address{})
+	inbox, err := NewInboxTracker(db, streamer, nil, DefaultSnapSyncConfig)
+	Require(t, err)
+	watcher, received := newTestBridgeEventWatcher(t, db, inbox, func(context.Context) (*staker.NodeInfo, error) { return nil, nil })
+
+	init, err := streamer.GetMessage(0)
+	Require(t, err)
+	initDelayed := &DelayedInboxMessage{
+		Message:                init.Message,
+		ParentChainBlockNumber: 7,
+	}
+	requestId := common.BigToHash(common.Big1)
+	sender := common.HexToAddress("0x1234")
+	deposit := &DelayedInboxMessage{
+		BeforeInboxAcc: initDelayed.AfterInboxAcc(),
+		Message: &arbostypes.L1IncomingMessage{
+			Header: &arbostypes.L1IncomingMessageHeader{
+				Kind:      arbostypes.L1MessageType_EthDeposit,
+				Poster:    sender,
+				RequestId: &requestId,
+				L1BaseFee: common.Big0,
+			},
+		},
+		ParentChainBlockNumber: 8,
+	}
+	Require(t, inbox.AddDelayedMessages([]*DelayedInboxMessage{initDelayed, deposit}, false))
+
+	// the init message isn't a bridge message
+	Require(t, watcher.updateDeposits(ctx))
+	var event DepositSeenEvent
+	expectBridgeEvent(t, received, BridgeEventDepositSeen, &event)
+	if event.SequenceNumber != 1 || event.Sender != sender || event.RequestId == nil || *event.RequestId != requestId || event.ParentChainBlock != 8 {
+		Fail(t, "unexpected deposit event", event)
+	}
+	Require(t, watcher.updateDeposits(ctx))
+	expectNoBridgeEvents(t, received)
+}
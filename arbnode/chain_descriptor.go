@@ -0,0 +1,81 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+
+	"github.com/offchainlabs/nitro/cmd/chaininfo"
+	"github.com/offchainlabs/nitro/solgen/go/rollupgen"
+)
+
+// ChainDescriptor describes the chain as this node knows it: the chain info it was started with, with the endpoints
+// it's configured to use in place of the chain info's, and the ArbOS version and wasm module root currently in use.
+// Credentials in the endpoints' URLs are stripped, as the descriptor is meant to be published.
+func (n *Node) ChainDescriptor(ctx context.Context, base *chaininfo.ChainInfo) (*chaininfo.Descriptor, error) {
+	descriptor := &chaininfo.Descriptor{ChainInfo: *base}
+	config := n.configFetcher.Get()
+	if len(config.Feed.Input.URL) > 0 {
+		descriptor.FeedUrl = strings.Join(config.Feed.Input.URL, ",")
+	}
+	if len(config.Feed.Input.SecondaryURL) > 0 {
+		descriptor.SecondaryFeedUrl = strings.Join(config.Feed.Input.SecondaryURL, ",")
+	}
+	if config.DataAvailability.Enable {
+		if config.DataAvailability.RestAggregator.OnlineUrlList != "" {
+			descriptor.DasIndexUrl = config.DataAvailability.RestAggregator.OnlineUrlList
+		}
+		descriptor.DasRestUrls = config.DataAvailability.RestAggregator.Urls
+	}
+	descriptor.RedactURLs()
+	if n.DeployInfo != nil {
+		rollupAddresses := *n.DeployInfo
+		descriptor.RollupAddresses = &rollupAddresses
+	}
+
+	msgCount, err := n.TxStreamer.GetMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	if msgCount > 0 {
+		descriptor.ArbOSVersion, err = n.Execution.ArbOSVersionForMessageNumber(msgCount - 1)
+		if err != nil {
+			return nil, fmt.Errorf("error getting ArbOS version: %w", err)
+		}
+	}
+	if n.L1Reader != nil && n.DeployInfo != nil {
+		rollup, err := rollupgen.NewRollupUserLogic(n.DeployInfo.Rollup, n.L1Reader.Client())
+		if err != nil {
+			return nil, err
+		}
+		descriptor.WasmModuleRoot, err = rollup.WasmModuleRoot(&bind.CallOpts{Context: ctx})
+		if err != nil {
+			return nil, fmt.Errorf("error reading wasm module root from the rollup: %w", err)
+		}
+	}
+	return descriptor, nil
+}
+
+// ChainInfoAPI serves the node's chain descriptor as canonical JSON.
+type ChainInfoAPI struct {
+	node *Node
+	base *chaininfo.ChainInfo
+}
+
+func NewChainInfoAPI(node *Node, base *chaininfo.ChainInfo) *ChainInfoAPI {
+	return &ChainInfoAPI{node: node, base: base}
+}
+
+func (a *ChainInfoAPI) ChainInfo(ctx context.Context) (json.RawMessage, error) {
+	descriptor, err := a.node.ChainDescriptor(ctx, a.base)
+	if err != nil {
+		return nil, err
+	}
+	return descriptor.CanonicalJSON()
+}
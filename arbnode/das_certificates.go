@@ -0,0 +1,223 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/offchainlabs/nitro/arbstate/daprovider"
+	"github.com/offchainlabs/nitro/blsSignatures"
+	"github.com/offchainlabs/nitro/das/dastree"
+)
+
+// DASCommitteeMember is a member of the committee a certificate's keyset names.
+type DASCommitteeMember struct {
+	Index  uint64        `json:"index"`
+	PubKey hexutil.Bytes `json:"pubKey"`
+	Signed bool          `json:"signed"`
+}
+
+// BatchCertificate is the data availability certificate a batch was posted with.
+type BatchCertificate struct {
+	BatchNum    uint64         `json:"batchNum"`
+	Version     uint8          `json:"version"`
+	KeysetHash  common.Hash    `json:"keysetHash"`
+	DataHash    common.Hash    `json:"dataHash"`
+	Expiry      uint64         `json:"expiry"`
+	SignersMask hexutil.Uint64 `json:"signersMask"`
+	Signature   hexutil.Bytes  `json:"signature"`
+	// AssumedHonest is how many committee members are assumed honest, so at most AssumedHonest-1 may not sign.
+	// It and Committee are only set if the keyset could be fetched.
+	AssumedHonest uint64               `json:"assumedHonest,omitempty"`
+	Committee     []DASCommitteeMember `json:"committee,omitempty"`
+	KeysetError   string               `json:"keysetError,omitempty"`
+}
+
+// BatchCertificateVerification is the result of checking a batch's certificate as the node does when reading the batch,
+// and that its data can still be fetched.
+type BatchCertificateVerification struct {
+	Certificate    *BatchCertificate `json:"certificate"`
+	Signers        uint64            `json:"signers"`
+	EnoughSigners  bool              `json:"enoughSigners"`
+	SignatureValid bool              `json:"signatureValid"`
+	// ExpiryValid is whether the certificate lasted long enough past the batch's max timestamp for the batch to be accepted.
+	ExpiryValid   bool   `json:"expiryValid"`
+	Expired       bool   `json:"expired"`
+	DataAvailable bool   `json:"dataAvailable"`
+	Valid         bool   `json:"valid"`
+	Error         string `json:"error,omitempty"`
+}
+
+// DASCertificateInspector reads the data availability certificates of batches posted to an AnyTrust chain.
+type DASCertificateInspector struct {
+	inboxReader   *InboxReader
+	inboxTracker  *InboxTracker
+	dasReader     daprovider.DASReader
+	keysetFetcher daprovider.DASKeysetFetcher
+}
+
+func NewDASCertificateInspector(inboxReader *InboxReader, inboxTracker *InboxTracker, dasReader daprovider.DASReader, keysetFetcher daprovider.DASKeysetFetcher) *DASCertificateInspector {
+	return &DASCertificateInspector{
+		inboxReader:   inboxReader,
+		inboxTracker:  inboxTracker,
+		dasReader:     dasReader,
+		keysetFetcher: keysetFetcher,
+	}
+}
+
+var errNotDASBatch = errors.New("batch wasn't posted with a data availability certificate")
+
+// batchCertificate returns the batch's certificate, along with the max timestamp from the batch's header.
+func (i *DASCertificateInspector) batchCertificate(ctx context.Context, batchNum uint64) (*daprovider.DataAvailabilityCertificate, uint64, error) {
+	batchCount, err := i.inboxTracker.GetBatchCount()
+	if err != nil {
+		return nil, 0, err
+	}
+	if batchNum >= batchCount {
+		return nil, 0, fmt.Errorf("batch %v not found (batch count is %v)", batchNum, batchCount)
+	}
+	sequencerMsg, _, err := i.inboxReader.GetSequencerMessageBytes(ctx, batchNum)
+	if err != nil {
+		return nil, 0, err
+	}
+	return parseBatchCertificate(sequencerMsg)
+}
+
+func parseBatchCertificate(sequencerMsg []byte) (*daprovider.DataAvailabilityCertificate, uint64, error) {
+	if len(sequencerMsg) <= 40 || !daprovider.IsDASMessageHeaderByte(sequencerMsg[40]) {
+		return nil, 0, errNotDASBatch
+	}
+	cert, err := daprovider.DeserializeDASCertFrom(bytes.NewReader(sequencerMsg[40:]))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to deserialize certificate: %w", err)
+	}
+	return cert, binary.BigEndian.Uint64(sequencerMsg[8:16]), nil
+}
+
+func (i *DASCertificateInspector) keyset(ctx context.Context, cert *daprovider.DataAvailabilityCertificate) (*daprovider.DataAvailabilityKeyset, error) {
+	keysetBytes, err := i.keysetFetcher.GetKeysetByHash(ctx, cert.KeysetHash)
+	if err != nil {
+		return nil, err
+	}
+	if !dastree.ValidHash(cert.KeysetHash, keysetBytes) {
+		return nil, errors.New("keyset does not match the certificate's keyset hash")
+	}
+	return daprovider.DeserializeKeyset(bytes.NewReader(keysetBytes), true)
+}
+
+func newBatchCertificate(batchNum uint64, cert *daprovider.DataAvailabilityCertificate, keyset *daprovider.DataAvailabilityKeyset, keysetErr error) *BatchCertificate {
+	result := &BatchCertificate{
+		BatchNum:    batchNum,
+		Version:     cert.Version,
+		KeysetHash:  cert.KeysetHash,
+		DataHash:    cert.DataHash,
+		Expiry:      cert.Timeout,
+		SignersMask: hexutil.Uint64(cert.SignersMask),
+		Signature:   blsSignatures.SignatureToBytes(cert.Sig),
+	}
+	if keysetErr != nil {
+		result.KeysetError = keysetErr.Error()
+		return result
+	}
+	result.AssumedHonest = keyset.AssumedHonest
+	for index, pubKey := range keyset.PubKeys {
+		result.Committee = append(result.Committee, DASCommitteeMember{
+			Index:  uint64(index),
+			PubKey: blsSignatures.PublicKeyToBytes(pubKey),
+			Signed: cert.SignersMask&(1<<index) != 0,
+		})
+	}
+	return result
+}
+
+// BatchCertificate returns the batch's certificate, including which committee members signed it.
+func (i *DASCertificateInspector) BatchCertificate(ctx context.Context, batchNum uint64) (*BatchCertificate, error) {
+	cert, _, err := i.batchCertificate(ctx, batchNum)
+	if err != nil {
+		return nil, err
+	}
+	keyset, err := i.keyset(ctx, cert)
+	return newBatchCertificate(batchNum, cert, keyset, err), nil
+}
+
+// certifiedDataMatches reads the certificate's data from the reader and checks it against the data hash.
+func certifiedDataMatches(ctx context.Context, reader daprovider.DASReader, cert *daprovider.DataAvailabilityCertificate) error {
+	hash := common.Hash(cert.DataHash)
+	if cert.Version == 0 {
+		// old certificates have flat hashes, which are stored under their tree hash too
+		data, err := reader.GetByHash(ctx, dastree.FlatHashToTreeHash(hash))
+		if err != nil {
+			data, err = reader.GetByHash(ctx, hash)
+		}
+		if err != nil {
+			return err
+		}
+		if crypto.Keccak256Hash(data) != hash {
+			return daprovider.ErrHashMismatch
+		}
+		return nil
+	}
+	data, err := reader.GetByHash(ctx, hash)
+	if err != nil {
+		return err
+	}
+	if dastree.Hash(data) != hash {
+		return daprovider.ErrHashMismatch
+	}
+	return nil
+}
+
+// VerifyBatchCertificate checks the batch's certificate: that enough committee members signed it with a valid
+// aggregate signature, that it didn't expire too soon, and whether the data it certifies can still be fetched.
+func (i *DASCertificateInspector) VerifyBatchCertificate(ctx context.Context, batchNum uint64) (*BatchCertificateVerification, error) {
+	cert, maxTimestamp, err := i.batchCertificate(ctx, batchNum)
+	if err != nil {
+		return nil, err
+	}
+	keyset, keysetErr := i.keyset(ctx, cert)
+	result := &BatchCertificateVerification{
+		Certificate: newBatchCertificate(batchNum, cert, keyset, keysetErr),
+		ExpiryValid: cert.Timeout >= maxTimestamp+daprovider.MinLifetimeSecondsForDataAvailabilityCert,
+		Expired:     cert.Timeout < uint64(time.Now().Unix()),
+	}
+	var errs []error
+	if keysetErr != nil {
+		errs = append(errs, fmt.Errorf("failed to get keyset: %w", keysetErr))
+	} else {
+		for _, member := range result.Certificate.Committee {
+			if member.Signed {
+				result.Signers++
+			}
+		}
+		nonSigners := uint64(len(keyset.PubKeys)) - result.Signers
+		result.EnoughSigners = nonSigners < keyset.AssumedHonest
+		if err := keyset.VerifySignature(cert.SignersMask, cert.SerializeSignableFields(), cert.Sig); err != nil {
+			errs = append(errs, fmt.Errorf("signature check failed: %w", err))
+		} else {
+			result.SignatureValid = true
+		}
+	}
+	if !result.ExpiryValid {
+		errs = append(errs, fmt.Errorf("certificate expiry %v is less than %v seconds after the batch's max timestamp %v", cert.Timeout, daprovider.MinLifetimeSecondsForDataAvailabilityCert, maxTimestamp))
+	}
+	if err := certifiedDataMatches(ctx, i.dasReader, cert); err != nil {
+		errs = append(errs, fmt.Errorf("failed to fetch data: %w", err))
+	} else {
+		result.DataAvailable = true
+	}
+	result.Valid = result.EnoughSigners && result.SignatureValid && result.ExpiryValid
+	if err := errors.Join(errs...); err != nil {
+		result.Error = err.Error()
+	}
+	return result, nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/offchainlabs/nitro/arbstate/daprovider"
+	"github.com/offchainlabs/nitro/blsSignatures"
+)
+
+func TestBatchCertificate(t *testing.T) {
+	var pubKeys []blsSignatures.PublicKey
+	var privKeys []blsSignatures.PrivateKey
+	for i := 0; i < 3; i++ {
+		pubKey, privKey, err := blsSignatures.GenerateKeys()
+		Require(t, err)
+		pubKeys = append(pubKeys, pubKey)
+		privKeys = append(privKeys, privKey)
+	}
+	keyset := &daprovider.DataAvailabilityKeyset{AssumedHonest: 2, PubKeys: pubKeys}
+
+	cert := &daprovider.DataAvailabilityCertificate{
+		DataHash:    [32]byte{1},
+		Timeout:     1000,
+		SignersMask: 0b101,
+		Version:     1,
+	}
+	var sigs []blsSignatures.Signature
+	for _, i := range []int{0, 2} {
+		sig, err := blsSignatures.SignMessage(privKeys[i], cert.SerializeSignableFields())
+		Require(t, err)
+		sigs = append(sigs, sig)
+	}
+	cert.Sig = blsSignatures.AggregateSignatures(sigs)
+
+	sequencerMsg := make([]byte, 40)
+	binary.BigEndian.PutUint64(sequencerMsg[8:16], 500)
+	sequencerMsg = append(sequencerMsg, daprovider.Serialize(cert)...)
+	parsed, maxTimestamp, err := parseBatchCertificate(sequencerMsg)
+	Require(t, err)
+	if maxTimestamp != 500 || parsed.DataHash != cert.DataHash || parsed.SignersMask != cert.SignersMask {
+		Fail(t, "unexpected parsed certificate", parsed, maxTimestamp)
+	}
+
+	result := newBatchCertificate(7, parsed, keyset, nil)
+	if result.BatchNum != 7 || result.Expiry != 1000 || result.AssumedHonest != 2 || len(result.Committee) != 3 {
+		Fail(t, "unexpected certificate", result)
+	}
+	for i, member := range result.Committee {
+		if member.Signed != (i != 1) {
+			Fail(t, "unexpected signer", i, member.Signed)
+		}
+	}
+	Require(t, keyset.VerifySignature(parsed.SignersMask, parsed.SerializeSignableFields(), parsed.Sig))
+
+	result = newBatchCertificate(7, parsed, nil, errors.New("keyset unavailable"))
+	if result.Committee != nil || result.KeysetError == "" {
+		Fail(t, "expected keyset error", result)
+	}
+
+	// a batch posted to L1 calldata has no certificate
+	if _, _, err := parseBatchCertificate(append(make([]byte, 40), daprovider.BrotliMessageHeaderByte)); !errors.Is(err, errNotDASBatch) {
+		Fail(t, "expected calldata batch to be rejected", err)
+	}
+}
@@ -0,0 +1,470 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/arbstate/daprovider"
+	"github.com/offchainlabs/nitro/blsSignatures"
+	"github.com/offchainlabs/nitro/das"
+	"github.com/offchainlabs/nitro/util/dbutil"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+var dasMonitorNextBatchKey = []byte("_dasMonitorNextBatch") // contains the next batch to check the signers of
+
+type DASMisbehaviorMonitorConfig struct {
+	Enable              bool                  `koanf:"enable"`
+	Members             das.BackendConfigList `koanf:"members"`
+	CheckInterval       time.Duration         `koanf:"check-interval" reload:"hot"`
+	RetryInterval       time.Duration         `koanf:"retry-interval" reload:"hot"`
+	FailuresForEvidence int                   `koanf:"failures-for-evidence" reload:"hot"`
+	MaxBatchesPerCheck  uint64                `koanf:"max-batches-per-check" reload:"hot"`
+	RequestTimeout      time.Duration         `koanf:"request-timeout" reload:"hot"`
+}
+
+type DASMisbehaviorMonitorConfigFetcher func() *DASMisbehaviorMonitorConfig
+
+var DefaultDASMisbehaviorMonitorConfig = DASMisbehaviorMonitorConfig{
+	Enable:              false,
+	Members:             nil,
+	CheckInterval:       time.Minute,
+	RetryInterval:       10 * time.Minute,
+	FailuresForEvidence: 3,
+	MaxBatchesPerCheck:  20,
+	RequestTimeout:      10 * time.Second,
+}
+
+var parsedDASMonitorMembers das.BackendConfigList
+
+func DASMisbehaviorMonitorConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultDASMisbehaviorMonitorConfig.Enable, "check that committee members serve the data of batches they signed certificates for, recording evidence when they repeatedly don't")
+	f.Var(&parsedDASMonitorMembers, prefix+".members", "committee members to check, as a JSON array of their REST URLs and public keys, eg: [{\"url\": \"...\", \"pubkey\": \"...\"},...]")
+	f.Duration(prefix+".check-interval", DefaultDASMisbehaviorMonitorConfig.CheckInterval, "how often to check new batches and retry failed retrievals")
+	f.Duration(prefix+".retry-interval", DefaultDASMisbehaviorMonitorConfig.RetryInterval, "how long to wait before retrying to retrieve data a member failed to serve")
+	f.Int(prefix+".failures-for-evidence", DefaultDASMisbehaviorMonitorConfig.FailuresForEvidence, "number of failed retrievals of data a member signed for before recording evidence of it")
+	f.Uint64(prefix+".max-batches-per-check", DefaultDASMisbehaviorMonitorConfig.MaxBatchesPerCheck, "maximum number of new batches to check the signers of at once")
+	f.Duration(prefix+".request-timeout", DefaultDASMisbehaviorMonitorConfig.RequestTimeout, "timeout of a single retrieval from a member")
+}
+
+func (c *DASMisbehaviorMonitorConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if len(c.Members) == 0 {
+		return errors.New("das misbehavior monitor requires committee members to check")
+	}
+	if c.FailuresForEvidence <= 0 {
+		return errors.New("das misbehavior monitor failures for evidence must be positive")
+	}
+	if c.MaxBatchesPerCheck == 0 {
+		return errors.New("das misbehavior monitor max batches per check must be positive")
+	}
+	return nil
+}
+
+// DASRetrievalAttempt is a failed attempt to retrieve data from a committee member.
+type DASRetrievalAttempt struct {
+	At    time.Time `json:"at"`
+	Error string    `json:"error"`
+}
+
+// DASMisbehaviorEvidence shows a committee member signed a certificate for data it then failed to serve
+// before the certificate expired.
+type DASMisbehaviorEvidence struct {
+	BatchNum uint64 `json:"batchNum"`
+	// Member is the member's index in the certificate's keyset.
+	Member uint64        `json:"member"`
+	PubKey hexutil.Bytes `json:"pubKey"`
+	URL    string        `json:"url"`
+	// Certificate is the certificate as posted in the batch, which the member's signature is aggregated into.
+	Certificate hexutil.Bytes         `json:"certificate"`
+	DataHash    common.Hash           `json:"dataHash"`
+	Expiry      uint64                `json:"expiry"`
+	Attempts    []DASRetrievalAttempt `json:"attempts"`
+}
+
+// DASMemberStats counts the checks of a committee member since the node started.
+type DASMemberStats struct {
+	URL       string        `json:"url"`
+	PubKey    hexutil.Bytes `json:"pubKey"`
+	Checked   uint64        `json:"checked"`
+	Failures  uint64        `json:"failures"`
+	Evidence  uint64        `json:"evidence"`
+	LastError string        `json:"lastError,omitempty"`
+}
+
+type monitoredDASMember struct {
+	reader   daprovider.DASReader
+	stats    DASMemberStats
+	failures metrics.Counter
+	evidence metrics.Counter
+}
+
+// pendingDASRetrieval is data a member signed for that hasn't been retrieved from it yet.
+type pendingDASRetrieval struct {
+	batchNum    uint64
+	member      uint64
+	pubKey      string
+	cert        *daprovider.DataAvailabilityCertificate
+	attempts    []DASRetrievalAttempt
+	nextAttempt time.Time
+}
+
+// storedDASRetrieval is the database encoding of a pendingDASRetrieval.
+type storedDASRetrieval struct {
+	PubKey      hexutil.Bytes         `json:"pubKey"`
+	Certificate hexutil.Bytes         `json:"certificate"`
+	Attempts    []DASRetrievalAttempt `json:"attempts"`
+	NextAttempt time.Time             `json:"nextAttempt"`
+}
+
+// DASMisbehaviorMonitor retrieves the data of each new batch from every committee member that signed its
+// certificate, retrying failures until the certificate expires. Members failing to serve data they signed
+// for too many times have the certificate and failed attempts recorded as evidence.
+// Retrievals still pending, with their failed attempts, are persisted so they survive restarts.
+type DASMisbehaviorMonitor struct {
+	stopwaiter.StopWaiter
+	db        ethdb.Database
+	inspector *DASCertificateInspector
+	tracker   *InboxTracker
+	config    DASMisbehaviorMonitorConfigFetcher
+
+	nextBatch uint64
+	pending   []*pendingDASRetrieval
+
+	mutex   sync.Mutex
+	members map[string]*monitoredDASMember // by public key
+	order   []string
+}
+
+func NewDASMisbehaviorMonitor(db ethdb.Database, inspector *DASCertificateInspector, tracker *InboxTracker, config DASMisbehaviorMonitorConfigFetcher) (*DASMisbehaviorMonitor, error) {
+	m := &DASMisbehaviorMonitor{
+		db:        db,
+		inspector: inspector,
+		tracker:   tracker,
+		config:    config,
+		members:   make(map[string]*monitoredDASMember),
+	}
+	for i, member := range config().Members {
+		pubKey, err := das.DecodeBase64BLSPublicKey([]byte(member.Pubkey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key of das member %v: %w", member.URL, err)
+		}
+		reader, err := das.NewRestfulDasClientFromURL(member.URL)
+		if err != nil {
+			return nil, err
+		}
+		pubKeyBytes := blsSignatures.PublicKeyToBytes(*pubKey)
+		key := string(pubKeyBytes)
+		if _, exists := m.members[key]; exists {
+			return nil, fmt.Errorf("das member %v is configured twice", member.URL)
+		}
+		m.members[key] = &monitoredDASMember{
+			reader:   reader,
+			stats:    DASMemberStats{URL: member.URL, PubKey: pubKeyBytes},
+			failures: metrics.NewRegisteredCounter(fmt.Sprintf("arb/das/monitor/members/%d/failures", i), nil),
+			evidence: metrics.NewRegisteredCounter(fmt.Sprintf("arb/das/monitor/members/%d/evidence", i), nil),
+		}
+		m.order = append(m.order, key)
+	}
+	data, err := db.Get(dasMonitorNextBatchKey)
+	if dbutil.IsErrNotFound(err) {
+		// without stored progress, only check batches posted from now on
+		m.nextBatch, err = tracker.GetBatchCount()
+		if err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	} else if len(data) != 8 {
+		return nil, fmt.Errorf("invalid das misbehavior monitor progress %v", hexutil.Bytes(data))
+	} else {
+		m.nextBatch = binary.BigEndian.Uint64(data)
+	}
+	if err := m.loadPending(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func dasPendingRetrievalKey(batchNum uint64, member uint64) []byte {
+	key := append([]byte{}, dasPendingRetrievalPrefix...)
+	key = binary.BigEndian.AppendUint64(key, batchNum)
+	return binary.BigEndian.AppendUint64(key, member)
+}
+
+func putPendingDASRetrieval(db ethdb.KeyValueWriter, retrieval *pendingDASRetrieval) error {
+	data, err := json.Marshal(&storedDASRetrieval{
+		PubKey:      []byte(retrieval.pubKey),
+		Certificate: daprovider.Serialize(retrieval.cert),
+		Attempts:    retrieval.attempts,
+		NextAttempt: retrieval.nextAttempt,
+	})
+	if err != nil {
+		return err
+	}
+	return db.Put(dasPendingRetrievalKey(retrieval.batchNum, retrieval.member), data)
+}
+
+// loadPending restores the retrievals pending before a restart, dropping those of members no longer checked.
+func (m *DASMisbehaviorMonitor) loadPending() error {
+	iter := m.db.NewIterator(dasPendingRetrievalPrefix, nil)
+	defer iter.Release()
+	var unmonitored [][]byte
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) != len(dasPendingRetrievalPrefix)+16 {
+			return fmt.Errorf("invalid das pending retrieval key %v", hexutil.Bytes(key))
+		}
+		var stored storedDASRetrieval
+		if err := json.Unmarshal(iter.Value(), &stored); err != nil {
+			return fmt.Errorf("failed to decode das pending retrieval: %w", err)
+		}
+		if _, monitored := m.members[string(stored.PubKey)]; !monitored {
+			unmonitored = append(unmonitored, common.CopyBytes(key))
+			continue
+		}
+		cert, err := daprovider.DeserializeDASCertFrom(bytes.NewReader(stored.Certificate))
+		if err != nil {
+			return fmt.Errorf("failed to decode das pending retrieval certificate: %w", err)
+		}
+		m.pending = append(m.pending, &pendingDASRetrieval{
+			batchNum:    binary.BigEndian.Uint64(key[len(dasPendingRetrievalPrefix):]),
+			member:      binary.BigEndian.Uint64(key[len(dasPendingRetrievalPrefix)+8:]),
+			pubKey:      string(stored.PubKey),
+			cert:        cert,
+			attempts:    stored.Attempts,
+			nextAttempt: stored.NextAttempt,
+		})
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	for _, key := range unmonitored {
+		if err := m.db.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *DASMisbehaviorMonitor) Start(ctxIn context.Context) {
+	m.StopWaiter.Start(ctxIn, m)
+	m.CallIteratively(func(ctx context.Context) time.Duration {
+		if err := m.update(ctx); err != nil && ctx.Err() == nil {
+			log.Warn("failed to check das committee members", "err", err)
+		}
+		return m.config().CheckInterval
+	})
+}
+
+func (m *DASMisbehaviorMonitor) update(ctx context.Context) error {
+	if err := m.checkNewBatches(ctx); err != nil {
+		return err
+	}
+	m.retrievePending(ctx)
+	return nil
+}
+
+// checkNewBatches queues retrievals of the data of new batches from the members that signed for it.
+func (m *DASMisbehaviorMonitor) checkNewBatches(ctx context.Context) error {
+	config := m.config()
+	batchCount, err := m.tracker.GetBatchCount()
+	if err != nil {
+		return err
+	}
+	if batchCount < m.nextBatch {
+		// the batches were reorged, the new ones will be checked
+		m.nextBatch = batchCount
+	}
+	now := time.Now()
+	for checked := uint64(0); m.nextBatch < batchCount && checked < config.MaxBatchesPerCheck; checked++ {
+		cert, _, err := m.inspector.batchCertificate(ctx, m.nextBatch)
+		if errors.Is(err, errNotDASBatch) {
+			m.nextBatch++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		var queued []*pendingDASRetrieval
+		if cert.Timeout > uint64(now.Unix()) {
+			keyset, err := m.inspector.keyset(ctx, cert)
+			if err != nil {
+				return err
+			}
+			for index, pubKey := range keyset.PubKeys {
+				if cert.SignersMask&(1<<index) == 0 {
+					continue
+				}
+				key := string(blsSignatures.PublicKeyToBytes(pubKey))
+				if _, monitored := m.members[key]; !monitored {
+					continue
+				}
+				queued = append(queued, &pendingDASRetrieval{
+					batchNum:    m.nextBatch,
+					member:      uint64(index),
+					pubKey:      key,
+					cert:        cert,
+					nextAttempt: now,
+				})
+			}
+		}
+		// queue the batch's retrievals and advance past it atomically, so neither is lost over a restart
+		dbBatch := m.db.NewBatch()
+		for _, retrieval := range queued {
+			if err := putPendingDASRetrieval(dbBatch, retrieval); err != nil {
+				return err
+			}
+		}
+		if err := dbBatch.Put(dasMonitorNextBatchKey, binary.BigEndian.AppendUint64(nil, m.nextBatch+1)); err != nil {
+			return err
+		}
+		if err := dbBatch.Write(); err != nil {
+			return err
+		}
+		m.pending = append(m.pending, queued...)
+		m.nextBatch++
+	}
+	return nil
+}
+
+func (m *DASMisbehaviorMonitor) retrievePending(ctx context.Context) {
+	config := m.config()
+	var stillPending []*pendingDASRetrieval
+	for _, retrieval := range m.pending {
+		now := time.Now()
+		if retrieval.cert.Timeout <= uint64(now.Unix()) {
+			// members only promise to serve the data until the certificate expires
+			m.deletePending(retrieval)
+			continue
+		}
+		if now.Before(retrieval.nextAttempt) || ctx.Err() != nil {
+			stillPending = append(stillPending, retrieval)
+			continue
+		}
+		member := m.members[retrieval.pubKey]
+		requestCtx, cancel := context.WithTimeout(ctx, config.RequestTimeout)
+		err := certifiedDataMatches(requestCtx, member.reader, retrieval.cert)
+		cancel()
+		if ctx.Err() != nil {
+			stillPending = append(stillPending, retrieval)
+			continue
+		}
+		m.mutex.Lock()
+		member.stats.Checked++
+		if err != nil {
+			member.stats.Failures++
+			member.stats.LastError = err.Error()
+		}
+		m.mutex.Unlock()
+		if err == nil {
+			m.deletePending(retrieval)
+			continue
+		}
+		member.failures.Inc(1)
+		retrieval.attempts = append(retrieval.attempts, DASRetrievalAttempt{At: now, Error: err.Error()})
+		if len(retrieval.attempts) < config.FailuresForEvidence {
+			log.Info("das member failed to serve data it signed for", "url", member.stats.URL, "batch", retrieval.batchNum, "attempt", len(retrieval.attempts), "err", err)
+			retrieval.nextAttempt = now.Add(config.RetryInterval)
+			if err := putPendingDASRetrieval(m.db, retrieval); err != nil {
+				log.Error("failed to store das pending retrieval", "url", member.stats.URL, "batch", retrieval.batchNum, "err", err)
+			}
+			stillPending = append(stillPending, retrieval)
+			continue
+		}
+		if err := m.recordEvidence(retrieval, member); err != nil {
+			log.Error("failed to record das misbehavior evidence", "url", member.stats.URL, "batch", retrieval.batchNum, "err", err)
+		}
+	}
+	m.pending = stillPending
+}
+
+func (m *DASMisbehaviorMonitor) deletePending(retrieval *pendingDASRetrieval) {
+	if err := m.db.Delete(dasPendingRetrievalKey(retrieval.batchNum, retrieval.member)); err != nil {
+		log.Error("failed to delete das pending retrieval", "batch", retrieval.batchNum, "member", retrieval.member, "err", err)
+	}
+}
+
+func dasMisbehaviorEvidenceKey(batchNum uint64, member uint64) []byte {
+	key := append([]byte{}, dasMisbehaviorEvidencePrefix...)
+	key = binary.BigEndian.AppendUint64(key, batchNum)
+	return binary.BigEndian.AppendUint64(key, member)
+}
+
+func (m *DASMisbehaviorMonitor) recordEvidence(retrieval *pendingDASRetrieval, member *monitoredDASMember) error {
+	evidence := DASMisbehaviorEvidence{
+		BatchNum:    retrieval.batchNum,
+		Member:      retrieval.member,
+		PubKey:      member.stats.PubKey,
+		URL:         member.stats.URL,
+		Certificate: daprovider.Serialize(retrieval.cert),
+		DataHash:    retrieval.cert.DataHash,
+		Expiry:      retrieval.cert.Timeout,
+		Attempts:    retrieval.attempts,
+	}
+	data, err := json.Marshal(&evidence)
+	if err != nil {
+		return err
+	}
+	dbBatch := m.db.NewBatch()
+	if err := dbBatch.Put(dasMisbehaviorEvidenceKey(retrieval.batchNum, retrieval.member), data); err != nil {
+		return err
+	}
+	if err := dbBatch.Delete(dasPendingRetrievalKey(retrieval.batchNum, retrieval.member)); err != nil {
+		return err
+	}
+	if err := dbBatch.Write(); err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	member.stats.Evidence++
+	m.mutex.Unlock()
+	member.evidence.Inc(1)
+	log.Warn("das member repeatedly failed to serve data it signed for, recorded evidence", "url", member.stats.URL, "batch", retrieval.batchNum, "attempts", len(retrieval.attempts))
+	return nil
+}
+
+// Evidence returns up to limit records of evidence, of batches from fromBatch on.
+func (m *DASMisbehaviorMonitor) Evidence(fromBatch uint64, limit int) ([]*DASMisbehaviorEvidence, error) {
+	iter := m.db.NewIterator(dasMisbehaviorEvidencePrefix, binary.BigEndian.AppendUint64(nil, fromBatch))
+	defer iter.Release()
+	evidence := []*DASMisbehaviorEvidence{}
+	for iter.Next() && len(evidence) < limit {
+		var record DASMisbehaviorEvidence
+		if err := json.Unmarshal(iter.Value(), &record); err != nil {
+			return nil, fmt.Errorf("failed to decode das misbehavior evidence: %w", err)
+		}
+		evidence = append(evidence, &record)
+	}
+	return evidence, iter.Error()
+}
+
+// MemberStats returns the stats of the checked members, in the order they're configured.
+func (m *DASMisbehaviorMonitor) MemberStats() []DASMemberStats {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	stats := make([]DASMemberStats, 0, len(m.order))
+	for _, key := range m.order {
+		stats = append(stats, m.members[key].stats)
+	}
+	return stats
+}
@@ -0,0 +1,117 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/arbstate/daprovider"
+	"github.com/offchainlabs/nitro/das/dastree"
+)
+
+type testDASMember struct {
+	data []byte
+}
+
+func (m *testDASMember) GetByHash(ctx context.Context, hash common.Hash) ([]byte, error) {
+	if m.data == nil {
+		return nil, errors.New("not found")
+	}
+	return m.data, nil
+}
+
+func (m *testDASMember) ExpirationPolicy(ctx context.Context) (daprovider.ExpirationPolicy, error) {
+	return daprovider.KeepForever, nil
+}
+
+func TestDASMisbehaviorMonitor(t *testing.T) {
+	config := DefaultDASMisbehaviorMonitorConfig
+	config.FailuresForEvidence = 2
+	config.RetryInterval = 0
+	data := []byte("batch data")
+	cert := &daprovider.DataAvailabilityCertificate{
+		DataHash:    dastree.Hash(data),
+		Timeout:     uint64(time.Now().Add(time.Hour).Unix()),
+		SignersMask: 0b11,
+		Version:     1,
+	}
+	newMember := func(url string, data []byte) *monitoredDASMember {
+		return &monitoredDASMember{
+			reader:   &testDASMember{data: data},
+			stats:    DASMemberStats{URL: url, PubKey: []byte(url)},
+			failures: metrics.NewCounter(),
+			evidence: metrics.NewCounter(),
+		}
+	}
+	monitor := &DASMisbehaviorMonitor{
+		db:     rawdb.NewMemoryDatabase(),
+		config: func() *DASMisbehaviorMonitorConfig { return &config },
+		members: map[string]*monitoredDASMember{
+			"honest":  newMember("honest", data),
+			"missing": newMember("missing", nil),
+		},
+		order: []string{"honest", "missing"},
+	}
+	expired := *cert
+	expired.Timeout = uint64(time.Now().Add(-time.Hour).Unix())
+	monitor.pending = []*pendingDASRetrieval{
+		{batchNum: 5, member: 0, pubKey: "honest", cert: cert},
+		{batchNum: 5, member: 1, pubKey: "missing", cert: cert},
+		{batchNum: 6, member: 1, pubKey: "missing", cert: &expired},
+	}
+	ctx := context.Background()
+
+	monitor.retrievePending(ctx)
+	if len(monitor.pending) != 1 {
+		Fail(t, "expected only the failed retrieval to be retried, got", len(monitor.pending))
+	}
+
+	// the failed retrieval survives a restart
+	restarted := &DASMisbehaviorMonitor{db: monitor.db, config: monitor.config, members: monitor.members}
+	Require(t, restarted.loadPending())
+	if len(restarted.pending) != 1 {
+		Fail(t, "expected the failed retrieval to be restored, got", len(restarted.pending))
+	}
+	retrieval := restarted.pending[0]
+	if retrieval.batchNum != 5 || retrieval.member != 1 || retrieval.pubKey != "missing" || len(retrieval.attempts) != 1 || retrieval.cert.DataHash != cert.DataHash {
+		Fail(t, "unexpected restored retrieval", retrieval)
+	}
+	monitor.pending = restarted.pending
+
+	monitor.retrievePending(ctx)
+	if len(monitor.pending) != 0 {
+		Fail(t, "expected evidence to be recorded after repeated failures, still pending", len(monitor.pending))
+	}
+
+	restarted.pending = nil
+	Require(t, restarted.loadPending())
+	if len(restarted.pending) != 0 {
+		Fail(t, "retrieval with recorded evidence is still stored", len(restarted.pending))
+	}
+	evidence, err := monitor.Evidence(0, 10)
+	Require(t, err)
+	if len(evidence) != 1 || evidence[0].BatchNum != 5 || evidence[0].Member != 1 || evidence[0].URL != "missing" || len(evidence[0].Attempts) != 2 {
+		Fail(t, "unexpected evidence", evidence)
+	}
+	if evidence[0].DataHash != common.Hash(cert.DataHash) || len(evidence[0].Certificate) == 0 {
+		Fail(t, "evidence doesn't include the certificate", evidence[0])
+	}
+	evidence, err = monitor.Evidence(6, 10)
+	Require(t, err)
+	if len(evidence) != 0 {
+		Fail(t, "unexpected evidence of later batches", evidence)
+	}
+
+	stats := monitor.MemberStats()
+	if stats[0].Checked != 1 || stats[0].Failures != 0 || stats[1].Checked != 2 || stats[1].Failures != 2 || stats[1].Evidence != 1 {
+		Fail(t, "unexpected member stats", stats)
+	}
+}
@@ -36,7 +36,9 @@ import (
 	"github.com/go-redis/redis/v8"
 	"github.com/holiman/uint256"
 	"github.com/offchainlabs/nitro/arbnode/dataposter/dbstorage"
+	"github.com/offchainlabs/nitro/arbnode/dataposter/grpcsigner"
 	"github.com/offchainlabs/nitro/arbnode/dataposter/noop"
+	"github.com/offchainlabs/nitro/arbnode/dataposter/postgres"
 	"github.com/offchainlabs/nitro/arbnode/dataposter/slice"
 	"github.com/offchainlabs/nitro/arbnode/dataposter/storage"
 	"github.com/offchainlabs/nitro/arbutil"
@@ -57,6 +59,7 @@ var (
 	latestUnconfirmedNonceGauge   = metrics.NewRegisteredGauge("arb/dataposter/nonce/unconfirmed", nil)
 	totalQueueLengthGauge         = metrics.NewRegisteredGauge("arb/dataposter/queue/length", nil)
 	totalQueueWeightGauge         = metrics.NewRegisteredGauge("arb/dataposter/queue/weight", nil)
+	crossCheckSkippedRbfCounter   = metrics.NewRegisteredCounter("arb/dataposter/crosscheck/skippedrbf", nil)
 )
 
 // Dataposter implements functionality to post transactions on the chain. It
@@ -78,6 +81,7 @@ type DataPoster struct {
 	extraBacklog      func() uint64
 	parentChainID     *big.Int
 	parentChainID256  *uint256.Int
+	crossCheckClients []arbutil.L1Interface
 
 	// These fields are protected by the mutex.
 	// TODO: factor out these fields into separate structure, since now one
@@ -109,6 +113,9 @@ type DataPosterOpts struct {
 	ExtraBacklog      func() uint64
 	RedisKey          string // Redis storage key
 	ParentChainID     *big.Int
+	// If set, checked for whether a transaction was already included before replacing it by fee, in case the
+	// header reader's endpoint lags behind
+	CrossCheckClients []arbutil.L1Interface
 }
 
 func NewDataPoster(ctx context.Context, opts *DataPosterOpts) (*DataPoster, error) {
@@ -128,6 +135,12 @@ func NewDataPoster(ctx context.Context, opts *DataPosterOpts) (*DataPoster, erro
 	switch {
 	case useNoOpStorage:
 		queue = &noop.Storage{}
+	case cfg.Postgres.URL != "":
+		var err error
+		queue, err = postgres.NewStorage(ctx, &cfg.Postgres, encF)
+		if err != nil {
+			return nil, err
+		}
 	case opts.RedisClient != nil:
 		var err error
 		queue, err = redisstorage.NewStorage(opts.RedisClient, opts.RedisKey, &cfg.RedisSigner, encF)
@@ -145,7 +158,10 @@ func NewDataPoster(ctx context.Context, opts *DataPosterOpts) (*DataPoster, erro
 	default:
 		queue = slice.NewStorage(func() storage.EncoderDecoderInterface { return &storage.EncoderDecoder{} })
 	}
-	expression, err := govaluate.NewEvaluableExpression(cfg.MaxFeeCapFormula)
+	if err := cfg.ReplacementStrategy.Validate(); err != nil {
+		return nil, err
+	}
+	expression, err := govaluate.NewEvaluableExpression(cfg.maxFeeCapFormula())
 	if err != nil {
 		return nil, fmt.Errorf("error creating govaluate evaluable expression for calculating maxFeeCap: %w", err)
 	}
@@ -164,6 +180,7 @@ func NewDataPoster(ctx context.Context, opts *DataPosterOpts) (*DataPoster, erro
 		maxFeeCapExpression: expression,
 		extraBacklog:        opts.ExtraBacklog,
 		parentChainID:       opts.ParentChainID,
+		crossCheckClients:   opts.CrossCheckClients,
 	}
 	var overflow bool
 	dp.parentChainID256, overflow = uint256.FromBig(opts.ParentChainID)
@@ -190,7 +207,7 @@ func NewDataPoster(ctx context.Context, opts *DataPosterOpts) (*DataPoster, erro
 	return dp, nil
 }
 
-func rpcClient(ctx context.Context, opts *ExternalSignerCfg) (*rpc.Client, error) {
+func externalSignerTLSConfig(opts *ExternalSignerCfg) (*tls.Config, error) {
 	tlsCfg := &tls.Config{
 		MinVersion: tls.VersionTLS12,
 		// Dataposter verifies that signed transaction was signed by the account
@@ -217,7 +234,14 @@ func rpcClient(ctx context.Context, opts *ExternalSignerCfg) (*rpc.Client, error
 		rootCertPool.AppendCertsFromPEM(rootCrt)
 		tlsCfg.RootCAs = rootCertPool
 	}
+	return tlsCfg, nil
+}
 
+func rpcClient(ctx context.Context, opts *ExternalSignerCfg) (*rpc.Client, error) {
+	tlsCfg, err := externalSignerTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
 	return rpc.DialOptions(
 		ctx,
 		opts.URL,
@@ -282,6 +306,13 @@ func externalSigner(ctx context.Context, opts *ExternalSignerCfg) (signerFn, com
 	if opts.Address == "" {
 		return nil, common.Address{}, errors.New("external signer (From) address specified")
 	}
+	switch opts.Protocol {
+	case "", ExternalSignerProtocolJsonRpc:
+	case ExternalSignerProtocolGrpc:
+		return grpcExternalSigner(opts)
+	default:
+		return nil, common.Address{}, fmt.Errorf("invalid external signer protocol \"%v\", expected \"%v\" or \"%v\"", opts.Protocol, ExternalSignerProtocolJsonRpc, ExternalSignerProtocolGrpc)
+	}
 
 	client, err := rpcClient(ctx, opts)
 	if err != nil {
@@ -316,6 +347,45 @@ func externalSigner(ctx context.Context, opts *ExternalSignerCfg) (signerFn, com
 	}, sender, nil
 }
 
+// grpcExternalSigner returns a signer function using an external signer serving the gRPC Signer service. The
+// signer only returns a signature, which is applied to the transaction as requested, so it can't sign anything
+// other than what it was asked to.
+func grpcExternalSigner(opts *ExternalSignerCfg) (signerFn, common.Address, error) {
+	tlsCfg, err := externalSignerTLSConfig(opts)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	client, err := grpcsigner.NewClient(opts.URL, tlsCfg)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	sender := common.HexToAddress(opts.Address)
+	return func(ctx context.Context, addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+		// blobs aren't signed over, so there's no need to send them
+		unsigned, err := tx.WithoutBlobTxSidecar().MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("marshaling transaction to sign: %w", err)
+		}
+		signature, err := client.SignTransaction(ctx, addr, tx.ChainId(), unsigned)
+		if err != nil {
+			return nil, fmt.Errorf("making signing request to external signer: %w", err)
+		}
+		hasher := types.LatestSignerForChainID(tx.ChainId())
+		signedTx, err := tx.WithSignature(hasher, signature)
+		if err != nil {
+			return nil, fmt.Errorf("applying external signer signature: %w", err)
+		}
+		signedBy, err := types.Sender(hasher, signedTx)
+		if err != nil {
+			return nil, fmt.Errorf("recovering external signer signature: %w", err)
+		}
+		if signedBy != addr {
+			return nil, fmt.Errorf("external signer signed transaction as %v instead of %v", signedBy, addr)
+		}
+		return signedTx, nil
+	}, sender, nil
+}
+
 func (p *DataPoster) Auth() *bind.TransactOpts {
 	return p.auth
 }
@@ -332,6 +402,12 @@ func (p *DataPoster) MaxMempoolTransactions() uint64 {
 	return arbmath.MinInt(config.MaxMempoolTransactions, config.MaxMempoolWeight)
 }
 
+// Queued returns whether a transaction with the nonce is in the queue, waiting to be confirmed.
+func (p *DataPoster) Queued(ctx context.Context, nonce uint64) (bool, error) {
+	tx, err := p.queue.Get(ctx, nonce)
+	return tx != nil, err
+}
+
 func (p *DataPoster) UsingNoOpStorage() bool {
 	return p.usingNoOpStorage
 }
@@ -531,13 +607,20 @@ func (p *DataPoster) feeAndTipCaps(ctx context.Context, nonce uint64, gasLimit u
 	if err != nil {
 		return nil, nil, nil, err
 	}
-	minTipCapGwei, maxTipCapGwei, minRbfIncrease := config.MinTipCapGwei, config.MaxTipCapGwei, minNonBlobRbfIncrease
+	strategy := &config.ReplacementStrategy
+	panicking := strategy.panicking(dataCreatedAt, lastTx != nil)
+	minTipCapGwei, maxTipCapGwei := config.MinTipCapGwei, config.MaxTipCapGwei
 	if numBlobs > 0 {
-		minTipCapGwei, maxTipCapGwei, minRbfIncrease = config.MinBlobTxTipCapGwei, config.MaxBlobTxTipCapGwei, minBlobRbfIncrease
+		minTipCapGwei, maxTipCapGwei = config.MinBlobTxTipCapGwei, config.MaxBlobTxTipCapGwei
 	}
+	minRbfIncrease := strategy.rbfIncrease(numBlobs > 0)
 	newTipCap := suggestedTip
 	newTipCap = arbmath.BigMax(newTipCap, arbmath.FloatToBig(minTipCapGwei*params.GWei))
 	newTipCap = arbmath.BigMin(newTipCap, arbmath.FloatToBig(maxTipCapGwei*params.GWei))
+	if panicking {
+		// bid the highest tip we're willing to, rather than following the suggestion
+		newTipCap = arbmath.BigMax(newTipCap, arbmath.FloatToBig(strategy.PanicMaxTipCapGwei*params.GWei))
+	}
 
 	// Compute the max fee with normalized gas so that blob txs aren't priced differently.
 	// Later, split the total cost bid into blob and non-blob fee caps.
@@ -546,6 +629,9 @@ func (p *DataPoster) feeAndTipCaps(ctx context.Context, nonce uint64, gasLimit u
 	if err != nil {
 		return nil, nil, nil, err
 	}
+	if panicking {
+		maxNormalizedFeeCap = arbmath.BigMulByBips(maxNormalizedFeeCap, strategy.PanicFeeCapMultipleBips)
+	}
 	normalizedGas := gasLimit + numBlobs*blobs.BlobEncodableData*params.TxDataNonZeroGasEIP2028
 	targetMaxCost := arbmath.BigMulByUint(maxNormalizedFeeCap, normalizedGas)
 
@@ -634,12 +720,20 @@ func (p *DataPoster) feeAndTipCaps(ctx context.Context, nonce uint64, gasLimit u
 	}
 
 	if config.MaxFeeBidMultipleBips > 0 {
+		maxFeeBidMultiple, maxBlobFeeBidMultiple := config.MaxFeeBidMultipleBips, config.MaxFeeBidMultipleBips
+		if strategy.MaxBlobFeeBidMultipleBips > 0 {
+			maxBlobFeeBidMultiple = strategy.MaxBlobFeeBidMultipleBips
+		}
+		if panicking {
+			maxFeeBidMultiple = arbmath.SaturatingMul(maxFeeBidMultiple, strategy.PanicFeeCapMultipleBips) / arbmath.OneInBips
+			maxBlobFeeBidMultiple = arbmath.SaturatingMul(maxBlobFeeBidMultiple, strategy.PanicFeeCapMultipleBips) / arbmath.OneInBips
+		}
 		// Limit the fee caps to be no greater than max(MaxFeeBidMultipleBips, minRbf)
-		maxNonBlobFee := arbmath.BigMulByBips(currentNonBlobFee, config.MaxFeeBidMultipleBips)
+		maxNonBlobFee := arbmath.BigMulByBips(currentNonBlobFee, maxFeeBidMultiple)
 		if lastTx != nil {
 			maxNonBlobFee = arbmath.BigMax(maxNonBlobFee, arbmath.BigMulByBips(lastTx.GasFeeCap(), minRbfIncrease))
 		}
-		maxBlobFee := arbmath.BigMulByBips(currentBlobFee, config.MaxFeeBidMultipleBips)
+		maxBlobFee := arbmath.BigMulByBips(currentBlobFee, maxBlobFeeBidMultiple)
 		if lastTx != nil && lastTx.BlobGasFeeCap() != nil {
 			maxBlobFee = arbmath.BigMax(maxBlobFee, arbmath.BigMulByBips(lastTx.BlobGasFeeCap(), minRbfIncrease))
 		}
@@ -662,6 +756,7 @@ func (p *DataPoster) feeAndTipCaps(ctx context.Context, nonce uint64, gasLimit u
 		"dataPosterBacklog", dataPosterBacklog,
 		"nonce", nonce,
 		"isReplacing", lastTx != nil,
+		"panicking", panicking,
 		"balanceForTx", balanceForTx,
 		"currentBaseFee", latestHeader.BaseFee,
 		"newBasefeeCap", newBaseFeeCap,
@@ -966,15 +1061,21 @@ func (p *DataPoster) replaceTx(ctx context.Context, prevTx *storage.QueuedTransa
 		return err
 	}
 
+	if prevTx.Sent && p.includedElsewhere(ctx, prevTx.FullTx) {
+		crossCheckSkippedRbfCounter.Inc(1)
+		newTx := *prevTx
+		newTx.NextReplacement = time.Now().Add(time.Minute)
+		return p.saveTx(ctx, prevTx, &newTx)
+	}
+
 	newFeeCap, newTipCap, newBlobFeeCap, err := p.feeAndTipCaps(ctx, prevTx.FullTx.Nonce(), prevTx.FullTx.Gas(), uint64(len(prevTx.FullTx.BlobHashes())), prevTx.FullTx, prevTx.Created, backlogWeight, latestHeader)
 	if err != nil {
 		return err
 	}
 
-	minRbfIncrease := minNonBlobRbfIncrease
-	if len(prevTx.FullTx.BlobHashes()) > 0 {
-		minRbfIncrease = minBlobRbfIncrease
-	}
+	strategy := &p.config().ReplacementStrategy
+	minRbfIncrease := strategy.rbfIncrease(len(prevTx.FullTx.BlobHashes()) > 0)
+	panicking := strategy.panicking(prevTx.Created, true)
 
 	newTx := *prevTx
 	if (prevTx.FullTx.GasFeeCap().Sign() > 0 && arbmath.BigDivToBips(newFeeCap, prevTx.FullTx.GasFeeCap()) < minRbfIncrease) ||
@@ -1006,6 +1107,11 @@ func (p *DataPoster) replaceTx(ctx context.Context, prevTx *storage.QueuedTransa
 		newTx.NextReplacement = prevTx.Created.Add(replacement)
 		break
 	}
+	if panicking {
+		if next := time.Now().Add(strategy.PanicReplacementInterval); strategy.PanicReplacementInterval > 0 && next.Before(newTx.NextReplacement) {
+			newTx.NextReplacement = next
+		}
+	}
 	newTx.Sent = false
 	newTx.DeprecatedData.GasFeeCap = newFeeCap
 	newTx.DeprecatedData.GasTipCap = newTipCap
@@ -1017,10 +1123,36 @@ func (p *DataPoster) replaceTx(ctx context.Context, prevTx *storage.QueuedTransa
 	if err != nil {
 		return err
 	}
+	if newTx.OriginalTipCap == nil {
+		newTx.OriginalTipCap = prevTx.FullTx.GasTipCap()
+	}
+	noteReplacement(panicking)
 
 	return p.sendTx(ctx, prevTx, &newTx)
 }
 
+// includedElsewhere checks the cross check clients for whether the transaction's nonce was already used, so it
+// isn't needlessly replaced by fee when the header reader's endpoint is lagging. Endpoints failing to answer are
+// ignored.
+func (p *DataPoster) includedElsewhere(ctx context.Context, tx *types.Transaction) bool {
+	for i, client := range p.crossCheckClients {
+		nonce, err := client.NonceAt(ctx, p.Sender(), nil)
+		if err != nil {
+			log.Debug("failed cross checking nonce", "endpoint", i, "err", err)
+			continue
+		}
+		if nonce > tx.Nonce() {
+			log.Warn("parent chain endpoints disagree, not replacing a transaction another endpoint has its nonce used for", "endpoint", i, "nonce", tx.Nonce(), "endpointNonce", nonce, "hash", tx.Hash())
+			return true
+		}
+		if receipt, err := client.TransactionReceipt(ctx, tx.Hash()); err == nil && receipt != nil {
+			log.Warn("parent chain endpoints disagree, not replacing a transaction another endpoint has a receipt for", "endpoint", i, "nonce", tx.Nonce(), "hash", tx.Hash(), "block", receipt.BlockNumber)
+			return true
+		}
+	}
+	return false
+}
+
 // Gets latest known or finalized block header (depending on config flag),
 // gets the nonce of the dataposter sender and stores it if it has increased.
 // The mutex must be held by the caller.
@@ -1059,6 +1191,9 @@ func (p *DataPoster) updateNonce(ctx context.Context) error {
 			delete(p.errorCount, x)
 		}
 	}
+	if p.lastBlock != nil {
+		p.noteOverpayments(ctx, p.nonce, nonce)
+	}
 	// We don't prune the most recent transaction in order to ensure that the data poster
 	// always has a reference point in its queue of the latest transaction nonce and metadata.
 	// nonce > 0 is implied by nonce > p.nonce, so this won't underflow.
@@ -1071,6 +1206,37 @@ func (p *DataPoster) updateNonce(ctx context.Context) error {
 	return nil
 }
 
+// noteOverpayments adds what the confirmed replacements with nonces in [from, to) overpaid in tips to the
+// overpayment metric. Failures are only logged, as the metric isn't worth holding up the nonce update for.
+func (p *DataPoster) noteOverpayments(ctx context.Context, from, to uint64) {
+	txs, err := p.queue.FetchContents(ctx, from, to-from)
+	if err != nil {
+		log.Warn("Failed to fetch confirmed transactions for the overpayment metric", "from", from, "to", to, "err", err)
+		return
+	}
+	for _, tx := range txs {
+		if tx.OriginalTipCap == nil {
+			continue
+		}
+		receipt, err := p.client.TransactionReceipt(ctx, tx.FullTx.Hash())
+		if err != nil {
+			// an earlier version of the transaction may have been included instead
+			log.Debug("No receipt for the latest version of a confirmed transaction", "nonce", tx.FullTx.Nonce(), "hash", tx.FullTx.Hash(), "err", err)
+			continue
+		}
+		header, err := p.client.HeaderByNumber(ctx, receipt.BlockNumber)
+		if err != nil {
+			log.Warn("Failed to get the header including a confirmed transaction", "nonce", tx.FullTx.Nonce(), "block", receipt.BlockNumber, "err", err)
+			continue
+		}
+		if header.BaseFee == nil {
+			continue
+		}
+		overpayment := tipOverpayment(receipt, header.BaseFee, tx.OriginalTipCap)
+		overpaymentCounter.Inc(arbmath.BigDivByUint(overpayment, params.GWei).Int64())
+	}
+}
+
 // Updates dataposter balance to balance at pending block.
 func (p *DataPoster) updateBalance(ctx context.Context) error {
 	// Use the pending (representated as -1) balance because we're looking at batches we'd post,
@@ -1201,6 +1367,14 @@ func (p *DataPoster) Start(ctxIn context.Context) {
 	})
 }
 
+func (p *DataPoster) StopAndWait() {
+	p.StopWaiter.StopAndWait()
+	// storage backed by a database connection releases it
+	if closer, ok := p.queue.(interface{ Close() }); ok {
+		closer.Close()
+	}
+}
+
 // Implements queue-alike storage that can
 // - Insert item at specified index
 // - Update item with the condition that existing value equals assumed value
@@ -1231,32 +1405,39 @@ type DataPosterConfig struct {
 	BlobTxReplacementTimes []time.Duration            `koanf:"blob-tx-replacement-times"`
 	// This is forcibly disabled if the parent chain is an Arbitrum chain,
 	// so you should probably use DataPoster's waitForL1Finality method instead of reading this field directly.
-	WaitForL1Finality      bool              `koanf:"wait-for-l1-finality" reload:"hot"`
-	MaxMempoolTransactions uint64            `koanf:"max-mempool-transactions" reload:"hot"`
-	MaxMempoolWeight       uint64            `koanf:"max-mempool-weight" reload:"hot"`
-	MaxQueuedTransactions  int               `koanf:"max-queued-transactions" reload:"hot"`
-	TargetPriceGwei        float64           `koanf:"target-price-gwei" reload:"hot"`
-	UrgencyGwei            float64           `koanf:"urgency-gwei" reload:"hot"`
-	MinTipCapGwei          float64           `koanf:"min-tip-cap-gwei" reload:"hot"`
-	MinBlobTxTipCapGwei    float64           `koanf:"min-blob-tx-tip-cap-gwei" reload:"hot"`
-	MaxTipCapGwei          float64           `koanf:"max-tip-cap-gwei" reload:"hot"`
-	MaxBlobTxTipCapGwei    float64           `koanf:"max-blob-tx-tip-cap-gwei" reload:"hot"`
-	MaxFeeBidMultipleBips  arbmath.Bips      `koanf:"max-fee-bid-multiple-bips" reload:"hot"`
-	NonceRbfSoftConfs      uint64            `koanf:"nonce-rbf-soft-confs" reload:"hot"`
-	AllocateMempoolBalance bool              `koanf:"allocate-mempool-balance" reload:"hot"`
-	UseDBStorage           bool              `koanf:"use-db-storage"`
-	UseNoOpStorage         bool              `koanf:"use-noop-storage"`
-	LegacyStorageEncoding  bool              `koanf:"legacy-storage-encoding" reload:"hot"`
-	Dangerous              DangerousConfig   `koanf:"dangerous"`
-	ExternalSigner         ExternalSignerCfg `koanf:"external-signer"`
-	MaxFeeCapFormula       string            `koanf:"max-fee-cap-formula" reload:"hot"`
-	ElapsedTimeBase        time.Duration     `koanf:"elapsed-time-base" reload:"hot"`
-	ElapsedTimeImportance  float64           `koanf:"elapsed-time-importance" reload:"hot"`
+	WaitForL1Finality      bool                      `koanf:"wait-for-l1-finality" reload:"hot"`
+	MaxMempoolTransactions uint64                    `koanf:"max-mempool-transactions" reload:"hot"`
+	MaxMempoolWeight       uint64                    `koanf:"max-mempool-weight" reload:"hot"`
+	MaxQueuedTransactions  int                       `koanf:"max-queued-transactions" reload:"hot"`
+	TargetPriceGwei        float64                   `koanf:"target-price-gwei" reload:"hot"`
+	UrgencyGwei            float64                   `koanf:"urgency-gwei" reload:"hot"`
+	MinTipCapGwei          float64                   `koanf:"min-tip-cap-gwei" reload:"hot"`
+	MinBlobTxTipCapGwei    float64                   `koanf:"min-blob-tx-tip-cap-gwei" reload:"hot"`
+	MaxTipCapGwei          float64                   `koanf:"max-tip-cap-gwei" reload:"hot"`
+	MaxBlobTxTipCapGwei    float64                   `koanf:"max-blob-tx-tip-cap-gwei" reload:"hot"`
+	MaxFeeBidMultipleBips  arbmath.Bips              `koanf:"max-fee-bid-multiple-bips" reload:"hot"`
+	NonceRbfSoftConfs      uint64                    `koanf:"nonce-rbf-soft-confs" reload:"hot"`
+	AllocateMempoolBalance bool                      `koanf:"allocate-mempool-balance" reload:"hot"`
+	UseDBStorage           bool                      `koanf:"use-db-storage"`
+	UseNoOpStorage         bool                      `koanf:"use-noop-storage"`
+	Postgres               postgres.Config           `koanf:"postgres"`
+	LegacyStorageEncoding  bool                      `koanf:"legacy-storage-encoding" reload:"hot"`
+	Dangerous              DangerousConfig           `koanf:"dangerous"`
+	ExternalSigner         ExternalSignerCfg         `koanf:"external-signer"`
+	MaxFeeCapFormula       string                    `koanf:"max-fee-cap-formula" reload:"hot"`
+	ElapsedTimeBase        time.Duration             `koanf:"elapsed-time-base" reload:"hot"`
+	ElapsedTimeImportance  float64                   `koanf:"elapsed-time-importance" reload:"hot"`
+	ReplacementStrategy    ReplacementStrategyConfig `koanf:"replacement-strategy" reload:"hot"`
 	// When set, dataposter will not post new batches, but will keep running to
 	// get existing batches confirmed.
 	DisableNewTx bool `koanf:"disable-new-tx" reload:"hot"`
 }
 
+const (
+	ExternalSignerProtocolJsonRpc = "json-rpc"
+	ExternalSignerProtocolGrpc    = "grpc"
+)
+
 type ExternalSignerCfg struct {
 	// URL of the external signer rpc server, if set this overrides transaction
 	// options and uses external signer
@@ -1264,7 +1445,9 @@ type ExternalSignerCfg struct {
 	URL string `koanf:"url"`
 	// Hex encoded ethereum address of the external signer.
 	Address string `koanf:"address"`
-	// API method name (e.g. eth_signTransaction).
+	// Protocol the external signer speaks, json-rpc or grpc.
+	Protocol string `koanf:"protocol"`
+	// API method name (e.g. eth_signTransaction), for json-rpc.
 	Method string `koanf:"method"`
 	// (Optional) Path to the external signer root CA certificate.
 	// This allows us to use self-signed certificats on the external signer.
@@ -1306,12 +1489,14 @@ func DataPosterConfigAddOptions(prefix string, f *pflag.FlagSet, defaultDataPost
 	f.Bool(prefix+".allocate-mempool-balance", defaultDataPosterConfig.AllocateMempoolBalance, "if true, don't put transactions in the mempool that spend a total greater than the batch poster's balance")
 	f.Bool(prefix+".use-db-storage", defaultDataPosterConfig.UseDBStorage, "uses database storage when enabled")
 	f.Bool(prefix+".use-noop-storage", defaultDataPosterConfig.UseNoOpStorage, "uses noop storage, it doesn't store anything")
+	postgres.ConfigAddOptions(prefix+".postgres", f)
 	f.Bool(prefix+".legacy-storage-encoding", defaultDataPosterConfig.LegacyStorageEncoding, "encodes items in a legacy way (as it was before dropping generics)")
 	f.String(prefix+".max-fee-cap-formula", defaultDataPosterConfig.MaxFeeCapFormula, "mathematical formula to calculate maximum fee cap gwei the result of which would be float64.\n"+
 		"This expression is expected to be evaluated please refer https://github.com/Knetic/govaluate/blob/master/MANUAL.md to find all available mathematical operators.\n"+
 		"Currently available variables to construct the formula are BacklogOfBatches, UrgencyGWei, ElapsedTime, ElapsedTimeBase, ElapsedTimeImportance, and TargetPriceGWei")
 	f.Duration(prefix+".elapsed-time-base", defaultDataPosterConfig.ElapsedTimeBase, "unit to measure the time elapsed since creation of transaction used for maximum fee cap calculation")
 	f.Float64(prefix+".elapsed-time-importance", defaultDataPosterConfig.ElapsedTimeImportance, "weight given to the units of time elapsed used for maximum fee cap calculation")
+	replacementStrategyAddOptions(prefix+".replacement-strategy", f, defaultDataPosterConfig.ReplacementStrategy)
 
 	signature.SimpleHmacConfigAddOptions(prefix+".redis-signer", f)
 	addDangerousOptions(prefix+".dangerous", f)
@@ -1326,6 +1511,7 @@ func addDangerousOptions(prefix string, f *pflag.FlagSet) {
 func addExternalSignerOptions(prefix string, f *pflag.FlagSet) {
 	f.String(prefix+".url", DefaultDataPosterConfig.ExternalSigner.URL, "external signer url")
 	f.String(prefix+".address", DefaultDataPosterConfig.ExternalSigner.Address, "external signer address")
+	f.String(prefix+".protocol", DefaultDataPosterConfig.ExternalSigner.Protocol, "external signer protocol, \"json-rpc\" (eth_signTransaction, as served by web3signer) or \"grpc\" (the Signer service in arbnode/dataposter/grpcsigner/signer.proto)")
 	f.String(prefix+".method", DefaultDataPosterConfig.ExternalSigner.Method, "external signer method")
 	f.String(prefix+".root-ca", DefaultDataPosterConfig.ExternalSigner.RootCA, "external signer root CA")
 	f.String(prefix+".client-cert", DefaultDataPosterConfig.ExternalSigner.ClientCert, "rpc client cert")
@@ -1350,12 +1536,14 @@ var DefaultDataPosterConfig = DataPosterConfig{
 	AllocateMempoolBalance: true,
 	UseDBStorage:           true,
 	UseNoOpStorage:         false,
+	Postgres:               postgres.DefaultConfig,
 	LegacyStorageEncoding:  false,
 	Dangerous:              DangerousConfig{ClearDBStorage: false},
-	ExternalSigner:         ExternalSignerCfg{Method: "eth_signTransaction", InsecureSkipVerify: false},
+	ExternalSigner:         ExternalSignerCfg{Protocol: ExternalSignerProtocolJsonRpc, Method: "eth_signTransaction", InsecureSkipVerify: false},
 	MaxFeeCapFormula:       "((BacklogOfBatches * UrgencyGWei) ** 2) + ((ElapsedTime/ElapsedTimeBase) ** 2) * ElapsedTimeImportance + TargetPriceGWei",
 	ElapsedTimeBase:        10 * time.Minute,
 	ElapsedTimeImportance:  10,
+	ReplacementStrategy:    DefaultReplacementStrategyConfig,
 	DisableNewTx:           false,
 }
 
@@ -1385,11 +1573,13 @@ var TestDataPosterConfig = DataPosterConfig{
 	AllocateMempoolBalance: true,
 	UseDBStorage:           false,
 	UseNoOpStorage:         false,
+	Postgres:               postgres.DefaultConfig,
 	LegacyStorageEncoding:  false,
-	ExternalSigner:         ExternalSignerCfg{Method: "eth_signTransaction", InsecureSkipVerify: true},
+	ExternalSigner:         ExternalSignerCfg{Protocol: ExternalSignerProtocolJsonRpc, Method: "eth_signTransaction", InsecureSkipVerify: true},
 	MaxFeeCapFormula:       "((BacklogOfBatches * UrgencyGWei) ** 2) + ((ElapsedTime/ElapsedTimeBase) ** 2) * ElapsedTimeImportance + TargetPriceGWei",
 	ElapsedTimeBase:        10 * time.Minute,
 	ElapsedTimeImportance:  10,
+	ReplacementStrategy:    DefaultReplacementStrategyConfig,
 	DisableNewTx:           false,
 }
 
@@ -19,6 +19,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/holiman/uint256"
 	"github.com/offchainlabs/nitro/arbnode/dataposter/externalsignertest"
+	"github.com/offchainlabs/nitro/arbutil"
 	"github.com/offchainlabs/nitro/util/arbmath"
 )
 
@@ -152,6 +153,58 @@ func TestMaxFeeCapFormulaCalculation(t *testing.T) {
 	}
 }
 
+func TestReplacementStrategyCurves(t *testing.T) {
+	evalCurve := func(curve string, elapsed time.Duration) *big.Int {
+		t.Helper()
+		config := DefaultDataPosterConfig
+		config.ReplacementStrategy.EscalationCurve = curve
+		if err := config.ReplacementStrategy.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		expression, err := govaluate.NewEvaluableExpression(config.maxFeeCapFormula())
+		if err != nil {
+			t.Fatalf("error creating govaluate evaluable expression for the %v curve: %v", curve, err)
+		}
+		p := &DataPoster{
+			config:              func() *DataPosterConfig { return &config },
+			maxFeeCapExpression: expression,
+		}
+		result, err := p.evalMaxFeeCapExpr(0, elapsed)
+		if err != nil {
+			t.Fatalf("error evaluating the %v curve: %v", curve, err)
+		}
+		return result
+	}
+	long := 10 * DefaultDataPosterConfig.ElapsedTimeBase
+	if evalCurve(EscalationCurveQuadratic, long).Cmp(evalCurve(EscalationCurveFormula, long)) != 0 {
+		t.Fatal("the quadratic curve should match the default formula")
+	}
+	linear, quadratic, exponential := evalCurve(EscalationCurveLinear, long), evalCurve(EscalationCurveQuadratic, long), evalCurve(EscalationCurveExponential, long)
+	if linear.Cmp(quadratic) >= 0 || quadratic.Cmp(exponential) >= 0 {
+		t.Fatalf("expected linear < quadratic < exponential, got %v, %v, %v", linear, quadratic, exponential)
+	}
+	for _, curve := range []string{EscalationCurveLinear, EscalationCurveQuadratic, EscalationCurveExponential} {
+		if evalCurve(curve, 0).Cmp(arbmath.FloatToBig(DefaultDataPosterConfig.TargetPriceGwei*params.GWei)) != 0 {
+			t.Fatalf("the %v curve should start at the target price", curve)
+		}
+	}
+
+	strategy := DefaultReplacementStrategyConfig
+	strategy.BlobRbfIncreaseBips = arbmath.OneInBips * 3 / 2
+	if err := strategy.Validate(); err == nil {
+		t.Fatal("expected a blob rbf increase geth rejects to be invalid")
+	}
+	var unset ReplacementStrategyConfig
+	if unset.rbfIncrease(false) != minNonBlobRbfIncrease || unset.rbfIncrease(true) != minBlobRbfIncrease {
+		t.Fatal("expected an unset strategy to use geth's minimum rbf increases")
+	}
+	strategy = DefaultReplacementStrategyConfig
+	strategy.PanicAfter = time.Minute
+	if strategy.panicking(time.Now().Add(-time.Hour), false) || !strategy.panicking(time.Now().Add(-time.Hour), true) || strategy.panicking(time.Now(), true) {
+		t.Fatal("expected only replacements unconfirmed for longer than panic-after to panic")
+	}
+}
+
 type stubL1Client struct {
 	senderNonce        uint64
 	suggestedGasTipCap *big.Int
@@ -168,6 +221,10 @@ func (c *stubL1Client) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
 	return c.suggestedGasTipCap, nil
 }
 
+func (c *stubL1Client) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return nil, ethereum.NotFound
+}
+
 // Not used but we need to define
 func (c *stubL1Client) BlockNumber(ctx context.Context) (uint64, error) {
 	return 0, nil
@@ -193,6 +250,38 @@ func (c *stubL1Client) TransactionSender(ctx context.Context, tx *types.Transact
 	return common.Address{}, nil
 }
 
+func TestIncludedElsewhere(t *testing.T) {
+	ctx := context.Background()
+	tx := types.NewTx(&types.DynamicFeeTx{Nonce: 5})
+	lagging := &stubL1Client{senderNonce: 5}
+	p := &DataPoster{
+		auth:              &bind.TransactOpts{},
+		crossCheckClients: []arbutil.L1Interface{lagging},
+	}
+	if p.includedElsewhere(ctx, tx) {
+		t.Fatal("transaction considered included with its nonce unused")
+	}
+	p.crossCheckClients = append(p.crossCheckClients, &stubL1Client{senderNonce: 6})
+	if !p.includedElsewhere(ctx, tx) {
+		t.Fatal("transaction not considered included with an endpoint having its nonce used")
+	}
+}
+
+func TestTipOverpayment(t *testing.T) {
+	baseFee := big.NewInt(10 * params.GWei)
+	receipt := &types.Receipt{
+		EffectiveGasPrice: big.NewInt(13 * params.GWei),
+		GasUsed:           100_000,
+	}
+	// only the tip paid above the original tip cap counts, for the gas actually used, not the gas limit
+	if got, want := tipOverpayment(receipt, baseFee, big.NewInt(1*params.GWei)), big.NewInt(2*params.GWei*100_000); !arbmath.BigEquals(got, want) {
+		t.Fatalf("unexpected overpayment: got %v, want %v", got, want)
+	}
+	if got := tipOverpayment(receipt, baseFee, big.NewInt(5*params.GWei)); got.Sign() != 0 {
+		t.Fatalf("expected no overpayment when paying less tip than originally offered, got %v", got)
+	}
+}
+
 func TestFeeAndTipCaps_EnoughBalance_NoBacklog_NoUnconfirmed_BlobTx(t *testing.T) {
 	conf := func() *DataPosterConfig {
 		// Set only the fields that are used by feeAndTipCaps
@@ -0,0 +1,194 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+// Package grpcsigner is a client for external signers implementing the Signer gRPC service in signer.proto, so
+// keys can be kept in an HSM behind a signing service rather than on the node host.
+package grpcsigner
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	signerServiceName     = "nitro.signer.v1.Signer"
+	signTransactionMethod = "SignTransaction"
+	signTransactionPath   = "/" + signerServiceName + "/" + signTransactionMethod
+)
+
+// signTimeout bounds a signing request whose context has no earlier deadline, so a signer that stops
+// responding doesn't hold up the data poster. gRPC sends the deadline to the signer as the request's timeout.
+const signTimeout = 30 * time.Second
+
+// maxResponseSize is far more than a signature needs, to bound what a misbehaving signer can make us read.
+const maxResponseSize = 1 << 16
+
+type signTransactionRequest struct {
+	Sender     common.Address
+	ChainID    *big.Int
+	UnsignedTx []byte
+}
+
+func (r *signTransactionRequest) marshalProto() []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendBytes(b, r.Sender.Bytes())
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendBytes(b, r.ChainID.Bytes())
+	b = protowire.AppendTag(b, 3, protowire.BytesType)
+	return protowire.AppendBytes(b, r.UnsignedTx)
+}
+
+func (r *signTransactionRequest) unmarshalProto(data []byte) error {
+	sender, err := bytesField(data, 1)
+	if err != nil {
+		return err
+	}
+	chainID, err := bytesField(data, 2)
+	if err != nil {
+		return err
+	}
+	unsigned, err := bytesField(data, 3)
+	if err != nil {
+		return err
+	}
+	r.Sender = common.BytesToAddress(sender)
+	r.ChainID = new(big.Int).SetBytes(chainID)
+	r.UnsignedTx = unsigned
+	return nil
+}
+
+type signTransactionResponse struct {
+	Signature []byte
+}
+
+func (r *signTransactionResponse) marshalProto() []byte {
+	b := protowire.AppendTag(nil, 1, protowire.BytesType)
+	return protowire.AppendBytes(b, r.Signature)
+}
+
+func (r *signTransactionResponse) unmarshalProto(data []byte) error {
+	signature, err := bytesField(data, 1)
+	if err != nil {
+		return err
+	}
+	r.Signature = signature
+	return nil
+}
+
+type protoMessage interface {
+	marshalProto() []byte
+	unmarshalProto(data []byte) error
+}
+
+// codec encodes the Signer service's messages.
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(protoMessage)
+	if !ok {
+		return nil, fmt.Errorf("unexpected external signer message type %T", v)
+	}
+	return msg.marshalProto(), nil
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	msg, ok := v.(protoMessage)
+	if !ok {
+		return fmt.Errorf("unexpected external signer message type %T", v)
+	}
+	return msg.unmarshalProto(data)
+}
+
+func (codec) Name() string {
+	return "proto"
+}
+
+type Client struct {
+	conn *grpc.ClientConn
+}
+
+// NewClient returns a client for the signer at the url, which must be https, or http for a signer serving gRPC
+// without TLS, such as one only listening locally. It connects lazily, so an unavailable signer fails each
+// signing request until it's back.
+func NewClient(signerURL string, tlsCfg *tls.Config) (*Client, error) {
+	parsed, err := url.Parse(signerURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid external signer url: %w", err)
+	}
+	var creds credentials.TransportCredentials
+	switch parsed.Scheme {
+	case "https":
+		creds = credentials.NewTLS(tlsCfg)
+	case "http":
+		creds = insecure.NewCredentials()
+	default:
+		return nil, fmt.Errorf("unsupported external signer url scheme \"%v\", expected https or http", parsed.Scheme)
+	}
+	if parsed.Path != "" && parsed.Path != "/" {
+		return nil, fmt.Errorf("external signer url %v has a path, which gRPC doesn't support", parsed.Redacted())
+	}
+	conn, err := grpc.Dial(parsed.Host,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(codec{}), grpc.MaxCallRecvMsgSize(maxResponseSize)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to external signer: %w", err)
+	}
+	return &Client{conn: conn}, nil
+}
+
+// SignTransaction has the signer sign the unsigned transaction as the sender, returning the 65 byte signature.
+func (c *Client) SignTransaction(ctx context.Context, sender common.Address, chainID *big.Int, unsignedTx []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, signTimeout)
+	defer cancel()
+	request := &signTransactionRequest{Sender: sender, ChainID: chainID, UnsignedTx: unsignedTx}
+	response := &signTransactionResponse{}
+	if err := c.conn.Invoke(ctx, signTransactionPath, request, response); err != nil {
+		return nil, err
+	}
+	if len(response.Signature) != crypto.SignatureLength {
+		return nil, fmt.Errorf("external signer returned a %v byte signature, expected %v", len(response.Signature), crypto.SignatureLength)
+	}
+	return response.Signature, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// bytesField returns the last value of the bytes field in the protobuf message, as later values override earlier ones.
+func bytesField(message []byte, field protowire.Number) ([]byte, error) {
+	var value []byte
+	for len(message) > 0 {
+		num, typ, n := protowire.ConsumeTag(message)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		message = message[n:]
+		if num == field && typ == protowire.BytesType {
+			var v []byte
+			v, n = protowire.ConsumeBytes(message)
+			value = v
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, message)
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		message = message[n:]
+	}
+	return value, nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package grpcsigner
+
+import (
+	"context"
+	"math/big"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+type signerService interface {
+	SignTransaction(ctx context.Context, request *signTransactionRequest) (*signTransactionResponse, error)
+}
+
+var signerServiceDesc = grpc.ServiceDesc{
+	ServiceName: signerServiceName,
+	HandlerType: (*signerService)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: signTransactionMethod,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			request := &signTransactionRequest{}
+			if err := dec(request); err != nil {
+				return nil, err
+			}
+			return srv.(signerService).SignTransaction(ctx, request)
+		},
+	}},
+	Metadata: "signer.proto",
+}
+
+type testSigner struct {
+	sign     func(hash []byte) ([]byte, error)
+	refuse   atomic.Bool
+	deadline atomic.Bool
+}
+
+func (s *testSigner) SignTransaction(ctx context.Context, request *signTransactionRequest) (*signTransactionResponse, error) {
+	_, hasDeadline := ctx.Deadline()
+	s.deadline.Store(hasDeadline)
+	if s.refuse.Load() {
+		return nil, status.Error(codes.PermissionDenied, "refused by policy")
+	}
+	var tx types.Transaction
+	if err := tx.UnmarshalBinary(request.UnsignedTx); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	hash := types.LatestSignerForChainID(request.ChainID).Hash(&tx)
+	signature, err := s.sign(hash.Bytes())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &signTransactionResponse{Signature: signature}, nil
+}
+
+func TestSignTransaction(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	signer := &testSigner{sign: func(hash []byte) ([]byte, error) { return crypto.Sign(hash, key) }}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(grpc.ForceServerCodec(codec{}))
+	server.RegisterService(&signerServiceDesc, signer)
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	client, err := NewClient("http://"+listener.Addr().String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	chainID := big.NewInt(1337)
+	to := common.HexToAddress("0x1234")
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     7,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(100),
+		Gas:       21000,
+		To:        &to,
+	})
+	unsigned, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := client.SignTransaction(context.Background(), sender, chainID, unsigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txSigner := types.LatestSignerForChainID(chainID)
+	signed, err := tx.WithSignature(txSigner, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := types.Sender(txSigner, signed); err != nil || got != sender {
+		t.Fatalf("transaction signed by %v (err %v), expected %v", got, err, sender)
+	}
+	if !signer.deadline.Load() {
+		t.Fatal("signing request was sent without a timeout")
+	}
+
+	signer.refuse.Store(true)
+	if _, err := client.SignTransaction(context.Background(), sender, chainID, unsigned); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected the signer's refusal, got %v", err)
+	}
+
+	// a signer returning something other than a signature is rejected
+	signer.refuse.Store(false)
+	signer.sign = func(hash []byte) ([]byte, error) { return hash, nil }
+	if _, err := client.SignTransaction(context.Background(), sender, chainID, unsigned); err == nil {
+		t.Fatal("accepted a 32 byte signature")
+	}
+}
+
+func TestSignTransactionTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := &testSigner{sign: func([]byte) ([]byte, error) {
+		time.Sleep(time.Second)
+		return nil, nil
+	}}
+	server := grpc.NewServer(grpc.ForceServerCodec(codec{}))
+	server.RegisterService(&signerServiceDesc, signer)
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	client, err := NewClient("http://"+listener.Addr().String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	tx, err := types.NewTx(&types.LegacyTx{Gas: 21000}).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := client.SignTransaction(ctx, common.Address{}, big.NewInt(1), tx); status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("expected the request to time out, got %v", err)
+	}
+}
+
+func TestNewClientRejectsUnknownScheme(t *testing.T) {
+	if _, err := NewClient("ws://localhost:1234", nil); err == nil {
+		t.Fatal("expected an error for a websocket url")
+	}
+	if _, err := NewClient("https://localhost:1234/signer", nil); err == nil {
+		t.Fatal("expected an error for a url with a path")
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package dataposter
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/util/arbmath"
+)
+
+var (
+	replacementCounter      = metrics.NewRegisteredCounter("arb/dataposter/replacement", nil)
+	panicReplacementCounter = metrics.NewRegisteredCounter("arb/dataposter/replacement/panic", nil)
+	// how much more confirmed replacements paid in tips than their original transactions would have for the
+	// gas they used, in gwei
+	overpaymentCounter = metrics.NewRegisteredCounter("arb/dataposter/replacement/overpayment", nil)
+)
+
+const (
+	EscalationCurveFormula     = "formula"
+	EscalationCurveLinear      = "linear"
+	EscalationCurveQuadratic   = "quadratic"
+	EscalationCurveExponential = "exponential"
+)
+
+// the backlog term of every preset curve, which only differ in how the bid grows with elapsed time
+const backlogTerm = "((BacklogOfBatches * UrgencyGWei) ** 2)"
+
+var escalationCurveFormulas = map[string]string{
+	EscalationCurveLinear:      backlogTerm + " + (ElapsedTime/ElapsedTimeBase) * ElapsedTimeImportance + TargetPriceGWei",
+	EscalationCurveQuadratic:   backlogTerm + " + ((ElapsedTime/ElapsedTimeBase) ** 2) * ElapsedTimeImportance + TargetPriceGWei",
+	EscalationCurveExponential: backlogTerm + " + (2 ** (ElapsedTime/ElapsedTimeBase) - 1) * ElapsedTimeImportance + TargetPriceGWei",
+}
+
+type ReplacementStrategyConfig struct {
+	// Shape of the max fee cap's growth with time unconfirmed, either a preset or the max-fee-cap-formula.
+	EscalationCurve string `koanf:"escalation-curve"`
+	// Minimum fee increase of a replacement, at least what geth's mempool requires (0 = that minimum).
+	RbfIncreaseBips     arbmath.Bips `koanf:"rbf-increase-bips" reload:"hot"`
+	BlobRbfIncreaseBips arbmath.Bips `koanf:"blob-rbf-increase-bips" reload:"hot"`
+	// Limit on the blob fee cap as a multiple of the current blob fee (0 = max-fee-bid-multiple-bips).
+	MaxBlobFeeBidMultipleBips arbmath.Bips `koanf:"max-blob-fee-bid-multiple-bips" reload:"hot"`
+	// Once a transaction has been unconfirmed this long, replace it aggressively (0 = never).
+	PanicAfter               time.Duration `koanf:"panic-after" reload:"hot"`
+	PanicFeeCapMultipleBips  arbmath.Bips  `koanf:"panic-fee-cap-multiple-bips" reload:"hot"`
+	PanicMaxTipCapGwei       float64       `koanf:"panic-max-tip-cap-gwei" reload:"hot"`
+	PanicReplacementInterval time.Duration `koanf:"panic-replacement-interval" reload:"hot"`
+}
+
+var DefaultReplacementStrategyConfig = ReplacementStrategyConfig{
+	EscalationCurve:           EscalationCurveFormula,
+	RbfIncreaseBips:           minNonBlobRbfIncrease,
+	BlobRbfIncreaseBips:       minBlobRbfIncrease,
+	MaxBlobFeeBidMultipleBips: 0,
+	PanicAfter:                0,
+	PanicFeeCapMultipleBips:   arbmath.OneInBips * 2,
+	PanicMaxTipCapGwei:        20,
+	PanicReplacementInterval:  time.Minute,
+}
+
+func replacementStrategyAddOptions(prefix string, f *pflag.FlagSet, defaultConfig ReplacementStrategyConfig) {
+	f.String(prefix+".escalation-curve", defaultConfig.EscalationCurve, "how the max fee cap grows the longer a transaction is unconfirmed: \"linear\", \"quadratic\" or \"exponential\" in units of elapsed-time-base, or \"formula\" to use max-fee-cap-formula")
+	f.Uint64(prefix+".rbf-increase-bips", uint64(defaultConfig.RbfIncreaseBips), "minimum fee increase of a replacement transaction, at least 11000 (a 10% increase) as geth requires")
+	f.Uint64(prefix+".blob-rbf-increase-bips", uint64(defaultConfig.BlobRbfIncreaseBips), "minimum fee and blob fee increase of a replacement blob transaction, at least 20000 (a 2x increase) as geth requires")
+	f.Uint64(prefix+".max-blob-fee-bid-multiple-bips", uint64(defaultConfig.MaxBlobFeeBidMultipleBips), "the maximum multiple of the current blob fee to bid as a blob fee cap (0 = max-fee-bid-multiple-bips)")
+	f.Duration(prefix+".panic-after", defaultConfig.PanicAfter, "after a transaction has been unconfirmed this long, replace it aggressively with higher fee caps and tips more often (0 = never)")
+	f.Uint64(prefix+".panic-fee-cap-multiple-bips", uint64(defaultConfig.PanicFeeCapMultipleBips), "multiple of the usual max fee cap, and max fee bid multiple, to bid in panic mode")
+	f.Float64(prefix+".panic-max-tip-cap-gwei", defaultConfig.PanicMaxTipCapGwei, "tip cap to bid in panic mode")
+	f.Duration(prefix+".panic-replacement-interval", defaultConfig.PanicReplacementInterval, "how often to replace a transaction in panic mode")
+}
+
+func (c *ReplacementStrategyConfig) Validate() error {
+	if c.EscalationCurve != "" && c.EscalationCurve != EscalationCurveFormula {
+		if _, ok := escalationCurveFormulas[c.EscalationCurve]; !ok {
+			return fmt.Errorf("invalid escalation curve \"%v\"", c.EscalationCurve)
+		}
+	}
+	if c.RbfIncreaseBips != 0 && c.RbfIncreaseBips < minNonBlobRbfIncrease {
+		return fmt.Errorf("rbf increase of %v bips is less than the minimum of %v geth accepts", c.RbfIncreaseBips, minNonBlobRbfIncrease)
+	}
+	if c.BlobRbfIncreaseBips != 0 && c.BlobRbfIncreaseBips < minBlobRbfIncrease {
+		return fmt.Errorf("blob rbf increase of %v bips is less than the minimum of %v geth accepts", c.BlobRbfIncreaseBips, minBlobRbfIncrease)
+	}
+	if c.PanicAfter > 0 && c.PanicFeeCapMultipleBips < arbmath.OneInBips {
+		return fmt.Errorf("panic fee cap multiple of %v bips would lower bids in panic mode", c.PanicFeeCapMultipleBips)
+	}
+	return nil
+}
+
+// maxFeeCapFormula returns the formula to evaluate for the max fee cap.
+func (c *DataPosterConfig) maxFeeCapFormula() string {
+	if formula, ok := escalationCurveFormulas[c.ReplacementStrategy.EscalationCurve]; ok {
+		return formula
+	}
+	return c.MaxFeeCapFormula
+}
+
+func (c *ReplacementStrategyConfig) rbfIncrease(blobTx bool) arbmath.Bips {
+	if blobTx {
+		return arbmath.MaxInt(c.BlobRbfIncreaseBips, minBlobRbfIncrease)
+	}
+	return arbmath.MaxInt(c.RbfIncreaseBips, minNonBlobRbfIncrease)
+}
+
+// panicking returns whether a transaction created at the given time should be replaced aggressively.
+// New transactions are never posted in panic mode, however old their data is.
+func (c *ReplacementStrategyConfig) panicking(created time.Time, replacing bool) bool {
+	return replacing && c.PanicAfter > 0 && time.Since(created) >= c.PanicAfter
+}
+
+// noteReplacement updates the replacement metrics when a transaction is replaced.
+func noteReplacement(panicking bool) {
+	replacementCounter.Inc(1)
+	if panicking {
+		panicReplacementCounter.Inc(1)
+	}
+}
+
+// tipOverpayment is how much more a confirmed transaction paid in tips than the original tip cap would have,
+// for the gas it actually used.
+func tipOverpayment(receipt *types.Receipt, baseFee *big.Int, originalTipCap *big.Int) *big.Int {
+	paidTip := arbmath.BigSub(receipt.EffectiveGasPrice, baseFee)
+	extra := arbmath.BigSub(paidTip, originalTipCap)
+	if extra.Sign() <= 0 {
+		return new(big.Int)
+	}
+	return arbmath.BigMulByUint(extra, receipt.GasUsed)
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/big"
 	"time"
 
 	"github.com/ethereum/go-ethereum/core/types"
@@ -18,9 +19,10 @@ import (
 var (
 	ErrStorageRace = errors.New("storage race error")
 
-	BlockValidatorPrefix string = "v" // the prefix for all block validator keys
-	StakerPrefix         string = "S" // the prefix for all staker keys
-	BatchPosterPrefix    string = "b" // the prefix for all batch poster keys
+	BlockValidatorPrefix    string = "v" // the prefix for all block validator keys
+	StakerPrefix            string = "S" // the prefix for all staker keys
+	BatchPosterPrefix       string = "b" // the prefix for all batch poster keys
+	BatchPosterIntentPrefix string = "i" // the prefix for the batch poster's intent log keys
 	// TODO(anodar): move everything else from schema.go file to here once
 	// execution split is complete.
 )
@@ -33,6 +35,7 @@ type QueuedTransaction struct {
 	Created                time.Time // may be earlier than the tx was given to the tx poster
 	NextReplacement        time.Time
 	StoredCumulativeWeight *uint64
+	OriginalTipCap         *big.Int // the tip cap first offered for this nonce, set once the transaction is replaced
 }
 
 // CumulativeWeight returns a rough estimate of the total number of batches submitted at this point, not guaranteed to be exact
@@ -50,10 +53,17 @@ type queuedTransactionForEncoding struct {
 	Sent                   bool
 	Created                RlpTime
 	NextReplacement        RlpTime
-	StoredCumulativeWeight *uint64 `rlp:"optional"`
+	StoredCumulativeWeight *uint64  `rlp:"optional"`
+	OriginalTipCap         *big.Int `rlp:"optional"`
 }
 
 func (qt *QueuedTransaction) EncodeRLP(w io.Writer) error {
+	storedCumulativeWeight := qt.StoredCumulativeWeight
+	if storedCumulativeWeight == nil && qt.OriginalTipCap != nil {
+		// a later optional field forces this one to be encoded, which would otherwise decode as a weight of 0
+		cumulativeWeight := qt.CumulativeWeight()
+		storedCumulativeWeight = &cumulativeWeight
+	}
 	return rlp.Encode(w, queuedTransactionForEncoding{
 		FullTx:                 qt.FullTx,
 		Data:                   qt.DeprecatedData,
@@ -61,7 +71,8 @@ func (qt *QueuedTransaction) EncodeRLP(w io.Writer) error {
 		Sent:                   qt.Sent,
 		Created:                (RlpTime)(qt.Created),
 		NextReplacement:        (RlpTime)(qt.NextReplacement),
-		StoredCumulativeWeight: qt.StoredCumulativeWeight,
+		StoredCumulativeWeight: storedCumulativeWeight,
+		OriginalTipCap:         qt.OriginalTipCap,
 	})
 }
 
@@ -77,6 +88,7 @@ func (qt *QueuedTransaction) DecodeRLP(s *rlp.Stream) error {
 	qt.Created = time.Time(qtEnc.Created)
 	qt.NextReplacement = time.Time(qtEnc.NextReplacement)
 	qt.StoredCumulativeWeight = qtEnc.StoredCumulativeWeight
+	qt.OriginalTipCap = qtEnc.OriginalTipCap
 	return nil
 }
 
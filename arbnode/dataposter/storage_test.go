@@ -5,7 +5,10 @@ package dataposter
 
 import (
 	"context"
+	"fmt"
 	"math/big"
+	"math/rand"
+	"os"
 	"path"
 	"testing"
 
@@ -15,7 +18,9 @@ import (
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/offchainlabs/nitro/arbnode/dataposter/dbstorage"
+	"github.com/offchainlabs/nitro/arbnode/dataposter/postgres"
 	"github.com/offchainlabs/nitro/arbnode/dataposter/redis"
 	"github.com/offchainlabs/nitro/arbnode/dataposter/slice"
 	"github.com/offchainlabs/nitro/arbnode/dataposter/storage"
@@ -70,12 +75,40 @@ func newRedisStorage(ctx context.Context, t *testing.T, encF storage.EncoderDeco
 	return s
 }
 
+// newPostgresStorage returns storage in a fresh table of the database at TEST_POSTGRES_URL, or nil if it isn't set.
+func newPostgresStorage(ctx context.Context, t *testing.T, encF storage.EncoderDecoderF) *postgres.Storage {
+	t.Helper()
+	url := os.Getenv("TEST_POSTGRES_URL")
+	if url == "" {
+		return nil
+	}
+	config := postgres.Config{URL: url, Table: fmt.Sprintf("dataposter_test_%v", rand.Uint64())}
+	s, err := postgres.NewStorage(ctx, &config, encF)
+	if err != nil {
+		t.Fatalf("postgres.NewStorage() unexpected error: %v", err)
+	}
+	t.Cleanup(func() {
+		defer s.Close()
+		pool, err := pgxpool.New(context.Background(), url)
+		if err != nil {
+			return
+		}
+		defer pool.Close()
+		_, _ = pool.Exec(context.Background(), "DROP TABLE "+config.Table)
+	})
+	return s
+}
+
 func valueOf(t *testing.T, i int) *storage.QueuedTransaction {
 	t.Helper()
 	meta, err := rlp.EncodeToBytes(storage.BatchPosterPosition{DelayedMessageCount: uint64(i)})
 	if err != nil {
 		t.Fatalf("Encoding batch poster position, error: %v", err)
 	}
+	var originalTipCap *big.Int
+	if i%2 == 1 {
+		originalTipCap = big.NewInt(int64(i))
+	}
 	return &storage.QueuedTransaction{
 		FullTx: types.NewTransaction(
 			uint64(i),
@@ -98,6 +131,7 @@ func valueOf(t *testing.T, i int) *storage.QueuedTransaction {
 			R:          big.NewInt(int64(i)),
 			S:          big.NewInt(int64(i)),
 		},
+		OriginalTipCap: originalTipCap,
 	}
 }
 
@@ -128,7 +162,7 @@ func storages(t *testing.T) map[string]QueueStorage {
 			return enc
 		}
 	}
-	return map[string]QueueStorage{
+	m := map[string]QueueStorage{
 		"levelDBLegacy": newLevelDBStorage(t, f(&storage.LegacyEncoderDecoder{})),
 		"sliceLegacy":   newSliceStorage(f(&storage.LegacyEncoderDecoder{})),
 		"redisLegacy":   newRedisStorage(context.Background(), t, f(&storage.LegacyEncoderDecoder{})),
@@ -137,6 +171,10 @@ func storages(t *testing.T) map[string]QueueStorage {
 		"slice":         newSliceStorage(f(&storage.EncoderDecoder{})),
 		"redis":         newRedisStorage(context.Background(), t, f(&storage.EncoderDecoder{})),
 	}
+	if s := newPostgresStorage(context.Background(), t, f(&storage.EncoderDecoder{})); s != nil {
+		m["postgres"] = s
+	}
+	return m
 }
 
 // Returns a map of all initialized storages.
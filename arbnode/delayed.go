@@ -139,6 +139,8 @@ type DelayedInboxMessage struct {
 	BeforeInboxAcc         common.Hash
 	Message                *arbostypes.L1IncomingMessage
 	ParentChainBlockNumber uint64
+	// the inbox contract that delivered the message to the bridge, if known
+	Inbox common.Address
 }
 
 func (m *DelayedInboxMessage) AfterInboxAcc() common.Hash {
@@ -256,6 +258,7 @@ func (b *DelayedBridge) logsToDeliveredMessages(ctx context.Context, logs []type
 				L2msg: data,
 			},
 			ParentChainBlockNumber: parsedLog.Raw.BlockNumber,
+			Inbox:                  parsedLog.Inbox,
 		}
 		err := msg.Message.FillInBatchGasCost(batchFetcher)
 		if err != nil {
@@ -264,6 +267,8 @@ func (b *DelayedBridge) logsToDeliveredMessages(ctx context.Context, logs []type
 		messages = append(messages, msg)
 	}
 
+	// The bridge numbers messages from all of its inboxes in the order they were delivered on the parent chain,
+	// so sorting by request id merges them deterministically regardless of the order their logs were returned in.
 	sort.Sort(sortableMessageList(messages))
 
 	return messages, nil
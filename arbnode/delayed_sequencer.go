@@ -9,36 +9,118 @@ import (
 	"fmt"
 	"math/big"
 	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	flag "github.com/spf13/pflag"
 
 	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
 	"github.com/offchainlabs/nitro/execution"
+	"github.com/offchainlabs/nitro/util/arbmath"
 	"github.com/offchainlabs/nitro/util/headerreader"
 	"github.com/offchainlabs/nitro/util/stopwaiter"
 )
 
+var (
+	delayedSequencerCoordinatorConflictCounter = metrics.NewRegisteredCounter("arb/delayedsequencer/coordinator_conflict", nil)
+	delayedSequencerForceIncludedCounter       = metrics.NewRegisteredCounter("arb/delayedsequencer/force_included", nil)
+	delayedSequencerSequencedCounter           = metrics.NewRegisteredCounter("arb/delayedsequencer/sequenced", nil)
+	delayedSequencerBacklogGauge               = metrics.NewRegisteredGauge("arb/delayedsequencer/backlog", nil)
+	delayedSequencerFinalizedBacklogGauge      = metrics.NewRegisteredGauge("arb/delayedsequencer/backlog/finalized", nil)
+	delayedSequencerOldestUnsequencedAgeGauge  = metrics.NewRegisteredGauge("arb/delayedsequencer/oldest_unsequenced_age", nil)
+	delayedSequencerSinceLastSequencedGauge    = metrics.NewRegisteredGauge("arb/delayedsequencer/since_last_sequenced", nil)
+	delayedSequencerLagAlertCounter            = metrics.NewRegisteredCounter("arb/delayedsequencer/lag_alert", nil)
+	delayedSequencerDryRunWouldSequenceGauge   = metrics.NewRegisteredGauge("arb/delayedsequencer/dryrun/would_sequence", nil)
+	delayedSequencerDryRunPendingAgeGauge      = metrics.NewRegisteredGauge("arb/delayedsequencer/dryrun/pending_age", nil)
+)
+
+// The parent chain and inbox facilities the DelayedSequencer depends on,
+// kept narrow so they can be replaced in tests.
+type delayedSequencerL1Reader interface {
+	LastHeader(ctx context.Context) (*types.Header, error)
+	LatestSafeBlockHeader(ctx context.Context) (*types.Header, error)
+	LatestFinalizedBlockHeader(ctx context.Context) (*types.Header, error)
+	Subscribe(requireBlockNrUpdates bool) (<-chan *types.Header, func())
+}
+
+type delayedSequencerBridge interface {
+	GetAccumulator(ctx context.Context, sequenceNumber uint64, blockNumber *big.Int, blockHash common.Hash) (common.Hash, error)
+}
+
+type delayedSequencerInbox interface {
+	GetDelayedCount() (uint64, error)
+	GetDelayedMessageAccumulatorAndParentChainBlockNumber(ctx context.Context, seqNum uint64) (*arbostypes.L1IncomingMessage, common.Hash, uint64, error)
+	GetDelayedMessageInbox(seqNum uint64) (common.Address, error)
+}
+
+type delayedInboxLabeler interface {
+	DelayedInboxLabel(inbox common.Address) string
+}
+
+type delayedSequencerInboxContract interface {
+	MaxTimeVariation(ctx context.Context) (uint64, uint64, error)
+}
+
+type delayedSequencerBatchReader interface {
+	GetSequencerMessageBytes(ctx context.Context, seqNum uint64) ([]byte, common.Hash, error)
+}
+
+type delayedSequencerExec interface {
+	SequenceDelayedMessage(message *arbostypes.L1IncomingMessage, delayedSeqNum uint64) error
+	NextDelayedMessageNumber() (uint64, error)
+}
+
 type DelayedSequencer struct {
 	stopwaiter.StopWaiter
-	l1Reader                 *headerreader.HeaderReader
-	bridge                   *DelayedBridge
-	inbox                    *InboxTracker
-	reader                   *InboxReader
-	exec                     execution.ExecutionSequencer
+	l1Reader                 delayedSequencerL1Reader
+	bridge                   delayedSequencerBridge
+	inbox                    delayedSequencerInbox
+	seqInbox                 delayedSequencerInboxContract
+	reader                   delayedSequencerBatchReader
+	inboxLabels              delayedInboxLabeler
+	exec                     delayedSequencerExec
 	coordinator              *SeqCoordinator
+	finalityProvider         FinalityProvider
+	lagHooks                 []DelayedSequencerLagHook
+	lastSequenced            time.Time
+	lastDryRun               *DelayedSequencerDryRun
 	waitingForFinalizedBlock uint64
 	mutex                    sync.Mutex
 	config                   DelayedSequencerConfigFetcher
 }
 
 type DelayedSequencerConfig struct {
-	Enable              bool  `koanf:"enable" reload:"hot"`
-	FinalizeDistance    int64 `koanf:"finalize-distance" reload:"hot"`
-	RequireFullFinality bool  `koanf:"require-full-finality" reload:"hot"`
-	UseMergeFinality    bool  `koanf:"use-merge-finality" reload:"hot"`
+	Enable           bool  `koanf:"enable" reload:"hot"`
+	FinalizeDistance int64 `koanf:"finalize-distance" reload:"hot"`
+	// 0 means finality is decided by the finalize distance or Merge finality instead
+	FinalizeAfterSeconds uint64 `koanf:"finalize-after-seconds" reload:"hot"`
+	RequireFullFinality  bool   `koanf:"require-full-finality" reload:"hot"`
+	UseMergeFinality     bool   `koanf:"use-merge-finality" reload:"hot"`
+	FastDeposits         bool   `koanf:"fast-deposits" reload:"hot"`
+	ForceIncludeExpired  bool   `koanf:"force-include-expired" reload:"hot"`
+	// 0 means no limit
+	MaxMessagesPerSequence uint64        `koanf:"max-messages-per-sequence" reload:"hot"`
+	LagAlertThreshold      time.Duration `koanf:"lag-alert-threshold" reload:"hot"`
+	DryRun                 bool          `koanf:"dry-run" reload:"hot"`
+
+	Dangerous DelayedSequencerDangerousConfig `koanf:"dangerous" reload:"hot"`
+}
+
+type DelayedSequencerDangerousConfig struct {
+	IgnoreCoordinator bool `koanf:"ignore-coordinator" reload:"hot"`
+}
+
+func DelayedSequencerDangerousConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".ignore-coordinator", DefaultDelayedSequencerDangerousConfig.IgnoreCoordinator, "DANGEROUS! sequence delayed messages without this node being the chosen sequencer, still writing them to the coordinator and taking its lockout if no other sequencer holds it (only for single-sequencer chains using the coordinator to mirror messages)")
+}
+
+var DefaultDelayedSequencerDangerousConfig = DelayedSequencerDangerousConfig{
+	IgnoreCoordinator: false,
 }
 
 type DelayedSequencerConfigFetcher func() *DelayedSequencerConfig
@@ -46,22 +128,69 @@ type DelayedSequencerConfigFetcher func() *DelayedSequencerConfig
 func DelayedSequencerConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".enable", DefaultDelayedSequencerConfig.Enable, "enable delayed sequencer")
 	f.Int64(prefix+".finalize-distance", DefaultDelayedSequencerConfig.FinalizeDistance, "how many blocks in the past L1 block is considered final (ignored when using Merge finality)")
+	f.Uint64(prefix+".finalize-after-seconds", DefaultDelayedSequencerConfig.FinalizeAfterSeconds, "if non-zero, consider delayed messages final once their parent chain timestamp is this many seconds older than the parent chain head, instead of using the finalize distance or Merge finality (for parent chains with irregular block times)")
 	f.Bool(prefix+".require-full-finality", DefaultDelayedSequencerConfig.RequireFullFinality, "whether to wait for full finality before sequencing delayed messages")
 	f.Bool(prefix+".use-merge-finality", DefaultDelayedSequencerConfig.UseMergeFinality, "whether to use The Merge's notion of finality before sequencing delayed messages")
+	f.Bool(prefix+".fast-deposits", DefaultDelayedSequencerConfig.FastDeposits, "sequence plain ETH deposits once their parent chain block is safe, even if require-full-finality is set (other delayed messages still wait for full finality)")
+	f.Bool(prefix+".force-include-expired", DefaultDelayedSequencerConfig.ForceIncludeExpired, "sequence delayed messages whose on-chain force inclusion window has passed, even if their parent chain block isn't final yet")
+	f.Uint64(prefix+".max-messages-per-sequence", DefaultDelayedSequencerConfig.MaxMessagesPerSequence, "maximum number of delayed messages to sequence at once, so a large backlog is worked through in chunks (0 = no limit)")
+	f.Duration(prefix+".lag-alert-threshold", DefaultDelayedSequencerConfig.LagAlertThreshold, "warn and call the lag hooks when final delayed messages are waiting to be sequenced and the oldest unsequenced one is older than this (0 = disabled)")
+	f.Bool(prefix+".dry-run", DefaultDelayedSequencerConfig.DryRun, "check and report which delayed messages would be sequenced, regardless of the coordinator, but never sequence them (e.g. to verify a standby sequencer's view before failing over to it)")
+	DelayedSequencerDangerousConfigAddOptions(prefix+".dangerous", f)
 }
 
 var DefaultDelayedSequencerConfig = DelayedSequencerConfig{
-	Enable:              false,
-	FinalizeDistance:    20,
-	RequireFullFinality: false,
-	UseMergeFinality:    true,
+	Enable:                 false,
+	FinalizeDistance:       20,
+	RequireFullFinality:    false,
+	UseMergeFinality:       true,
+	FastDeposits:           false,
+	ForceIncludeExpired:    false,
+	MaxMessagesPerSequence: 1000,
+	LagAlertThreshold:      0,
+	DryRun:                 false,
+	Dangerous:              DefaultDelayedSequencerDangerousConfig,
 }
 
 var TestDelayedSequencerConfig = DelayedSequencerConfig{
-	Enable:              true,
-	FinalizeDistance:    20,
-	RequireFullFinality: false,
-	UseMergeFinality:    false,
+	Enable:                 true,
+	FinalizeDistance:       20,
+	RequireFullFinality:    false,
+	UseMergeFinality:       false,
+	FastDeposits:           false,
+	ForceIncludeExpired:    false,
+	MaxMessagesPerSequence: 1000,
+	LagAlertThreshold:      0,
+	DryRun:                 false,
+	Dangerous:              DefaultDelayedSequencerDangerousConfig,
+}
+
+// DelayedSequencerFinalityPolicyVersion is bumped whenever the rules deciding
+// when a delayed message may be sequenced change.
+const DelayedSequencerFinalityPolicyVersion = 1
+
+// DelayedSequencerFinalityPolicy describes which parent chain block a delayed
+// message must be included at or before to be sequenced, by message type.
+type DelayedSequencerFinalityPolicy struct {
+	Version  uint64 `json:"version"`
+	Messages string `json:"messages"`
+	Deposits string `json:"deposits"`
+	// whether messages past the force inclusion window are sequenced regardless
+	ForceIncludeExpired bool `json:"forceIncludeExpired"`
+}
+
+func (c *DelayedSequencerConfig) FinalityPolicy() DelayedSequencerFinalityPolicy {
+	messages, deposits := c.finalityProviders(nil)
+	return DelayedSequencerFinalityPolicy{
+		Version:             DelayedSequencerFinalityPolicyVersion,
+		Messages:            messages.Describe(),
+		Deposits:            deposits.Describe(),
+		ForceIncludeExpired: c.ForceIncludeExpired,
+	}
+}
+
+func (c *DelayedSequencerConfig) fastDepositsApply() bool {
+	return c.FastDeposits && c.UseMergeFinality && c.RequireFullFinality
 }
 
 func NewDelayedSequencer(l1Reader *headerreader.HeaderReader, reader *InboxReader, exec execution.ExecutionSequencer, coordinator *SeqCoordinator, config DelayedSequencerConfigFetcher) (*DelayedSequencer, error) {
@@ -69,7 +198,9 @@ func NewDelayedSequencer(l1Reader *headerreader.HeaderReader, reader *InboxReade
 		l1Reader:    l1Reader,
 		bridge:      reader.DelayedBridge(),
 		inbox:       reader.Tracker(),
+		seqInbox:    reader.SequencerInbox(),
 		reader:      reader,
+		inboxLabels: reader,
 		coordinator: coordinator,
 		exec:        exec,
 		config:      config,
@@ -84,48 +215,149 @@ func (d *DelayedSequencer) getDelayedMessagesRead() (uint64, error) {
 	return d.exec.NextDelayedMessageNumber()
 }
 
+// forceInclusionWindow tells which delayed messages can be force included on the parent chain,
+// and so may be sequenced before they're final.
+type forceInclusionWindow struct {
+	delayBlocks  uint64
+	delaySeconds uint64
+	l1Block      uint64
+	timestamp    uint64
+}
+
+func (d *DelayedSequencer) forceInclusionWindow(ctx context.Context, lastBlockHeader *types.Header) (*forceInclusionWindow, error) {
+	if d.seqInbox == nil {
+		return nil, errors.New("delayed sequencer has no sequencer inbox to read the force inclusion window from")
+	}
+	delayBlocks, delaySeconds, err := d.seqInbox.MaxTimeVariation(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the force inclusion window: %w", err)
+	}
+	return &forceInclusionWindow{
+		delayBlocks:  delayBlocks,
+		delaySeconds: delaySeconds,
+		l1Block:      arbutil.ParentHeaderToL1BlockNumber(lastBlockHeader),
+		timestamp:    lastBlockHeader.Time,
+	}, nil
+}
+
+// expired mirrors the sequencer inbox's checks for whether a delayed message can be force included.
+func (w *forceInclusionWindow) expired(header *arbostypes.L1IncomingMessageHeader) bool {
+	return arbmath.SaturatingUAdd(header.BlockNumber, w.delayBlocks) < w.l1Block &&
+		arbmath.SaturatingUAdd(header.Timestamp, w.delaySeconds) < w.timestamp
+}
+
+// ForceInclusionResult describes the delayed messages sequenced by a forced run of the delayed sequencer.
+type ForceInclusionResult struct {
+	Sequenced hexutil.Uint64 `json:"sequenced"`
+	// how many of the sequenced messages weren't final yet, but past the force inclusion window
+	ForceIncluded hexutil.Uint64 `json:"forceIncluded"`
+	// how many delayed messages were left for later because of max-messages-per-sequence
+	// (some of them may not be final yet)
+	Remaining hexutil.Uint64 `json:"remaining"`
+	// set in dry-run mode, where nothing is sequenced
+	DryRun *DelayedSequencerDryRun `json:"dryRun,omitempty"`
+}
+
+// DelayedSequencerDryRun describes the delayed messages a dry run found ready to be sequenced.
+// On a standby sequencer, they should be sequenced by the active one shortly after.
+type DelayedSequencerDryRun struct {
+	// the first delayed message that would be sequenced, which is the execution's next delayed message
+	StartPos hexutil.Uint64 `json:"startPos"`
+	// how many delayed messages would be sequenced
+	Count hexutil.Uint64 `json:"count"`
+	// the delayed inbox accumulator after the last message, as verified against the delayed bridge
+	Accumulator common.Hash `json:"accumulator,omitempty"`
+	// how many of them would be force included
+	ForceIncluded hexutil.Uint64 `json:"forceIncluded"`
+	// when messages from StartPos were first found ready, if they still are
+	PendingSince time.Time `json:"pendingSince"`
+	CheckedAt    time.Time `json:"checkedAt"`
+}
+
 func (d *DelayedSequencer) trySequence(ctx context.Context, lastBlockHeader *types.Header) error {
-	if d.coordinator != nil && !d.coordinator.CurrentlyChosen() {
-		return nil
+	_, err := d.trySequenceWithForce(ctx, lastBlockHeader, d.config().ForceIncludeExpired)
+	return err
+}
+
+// trySequenceWithForce sequences the delayed messages that are final, and if forceExpired is set, those
+// past the force inclusion window. It returns nil if this node isn't the one to sequence them.
+func (d *DelayedSequencer) trySequenceWithForce(ctx context.Context, lastBlockHeader *types.Header, forceExpired bool) (*ForceInclusionResult, error) {
+	// a dry run never sequences anything, so it doesn't need the lockout
+	if d.coordinator != nil && !d.coordinator.CurrentlyChosen() && !d.config().DryRun {
+		if !d.ignoresCoordinator() {
+			return nil, nil
+		}
+		// even when told to ignore the coordinator, never race another sequencer holding the lockout
+		chosen, err := d.coordinator.CurrentChosenSequencer(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("delayed sequencer failed checking for a conflicting sequencer: %w", err)
+		}
+		if chosen != "" && chosen != d.coordinator.config.Url() {
+			delayedSequencerCoordinatorConflictCounter.Inc(1)
+			log.Warn("delayed sequencer ignores the coordinator, but another sequencer is chosen; not sequencing delayed messages", "chosen", chosen, "myUrl", d.coordinator.config.Url())
+			return nil, nil
+		}
 	}
 
-	return d.sequenceWithoutLockout(ctx, lastBlockHeader)
+	return d.sequenceWithoutLockout(ctx, lastBlockHeader, forceExpired)
+}
+
+// ignoresCoordinator returns whether delayed messages may be sequenced without this node being the chosen sequencer.
+func (d *DelayedSequencer) ignoresCoordinator() bool {
+	return d.config().Dangerous.IgnoreCoordinator
 }
 
-func (d *DelayedSequencer) sequenceWithoutLockout(ctx context.Context, lastBlockHeader *types.Header) error {
+func (d *DelayedSequencer) sequenceWithoutLockout(ctx context.Context, lastBlockHeader *types.Header, forceExpired bool) (*ForceInclusionResult, error) {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 
+	result := &ForceInclusionResult{}
 	config := d.config()
 	if !config.Enable {
-		return nil
+		return result, nil
 	}
 
-	var finalized uint64
-	var finalizedHash common.Hash
-	if config.UseMergeFinality && headerreader.HeaderIndicatesFinalitySupport(lastBlockHeader) {
-		var header *types.Header
-		var err error
-		if config.RequireFullFinality {
-			header, err = d.l1Reader.LatestFinalizedBlockHeader(ctx)
-		} else {
-			header, err = d.l1Reader.LatestSafeBlockHeader(ctx)
+	messageFinality, depositFinality := d.finalityProvidersLocked(config)
+	finalizedBlock, err := messageFinality.Finalized(ctx, lastBlockHeader)
+	if err != nil {
+		return nil, err
+	}
+	if finalizedBlock == nil {
+		if !forceExpired {
+			return result, nil
+		}
+		// nothing is final yet, but expired messages may still be force included
+		finalizedBlock = &FinalizedBlock{}
+	}
+	finalized, finalizedHash := finalizedBlock.Number, finalizedBlock.Hash
+	// measured after sequencing, and even if sequencing fails, so a stuck delayed sequencer shows up
+	defer func() {
+		if err := d.updateLagLocked(ctx, config, finalizedBlock); err != nil {
+			log.Warn("failed to measure delayed sequencer lag", "err", err)
 		}
+	}()
+
+	// ETH deposits may be final earlier, e.g. at the safe block, which is never behind the finalized one
+	depositFinalizedBlock := finalizedBlock
+	if depositFinality != messageFinality {
+		block, err := depositFinality.Finalized(ctx, lastBlockHeader)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		finalized = header.Number.Uint64()
-		finalizedHash = header.Hash()
-	} else {
-		currentNum := lastBlockHeader.Number.Int64()
-		if currentNum < config.FinalizeDistance {
-			return nil
+		if block != nil && block.Number > finalized {
+			depositFinalizedBlock = block
 		}
-		finalized = uint64(currentNum - config.FinalizeDistance)
 	}
+	depositFinalized, depositFinalizedHash := depositFinalizedBlock.Number, depositFinalizedBlock.Hash
 
-	if d.waitingForFinalizedBlock > finalized {
-		return nil
+	var window *forceInclusionWindow
+	if forceExpired {
+		window, err = d.forceInclusionWindow(ctx, lastBlockHeader)
+		if err != nil {
+			return nil, err
+		}
+	} else if d.waitingForFinalizedBlock > depositFinalized {
+		return result, nil
 	}
 
 	// Unless we find an unfinalized message (which sets waitingForBlock),
@@ -134,26 +366,45 @@ func (d *DelayedSequencer) sequenceWithoutLockout(ctx context.Context, lastBlock
 
 	dbDelayedCount, err := d.inbox.GetDelayedCount()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	startPos, err := d.getDelayedMessagesRead()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Retrieve all finalized delayed messages
 	pos := startPos
 	var lastDelayedAcc common.Hash
 	var messages []*arbostypes.L1IncomingMessage
+	// the accumulator is checked at the latest block any sequenced message required
+	checkAccAt, checkAccAtHash := finalized, finalizedHash
 	for pos < dbDelayedCount {
+		if config.MaxMessagesPerSequence != 0 && uint64(len(messages)) >= config.MaxMessagesPerSequence {
+			result.Remaining = hexutil.Uint64(dbDelayedCount - pos)
+			// the rest of the backlog may already be final
+			d.waitingForFinalizedBlock = 0
+			break
+		}
 		msg, acc, parentChainBlockNumber, err := d.inbox.GetDelayedMessageAccumulatorAndParentChainBlockNumber(ctx, pos)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		if parentChainBlockNumber > finalized {
-			// Message isn't finalized yet; stop here
-			d.waitingForFinalizedBlock = parentChainBlockNumber
-			break
+		msgFinalized := finalizedBlock
+		if msg.Header.Kind == arbostypes.L1MessageType_EthDeposit {
+			msgFinalized = depositFinalizedBlock
+		}
+		if !msgFinalized.includes(parentChainBlockNumber, msg.Header.Timestamp) {
+			if window == nil || !window.expired(msg.Header) {
+				// Message isn't finalized yet; stop here
+				d.waitingForFinalizedBlock = parentChainBlockNumber
+				break
+			}
+			// anyone could force include this message, so there's no point waiting for it to be final
+			result.ForceIncluded++
+			checkAccAt, checkAccAtHash = lastBlockHeader.Number.Uint64(), lastBlockHeader.Hash()
+		} else if parentChainBlockNumber > finalized && checkAccAt < depositFinalized {
+			checkAccAt, checkAccAtHash = depositFinalized, depositFinalizedHash
 		}
 		if lastDelayedAcc != (common.Hash{}) {
 			// Ensure that there hasn't been a reorg and this message follows the last
@@ -163,7 +414,7 @@ func (d *DelayedSequencer) sequenceWithoutLockout(ctx context.Context, lastBlock
 				ParentChainBlockNumber: parentChainBlockNumber,
 			}
 			if fullMsg.AfterInboxAcc() != acc {
-				return errors.New("delayed message accumulator mismatch while sequencing")
+				return nil, errors.New("delayed message accumulator mismatch while sequencing")
 			}
 		}
 		lastDelayedAcc = acc
@@ -172,7 +423,7 @@ func (d *DelayedSequencer) sequenceWithoutLockout(ctx context.Context, lastBlock
 			return data, err
 		})
 		if err != nil {
-			return err
+			return nil, err
 		}
 		messages = append(messages, msg)
 		pos++
@@ -180,33 +431,243 @@ func (d *DelayedSequencer) sequenceWithoutLockout(ctx context.Context, lastBlock
 
 	// Sequence the delayed messages, if any
 	if len(messages) > 0 {
-		delayedBridgeAcc, err := d.bridge.GetAccumulator(ctx, pos-1, new(big.Int).SetUint64(finalized), finalizedHash)
+		delayedBridgeAcc, err := d.bridge.GetAccumulator(ctx, pos-1, new(big.Int).SetUint64(checkAccAt), checkAccAtHash)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if delayedBridgeAcc != lastDelayedAcc {
 			// Probably a reorg that hasn't been picked up by the inbox reader
-			return fmt.Errorf("inbox reader at delayed message %v db accumulator %v doesn't match delayed bridge accumulator %v at L1 block %v", pos-1, lastDelayedAcc, delayedBridgeAcc, finalized)
+			return nil, fmt.Errorf("inbox reader at delayed message %v db accumulator %v doesn't match delayed bridge accumulator %v at L1 block %v", pos-1, lastDelayedAcc, delayedBridgeAcc, checkAccAt)
+		}
+		if config.DryRun {
+			// the active sequencer may sequence them at any time, so check again on the next update
+			d.waitingForFinalizedBlock = 0
+			result.DryRun = d.recordDryRunLocked(startPos, uint64(len(messages)), lastDelayedAcc, uint64(result.ForceIncluded))
+			return result, nil
 		}
 		for i, msg := range messages {
 			err = d.exec.SequenceDelayedMessage(msg, startPos+uint64(i))
 			if err != nil {
-				return err
+				// the rest of the messages are final, so retry them on the next update
+				d.waitingForFinalizedBlock = 0
+				return nil, err
 			}
+			result.Sequenced++
+			delayedSequencerSequencedCounter.Inc(1)
+			d.countSequencedByInbox(startPos + uint64(i))
+			d.lastSequenced = time.Now()
+		}
+		log.Info("DelayedSequencer: Sequenced", "msgnum", len(messages), "startpos", startPos, "forceIncluded", result.ForceIncluded, "remaining", result.Remaining)
+		delayedSequencerForceIncludedCounter.Inc(int64(result.ForceIncluded))
+	}
+
+	if config.DryRun {
+		d.waitingForFinalizedBlock = 0
+		result.DryRun = d.recordDryRunLocked(startPos, 0, common.Hash{}, 0)
+	}
+	return result, nil
+}
+
+// countSequencedByInbox updates the per-inbox metric for a sequenced delayed message.
+func (d *DelayedSequencer) countSequencedByInbox(seqNum uint64) {
+	if d.inboxLabels == nil {
+		return
+	}
+	inbox, err := d.inbox.GetDelayedMessageInbox(seqNum)
+	if err != nil {
+		log.Warn("failed to look up delayed message inbox", "seqNum", seqNum, "err", err)
+		return
+	}
+	metrics.GetOrRegisterCounter("arb/delayedsequencer/sequenced/"+d.inboxLabels.DelayedInboxLabel(inbox), nil).Inc(1)
+}
+
+// recordDryRunLocked records the delayed messages a dry run would sequence, and how long the ones
+// at startPos have been waiting for the active sequencer.
+func (d *DelayedSequencer) recordDryRunLocked(startPos uint64, count uint64, acc common.Hash, forceIncluded uint64) *DelayedSequencerDryRun {
+	now := time.Now()
+	pendingSince := now
+	if d.lastDryRun != nil && uint64(d.lastDryRun.StartPos) == startPos && d.lastDryRun.Count > 0 {
+		pendingSince = d.lastDryRun.PendingSince
+	}
+	dryRun := &DelayedSequencerDryRun{
+		StartPos:      hexutil.Uint64(startPos),
+		Count:         hexutil.Uint64(count),
+		Accumulator:   acc,
+		ForceIncluded: hexutil.Uint64(forceIncluded),
+		PendingSince:  pendingSince,
+		CheckedAt:     now,
+	}
+	d.lastDryRun = dryRun
+	delayedSequencerDryRunWouldSequenceGauge.Update(arbmath.SaturatingCast[int64](count))
+	if count > 0 {
+		delayedSequencerDryRunPendingAgeGauge.Update(int64(now.Sub(pendingSince).Seconds()))
+		log.Info("DelayedSequencer: dry run would sequence", "msgnum", count, "startpos", startPos, "accumulator", acc, "forceIncluded", forceIncluded, "pendingFor", now.Sub(pendingSince))
+	} else {
+		delayedSequencerDryRunPendingAgeGauge.Update(0)
+	}
+	return dryRun
+}
+
+// LastDryRun returns what the latest dry run found ready to be sequenced, or nil if there wasn't one.
+func (d *DelayedSequencer) LastDryRun() *DelayedSequencerDryRun {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.lastDryRun
+}
+
+// DelayedSequencerLag describes how far the delayed sequencer is behind the delayed inbox.
+type DelayedSequencerLag struct {
+	// delayed messages that are final, but not sequenced yet
+	FinalizedUnsequenced uint64
+	// the age of the oldest delayed message that isn't sequenced yet, by its parent chain timestamp
+	OldestUnsequencedAge time.Duration
+	// time since delayed messages were last sequenced, or since the delayed sequencer started
+	SinceLastSequenced time.Duration
+}
+
+// DelayedSequencerLagHook is called when the delayed sequencer's lag exceeds the lag-alert-threshold.
+// It's called while sequencing is blocked, so it must not block.
+type DelayedSequencerLagHook func(lag *DelayedSequencerLag)
+
+// AddLagHook registers a hook to alert on, e.g. to page an operator.
+func (d *DelayedSequencer) AddLagHook(hook DelayedSequencerLagHook) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.lagHooks = append(d.lagHooks, hook)
+}
+
+// countFinalizedLocked returns how many of the delayed messages in [pos, count) are final.
+func (d *DelayedSequencer) countFinalizedLocked(ctx context.Context, pos uint64, count uint64, finalized *FinalizedBlock) (uint64, error) {
+	// parent chain block numbers and timestamps never decrease along the delayed inbox, so search for the first unfinalized message
+	low, high := pos, count
+	for low < high {
+		mid := low + (high-low)/2
+		msg, _, parentChainBlockNumber, err := d.inbox.GetDelayedMessageAccumulatorAndParentChainBlockNumber(ctx, mid)
+		if err != nil {
+			return 0, err
+		}
+		if finalized.includes(parentChainBlockNumber, msg.Header.Timestamp) {
+			low = mid + 1
+		} else {
+			high = mid
 		}
-		log.Info("DelayedSequencer: Sequenced", "msgnum", len(messages), "startpos", startPos)
 	}
+	return low - pos, nil
+}
 
+func (d *DelayedSequencer) updateLagLocked(ctx context.Context, config *DelayedSequencerConfig, finalized *FinalizedBlock) error {
+	now := time.Now()
+	if d.lastSequenced.IsZero() {
+		d.lastSequenced = now
+	}
+	dbDelayedCount, err := d.inbox.GetDelayedCount()
+	if err != nil {
+		return err
+	}
+	pos, err := d.getDelayedMessagesRead()
+	if err != nil {
+		return err
+	}
+	lag := &DelayedSequencerLag{SinceLastSequenced: now.Sub(d.lastSequenced)}
+	if pos < dbDelayedCount {
+		lag.FinalizedUnsequenced, err = d.countFinalizedLocked(ctx, pos, dbDelayedCount, finalized)
+		if err != nil {
+			return err
+		}
+		msg, _, _, err := d.inbox.GetDelayedMessageAccumulatorAndParentChainBlockNumber(ctx, pos)
+		if err != nil {
+			return err
+		}
+		lag.OldestUnsequencedAge = now.Sub(time.Unix(arbmath.SaturatingCast[int64](msg.Header.Timestamp), 0))
+	}
+	delayedSequencerBacklogGauge.Update(arbmath.SaturatingCast[int64](arbmath.SaturatingUSub(dbDelayedCount, pos)))
+	delayedSequencerFinalizedBacklogGauge.Update(arbmath.SaturatingCast[int64](lag.FinalizedUnsequenced))
+	delayedSequencerOldestUnsequencedAgeGauge.Update(int64(lag.OldestUnsequencedAge.Seconds()))
+	delayedSequencerSinceLastSequencedGauge.Update(int64(lag.SinceLastSequenced.Seconds()))
+
+	if config.LagAlertThreshold != 0 && lag.FinalizedUnsequenced > 0 && lag.OldestUnsequencedAge > config.LagAlertThreshold {
+		delayedSequencerLagAlertCounter.Inc(1)
+		log.Warn("delayed sequencer is lagging", "finalizedUnsequenced", lag.FinalizedUnsequenced, "oldestUnsequencedAge", lag.OldestUnsequencedAge, "sinceLastSequenced", lag.SinceLastSequenced)
+		for _, hook := range d.lagHooks {
+			hook(lag)
+		}
+	}
 	return nil
 }
 
+// SetFinalityProvider replaces the finality rules derived from the config with a custom provider,
+// which decides finality for all delayed messages, including ETH deposits. Passing nil restores the config's rules.
+func (d *DelayedSequencer) SetFinalityProvider(provider FinalityProvider) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.finalityProvider = provider
+	// the new provider may consider earlier blocks final
+	d.waitingForFinalizedBlock = 0
+}
+
+// finalityProvidersLocked returns the providers for delayed messages and ETH deposits.
+func (d *DelayedSequencer) finalityProvidersLocked(config *DelayedSequencerConfig) (FinalityProvider, FinalityProvider) {
+	if d.finalityProvider != nil {
+		return d.finalityProvider, d.finalityProvider
+	}
+	return config.finalityProviders(d.l1Reader)
+}
+
+// FinalityPolicy returns the currently configured rules for when delayed messages are sequenced.
+func (d *DelayedSequencer) FinalityPolicy() DelayedSequencerFinalityPolicy {
+	config := d.config()
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	messages, deposits := d.finalityProvidersLocked(config)
+	return DelayedSequencerFinalityPolicy{
+		Version:             DelayedSequencerFinalityPolicyVersion,
+		Messages:            messages.Describe(),
+		Deposits:            deposits.Describe(),
+		ForceIncludeExpired: config.ForceIncludeExpired,
+	}
+}
+
 // Dangerous: bypasses lockout check!
 func (d *DelayedSequencer) ForceSequenceDelayed(ctx context.Context) error {
 	lastBlockHeader, err := d.l1Reader.LastHeader(ctx)
 	if err != nil {
 		return err
 	}
-	return d.sequenceWithoutLockout(ctx, lastBlockHeader)
+	_, err = d.sequenceWithoutLockout(ctx, lastBlockHeader, d.config().ForceIncludeExpired)
+	return err
+}
+
+// ForceIncludeExpired sequences the delayed messages that are past the on-chain force inclusion window,
+// along with any final ones before them, regardless of whether force-include-expired is set.
+// The messages are checked against the delayed bridge's accumulator at the latest parent chain block.
+func (d *DelayedSequencer) ForceIncludeExpired(ctx context.Context) (*ForceInclusionResult, error) {
+	lastBlockHeader, err := d.l1Reader.LastHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := d.trySequenceWithForce(ctx, lastBlockHeader, true)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, errors.New("this node isn't the chosen sequencer")
+	}
+	return result, nil
+}
+
+// sequenceBacklog keeps sequencing chunks of delayed messages until there are no more final ones.
+func (d *DelayedSequencer) sequenceBacklog(ctx context.Context, lastBlockHeader *types.Header) {
+	for ctx.Err() == nil {
+		result, err := d.trySequenceWithForce(ctx, lastBlockHeader, d.config().ForceIncludeExpired)
+		if err != nil {
+			log.Error("Delayed sequencer error", "err", err)
+			return
+		}
+		if result == nil || result.Remaining == 0 || result.Sequenced == 0 {
+			return
+		}
+		log.Info("DelayedSequencer: working through backlog", "sequenced", result.Sequenced, "remaining", result.Remaining)
+	}
 }
 
 func (d *DelayedSequencer) run(ctx context.Context) {
@@ -220,9 +681,7 @@ func (d *DelayedSequencer) run(ctx context.Context) {
 				log.Info("delayed sequencer: header channel close")
 				return
 			}
-			if err := d.trySequence(ctx, nextHeader); err != nil {
-				log.Error("Delayed sequencer error", "err", err)
-			}
+			d.sequenceBacklog(ctx, nextHeader)
 		case <-ctx.Done():
 			log.Info("delayed sequencer: context done", "err", ctx.Err())
 			return
@@ -231,6 +690,9 @@ func (d *DelayedSequencer) run(ctx context.Context) {
 }
 
 func (d *DelayedSequencer) Start(ctxIn context.Context) {
+	if d.coordinator != nil && d.ignoresCoordinator() {
+		log.Warn("delayed sequencer will sequence delayed messages without holding the sequencer coordinator's lockout; this is only safe when a single sequencer is ever running")
+	}
 	d.StopWaiter.Start(ctxIn, d)
 	d.LaunchThread(d.run)
 }
@@ -0,0 +1,792 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/execution"
+)
+
+// simulatedDelayedL1 is a scriptable parent chain: the head, safe and finalized
+// blocks can be moved independently, and reorgs replace block hashes and the
+// delayed messages posted from a given block onwards.
+type simulatedDelayedL1 struct {
+	mutex        sync.Mutex
+	proofOfStake bool
+	fork         byte
+	head         uint64
+	safe         uint64
+	finalized    uint64
+	// the sequencer inbox's force inclusion window
+	delayBlocks  uint64
+	delaySeconds uint64
+	// delayed messages as the bridge contract currently sees them
+	messages []*DelayedInboxMessage
+	// called before the bridge accumulator is read, to inject reorgs mid-sequencing
+	beforeGetAccumulator func()
+	headers              chan *types.Header
+}
+
+func newSimulatedDelayedL1(proofOfStake bool) *simulatedDelayedL1 {
+	return &simulatedDelayedL1{
+		proofOfStake: proofOfStake,
+		headers:      make(chan *types.Header, 16),
+	}
+}
+
+func (l *simulatedDelayedL1) headerAtLocked(number uint64) *types.Header {
+	difficulty := common.Big1
+	if l.proofOfStake {
+		difficulty = common.Big0
+	}
+	return &types.Header{
+		Number:     new(big.Int).SetUint64(number),
+		Time:       number,
+		Difficulty: difficulty,
+		// different forks produce different block hashes
+		Coinbase: common.Address{l.fork},
+	}
+}
+
+func (l *simulatedDelayedL1) LastHeader(ctx context.Context) (*types.Header, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.headerAtLocked(l.head), nil
+}
+
+func (l *simulatedDelayedL1) LatestSafeBlockHeader(ctx context.Context) (*types.Header, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if !l.proofOfStake {
+		return nil, errors.New("safe block not supported")
+	}
+	return l.headerAtLocked(l.safe), nil
+}
+
+func (l *simulatedDelayedL1) LatestFinalizedBlockHeader(ctx context.Context) (*types.Header, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if !l.proofOfStake {
+		return nil, errors.New("finalized block not supported")
+	}
+	return l.headerAtLocked(l.finalized), nil
+}
+
+func (l *simulatedDelayedL1) MaxTimeVariation(ctx context.Context) (uint64, uint64, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.delayBlocks, l.delaySeconds, nil
+}
+
+func (l *simulatedDelayedL1) Subscribe(requireBlockNrUpdates bool) (<-chan *types.Header, func()) {
+	return l.headers, func() {}
+}
+
+// setBlocks moves the chain's head, safe and finalized blocks.
+func (l *simulatedDelayedL1) setBlocks(head, safe, finalized uint64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.head, l.safe, l.finalized = head, safe, finalized
+}
+
+// postDelayed adds a delayed message to the bridge at the given parent chain block.
+func (l *simulatedDelayedL1) postDelayed(kind uint8, parentChainBlock uint64, payload byte) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.messages = appendDelayedMessage(l.messages, kind, parentChainBlock, payload)
+}
+
+// postDelayedFrom adds a delayed message delivered to the bridge by the given inbox contract.
+func (l *simulatedDelayedL1) postDelayedFrom(inbox common.Address, kind uint8, parentChainBlock uint64, payload byte) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.messages = appendDelayedMessage(l.messages, kind, parentChainBlock, payload)
+	l.messages[len(l.messages)-1].Inbox = inbox
+}
+
+// reorg switches to a new fork, dropping all delayed messages posted at or after fromBlock.
+func (l *simulatedDelayedL1) reorg(fromBlock uint64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.fork++
+	for i, msg := range l.messages {
+		if msg.ParentChainBlockNumber >= fromBlock {
+			l.messages = l.messages[:i]
+			break
+		}
+	}
+}
+
+func (l *simulatedDelayedL1) GetAccumulator(ctx context.Context, sequenceNumber uint64, blockNumber *big.Int, blockHash common.Hash) (common.Hash, error) {
+	l.mutex.Lock()
+	hook := l.beforeGetAccumulator
+	l.mutex.Unlock()
+	if hook != nil {
+		hook()
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	number := blockNumber.Uint64()
+	if number > l.head {
+		return common.Hash{}, fmt.Errorf("block %v is beyond head %v", number, l.head)
+	}
+	if blockHash != (common.Hash{}) && blockHash != l.headerAtLocked(number).Hash() {
+		return common.Hash{}, fmt.Errorf("block hash %v not found", blockHash)
+	}
+	if sequenceNumber >= uint64(len(l.messages)) || l.messages[sequenceNumber].ParentChainBlockNumber > number {
+		return common.Hash{}, fmt.Errorf("delayed message %v not posted as of block %v", sequenceNumber, number)
+	}
+	return l.messages[sequenceNumber].AfterInboxAcc(), nil
+}
+
+func appendDelayedMessage(messages []*DelayedInboxMessage, kind uint8, parentChainBlock uint64, payload byte) []*DelayedInboxMessage {
+	var beforeAcc common.Hash
+	if len(messages) > 0 {
+		beforeAcc = messages[len(messages)-1].AfterInboxAcc()
+	}
+	requestId := common.BigToHash(big.NewInt(int64(len(messages))))
+	return append(messages, &DelayedInboxMessage{
+		BeforeInboxAcc: beforeAcc,
+		Message: &arbostypes.L1IncomingMessage{
+			Header: &arbostypes.L1IncomingMessageHeader{
+				Kind:        kind,
+				Poster:      common.Address{1},
+				BlockNumber: parentChainBlock,
+				Timestamp:   parentChainBlock,
+				RequestId:   &requestId,
+				L1BaseFee:   big.NewInt(0),
+			},
+			L2msg: []byte{payload},
+		},
+		ParentChainBlockNumber: parentChainBlock,
+	})
+}
+
+// simulatedDelayedInbox stands in for the InboxTracker's view of the delayed inbox,
+// which lags behind (and may diverge from) the bridge until synced.
+type simulatedDelayedInbox struct {
+	mutex    sync.Mutex
+	messages []*DelayedInboxMessage
+}
+
+func (i *simulatedDelayedInbox) GetDelayedCount() (uint64, error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return uint64(len(i.messages)), nil
+}
+
+func (i *simulatedDelayedInbox) GetDelayedMessageAccumulatorAndParentChainBlockNumber(ctx context.Context, seqNum uint64) (*arbostypes.L1IncomingMessage, common.Hash, uint64, error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if seqNum >= uint64(len(i.messages)) {
+		return nil, common.Hash{}, 0, AccumulatorNotFoundErr
+	}
+	msg := i.messages[seqNum]
+	// hand out a copy, as the sequencer may fill in the message
+	msgCopy := *msg.Message
+	return &msgCopy, msg.AfterInboxAcc(), msg.ParentChainBlockNumber, nil
+}
+
+func (i *simulatedDelayedInbox) GetDelayedMessageInbox(seqNum uint64) (common.Address, error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if seqNum >= uint64(len(i.messages)) {
+		return common.Address{}, AccumulatorNotFoundErr
+	}
+	return i.messages[seqNum].Inbox, nil
+}
+
+func (i *simulatedDelayedInbox) GetSequencerMessageBytes(ctx context.Context, seqNum uint64) ([]byte, common.Hash, error) {
+	return nil, common.Hash{}, fmt.Errorf("batch %v not available", seqNum)
+}
+
+// syncFrom copies the bridge's current delayed messages, as the inbox reader would.
+func (i *simulatedDelayedInbox) syncFrom(l1 *simulatedDelayedL1) {
+	l1.mutex.Lock()
+	defer l1.mutex.Unlock()
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.messages = append([]*DelayedInboxMessage{}, l1.messages...)
+}
+
+// corrupt replaces the payload of a delayed message without updating the bridge.
+func (i *simulatedDelayedInbox) corrupt(seqNum uint64) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	msg := *i.messages[seqNum]
+	l1Msg := *msg.Message
+	l1Msg.L2msg = []byte{0xff}
+	msg.Message = &l1Msg
+	i.messages[seqNum] = &msg
+}
+
+type recordingDelayedExec struct {
+	mutex     sync.Mutex
+	sequenced []*arbostypes.L1IncomingMessage
+	err       error
+}
+
+func (e *recordingDelayedExec) SequenceDelayedMessage(message *arbostypes.L1IncomingMessage, delayedSeqNum uint64) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.err != nil {
+		return e.err
+	}
+	if delayedSeqNum != uint64(len(e.sequenced)) {
+		return fmt.Errorf("sequenced delayed message %v, expected %v", delayedSeqNum, len(e.sequenced))
+	}
+	e.sequenced = append(e.sequenced, message)
+	return nil
+}
+
+func (e *recordingDelayedExec) NextDelayedMessageNumber() (uint64, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return uint64(len(e.sequenced)), nil
+}
+
+func (e *recordingDelayedExec) payloads() []byte {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	var payloads []byte
+	for _, msg := range e.sequenced {
+		payloads = append(payloads, msg.L2msg[0])
+	}
+	return payloads
+}
+
+type delayedSequencerHarness struct {
+	t      *testing.T
+	l1     *simulatedDelayedL1
+	inbox  *simulatedDelayedInbox
+	exec   *recordingDelayedExec
+	config DelayedSequencerConfig
+	seq    *DelayedSequencer
+}
+
+func newDelayedSequencerHarness(t *testing.T, proofOfStake bool, config DelayedSequencerConfig) *delayedSequencerHarness {
+	h := &delayedSequencerHarness{
+		t:      t,
+		l1:     newSimulatedDelayedL1(proofOfStake),
+		inbox:  &simulatedDelayedInbox{},
+		exec:   &recordingDelayedExec{},
+		config: config,
+	}
+	h.seq = &DelayedSequencer{
+		l1Reader: h.l1,
+		bridge:   h.l1,
+		inbox:    h.inbox,
+		seqInbox: h.l1,
+		reader:   h.inbox,
+		exec:     h.exec,
+		config:   func() *DelayedSequencerConfig { return &h.config },
+	}
+	return h
+}
+
+// step syncs the inbox with the bridge and runs the sequencer on the current head.
+func (h *delayedSequencerHarness) step(ctx context.Context) error {
+	h.inbox.syncFrom(h.l1)
+	return h.stepWithoutSync(ctx)
+}
+
+func (h *delayedSequencerHarness) stepWithoutSync(ctx context.Context) error {
+	head, err := h.l1.LastHeader(ctx)
+	if err != nil {
+		return err
+	}
+	return h.seq.trySequence(ctx, head)
+}
+
+func (h *delayedSequencerHarness) requireSequenced(expected ...byte) {
+	h.t.Helper()
+	got := h.exec.payloads()
+	if string(got) != string(expected) {
+		Fail(h.t, "sequenced delayed messages", got, "expected", expected)
+	}
+}
+
+func mergeFinalityConfig(requireFull bool) DelayedSequencerConfig {
+	config := TestDelayedSequencerConfig
+	config.UseMergeFinality = true
+	config.RequireFullFinality = requireFull
+	return config
+}
+
+func TestDelayedSequencerFinalizeDistance(t *testing.T) {
+	ctx := context.Background()
+	h := newDelayedSequencerHarness(t, false, TestDelayedSequencerConfig)
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 5, 1)
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 10, 2)
+
+	h.l1.setBlocks(15, 0, 0)
+	Require(t, h.step(ctx))
+	h.requireSequenced()
+
+	h.l1.setBlocks(25, 0, 0)
+	Require(t, h.step(ctx))
+	h.requireSequenced(1)
+
+	h.l1.setBlocks(30, 0, 0)
+	Require(t, h.step(ctx))
+	h.requireSequenced(1, 2)
+}
+
+func TestDelayedSequencerFinalityStall(t *testing.T) {
+	ctx := context.Background()
+	h := newDelayedSequencerHarness(t, true, mergeFinalityConfig(true))
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 5, 1)
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 12, 2)
+
+	h.l1.setBlocks(20, 15, 8)
+	Require(t, h.step(ctx))
+	h.requireSequenced(1)
+
+	// the head keeps moving but finality doesn't
+	for head := uint64(21); head < 100; head += 10 {
+		h.l1.setBlocks(head, head-2, 8)
+		Require(t, h.step(ctx))
+		h.requireSequenced(1)
+	}
+
+	h.l1.setBlocks(100, 98, 12)
+	Require(t, h.step(ctx))
+	h.requireSequenced(1, 2)
+}
+
+func TestDelayedSequencerSafeFinality(t *testing.T) {
+	ctx := context.Background()
+	h := newDelayedSequencerHarness(t, true, mergeFinalityConfig(false))
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 5, 1)
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 12, 2)
+
+	h.l1.setBlocks(20, 12, 4)
+	Require(t, h.step(ctx))
+	h.requireSequenced(1, 2)
+}
+
+func TestDelayedSequencerAccumulatorDivergence(t *testing.T) {
+	ctx := context.Background()
+	h := newDelayedSequencerHarness(t, true, mergeFinalityConfig(true))
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 5, 1)
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 6, 2)
+	h.l1.setBlocks(20, 15, 10)
+	h.inbox.syncFrom(h.l1)
+	h.inbox.corrupt(1)
+
+	if err := h.stepWithoutSync(ctx); err == nil {
+		Fail(t, "expected accumulator mismatch to be detected")
+	}
+	h.requireSequenced()
+
+	// once the inbox catches up with the bridge and a later block is finalized, sequencing resumes
+	h.l1.setBlocks(40, 35, 30)
+	Require(t, h.step(ctx))
+	h.requireSequenced(1, 2)
+}
+
+func TestDelayedSequencerReorg(t *testing.T) {
+	ctx := context.Background()
+	h := newDelayedSequencerHarness(t, true, mergeFinalityConfig(false))
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 5, 1)
+	h.l1.setBlocks(20, 15, 10)
+	Require(t, h.step(ctx))
+	h.requireSequenced(1)
+
+	// the inbox reader picked up a message that is then reorged out
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 16, 2)
+	h.l1.setBlocks(30, 25, 10)
+	h.inbox.syncFrom(h.l1)
+	h.l1.reorg(16)
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 17, 3)
+	if err := h.stepWithoutSync(ctx); err == nil {
+		Fail(t, "expected reorged delayed message to be rejected")
+	}
+	h.requireSequenced(1)
+
+	h.l1.setBlocks(40, 35, 30)
+	Require(t, h.step(ctx))
+	h.requireSequenced(1, 3)
+}
+
+func TestDelayedSequencerReorgWhileSequencing(t *testing.T) {
+	ctx := context.Background()
+	h := newDelayedSequencerHarness(t, true, mergeFinalityConfig(false))
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 5, 1)
+	h.l1.setBlocks(20, 15, 10)
+	// the safe block is reorged after the sequencer read its header
+	h.l1.beforeGetAccumulator = func() {
+		h.l1.beforeGetAccumulator = nil
+		h.l1.reorg(10)
+	}
+	h.inbox.syncFrom(h.l1)
+	if err := h.stepWithoutSync(ctx); err == nil {
+		Fail(t, "expected stale block hash to be rejected")
+	}
+	h.requireSequenced()
+
+	h.l1.setBlocks(30, 25, 20)
+	Require(t, h.step(ctx))
+	h.requireSequenced(1)
+}
+
+func TestDelayedSequencerFastDeposits(t *testing.T) {
+	ctx := context.Background()
+	config := mergeFinalityConfig(true)
+	config.FastDeposits = true
+	h := newDelayedSequencerHarness(t, true, config)
+	h.l1.postDelayed(arbostypes.L1MessageType_EthDeposit, 5, 1)
+	h.l1.postDelayed(arbostypes.L1MessageType_EthDeposit, 12, 2)
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 14, 3)
+	h.l1.postDelayed(arbostypes.L1MessageType_EthDeposit, 15, 4)
+
+	// the deposit at block 12 is only safe, but the message after it isn't finalized
+	h.l1.setBlocks(20, 16, 8)
+	Require(t, h.step(ctx))
+	h.requireSequenced(1, 2)
+
+	h.l1.setBlocks(30, 25, 16)
+	Require(t, h.step(ctx))
+	h.requireSequenced(1, 2, 3, 4)
+
+	policy := h.seq.FinalityPolicy()
+	if policy.Version != DelayedSequencerFinalityPolicyVersion || policy.Deposits == policy.Messages {
+		Fail(t, "unexpected finality policy", policy)
+	}
+	h.config.FastDeposits = false
+	policy = h.seq.FinalityPolicy()
+	if policy.Deposits != policy.Messages {
+		Fail(t, "deposits should follow the message policy without fast deposits", policy)
+	}
+}
+
+func TestDelayedSequencerRetryFromExecution(t *testing.T) {
+	ctx := context.Background()
+	h := newDelayedSequencerHarness(t, true, mergeFinalityConfig(true))
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 5, 1)
+	h.l1.setBlocks(20, 15, 10)
+	h.exec.err = execution.ErrRetrySequencer
+	if err := h.step(ctx); !errors.Is(err, execution.ErrRetrySequencer) {
+		Fail(t, "expected retry error, got", err)
+	}
+	h.exec.err = nil
+	h.l1.setBlocks(40, 35, 30)
+	Require(t, h.step(ctx))
+	h.requireSequenced(1)
+}
+
+func TestDelayedSequencerForceIncludeExpired(t *testing.T) {
+	ctx := context.Background()
+	h := newDelayedSequencerHarness(t, true, mergeFinalityConfig(true))
+	h.l1.delayBlocks = 20
+	h.l1.delaySeconds = 20
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 5, 1)
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 12, 2)
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 25, 3)
+
+	// finality stalls at block 8, and the second message isn't past the force inclusion window yet
+	h.l1.setBlocks(30, 28, 8)
+	Require(t, h.step(ctx))
+	h.requireSequenced(1)
+	result, err := h.seq.ForceIncludeExpired(ctx)
+	Require(t, err)
+	if result.Sequenced != 0 || result.ForceIncluded != 0 {
+		Fail(t, "force included messages inside the window", result)
+	}
+	h.requireSequenced(1)
+
+	// once the window passes, the message is only sequenced when forced
+	h.l1.setBlocks(40, 38, 8)
+	Require(t, h.step(ctx))
+	h.requireSequenced(1)
+	result, err = h.seq.ForceIncludeExpired(ctx)
+	Require(t, err)
+	if result.Sequenced != 1 || result.ForceIncluded != 1 {
+		Fail(t, "unexpected force inclusion result", result)
+	}
+	h.requireSequenced(1, 2)
+
+	// with force-include-expired set, the sequencer does it by itself
+	h.config.ForceIncludeExpired = true
+	h.l1.setBlocks(50, 48, 8)
+	Require(t, h.step(ctx))
+	h.requireSequenced(1, 2, 3)
+}
+
+func TestDelayedSequencerForceIncludeReorg(t *testing.T) {
+	ctx := context.Background()
+	config := mergeFinalityConfig(true)
+	config.ForceIncludeExpired = true
+	h := newDelayedSequencerHarness(t, true, config)
+	h.l1.delayBlocks = 10
+	h.l1.delaySeconds = 10
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 5, 1)
+	h.l1.setBlocks(30, 28, 2)
+	h.inbox.syncFrom(h.l1)
+	h.inbox.corrupt(0)
+
+	// the accumulator is checked against the bridge at the head before force including
+	if err := h.stepWithoutSync(ctx); err == nil {
+		Fail(t, "expected accumulator mismatch to be detected")
+	}
+	h.requireSequenced()
+
+	Require(t, h.step(ctx))
+	h.requireSequenced(1)
+}
+
+// fixedFinalityProvider is a custom finality source, like an external finality gadget.
+type fixedFinalityProvider struct {
+	finalized *FinalizedBlock
+	err       error
+}
+
+func (p *fixedFinalityProvider) Finalized(ctx context.Context, lastBlockHeader *types.Header) (*FinalizedBlock, error) {
+	return p.finalized, p.err
+}
+
+func (p *fixedFinalityProvider) Describe() string {
+	return "fixed"
+}
+
+func TestDelayedSequencerCustomFinalityProvider(t *testing.T) {
+	ctx := context.Background()
+	h := newDelayedSequencerHarness(t, true, mergeFinalityConfig(true))
+	h.l1.postDelayed(arbostypes.L1MessageType_EthDeposit, 5, 1)
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 10, 2)
+	h.l1.setBlocks(30, 25, 20)
+
+	gadget := &fixedFinalityProvider{}
+	h.seq.SetFinalityProvider(gadget)
+	Require(t, h.step(ctx))
+	h.requireSequenced()
+	if policy := h.seq.FinalityPolicy(); policy.Messages != "fixed" || policy.Deposits != "fixed" {
+		Fail(t, "finality policy doesn't describe the custom provider", policy)
+	}
+
+	gadget.finalized = &FinalizedBlock{Number: 7}
+	Require(t, h.step(ctx))
+	h.requireSequenced(1)
+
+	// two of three providers must agree a block is final
+	h.seq.SetFinalityProvider(&QuorumFinalityProvider{
+		Providers: []FinalityProvider{
+			&fixedFinalityProvider{finalized: &FinalizedBlock{Number: 12}},
+			&fixedFinalityProvider{err: errors.New("provider down")},
+			&DistanceFinalityProvider{Distance: 25},
+		},
+		Quorum: 2,
+	})
+	Require(t, h.step(ctx))
+	h.requireSequenced(1)
+
+	h.l1.setBlocks(40, 35, 30)
+	Require(t, h.step(ctx))
+	h.requireSequenced(1, 2)
+
+	h.seq.SetFinalityProvider(nil)
+	if policy := h.seq.FinalityPolicy(); policy != h.config.FinalityPolicy() {
+		Fail(t, "finality policy doesn't follow the config after removing the custom provider", policy)
+	}
+}
+
+func TestDelayedSequencerMaxMessagesPerSequence(t *testing.T) {
+	ctx := context.Background()
+	config := TestDelayedSequencerConfig
+	config.MaxMessagesPerSequence = 2
+	h := newDelayedSequencerHarness(t, false, config)
+	for i := byte(1); i <= 5; i++ {
+		h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 5, i)
+	}
+	h.l1.setBlocks(30, 0, 0)
+
+	// each update sequences a chunk of the backlog
+	Require(t, h.step(ctx))
+	h.requireSequenced(1, 2)
+	Require(t, h.step(ctx))
+	h.requireSequenced(1, 2, 3, 4)
+
+	// the run loop works through the rest of the backlog without waiting for new parent chain blocks
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 5, 6)
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 20, 7)
+	h.inbox.syncFrom(h.l1)
+	head, err := h.l1.LastHeader(ctx)
+	Require(t, err)
+	h.seq.sequenceBacklog(ctx, head)
+	h.requireSequenced(1, 2, 3, 4, 5, 6)
+}
+
+func TestDelayedSequencerLagHooks(t *testing.T) {
+	ctx := context.Background()
+	config := TestDelayedSequencerConfig
+	config.MaxMessagesPerSequence = 2
+	config.LagAlertThreshold = time.Hour
+	h := newDelayedSequencerHarness(t, false, config)
+	var alerts []DelayedSequencerLag
+	h.seq.AddLagHook(func(lag *DelayedSequencerLag) {
+		alerts = append(alerts, *lag)
+	})
+	for i := byte(1); i <= 4; i++ {
+		h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 5, i)
+	}
+	// not final yet, so not lagging no matter how old
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 25, 5)
+
+	h.l1.setBlocks(30, 0, 0)
+	Require(t, h.step(ctx))
+	h.requireSequenced(1, 2)
+	if len(alerts) != 1 || alerts[0].FinalizedUnsequenced != 2 || alerts[0].OldestUnsequencedAge < time.Hour {
+		Fail(t, "unexpected lag alerts", alerts)
+	}
+
+	// a failing delayed sequencer is still measured
+	h.exec.err = errors.New("execution down")
+	if err := h.step(ctx); err == nil {
+		Fail(t, "expected sequencing to fail")
+	}
+	if len(alerts) != 2 || alerts[1].FinalizedUnsequenced != 2 {
+		Fail(t, "unexpected lag alerts", alerts)
+	}
+
+	h.exec.err = nil
+	Require(t, h.step(ctx))
+	h.requireSequenced(1, 2, 3, 4)
+	if len(alerts) != 2 {
+		Fail(t, "alerted with only unfinalized messages left", alerts)
+	}
+}
+
+func TestDelayedSequencerFinalizeAfterSeconds(t *testing.T) {
+	ctx := context.Background()
+	config := TestDelayedSequencerConfig
+	config.FinalizeAfterSeconds = 100
+	h := newDelayedSequencerHarness(t, true, config)
+	// the simulated parent chain's timestamps equal its block numbers
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 50, 1)
+	h.l1.postDelayed(arbostypes.L1MessageType_EthDeposit, 120, 2)
+
+	// far more than the finalize distance of blocks, but not enough time has passed
+	h.l1.setBlocks(140, 140, 140)
+	Require(t, h.step(ctx))
+	h.requireSequenced()
+
+	h.l1.setBlocks(150, 150, 150)
+	Require(t, h.step(ctx))
+	h.requireSequenced(1)
+
+	h.l1.setBlocks(220, 220, 220)
+	Require(t, h.step(ctx))
+	h.requireSequenced(1, 2)
+
+	if policy := h.seq.FinalityPolicy(); policy.Messages != "100 seconds older than head" {
+		Fail(t, "unexpected finality policy", policy)
+	}
+}
+
+func TestDelayedSequencerDryRun(t *testing.T) {
+	ctx := context.Background()
+	config := TestDelayedSequencerConfig
+	config.DryRun = true
+	h := newDelayedSequencerHarness(t, false, config)
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 5, 1)
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 10, 2)
+
+	h.l1.setBlocks(25, 0, 0)
+	Require(t, h.step(ctx))
+	h.requireSequenced()
+	dryRun := h.seq.LastDryRun()
+	if dryRun == nil || dryRun.StartPos != 0 || dryRun.Count != 1 {
+		Fail(t, "unexpected dry run", dryRun)
+	}
+	pendingSince := dryRun.PendingSince
+
+	// still waiting for the active sequencer
+	h.l1.setBlocks(30, 0, 0)
+	Require(t, h.step(ctx))
+	h.requireSequenced()
+	dryRun = h.seq.LastDryRun()
+	if dryRun.Count != 2 || dryRun.PendingSince != pendingSince {
+		Fail(t, "unexpected dry run", dryRun)
+	}
+
+	// the accumulator is still checked against the bridge
+	h.inbox.corrupt(1)
+	if err := h.stepWithoutSync(ctx); err == nil {
+		Fail(t, "expected the dry run to detect the accumulator mismatch")
+	}
+	h.requireSequenced()
+
+	// once the dry run is turned off, the messages are sequenced
+	h.config.DryRun = false
+	Require(t, h.step(ctx))
+	h.requireSequenced(1, 2)
+}
+
+// recordingInboxLabeler records the labels delayed messages are reported under.
+type recordingInboxLabeler struct {
+	config *InboxReaderConfig
+	labels []string
+}
+
+func (l *recordingInboxLabeler) DelayedInboxLabel(inbox common.Address) string {
+	label := l.config.DelayedInboxLabel(inbox)
+	l.labels = append(l.labels, label)
+	return label
+}
+
+func TestDelayedSequencerMultipleInboxes(t *testing.T) {
+	ctx := context.Background()
+	h := newDelayedSequencerHarness(t, false, TestDelayedSequencerConfig)
+	publicInbox := common.HexToAddress("0x1001")
+	systemInbox := common.HexToAddress("0x1002")
+	otherInbox := common.HexToAddress("0x1003")
+	readerConfig := TestInboxReaderConfig
+	readerConfig.DelayedInboxes = []string{"public:" + publicInbox.Hex(), "system:" + systemInbox.Hex()}
+	Require(t, readerConfig.Validate())
+	labeler := &recordingInboxLabeler{config: &readerConfig}
+	h.seq.inboxLabels = labeler
+
+	// the bridge orders messages from all of its inboxes by when they were delivered
+	h.l1.postDelayedFrom(systemInbox, arbostypes.L1MessageType_L2Message, 5, 1)
+	h.l1.postDelayedFrom(publicInbox, arbostypes.L1MessageType_L2Message, 5, 2)
+	h.l1.postDelayedFrom(systemInbox, arbostypes.L1MessageType_L2Message, 6, 3)
+	h.l1.postDelayedFrom(otherInbox, arbostypes.L1MessageType_L2Message, 7, 4)
+	h.l1.postDelayed(arbostypes.L1MessageType_L2Message, 8, 5)
+	h.l1.setBlocks(30, 0, 0)
+	Require(t, h.step(ctx))
+	h.requireSequenced(1, 2, 3, 4, 5)
+
+	expected := []string{"system", "public", "system", "unlisted", "unknown"}
+	if len(labeler.labels) != len(expected) {
+		Fail(t, "sequenced delayed messages labeled", labeler.labels, "expected", expected)
+	}
+	for i := range expected {
+		if labeler.labels[i] != expected[i] {
+			Fail(t, "sequenced delayed messages labeled", labeler.labels, "expected", expected)
+		}
+	}
+
+	readerConfig.DelayedInboxes = nil
+	if label := readerConfig.DelayedInboxLabel(publicInbox); label != strings.ToLower(publicInbox.Hex()) {
+		Fail(t, "unexpected label without configured inboxes", label)
+	}
+	for _, invalid := range [][]string{{"public"}, {"public:0x12"}, {":" + publicInbox.Hex()}, {"public:" + publicInbox.Hex(), "public:" + systemInbox.Hex()}} {
+		readerConfig.DelayedInboxes = invalid
+		if readerConfig.Validate() == nil {
+			Fail(t, "invalid delayed inboxes accepted", invalid)
+		}
+	}
+}
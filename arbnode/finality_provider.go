@@ -0,0 +1,187 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/offchainlabs/nitro/util/headerreader"
+)
+
+// FinalizedBlock is a parent chain block the delayed sequencer may sequence messages up to.
+// The hash is optional; without it, the delayed bridge's accumulator is read by block number.
+type FinalizedBlock struct {
+	Number uint64
+	Hash   common.Hash
+	// If set, messages are only final if their parent chain timestamp is at or before it.
+	Timestamp uint64
+}
+
+// includes returns whether a message at the given parent chain block and timestamp is final.
+func (b *FinalizedBlock) includes(parentChainBlockNumber uint64, timestamp uint64) bool {
+	return parentChainBlockNumber <= b.Number && (b.Timestamp == 0 || timestamp <= b.Timestamp)
+}
+
+// FinalityProvider decides which parent chain blocks the delayed sequencer treats as final.
+// Operators whose parent chain needs a different notion of finality, e.g. an external finality
+// gadget or a quorum of RPC providers, can set their own with DelayedSequencer.SetFinalityProvider.
+type FinalityProvider interface {
+	// Finalized returns the latest final parent chain block given the latest header,
+	// or nil if no block is final yet.
+	Finalized(ctx context.Context, lastBlockHeader *types.Header) (*FinalizedBlock, error)
+	// Describe summarizes the rule, as reported by the delayed sequencer's finality policy.
+	Describe() string
+}
+
+// FinalityHeaderReader provides a parent chain's safe and finalized blocks.
+// headerreader.HeaderReader implements it.
+type FinalityHeaderReader interface {
+	LatestSafeBlockHeader(ctx context.Context) (*types.Header, error)
+	LatestFinalizedBlockHeader(ctx context.Context) (*types.Header, error)
+}
+
+// DistanceFinalityProvider treats blocks a fixed number of blocks behind the head as final.
+type DistanceFinalityProvider struct {
+	Distance int64
+}
+
+func (p *DistanceFinalityProvider) Finalized(ctx context.Context, lastBlockHeader *types.Header) (*FinalizedBlock, error) {
+	currentNum := lastBlockHeader.Number.Int64()
+	if currentNum < p.Distance {
+		return nil, nil
+	}
+	return &FinalizedBlock{Number: uint64(currentNum - p.Distance)}, nil
+}
+
+func (p *DistanceFinalityProvider) Describe() string {
+	return fmt.Sprintf("%d blocks behind head", p.Distance)
+}
+
+// MergeFinalityProvider uses the parent chain's safe or finalized block, falling back to another
+// provider if the parent chain doesn't support The Merge's notion of finality.
+type MergeFinalityProvider struct {
+	Reader      FinalityHeaderReader
+	RequireFull bool
+	Fallback    FinalityProvider
+}
+
+func (p *MergeFinalityProvider) Finalized(ctx context.Context, lastBlockHeader *types.Header) (*FinalizedBlock, error) {
+	if !headerreader.HeaderIndicatesFinalitySupport(lastBlockHeader) {
+		if p.Fallback == nil {
+			return nil, errors.New("parent chain doesn't support merge finality")
+		}
+		return p.Fallback.Finalized(ctx, lastBlockHeader)
+	}
+	var header *types.Header
+	var err error
+	if p.RequireFull {
+		header, err = p.Reader.LatestFinalizedBlockHeader(ctx)
+	} else {
+		header, err = p.Reader.LatestSafeBlockHeader(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &FinalizedBlock{Number: header.Number.Uint64(), Hash: header.Hash()}, nil
+}
+
+func (p *MergeFinalityProvider) Describe() string {
+	description := "safe"
+	if p.RequireFull {
+		description = "finalized"
+	}
+	if p.Fallback != nil {
+		description += " (or " + p.Fallback.Describe() + ")"
+	}
+	return description
+}
+
+// TimestampFinalityProvider treats messages as final once their parent chain timestamp is at least
+// Delay seconds older than the parent chain's head. Unlike a block distance, this doesn't depend on the
+// parent chain's block time, which is irregular for rollups.
+type TimestampFinalityProvider struct {
+	Delay uint64
+}
+
+func (p *TimestampFinalityProvider) Finalized(ctx context.Context, lastBlockHeader *types.Header) (*FinalizedBlock, error) {
+	if lastBlockHeader.Time <= p.Delay {
+		return nil, nil
+	}
+	return &FinalizedBlock{
+		Number:    lastBlockHeader.Number.Uint64(),
+		Hash:      lastBlockHeader.Hash(),
+		Timestamp: lastBlockHeader.Time - p.Delay,
+	}, nil
+}
+
+func (p *TimestampFinalityProvider) Describe() string {
+	return fmt.Sprintf("%d seconds older than head", p.Delay)
+}
+
+// QuorumFinalityProvider treats a block as final once at least Quorum of the providers do,
+// e.g. to require several independent RPC providers to agree on the parent chain's finality.
+// Providers that fail are logged and count as not having finalized anything.
+type QuorumFinalityProvider struct {
+	Providers []FinalityProvider
+	Quorum    int
+}
+
+func (p *QuorumFinalityProvider) Finalized(ctx context.Context, lastBlockHeader *types.Header) (*FinalizedBlock, error) {
+	if p.Quorum <= 0 || p.Quorum > len(p.Providers) {
+		return nil, fmt.Errorf("invalid finality quorum %v of %v providers", p.Quorum, len(p.Providers))
+	}
+	var blocks []*FinalizedBlock
+	for i, provider := range p.Providers {
+		block, err := provider.Finalized(ctx, lastBlockHeader)
+		if err != nil {
+			log.Warn("finality provider failed", "provider", i, "err", err)
+			continue
+		}
+		if block != nil {
+			blocks = append(blocks, block)
+		}
+	}
+	if len(blocks) < p.Quorum {
+		return nil, nil
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Number > blocks[j].Number })
+	// providers may be on different forks, so only the number is agreed on; a timestamp bound is
+	// taken from the provider at the quorum position
+	return &FinalizedBlock{Number: blocks[p.Quorum-1].Number, Timestamp: blocks[p.Quorum-1].Timestamp}, nil
+}
+
+func (p *QuorumFinalityProvider) Describe() string {
+	description := fmt.Sprintf("final according to %v of", p.Quorum)
+	for i, provider := range p.Providers {
+		if i > 0 {
+			description += ","
+		}
+		description += " [" + provider.Describe() + "]"
+	}
+	return description
+}
+
+// finalityProviders builds the providers for delayed messages and ETH deposits from the config.
+func (c *DelayedSequencerConfig) finalityProviders(reader FinalityHeaderReader) (FinalityProvider, FinalityProvider) {
+	if c.FinalizeAfterSeconds != 0 {
+		timestamp := &TimestampFinalityProvider{Delay: c.FinalizeAfterSeconds}
+		return timestamp, timestamp
+	}
+	distance := &DistanceFinalityProvider{Distance: c.FinalizeDistance}
+	if !c.UseMergeFinality {
+		return distance, distance
+	}
+	messages := &MergeFinalityProvider{Reader: reader, RequireFull: c.RequireFullFinality, Fallback: distance}
+	if c.fastDepositsApply() {
+		return messages, &MergeFinalityProvider{Reader: reader, RequireFull: false, Fallback: distance}
+	}
+	return messages, messages
+}
@@ -15,6 +15,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	flag "github.com/spf13/pflag"
 
 	"github.com/offchainlabs/nitro/arbutil"
@@ -24,14 +25,17 @@ import (
 )
 
 type InboxReaderConfig struct {
-	DelayBlocks         uint64        `koanf:"delay-blocks" reload:"hot"`
-	CheckDelay          time.Duration `koanf:"check-delay" reload:"hot"`
-	HardReorg           bool          `koanf:"hard-reorg" reload:"hot"`
-	MinBlocksToRead     uint64        `koanf:"min-blocks-to-read" reload:"hot"`
-	DefaultBlocksToRead uint64        `koanf:"default-blocks-to-read" reload:"hot"`
-	TargetMessagesRead  uint64        `koanf:"target-messages-read" reload:"hot"`
-	MaxBlocksToRead     uint64        `koanf:"max-blocks-to-read" reload:"hot"`
-	ReadMode            string        `koanf:"read-mode" reload:"hot"`
+	DelayBlocks         uint64                    `koanf:"delay-blocks" reload:"hot"`
+	CheckDelay          time.Duration             `koanf:"check-delay" reload:"hot"`
+	HardReorg           bool                      `koanf:"hard-reorg" reload:"hot"`
+	MinBlocksToRead     uint64                    `koanf:"min-blocks-to-read" reload:"hot"`
+	DefaultBlocksToRead uint64                    `koanf:"default-blocks-to-read" reload:"hot"`
+	TargetMessagesRead  uint64                    `koanf:"target-messages-read" reload:"hot"`
+	MaxBlocksToRead     uint64                    `koanf:"max-blocks-to-read" reload:"hot"`
+	ReadMode            string                    `koanf:"read-mode" reload:"hot"`
+	LogQuery            InboxReaderLogQueryConfig `koanf:"log-query" reload:"hot"`
+	// name:address pairs, labeling the inbox contracts delivering delayed messages to the bridge
+	DelayedInboxes []string `koanf:"delayed-inboxes" reload:"hot"`
 }
 
 type InboxReaderConfigFetcher func() *InboxReaderConfig
@@ -44,7 +48,36 @@ func (c *InboxReaderConfig) Validate() error {
 	if c.ReadMode != "latest" && c.ReadMode != "safe" && c.ReadMode != "finalized" {
 		return fmt.Errorf("inbox reader read-mode is invalid, want: latest or safe or finalized, got: %s", c.ReadMode)
 	}
-	return nil
+	names := make(map[string]struct{}, len(c.DelayedInboxes))
+	for _, entry := range c.DelayedInboxes {
+		name, address, found := strings.Cut(entry, ":")
+		if !found || name == "" || !common.IsHexAddress(address) {
+			return fmt.Errorf("inbox reader delayed-inboxes entry %q is invalid, want name:address", entry)
+		}
+		if _, ok := names[name]; ok {
+			return fmt.Errorf("inbox reader delayed-inboxes name %q is used more than once", name)
+		}
+		names[name] = struct{}{}
+	}
+	return c.LogQuery.Validate()
+}
+
+// DelayedInboxLabel returns the name to report delayed messages from the inbox contract under.
+// Without any delayed-inboxes configured, inboxes are reported by address.
+func (c *InboxReaderConfig) DelayedInboxLabel(inbox common.Address) string {
+	if inbox == (common.Address{}) {
+		return "unknown"
+	}
+	if len(c.DelayedInboxes) == 0 {
+		return strings.ToLower(inbox.Hex())
+	}
+	for _, entry := range c.DelayedInboxes {
+		name, address, _ := strings.Cut(entry, ":")
+		if common.HexToAddress(address) == inbox {
+			return name
+		}
+	}
+	return "unlisted"
 }
 
 func InboxReaderConfigAddOptions(prefix string, f *flag.FlagSet) {
@@ -56,6 +89,8 @@ func InboxReaderConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Uint64(prefix+".target-messages-read", DefaultInboxReaderConfig.TargetMessagesRead, "if adjust-blocks-to-read is enabled, the target number of messages to read at once")
 	f.Uint64(prefix+".max-blocks-to-read", DefaultInboxReaderConfig.MaxBlocksToRead, "if adjust-blocks-to-read is enabled, the maximum number of blocks to read at once")
 	f.String(prefix+".read-mode", DefaultInboxReaderConfig.ReadMode, "mode to only read latest or safe or finalized L1 blocks. Enabling safe or finalized disables feed input and output. Defaults to latest. Takes string input, valid strings- latest, safe, finalized")
+	InboxReaderLogQueryConfigAddOptions(prefix+".log-query", f)
+	f.StringSlice(prefix+".delayed-inboxes", DefaultInboxReaderConfig.DelayedInboxes, "name:address pairs of the inbox contracts expected to deliver delayed messages to the bridge, used to label per-inbox metrics (messages from other inboxes are reported as unlisted)")
 }
 
 var DefaultInboxReaderConfig = InboxReaderConfig{
@@ -67,6 +102,7 @@ var DefaultInboxReaderConfig = InboxReaderConfig{
 	TargetMessagesRead:  500,
 	MaxBlocksToRead:     2000,
 	ReadMode:            "latest",
+	LogQuery:            DefaultInboxReaderLogQueryConfig,
 }
 
 var TestInboxReaderConfig = InboxReaderConfig{
@@ -78,6 +114,7 @@ var TestInboxReaderConfig = InboxReaderConfig{
 	TargetMessagesRead:  500,
 	MaxBlocksToRead:     2000,
 	ReadMode:            "latest",
+	LogQuery:            DefaultInboxReaderLogQueryConfig,
 }
 
 type InboxReader struct {
@@ -87,6 +124,7 @@ type InboxReader struct {
 	caughtUp          bool
 	firstMessageBlock *big.Int
 	config            InboxReaderConfigFetcher
+	logQueryCeiling   uint64 // the most blocks a log query has been found to succeed with, or 0 if unlimited
 
 	// Thread safe
 	tracker        *InboxTracker
@@ -176,6 +214,30 @@ func (r *InboxReader) Start(ctxIn context.Context) error {
 	return nil
 }
 
+// confirmArchivedInbox checks the accumulators of a message archive's last batch and delayed message against the
+// sequencer inbox and bridge at the parent chain's latest block.
+func (r *InboxReader) confirmArchivedInbox(ctx context.Context, lastBatch uint64, batchAcc common.Hash, lastDelayed uint64, delayedAcc common.Hash) error {
+	header, err := r.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return err
+	}
+	haveBatchAcc, err := r.sequencerInbox.GetAccumulator(ctx, lastBatch, header.Number)
+	if err != nil {
+		return err
+	}
+	if haveBatchAcc != batchAcc {
+		return fmt.Errorf("batch %v has accumulator %v on the parent chain, not %v", lastBatch, haveBatchAcc, batchAcc)
+	}
+	haveDelayedAcc, err := r.delayedBridge.GetAccumulator(ctx, lastDelayed, header.Number, header.Hash())
+	if err != nil {
+		return err
+	}
+	if haveDelayedAcc != delayedAcc {
+		return fmt.Errorf("delayed message %v has accumulator %v on the parent chain, not %v", lastDelayed, haveDelayedAcc, delayedAcc)
+	}
+	return nil
+}
+
 // assumes l1block is recent so we could do a simple-search from the end
 func (r *InboxReader) recentParentChainBlockToMsg(ctx context.Context, parentChainBlock uint64) (arbutil.MessageIndex, error) {
 	batch, err := r.tracker.GetBatchCount()
@@ -224,6 +286,10 @@ func (r *InboxReader) DelayedBridge() *DelayedBridge {
 	return r.delayedBridge
 }
 
+func (r *InboxReader) SequencerInbox() *SequencerInbox {
+	return r.sequencerInbox
+}
+
 func (r *InboxReader) CaughtUp() chan struct{} {
 	return r.caughtUpChan
 }
@@ -236,6 +302,8 @@ func (r *InboxReader) run(ctx context.Context, hadError bool) error {
 	}
 	newHeaders, unsubscribe := r.l1Reader.Subscribe(false)
 	defer unsubscribe()
+	prefetcher := newInboxPrefetcher(ctx, r)
+	defer prefetcher.stop()
 	blocksToFetch := r.config().DefaultBlocksToRead
 	if hadError {
 		blocksToFetch = 1
@@ -427,28 +495,23 @@ func (r *InboxReader) run(ctx context.Context, hadError bool) error {
 					from = new(big.Int).Set(currentHeight)
 				}
 			}
+			blocksToFetch = arbmath.MinInt(blocksToFetch, r.logQueryLimit(config))
 			to := new(big.Int).Add(from, new(big.Int).SetUint64(blocksToFetch))
 			if to.Cmp(currentHeight) > 0 {
 				to.Set(currentHeight)
 			}
-			sequencerBatches, err := r.sequencerInbox.LookupBatchesInRange(ctx, from, to)
-			if err != nil {
-				return err
-			}
-			delayedMessages, err := r.delayedBridge.LookupMessagesInRange(ctx, from, to, func(batchNum uint64) ([]byte, error) {
-				if len(sequencerBatches) > 0 && batchNum >= sequencerBatches[0].SequenceNumber {
-					idx := int(batchNum - sequencerBatches[0].SequenceNumber)
-					if idx < len(sequencerBatches) {
-						return sequencerBatches[idx].Serialize(ctx, r.l1Reader.Client())
-					}
-					log.Warn("missing mentioned batch in L1 message lookup", "batch", batchNum)
+			fetched := prefetcher.fetch(ctx, from, to)
+			if fetched.err != nil {
+				if r.shrinkLogQuery(config, fetched.err, new(big.Int).Sub(fetched.to, fetched.from).Uint64()+1) {
+					prefetcher.reset()
+					continue
 				}
-				data, _, err := r.GetSequencerMessageBytes(ctx, batchNum)
-				return data, err
-			})
-			if err != nil {
-				return err
+				return fetched.err
 			}
+			r.growLogQuery(config)
+			to = fetched.to
+			sequencerBatches := fetched.batches
+			delayedMessages := fetched.delayed
 			if !r.caughtUp && to.Cmp(currentHeight) == 0 && readMode == "latest" {
 				r.caughtUp = true
 				close(r.caughtUpChan)
@@ -542,6 +605,11 @@ func (r *InboxReader) run(ctx context.Context, hadError bool) error {
 			} else {
 				from = arbmath.BigAddByUint(to, 1)
 			}
+			if reorgingDelayed || reorgingSequencer {
+				prefetcher.reset()
+			} else if config.LogQuery.MaxParallel > 1 {
+				prefetcher.schedule(from, currentHeight, arbmath.MinInt(blocksToFetch, r.logQueryLimit(config)), config.LogQuery.MaxParallel)
+			}
 			haveMessages := uint64(len(delayedMessages) + len(sequencerBatches))
 			if haveMessages <= (config.TargetMessagesRead / 2) {
 				blocksToFetch += (blocksToFetch + 4) / 5
@@ -564,10 +632,15 @@ func (r *InboxReader) run(ctx context.Context, hadError bool) error {
 }
 
 func (r *InboxReader) addMessages(ctx context.Context, sequencerBatches []*SequencerInboxBatch, delayedMessages []*DelayedInboxMessage) (bool, error) {
-	err := r.tracker.AddDelayedMessages(delayedMessages, r.config().HardReorg)
+	prevDelayedCount, err := r.tracker.GetDelayedCount()
 	if err != nil {
 		return false, err
 	}
+	err = r.tracker.AddDelayedMessages(delayedMessages, r.config().HardReorg)
+	if err != nil {
+		return false, err
+	}
+	r.countDelayedMessages(delayedMessages, prevDelayedCount)
 	err = r.tracker.AddSequencerBatches(ctx, r.client, sequencerBatches)
 	if errors.Is(err, delayedMessagesMismatch) {
 		return true, nil
@@ -577,6 +650,27 @@ func (r *InboxReader) addMessages(ctx context.Context, sequencerBatches []*Seque
 	return false, nil
 }
 
+// countDelayedMessages updates the per-inbox metrics for the delayed messages not read before.
+func (r *InboxReader) countDelayedMessages(delayedMessages []*DelayedInboxMessage, prevDelayedCount uint64) {
+	config := r.config()
+	for _, message := range delayedMessages {
+		seqNum, err := message.Message.Header.SeqNum()
+		if err != nil || seqNum < prevDelayedCount {
+			continue
+		}
+		label := config.DelayedInboxLabel(message.Inbox)
+		if label == "unlisted" {
+			log.Warn("delayed message from an inbox not in the configured delayed inboxes", "inbox", message.Inbox, "seqNum", seqNum)
+		}
+		metrics.GetOrRegisterCounter("arb/inbox/delayed/"+label+"/messages", nil).Inc(1)
+	}
+}
+
+// DelayedInboxLabel returns the name delayed messages from the inbox contract are reported under.
+func (r *InboxReader) DelayedInboxLabel(inbox common.Address) string {
+	return r.config().DelayedInboxLabel(inbox)
+}
+
 func (r *InboxReader) getPrevBlockForReorg(from *big.Int) (*big.Int, error) {
 	if from.Cmp(r.firstMessageBlock) <= 0 {
 		return nil, errors.New("can't get older messages")
@@ -0,0 +1,225 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/util/arbmath"
+)
+
+var (
+	logQueryShrinkCounter = metrics.NewRegisteredCounter("arb/inboxreader/logquery/shrink", nil)
+	logQueryCeilingGauge  = metrics.NewRegisteredGauge("arb/inboxreader/logquery/ceiling", nil)
+)
+
+// InboxReaderLogQueryConfig configures how the inbox reader splits its log queries to fit the parent chain
+// provider's limits, and how many it makes in parallel while catching up.
+type InboxReaderLogQueryConfig struct {
+	MaxBlocks            uint64   `koanf:"max-blocks" reload:"hot"`
+	TooManyResultsErrors string   `koanf:"too-many-results-errors" reload:"hot"`
+	ProviderLimits       []string `koanf:"provider-limits" reload:"hot"`
+	MaxParallel          int      `koanf:"max-parallel" reload:"hot"`
+
+	tooManyResults *regexp.Regexp
+	providerLimits []providerLogQueryLimit
+}
+
+// providerLogQueryLimit is the block range a provider limits log queries to, identified by its error message.
+type providerLogQueryLimit struct {
+	errorSubstring string
+	maxBlocks      uint64
+}
+
+var DefaultInboxReaderLogQueryConfig = InboxReaderLogQueryConfig{
+	MaxBlocks:            0,
+	TooManyResultsErrors: "(?i)(too many results|returned more than|response size exceeded|exceed(s|ed)? (the )?max(imum)? block range|block range (is )?too (large|wide)|range limit exceeded|limited to a [0-9,]+ (block )?range)",
+	ProviderLimits:       []string{},
+	MaxParallel:          1,
+}
+
+func InboxReaderLogQueryConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Uint64(prefix+".max-blocks", DefaultInboxReaderLogQueryConfig.MaxBlocks, "the maximum number of blocks the parent chain provider allows in a log query (0 for no limit)")
+	f.String(prefix+".too-many-results-errors", DefaultInboxReaderLogQueryConfig.TooManyResultsErrors, "regular expression of log query errors that mean the query should be split into smaller ranges")
+	f.StringSlice(prefix+".provider-limits", DefaultInboxReaderLogQueryConfig.ProviderLimits, "log query block range limits of parent chain providers, as error-substring=max-blocks, applied when a log query error contains the substring")
+	f.Int(prefix+".max-parallel", DefaultInboxReaderLogQueryConfig.MaxParallel, "the maximum number of log query ranges to fetch in parallel while catching up")
+}
+
+func (c *InboxReaderLogQueryConfig) Validate() error {
+	if c.MaxParallel < 1 {
+		return fmt.Errorf("inbox reader log-query.max-parallel must be at least 1, got %v", c.MaxParallel)
+	}
+	var err error
+	c.tooManyResults, err = regexp.Compile(c.TooManyResultsErrors)
+	if err != nil {
+		return fmt.Errorf("inbox reader log-query.too-many-results-errors is invalid: %w", err)
+	}
+	c.providerLimits = nil
+	for _, limit := range c.ProviderLimits {
+		substring, blocks, ok := strings.Cut(limit, "=")
+		maxBlocks, err := strconv.ParseUint(blocks, 10, 64)
+		if !ok || substring == "" || err != nil || maxBlocks == 0 {
+			return fmt.Errorf("inbox reader log-query.provider-limits entry %q isn't error-substring=max-blocks", limit)
+		}
+		c.providerLimits = append(c.providerLimits, providerLogQueryLimit{substring, maxBlocks})
+	}
+	return nil
+}
+
+// logQueryLimit returns the most blocks a log query may span past its first block, given the learned ceiling.
+func (r *InboxReader) logQueryLimit(config *InboxReaderConfig) uint64 {
+	limit := config.MaxBlocksToRead
+	if config.LogQuery.MaxBlocks > 0 {
+		limit = arbmath.MinInt(limit, config.LogQuery.MaxBlocks-1)
+	}
+	if r.logQueryCeiling > 0 {
+		limit = arbmath.MinInt(limit, r.logQueryCeiling-1)
+	}
+	return limit
+}
+
+// shrinkLogQuery lowers the learned ceiling after a log query of the given number of blocks failed, returning
+// false if the error isn't about the query's size or the query can't be any smaller.
+func (r *InboxReader) shrinkLogQuery(config *InboxReaderConfig, err error, blocks uint64) bool {
+	if err == nil || errors.Is(err, context.Canceled) || blocks <= 1 {
+		return false
+	}
+	message := err.Error()
+	ceiling := uint64(0)
+	for _, limit := range config.LogQuery.providerLimits {
+		if strings.Contains(message, limit.errorSubstring) && limit.maxBlocks < blocks {
+			ceiling = limit.maxBlocks
+			break
+		}
+	}
+	if ceiling == 0 {
+		if config.LogQuery.tooManyResults == nil || !config.LogQuery.tooManyResults.MatchString(message) {
+			return false
+		}
+		ceiling = blocks / 2
+	}
+	r.logQueryCeiling = ceiling
+	logQueryShrinkCounter.Inc(1)
+	logQueryCeilingGauge.Update(int64(ceiling))
+	log.Info("parent chain log query too large, splitting it", "blocks", blocks, "ceiling", ceiling, "err", err)
+	return true
+}
+
+// growLogQuery raises the learned ceiling after a successful log query, so it recovers from temporary limits.
+func (r *InboxReader) growLogQuery(config *InboxReaderConfig) {
+	if r.logQueryCeiling == 0 {
+		return
+	}
+	r.logQueryCeiling += (r.logQueryCeiling + 9) / 10
+	if r.logQueryCeiling > config.MaxBlocksToRead {
+		r.logQueryCeiling = 0
+	}
+	logQueryCeilingGauge.Update(int64(r.logQueryCeiling))
+}
+
+// inboxRangeFetch is a lookup of the sequencer batches and delayed messages in a block range.
+type inboxRangeFetch struct {
+	from    *big.Int
+	to      *big.Int
+	done    chan struct{}
+	batches []*SequencerInboxBatch
+	delayed []*DelayedInboxMessage
+	err     error
+}
+
+func (r *InboxReader) lookupRange(ctx context.Context, fetch *inboxRangeFetch) {
+	defer close(fetch.done)
+	fetch.batches, fetch.err = r.sequencerInbox.LookupBatchesInRange(ctx, fetch.from, fetch.to)
+	if fetch.err != nil {
+		return
+	}
+	fetch.delayed, fetch.err = r.delayedBridge.LookupMessagesInRange(ctx, fetch.from, fetch.to, func(batchNum uint64) ([]byte, error) {
+		if len(fetch.batches) > 0 && batchNum >= fetch.batches[0].SequenceNumber {
+			idx := int(batchNum - fetch.batches[0].SequenceNumber)
+			if idx < len(fetch.batches) {
+				return fetch.batches[idx].Serialize(ctx, r.l1Reader.Client())
+			}
+			log.Warn("missing mentioned batch in L1 message lookup", "batch", batchNum)
+		}
+		data, _, err := r.GetSequencerMessageBytes(ctx, batchNum)
+		return data, err
+	})
+}
+
+// inboxPrefetcher looks up the ranges after the one being processed in the background while catching up.
+// It's only used by the run thread.
+type inboxPrefetcher struct {
+	reader  *InboxReader
+	parent  context.Context
+	ctx     context.Context
+	cancel  context.CancelFunc
+	pending []*inboxRangeFetch // consecutive ranges, in order
+}
+
+func newInboxPrefetcher(ctx context.Context, reader *InboxReader) *inboxPrefetcher {
+	p := &inboxPrefetcher{reader: reader, parent: ctx}
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	return p
+}
+
+// fetch returns the lookup of the range starting at from, which if prefetched may end somewhere other than to.
+func (p *inboxPrefetcher) fetch(ctx context.Context, from *big.Int, to *big.Int) *inboxRangeFetch {
+	if len(p.pending) > 0 && p.pending[0].from.Cmp(from) == 0 {
+		fetch := p.pending[0]
+		p.pending = p.pending[1:]
+		select {
+		case <-fetch.done:
+			return fetch
+		case <-ctx.Done():
+			return &inboxRangeFetch{from: from, to: to, err: ctx.Err()}
+		}
+	}
+	p.reset()
+	fetch := &inboxRangeFetch{from: from, to: to, done: make(chan struct{})}
+	p.reader.lookupRange(ctx, fetch)
+	return fetch
+}
+
+// schedule starts looking up ranges of blocks past their first block from next, up to the limit of parallel lookups.
+func (p *inboxPrefetcher) schedule(next *big.Int, currentHeight *big.Int, blocks uint64, parallel int) {
+	if len(p.pending) > 0 {
+		next = arbmath.BigAddByUint(p.pending[len(p.pending)-1].to, 1)
+	}
+	// the range being processed counts towards the limit
+	for len(p.pending) < parallel-1 && next.Cmp(currentHeight) <= 0 {
+		to := arbmath.BigAddByUint(next, blocks)
+		if to.Cmp(currentHeight) > 0 {
+			to = new(big.Int).Set(currentHeight)
+		}
+		fetch := &inboxRangeFetch{from: next, to: to, done: make(chan struct{})}
+		p.pending = append(p.pending, fetch)
+		go p.reader.lookupRange(p.ctx, fetch)
+		next = arbmath.BigAddByUint(to, 1)
+	}
+}
+
+// reset drops any prefetched ranges, as after a reorg or when the ranges need splitting.
+func (p *inboxPrefetcher) reset() {
+	if len(p.pending) == 0 {
+		return
+	}
+	p.cancel()
+	p.pending = nil
+	p.ctx, p.cancel = context.WithCancel(p.parent)
+}
+
+func (p *inboxPrefetcher) stop() {
+	p.cancel()
+	p.pending = nil
+}
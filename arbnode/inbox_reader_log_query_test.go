@@ -0,0 +1,55 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLogQueryLimit(t *testing.T) {
+	config := DefaultInboxReaderConfig
+	config.LogQuery.ProviderLimits = []string{"limited to a 10,000 range=10000"}
+	Require(t, config.Validate())
+	r := &InboxReader{}
+
+	if limit := r.logQueryLimit(&config); limit != config.MaxBlocksToRead {
+		t.Fatalf("unexpected initial limit %v", limit)
+	}
+	if r.shrinkLogQuery(&config, errors.New("connection refused"), 2000) {
+		t.Fatal("shrank the log query on an unrelated error")
+	}
+	if !r.shrinkLogQuery(&config, errors.New("query returned more than 10000 results"), 2001) {
+		t.Fatal("didn't shrink the log query on a too many results error")
+	}
+	if limit := r.logQueryLimit(&config); limit != 999 {
+		t.Fatalf("expected the log query to halve, got limit %v", limit)
+	}
+	for i := 0; i < 100 && r.logQueryCeiling != 0; i++ {
+		r.growLogQuery(&config)
+	}
+	if r.logQueryCeiling != 0 {
+		t.Fatalf("log query ceiling didn't grow back, got %v", r.logQueryCeiling)
+	}
+	if r.shrinkLogQuery(&config, errors.New("query returned more than 10000 results"), 1) {
+		t.Fatal("shrank a single block log query")
+	}
+
+	config.MaxBlocksToRead = 20000
+	if !r.shrinkLogQuery(&config, errors.New("eth_getLogs is limited to a 10,000 range"), 15000) {
+		t.Fatal("didn't shrink the log query on a provider limit error")
+	}
+	if limit := r.logQueryLimit(&config); limit != 9999 {
+		t.Fatalf("expected the provider's limit, got limit %v", limit)
+	}
+	config.LogQuery.MaxBlocks = 500
+	if limit := r.logQueryLimit(&config); limit != 499 {
+		t.Fatalf("expected the configured limit, got limit %v", limit)
+	}
+
+	config.LogQuery.ProviderLimits = []string{"missing-limit"}
+	if err := config.Validate(); err == nil {
+		t.Fatal("accepted an invalid provider limit")
+	}
+}
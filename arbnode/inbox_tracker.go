@@ -26,6 +26,7 @@ import (
 	m "github.com/offchainlabs/nitro/broadcaster/message"
 	"github.com/offchainlabs/nitro/staker"
 	"github.com/offchainlabs/nitro/util/containers"
+	"github.com/offchainlabs/nitro/util/dbutil"
 )
 
 var (
@@ -43,6 +44,8 @@ type InboxTracker struct {
 
 	batchMetaMutex sync.Mutex
 	batchMeta      *containers.LruCache[uint64, BatchMetadata]
+
+	inclusionStats *InclusionStats
 }
 
 func NewInboxTracker(db ethdb.Database, txStreamer *TransactionStreamer, dapReaders []daprovider.Reader, snapSyncConfig SnapSyncConfig) (*InboxTracker, error) {
@@ -52,6 +55,7 @@ func NewInboxTracker(db ethdb.Database, txStreamer *TransactionStreamer, dapRead
 		dapReaders:     dapReaders,
 		batchMeta:      containers.NewLruCache[uint64, BatchMetadata](1000),
 		snapSyncConfig: snapSyncConfig,
+		inclusionStats: NewInclusionStats(),
 	}
 	return tracker, nil
 }
@@ -225,6 +229,32 @@ func (t *InboxTracker) GetBatchCount() (uint64, error) {
 	return count, nil
 }
 
+// GetFirstRetainedBatch returns the lowest batch whose metadata hasn't been pruned, or 0 if none was pruned.
+func (t *InboxTracker) GetFirstRetainedBatch() (uint64, error) {
+	hasKey, err := t.db.Has(firstRetainedBatchKey)
+	if err != nil || !hasKey {
+		return 0, err
+	}
+	data, err := t.db.Get(firstRetainedBatchKey)
+	if err != nil {
+		return 0, err
+	}
+	var batch uint64
+	err = rlp.DecodeBytes(data, &batch)
+	if err != nil {
+		return 0, err
+	}
+	return batch, nil
+}
+
+func (t *InboxTracker) setFirstRetainedBatch(batch uint64) error {
+	data, err := rlp.EncodeToBytes(batch)
+	if err != nil {
+		return err
+	}
+	return t.db.Put(firstRetainedBatchKey, data)
+}
+
 // err will return unexpected/internal errors
 // bool will be false if batch not found (meaning, block not yet posted on a batch)
 func (t *InboxTracker) FindInboxBatchContainingMessage(pos arbutil.MessageIndex) (uint64, bool, error) {
@@ -232,6 +262,9 @@ func (t *InboxTracker) FindInboxBatchContainingMessage(pos arbutil.MessageIndex)
 	if err != nil {
 		return 0, false, err
 	}
+	if batchCount == 0 {
+		return 0, false, nil
+	}
 	low := uint64(0)
 	high := batchCount - 1
 	lastBatchMessageCount, err := t.GetBatchMessageCount(high)
@@ -241,6 +274,21 @@ func (t *InboxTracker) FindInboxBatchContainingMessage(pos arbutil.MessageIndex)
 	if lastBatchMessageCount <= pos {
 		return 0, false, nil
 	}
+	firstRetained, err := t.GetFirstRetainedBatch()
+	if err != nil {
+		return 0, false, err
+	}
+	if firstRetained > 0 {
+		// the metadata of batches before the first retained one has been pruned
+		firstRetainedCount, err := t.GetBatchMessageCount(firstRetained)
+		if err != nil {
+			return 0, false, err
+		}
+		if firstRetainedCount > pos {
+			return 0, false, fmt.Errorf("batch metadata containing message %v has been pruned, first retained batch is %v with message count %v", pos, firstRetained, firstRetainedCount)
+		}
+		low = firstRetained + 1
+	}
 	// Iteration preconditions:
 	// - high >= low
 	// - msgCount(low - 1) <= pos implies low <= target
@@ -390,6 +438,19 @@ func (t *InboxTracker) GetDelayedMessageAccumulatorAndParentChainBlockNumber(ctx
 
 }
 
+// GetDelayedMessageInbox returns the inbox contract that delivered the delayed message to the bridge,
+// or the zero address if it wasn't recorded, as for messages read by older versions.
+func (t *InboxTracker) GetDelayedMessageInbox(seqNum uint64) (common.Address, error) {
+	data, err := t.db.Get(dbKey(delayedMessageInboxPrefix, seqNum))
+	if err != nil {
+		if dbutil.IsErrNotFound(err) {
+			return common.Address{}, nil
+		}
+		return common.Address{}, err
+	}
+	return common.BytesToAddress(data), nil
+}
+
 func (t *InboxTracker) GetDelayedMessage(ctx context.Context, seqNum uint64) (*arbostypes.L1IncomingMessage, error) {
 	msg, _, _, err := t.GetDelayedMessageAccumulatorAndParentChainBlockNumber(ctx, seqNum)
 	return msg, err
@@ -502,6 +563,13 @@ func (t *InboxTracker) AddDelayedMessages(messages []*DelayedInboxMessage, hardR
 			}
 		}
 
+		if message.Inbox != (common.Address{}) {
+			err = batch.Put(dbKey(delayedMessageInboxPrefix, seqNum), message.Inbox.Bytes())
+			if err != nil {
+				return err
+			}
+		}
+
 		pos++
 	}
 
@@ -520,6 +588,10 @@ func (t *InboxTracker) setDelayedCountReorgAndWriteBatch(batch ethdb.Batch, newD
 	if err != nil {
 		return err
 	}
+	err = deleteStartingAt(t.db, batch, delayedMessageInboxPrefix, uint64ToKey(newDelayedCount))
+	if err != nil {
+		return err
+	}
 	err = deleteStartingAt(t.db, batch, legacyDelayedMessagePrefix, uint64ToKey(newDelayedCount))
 	if err != nil {
 		return err
@@ -831,6 +903,10 @@ func (t *InboxTracker) AddSequencerBatches(ctx context.Context, client arbutil.L
 	}
 	t.batchMetaMutex.Unlock()
 
+	if err := t.recordInclusions(ctx, batches, prevbatchmeta.DelayedMessageCount); err != nil {
+		log.Warn("error recording how delayed messages were included", "err", err)
+	}
+
 	if t.txStreamer.broadcastServer != nil && pos > 1 {
 		prevprevbatchmeta, err := t.GetBatchMetadata(pos - 2)
 		if errors.Is(err, AccumulatorNotFoundErr) {
@@ -870,9 +946,16 @@ func (t *InboxTracker) ReorgBatchesTo(count uint64) error {
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
 
+	firstRetained, err := t.GetFirstRetainedBatch()
+	if err != nil {
+		return err
+	}
+	if firstRetained > 0 && count <= firstRetained {
+		return fmt.Errorf("attempted to reorg to batch count %v but metadata before batch %v has been pruned", count, firstRetained)
+	}
+
 	var prevBatchMeta BatchMetadata
 	if count > 0 {
-		var err error
 		prevBatchMeta, err = t.GetBatchMetadata(count - 1)
 		if errors.Is(err, AccumulatorNotFoundErr) {
 			return errors.New("attempted to reorg to future batch count")
@@ -888,7 +971,7 @@ func (t *InboxTracker) ReorgBatchesTo(count uint64) error {
 
 	dbBatch := t.db.NewBatch()
 
-	err := deleteStartingAt(t.db, dbBatch, delayedSequencedPrefix, uint64ToKey(prevBatchMeta.DelayedMessageCount+1))
+	err = deleteStartingAt(t.db, dbBatch, delayedSequencedPrefix, uint64ToKey(prevBatchMeta.DelayedMessageCount+1))
 	if err != nil {
 		return err
 	}
@@ -1,9 +1,14 @@
 package arbnode
 
 import (
+	"context"
+	"encoding/binary"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
 	"github.com/offchainlabs/nitro/util/containers"
 )
 
@@ -56,3 +61,74 @@ func TestDeleteBatchMetadata(t *testing.T) {
 	}
 
 }
+
+func TestInboxAPI(t *testing.T) {
+	ctx := context.Background()
+	tracker := &InboxTracker{
+		db:        rawdb.NewMemoryDatabase(),
+		batchMeta: containers.NewLruCache[uint64, BatchMetadata](100),
+	}
+	api := &InboxAPI{tracker: tracker}
+	put := func(key []byte, value interface{}) {
+		t.Helper()
+		data, err := rlp.EncodeToBytes(value)
+		Require(t, err)
+		Require(t, tracker.db.Put(key, data))
+	}
+	put(sequencerBatchCountKey, uint64(0))
+	put(delayedMessageCountKey, uint64(0))
+
+	batch, err := api.BatchContainingMessage(ctx, 0)
+	Require(t, err)
+	if batch != nil {
+		Fail(t, "found a batch without any batches posted", batch)
+	}
+
+	// two batches of three messages each, the first one reading one of the two delayed messages
+	put(dbKey(sequencerBatchMetaPrefix, 0), BatchMetadata{Accumulator: common.Hash{1}, MessageCount: 3, DelayedMessageCount: 1, ParentChainBlock: 10})
+	put(dbKey(sequencerBatchMetaPrefix, 1), BatchMetadata{Accumulator: common.Hash{2}, MessageCount: 6, DelayedMessageCount: 1, ParentChainBlock: 20})
+	put(sequencerBatchCountKey, uint64(2))
+	for i := uint64(0); i < 2; i++ {
+		msg := &arbostypes.L1IncomingMessage{
+			Header: &arbostypes.L1IncomingMessageHeader{Kind: arbostypes.L1MessageType_L2Message, BlockNumber: 5 + i, L1BaseFee: common.Big0},
+			L2msg:  []byte{byte(i)},
+		}
+		data, err := rlp.EncodeToBytes(msg)
+		Require(t, err)
+		acc := common.Hash{byte(0x10 + i)}
+		Require(t, tracker.db.Put(dbKey(rlpDelayedMessagePrefix, i), append(acc.Bytes(), data...)))
+		Require(t, tracker.db.Put(dbKey(parentChainBlockNumberPrefix, i), binary.BigEndian.AppendUint64(nil, 7+i)))
+	}
+	put(delayedMessageCountKey, uint64(2))
+
+	counts, err := api.InboxCounts(ctx)
+	Require(t, err)
+	if counts.DelayedCount != 2 || counts.DelayedSequencedCount != 1 || counts.BatchCount != 2 || counts.BatchedMessageCount != 6 || counts.FirstRetainedBatch != nil {
+		Fail(t, "unexpected inbox counts", counts)
+	}
+
+	delayed, err := api.DelayedMessage(ctx, 1)
+	Require(t, err)
+	if delayed.Accumulator != (common.Hash{0x11}) || delayed.ParentChainBlock != 8 || delayed.Sequenced || delayed.Message.L2msg[0] != 1 {
+		Fail(t, "unexpected delayed message", delayed)
+	}
+	delayed, err = api.DelayedMessage(ctx, 0)
+	Require(t, err)
+	if !delayed.Sequenced {
+		Fail(t, "delayed message 0 should have been sequenced")
+	}
+	if _, err := api.DelayedMessage(ctx, 2); err == nil {
+		Fail(t, "returned a delayed message that wasn't read yet")
+	}
+
+	batch, err = api.BatchContainingMessage(ctx, 4)
+	Require(t, err)
+	if batch == nil || batch.Batch != 1 || batch.PreviousMessageCount != 3 || batch.MessageCount != 6 || batch.Accumulator != (common.Hash{2}) {
+		Fail(t, "unexpected batch containing message 4", batch)
+	}
+	batch, err = api.BatchContainingMessage(ctx, 6)
+	Require(t, err)
+	if batch != nil {
+		Fail(t, "found a batch for a message that wasn't posted yet", batch)
+	}
+}
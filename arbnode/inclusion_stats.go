@@ -0,0 +1,197 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/util/arbmath"
+)
+
+var (
+	delayedSequencedCounter     = metrics.NewRegisteredCounter("arb/inbox/delayed/sequenced", nil)
+	delayedForceIncludedCounter = metrics.NewRegisteredCounter("arb/inbox/delayed/forceincluded", nil)
+	delayedSequencedAgeHist     = metrics.NewRegisteredHistogram("arb/inbox/delayed/sequenced/age", nil, metrics.NewBoundedHistogramSample())
+	delayedForceIncludedAgeHist = metrics.NewRegisteredHistogram("arb/inbox/delayed/forceincluded/age", nil, metrics.NewBoundedHistogramSample())
+)
+
+const (
+	maxInclusionStatsSenders     = 1000
+	maxRecentForceIncludedRecord = 100
+)
+
+// InclusionTally summarizes delayed messages included one way, with ages in parent chain blocks between the
+// delayed message and the batch including it.
+type InclusionTally struct {
+	Count          uint64 `json:"count"`
+	TotalAgeBlocks uint64 `json:"totalAgeBlocks"`
+	MaxAgeBlocks   uint64 `json:"maxAgeBlocks"`
+}
+
+func (t *InclusionTally) add(age uint64) {
+	t.Count++
+	t.TotalAgeBlocks = arbmath.SaturatingUAdd(t.TotalAgeBlocks, age)
+	t.MaxAgeBlocks = arbmath.MaxInt(t.MaxAgeBlocks, age)
+}
+
+func (t *InclusionTally) merge(other *InclusionTally) {
+	t.Count += other.Count
+	t.TotalAgeBlocks = arbmath.SaturatingUAdd(t.TotalAgeBlocks, other.TotalAgeBlocks)
+	t.MaxAgeBlocks = arbmath.MaxInt(t.MaxAgeBlocks, other.MaxAgeBlocks)
+}
+
+type ForceIncludedSender struct {
+	Sender common.Address `json:"sender"`
+	Kind   uint8          `json:"kind"`
+	Count  uint64         `json:"count"`
+}
+
+type ForceIncludedMessage struct {
+	DelayedSeqNum  uint64         `json:"delayedSeqNum"`
+	Kind           uint8          `json:"kind"`
+	Sender         common.Address `json:"sender"`
+	BatchSeqNum    uint64         `json:"batchSeqNum"`
+	AgeBlocks      uint64         `json:"ageBlocks"`
+	IncludedAtTime time.Time      `json:"includedAtTime"`
+}
+
+// DelayedInclusionStats is how delayed messages reached the inbox since the node started.
+type DelayedInclusionStats struct {
+	Since               time.Time              `json:"since"`
+	Sequenced           InclusionTally         `json:"sequenced"`
+	ForceIncluded       InclusionTally         `json:"forceIncluded"`
+	ForceIncludedBy     []ForceIncludedSender  `json:"forceIncludedBy"`
+	RecentForceIncluded []ForceIncludedMessage `json:"recentForceIncluded"`
+}
+
+type inclusionSenderKey struct {
+	kind   uint8
+	sender common.Address
+}
+
+// InclusionStats tells apart delayed messages the sequencer included from those force included through the
+// sequencer inbox once the sequencer failed to include them in time, so operators and users can monitor whether
+// the sequencer meets its inclusion expectations. Batches read again after a parent chain reorg count again.
+type InclusionStats struct {
+	mutex         sync.Mutex
+	since         time.Time
+	sequenced     map[uint8]*InclusionTally
+	forceIncluded map[uint8]*InclusionTally
+	senders       map[inclusionSenderKey]uint64
+	recent        []ForceIncludedMessage // oldest first
+}
+
+func NewInclusionStats() *InclusionStats {
+	return &InclusionStats{
+		since:         time.Now(),
+		sequenced:     make(map[uint8]*InclusionTally),
+		forceIncluded: make(map[uint8]*InclusionTally),
+		senders:       make(map[inclusionSenderKey]uint64),
+	}
+}
+
+func (s *InclusionStats) record(kind uint8, sender common.Address, delayedSeqNum uint64, batchSeqNum uint64, age uint64, forced bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	tallies := s.sequenced
+	if forced {
+		tallies = s.forceIncluded
+	}
+	tally := tallies[kind]
+	if tally == nil {
+		tally = &InclusionTally{}
+		tallies[kind] = tally
+	}
+	tally.add(age)
+	if !forced {
+		delayedSequencedCounter.Inc(1)
+		delayedSequencedAgeHist.Update(int64(age))
+		return
+	}
+	delayedForceIncludedCounter.Inc(1)
+	delayedForceIncludedAgeHist.Update(int64(age))
+	key := inclusionSenderKey{kind, sender}
+	if _, ok := s.senders[key]; ok || len(s.senders) < maxInclusionStatsSenders {
+		s.senders[key]++
+	}
+	s.recent = append(s.recent, ForceIncludedMessage{
+		DelayedSeqNum:  delayedSeqNum,
+		Kind:           kind,
+		Sender:         sender,
+		BatchSeqNum:    batchSeqNum,
+		AgeBlocks:      age,
+		IncludedAtTime: time.Now(),
+	})
+	if len(s.recent) > maxRecentForceIncludedRecord {
+		s.recent = s.recent[len(s.recent)-maxRecentForceIncludedRecord:]
+	}
+}
+
+// Stats returns the statistics of delayed messages of the given kind, or of all kinds if it's nil.
+func (s *InclusionStats) Stats(kind *uint8) *DelayedInclusionStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	matches := func(k uint8) bool { return kind == nil || *kind == k }
+	stats := &DelayedInclusionStats{
+		Since:               s.since,
+		ForceIncludedBy:     []ForceIncludedSender{},
+		RecentForceIncluded: []ForceIncludedMessage{},
+	}
+	for k, tally := range s.sequenced {
+		if matches(k) {
+			stats.Sequenced.merge(tally)
+		}
+	}
+	for k, tally := range s.forceIncluded {
+		if matches(k) {
+			stats.ForceIncluded.merge(tally)
+		}
+	}
+	for key, count := range s.senders {
+		if matches(key.kind) {
+			stats.ForceIncludedBy = append(stats.ForceIncludedBy, ForceIncludedSender{Sender: key.sender, Kind: key.kind, Count: count})
+		}
+	}
+	sort.Slice(stats.ForceIncludedBy, func(i, j int) bool {
+		if stats.ForceIncludedBy[i].Count != stats.ForceIncludedBy[j].Count {
+			return stats.ForceIncludedBy[i].Count > stats.ForceIncludedBy[j].Count
+		}
+		return stats.ForceIncludedBy[i].Sender.Cmp(stats.ForceIncludedBy[j].Sender) < 0
+	})
+	for _, msg := range s.recent {
+		if matches(msg.Kind) {
+			stats.RecentForceIncluded = append(stats.RecentForceIncluded, msg)
+		}
+	}
+	return stats
+}
+
+// recordInclusions records how the delayed messages each batch read were included, which was by force inclusion if
+// the batch carries no data of its own.
+func (t *InboxTracker) recordInclusions(ctx context.Context, batches []*SequencerInboxBatch, prevDelayedCount uint64) error {
+	for _, batch := range batches {
+		forced := batch.dataLocation == batchDataNone
+		for seqNum := prevDelayedCount; seqNum < batch.AfterDelayedCount; seqNum++ {
+			msg, _, parentChainBlock, err := t.GetDelayedMessageAccumulatorAndParentChainBlockNumber(ctx, seqNum)
+			if err != nil {
+				return err
+			}
+			age := arbmath.SaturatingUSub(batch.ParentChainBlockNumber, parentChainBlock)
+			t.inclusionStats.record(msg.Header.Kind, msg.Header.Poster, seqNum, batch.SequenceNumber, age, forced)
+		}
+		prevDelayedCount = arbmath.MaxInt(prevDelayedCount, batch.AfterDelayedCount)
+	}
+	return nil
+}
+
+// InclusionStats returns the statistics of how delayed messages were included since the node started.
+func (t *InboxTracker) InclusionStats() *InclusionStats {
+	return t.inclusionStats
+}
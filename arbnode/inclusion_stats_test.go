@@ -0,0 +1,49 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+)
+
+func TestInclusionStats(t *testing.T) {
+	stats := NewInclusionStats()
+	alice := common.HexToAddress("0xa")
+	bob := common.HexToAddress("0xb")
+	stats.record(arbostypes.L1MessageType_L2Message, alice, 0, 1, 10, false)
+	stats.record(arbostypes.L1MessageType_L2Message, alice, 1, 1, 20, false)
+	stats.record(arbostypes.L1MessageType_L2Message, bob, 2, 2, 7000, true)
+	stats.record(arbostypes.L1MessageType_L2Message, bob, 3, 2, 6000, true)
+	stats.record(arbostypes.L1MessageType_EthDeposit, alice, 4, 3, 8000, true)
+
+	all := stats.Stats(nil)
+	if all.Sequenced != (InclusionTally{Count: 2, TotalAgeBlocks: 30, MaxAgeBlocks: 20}) {
+		t.Errorf("unexpected sequenced tally %+v", all.Sequenced)
+	}
+	if all.ForceIncluded != (InclusionTally{Count: 3, TotalAgeBlocks: 21000, MaxAgeBlocks: 8000}) {
+		t.Errorf("unexpected force included tally %+v", all.ForceIncluded)
+	}
+	if len(all.ForceIncludedBy) != 2 || all.ForceIncludedBy[0].Sender != bob || all.ForceIncludedBy[0].Count != 2 {
+		t.Errorf("unexpected force included senders %+v", all.ForceIncludedBy)
+	}
+	if len(all.RecentForceIncluded) != 3 || all.RecentForceIncluded[2].DelayedSeqNum != 4 {
+		t.Errorf("unexpected recent force included messages %+v", all.RecentForceIncluded)
+	}
+
+	kind := uint8(arbostypes.L1MessageType_EthDeposit)
+	deposits := stats.Stats(&kind)
+	if deposits.Sequenced.Count != 0 || deposits.ForceIncluded.Count != 1 {
+		t.Errorf("unexpected deposit tallies %+v %+v", deposits.Sequenced, deposits.ForceIncluded)
+	}
+	if len(deposits.ForceIncludedBy) != 1 || deposits.ForceIncludedBy[0].Sender != alice {
+		t.Errorf("unexpected deposit senders %+v", deposits.ForceIncludedBy)
+	}
+	if len(deposits.RecentForceIncluded) != 1 {
+		t.Errorf("unexpected recent deposits %+v", deposits.RecentForceIncluded)
+	}
+}
@@ -99,13 +99,16 @@ func NewMaintenanceRunner(config MaintenanceConfigFetcher, seqCoordinator *SeqCo
 	}
 
 	if seqCoordinator != nil {
-		c := func() *redislock.SimpleCfg { return &cfg.Lock }
-		r := func() bool { return true } // always ready to lock
-		rl, err := redislock.NewSimple(seqCoordinator.Client, c, r)
-		if err != nil {
-			return nil, fmt.Errorf("creating new simple redis lock: %w", err)
+		// the maintenance lock lives in redis, so it's only taken when coordinating through redis
+		if redisBackend, ok := seqCoordinator.CoordinationBackend.(*RedisCoordinationBackend); ok {
+			c := func() *redislock.SimpleCfg { return &cfg.Lock }
+			r := func() bool { return true } // always ready to lock
+			rl, err := redislock.NewSimple(redisBackend.Client, c, r)
+			if err != nil {
+				return nil, fmt.Errorf("creating new simple redis lock: %w", err)
+			}
+			res.lock = rl
 		}
-		res.lock = rl
 	}
 	return res, nil
 }
@@ -152,10 +155,12 @@ func (mr *MaintenanceRunner) maybeRunMaintenance(ctx context.Context) time.Durat
 		return time.Minute
 	}
 
-	if !mr.lock.AttemptLock(ctx) {
-		return time.Minute
+	if mr.lock != nil {
+		if !mr.lock.AttemptLock(ctx) {
+			return time.Minute
+		}
+		defer mr.lock.Release(ctx)
 	}
-	defer mr.lock.Release(ctx)
 
 	log.Info("Attempting avoiding lockout and handing off", "targetTime", config.TimeOfDay)
 	// Avoid lockout for the sequencer and try to handoff.
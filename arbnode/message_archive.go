@@ -0,0 +1,500 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/util/dbutil"
+)
+
+// A message archive is a gzip compressed stream of RLP items: the magic string, a messageArchiveHeader,
+// one archivedMessage for each message of the header's range, then the inbox state confirming them:
+// one archivedDelayedMessage for each of the header's delayed messages and a BatchMetadata for each of its batches.
+const (
+	messageArchiveMagic   = "nitro-message-archive"
+	messageArchiveVersion = 2
+	// messages are imported in chunks so a large archive doesn't have to fit in memory
+	messageArchiveImportChunk = 1024
+)
+
+type messageArchiveHeader struct {
+	Version uint64
+	ChainId uint64
+	Start   uint64
+	Count   uint64
+	// the inbox state is only exported with the messages from the start, and covers the batches all of whose
+	// messages are in the archive
+	BatchCount   uint64
+	BatchAcc     common.Hash
+	DelayedCount uint64
+	DelayedAcc   common.Hash
+}
+
+type archivedMessage struct {
+	// the message with metadata as stored by the transaction streamer
+	Message   rlp.RawValue
+	BlockHash *common.Hash `rlp:"nil"`
+}
+
+type archivedDelayedMessage struct {
+	BeforeInboxAcc         common.Hash
+	Message                *arbostypes.L1IncomingMessage
+	ParentChainBlockNumber uint64
+	Inbox                  common.Address
+}
+
+// ArchivedInboxConfirmer checks an archive's inbox state against the parent chain, given the sequence numbers
+// and accumulators of its last batch and delayed message.
+type ArchivedInboxConfirmer func(ctx context.Context, lastBatch uint64, batchAcc common.Hash, lastDelayed uint64, delayedAcc common.Hash) error
+
+// ExportMessageArchive writes the messages in [from, to) from the transaction streamer's database to w,
+// with their metadata and the block hashes the feed reported for them. Exports from the first message also
+// include the inbox state confirming them, if the tracker has it. A to of 0 exports up to the last message.
+// It returns the number of messages exported.
+func ExportMessageArchive(ctx context.Context, db ethdb.Database, w io.Writer, chainId uint64, from arbutil.MessageIndex, to arbutil.MessageIndex) (uint64, error) {
+	countBytes, err := db.Get(messageCountKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read message count: %w", err)
+	}
+	var msgCount uint64
+	if err := rlp.DecodeBytes(countBytes, &msgCount); err != nil {
+		return 0, err
+	}
+	if to == 0 || uint64(to) > msgCount {
+		to = arbutil.MessageIndex(msgCount)
+	}
+	if from > to {
+		return 0, fmt.Errorf("export start %v is after the message count %v", from, to)
+	}
+	header := messageArchiveHeader{
+		Version: messageArchiveVersion,
+		ChainId: chainId,
+		Start:   uint64(from),
+		Count:   uint64(to - from),
+	}
+	tracker, err := NewInboxTracker(db, nil, nil, DefaultSnapSyncConfig)
+	if err != nil {
+		return 0, err
+	}
+	if from == 0 {
+		header.BatchCount, err = archivedBatchCount(tracker, to)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if header.BatchCount > 0 {
+		lastBatch, err := tracker.GetBatchMetadata(header.BatchCount - 1)
+		if err != nil {
+			return 0, err
+		}
+		header.BatchAcc = lastBatch.Accumulator
+		header.DelayedCount = lastBatch.DelayedMessageCount
+		if header.DelayedCount > 0 {
+			header.DelayedAcc, err = tracker.GetDelayedAcc(header.DelayedCount - 1)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	zw := gzip.NewWriter(w)
+	buffered := bufio.NewWriter(zw)
+	if err := rlp.Encode(buffered, messageArchiveMagic); err != nil {
+		return 0, err
+	}
+	if err := rlp.Encode(buffered, &header); err != nil {
+		return 0, err
+	}
+	for pos := from; pos < to; pos++ {
+		if pos%100_000 == 0 {
+			if ctx.Err() != nil {
+				return 0, ctx.Err()
+			}
+			log.Info("exporting messages", "pos", pos, "end", to)
+		}
+		data, err := db.Get(dbKey(messagePrefix, uint64(pos)))
+		if err != nil {
+			return 0, fmt.Errorf("failed to read message %v: %w", pos, err)
+		}
+		entry := archivedMessage{Message: data}
+		blockHashData, err := db.Get(dbKey(blockHashInputFeedPrefix, uint64(pos)))
+		if err == nil {
+			var blockHash blockHashDBValue
+			if err := rlp.DecodeBytes(blockHashData, &blockHash); err != nil {
+				return 0, err
+			}
+			entry.BlockHash = blockHash.BlockHash
+		}
+		if err := rlp.Encode(buffered, &entry); err != nil {
+			return 0, err
+		}
+	}
+	var beforeInboxAcc common.Hash
+	for seqNum := uint64(0); seqNum < header.DelayedCount; seqNum++ {
+		msg, afterInboxAcc, parentChainBlockNumber, err := tracker.GetDelayedMessageAccumulatorAndParentChainBlockNumber(ctx, seqNum)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read delayed message %v: %w", seqNum, err)
+		}
+		inbox, err := tracker.GetDelayedMessageInbox(seqNum)
+		if err != nil {
+			return 0, err
+		}
+		entry := archivedDelayedMessage{
+			BeforeInboxAcc:         beforeInboxAcc,
+			Message:                msg,
+			ParentChainBlockNumber: parentChainBlockNumber,
+			Inbox:                  inbox,
+		}
+		if err := rlp.Encode(buffered, &entry); err != nil {
+			return 0, err
+		}
+		beforeInboxAcc = afterInboxAcc
+	}
+	for seqNum := uint64(0); seqNum < header.BatchCount; seqNum++ {
+		meta, err := tracker.GetBatchMetadata(seqNum)
+		if err != nil {
+			return 0, err
+		}
+		if err := rlp.Encode(buffered, &meta); err != nil {
+			return 0, err
+		}
+	}
+	if err := buffered.Flush(); err != nil {
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+	return header.Count, nil
+}
+
+// archivedBatchCount returns how many batches only contain messages before to, or 0 if the tracker has pruned
+// the metadata of any of them.
+func archivedBatchCount(tracker *InboxTracker, to arbutil.MessageIndex) (uint64, error) {
+	batchCount, err := tracker.GetBatchCount()
+	if dbutil.IsErrNotFound(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	firstRetained, err := tracker.GetFirstRetainedBatch()
+	if err != nil || firstRetained > 0 {
+		return 0, err
+	}
+	// batches' message counts only grow, so the ones within the range are a prefix
+	var searchErr error
+	count := sort.Search(int(batchCount), func(i int) bool {
+		meta, err := tracker.GetBatchMetadata(uint64(i))
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		return meta.MessageCount > to
+	})
+	return uint64(count), searchErr
+}
+
+// ExportMessageArchive exports the messages in [from, to) of a running node, see ExportMessageArchive.
+func (s *TransactionStreamer) ExportMessageArchive(ctx context.Context, w io.Writer, from arbutil.MessageIndex, to arbutil.MessageIndex) (uint64, error) {
+	return ExportMessageArchive(ctx, s.db, w, s.chainConfig.ChainID.Uint64(), from, to)
+}
+
+type messageArchiveReader struct {
+	stream *rlp.Stream
+	header messageArchiveHeader
+	read   uint64
+}
+
+func newMessageArchiveReader(r io.Reader) (*messageArchiveReader, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a message archive: %w", err)
+	}
+	stream := rlp.NewStream(bufio.NewReader(zr), 0)
+	var magic string
+	if err := stream.Decode(&magic); err != nil || magic != messageArchiveMagic {
+		return nil, errors.New("not a message archive")
+	}
+	reader := &messageArchiveReader{stream: stream}
+	if err := stream.Decode(&reader.header); err != nil {
+		return nil, fmt.Errorf("invalid message archive header: %w", err)
+	}
+	if reader.header.Version != messageArchiveVersion {
+		return nil, fmt.Errorf("unsupported message archive version %v", reader.header.Version)
+	}
+	return reader, nil
+}
+
+// next returns the next message of the archive, or io.EOF after the last one.
+func (r *messageArchiveReader) next() (*arbostypes.MessageWithMetadataAndBlockHash, error) {
+	if r.read >= r.header.Count {
+		return nil, io.EOF
+	}
+	var entry archivedMessage
+	if err := r.stream.Decode(&entry); err != nil {
+		return nil, fmt.Errorf("failed to read archived message %v: %w", r.header.Start+r.read, err)
+	}
+	var msg arbostypes.MessageWithMetadataAndBlockHash
+	if err := rlp.DecodeBytes(entry.Message, &msg.MessageWithMeta); err != nil {
+		return nil, fmt.Errorf("invalid archived message %v: %w", r.header.Start+r.read, err)
+	}
+	msg.BlockHash = entry.BlockHash
+	r.read++
+	return &msg, nil
+}
+
+func (r *messageArchiveReader) nextDelayed() (*DelayedInboxMessage, error) {
+	var entry archivedDelayedMessage
+	if err := r.stream.Decode(&entry); err != nil {
+		return nil, fmt.Errorf("failed to read archived delayed message: %w", err)
+	}
+	return &DelayedInboxMessage{
+		BeforeInboxAcc:         entry.BeforeInboxAcc,
+		Message:                entry.Message,
+		ParentChainBlockNumber: entry.ParentChainBlockNumber,
+		Inbox:                  entry.Inbox,
+	}, nil
+}
+
+func (r *messageArchiveReader) nextBatch() (BatchMetadata, error) {
+	var meta BatchMetadata
+	if err := r.stream.Decode(&meta); err != nil {
+		return BatchMetadata{}, fmt.Errorf("failed to read archived batch metadata: %w", err)
+	}
+	return meta, nil
+}
+
+// ImportMessageArchive adds the messages of an archive after the node's last message, so they're executed
+// without re-deriving them from the parent chain. The archive may overlap the node's messages, but must agree
+// with them. If the tracker hasn't read any batches yet, the archive's inbox state is checked against the parent
+// chain with confirm before anything is imported, then becomes the tracker's: the messages of its batches are
+// confirmed, and the inbox reader carries on after its last batch. Any other messages are added unconfirmed,
+// the same as feed messages, until the inbox reader reads the batches that posted them.
+// It returns the message count after the import.
+func (t *InboxTracker) ImportMessageArchive(ctx context.Context, r io.Reader, confirm ArchivedInboxConfirmer) (arbutil.MessageIndex, error) {
+	archive, err := newMessageArchiveReader(r)
+	if err != nil {
+		return 0, err
+	}
+	if chainId := t.txStreamer.chainConfig.ChainID.Uint64(); archive.header.ChainId != chainId {
+		return 0, fmt.Errorf("message archive is of chain %v, not %v", archive.header.ChainId, chainId)
+	}
+	if err := t.Initialize(); err != nil {
+		return 0, err
+	}
+	batchCount, err := t.GetBatchCount()
+	if err != nil {
+		return 0, err
+	}
+	header := &archive.header
+	importInbox := header.BatchCount > 0 && batchCount == 0
+	if importInbox {
+		if header.DelayedCount == 0 {
+			return 0, errors.New("message archive has batches but no delayed messages")
+		}
+		if err := confirm(ctx, header.BatchCount-1, header.BatchAcc, header.DelayedCount-1, header.DelayedAcc); err != nil {
+			return 0, fmt.Errorf("message archive's inbox state doesn't match the parent chain: %w", err)
+		}
+	}
+	msgCount, err := t.txStreamer.importArchivedMessages(ctx, archive)
+	if err != nil {
+		return 0, err
+	}
+	if !importInbox {
+		log.Warn("not importing the message archive's inbox state, the messages are confirmed once the inbox reader reads their batches", "archiveBatches", header.BatchCount, "trackerBatches", batchCount)
+		return msgCount, nil
+	}
+	if err := t.importArchivedInbox(ctx, archive, msgCount); err != nil {
+		return 0, fmt.Errorf("failed to import the message archive's inbox state: %w", err)
+	}
+	log.Info("confirmed imported messages against the parent chain", "batchCount", header.BatchCount, "delayedCount", header.DelayedCount)
+	return msgCount, nil
+}
+
+// importArchivedMessages adds the archive's messages to the streamer, see InboxTracker.ImportMessageArchive.
+func (s *TransactionStreamer) importArchivedMessages(ctx context.Context, archive *messageArchiveReader) (arbutil.MessageIndex, error) {
+	msgCount, err := s.GetMessageCount()
+	if err != nil {
+		return 0, err
+	}
+	start := arbutil.MessageIndex(archive.header.Start)
+	if start > msgCount {
+		return 0, fmt.Errorf("message archive starts at message %v but the node only has %v messages", start, msgCount)
+	}
+	pos := start
+	for {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		var chunk []arbostypes.MessageWithMetadataAndBlockHash
+		for len(chunk) < messageArchiveImportChunk {
+			msg, err := archive.next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return 0, err
+			}
+			chunk = append(chunk, *msg)
+		}
+		if len(chunk) == 0 {
+			break
+		}
+		for i := range chunk {
+			msgPos := pos + arbutil.MessageIndex(i)
+			if msgPos >= msgCount {
+				break
+			}
+			if err := s.checkArchivedMessage(msgPos, &chunk[i]); err != nil {
+				return 0, err
+			}
+		}
+		s.insertionMutex.Lock()
+		err := s.addMessagesAndEndBatchImpl(pos, false, chunk, nil)
+		s.insertionMutex.Unlock()
+		if err != nil {
+			return 0, fmt.Errorf("failed to import messages starting at %v: %w", pos, err)
+		}
+		pos += arbutil.MessageIndex(len(chunk))
+		msgCount, err = s.GetMessageCount()
+		if err != nil {
+			return 0, err
+		}
+		if msgCount < pos {
+			return 0, fmt.Errorf("imported messages up to %v but the node only has %v messages", pos, msgCount)
+		}
+		log.Info("imported messages", "pos", pos, "end", archive.header.Start+archive.header.Count)
+	}
+	return msgCount, nil
+}
+
+// importArchivedInbox makes the archive's confirmed inbox state the tracker's. The tracker must have no batches.
+func (t *InboxTracker) importArchivedInbox(ctx context.Context, archive *messageArchiveReader, msgCount arbutil.MessageIndex) error {
+	header := &archive.header
+	var chunk []*DelayedInboxMessage
+	for seqNum := uint64(0); seqNum < header.DelayedCount; seqNum++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		msg, err := archive.nextDelayed()
+		if err != nil {
+			return err
+		}
+		chunk = append(chunk, msg)
+		if len(chunk) == messageArchiveImportChunk || seqNum+1 == header.DelayedCount {
+			if err := t.AddDelayedMessages(chunk, false); err != nil {
+				return err
+			}
+			chunk = nil
+		}
+	}
+	delayedAcc, err := t.GetDelayedAcc(header.DelayedCount - 1)
+	if err != nil {
+		return err
+	}
+	if delayedAcc != header.DelayedAcc {
+		return fmt.Errorf("archived delayed messages end at accumulator %v, not the confirmed %v", delayedAcc, header.DelayedAcc)
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	// the batch count is written last, so the metadata written before an error is overwritten as batches are read
+	dbBatch := t.db.NewBatch()
+	var prev BatchMetadata
+	for seqNum := uint64(0); seqNum < header.BatchCount; seqNum++ {
+		meta, err := archive.nextBatch()
+		if err != nil {
+			return err
+		}
+		if meta.MessageCount < prev.MessageCount || meta.DelayedMessageCount < prev.DelayedMessageCount {
+			return fmt.Errorf("archived batch %v goes backwards", seqNum)
+		}
+		metaBytes, err := rlp.EncodeToBytes(meta)
+		if err != nil {
+			return err
+		}
+		if err := dbBatch.Put(dbKey(sequencerBatchMetaPrefix, seqNum), metaBytes); err != nil {
+			return err
+		}
+		if meta.DelayedMessageCount > prev.DelayedMessageCount {
+			seqNumData, err := rlp.EncodeToBytes(seqNum)
+			if err != nil {
+				return err
+			}
+			if err := dbBatch.Put(dbKey(delayedSequencedPrefix, meta.DelayedMessageCount), seqNumData); err != nil {
+				return err
+			}
+		}
+		prev = meta
+		if dbBatch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := dbBatch.Write(); err != nil {
+				return err
+			}
+			dbBatch.Reset()
+		}
+	}
+	if prev.Accumulator != header.BatchAcc || prev.DelayedMessageCount != header.DelayedCount {
+		return errors.New("archived batches don't end at the confirmed batch")
+	}
+	if prev.MessageCount > msgCount {
+		return fmt.Errorf("archived batches have %v messages but only %v were imported", prev.MessageCount, msgCount)
+	}
+	if prev.MessageCount > 0 {
+		lastMsg, err := t.txStreamer.GetMessage(prev.MessageCount - 1)
+		if err != nil {
+			return err
+		}
+		if lastMsg.DelayedMessagesRead != prev.DelayedMessageCount {
+			return fmt.Errorf("message %v read %v delayed messages, but its batch read %v", prev.MessageCount-1, lastMsg.DelayedMessagesRead, prev.DelayedMessageCount)
+		}
+	}
+	countData, err := rlp.EncodeToBytes(header.BatchCount)
+	if err != nil {
+		return err
+	}
+	if err := dbBatch.Put(sequencerBatchCountKey, countData); err != nil {
+		return err
+	}
+	return dbBatch.Write()
+}
+
+// ImportMessageArchive imports a message archive, confirming it against the parent chain, see
+// InboxTracker.ImportMessageArchive.
+func (n *Node) ImportMessageArchive(ctx context.Context, r io.Reader) (arbutil.MessageIndex, error) {
+	if n.InboxReader == nil {
+		return 0, errors.New("importing a message archive requires the parent chain reader, to confirm the messages")
+	}
+	return n.InboxTracker.ImportMessageArchive(ctx, r, n.InboxReader.confirmArchivedInbox)
+}
+
+// checkArchivedMessage returns an error if the node already has a different message at pos.
+func (s *TransactionStreamer) checkArchivedMessage(pos arbutil.MessageIndex, msg *arbostypes.MessageWithMetadataAndBlockHash) error {
+	existing, err := s.db.Get(dbKey(messagePrefix, uint64(pos)))
+	if err != nil {
+		return err
+	}
+	archived, err := rlp.EncodeToBytes(msg.MessageWithMeta)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(existing, archived) {
+		return fmt.Errorf("message archive disagrees with the node's message %v", pos)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+)
+
+func archiveTestMessages(count int) []arbostypes.MessageWithMetadata {
+	var messages []arbostypes.MessageWithMetadata
+	for i := 0; i < count; i++ {
+		messages = append(messages, arbostypes.MessageWithMetadata{
+			Message: &arbostypes.L1IncomingMessage{
+				Header: &arbostypes.L1IncomingMessageHeader{
+					Kind:      arbostypes.L1MessageType_L2Message,
+					RequestId: &common.Hash{},
+					L1BaseFee: common.Big0,
+				},
+				L2msg: []byte{byte(i)},
+			},
+			DelayedMessagesRead: 1,
+		})
+	}
+	return messages
+}
+
+// writeTestBatchMetadata writes batches' metadata as the inbox reader would have.
+func writeTestBatchMetadata(t *testing.T, db ethdb.Database, batches []BatchMetadata) {
+	t.Helper()
+	for i, meta := range batches {
+		data, err := rlp.EncodeToBytes(meta)
+		Require(t, err)
+		Require(t, db.Put(dbKey(sequencerBatchMetaPrefix, uint64(i)), data))
+	}
+	count, err := rlp.EncodeToBytes(uint64(len(batches)))
+	Require(t, err)
+	Require(t, db.Put(sequencerBatchCountKey, count))
+}
+
+func TestMessageArchive(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, source, sourceDb, _ := NewTransactionStreamerForTest(t, common.Address{})
+	Require(t, source.AddMessages(1, false, archiveTestMessages(2000)))
+	sourceInbox, err := NewInboxTracker(sourceDb, source, nil, DefaultSnapSyncConfig)
+	Require(t, err)
+	Require(t, sourceInbox.Initialize())
+	init, err := source.GetMessage(0)
+	Require(t, err)
+	initDelayed := &DelayedInboxMessage{
+		Message:                init.Message,
+		ParentChainBlockNumber: 7,
+	}
+	Require(t, sourceInbox.AddDelayedMessages([]*DelayedInboxMessage{initDelayed}, false))
+	batches := []BatchMetadata{
+		{Accumulator: common.HexToHash("0x01"), MessageCount: 1, DelayedMessageCount: 1, ParentChainBlock: 7},
+		{Accumulator: common.HexToHash("0x02"), MessageCount: 1500, DelayedMessageCount: 1, ParentChainBlock: 8},
+		{Accumulator: common.HexToHash("0x03"), MessageCount: 2001, DelayedMessageCount: 1, ParentChainBlock: 9},
+	}
+	writeTestBatchMetadata(t, sourceDb, batches)
+	partial, err := archivedBatchCount(sourceInbox, 1800)
+	Require(t, err)
+	if partial != 2 {
+		Fail(t, "expected the 2 batches before message 1800 archived, got", partial)
+	}
+
+	var archive bytes.Buffer
+	count, err := source.ExportMessageArchive(ctx, &archive, 0, 0)
+	Require(t, err)
+	if count != 2001 {
+		Fail(t, "exported", count, "messages instead of 2001")
+	}
+	confirm := func(ctx context.Context, lastBatch uint64, batchAcc common.Hash, lastDelayed uint64, delayedAcc common.Hash) error {
+		if lastBatch != 2 || batchAcc != batches[2].Accumulator || lastDelayed != 0 || delayedAcc != initDelayed.AfterInboxAcc() {
+			Fail(t, "unexpected inbox state to confirm", lastBatch, batchAcc, lastDelayed, delayedAcc)
+		}
+		return nil
+	}
+
+	// the destination already has the init message, which the archive overlaps
+	_, dest, destDb, _ := NewTransactionStreamerForTest(t, common.Address{})
+	destInbox, err := NewInboxTracker(destDb, dest, nil, DefaultSnapSyncConfig)
+	Require(t, err)
+	msgCount, err := destInbox.ImportMessageArchive(ctx, bytes.NewReader(archive.Bytes()), confirm)
+	Require(t, err)
+	if msgCount != 2001 {
+		Fail(t, "imported up to message count", msgCount, "instead of 2001")
+	}
+	for _, pos := range []arbutil.MessageIndex{0, 1, 1024, 2000} {
+		expected, err := source.GetMessage(pos)
+		Require(t, err)
+		imported, err := dest.GetMessage(pos)
+		Require(t, err)
+		if !reflect.DeepEqual(expected, imported) {
+			Fail(t, "imported message", pos, "differs from the exported one")
+		}
+	}
+	// the messages are confirmed by the imported batches
+	batchCount, err := destInbox.GetBatchCount()
+	Require(t, err)
+	if batchCount != 3 {
+		Fail(t, "imported", batchCount, "batches instead of 3")
+	}
+	for i, expected := range batches {
+		meta, err := destInbox.GetBatchMetadata(uint64(i))
+		Require(t, err)
+		if meta != expected {
+			Fail(t, "imported batch", i, "metadata", meta, "instead of", expected)
+		}
+	}
+	delayedAcc, err := destInbox.GetDelayedAcc(0)
+	Require(t, err)
+	if delayedAcc != initDelayed.AfterInboxAcc() {
+		Fail(t, "imported the wrong delayed message")
+	}
+
+	// nothing is imported when the parent chain disagrees with the archive's inbox state
+	_, unconfirmed, unconfirmedDb, _ := NewTransactionStreamerForTest(t, common.Address{})
+	unconfirmedInbox, err := NewInboxTracker(unconfirmedDb, unconfirmed, nil, DefaultSnapSyncConfig)
+	Require(t, err)
+	disagree := func(context.Context, uint64, common.Hash, uint64, common.Hash) error {
+		return errors.New("accumulator mismatch")
+	}
+	if _, err := unconfirmedInbox.ImportMessageArchive(ctx, bytes.NewReader(archive.Bytes()), disagree); err == nil {
+		Fail(t, "imported an archive the parent chain disagrees with")
+	}
+	msgCount, err = unconfirmed.GetMessageCount()
+	Require(t, err)
+	if msgCount != 1 {
+		Fail(t, "imported messages of an archive the parent chain disagrees with")
+	}
+
+	// an archive disagreeing with the node's messages is rejected
+	_, diverged, divergedDb, _ := NewTransactionStreamerForTest(t, common.Address{})
+	other := archiveTestMessages(3)
+	other[1].Message.L2msg = []byte{0xff}
+	Require(t, diverged.AddMessages(1, false, other))
+	divergedInbox, err := NewInboxTracker(divergedDb, diverged, nil, DefaultSnapSyncConfig)
+	Require(t, err)
+	if _, err := divergedInbox.ImportMessageArchive(ctx, bytes.NewReader(archive.Bytes()), confirm); err == nil {
+		Fail(t, "imported an archive disagreeing with the node's messages")
+	}
+}
@@ -32,6 +32,10 @@ type MessagePruner struct {
 	cachedPrunedBlockHashesInputFeed uint64
 	cachedPrunedMessageResult        uint64
 	cachedPrunedDelayedMessages      uint64
+	cachedPrunedLegacyDelayed        uint64
+	cachedPrunedParentChainBlocks    uint64
+	cachedPrunedBatchMetadata        uint64
+	cachedPrunedDelayedSequenced     uint64
 }
 
 type MessagePrunerConfig struct {
@@ -39,20 +43,27 @@ type MessagePrunerConfig struct {
 	// Message pruning interval.
 	PruneInterval  time.Duration `koanf:"prune-interval" reload:"hot"`
 	MinBatchesLeft uint64        `koanf:"min-batches-left" reload:"hot"`
+	// Whether to also prune the inbox tracker's batch metadata and delayed message bookkeeping.
+	PruneInboxMetadata    bool   `koanf:"prune-inbox-metadata"`
+	InboxRetentionBatches uint64 `koanf:"inbox-retention-batches" reload:"hot"`
 }
 
 type MessagePrunerConfigFetcher func() *MessagePrunerConfig
 
 var DefaultMessagePrunerConfig = MessagePrunerConfig{
-	Enable:         true,
-	PruneInterval:  time.Minute,
-	MinBatchesLeft: 2,
+	Enable:                true,
+	PruneInterval:         time.Minute,
+	MinBatchesLeft:        2,
+	PruneInboxMetadata:    false,
+	InboxRetentionBatches: 10000,
 }
 
 func MessagePrunerConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".enable", DefaultMessagePrunerConfig.Enable, "enable message pruning")
 	f.Duration(prefix+".prune-interval", DefaultMessagePrunerConfig.PruneInterval, "interval for running message pruner")
 	f.Uint64(prefix+".min-batches-left", DefaultMessagePrunerConfig.MinBatchesLeft, "min number of batches not pruned")
+	f.Bool(prefix+".prune-inbox-metadata", DefaultMessagePrunerConfig.PruneInboxMetadata, "also prune the inbox tracker's metadata of batches before the latest confirmed assertion, and the bookkeeping of their delayed messages")
+	f.Uint64(prefix+".inbox-retention-batches", DefaultMessagePrunerConfig.InboxRetentionBatches, "min number of batches whose inbox tracker metadata isn't pruned")
 }
 
 func NewMessagePruner(transactionStreamer *TransactionStreamer, inboxTracker *InboxTracker, config MessagePrunerConfigFetcher) *MessagePruner {
@@ -113,7 +124,66 @@ func (m *MessagePruner) prune(ctx context.Context, count arbutil.MessageIndex, g
 	msgCount := endBatchMetadata.MessageCount
 	delayedCount := endBatchMetadata.DelayedMessageCount
 
-	return m.deleteOldMessagesFromDB(ctx, msgCount, delayedCount)
+	if err := m.deleteOldMessagesFromDB(ctx, msgCount, delayedCount); err != nil {
+		return err
+	}
+	if !m.config().PruneInboxMetadata {
+		return nil
+	}
+	return m.pruneInboxMetadata(ctx, globalState.Batch, batchCount)
+}
+
+// pruneInboxMetadata deletes the metadata of batches before the confirmed batch and the retention, along with the
+// bookkeeping of the delayed messages they read. The metadata of the first retained batch is kept, so later
+// batches can still be checked against its accumulator and reorgs back to it are still handled.
+func (m *MessagePruner) pruneInboxMetadata(ctx context.Context, confirmedBatch uint64, batchCount uint64) error {
+	config := m.config()
+	retention := max(config.MinBatchesLeft, config.InboxRetentionBatches)
+	if batchCount <= retention || confirmedBatch < 2 {
+		return nil
+	}
+	firstRetained := min(confirmedBatch, batchCount-retention) - 1
+	if firstRetained == 0 {
+		return nil
+	}
+	firstMetadata, err := m.inboxTracker.GetBatchMetadata(firstRetained)
+	if err != nil {
+		return err
+	}
+	// readers must stop reaching below the first retained batch before its predecessors are gone
+	if err := m.inboxTracker.setFirstRetainedBatch(firstRetained); err != nil {
+		return err
+	}
+	prunedKeysRange, err := deleteFromLastPrunedUptoEndKey(ctx, m.inboxTracker.db, sequencerBatchMetaPrefix, &m.cachedPrunedBatchMetadata, firstRetained+1)
+	if err != nil {
+		return fmt.Errorf("error deleting batch metadata: %w", err)
+	}
+	if len(prunedKeysRange) > 0 {
+		log.Info("Pruned batch metadata:", "first pruned key", prunedKeysRange[0], "last pruned key", prunedKeysRange[len(prunedKeysRange)-1])
+	}
+	delayedCount := firstMetadata.DelayedMessageCount
+	prunedKeysRange, err = deleteFromLastPrunedUptoEndKey(ctx, m.inboxTracker.db, delayedSequencedPrefix, &m.cachedPrunedDelayedSequenced, delayedCount+1)
+	if err != nil {
+		return fmt.Errorf("error deleting sequenced delayed message counts: %w", err)
+	}
+	if len(prunedKeysRange) > 0 {
+		log.Info("Pruned sequenced delayed message counts:", "first pruned key", prunedKeysRange[0], "last pruned key", prunedKeysRange[len(prunedKeysRange)-1])
+	}
+	prunedKeysRange, err = deleteFromLastPrunedUptoEndKey(ctx, m.inboxTracker.db, legacyDelayedMessagePrefix, &m.cachedPrunedLegacyDelayed, delayedCount)
+	if err != nil {
+		return fmt.Errorf("error deleting legacy delayed messages: %w", err)
+	}
+	if len(prunedKeysRange) > 0 {
+		log.Info("Pruned legacy delayed messages:", "first pruned key", prunedKeysRange[0], "last pruned key", prunedKeysRange[len(prunedKeysRange)-1])
+	}
+	prunedKeysRange, err = deleteFromLastPrunedUptoEndKey(ctx, m.inboxTracker.db, parentChainBlockNumberPrefix, &m.cachedPrunedParentChainBlocks, delayedCount)
+	if err != nil {
+		return fmt.Errorf("error deleting delayed message parent chain blocks: %w", err)
+	}
+	if len(prunedKeysRange) > 0 {
+		log.Info("Pruned delayed message parent chain blocks:", "first pruned key", prunedKeysRange[0], "last pruned key", prunedKeysRange[len(prunedKeysRange)-1])
+	}
+	return nil
 }
 
 func (m *MessagePruner) deleteOldMessagesFromDB(ctx context.Context, messageCount arbutil.MessageIndex, delayedMessageCount uint64) error {
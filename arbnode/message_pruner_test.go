@@ -9,7 +9,9 @@ import (
 
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/util/containers"
 )
 
 func TestMessagePrunerWithPruningEligibleMessagePresent(t *testing.T) {
@@ -77,6 +79,69 @@ func TestMessagePrunerWithNoPruningEligibleMessagePresent(t *testing.T) {
 	checkDbKeys(t, messagesCount, inboxTrackerDb, rlpDelayedMessagePrefix)
 }
 
+func TestMessagePrunerInboxMetadata(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// every batch has two messages, one of them delayed
+	batchCount := uint64(20)
+	db := rawdb.NewMemoryDatabase()
+	Require(t, db.Put(dbKey(delayedSequencedPrefix, 0), []byte{}))
+	for i := uint64(0); i < batchCount; i++ {
+		data, err := rlp.EncodeToBytes(BatchMetadata{MessageCount: arbutil.MessageIndex(2 * (i + 1)), DelayedMessageCount: i + 1})
+		Require(t, err)
+		Require(t, db.Put(dbKey(sequencerBatchMetaPrefix, i), data))
+		data, err = rlp.EncodeToBytes(i)
+		Require(t, err)
+		Require(t, db.Put(dbKey(delayedSequencedPrefix, i+1), data))
+		Require(t, db.Put(dbKey(legacyDelayedMessagePrefix, i), []byte{}))
+		Require(t, db.Put(dbKey(parentChainBlockNumberPrefix, i), []byte{}))
+	}
+	data, err := rlp.EncodeToBytes(batchCount)
+	Require(t, err)
+	Require(t, db.Put(sequencerBatchCountKey, data))
+
+	tracker := &InboxTracker{db: db, batchMeta: containers.NewLruCache[uint64, BatchMetadata](1000)}
+	config := DefaultMessagePrunerConfig
+	config.PruneInboxMetadata = true
+	config.InboxRetentionBatches = 5
+	pruner := &MessagePruner{
+		inboxTracker: tracker,
+		config:       func() *MessagePrunerConfig { return &config },
+	}
+	// the confirmed batch is further back than the retention, so it decides what's pruned
+	err = pruner.pruneInboxMetadata(ctx, 10, batchCount)
+	Require(t, err)
+
+	firstRetained, err := tracker.GetFirstRetainedBatch()
+	Require(t, err)
+	if firstRetained != 9 {
+		Fail(t, "unexpected first retained batch", firstRetained)
+	}
+	checkDbKeys(t, 10, db, sequencerBatchMetaPrefix)
+	checkDbKeys(t, 11, db, delayedSequencedPrefix)
+	checkDbKeys(t, 10, db, legacyDelayedMessagePrefix)
+	checkDbKeys(t, 10, db, parentChainBlockNumberPrefix)
+
+	// message 20 is the first one after the first retained batch
+	batch, found, err := tracker.FindInboxBatchContainingMessage(20)
+	Require(t, err)
+	if !found || batch != 10 {
+		Fail(t, "expected message 20 in batch 10, got", batch, "found", found)
+	}
+	batch, found, err = tracker.FindInboxBatchContainingMessage(37)
+	Require(t, err)
+	if !found || batch != 18 {
+		Fail(t, "expected message 37 in batch 18, got", batch, "found", found)
+	}
+	if _, _, err := tracker.FindInboxBatchContainingMessage(5); err == nil {
+		Fail(t, "found a batch whose metadata was pruned")
+	}
+	if err := tracker.ReorgBatchesTo(9); err == nil {
+		Fail(t, "reorged to a batch whose predecessor's metadata was pruned")
+	}
+}
+
 func setupDatabase(t *testing.T, messageCount, delayedMessageCount uint64) (ethdb.Database, ethdb.Database, *MessagePruner) {
 	transactionStreamerDb := rawdb.NewMemoryDatabase()
 	for i := uint64(0); i < uint64(messageCount); i++ {
@@ -0,0 +1,228 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/util/dbutil"
+)
+
+var (
+	quarantinedMessagesGauge = metrics.NewRegisteredGauge("arb/streamer/quarantined", nil)
+	quarantineHaltedGauge    = metrics.NewRegisteredGauge("arb/streamer/quarantine/halted", nil)
+	quarantineSkippedCounter = metrics.NewRegisteredCounter("arb/streamer/quarantine/skipped", nil)
+)
+
+// QuarantineAction is how a quarantined message is executed.
+type QuarantineAction string
+
+const (
+	// Stop executing messages before the quarantined one, leaving the node otherwise running.
+	QuarantineActionHalt QuarantineAction = "halt"
+	// Execute the message as an invalid message instead, producing an empty block.
+	// The resulting block differs from the rest of the network's unless they apply the same override.
+	QuarantineActionEmptyBlock QuarantineAction = "empty-block"
+)
+
+func (a QuarantineAction) validate() error {
+	switch a {
+	case QuarantineActionHalt, QuarantineActionEmptyBlock:
+		return nil
+	default:
+		return fmt.Errorf("unknown quarantine action %q (expected %q or %q)", a, QuarantineActionHalt, QuarantineActionEmptyBlock)
+	}
+}
+
+// MessageQuarantine is an operator override for a message that reproducibly breaks execution, e.g. because
+// of a fork bug. Overrides are stored in the database until cleared, so they survive restarts.
+type MessageQuarantine struct {
+	Pos       arbutil.MessageIndex `json:"pos"`
+	Action    QuarantineAction     `json:"action"`
+	Reason    string               `json:"reason"`
+	CreatedAt time.Time            `json:"createdAt"`
+	// Whether the node recorded it itself after execution panicked, rather than an operator.
+	Automatic bool `json:"automatic"`
+}
+
+func (s *TransactionStreamer) getQuarantine(pos arbutil.MessageIndex) (*MessageQuarantine, error) {
+	data, err := s.db.Get(dbKey(quarantinedMessagePrefix, uint64(pos)))
+	if dbutil.IsErrNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var quarantine MessageQuarantine
+	if err := json.Unmarshal(data, &quarantine); err != nil {
+		return nil, err
+	}
+	return &quarantine, nil
+}
+
+func (s *TransactionStreamer) putQuarantine(quarantine *MessageQuarantine) error {
+	data, err := json.Marshal(quarantine)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Put(dbKey(quarantinedMessagePrefix, uint64(quarantine.Pos)), data); err != nil {
+		return err
+	}
+	s.updateQuarantineGauge()
+	return nil
+}
+
+// QuarantineMessage records an override for executing the message at pos.
+// It only takes effect for messages that haven't been executed yet.
+func (s *TransactionStreamer) QuarantineMessage(pos arbutil.MessageIndex, action QuarantineAction, reason string) (*MessageQuarantine, error) {
+	if err := action.validate(); err != nil {
+		return nil, err
+	}
+	if reason == "" {
+		return nil, fmt.Errorf("a reason is required to quarantine a message")
+	}
+	quarantine := &MessageQuarantine{
+		Pos:       pos,
+		Action:    action,
+		Reason:    reason,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.putQuarantine(quarantine); err != nil {
+		return nil, err
+	}
+	log.Warn("quarantined message", "pos", pos, "action", action, "reason", reason)
+	s.notifyNewMessages()
+	return quarantine, nil
+}
+
+// ClearQuarantine removes the override for the message at pos, returning whether there was one.
+func (s *TransactionStreamer) ClearQuarantine(pos arbutil.MessageIndex) (bool, error) {
+	existing, err := s.getQuarantine(pos)
+	if err != nil || existing == nil {
+		return false, err
+	}
+	if err := s.db.Delete(dbKey(quarantinedMessagePrefix, uint64(pos))); err != nil {
+		return false, err
+	}
+	if halted := s.haltedAt.Load(); halted != nil && halted.Pos == pos {
+		s.haltedAt.Store(nil)
+		quarantineHaltedGauge.Update(0)
+	}
+	s.updateQuarantineGauge()
+	log.Warn("cleared message quarantine", "pos", pos, "action", existing.Action)
+	s.notifyNewMessages()
+	return true, nil
+}
+
+// QuarantinedMessages lists all recorded overrides in message order.
+func (s *TransactionStreamer) QuarantinedMessages() ([]*MessageQuarantine, error) {
+	iter := s.db.NewIterator(quarantinedMessagePrefix, nil)
+	defer iter.Release()
+	var quarantines []*MessageQuarantine
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) != len(quarantinedMessagePrefix)+8 {
+			continue
+		}
+		var quarantine MessageQuarantine
+		if err := json.Unmarshal(iter.Value(), &quarantine); err != nil {
+			return nil, fmt.Errorf("failed to decode quarantine for message %v: %w", binary.BigEndian.Uint64(key[len(quarantinedMessagePrefix):]), err)
+		}
+		quarantines = append(quarantines, &quarantine)
+	}
+	return quarantines, iter.Error()
+}
+
+// QuarantineHalt returns the override execution is currently halted at, if any.
+func (s *TransactionStreamer) QuarantineHalt() *MessageQuarantine {
+	return s.haltedAt.Load()
+}
+
+func (s *TransactionStreamer) updateQuarantineGauge() {
+	quarantines, err := s.QuarantinedMessages()
+	if err != nil {
+		log.Warn("failed to count quarantined messages", "err", err)
+		return
+	}
+	quarantinedMessagesGauge.Update(int64(len(quarantines)))
+}
+
+func (s *TransactionStreamer) notifyNewMessages() {
+	select {
+	case s.newMessageNotifier <- struct{}{}:
+	default:
+	}
+}
+
+// applyQuarantine returns the message to execute at pos in place of msg,
+// or nil if execution must halt before it.
+func (s *TransactionStreamer) applyQuarantine(pos arbutil.MessageIndex, msg *arbostypes.MessageWithMetadata) (*arbostypes.MessageWithMetadata, error) {
+	quarantine, err := s.getQuarantine(pos)
+	if err != nil {
+		return nil, err
+	}
+	if quarantine == nil {
+		if s.haltedAt.Load() != nil {
+			s.haltedAt.Store(nil)
+			quarantineHaltedGauge.Update(0)
+		}
+		return msg, nil
+	}
+	switch quarantine.Action {
+	case QuarantineActionHalt:
+		if s.haltedAt.Swap(quarantine) == nil {
+			log.Error("execution halted at quarantined message; export it for debugging and clear the quarantine once fixed", "pos", pos, "reason", quarantine.Reason, "automatic", quarantine.Automatic)
+		}
+		quarantineHaltedGauge.Update(1)
+		return nil, nil
+	case QuarantineActionEmptyBlock:
+		log.Error("executing quarantined message as an empty block; this node's state may diverge from the rest of the chain", "pos", pos, "reason", quarantine.Reason)
+		quarantineSkippedCounter.Inc(1)
+		// keep the header's timestamp, block number and delayed message count, so the chain otherwise
+		// continues as it would have
+		header := *msg.Message.Header
+		header.Kind = arbostypes.L1MessageType_Invalid
+		return &arbostypes.MessageWithMetadata{
+			Message: &arbostypes.L1IncomingMessage{
+				Header: &header,
+				L2msg:  []byte{},
+			},
+			DelayedMessagesRead: msg.DelayedMessagesRead,
+		}, nil
+	default:
+		return nil, fmt.Errorf("message %v has unknown quarantine action %q", pos, quarantine.Action)
+	}
+}
+
+// quarantineOnPanic records a halt override for the message at pos if execution panics, then continues
+// panicking. Once the node restarts, it halts cleanly at the message instead of crash-looping on it.
+func (s *TransactionStreamer) quarantineOnPanic(pos arbutil.MessageIndex) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	if s.config().QuarantineOnPanic {
+		quarantine := &MessageQuarantine{
+			Pos:       pos,
+			Action:    QuarantineActionHalt,
+			Reason:    fmt.Sprintf("execution panicked: %v", recovered),
+			CreatedAt: time.Now().UTC(),
+			Automatic: true,
+		}
+		if err := s.putQuarantine(quarantine); err != nil {
+			log.Error("failed to quarantine message after execution panicked", "pos", pos, "err", err)
+		} else {
+			log.Error("quarantined message after execution panicked", "pos", pos, "panic", recovered)
+		}
+	}
+	panic(recovered)
+}
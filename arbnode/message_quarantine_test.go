@@ -0,0 +1,75 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+)
+
+func TestMessageQuarantine(t *testing.T) {
+	streamer := &TransactionStreamer{
+		db:                 rawdb.NewMemoryDatabase(),
+		newMessageNotifier: make(chan struct{}, 1),
+		config:             func() *TransactionStreamerConfig { return &TestTransactionStreamerConfig },
+	}
+	msg := &makeStreamerTestMessages(1, 10)[0].MessageWithMeta
+
+	if _, err := streamer.QuarantineMessage(3, "skip", "bad action"); err == nil {
+		Fail(t, "expected an unknown action to be rejected")
+	}
+	_, err := streamer.QuarantineMessage(3, QuarantineActionEmptyBlock, "fork bug")
+	Require(t, err)
+	_, err = streamer.QuarantineMessage(5, QuarantineActionHalt, "fork bug")
+	Require(t, err)
+
+	got, err := streamer.applyQuarantine(2, msg)
+	Require(t, err)
+	if got != msg || streamer.QuarantineHalt() != nil {
+		Fail(t, "unquarantined message was changed")
+	}
+	got, err = streamer.applyQuarantine(3, msg)
+	Require(t, err)
+	if got.Message.Header.Kind != arbostypes.L1MessageType_Invalid || got.Message.Header.Timestamp != msg.Message.Header.Timestamp || got.DelayedMessagesRead != msg.DelayedMessagesRead {
+		Fail(t, "unexpected substitute for empty block", got.Message.Header)
+	}
+	if msg.Message.Header.Kind != arbostypes.L1MessageType_L2Message {
+		Fail(t, "original message was modified")
+	}
+	got, err = streamer.applyQuarantine(5, msg)
+	Require(t, err)
+	if got != nil || streamer.QuarantineHalt() == nil {
+		Fail(t, "expected execution to halt")
+	}
+
+	cleared, err := streamer.ClearQuarantine(5)
+	Require(t, err)
+	if !cleared || streamer.QuarantineHalt() != nil {
+		Fail(t, "expected halt to be cleared")
+	}
+	quarantines, err := streamer.QuarantinedMessages()
+	Require(t, err)
+	if len(quarantines) != 1 || quarantines[0].Pos != 3 {
+		Fail(t, "unexpected quarantines", quarantines)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				Fail(t, "expected the panic to continue")
+			}
+		}()
+		defer streamer.quarantineOnPanic(7)
+		panic("fork bug")
+	}()
+	quarantine, err := streamer.getQuarantine(7)
+	Require(t, err)
+	if quarantine == nil || !quarantine.Automatic || quarantine.Action != QuarantineActionHalt || !strings.Contains(quarantine.Reason, "fork bug") {
+		Fail(t, "expected the panic to quarantine the message", quarantine)
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/offchainlabs/nitro/arbnode/messagesink"
+	"github.com/offchainlabs/nitro/arbutil"
+	m "github.com/offchainlabs/nitro/broadcaster/message"
+	"github.com/offchainlabs/nitro/util/dbutil"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+var (
+	messageSinkPublishedCounter = metrics.NewRegisteredCounter("arb/messagesink/published", nil)
+	messageSinkErrorCounter     = metrics.NewRegisteredCounter("arb/messagesink/errors", nil)
+	messageSinkLagGauge         = metrics.NewRegisteredGauge("arb/messagesink/lag", nil)
+)
+
+// MessageSinkPublisher mirrors every sequenced message into a message queue as a feed message.
+// The position of the next message to publish is stored once the queue acknowledged the previous ones,
+// so after a restart publishing resumes there, and messages are delivered at least once.
+// When messages are reorged, the publisher goes back and publishes the replacements, which consumers
+// recognize by their repeated sequence numbers, the same as on the feed. The digests of the last published
+// messages are stored too, so messages reorged while the publisher wasn't running are republished as well.
+type MessageSinkPublisher struct {
+	stopwaiter.StopWaiter
+	streamer *TransactionStreamer
+	sink     messagesink.Sink
+	config   messagesink.ConfigFetcher
+
+	wake chan struct{}
+
+	reorgMutex sync.Mutex
+	// the first message reorged since the last publish, if any
+	reorgedTo *arbutil.MessageIndex
+}
+
+func NewMessageSinkPublisher(streamer *TransactionStreamer, config messagesink.ConfigFetcher) (*MessageSinkPublisher, error) {
+	sink, err := messagesink.New(config())
+	if err != nil {
+		return nil, err
+	}
+	return newMessageSinkPublisher(streamer, sink, config), nil
+}
+
+func newMessageSinkPublisher(streamer *TransactionStreamer, sink messagesink.Sink, config messagesink.ConfigFetcher) *MessageSinkPublisher {
+	return &MessageSinkPublisher{
+		streamer: streamer,
+		sink:     sink,
+		config:   config,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+func (p *MessageSinkPublisher) Start(ctxIn context.Context) {
+	p.StopWaiter.Start(ctxIn, p)
+	// subscribed before the first publish, so no reorg after it is missed
+	events := make(chan MessageEvent, 1024)
+	sub := p.streamer.SubscribeMessageEvents(events)
+	p.LaunchThread(func(ctx context.Context) {
+		defer func() { sub.Unsubscribe() }()
+		for {
+			select {
+			case ev := <-events:
+				if ev.Kind == MessageEventReorged {
+					p.noteReorg(ev.Index)
+				}
+				select {
+				case p.wake <- struct{}{}:
+				default:
+				}
+			case err := <-sub.Err():
+				var dropped *MessageEventsDroppedError
+				if !errors.As(err, &dropped) {
+					return
+				}
+				// the events missed may have included reorgs, so everything from the first one missed is republished,
+				// and from before it if the streamer has fewer messages by the time it's resubscribed
+				log.Warn("message sink fell behind the streamer's events, resubscribing", "pos", dropped.From)
+				sub = p.streamer.SubscribeMessageEvents(events)
+				from := dropped.From
+				if msgCount, err := p.streamer.GetMessageCount(); err == nil {
+					from = min(from, msgCount)
+				}
+				p.noteReorg(from)
+				select {
+				case p.wake <- struct{}{}:
+				default:
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+	p.LaunchThread(func(ctx context.Context) {
+		for {
+			published, err := p.publishNext(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				messageSinkErrorCounter.Inc(1)
+				log.Warn("failed to publish messages to message sink", "err", err)
+				select {
+				case <-time.After(p.config().RetryInterval):
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+			if published > 0 {
+				continue
+			}
+			// every new message sends an event, which wakes the publisher
+			select {
+			case <-p.wake:
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+}
+
+// noteReorg records that the messages from index on may have been reorged since they were published.
+func (p *MessageSinkPublisher) noteReorg(index arbutil.MessageIndex) {
+	p.reorgMutex.Lock()
+	defer p.reorgMutex.Unlock()
+	if p.reorgedTo == nil || index < *p.reorgedTo {
+		p.reorgedTo = &index
+	}
+}
+
+func (p *MessageSinkPublisher) StopAndWait() {
+	p.StopWaiter.StopAndWait()
+	if err := p.sink.Close(); err != nil {
+		log.Warn("failed to close message sink", "err", err)
+	}
+}
+
+func (p *MessageSinkPublisher) readCursor() (arbutil.MessageIndex, bool, error) {
+	data, err := p.streamer.db.Get(messageSinkCursorKey)
+	if dbutil.IsErrNotFound(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	var cursor uint64
+	if err := rlp.DecodeBytes(data, &cursor); err != nil {
+		return 0, false, err
+	}
+	return arbutil.MessageIndex(cursor), true, nil
+}
+
+func (p *MessageSinkPublisher) writeCursor(db ethdb.KeyValueWriter, cursor arbutil.MessageIndex) error {
+	data, err := rlp.EncodeToBytes(uint64(cursor))
+	if err != nil {
+		return err
+	}
+	return db.Put(messageSinkCursorKey, data)
+}
+
+// readDigest returns the digest of the message published at pos, or nil if it isn't known.
+func (p *MessageSinkPublisher) readDigest(pos arbutil.MessageIndex) (*common.Hash, error) {
+	data, err := p.streamer.db.Get(dbKey(messageSinkDigestPrefix, uint64(pos)))
+	if dbutil.IsErrNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	digest := common.BytesToHash(data)
+	return &digest, nil
+}
+
+// firstUnpublished goes back from the cursor over the messages reorged since they were published, which the
+// reorg events don't cover if the reorg happened while the publisher wasn't running, and returns the first of them.
+// Reorgs deeper than ReorgCheckDepth are republished from that depth.
+func (p *MessageSinkPublisher) firstUnpublished(cursor arbutil.MessageIndex) (arbutil.MessageIndex, error) {
+	prevCursor := cursor
+	for depth := p.config().ReorgCheckDepth; cursor > 0 && depth > 0; depth-- {
+		published, err := p.readDigest(cursor - 1)
+		if err != nil {
+			return 0, err
+		}
+		if published == nil {
+			break
+		}
+		msg, err := p.streamer.GetMessage(cursor - 1)
+		if err != nil {
+			return 0, err
+		}
+		digest, err := msg.Hash(cursor-1, p.streamer.chainConfig.ChainID.Uint64())
+		if err != nil {
+			return 0, err
+		}
+		if digest == *published {
+			break
+		}
+		cursor--
+	}
+	if cursor < prevCursor {
+		log.Warn("message sink republishing messages reorged since they were published", "pos", cursor, "prevCursor", prevCursor)
+	}
+	return cursor, nil
+}
+
+// publishNext publishes the next messages after the cursor, returning how many it published.
+func (p *MessageSinkPublisher) publishNext(ctx context.Context) (int, error) {
+	config := p.config()
+	msgCount, err := p.streamer.GetMessageCount()
+	if err != nil {
+		return 0, err
+	}
+	cursor, found, err := p.readCursor()
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		// the first time, start from the node's current messages rather than its whole history
+		cursor = msgCount
+		log.Info("message sink starting to publish", "pos", cursor)
+	}
+	p.reorgMutex.Lock()
+	reorgedTo := p.reorgedTo
+	p.reorgedTo = nil
+	p.reorgMutex.Unlock()
+	if reorgedTo != nil && *reorgedTo < cursor {
+		log.Info("message sink republishing reorged messages", "pos", *reorgedTo, "prevCursor", cursor)
+		cursor = *reorgedTo
+	}
+	// a reorg may also have happened before the last reorg event was received
+	checkedCursor := min(cursor, msgCount)
+	checkedCursor, err = p.firstUnpublished(checkedCursor)
+	if err != nil {
+		return 0, err
+	}
+	if !found || checkedCursor != cursor {
+		if err := p.writeCursor(p.streamer.db, checkedCursor); err != nil {
+			return 0, err
+		}
+	}
+	cursor = checkedCursor
+	messageSinkLagGauge.Update(int64(msgCount - cursor))
+	end := min(msgCount, cursor+arbutil.MessageIndex(config.MaxBatchMessages))
+	if cursor >= end {
+		return 0, nil
+	}
+	records := make([]messagesink.Record, 0, end-cursor)
+	digests := make([]common.Hash, 0, end-cursor)
+	for pos := cursor; pos < end; pos++ {
+		record, digest, err := p.messageRecord(pos)
+		if err != nil {
+			return 0, err
+		}
+		records = append(records, *record)
+		digests = append(digests, digest)
+	}
+	publishCtx, cancel := context.WithTimeout(ctx, config.Timeout)
+	defer cancel()
+	if err := p.sink.Publish(publishCtx, records); err != nil {
+		return 0, err
+	}
+	messageSinkPublishedCounter.Inc(int64(len(records)))
+	batch := p.streamer.db.NewBatch()
+	depth := arbutil.MessageIndex(config.ReorgCheckDepth)
+	for i, digest := range digests {
+		pos := cursor + arbutil.MessageIndex(i)
+		if depth > 0 {
+			if err := batch.Put(dbKey(messageSinkDigestPrefix, uint64(pos)), digest.Bytes()); err != nil {
+				return 0, err
+			}
+		}
+		if pos >= depth {
+			if err := batch.Delete(dbKey(messageSinkDigestPrefix, uint64(pos-depth))); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := p.writeCursor(batch, end); err != nil {
+		return 0, err
+	}
+	if err := batch.Write(); err != nil {
+		return 0, err
+	}
+	return len(records), nil
+}
+
+func (p *MessageSinkPublisher) messageRecord(pos arbutil.MessageIndex) (*messagesink.Record, common.Hash, error) {
+	feedMsg, err := p.streamer.FeedMessage(pos)
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+	value, err := json.Marshal(&m.BroadcastMessage{
+		Version:  m.V1,
+		Messages: []*m.BroadcastFeedMessage{feedMsg},
+	})
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+	digest, err := feedMsg.Message.Hash(pos, p.streamer.chainConfig.ChainID.Uint64())
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+	return &messagesink.Record{
+		Key:   strconv.FormatUint(uint64(pos), 10),
+		ID:    fmt.Sprintf("%v-%v", pos, digest.Hex()),
+		Value: value,
+	}, digest, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/arbnode/messagesink"
+	"github.com/offchainlabs/nitro/arbutil"
+	m "github.com/offchainlabs/nitro/broadcaster/message"
+)
+
+type recordingSink struct {
+	published []arbutil.MessageIndex
+}
+
+func (s *recordingSink) Publish(_ context.Context, records []messagesink.Record) error {
+	for _, record := range records {
+		var msg m.BroadcastMessage
+		if err := json.Unmarshal(record.Value, &msg); err != nil {
+			return err
+		}
+		s.published = append(s.published, msg.Messages[0].SequenceNumber)
+	}
+	return nil
+}
+
+func (s *recordingSink) Close() error { return nil }
+
+func TestMessageSinkPublisher(t *testing.T) {
+	ctx := context.Background()
+	_, streamer, _, _ := NewTransactionStreamerForTest(t, common.Address{})
+	config := messagesink.DefaultConfig
+	config.MaxBatchMessages = 2
+	sink := &recordingSink{}
+	publisher := newMessageSinkPublisher(streamer, sink, func() *messagesink.Config { return &config })
+	publishAll := func() {
+		t.Helper()
+		for {
+			published, err := publisher.publishNext(ctx)
+			Require(t, err)
+			if published == 0 {
+				return
+			}
+		}
+	}
+	expect := func(expected ...arbutil.MessageIndex) {
+		t.Helper()
+		if len(sink.published) != len(expected) {
+			Fail(t, "published", sink.published, "instead of", expected)
+		}
+		for i := range expected {
+			if sink.published[i] != expected[i] {
+				Fail(t, "published", sink.published, "instead of", expected)
+			}
+		}
+		sink.published = nil
+	}
+
+	// the first time it starts from the node's current messages
+	publishAll()
+	expect()
+	Require(t, streamer.AddMessages(1, false, archiveTestMessages(3)))
+	publishAll()
+	expect(1, 2, 3)
+
+	// a restarted publisher resumes at its cursor
+	Require(t, streamer.AddMessages(4, false, archiveTestMessages(1)))
+	publisher = newMessageSinkPublisher(streamer, sink, func() *messagesink.Config { return &config })
+	publishAll()
+	expect(4)
+
+	// after a reorg the replaced messages are published again
+	index := arbutil.MessageIndex(2)
+	publisher.reorgedTo = &index
+	publishAll()
+	expect(2, 3, 4)
+
+	// messages reorged while the publisher wasn't running are found by their digests
+	Require(t, streamer.ReorgTo(3))
+	replacements := archiveTestMessages(2)
+	for i := range replacements {
+		replacements[i].Message.L2msg = []byte{0xff, byte(i)}
+	}
+	Require(t, streamer.AddMessages(3, false, replacements))
+	publisher = newMessageSinkPublisher(streamer, sink, func() *messagesink.Config { return &config })
+	publishAll()
+	expect(3, 4)
+	publishAll()
+	expect()
+}
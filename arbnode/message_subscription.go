@@ -0,0 +1,213 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+)
+
+var messageEventsDroppedCounter = metrics.NewRegisteredCounter("arb/streamer/message_events/dropped", nil)
+
+type MessageEventKind string
+
+const (
+	// a message was written after the streamer's last message
+	MessageEventSequenced MessageEventKind = "sequenced"
+	// the messages from Index on were removed, and are replaced by the messages sequenced next
+	MessageEventReorged MessageEventKind = "reorged"
+)
+
+// MessageEvent is sent to the streamer's subscribers once a change to its messages is committed.
+// Sequenced and reorged events are sent in the order the changes happened.
+type MessageEvent struct {
+	Kind MessageEventKind
+	// the sequenced message, or the first message removed by a reorg
+	Index arbutil.MessageIndex
+	// for sequenced messages, the hash the feed signs the message with
+	Digest common.Hash
+	// for sequenced messages, the block hash the feed reported for the message, if any
+	BlockHash *common.Hash
+	// for reorgs, the message count before the reorg
+	PrevMessageCount arbutil.MessageIndex
+}
+
+// MessageEventsDroppedError ends the subscription of a subscriber that fell behind receiving events.
+// The subscriber missed the event for message From, and every event sent until it resubscribes.
+type MessageEventsDroppedError struct {
+	From arbutil.MessageIndex
+}
+
+func (e *MessageEventsDroppedError) Error() string {
+	return fmt.Sprintf("message event subscriber fell behind, dropped at message %v", e.From)
+}
+
+type messageEventSubscription struct {
+	streamer *TransactionStreamer
+	ch       chan<- MessageEvent
+	err      chan error
+	once     sync.Once
+}
+
+func (sub *messageEventSubscription) Unsubscribe() {
+	sub.streamer.messageEventMutex.Lock()
+	delete(sub.streamer.messageEventSubscribers, sub)
+	sub.streamer.messageEventMutex.Unlock()
+	sub.end(nil)
+}
+
+func (sub *messageEventSubscription) Err() <-chan error {
+	return sub.err
+}
+
+func (sub *messageEventSubscription) end(err error) {
+	sub.once.Do(func() {
+		if err != nil {
+			sub.err <- err
+		}
+		close(sub.err)
+	})
+}
+
+// SubscribeMessageEvents sends the streamer's message events to ch. Events are sent without blocking the
+// streamer, and a subscriber whose channel is full is dropped with a MessageEventsDroppedError, so ch should
+// be buffered.
+func (s *TransactionStreamer) SubscribeMessageEvents(ch chan<- MessageEvent) event.Subscription {
+	sub := &messageEventSubscription{
+		streamer: s,
+		ch:       ch,
+		err:      make(chan error, 1),
+	}
+	s.messageEventMutex.Lock()
+	defer s.messageEventMutex.Unlock()
+	if s.messageEventSubscribers == nil {
+		s.messageEventSubscribers = make(map[*messageEventSubscription]struct{})
+	}
+	s.messageEventSubscribers[sub] = struct{}{}
+	return sub
+}
+
+func (s *TransactionStreamer) hasMessageEventSubscribers() bool {
+	s.messageEventMutex.Lock()
+	defer s.messageEventMutex.Unlock()
+	return len(s.messageEventSubscribers) > 0
+}
+
+// sendMessageEvent is called with the insertionMutex held, so it never waits for subscribers.
+func (s *TransactionStreamer) sendMessageEvent(ev MessageEvent) {
+	s.messageEventMutex.Lock()
+	defer s.messageEventMutex.Unlock()
+	for sub := range s.messageEventSubscribers {
+		select {
+		case sub.ch <- ev:
+		default:
+			delete(s.messageEventSubscribers, sub)
+			messageEventsDroppedCounter.Inc(1)
+			log.Warn("dropped message event subscriber that fell behind", "pos", ev.Index)
+			sub.end(&MessageEventsDroppedError{From: ev.Index})
+		}
+	}
+}
+
+// The batch writing the messages must be committed.
+func (s *TransactionStreamer) sendSequencedEvents(pos arbutil.MessageIndex, messages []arbostypes.MessageWithMetadataAndBlockHash) {
+	if !s.hasMessageEventSubscribers() {
+		return
+	}
+	chainId := s.chainConfig.ChainID.Uint64()
+	for i := range messages {
+		msgPos := pos + arbutil.MessageIndex(i)
+		digest, err := messages[i].MessageWithMeta.Hash(msgPos, chainId)
+		if err != nil {
+			log.Error("failed to hash sequenced message for subscribers", "pos", msgPos, "err", err)
+			continue
+		}
+		s.sendMessageEvent(MessageEvent{
+			Kind:      MessageEventSequenced,
+			Index:     msgPos,
+			Digest:    digest,
+			BlockHash: messages[i].BlockHash,
+		})
+	}
+}
+
+// The batch removing the messages must be committed.
+func (s *TransactionStreamer) sendReorgEvent(count arbutil.MessageIndex, prevCount arbutil.MessageIndex) {
+	if count >= prevCount || !s.hasMessageEventSubscribers() {
+		return
+	}
+	s.sendMessageEvent(MessageEvent{
+		Kind:             MessageEventReorged,
+		Index:            count,
+		PrevMessageCount: prevCount,
+	})
+}
+
+type RPCMessageEvent struct {
+	Kind             MessageEventKind `json:"kind"`
+	Index            hexutil.Uint64   `json:"index"`
+	Digest           *common.Hash     `json:"digest,omitempty"`
+	BlockHash        *common.Hash     `json:"blockHash,omitempty"`
+	PrevMessageCount *hexutil.Uint64  `json:"prevMessageCount,omitempty"`
+}
+
+func newRPCMessageEvent(ev *MessageEvent) *RPCMessageEvent {
+	result := &RPCMessageEvent{
+		Kind:  ev.Kind,
+		Index: hexutil.Uint64(ev.Index),
+	}
+	if ev.Kind == MessageEventSequenced {
+		digest := ev.Digest
+		result.Digest = &digest
+		result.BlockHash = ev.BlockHash
+	} else {
+		prevCount := hexutil.Uint64(ev.PrevMessageCount)
+		result.PrevMessageCount = &prevCount
+	}
+	return result
+}
+
+// MessageSubscriptionAPI lets indexers follow the streamer's messages over a websocket,
+// including the reorgs they'd miss by polling block numbers.
+type MessageSubscriptionAPI struct {
+	streamer *TransactionStreamer
+}
+
+// MessageEvents subscribes to arb_subscribe("messageEvents"), sending an event for every sequenced message and reorg.
+func (a *MessageSubscriptionAPI) MessageEvents(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+	events := make(chan MessageEvent, 1024)
+	sub := a.streamer.SubscribeMessageEvents(events)
+	go func() {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case ev := <-events:
+				if err := notifier.Notify(rpcSub.ID, newRPCMessageEvent(&ev)); err != nil {
+					return
+				}
+			case <-rpcSub.Err():
+				return
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package messagesink
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/IBM/sarama"
+)
+
+// KafkaSink produces records to a Kafka topic, waiting for every in-sync replica to acknowledge them.
+// The producer is idempotent, so records retried after a lost acknowledgement aren't stored twice.
+type KafkaSink struct {
+	producer sarama.AsyncProducer
+	topic    string
+
+	// Publish calls are serialized, so every acknowledgement belongs to the current or an abandoned batch
+	mutex sync.Mutex
+	batch uint64
+}
+
+// kafkaRecordId identifies a record in the acknowledgements the producer returns.
+type kafkaRecordId struct {
+	batch uint64
+	index int
+}
+
+func NewKafkaSink(config *Config) (*KafkaSink, error) {
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.ClientID = "nitro-message-sink"
+	saramaConfig.Version = sarama.V2_1_0_0
+	saramaConfig.Producer.RequiredAcks = sarama.WaitForAll
+	saramaConfig.Producer.Idempotent = true
+	saramaConfig.Producer.Return.Successes = true
+	saramaConfig.Producer.Return.Errors = true
+	saramaConfig.Net.MaxOpenRequests = 1
+	if config.TLS {
+		saramaConfig.Net.TLS.Enable = true
+		saramaConfig.Net.TLS.Config = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if config.Username != "" {
+		saramaConfig.Net.SASL.Enable = true
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		saramaConfig.Net.SASL.User = config.Username
+		saramaConfig.Net.SASL.Password = config.Password
+	}
+	producer, err := sarama.NewAsyncProducer(strings.Split(config.URL, ","), saramaConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &KafkaSink{producer: producer, topic: config.Topic}, nil
+}
+
+func (s *KafkaSink) Publish(ctx context.Context, records []Record) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.batch++
+	sent, acked := 0, 0
+	for acked < len(records) {
+		// the producer's inputs and acknowledgements are handled together, as it blocks on either when the other is full
+		var input chan<- *sarama.ProducerMessage
+		var message *sarama.ProducerMessage
+		if sent < len(records) {
+			input = s.producer.Input()
+			message = &sarama.ProducerMessage{
+				Topic:    s.topic,
+				Key:      sarama.StringEncoder(records[sent].Key),
+				Value:    sarama.ByteEncoder(records[sent].Value),
+				Metadata: kafkaRecordId{batch: s.batch, index: sent},
+			}
+		}
+		select {
+		case input <- message:
+			sent++
+		case message := <-s.producer.Successes():
+			if id, ok := message.Metadata.(kafkaRecordId); ok && id.batch == s.batch {
+				acked++
+			}
+		case err := <-s.producer.Errors():
+			if id, ok := err.Msg.Metadata.(kafkaRecordId); ok && id.batch == s.batch {
+				return fmt.Errorf("kafka rejected record %v: %w", records[id.index].Key, err.Err)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (s *KafkaSink) Close() error {
+	return s.producer.Close()
+}
@@ -0,0 +1,100 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package messagesink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const kafkaRestContentType = "application/vnd.kafka.json.v2+json"
+
+// KafkaRestSink produces records to a Kafka topic through the v2 API of a Kafka REST proxy.
+// The proxy only responds once the brokers acknowledged the records.
+type KafkaRestSink struct {
+	endpoint string
+	client   *http.Client
+}
+
+func NewKafkaRestSink(proxyURL string, topic string) *KafkaRestSink {
+	return &KafkaRestSink{
+		endpoint: strings.TrimSuffix(proxyURL, "/") + "/topics/" + url.PathEscape(topic),
+		client:   &http.Client{},
+	}
+}
+
+type kafkaRestRecord struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+type kafkaRestRequest struct {
+	Records []kafkaRestRecord `json:"records"`
+}
+
+type kafkaRestResponse struct {
+	Offsets []struct {
+		Partition *int32  `json:"partition"`
+		Offset    *int64  `json:"offset"`
+		ErrorCode *int    `json:"error_code"`
+		Error     *string `json:"error"`
+	} `json:"offsets"`
+}
+
+func (s *KafkaRestSink) Publish(ctx context.Context, records []Record) error {
+	request := kafkaRestRequest{Records: make([]kafkaRestRecord, 0, len(records))}
+	for _, record := range records {
+		request.Records = append(request.Records, kafkaRestRecord{Key: record.Key, Value: record.Value})
+	}
+	body, err := json.Marshal(&request)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", kafkaRestContentType)
+	httpReq.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("kafka rest proxy returned %v: %v", resp.Status, string(respBody))
+	}
+	var result kafkaRestResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("invalid kafka rest proxy response: %w", err)
+	}
+	if len(result.Offsets) != len(records) {
+		return fmt.Errorf("kafka rest proxy acknowledged %v of %v records", len(result.Offsets), len(records))
+	}
+	for i, offset := range result.Offsets {
+		if offset.Error != nil || offset.ErrorCode != nil {
+			description := ""
+			if offset.Error != nil {
+				description = *offset.Error
+			}
+			return fmt.Errorf("kafka rejected record %v: %v", records[i].Key, description)
+		}
+	}
+	return nil
+}
+
+func (s *KafkaRestSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+// Package messagesink publishes sequenced feed messages to external message queues.
+package messagesink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	flag "github.com/spf13/pflag"
+)
+
+const (
+	KindNats      = "nats"
+	KindKafka     = "kafka"
+	KindKafkaRest = "kafka-rest"
+)
+
+type Config struct {
+	Enable bool `koanf:"enable"`
+	// "nats" publishes to a JetStream stream, "kafka" to Kafka brokers, "kafka-rest" through a Kafka REST proxy
+	Kind             string        `koanf:"kind"`
+	URL              string        `koanf:"url"`
+	Topic            string        `koanf:"topic"`
+	TLS              bool          `koanf:"tls"`
+	Username         string        `koanf:"username"`
+	Password         string        `koanf:"password"`
+	ReorgCheckDepth  uint64        `koanf:"reorg-check-depth"`
+	MaxBatchMessages int           `koanf:"max-batch-messages" reload:"hot"`
+	Timeout          time.Duration `koanf:"timeout" reload:"hot"`
+	RetryInterval    time.Duration `koanf:"retry-interval" reload:"hot"`
+}
+
+type ConfigFetcher func() *Config
+
+var DefaultConfig = Config{
+	Enable:           false,
+	Kind:             KindNats,
+	URL:              "",
+	Topic:            "",
+	TLS:              false,
+	Username:         "",
+	Password:         "",
+	ReorgCheckDepth:  1024,
+	MaxBatchMessages: 256,
+	Timeout:          10 * time.Second,
+	RetryInterval:    time.Second,
+}
+
+func ConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultConfig.Enable, "mirror every sequenced message into a message queue, resuming after the last acknowledged message on restart")
+	f.String(prefix+".kind", DefaultConfig.Kind, "the message queue to publish to: \"nats\" for a NATS JetStream stream, \"kafka\" for a Kafka topic, or \"kafka-rest\" for a Kafka topic through a Kafka REST proxy")
+	f.String(prefix+".url", DefaultConfig.URL, "the NATS server (nats://[user:password@]host:port, or tls://host:port), comma separated Kafka brokers (host:port,host:port) or Kafka REST proxy (http(s)://host:port) to publish to")
+	f.String(prefix+".topic", DefaultConfig.Topic, "the NATS subject or Kafka topic to publish to")
+	f.Bool(prefix+".tls", DefaultConfig.TLS, "connect to the NATS server or Kafka brokers over TLS")
+	f.String(prefix+".username", DefaultConfig.Username, "username to authenticate to the NATS server, or to the Kafka brokers with SASL/PLAIN")
+	f.String(prefix+".password", DefaultConfig.Password, "password to authenticate to the NATS server or Kafka brokers with")
+	f.Uint64(prefix+".reorg-check-depth", DefaultConfig.ReorgCheckDepth, "how many published messages to remember, so messages reorged while the node was down are republished")
+	f.Int(prefix+".max-batch-messages", DefaultConfig.MaxBatchMessages, "maximum number of messages to publish before waiting for the queue's acknowledgement")
+	f.Duration(prefix+".timeout", DefaultConfig.Timeout, "how long the queue may take to acknowledge a batch of messages")
+	f.Duration(prefix+".retry-interval", DefaultConfig.RetryInterval, "how long to wait before publishing again after an error")
+}
+
+func (c *Config) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.Kind != KindNats && c.Kind != KindKafka && c.Kind != KindKafkaRest {
+		return fmt.Errorf("invalid message-sink.kind %q (expected \"%v\", \"%v\" or \"%v\")", c.Kind, KindNats, KindKafka, KindKafkaRest)
+	}
+	if c.URL == "" || c.Topic == "" {
+		return errors.New("message-sink.url and message-sink.topic must be set")
+	}
+	if c.MaxBatchMessages <= 0 {
+		return errors.New("message-sink.max-batch-messages must be positive")
+	}
+	return nil
+}
+
+// Record is a message to publish.
+type Record struct {
+	// the key messages are partitioned by, the message's sequence number
+	Key string
+	// identifies this exact message, so queues that deduplicate can drop republished messages
+	ID    string
+	Value []byte
+}
+
+// Sink publishes records to a message queue.
+type Sink interface {
+	// Publish returns once the queue has acknowledged storing every record, in order.
+	// After an error, some of the records may have been stored.
+	Publish(ctx context.Context, records []Record) error
+	Close() error
+}
+
+func New(config *Config) (Sink, error) {
+	switch config.Kind {
+	case KindNats:
+		return NewNatsSink(config)
+	case KindKafka:
+		return NewKafkaSink(config)
+	case KindKafkaRest:
+		return NewKafkaRestSink(config.URL, config.Topic), nil
+	default:
+		return nil, fmt.Errorf("unknown message sink kind %q", config.Kind)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package messagesink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func testRecords() []Record {
+	return []Record{
+		{Key: "1", ID: "1-a", Value: []byte(`{"version":1}`)},
+		{Key: "2", ID: "2-b", Value: []byte(`{"version":2}`)},
+	}
+}
+
+func TestKafkaRestSink(t *testing.T) {
+	var received kafkaRestRequest
+	var fail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/topics/sequenced" || r.Header.Get("Content-Type") != kafkaRestContentType {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if fail.Load() {
+			fmt.Fprint(w, `{"offsets":[{"partition":0,"offset":7},{"error_code":50003,"error":"broker unavailable"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"offsets":[{"partition":0,"offset":7},{"partition":0,"offset":8}]}`)
+	}))
+	defer server.Close()
+
+	sink := NewKafkaRestSink(server.URL, "sequenced")
+	defer sink.Close()
+	if err := sink.Publish(context.Background(), testRecords()); err != nil {
+		t.Fatal(err)
+	}
+	if len(received.Records) != 2 || received.Records[1].Key != "2" || string(received.Records[1].Value) != `{"version":2}` {
+		t.Fatal("unexpected records", received.Records)
+	}
+	fail.Store(true)
+	if err := sink.Publish(context.Background(), testRecords()); err == nil || !strings.Contains(err.Error(), "broker unavailable") {
+		t.Fatal("expected a rejected record to fail the publish, got", err)
+	}
+}
+
+func TestKafkaSink(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	produceResponse := sarama.NewMockProduceResponse(t)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("sequenced", 0, broker.BrokerID()),
+		"InitProducerIDRequest": sarama.NewMockInitProducerIDResponse(t),
+		"ProduceRequest":        produceResponse,
+	})
+
+	config := DefaultConfig
+	config.Kind = KindKafka
+	config.URL = broker.Addr()
+	config.Topic = "sequenced"
+	sink, err := NewKafkaSink(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := sink.Publish(ctx, testRecords()); err != nil {
+		t.Fatal(err)
+	}
+	produced := false
+	for _, exchange := range broker.History() {
+		if _, ok := exchange.Request.(*sarama.ProduceRequest); ok {
+			produced = true
+		}
+	}
+	if !produced {
+		t.Fatal("records acknowledged without being produced")
+	}
+
+	produceResponse.SetError("sequenced", 0, sarama.ErrMessageSizeTooLarge)
+	if err := sink.Publish(ctx, testRecords()); !errors.Is(err, sarama.ErrMessageSizeTooLarge) {
+		t.Fatal("expected a rejected record to fail the publish, got", err)
+	}
+}
+
+func TestNatsSink(t *testing.T) {
+	opts := natsserver.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	opts.Username = "sink"
+	opts.Password = "secret"
+	server := natsserver.RunServer(&opts)
+	defer server.Shutdown()
+	url := fmt.Sprintf("nats://sink:secret@%v", server.Addr())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn, err := nats.Connect(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	js, err := jetstream.New(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "SEQUENCED", Subjects: []string{"sequenced"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultConfig
+	config.URL = url
+	config.Topic = "sequenced"
+	sink, err := NewNatsSink(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	if err := sink.Publish(ctx, testRecords()); err != nil {
+		t.Fatal(err)
+	}
+	// republished records are dropped by their message ids
+	if err := sink.Publish(ctx, testRecords()); err != nil {
+		t.Fatal(err)
+	}
+	info, err := stream.Info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.State.Msgs != 2 {
+		t.Fatal("stream has", info.State.Msgs, "messages instead of 2")
+	}
+	for i, expected := range testRecords() {
+		msg, err := stream.GetMsg(ctx, uint64(i+1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.Header.Get(jetstream.MsgIDHeader) != expected.ID || string(msg.Data) != string(expected.Value) {
+			t.Fatal("stored message", i, "has id", msg.Header.Get(jetstream.MsgIDHeader), "instead of", expected.ID)
+		}
+	}
+
+	config.Topic = "uncaptured"
+	uncaptured, err := NewNatsSink(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer uncaptured.Close()
+	if err := uncaptured.Publish(ctx, testRecords()); err == nil {
+		t.Fatal("publishing to a subject no stream captures succeeded")
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/offchainlabs/nitro/arbnode/dataposter"
 	"github.com/offchainlabs/nitro/arbnode/dataposter/storage"
+	"github.com/offchainlabs/nitro/arbnode/messagesink"
 	"github.com/offchainlabs/nitro/arbnode/resourcemanager"
 	"github.com/offchainlabs/nitro/arbos/arbostypes"
 	"github.com/offchainlabs/nitro/arbstate/daprovider"
@@ -77,22 +78,28 @@ func GenerateRollupConfig(prod bool, wasmModuleRoot common.Hash, rollupOwner com
 }
 
 type Config struct {
-	Sequencer           bool                        `koanf:"sequencer"`
-	ParentChainReader   headerreader.Config         `koanf:"parent-chain-reader" reload:"hot"`
-	InboxReader         InboxReaderConfig           `koanf:"inbox-reader" reload:"hot"`
-	DelayedSequencer    DelayedSequencerConfig      `koanf:"delayed-sequencer" reload:"hot"`
-	BatchPoster         BatchPosterConfig           `koanf:"batch-poster" reload:"hot"`
-	MessagePruner       MessagePrunerConfig         `koanf:"message-pruner" reload:"hot"`
-	BlockValidator      staker.BlockValidatorConfig `koanf:"block-validator" reload:"hot"`
-	Feed                broadcastclient.FeedConfig  `koanf:"feed" reload:"hot"`
-	Staker              staker.L1ValidatorConfig    `koanf:"staker" reload:"hot"`
-	SeqCoordinator      SeqCoordinatorConfig        `koanf:"seq-coordinator"`
-	DataAvailability    das.DataAvailabilityConfig  `koanf:"data-availability"`
-	SyncMonitor         SyncMonitorConfig           `koanf:"sync-monitor"`
-	Dangerous           DangerousConfig             `koanf:"dangerous"`
-	TransactionStreamer TransactionStreamerConfig   `koanf:"transaction-streamer" reload:"hot"`
-	Maintenance         MaintenanceConfig           `koanf:"maintenance" reload:"hot"`
-	ResourceMgmt        resourcemanager.Config      `koanf:"resource-mgmt" reload:"hot"`
+	Sequencer           bool                          `koanf:"sequencer"`
+	ParentChainReader   headerreader.Config           `koanf:"parent-chain-reader" reload:"hot"`
+	InboxReader         InboxReaderConfig             `koanf:"inbox-reader" reload:"hot"`
+	DelayedSequencer    DelayedSequencerConfig        `koanf:"delayed-sequencer" reload:"hot"`
+	BatchPoster         BatchPosterConfig             `koanf:"batch-poster" reload:"hot"`
+	MessagePruner       MessagePrunerConfig           `koanf:"message-pruner" reload:"hot"`
+	BlockValidator      staker.BlockValidatorConfig   `koanf:"block-validator" reload:"hot"`
+	Feed                broadcastclient.FeedConfig    `koanf:"feed" reload:"hot"`
+	Staker              staker.L1ValidatorConfig      `koanf:"staker" reload:"hot"`
+	SeqCoordinator      SeqCoordinatorConfig          `koanf:"seq-coordinator"`
+	DataAvailability    das.DataAvailabilityConfig    `koanf:"data-availability"`
+	SyncMonitor         SyncMonitorConfig             `koanf:"sync-monitor"`
+	SoftConfirmation    SoftConfirmationMonitorConfig `koanf:"soft-confirmation-monitor" reload:"hot"`
+	Dangerous           DangerousConfig               `koanf:"dangerous"`
+	TransactionStreamer TransactionStreamerConfig     `koanf:"transaction-streamer" reload:"hot"`
+	Maintenance         MaintenanceConfig             `koanf:"maintenance" reload:"hot"`
+	ResourceMgmt        resourcemanager.Config        `koanf:"resource-mgmt" reload:"hot"`
+	BridgeEvents        BridgeEventsConfig            `koanf:"bridge-events" reload:"hot"`
+	DASMonitor          DASMisbehaviorMonitorConfig   `koanf:"das-misbehavior-monitor" reload:"hot"`
+	BlockWitness        BlockWitnessConfig            `koanf:"block-witness"`
+	ReceiptFinality     ReceiptFinalityConfig         `koanf:"receipt-finality" reload:"hot"`
+	MessageSink         messagesink.Config            `koanf:"message-sink" reload:"hot"`
 	// SnapSyncConfig is only used for testing purposes, these should not be configured in production.
 	SnapSyncTest SnapSyncConfig
 }
@@ -104,6 +111,9 @@ func (c *Config) Validate() error {
 	if c.DelayedSequencer.Enable && !c.Sequencer {
 		return errors.New("cannot enable delayed sequencer without enabling sequencer")
 	}
+	if c.DelayedSequencer.Dangerous.IgnoreCoordinator && !c.SeqCoordinator.Enable {
+		log.Warn("delayed-sequencer.dangerous.ignore-coordinator has no effect without a sequencer coordinator")
+	}
 	if c.InboxReader.ReadMode != "latest" {
 		if c.Sequencer {
 			return errors.New("cannot enable inboxreader in safe or finalized mode along with sequencer")
@@ -117,6 +127,9 @@ func (c *Config) Validate() error {
 	if err := c.Maintenance.Validate(); err != nil {
 		return err
 	}
+	if err := c.TransactionStreamer.Validate(); err != nil {
+		return err
+	}
 	if err := c.InboxReader.Validate(); err != nil {
 		return err
 	}
@@ -129,6 +142,18 @@ func (c *Config) Validate() error {
 	if err := c.Staker.Validate(); err != nil {
 		return err
 	}
+	if err := c.BridgeEvents.Validate(); err != nil {
+		return err
+	}
+	if err := c.DASMonitor.Validate(); err != nil {
+		return err
+	}
+	if err := c.BlockWitness.Validate(); err != nil {
+		return err
+	}
+	if err := c.MessageSink.Validate(); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -155,9 +180,15 @@ func ConfigAddOptions(prefix string, f *flag.FlagSet, feedInputEnable bool, feed
 	SeqCoordinatorConfigAddOptions(prefix+".seq-coordinator", f)
 	das.DataAvailabilityConfigAddNodeOptions(prefix+".data-availability", f)
 	SyncMonitorConfigAddOptions(prefix+".sync-monitor", f)
+	SoftConfirmationMonitorConfigAddOptions(prefix+".soft-confirmation-monitor", f)
 	DangerousConfigAddOptions(prefix+".dangerous", f)
 	TransactionStreamerConfigAddOptions(prefix+".transaction-streamer", f)
 	MaintenanceConfigAddOptions(prefix+".maintenance", f)
+	BridgeEventsConfigAddOptions(prefix+".bridge-events", f)
+	DASMisbehaviorMonitorConfigAddOptions(prefix+".das-misbehavior-monitor", f)
+	BlockWitnessConfigAddOptions(prefix+".block-witness", f)
+	ReceiptFinalityConfigAddOptions(prefix+".receipt-finality", f)
+	messagesink.ConfigAddOptions(prefix+".message-sink", f)
 }
 
 var ConfigDefault = Config{
@@ -173,10 +204,16 @@ var ConfigDefault = Config{
 	SeqCoordinator:      DefaultSeqCoordinatorConfig,
 	DataAvailability:    das.DefaultDataAvailabilityConfig,
 	SyncMonitor:         DefaultSyncMonitorConfig,
+	SoftConfirmation:    DefaultSoftConfirmationMonitorConfig,
 	Dangerous:           DefaultDangerousConfig,
 	TransactionStreamer: DefaultTransactionStreamerConfig,
 	ResourceMgmt:        resourcemanager.DefaultConfig,
 	Maintenance:         DefaultMaintenanceConfig,
+	BridgeEvents:        DefaultBridgeEventsConfig,
+	DASMonitor:          DefaultDASMisbehaviorMonitorConfig,
+	BlockWitness:        DefaultBlockWitnessConfig,
+	ReceiptFinality:     DefaultReceiptFinalityConfig,
+	MessageSink:         messagesink.DefaultConfig,
 	SnapSyncTest:        DefaultSnapSyncConfig,
 }
 
@@ -201,6 +238,7 @@ func ConfigDefaultL1NonSequencerTest() *Config {
 	config.SeqCoordinator.Enable = false
 	config.BlockValidator = staker.TestBlockValidatorConfig
 	config.SyncMonitor = TestSyncMonitorConfig
+	config.SoftConfirmation = TestSoftConfirmationMonitorConfig
 	config.Staker = staker.TestL1ValidatorConfig
 	config.Staker.Enable = false
 	config.BlockValidator.ValidationServerConfigs = []rpcclient.ClientConfig{{URL: ""}}
@@ -263,6 +301,10 @@ type Node struct {
 	DelayedSequencer        *DelayedSequencer
 	BatchPoster             *BatchPoster
 	MessagePruner           *MessagePruner
+	SoftConfirmationMonitor *SoftConfirmationMonitor
+	ReceiptFinality         *ReceiptFinalityTracker
+	MessageSink             *MessageSinkPublisher
+	BridgeEvents            *BridgeEventWatcher
 	BlockValidator          *staker.BlockValidator
 	StatelessBlockValidator *staker.StatelessBlockValidator
 	Staker                  *staker.Staker
@@ -271,6 +313,8 @@ type Node struct {
 	SeqCoordinator          *SeqCoordinator
 	MaintenanceRunner       *MaintenanceRunner
 	DASLifecycleManager     *das.LifecycleManager
+	DASCertificates         *DASCertificateInspector
+	DASMonitor              *DASMisbehaviorMonitor
 	SyncMonitor             *SyncMonitor
 	configFetcher           ConfigFetcher
 	ctx                     context.Context
@@ -433,6 +477,13 @@ func createNodeImpl(
 	if err != nil {
 		return nil, err
 	}
+	var messageSink *MessageSinkPublisher
+	if config.MessageSink.Enable {
+		messageSink, err = NewMessageSinkPublisher(txStreamer, func() *messagesink.Config { return &configFetcher.Get().MessageSink })
+		if err != nil {
+			return nil, err
+		}
+	}
 	var coordinator *SeqCoordinator
 	var bpVerifier *contracts.AddressVerifier
 	if deployInfo != nil && l1client != nil {
@@ -450,6 +501,9 @@ func createNodeImpl(
 		if err != nil {
 			return nil, err
 		}
+		if config.SeqCoordinator.Health.MinFreeDiskMB > 0 {
+			coordinator.AddHealthCheck(NewDiskSpaceHealthCheck(stack.InstanceDir(), config.SeqCoordinator.Health.MinFreeDiskMB))
+		}
 	} else if config.Sequencer && !config.Dangerous.NoSequencerCoordinator {
 		return nil, errors.New("sequencer must be enabled with coordinator, unless dangerous.no-sequencer-coordinator set")
 	}
@@ -461,6 +515,16 @@ func createNodeImpl(
 
 	var broadcastClients *broadcastclients.BroadcastClients
 	if config.Feed.Input.Enable() {
+		var keyRegistry contracts.FeedKeyRegistryInterface
+		if config.Feed.Input.SigningKeys.RegistryAddress != "" {
+			if l1client == nil {
+				return nil, errors.New("feed signing key registry requires a parent chain connection")
+			}
+			keyRegistry, err = contracts.NewFeedKeyRegistry(common.HexToAddress(config.Feed.Input.SigningKeys.RegistryAddress), l1client)
+			if err != nil {
+				return nil, err
+			}
+		}
 		currentMessageCount, err := txStreamer.GetMessageCount()
 		if err != nil {
 			return nil, err
@@ -474,6 +538,7 @@ func createNodeImpl(
 			nil,
 			fatalErrChan,
 			bpVerifier,
+			keyRegistry,
 		)
 		if err != nil {
 			return nil, err
@@ -494,6 +559,10 @@ func createNodeImpl(
 			DelayedSequencer:        nil,
 			BatchPoster:             nil,
 			MessagePruner:           nil,
+			SoftConfirmationMonitor: nil,
+			ReceiptFinality:         nil,
+			MessageSink:             messageSink,
+			BridgeEvents:            nil,
 			BlockValidator:          nil,
 			StatelessBlockValidator: nil,
 			Staker:                  nil,
@@ -502,6 +571,8 @@ func createNodeImpl(
 			SeqCoordinator:          coordinator,
 			MaintenanceRunner:       maintenanceRunner,
 			DASLifecycleManager:     nil,
+			DASCertificates:         nil,
+			DASMonitor:              nil,
 			SyncMonitor:             syncMonitor,
 			configFetcher:           configFetcher,
 			ctx:                     ctx,
@@ -569,6 +640,20 @@ func createNodeImpl(
 		return nil, err
 	}
 	txStreamer.SetInboxReaders(inboxReader, delayedBridge)
+	var dasCertificates *DASCertificateInspector
+	if daReader != nil && dasKeysetFetcher != nil {
+		dasCertificates = NewDASCertificateInspector(inboxReader, inboxTracker, daReader, dasKeysetFetcher)
+	}
+	var dasMonitor *DASMisbehaviorMonitor
+	if config.DASMonitor.Enable {
+		if dasCertificates == nil {
+			return nil, errors.New("das misbehavior monitor requires data availability to be enabled")
+		}
+		dasMonitor, err = NewDASMisbehaviorMonitor(arbDb, dasCertificates, inboxTracker, func() *DASMisbehaviorMonitorConfig { return &configFetcher.Get().DASMonitor })
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	var statelessBlockValidator *staker.StatelessBlockValidator
 	if config.BlockValidator.RedisValidationClientConfig.Enabled() || config.BlockValidator.ValidationServerConfigs[0].URL != "" {
@@ -607,6 +692,14 @@ func createNodeImpl(
 		}
 	}
 
+	var receiptFinality *ReceiptFinalityTracker
+	if config.ReceiptFinality.Enable {
+		receiptFinality, err = NewReceiptFinalityTracker(inboxTracker, txStreamer, l1Reader, deployInfo.Rollup, config.Staker.Enable, func() *ReceiptFinalityConfig { return &configFetcher.Get().ReceiptFinality })
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var stakerObj *staker.Staker
 	var messagePruner *MessagePruner
 	var stakerAddr common.Address
@@ -659,6 +752,9 @@ func createNodeImpl(
 			messagePruner = NewMessagePruner(txStreamer, inboxTracker, func() *MessagePrunerConfig { return &configFetcher.Get().MessagePruner })
 			confirmedNotifiers = append(confirmedNotifiers, messagePruner)
 		}
+		if receiptFinality != nil {
+			confirmedNotifiers = append(confirmedNotifiers, receiptFinality)
+		}
 
 		stakerObj, err = staker.NewStaker(l1Reader, wallet, bind.CallOpts{}, config.Staker, blockValidator, statelessBlockValidator, nil, confirmedNotifiers, deployInfo.ValidatorUtils, fatalErrChan)
 		if err != nil {
@@ -689,6 +785,7 @@ func createNodeImpl(
 		}
 		batchPoster, err = NewBatchPoster(ctx, &BatchPosterOpts{
 			DataPosterDB:  rawdb.NewTable(arbDb, storage.BatchPosterPrefix),
+			IntentDB:      rawdb.NewTable(arbDb, storage.BatchPosterIntentPrefix),
 			L1Reader:      l1Reader,
 			Inbox:         inboxTracker,
 			Streamer:      txStreamer,
@@ -711,6 +808,19 @@ func createNodeImpl(
 		}
 	}
 
+	var softConfirmationMonitor *SoftConfirmationMonitor
+	if config.SoftConfirmation.Enable {
+		softConfirmationMonitor = NewSoftConfirmationMonitor(txStreamer, inboxTracker, l1Reader, func() *SoftConfirmationMonitorConfig { return &configFetcher.Get().SoftConfirmation })
+	}
+
+	var bridgeEvents *BridgeEventWatcher
+	if config.BridgeEvents.Enable {
+		bridgeEvents, err = NewBridgeEventWatcher(arbDb, inboxTracker, l1Reader, deployInfo.Rollup, func() *BridgeEventsConfig { return &configFetcher.Get().BridgeEvents })
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// always create DelayedSequencer, it won't do anything if it is disabled
 	delayedSequencer, err = NewDelayedSequencer(l1Reader, inboxReader, exec, coordinator, func() *DelayedSequencerConfig { return &configFetcher.Get().DelayedSequencer })
 	if err != nil {
@@ -730,6 +840,10 @@ func createNodeImpl(
 		DelayedSequencer:        delayedSequencer,
 		BatchPoster:             batchPoster,
 		MessagePruner:           messagePruner,
+		SoftConfirmationMonitor: softConfirmationMonitor,
+		ReceiptFinality:         receiptFinality,
+		MessageSink:             messageSink,
+		BridgeEvents:            bridgeEvents,
 		BlockValidator:          blockValidator,
 		StatelessBlockValidator: statelessBlockValidator,
 		Staker:                  stakerObj,
@@ -738,6 +852,8 @@ func createNodeImpl(
 		SeqCoordinator:          coordinator,
 		MaintenanceRunner:       maintenanceRunner,
 		DASLifecycleManager:     dasLifecycleManager,
+		DASCertificates:         dasCertificates,
+		DASMonitor:              dasMonitor,
 		SyncMonitor:             syncMonitor,
 		configFetcher:           configFetcher,
 		ctx:                     ctx,
@@ -778,6 +894,113 @@ func CreateNode(
 			Public:    false,
 		})
 	}
+	if currentNode.DelayedSequencer != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "arb",
+			Version:   "1.0",
+			Service:   &DelayedSequencerAPI{delayedSequencer: currentNode.DelayedSequencer},
+			Public:    false,
+		})
+		apis = append(apis, rpc.API{
+			Namespace: "admin",
+			Version:   "1.0",
+			Service:   &DelayedSequencerAdminAPI{delayedSequencer: currentNode.DelayedSequencer},
+			Public:    false,
+		})
+	}
+	if currentNode.TxStreamer != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "admin",
+			Version:   "1.0",
+			Service:   &MessageQuarantineAdminAPI{streamer: currentNode.TxStreamer},
+			Public:    false,
+		})
+	}
+	if currentNode.SeqCoordinator != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "admin",
+			Version:   "1.0",
+			Service:   &SeqCoordinatorAdminAPI{coordinator: currentNode.SeqCoordinator},
+			Public:    false,
+		})
+	}
+	if currentNode.DASCertificates != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "arb",
+			Version:   "1.0",
+			Service:   &DASCertificateAPI{inspector: currentNode.DASCertificates, inboxTracker: currentNode.InboxTracker, genesisBlockNum: l2Config.ArbitrumChainParams.GenesisBlockNum},
+			Public:    false,
+		})
+	}
+	if currentNode.DASMonitor != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "arb",
+			Version:   "1.0",
+			Service:   &DASMisbehaviorAPI{monitor: currentNode.DASMonitor},
+			Public:    false,
+		})
+	}
+	if currentNode.InboxTracker != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "arb",
+			Version:   "1.0",
+			Service:   &InclusionStatsAPI{stats: currentNode.InboxTracker.InclusionStats()},
+			Public:    false,
+		})
+		apis = append(apis, rpc.API{
+			Namespace: "arb",
+			Version:   "1.0",
+			Service:   &InboxAPI{tracker: currentNode.InboxTracker},
+			Public:    false,
+		})
+	}
+	if currentNode.SoftConfirmationMonitor != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "arb",
+			Version:   "1.0",
+			Service:   &SoftConfirmationAPI{monitor: currentNode.SoftConfirmationMonitor, genesisBlockNum: l2Config.ArbitrumChainParams.GenesisBlockNum},
+			Public:    false,
+		})
+	}
+	if execNode, ok := exec.(*gethexec.ExecutionNode); ok && currentNode.ReceiptFinality != nil {
+		// registered after the execution node's APIs, so this takes over their eth_getTransactionReceipt
+		apis = append(apis, rpc.API{
+			Namespace: "eth",
+			Version:   "1.0",
+			Service:   NewReceiptFinalityAPI(currentNode.ReceiptFinality, execNode.EthClient, l2Config.ArbitrumChainParams.GenesisBlockNum),
+			Public:    true,
+		})
+	}
+	if currentNode.Staker != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "arb",
+			Version:   "1.0",
+			Service:   &AssertionAPI{staker: currentNode.Staker},
+			Public:    false,
+		})
+	}
+	if currentNode.TxStreamer != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "arb",
+			Version:   "1.0",
+			Service:   &MessageSubscriptionAPI{streamer: currentNode.TxStreamer},
+			Public:    false,
+		})
+		apis = append(apis, rpc.API{
+			Namespace: "arb",
+			Version:   "1.0",
+			Service:   &FeedMessagesAPI{streamer: currentNode.TxStreamer},
+			Public:    false,
+		})
+	}
+	if config := configFetcher.Get(); config.BlockWitness.Enable && currentNode.TxStreamer != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "arb",
+			Version:   "1.0",
+			Service:   &BlockWitnessAPI{server: NewBlockWitnessServer(currentNode.TxStreamer, currentNode.Execution, &config.BlockWitness)},
+			Public:    false,
+		})
+	}
 	if currentNode.StatelessBlockValidator != nil {
 		apis = append(apis, rpc.API{
 			Namespace: "arbdebug",
@@ -789,6 +1012,13 @@ func CreateNode(
 		})
 	}
 
+	apis = append(apis, rpc.API{
+		Namespace: "arbdebug",
+		Version:   "1.0",
+		Service:   &ProfilingAPI{},
+		Public:    false,
+	})
+
 	stack.RegisterAPIs(apis)
 
 	return currentNode, nil
@@ -877,6 +1107,21 @@ func (n *Node) Start(ctx context.Context) error {
 	if n.MessagePruner != nil {
 		n.MessagePruner.Start(ctx)
 	}
+	if n.SoftConfirmationMonitor != nil {
+		n.SoftConfirmationMonitor.Start(ctx)
+	}
+	if n.ReceiptFinality != nil {
+		n.ReceiptFinality.Start(ctx)
+	}
+	if n.MessageSink != nil {
+		n.MessageSink.Start(ctx)
+	}
+	if n.DASMonitor != nil {
+		n.DASMonitor.Start(ctx)
+	}
+	if n.BridgeEvents != nil {
+		n.BridgeEvents.Start(ctx)
+	}
 	if n.Staker != nil {
 		err = n.Staker.Initialize(ctx)
 		if err != nil {
@@ -951,6 +1196,21 @@ func (n *Node) StopAndWait() {
 	if n.MessagePruner != nil && n.MessagePruner.Started() {
 		n.MessagePruner.StopAndWait()
 	}
+	if n.SoftConfirmationMonitor != nil && n.SoftConfirmationMonitor.Started() {
+		n.SoftConfirmationMonitor.StopAndWait()
+	}
+	if n.ReceiptFinality != nil && n.ReceiptFinality.Started() {
+		n.ReceiptFinality.StopAndWait()
+	}
+	if n.MessageSink != nil && n.MessageSink.Started() {
+		n.MessageSink.StopAndWait()
+	}
+	if n.DASMonitor != nil && n.DASMonitor.Started() {
+		n.DASMonitor.StopAndWait()
+	}
+	if n.BridgeEvents != nil && n.BridgeEvents.Started() {
+		n.BridgeEvents.StopAndWait()
+	}
 	if n.BroadcastServer != nil && n.BroadcastServer.Started() {
 		n.BroadcastServer.StopAndWait()
 	}
@@ -1020,6 +1280,13 @@ func (n *Node) GetFinalizedMsgCount(ctx context.Context) (arbutil.MessageIndex,
 	return n.InboxReader.GetFinalizedMsgCount(ctx)
 }
 
+func (n *Node) GetPublishedMsgCount(ctx context.Context) (arbutil.MessageIndex, error) {
+	if n.SeqCoordinator == nil {
+		return 0, errors.New("sequencer coordinator not set up")
+	}
+	return n.SeqCoordinator.PublishedMsgCount(), nil
+}
+
 func (n *Node) WriteMessageFromSequencer(pos arbutil.MessageIndex, msgWithMeta arbostypes.MessageWithMetadata, msgResult execution.MessageResult) error {
 	return n.TxStreamer.WriteMessageFromSequencer(pos, msgWithMeta, msgResult)
 }
@@ -0,0 +1,205 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/staker"
+	"github.com/offchainlabs/nitro/util/headerreader"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+	"github.com/offchainlabs/nitro/validator"
+)
+
+type ReceiptFinalityStatus string
+
+const (
+	// the message is only known through the sequencer's word
+	ReceiptFinalitySoft ReceiptFinalityStatus = "soft"
+	// the message was posted to the parent chain in a batch
+	ReceiptFinalityPosted ReceiptFinalityStatus = "posted"
+	// the message is covered by the latest confirmed assertion
+	ReceiptFinalityConfirmed ReceiptFinalityStatus = "confirmed"
+)
+
+type ReceiptFinalityConfig struct {
+	Enable       bool          `koanf:"enable"`
+	PollInterval time.Duration `koanf:"poll-interval" reload:"hot"`
+}
+
+type ReceiptFinalityConfigFetcher func() *ReceiptFinalityConfig
+
+var DefaultReceiptFinalityConfig = ReceiptFinalityConfig{
+	Enable:       false,
+	PollInterval: time.Minute,
+}
+
+func ReceiptFinalityConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultReceiptFinalityConfig.Enable, "add a finalityStatus field (soft, posted or confirmed) to eth_getTransactionReceipt results")
+	f.Duration(prefix+".poll-interval", DefaultReceiptFinalityConfig.PollInterval, "how often to read the latest confirmed assertion from the parent chain when the staker isn't enabled")
+}
+
+// ReceiptFinalityTracker tells how final the message producing a block is: soft confirmed by the sequencer,
+// posted to the parent chain in a batch, or confirmed by an assertion.
+// When the node runs a staker, it's notified of the latest confirmed assertion like the message pruner,
+// otherwise it reads it from the rollup contract itself.
+type ReceiptFinalityTracker struct {
+	stopwaiter.StopWaiter
+	inboxTracker *InboxTracker
+	txStreamer   *TransactionStreamer
+	// nil when the staker notifies the tracker
+	rollup *staker.RollupWatcher
+	config ReceiptFinalityConfigFetcher
+
+	confirmedMsgCount atomic.Uint64
+}
+
+func NewReceiptFinalityTracker(inboxTracker *InboxTracker, txStreamer *TransactionStreamer, l1Reader *headerreader.HeaderReader, rollupAddress common.Address, notifiedByStaker bool, config ReceiptFinalityConfigFetcher) (*ReceiptFinalityTracker, error) {
+	tracker := &ReceiptFinalityTracker{
+		inboxTracker: inboxTracker,
+		txStreamer:   txStreamer,
+		config:       config,
+	}
+	if !notifiedByStaker {
+		rollup, err := staker.NewRollupWatcher(rollupAddress, l1Reader.Client(), bind.CallOpts{})
+		if err != nil {
+			return nil, err
+		}
+		tracker.rollup = rollup
+	}
+	return tracker, nil
+}
+
+func (t *ReceiptFinalityTracker) Start(ctxIn context.Context) {
+	t.StopWaiter.Start(ctxIn, t)
+	if t.rollup == nil {
+		return
+	}
+	t.CallIteratively(func(ctx context.Context) time.Duration {
+		if err := t.pollLatestConfirmed(ctx); err != nil && ctx.Err() == nil {
+			log.Warn("failed to read latest confirmed assertion for receipt finality", "err", err)
+		}
+		return t.config().PollInterval
+	})
+}
+
+func (t *ReceiptFinalityTracker) UpdateLatestConfirmed(count arbutil.MessageIndex, _ validator.GoGlobalState) {
+	t.setConfirmedMsgCount(count)
+}
+
+// setConfirmedMsgCount only moves the confirmed message count forward, confirmed assertions are never reverted.
+func (t *ReceiptFinalityTracker) setConfirmedMsgCount(count arbutil.MessageIndex) {
+	for {
+		current := t.confirmedMsgCount.Load()
+		if uint64(count) <= current || t.confirmedMsgCount.CompareAndSwap(current, uint64(count)) {
+			return
+		}
+	}
+}
+
+// latestConfirmedAssertion looks up the rollup's latest confirmed assertion, or returns nil while only the
+// genesis assertion is confirmed.
+func latestConfirmedAssertion(ctx context.Context, rollup *staker.RollupWatcher) (*staker.NodeInfo, error) {
+	latestConfirmed, err := rollup.LatestConfirmed(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, err
+	}
+	if latestConfirmed == 0 {
+		return nil, nil
+	}
+	info, err := rollup.LookupNode(ctx, latestConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't look up latest confirmed assertion %v: %w", latestConfirmed, err)
+	}
+	return info, nil
+}
+
+func (t *ReceiptFinalityTracker) pollLatestConfirmed(ctx context.Context) error {
+	info, err := latestConfirmedAssertion(ctx, t.rollup)
+	if err != nil || info == nil {
+		return err
+	}
+	caughtUp, count, err := staker.GlobalStateToMsgCount(t.inboxTracker, t.txStreamer, info.AfterState().GlobalState)
+	if err != nil {
+		return err
+	}
+	if caughtUp {
+		t.setConfirmedMsgCount(count)
+	}
+	return nil
+}
+
+// MessageFinality returns how final the message at pos is.
+func (t *ReceiptFinalityTracker) MessageFinality(pos arbutil.MessageIndex) (ReceiptFinalityStatus, error) {
+	if uint64(pos) < t.confirmedMsgCount.Load() {
+		return ReceiptFinalityConfirmed, nil
+	}
+	batchCount, err := t.inboxTracker.GetBatchCount()
+	if err != nil || batchCount == 0 {
+		return ReceiptFinalitySoft, err
+	}
+	batchedCount, err := t.inboxTracker.GetBatchMessageCount(batchCount - 1)
+	if err != nil {
+		return "", err
+	}
+	if pos < batchedCount {
+		return ReceiptFinalityPosted, nil
+	}
+	return ReceiptFinalitySoft, nil
+}
+
+// ReceiptFinalityAPI serves eth_getTransactionReceipt with a finalityStatus field, so consumers can tell
+// whether to act on a receipt without querying batch and assertion endpoints too.
+// It's registered after the execution node's APIs, so it takes over their method, and adds the field to the
+// receipt they return.
+type ReceiptFinalityAPI struct {
+	tracker *ReceiptFinalityTracker
+	// serves the execution node's eth namespace
+	eth             *rpc.Client
+	genesisBlockNum uint64
+}
+
+func NewReceiptFinalityAPI(tracker *ReceiptFinalityTracker, eth *rpc.Client, genesisBlockNum uint64) *ReceiptFinalityAPI {
+	return &ReceiptFinalityAPI{tracker, eth, genesisBlockNum}
+}
+
+// GetTransactionReceipt returns the execution node's receipt of a transaction, along with its finalityStatus.
+func (api *ReceiptFinalityAPI) GetTransactionReceipt(ctx context.Context, hash common.Hash) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := api.eth.CallContext(ctx, &fields, "eth_getTransactionReceipt", hash); err != nil || fields == nil {
+		return nil, err
+	}
+	var blockNumber hexutil.Uint64
+	if err := json.Unmarshal(fields["blockNumber"], &blockNumber); err != nil {
+		return nil, fmt.Errorf("receipt of transaction %v has an invalid block number: %w", hash, err)
+	}
+	status, err := api.finality(uint64(blockNumber))
+	if err != nil {
+		return nil, err
+	}
+	fields["finalityStatus"], err = json.Marshal(status)
+	return fields, err
+}
+
+func (api *ReceiptFinalityAPI) finality(blockNumber uint64) (ReceiptFinalityStatus, error) {
+	if blockNumber < api.genesisBlockNum {
+		// classic blocks were final before the chain's nitro genesis
+		return ReceiptFinalityConfirmed, nil
+	}
+	pos := arbutil.BlockNumberToMessageCount(blockNumber, api.genesisBlockNum) - 1
+	return api.tracker.MessageFinality(pos)
+}
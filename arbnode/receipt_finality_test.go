@@ -0,0 +1,120 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/util/containers"
+	"github.com/offchainlabs/nitro/validator"
+)
+
+// newTestFinalityTracker returns a tracker of an inbox with no batches yet, and a function to write the inbox's
+// database.
+func newTestFinalityTracker(t *testing.T) (*ReceiptFinalityTracker, func(key []byte, value interface{})) {
+	t.Helper()
+	inboxTracker := &InboxTracker{
+		db:        rawdb.NewMemoryDatabase(),
+		batchMeta: containers.NewLruCache[uint64, BatchMetadata](100),
+	}
+	put := func(key []byte, value interface{}) {
+		t.Helper()
+		data, err := rlp.EncodeToBytes(value)
+		Require(t, err)
+		Require(t, inboxTracker.db.Put(key, data))
+	}
+	return &ReceiptFinalityTracker{inboxTracker: inboxTracker}, put
+}
+
+func TestReceiptMessageFinality(t *testing.T) {
+	tracker, put := newTestFinalityTracker(t)
+	expect := func(pos arbutil.MessageIndex, expected ReceiptFinalityStatus) {
+		t.Helper()
+		status, err := tracker.MessageFinality(pos)
+		Require(t, err)
+		if status != expected {
+			Fail(t, "message", pos, "is", status, "instead of", expected)
+		}
+	}
+
+	put(sequencerBatchCountKey, uint64(0))
+	expect(0, ReceiptFinalitySoft)
+
+	// two batches posting messages up to 6
+	put(dbKey(sequencerBatchMetaPrefix, 0), BatchMetadata{MessageCount: 3})
+	put(dbKey(sequencerBatchMetaPrefix, 1), BatchMetadata{MessageCount: 6})
+	put(sequencerBatchCountKey, uint64(2))
+	expect(0, ReceiptFinalityPosted)
+	expect(5, ReceiptFinalityPosted)
+	expect(6, ReceiptFinalitySoft)
+
+	tracker.UpdateLatestConfirmed(3, validator.GoGlobalState{})
+	expect(2, ReceiptFinalityConfirmed)
+	expect(3, ReceiptFinalityPosted)
+
+	// an older confirmation doesn't move finality back
+	tracker.UpdateLatestConfirmed(1, validator.GoGlobalState{})
+	expect(2, ReceiptFinalityConfirmed)
+}
+
+// testReceiptEthAPI stands in for the execution node's eth API.
+type testReceiptEthAPI struct{}
+
+func (api *testReceiptEthAPI) GetTransactionReceipt(hash common.Hash) map[string]interface{} {
+	if hash == (common.Hash{}) {
+		return nil
+	}
+	return map[string]interface{}{
+		"transactionHash": hash,
+		"blockNumber":     hexutil.Uint64(hash.Big().Uint64()),
+		"blobGasUsed":     hexutil.Uint64(1),
+	}
+}
+
+func TestReceiptFinalityAPI(t *testing.T) {
+	tracker, put := newTestFinalityTracker(t)
+	put(dbKey(sequencerBatchMetaPrefix, 0), BatchMetadata{MessageCount: 3})
+	put(sequencerBatchCountKey, uint64(1))
+	server := rpc.NewServer()
+	Require(t, server.RegisterName("eth", &testReceiptEthAPI{}))
+	defer server.Stop()
+	client := rpc.DialInProc(server)
+	defer client.Close()
+	// the nitro genesis is block 10, so its message is the first
+	api := NewReceiptFinalityAPI(tracker, client, 10)
+
+	expect := func(blockNumber int64, expected ReceiptFinalityStatus) {
+		t.Helper()
+		fields, err := api.GetTransactionReceipt(context.Background(), common.BigToHash(big.NewInt(blockNumber)))
+		Require(t, err)
+		var status ReceiptFinalityStatus
+		Require(t, json.Unmarshal(fields["finalityStatus"], &status))
+		if status != expected {
+			Fail(t, "receipt in block", blockNumber, "is", status, "instead of", expected)
+		}
+		// the rest of the receipt is the execution node's
+		if string(fields["blobGasUsed"]) != `"0x1"` {
+			Fail(t, "receipt lost its fields", fields)
+		}
+	}
+	expect(5, ReceiptFinalityConfirmed)
+	expect(12, ReceiptFinalityPosted)
+	expect(13, ReceiptFinalitySoft)
+
+	fields, err := api.GetTransactionReceipt(context.Background(), common.Hash{})
+	Require(t, err)
+	if fields != nil {
+		Fail(t, "unknown transaction has a receipt", fields)
+	}
+}
@@ -70,6 +70,13 @@ var DefaultCfg = SimpleCfg{
 }
 
 func (l *Simple) attemptLock(ctx context.Context) (bool, error) {
+	return l.lockIf(ctx, func(current string) bool {
+		return current == "" || current == l.myId
+	})
+}
+
+// lockIf sets the lock to this node if canTake accepts its current holder, "" if it's free.
+func (l *Simple) lockIf(ctx context.Context, canTake func(current string) bool) (bool, error) {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 	if l.stopping || l.client == nil {
@@ -91,7 +98,7 @@ func (l *Simple) attemptLock(ctx context.Context) (bool, error) {
 		if err != nil {
 			return err
 		}
-		if current != "" && (current != l.myId) {
+		if !canTake(current) {
 			return nil
 		}
 		pipe := tx.TxPipeline()
@@ -124,6 +131,46 @@ func (l *Simple) attemptLock(ctx context.Context) (bool, error) {
 	return gotLock, nil
 }
 
+// TryLock acquires the lock if it's free, or extends it if this node holds it, checking redis even if
+// the lock was acquired recently.
+func (l *Simple) TryLock(ctx context.Context) (bool, error) {
+	return l.attemptLock(ctx)
+}
+
+// TakeOver takes the lock from holder if it still holds it, for callers that decided it's unfit to.
+func (l *Simple) TakeOver(ctx context.Context, holder string) (bool, error) {
+	return l.lockIf(ctx, func(current string) bool {
+		return current == holder
+	})
+}
+
+// Holder returns the id of the node holding the lock, or "" if it's free.
+func (l *Simple) Holder(ctx context.Context) (string, error) {
+	if l.client == nil {
+		return "", errors.New("redis lock has no redis client")
+	}
+	current, err := l.client.Get(ctx, l.config().Key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return current, err
+}
+
+// MyId returns the id this node holds the lock with.
+func (l *Simple) MyId() string {
+	return l.myId
+}
+
+// RefreshDuration returns how often the lock is refreshed while held.
+func (l *Simple) RefreshDuration() time.Duration {
+	return l.config().RefreshDuration
+}
+
+// Key returns the redis key the lock is held under.
+func (l *Simple) Key() string {
+	return l.config().Key
+}
+
 func (l *Simple) AttemptLock(ctx context.Context) bool {
 	if l.Locked() {
 		return true
@@ -174,6 +221,7 @@ func (l *Simple) Release(ctx context.Context) {
 	if l.client == nil {
 		return
 	}
+	atomicTimeWrite(&l.lockedUntil, time.Time{})
 
 	config := l.config()
 	err := l.client.Watch(ctx, func(tx *redis.Tx) error {
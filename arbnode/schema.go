@@ -10,13 +10,20 @@ var (
 	legacyDelayedMessagePrefix   []byte = []byte("d") // maps a delayed sequence number to an accumulator and a message as serialized on L1
 	rlpDelayedMessagePrefix      []byte = []byte("e") // maps a delayed sequence number to an accumulator and an RLP encoded message
 	parentChainBlockNumberPrefix []byte = []byte("p") // maps a delayed sequence number to a parent chain block number
+	delayedMessageInboxPrefix    []byte = []byte("c") // maps a delayed sequence number to the inbox contract that delivered it to the bridge
 	sequencerBatchMetaPrefix     []byte = []byte("s") // maps a batch sequence number to BatchMetadata
 	delayedSequencedPrefix       []byte = []byte("a") // maps a delayed message count to the first sequencer batch sequence number with this delayed count
+	quarantinedMessagePrefix     []byte = []byte("q") // maps a message sequence number to an operator override for executing it
+	dasMisbehaviorEvidencePrefix []byte = []byte("u") // maps a batch sequence number and committee member index to evidence of the member not serving the batch's data
+	dasPendingRetrievalPrefix    []byte = []byte("w") // maps a batch sequence number and committee member index to a retrieval of the batch's data from the member still to be made
+	messageSinkDigestPrefix      []byte = []byte("f") // maps a message sequence number to the digest of the message published to the message sink
 
 	messageCountKey        []byte = []byte("_messageCount")        // contains the current message count
 	delayedMessageCountKey []byte = []byte("_delayedMessageCount") // contains the current delayed message count
 	sequencerBatchCountKey []byte = []byte("_sequencerBatchCount") // contains the current sequencer message count
 	dbSchemaVersion        []byte = []byte("_schemaVersion")       // contains a uint64 representing the database schema version
+	firstRetainedBatchKey  []byte = []byte("_firstRetainedBatch")  // contains the lowest sequencer batch whose metadata hasn't been pruned
+	messageSinkCursorKey   []byte = []byte("_messageSinkCursor")   // contains the next message to publish to the message sink
 )
 
 const currentDbSchemaVersion uint64 = 1
@@ -16,7 +16,9 @@ import (
 
 	"github.com/go-redis/redis/v8"
 	flag "github.com/spf13/pflag"
+	"google.golang.org/grpc"
 
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 
@@ -25,6 +27,7 @@ import (
 	"github.com/offchainlabs/nitro/execution"
 	"github.com/offchainlabs/nitro/util/arbmath"
 	"github.com/offchainlabs/nitro/util/contracts"
+	"github.com/offchainlabs/nitro/util/etcdutil"
 	"github.com/offchainlabs/nitro/util/redisutil"
 	"github.com/offchainlabs/nitro/util/signature"
 	"github.com/offchainlabs/nitro/util/stopwaiter"
@@ -32,46 +35,98 @@ import (
 
 var (
 	isActiveSequencer = metrics.NewRegisteredGauge("arb/sequencer/active", nil)
+
+	backendFailoversCounter   = metrics.NewRegisteredCounter("arb/seqcoordinator/backend/failovers", nil)
+	lockoutLostCounter        = metrics.NewRegisteredCounter("arb/seqcoordinator/lockout/lost", nil)
+	backendUnavailableCounter = metrics.NewRegisteredCounter("arb/seqcoordinator/backend/unavailable", nil)
 )
 
 type SeqCoordinator struct {
 	stopwaiter.StopWaiter
 
-	redisutil.RedisCoordinator
+	CoordinationBackend
 
 	streamer         *TransactionStreamer
 	sequencer        execution.ExecutionSequencer
 	delayedSequencer *DelayedSequencer
 	signer           *signature.SignVerify
+	encryptor        *redisutil.PayloadEncryptor
 	config           SeqCoordinatorConfig // warning: static, don't use for hot reloadable fields
 
 	prevChosenSequencer  string
 	reportedWantsLockout bool
 
 	lockoutUntil atomic.Int64 // atomic
+	chosenSince  atomic.Int64 // when the lockout was last caught, atomic
+
+	publishedMsgCount atomic.Uint64 // the highest message count seen published through the coordination backend
 
 	wantsLockoutMutex sync.Mutex // manages access to acquireLockoutAndWriteMessage and generally the wants lockout key
 	avoidLockout      int        // If > 0, prevents acquiring the lockout but not extending the lockout if no alternative sequencer wants the lockout. Protected by chosenUpdateMutex.
 
 	redisErrors int // error counter, from workthread
+
+	handoffMutex sync.Mutex // allows one handoff at a time
+
+	healthChecks    []SequencerHealthCheck
+	lastHealthCheck time.Time   // from workthread
+	healthVetoed    atomic.Bool // whether a health check vetoes becoming chosen
+
+	syncClient      *grpc.ClientConn // connected to the chosen sequencer's message sync address, from workthread
+	syncClientAddr  string
+	syncMutex       sync.Mutex
+	standbySynced   map[string]arbutil.MessageIndex // the message count each standby fetched up to, protected by syncMutex
+	syncPrunedUntil arbutil.MessageIndex            // the messages this sequencer wrote to the backend before this are deleted, from workthread
+
+	backendIdentity string                         // identity of the server behind the backend, from workthread
+	lastLockoutLoss atomic.Pointer[lockoutFailure] // why keeping the lockout last failed, for the healthcheck
+
+	outageMutex     sync.Mutex                // held while writing to the backend as the chosen sequencer
+	outageSince     atomic.Pointer[time.Time] // since when the chosen sequencer has been sequencing without the backend
+	unpublishedFrom *arbutil.MessageIndex     // the first message not written to the backend, protected by outageMutex
+	unpublishedTo   arbutil.MessageIndex      // the message count sequenced without the backend, protected by outageMutex
+
+	backendUnavailable bool      // whether the backend couldn't be read on the last update, from workthread
+	backendRecoveredAt time.Time // when the backend last became available again, from workthread
+}
+
+// lockoutFailure is why keeping the lockout failed.
+type lockoutFailure struct {
+	Cause string
+	At    time.Time
 }
 
+const (
+	lockoutFailureFailover    = "coordination backend failed over"
+	lockoutFailureLost        = "lost the lockout"
+	lockoutFailureUnavailable = "coordination backend unavailable"
+	lockoutFailureDegraded    = "released while degraded"
+)
+
 type SeqCoordinatorConfig struct {
-	Enable                bool          `koanf:"enable"`
-	ChosenHealthcheckAddr string        `koanf:"chosen-healthcheck-addr"`
-	RedisUrl              string        `koanf:"redis-url"`
-	LockoutDuration       time.Duration `koanf:"lockout-duration"`
-	LockoutSpare          time.Duration `koanf:"lockout-spare"`
-	SeqNumDuration        time.Duration `koanf:"seq-num-duration"`
-	UpdateInterval        time.Duration `koanf:"update-interval"`
-	RetryInterval         time.Duration `koanf:"retry-interval"`
-	HandoffTimeout        time.Duration `koanf:"handoff-timeout"`
-	SafeShutdownDelay     time.Duration `koanf:"safe-shutdown-delay"`
-	ReleaseRetries        int           `koanf:"release-retries"`
+	Enable                bool            `koanf:"enable"`
+	ChosenHealthcheckAddr string          `koanf:"chosen-healthcheck-addr"`
+	Backend               string          `koanf:"backend"`
+	RedisUrl              string          `koanf:"redis-url"`
+	Etcd                  etcdutil.Config `koanf:"etcd"`
+	LockoutDuration       time.Duration   `koanf:"lockout-duration"`
+	LockoutSpare          time.Duration   `koanf:"lockout-spare"`
+	SeqNumDuration        time.Duration   `koanf:"seq-num-duration"`
+	UpdateInterval        time.Duration   `koanf:"update-interval"`
+	RetryInterval         time.Duration   `koanf:"retry-interval"`
+	HandoffTimeout        time.Duration   `koanf:"handoff-timeout"`
+	HandoffTargetTTL      time.Duration   `koanf:"handoff-target-ttl"`
+	SafeShutdownDelay     time.Duration   `koanf:"safe-shutdown-delay"`
+	ReleaseRetries        int             `koanf:"release-retries"`
+	// How long the chosen sequencer keeps sequencing while the backend is unavailable.
+	BackendOutageGracePeriod time.Duration `koanf:"backend-outage-grace-period"`
 	// Max message per poll.
-	MsgPerPoll arbutil.MessageIndex       `koanf:"msg-per-poll"`
-	MyUrl      string                     `koanf:"my-url"`
-	Signer     signature.SignVerifyConfig `koanf:"signer"`
+	MsgPerPoll  arbutil.MessageIndex              `koanf:"msg-per-poll"`
+	MyUrl       string                            `koanf:"my-url"`
+	Signer      signature.SignVerifyConfig        `koanf:"signer"`
+	Encryption  redisutil.PayloadEncryptionConfig `koanf:"encryption"`
+	Health      SeqCoordinatorHealthConfig        `koanf:"health"`
+	MessageSync SeqCoordinatorMessageSyncConfig   `koanf:"message-sync"`
 }
 
 func (c *SeqCoordinatorConfig) Url() string {
@@ -83,52 +138,72 @@ func (c *SeqCoordinatorConfig) Url() string {
 
 func SeqCoordinatorConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".enable", DefaultSeqCoordinatorConfig.Enable, "enable sequence coordinator")
-	f.String(prefix+".redis-url", DefaultSeqCoordinatorConfig.RedisUrl, "the Redis URL to coordinate via")
-	f.String(prefix+".chosen-healthcheck-addr", DefaultSeqCoordinatorConfig.ChosenHealthcheckAddr, "if non-empty, launch an HTTP service binding to this address that returns status code 200 when chosen and 503 otherwise")
+	f.String(prefix+".backend", DefaultSeqCoordinatorConfig.Backend, "what to coordinate via: \"redis\" or \"etcd\"")
+	f.String(prefix+".redis-url", DefaultSeqCoordinatorConfig.RedisUrl, "the Redis URL to coordinate via (redis-sentinel:// and redis-cluster:// URLs follow failovers)")
+	etcdutil.ConfigAddOptions(prefix+".etcd", f)
+	f.String(prefix+".chosen-healthcheck-addr", DefaultSeqCoordinatorConfig.ChosenHealthcheckAddr, "if non-empty, launch an HTTP service binding to this address that returns status code 200 when chosen and 503 otherwise, with why the lockout was last lost")
 	f.Duration(prefix+".lockout-duration", DefaultSeqCoordinatorConfig.LockoutDuration, "")
 	f.Duration(prefix+".lockout-spare", DefaultSeqCoordinatorConfig.LockoutSpare, "")
 	f.Duration(prefix+".seq-num-duration", DefaultSeqCoordinatorConfig.SeqNumDuration, "")
 	f.Duration(prefix+".update-interval", DefaultSeqCoordinatorConfig.UpdateInterval, "")
 	f.Duration(prefix+".retry-interval", DefaultSeqCoordinatorConfig.RetryInterval, "")
 	f.Duration(prefix+".handoff-timeout", DefaultSeqCoordinatorConfig.HandoffTimeout, "the maximum amount of time to spend waiting for another sequencer to accept the lockout when handing it off on shutdown or db compaction")
+	f.Duration(prefix+".handoff-target-ttl", DefaultSeqCoordinatorConfig.HandoffTargetTTL, "how long the sequencer handed off to stays recommended over the priorities, so a handoff interrupted before it's cleared doesn't pin the lockout to its target")
 	f.Duration(prefix+".safe-shutdown-delay", DefaultSeqCoordinatorConfig.SafeShutdownDelay, "if non-zero will add delay after transferring control")
 	f.Int(prefix+".release-retries", DefaultSeqCoordinatorConfig.ReleaseRetries, "the number of times to retry releasing the wants lockout and chosen one status on shutdown")
+	f.Duration(prefix+".backend-outage-grace-period", DefaultSeqCoordinatorConfig.BackendOutageGracePeriod, "if non-zero, how long the chosen sequencer keeps sequencing on its last known lockout (never past its expiry) while the coordination backend is unreachable, writing the messages once it's reachable again; standbys then wait a lockout duration before taking the lockout. If zero, sequencing stops as soon as a message can't be written")
 	f.Uint64(prefix+".msg-per-poll", uint64(DefaultSeqCoordinatorConfig.MsgPerPoll), "will only be marked as wanting the lockout if not too far behind")
 	f.String(prefix+".my-url", DefaultSeqCoordinatorConfig.MyUrl, "url for this sequencer if it is the chosen")
 	signature.SignVerifyConfigAddOptions(prefix+".signer", f)
+	redisutil.PayloadEncryptionConfigAddOptions(prefix+".encryption", f)
+	SeqCoordinatorHealthConfigAddOptions(prefix+".health", f)
+	SeqCoordinatorMessageSyncConfigAddOptions(prefix+".message-sync", f)
 }
 
 var DefaultSeqCoordinatorConfig = SeqCoordinatorConfig{
-	Enable:                false,
-	ChosenHealthcheckAddr: "",
-	RedisUrl:              "",
-	LockoutDuration:       time.Minute,
-	LockoutSpare:          30 * time.Second,
-	SeqNumDuration:        24 * time.Hour,
-	UpdateInterval:        250 * time.Millisecond,
-	HandoffTimeout:        30 * time.Second,
-	SafeShutdownDelay:     5 * time.Second,
-	ReleaseRetries:        4,
-	RetryInterval:         50 * time.Millisecond,
-	MsgPerPoll:            2000,
-	MyUrl:                 redisutil.INVALID_URL,
-	Signer:                signature.DefaultSignVerifyConfig,
+	Enable:                   false,
+	ChosenHealthcheckAddr:    "",
+	Backend:                  "redis",
+	RedisUrl:                 "",
+	Etcd:                     etcdutil.DefaultConfig,
+	LockoutDuration:          time.Minute,
+	LockoutSpare:             30 * time.Second,
+	SeqNumDuration:           24 * time.Hour,
+	UpdateInterval:           250 * time.Millisecond,
+	HandoffTimeout:           30 * time.Second,
+	HandoffTargetTTL:         time.Hour,
+	SafeShutdownDelay:        5 * time.Second,
+	ReleaseRetries:           4,
+	BackendOutageGracePeriod: 0,
+	RetryInterval:            50 * time.Millisecond,
+	MsgPerPoll:               2000,
+	MyUrl:                    redisutil.INVALID_URL,
+	Signer:                   signature.DefaultSignVerifyConfig,
+	Encryption:               redisutil.DefaultPayloadEncryptionConfig,
+	Health:                   DefaultSeqCoordinatorHealthConfig,
+	MessageSync:              DefaultSeqCoordinatorMessageSyncConfig,
 }
 
 var TestSeqCoordinatorConfig = SeqCoordinatorConfig{
 	Enable:            false,
+	Backend:           "redis",
 	RedisUrl:          "",
+	Etcd:              etcdutil.DefaultConfig,
 	LockoutDuration:   time.Second * 2,
 	LockoutSpare:      time.Millisecond * 10,
 	SeqNumDuration:    time.Minute * 10,
 	UpdateInterval:    time.Millisecond * 10,
 	HandoffTimeout:    time.Millisecond * 200,
+	HandoffTargetTTL:  time.Minute,
 	SafeShutdownDelay: time.Millisecond * 100,
 	ReleaseRetries:    4,
 	RetryInterval:     time.Millisecond * 3,
 	MsgPerPoll:        20,
 	MyUrl:             redisutil.INVALID_URL,
 	Signer:            signature.DefaultSignVerifyConfig,
+	Encryption:        redisutil.DefaultPayloadEncryptionConfig,
+	Health:            TestSeqCoordinatorHealthConfig,
+	MessageSync:       TestSeqCoordinatorMessageSyncConfig,
 }
 
 func NewSeqCoordinator(
@@ -139,7 +214,16 @@ func NewSeqCoordinator(
 	sync *SyncMonitor,
 	config SeqCoordinatorConfig,
 ) (*SeqCoordinator, error) {
-	redisCoordinator, err := redisutil.NewRedisCoordinator(config.RedisUrl)
+	if err := config.MessageSync.Validate(); err != nil {
+		return nil, err
+	}
+	if config.HandoffTargetTTL <= 0 {
+		return nil, errors.New("seq-coordinator.handoff-target-ttl must be positive")
+	}
+	if config.MessageSync.Enable && config.MessageSync.ListenAddr == "" && config.Url() != redisutil.INVALID_URL {
+		return nil, errors.New("seq-coordinator.message-sync.listen-addr is required for a sequencer to serve its messages")
+	}
+	backend, err := newCoordinationBackend(&config)
 	if err != nil {
 		return nil, err
 	}
@@ -147,12 +231,37 @@ func NewSeqCoordinator(
 	if err != nil {
 		return nil, err
 	}
+	encryptor, err := redisutil.NewPayloadEncryptor(&config.Encryption)
+	if err != nil {
+		return nil, err
+	}
 	coordinator := &SeqCoordinator{
-		RedisCoordinator: *redisCoordinator,
-		streamer:         streamer,
-		sequencer:        sequencer,
-		config:           config,
-		signer:           signer,
+		CoordinationBackend: backend,
+		streamer:            streamer,
+		sequencer:           sequencer,
+		config:              config,
+		signer:              signer,
+		encryptor:           encryptor,
+	}
+	if config.Health.MaxBlockLag > 0 {
+		coordinator.AddHealthCheck(&blockLagHealthCheck{c: coordinator, maxLag: config.Health.MaxBlockLag})
+	}
+	if config.Health.ExternalURL != "" {
+		coordinator.AddHealthCheck(&externalHealthCheck{url: config.Health.ExternalURL, timeout: config.Health.ExternalTimeout})
+	}
+	if config.Health.MaxBatchPostingDelay > 0 {
+		coordinator.AddHealthCheck(&batchPostingHealthCheck{
+			chosenSince:    coordinator.chosenSinceTime,
+			oldestUnposted: coordinator.oldestUnpostedMessage,
+			maxDelay:       config.Health.MaxBatchPostingDelay,
+		})
+	}
+	if config.Health.MaxFeedWithoutClients > 0 && streamer.broadcastServer != nil {
+		coordinator.AddHealthCheck(&feedClientsHealthCheck{
+			chosenSince: coordinator.chosenSinceTime,
+			clients:     streamer.broadcastServer.ClientCount,
+			maxDuration: config.Health.MaxFeedWithoutClients,
+		})
 	}
 	streamer.SetSeqCoordinator(coordinator)
 	return coordinator, nil
@@ -186,8 +295,8 @@ func StandaloneSeqCoordinatorInvalidateMsgIndex(ctx context.Context, redisClient
 	if err != nil {
 		return err
 	}
-	redisClient.Set(ctx, redisutil.MessageKeyFor(msgIndex), msg, DefaultSeqCoordinatorConfig.SeqNumDuration)
-	redisClient.Set(ctx, redisutil.MessageSigKeyFor(msgIndex), sig, DefaultSeqCoordinatorConfig.SeqNumDuration)
+	redisClient.Set(ctx, redisutil.KeyFor(redisClient, redisutil.MessageKeyFor(msgIndex)), msg, DefaultSeqCoordinatorConfig.SeqNumDuration)
+	redisClient.Set(ctx, redisutil.KeyFor(redisClient, redisutil.MessageSigKeyFor(msgIndex)), sig, DefaultSeqCoordinatorConfig.SeqNumDuration)
 	return nil
 }
 
@@ -202,19 +311,6 @@ func atomicTimeRead(addr *atomic.Int64) time.Time {
 	return time.UnixMilli(asint64)
 }
 
-func execTestPipe(pipe redis.Pipeliner, ctx context.Context) error {
-	cmders, err := pipe.Exec(ctx)
-	if err != nil {
-		return err
-	}
-	for _, cmder := range cmders {
-		if err := cmder.Err(); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
 func (c *SeqCoordinator) msgCountToSignedBytes(msgCount arbutil.MessageIndex) ([]byte, error) {
 	var msgCountBytes [8]byte
 	binary.BigEndian.PutUint64(msgCountBytes[:], uint64(msgCount))
@@ -226,6 +322,9 @@ func (c *SeqCoordinator) msgCountToSignedBytes(msgCount arbutil.MessageIndex) ([
 }
 
 func (c *SeqCoordinator) signedBytesToMsgCount(ctx context.Context, data []byte) (arbutil.MessageIndex, error) {
+	if data == nil {
+		return 0, nil
+	}
 	datalen := len(data)
 	if datalen < 8 {
 		return 0, errors.New("msgcount value too short")
@@ -239,118 +338,114 @@ func (c *SeqCoordinator) signedBytesToMsgCount(ctx context.Context, data []byte)
 	return arbutil.MessageIndex(binary.BigEndian.Uint64(msgCountBytes)), nil
 }
 
-// Acquires or refreshes the chosen one lockout and optionally writes a message into redis atomically.
+// encodeMessage returns the message at pos as stored in the coordination backend, and its signature.
+func (c *SeqCoordinator) encodeMessage(pos arbutil.MessageIndex, msg *arbostypes.MessageWithMetadata) ([]byte, []byte, error) {
+	msgBytes, err := json.Marshal(msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the signature covers the stored bytes, so it can be checked before decrypting
+	msgBytes, err = c.encryptor.Encrypt(msgBytes, arbmath.UintToBytes(uint64(pos)))
+	if err != nil {
+		return nil, nil, err
+	}
+	msgSig, err := c.signer.SignMessage(arbmath.UintToBytes(uint64(pos)), msgBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return msgBytes, msgSig, nil
+}
+
+// Acquires or refreshes the chosen one lockout and optionally writes a message into the coordination backend atomically.
+// With message sync, standbys fetch messages from this sequencer, but the message is still written so a standby can
+// take over without this sequencer, until every standby fetched it.
 func (c *SeqCoordinator) acquireLockoutAndWriteMessage(ctx context.Context, msgCountExpected, msgCountToWrite arbutil.MessageIndex, lastmsg *arbostypes.MessageWithMetadata) error {
-	var messageData *string
-	var messageSigData *string
+	update := &LockoutUpdate{MsgPos: msgCountToWrite - 1, MessageRetention: c.config.SeqNumDuration}
+	if c.config.MessageSync.Enable {
+		update.SyncUrl = c.config.MessageSync.MyAddr
+	}
 	if lastmsg != nil {
-		msgBytes, err := json.Marshal(lastmsg)
-		if err != nil {
-			return err
-		}
-		msgSig, err := c.signer.SignMessage(arbmath.UintToBytes(uint64(msgCountToWrite-1)), msgBytes)
+		msgBytes, msgSig, err := c.encodeMessage(msgCountToWrite-1, lastmsg)
 		if err != nil {
 			return err
 		}
 		if c.config.Signer.SymmetricSign {
-			messageString := string(append(msgSig, msgBytes...))
-			messageData = &messageString
+			update.Message = append(msgSig, msgBytes...)
 		} else {
-			messageString := string(msgBytes)
-			sigString := string(msgSig)
-			messageData = &messageString
-			messageSigData = &sigString
+			update.Message = msgBytes
+			update.MessageSig = msgSig
 		}
 	}
 	msgCountMsg, err := c.msgCountToSignedBytes(msgCountToWrite)
 	if err != nil {
 		return err
 	}
+	update.MsgCount = msgCountMsg
 	c.wantsLockoutMutex.Lock()
 	defer c.wantsLockoutMutex.Unlock()
-	setWantsLockout := c.avoidLockout <= 0
-	lockoutUntil := time.Now().Add(c.config.LockoutDuration)
-	err = c.Client.Watch(ctx, func(tx *redis.Tx) error {
-		current, err := tx.Get(ctx, redisutil.CHOSENSEQ_KEY).Result()
-		var wasEmpty bool
-		if errors.Is(err, redis.Nil) {
-			wasEmpty = true
-			err = nil
-		}
-		if err != nil {
-			return err
-		}
-		if !wasEmpty && (current != c.config.Url()) {
-			return fmt.Errorf("%w: failed to catch lock. redis shows chosen: %s", execution.ErrRetrySequencer, current)
+	update.SetWantsLockout = c.avoidLockout <= 0 && !c.healthVetoed.Load()
+	now := time.Now()
+	update.LockoutUntil = now.Add(c.config.LockoutDuration)
+	// an existing lockout may be kept if it stays valid for at least half the time outside the spare
+	update.MinLockoutUntil = now.Add(c.config.LockoutSpare + (c.config.LockoutDuration-c.config.LockoutSpare)/2)
+	lockoutUntil, err := c.AcquireLockout(ctx, c.config.Url(), func(current string, msgCountData []byte) (bool, error) {
+		if current != "" && current != c.config.Url() {
+			return false, fmt.Errorf("%w: failed to catch lock. coordinator shows chosen: %s", execution.ErrRetrySequencer, current)
 		}
-		remoteMsgCount, err := c.getRemoteMsgCountImpl(ctx, tx)
+		remoteMsgCount, err := c.signedBytesToMsgCount(ctx, msgCountData)
 		if err != nil {
-			return err
+			return false, err
 		}
 		if remoteMsgCount > msgCountExpected {
-			if messageData == nil && c.CurrentlyChosen() {
+			if lastmsg == nil && c.CurrentlyChosen() {
 				// this was called from update(), while msgCount was changed by a call from SequencingMessage
 				// no need to do anything
-				return nil
-			}
-			log.Info("coordinator failed to become main", "expected", msgCountExpected, "found", remoteMsgCount, "message is nil?", messageData == nil)
-			return fmt.Errorf("%w: failed to catch lock. expected msg %d found %d", execution.ErrRetrySequencer, msgCountExpected, remoteMsgCount)
-		}
-		pipe := tx.TxPipeline()
-		initialDuration := c.config.LockoutDuration
-		if initialDuration < 2*time.Second {
-			initialDuration = 2 * time.Second
-		}
-		if wasEmpty {
-			pipe.Set(ctx, redisutil.CHOSENSEQ_KEY, c.config.Url(), initialDuration)
-		}
-		pipe.Set(ctx, redisutil.MSG_COUNT_KEY, msgCountMsg, c.config.SeqNumDuration)
-		if messageData != nil {
-			pipe.Set(ctx, redisutil.MessageKeyFor(msgCountToWrite-1), *messageData, c.config.SeqNumDuration)
-			if messageSigData != nil {
-				pipe.Set(ctx, redisutil.MessageSigKeyFor(msgCountToWrite-1), *messageSigData, c.config.SeqNumDuration)
+				return false, nil
 			}
+			log.Info("coordinator failed to become main", "expected", msgCountExpected, "found", remoteMsgCount, "message is nil?", lastmsg == nil)
+			return false, fmt.Errorf("%w: failed to catch lock. expected msg %d found %d", execution.ErrRetrySequencer, msgCountExpected, remoteMsgCount)
 		}
-		pipe.PExpireAt(ctx, redisutil.CHOSENSEQ_KEY, lockoutUntil)
-		if setWantsLockout {
-			myWantsLockoutKey := redisutil.WantsLockoutKeyFor(c.config.Url())
-			pipe.Set(ctx, myWantsLockoutKey, redisutil.WANTS_LOCKOUT_VAL, initialDuration)
-			pipe.PExpireAt(ctx, myWantsLockoutKey, lockoutUntil)
-		}
-		err = execTestPipe(pipe, ctx)
-		if errors.Is(err, redis.TxFailedErr) {
-			return fmt.Errorf("%w: failed to catch sequencer lock", execution.ErrRetrySequencer)
-		}
-		if err != nil {
-			return fmt.Errorf("chosen sequencer failed to update redis: %w", err)
-		}
-		return nil
-	}, redisutil.CHOSENSEQ_KEY, redisutil.MSG_COUNT_KEY)
-
+		return true, nil
+	}, update)
 	if err != nil {
 		return err
 	}
-	if setWantsLockout {
+	if update.SetWantsLockout {
 		c.reportedWantsLockout = true
 	}
 	isActiveSequencer.Update(1)
 	atomicTimeWrite(&c.lockoutUntil, lockoutUntil.Add(-c.config.LockoutSpare))
+	c.notePublishedMsgCount(msgCountToWrite)
 	return nil
 }
 
-func (c *SeqCoordinator) getRemoteMsgCountImpl(ctx context.Context, r redis.Cmdable) (arbutil.MessageIndex, error) {
-	resStr, err := r.Get(ctx, redisutil.MSG_COUNT_KEY).Result()
-	if errors.Is(err, redis.Nil) {
-		return 0, nil
+func (c *SeqCoordinator) notePublishedMsgCount(msgCount arbutil.MessageIndex) {
+	for {
+		published := c.publishedMsgCount.Load()
+		if uint64(msgCount) <= published || c.publishedMsgCount.CompareAndSwap(published, uint64(msgCount)) {
+			return
+		}
 	}
-	if err != nil {
-		return 0, err
+}
+
+// PublishedMsgCount returns the highest message count the chosen sequencer published through the coordination
+// backend that this node has, which its view must not be ahead of to be consistent with the chosen sequencer's.
+func (c *SeqCoordinator) PublishedMsgCount() arbutil.MessageIndex {
+	published := arbutil.MessageIndex(c.publishedMsgCount.Load())
+	localMsgCount, err := c.streamer.GetMessageCount()
+	if err == nil && localMsgCount < published {
+		return localMsgCount
 	}
-	return c.signedBytesToMsgCount(ctx, []byte(resStr))
+	return published
 }
 
 func (c *SeqCoordinator) GetRemoteMsgCount() (arbutil.MessageIndex, error) {
-	return c.getRemoteMsgCountImpl(c.GetContext(), c.Client)
+	ctx := c.GetContext()
+	data, err := c.GetMsgCount(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return c.signedBytesToMsgCount(ctx, data)
 }
 
 func (c *SeqCoordinator) wantsLockoutUpdate(ctx context.Context) error {
@@ -361,21 +456,13 @@ func (c *SeqCoordinator) wantsLockoutUpdate(ctx context.Context) error {
 
 // Requires the caller hold the wantsLockoutMutex
 func (c *SeqCoordinator) wantsLockoutUpdateWithMutex(ctx context.Context) error {
-	if c.avoidLockout > 0 {
+	if c.avoidLockout > 0 || c.healthVetoed.Load() {
 		return nil
 	}
-	myWantsLockoutKey := redisutil.WantsLockoutKeyFor(c.config.Url())
 	wantsLockoutUntil := time.Now().Add(c.config.LockoutDuration)
-	pipe := c.Client.TxPipeline()
-	initialDuration := c.config.LockoutDuration
-	if initialDuration < 2*time.Second {
-		initialDuration = 2 * time.Second
-	}
-	pipe.Set(ctx, myWantsLockoutKey, redisutil.WANTS_LOCKOUT_VAL, initialDuration)
-	pipe.PExpireAt(ctx, myWantsLockoutKey, wantsLockoutUntil)
-	err := execTestPipe(pipe, ctx)
+	err := c.SetWantsLockout(ctx, c.config.Url(), wantsLockoutUntil)
 	if err != nil {
-		return fmt.Errorf("failed to update wants lockout key in redis: %w", err)
+		return fmt.Errorf("failed to update wants lockout key: %w", err)
 	}
 	c.reportedWantsLockout = true
 	return nil
@@ -384,37 +471,7 @@ func (c *SeqCoordinator) wantsLockoutUpdateWithMutex(ctx context.Context) error
 func (c *SeqCoordinator) chosenOneRelease(ctx context.Context) error {
 	atomicTimeWrite(&c.lockoutUntil, time.Time{})
 	isActiveSequencer.Update(0)
-	releaseErr := c.Client.Watch(ctx, func(tx *redis.Tx) error {
-		current, err := tx.Get(ctx, redisutil.CHOSENSEQ_KEY).Result()
-		if errors.Is(err, redis.Nil) {
-			return nil
-		}
-		if err != nil {
-			return err
-		}
-		if current != c.config.Url() {
-			return nil
-		}
-		pipe := tx.TxPipeline()
-		pipe.Del(ctx, redisutil.CHOSENSEQ_KEY)
-		err = execTestPipe(pipe, ctx)
-		if err != nil {
-			return fmt.Errorf("chosen sequencer failed to update redis: %w", err)
-		}
-		return nil
-	}, redisutil.CHOSENSEQ_KEY)
-	if releaseErr == nil {
-		return nil
-	}
-	// got error - was it still released?
-	current, readErr := c.Client.Get(ctx, redisutil.CHOSENSEQ_KEY).Result()
-	if errors.Is(readErr, redis.Nil) {
-		return nil
-	}
-	if current != c.config.Url() {
-		return nil
-	}
-	return releaseErr
+	return c.ReleaseLockout(ctx, c.config.Url())
 }
 
 func (c *SeqCoordinator) wantsLockoutRelease(ctx context.Context) error {
@@ -423,14 +480,8 @@ func (c *SeqCoordinator) wantsLockoutRelease(ctx context.Context) error {
 	if !c.reportedWantsLockout {
 		return nil
 	}
-	myWantsLockoutKey := redisutil.WantsLockoutKeyFor(c.config.Url())
-	releaseErr := c.Client.Del(ctx, myWantsLockoutKey).Err()
-	if releaseErr != nil {
-		// got error - was it still deleted?
-		readErr := c.Client.Get(ctx, myWantsLockoutKey).Err()
-		if !errors.Is(readErr, redis.Nil) {
-			return releaseErr
-		}
+	if err := c.ReleaseWantsLockout(ctx, c.config.Url()); err != nil {
+		return err
 	}
 	c.reportedWantsLockout = false
 	return nil
@@ -452,9 +503,13 @@ func (c *SeqCoordinator) noRedisError() time.Duration {
 
 // update for the prev known-chosen sequencer (no need to load new messages)
 func (c *SeqCoordinator) updateWithLockout(ctx context.Context, nextChosen string) time.Duration {
-	if nextChosen != "" && nextChosen != c.config.Url() {
+	if nextChosen != "" && nextChosen != c.config.Url() && c.hasUnpublishedMessages() {
+		// the next sequencer would sequence over the messages it can't see yet, so publish them first
+		log.Warn("holding on to the lockout until the messages sequenced while the coordination backend was unavailable are published", "myUrl", c.config.Url(), "nextChosen", nextChosen)
+	} else if nextChosen != "" && nextChosen != c.config.Url() {
 		// was the active sequencer, but no longer
 		// we maintain chosen status if we had it and nobody in the priorities wants the lockout
+		degraded := c.healthVetoed.Load()
 		setPrevChosenTo := nextChosen
 		if c.sequencer != nil {
 			err := c.sequencer.ForwardTo(nextChosen)
@@ -469,13 +524,24 @@ func (c *SeqCoordinator) updateWithLockout(ctx context.Context, nextChosen strin
 			return c.retryAfterRedisError()
 		}
 		c.prevChosenSequencer = setPrevChosenTo
-		log.Info("released chosen-coordinator lock", "myUrl", c.config.Url(), "nextChosen", nextChosen)
+		if degraded {
+			selfDemotionsCounter.Inc(1)
+			c.lastLockoutLoss.Store(&lockoutFailure{Cause: lockoutFailureDegraded, At: time.Now()})
+			log.Warn("released chosen-coordinator lock while degraded", "myUrl", c.config.Url(), "nextChosen", nextChosen)
+		} else {
+			log.Info("released chosen-coordinator lock", "myUrl", c.config.Url(), "nextChosen", nextChosen)
+		}
 		return c.noRedisError()
 	}
 	// Was, and still is, the active sequencer
+	if c.config.MessageSync.Enable {
+		c.pruneSyncedMessages(ctx)
+	}
 	// We leave a margin of error of either a five times the update interval or a fifth of the lockout duration, whichever is greater.
 	marginOfError := arbmath.MaxInt(c.config.LockoutDuration/5, c.config.UpdateInterval*5)
-	if time.Now().Add(marginOfError).Before(atomicTimeRead(&c.lockoutUntil)) {
+	c.outageMutex.Lock()
+	defer c.outageMutex.Unlock()
+	if c.unpublishedFrom == nil && time.Now().Add(marginOfError).Before(atomicTimeRead(&c.lockoutUntil)) {
 		// if we recently sequenced - no need for an update
 		return c.noRedisError()
 	}
@@ -484,20 +550,80 @@ func (c *SeqCoordinator) updateWithLockout(ctx context.Context, nextChosen strin
 		log.Error("coordinator cannot read message count", "err", err)
 		return c.config.UpdateInterval
 	}
-	err = c.acquireLockoutAndWriteMessage(ctx, localMsgCount, localMsgCount, nil)
+	err = c.publishUnpublishedWithMutex(ctx, localMsgCount)
+	if err == nil {
+		err = c.acquireLockoutAndWriteMessage(ctx, localMsgCount, localMsgCount, nil)
+	}
 	if err != nil {
-		log.Warn("coordinator failed chosen-one keepalive", "err", err)
+		cause := c.lockoutFailureCause(ctx, err)
+		if cause == lockoutFailureUnavailable && c.noteBackendOutageWithMutex(err) == nil {
+			log.Warn("coordinator failed chosen-one keepalive, sequencing on the last known lockout", "lockoutUntil", atomicTimeRead(&c.lockoutUntil), "err", err)
+			return c.retryAfterRedisError()
+		}
+		c.lastLockoutLoss.Store(&lockoutFailure{Cause: cause, At: time.Now()})
+		log.Warn("coordinator failed chosen-one keepalive", "cause", cause, "err", err)
 		return c.retryAfterRedisError()
 	}
+	c.backendAvailableWithMutex()
+	if c.backendFailedOver(ctx) {
+		// the new server had our lockout, so nothing was lost
+		backendFailoversCounter.Inc(1)
+		log.Warn("coordination backend failed over, lockout kept")
+	}
 	return c.noRedisError()
 }
 
+// backendFailedOver returns whether the server behind the coordination backend changed since last checked,
+// as when Redis fails over to a replica or restarts, which may lose the latest writes including the lockout.
+func (c *SeqCoordinator) backendFailedOver(ctx context.Context) bool {
+	detector, ok := c.CoordinationBackend.(failoverDetector)
+	if !ok {
+		return false
+	}
+	identity, err := detector.ServerIdentity(ctx)
+	if err != nil {
+		log.Debug("coordinator failed to identify backend server", "err", err)
+		return false
+	}
+	changed := c.backendIdentity != "" && identity != c.backendIdentity
+	c.backendIdentity = identity
+	return changed
+}
+
+// lockoutFailureCause distinguishes the backend failing over, which may have lost our lockout with the writes
+// not yet replicated, from another sequencer taking the lockout, and from the backend being unreachable.
+func (c *SeqCoordinator) lockoutFailureCause(ctx context.Context, err error) string {
+	if c.backendFailedOver(ctx) {
+		backendFailoversCounter.Inc(1)
+		return lockoutFailureFailover
+	}
+	if errors.Is(err, execution.ErrRetrySequencer) {
+		lockoutLostCounter.Inc(1)
+		return lockoutFailureLost
+	}
+	backendUnavailableCounter.Inc(1)
+	return lockoutFailureUnavailable
+}
+
 func (c *SeqCoordinator) update(ctx context.Context) time.Duration {
+	vetoed := c.updateHealth(ctx)
+	if vetoed {
+		// stop wanting the lockout now, rather than when it expires, so another sequencer is recommended
+		if err := c.wantsLockoutRelease(ctx); err != nil {
+			log.Warn("coordinator failed to release wanting the lockout while degraded", "err", err)
+		}
+	}
 	chosenSeq, err := c.RecommendSequencerWantingLockout(ctx)
+	c.noteStandbyBackendAvailable(err == nil)
 	if err != nil {
 		log.Warn("coordinator failed finding sequencer wanting lockout", "err", err)
 		return c.retryAfterRedisError()
 	}
+	if chosenSeq == c.config.Url() {
+		recommendedSelfGauge.Update(1)
+	} else {
+		recommendedSelfGauge.Update(0)
+	}
 	if c.prevChosenSequencer == c.config.Url() {
 		return c.updateWithLockout(ctx, chosenSeq)
 	}
@@ -516,7 +642,7 @@ func (c *SeqCoordinator) update(ctx context.Context) time.Duration {
 		}
 	}
 
-	// read messages from redis
+	// read messages from redis, or the chosen sequencer with message sync
 	localMsgCount, err := c.streamer.GetMessageCount()
 	if err != nil {
 		log.Error("cannot read message count", "err", err)
@@ -527,26 +653,28 @@ func (c *SeqCoordinator) update(ctx context.Context) time.Duration {
 		log.Warn("cannot get remote message count", "err", err)
 		return c.retryAfterRedisError()
 	}
+	c.notePublishedMsgCount(remoteMsgCount)
 	readUntil := remoteMsgCount
 	if readUntil > localMsgCount+c.config.MsgPerPoll {
 		readUntil = localMsgCount + c.config.MsgPerPoll
 	}
+	getMessage := c.GetMessage
+	if c.config.MessageSync.Enable {
+		// fetched even when there's nothing to, to report which messages this sequencer has
+		getMessage = c.syncedMessageReader(ctx, localMsgCount, readUntil)
+	}
 	var messages []arbostypes.MessageWithMetadata
 	msgToRead := localMsgCount
 	var msgReadErr error
 	for msgToRead < readUntil {
-		var resString string
-		resString, msgReadErr = c.Client.Get(ctx, redisutil.MessageKeyFor(msgToRead)).Result()
+		var rsBytes, sigBytes []byte
+		rsBytes, sigBytes, msgReadErr = getMessage(ctx, msgToRead)
 		if msgReadErr != nil {
 			log.Warn("coordinator failed reading message", "pos", msgToRead, "err", msgReadErr)
 			break
 		}
-		rsBytes := []byte(resString)
-		var sigString string
-		var sigBytes []byte
 		sigSeparateKey := true
-		sigString, msgReadErr = c.Client.Get(ctx, redisutil.MessageSigKeyFor(msgToRead)).Result()
-		if errors.Is(msgReadErr, redis.Nil) {
+		if sigBytes == nil {
 			// no separate signature. Try reading old-style sig
 			if len(rsBytes) < 32 {
 				log.Warn("signature not found for msg", "pos", msgToRead)
@@ -556,17 +684,17 @@ func (c *SeqCoordinator) update(ctx context.Context) time.Duration {
 			sigBytes = rsBytes[:32]
 			rsBytes = rsBytes[32:]
 			sigSeparateKey = false
-		} else if msgReadErr != nil {
-			log.Warn("coordinator failed reading sig", "pos", msgToRead, "err", msgReadErr)
-			break
-		} else {
-			sigBytes = []byte(sigString)
 		}
 		msgReadErr = c.signer.VerifySignature(ctx, sigBytes, arbmath.UintToBytes(uint64(msgToRead)), rsBytes)
 		if msgReadErr != nil {
 			log.Warn("coordinator failed verifying message signature", "pos", msgToRead, "err", msgReadErr, "separate-key", sigSeparateKey)
 			break
 		}
+		rsBytes, msgReadErr = c.encryptor.Decrypt(rsBytes, arbmath.UintToBytes(uint64(msgToRead)))
+		if msgReadErr != nil {
+			log.Warn("coordinator failed decrypting message", "pos", msgToRead, "err", msgReadErr)
+			break
+		}
 		var message arbostypes.MessageWithMetadata
 		err = json.Unmarshal(rsBytes, &message)
 		if err != nil {
@@ -617,7 +745,7 @@ func (c *SeqCoordinator) update(ctx context.Context) time.Duration {
 	}
 
 	// can take over as main sequencer?
-	if synced && localMsgCount >= remoteMsgCount && chosenSeq == c.config.Url() {
+	if synced && !vetoed && localMsgCount >= remoteMsgCount && chosenSeq == c.config.Url() && !c.holdingOffAfterOutage() {
 		if c.sequencer == nil {
 			log.Error("myurl main sequencer, but no sequencer exists")
 			return c.noRedisError()
@@ -643,6 +771,8 @@ func (c *SeqCoordinator) update(ctx context.Context) time.Duration {
 				return c.retryAfterRedisError()
 			}
 			log.Info("caught chosen-coordinator lock", "myUrl", c.config.Url())
+			c.syncPrunedUntil = localMsgCount
+			atomicTimeWrite(&c.chosenSince, time.Now())
 			if c.delayedSequencer != nil {
 				err = c.delayedSequencer.ForceSequenceDelayed(ctx)
 				if err != nil {
@@ -663,7 +793,7 @@ func (c *SeqCoordinator) update(ctx context.Context) time.Duration {
 
 	// update wanting the lockout
 	var wantsLockoutErr error
-	if synced && !c.AvoidingLockout() {
+	if synced && !vetoed && !c.AvoidingLockout() {
 		wantsLockoutErr = c.wantsLockoutUpdate(ctx)
 	} else {
 		wantsLockoutErr = c.wantsLockoutRelease(ctx)
@@ -703,8 +833,14 @@ type seqCoordinatorChosenHealthcheck struct {
 func (h seqCoordinatorChosenHealthcheck) ServeHTTP(response http.ResponseWriter, _ *http.Request) {
 	if h.c.CurrentlyChosen() {
 		response.WriteHeader(http.StatusOK)
-	} else {
-		response.WriteHeader(http.StatusServiceUnavailable)
+		if since := h.c.outageSince.Load(); since != nil {
+			fmt.Fprintf(response, "chosen, but the coordination backend has been unreachable since %v; sequencing on the lockout until %v\n", since.UTC().Format(time.RFC3339), atomicTimeRead(&h.c.lockoutUntil).UTC().Format(time.RFC3339))
+		}
+		return
+	}
+	response.WriteHeader(http.StatusServiceUnavailable)
+	if loss := h.c.lastLockoutLoss.Load(); loss != nil {
+		fmt.Fprintf(response, "not chosen; last failed to keep the lockout at %v: %v\n", loss.At.UTC().Format(time.RFC3339), loss.Cause)
 	}
 }
 
@@ -735,6 +871,9 @@ func (c *SeqCoordinator) Start(ctxIn context.Context) {
 	if c.config.ChosenHealthcheckAddr != "" {
 		c.StopWaiter.LaunchThread(c.launchHealthcheckServer)
 	}
+	if c.config.MessageSync.Enable && c.config.MessageSync.ListenAddr != "" {
+		c.StopWaiter.LaunchThread(c.launchMessageSyncServer)
+	}
 }
 
 // Calls check() every c.config.RetryInterval until it returns true, or the context times out.
@@ -786,21 +925,69 @@ func (c *SeqCoordinator) StopAndWait() {
 			time.Sleep(c.retryAfterRedisError())
 		}
 	}
-	_ = c.Client.Close()
+	c.closeMessageSyncClient()
+	_ = c.CoordinationBackend.Close()
 }
 
 func (c *SeqCoordinator) CurrentlyChosen() bool {
 	return time.Now().Before(atomicTimeRead(&c.lockoutUntil))
 }
 
-func (c *SeqCoordinator) SequencingMessage(pos arbutil.MessageIndex, msg *arbostypes.MessageWithMetadata) error {
+// SequencingMessage writes the message the sequencer is about to sequence at pos to the backend. During an
+// outage within the grace period, it returns false without an error: the message is sequenced, but must not
+// be broadcast until it's published, which broadcasts it.
+func (c *SeqCoordinator) SequencingMessage(pos arbutil.MessageIndex, msg *arbostypes.MessageWithMetadata) (bool, error) {
 	if !c.CurrentlyChosen() {
-		return fmt.Errorf("%w: not main sequencer", execution.ErrRetrySequencer)
+		return false, fmt.Errorf("%w: not main sequencer", execution.ErrRetrySequencer)
 	}
-	if err := c.acquireLockoutAndWriteMessage(c.GetContext(), pos, pos+1, msg); err != nil {
-		return err
+	ctx := c.GetContext()
+	c.outageMutex.Lock()
+	defer c.outageMutex.Unlock()
+	// messages sequenced during an outage are written first, so the backend's messages stay contiguous
+	err := c.publishUnpublishedWithMutex(ctx, pos)
+	if err == nil {
+		err = c.acquireLockoutAndWriteMessage(ctx, pos, pos+1, msg)
 	}
-	return nil
+	if err != nil {
+		if err := c.noteBackendOutageWithMutex(err); err != nil {
+			return false, err
+		}
+		c.noteUnpublishedWithMutex(pos)
+		return false, nil
+	}
+	c.backendAvailableWithMutex()
+	return true, nil
+}
+
+// delayedIgnoresLockout returns whether the delayed sequencer is configured to sequence without the lockout.
+func (c *SeqCoordinator) delayedIgnoresLockout() bool {
+	return c.delayedSequencer != nil && c.delayedSequencer.ignoresCoordinator()
+}
+
+// SequencingDelayedMessage is SequencingMessage for a delayed message, which the delayed sequencer may be
+// configured to sequence without this sequencer being chosen. The message is still written to the backend,
+// taking the lockout if no other sequencer holds it, and it fails if another sequencer holds the lockout or
+// sequenced past pos.
+func (c *SeqCoordinator) SequencingDelayedMessage(pos arbutil.MessageIndex, msg *arbostypes.MessageWithMetadata) (bool, error) {
+	if c.CurrentlyChosen() || !c.delayedIgnoresLockout() {
+		return c.SequencingMessage(pos, msg)
+	}
+	ctx := c.GetContext()
+	c.outageMutex.Lock()
+	defer c.outageMutex.Unlock()
+	err := c.publishUnpublishedWithMutex(ctx, pos)
+	if err == nil {
+		err = c.acquireLockoutAndWriteMessage(ctx, pos, pos+1, msg)
+	}
+	if err != nil {
+		if errors.Is(err, execution.ErrRetrySequencer) {
+			delayedSequencerCoordinatorConflictCounter.Inc(1)
+			log.Warn("delayed sequencer ignores the coordinator, but another sequencer conflicts with the delayed message", "pos", pos, "myUrl", c.config.Url(), "err", err)
+		}
+		return false, err
+	}
+	c.backendAvailableWithMutex()
+	return true, nil
 }
 
 // Returns true if the wanting the lockout key was released.
@@ -842,6 +1029,143 @@ func (c *SeqCoordinator) TryToHandoffChosenOne(ctx context.Context) bool {
 	return true
 }
 
+// SequencerHandoff is the result of handing the lockout off to another sequencer.
+type SequencerHandoff struct {
+	Target string `json:"target"`
+	// MsgCount is the message count when the lockout was released, which the target picked up at.
+	MsgCount hexutil.Uint64 `json:"msgCount"`
+}
+
+// waitForSequencedMessages waits until every message sequenced has been written both locally and to the backend,
+// and no more have been for an update interval, returning the message count.
+func (c *SeqCoordinator) waitForSequencedMessages(ctx context.Context) (arbutil.MessageIndex, error) {
+	var lastCount arbutil.MessageIndex
+	var stableSince time.Time
+	var err error
+	success := c.waitFor(ctx, func() bool {
+		var localMsgCount, remoteMsgCount arbutil.MessageIndex
+		localMsgCount, err = c.streamer.GetMessageCount()
+		if err != nil {
+			return true
+		}
+		remoteMsgCount, err = c.GetRemoteMsgCount()
+		if err != nil {
+			log.Warn("handoff failed to read the remote message count", "err", err)
+			err = nil
+			return false
+		}
+		if localMsgCount != remoteMsgCount || localMsgCount != lastCount || stableSince.IsZero() {
+			lastCount = localMsgCount
+			stableSince = time.Now()
+			return false
+		}
+		return time.Since(stableSince) >= c.config.UpdateInterval
+	})
+	if err != nil {
+		return 0, err
+	}
+	if !success {
+		return 0, fmt.Errorf("timed out waiting for sequenced messages to be written, at message count %v", lastCount)
+	}
+	return lastCount, nil
+}
+
+// Handoff hands the lockout off to the target sequencer without losing messages: it stops sequencing, waits for
+// every message sequenced to be written and broadcast, then makes the target the recommended sequencer so the
+// update loop releases the lockout to it, and waits for the target to become chosen at the same message count.
+// The target stays recommended over the priorities while it wants the lockout, until the next handoff or
+// ClearHandoff, so it isn't handed back. Each step is bounded by the handoff timeout.
+func (c *SeqCoordinator) Handoff(ctx context.Context, target string) (*SequencerHandoff, error) {
+	if target == "" || target == c.config.Url() {
+		return nil, errors.New("handoff target must be another sequencer")
+	}
+	if c.sequencer == nil {
+		return nil, errors.New("no sequencer to hand off from")
+	}
+	c.handoffMutex.Lock()
+	defer c.handoffMutex.Unlock()
+	if !c.CurrentlyChosen() {
+		return nil, errors.New("not the chosen sequencer")
+	}
+	log.Info("handing off the lockout", "myUrl", c.config.Url(), "target", target)
+
+	// stop accepting transactions; the update loop resumes forwarding them once the lockout is released
+	c.sequencer.Pause()
+	resume := func() {
+		if c.CurrentlyChosen() {
+			c.sequencer.Activate()
+		}
+	}
+	flushCtx, cancel := context.WithTimeout(ctx, c.config.HandoffTimeout)
+	defer cancel()
+	msgCount, err := c.waitForSequencedMessages(flushCtx)
+	if err != nil {
+		resume()
+		return nil, err
+	}
+	if err := c.streamer.PopulateFeedBacklog(); err != nil {
+		resume()
+		return nil, fmt.Errorf("failed to flush the feed: %w", err)
+	}
+
+	if err := c.SetHandoffTarget(ctx, target, c.config.HandoffTargetTTL); err != nil {
+		resume()
+		return nil, fmt.Errorf("failed to set the handoff target: %w", err)
+	}
+	abort := func(err error) (*SequencerHandoff, error) {
+		// fall back to the priorities, which will keep or give back the lockout as usual
+		if clearErr := c.SetHandoffTarget(c.GetContext(), "", 0); clearErr != nil {
+			log.Error("failed to clear the handoff target after a failed handoff", "target", target, "err", clearErr)
+		}
+		resume()
+		return nil, err
+	}
+	recommended, err := c.RecommendSequencerWantingLockout(ctx)
+	if err != nil {
+		return abort(err)
+	}
+	if recommended != target {
+		return abort(fmt.Errorf("handoff target %v doesn't want the lockout", target))
+	}
+
+	releaseCtx, cancel := context.WithTimeout(ctx, c.config.HandoffTimeout)
+	defer cancel()
+	if !c.waitFor(releaseCtx, func() bool { return !c.CurrentlyChosen() }) {
+		return abort(errors.New("timed out waiting to release the lockout"))
+	}
+	pickupCtx, cancel := context.WithTimeout(ctx, c.config.HandoffTimeout)
+	defer cancel()
+	var pickupErr error
+	pickedUp := c.waitFor(pickupCtx, func() bool {
+		var chosen string
+		chosen, pickupErr = c.CurrentChosenSequencer(pickupCtx)
+		if pickupErr != nil || chosen != target {
+			return false
+		}
+		var remoteMsgCount arbutil.MessageIndex
+		remoteMsgCount, pickupErr = c.GetRemoteMsgCount()
+		if pickupErr == nil && remoteMsgCount < msgCount {
+			pickupErr = fmt.Errorf("target became chosen at message count %v, before %v", remoteMsgCount, msgCount)
+			return true
+		}
+		return pickupErr == nil
+	})
+	if !pickedUp || pickupErr != nil {
+		if pickupErr == nil {
+			pickupErr = errors.New("timed out")
+		}
+		// the lockout is already released, so this only unpins the target
+		return abort(fmt.Errorf("handoff target %v didn't pick up the lockout: %w", target, pickupErr))
+	}
+	log.Info("handed off the lockout", "myUrl", c.config.Url(), "target", target, "msgCount", msgCount)
+	return &SequencerHandoff{Target: target, MsgCount: hexutil.Uint64(msgCount)}, nil
+}
+
+// ClearHandoff makes the priorities pick the recommended sequencer again, undoing the last handoff.
+func (c *SeqCoordinator) ClearHandoff(ctx context.Context) error {
+	return c.SetHandoffTarget(ctx, "", 0)
+}
+
 // Undoes the effects of AvoidLockout. AvoidLockout must've been called before an equal number of times.
 func (c *SeqCoordinator) SeekLockout(ctx context.Context) {
 	c.wantsLockoutMutex.Lock()
@@ -110,6 +110,8 @@ func TestRedisSeqCoordinatorAtomic(t *testing.T) {
 	testData.testStartRound.Store(-1)
 	nullSigner, err := signature.NewSignVerify(&coordConfig.Signer, nil, nil)
 	Require(t, err)
+	encryptor, err := redisutil.NewPayloadEncryptor(&coordConfig.Encryption)
+	Require(t, err)
 
 	redisUrl := redisutil.CreateTestRedis(ctx, t)
 	coordConfig.RedisUrl = redisUrl
@@ -125,9 +127,10 @@ func TestRedisSeqCoordinatorAtomic(t *testing.T) {
 		redisCoordinator, err := redisutil.NewRedisCoordinator(config.RedisUrl)
 		Require(t, err)
 		coordinator := &SeqCoordinator{
-			RedisCoordinator: *redisCoordinator,
-			config:           config,
-			signer:           nullSigner,
+			CoordinationBackend: &RedisCoordinationBackend{RedisCoordinator: redisCoordinator},
+			config:              config,
+			signer:              nullSigner,
+			encryptor:           encryptor,
 		}
 		go coordinatorTestThread(ctx, coordinator, &testData)
 	}
@@ -0,0 +1,277 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/execution"
+	"github.com/offchainlabs/nitro/util/redisutil"
+)
+
+// CoordinationBackend stores the state sequencers coordinate through: the lockout held by the chosen
+// sequencer, the message count and messages it sequenced, and which sequencers want the lockout.
+type CoordinationBackend interface {
+	// RecommendSequencerWantingLockout returns the sequencer the lockout was handed off to if it wants the lockout,
+	// and otherwise the top priority sequencer wanting the lockout.
+	RecommendSequencerWantingLockout(ctx context.Context) (string, error)
+	// SetHandoffTarget makes url the recommended sequencer whenever it wants the lockout, until the
+	// ttl passes or the next handoff, or clears it if url is empty.
+	SetHandoffTarget(ctx context.Context, url string, ttl time.Duration) error
+	// CurrentChosenSequencer returns the sequencer holding the lockout, or "" if none does.
+	CurrentChosenSequencer(ctx context.Context) (string, error)
+	// CurrentChosenSyncUrl returns the address the chosen sequencer serves its messages at, or "" if it doesn't.
+	CurrentChosenSyncUrl(ctx context.Context) (string, error)
+	// GetPriorities returns the sequencers in priority order.
+	GetPriorities(ctx context.Context) ([]string, error)
+	// GetMsgCount returns the signed message count, or nil if it isn't set.
+	GetMsgCount(ctx context.Context) ([]byte, error)
+	// GetMessage returns the message at pos and its signature, which is nil for messages written
+	// with the signature in front of them.
+	GetMessage(ctx context.Context, pos arbutil.MessageIndex) ([]byte, []byte, error)
+	// DeleteMessages deletes the messages from from until to, and their signatures.
+	DeleteMessages(ctx context.Context, from, to arbutil.MessageIndex) error
+	// AcquireLockout atomically reads the chosen sequencer and signed message count and passes them to
+	// check. If check returns true, it takes or extends the lockout for url and applies the update,
+	// failing with execution.ErrRetrySequencer if another sequencer changed either meanwhile.
+	// It returns until when the lockout is held.
+	AcquireLockout(ctx context.Context, url string, check func(chosen string, msgCount []byte) (bool, error), update *LockoutUpdate) (time.Time, error)
+	// SetWantsLockout marks url as wanting the lockout until the given time.
+	SetWantsLockout(ctx context.Context, url string, until time.Time) error
+	// ReleaseWantsLockout clears url wanting the lockout.
+	ReleaseWantsLockout(ctx context.Context, url string) error
+	// ReleaseLockout gives up the lockout if url holds it.
+	ReleaseLockout(ctx context.Context, url string) error
+	Close() error
+}
+
+// failoverDetector is implemented by backends which can tell when the server behind them changes.
+type failoverDetector interface {
+	// ServerIdentity returns an identifier of the server, which changes when it fails over or restarts.
+	ServerIdentity(ctx context.Context) (string, error)
+}
+
+// LockoutUpdate is written along with taking or extending the lockout.
+type LockoutUpdate struct {
+	// the lockout is held until LockoutUntil, but a backend may keep an earlier expiry at or after MinLockoutUntil
+	LockoutUntil    time.Time
+	MinLockoutUntil time.Time
+	MsgCount        []byte
+	// if Message is set, it's written at MsgPos, along with MessageSig unless that's nil
+	MsgPos     arbutil.MessageIndex
+	Message    []byte
+	MessageSig []byte
+	// how long the message count and messages are kept
+	MessageRetention time.Duration
+	// if set, written as the address the sequencer serves its messages at, held as long as the lockout
+	SyncUrl string
+	// whether to also mark the sequencer as wanting the lockout, until LockoutUntil
+	SetWantsLockout bool
+}
+
+func newCoordinationBackend(config *SeqCoordinatorConfig) (CoordinationBackend, error) {
+	switch config.Backend {
+	case "redis":
+		redisCoordinator, err := redisutil.NewRedisCoordinator(config.RedisUrl)
+		if err != nil {
+			return nil, err
+		}
+		if redisCoordinator.Client == nil {
+			return nil, errors.New("seq-coordinator.redis-url is required with the redis backend")
+		}
+		return &RedisCoordinationBackend{RedisCoordinator: redisCoordinator}, nil
+	case "etcd":
+		return NewEtcdCoordinationBackend(&config.Etcd)
+	default:
+		return nil, fmt.Errorf("unknown seq-coordinator backend %q (expected \"redis\" or \"etcd\")", config.Backend)
+	}
+}
+
+// minLockoutTTL keeps keys from expiring before the transaction setting their exact expiry completes.
+const minLockoutTTL = 2 * time.Second
+
+func lockoutTTL(until time.Time) time.Duration {
+	ttl := time.Until(until)
+	if ttl < minLockoutTTL {
+		ttl = minLockoutTTL
+	}
+	return ttl
+}
+
+// RedisCoordinationBackend coordinates sequencers through Redis.
+type RedisCoordinationBackend struct {
+	*redisutil.RedisCoordinator
+}
+
+func execTestPipe(pipe redis.Pipeliner, ctx context.Context) error {
+	cmders, err := pipe.Exec(ctx)
+	if err != nil {
+		return err
+	}
+	for _, cmder := range cmders {
+		if err := cmder.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *RedisCoordinationBackend) getMsgCountBytes(ctx context.Context, r redis.Cmdable) ([]byte, error) {
+	resStr, err := r.Get(ctx, b.Key(redisutil.MSG_COUNT_KEY)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(resStr), nil
+}
+
+func (b *RedisCoordinationBackend) GetMsgCount(ctx context.Context) ([]byte, error) {
+	return b.getMsgCountBytes(ctx, b.Client)
+}
+
+func (b *RedisCoordinationBackend) GetMessage(ctx context.Context, pos arbutil.MessageIndex) ([]byte, []byte, error) {
+	msg, err := b.Client.Get(ctx, b.Key(redisutil.MessageKeyFor(pos))).Result()
+	if err != nil {
+		return nil, nil, err
+	}
+	sig, err := b.Client.Get(ctx, b.Key(redisutil.MessageSigKeyFor(pos))).Result()
+	if errors.Is(err, redis.Nil) {
+		return []byte(msg), nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return []byte(msg), []byte(sig), nil
+}
+
+func (b *RedisCoordinationBackend) DeleteMessages(ctx context.Context, from, to arbutil.MessageIndex) error {
+	pipe := b.Client.Pipeline()
+	for pos := from; pos < to; pos++ {
+		pipe.Del(ctx, b.Key(redisutil.MessageKeyFor(pos)), b.Key(redisutil.MessageSigKeyFor(pos)))
+	}
+	return execTestPipe(pipe, ctx)
+}
+
+func (b *RedisCoordinationBackend) AcquireLockout(ctx context.Context, url string, check func(chosen string, msgCount []byte) (bool, error), update *LockoutUpdate) (time.Time, error) {
+	err := b.Client.Watch(ctx, func(tx *redis.Tx) error {
+		current, err := tx.Get(ctx, b.Key(redisutil.CHOSENSEQ_KEY)).Result()
+		var wasEmpty bool
+		if errors.Is(err, redis.Nil) {
+			wasEmpty = true
+			err = nil
+		}
+		if err != nil {
+			return err
+		}
+		msgCount, err := b.getMsgCountBytes(ctx, tx)
+		if err != nil {
+			return err
+		}
+		write, err := check(current, msgCount)
+		if err != nil || !write {
+			return err
+		}
+		pipe := tx.TxPipeline()
+		initialDuration := lockoutTTL(update.LockoutUntil)
+		if wasEmpty {
+			pipe.Set(ctx, b.Key(redisutil.CHOSENSEQ_KEY), url, initialDuration)
+		}
+		pipe.Set(ctx, b.Key(redisutil.MSG_COUNT_KEY), update.MsgCount, update.MessageRetention)
+		if update.Message != nil {
+			pipe.Set(ctx, b.Key(redisutil.MessageKeyFor(update.MsgPos)), update.Message, update.MessageRetention)
+			if update.MessageSig != nil {
+				pipe.Set(ctx, b.Key(redisutil.MessageSigKeyFor(update.MsgPos)), update.MessageSig, update.MessageRetention)
+			}
+		}
+		pipe.PExpireAt(ctx, b.Key(redisutil.CHOSENSEQ_KEY), update.LockoutUntil)
+		if update.SyncUrl != "" {
+			pipe.Set(ctx, b.Key(redisutil.SYNC_URL_KEY), update.SyncUrl, initialDuration)
+			pipe.PExpireAt(ctx, b.Key(redisutil.SYNC_URL_KEY), update.LockoutUntil)
+		}
+		if update.SetWantsLockout {
+			myWantsLockoutKey := b.Key(redisutil.WantsLockoutKeyFor(url))
+			pipe.Set(ctx, myWantsLockoutKey, redisutil.WANTS_LOCKOUT_VAL, initialDuration)
+			pipe.PExpireAt(ctx, myWantsLockoutKey, update.LockoutUntil)
+		}
+		err = execTestPipe(pipe, ctx)
+		if errors.Is(err, redis.TxFailedErr) {
+			return fmt.Errorf("%w: failed to catch sequencer lock", execution.ErrRetrySequencer)
+		}
+		if err != nil {
+			return fmt.Errorf("chosen sequencer failed to update redis: %w", err)
+		}
+		return nil
+	}, b.Key(redisutil.CHOSENSEQ_KEY), b.Key(redisutil.MSG_COUNT_KEY))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return update.LockoutUntil, nil
+}
+
+func (b *RedisCoordinationBackend) SetWantsLockout(ctx context.Context, url string, until time.Time) error {
+	myWantsLockoutKey := b.Key(redisutil.WantsLockoutKeyFor(url))
+	pipe := b.Client.TxPipeline()
+	pipe.Set(ctx, myWantsLockoutKey, redisutil.WANTS_LOCKOUT_VAL, lockoutTTL(until))
+	pipe.PExpireAt(ctx, myWantsLockoutKey, until)
+	return execTestPipe(pipe, ctx)
+}
+
+func (b *RedisCoordinationBackend) ReleaseWantsLockout(ctx context.Context, url string) error {
+	myWantsLockoutKey := b.Key(redisutil.WantsLockoutKeyFor(url))
+	releaseErr := b.Client.Del(ctx, myWantsLockoutKey).Err()
+	if releaseErr != nil {
+		// got error - was it still deleted?
+		readErr := b.Client.Get(ctx, myWantsLockoutKey).Err()
+		if !errors.Is(readErr, redis.Nil) {
+			return releaseErr
+		}
+	}
+	return nil
+}
+
+func (b *RedisCoordinationBackend) ReleaseLockout(ctx context.Context, url string) error {
+	releaseErr := b.Client.Watch(ctx, func(tx *redis.Tx) error {
+		current, err := tx.Get(ctx, b.Key(redisutil.CHOSENSEQ_KEY)).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if current != url {
+			return nil
+		}
+		pipe := tx.TxPipeline()
+		pipe.Del(ctx, b.Key(redisutil.CHOSENSEQ_KEY))
+		err = execTestPipe(pipe, ctx)
+		if err != nil {
+			return fmt.Errorf("chosen sequencer failed to update redis: %w", err)
+		}
+		return nil
+	}, b.Key(redisutil.CHOSENSEQ_KEY))
+	if releaseErr == nil {
+		return nil
+	}
+	// got error - was it still released?
+	current, readErr := b.Client.Get(ctx, b.Key(redisutil.CHOSENSEQ_KEY)).Result()
+	if errors.Is(readErr, redis.Nil) {
+		return nil
+	}
+	if current != url {
+		return nil
+	}
+	return releaseErr
+}
+
+func (b *RedisCoordinationBackend) Close() error {
+	return b.Client.Close()
+}
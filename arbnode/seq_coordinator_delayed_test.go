@@ -0,0 +1,120 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/execution"
+	"github.com/offchainlabs/nitro/util/redisutil"
+	"github.com/offchainlabs/nitro/util/signature"
+)
+
+func TestSeqCoordinatorDelayedIgnoresLockout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config := TestSeqCoordinatorConfig
+	config.RedisUrl = redisutil.CreateTestRedis(ctx, t)
+	config.MyUrl = "single"
+	config.LockoutDuration = time.Minute
+	config.Signer.ECDSA.AcceptSequencer = false
+	config.Signer.SymmetricFallback = true
+	config.Signer.SymmetricSign = true
+	config.Signer.Symmetric = signature.TestSimpleHmacConfig
+	newCoordinator := func(config SeqCoordinatorConfig, streamer *TransactionStreamer) *SeqCoordinator {
+		t.Helper()
+		signer, err := signature.NewSignVerify(&config.Signer, nil, nil)
+		Require(t, err)
+		encryptor, err := redisutil.NewPayloadEncryptor(&config.Encryption)
+		Require(t, err)
+		backend, err := newCoordinationBackend(&config)
+		Require(t, err)
+		coordinator := &SeqCoordinator{
+			CoordinationBackend: backend,
+			streamer:            streamer,
+			config:              config,
+			signer:              signer,
+			encryptor:           encryptor,
+		}
+		coordinator.StopWaiter.Start(ctx, coordinator)
+		t.Cleanup(coordinator.StopWaiter.StopAndWait)
+		return coordinator
+	}
+
+	streamer := &TransactionStreamer{
+		db:                 rawdb.NewMemoryDatabase(),
+		newMessageNotifier: make(chan struct{}, 1),
+	}
+	messages := makeStreamerTestMessages(6, 100)
+	Require(t, streamer.writeMessages(0, messages[:2], nil))
+	coordinator := newCoordinator(config, streamer)
+	streamer.coordinator = coordinator
+	delayedConfig := TestDelayedSequencerConfig
+	delayedConfig.Dangerous.IgnoreCoordinator = true
+	coordinator.delayedSequencer = &DelayedSequencer{config: func() *DelayedSequencerConfig { return &delayedConfig }}
+
+	sequence := func(pos arbutil.MessageIndex, delayed bool) error {
+		t.Helper()
+		msg := messages[pos].MessageWithMeta
+		if delayed {
+			// a delayed message reads one more delayed message than the message before it
+			prev, err := streamer.GetMessage(pos - 1)
+			Require(t, err)
+			msg.DelayedMessagesRead = prev.DelayedMessagesRead + 1
+		}
+		return streamer.WriteMessageFromSequencer(pos, msg, execution.MessageResult{BlockHash: *messages[pos].BlockHash})
+	}
+	remoteMsgCount := func() arbutil.MessageIndex {
+		t.Helper()
+		count, err := coordinator.GetRemoteMsgCount()
+		Require(t, err)
+		return count
+	}
+
+	// without the lockout, only delayed messages are sequenced, and they're still written to the backend
+	if err := sequence(2, false); !errors.Is(err, execution.ErrRetrySequencer) {
+		Fail(t, "sequenced a message without the lockout", err)
+	}
+	Require(t, sequence(2, true))
+	if remoteMsgCount() != 3 {
+		Fail(t, "delayed message not written to the backend", remoteMsgCount())
+	}
+	if _, _, err := coordinator.GetMessage(ctx, 2); err != nil {
+		Fail(t, "delayed message not mirrored", err)
+	}
+
+	// with the flag off, delayed messages need the lockout too
+	Require(t, coordinator.ReleaseLockout(ctx, config.Url()))
+	atomicTimeWrite(&coordinator.lockoutUntil, time.Time{})
+	delayedConfig.Dangerous.IgnoreCoordinator = false
+	if err := sequence(3, true); !errors.Is(err, execution.ErrRetrySequencer) {
+		Fail(t, "sequenced a delayed message without the lockout or the flag", err)
+	}
+
+	// another sequencer holding the lockout is a conflict, and the delayed message isn't sequenced
+	delayedConfig.Dangerous.IgnoreCoordinator = true
+	otherConfig := config
+	otherConfig.MyUrl = "other"
+	other := newCoordinator(otherConfig, streamer)
+	Require(t, other.acquireLockoutAndWriteMessage(ctx, 3, 3, nil))
+	conflicts := delayedSequencerCoordinatorConflictCounter.Count()
+	if err := sequence(3, true); !errors.Is(err, execution.ErrRetrySequencer) {
+		Fail(t, "sequenced a delayed message while another sequencer holds the lockout", err)
+	}
+	if delayedSequencerCoordinatorConflictCounter.Count() != conflicts+1 {
+		Fail(t, "conflict with the other sequencer not counted")
+	}
+	msgCount, err := streamer.GetMessageCount()
+	Require(t, err)
+	if msgCount != 3 {
+		Fail(t, "conflicting delayed message written", msgCount)
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/execution"
+	"github.com/offchainlabs/nitro/util/etcdutil"
+	"github.com/offchainlabs/nitro/util/redisutil"
+)
+
+// etcdLease is a lease keys are attached to, so they expire like Redis keys with a TTL.
+type etcdLease struct {
+	id     clientv3.LeaseID
+	expiry time.Time
+}
+
+// EtcdCoordinationBackend coordinates sequencers through etcd, using the same key names as Redis.
+// Keys expire through leases, which are reused while they last long enough, so not every update needs a new lease.
+type EtcdCoordinationBackend struct {
+	*etcdutil.EtcdCoordinator
+	client *etcdutil.Client
+
+	leaseMutex    sync.Mutex
+	lockoutLease  etcdLease
+	wantsLease    etcdLease
+	messagesLease etcdLease
+}
+
+func NewEtcdCoordinationBackend(config *etcdutil.Config) (*EtcdCoordinationBackend, error) {
+	coordinator, err := etcdutil.NewEtcdCoordinator(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid seq-coordinator etcd config: %w", err)
+	}
+	return &EtcdCoordinationBackend{EtcdCoordinator: coordinator, client: coordinator.Client}, nil
+}
+
+// lease returns the cached lease if it lasts until at least minExpiry, or grants a new one lasting ttl.
+func (b *EtcdCoordinationBackend) lease(ctx context.Context, cached *etcdLease, ttl time.Duration, minExpiry time.Time) (etcdLease, error) {
+	b.leaseMutex.Lock()
+	defer b.leaseMutex.Unlock()
+	if cached.id != 0 && !cached.expiry.Before(minExpiry) {
+		return *cached, nil
+	}
+	// the lease's TTL counts from when etcd grants it, which is after now
+	start := time.Now()
+	id, err := b.client.Grant(ctx, ttl)
+	if err != nil {
+		return etcdLease{}, err
+	}
+	*cached = etcdLease{id: id, expiry: start.Add(ttl.Truncate(time.Second))}
+	return *cached, nil
+}
+
+func (b *EtcdCoordinationBackend) SetHandoffTarget(ctx context.Context, url string, ttl time.Duration) error {
+	if url == "" {
+		return b.client.Delete(ctx, redisutil.HANDOFF_KEY)
+	}
+	// handoffs are rare, so each gets its own lease rather than a cached one
+	lease, err := b.client.Grant(ctx, ttl)
+	if err != nil {
+		return err
+	}
+	return b.client.Put(ctx, redisutil.HANDOFF_KEY, []byte(url), lease)
+}
+
+func (b *EtcdCoordinationBackend) GetMsgCount(ctx context.Context) ([]byte, error) {
+	kv, err := b.client.Get(ctx, redisutil.MSG_COUNT_KEY)
+	if err != nil || kv == nil {
+		return nil, err
+	}
+	return kv.Value, nil
+}
+
+func (b *EtcdCoordinationBackend) GetMessage(ctx context.Context, pos arbutil.MessageIndex) ([]byte, []byte, error) {
+	msg, err := b.client.Get(ctx, redisutil.MessageKeyFor(pos))
+	if err != nil {
+		return nil, nil, err
+	}
+	if msg == nil {
+		return nil, nil, fmt.Errorf("message %v not found on etcd", pos)
+	}
+	sig, err := b.client.Get(ctx, redisutil.MessageSigKeyFor(pos))
+	if err != nil {
+		return nil, nil, err
+	}
+	if sig == nil {
+		return msg.Value, nil, nil
+	}
+	return msg.Value, sig.Value, nil
+}
+
+// etcdMaxTxnOps is etcd's default limit of operations in a transaction.
+const etcdMaxTxnOps = 128
+
+func (b *EtcdCoordinationBackend) DeleteMessages(ctx context.Context, from, to arbutil.MessageIndex) error {
+	var ops []clientv3.Op
+	for pos := from; pos < to; pos++ {
+		ops = append(ops, clientv3.OpDelete(redisutil.MessageKeyFor(pos)), clientv3.OpDelete(redisutil.MessageSigKeyFor(pos)))
+		if len(ops) >= etcdMaxTxnOps || pos+1 == to {
+			if _, err := b.client.Txn(ctx, nil, ops); err != nil {
+				return err
+			}
+			ops = nil
+		}
+	}
+	return nil
+}
+
+func (b *EtcdCoordinationBackend) AcquireLockout(ctx context.Context, url string, check func(chosen string, msgCount []byte) (bool, error), update *LockoutUpdate) (time.Time, error) {
+	chosen, err := b.client.Get(ctx, redisutil.CHOSENSEQ_KEY)
+	if err != nil {
+		return time.Time{}, err
+	}
+	msgCount, err := b.client.Get(ctx, redisutil.MSG_COUNT_KEY)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var chosenUrl string
+	var msgCountData []byte
+	var chosenRevision, msgCountRevision int64
+	if chosen != nil {
+		chosenUrl, chosenRevision = string(chosen.Value), chosen.ModRevision
+	}
+	if msgCount != nil {
+		msgCountData, msgCountRevision = msgCount.Value, msgCount.ModRevision
+	}
+	write, err := check(chosenUrl, msgCountData)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !write {
+		return update.LockoutUntil, nil
+	}
+
+	lockoutLease, err := b.lease(ctx, &b.lockoutLease, lockoutTTL(update.LockoutUntil), update.MinLockoutUntil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	messagesLease, err := b.lease(ctx, &b.messagesLease, update.MessageRetention, time.Now().Add(update.MessageRetention/2))
+	if err != nil {
+		return time.Time{}, err
+	}
+	withLockout, withMessages := clientv3.WithLease(lockoutLease.id), clientv3.WithLease(messagesLease.id)
+	ops := []clientv3.Op{
+		clientv3.OpPut(redisutil.CHOSENSEQ_KEY, url, withLockout),
+		clientv3.OpPut(redisutil.MSG_COUNT_KEY, string(update.MsgCount), withMessages),
+	}
+	if update.Message != nil {
+		ops = append(ops, clientv3.OpPut(redisutil.MessageKeyFor(update.MsgPos), string(update.Message), withMessages))
+		if update.MessageSig != nil {
+			ops = append(ops, clientv3.OpPut(redisutil.MessageSigKeyFor(update.MsgPos), string(update.MessageSig), withMessages))
+		}
+	}
+	if update.SyncUrl != "" {
+		ops = append(ops, clientv3.OpPut(redisutil.SYNC_URL_KEY, update.SyncUrl, withLockout))
+	}
+	if update.SetWantsLockout {
+		ops = append(ops, clientv3.OpPut(redisutil.WantsLockoutKeyFor(url), redisutil.WANTS_LOCKOUT_VAL, withLockout))
+	}
+	// the revisions compared make the transaction safe to apply only once, so if it fails
+	// ambiguously, the next update reads the keys again rather than retrying it
+	succeeded, err := b.client.Txn(ctx, []clientv3.Cmp{
+		clientv3.Compare(clientv3.ModRevision(redisutil.CHOSENSEQ_KEY), "=", chosenRevision),
+		clientv3.Compare(clientv3.ModRevision(redisutil.MSG_COUNT_KEY), "=", msgCountRevision),
+	}, ops)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("chosen sequencer failed to update etcd: %w", err)
+	}
+	if !succeeded {
+		return time.Time{}, fmt.Errorf("%w: failed to catch sequencer lock", execution.ErrRetrySequencer)
+	}
+	return lockoutLease.expiry, nil
+}
+
+func (b *EtcdCoordinationBackend) SetWantsLockout(ctx context.Context, url string, until time.Time) error {
+	ttl := lockoutTTL(until)
+	lease, err := b.lease(ctx, &b.wantsLease, ttl, time.Now().Add(ttl/2))
+	if err != nil {
+		return err
+	}
+	return b.client.Put(ctx, redisutil.WantsLockoutKeyFor(url), []byte(redisutil.WANTS_LOCKOUT_VAL), lease.id)
+}
+
+func (b *EtcdCoordinationBackend) ReleaseWantsLockout(ctx context.Context, url string) error {
+	return b.client.Delete(ctx, redisutil.WantsLockoutKeyFor(url))
+}
+
+func (b *EtcdCoordinationBackend) ReleaseLockout(ctx context.Context, url string) error {
+	// only deletes the lockout if it's still ours
+	_, err := b.client.Txn(ctx,
+		[]clientv3.Cmp{clientv3.Compare(clientv3.Value(redisutil.CHOSENSEQ_KEY), "=", url)},
+		[]clientv3.Op{clientv3.OpDelete(redisutil.CHOSENSEQ_KEY)},
+	)
+	if err != nil {
+		return err
+	}
+	b.leaseMutex.Lock()
+	defer b.leaseMutex.Unlock()
+	// a later lockout mustn't outlive its expected expiry by reusing this lease
+	b.lockoutLease = etcdLease{}
+	return nil
+}
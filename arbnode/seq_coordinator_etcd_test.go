@@ -0,0 +1,107 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/execution"
+	"github.com/offchainlabs/nitro/util/etcdutil"
+	"github.com/offchainlabs/nitro/util/redisutil"
+)
+
+func TestEtcdCoordinationBackend(t *testing.T) {
+	ctx := context.Background()
+	backend, err := NewEtcdCoordinationBackend(etcdutil.CreateTestEtcd(t))
+	Require(t, err)
+	defer backend.Close()
+
+	update := func(msgPos uint64, message string) *LockoutUpdate {
+		return &LockoutUpdate{
+			LockoutUntil:     time.Now().Add(time.Minute),
+			MinLockoutUntil:  time.Now().Add(30 * time.Second),
+			MsgCount:         []byte{byte(msgPos + 1)},
+			MsgPos:           arbutil.MessageIndex(msgPos),
+			Message:          []byte(message),
+			MessageSig:       []byte("sig"),
+			MessageRetention: time.Minute,
+			SetWantsLockout:  true,
+		}
+	}
+	takeIfFree := func(chosen string, msgCount []byte) (bool, error) {
+		return chosen == "" || chosen == "a", nil
+	}
+
+	until, err := backend.AcquireLockout(ctx, "a", takeIfFree, update(0, "first"))
+	Require(t, err)
+	if time.Until(until) < 30*time.Second {
+		Fail(t, "lockout held until", until)
+	}
+	chosen, err := backend.CurrentChosenSequencer(ctx)
+	Require(t, err)
+	if chosen != "a" {
+		Fail(t, "unexpected chosen sequencer", chosen)
+	}
+	msg, sig, err := backend.GetMessage(ctx, 0)
+	Require(t, err)
+	if string(msg) != "first" || string(sig) != "sig" {
+		Fail(t, "unexpected message", string(msg), string(sig))
+	}
+	liveliness, err := backend.GetLiveliness(ctx)
+	Require(t, err)
+	if len(liveliness) != 1 || liveliness[0] != "a" {
+		Fail(t, "unexpected liveliness", liveliness)
+	}
+
+	// the lockout isn't taken if another sequencer changes it meanwhile
+	_, err = backend.AcquireLockout(ctx, "a", func(chosen string, msgCount []byte) (bool, error) {
+		Require(t, backend.client.Put(ctx, redisutil.CHOSENSEQ_KEY, []byte("b"), clientv3.NoLease))
+		return true, nil
+	}, update(1, "second"))
+	if !errors.Is(err, execution.ErrRetrySequencer) {
+		Fail(t, "expected a conflicting update to fail with a retry, got", err)
+	}
+	if _, _, err := backend.GetMessage(ctx, 1); err == nil {
+		Fail(t, "message of a failed update was written")
+	}
+
+	// only the sequencer holding the lockout releases it
+	Require(t, backend.ReleaseLockout(ctx, "a"))
+	chosen, err = backend.CurrentChosenSequencer(ctx)
+	Require(t, err)
+	if chosen != "b" {
+		Fail(t, "released another sequencer's lockout, chosen", chosen)
+	}
+	Require(t, backend.ReleaseLockout(ctx, "b"))
+	chosen, err = backend.CurrentChosenSequencer(ctx)
+	Require(t, err)
+	if chosen != "" {
+		Fail(t, "lockout wasn't released, chosen", chosen)
+	}
+
+	Require(t, backend.DeleteMessages(ctx, 0, 1))
+	if _, _, err := backend.GetMessage(ctx, 0); err == nil {
+		Fail(t, "deleted message still found")
+	}
+
+	// the handoff target expires with a lease, so an interrupted handoff doesn't pin the lockout to it
+	Require(t, backend.SetHandoffTarget(ctx, "b", time.Minute))
+	handoff, err := backend.client.Get(ctx, redisutil.HANDOFF_KEY)
+	Require(t, err)
+	if handoff == nil || string(handoff.Value) != "b" || handoff.Lease == int64(clientv3.NoLease) {
+		Fail(t, "handoff target not set with a lease", handoff)
+	}
+	Require(t, backend.SetHandoffTarget(ctx, "", 0))
+	handoff, err = backend.client.Get(ctx, redisutil.HANDOFF_KEY)
+	Require(t, err)
+	if handoff != nil {
+		Fail(t, "handoff target not cleared", string(handoff.Value))
+	}
+}
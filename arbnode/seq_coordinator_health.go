@@ -0,0 +1,308 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"syscall"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/util/arbmath"
+)
+
+var (
+	healthVetoedGauge    = metrics.NewRegisteredGauge("arb/seqcoordinator/health/vetoed", nil)
+	healthVetoesCounter  = metrics.NewRegisteredCounter("arb/seqcoordinator/health/vetoes", nil)
+	healthBlockLagGauge  = metrics.NewRegisteredGauge("arb/seqcoordinator/health/block_lag", nil)
+	healthDiskFreeGauge  = metrics.NewRegisteredGauge("arb/seqcoordinator/health/disk_free", nil)
+	healthUnpostedGauge  = metrics.NewRegisteredGauge("arb/seqcoordinator/health/unposted_age", nil)
+	recommendedSelfGauge = metrics.NewRegisteredGauge("arb/seqcoordinator/recommended_self", nil)
+	selfDemotionsCounter = metrics.NewRegisteredCounter("arb/seqcoordinator/self_demotions", nil)
+)
+
+// SequencerHealthCheck reports whether the sequencer is healthy enough to become chosen. A sequencer that's up
+// but degraded can veto itself: it stops wanting the lockout, so the priorities recommend another sequencer,
+// and doesn't take the lockout until healthy again. The relative weight of healthy sequencers is up to the
+// priority policy on the coordination backend.
+//
+// The chosen sequencer runs the same checks, and a veto demotes it: once it stops wanting the lockout, another
+// sequencer is recommended, and it releases the lockout to it. It only does so once every message it sequenced
+// has been written to the backend, and keeps the lockout while no other sequencer wants it.
+type SequencerHealthCheck interface {
+	Name() string
+	// CheckHealth returns why the sequencer is degraded, or nil if it isn't.
+	CheckHealth(ctx context.Context) error
+}
+
+type SeqCoordinatorHealthConfig struct {
+	CheckInterval         time.Duration `koanf:"check-interval"`
+	MaxBlockLag           uint64        `koanf:"max-block-lag"`
+	MinFreeDiskMB         uint64        `koanf:"min-free-disk-mb"`
+	ExternalURL           string        `koanf:"external-url"`
+	ExternalTimeout       time.Duration `koanf:"external-timeout"`
+	MaxBatchPostingDelay  time.Duration `koanf:"max-batch-posting-delay"`
+	MaxFeedWithoutClients time.Duration `koanf:"max-feed-without-clients"`
+}
+
+var DefaultSeqCoordinatorHealthConfig = SeqCoordinatorHealthConfig{
+	CheckInterval:         5 * time.Second,
+	MaxBlockLag:           0,
+	MinFreeDiskMB:         0,
+	ExternalURL:           "",
+	ExternalTimeout:       time.Second,
+	MaxBatchPostingDelay:  0,
+	MaxFeedWithoutClients: 0,
+}
+
+var TestSeqCoordinatorHealthConfig = SeqCoordinatorHealthConfig{
+	CheckInterval:   10 * time.Millisecond,
+	ExternalTimeout: 100 * time.Millisecond,
+}
+
+func SeqCoordinatorHealthConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Duration(prefix+".check-interval", DefaultSeqCoordinatorHealthConfig.CheckInterval, "how often to rerun the health checks that can veto this sequencer becoming chosen")
+	f.Uint64(prefix+".max-block-lag", DefaultSeqCoordinatorHealthConfig.MaxBlockLag, "veto becoming chosen while execution is more than this many messages behind the coordinator (0 to disable)")
+	f.Uint64(prefix+".min-free-disk-mb", DefaultSeqCoordinatorHealthConfig.MinFreeDiskMB, "veto becoming chosen while the data directory has less than this many MB free (0 to disable)")
+	f.String(prefix+".external-url", DefaultSeqCoordinatorHealthConfig.ExternalURL, "if set, veto becoming chosen while a GET of this URL doesn't return a 2xx status")
+	f.Duration(prefix+".external-timeout", DefaultSeqCoordinatorHealthConfig.ExternalTimeout, "timeout for the external health check")
+	f.Duration(prefix+".max-batch-posting-delay", DefaultSeqCoordinatorHealthConfig.MaxBatchPostingDelay, "while chosen, veto keeping the lockout once a message sequenced has gone unposted in a batch for this long (should exceed the batch poster's max delay, 0 to disable)")
+	f.Duration(prefix+".max-feed-without-clients", DefaultSeqCoordinatorHealthConfig.MaxFeedWithoutClients, "while chosen, veto keeping the lockout once the sequencer feed has had no clients for this long (0 to disable)")
+}
+
+// blockLagHealthCheck vetoes while execution hasn't processed the messages the chosen sequencer already sequenced.
+type blockLagHealthCheck struct {
+	c      *SeqCoordinator
+	maxLag uint64
+}
+
+func (h *blockLagHealthCheck) Name() string { return "block-lag" }
+
+func (h *blockLagHealthCheck) CheckHealth(ctx context.Context) error {
+	processed, err := h.c.streamer.GetProcessedMessageCount()
+	if err != nil {
+		return err
+	}
+	remote, err := h.c.GetRemoteMsgCount()
+	if err != nil {
+		// the coordination backend being unreachable isn't this sequencer's health
+		log.Debug("block lag health check failed to read the remote message count", "err", err)
+		return nil
+	}
+	var lag uint64
+	if remote > processed {
+		lag = uint64(remote - processed)
+	}
+	healthBlockLagGauge.Update(int64(lag))
+	if lag > h.maxLag {
+		return fmt.Errorf("execution is %v messages behind the coordinator", lag)
+	}
+	return nil
+}
+
+// diskSpaceHealthCheck vetoes while the disk holding the data directory is nearly full.
+type diskSpaceHealthCheck struct {
+	path    string
+	minFree uint64
+}
+
+func NewDiskSpaceHealthCheck(path string, minFreeMB uint64) SequencerHealthCheck {
+	return &diskSpaceHealthCheck{path: path, minFree: minFreeMB * 1024 * 1024}
+}
+
+func (h *diskSpaceHealthCheck) Name() string { return "disk-space" }
+
+func (h *diskSpaceHealthCheck) CheckHealth(ctx context.Context) error {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(h.path, &stat); err != nil {
+		return fmt.Errorf("failed to read free space of %v: %w", h.path, err)
+	}
+	free := uint64(stat.Bavail) * uint64(stat.Bsize)
+	healthDiskFreeGauge.Update(int64(free))
+	if free < h.minFree {
+		return fmt.Errorf("only %v MB free in %v", free/1024/1024, h.path)
+	}
+	return nil
+}
+
+// externalHealthCheck defers to an operator supplied endpoint, e.g. one aggregating host metrics.
+type externalHealthCheck struct {
+	url     string
+	timeout time.Duration
+}
+
+func (h *externalHealthCheck) Name() string { return "external" }
+
+func (h *externalHealthCheck) CheckHealth(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.url, nil)
+	if err != nil {
+		return err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("external health check returned %v", res.Status)
+	}
+	return nil
+}
+
+// chosenSinceTime returns when this sequencer caught the lockout it holds, or the zero time if it isn't chosen.
+func (c *SeqCoordinator) chosenSinceTime() time.Time {
+	if !c.CurrentlyChosen() {
+		return time.Time{}
+	}
+	return atomicTimeRead(&c.chosenSince)
+}
+
+// oldestUnpostedMessage returns when the first message not yet posted in a batch was sequenced, or false if
+// every message has been posted.
+func (c *SeqCoordinator) oldestUnpostedMessage() (time.Time, bool, error) {
+	if c.streamer.inboxReader == nil {
+		return time.Time{}, false, nil
+	}
+	tracker := c.streamer.inboxReader.tracker
+	batchCount, err := tracker.GetBatchCount()
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	var posted arbutil.MessageIndex
+	if batchCount > 0 {
+		posted, err = tracker.GetBatchMessageCount(batchCount - 1)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+	}
+	msgCount, err := c.streamer.GetMessageCount()
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if posted >= msgCount {
+		return time.Time{}, false, nil
+	}
+	msg, err := c.streamer.GetMessage(posted)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return time.Unix(arbmath.SaturatingCast[int64](msg.Message.Header.Timestamp), 0), true, nil
+}
+
+// stalledFor returns how long something the chosen sequencer is responsible for has been stalled since the
+// given time, counting only from when it was chosen, so a newly chosen sequencer isn't blamed for its
+// predecessor's stall.
+func stalledFor(now, chosenSince, since time.Time) time.Duration {
+	if since.Before(chosenSince) {
+		since = chosenSince
+	}
+	return now.Sub(since)
+}
+
+// batchPostingHealthCheck vetoes while the chosen sequencer's messages aren't being posted to the parent chain.
+type batchPostingHealthCheck struct {
+	chosenSince    func() time.Time
+	oldestUnposted func() (time.Time, bool, error)
+	maxDelay       time.Duration
+}
+
+func (h *batchPostingHealthCheck) Name() string { return "batch-posting" }
+
+func (h *batchPostingHealthCheck) CheckHealth(ctx context.Context) error {
+	chosenSince := h.chosenSince()
+	if chosenSince.IsZero() {
+		// a standby doesn't post batches
+		return nil
+	}
+	oldest, found, err := h.oldestUnposted()
+	if err != nil {
+		return err
+	}
+	if !found {
+		healthUnpostedGauge.Update(0)
+		return nil
+	}
+	delay := stalledFor(time.Now(), chosenSince, oldest)
+	healthUnpostedGauge.Update(int64(delay.Seconds()))
+	if delay > h.maxDelay {
+		return fmt.Errorf("messages sequenced have gone unposted for %v", delay)
+	}
+	return nil
+}
+
+// feedClientsHealthCheck vetoes while the chosen sequencer's feed reaches no one, e.g. as the relays can't connect.
+type feedClientsHealthCheck struct {
+	chosenSince func() time.Time
+	clients     func() int32
+	maxDuration time.Duration
+	// when the feed was last seen without clients, from the workthread
+	withoutClientsSince time.Time
+}
+
+func (h *feedClientsHealthCheck) Name() string { return "feed-clients" }
+
+func (h *feedClientsHealthCheck) CheckHealth(ctx context.Context) error {
+	chosenSince := h.chosenSince()
+	if chosenSince.IsZero() || h.clients() > 0 {
+		h.withoutClientsSince = time.Time{}
+		return nil
+	}
+	now := time.Now()
+	if h.withoutClientsSince.IsZero() {
+		h.withoutClientsSince = now
+	}
+	if duration := stalledFor(now, chosenSince, h.withoutClientsSince); duration > h.maxDuration {
+		return fmt.Errorf("feed has had no clients for %v", duration)
+	}
+	return nil
+}
+
+// AddHealthCheck adds a check that can veto this sequencer becoming chosen. It must be called before Start.
+func (c *SeqCoordinator) AddHealthCheck(check SequencerHealthCheck) {
+	if c.Started() {
+		panic("trying to add a health check after start")
+	}
+	c.healthChecks = append(c.healthChecks, check)
+}
+
+// updateHealth reruns the health checks if they're due, returning whether any vetoes becoming chosen.
+func (c *SeqCoordinator) updateHealth(ctx context.Context) bool {
+	if len(c.healthChecks) == 0 {
+		return false
+	}
+	if time.Since(c.lastHealthCheck) < c.config.Health.CheckInterval {
+		return c.healthVetoed.Load()
+	}
+	c.lastHealthCheck = time.Now()
+	var vetoes []string
+	for _, check := range c.healthChecks {
+		if err := check.CheckHealth(ctx); err != nil {
+			vetoes = append(vetoes, fmt.Sprintf("%v: %v", check.Name(), err))
+		}
+	}
+	vetoed := len(vetoes) > 0
+	if vetoed != c.healthVetoed.Swap(vetoed) {
+		if vetoed {
+			healthVetoesCounter.Inc(1)
+			log.Warn("sequencer degraded, declining to become chosen", "myUrl", c.config.Url(), "vetoes", vetoes)
+		} else {
+			log.Info("sequencer healthy again", "myUrl", c.config.Url())
+		}
+	}
+	if vetoed {
+		healthVetoedGauge.Update(1)
+	} else {
+		healthVetoedGauge.Update(0)
+	}
+	return vetoed
+}
@@ -0,0 +1,123 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testHealthCheck struct {
+	err error
+}
+
+func (h *testHealthCheck) Name() string { return "test" }
+
+func (h *testHealthCheck) CheckHealth(ctx context.Context) error { return h.err }
+
+func TestSeqCoordinatorHealthVeto(t *testing.T) {
+	ctx := context.Background()
+	config := TestSeqCoordinatorConfig
+	config.Health.CheckInterval = time.Hour
+	coordinator := &SeqCoordinator{config: config}
+	if coordinator.updateHealth(ctx) {
+		Fail(t, "vetoed without health checks")
+	}
+
+	check := &testHealthCheck{}
+	coordinator.AddHealthCheck(check)
+	if coordinator.updateHealth(ctx) {
+		Fail(t, "vetoed while healthy")
+	}
+	check.err = errors.New("degraded")
+	if coordinator.updateHealth(ctx) {
+		Fail(t, "health rechecked before the check interval")
+	}
+	coordinator.lastHealthCheck = time.Time{}
+	if !coordinator.updateHealth(ctx) || !coordinator.healthVetoed.Load() {
+		Fail(t, "degraded sequencer not vetoed")
+	}
+	check.err = nil
+	coordinator.lastHealthCheck = time.Time{}
+	if coordinator.updateHealth(ctx) || coordinator.healthVetoed.Load() {
+		Fail(t, "veto not lifted once healthy")
+	}
+}
+
+func TestSeqCoordinatorBuiltinHealthChecks(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	Require(t, NewDiskSpaceHealthCheck(dir, 1).CheckHealth(ctx))
+	if NewDiskSpaceHealthCheck(dir, math.MaxUint64/1024/1024).CheckHealth(ctx) == nil {
+		Fail(t, "disk space check passed with an impossible minimum")
+	}
+
+	var healthy atomic.Bool
+	healthy.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	external := &externalHealthCheck{url: server.URL, timeout: time.Second}
+	Require(t, external.CheckHealth(ctx))
+	healthy.Store(false)
+	if external.CheckHealth(ctx) == nil {
+		Fail(t, "external check passed despite an unhealthy status")
+	}
+}
+
+func TestSeqCoordinatorStallHealthChecks(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	var chosenSince time.Time
+	oldest := now.Add(-time.Hour)
+	unposted := true
+	batchPosting := &batchPostingHealthCheck{
+		chosenSince:    func() time.Time { return chosenSince },
+		oldestUnposted: func() (time.Time, bool, error) { return oldest, unposted, nil },
+		maxDelay:       time.Minute,
+	}
+	Require(t, batchPosting.CheckHealth(ctx), "standby vetoed for batch posting")
+	// a newly chosen sequencer isn't blamed for its predecessor's stall
+	chosenSince = now.Add(-time.Second)
+	Require(t, batchPosting.CheckHealth(ctx))
+	chosenSince = now.Add(-2 * time.Minute)
+	if batchPosting.CheckHealth(ctx) == nil {
+		Fail(t, "stalled batch posting not vetoed")
+	}
+	unposted = false
+	Require(t, batchPosting.CheckHealth(ctx))
+
+	var clients atomic.Int32
+	feedClients := &feedClientsHealthCheck{
+		chosenSince: func() time.Time { return chosenSince },
+		clients:     clients.Load,
+		maxDuration: time.Minute,
+	}
+	Require(t, feedClients.CheckHealth(ctx))
+	if feedClients.withoutClientsSince.IsZero() {
+		Fail(t, "feed without clients not noticed")
+	}
+	feedClients.withoutClientsSince = now.Add(-2 * time.Minute)
+	if feedClients.CheckHealth(ctx) == nil {
+		Fail(t, "feed without clients not vetoed")
+	}
+	clients.Store(1)
+	Require(t, feedClients.CheckHealth(ctx))
+	if !feedClients.withoutClientsSince.IsZero() {
+		Fail(t, "feed clients not noticed")
+	}
+	clients.Store(0)
+	chosenSince = time.Time{}
+	feedClients.withoutClientsSince = now.Add(-2 * time.Minute)
+	Require(t, feedClients.CheckHealth(ctx), "standby vetoed for its feed")
+}
@@ -0,0 +1,151 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/execution"
+)
+
+var (
+	backendDegradedGauge     = metrics.NewRegisteredGauge("arb/seqcoordinator/backend/degraded", nil)
+	unpublishedMessagesGauge = metrics.NewRegisteredGauge("arb/seqcoordinator/backend/unpublished_messages", nil)
+)
+
+// How the coordinator behaves while the coordination backend is unreachable:
+//
+// The chosen sequencer can't extend its lockout, nor write the messages it sequences. Without a grace period,
+// it stops sequencing as soon as a message can't be written. With one, it keeps sequencing on its last known
+// lockout for up to the grace period, and never past the lockout's expiry, as another sequencer may take the
+// lockout after that. The messages it sequences meanwhile aren't broadcast, as another sequencer may sequence
+// over them. Once the backend is available again, it publishes and broadcasts them before anything else, and if
+// the grace period ran out, it takes the lockout back if no one else has.
+//
+// Standbys can't take the lockout while the backend is unreachable. With a grace period, once it's available
+// again they wait a lockout duration before taking the lockout, as the chosen sequencer may still have to
+// publish what it sequenced during the outage, so its messages aren't sequenced over.
+
+// noteBackendOutageWithMutex decides whether the chosen sequencer keeps sequencing after failing to write to
+// the backend with err, returning the error to fail sequencing with if not. The outageMutex must be held.
+func (c *SeqCoordinator) noteBackendOutageWithMutex(err error) error {
+	grace := c.config.BackendOutageGracePeriod
+	if grace <= 0 || errors.Is(err, execution.ErrRetrySequencer) {
+		return err
+	}
+	now := time.Now()
+	since := c.outageSince.Load()
+	if since == nil {
+		if !c.CurrentlyChosen() {
+			return err
+		}
+		since = &now
+		c.outageSince.Store(since)
+		backendDegradedGauge.Update(1)
+		log.Error("coordination backend unavailable, sequencing on the last known lockout", "myUrl", c.config.Url(), "lockoutUntil", atomicTimeRead(&c.lockoutUntil), "gracePeriod", grace, "err", err)
+	}
+	if c.CurrentlyChosen() && now.Sub(*since) < grace {
+		return nil
+	}
+	if c.CurrentlyChosen() {
+		atomicTimeWrite(&c.lockoutUntil, time.Time{})
+		isActiveSequencer.Update(0)
+		c.lastLockoutLoss.Store(&lockoutFailure{Cause: lockoutFailureUnavailable, At: now})
+		log.Error("coordination backend unavailable past the grace period, stopped sequencing", "myUrl", c.config.Url(), "outageSince", *since, "gracePeriod", grace, "err", err)
+	}
+	c.outageSince.Store(nil)
+	backendDegradedGauge.Update(0)
+	return fmt.Errorf("%w: coordination backend unavailable since %v: %w", execution.ErrRetrySequencer, since.UTC().Format(time.RFC3339), err)
+}
+
+// noteUnpublishedWithMutex records that the message at pos was sequenced without being written to the backend.
+// The outageMutex must be held.
+func (c *SeqCoordinator) noteUnpublishedWithMutex(pos arbutil.MessageIndex) {
+	if c.unpublishedFrom == nil {
+		c.unpublishedFrom = &pos
+	}
+	c.unpublishedTo = pos + 1
+	unpublishedMessagesGauge.Update(int64(pos + 1 - *c.unpublishedFrom))
+}
+
+// publishUnpublishedWithMutex writes and broadcasts the messages before msgCount that were sequenced while the
+// backend was unavailable. The outageMutex must be held.
+func (c *SeqCoordinator) publishUnpublishedWithMutex(ctx context.Context, msgCount arbutil.MessageIndex) error {
+	if c.unpublishedFrom == nil {
+		return nil
+	}
+	// the last message noted may not be written to the streamer yet
+	msgCount = min(msgCount, c.unpublishedTo)
+	for pos := *c.unpublishedFrom; pos < msgCount; pos++ {
+		msg, err := c.streamer.GetMessage(pos)
+		if err != nil {
+			return err
+		}
+		if err := c.acquireLockoutAndWriteMessage(ctx, pos, pos+1, msg); err != nil {
+			return err
+		}
+		next := pos + 1
+		c.unpublishedFrom = &next
+		unpublishedMessagesGauge.Update(int64(c.unpublishedTo - next))
+		if err := c.streamer.broadcastPublishedMessage(pos); err != nil {
+			log.Error("failed broadcasting message published after a coordination backend outage", "pos", pos, "err", err)
+		}
+	}
+	return nil
+}
+
+// hasUnpublishedMessages returns whether messages sequenced while the backend was unavailable are yet to be written.
+func (c *SeqCoordinator) hasUnpublishedMessages() bool {
+	c.outageMutex.Lock()
+	defer c.outageMutex.Unlock()
+	return c.unpublishedFrom != nil
+}
+
+// backendAvailableWithMutex records that the backend could be written to again, and whether every message
+// sequenced meanwhile has been. The outageMutex must be held.
+func (c *SeqCoordinator) backendAvailableWithMutex() {
+	if since := c.outageSince.Load(); since != nil {
+		log.Warn("coordination backend available again", "myUrl", c.config.Url(), "outageSince", *since, "outage", time.Since(*since))
+		c.outageSince.Store(nil)
+	}
+	if c.unpublishedFrom != nil && *c.unpublishedFrom >= c.unpublishedTo {
+		log.Info("published the messages sequenced while the coordination backend was unavailable", "myUrl", c.config.Url())
+		c.unpublishedFrom = nil
+	}
+	backendDegradedGauge.Update(0)
+	if c.unpublishedFrom == nil {
+		unpublishedMessagesGauge.Update(0)
+	}
+}
+
+// noteStandbyBackendAvailable is called from the update loop with whether the backend could be read.
+func (c *SeqCoordinator) noteStandbyBackendAvailable(available bool) {
+	if !available {
+		c.backendUnavailable = true
+		return
+	}
+	if c.backendUnavailable {
+		c.backendUnavailable = false
+		c.backendRecoveredAt = time.Now()
+		if c.config.BackendOutageGracePeriod > 0 {
+			log.Info("coordination backend available again, waiting a lockout duration before taking the lockout", "myUrl", c.config.Url(), "wait", c.config.LockoutDuration)
+		}
+	}
+}
+
+// holdingOffAfterOutage returns whether a standby waits before taking the lockout, as the chosen sequencer may
+// not have published the messages it sequenced while the backend was unavailable yet.
+func (c *SeqCoordinator) holdingOffAfterOutage() bool {
+	if c.config.BackendOutageGracePeriod <= 0 || c.backendRecoveredAt.IsZero() {
+		return false
+	}
+	return time.Since(c.backendRecoveredAt) < c.config.LockoutDuration
+}
@@ -0,0 +1,190 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/broadcaster"
+	m "github.com/offchainlabs/nitro/broadcaster/message"
+	"github.com/offchainlabs/nitro/execution"
+	"github.com/offchainlabs/nitro/util/redisutil"
+	"github.com/offchainlabs/nitro/util/signature"
+	"github.com/offchainlabs/nitro/wsbroadcastserver"
+)
+
+var errTestBackendDown = errors.New("coordination backend down")
+
+// outageBackend fails writing the lockout and messages while down.
+type outageBackend struct {
+	CoordinationBackend
+	down atomic.Bool
+}
+
+func (b *outageBackend) AcquireLockout(ctx context.Context, url string, check func(chosen string, msgCount []byte) (bool, error), update *LockoutUpdate) (time.Time, error) {
+	if b.down.Load() {
+		return time.Time{}, errTestBackendDown
+	}
+	return b.CoordinationBackend.AcquireLockout(ctx, url, check, update)
+}
+
+func TestSeqCoordinatorBackendOutage(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config := TestSeqCoordinatorConfig
+	config.RedisUrl = redisutil.CreateTestRedis(ctx, t)
+	config.MyUrl = "chosen"
+	config.LockoutDuration = time.Minute
+	config.BackendOutageGracePeriod = time.Hour
+	config.Signer.ECDSA.AcceptSequencer = false
+	config.Signer.SymmetricFallback = true
+	config.Signer.SymmetricSign = true
+	config.Signer.Symmetric = signature.TestSimpleHmacConfig
+	signer, err := signature.NewSignVerify(&config.Signer, nil, nil)
+	Require(t, err)
+	encryptor, err := redisutil.NewPayloadEncryptor(&config.Encryption)
+	Require(t, err)
+	redisBackend, err := newCoordinationBackend(&config)
+	Require(t, err)
+	backend := &outageBackend{CoordinationBackend: redisBackend}
+
+	broadcasterConfig := wsbroadcastserver.DefaultTestBroadcasterConfig
+	feed := broadcaster.NewBroadcaster(func() *wsbroadcastserver.BroadcasterConfig { return &broadcasterConfig }, 1, make(chan error, 10), nil)
+	Require(t, feed.Initialize())
+	Require(t, feed.Start(ctx))
+	defer feed.StopAndWait()
+	streamer := &TransactionStreamer{
+		db:                 rawdb.NewMemoryDatabase(),
+		newMessageNotifier: make(chan struct{}, 1),
+		broadcastServer:    feed,
+	}
+	messages := makeStreamerTestMessages(8, 100)
+	Require(t, streamer.writeMessages(0, messages[:2], nil))
+	chosen := &SeqCoordinator{
+		CoordinationBackend: backend,
+		streamer:            streamer,
+		config:              config,
+		signer:              signer,
+		encryptor:           encryptor,
+	}
+	streamer.coordinator = chosen
+	chosen.StopWaiter.Start(ctx, chosen)
+	defer chosen.StopWaiter.StopAndWait()
+	Require(t, chosen.acquireLockoutAndWriteMessage(ctx, 2, 2, nil))
+
+	sequence := func(pos arbutil.MessageIndex) error {
+		t.Helper()
+		return streamer.WriteMessageFromSequencer(pos, messages[pos].MessageWithMeta, execution.MessageResult{BlockHash: *messages[pos].BlockHash})
+	}
+	// broadcast returns the messages broadcast from the first sequenced, once the feed has count of them
+	broadcast := func(count int) []*m.BroadcastFeedMessage {
+		t.Helper()
+		for i := 0; feed.GetCachedMessageCount() < count && i < 100; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		// give a message that shouldn't have been broadcast the time to show up
+		time.Sleep(20 * time.Millisecond)
+		msgs, err := feed.FeedMessages(ctx, 2, 10)
+		Require(t, err)
+		return msgs
+	}
+	remoteMsgCount := func() arbutil.MessageIndex {
+		t.Helper()
+		count, err := chosen.GetRemoteMsgCount()
+		Require(t, err)
+		return count
+	}
+
+	// within the grace period, sequencing continues without the backend
+	backend.down.Store(true)
+	Require(t, sequence(2))
+	Require(t, sequence(3))
+	if chosen.outageSince.Load() == nil || chosen.unpublishedFrom == nil || *chosen.unpublishedFrom != 2 {
+		Fail(t, "outage not recorded")
+	}
+	if remoteMsgCount() != 2 {
+		Fail(t, "messages written during the outage")
+	}
+	// another sequencer may yet sequence over them, so they aren't broadcast
+	if msgs := broadcast(0); len(msgs) != 0 {
+		Fail(t, "messages broadcast before being published", len(msgs))
+	}
+
+	// once the backend is back, the messages sequenced meanwhile are written before the next
+	backend.down.Store(false)
+	Require(t, sequence(4))
+	if remoteMsgCount() != 5 {
+		Fail(t, "unexpected remote message count after the outage", remoteMsgCount())
+	}
+	for pos := arbutil.MessageIndex(2); pos < 5; pos++ {
+		if _, _, err := chosen.GetMessage(ctx, pos); err != nil {
+			Fail(t, "message sequenced during the outage not written", pos, err)
+		}
+	}
+	if chosen.outageSince.Load() != nil || chosen.unpublishedFrom != nil {
+		Fail(t, "outage not cleared")
+	}
+	msgs := broadcast(3)
+	if len(msgs) != 3 {
+		Fail(t, "unexpected messages broadcast once published", len(msgs))
+	}
+	for i, msg := range msgs {
+		if msg.SequenceNumber != arbutil.MessageIndex(2+i) || msg.BlockHash == nil || *msg.BlockHash != *messages[2+i].BlockHash {
+			Fail(t, "messages broadcast out of order or without their block hash", i, msg.SequenceNumber)
+		}
+	}
+
+	// past the grace period, the sequencer stops being chosen
+	chosen.config.BackendOutageGracePeriod = 50 * time.Millisecond
+	backend.down.Store(true)
+	Require(t, sequence(5))
+	time.Sleep(100 * time.Millisecond)
+	err = sequence(6)
+	if !errors.Is(err, execution.ErrRetrySequencer) || !errors.Is(err, errTestBackendDown) {
+		Fail(t, "sequenced past the grace period, err:", err)
+	}
+	if chosen.CurrentlyChosen() {
+		Fail(t, "still chosen past the grace period")
+	}
+	if msgs := broadcast(3); len(msgs) != 3 {
+		Fail(t, "message sequenced before the grace period ran out broadcast while unpublished")
+	}
+
+	// the keepalive takes the lockout back, writing the remaining message first
+	backend.down.Store(false)
+	chosen.updateWithLockout(ctx, "")
+	if !chosen.CurrentlyChosen() || remoteMsgCount() != 6 || chosen.unpublishedFrom != nil {
+		Fail(t, "lockout not taken back after the outage, remote message count", remoteMsgCount())
+	}
+	if msgs := broadcast(4); len(msgs) != 4 || msgs[3].SequenceNumber != 5 {
+		Fail(t, "message published by the keepalive not broadcast")
+	}
+
+	// without a grace period, sequencing stops immediately
+	chosen.config.BackendOutageGracePeriod = 0
+	backend.down.Store(true)
+	if err := sequence(6); !errors.Is(err, errTestBackendDown) {
+		Fail(t, "sequenced without the backend and no grace period, err:", err)
+	}
+
+	// standbys wait a lockout duration after an outage before taking the lockout
+	standby := &SeqCoordinator{config: config}
+	standby.noteStandbyBackendAvailable(false)
+	standby.noteStandbyBackendAvailable(true)
+	if !standby.holdingOffAfterOutage() {
+		Fail(t, "standby not holding off after an outage")
+	}
+	standby.backendRecoveredAt = time.Now().Add(-config.LockoutDuration)
+	if standby.holdingOffAfterOutage() {
+		Fail(t, "standby still holding off a lockout duration after an outage")
+	}
+}
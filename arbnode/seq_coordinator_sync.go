@@ -0,0 +1,584 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	flag "github.com/spf13/pflag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/util/signature"
+)
+
+var (
+	syncFetchedCounter  = metrics.NewRegisteredCounter("arb/seqcoordinator/sync/fetched", nil)
+	syncFallbackCounter = metrics.NewRegisteredCounter("arb/seqcoordinator/sync/fallback", nil)
+	syncPrunedCounter   = metrics.NewRegisteredCounter("arb/seqcoordinator/sync/pruned", nil)
+)
+
+// SeqCoordinatorMessageSyncConfig has standbys fetch the messages the chosen sequencer sequences from it over
+// an authenticated gRPC channel, rather than reading each one from the coordination backend. The chosen
+// sequencer still writes every message to the backend, so a standby can take over if it fails, but deletes
+// them as soon as every other sequencer in the priorities has fetched them.
+type SeqCoordinatorMessageSyncConfig struct {
+	Enable     bool                               `koanf:"enable"`
+	ListenAddr string                             `koanf:"listen-addr"`
+	MyAddr     string                             `koanf:"my-addr"`
+	JWTSecret  string                             `koanf:"jwtsecret"`
+	TLS        SeqCoordinatorMessageSyncTLSConfig `koanf:"tls"`
+	Timeout    time.Duration                      `koanf:"timeout"`
+}
+
+type SeqCoordinatorMessageSyncTLSConfig struct {
+	Enable   bool   `koanf:"enable"`
+	CertFile string `koanf:"cert-file"`
+	KeyFile  string `koanf:"key-file"`
+	CAFile   string `koanf:"ca-file"`
+}
+
+var DefaultSeqCoordinatorMessageSyncConfig = SeqCoordinatorMessageSyncConfig{
+	Enable:     false,
+	ListenAddr: "",
+	MyAddr:     "",
+	JWTSecret:  "",
+	TLS:        DefaultSeqCoordinatorMessageSyncTLSConfig,
+	Timeout:    5 * time.Second,
+}
+
+var DefaultSeqCoordinatorMessageSyncTLSConfig = SeqCoordinatorMessageSyncTLSConfig{
+	Enable:   false,
+	CertFile: "",
+	KeyFile:  "",
+	CAFile:   "",
+}
+
+var TestSeqCoordinatorMessageSyncConfig = SeqCoordinatorMessageSyncConfig{
+	Enable:  false,
+	Timeout: time.Second,
+}
+
+func SeqCoordinatorMessageSyncConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultSeqCoordinatorMessageSyncConfig.Enable, "have standby sequencers fetch sequenced messages from the chosen sequencer over gRPC instead of reading them from the coordination backend, which only keeps them until every sequencer in the priorities fetched them (must be enabled on every sequencer)")
+	f.String(prefix+".listen-addr", DefaultSeqCoordinatorMessageSyncConfig.ListenAddr, "address to serve this sequencer's messages to standbys on while it's chosen (e.g. :9645)")
+	f.String(prefix+".my-addr", DefaultSeqCoordinatorMessageSyncConfig.MyAddr, "address other sequencers reach this one's listen-addr at, as host:port")
+	f.String(prefix+".jwtsecret", DefaultSeqCoordinatorMessageSyncConfig.JWTSecret, "path to file with the jwtsecret sequencers authenticate to each other with")
+	f.Bool(prefix+".tls.enable", DefaultSeqCoordinatorMessageSyncTLSConfig.Enable, "use TLS for serving and fetching messages")
+	f.String(prefix+".tls.cert-file", DefaultSeqCoordinatorMessageSyncTLSConfig.CertFile, "path to the certificate to serve messages with")
+	f.String(prefix+".tls.key-file", DefaultSeqCoordinatorMessageSyncTLSConfig.KeyFile, "path to the key of the certificate to serve messages with")
+	f.String(prefix+".tls.ca-file", DefaultSeqCoordinatorMessageSyncTLSConfig.CAFile, "path to the CA certificates to verify the chosen sequencer's certificate with (empty for the system's)")
+	f.Duration(prefix+".timeout", DefaultSeqCoordinatorMessageSyncConfig.Timeout, "timeout fetching messages from the chosen sequencer")
+}
+
+func (c *SeqCoordinatorMessageSyncConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.JWTSecret == "" {
+		return errors.New("seq-coordinator.message-sync.jwtsecret is required to authenticate sequencers to each other")
+	}
+	if c.ListenAddr != "" && c.MyAddr == "" {
+		return errors.New("seq-coordinator.message-sync.my-addr is required to serve messages")
+	}
+	if c.TLS.Enable && c.ListenAddr != "" && (c.TLS.CertFile == "" || c.TLS.KeyFile == "") {
+		return errors.New("seq-coordinator.message-sync.tls requires a cert-file and key-file to serve messages")
+	}
+	if c.Timeout <= 0 {
+		return errors.New("seq-coordinator.message-sync.timeout must be positive")
+	}
+	return nil
+}
+
+// SyncedMessage is a sequenced message as it's written to the coordination backend.
+type SyncedMessage struct {
+	Message   []byte
+	Signature []byte
+}
+
+// syncedMessages returns up to count of this sequencer's messages starting at from, encrypted and signed.
+func (c *SeqCoordinator) syncedMessages(from arbutil.MessageIndex, count uint64) ([]SyncedMessage, error) {
+	msgCount, err := c.streamer.GetMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	until := from + arbutil.MessageIndex(count)
+	if count > uint64(c.config.MsgPerPoll) {
+		until = from + c.config.MsgPerPoll
+	}
+	if until > msgCount {
+		until = msgCount
+	}
+	var messages []SyncedMessage
+	for pos := from; pos < until; pos++ {
+		msg, err := c.streamer.GetMessage(pos)
+		if err != nil {
+			return nil, err
+		}
+		msgBytes, msgSig, err := c.encodeMessage(pos, msg)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, SyncedMessage{Message: msgBytes, Signature: msgSig})
+	}
+	return messages, nil
+}
+
+// noteStandbySynced records that standby has every message before msgCount.
+func (c *SeqCoordinator) noteStandbySynced(standby string, msgCount arbutil.MessageIndex) {
+	c.syncMutex.Lock()
+	defer c.syncMutex.Unlock()
+	if c.standbySynced == nil {
+		c.standbySynced = make(map[string]arbutil.MessageIndex)
+	}
+	if msgCount > c.standbySynced[standby] {
+		c.standbySynced[standby] = msgCount
+	}
+}
+
+// pruneSyncedMessages deletes the messages this sequencer wrote to the coordination backend once every other
+// sequencer in the priorities has fetched them. Until a sequencer in the priorities has fetched any, as when it's
+// down, messages are left to expire as without message sync. It must only be called from the workthread.
+func (c *SeqCoordinator) pruneSyncedMessages(ctx context.Context) {
+	priorities, err := c.GetPriorities(ctx)
+	if err != nil {
+		log.Warn("coordinator failed reading priorities to prune synced messages", "err", err)
+		return
+	}
+	c.syncMutex.Lock()
+	synced := arbutil.MessageIndex(0)
+	found := false
+	for _, url := range priorities {
+		if url == c.config.Url() {
+			continue
+		}
+		standbySynced, ok := c.standbySynced[url]
+		if !ok {
+			c.syncMutex.Unlock()
+			return
+		}
+		if !found || standbySynced < synced {
+			synced = standbySynced
+			found = true
+		}
+	}
+	c.syncMutex.Unlock()
+	from := c.syncPrunedUntil
+	if !found || synced <= from {
+		return
+	}
+	if synced > from+c.config.MsgPerPoll {
+		synced = from + c.config.MsgPerPoll
+	}
+	if err := c.DeleteMessages(ctx, from, synced); err != nil {
+		log.Warn("coordinator failed pruning synced messages", "from", from, "to", synced, "err", err)
+		return
+	}
+	syncPrunedCounter.Inc(int64(synced - from))
+	c.syncPrunedUntil = synced
+}
+
+// The gRPC service standbys fetch messages from the chosen sequencer with, as described by
+// seq_coordinator_sync.proto. Its messages are encoded with protowire rather than generated code.
+const (
+	messageSyncServiceName   = "nitro.seqcoordinator.v1.MessageSync"
+	sequencedMessagesMethod  = "SequencedMessages"
+	sequencedMessagesPath    = "/" + messageSyncServiceName + "/" + sequencedMessagesMethod
+	messageSyncJWTExpiry     = time.Minute
+	messageSyncAuthorization = "authorization"
+)
+
+type syncProtoMessage interface {
+	marshalProto() []byte
+	unmarshalProto(data []byte) error
+}
+
+type sequencedMessagesRequest struct {
+	From    uint64
+	Count   uint64
+	Standby string
+}
+
+func (r *sequencedMessagesRequest) marshalProto() []byte {
+	var b []byte
+	b = appendSyncProtoUint(b, 1, r.From)
+	b = appendSyncProtoUint(b, 2, r.Count)
+	if r.Standby != "" {
+		b = appendSyncProtoBytes(b, 3, []byte(r.Standby))
+	}
+	return b
+}
+
+func (r *sequencedMessagesRequest) unmarshalProto(data []byte) error {
+	return readSyncProtoFields(data, func(num protowire.Number, varint uint64, bytes []byte) {
+		switch num {
+		case 1:
+			r.From = varint
+		case 2:
+			r.Count = varint
+		case 3:
+			r.Standby = string(bytes)
+		}
+	})
+}
+
+type sequencedMessagesResponse struct {
+	Messages []SyncedMessage
+}
+
+func (r *sequencedMessagesResponse) marshalProto() []byte {
+	var b []byte
+	for _, msg := range r.Messages {
+		var inner []byte
+		inner = appendSyncProtoBytes(inner, 1, msg.Message)
+		inner = appendSyncProtoBytes(inner, 2, msg.Signature)
+		b = appendSyncProtoBytes(b, 1, inner)
+	}
+	return b
+}
+
+func (r *sequencedMessagesResponse) unmarshalProto(data []byte) error {
+	var innerErr error
+	err := readSyncProtoFields(data, func(num protowire.Number, _ uint64, bytes []byte) {
+		if num != 1 || innerErr != nil {
+			return
+		}
+		var msg SyncedMessage
+		innerErr = readSyncProtoFields(bytes, func(num protowire.Number, _ uint64, bytes []byte) {
+			switch num {
+			case 1:
+				msg.Message = common.CopyBytes(bytes)
+			case 2:
+				msg.Signature = common.CopyBytes(bytes)
+			}
+		})
+		r.Messages = append(r.Messages, msg)
+	})
+	if err != nil {
+		return err
+	}
+	return innerErr
+}
+
+func appendSyncProtoUint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendSyncProtoBytes(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+// readSyncProtoFields calls handle with each varint and length-delimited field, skipping fields of other types.
+func readSyncProtoFields(data []byte, handle func(num protowire.Number, varint uint64, bytes []byte)) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			handle(num, v, nil)
+			data = data[n:]
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			handle(num, 0, v)
+			data = data[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+	return nil
+}
+
+// syncCodec encodes the message sync service's messages.
+type syncCodec struct{}
+
+func (syncCodec) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(syncProtoMessage)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message sync message type %T", v)
+	}
+	return msg.marshalProto(), nil
+}
+
+func (syncCodec) Unmarshal(data []byte, v interface{}) error {
+	msg, ok := v.(syncProtoMessage)
+	if !ok {
+		return fmt.Errorf("unexpected message sync message type %T", v)
+	}
+	return msg.unmarshalProto(data)
+}
+
+func (syncCodec) Name() string {
+	return "proto"
+}
+
+type messageSyncService interface {
+	SequencedMessages(ctx context.Context, request *sequencedMessagesRequest) (*sequencedMessagesResponse, error)
+}
+
+var messageSyncServiceDesc = grpc.ServiceDesc{
+	ServiceName: messageSyncServiceName,
+	HandlerType: (*messageSyncService)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: sequencedMessagesMethod,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			request := &sequencedMessagesRequest{}
+			if err := dec(request); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.(messageSyncService).SequencedMessages(ctx, req.(*sequencedMessagesRequest))
+			}
+			if interceptor == nil {
+				return handler(ctx, request)
+			}
+			return interceptor(ctx, request, &grpc.UnaryServerInfo{Server: srv, FullMethod: sequencedMessagesPath}, handler)
+		},
+	}},
+	Metadata: "seq_coordinator_sync.proto",
+}
+
+// messageSyncServer serves the chosen sequencer's messages to standbys.
+type messageSyncServer struct {
+	coordinator *SeqCoordinator
+}
+
+func (s *messageSyncServer) SequencedMessages(ctx context.Context, request *sequencedMessagesRequest) (*sequencedMessagesResponse, error) {
+	if request.Standby != "" {
+		// a standby asks for the messages from its message count on, so it has every earlier one
+		s.coordinator.noteStandbySynced(request.Standby, arbutil.MessageIndex(request.From))
+	}
+	messages, err := s.coordinator.syncedMessages(arbutil.MessageIndex(request.From), request.Count)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &sequencedMessagesResponse{Messages: messages}, nil
+}
+
+func loadMessageSyncJWTSecret(path string) ([]byte, error) {
+	secret, err := signature.LoadSigningKey(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load seq-coordinator.message-sync.jwtsecret: %w", err)
+	}
+	return secret.Bytes(), nil
+}
+
+// authenticateMessageSync checks requests carry a token signed with the shared secret, issued within the
+// expiry, as the node's authenticated RPC does.
+func authenticateMessageSync(secret []byte) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get(messageSyncAuthorization)
+		if len(values) == 0 || !strings.HasPrefix(values[0], "Bearer ") {
+			return nil, status.Error(codes.Unauthenticated, "missing token")
+		}
+		claims := jwt.MapClaims{}
+		_, err := jwt.ParseWithClaims(strings.TrimPrefix(values[0], "Bearer "), claims, func(*jwt.Token) (interface{}, error) {
+			return secret, nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		issuedAt, ok := claims["iat"].(float64)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "missing issued-at")
+		}
+		if age := time.Since(time.Unix(int64(issuedAt), 0)); age > messageSyncJWTExpiry || age < -messageSyncJWTExpiry {
+			return nil, status.Error(codes.Unauthenticated, "stale token")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// messageSyncJWT authenticates requests to the chosen sequencer with a token signed with the shared secret.
+type messageSyncJWT struct {
+	secret     []byte
+	requireTLS bool
+}
+
+func (a messageSyncJWT) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"iat": time.Now().Unix()}).SignedString(a.secret)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{messageSyncAuthorization: "Bearer " + token}, nil
+}
+
+func (a messageSyncJWT) RequireTransportSecurity() bool {
+	return a.requireTLS
+}
+
+func (c *SeqCoordinator) messageSyncTransportCredentials(serving bool) (credentials.TransportCredentials, error) {
+	config := &c.config.MessageSync.TLS
+	if !config.Enable {
+		return insecure.NewCredentials(), nil
+	}
+	if serving {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return credentials.NewServerTLSFromCert(&cert), nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %v", config.CAFile)
+		}
+	}
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// newMessageSyncServer returns the server of this sequencer's messages, listening on the configured address.
+func (c *SeqCoordinator) newMessageSyncServer() (*grpc.Server, net.Listener, error) {
+	secret, err := loadMessageSyncJWTSecret(c.config.MessageSync.JWTSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+	creds, err := c.messageSyncTransportCredentials(true)
+	if err != nil {
+		return nil, nil, err
+	}
+	listener, err := net.Listen("tcp", c.config.MessageSync.ListenAddr)
+	if err != nil {
+		return nil, nil, err
+	}
+	server := grpc.NewServer(
+		grpc.Creds(creds),
+		grpc.ForceServerCodec(syncCodec{}),
+		grpc.UnaryInterceptor(authenticateMessageSync(secret)),
+	)
+	server.RegisterService(&messageSyncServiceDesc, &messageSyncServer{coordinator: c})
+	return server, listener, nil
+}
+
+func (c *SeqCoordinator) launchMessageSyncServer(ctx context.Context) {
+	server, listener, err := c.newMessageSyncServer()
+	if err != nil {
+		log.Error("failed to start seq coordinator message sync server", "err", err)
+		return
+	}
+	go func() {
+		<-ctx.Done()
+		server.Stop()
+	}()
+	if err := server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		log.Error("error serving seq coordinator message sync", "err", err)
+	}
+}
+
+// messageSyncClient returns a connection to the chosen sequencer's message sync address, reconnecting when
+// that changes. It must only be called from the workthread.
+func (c *SeqCoordinator) messageSyncClient(ctx context.Context) (*grpc.ClientConn, error) {
+	addr, err := c.CurrentChosenSyncUrl(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if addr == "" {
+		return nil, errors.New("chosen sequencer isn't serving its messages")
+	}
+	if c.syncClient != nil && c.syncClientAddr == addr {
+		return c.syncClient, nil
+	}
+	c.closeMessageSyncClient()
+	secret, err := loadMessageSyncJWTSecret(c.config.MessageSync.JWTSecret)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := c.messageSyncTransportCredentials(false)
+	if err != nil {
+		return nil, err
+	}
+	// connects lazily, so a sequencer that's down fails each fetch until it's back or replaced
+	conn, err := grpc.DialContext(ctx, addr,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithPerRPCCredentials(messageSyncJWT{secret: secret, requireTLS: c.config.MessageSync.TLS.Enable}),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(syncCodec{})),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the chosen sequencer at %v: %w", addr, err)
+	}
+	c.syncClient = conn
+	c.syncClientAddr = addr
+	return conn, nil
+}
+
+func (c *SeqCoordinator) closeMessageSyncClient() {
+	if c.syncClient != nil {
+		_ = c.syncClient.Close()
+		c.syncClient = nil
+		c.syncClientAddr = ""
+	}
+}
+
+// syncedMessageReader fetches the messages from from until until from the chosen sequencer, and returns a reader
+// of them which falls back to the coordination backend for any it didn't get, as when the chosen sequencer failed.
+func (c *SeqCoordinator) syncedMessageReader(ctx context.Context, from, until arbutil.MessageIndex) func(context.Context, arbutil.MessageIndex) ([]byte, []byte, error) {
+	response := &sequencedMessagesResponse{}
+	conn, err := c.messageSyncClient(ctx)
+	if err == nil {
+		request := &sequencedMessagesRequest{From: uint64(from), Standby: c.config.Url()}
+		if until > from {
+			request.Count = uint64(until - from)
+		}
+		callCtx, cancel := context.WithTimeout(ctx, c.config.MessageSync.Timeout)
+		err = conn.Invoke(callCtx, sequencedMessagesPath, request, response)
+		cancel()
+	}
+	if err != nil {
+		log.Warn("coordinator failed fetching messages from the chosen sequencer", "from", from, "err", err)
+	}
+	messages := response.Messages
+	syncFetchedCounter.Inc(int64(len(messages)))
+	return func(ctx context.Context, pos arbutil.MessageIndex) ([]byte, []byte, error) {
+		if pos >= from && pos-from < arbutil.MessageIndex(len(messages)) {
+			msg := messages[pos-from]
+			return msg.Message, msg.Signature, nil
+		}
+		syncFallbackCounter.Inc(1)
+		return c.GetMessage(ctx, pos)
+	}
+}
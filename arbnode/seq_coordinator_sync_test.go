@@ -0,0 +1,170 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/util/arbmath"
+	"github.com/offchainlabs/nitro/util/redisutil"
+	"github.com/offchainlabs/nitro/util/signature"
+	"github.com/offchainlabs/nitro/util/testhelpers"
+)
+
+func TestSeqCoordinatorMessageSync(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config := TestSeqCoordinatorConfig
+	config.RedisUrl = redisutil.CreateTestRedis(ctx, t)
+	config.Signer.ECDSA.AcceptSequencer = false
+	config.Signer.SymmetricFallback = true
+	config.Signer.SymmetricSign = true
+	config.Signer.Symmetric = signature.TestSimpleHmacConfig
+	config.MessageSync.Enable = true
+	config.MessageSync.ListenAddr = "127.0.0.1:0"
+	config.MessageSync.JWTSecret = testhelpers.RandomHash().Hex()
+	signer, err := signature.NewSignVerify(&config.Signer, nil, nil)
+	Require(t, err)
+	encryptor, err := redisutil.NewPayloadEncryptor(&config.Encryption)
+	Require(t, err)
+	newCoordinator := func(url string, streamer *TransactionStreamer) *SeqCoordinator {
+		backend, err := newCoordinationBackend(&config)
+		Require(t, err)
+		coordinatorConfig := config
+		coordinatorConfig.MyUrl = url
+		return &SeqCoordinator{
+			CoordinationBackend: backend,
+			streamer:            streamer,
+			config:              coordinatorConfig,
+			signer:              signer,
+			encryptor:           encryptor,
+		}
+	}
+
+	streamer := &TransactionStreamer{
+		db:                 rawdb.NewMemoryDatabase(),
+		newMessageNotifier: make(chan struct{}, 1),
+	}
+	messages := makeStreamerTestMessages(5, 100)
+	Require(t, streamer.writeMessages(0, messages, nil))
+	chosen := newCoordinator("chosen", streamer)
+
+	server, listener, err := chosen.newMessageSyncServer()
+	Require(t, err)
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+	chosen.config.MessageSync.MyAddr = listener.Addr().String()
+
+	redisCoordinator, err := redisutil.NewRedisCoordinator(config.RedisUrl)
+	Require(t, err)
+	Require(t, redisCoordinator.Client.Set(ctx, redisCoordinator.Key(redisutil.PRIORITIES_KEY), "chosen,standby", 0).Err())
+
+	for pos := arbutil.MessageIndex(0); pos < 5; pos++ {
+		msg := messages[pos].MessageWithMeta
+		Require(t, chosen.acquireLockoutAndWriteMessage(ctx, pos, pos+1, &msg))
+	}
+	// the backend keeps the messages, so a standby can take over without the chosen sequencer
+	if _, _, err := chosen.GetMessage(ctx, 4); err != nil {
+		Fail(t, "message payload not written to the coordination backend with message sync", err)
+	}
+
+	standby := newCoordinator("standby", nil)
+	defer standby.closeMessageSyncClient()
+	msgCountData, err := standby.GetMsgCount(ctx)
+	Require(t, err)
+	remoteMsgCount, err := standby.signedBytesToMsgCount(ctx, msgCountData)
+	Require(t, err)
+	if remoteMsgCount != 5 {
+		Fail(t, "unexpected remote message count", remoteMsgCount)
+	}
+	fallbacks := syncFallbackCounter.Count()
+	getMessage := standby.syncedMessageReader(ctx, 1, remoteMsgCount)
+	if standby.syncClientAddr != listener.Addr().String() {
+		Fail(t, "standby didn't connect to the chosen sequencer's sync address, got", standby.syncClientAddr)
+	}
+	for pos := arbutil.MessageIndex(1); pos < remoteMsgCount; pos++ {
+		msgBytes, sig, err := getMessage(ctx, pos)
+		Require(t, err)
+		Require(t, signer.VerifySignature(ctx, sig, arbmath.UintToBytes(uint64(pos)), msgBytes))
+		msgBytes, err = encryptor.Decrypt(msgBytes, arbmath.UintToBytes(uint64(pos)))
+		Require(t, err)
+		var msg arbostypes.MessageWithMetadata
+		Require(t, json.Unmarshal(msgBytes, &msg))
+		if msg.Message.L2msg[0] != byte(pos) {
+			Fail(t, "unexpected message synced at", pos)
+		}
+	}
+	if syncFallbackCounter.Count() != fallbacks {
+		Fail(t, "messages read from the backend rather than the chosen sequencer")
+	}
+	// a message the chosen sequencer doesn't have yet falls back to the backend, which doesn't have it either
+	if _, _, err := getMessage(ctx, remoteMsgCount); err == nil {
+		Fail(t, "read a message past the chosen sequencer's")
+	}
+
+	// the standby asked for the messages from 1 on, so the chosen sequencer may delete message 0
+	chosen.pruneSyncedMessages(ctx)
+	if _, _, err := chosen.GetMessage(ctx, 0); err == nil {
+		Fail(t, "message every standby fetched wasn't pruned")
+	}
+	if _, _, err := chosen.GetMessage(ctx, 1); err != nil {
+		Fail(t, "message a standby may not have fetched was pruned", err)
+	}
+	// once caught up, the standby still reports having every message
+	standby.syncedMessageReader(ctx, remoteMsgCount, remoteMsgCount)
+	chosen.pruneSyncedMessages(ctx)
+	if _, _, err := chosen.GetMessage(ctx, 4); err == nil {
+		Fail(t, "messages weren't pruned once every standby fetched them")
+	}
+	if chosen.syncPrunedUntil != remoteMsgCount {
+		Fail(t, "unexpected pruned message count", chosen.syncPrunedUntil)
+	}
+
+	// a sequencer in the priorities that never fetched any messages keeps them from being pruned
+	Require(t, redisCoordinator.Client.Set(ctx, redisCoordinator.Key(redisutil.PRIORITIES_KEY), "chosen,standby,down", 0).Err())
+	chosen.noteStandbySynced("standby", remoteMsgCount+1)
+	chosen.pruneSyncedMessages(ctx)
+	if chosen.syncPrunedUntil != remoteMsgCount {
+		Fail(t, "pruned messages a sequencer in the priorities hasn't fetched")
+	}
+
+	// requests without the shared secret are rejected
+	unauthenticated := newCoordinator("unauthenticated", nil)
+	unauthenticated.config.MessageSync.JWTSecret = testhelpers.RandomHash().Hex()
+	defer unauthenticated.closeMessageSyncClient()
+	conn, err := unauthenticated.messageSyncClient(ctx)
+	Require(t, err)
+	err = conn.Invoke(ctx, sequencedMessagesPath, &sequencedMessagesRequest{From: 1, Count: 1}, &sequencedMessagesResponse{})
+	if status.Code(err) != codes.Unauthenticated {
+		Fail(t, "expected an unauthenticated request to be rejected, got", err)
+	}
+}
+
+func TestSyncProtoEncoding(t *testing.T) {
+	request := &sequencedMessagesRequest{From: 7, Count: 3, Standby: "standby"}
+	var decodedRequest sequencedMessagesRequest
+	Require(t, decodedRequest.unmarshalProto(request.marshalProto()))
+	if decodedRequest != *request {
+		Fail(t, "unexpected decoded request", decodedRequest)
+	}
+	response := &sequencedMessagesResponse{Messages: []SyncedMessage{
+		{Message: []byte("first"), Signature: []byte("sig")},
+		{Message: []byte("second")},
+	}}
+	var decodedResponse sequencedMessagesResponse
+	Require(t, decodedResponse.unmarshalProto(response.marshalProto()))
+	if len(decodedResponse.Messages) != 2 || string(decodedResponse.Messages[0].Message) != "first" || string(decodedResponse.Messages[0].Signature) != "sig" || string(decodedResponse.Messages[1].Message) != "second" || len(decodedResponse.Messages[1].Signature) != 0 {
+		Fail(t, "unexpected decoded response", decodedResponse)
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/offchainlabs/nitro/arbstate/daprovider"
 	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/util/arbmath"
 
 	"github.com/offchainlabs/nitro/solgen/go/bridgegen"
 )
@@ -96,6 +97,16 @@ func (i *SequencerInbox) GetAccumulator(ctx context.Context, sequenceNumber uint
 	return acc, err
 }
 
+// MaxTimeVariation returns how many parent chain blocks and seconds must pass after a delayed message
+// was posted before it can be force included.
+func (i *SequencerInbox) MaxTimeVariation(ctx context.Context) (uint64, uint64, error) {
+	delayBlocks, _, delaySeconds, _, err := i.con.MaxTimeVariation(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return 0, 0, err
+	}
+	return arbmath.BigToUintSaturating(delayBlocks), arbmath.BigToUintSaturating(delaySeconds), nil
+}
+
 type SequencerInboxBatch struct {
 	BlockHash              common.Hash
 	ParentChainBlockNumber uint64
@@ -0,0 +1,276 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/util/arbmath"
+	"github.com/offchainlabs/nitro/util/headerreader"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+var (
+	inclusionDelayHistogram   = metrics.NewRegisteredHistogram("arb/softconfirm/inclusion/delay", nil, metrics.NewBoundedHistogramSample())
+	unbatchedMessagesGauge    = metrics.NewRegisteredGauge("arb/softconfirm/outstanding/messages", nil)
+	worstCaseExposureGauge    = metrics.NewRegisteredGauge("arb/softconfirm/outstanding/age", nil)
+	oldestUnbatchedIndexGauge = metrics.NewRegisteredGauge("arb/softconfirm/outstanding/oldest", nil)
+)
+
+// SoftConfirmationMonitor measures how long sequenced messages stay soft confirmed
+// (known only through the sequencer's word) before they're posted to the parent chain in a batch.
+// A message is considered soft confirmed at the timestamp the sequencer gave it,
+// and included at the timestamp of the parent chain block that posted its batch.
+type SoftConfirmationMonitor struct {
+	stopwaiter.StopWaiter
+	streamer *TransactionStreamer
+	inbox    *InboxTracker
+	l1Reader *headerreader.HeaderReader
+	config   SoftConfirmationMonitorConfigFetcher
+
+	mutex sync.Mutex
+	// the number of batches whose messages have been measured, or nil before the first update
+	measuredBatches *uint64
+}
+
+type SoftConfirmationMonitorConfig struct {
+	Enable               bool          `koanf:"enable"`
+	UpdateInterval       time.Duration `koanf:"update-interval" reload:"hot"`
+	MaxMessagesPerUpdate uint64        `koanf:"max-messages-per-update" reload:"hot"`
+}
+
+type SoftConfirmationMonitorConfigFetcher func() *SoftConfirmationMonitorConfig
+
+var DefaultSoftConfirmationMonitorConfig = SoftConfirmationMonitorConfig{
+	Enable:               true,
+	UpdateInterval:       5 * time.Second,
+	MaxMessagesPerUpdate: 10_000,
+}
+
+var TestSoftConfirmationMonitorConfig = SoftConfirmationMonitorConfig{
+	Enable:               true,
+	UpdateInterval:       100 * time.Millisecond,
+	MaxMessagesPerUpdate: 10_000,
+}
+
+func SoftConfirmationMonitorConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultSoftConfirmationMonitorConfig.Enable, "measure how long messages stay soft confirmed before batch inclusion")
+	f.Duration(prefix+".update-interval", DefaultSoftConfirmationMonitorConfig.UpdateInterval, "how often to update the soft confirmation metrics")
+	f.Uint64(prefix+".max-messages-per-update", DefaultSoftConfirmationMonitorConfig.MaxMessagesPerUpdate, "maximum number of newly batched messages to measure per update; older ones are skipped")
+}
+
+func NewSoftConfirmationMonitor(streamer *TransactionStreamer, inbox *InboxTracker, l1Reader *headerreader.HeaderReader, config SoftConfirmationMonitorConfigFetcher) *SoftConfirmationMonitor {
+	return &SoftConfirmationMonitor{
+		streamer: streamer,
+		inbox:    inbox,
+		l1Reader: l1Reader,
+		config:   config,
+	}
+}
+
+func (m *SoftConfirmationMonitor) Start(ctxIn context.Context) {
+	m.StopWaiter.Start(ctxIn, m)
+	m.CallIteratively(func(ctx context.Context) time.Duration {
+		if err := m.update(ctx); err != nil && ctx.Err() == nil {
+			log.Warn("failed to update soft confirmation metrics", "err", err)
+		}
+		return m.config().UpdateInterval
+	})
+}
+
+type SoftConfirmationExposure struct {
+	MessageCount             hexutil.Uint64 `json:"messageCount"`
+	BatchedMessageCount      hexutil.Uint64 `json:"batchedMessageCount"`
+	UnbatchedMessages        hexutil.Uint64 `json:"unbatchedMessages"`
+	OldestUnbatchedMessage   hexutil.Uint64 `json:"oldestUnbatchedMessage"`
+	OldestUnbatchedTimestamp hexutil.Uint64 `json:"oldestUnbatchedTimestamp"`
+	// how long the oldest message that isn't in a batch yet has been soft confirmed for
+	WorstCaseExposureSeconds hexutil.Uint64               `json:"worstCaseExposureSeconds"`
+	RecentInclusionDelay     SoftConfirmationDelaySummary `json:"recentInclusionDelay"`
+}
+
+type SoftConfirmationDelaySummary struct {
+	Samples     hexutil.Uint64 `json:"samples"`
+	MeanSeconds float64        `json:"meanSeconds"`
+	P50Seconds  float64        `json:"p50Seconds"`
+	P99Seconds  float64        `json:"p99Seconds"`
+	MaxSeconds  hexutil.Uint64 `json:"maxSeconds"`
+}
+
+type MessageSoftConfirmation struct {
+	MessageIndex hexutil.Uint64 `json:"messageIndex"`
+	// delayed messages are included through the delayed inbox rather than trusted from the sequencer
+	Delayed         bool            `json:"delayed"`
+	SoftConfirmedAt hexutil.Uint64  `json:"softConfirmedAt"`
+	Batched         bool            `json:"batched"`
+	Batch           *hexutil.Uint64 `json:"batch,omitempty"`
+	BatchIncludedAt *hexutil.Uint64 `json:"batchIncludedAt,omitempty"`
+	// for unbatched messages, how long they've been soft confirmed so far
+	DelaySeconds hexutil.Uint64 `json:"delaySeconds"`
+}
+
+func (m *SoftConfirmationMonitor) batchedMessageCount() (arbutil.MessageIndex, error) {
+	batchCount, err := m.inbox.GetBatchCount()
+	if err != nil || batchCount == 0 {
+		return 0, err
+	}
+	return m.inbox.GetBatchMessageCount(batchCount - 1)
+}
+
+func (m *SoftConfirmationMonitor) parentChainTimestamp(ctx context.Context, block uint64) (uint64, error) {
+	if m.l1Reader == nil {
+		return uint64(time.Now().Unix()), nil
+	}
+	header, err := m.l1Reader.Client().HeaderByNumber(ctx, new(big.Int).SetUint64(block))
+	if err != nil {
+		return 0, err
+	}
+	return header.Time, nil
+}
+
+// Exposure reports how much of the chain is currently only soft confirmed.
+func (m *SoftConfirmationMonitor) Exposure() (*SoftConfirmationExposure, error) {
+	msgCount, err := m.streamer.GetMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	batchedCount, err := m.batchedMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	snapshot := inclusionDelayHistogram.Snapshot()
+	exposure := &SoftConfirmationExposure{
+		MessageCount:        hexutil.Uint64(msgCount),
+		BatchedMessageCount: hexutil.Uint64(batchedCount),
+		RecentInclusionDelay: SoftConfirmationDelaySummary{
+			Samples:     hexutil.Uint64(snapshot.Count()),
+			MeanSeconds: snapshot.Mean(),
+			P50Seconds:  snapshot.Percentile(0.5),
+			P99Seconds:  snapshot.Percentile(0.99),
+			MaxSeconds:  hexutil.Uint64(arbmath.SaturatingUCast[uint64](snapshot.Max())),
+		},
+	}
+	if msgCount <= batchedCount {
+		return exposure, nil
+	}
+	oldest, err := m.streamer.GetMessage(batchedCount)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := oldest.Message.Header.Timestamp
+	exposure.UnbatchedMessages = hexutil.Uint64(msgCount - batchedCount)
+	exposure.OldestUnbatchedMessage = hexutil.Uint64(batchedCount)
+	exposure.OldestUnbatchedTimestamp = hexutil.Uint64(timestamp)
+	exposure.WorstCaseExposureSeconds = hexutil.Uint64(arbmath.SaturatingUSub(uint64(time.Now().Unix()), timestamp))
+	return exposure, nil
+}
+
+// MessageSoftConfirmation reports when a message was soft confirmed and when its batch was posted, if it has been.
+func (m *SoftConfirmationMonitor) MessageSoftConfirmation(ctx context.Context, pos arbutil.MessageIndex) (*MessageSoftConfirmation, error) {
+	msg, err := m.streamer.GetMessage(pos)
+	if err != nil {
+		return nil, err
+	}
+	header := msg.Message.Header
+	result := &MessageSoftConfirmation{
+		MessageIndex:    hexutil.Uint64(pos),
+		Delayed:         header.RequestId != nil,
+		SoftConfirmedAt: hexutil.Uint64(header.Timestamp),
+	}
+	batch, found, err := m.inbox.FindInboxBatchContainingMessage(pos)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		result.DelaySeconds = hexutil.Uint64(arbmath.SaturatingUSub(uint64(time.Now().Unix()), header.Timestamp))
+		return result, nil
+	}
+	parentChainBlock, err := m.inbox.GetBatchParentChainBlock(batch)
+	if err != nil {
+		return nil, err
+	}
+	includedAt, err := m.parentChainTimestamp(ctx, parentChainBlock)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get timestamp of parent chain block %v: %w", parentChainBlock, err)
+	}
+	batchNum := hexutil.Uint64(batch)
+	includedAtHex := hexutil.Uint64(includedAt)
+	result.Batched = true
+	result.Batch = &batchNum
+	result.BatchIncludedAt = &includedAtHex
+	result.DelaySeconds = hexutil.Uint64(arbmath.SaturatingUSub(includedAt, header.Timestamp))
+	return result, nil
+}
+
+func (m *SoftConfirmationMonitor) update(ctx context.Context) error {
+	exposure, err := m.Exposure()
+	if err != nil {
+		return err
+	}
+	unbatchedMessagesGauge.Update(int64(exposure.UnbatchedMessages))
+	worstCaseExposureGauge.Update(int64(exposure.WorstCaseExposureSeconds))
+	oldestUnbatchedIndexGauge.Update(int64(exposure.OldestUnbatchedMessage))
+	return m.measureNewBatches(ctx)
+}
+
+// measureNewBatches records the inclusion delay of every sequenced message in batches posted since the last update.
+func (m *SoftConfirmationMonitor) measureNewBatches(ctx context.Context) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	batchCount, err := m.inbox.GetBatchCount()
+	if err != nil {
+		return err
+	}
+	if m.measuredBatches == nil || *m.measuredBatches > batchCount {
+		// don't backfill history on startup, and start over after a batch reorg
+		m.measuredBatches = &batchCount
+		return nil
+	}
+	maxMessages := m.config().MaxMessagesPerUpdate
+	for batch := *m.measuredBatches; batch < batchCount; batch++ {
+		meta, err := m.inbox.GetBatchMetadata(batch)
+		if err != nil {
+			return err
+		}
+		var start arbutil.MessageIndex
+		if batch > 0 {
+			start, err = m.inbox.GetBatchMessageCount(batch - 1)
+			if err != nil {
+				return err
+			}
+		}
+		if uint64(meta.MessageCount-start) > maxMessages {
+			start = meta.MessageCount - arbutil.MessageIndex(maxMessages)
+		}
+		includedAt, err := m.parentChainTimestamp(ctx, meta.ParentChainBlock)
+		if err != nil {
+			return err
+		}
+		for pos := start; pos < meta.MessageCount; pos++ {
+			msg, err := m.streamer.GetMessage(pos)
+			if err != nil {
+				return err
+			}
+			if msg.Message.Header.RequestId != nil {
+				continue
+			}
+			delay := arbmath.SaturatingUSub(includedAt, msg.Message.Header.Timestamp)
+			inclusionDelayHistogram.Update(int64(delay))
+		}
+		next := batch + 1
+		m.measuredBatches = &next
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/util/containers"
+)
+
+func writeTestBatches(t *testing.T, inbox *InboxTracker, messageCounts ...arbutil.MessageIndex) {
+	t.Helper()
+	for i, count := range messageCounts {
+		data, err := rlp.EncodeToBytes(BatchMetadata{MessageCount: count, ParentChainBlock: uint64(i)})
+		Require(t, err)
+		Require(t, inbox.db.Put(dbKey(sequencerBatchMetaPrefix, uint64(i)), data))
+	}
+	data, err := rlp.EncodeToBytes(uint64(len(messageCounts)))
+	Require(t, err)
+	Require(t, inbox.db.Put(sequencerBatchCountKey, data))
+}
+
+func TestSoftConfirmationMonitor(t *testing.T) {
+	ctx := context.Background()
+	streamer := &TransactionStreamer{
+		db:                 rawdb.NewMemoryDatabase(),
+		newMessageNotifier: make(chan struct{}, 1),
+	}
+	Require(t, streamer.writeMessages(0, makeStreamerTestMessages(6, 10), nil))
+	inbox := &InboxTracker{
+		db:        rawdb.NewMemoryDatabase(),
+		batchMeta: containers.NewLruCache[uint64, BatchMetadata](10),
+	}
+	writeTestBatches(t, inbox, 3)
+	config := TestSoftConfirmationMonitorConfig
+	monitor := NewSoftConfirmationMonitor(streamer, inbox, nil, func() *SoftConfirmationMonitorConfig { return &config })
+
+	exposure, err := monitor.Exposure()
+	Require(t, err)
+	if exposure.UnbatchedMessages != 3 || exposure.OldestUnbatchedMessage != 3 || exposure.OldestUnbatchedTimestamp != 3 {
+		Fail(t, "unexpected exposure", exposure)
+	}
+	if exposure.WorstCaseExposureSeconds == 0 {
+		Fail(t, "expected the oldest unbatched message to be exposed")
+	}
+
+	batched, err := monitor.MessageSoftConfirmation(ctx, 1)
+	Require(t, err)
+	if !batched.Batched || batched.Batch == nil || *batched.Batch != 0 || batched.SoftConfirmedAt != 1 {
+		Fail(t, "unexpected batched message status", batched)
+	}
+	unbatched, err := monitor.MessageSoftConfirmation(ctx, 4)
+	Require(t, err)
+	if unbatched.Batched || unbatched.Batch != nil {
+		Fail(t, "unexpected unbatched message status", unbatched)
+	}
+
+	// the first update only records where measuring starts
+	Require(t, monitor.measureNewBatches(ctx))
+	writeTestBatches(t, inbox, 3, 6)
+	Require(t, monitor.measureNewBatches(ctx))
+	if monitor.measuredBatches == nil || *monitor.measuredBatches != 2 {
+		Fail(t, "expected the new batch to be measured, got", monitor.measuredBatches)
+	}
+	exposure, err = monitor.Exposure()
+	Require(t, err)
+	if exposure.UnbatchedMessages != 0 || exposure.WorstCaseExposureSeconds != 0 {
+		Fail(t, "expected no exposure once everything is batched", exposure)
+	}
+}
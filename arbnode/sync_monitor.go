@@ -130,6 +130,10 @@ func (s *SyncMonitor) FullSyncProgressMap() map[string]interface{} {
 
 	res["feedPendingMessageCount"] = s.txStreamer.FeedPendingMessageCount()
 
+	if halt := s.txStreamer.QuarantineHalt(); halt != nil {
+		res["quarantineHalt"] = halt
+	}
+
 	if s.inboxReader != nil {
 		batchSeen := s.inboxReader.GetLastSeenBatchCount()
 		res["batchSeen"] = batchSeen
@@ -200,6 +204,10 @@ func (s *SyncMonitor) Synced() bool {
 		return false
 	}
 
+	if s.txStreamer.QuarantineHalt() != nil {
+		return false
+	}
+
 	if s.inboxReader != nil {
 		batchSeen := s.inboxReader.GetLastSeenBatchCount()
 		if batchSeen == 0 {
@@ -24,6 +24,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 
@@ -39,6 +40,8 @@ import (
 	"github.com/offchainlabs/nitro/util/stopwaiter"
 )
 
+var blockHashMismatchCounter = metrics.NewRegisteredCounter("arb/feed/blockhash/mismatch", nil)
+
 // TransactionStreamer produces blocks from a node's L1 messages, storing the results in the blockchain and recording their positions
 // The streamer is notified when there's new batches to process
 type TransactionStreamer struct {
@@ -63,17 +66,58 @@ type TransactionStreamer struct {
 	broadcasterQueuedMessages            []arbostypes.MessageWithMetadataAndBlockHash
 	broadcasterQueuedMessagesPos         atomic.Uint64
 	broadcasterQueuedMessagesActiveReorg bool
+	broadcasterQueuedSince               time.Time // when the oldest queued feed message was queued
+
+	// if set, message writes are synced to disk on commit
+	syncer keyValueSyncer
+
+	// the override execution is halted at, if any
+	haltedAt atomic.Pointer[MessageQuarantine]
 
 	coordinator     *SeqCoordinator
 	broadcastServer *broadcaster.Broadcaster
 	inboxReader     *InboxReader
 	delayedBridge   *DelayedBridge
+
+	messageEventMutex       sync.Mutex
+	messageEventSubscribers map[*messageEventSubscription]struct{}
 }
 
 type TransactionStreamerConfig struct {
 	MaxBroadcasterQueueSize int           `koanf:"max-broadcaster-queue-size"`
 	MaxReorgResequenceDepth int64         `koanf:"max-reorg-resequence-depth" reload:"hot"`
 	ExecuteMessageLoopDelay time.Duration `koanf:"execute-message-loop-delay" reload:"hot"`
+	HaltOnBlockHashMismatch bool          `koanf:"halt-on-block-hash-mismatch" reload:"hot"`
+	QuarantineOnPanic       bool          `koanf:"quarantine-on-panic" reload:"hot"`
+	// Feed messages are queued and committed together once WriteBatchMessages are queued or the oldest has
+	// waited WriteBatchInterval. Queued messages aren't executed, rebroadcast or durable until committed, and
+	// after a crash are read again from the feed or parent chain, as when the feed runs ahead of the database.
+	WriteBatchMessages int           `koanf:"write-batch-messages" reload:"hot"`
+	WriteBatchInterval time.Duration `koanf:"write-batch-interval" reload:"hot"`
+	// Messages and the message count are always committed atomically, so a crash never leaves the count past
+	// the stored messages. With "commit" each commit is also synced to disk, so committed messages survive power
+	// loss too; with "none" durability is left to the database's write-ahead log.
+	Fsync string `koanf:"fsync"`
+}
+
+const (
+	StreamerFsyncNone   = "none"
+	StreamerFsyncCommit = "commit"
+)
+
+func (c *TransactionStreamerConfig) Validate() error {
+	if c.Fsync != StreamerFsyncNone && c.Fsync != StreamerFsyncCommit {
+		return fmt.Errorf("invalid transaction-streamer.fsync %q (expected \"%v\" or \"%v\")", c.Fsync, StreamerFsyncNone, StreamerFsyncCommit)
+	}
+	if c.WriteBatchMessages > 1 && c.WriteBatchInterval <= 0 {
+		return errors.New("transaction-streamer.write-batch-interval must be set to batch writes, so queued messages are eventually committed")
+	}
+	return nil
+}
+
+// keyValueSyncer is implemented by databases which can sync their writes to disk on demand.
+type keyValueSyncer interface {
+	SyncKeyValue() error
 }
 
 type TransactionStreamerConfigFetcher func() *TransactionStreamerConfig
@@ -82,18 +126,33 @@ var DefaultTransactionStreamerConfig = TransactionStreamerConfig{
 	MaxBroadcasterQueueSize: 50_000,
 	MaxReorgResequenceDepth: 1024,
 	ExecuteMessageLoopDelay: time.Millisecond * 100,
+	HaltOnBlockHashMismatch: false,
+	QuarantineOnPanic:       true,
+	WriteBatchMessages:      1,
+	WriteBatchInterval:      0,
+	Fsync:                   StreamerFsyncNone,
 }
 
 var TestTransactionStreamerConfig = TransactionStreamerConfig{
 	MaxBroadcasterQueueSize: 10_000,
 	MaxReorgResequenceDepth: 128 * 1024,
 	ExecuteMessageLoopDelay: time.Millisecond,
+	HaltOnBlockHashMismatch: false,
+	QuarantineOnPanic:       true,
+	WriteBatchMessages:      1,
+	WriteBatchInterval:      0,
+	Fsync:                   StreamerFsyncNone,
 }
 
 func TransactionStreamerConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Int(prefix+".max-broadcaster-queue-size", DefaultTransactionStreamerConfig.MaxBroadcasterQueueSize, "maximum cache of pending broadcaster messages")
 	f.Int64(prefix+".max-reorg-resequence-depth", DefaultTransactionStreamerConfig.MaxReorgResequenceDepth, "maximum number of messages to attempt to resequence on reorg (0 = never resequence, -1 = always resequence)")
 	f.Duration(prefix+".execute-message-loop-delay", DefaultTransactionStreamerConfig.ExecuteMessageLoopDelay, "delay when polling calls to execute messages")
+	f.Bool(prefix+".halt-on-block-hash-mismatch", DefaultTransactionStreamerConfig.HaltOnBlockHashMismatch, "stop the node when a block hash from the feed doesn't match the locally computed one")
+	f.Bool(prefix+".quarantine-on-panic", DefaultTransactionStreamerConfig.QuarantineOnPanic, "if executing a message panics, record a halt override for it before crashing, so the node halts cleanly at that message after restarting instead of crash-looping")
+	f.Int(prefix+".write-batch-messages", DefaultTransactionStreamerConfig.WriteBatchMessages, "commit feed messages to the database in batches of up to this many (1 commits each as it arrives)")
+	f.Duration(prefix+".write-batch-interval", DefaultTransactionStreamerConfig.WriteBatchInterval, "the longest a feed message waits for its batch to fill before being committed")
+	f.String(prefix+".fsync", DefaultTransactionStreamerConfig.Fsync, "when to sync message writes to disk: \"none\" to leave it to the database, or \"commit\" on every commit")
 }
 
 func NewTransactionStreamer(
@@ -115,6 +174,13 @@ func NewTransactionStreamer(
 		config:             config,
 		snapSyncConfig:     snapSyncConfig,
 	}
+	if config().Fsync == StreamerFsyncCommit {
+		syncer, ok := db.(keyValueSyncer)
+		if !ok {
+			return nil, errors.New("transaction-streamer.fsync is \"commit\" but the database can't sync on demand")
+		}
+		streamer.syncer = syncer
+	}
 	err := streamer.cleanupInconsistentState()
 	if err != nil {
 		return nil, err
@@ -200,7 +266,11 @@ func (s *TransactionStreamer) ReorgTo(count arbutil.MessageIndex) error {
 func (s *TransactionStreamer) ReorgToAndEndBatch(batch ethdb.Batch, count arbutil.MessageIndex) error {
 	s.insertionMutex.Lock()
 	defer s.insertionMutex.Unlock()
-	err := s.reorg(batch, count, nil)
+	prevCount, err := s.GetMessageCount()
+	if err != nil {
+		return err
+	}
+	err = s.reorg(batch, count, nil)
 	if err != nil {
 		return err
 	}
@@ -208,6 +278,7 @@ func (s *TransactionStreamer) ReorgToAndEndBatch(batch ethdb.Batch, count arbuti
 	if err != nil {
 		return err
 	}
+	s.sendReorgEvent(count, prevCount)
 	return nil
 }
 
@@ -413,10 +484,36 @@ func setMessageCount(batch ethdb.KeyValueWriter, count arbutil.MessageIndex) err
 }
 
 func dbKey(prefix []byte, pos uint64) []byte {
-	var key []byte
+	key := make([]byte, 0, len(prefix)+8)
 	key = append(key, prefix...)
-	key = append(key, uint64ToKey(pos)...)
-	return key
+	return binary.BigEndian.AppendUint64(key, pos)
+}
+
+// Buffers larger than this aren't returned to the pool, so a rare huge message doesn't pin its memory.
+const maxPooledEncodeBufferSize = 1 << 20
+
+var encodeBufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// withRLPEncoding encodes val into a pooled buffer and passes the encoding to use.
+// The encoding is only valid during the call, so use must not retain it; this is
+// safe for ethdb batch and database writes, which copy the value they are given.
+func withRLPEncoding(val interface{}, use func(encoded []byte) error) error {
+	buf, ok := encodeBufferPool.Get().(*bytes.Buffer)
+	if !ok {
+		buf = new(bytes.Buffer)
+	}
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= maxPooledEncodeBufferSize {
+			encodeBufferPool.Put(buf)
+		}
+	}()
+	if err := rlp.Encode(buf, val); err != nil {
+		return err
+	}
+	return use(buf.Bytes())
 }
 
 // Note: if changed to acquire the mutex, some internal users may need to be updated to a non-locking version.
@@ -477,6 +574,22 @@ func (s *TransactionStreamer) getMessageWithMetadataAndBlockHash(seqNum arbutil.
 	return &msgWithBlockHash, nil
 }
 
+// FeedMessage returns the message at seqNum as the feed broadcasts it, signed if the node runs a feed.
+func (s *TransactionStreamer) FeedMessage(seqNum arbutil.MessageIndex) (*m.BroadcastFeedMessage, error) {
+	msg, err := s.getMessageWithMetadataAndBlockHash(seqNum)
+	if err != nil {
+		return nil, err
+	}
+	if s.broadcastServer != nil {
+		return s.broadcastServer.NewBroadcastFeedMessage(msg.MessageWithMeta, seqNum, msg.BlockHash)
+	}
+	return &m.BroadcastFeedMessage{
+		SequenceNumber: seqNum,
+		Message:        msg.MessageWithMeta,
+		BlockHash:      msg.BlockHash,
+	}, nil
+}
+
 // Note: if changed to acquire the mutex, some internal users may need to be updated to a non-locking version.
 func (s *TransactionStreamer) GetMessageCount() (arbutil.MessageIndex, error) {
 	posBytes, err := s.db.Get(messageCountKey)
@@ -530,7 +643,8 @@ func (s *TransactionStreamer) AddBroadcastMessages(feedMessages []*m.BroadcastFe
 		return nil
 	}
 	broadcastStartPos := feedMessages[0].SequenceNumber
-	var messages []arbostypes.MessageWithMetadataAndBlockHash
+	// the message payloads are shared with the feed messages, not copied
+	messages := make([]arbostypes.MessageWithMetadataAndBlockHash, 0, len(feedMessages))
 	broadcastAfterPos := broadcastStartPos
 	for _, feedMessage := range feedMessages {
 		if broadcastAfterPos != feedMessage.SequenceNumber {
@@ -559,6 +673,7 @@ func (s *TransactionStreamer) AddBroadcastMessages(feedMessages []*m.BroadcastFe
 	if err != nil {
 		return err
 	}
+	s.checkDuplicateBlockHashes(broadcastStartPos, messages[:dups])
 	messages = messages[dups:]
 	broadcastStartPos += arbutil.MessageIndex(dups)
 	if oldMsg != nil {
@@ -574,6 +689,7 @@ func (s *TransactionStreamer) AddBroadcastMessages(feedMessages []*m.BroadcastFe
 		s.broadcasterQueuedMessages = messages
 		s.broadcasterQueuedMessagesPos.Store(uint64(broadcastStartPos))
 		s.broadcasterQueuedMessagesActiveReorg = feedReorg
+		s.broadcasterQueuedSince = time.Now()
 	} else {
 		broadcasterQueuedMessagesPos := arbutil.MessageIndex(s.broadcasterQueuedMessagesPos.Load())
 		if broadcasterQueuedMessagesPos >= broadcastStartPos {
@@ -581,6 +697,7 @@ func (s *TransactionStreamer) AddBroadcastMessages(feedMessages []*m.BroadcastFe
 			s.broadcasterQueuedMessages = messages
 			s.broadcasterQueuedMessagesPos.Store(uint64(broadcastStartPos))
 			s.broadcasterQueuedMessagesActiveReorg = feedReorg
+			s.broadcasterQueuedSince = time.Now()
 		} else if broadcasterQueuedMessagesPos+arbutil.MessageIndex(len(s.broadcasterQueuedMessages)) == broadcastStartPos {
 			// Feed messages can be added directly to end of cache
 			maxQueueSize := s.config().MaxBroadcasterQueueSize
@@ -601,14 +718,36 @@ func (s *TransactionStreamer) AddBroadcastMessages(feedMessages []*m.BroadcastFe
 			s.broadcasterQueuedMessages = messages
 			s.broadcasterQueuedMessagesPos.Store(uint64(broadcastStartPos))
 			s.broadcasterQueuedMessagesActiveReorg = feedReorg
+			s.broadcasterQueuedSince = time.Now()
 		}
 	}
 
+	if !s.queuedFeedWriteDue() {
+		// committed along with the messages still to come, or by writeQueuedFeedMessages
+		return nil
+	}
+	return s.addQueuedBroadcastMessages()
+}
+
+// queuedFeedWriteDue returns whether enough feed messages are queued, or have been for long enough,
+// to commit them. The insertionMutex must be held.
+func (s *TransactionStreamer) queuedFeedWriteDue() bool {
+	config := s.config()
+	if config.WriteBatchMessages <= 1 {
+		return true
+	}
+	return len(s.broadcasterQueuedMessages) >= config.WriteBatchMessages || time.Since(s.broadcasterQueuedSince) >= config.WriteBatchInterval
+}
+
+// addQueuedBroadcastMessages adds the queued feed messages to the database if they follow on from it.
+// The insertionMutex must be held.
+func (s *TransactionStreamer) addQueuedBroadcastMessages() error {
 	if s.broadcasterQueuedMessagesActiveReorg || len(s.broadcasterQueuedMessages) == 0 {
 		// Broadcaster never triggered reorg or no messages to add
 		return nil
 	}
 
+	broadcastStartPos := arbutil.MessageIndex(s.broadcasterQueuedMessagesPos.Load())
 	if broadcastStartPos > 0 {
 		_, err := s.GetMessage(broadcastStartPos - 1)
 		if err != nil {
@@ -620,7 +759,7 @@ func (s *TransactionStreamer) AddBroadcastMessages(feedMessages []*m.BroadcastFe
 		}
 	}
 
-	err = s.addMessagesAndEndBatchImpl(broadcastStartPos, false, nil, nil)
+	err := s.addMessagesAndEndBatchImpl(broadcastStartPos, false, nil, nil)
 	if err != nil {
 		return fmt.Errorf("error adding pending broadcaster messages: %w", err)
 	}
@@ -628,6 +767,23 @@ func (s *TransactionStreamer) AddBroadcastMessages(feedMessages []*m.BroadcastFe
 	return nil
 }
 
+// writeQueuedFeedMessages commits batched feed messages which have waited the write batch interval.
+func (s *TransactionStreamer) writeQueuedFeedMessages(ctx context.Context) time.Duration {
+	interval := s.config().WriteBatchInterval
+	if s.config().WriteBatchMessages <= 1 || interval <= 0 {
+		// not batching, but that's hot reloadable
+		return time.Second
+	}
+	s.insertionMutex.Lock()
+	defer s.insertionMutex.Unlock()
+	if len(s.broadcasterQueuedMessages) > 0 && s.queuedFeedWriteDue() {
+		if err := s.addQueuedBroadcastMessages(); err != nil {
+			log.Error("failed to commit queued feed messages", "err", err)
+		}
+	}
+	return interval / 2
+}
+
 // AddFakeInitMessage should only be used for testing or running a local dev node
 func (s *TransactionStreamer) AddFakeInitMessage() error {
 	chainConfigJson, err := json.Marshal(s.chainConfig)
@@ -722,23 +878,23 @@ func (s *TransactionStreamer) countDuplicateMessages(
 			break
 		}
 		key := dbKey(messagePrefix, uint64(pos))
-		hasMessage, err := s.db.Has(key)
-		if err != nil {
-			return 0, false, nil, err
-		}
-		if !hasMessage {
+		haveMessage, err := s.db.Get(key)
+		if dbutil.IsErrNotFound(err) {
 			break
 		}
-		haveMessage, err := s.db.Get(key)
 		if err != nil {
 			return 0, false, nil, err
 		}
 		nextMessage := messages[curMsg]
-		wantMessage, err := rlp.EncodeToBytes(nextMessage.MessageWithMeta)
+		var matches bool
+		err = withRLPEncoding(nextMessage.MessageWithMeta, func(wantMessage []byte) error {
+			matches = bytes.Equal(haveMessage, wantMessage)
+			return nil
+		})
 		if err != nil {
 			return 0, false, nil, err
 		}
-		if !bytes.Equal(haveMessage, wantMessage) {
+		if !matches {
 			// Current message does not exactly match message in database
 			var dbMessageParsed arbostypes.MessageWithMetadata
 
@@ -900,8 +1056,12 @@ func (s *TransactionStreamer) addMessagesAndEndBatchImpl(messageStartPos arbutil
 	}
 
 	if confirmedReorg {
+		prevCount, err := s.GetMessageCount()
+		if err != nil {
+			return err
+		}
 		reorgBatch := s.db.NewBatch()
-		err := s.reorg(reorgBatch, messageStartPos, messages)
+		err = s.reorg(reorgBatch, messageStartPos, messages)
 		if err != nil {
 			return err
 		}
@@ -909,6 +1069,7 @@ func (s *TransactionStreamer) addMessagesAndEndBatchImpl(messageStartPos arbutil
 		if err != nil {
 			return err
 		}
+		s.sendReorgEvent(messageStartPos, prevCount)
 	}
 	if len(messages) == 0 {
 		return endBatch(batch)
@@ -949,8 +1110,12 @@ func (s *TransactionStreamer) WriteMessageFromSequencer(
 	msgWithMeta arbostypes.MessageWithMetadata,
 	msgResult execution.MessageResult,
 ) error {
-	if err := s.ExpectChosenSequencer(); err != nil {
-		return err
+	// the delayed sequencer may be configured to sequence delayed messages without the lockout
+	delayedIgnoresLockout := s.coordinator != nil && s.coordinator.delayedIgnoresLockout()
+	if !delayedIgnoresLockout {
+		if err := s.ExpectChosenSequencer(); err != nil {
+			return err
+		}
 	}
 	if !s.insertionMutex.TryLock() {
 		return execution.ErrSequencerInsertLockTaken
@@ -966,8 +1131,20 @@ func (s *TransactionStreamer) WriteMessageFromSequencer(
 		return fmt.Errorf("wrong pos got %d expected %d", pos, msgCount)
 	}
 
+	published := true
 	if s.coordinator != nil {
-		if err := s.coordinator.SequencingMessage(pos, &msgWithMeta); err != nil {
+		sequencingMessage := s.coordinator.SequencingMessage
+		if delayedIgnoresLockout {
+			delayed, err := s.readsDelayedMessage(pos, &msgWithMeta)
+			if err != nil {
+				return err
+			}
+			if delayed {
+				sequencingMessage = s.coordinator.SequencingDelayedMessage
+			}
+		}
+		published, err = sequencingMessage(pos, &msgWithMeta)
+		if err != nil {
 			return err
 		}
 	}
@@ -980,11 +1157,42 @@ func (s *TransactionStreamer) WriteMessageFromSequencer(
 	if err := s.writeMessages(pos, []arbostypes.MessageWithMetadataAndBlockHash{msgWithBlockHash}, nil); err != nil {
 		return err
 	}
-	s.broadcastMessages([]arbostypes.MessageWithMetadataAndBlockHash{msgWithBlockHash}, pos)
+	// a message the coordinator couldn't publish may yet be sequenced over by another sequencer,
+	// so it's only broadcast once published
+	if published {
+		s.broadcastMessages([]arbostypes.MessageWithMetadataAndBlockHash{msgWithBlockHash}, pos)
+	}
 
 	return nil
 }
 
+// readsDelayedMessage returns whether msg, to be written at pos, is a delayed message, reading one more
+// delayed message than the message before it.
+func (s *TransactionStreamer) readsDelayedMessage(pos arbutil.MessageIndex, msg *arbostypes.MessageWithMetadata) (bool, error) {
+	var prevDelayedRead uint64
+	if pos > 0 {
+		prev, err := s.GetMessage(pos - 1)
+		if err != nil {
+			return false, err
+		}
+		prevDelayedRead = prev.DelayedMessagesRead
+	}
+	return msg.DelayedMessagesRead > prevDelayedRead, nil
+}
+
+// broadcastPublishedMessage broadcasts the message at pos, held back until the coordinator published it.
+func (s *TransactionStreamer) broadcastPublishedMessage(pos arbutil.MessageIndex) error {
+	if s.broadcastServer == nil {
+		return nil
+	}
+	msg, err := s.getMessageWithMetadataAndBlockHash(pos)
+	if err != nil {
+		return err
+	}
+	s.broadcastMessages([]arbostypes.MessageWithMetadataAndBlockHash{*msg}, pos)
+	return nil
+}
+
 // PauseReorgs until a matching call to ResumeReorgs (may be called concurrently)
 func (s *TransactionStreamer) PauseReorgs() {
 	s.reorgMutex.RLock()
@@ -1004,24 +1212,21 @@ func (s *TransactionStreamer) PopulateFeedBacklog() error {
 func (s *TransactionStreamer) writeMessage(pos arbutil.MessageIndex, msg arbostypes.MessageWithMetadataAndBlockHash, batch ethdb.Batch) error {
 	// write message with metadata
 	key := dbKey(messagePrefix, uint64(pos))
-	msgBytes, err := rlp.EncodeToBytes(msg.MessageWithMeta)
+	err := withRLPEncoding(msg.MessageWithMeta, func(msgBytes []byte) error {
+		return batch.Put(key, msgBytes)
+	})
 	if err != nil {
 		return err
 	}
-	if err := batch.Put(key, msgBytes); err != nil {
-		return err
-	}
 
 	// write block hash
 	blockHashDBVal := blockHashDBValue{
 		BlockHash: msg.BlockHash,
 	}
 	key = dbKey(blockHashInputFeedPrefix, uint64(pos))
-	msgBytes, err = rlp.EncodeToBytes(blockHashDBVal)
-	if err != nil {
-		return err
-	}
-	return batch.Put(key, msgBytes)
+	return withRLPEncoding(blockHashDBVal, func(encoded []byte) error {
+		return batch.Put(key, encoded)
+	})
 }
 
 func (s *TransactionStreamer) broadcastMessages(
@@ -1057,6 +1262,12 @@ func (s *TransactionStreamer) writeMessages(pos arbutil.MessageIndex, messages [
 	if err != nil {
 		return err
 	}
+	if s.syncer != nil {
+		if err := s.syncer.SyncKeyValue(); err != nil {
+			return fmt.Errorf("failed to sync message writes: %w", err)
+		}
+	}
+	s.sendSequencedEvents(pos, messages)
 
 	select {
 	case s.newMessageNotifier <- struct{}{}:
@@ -1105,31 +1316,58 @@ func (s *TransactionStreamer) ResultAtCount(count arbutil.MessageIndex) (*execut
 	return msgResult, nil
 }
 
-func (s *TransactionStreamer) checkResult(msgResult *execution.MessageResult, expectedBlockHash *common.Hash) {
+func (s *TransactionStreamer) checkResult(pos arbutil.MessageIndex, msgResult *execution.MessageResult, expectedBlockHash *common.Hash) {
 	if expectedBlockHash == nil {
 		return
 	}
 	if msgResult.BlockHash != *expectedBlockHash {
 		log.Error(
 			BlockHashMismatchLogMsg,
+			"pos", pos,
 			"expected", expectedBlockHash,
 			"actual", msgResult.BlockHash,
 		)
+		blockHashMismatchCounter.Inc(1)
+		if s.config().HaltOnBlockHashMismatch {
+			s.fatalErrChan <- fmt.Errorf("block hash mismatch at message %v: feed has %v but computed %v", pos, expectedBlockHash, msgResult.BlockHash)
+		}
 		return
 	}
 }
 
+// checkDuplicateBlockHashes compares the block hashes of feed messages we already have against the results we've
+// already computed for them, so divergence is noticed as soon as the feed catches up rather than when batches post.
+func (s *TransactionStreamer) checkDuplicateBlockHashes(pos arbutil.MessageIndex, messages []arbostypes.MessageWithMetadataAndBlockHash) {
+	for i, msg := range messages {
+		if msg.BlockHash == nil {
+			continue
+		}
+		msgPos := pos + arbutil.MessageIndex(i)
+		data, err := s.db.Get(dbKey(messageResultPrefix, uint64(msgPos)))
+		if err != nil {
+			if !dbutil.IsErrNotFound(err) {
+				log.Warn("failed to read message result to check feed block hash", "pos", msgPos, "err", err)
+			}
+			continue
+		}
+		var msgResult execution.MessageResult
+		if err := rlp.DecodeBytes(data, &msgResult); err != nil {
+			log.Warn("failed to decode message result to check feed block hash", "pos", msgPos, "err", err)
+			continue
+		}
+		s.checkResult(msgPos, &msgResult, msg.BlockHash)
+	}
+}
+
 func (s *TransactionStreamer) storeResult(
 	pos arbutil.MessageIndex,
 	msgResult execution.MessageResult,
 	batch ethdb.Batch,
 ) error {
-	msgResultBytes, err := rlp.EncodeToBytes(msgResult)
-	if err != nil {
-		return err
-	}
 	key := dbKey(messageResultPrefix, uint64(pos))
-	return batch.Put(key, msgResultBytes)
+	return withRLPEncoding(msgResult, func(encoded []byte) error {
+		return batch.Put(key, encoded)
+	})
 }
 
 // exposed for testing
@@ -1172,7 +1410,15 @@ func (s *TransactionStreamer) ExecuteNextMsg(ctx context.Context, exec execution
 		}
 		msgForPrefetch = msg
 	}
-	msgResult, err := s.exec.DigestMessage(pos, &msgAndBlockHash.MessageWithMeta, msgForPrefetch)
+	msgToExecute, err := s.applyQuarantine(pos, &msgAndBlockHash.MessageWithMeta)
+	if err != nil {
+		log.Error("feedOneMsg failed to read message quarantine", "err", err, "pos", pos)
+		return false
+	}
+	if msgToExecute == nil {
+		return false
+	}
+	msgResult, err := s.digestMessage(pos, msgToExecute, msgForPrefetch)
 	if err != nil {
 		logger := log.Warn
 		if prevMessageCount < msgCount {
@@ -1182,7 +1428,7 @@ func (s *TransactionStreamer) ExecuteNextMsg(ctx context.Context, exec execution
 		return false
 	}
 
-	s.checkResult(msgResult, msgAndBlockHash.BlockHash)
+	s.checkResult(pos, msgResult, msgAndBlockHash.BlockHash)
 
 	batch := s.db.NewBatch()
 	err = s.storeResult(pos, *msgResult, batch)
@@ -1205,6 +1451,11 @@ func (s *TransactionStreamer) ExecuteNextMsg(ctx context.Context, exec execution
 	return pos+1 < msgCount
 }
 
+func (s *TransactionStreamer) digestMessage(pos arbutil.MessageIndex, msg *arbostypes.MessageWithMetadata, msgForPrefetch *arbostypes.MessageWithMetadata) (*execution.MessageResult, error) {
+	defer s.quarantineOnPanic(pos)
+	return s.exec.DigestMessage(pos, msg, msgForPrefetch)
+}
+
 func (s *TransactionStreamer) executeMessages(ctx context.Context, ignored struct{}) time.Duration {
 	if s.ExecuteNextMsg(ctx, s.exec) {
 		return 0
@@ -1214,5 +1465,8 @@ func (s *TransactionStreamer) executeMessages(ctx context.Context, ignored struc
 
 func (s *TransactionStreamer) Start(ctxIn context.Context) error {
 	s.StopWaiter.Start(ctxIn, s)
+	if err := s.CallIterativelySafe(s.writeQueuedFeedMessages); err != nil {
+		return err
+	}
 	return stopwaiter.CallIterativelyWith[struct{}](&s.StopWaiterSafe, s.executeMessages, s.newMessageNotifier)
 }
@@ -0,0 +1,296 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbnode
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+	m "github.com/offchainlabs/nitro/broadcaster/message"
+	"github.com/offchainlabs/nitro/execution"
+)
+
+func makeStreamerTestMessages(count int, payloadSize int) []arbostypes.MessageWithMetadataAndBlockHash {
+	messages := make([]arbostypes.MessageWithMetadataAndBlockHash, 0, count)
+	for i := 0; i < count; i++ {
+		payload := make([]byte, payloadSize)
+		payload[0] = byte(i)
+		messages = append(messages, arbostypes.MessageWithMetadataAndBlockHash{
+			MessageWithMeta: arbostypes.MessageWithMetadata{
+				Message: &arbostypes.L1IncomingMessage{
+					Header: &arbostypes.L1IncomingMessageHeader{
+						Kind:        arbostypes.L1MessageType_L2Message,
+						Poster:      common.Address{1},
+						BlockNumber: uint64(i),
+						Timestamp:   uint64(i),
+						RequestId:   nil,
+						L1BaseFee:   big.NewInt(0),
+					},
+					L2msg: payload,
+				},
+				DelayedMessagesRead: 1,
+			},
+			BlockHash: &common.Hash{byte(i)},
+		})
+	}
+	return messages
+}
+
+func TestStreamerWriteMessagesRoundTrip(t *testing.T) {
+	streamer := &TransactionStreamer{
+		db:                 rawdb.NewMemoryDatabase(),
+		newMessageNotifier: make(chan struct{}, 1),
+	}
+	messages := makeStreamerTestMessages(20, 1000)
+	Require(t, streamer.writeMessages(0, messages, nil))
+	for i, want := range messages {
+		got, err := streamer.getMessageWithMetadataAndBlockHash(arbutil.MessageIndex(i))
+		Require(t, err)
+		if got.MessageWithMeta.Message.L2msg[0] != byte(i) || *got.BlockHash != *want.BlockHash {
+			Fail(t, "unexpected message read back at", i)
+		}
+	}
+	dups, reorg, _, err := streamer.countDuplicateMessages(0, messages, nil)
+	Require(t, err)
+	if dups != len(messages) || reorg {
+		Fail(t, "expected all messages to be duplicates, got", dups, "reorg", reorg)
+	}
+	dups, reorg, _, err = streamer.countDuplicateMessages(0, makeStreamerTestMessages(25, 999), nil)
+	Require(t, err)
+	if dups != 0 || !reorg {
+		Fail(t, "expected reorg at first message, got", dups, "reorg", reorg)
+	}
+}
+
+func TestFeedBlockHashCheckedForKnownMessages(t *testing.T) {
+	fatalErrChan := make(chan error, 10)
+	config := TestTransactionStreamerConfig
+	config.HaltOnBlockHashMismatch = true
+	streamer := &TransactionStreamer{
+		db:                 rawdb.NewMemoryDatabase(),
+		newMessageNotifier: make(chan struct{}, 1),
+		fatalErrChan:       fatalErrChan,
+		config:             func() *TransactionStreamerConfig { return &config },
+	}
+	messages := makeStreamerTestMessages(5, 10)
+	Require(t, streamer.writeMessages(0, messages, nil))
+	batch := streamer.db.NewBatch()
+	for i, msg := range messages {
+		result := execution.MessageResult{BlockHash: *msg.BlockHash}
+		if i == 3 {
+			result.BlockHash = common.Hash{0xff}
+		}
+		Require(t, streamer.storeResult(arbutil.MessageIndex(i), result, batch))
+	}
+	Require(t, batch.Write())
+
+	feedMessages := make([]*m.BroadcastFeedMessage, 0, len(messages))
+	for i, msg := range messages {
+		feedMessages = append(feedMessages, &m.BroadcastFeedMessage{
+			SequenceNumber: arbutil.MessageIndex(i),
+			Message:        msg.MessageWithMeta,
+			BlockHash:      msg.BlockHash,
+		})
+	}
+	Require(t, streamer.AddBroadcastMessages(feedMessages))
+	if len(fatalErrChan) != 1 {
+		Fail(t, "expected exactly one block hash mismatch, got", len(fatalErrChan))
+	}
+	if err := <-fatalErrChan; !strings.Contains(err.Error(), "message 3") {
+		Fail(t, "unexpected mismatch error", err)
+	}
+}
+
+func TestStreamerFeedWriteBatching(t *testing.T) {
+	config := TestTransactionStreamerConfig
+	config.WriteBatchMessages = 3
+	config.WriteBatchInterval = time.Hour
+	db := rawdb.NewMemoryDatabase()
+	newStreamer := func() *TransactionStreamer {
+		return &TransactionStreamer{
+			db:                 db,
+			newMessageNotifier: make(chan struct{}, 1),
+			config:             func() *TransactionStreamerConfig { return &config },
+			snapSyncConfig:     &DefaultSnapSyncConfig,
+		}
+	}
+	streamer := newStreamer()
+	messages := makeStreamerTestMessages(6, 10)
+	Require(t, streamer.writeMessages(0, messages[:1], nil))
+	addFeedMessage := func(pos int) {
+		t.Helper()
+		Require(t, streamer.AddBroadcastMessages([]*m.BroadcastFeedMessage{{
+			SequenceNumber: arbutil.MessageIndex(pos),
+			Message:        messages[pos].MessageWithMeta,
+			BlockHash:      messages[pos].BlockHash,
+		}}))
+	}
+	checkCount := func(expected arbutil.MessageIndex) {
+		t.Helper()
+		count, err := streamer.GetMessageCount()
+		Require(t, err)
+		if count != expected {
+			Fail(t, "expected message count", expected, "got", count)
+		}
+	}
+
+	addFeedMessage(1)
+	addFeedMessage(2)
+	checkCount(1)
+	addFeedMessage(3)
+	checkCount(4)
+
+	// a partial batch is committed once it's waited the write batch interval
+	addFeedMessage(4)
+	checkCount(4)
+	config.WriteBatchInterval = time.Millisecond
+	time.Sleep(2 * time.Millisecond)
+	streamer.writeQueuedFeedMessages(context.Background())
+	checkCount(5)
+
+	// crashing with messages queued loses only those, leaving every counted message readable
+	config.WriteBatchInterval = time.Hour
+	addFeedMessage(5)
+	streamer = newStreamer()
+	checkCount(5)
+	for i := 0; i < 5; i++ {
+		msg, err := streamer.GetMessage(arbutil.MessageIndex(i))
+		Require(t, err)
+		if msg.Message.L2msg[0] != byte(i) {
+			Fail(t, "unexpected message at", i)
+		}
+	}
+}
+
+type syncCountingDB struct {
+	ethdb.Database
+	syncs int
+}
+
+func (db *syncCountingDB) SyncKeyValue() error {
+	db.syncs++
+	return nil
+}
+
+func TestStreamerFsyncCommit(t *testing.T) {
+	config := TestTransactionStreamerConfig
+	config.Fsync = StreamerFsyncCommit
+	fetcher := func() *TransactionStreamerConfig { return &config }
+	if _, err := NewTransactionStreamer(rawdb.NewMemoryDatabase(), nil, nil, nil, nil, fetcher, &DefaultSnapSyncConfig); err == nil {
+		Fail(t, "fsync on commit accepted for a database that can't sync")
+	}
+	db := &syncCountingDB{Database: rawdb.NewMemoryDatabase()}
+	streamer, err := NewTransactionStreamer(db, nil, nil, nil, nil, fetcher, &DefaultSnapSyncConfig)
+	Require(t, err)
+	Require(t, streamer.writeMessages(0, makeStreamerTestMessages(2, 10), nil))
+	if db.syncs != 1 {
+		Fail(t, "expected one sync per commit, got", db.syncs)
+	}
+}
+
+func BenchmarkStreamerWriteMessages(b *testing.B) {
+	messages := makeStreamerTestMessages(100, 4096)
+	streamer := &TransactionStreamer{
+		db:                 rawdb.NewMemoryDatabase(),
+		newMessageNotifier: make(chan struct{}, 1),
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := streamer.writeMessages(0, messages, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStreamerCountDuplicateMessages(b *testing.B) {
+	messages := makeStreamerTestMessages(100, 4096)
+	streamer := &TransactionStreamer{
+		db:                 rawdb.NewMemoryDatabase(),
+		newMessageNotifier: make(chan struct{}, 1),
+	}
+	if err := streamer.writeMessages(0, messages, nil); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, err := streamer.countDuplicateMessages(0, messages, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestStreamerMessageEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	exec, streamer, _, _ := NewTransactionStreamerForTest(t, common.Address{})
+	Require(t, streamer.Start(ctx))
+	exec.Start(ctx)
+	events := make(chan MessageEvent, 16)
+	sub := streamer.SubscribeMessageEvents(events)
+	defer sub.Unsubscribe()
+
+	messages := archiveTestMessages(3)
+	Require(t, streamer.AddMessages(1, false, messages))
+	for i := range messages {
+		ev := <-events
+		pos := arbutil.MessageIndex(1 + i)
+		digest, err := messages[i].Hash(pos, streamer.ChainConfig().ChainID.Uint64())
+		Require(t, err)
+		if ev.Kind != MessageEventSequenced || ev.Index != pos || ev.Digest != digest {
+			Fail(t, "unexpected event for message", pos, ev)
+		}
+	}
+
+	// messages are only reorged once executed
+	for {
+		processed, err := streamer.GetProcessedMessageCount()
+		Require(t, err)
+		if processed == 4 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	Require(t, streamer.ReorgTo(2))
+	ev := <-events
+	if ev.Kind != MessageEventReorged || ev.Index != 2 || ev.PrevMessageCount != 4 {
+		Fail(t, "unexpected reorg event", ev)
+	}
+	select {
+	case ev := <-events:
+		Fail(t, "unexpected event after the reorg", ev)
+	default:
+	}
+	// a subscriber that falls behind is dropped rather than blocking the streamer
+	slow := make(chan MessageEvent, 1)
+	slowSub := streamer.SubscribeMessageEvents(slow)
+	defer slowSub.Unsubscribe()
+	Require(t, streamer.AddMessages(2, false, messages[:2]))
+	for pos := arbutil.MessageIndex(2); pos < 4; pos++ {
+		if ev := <-events; ev.Kind != MessageEventSequenced || ev.Index != pos {
+			Fail(t, "unexpected event for message", pos, ev)
+		}
+	}
+	if ev := <-slow; ev.Index != 2 {
+		Fail(t, "unexpected event for the slow subscriber", ev)
+	}
+	var dropped *MessageEventsDroppedError
+	if err := <-slowSub.Err(); !errors.As(err, &dropped) || dropped.From != 3 {
+		Fail(t, "slow subscriber not dropped at the event it missed, err:", err)
+	}
+	if !streamer.hasMessageEventSubscribers() {
+		Fail(t, "subscriber that kept up was dropped")
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbos
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/offchainlabs/nitro/arbos/arbosState"
+	"github.com/offchainlabs/nitro/util/testhelpers"
+)
+
+func TestAllowlists(t *testing.T) {
+	state, statedb := arbosState.NewArbosMemoryBackedArbOSState()
+	Require(t, state.UpgradeArbosVersion(32, false, statedb, params.ArbitrumDevTestChainConfig()))
+
+	owner := testhelpers.RandomAddress()
+	sender := testhelpers.RandomAddress()
+	deployer := testhelpers.RandomAddress()
+	stranger := testhelpers.RandomAddress()
+	to := testhelpers.RandomAddress()
+	Require(t, state.ChainOwners().Add(owner))
+	Require(t, state.AllowedSenders().Add(sender))
+	Require(t, state.AllowedSenders().Add(deployer))
+	Require(t, state.AllowedDeployers().Add(deployer))
+
+	check := func(from common.Address, to *common.Address, expected error) {
+		t.Helper()
+		if err := checkAllowlists(state, from, to); !errors.Is(err, expected) {
+			Fail(t, "unexpected allowlist result for", from, "got", err, "expected", expected)
+		}
+	}
+
+	// nothing is enforced by default
+	check(stranger, &to, nil)
+	check(stranger, nil, nil)
+
+	Require(t, state.SetAllowlistMode(arbosState.AllowlistSenders))
+	check(stranger, &to, ErrSenderNotAllowed)
+	check(sender, &to, nil)
+	check(sender, nil, nil)
+	check(owner, &to, nil)
+
+	Require(t, state.SetAllowlistMode(arbosState.AllowlistSenders|arbosState.AllowlistDeployers))
+	check(sender, nil, ErrDeployerNotAllowed)
+	check(deployer, nil, nil)
+	check(owner, nil, nil)
+
+	Require(t, state.SetAllowlistMode(arbosState.AllowlistDeployers))
+	check(stranger, &to, nil)
+	check(stranger, nil, ErrDeployerNotAllowed)
+
+	if err := state.SetAllowlistMode(4); err == nil {
+		Fail(t, "invalid allowlist mode accepted")
+	}
+}
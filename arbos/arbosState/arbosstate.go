@@ -25,6 +25,8 @@ import (
 	"github.com/offchainlabs/nitro/arbos/arbostypes"
 	"github.com/offchainlabs/nitro/arbos/blockhash"
 	"github.com/offchainlabs/nitro/arbos/burn"
+	"github.com/offchainlabs/nitro/arbos/disabledmethods"
+	"github.com/offchainlabs/nitro/arbos/guardrails"
 	"github.com/offchainlabs/nitro/arbos/l1pricing"
 	"github.com/offchainlabs/nitro/arbos/l2pricing"
 	"github.com/offchainlabs/nitro/arbos/merkleAccumulator"
@@ -32,6 +34,7 @@ import (
 	"github.com/offchainlabs/nitro/arbos/retryables"
 	"github.com/offchainlabs/nitro/arbos/storage"
 	"github.com/offchainlabs/nitro/arbos/util"
+	"github.com/offchainlabs/nitro/util/arbmath"
 	"github.com/offchainlabs/nitro/util/testhelpers/env"
 )
 
@@ -60,6 +63,21 @@ type ArbosState struct {
 	genesisBlockNum               storage.StorageBackedUint64
 	infraFeeAccount               storage.StorageBackedAddress
 	brotliCompressionLevel        storage.StorageBackedUint64 // brotli compression level used for pricing
+	delayedMessagesRead           storage.StorageBackedUint64 // delayed messages sequenced as of the current block
+	lastReportDelayedCount        storage.StorageBackedUint64 // delayed inbox size on L1 when the last sequenced batch posting report was made
+	lastReportTimestamp           storage.StorageBackedUint64 // L1 timestamp of the last sequenced batch posting report
+	emergencyPauseExpiry          storage.StorageBackedUint64 // timestamp until which user transactions are paused, or 0
+	allowlistMode                 storage.StorageBackedUint64 // which of the allowlists below are enforced
+	allowedSenders                *addressSet.AddressSet
+	allowedDeployers              *addressSet.AddressSet
+	gasFreeBudget                 storage.StorageBackedUint64 // per-block gas that designated (sender, target) pairs may use for free
+	gasFreeUsed                   storage.StorageBackedUint64 // gas-free gas used in gasFreeUsedBlock
+	gasFreeUsedBlock              storage.StorageBackedUint64
+	gasFreePairs                  *storage.Storage
+	feeTokenDecimals              storage.StorageBackedUint64  // decimals of the fee token, or 0 for ether's 18
+	feeTokenExchangeRate          storage.StorageBackedBigUint // the wei one whole fee token is worth, or 0 for one ether
+	parameterGuardrails           *guardrails.Guardrails
+	disabledPrecompileMethods     *disabledmethods.Set // precompile methods the chain owner disabled
 	backingStorage                *storage.Storage
 	Burner                        burn.Burner
 }
@@ -78,8 +96,8 @@ func OpenArbosState(stateDB vm.StateDB, burner burn.Burner) (*ArbosState, error)
 	}
 	return &ArbosState{
 		arbosVersion,
-		31,
-		31,
+		32,
+		32,
 		backingStorage.OpenStorageBackedUint64(uint64(upgradeVersionOffset)),
 		backingStorage.OpenStorageBackedUint64(uint64(upgradeTimestampOffset)),
 		backingStorage.OpenStorageBackedAddress(uint64(networkFeeAccountOffset)),
@@ -96,6 +114,21 @@ func OpenArbosState(stateDB vm.StateDB, burner burn.Burner) (*ArbosState, error)
 		backingStorage.OpenStorageBackedUint64(uint64(genesisBlockNumOffset)),
 		backingStorage.OpenStorageBackedAddress(uint64(infraFeeAccountOffset)),
 		backingStorage.OpenStorageBackedUint64(uint64(brotliCompressionLevelOffset)),
+		backingStorage.OpenStorageBackedUint64(uint64(delayedMessagesReadOffset)),
+		backingStorage.OpenStorageBackedUint64(uint64(lastReportDelayedCountOffset)),
+		backingStorage.OpenStorageBackedUint64(uint64(lastReportTimestampOffset)),
+		backingStorage.OpenStorageBackedUint64(uint64(emergencyPauseExpiryOffset)),
+		backingStorage.OpenStorageBackedUint64(uint64(allowlistModeOffset)),
+		addressSet.OpenAddressSet(backingStorage.OpenCachedSubStorage(allowedSendersSubspace)),
+		addressSet.OpenAddressSet(backingStorage.OpenCachedSubStorage(allowedDeployersSubspace)),
+		backingStorage.OpenStorageBackedUint64(uint64(gasFreeBudgetOffset)),
+		backingStorage.OpenStorageBackedUint64(uint64(gasFreeUsedOffset)),
+		backingStorage.OpenStorageBackedUint64(uint64(gasFreeUsedBlockOffset)),
+		backingStorage.OpenCachedSubStorage(gasFreePairsSubspace),
+		backingStorage.OpenStorageBackedUint64(uint64(feeTokenDecimalsOffset)),
+		backingStorage.OpenStorageBackedBigUint(uint64(feeTokenExchangeRateOffset)),
+		guardrails.Open(backingStorage.OpenCachedSubStorage(parameterGuardrailsSubspace)),
+		disabledmethods.Open(backingStorage.OpenCachedSubStorage(disabledPrecompileMethodsSubspace)),
 		backingStorage,
 		burner,
 	}, nil
@@ -147,6 +180,18 @@ func ArbOSVersion(stateDB vm.StateDB) uint64 {
 	return arbosVersion
 }
 
+// PrecompileMethodDisabled checks whether the chain owner disabled the precompile method. Like ArbOSVersion,
+// it doesn't charge gas, as the precompile dispatcher checks it on every call.
+func PrecompileMethodDisabled(stateDB vm.StateDB, precompile common.Address, selector [4]byte) (bool, error) {
+	backingStorage := storage.NewGeth(stateDB, burn.NewSystemBurner(nil, false))
+	disabled := disabledmethods.Open(backingStorage.OpenCachedSubStorage(disabledPrecompileMethodsSubspace))
+	total, err := disabled.Total()
+	if err != nil || total == 0 {
+		return false, err
+	}
+	return disabled.IsDisabled(precompile, selector)
+}
+
 type Offset uint64
 
 const (
@@ -158,20 +203,41 @@ const (
 	genesisBlockNumOffset
 	infraFeeAccountOffset
 	brotliCompressionLevelOffset
+	delayedMessagesReadOffset
+	lastReportDelayedCountOffset
+	lastReportTimestampOffset
+	emergencyPauseExpiryOffset
+	allowlistModeOffset
+	gasFreeBudgetOffset
+	gasFreeUsedOffset
+	gasFreeUsedBlockOffset
+	feeTokenDecimalsOffset
+	feeTokenExchangeRateOffset
 )
 
 type SubspaceID []byte
 
 var (
-	l1PricingSubspace    SubspaceID = []byte{0}
-	l2PricingSubspace    SubspaceID = []byte{1}
-	retryablesSubspace   SubspaceID = []byte{2}
-	addressTableSubspace SubspaceID = []byte{3}
-	chainOwnerSubspace   SubspaceID = []byte{4}
-	sendMerkleSubspace   SubspaceID = []byte{5}
-	blockhashesSubspace  SubspaceID = []byte{6}
-	chainConfigSubspace  SubspaceID = []byte{7}
-	programsSubspace     SubspaceID = []byte{8}
+	l1PricingSubspace                 SubspaceID = []byte{0}
+	l2PricingSubspace                 SubspaceID = []byte{1}
+	retryablesSubspace                SubspaceID = []byte{2}
+	addressTableSubspace              SubspaceID = []byte{3}
+	chainOwnerSubspace                SubspaceID = []byte{4}
+	sendMerkleSubspace                SubspaceID = []byte{5}
+	blockhashesSubspace               SubspaceID = []byte{6}
+	chainConfigSubspace               SubspaceID = []byte{7}
+	programsSubspace                  SubspaceID = []byte{8}
+	allowedSendersSubspace            SubspaceID = []byte{9}
+	allowedDeployersSubspace          SubspaceID = []byte{10}
+	gasFreePairsSubspace              SubspaceID = []byte{11}
+	parameterGuardrailsSubspace       SubspaceID = []byte{12}
+	disabledPrecompileMethodsSubspace SubspaceID = []byte{13}
+)
+
+// Bits of the allowlist mode, selecting which allowlists are enforced
+const (
+	AllowlistSenders uint64 = 1 << iota
+	AllowlistDeployers
 )
 
 var PrecompileMinArbOSVersions = make(map[common.Address]uint64)
@@ -332,6 +398,11 @@ func (state *ArbosState) UpgradeArbosVersion(
 			ensure(params.UpgradeToVersion(2))
 			ensure(params.Save())
 
+		case 32:
+			// the delayed message backlog is recorded from the next block on
+			ensure(addressSet.Initialize(state.backingStorage.OpenCachedSubStorage(allowedSendersSubspace)))
+			ensure(addressSet.Initialize(state.backingStorage.OpenCachedSubStorage(allowedDeployersSubspace)))
+
 		default:
 			return fmt.Errorf(
 				"the chain is upgrading to unsupported ArbOS version %v, %w",
@@ -412,6 +483,199 @@ func (state *ArbosState) SetBrotliCompressionLevel(val uint64) error {
 	return errors.New("invalid brotli compression level")
 }
 
+// DelayedMessagesRead returns how many delayed messages have been sequenced as of the current block.
+func (state *ArbosState) DelayedMessagesRead() (uint64, error) {
+	return state.delayedMessagesRead.Get()
+}
+
+// LastBatchPostingReport returns the size of the L1 delayed inbox when the last sequenced batch
+// posting report was made, along with the L1 timestamp of that report.
+func (state *ArbosState) LastBatchPostingReport() (uint64, uint64, error) {
+	delayedCount, err := state.lastReportDelayedCount.Get()
+	if err != nil {
+		return 0, 0, err
+	}
+	timestamp, err := state.lastReportTimestamp.Get()
+	return delayedCount, timestamp, err
+}
+
+// RecordDelayedMessagesRead records the delayed inbox position of a new block. Batch posting
+// reports are themselves delayed messages, so their request id tells us how many delayed
+// messages existed on L1 when the batch was posted.
+func (state *ArbosState) RecordDelayedMessagesRead(delayedMessagesRead uint64, l1Header *arbostypes.L1IncomingMessageHeader) error {
+	if err := state.delayedMessagesRead.Set(delayedMessagesRead); err != nil {
+		return err
+	}
+	if l1Header.Kind != arbostypes.L1MessageType_BatchPostingReport || l1Header.RequestId == nil {
+		return nil
+	}
+	reportIndex := l1Header.RequestId.Big()
+	if !reportIndex.IsUint64() {
+		return fmt.Errorf("batch posting report request id %v is not a uint64", reportIndex)
+	}
+	if err := state.lastReportDelayedCount.Set(reportIndex.Uint64() + 1); err != nil {
+		return err
+	}
+	return state.lastReportTimestamp.Set(l1Header.Timestamp)
+}
+
+// EmergencyPauseExpiry returns the timestamp until which user transactions are paused, or 0 if there's no pause.
+func (state *ArbosState) EmergencyPauseExpiry() (uint64, error) {
+	return state.emergencyPauseExpiry.Get()
+}
+
+func (state *ArbosState) SetEmergencyPauseExpiry(timestamp uint64) error {
+	return state.emergencyPauseExpiry.Set(timestamp)
+}
+
+// EmergencyPaused checks whether user transactions are paused at the given block timestamp.
+func (state *ArbosState) EmergencyPaused(timestamp uint64) (bool, error) {
+	if state.arbosVersion < 32 {
+		return false, nil
+	}
+	expiry, err := state.emergencyPauseExpiry.Get()
+	return timestamp < expiry, err
+}
+
+func (state *ArbosState) AllowlistMode() (uint64, error) {
+	if state.arbosVersion < 32 {
+		return 0, nil
+	}
+	return state.allowlistMode.Get()
+}
+
+func (state *ArbosState) SetAllowlistMode(mode uint64) error {
+	if mode > AllowlistSenders|AllowlistDeployers {
+		return fmt.Errorf("invalid allowlist mode %v", mode)
+	}
+	return state.allowlistMode.Set(mode)
+}
+
+func (state *ArbosState) AllowedSenders() *addressSet.AddressSet {
+	return state.allowedSenders
+}
+
+func (state *ArbosState) AllowedDeployers() *addressSet.AddressSet {
+	return state.allowedDeployers
+}
+
+// ParameterGuardrails bounds how fast chain owners can change pricing parameters.
+func (state *ArbosState) ParameterGuardrails() *guardrails.Guardrails {
+	return state.parameterGuardrails
+}
+
+// DisabledPrecompileMethods holds the precompile methods the chain owner disabled.
+func (state *ArbosState) DisabledPrecompileMethods() *disabledmethods.Set {
+	return state.disabledPrecompileMethods
+}
+
+// IsGasFreePair checks whether the chain owner designated transactions from sender to target as gas-free.
+func (state *ArbosState) IsGasFreePair(sender, target common.Address) (bool, error) {
+	value, err := state.gasFreePairs.OpenSubStorage(sender.Bytes()).Get(util.AddressToHash(target))
+	return value != (common.Hash{}), err
+}
+
+func (state *ArbosState) SetGasFreePair(sender, target common.Address, gasFree bool) error {
+	value := common.Hash{}
+	if gasFree {
+		value = common.BigToHash(common.Big1)
+	}
+	return state.gasFreePairs.OpenSubStorage(sender.Bytes()).Set(util.AddressToHash(target), value)
+}
+
+// FeeTokenDecimals returns how many decimals the chain's fee token has, which balances, gas prices and fees are
+// denominated in the smallest unit of.
+func (state *ArbosState) FeeTokenDecimals() (uint64, error) {
+	decimals, err := state.feeTokenDecimals.Get()
+	if err != nil || decimals == 0 {
+		return arbmath.EtherDecimals, err
+	}
+	return decimals, nil
+}
+
+// SetFeeTokenDecimals sets how many decimals the fee token has. The funds owed to batch posters, which are
+// denominated in the token's smallest unit, are rescaled to the new decimals.
+func (state *ArbosState) SetFeeTokenDecimals(decimals uint64) error {
+	prev, err := state.FeeTokenDecimals()
+	if err != nil {
+		return err
+	}
+	if prev != decimals {
+		if err := state.l1PricingState.BatchPosterTable().RescaleFundsDue(prev, decimals); err != nil {
+			return err
+		}
+	}
+	return state.feeTokenDecimals.Set(decimals)
+}
+
+// FeeToken returns the chain's fee token: its decimals, and how much it's worth in wei, which the L1 costs
+// reimbursed to batch posters are converted at.
+func (state *ArbosState) FeeToken() (arbmath.FeeToken, error) {
+	decimals, err := state.FeeTokenDecimals()
+	if err != nil {
+		return arbmath.EtherFeeToken, err
+	}
+	weiPerToken, err := state.feeTokenExchangeRate.Get()
+	if err != nil {
+		return arbmath.EtherFeeToken, err
+	}
+	if weiPerToken.Sign() == 0 {
+		weiPerToken = arbmath.EtherFeeToken.WeiPerToken
+	}
+	return arbmath.FeeToken{Decimals: decimals, WeiPerToken: weiPerToken}, nil
+}
+
+// SetFeeTokenExchangeRate sets how many wei one whole fee token is worth.
+func (state *ArbosState) SetFeeTokenExchangeRate(weiPerToken *big.Int) error {
+	return state.feeTokenExchangeRate.SetChecked(weiPerToken)
+}
+
+func (state *ArbosState) GasFreeBudget() (uint64, error) {
+	return state.gasFreeBudget.Get()
+}
+
+func (state *ArbosState) SetGasFreeBudget(gas uint64) error {
+	return state.gasFreeBudget.Set(gas)
+}
+
+// GasFreeUsed returns how much of the gas-free budget has been used in the given block.
+func (state *ArbosState) GasFreeUsed(blockNumber uint64) (uint64, error) {
+	usedBlock, err := state.gasFreeUsedBlock.Get()
+	if err != nil || usedBlock != blockNumber {
+		return 0, err
+	}
+	return state.gasFreeUsed.Get()
+}
+
+// GasFreeBudgetLeft returns how much of the given block's gas-free budget hasn't been used yet.
+func (state *ArbosState) GasFreeBudgetLeft(blockNumber uint64) (uint64, error) {
+	budget, err := state.gasFreeBudget.Get()
+	if err != nil {
+		return 0, err
+	}
+	used, err := state.GasFreeUsed(blockNumber)
+	return arbmath.SaturatingUSub(budget, used), err
+}
+
+// ConsumeGasFreeBudget uses gas from the given block's gas-free budget, returning false if there isn't enough left.
+func (state *ArbosState) ConsumeGasFreeBudget(blockNumber uint64, gas uint64) (bool, error) {
+	budget, err := state.gasFreeBudget.Get()
+	if err != nil {
+		return false, err
+	}
+	used, err := state.GasFreeUsed(blockNumber)
+	if err != nil {
+		return false, err
+	}
+	if arbmath.SaturatingUAdd(used, gas) > budget {
+		return false, nil
+	}
+	if err := state.gasFreeUsedBlock.Set(blockNumber); err != nil {
+		return false, err
+	}
+	return true, state.gasFreeUsed.Set(used + gas)
+}
+
 func (state *ArbosState) RetryableState() *retryables.RetryableState {
 	return state.retryableState
 }
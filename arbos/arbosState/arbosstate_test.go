@@ -5,9 +5,11 @@ package arbosState
 
 import (
 	"bytes"
+	"math/big"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
 	"github.com/offchainlabs/nitro/arbos/burn"
 	"github.com/offchainlabs/nitro/arbos/storage"
 	"github.com/offchainlabs/nitro/arbos/util"
@@ -91,3 +93,116 @@ func TestStorageSlots(t *testing.T) {
 		Fail(t, "page offset mismatch")
 	}
 }
+
+func TestRecordDelayedMessagesRead(t *testing.T) {
+	state, _ := NewArbosMemoryBackedArbOSState()
+	header := &arbostypes.L1IncomingMessageHeader{
+		Kind:      arbostypes.L1MessageType_L2Message,
+		Timestamp: 100,
+		L1BaseFee: big.NewInt(0),
+	}
+	Require(t, state.RecordDelayedMessagesRead(3, header))
+
+	reportId := common.BigToHash(big.NewInt(7))
+	header = &arbostypes.L1IncomingMessageHeader{
+		Kind:      arbostypes.L1MessageType_BatchPostingReport,
+		Timestamp: 200,
+		RequestId: &reportId,
+		L1BaseFee: big.NewInt(0),
+	}
+	Require(t, state.RecordDelayedMessagesRead(8, header))
+
+	read, err := state.DelayedMessagesRead()
+	Require(t, err)
+	reported, timestamp, err := state.LastBatchPostingReport()
+	Require(t, err)
+	if read != 8 || reported != 8 || timestamp != 200 {
+		Fail(t, "unexpected delayed backlog", read, reported, timestamp)
+	}
+}
+
+func TestGasFreeBudget(t *testing.T) {
+	state, _ := NewArbosMemoryBackedArbOSState()
+	sender := common.Address{1}
+	target := common.Address{2}
+
+	Require(t, state.SetGasFreePair(sender, target, true))
+	gasFree, err := state.IsGasFreePair(sender, target)
+	Require(t, err)
+	otherGasFree, err := state.IsGasFreePair(target, sender)
+	Require(t, err)
+	if !gasFree || otherGasFree {
+		Fail(t, "unexpected gas-free pairs", gasFree, otherGasFree)
+	}
+
+	Require(t, state.SetGasFreeBudget(100_000))
+	consume := func(block uint64, gas uint64, expected bool) {
+		t.Helper()
+		consumed, err := state.ConsumeGasFreeBudget(block, gas)
+		Require(t, err)
+		if consumed != expected {
+			Fail(t, "unexpected gas-free budget result at block", block, "gas", gas)
+		}
+	}
+	consume(1, 60_000, true)
+	consume(1, 60_000, false)
+	left, err := state.GasFreeBudgetLeft(1)
+	Require(t, err)
+	if left != 40_000 {
+		Fail(t, "unexpected gas-free budget left", left)
+	}
+	consume(1, 40_000, true)
+	consume(1, 1, false)
+	// the budget resets every block
+	consume(2, 100_000, true)
+	used, err := state.GasFreeUsed(2)
+	Require(t, err)
+	if used != 100_000 {
+		Fail(t, "unexpected gas-free usage", used)
+	}
+
+	Require(t, state.SetGasFreePair(sender, target, false))
+	gasFree, err = state.IsGasFreePair(sender, target)
+	Require(t, err)
+	if gasFree {
+		Fail(t, "gas-free pair wasn't removed")
+	}
+}
+
+func TestFeeTokenRescalesFundsDue(t *testing.T) {
+	state, _ := NewArbosMemoryBackedArbOSState()
+	feeToken, err := state.FeeToken()
+	Require(t, err)
+	if !feeToken.IsEther() {
+		Fail(t, "fee token not ether by default", feeToken)
+	}
+
+	posters := state.L1PricingState().BatchPosterTable()
+	poster, err := posters.AddPoster(common.Address{1}, common.Address{1})
+	Require(t, err)
+	Require(t, poster.SetFundsDue(big.NewInt(1_500_000_000_000)))
+	fundsDue := func(expected int64) {
+		t.Helper()
+		due, err := poster.FundsDue()
+		Require(t, err)
+		total, err := posters.TotalFundsDue()
+		Require(t, err)
+		if due.Int64() != expected || total.Cmp(due) != 0 {
+			Fail(t, "unexpected funds due", due, "total", total, "expected", expected)
+		}
+	}
+
+	Require(t, state.SetFeeTokenDecimals(6))
+	fundsDue(2)
+	Require(t, state.SetFeeTokenDecimals(8))
+	fundsDue(200)
+	Require(t, state.SetFeeTokenDecimals(8))
+	fundsDue(200)
+
+	Require(t, state.SetFeeTokenExchangeRate(big.NewInt(500_000_000_000_000_000)))
+	feeToken, err = state.FeeToken()
+	Require(t, err)
+	if feeToken.Decimals != 8 || feeToken.WeiPerToken.Int64() != 500_000_000_000_000_000 {
+		Fail(t, "unexpected fee token", feeToken)
+	}
+}
@@ -37,6 +37,9 @@ var L2ToL1TransactionEventID common.Hash
 var L2ToL1TxEventID common.Hash
 var EmitReedeemScheduledEvent func(*vm.EVM, uint64, uint64, [32]byte, [32]byte, common.Address, *big.Int, *big.Int) error
 var EmitTicketCreatedEvent func(*vm.EVM, [32]byte) error
+var EmitGasFreeBudgetExhaustedEvent func(*vm.EVM, uint64, common.Address, common.Address) error
+
+var ErrEmergencyPause = errors.New("user transactions are paused by the chain owner")
 
 // A helper struct that implements String() by marshalling to JSON.
 // This is useful for logging because it's lazy, so if the log level is too high to print the transaction,
@@ -194,6 +197,17 @@ func ProduceBlockAdvanced(
 	blockGasLeft, _ := state.L2PricingState().PerBlockGasLimit()
 	l1BlockNum := l1Info.l1BlockNumber
 
+	if state.ArbOSVersion() >= 32 {
+		// Make the delayed inbox position visible to contracts via ArbSys
+		writableState, err := arbosState.OpenSystemArbosState(statedb, nil, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := writableState.RecordDelayedMessagesRead(delayedMessagesRead, l1Header); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	// Prepend a tx before all others to touch up the state (update the L1 block num, pricing pools, etc)
 	startTx := InternalTxStartBlock(chainConfig.ChainID, l1Header.L1BaseFee, l1BlockNum, header, lastBlockHeader)
 	txes = append(types.Transactions{types.NewTx(startTx)}, txes...)
@@ -265,10 +279,19 @@ func ProduceBlockAdvanced(
 				return nil, nil, err
 			}
 
+			if isUserTx {
+				if err = checkEmergencyPause(state, l1Header, header.Time, sender); err != nil {
+					return nil, nil, err
+				}
+			}
+
 			// Additional pre-transaction validity check
 			if err = extraPreTxFilter(chainConfig, header, statedb, state, tx, options, sender, l1Info); err != nil {
 				return nil, nil, err
 			}
+			if err = runPreTxHooks(chainConfig, header, statedb, tx, sender, l1Info); err != nil {
+				return nil, nil, err
+			}
 
 			if basefee.Sign() > 0 {
 				dataGas = math.MaxUint64
@@ -333,6 +356,10 @@ func ProduceBlockAdvanced(
 				statedb.RevertToSnapshot(snap)
 				return nil, nil, err
 			}
+			if err = runPostTxHooks(chainConfig, header, statedb, tx, sender, l1Info, result); err != nil {
+				statedb.RevertToSnapshot(snap)
+				return nil, nil, err
+			}
 
 			return receipt, result, nil
 		})()
@@ -359,6 +386,10 @@ func ProduceBlockAdvanced(
 			}
 			if !isMsgForPrefetch {
 				logLevel("error applying transaction", "tx", printTxAsJson{tx}, "err", err)
+				if retry, ok := tx.GetInner().(*types.ArbitrumRetryTx); ok {
+					// a redeem that fails to apply leaves no receipt, e.g. when the allowlists reject its sender
+					log.Warn("scheduled redeem of retryable failed, it's still redeemable until it expires", "ticketId", retry.TicketId, "from", retry.From, "err", err)
+				}
 			}
 			if !hooks.DiscardInvalidTxsEarly {
 				// we'll still deduct a TxGas's worth from the block-local rate limiter even if the tx was invalid
@@ -454,6 +485,8 @@ func ProduceBlockAdvanced(
 
 	binary.BigEndian.PutUint64(header.Nonce[:], delayedMessagesRead)
 
+	runEndBlockHooks(chainConfig, header, statedb, complete, l1Info)
+
 	FinalizeBlock(header, complete, statedb, chainConfig)
 
 	// Touch up the block hashes in receipts
@@ -486,6 +519,27 @@ func ProduceBlockAdvanced(
 	return block, receipts, nil
 }
 
+// checkEmergencyPause rejects user transactions while the chain owner has paused the chain, except for
+// transactions sent by chain owners. Every transaction from a delayed message is still executed, including L2
+// transactions sent through the delayed inbox rather than just deposits and retryables: a delayed message is
+// consumed when it's read, so a transaction rejected from one would be dropped for good rather than held back
+// until the pause ends, and the pause would let the owner censor the transactions the delayed inbox
+// guarantees are included.
+func checkEmergencyPause(state *arbosState.ArbosState, l1Header *arbostypes.L1IncomingMessageHeader, timestamp uint64, sender common.Address) error {
+	if l1Header.RequestId != nil {
+		return nil
+	}
+	paused, err := state.EmergencyPaused(timestamp)
+	if err != nil || !paused {
+		return err
+	}
+	isOwner, err := state.ChainOwners().IsMember(sender)
+	if err != nil || isOwner {
+		return err
+	}
+	return ErrEmergencyPause
+}
+
 // Also sets header.Root
 func FinalizeBlock(header *types.Header, txs types.Transactions, statedb *state.StateDB, chainConfig *params.ChainConfig) {
 	if header != nil {
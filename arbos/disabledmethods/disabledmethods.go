@@ -0,0 +1,136 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package disabledmethods
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/arbos/storage"
+	"github.com/offchainlabs/nitro/arbos/util"
+)
+
+// Set holds the precompile methods the chain owner disabled, by precompile and method selector.
+// The total count across precompiles is stored at position 0, so calls skip the lookup while none are disabled.
+// Each precompile's selectors are stored like an address set: its count at position 0 of its substorage,
+// the selectors sequentially from 1 onward, and a mapping from each selector to its position.
+type Set struct {
+	total          storage.StorageBackedUint64
+	backingStorage *storage.Storage
+}
+
+var bySelectorKey = []byte{0}
+
+func Open(sto *storage.Storage) *Set {
+	return &Set{
+		total:          sto.OpenStorageBackedUint64(0),
+		backingStorage: sto,
+	}
+}
+
+type precompileMethods struct {
+	size       storage.StorageBackedUint64
+	selectors  *storage.Storage
+	bySelector *storage.Storage
+}
+
+func (s *Set) open(precompile common.Address) *precompileMethods {
+	sto := s.backingStorage.OpenSubStorage(precompile.Bytes())
+	return &precompileMethods{
+		size:       sto.OpenStorageBackedUint64(0),
+		selectors:  sto,
+		bySelector: sto.OpenSubStorage(bySelectorKey),
+	}
+}
+
+func selectorToHash(selector [4]byte) common.Hash {
+	return common.BytesToHash(selector[:])
+}
+
+// Total returns how many methods are disabled across all precompiles.
+func (s *Set) Total() (uint64, error) {
+	return s.total.Get()
+}
+
+func (s *Set) IsDisabled(precompile common.Address, selector [4]byte) (bool, error) {
+	slot, err := s.open(precompile).bySelector.GetUint64(selectorToHash(selector))
+	return slot != 0, err
+}
+
+// Disable disables the method, doing nothing if it's already disabled.
+func (s *Set) Disable(precompile common.Address, selector [4]byte) error {
+	methods := s.open(precompile)
+	key := selectorToHash(selector)
+	slot, err := methods.bySelector.GetUint64(key)
+	if slot != 0 || err != nil {
+		return err
+	}
+	size, err := methods.size.Increment()
+	if err != nil {
+		return err
+	}
+	if err := methods.selectors.SetByUint64(size, key); err != nil {
+		return err
+	}
+	if err := methods.bySelector.Set(key, util.UintToHash(size)); err != nil {
+		return err
+	}
+	_, err = s.total.Increment()
+	return err
+}
+
+// Enable enables the method again, doing nothing if it isn't disabled.
+func (s *Set) Enable(precompile common.Address, selector [4]byte) error {
+	methods := s.open(precompile)
+	key := selectorToHash(selector)
+	slot, err := methods.bySelector.GetUint64(key)
+	if slot == 0 || err != nil {
+		return err
+	}
+	if err := methods.bySelector.Clear(key); err != nil {
+		return err
+	}
+	size, err := methods.size.Get()
+	if err != nil {
+		return err
+	}
+	if slot < size {
+		// move the last selector into the freed slot
+		last, err := methods.selectors.GetByUint64(size)
+		if err != nil {
+			return err
+		}
+		if err := methods.selectors.SetByUint64(slot, last); err != nil {
+			return err
+		}
+		if err := methods.bySelector.Set(last, util.UintToHash(slot)); err != nil {
+			return err
+		}
+	}
+	if err := methods.selectors.ClearByUint64(size); err != nil {
+		return err
+	}
+	if _, err := methods.size.Decrement(); err != nil {
+		return err
+	}
+	_, err = s.total.Decrement()
+	return err
+}
+
+// Disabled returns the selectors of the precompile's disabled methods, in no particular order.
+func (s *Set) Disabled(precompile common.Address) ([][4]byte, error) {
+	methods := s.open(precompile)
+	size, err := methods.size.Get()
+	if err != nil {
+		return nil, err
+	}
+	selectors := make([][4]byte, size)
+	for i := range selectors {
+		value, err := methods.selectors.GetByUint64(uint64(i + 1))
+		if err != nil {
+			return nil, err
+		}
+		copy(selectors[i][:], value[common.HashLength-4:])
+	}
+	return selectors, nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package disabledmethods
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/arbos/burn"
+	"github.com/offchainlabs/nitro/arbos/storage"
+)
+
+func TestDisabledMethods(t *testing.T) {
+	set := Open(storage.NewMemoryBacked(burn.NewSystemBurner(nil, false)))
+	precompile := common.Address{0x6e}
+	other := common.Address{0x6f}
+	a, b, c := [4]byte{1}, [4]byte{2}, [4]byte{3}
+
+	check := func(addr common.Address, total uint64, expected ...[4]byte) {
+		t.Helper()
+		count, err := set.Total()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != total {
+			t.Fatalf("expected %v disabled methods in total, got %v", total, count)
+		}
+		disabled, err := set.Disabled(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(disabled) != len(expected) {
+			t.Fatalf("expected %v disabled, got %v", expected, disabled)
+		}
+		for _, selector := range expected {
+			isDisabled, err := set.IsDisabled(addr, selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !isDisabled {
+				t.Fatalf("expected %v disabled, got %v", expected, disabled)
+			}
+		}
+	}
+
+	check(precompile, 0)
+	for _, selector := range [][4]byte{a, b, c, a} {
+		if err := set.Disable(precompile, selector); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := set.Disable(other, a); err != nil {
+		t.Fatal(err)
+	}
+	check(precompile, 4, a, b, c)
+	check(other, 4, a)
+
+	// enabling a method moves the last one into its slot
+	if err := set.Enable(precompile, a); err != nil {
+		t.Fatal(err)
+	}
+	check(precompile, 3, b, c)
+	if err := set.Enable(precompile, c); err != nil {
+		t.Fatal(err)
+	}
+	if err := set.Enable(precompile, c); err != nil {
+		t.Fatal(err)
+	}
+	check(precompile, 2, b)
+	isDisabled, err := set.IsDisabled(precompile, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isDisabled {
+		t.Fatal("enabled method still disabled")
+	}
+	check(other, 2, a)
+}
@@ -0,0 +1,194 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbos
+
+import (
+	"fmt"
+
+	"github.com/holiman/uint256"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// ExecutionHooks let a fork add its own chain logic to block production from a separate package, instead of
+// patching the state transition. Implementations are registered with RegisterExecutionHooks from an init
+// function, and the package must be imported by every binary that executes blocks, including the replay
+// binary run by validators, or their results will diverge from the sequencer's.
+//
+// Hooks are part of the state transition function, so they must be deterministic: they may only depend on
+// what they're passed, and mustn't read the clock, randomness, the network, or the node's configuration.
+// They can read any account, but only write to the storage of their own account, and can't move funds.
+//
+// Registering hooks mustn't change the result of blocks already produced, so they only run on the chains
+// they're scheduled for by Activation, from the block their activation is reached. The transaction hooks
+// aren't run on ArbOS's internal transactions, which start each block and can't be rejected.
+type ExecutionHooks interface {
+	// Name identifies the hooks, and derives the account they write to.
+	Name() string
+	// Activation returns when the hooks activate on the chain, or false if they never run on it.
+	Activation(chainId uint64) (HookActivation, bool)
+	// PreTx runs before a transaction is applied, and rejects it by returning an error.
+	PreTx(ctx *HookContext, state HookStateReader, tx *types.Transaction, sender common.Address) error
+	// PostTx runs after a transaction is applied, and rejects it, reverting its changes along with those
+	// of the hooks, by returning an error.
+	PostTx(ctx *HookContext, state HookState, tx *types.Transaction, sender common.Address, result *core.ExecutionResult) error
+	// EndBlock runs after a block's transactions are applied, before its state root is computed.
+	// It can't reject the block, which has already been sequenced.
+	EndBlock(ctx *HookContext, state HookState, txs types.Transactions)
+}
+
+// HookActivation is the first block the hooks run in: the first at or past BlockNumber whose ArbOS version
+// is at least ArbOSVersion. Scheduling hooks past the chain's current block lets every node upgrade first.
+type HookActivation struct {
+	ArbOSVersion uint64
+	BlockNumber  uint64
+}
+
+// HookContext describes the block the hooks are run in.
+type HookContext struct {
+	ChainId       uint64
+	BlockNumber   uint64
+	Timestamp     uint64
+	L1BlockNumber uint64
+	ArbOSVersion  uint64
+}
+
+// HookStateReader is the state the hooks can read.
+type HookStateReader interface {
+	GetBalance(account common.Address) *uint256.Int
+	GetNonce(account common.Address) uint64
+	GetCodeHash(account common.Address) common.Hash
+	GetState(account common.Address, key common.Hash) common.Hash
+	// Account is the account the hooks store their state in.
+	Account() common.Address
+	// GetStorage reads from the storage of the hooks' account.
+	GetStorage(key common.Hash) common.Hash
+}
+
+// HookState is the state the hooks can read and write.
+type HookState interface {
+	HookStateReader
+	// SetStorage writes to the storage of the hooks' account.
+	SetStorage(key, value common.Hash)
+}
+
+var executionHooks []ExecutionHooks
+
+// RegisterExecutionHooks adds hooks to run during block production, in the order they were registered.
+// It should only be called from init functions, and panics if hooks of the same name are already registered.
+func RegisterExecutionHooks(hooks ExecutionHooks) {
+	for _, registered := range executionHooks {
+		if registered.Name() == hooks.Name() {
+			panic(fmt.Sprintf("execution hooks %v registered twice", hooks.Name()))
+		}
+	}
+	executionHooks = append(executionHooks, hooks)
+}
+
+// ExecutionHooksAccount returns the account the named hooks store their state in.
+func ExecutionHooksAccount(name string) common.Address {
+	return common.BytesToAddress(crypto.Keccak256([]byte("nitro execution hooks"), []byte(name)))
+}
+
+type hookState struct {
+	statedb *state.StateDB
+	account common.Address
+}
+
+func (s *hookState) GetBalance(account common.Address) *uint256.Int {
+	return new(uint256.Int).Set(s.statedb.GetBalance(account))
+}
+
+func (s *hookState) GetNonce(account common.Address) uint64 {
+	return s.statedb.GetNonce(account)
+}
+
+func (s *hookState) GetCodeHash(account common.Address) common.Hash {
+	return s.statedb.GetCodeHash(account)
+}
+
+func (s *hookState) GetState(account common.Address, key common.Hash) common.Hash {
+	return s.statedb.GetState(account, key)
+}
+
+func (s *hookState) Account() common.Address {
+	return s.account
+}
+
+func (s *hookState) GetStorage(key common.Hash) common.Hash {
+	return s.statedb.GetState(s.account, key)
+}
+
+func (s *hookState) SetStorage(key, value common.Hash) {
+	if s.statedb.GetNonce(s.account) == 0 {
+		s.statedb.SetNonce(s.account, 1) // setting the nonce ensures Geth won't treat the account as empty
+	}
+	s.statedb.SetState(s.account, key, value)
+}
+
+func newHookContext(chainConfig *params.ChainConfig, header *types.Header, l1Info *L1Info) *HookContext {
+	return &HookContext{
+		ChainId:       chainConfig.ChainID.Uint64(),
+		BlockNumber:   header.Number.Uint64(),
+		Timestamp:     header.Time,
+		L1BlockNumber: l1Info.l1BlockNumber,
+		ArbOSVersion:  types.DeserializeHeaderExtraInformation(header).ArbOSFormatVersion,
+	}
+}
+
+// activeExecutionHooks returns the registered hooks activated by the block.
+func activeExecutionHooks(ctx *HookContext) []ExecutionHooks {
+	var active []ExecutionHooks
+	for _, hooks := range executionHooks {
+		activation, ok := hooks.Activation(ctx.ChainId)
+		if ok && ctx.BlockNumber >= activation.BlockNumber && ctx.ArbOSVersion >= activation.ArbOSVersion {
+			active = append(active, hooks)
+		}
+	}
+	return active
+}
+
+func runPreTxHooks(chainConfig *params.ChainConfig, header *types.Header, statedb *state.StateDB, tx *types.Transaction, sender common.Address, l1Info *L1Info) error {
+	if len(executionHooks) == 0 || tx.Type() == types.ArbitrumInternalTxType {
+		return nil
+	}
+	ctx := newHookContext(chainConfig, header, l1Info)
+	for _, hooks := range activeExecutionHooks(ctx) {
+		// wrapped so the hooks can't assert their way to writing
+		reader := struct{ HookStateReader }{&hookState{statedb, ExecutionHooksAccount(hooks.Name())}}
+		if err := hooks.PreTx(ctx, reader, tx, sender); err != nil {
+			return fmt.Errorf("rejected by %v: %w", hooks.Name(), err)
+		}
+	}
+	return nil
+}
+
+func runPostTxHooks(chainConfig *params.ChainConfig, header *types.Header, statedb *state.StateDB, tx *types.Transaction, sender common.Address, l1Info *L1Info, result *core.ExecutionResult) error {
+	if len(executionHooks) == 0 || tx.Type() == types.ArbitrumInternalTxType {
+		return nil
+	}
+	ctx := newHookContext(chainConfig, header, l1Info)
+	for _, hooks := range activeExecutionHooks(ctx) {
+		writer := &hookState{statedb, ExecutionHooksAccount(hooks.Name())}
+		if err := hooks.PostTx(ctx, writer, tx, sender, result); err != nil {
+			return fmt.Errorf("rejected by %v: %w", hooks.Name(), err)
+		}
+	}
+	return nil
+}
+
+func runEndBlockHooks(chainConfig *params.ChainConfig, header *types.Header, statedb *state.StateDB, txs types.Transactions, l1Info *L1Info) {
+	if len(executionHooks) == 0 {
+		return
+	}
+	ctx := newHookContext(chainConfig, header, l1Info)
+	for _, hooks := range activeExecutionHooks(ctx) {
+		hooks.EndBlock(ctx, &hookState{statedb, ExecutionHooksAccount(hooks.Name())}, txs)
+	}
+}
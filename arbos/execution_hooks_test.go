@@ -0,0 +1,127 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbos
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/offchainlabs/nitro/arbos/arbosState"
+)
+
+var errTestHookRejected = errors.New("rejected")
+
+type testExecutionHooks struct {
+	name       string
+	reject     common.Address
+	chainId    uint64
+	activation HookActivation
+}
+
+func (h *testExecutionHooks) Name() string {
+	return h.name
+}
+
+func (h *testExecutionHooks) Activation(chainId uint64) (HookActivation, bool) {
+	return h.activation, chainId == h.chainId
+}
+
+func (h *testExecutionHooks) PreTx(ctx *HookContext, state HookStateReader, tx *types.Transaction, sender common.Address) error {
+	if _, ok := state.(HookState); ok {
+		return errors.New("pre-tx hooks were given writable state")
+	}
+	if sender == h.reject {
+		return errTestHookRejected
+	}
+	return nil
+}
+
+func (h *testExecutionHooks) PostTx(ctx *HookContext, state HookState, tx *types.Transaction, sender common.Address, result *core.ExecutionResult) error {
+	return nil
+}
+
+func (h *testExecutionHooks) EndBlock(ctx *HookContext, state HookState, txs types.Transactions) {
+	state.SetStorage(common.Hash{}, common.BigToHash(new(big.Int).SetUint64(ctx.BlockNumber)))
+}
+
+func TestExecutionHooks(t *testing.T) {
+	registered := executionHooks
+	defer func() { executionHooks = registered }()
+	executionHooks = nil
+
+	chainConfig := params.ArbitrumDevTestChainConfig()
+	rejected := common.HexToAddress("0x1234")
+	hooks := &testExecutionHooks{
+		name:       "test",
+		reject:     rejected,
+		chainId:    chainConfig.ChainID.Uint64(),
+		activation: HookActivation{BlockNumber: 7},
+	}
+	RegisterExecutionHooks(hooks)
+	func() {
+		defer func() {
+			if recover() == nil {
+				Fail(t, "registering hooks of the same name twice didn't panic")
+			}
+		}()
+		RegisterExecutionHooks(&testExecutionHooks{name: "test"})
+	}()
+
+	_, statedb := arbosState.NewArbosMemoryBackedArbOSState()
+	header := &types.Header{Number: big.NewInt(7), Time: 100}
+	l1Info := &L1Info{l1BlockNumber: 5}
+	tx := types.NewTx(&types.LegacyTx{})
+
+	if err := runPreTxHooks(chainConfig, header, statedb, tx, common.Address{}, l1Info); err != nil {
+		Fail(t, "hooks rejected a transaction:", err)
+	}
+	if err := runPreTxHooks(chainConfig, header, statedb, tx, rejected, l1Info); !errors.Is(err, errTestHookRejected) {
+		Fail(t, "hooks didn't reject a transaction, got", err)
+	}
+	// the internal transaction starting each block can't be rejected
+	internalTx := types.NewTx(&types.ArbitrumInternalTx{ChainId: chainConfig.ChainID})
+	if err := runPreTxHooks(chainConfig, header, statedb, internalTx, rejected, l1Info); err != nil {
+		Fail(t, "hooks ran on an internal transaction:", err)
+	}
+	// blocks before the activation are unaffected
+	earlier := &types.Header{Number: big.NewInt(6), Time: 90}
+	if err := runPreTxHooks(chainConfig, earlier, statedb, tx, rejected, l1Info); err != nil {
+		Fail(t, "hooks ran before their activation:", err)
+	}
+
+	runEndBlockHooks(chainConfig, header, statedb, nil, l1Info)
+	account := ExecutionHooksAccount(hooks.name)
+	if stored := statedb.GetState(account, common.Hash{}).Big().Uint64(); stored != 7 {
+		Fail(t, "hooks stored", stored, "instead of the block number")
+	}
+	if statedb.GetNonce(account) == 0 {
+		Fail(t, "the hooks' account could be treated as empty")
+	}
+}
+
+func TestExecutionHooksActivation(t *testing.T) {
+	registered := executionHooks
+	defer func() { executionHooks = registered }()
+	executionHooks = nil
+
+	hooks := &testExecutionHooks{name: "test", chainId: 412346, activation: HookActivation{ArbOSVersion: 31, BlockNumber: 100}}
+	RegisterExecutionHooks(hooks)
+	check := func(ctx *HookContext, expected bool) {
+		t.Helper()
+		if active := len(activeExecutionHooks(ctx)) > 0; active != expected {
+			Fail(t, "hooks active", active, "in block", ctx.BlockNumber, "of chain", ctx.ChainId, "with ArbOS version", ctx.ArbOSVersion)
+		}
+	}
+	check(&HookContext{ChainId: 412346, BlockNumber: 100, ArbOSVersion: 31}, true)
+	check(&HookContext{ChainId: 412346, BlockNumber: 99, ArbOSVersion: 31}, false)
+	check(&HookContext{ChainId: 412346, BlockNumber: 100, ArbOSVersion: 30}, false)
+	// hooks never run on chains they aren't scheduled for
+	check(&HookContext{ChainId: 42161, BlockNumber: 1000, ArbOSVersion: 31}, false)
+}
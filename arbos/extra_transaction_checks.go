@@ -10,7 +10,8 @@ import (
 	"github.com/offchainlabs/nitro/arbos/arbosState"
 )
 
-// extraPreTxFilter should be modified by chain operators to enforce additional pre-transaction validity rules
+// extraPreTxFilter should be modified by chain operators to enforce additional pre-transaction validity rules.
+// Forks can instead register ExecutionHooks from their own package.
 func extraPreTxFilter(
 	chainConfig *params.ChainConfig,
 	currentBlockHeader *types.Header,
@@ -25,7 +26,8 @@ func extraPreTxFilter(
 	return nil
 }
 
-// extraPostTxFilter should be modified by chain operators to enforce additional post-transaction validity rules
+// extraPostTxFilter should be modified by chain operators to enforce additional post-transaction validity rules.
+// Forks can instead register ExecutionHooks from their own package.
 func extraPostTxFilter(
 	chainConfig *params.ChainConfig,
 	currentBlockHeader *types.Header,
@@ -0,0 +1,52 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/offchainlabs/nitro/arbos/arbosState"
+	"github.com/offchainlabs/nitro/util/testhelpers"
+)
+
+func TestGasFreeLoan(t *testing.T) {
+	state, statedb := arbosState.NewArbosMemoryBackedArbOSState()
+	Require(t, state.UpgradeArbosVersion(32, false, statedb, params.ArbitrumDevTestChainConfig()))
+
+	sender := testhelpers.RandomAddress()
+	target := testhelpers.RandomAddress()
+	msg := &core.Message{From: sender, To: &target, GasLimit: 50_000, GasFeeCap: big.NewInt(100)}
+	expect := func(expected int64) {
+		t.Helper()
+		loan, err := gasFreeLoan(state, msg, 1)
+		Require(t, err)
+		if (loan == nil) != (expected == 0) || (loan != nil && loan.Int64() != expected) {
+			Fail(t, "unexpected gas-free loan", loan, "expected", expected)
+		}
+	}
+
+	// only designated pairs get a loan
+	Require(t, state.SetGasFreeBudget(100_000))
+	expect(0)
+	Require(t, state.SetGasFreePair(sender, target, true))
+	expect(5_000_000)
+
+	// the loan covers the whole gas limit, so it's only made while the budget does
+	consumed, err := state.ConsumeGasFreeBudget(1, 60_000)
+	Require(t, err)
+	if !consumed {
+		Fail(t, "failed to consume the gas-free budget")
+	}
+	expect(0)
+	msg.GasLimit = 40_000
+	expect(4_000_000)
+
+	// contract creations can't be gas-free
+	msg.To = nil
+	expect(0)
+}
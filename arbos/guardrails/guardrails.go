@@ -0,0 +1,146 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package guardrails
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/offchainlabs/nitro/arbos/storage"
+	"github.com/offchainlabs/nitro/util/arbmath"
+)
+
+// Guardrails bound how far chain owners can move certain parameters per time window, so a fat-fingered
+// change can't take effect all at once. Within a window, a parameter may only move by maxChangeBips of its
+// value when the window started. An owner can open a bypass to make larger changes deliberately.
+type Guardrails struct {
+	maxChangeBips storage.StorageBackedUint64 // 0 disables the guardrails
+	window        storage.StorageBackedUint64 // in seconds
+	bypassExpiry  storage.StorageBackedUint64 // timestamp until which changes aren't bounded
+	baselines     *storage.Storage
+}
+
+// Parameter identifies a guarded parameter.
+type Parameter byte
+
+const (
+	MinimumL2BaseFee Parameter = iota
+	SpeedLimit
+	L1PricingInertia
+	L1PricingEquilibrationUnits
+	L1PricingRewardRate
+	L1PricePerUnit
+	PerBatchGasCharge
+	AmortizedCostCapBips
+)
+
+const (
+	maxChangeBipsOffset uint64 = iota
+	windowOffset
+	bypassExpiryOffset
+)
+
+var baselinesKey = []byte{0}
+
+// the per parameter baseline a window's changes are bounded relative to
+const (
+	baselineStartOffset uint64 = iota
+	baselineValueOffset
+)
+
+var ErrExceedsGuardrail = errors.New("parameter change exceeds its rate-of-change guardrail")
+
+func Open(sto *storage.Storage) *Guardrails {
+	return &Guardrails{
+		maxChangeBips: sto.OpenStorageBackedUint64(maxChangeBipsOffset),
+		window:        sto.OpenStorageBackedUint64(windowOffset),
+		bypassExpiry:  sto.OpenStorageBackedUint64(bypassExpiryOffset),
+		baselines:     sto.OpenSubStorage(baselinesKey),
+	}
+}
+
+// Config returns the maximum change per window in basis points, the window in seconds, and until when changes
+// aren't bounded.
+func (g *Guardrails) Config() (uint64, uint64, uint64, error) {
+	maxChange, err := g.maxChangeBips.Get()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	window, err := g.window.Get()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	bypassExpiry, err := g.bypassExpiry.Get()
+	return maxChange, window, bypassExpiry, err
+}
+
+// SetConfig sets the maximum change per window, with 0 disabling the guardrails, and the window. Loosening
+// enabled guardrails, with a larger maximum change or a shorter window, requires the bypass be open.
+func (g *Guardrails) SetConfig(maxChangeBips, window, now uint64) error {
+	oldMaxChange, oldWindow, bypassExpiry, err := g.Config()
+	if err != nil {
+		return err
+	}
+	loosening := maxChangeBips == 0 || maxChangeBips > oldMaxChange || window < oldWindow
+	if oldMaxChange != 0 && loosening && now >= bypassExpiry {
+		return fmt.Errorf("%w: loosening the guardrails requires opening the bypass first", ErrExceedsGuardrail)
+	}
+	if err := g.maxChangeBips.Set(maxChangeBips); err != nil {
+		return err
+	}
+	return g.window.Set(window)
+}
+
+// SetBypassExpiry opens the bypass until the given timestamp, or closes it if that's passed.
+func (g *Guardrails) SetBypassExpiry(timestamp uint64) error {
+	return g.bypassExpiry.Set(timestamp)
+}
+
+// CheckChange checks changing the parameter from current to next at time now is within its guardrail. A window
+// starts with the first change after the last one ended, bounding changes relative to the value at its start.
+// While the bypass is open any change is allowed, and starts a new window at the new value. A window starting
+// at zero has no relative bound, so the first change away from zero is allowed the same way.
+func (g *Guardrails) CheckChange(param Parameter, current, next *big.Int, now uint64) error {
+	maxChange, window, bypassExpiry, err := g.Config()
+	if err != nil || maxChange == 0 {
+		return err
+	}
+	baseline := g.baselines.OpenSubStorage([]byte{byte(param)})
+	start := baseline.OpenStorageBackedUint64(baselineStartOffset)
+	value := baseline.OpenStorageBackedBigInt(baselineValueOffset)
+	restart := func() error {
+		if err := start.Set(now); err != nil {
+			return err
+		}
+		return value.SetChecked(next)
+	}
+	if now < bypassExpiry {
+		return restart()
+	}
+	windowStart, err := start.Get()
+	if err != nil {
+		return err
+	}
+	if windowStart == 0 || now >= arbmath.SaturatingUAdd(windowStart, window) {
+		if err := start.Set(now); err != nil {
+			return err
+		}
+		if err := value.SetChecked(current); err != nil {
+			return err
+		}
+	}
+	base, err := value.Get()
+	if err != nil {
+		return err
+	}
+	if base.Sign() == 0 {
+		return restart()
+	}
+	limit := arbmath.BigMulByBips(arbmath.BigAbs(base), arbmath.Bips(maxChange))
+	if arbmath.BigGreaterThan(arbmath.BigAbs(arbmath.BigSub(next, base)), limit) {
+		return fmt.Errorf("%w: can move at most %v bips from %v within %v seconds, but tried setting %v", ErrExceedsGuardrail, maxChange, base, window, next)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package guardrails
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/offchainlabs/nitro/arbos/burn"
+	"github.com/offchainlabs/nitro/arbos/storage"
+)
+
+func TestGuardrails(t *testing.T) {
+	g := Open(storage.NewMemoryBacked(burn.NewSystemBurner(nil, false)))
+	check := func(param Parameter, current, next int64, now uint64, allowed bool) {
+		t.Helper()
+		err := g.CheckChange(param, big.NewInt(current), big.NewInt(next), now)
+		if allowed && err != nil {
+			t.Fatalf("changing %v to %v at %v: %v", current, next, now, err)
+		}
+		if !allowed && !errors.Is(err, ErrExceedsGuardrail) {
+			t.Fatalf("changing %v to %v at %v wasn't rejected, got %v", current, next, now, err)
+		}
+	}
+
+	// disabled by default
+	check(SpeedLimit, 100, 1_000_000, 10, true)
+
+	// 10% per hour
+	if err := g.SetConfig(1000, 3600, 10); err != nil {
+		t.Fatal(err)
+	}
+	check(SpeedLimit, 100, 110, 100, true)
+	// bounded relative to the window's start, not the last change
+	check(SpeedLimit, 110, 115, 200, false)
+	check(SpeedLimit, 110, 90, 300, true)
+	// other parameters have their own windows, and negative values are bounded by their magnitude
+	check(PerBatchGasCharge, -100, -109, 300, true)
+	check(PerBatchGasCharge, -100, -120, 300, false)
+	// the next window starts from the value then
+	check(SpeedLimit, 90, 99, 3700, true)
+	check(SpeedLimit, 99, 100, 3800, false)
+
+	// loosening needs the bypass
+	if err := g.SetConfig(0, 3600, 3800); !errors.Is(err, ErrExceedsGuardrail) {
+		t.Fatal("disabled guardrails without the bypass, got", err)
+	}
+	if err := g.SetConfig(500, 7200, 3800); err != nil {
+		t.Fatal("failed tightening guardrails", err)
+	}
+	if err := g.SetBypassExpiry(4000); err != nil {
+		t.Fatal(err)
+	}
+	check(SpeedLimit, 90, 1000, 3900, true)
+	// after the bypass, changes are bounded relative to the value set through it
+	check(SpeedLimit, 1000, 1040, 4100, true)
+	check(SpeedLimit, 1040, 1100, 4200, false)
+
+	// a parameter at zero can be moved off it, and is then bounded relative to its new value
+	check(L1PricingRewardRate, 0, 50, 4200, true)
+	check(L1PricingRewardRate, 50, 52, 4300, true)
+	check(L1PricingRewardRate, 52, 53, 4400, false)
+	check(L1PricingRewardRate, 52, 0, 4400, false)
+}
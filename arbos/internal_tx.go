@@ -87,6 +87,9 @@ func ApplyInternalTxUpdate(tx *types.ArbitrumInternalTx, state *arbosState.Arbos
 		_ = state.RetryableState().TryToReapOneRetryable(currentTime, evm, util.TracingDuringEVM)
 
 		state.L2PricingState().UpdatePricingModel(l2BaseFee, timePassed, false)
+		if state.ArbOSVersion() >= 32 {
+			state.Restrict(state.L2PricingState().StepGasLimitRamp(currentTime))
+		}
 
 		return state.UpgradeArbosVersionIfNecessary(currentTime, evm.StateDB, evm.ChainConfig())
 	case InternalTxBatchPostingReportMethodID:
@@ -106,6 +109,10 @@ func ApplyInternalTxUpdate(tx *types.ArbitrumInternalTx, state *arbosState.Arbos
 		}
 		gasSpent := arbmath.SaturatingAdd(perBatchGas, arbmath.SaturatingCast[int64](batchDataGas))
 		weiSpent := arbmath.BigMulByUint(l1BaseFeeWei, arbmath.SaturatingUCast[uint64](gasSpent))
+		feeToken, err := state.FeeToken()
+		if err != nil {
+			log.Warn("L1Pricing FeeToken failed", "err", err)
+		}
 		err = l1p.UpdateForBatchPosterSpending(
 			evm.StateDB,
 			evm,
@@ -115,6 +122,7 @@ func ApplyInternalTxUpdate(tx *types.ArbitrumInternalTx, state *arbosState.Arbos
 			batchPosterAddress,
 			weiSpent,
 			l1BaseFeeWei,
+			feeToken,
 			util.TracingDuringEVM,
 		)
 		if err != nil {
@@ -140,6 +140,32 @@ func (bps *BatchPosterState) SetPayTo(addr common.Address) error {
 	return bps.payTo.Set(addr)
 }
 
+// RescaleFundsDue converts the funds due to every poster from a fee token with fromDecimals decimals to one
+// with toDecimals, rounding up so posters aren't shortchanged.
+func (bpt *BatchPostersTable) RescaleFundsDue(fromDecimals, toDecimals uint64) error {
+	allPosters, err := bpt.AllPosters(math.MaxUint64)
+	if err != nil {
+		return err
+	}
+	for _, posterAddr := range allPosters {
+		poster, err := bpt.OpenPoster(posterAddr, false)
+		if err != nil {
+			return err
+		}
+		due, err := poster.FundsDue()
+		if err != nil {
+			return err
+		}
+		if due.Sign() == 0 {
+			continue
+		}
+		if err := poster.SetFundsDue(arbmath.ScaleDecimalsUp(due, fromDecimals, toDecimals)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type FundsDueItem struct {
 	dueTo   common.Address
 	balance *big.Int
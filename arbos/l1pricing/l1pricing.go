@@ -53,7 +53,8 @@ var (
 	BatchPosterPayToAddress  = BatchPosterAddress
 	L1PricerFundsPoolAddress = common.HexToAddress("0xA4B00000000000000000000000000000000000f6")
 
-	ErrInvalidTime = errors.New("invalid timestamp")
+	ErrInvalidTime                  = errors.New("invalid timestamp")
+	ErrSurplusCorrectionOutOfBounds = errors.New("surplus correction out of bounds")
 )
 
 const (
@@ -212,6 +213,39 @@ func (ps *L1PricingState) GetL1PricingSurplus() (*big.Int, error) {
 	return arbmath.BigSub(haveFunds, needFunds), nil
 }
 
+// CorrectSurplus adjusts the fees recognized as available by delta, correcting a surplus (with a negative delta) or
+// deficit (with a positive delta) faster than the pricing model would. A correction can bring the surplus to zero
+// but not past it, and can't recognize more than the pool's balance. The last surplus moves by delta too, so the
+// next price update doesn't mistake the correction for a change in costs.
+func (ps *L1PricingState) CorrectSurplus(delta, poolBalance *big.Int, arbosVersion uint64) error {
+	surplus, err := ps.GetL1PricingSurplus()
+	if err != nil {
+		return err
+	}
+	if delta.Sign() == 0 {
+		return nil
+	}
+	if delta.Sign() == surplus.Sign() || arbmath.BigGreaterThan(arbmath.BigAbs(delta), arbmath.BigAbs(surplus)) {
+		return fmt.Errorf("%w: correcting surplus %v by %v would overshoot", ErrSurplusCorrectionOutOfBounds, surplus, delta)
+	}
+	available, err := ps.L1FeesAvailable()
+	if err != nil {
+		return err
+	}
+	corrected := arbmath.BigAdd(available, delta)
+	if corrected.Sign() < 0 || arbmath.BigGreaterThan(corrected, poolBalance) {
+		return fmt.Errorf("%w: pool balance %v can't back %v available fees", ErrSurplusCorrectionOutOfBounds, poolBalance, corrected)
+	}
+	if err := ps.SetL1FeesAvailable(corrected); err != nil {
+		return err
+	}
+	lastSurplus, err := ps.LastSurplus()
+	if err != nil {
+		return err
+	}
+	return ps.SetLastSurplus(arbmath.BigAdd(lastSurplus, delta), arbosVersion)
+}
+
 func (ps *L1PricingState) LastSurplus() (*big.Int, error) {
 	return ps.lastSurplus.Get()
 }
@@ -299,7 +333,8 @@ func (ps *L1PricingState) TransferFromL1FeesAvailable(
 	return updated, nil
 }
 
-// UpdateForBatchPosterSpending updates the pricing model based on a payment by a batch poster
+// UpdateForBatchPosterSpending updates the pricing model based on a payment by a batch poster.
+// From ArbOS 32, the wei spent is converted to the fee token at its exchange rate before it's owed to the poster.
 func (ps *L1PricingState) UpdateForBatchPosterSpending(
 	statedb vm.StateDB,
 	evm *vm.EVM,
@@ -308,6 +343,7 @@ func (ps *L1PricingState) UpdateForBatchPosterSpending(
 	batchPoster common.Address,
 	weiSpent *big.Int,
 	l1Basefee *big.Int,
+	feeToken am.FeeToken,
 	scenario util.TracingScenario,
 ) error {
 	if arbosVersion < 10 {
@@ -377,6 +413,10 @@ func (ps *L1PricingState) UpdateForBatchPosterSpending(
 			}
 		}
 	}
+	if arbosVersion >= 32 && !feeToken.IsEther() {
+		// round up so posters aren't shortchanged by the conversion
+		weiSpent = feeToken.FromWei(weiSpent, true)
+	}
 
 	dueToPoster, err := posterState.FundsDue()
 	if err != nil {
@@ -4,6 +4,7 @@
 package l1pricing
 
 import (
+	"errors"
 	"math/big"
 	"testing"
 
@@ -32,3 +33,41 @@ func TestL1PriceUpdate(t *testing.T) {
 		Fail(t)
 	}
 }
+
+func TestCorrectSurplus(t *testing.T) {
+	sto := storage.NewMemoryBacked(burn.NewSystemBurner(nil, false))
+	Require(t, InitializeL1PricingState(sto, common.Address{}, big.NewInt(params.GWei)))
+	ps := OpenL1PricingState(sto)
+	version := params.ArbosVersion_Stylus
+	Require(t, ps.SetL1FeesAvailable(big.NewInt(1000)))
+	pool := big.NewInt(1000)
+
+	expect := func(surplus, lastSurplus int64) {
+		t.Helper()
+		have, err := ps.GetL1PricingSurplus()
+		Require(t, err)
+		last, err := ps.LastSurplus()
+		Require(t, err)
+		if have.Int64() != surplus || last.Int64() != lastSurplus {
+			Fail(t, "unexpected surplus", have, "and last surplus", last)
+		}
+	}
+	Require(t, ps.CorrectSurplus(big.NewInt(-400), pool, version))
+	expect(600, -400)
+	// can't move away from zero, or past it
+	if err := ps.CorrectSurplus(big.NewInt(100), pool, version); !errors.Is(err, ErrSurplusCorrectionOutOfBounds) {
+		Fail(t, "increased a surplus, got", err)
+	}
+	if err := ps.CorrectSurplus(big.NewInt(-700), pool, version); !errors.Is(err, ErrSurplusCorrectionOutOfBounds) {
+		Fail(t, "overshot a surplus, got", err)
+	}
+
+	// a deficit can only be corrected with funds in the pool
+	Require(t, ps.SetFundsDueForRewards(big.NewInt(2000)))
+	expect(-1400, -400)
+	if err := ps.CorrectSurplus(big.NewInt(500), pool, version); !errors.Is(err, ErrSurplusCorrectionOutOfBounds) {
+		Fail(t, "recognized more than the pool's balance, got", err)
+	}
+	Require(t, ps.CorrectSurplus(big.NewInt(500), big.NewInt(2000), version))
+	expect(-900, 100)
+}
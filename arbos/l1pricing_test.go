@@ -184,7 +184,7 @@ func _testL1PricingFundsDue(t *testing.T, testParams *l1PricingTest, expectedRes
 	version := arbosSt.ArbOSVersion()
 	scenario := util.TracingDuringEVM
 	err = l1p.UpdateForBatchPosterSpending(
-		evm.StateDB, evm, version, 1, 3, firstPoster, arbmath.UintToBig(testParams.fundsSpent), arbmath.UintToBig(testParams.l1BasefeeGwei*params.GWei), scenario,
+		evm.StateDB, evm, version, 1, 3, firstPoster, arbmath.UintToBig(testParams.fundsSpent), arbmath.UintToBig(testParams.l1BasefeeGwei*params.GWei), arbmath.EtherFeeToken, scenario,
 	)
 	Require(t, err)
 	rewardRecipientBalance := evm.StateDB.GetBalance(rewardAddress)
@@ -232,12 +232,12 @@ func TestUpdateTimeUpgradeBehavior(t *testing.T) {
 	}
 	stateCheck(t, statedb, false, "uh oh, nothing should have happened", func() {
 		Require(t, l1p.UpdateForBatchPosterSpending(
-			evm.StateDB, evm, 1, 1, 1, poster, common.Big1, amount, util.TracingDuringEVM,
+			evm.StateDB, evm, 1, 1, 1, poster, common.Big1, amount, arbmath.EtherFeeToken, util.TracingDuringEVM,
 		))
 	})
 
 	Require(t, l1p.UpdateForBatchPosterSpending(
-		evm.StateDB, evm, 3, 1, 1, poster, common.Big1, amount, util.TracingDuringEVM,
+		evm.StateDB, evm, 3, 1, 1, poster, common.Big1, amount, arbmath.EtherFeeToken, util.TracingDuringEVM,
 	))
 }
 
@@ -284,6 +284,7 @@ func _testL1PriceEquilibration(t *testing.T, initialL1BasefeeEstimate *big.Int,
 			bpAddr,
 			arbmath.BigMulByUint(equilibriumL1BasefeeEstimate, unitsToAdd),
 			equilibriumL1BasefeeEstimate,
+			arbmath.EtherFeeToken,
 			util.TracingBeforeEVM,
 		)
 		Require(t, err)
@@ -0,0 +1,96 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package l2pricing
+
+import (
+	"errors"
+
+	"github.com/offchainlabs/nitro/arbos/storage"
+	"github.com/offchainlabs/nitro/util/arbmath"
+)
+
+// gasLimitRamp moves the per block gas limit toward a target by a fraction of its value each period, so capacity
+// can be changed gradually rather than in one step. A target of 0 means no ramp is scheduled.
+type gasLimitRamp struct {
+	target   storage.StorageBackedUint64
+	stepBips storage.StorageBackedUint64 // how much of the limit to move it by each step
+	period   storage.StorageBackedUint64 // seconds between steps
+	lastStep storage.StorageBackedUint64 // timestamp of the last step, or of scheduling the ramp
+}
+
+var ErrInvalidGasLimitRamp = errors.New("invalid gas limit ramp")
+
+// ScheduleGasLimitRamp starts moving the per block gas limit toward target by stepBips of its value every period
+// seconds, with the first step a period from now. Setting the limit directly doesn't cancel the ramp, which carries
+// on from the new value.
+func (ps *L2PricingState) ScheduleGasLimitRamp(target, stepBips, period, now uint64) error {
+	if target == 0 || stepBips == 0 || stepBips > uint64(arbmath.OneInBips) || period == 0 {
+		return ErrInvalidGasLimitRamp
+	}
+	ramp := &ps.gasLimitRamp
+	if err := ramp.target.Set(target); err != nil {
+		return err
+	}
+	if err := ramp.stepBips.Set(stepBips); err != nil {
+		return err
+	}
+	if err := ramp.period.Set(period); err != nil {
+		return err
+	}
+	return ramp.lastStep.Set(now)
+}
+
+// CancelGasLimitRamp stops any scheduled ramp, leaving the per block gas limit where it is.
+func (ps *L2PricingState) CancelGasLimitRamp() error {
+	return ps.gasLimitRamp.target.Set(0)
+}
+
+// GasLimitRamp returns the ramp's target, step in basis points, period in seconds, and the timestamp of its next
+// step, with a target of 0 if none is scheduled.
+func (ps *L2PricingState) GasLimitRamp() (uint64, uint64, uint64, uint64, error) {
+	ramp := &ps.gasLimitRamp
+	target, err := ramp.target.Get()
+	if err != nil || target == 0 {
+		return 0, 0, 0, 0, err
+	}
+	stepBips, err := ramp.stepBips.Get()
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	period, err := ramp.period.Get()
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	lastStep, err := ramp.lastStep.Get()
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	return target, stepBips, period, arbmath.SaturatingUAdd(lastStep, period), nil
+}
+
+// StepGasLimitRamp moves the per block gas limit one step toward the ramp's target if a step is due, ending the
+// ramp once the target is reached. A chain that was idle for several periods takes a single step, not several.
+func (ps *L2PricingState) StepGasLimitRamp(now uint64) error {
+	target, stepBips, _, nextStep, err := ps.GasLimitRamp()
+	if err != nil || target == 0 || now < nextStep {
+		return err
+	}
+	limit, err := ps.PerBlockGasLimit()
+	if err != nil {
+		return err
+	}
+	step := arbmath.MaxInt(arbmath.UintMulByBips(limit, arbmath.Bips(stepBips)), 1)
+	if limit < target {
+		limit = arbmath.MinInt(arbmath.SaturatingUAdd(limit, step), target)
+	} else {
+		limit = arbmath.MaxInt(arbmath.SaturatingUSub(limit, step), target)
+	}
+	if err := ps.SetMaxPerBlockGasLimit(limit); err != nil {
+		return err
+	}
+	if limit == target {
+		return ps.CancelGasLimitRamp()
+	}
+	return ps.gasLimitRamp.lastStep.Set(now)
+}
@@ -18,6 +18,7 @@ type L2PricingState struct {
 	gasBacklog          storage.StorageBackedUint64
 	pricingInertia      storage.StorageBackedUint64
 	backlogTolerance    storage.StorageBackedUint64
+	gasLimitRamp        gasLimitRamp
 }
 
 const (
@@ -28,6 +29,10 @@ const (
 	gasBacklogOffset
 	pricingInertiaOffset
 	backlogToleranceOffset
+	gasLimitRampTargetOffset
+	gasLimitRampStepBipsOffset
+	gasLimitRampPeriodOffset
+	gasLimitRampLastStepOffset
 )
 
 const GethBlockGasLimit = 1 << 50
@@ -52,6 +57,12 @@ func OpenL2PricingState(sto *storage.Storage) *L2PricingState {
 		sto.OpenStorageBackedUint64(gasBacklogOffset),
 		sto.OpenStorageBackedUint64(pricingInertiaOffset),
 		sto.OpenStorageBackedUint64(backlogToleranceOffset),
+		gasLimitRamp{
+			target:   sto.OpenStorageBackedUint64(gasLimitRampTargetOffset),
+			stepBips: sto.OpenStorageBackedUint64(gasLimitRampStepBipsOffset),
+			period:   sto.OpenStorageBackedUint64(gasLimitRampPeriodOffset),
+			lastStep: sto.OpenStorageBackedUint64(gasLimitRampLastStepOffset),
+		},
 	}
 }
 
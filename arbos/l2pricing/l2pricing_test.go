@@ -114,3 +114,45 @@ func Fail(t *testing.T, printables ...interface{}) {
 	t.Helper()
 	testhelpers.FailImpl(t, printables...)
 }
+
+func TestGasLimitRamp(t *testing.T) {
+	pricing := PricingForTest(t)
+	Require(t, pricing.SetMaxPerBlockGasLimit(1000))
+	if err := pricing.ScheduleGasLimitRamp(1200, 0, 60, 0); err == nil {
+		Fail(t, "scheduled a ramp without a step")
+	}
+	Require(t, pricing.ScheduleGasLimitRamp(1200, 500, 60, 100))
+
+	step := func(now uint64, expected uint64) {
+		t.Helper()
+		Require(t, pricing.StepGasLimitRamp(now))
+		limit, err := pricing.PerBlockGasLimit()
+		Require(t, err)
+		if limit != expected {
+			Fail(t, "unexpected gas limit at", now, limit, expected)
+		}
+	}
+	step(159, 1000)
+	step(160, 1050)
+	step(161, 1050)
+	// an idle chain takes a single step
+	step(1000, 1102)
+	step(1060, 1157)
+	target, _, _, nextStep, err := pricing.GasLimitRamp()
+	Require(t, err)
+	if target != 1200 || nextStep != 1120 {
+		Fail(t, "unexpected ramp", target, nextStep)
+	}
+	step(1120, 1200)
+	target, _, _, _, err = pricing.GasLimitRamp()
+	Require(t, err)
+	if target != 0 {
+		Fail(t, "ramp didn't end at its target")
+	}
+	step(2000, 1200)
+
+	// ramping down
+	Require(t, pricing.ScheduleGasLimitRamp(1000, 1000, 10, 2000))
+	step(2010, 1080)
+	step(2020, 1000)
+}
@@ -30,6 +30,10 @@ type Programs struct {
 	cacheManagers  *addressSet.AddressSet
 }
 
+// Program is the metadata of an activated program, stored in a single slot keyed by the program's codehash.
+// The slot holds, in order, the version (2 bytes), init cost (2), cached cost (2), footprint (2),
+// activation hour (3), asm size estimate in kilobytes (3), and whether the program is cached (1).
+// The remaining bytes are zero, leaving room for later fields without migrating existing entries.
 type Program struct {
 	version       uint16
 	initCost      uint16
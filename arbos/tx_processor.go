@@ -47,6 +47,7 @@ type TxProcessor struct {
 	evm              *vm.EVM
 	CurrentRetryable *common.Hash
 	CurrentRefundTo  *common.Address
+	gasFreeLoan      *big.Int // lent to the sender of a gas-free tx in StartTxHook, taken back in EndTxHook
 
 	// Caches for the latest L1 block number and hash,
 	// for the NUMBER and BLOCKHASH opcodes.
@@ -397,6 +398,16 @@ func (p *TxProcessor) StartTxHook() (endTxNow bool, gasUsed uint64, err error, r
 		refundTo := tx.RefundTo
 		p.CurrentRetryable = &ticketId
 		p.CurrentRefundTo = &refundTo
+		return false, 0, nil, nil
+	}
+
+	loan, err := gasFreeLoan(p.state, p.msg, p.evm.Context.BlockNumber.Uint64())
+	if err != nil {
+		return true, 0, err, nil
+	}
+	if loan != nil {
+		util.MintBalance(&p.msg.From, loan, evm, util.TracingBeforeEVM, "gasFreeLoan")
+		p.gasFreeLoan = loan
 	}
 	return false, 0, nil, nil
 }
@@ -429,6 +440,13 @@ func (p *TxProcessor) GasChargingHook(gasRemaining *uint64) (common.Address, err
 
 	var gasNeededToStartEVM uint64
 	tipReceipient, _ := p.state.NetworkFeeAccount()
+
+	if p.msg.TxRunMode != core.MessageEthcallMode {
+		// Enforced here rather than at the sequencer so that force-included transactions are covered too
+		if err := checkAllowlists(p.state, p.msg.From, p.msg.To); err != nil {
+			return tipReceipient, err
+		}
+	}
 	var basefee *big.Int
 	if p.evm.Context.BaseFeeInBlock != nil {
 		basefee = p.evm.Context.BaseFeeInBlock
@@ -467,6 +485,16 @@ func (p *TxProcessor) GasChargingHook(gasRemaining *uint64) (common.Address, err
 		// the user couldn't pay for call data, so give up
 		return tipReceipient, core.ErrIntrinsicGas
 	}
+	if p.gasFreeLoan != nil {
+		// the loan only covers the L2 fee, so the sender's own funds must cover the rest
+		balance := p.evm.StateDB.GetBalance(p.msg.From).ToBig()
+		bought := arbmath.BigMulByUint(p.msg.GasPrice, p.msg.GasLimit)
+		own := arbmath.BigSub(arbmath.BigAdd(balance, bought), p.gasFreeLoan)
+		needed := arbmath.BigAdd(p.PosterFee, p.msg.Value)
+		if arbmath.BigLessThan(own, needed) {
+			return tipReceipient, fmt.Errorf("%w: address %v has %v but needs %v for the L1 data and value of its gas-free transaction", core.ErrInsufficientFunds, p.msg.From, own, needed)
+		}
+	}
 	*gasRemaining -= gasNeededToStartEVM
 
 	if p.msg.TxRunMode != core.MessageEthcallMode {
@@ -481,6 +509,41 @@ func (p *TxProcessor) GasChargingHook(gasRemaining *uint64) (common.Address, err
 	return tipReceipient, nil
 }
 
+var ErrSenderNotAllowed = errors.New("sender is not on the chain's allowlist")
+var ErrDeployerNotAllowed = errors.New("sender is not allowed to deploy contracts")
+
+// checkAllowlists enforces the chain owner's sender and deployer allowlists on a transaction.
+// Chain owners are always allowed so that they can't lock themselves out.
+func checkAllowlists(state *arbosState.ArbosState, from common.Address, to *common.Address) error {
+	mode, err := state.AllowlistMode()
+	if err != nil || mode == 0 {
+		return err
+	}
+	isOwner, err := state.ChainOwners().IsMember(from)
+	if err != nil || isOwner {
+		return err
+	}
+	if mode&arbosState.AllowlistSenders != 0 {
+		allowed, err := state.AllowedSenders().IsMember(from)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return ErrSenderNotAllowed
+		}
+	}
+	if to == nil && mode&arbosState.AllowlistDeployers != 0 {
+		allowed, err := state.AllowedDeployers().IsMember(from)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return ErrDeployerNotAllowed
+		}
+	}
+	return nil
+}
+
 func (p *TxProcessor) RunMode() core.MessageRunMode {
 	return p.msg.TxRunMode
 }
@@ -613,7 +676,20 @@ func (p *TxProcessor) EndTxHook(gasLeft uint64, success bool) {
 	}
 
 	purpose := "feeCollection"
-	if p.state.ArbOSVersion() > 4 {
+	gasFree := p.gasFree(arbmath.SaturatingUSub(gasUsed, p.posterGas))
+	if gasFree {
+		// the sender gets the L2 fee back, but still pays for its L1 data
+		util.MintBalance(&p.msg.From, computeCost, p.evm, scenario, "gasFreeRefund")
+		computeCost = big.NewInt(0)
+	}
+	if p.gasFreeLoan != nil {
+		// the budget covered the whole gas limit when the loan was made, so the tx was gas-free and the sender
+		// was refunded what the loan paid for
+		if err := util.BurnBalance(&p.msg.From, p.gasFreeLoan, p.evm, scenario, "gasFreeLoan"); err != nil {
+			log.Error("failed to take back gas-free loan", "sender", p.msg.From, "loan", p.gasFreeLoan, "gasFree", gasFree, "err", err)
+		}
+	}
+	if p.state.ArbOSVersion() > 4 && !gasFree {
 		infraFeeAccount, err := p.state.InfraFeeAccount()
 		p.state.Restrict(err)
 		if infraFeeAccount != (common.Address{}) {
@@ -659,6 +735,51 @@ func (p *TxProcessor) EndTxHook(gasLeft uint64, success bool) {
 	}
 }
 
+// gasFreeDesignated checks whether the chain owner designated the message's (sender, target) pair as gas-free.
+func gasFreeDesignated(state *arbosState.ArbosState, msg *core.Message) (bool, error) {
+	if state.ArbOSVersion() < 32 || msg.To == nil {
+		return false, nil
+	}
+	return state.IsGasFreePair(msg.From, *msg.To)
+}
+
+// gasFreeLoan returns what to lend the sender of a gas-free transaction so it can buy its gas up front, which
+// is nil unless the block's gas-free budget covers the whole gas limit. The sender then only needs its own funds
+// for what it actually pays: the L1 data cost and the value sent.
+func gasFreeLoan(state *arbosState.ArbosState, msg *core.Message, blockNumber uint64) (*big.Int, error) {
+	designated, err := gasFreeDesignated(state, msg)
+	if err != nil || !designated {
+		return nil, err
+	}
+	left, err := state.GasFreeBudgetLeft(blockNumber)
+	if err != nil || left < msg.GasLimit {
+		return nil, err
+	}
+	loan := arbmath.BigMulByUint(msg.GasFeeCap, msg.GasLimit)
+	if loan.Sign() == 0 {
+		return nil, nil
+	}
+	return loan, nil
+}
+
+// gasFree checks whether the chain owner designated this transaction's (sender, target) pair as gas-free,
+// using the block's gas-free budget if so. Once the budget is exhausted, designated transactions pay as usual.
+func (p *TxProcessor) gasFree(computeGas uint64) bool {
+	designated, err := gasFreeDesignated(p.state, p.msg)
+	if err != nil || !designated {
+		return false
+	}
+	blockNumber := p.evm.Context.BlockNumber.Uint64()
+	consumed, err := p.state.ConsumeGasFreeBudget(blockNumber, computeGas)
+	p.state.Restrict(err)
+	if !consumed {
+		if err := EmitGasFreeBudgetExhaustedEvent(p.evm, blockNumber, p.msg.From, *p.msg.To); err != nil {
+			log.Error("failed to emit GasFreeBudgetExhausted event", "err", err)
+		}
+	}
+	return consumed
+}
+
 func (p *TxProcessor) ScheduledTxes() types.Transactions {
 	scheduled := types.Transactions{}
 	time := p.evm.Context.Time
@@ -88,8 +88,12 @@ const BlobHashesHeaderFlag byte = L1AuthenticatedMessageHeaderFlag | 0x10 // 0x5
 // BrotliMessageHeaderByte indicates that the message is brotli-compressed.
 const BrotliMessageHeaderByte byte = 0
 
+// ZstdMessageHeaderByte indicates that the message is zstd-compressed.
+// Its bit is reserved for it alone, so a header byte setting it along with any other bit is invalid.
+const ZstdMessageHeaderByte byte = 0x01
+
 // KnownHeaderBits is all header bits with known meaning to this nitro version
-const KnownHeaderBits byte = DASMessageHeaderFlag | TreeDASMessageHeaderFlag | L1AuthenticatedMessageHeaderFlag | ZeroheavyMessageHeaderFlag | BlobHashesHeaderFlag | BrotliMessageHeaderByte
+const KnownHeaderBits byte = DASMessageHeaderFlag | TreeDASMessageHeaderFlag | L1AuthenticatedMessageHeaderFlag | ZeroheavyMessageHeaderFlag | BlobHashesHeaderFlag | BrotliMessageHeaderByte | ZstdMessageHeaderByte
 
 // hasBits returns true if `checking` has all `bits`
 func hasBits(checking byte, bits byte) bool {
@@ -120,6 +124,15 @@ func IsBrotliMessageHeaderByte(b uint8) bool {
 	return b == BrotliMessageHeaderByte
 }
 
+func IsZstdMessageHeaderByte(b uint8) bool {
+	return b == ZstdMessageHeaderByte
+}
+
+// HasValidZstdBit returns false if the header byte sets the zstd bit along with another bit
+func HasValidZstdBit(b uint8) bool {
+	return !hasBits(b, ZstdMessageHeaderByte) || b == ZstdMessageHeaderByte
+}
+
 // IsKnownHeaderByte returns true if the supplied header byte has only known bits
 func IsKnownHeaderByte(b uint8) bool {
 	return b&^KnownHeaderBits == 0
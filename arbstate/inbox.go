@@ -70,6 +70,12 @@ func parseSequencerMessage(ctx context.Context, batchNum uint64, batchBlockHash
 	if len(payload) > 0 && daprovider.IsL1AuthenticatedMessageHeaderByte(payload[0]) && !daprovider.IsKnownHeaderByte(payload[0]) {
 		return nil, fmt.Errorf("%w: batch has unsupported authenticated header byte 0x%02x", arbosState.ErrFatalNodeOutOfDate, payload[0])
 	}
+	// The zstd bit marks the compression of the payload itself, so it can't be combined with the flags of
+	// the data availability headers, which would otherwise ignore it.
+	if len(payload) > 0 && !daprovider.HasValidZstdBit(payload[0]) {
+		log.Warn("sequencer message header byte sets the zstd bit along with other flags", "firstByte", payload[0])
+		return parsedMsg, nil
+	}
 
 	// Stage 1: Extract the payload from any data availability header.
 	// It's important that multiple DAS strategies can't both be invoked in the same batch,
@@ -124,9 +130,15 @@ func parseSequencerMessage(ctx context.Context, batchNum uint64, batchBlockHash
 		payload = pl
 	}
 
-	// Stage 3: Decompress the brotli payload and fill the parsedMsg.segments list.
-	if len(payload) > 0 && daprovider.IsBrotliMessageHeaderByte(payload[0]) {
-		decompressed, err := arbcompress.Decompress(payload[1:], MaxDecompressedLen)
+	// Stage 3: Decompress the brotli or zstd payload and fill the parsedMsg.segments list.
+	if len(payload) > 0 && (daprovider.IsBrotliMessageHeaderByte(payload[0]) || daprovider.IsZstdMessageHeaderByte(payload[0])) {
+		var decompressed []byte
+		var err error
+		if daprovider.IsZstdMessageHeaderByte(payload[0]) {
+			decompressed, err = arbcompress.DecompressZstd(payload[1:], MaxDecompressedLen)
+		} else {
+			decompressed, err = arbcompress.Decompress(payload[1:], MaxDecompressedLen)
+		}
 		if err == nil {
 			reader := bytes.NewReader(decompressed)
 			stream := rlp.NewStream(reader, uint64(MaxDecompressedLen))
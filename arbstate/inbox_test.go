@@ -0,0 +1,51 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbstate
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/offchainlabs/nitro/arbstate/daprovider"
+)
+
+func TestParseSequencerMessageZstdHeaderBit(t *testing.T) {
+	var segments bytes.Buffer
+	for _, segment := range [][]byte{{1, 2, 3}, {4, 5}} {
+		if err := rlp.Encode(&segments, segment); err != nil {
+			t.Fatal(err)
+		}
+	}
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed := encoder.EncodeAll(segments.Bytes(), nil)
+
+	for _, header := range []byte{
+		daprovider.ZstdMessageHeaderByte,
+		daprovider.ZstdMessageHeaderByte | daprovider.DASMessageHeaderFlag,
+		daprovider.ZstdMessageHeaderByte | daprovider.BlobHashesHeaderFlag,
+		daprovider.ZstdMessageHeaderByte | daprovider.ZeroheavyMessageHeaderFlag,
+	} {
+		batch := append(append(make([]byte, 40), header), compressed...)
+		parsed, err := parseSequencerMessage(context.Background(), 0, common.Hash{}, batch, nil, daprovider.KeysetValidate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := 0
+		if header == daprovider.ZstdMessageHeaderByte {
+			expected = 2
+		}
+		if len(parsed.segments) != expected {
+			t.Errorf("header byte 0x%02x: parsed %v segments, expected %v", header, len(parsed.segments), expected)
+		}
+	}
+}
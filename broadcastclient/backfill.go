@@ -0,0 +1,167 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package broadcastclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	m "github.com/offchainlabs/nitro/broadcaster/message"
+)
+
+var (
+	backfillMessagesCounter = metrics.NewRegisteredCounter("arb/feed/backfill/messages", nil)
+	backfillFailuresCounter = metrics.NewRegisteredCounter("arb/feed/backfill/failures", nil)
+)
+
+// BackfillConfig configures where a client fetches the messages missing from the feed after it reconnects,
+// such as a relay's backfill endpoint, so it needn't wait for them to be read from the parent chain.
+type BackfillConfig struct {
+	URL           string        `koanf:"url" reload:"hot"`
+	MaxMessages   uint64        `koanf:"max-messages" reload:"hot"`
+	RequestLength uint64        `koanf:"request-length" reload:"hot"`
+	Timeout       time.Duration `koanf:"timeout" reload:"hot"`
+	TotalTimeout  time.Duration `koanf:"total-timeout" reload:"hot"`
+}
+
+func BackfillConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.String(prefix+".url", DefaultBackfillConfig.URL, "URL of a feed backfill endpoint (such as a relay's http://host:port/feed/messages) to fetch the messages missing from the feed after reconnecting; disabled if empty")
+	f.Uint64(prefix+".max-messages", DefaultBackfillConfig.MaxMessages, "maximum number of missing messages to backfill after reconnecting, beyond which they're left to be read from the parent chain")
+	f.Uint64(prefix+".request-length", DefaultBackfillConfig.RequestLength, "number of messages to request at a time")
+	f.Duration(prefix+".timeout", DefaultBackfillConfig.Timeout, "timeout of each backfill request")
+	f.Duration(prefix+".total-timeout", DefaultBackfillConfig.TotalTimeout, "maximum time spent backfilling after reconnecting, during which the feed isn't read, so it must stay well below the feed server's client timeout")
+}
+
+func (c *BackfillConfig) Validate() error {
+	if c.URL == "" {
+		return nil
+	}
+	if _, err := url.Parse(c.URL); err != nil {
+		return fmt.Errorf("invalid feed backfill url: %w", err)
+	}
+	if c.RequestLength == 0 {
+		return errors.New("the feed backfill request-length must be positive")
+	}
+	if c.TotalTimeout <= 0 {
+		return errors.New("the feed backfill total-timeout must be positive")
+	}
+	return nil
+}
+
+var DefaultBackfillConfig = BackfillConfig{
+	URL:           "",
+	MaxMessages:   100_000,
+	RequestLength: 1000,
+	Timeout:       10 * time.Second,
+	TotalTimeout:  5 * time.Second,
+}
+
+// fetchBackfill requests the messages from up to (not including) until from the backfill endpoint, sending
+// apiKey if set. It stops at the first message the endpoint doesn't have, so it may return fewer messages
+// than requested.
+func fetchBackfill(ctx context.Context, config *BackfillConfig, apiKey string, from, until arbutil.MessageIndex) ([]*m.BroadcastFeedMessage, error) {
+	client := &http.Client{Timeout: config.Timeout}
+	var msgs []*m.BroadcastFeedMessage
+	for from < until {
+		count := min(uint64(until-from), config.RequestLength)
+		requestUrl, err := url.Parse(config.URL)
+		if err != nil {
+			return msgs, err
+		}
+		query := requestUrl.Query()
+		query.Set("from", strconv.FormatUint(uint64(from), 10))
+		query.Set("count", strconv.FormatUint(count, 10))
+		requestUrl.RawQuery = query.Encode()
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, requestUrl.String(), nil)
+		if err != nil {
+			return msgs, err
+		}
+		if apiKey != "" {
+			request.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+		response, err := client.Do(request)
+		if err != nil {
+			return msgs, err
+		}
+		if response.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+			response.Body.Close()
+			return msgs, fmt.Errorf("feed backfill returned status %v: %s", response.StatusCode, body)
+		}
+		var res m.BroadcastMessage
+		err = json.NewDecoder(response.Body).Decode(&res)
+		response.Body.Close()
+		if err != nil {
+			return msgs, fmt.Errorf("error decoding feed backfill response: %w", err)
+		}
+		if len(res.Messages) == 0 {
+			return msgs, nil
+		}
+		for _, msg := range res.Messages {
+			if from >= until {
+				break
+			}
+			if msg == nil || msg.SequenceNumber != from {
+				return msgs, fmt.Errorf("feed backfill returned messages out of order, expected %v", from)
+			}
+			msgs = append(msgs, msg)
+			from++
+		}
+	}
+	return msgs, nil
+}
+
+// backfill passes on the messages missing between the last one received and until, the first received after
+// reconnecting. It's run from the read loop, so it gives up after the total timeout, before the feed server
+// drops the unread connection. Messages that fail to backfill in time are left to be read from the parent chain.
+func (bc *BroadcastClient) backfill(ctx context.Context, until arbutil.MessageIndex) {
+	clientConfig := bc.config()
+	config := clientConfig.Backfill
+	from := bc.nextSeqNum
+	if config.URL == "" || from == 0 || until <= from {
+		return
+	}
+	if uint64(until-from) > config.MaxMessages {
+		log.Warn("too many feed messages missing to backfill", "url", bc.websocketUrl, "from", from, "until", until, "max", config.MaxMessages)
+		return
+	}
+	fetchCtx, cancel := context.WithTimeout(ctx, config.TotalTimeout)
+	defer cancel()
+	msgs, err := fetchBackfill(fetchCtx, &config, clientConfig.APIKey, from, until)
+	if err != nil {
+		backfillFailuresCounter.Inc(1)
+		log.Warn("error backfilling feed messages", "url", bc.websocketUrl, "from", from, "until", until, "fetched", len(msgs), "err", err)
+	}
+	for i, msg := range msgs {
+		if err := bc.isValidSignature(ctx, msg); err != nil {
+			backfillFailuresCounter.Inc(1)
+			log.Warn("invalid feed signature on backfilled message", "sequence number", msg.SequenceNumber, "err", err)
+			msgs = msgs[:i]
+			break
+		}
+	}
+	if len(msgs) == 0 {
+		return
+	}
+	if err := bc.txStreamer.AddBroadcastMessages(msgs); err != nil {
+		log.Error("Error adding backfilled feed messages", "err", err)
+		return
+	}
+	backfillMessagesCounter.Inc(int64(len(msgs)))
+	bc.nextSeqNum = msgs[len(msgs)-1].SequenceNumber + 1
+	log.Info("backfilled feed messages missing after reconnecting", "url", bc.websocketUrl, "from", from, "count", len(msgs))
+}
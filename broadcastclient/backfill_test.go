@@ -0,0 +1,55 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package broadcastclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	m "github.com/offchainlabs/nitro/broadcaster/message"
+)
+
+func TestFetchBackfill(t *testing.T) {
+	ctx := context.Background()
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		from, err := strconv.ParseUint(r.URL.Query().Get("from"), 10, 64)
+		Require(t, err)
+		count, err := strconv.ParseUint(r.URL.Query().Get("count"), 10, 64)
+		Require(t, err)
+		// the endpoint only has the messages before 50
+		var seqNums []arbutil.MessageIndex
+		for pos := from; pos < min(from+count, 50); pos++ {
+			seqNums = append(seqNums, arbutil.MessageIndex(pos))
+		}
+		Require(t, json.NewEncoder(w).Encode(&m.BroadcastMessage{Version: 1, Messages: m.CreateDummyBroadcastMessages(seqNums)}))
+	}))
+	defer server.Close()
+
+	config := DefaultBackfillConfig
+	config.URL = server.URL
+	config.RequestLength = 8
+	msgs, err := fetchBackfill(ctx, &config, "", 10, 30)
+	Require(t, err)
+	if len(msgs) != 20 || requests != 3 {
+		t.Fatalf("expected 20 messages in 3 requests, got %v messages in %v requests", len(msgs), requests)
+	}
+	for i, msg := range msgs {
+		if msg.SequenceNumber != arbutil.MessageIndex(10+i) {
+			t.Fatal("unexpected message", msg.SequenceNumber, "at", i)
+		}
+	}
+
+	msgs, err = fetchBackfill(ctx, &config, "", 45, 60)
+	Require(t, err)
+	if len(msgs) != 5 {
+		t.Fatal("expected the 5 messages the endpoint has, got", len(msgs))
+	}
+}
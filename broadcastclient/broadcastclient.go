@@ -36,6 +36,7 @@ import (
 var (
 	sourcesConnectedGauge    = metrics.NewRegisteredGauge("arb/feed/sources/connected", nil)
 	sourcesDisconnectedGauge = metrics.NewRegisteredGauge("arb/feed/sources/disconnected", nil)
+	missingBlockHashCounter  = metrics.NewRegisteredCounter("arb/feed/blockhash/missing", nil)
 )
 
 type FeedConfig struct {
@@ -44,6 +45,9 @@ type FeedConfig struct {
 }
 
 func (fc *FeedConfig) Validate() error {
+	if err := fc.Input.Validate(); err != nil {
+		return err
+	}
 	return fc.Output.Validate()
 }
 
@@ -66,17 +70,36 @@ type Config struct {
 	ReconnectMaximumBackoff time.Duration            `koanf:"reconnect-maximum-backoff" reload:"hot"`
 	RequireChainId          bool                     `koanf:"require-chain-id" reload:"hot"`
 	RequireFeedVersion      bool                     `koanf:"require-feed-version" reload:"hot"`
+	RequireBlockHash        bool                     `koanf:"require-block-hash" reload:"hot"`
 	Timeout                 time.Duration            `koanf:"timeout" reload:"hot"`
 	URL                     []string                 `koanf:"url"`
 	SecondaryURL            []string                 `koanf:"secondary-url"`
 	Verify                  signature.VerifierConfig `koanf:"verify"`
+	SigningKeys             SigningKeysConfig        `koanf:"signing-keys" reload:"hot"`
 	EnableCompression       bool                     `koanf:"enable-compression" reload:"hot"`
+	Encoding                string                   `koanf:"encoding" reload:"hot"`
+	APIKey                  string                   `koanf:"api-key" reload:"hot"`
+	Cache                   FeedCacheConfig          `koanf:"cache"`
+	Backfill                BackfillConfig           `koanf:"backfill" reload:"hot"`
 }
 
 func (c *Config) Enable() bool {
 	return len(c.URL) > 0 && c.URL[0] != ""
 }
 
+func (c *Config) Validate() error {
+	if _, err := wsbroadcastserver.ParseFeedEncoding(c.Encoding); err != nil {
+		return fmt.Errorf("invalid feed input encoding: %w", err)
+	}
+	if err := c.SigningKeys.Validate(); err != nil {
+		return err
+	}
+	if err := c.Cache.Validate(); err != nil {
+		return err
+	}
+	return c.Backfill.Validate()
+}
+
 type ConfigFetcher func() *Config
 
 func ConfigAddOptions(prefix string, f *flag.FlagSet) {
@@ -84,11 +107,17 @@ func ConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Duration(prefix+".reconnect-maximum-backoff", DefaultConfig.ReconnectMaximumBackoff, "maximum duration to wait before reconnect")
 	f.Bool(prefix+".require-chain-id", DefaultConfig.RequireChainId, "require chain id to be present on connect")
 	f.Bool(prefix+".require-feed-version", DefaultConfig.RequireFeedVersion, "require feed version to be present on connect")
+	f.Bool(prefix+".require-block-hash", DefaultConfig.RequireBlockHash, "require the feed to include block hashes in its messages, so they can be checked against the locally computed ones")
 	f.Duration(prefix+".timeout", DefaultConfig.Timeout, "duration to wait before timing out connection to sequencer feed")
 	f.StringSlice(prefix+".url", DefaultConfig.URL, "list of primary URLs of sequencer feed source")
 	f.StringSlice(prefix+".secondary-url", DefaultConfig.SecondaryURL, "list of secondary URLs of sequencer feed source. Would be started in the order they appear in the list when primary feeds fails")
 	signature.FeedVerifierConfigAddOptions(prefix+".verify", f)
+	SigningKeysConfigAddOptions(prefix+".signing-keys", f)
 	f.Bool(prefix+".enable-compression", DefaultConfig.EnableCompression, "enable per message deflate compression support")
+	FeedCacheConfigAddOptions(prefix+".cache", f)
+	BackfillConfigAddOptions(prefix+".backfill", f)
+	f.String(prefix+".api-key", DefaultConfig.APIKey, "api key to authenticate to feed servers requiring one, such as relays with authentication enabled")
+	f.String(prefix+".encoding", DefaultConfig.Encoding, "encoding to request feed messages in, \""+wsbroadcastserver.FeedEncodingJSONName+"\" or \""+wsbroadcastserver.FeedEncodingProtobufName+"\" (falls back to JSON if the server doesn't support it)")
 }
 
 var DefaultConfig = Config{
@@ -96,11 +125,17 @@ var DefaultConfig = Config{
 	ReconnectMaximumBackoff: time.Second * 64,
 	RequireChainId:          false,
 	RequireFeedVersion:      false,
+	RequireBlockHash:        false,
 	Verify:                  signature.DefultFeedVerifierConfig,
+	SigningKeys:             DefaultSigningKeysConfig,
 	URL:                     []string{},
 	SecondaryURL:            []string{},
 	Timeout:                 20 * time.Second,
 	EnableCompression:       true,
+	Encoding:                wsbroadcastserver.FeedEncodingJSONName,
+	APIKey:                  "",
+	Cache:                   DefaultFeedCacheConfig,
+	Backfill:                DefaultBackfillConfig,
 }
 
 var DefaultTestConfig = Config{
@@ -108,11 +143,17 @@ var DefaultTestConfig = Config{
 	ReconnectMaximumBackoff: 0,
 	RequireChainId:          false,
 	RequireFeedVersion:      false,
+	RequireBlockHash:        false,
 	Verify:                  signature.DefultFeedVerifierConfig,
+	SigningKeys:             DefaultSigningKeysConfig,
 	URL:                     []string{""},
 	SecondaryURL:            []string{},
 	Timeout:                 200 * time.Millisecond,
 	EnableCompression:       true,
+	Encoding:                wsbroadcastserver.FeedEncodingJSONName,
+	APIKey:                  "",
+	Cache:                   DefaultFeedCacheConfig,
+	Backfill:                DefaultBackfillConfig,
 }
 
 type TransactionStreamerInterface interface {
@@ -126,6 +167,7 @@ type BroadcastClient struct {
 	websocketUrl string
 	nextSeqNum   arbutil.MessageIndex
 	sigVerifier  *signature.Verifier
+	signingKeys  *signingKeys
 
 	chainId uint64
 
@@ -134,6 +176,8 @@ type BroadcastClient struct {
 	conn      net.Conn
 
 	retryCount atomic.Int64
+	// the feed message version advertised by the server we're connected to
+	feedMessageVersion atomic.Uint64
 
 	retrying                        bool
 	shuttingDown                    bool
@@ -147,6 +191,7 @@ var ErrIncorrectFeedServerVersion = errors.New("incorrect feed server version")
 var ErrIncorrectChainId = errors.New("incorrect chain id")
 var ErrMissingChainId = errors.New("missing chain id")
 var ErrMissingFeedServerVersion = errors.New("missing feed server version")
+var ErrMissingBlockHashes = errors.New("feed server doesn't include block hashes")
 
 func NewBroadcastClient(
 	config ConfigFetcher,
@@ -157,6 +202,7 @@ func NewBroadcastClient(
 	confirmedSequencerNumberListener chan arbutil.MessageIndex,
 	fatalErrChan chan error,
 	addrVerifier contracts.AddressVerifierInterface,
+	keyRegistry contracts.FeedKeyRegistryInterface,
 	adjustCount func(int32),
 ) (*BroadcastClient, error) {
 	sigVerifier, err := signature.NewVerifier(&config().Verify, addrVerifier)
@@ -172,6 +218,7 @@ func NewBroadcastClient(
 		confirmedSequenceNumberListener: confirmedSequencerNumberListener,
 		fatalErrChan:                    fatalErrChan,
 		sigVerifier:                     sigVerifier,
+		signingKeys:                     newSigningKeys(func() *SigningKeysConfig { return &config().SigningKeys }, keyRegistry),
 		adjustCount:                     adjustCount,
 	}, err
 }
@@ -189,7 +236,8 @@ func (bc *BroadcastClient) Start(ctxIn context.Context) {
 			if errors.Is(err, ErrMissingChainId) ||
 				errors.Is(err, ErrIncorrectChainId) ||
 				errors.Is(err, ErrMissingFeedServerVersion) ||
-				errors.Is(err, ErrIncorrectFeedServerVersion) {
+				errors.Is(err, ErrIncorrectFeedServerVersion) ||
+				errors.Is(err, ErrMissingBlockHashes) {
 				bc.fatalErrChan <- fmt.Errorf("failed connecting to server feed due to %w", err)
 				return
 			}
@@ -218,18 +266,31 @@ func (bc *BroadcastClient) connect(ctx context.Context, nextSeqNum arbutil.Messa
 		return nil, nil
 	}
 
-	header := ws.HandshakeHeaderHTTP(http.Header{
+	config := bc.config()
+	requestHeader := http.Header{
 		wsbroadcastserver.HTTPHeaderFeedClientVersion:       []string{strconv.Itoa(wsbroadcastserver.FeedClientVersion)},
 		wsbroadcastserver.HTTPHeaderRequestedSequenceNumber: []string{strconv.FormatUint(uint64(nextSeqNum), 10)},
-	})
+	}
+	requestedEncoding, err := wsbroadcastserver.ParseFeedEncoding(config.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	if requestedEncoding != wsbroadcastserver.FeedEncodingJSON {
+		requestHeader.Set(wsbroadcastserver.HTTPHeaderFeedEncoding, requestedEncoding.String())
+	}
+	if config.APIKey != "" {
+		requestHeader.Set("Authorization", "Bearer "+config.APIKey)
+	}
+	header := ws.HandshakeHeaderHTTP(requestHeader)
 
 	log.Info("connecting to arbitrum inbox message broadcaster", "url", bc.websocketUrl)
 	var foundChainId bool
 	var foundFeedServerVersion bool
 	var chainId uint64
 	var feedServerVersion uint64
+	var feedMessageVersion uint64
+	encoding := wsbroadcastserver.FeedEncodingJSON
 
-	config := bc.config()
 	var extensions []httphead.Option
 	deflateExt := wsflate.DefaultParameters.Option()
 	if config.EnableCompression {
@@ -256,6 +317,16 @@ func (bc *BroadcastClient) connect(ctx context.Context, nextSeqNum arbutil.Messa
 					)
 					return ErrIncorrectFeedServerVersion
 				}
+			} else if headerName == wsbroadcastserver.HTTPHeaderFeedMessageVersion {
+				feedMessageVersion, err = strconv.ParseUint(headerValue, 0, 64)
+				if err != nil {
+					return err
+				}
+			} else if headerName == wsbroadcastserver.HTTPHeaderFeedEncoding {
+				encoding, err = wsbroadcastserver.ParseFeedEncoding(headerValue)
+				if err != nil {
+					return err
+				}
 			} else if headerName == wsbroadcastserver.HTTPHeaderChainId {
 				foundChainId = true
 				chainId, err = strconv.ParseUint(headerValue, 0, 64)
@@ -311,6 +382,17 @@ func (bc *BroadcastClient) connect(ctx context.Context, nextSeqNum arbutil.Messa
 		}
 		return nil, ErrMissingFeedServerVersion
 	}
+	if config.RequireBlockHash && feedMessageVersion < wsbroadcastserver.FeedMessageVersion {
+		err := conn.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error closing connection when missing block hashes: %w", err)
+		}
+		return nil, ErrMissingBlockHashes
+	}
+	bc.feedMessageVersion.Store(feedMessageVersion)
+	if encoding != requestedEncoding {
+		log.Warn("feed server doesn't support the requested encoding, using JSON", "url", bc.websocketUrl, "requestedEncoding", requestedEncoding)
+	}
 
 	var earlyFrameData io.Reader
 	if br != nil {
@@ -327,7 +409,7 @@ func (bc *BroadcastClient) connect(ctx context.Context, nextSeqNum arbutil.Messa
 	bc.connMutex.Lock()
 	bc.conn = conn
 	bc.connMutex.Unlock()
-	log.Info("Feed connected", "feedServerVersion", feedServerVersion, "chainId", chainId, "requestedSeqNum", nextSeqNum)
+	log.Info("Feed connected", "feedServerVersion", feedServerVersion, "chainId", chainId, "requestedSeqNum", nextSeqNum, "encoding", encoding)
 
 	return earlyFrameData, nil
 }
@@ -384,8 +466,14 @@ func (bc *BroadcastClient) startBackgroundReader(earlyFrameData io.Reader) {
 			backoffDuration = bc.config().ReconnectInitialBackoff
 
 			if msg != nil {
-				res := m.BroadcastMessage{}
-				err = json.Unmarshal(msg, &res)
+				// the server sends protobuf encoded messages as binary frames, and JSON as text frames
+				var res *m.BroadcastMessage
+				if op == ws.OpBinary {
+					res, err = m.DecodeProto(msg)
+				} else {
+					res = &m.BroadcastMessage{}
+					err = json.Unmarshal(msg, res)
+				}
 				if err != nil {
 					log.Error("error unmarshalling message", "msg", msg, "err", err)
 					continue
@@ -406,6 +494,10 @@ func (bc *BroadcastClient) startBackgroundReader(earlyFrameData io.Reader) {
 				}
 				if res.Version == 1 {
 					if len(res.Messages) > 0 {
+						if first := res.Messages[0]; first != nil && first.SequenceNumber > bc.nextSeqNum {
+							// the server no longer had the messages we requested when reconnecting
+							bc.backfill(ctx, first.SequenceNumber)
+						}
 						for _, message := range res.Messages {
 							if message == nil {
 								log.Warn("ignoring nil feed message")
@@ -418,6 +510,16 @@ func (bc *BroadcastClient) startBackgroundReader(earlyFrameData io.Reader) {
 								bc.fatalErrChan <- fmt.Errorf("error validating feed signature %v: %w", message.SequenceNumber, err)
 								continue
 							}
+							if message.BlockHash == nil && bc.feedMessageVersion.Load() >= wsbroadcastserver.FeedMessageVersion {
+								// the transaction streamer compares the hash against its own once it executes the message,
+								// so without it a diverged node only notices at batch or validation time
+								missingBlockHashCounter.Inc(1)
+								if config.RequireBlockHash {
+									log.Error("feed message is missing its block hash", "sequence number", message.SequenceNumber)
+									bc.fatalErrChan <- fmt.Errorf("feed message %v is missing its block hash", message.SequenceNumber)
+									continue
+								}
+							}
 
 							bc.nextSeqNum = message.SequenceNumber + 1
 						}
@@ -495,5 +597,10 @@ func (bc *BroadcastClient) isValidSignature(ctx context.Context, message *m.Broa
 	if err != nil {
 		return fmt.Errorf("error getting message hash for sequence number %v: %w", message.SequenceNumber, err)
 	}
+	// messages naming a known signing key must be signed by it, others are checked against the allowed signers
+	verified, err := bc.signingKeys.verifySignedBy(ctx, message, hash)
+	if verified || err != nil {
+		return err
+	}
 	return bc.sigVerifier.VerifyHash(ctx, message.Signature, hash)
 }
@@ -65,14 +65,55 @@ func TestReceiveMessagesWithRequiredCompressionButClientDisabled(t *testing.T) {
 	testReceiveMessages(t, false, true, true, true)
 }
 
+func TestReceiveMessagesWithProtobuf(t *testing.T) {
+	t.Parallel()
+	for _, compression := range []bool{false, true} {
+		broadcasterConfig := wsbroadcastserver.DefaultTestBroadcasterConfig
+		broadcasterConfig.EnableCompression = compression
+		broadcasterConfig.EnableProtobuf = true
+		config := DefaultTestConfig
+		config.EnableCompression = compression
+		config.Encoding = wsbroadcastserver.FeedEncodingProtobufName
+		testReceiveMessagesWithConfig(t, config, broadcasterConfig, false)
+	}
+}
+
+func TestReceiveMessagesWithProtobufServerDisabled(t *testing.T) {
+	t.Parallel()
+	broadcasterConfig := wsbroadcastserver.DefaultTestBroadcasterConfig
+	broadcasterConfig.EnableProtobuf = false
+	config := DefaultTestConfig
+	config.Encoding = wsbroadcastserver.FeedEncodingProtobufName
+	testReceiveMessagesWithConfig(t, config, broadcasterConfig, false)
+}
+
+func TestReceiveMessagesWithAuth(t *testing.T) {
+	t.Parallel()
+	broadcasterConfig := wsbroadcastserver.DefaultTestBroadcasterConfig
+	broadcasterConfig.Auth.Enable = true
+	broadcasterConfig.Auth.Keys = []string{"test:secretkey:2"}
+	config := DefaultTestConfig
+	config.APIKey = "secretkey"
+	testReceiveMessagesWithConfig(t, config, broadcasterConfig, false)
+
+	config.APIKey = "wrongkey"
+	testReceiveMessagesWithConfig(t, config, broadcasterConfig, true)
+}
+
 func testReceiveMessages(t *testing.T, clientCompression bool, serverCompression bool, serverRequire bool, expectNoMessagesReceived bool) {
 	t.Helper()
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
 	broadcasterConfig := wsbroadcastserver.DefaultTestBroadcasterConfig
 	broadcasterConfig.EnableCompression = serverCompression
 	broadcasterConfig.RequireCompression = serverRequire
+	config := DefaultTestConfig
+	config.EnableCompression = clientCompression
+	testReceiveMessagesWithConfig(t, config, broadcasterConfig, expectNoMessagesReceived)
+}
+
+func testReceiveMessagesWithConfig(t *testing.T, config Config, broadcasterConfig wsbroadcastserver.BroadcasterConfig, expectNoMessagesReceived bool) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	messageCount := 1000
 	clientCount := 2
@@ -90,8 +131,6 @@ func testReceiveMessages(t *testing.T, clientCompression bool, serverCompression
 	Require(t, b.Start(ctx))
 	defer b.StopAndWait()
 
-	config := DefaultTestConfig
-	config.EnableCompression = clientCompression
 	var wg sync.WaitGroup
 	var expectedCount int
 	if expectNoMessagesReceived {
@@ -208,7 +247,7 @@ func newTestBroadcastClient(config Config, listenerAddress net.Addr, chainId uin
 	} else {
 		config.Verify.AcceptSequencer = false
 	}
-	return NewBroadcastClient(func() *Config { return &config }, fmt.Sprintf("ws://127.0.0.1:%d/", port), chainId, currentMessageCount, txStreamer, confirmedSequenceNumberListener, feedErrChan, av, func(_ int32) {})
+	return NewBroadcastClient(func() *Config { return &config }, fmt.Sprintf("ws://127.0.0.1:%d/", port), chainId, currentMessageCount, txStreamer, confirmedSequenceNumberListener, feedErrChan, av, nil, func(_ int32) {})
 }
 
 func startMakeBroadcastClient(ctx context.Context, t *testing.T, clientConfig Config, addr net.Addr, index int, expectedCount int, chainId uint64, wg *sync.WaitGroup, sequencerAddr *common.Address) {
@@ -841,3 +880,54 @@ func Require(t *testing.T, err error, printables ...interface{}) {
 	t.Helper()
 	testhelpers.RequireImpl(t, err, printables...)
 }
+
+func TestServerMultipleChains(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config := wsbroadcastserver.DefaultTestBroadcasterConfig
+	config.Ping = 1 * time.Second
+
+	privateKey, err := crypto.GenerateKey()
+	Require(t, err)
+	sequencerAddr := crypto.PubkeyToAddress(privateKey.PublicKey)
+	dataSigner := signature.DataSignerFromPrivateKey(privateKey)
+
+	chainId := uint64(8742)
+	otherChainId := uint64(8743)
+	feedErrChan := make(chan error, 10)
+	b := broadcaster.NewBroadcaster(func() *wsbroadcastserver.BroadcasterConfig { return &config }, chainId, feedErrChan, dataSigner)
+	Require(t, b.Initialize())
+	Require(t, b.Start(ctx))
+	defer b.StopAndWait()
+
+	// the other chain is served through the first chain's listener
+	other := broadcaster.NewBroadcaster(func() *wsbroadcastserver.BroadcasterConfig { return &config }, otherChainId, feedErrChan, dataSigner)
+	Require(t, other.Initialize())
+	Require(t, other.StartOn(ctx, b))
+	defer other.StopAndWait()
+
+	// messages are signed over their chain id, so a message of the wrong chain fails to verify
+	Require(t, b.BroadcastSingle(arbostypes.EmptyTestMessageWithMetadata, 0, nil))
+	Require(t, other.BroadcastSingle(arbostypes.EmptyTestMessageWithMetadata, 0, nil))
+
+	clientConfig := DefaultTestConfig
+	clientConfig.Verify.AcceptSequencer = true
+	ts := NewDummyTransactionStreamer(otherChainId, nil)
+	url := fmt.Sprintf("ws://127.0.0.1:%d%s%d", b.ListenerAddr().(*net.TCPAddr).Port, wsbroadcastserver.ChainPathPrefix, otherChainId)
+	broadcastClient, err := NewBroadcastClient(func() *Config { return &clientConfig }, url, otherChainId, 0, ts, nil, feedErrChan, contracts.NewMockAddressVerifier(sequencerAddr), nil, func(_ int32) {})
+	Require(t, err)
+	broadcastClient.Start(ctx)
+	defer broadcastClient.StopAndWait()
+
+	timer := time.NewTimer(5 * time.Second)
+	defer timer.Stop()
+	select {
+	case err := <-feedErrChan:
+		t.Fatal("unexpected feed error", err)
+	case <-ts.messageReceiver:
+	case <-timer.C:
+		t.Fatal("client of the other chain did not receive its messages")
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package broadcastclient
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	m "github.com/offchainlabs/nitro/broadcaster/message"
+	"github.com/offchainlabs/nitro/util/dbutil"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+var (
+	feedCacheWrittenCounter  = metrics.NewRegisteredCounter("arb/feed/cache/written", nil)
+	feedCacheDroppedCounter  = metrics.NewRegisteredCounter("arb/feed/cache/dropped", nil)
+	feedCacheReplayedCounter = metrics.NewRegisteredCounter("arb/feed/cache/replayed", nil)
+)
+
+const (
+	feedCachePendingMessages = 4096
+	feedCacheReplayBatch     = 1024
+)
+
+var (
+	feedCacheSlotPrefix = []byte("s")
+	feedCacheLastKey    = []byte("_last") // contains the sequence number of the last message written
+)
+
+// FeedCacheConfig configures an on-disk cache of the most recently received feed messages, which a
+// restarting node or relay replays instead of requesting them from the feed again.
+type FeedCacheConfig struct {
+	Enable      bool   `koanf:"enable"`
+	Directory   string `koanf:"directory"`
+	MaxMessages uint64 `koanf:"max-messages"`
+}
+
+func FeedCacheConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultFeedCacheConfig.Enable, "keep the most recently received feed messages on disk, to replay on restart instead of requesting them from the feed")
+	f.String(prefix+".directory", DefaultFeedCacheConfig.Directory, "directory of the feed cache database")
+	f.Uint64(prefix+".max-messages", DefaultFeedCacheConfig.MaxMessages, "the number of messages the feed cache holds, after which the oldest are overwritten")
+}
+
+func (c *FeedCacheConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.Directory == "" {
+		return errors.New("the feed cache requires a directory")
+	}
+	if c.MaxMessages == 0 {
+		return errors.New("the feed cache max-messages must be positive")
+	}
+	return nil
+}
+
+var DefaultFeedCacheConfig = FeedCacheConfig{
+	Enable:      false,
+	Directory:   "",
+	MaxMessages: 100_000,
+}
+
+// FeedCache is an on-disk ring buffer of received feed messages. Each message is stored in the slot of its
+// sequence number modulo the cache size, overwriting the message received a full cache size before it.
+// Messages are written in the background, so caching doesn't hold up passing them on.
+type FeedCache struct {
+	stopwaiter.StopWaiter
+	size    uint64
+	db      ethdb.Database
+	pending chan *m.BroadcastFeedMessage
+}
+
+func NewFeedCache(config *FeedCacheConfig) (*FeedCache, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	db, err := rawdb.NewLevelDBDatabase(config.Directory, 16, 16, "feedcache", false)
+	if err != nil {
+		return nil, fmt.Errorf("error opening the feed cache: %w", err)
+	}
+	return &FeedCache{
+		size:    config.MaxMessages,
+		db:      db,
+		pending: make(chan *m.BroadcastFeedMessage, feedCachePendingMessages),
+	}, nil
+}
+
+func (c *FeedCache) Start(ctx context.Context) {
+	c.StopWaiter.Start(ctx, c)
+	c.LaunchThread(c.writeLoop)
+}
+
+// StopAndWait writes the messages already added, then closes the database.
+func (c *FeedCache) StopAndWait() {
+	c.StopWaiter.StopAndWait()
+	if err := c.db.Close(); err != nil {
+		log.Warn("error closing the feed cache", "err", err)
+	}
+}
+
+// Add queues the message to be written, dropping it if the writer is too far behind.
+func (c *FeedCache) Add(msg *m.BroadcastFeedMessage) {
+	select {
+	case c.pending <- msg:
+	default:
+		feedCacheDroppedCounter.Inc(1)
+	}
+}
+
+func (c *FeedCache) slotKey(seqNum arbutil.MessageIndex) []byte {
+	return binary.BigEndian.AppendUint64(append([]byte{}, feedCacheSlotPrefix...), uint64(seqNum)%c.size)
+}
+
+// drain returns the message along with up to a batch of any others pending.
+func (c *FeedCache) drain(msg *m.BroadcastFeedMessage) []*m.BroadcastFeedMessage {
+	msgs := []*m.BroadcastFeedMessage{msg}
+	for len(msgs) < feedCacheReplayBatch {
+		select {
+		case msg := <-c.pending:
+			msgs = append(msgs, msg)
+		default:
+			return msgs
+		}
+	}
+	return msgs
+}
+
+func (c *FeedCache) writeLoop(ctx context.Context) {
+	for {
+		select {
+		case msg := <-c.pending:
+			if err := c.write(c.drain(msg)); err != nil {
+				log.Error("error writing to the feed cache", "err", err)
+			}
+		case <-ctx.Done():
+			// write what was already added before stopping
+			for {
+				select {
+				case msg := <-c.pending:
+					if err := c.write(c.drain(msg)); err != nil {
+						log.Error("error writing to the feed cache", "err", err)
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *FeedCache) write(msgs []*m.BroadcastFeedMessage) error {
+	batch := c.db.NewBatch()
+	for _, msg := range msgs {
+		encoded, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		value := binary.BigEndian.AppendUint64(make([]byte, 0, 8+len(encoded)), uint64(msg.SequenceNumber))
+		if err := batch.Put(c.slotKey(msg.SequenceNumber), append(value, encoded...)); err != nil {
+			return err
+		}
+	}
+	// after a feed reorg, the messages past the last one written are stale
+	last := msgs[len(msgs)-1].SequenceNumber
+	if err := batch.Put(feedCacheLastKey, binary.BigEndian.AppendUint64(nil, uint64(last))); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	feedCacheWrittenCounter.Inc(int64(len(msgs)))
+	return nil
+}
+
+// get returns the cached message with the sequence number, or nil if its slot holds a different message.
+func (c *FeedCache) get(seqNum arbutil.MessageIndex) (*m.BroadcastFeedMessage, error) {
+	value, err := c.db.Get(c.slotKey(seqNum))
+	if dbutil.IsErrNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if len(value) < 8 {
+		return nil, fmt.Errorf("invalid feed cache entry for message %v", seqNum)
+	}
+	if binary.BigEndian.Uint64(value) != uint64(seqNum) {
+		return nil, nil
+	}
+	msg := &m.BroadcastFeedMessage{}
+	if err := json.Unmarshal(value[8:], msg); err != nil {
+		return nil, fmt.Errorf("error decoding feed cache message %v: %w", seqNum, err)
+	}
+	return msg, nil
+}
+
+// Range returns the cached messages [start, end) to replay for a client resuming at the sequence number from.
+// The messages run up to the last one received, and start at from unless it's zero, in which case the
+// client has nothing to resume and starts from the oldest message cached. Without such messages, end is start.
+func (c *FeedCache) Range(from arbutil.MessageIndex) (arbutil.MessageIndex, arbutil.MessageIndex, error) {
+	data, err := c.db.Get(feedCacheLastKey)
+	if dbutil.IsErrNotFound(err) {
+		return from, from, nil
+	} else if err != nil {
+		return from, from, err
+	}
+	if len(data) != 8 {
+		return from, from, errors.New("invalid feed cache last message")
+	}
+	end := arbutil.MessageIndex(binary.BigEndian.Uint64(data)) + 1
+	if from >= end {
+		return from, from, nil
+	}
+	oldest := arbutil.MessageIndex(0)
+	if uint64(end) > c.size {
+		oldest = end - arbutil.MessageIndex(c.size)
+	}
+	start := end
+	for start > from && start > oldest {
+		msg, err := c.get(start - 1)
+		if err != nil {
+			return from, from, err
+		}
+		if msg == nil {
+			break
+		}
+		start--
+	}
+	if start > from && from != 0 {
+		// replaying would leave a gap before the cached messages
+		return from, from, nil
+	}
+	return start, end, nil
+}
+
+// Replay passes the cached messages [start, end), as returned by Range, to the callback in batches.
+func (c *FeedCache) Replay(ctx context.Context, start, end arbutil.MessageIndex, callback func([]*m.BroadcastFeedMessage) error) error {
+	for start < end {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var msgs []*m.BroadcastFeedMessage
+		for ; start < end && len(msgs) < feedCacheReplayBatch; start++ {
+			msg, err := c.get(start)
+			if err != nil {
+				return err
+			}
+			if msg == nil {
+				return fmt.Errorf("feed cache message %v overwritten during replay", start)
+			}
+			msgs = append(msgs, msg)
+		}
+		if err := callback(msgs); err != nil {
+			return err
+		}
+		feedCacheReplayedCounter.Inc(int64(len(msgs)))
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package broadcastclient
+
+import (
+	"context"
+	"testing"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	m "github.com/offchainlabs/nitro/broadcaster/message"
+)
+
+func TestFeedCache(t *testing.T) {
+	ctx := context.Background()
+	config := DefaultFeedCacheConfig
+	config.Enable = true
+	config.Directory = t.TempDir()
+	config.MaxMessages = 5
+
+	// messages are written by the time the cache is stopped, and read back once reopened
+	write := func(seqNums ...arbutil.MessageIndex) {
+		t.Helper()
+		cache, err := NewFeedCache(&config)
+		Require(t, err)
+		cache.Start(ctx)
+		for _, msg := range m.CreateDummyBroadcastMessages(seqNums) {
+			cache.Add(msg)
+		}
+		cache.StopAndWait()
+	}
+	write(10, 11, 12, 13, 14, 15, 16)
+
+	cache, err := NewFeedCache(&config)
+	Require(t, err)
+	checkRange := func(from, expectedStart, expectedEnd arbutil.MessageIndex) {
+		t.Helper()
+		start, end, err := cache.Range(from)
+		Require(t, err)
+		if start != expectedStart || end != expectedEnd {
+			t.Fatalf("expected cached range [%v, %v) from %v, got [%v, %v)", expectedStart, expectedEnd, from, start, end)
+		}
+	}
+	// the oldest messages were overwritten
+	checkRange(0, 12, 17)
+	checkRange(14, 14, 17)
+	checkRange(17, 17, 17)
+	// replaying from the oldest message cached would leave a gap
+	checkRange(10, 10, 10)
+
+	var replayed []arbutil.MessageIndex
+	err = cache.Replay(ctx, 12, 17, func(msgs []*m.BroadcastFeedMessage) error {
+		for _, msg := range msgs {
+			replayed = append(replayed, msg.SequenceNumber)
+		}
+		return nil
+	})
+	Require(t, err)
+	if len(replayed) != 5 {
+		t.Fatal("expected 5 messages replayed, got", replayed)
+	}
+	for i, seqNum := range replayed {
+		if seqNum != arbutil.MessageIndex(12+i) {
+			t.Fatal("unexpected messages replayed", replayed)
+		}
+	}
+	cache.StopAndWait()
+
+	// after a feed reorg, the messages past the last one received are stale
+	write(13)
+	cache, err = NewFeedCache(&config)
+	Require(t, err)
+	defer cache.StopAndWait()
+	checkRange(0, 12, 14)
+}
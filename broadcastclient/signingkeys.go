@@ -0,0 +1,158 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package broadcastclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+
+	m "github.com/offchainlabs/nitro/broadcaster/message"
+	"github.com/offchainlabs/nitro/util/contracts"
+	"github.com/offchainlabs/nitro/util/signature"
+)
+
+type SigningKeysConfig struct {
+	Keys            []string      `koanf:"keys" reload:"hot"`
+	RegistryAddress string        `koanf:"registry-address"`
+	RefreshInterval time.Duration `koanf:"refresh-interval" reload:"hot"`
+}
+
+func SigningKeysConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.StringSlice(prefix+".keys", DefaultSigningKeysConfig.Keys, "feed signing keys as <key id>:<address>, messages with a listed key id must be signed by its address")
+	f.String(prefix+".registry-address", DefaultSigningKeysConfig.RegistryAddress, "address of the parent chain contract listing the currently valid feed signing keys")
+	f.Duration(prefix+".refresh-interval", DefaultSigningKeysConfig.RefreshInterval, "how often to fetch the feed signing keys from the registry")
+}
+
+var DefaultSigningKeysConfig = SigningKeysConfig{
+	Keys:            []string{},
+	RegistryAddress: "",
+	RefreshInterval: 5 * time.Minute,
+}
+
+func (c *SigningKeysConfig) Validate() error {
+	if _, err := parseSigningKeys(c.Keys); err != nil {
+		return err
+	}
+	if c.RegistryAddress != "" {
+		if !common.IsHexAddress(c.RegistryAddress) {
+			return fmt.Errorf("invalid feed signing key registry address \"%v\"", c.RegistryAddress)
+		}
+		if c.RefreshInterval <= 0 {
+			return errors.New("feed signing key refresh interval must be positive")
+		}
+	}
+	return nil
+}
+
+func parseSigningKeys(keys []string) (map[uint64]common.Address, error) {
+	parsed := make(map[uint64]common.Address, len(keys))
+	for _, key := range keys {
+		idString, addrString, found := strings.Cut(key, ":")
+		if !found {
+			return nil, fmt.Errorf("feed signing key \"%v\" isn't <key id>:<address>", key)
+		}
+		keyId, err := strconv.ParseUint(idString, 10, 64)
+		if err != nil || keyId == 0 {
+			return nil, fmt.Errorf("invalid feed signing key id \"%v\"", idString)
+		}
+		if !common.IsHexAddress(addrString) {
+			return nil, fmt.Errorf("invalid feed signing key address \"%v\"", addrString)
+		}
+		parsed[keyId] = common.HexToAddress(addrString)
+	}
+	return parsed, nil
+}
+
+// the least time between registry fetches triggered by messages signed with an unknown key
+const minSigningKeysRefreshInterval = 10 * time.Second
+
+// signingKeys finds the address signing with each feed key id, from the config or the registry.
+// Keys from the registry are refreshed periodically, and early when a message names a key it doesn't
+// list yet, so a rotation is picked up as soon as the new key is registered.
+type signingKeys struct {
+	config   func() *SigningKeysConfig
+	registry contracts.FeedKeyRegistryInterface
+
+	mutex       sync.Mutex
+	configKeys  []string
+	parsedKeys  map[uint64]common.Address
+	fetched     map[uint64]common.Address
+	lastFetched time.Time
+}
+
+func newSigningKeys(config func() *SigningKeysConfig, registry contracts.FeedKeyRegistryInterface) *signingKeys {
+	return &signingKeys{
+		config:   config,
+		registry: registry,
+	}
+}
+
+// lookup returns the address signing with the key, if it's known.
+func (k *signingKeys) lookup(ctx context.Context, keyId uint64) (common.Address, bool, error) {
+	config := k.config()
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	if k.parsedKeys == nil || !slices.Equal(k.configKeys, config.Keys) {
+		parsed, err := parseSigningKeys(config.Keys)
+		if err != nil {
+			return common.Address{}, false, err
+		}
+		k.configKeys = config.Keys
+		k.parsedKeys = parsed
+	}
+	if signer, ok := k.parsedKeys[keyId]; ok {
+		return signer, true, nil
+	}
+	if k.registry == nil {
+		return common.Address{}, false, nil
+	}
+	signer, ok := k.fetched[keyId]
+	sinceFetched := time.Since(k.lastFetched)
+	if sinceFetched >= config.RefreshInterval || (!ok && sinceFetched >= minSigningKeysRefreshInterval) {
+		fetched, err := k.registry.FeedSigningKeys(ctx)
+		if err != nil {
+			// until the registry can be reached, messages with keys it would list are verified as if they had no key id
+			log.Warn("error fetching feed signing keys from the registry", "err", err)
+		} else {
+			k.fetched = fetched
+			signer, ok = fetched[keyId]
+		}
+		k.lastFetched = time.Now()
+	}
+	return signer, ok, nil
+}
+
+// verifySignedBy checks the message is signed by the key its key id names, returning false if that key isn't known.
+func (k *signingKeys) verifySignedBy(ctx context.Context, message *m.BroadcastFeedMessage, hash common.Hash) (bool, error) {
+	if message.KeyId == 0 {
+		return false, nil
+	}
+	signer, known, err := k.lookup(ctx, message.KeyId)
+	if err != nil || !known {
+		return false, err
+	}
+	if len(message.Signature) == 0 {
+		return true, signature.ErrMissingSignature
+	}
+	sigPublicKey, err := crypto.SigToPub(hash.Bytes(), message.Signature)
+	if err != nil {
+		return true, signature.ErrSignatureNotVerified
+	}
+	if crypto.PubkeyToAddress(*sigPublicKey) != signer {
+		return true, fmt.Errorf("%w: not signed by feed key %v", signature.ErrSignerNotApproved, message.KeyId)
+	}
+	return true, nil
+}
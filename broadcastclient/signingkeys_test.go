@@ -0,0 +1,108 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package broadcastclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	m "github.com/offchainlabs/nitro/broadcaster/message"
+	"github.com/offchainlabs/nitro/util/signature"
+)
+
+type testKeyRegistry struct {
+	keys    map[uint64]common.Address
+	fetches int
+}
+
+func (r *testKeyRegistry) FeedSigningKeys(ctx context.Context) (map[uint64]common.Address, error) {
+	r.fetches++
+	keys := make(map[uint64]common.Address, len(r.keys))
+	for keyId, signer := range r.keys {
+		keys[keyId] = signer
+	}
+	return keys, nil
+}
+
+func TestSigningKeys(t *testing.T) {
+	ctx := context.Background()
+	chainId := uint64(8742)
+	configKey, err := crypto.GenerateKey()
+	Require(t, err)
+	registryKey, err := crypto.GenerateKey()
+	Require(t, err)
+	otherKey, err := crypto.GenerateKey()
+	Require(t, err)
+
+	config := DefaultSigningKeysConfig
+	config.Keys = []string{fmt.Sprintf("1:%v", crypto.PubkeyToAddress(configKey.PublicKey))}
+	config.RegistryAddress = common.Address{1}.Hex()
+	Require(t, config.Validate())
+	registry := &testKeyRegistry{keys: map[uint64]common.Address{}}
+	keys := newSigningKeys(func() *SigningKeysConfig { return &config }, registry)
+
+	verify := func(keyId uint64, key *ecdsa.PrivateKey) (bool, error) {
+		t.Helper()
+		message := &m.BroadcastFeedMessage{
+			SequenceNumber: 10,
+			Message:        arbostypes.TestMessageWithMetadataAndRequestId,
+			KeyId:          keyId,
+		}
+		hash, err := message.Hash(chainId)
+		Require(t, err)
+		message.Signature, err = crypto.Sign(hash.Bytes(), key)
+		Require(t, err)
+		return keys.verifySignedBy(ctx, message, hash)
+	}
+
+	known, err := verify(1, configKey)
+	if !known || err != nil {
+		t.Fatal("message signed by configured key not verified", known, err)
+	}
+	known, err = verify(1, otherKey)
+	if !known || !errors.Is(err, signature.ErrSignerNotApproved) {
+		t.Fatal("message not signed by configured key verified", known, err)
+	}
+	known, err = verify(0, otherKey)
+	if known || err != nil {
+		t.Fatal("message without a key id verified by key", known, err)
+	}
+
+	// a key not registered yet is left to the other checks
+	known, err = verify(2, registryKey)
+	Require(t, err)
+	if known {
+		t.Fatal("unregistered key known")
+	}
+	if registry.fetches != 1 {
+		t.Fatal("expected keys fetched once, got", registry.fetches)
+	}
+
+	// once registered, the key is picked up on the next refresh
+	registry.keys[2] = crypto.PubkeyToAddress(registryKey.PublicKey)
+	keys.lastFetched = keys.lastFetched.Add(-minSigningKeysRefreshInterval)
+	known, err = verify(2, registryKey)
+	if !known || err != nil {
+		t.Fatal("message signed by registered key not verified", known, err)
+	}
+	known, err = verify(2, configKey)
+	if !known || !errors.Is(err, signature.ErrSignerNotApproved) {
+		t.Fatal("message not signed by registered key verified", known, err)
+	}
+	if registry.fetches != 2 {
+		t.Fatal("expected keys fetched twice, got", registry.fetches)
+	}
+
+	config.Keys = []string{"invalid"}
+	if config.Validate() == nil {
+		t.Fatal("invalid signing key accepted")
+	}
+}
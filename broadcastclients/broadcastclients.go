@@ -5,6 +5,7 @@ package broadcastclients
 
 import (
 	"context"
+	"math"
 	"sync/atomic"
 	"time"
 
@@ -23,31 +24,150 @@ const RECENT_FEED_ITEM_TTL = time.Second * 10
 const MAX_FEED_INACTIVE_TIME = time.Second * 5
 const PRIMARY_FEED_UPTIME = time.Minute * 10
 
+// a secondary feed delivering messages this much sooner than the primary feeds is kept running
+const SECONDARY_FEED_PREFERENCE_MARGIN = time.Millisecond * 100
+
+// routedMessage is a feed message along with the URL of the feed it came from and when it arrived.
+type routedMessage struct {
+	message  m.BroadcastFeedMessage
+	source   string
+	received time.Time
+}
+
 type Router struct {
 	stopwaiter.StopWaiter
-	messageChan                 chan m.BroadcastFeedMessage
+	messageChan                 chan routedMessage
 	confirmedSequenceNumberChan chan arbutil.MessageIndex
 
 	forwardTxStreamer       broadcastclient.TransactionStreamerInterface
 	forwardConfirmationChan chan arbutil.MessageIndex
 }
 
-func (r *Router) AddBroadcastMessages(feedMessages []*m.BroadcastFeedMessage) error {
+// sourceRouter passes a client's messages to its router, tagged with the client's URL and the time they
+// arrived, so a feed's lag doesn't depend on when its router's queue is read.
+type sourceRouter struct {
+	router *Router
+	source string
+}
+
+func (r *sourceRouter) AddBroadcastMessages(feedMessages []*m.BroadcastFeedMessage) error {
+	received := time.Now()
 	for _, feedMessage := range feedMessages {
-		r.messageChan <- *feedMessage
+		r.router.messageChan <- routedMessage{message: *feedMessage, source: r.source, received: received}
 	}
 	return nil
 }
 
+// feedSource tracks how long after the first delivery of each message a feed delivers it.
+type feedSource struct {
+	// moving average of the delay, zero for a feed always delivering messages first
+	lag             time.Duration
+	lastMessage     time.Time
+	firstDeliveries uint64
+}
+
+func (s *feedSource) record(now time.Time, lag time.Duration) {
+	if s.lastMessage.IsZero() {
+		s.lag = lag
+	} else {
+		s.lag += (lag - s.lag) / 8
+	}
+	s.lastMessage = now
+}
+
+func (s *feedSource) healthy(now time.Time) bool {
+	return s != nil && now.Sub(s.lastMessage) < MAX_FEED_INACTIVE_TIME
+}
+
+// fastestSource returns the healthy feed among the urls with the least lag, or false if none are healthy.
+func fastestSource(sources map[string]*feedSource, urls []string, now time.Time) (string, time.Duration, bool) {
+	var fastest string
+	var fastestLag time.Duration
+	found := false
+	for _, url := range urls {
+		source := sources[url]
+		if source.healthy(now) && (!found || source.lag < fastestLag) {
+			fastest, fastestLag, found = url, source.lag, true
+		}
+	}
+	return fastest, fastestLag, found
+}
+
+// feedTracker drops messages already delivered by another feed, tracking how far each feed lags behind
+// the first delivery of the messages to find the fastest feed.
+type feedTracker struct {
+	recentFeedItemsNew map[arbutil.MessageIndex]time.Time
+	recentFeedItemsOld map[arbutil.MessageIndex]time.Time
+	sources            map[string]*feedSource
+	fastest            string
+}
+
+func newFeedTracker() *feedTracker {
+	return &feedTracker{
+		recentFeedItemsNew: make(map[arbutil.MessageIndex]time.Time, RECENT_FEED_INITIAL_MAP_SIZE),
+		recentFeedItemsOld: make(map[arbutil.MessageIndex]time.Time, RECENT_FEED_INITIAL_MAP_SIZE),
+		sources:            make(map[string]*feedSource),
+	}
+}
+
+// deliver records the delivery of a message by its feed, returning whether it's the first delivery.
+func (t *feedTracker) deliver(routed *routedMessage) bool {
+	source, ok := t.sources[routed.source]
+	if !ok {
+		source = &feedSource{}
+		t.sources[routed.source] = source
+	}
+	now := routed.received
+	if first, ok := t.recentFeedItemsNew[routed.message.SequenceNumber]; ok {
+		source.record(now, now.Sub(first))
+		return false
+	}
+	if first, ok := t.recentFeedItemsOld[routed.message.SequenceNumber]; ok {
+		source.record(now, now.Sub(first))
+		return false
+	}
+	source.record(now, 0)
+	source.firstDeliveries++
+	t.recentFeedItemsNew[routed.message.SequenceNumber] = now
+	return true
+}
+
+// cycle forgets the messages first delivered before the previous cycle, and returns the fastest healthy
+// feed among the urls if it changed.
+func (t *feedTracker) cycle(urls []string, now time.Time) (string, bool) {
+	t.recentFeedItemsOld = t.recentFeedItemsNew
+	t.recentFeedItemsNew = make(map[arbutil.MessageIndex]time.Time, RECENT_FEED_INITIAL_MAP_SIZE)
+	url, lag, ok := fastestSource(t.sources, urls, now)
+	if !ok || url == t.fastest {
+		return "", false
+	}
+	log.Info("fastest feed changed", "url", url, "lag", lag, "firstDeliveries", t.sources[url].firstDeliveries)
+	t.fastest = url
+	return url, true
+}
+
 type BroadcastClients struct {
 	primaryClients   []*broadcastclient.BroadcastClient
 	secondaryClients []*broadcastclient.BroadcastClient
 	secondaryURL     []string
-	makeClient       func(string, *Router) (*broadcastclient.BroadcastClient, error)
+	primaryURL       []string
+	makeClient       func(string, *Router, arbutil.MessageIndex) (*broadcastclient.BroadcastClient, error)
+	chainId          uint64
+
+	// the message count the node resumes from, and the sequence number clients request the feed from
+	messageCount arbutil.MessageIndex
+	feedStart    arbutil.MessageIndex
 
 	primaryRouter   *Router
 	secondaryRouter *Router
 
+	// the cached messages to replay on start, before any the clients receive
+	cache       *broadcastclient.FeedCache
+	replayStart arbutil.MessageIndex
+	replayEnd   arbutil.MessageIndex
+	// the last message replayed, until the feed's copy of it confirms the cache wasn't stale
+	lastReplayed *m.BroadcastFeedMessage
+
 	// Use atomic access
 	connected atomic.Int32
 }
@@ -60,6 +180,7 @@ func NewBroadcastClients(
 	confirmedSequenceNumberListener chan arbutil.MessageIndex,
 	fatalErrChan chan error,
 	addrVerifier contracts.AddressVerifierInterface,
+	keyRegistry contracts.FeedKeyRegistryInterface,
 ) (*BroadcastClients, error) {
 	config := configFetcher()
 	if len(config.URL) == 0 && len(config.SecondaryURL) == 0 {
@@ -67,7 +188,7 @@ func NewBroadcastClients(
 	}
 	newStandardRouter := func() *Router {
 		return &Router{
-			messageChan:                 make(chan m.BroadcastFeedMessage, ROUTER_QUEUE_SIZE),
+			messageChan:                 make(chan routedMessage, ROUTER_QUEUE_SIZE),
 			confirmedSequenceNumberChan: make(chan arbutil.MessageIndex, ROUTER_QUEUE_SIZE),
 			forwardTxStreamer:           txStreamer,
 			forwardConfirmationChan:     confirmedSequenceNumberListener,
@@ -79,33 +200,56 @@ func NewBroadcastClients(
 		primaryClients:   make([]*broadcastclient.BroadcastClient, 0, len(config.URL)),
 		secondaryClients: make([]*broadcastclient.BroadcastClient, 0, len(config.SecondaryURL)),
 		secondaryURL:     config.SecondaryURL,
+		chainId:          l2ChainId,
+		messageCount:     currentMessageCount,
+		feedStart:        currentMessageCount,
+	}
+	if config.Cache.Enable {
+		cache, err := broadcastclient.NewFeedCache(&config.Cache)
+		if err != nil {
+			return nil, err
+		}
+		clients.replayStart, clients.replayEnd, err = cache.Range(currentMessageCount)
+		if err != nil {
+			log.Warn("error reading the feed cache, requesting all messages from the feed", "err", err)
+		} else if clients.replayEnd > clients.replayStart {
+			log.Info("replaying feed messages from the cache", "start", clients.replayStart, "end", clients.replayEnd)
+			// the feed only needs to send what comes after the cached messages, and the last of them to check against
+			clients.feedStart = clients.replayEnd - 1
+		}
+		clients.cache = cache
 	}
-	clients.makeClient = func(url string, router *Router) (*broadcastclient.BroadcastClient, error) {
+	clients.makeClient = func(url string, router *Router, start arbutil.MessageIndex) (*broadcastclient.BroadcastClient, error) {
 		return broadcastclient.NewBroadcastClient(
 			configFetcher,
 			url,
 			l2ChainId,
-			currentMessageCount,
-			router,
+			start,
+			&sourceRouter{router: router, source: url},
 			router.confirmedSequenceNumberChan,
 			fatalErrChan,
 			addrVerifier,
+			keyRegistry,
 			func(delta int32) { clients.adjustCount(delta) },
 		)
 	}
 
 	var lastClientErr error
 	for _, address := range config.URL {
-		client, err := clients.makeClient(address, clients.primaryRouter)
+		client, err := clients.makeClient(address, clients.primaryRouter, clients.feedStart)
 		if err != nil {
 			lastClientErr = err
 			log.Warn("init broadcast client failed", "address", address)
 			continue
 		}
 		clients.primaryClients = append(clients.primaryClients, client)
+		clients.primaryURL = append(clients.primaryURL, address)
 	}
 	if len(clients.primaryClients) == 0 {
 		log.Error("no connected feed on startup, last error: %w", lastClientErr)
+		if clients.cache != nil {
+			clients.cache.StopAndWait()
+		}
 		return nil, nil
 	}
 
@@ -134,14 +278,18 @@ func clearAndResetTicker(timer *time.Ticker, interval time.Duration) {
 func (bcs *BroadcastClients) Start(ctx context.Context) {
 	bcs.primaryRouter.StopWaiter.Start(ctx, bcs.primaryRouter)
 	bcs.secondaryRouter.StopWaiter.Start(ctx, bcs.secondaryRouter)
+	if bcs.cache != nil {
+		bcs.cache.Start(ctx)
+	}
+	// the cached messages are passed on before the clients start, so they come before any the feed sends
+	bcs.replay(ctx)
 
 	for _, client := range bcs.primaryClients {
 		client.Start(ctx)
 	}
 
 	var lastConfirmed arbutil.MessageIndex
-	recentFeedItemsNew := make(map[arbutil.MessageIndex]time.Time, RECENT_FEED_INITIAL_MAP_SIZE)
-	recentFeedItemsOld := make(map[arbutil.MessageIndex]time.Time, RECENT_FEED_INITIAL_MAP_SIZE)
+	tracker := newFeedTracker()
 	bcs.primaryRouter.LaunchThread(func(ctx context.Context) {
 		recentFeedItemsCleanup := time.NewTicker(RECENT_FEED_ITEM_TTL)
 		startSecondaryFeedTimer := time.NewTicker(MAX_FEED_INACTIVE_TIME)
@@ -152,17 +300,20 @@ func (bcs *BroadcastClients) Start(ctx context.Context) {
 		defer stopSecondaryFeedTimer.Stop()
 		defer primaryFeedIsDownTimer.Stop()
 
-		msgHandler := func(msg m.BroadcastFeedMessage, router *Router) error {
-			if _, ok := recentFeedItemsNew[msg.SequenceNumber]; ok {
+		msgHandler := func(routed routedMessage, router *Router) error {
+			msg := routed.message
+			if bcs.lastReplayed != nil && !bcs.checkReplayed(ctx, &msg) {
 				return nil
 			}
-			if _, ok := recentFeedItemsOld[msg.SequenceNumber]; ok {
+			if !tracker.deliver(&routed) {
 				return nil
 			}
-			recentFeedItemsNew[msg.SequenceNumber] = time.Now()
 			if err := router.forwardTxStreamer.AddBroadcastMessages([]*m.BroadcastFeedMessage{&msg}); err != nil {
 				return err
 			}
+			if bcs.cache != nil {
+				bcs.cache.Add(&msg)
+			}
 			return nil
 		}
 		confSeqHandler := func(cs arbutil.MessageIndex, router *Router) {
@@ -174,34 +325,46 @@ func (bcs *BroadcastClients) Start(ctx context.Context) {
 				router.forwardConfirmationChan <- cs
 			}
 		}
+		handleMessage := func(msg routedMessage, router *Router) {
+			if router == bcs.primaryRouter {
+				if err := msgHandler(msg, router); err != nil {
+					log.Error("Error routing message from Primary Sequencer Feeds", "err", err)
+				}
+				clearAndResetTicker(primaryFeedIsDownTimer, MAX_FEED_INACTIVE_TIME)
+			} else if err := msgHandler(msg, router); err != nil {
+				log.Error("Error routing message from Secondary Sequencer Feeds", "err", err)
+			}
+			clearAndResetTicker(startSecondaryFeedTimer, MAX_FEED_INACTIVE_TIME)
+		}
+		handleConfirmation := func(cs arbutil.MessageIndex, router *Router) {
+			confSeqHandler(cs, router)
+			if router == bcs.primaryRouter {
+				clearAndResetTicker(primaryFeedIsDownTimer, MAX_FEED_INACTIVE_TIME)
+			}
+			clearAndResetTicker(startSecondaryFeedTimer, MAX_FEED_INACTIVE_TIME)
+		}
 
-		// Multiple select statements to prioritize reading messages from primary feeds' channels and avoid starving of timers
+		// Multiple select statements to prioritize reading messages from the fastest feed's router and avoid starving of timers
 		for {
 			select {
 			// Cycle buckets to get rid of old entries
 			case <-recentFeedItemsCleanup.C:
-				recentFeedItemsOld = recentFeedItemsNew
-				recentFeedItemsNew = make(map[arbutil.MessageIndex]time.Time, RECENT_FEED_INITIAL_MAP_SIZE)
-			// Primary feeds have been up and running for PRIMARY_FEED_UPTIME=10 mins without a failure, stop the recently started secondary feed
+				urls := append(append([]string{}, bcs.primaryURL...), bcs.secondaryURL[:len(bcs.secondaryClients)]...)
+				tracker.cycle(urls, time.Now())
+			// Primary feeds have been up and running for PRIMARY_FEED_UPTIME=10 mins without a failure, stop the slowest secondary feed
 			case <-stopSecondaryFeedTimer.C:
-				bcs.stopSecondaryFeed()
+				bcs.stopSecondaryFeed(tracker.sources)
 			default:
 			}
 
+			preferred, other := bcs.routerOrder(tracker.fastest)
 			select {
 			case <-ctx.Done():
 				return
-			// Primary feeds
-			case msg := <-bcs.primaryRouter.messageChan:
-				if err := msgHandler(msg, bcs.primaryRouter); err != nil {
-					log.Error("Error routing message from Primary Sequencer Feeds", "err", err)
-				}
-				clearAndResetTicker(startSecondaryFeedTimer, MAX_FEED_INACTIVE_TIME)
-				clearAndResetTicker(primaryFeedIsDownTimer, MAX_FEED_INACTIVE_TIME)
-			case cs := <-bcs.primaryRouter.confirmedSequenceNumberChan:
-				confSeqHandler(cs, bcs.primaryRouter)
-				clearAndResetTicker(startSecondaryFeedTimer, MAX_FEED_INACTIVE_TIME)
-				clearAndResetTicker(primaryFeedIsDownTimer, MAX_FEED_INACTIVE_TIME)
+			case msg := <-preferred.messageChan:
+				handleMessage(msg, preferred)
+			case cs := <-preferred.confirmedSequenceNumberChan:
+				handleConfirmation(cs, preferred)
 			// Failed to get messages from primary feed for ~5 seconds, reset the timer responsible for stopping a secondary
 			case <-primaryFeedIsDownTimer.C:
 				clearAndResetTicker(stopSecondaryFeedTimer, PRIMARY_FEED_UPTIME)
@@ -209,25 +372,14 @@ func (bcs *BroadcastClients) Start(ctx context.Context) {
 				select {
 				case <-ctx.Done():
 					return
-				// Secondary Feeds
-				case msg := <-bcs.secondaryRouter.messageChan:
-					if err := msgHandler(msg, bcs.secondaryRouter); err != nil {
-						log.Error("Error routing message from Secondary Sequencer Feeds", "err", err)
-					}
-					clearAndResetTicker(startSecondaryFeedTimer, MAX_FEED_INACTIVE_TIME)
-				case cs := <-bcs.secondaryRouter.confirmedSequenceNumberChan:
-					confSeqHandler(cs, bcs.secondaryRouter)
-					clearAndResetTicker(startSecondaryFeedTimer, MAX_FEED_INACTIVE_TIME)
-				case msg := <-bcs.primaryRouter.messageChan:
-					if err := msgHandler(msg, bcs.primaryRouter); err != nil {
-						log.Error("Error routing message from Primary Sequencer Feeds", "err", err)
-					}
-					clearAndResetTicker(startSecondaryFeedTimer, MAX_FEED_INACTIVE_TIME)
-					clearAndResetTicker(primaryFeedIsDownTimer, MAX_FEED_INACTIVE_TIME)
-				case cs := <-bcs.primaryRouter.confirmedSequenceNumberChan:
-					confSeqHandler(cs, bcs.primaryRouter)
-					clearAndResetTicker(startSecondaryFeedTimer, MAX_FEED_INACTIVE_TIME)
-					clearAndResetTicker(primaryFeedIsDownTimer, MAX_FEED_INACTIVE_TIME)
+				case msg := <-other.messageChan:
+					handleMessage(msg, other)
+				case cs := <-other.confirmedSequenceNumberChan:
+					handleConfirmation(cs, other)
+				case msg := <-preferred.messageChan:
+					handleMessage(msg, preferred)
+				case cs := <-preferred.confirmedSequenceNumberChan:
+					handleConfirmation(cs, preferred)
 				case <-startSecondaryFeedTimer.C:
 					bcs.startSecondaryFeed(ctx)
 				case <-primaryFeedIsDownTimer.C:
@@ -238,11 +390,107 @@ func (bcs *BroadcastClients) Start(ctx context.Context) {
 	})
 }
 
+// routerOrder returns the router whose queue is read first, that of the fastest feed's if it's a running
+// secondary feed and otherwise the primary feeds', so the fastest feed's copy of a message is the one passed on.
+func (bcs *BroadcastClients) routerOrder(fastest string) (*Router, *Router) {
+	for _, url := range bcs.secondaryURL[:len(bcs.secondaryClients)] {
+		if url == fastest {
+			return bcs.secondaryRouter, bcs.primaryRouter
+		}
+	}
+	return bcs.primaryRouter, bcs.secondaryRouter
+}
+
+// replay passes the cached messages to the transaction streamer. If it fails partway, the clients, which
+// haven't been started yet, are remade to request every message after the node's own from the feed.
+func (bcs *BroadcastClients) replay(ctx context.Context) {
+	if bcs.replayEnd <= bcs.replayStart {
+		return
+	}
+	err := bcs.cache.Replay(ctx, bcs.replayStart, bcs.replayEnd, func(msgs []*m.BroadcastFeedMessage) error {
+		if err := bcs.primaryRouter.forwardTxStreamer.AddBroadcastMessages(msgs); err != nil {
+			return err
+		}
+		bcs.lastReplayed = msgs[len(msgs)-1]
+		return nil
+	})
+	if err == nil {
+		return
+	}
+	if ctx.Err() == nil {
+		log.Error("error replaying feed messages from the cache, requesting the rest from the feed", "err", err)
+	}
+	bcs.remakePrimaryClients(bcs.messageCount)
+}
+
+// checkReplayed compares a feed message against the last message replayed from the cache, returning whether
+// to pass the feed message on. If the feed's copy differs, the cache predates a reorg of the feed, so the
+// primary clients are restarted to request every message after the node's own from the feed again.
+func (bcs *BroadcastClients) checkReplayed(ctx context.Context, msg *m.BroadcastFeedMessage) bool {
+	replayed := bcs.lastReplayed
+	if msg.SequenceNumber < replayed.SequenceNumber {
+		return true
+	}
+	bcs.lastReplayed = nil
+	if msg.SequenceNumber > replayed.SequenceNumber {
+		log.Warn("feed didn't resend the last cached message, unable to check the cache against it", "sequenceNumber", replayed.SequenceNumber, "received", msg.SequenceNumber)
+		return true
+	}
+	same, err := sameFeedMessage(msg, replayed, bcs.chainId)
+	if err != nil {
+		log.Warn("error comparing the last cached message against the feed", "sequenceNumber", msg.SequenceNumber, "err", err)
+	}
+	if same {
+		return false
+	}
+	log.Warn("cached feed messages are stale, requesting them from the feed again", "start", bcs.messageCount, "end", bcs.replayEnd)
+	for _, client := range bcs.primaryClients {
+		client.StopAndWait()
+	}
+	bcs.remakePrimaryClients(bcs.messageCount)
+	for _, client := range bcs.primaryClients {
+		client.Start(ctx)
+	}
+	return false
+}
+
+// remakePrimaryClients replaces the primary clients, which must not be running, with ones requesting the
+// feed from the sequence number. Secondary clients started later request it from there too.
+func (bcs *BroadcastClients) remakePrimaryClients(feedStart arbutil.MessageIndex) {
+	bcs.feedStart = feedStart
+	for i, url := range bcs.primaryURL {
+		client, err := bcs.makeClient(url, bcs.primaryRouter, feedStart)
+		if err != nil {
+			// creating the client succeeded before with the same config
+			log.Error("error remaking broadcast client", "url", url, "err", err)
+			continue
+		}
+		bcs.primaryClients[i] = client
+	}
+}
+
+// sameFeedMessage returns whether the feed messages hold the same message, comparing block hashes when
+// both have them.
+func sameFeedMessage(a, b *m.BroadcastFeedMessage, chainId uint64) (bool, error) {
+	if a.BlockHash != nil && b.BlockHash != nil && *a.BlockHash != *b.BlockHash {
+		return false, nil
+	}
+	aHash, err := a.Hash(chainId)
+	if err != nil {
+		return false, err
+	}
+	bHash, err := b.Hash(chainId)
+	if err != nil {
+		return false, err
+	}
+	return aHash == bHash, nil
+}
+
 func (bcs *BroadcastClients) startSecondaryFeed(ctx context.Context) {
 	pos := len(bcs.secondaryClients)
 	if pos < len(bcs.secondaryURL) {
 		url := bcs.secondaryURL[pos]
-		client, err := bcs.makeClient(url, bcs.secondaryRouter)
+		client, err := bcs.makeClient(url, bcs.secondaryRouter, bcs.feedStart)
 		if err != nil {
 			log.Warn("init broadcast secondary client failed", "address", url)
 			bcs.secondaryURL = append(bcs.secondaryURL[:pos], bcs.secondaryURL[pos+1:]...)
@@ -256,12 +504,30 @@ func (bcs *BroadcastClients) startSecondaryFeed(ctx context.Context) {
 	}
 }
 
-func (bcs *BroadcastClients) stopSecondaryFeed() {
+// stopSecondaryFeed stops the started secondary feed with the most lag, unless even that feed
+// delivers messages sooner than the healthy primary feeds.
+func (bcs *BroadcastClients) stopSecondaryFeed(sources map[string]*feedSource) {
 	pos := len(bcs.secondaryClients)
 	if pos > 0 {
+		now := time.Now()
+		slowest := 0
+		for i := 1; i < pos; i++ {
+			if secondaryLag(sources, bcs.secondaryURL[i], now) > secondaryLag(sources, bcs.secondaryURL[slowest], now) {
+				slowest = i
+			}
+		}
+		slowestLag := secondaryLag(sources, bcs.secondaryURL[slowest], now)
+		if _, primaryLag, ok := fastestSource(sources, bcs.primaryURL, now); ok && slowestLag < primaryLag-SECONDARY_FEED_PREFERENCE_MARGIN {
+			log.Info("keeping secondary feed faster than the primary feeds", "url", bcs.secondaryURL[slowest], "lag", slowestLag, "primaryLag", primaryLag)
+			return
+		}
+		// the started secondary feeds must remain a prefix of the secondary urls
 		pos -= 1
+		bcs.secondaryClients[slowest], bcs.secondaryClients[pos] = bcs.secondaryClients[pos], bcs.secondaryClients[slowest]
+		bcs.secondaryURL[slowest], bcs.secondaryURL[pos] = bcs.secondaryURL[pos], bcs.secondaryURL[slowest]
 		bcs.secondaryClients[pos].StopAndWait()
 		bcs.secondaryClients = bcs.secondaryClients[:pos]
+		delete(sources, bcs.secondaryURL[pos])
 		log.Info("disconnected secondary feed", "url", bcs.secondaryURL[pos])
 
 		// flush the secondary feeds' message and confirmedSequenceNumber channels
@@ -276,6 +542,15 @@ func (bcs *BroadcastClients) stopSecondaryFeed() {
 	}
 }
 
+// secondaryLag is the lag of a secondary feed, treating an unhealthy feed as infinitely slow.
+func secondaryLag(sources map[string]*feedSource, url string, now time.Time) time.Duration {
+	source := sources[url]
+	if !source.healthy(now) {
+		return math.MaxInt64
+	}
+	return source.lag
+}
+
 func (bcs *BroadcastClients) StopAndWait() {
 	for _, client := range bcs.primaryClients {
 		client.StopAndWait()
@@ -283,4 +558,7 @@ func (bcs *BroadcastClients) StopAndWait() {
 	for _, client := range bcs.secondaryClients {
 		client.StopAndWait()
 	}
+	if bcs.cache != nil {
+		bcs.cache.StopAndWait()
+	}
 }
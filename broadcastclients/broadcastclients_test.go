@@ -0,0 +1,261 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package broadcastclients
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/broadcastclient"
+	m "github.com/offchainlabs/nitro/broadcaster/message"
+)
+
+type testTxStreamer struct {
+	err      error
+	messages []*m.BroadcastFeedMessage
+}
+
+func (s *testTxStreamer) AddBroadcastMessages(feedMessages []*m.BroadcastFeedMessage) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.messages = append(s.messages, feedMessages...)
+	return nil
+}
+
+// newTestClients returns clients replaying the cached messages [10, 15) for a node with 10 messages, along
+// with the sequence numbers each client was made to request the feed from.
+func newTestClients(t *testing.T, streamer *testTxStreamer) (*BroadcastClients, *[]arbutil.MessageIndex) {
+	t.Helper()
+	ctx := context.Background()
+	cacheConfig := broadcastclient.DefaultFeedCacheConfig
+	cacheConfig.Enable = true
+	cacheConfig.Directory = t.TempDir()
+	cache, err := broadcastclient.NewFeedCache(&cacheConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Start(ctx)
+	for _, msg := range m.CreateDummyBroadcastMessages([]arbutil.MessageIndex{10, 11, 12, 13, 14}) {
+		cache.Add(msg)
+	}
+	// stopping writes the messages added
+	cache.StopAndWait()
+	cache, err = broadcastclient.NewFeedCache(&cacheConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cache.StopAndWait)
+	start, end, err := cache.Range(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start != 10 || end != 15 {
+		t.Fatalf("unexpected cached range [%v, %v)", start, end)
+	}
+
+	config := broadcastclient.DefaultTestConfig
+	var starts []arbutil.MessageIndex
+	clients := &BroadcastClients{
+		primaryRouter: &Router{forwardTxStreamer: streamer},
+		primaryURL:    []string{"ws://127.0.0.1:1"},
+		messageCount:  10,
+		feedStart:     end - 1,
+		cache:         cache,
+		replayStart:   start,
+		replayEnd:     end,
+	}
+	clients.makeClient = func(url string, router *Router, start arbutil.MessageIndex) (*broadcastclient.BroadcastClient, error) {
+		starts = append(starts, start)
+		return broadcastclient.NewBroadcastClient(
+			func() *broadcastclient.Config { return &config },
+			url,
+			0,
+			start,
+			&sourceRouter{router: router, source: url},
+			nil,
+			make(chan error, 1),
+			nil,
+			nil,
+			func(int32) {},
+		)
+	}
+	client, err := clients.makeClient(clients.primaryURL[0], clients.primaryRouter, clients.feedStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients.primaryClients = []*broadcastclient.BroadcastClient{client}
+	t.Cleanup(func() {
+		for _, client := range clients.primaryClients {
+			client.StopAndWait()
+		}
+	})
+	return clients, &starts
+}
+
+func checkStarts(t *testing.T, starts []arbutil.MessageIndex, expected ...arbutil.MessageIndex) {
+	t.Helper()
+	if len(starts) != len(expected) {
+		t.Fatalf("clients requested the feed from %v, expected %v", starts, expected)
+	}
+	for i := range starts {
+		if starts[i] != expected[i] {
+			t.Fatalf("clients requested the feed from %v, expected %v", starts, expected)
+		}
+	}
+}
+
+func TestReplayFailureRequestsFromMessageCount(t *testing.T) {
+	streamer := &testTxStreamer{err: errors.New("streamer unavailable")}
+	clients, starts := newTestClients(t, streamer)
+	clients.replay(context.Background())
+	// the client made to resume after the cached messages is replaced before it's started
+	checkStarts(t, *starts, 14, 10)
+	if clients.feedStart != 10 || clients.lastReplayed != nil {
+		t.Fatal("unexpected state after a failed replay, feed start", clients.feedStart)
+	}
+}
+
+func TestReplayCheckedAgainstFeed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	// clients restarted by the check stop retrying right away
+	cancel()
+	streamer := &testTxStreamer{}
+	clients, starts := newTestClients(t, streamer)
+	clients.replay(context.Background())
+	if len(streamer.messages) != 5 || clients.lastReplayed == nil || clients.lastReplayed.SequenceNumber != 14 {
+		t.Fatal("expected the 5 cached messages replayed, got", len(streamer.messages))
+	}
+	checkStarts(t, *starts, 14)
+
+	feed := m.CreateDummyBroadcastMessages([]arbutil.MessageIndex{13, 14, 15})
+	if !clients.checkReplayed(ctx, feed[0]) {
+		t.Fatal("dropped a feed message before the one checked")
+	}
+	// the feed's copy of the last cached message is dropped once it confirms the cache
+	if clients.checkReplayed(ctx, feed[1]) {
+		t.Fatal("passed on the feed's copy of a replayed message")
+	}
+	if clients.lastReplayed != nil {
+		t.Fatal("still checking feed messages against the cache after confirming it")
+	}
+	checkStarts(t, *starts, 14)
+
+	// a feed that no longer has the last cached message leaves the cache unchecked
+	clients.lastReplayed = streamer.messages[4]
+	if !clients.checkReplayed(ctx, feed[2]) || clients.lastReplayed != nil {
+		t.Fatal("expected a later feed message to be passed on and end the check")
+	}
+
+	// a cache from before a feed reorg has the clients request every message after the node's again
+	clients.lastReplayed = streamer.messages[4]
+	reorged := *feed[1]
+	reorged.Message.DelayedMessagesRead = 1
+	if clients.checkReplayed(ctx, &reorged) {
+		t.Fatal("passed on a feed message while restarting the clients")
+	}
+	checkStarts(t, *starts, 14, 10)
+	if clients.feedStart != 10 {
+		t.Fatal("secondary feeds would resume after the stale messages, from", clients.feedStart)
+	}
+}
+
+func TestSameFeedMessage(t *testing.T) {
+	msgs := m.CreateDummyBroadcastMessages([]arbutil.MessageIndex{1, 1})
+	same, err := sameFeedMessage(msgs[0], msgs[1], 0)
+	if err != nil || !same {
+		t.Fatal("identical messages compared as different", err)
+	}
+	// the block hash differs when a message executes on top of a different state
+	hashA, hashB := common.HexToHash("0x1"), common.HexToHash("0x2")
+	msgs[0].BlockHash, msgs[1].BlockHash = &hashA, &hashB
+	if same, _ := sameFeedMessage(msgs[0], msgs[1], 0); same {
+		t.Fatal("messages with different block hashes compared as the same")
+	}
+	msgs[1].BlockHash = nil
+	if same, _ := sameFeedMessage(msgs[0], msgs[1], 0); !same {
+		t.Fatal("a message without a block hash compared as different")
+	}
+}
+
+func deliverFromFeeds(tracker *feedTracker, seqNum arbutil.MessageIndex, start time.Time, lags map[string]time.Duration) map[string]bool {
+	firsts := make(map[string]bool)
+	urls := make([]string, 0, len(lags))
+	for url := range lags {
+		urls = append(urls, url)
+	}
+	// deliveries are handled in order of arrival, as they are when the fastest feed's router is read first
+	sort.Slice(urls, func(i, j int) bool { return lags[urls[i]] < lags[urls[j]] })
+	for _, url := range urls {
+		msg := m.CreateDummyBroadcastMessages([]arbutil.MessageIndex{seqNum})[0]
+		firsts[url] = tracker.deliver(&routedMessage{message: *msg, source: url, received: start.Add(lags[url])})
+	}
+	return firsts
+}
+
+func TestFeedTrackerFastestFeed(t *testing.T) {
+	tracker := newFeedTracker()
+	urls := []string{"primary", "secondary"}
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		firsts := deliverFromFeeds(tracker, arbutil.MessageIndex(i), start, map[string]time.Duration{"primary": 0, "secondary": 50 * time.Millisecond})
+		if !firsts["primary"] || firsts["secondary"] {
+			t.Fatal("expected only the first delivery of a message passed on, got", firsts)
+		}
+	}
+	if url, changed := tracker.cycle(urls, start); !changed || url != "primary" {
+		t.Fatal("expected the primary feed to be the fastest, got", url)
+	}
+	// messages delivered before the previous cycle are still recognized
+	if deliverFromFeeds(tracker, 0, start, map[string]time.Duration{"secondary": 0})["secondary"] {
+		t.Fatal("passed on a message delivered before the last cycle")
+	}
+
+	// the secondary feed takes over once it delivers messages sooner
+	for i := 20; i < 60; i++ {
+		deliverFromFeeds(tracker, arbutil.MessageIndex(i), start, map[string]time.Duration{"secondary": 0, "primary": 80 * time.Millisecond})
+	}
+	if url, changed := tracker.cycle(urls, start); !changed || url != "secondary" {
+		t.Fatal("expected the secondary feed to become the fastest, got", url)
+	}
+	if _, changed := tracker.cycle(urls, start); changed {
+		t.Fatal("fastest feed changed without new deliveries")
+	}
+
+	// a feed that stopped delivering messages is no longer the fastest
+	if url, changed := tracker.cycle(urls, start.Add(2*MAX_FEED_INACTIVE_TIME)); changed || url != "" {
+		t.Fatal("expected no healthy feed, got", url)
+	}
+}
+
+func TestRouterOrderPrefersFastestFeed(t *testing.T) {
+	primary, secondary := &Router{}, &Router{}
+	clients := &BroadcastClients{
+		primaryRouter:   primary,
+		secondaryRouter: secondary,
+		primaryURL:      []string{"primary"},
+		secondaryURL:    []string{"secondary", "unstarted"},
+		// only the number of started secondary clients matters here
+		secondaryClients: make([]*broadcastclient.BroadcastClient, 1),
+	}
+	if preferred, other := clients.routerOrder("primary"); preferred != primary || other != secondary {
+		t.Fatal("expected the primary router read first while a primary feed is the fastest")
+	}
+	if preferred, other := clients.routerOrder("secondary"); preferred != secondary || other != primary {
+		t.Fatal("expected the secondary router read first while a secondary feed is the fastest")
+	}
+	if preferred, _ := clients.routerOrder("unstarted"); preferred != primary {
+		t.Fatal("preferred the router of a secondary feed that isn't running")
+	}
+	clients.secondaryClients = nil
+	if preferred, _ := clients.routerOrder("secondary"); preferred != primary {
+		t.Fatal("preferred the router of a stopped secondary feed")
+	}
+}
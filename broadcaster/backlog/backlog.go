@@ -40,12 +40,19 @@ type backlog struct {
 	lookupByIndex atomic.Pointer[containers.SyncMap[uint64, *backlogSegment]]
 	config        ConfigFetcher
 	messageCount  atomic.Uint64
+	spill         Spill
 }
 
 // NewBacklog creates a backlog.
 func NewBacklog(c ConfigFetcher) Backlog {
+	return NewBacklogWithSpill(c, nil)
+}
+
+// NewBacklogWithSpill creates a backlog which passes the messages it removes once confirmed to spill, if not nil.
+func NewBacklogWithSpill(c ConfigFetcher, spill Spill) Backlog {
 	b := &backlog{
 		config: c,
+		spill:  spill,
 	}
 	b.lookupByIndex.Store(&containers.SyncMap[uint64, *backlogSegment]{})
 	return b
@@ -209,9 +216,11 @@ func (b *backlog) delete(confirmed uint64) {
 
 	if confirmed > tail.End() {
 		log.Warn("confirmed sequence number is past the end of stored messages", "confirmed sequence number", confirmed, "last stored sequence number", tail.End())
+		b.spillConfirmed(head.Start(), tail.End())
 		b.reset()
 		return
 	}
+	b.spillConfirmed(head.Start(), confirmed)
 
 	confirmedSequenceNumberGauge.Update(int64(confirmed))
 
@@ -256,6 +265,19 @@ func (b *backlog) delete(confirmed uint64) {
 	b.head.Store(newHead)
 }
 
+// spillConfirmed passes the confirmed messages from start to end, which are about to be removed, to the spill.
+func (b *backlog) spillConfirmed(start, end uint64) {
+	if b.spill == nil {
+		return
+	}
+	bm, err := b.Get(start, end)
+	if err != nil {
+		log.Warn("error reading confirmed messages to spill from the feed backlog", "start", start, "end", end, "err", err)
+		return
+	}
+	b.spill.Spill(bm.Messages)
+}
+
 // removeFromLookup removes all entries from the head segment's start index to
 // the given confirmed index.
 func (b *backlog) removeFromLookup(start, end uint64) {
@@ -1,24 +1,66 @@
 package backlog
 
 import (
+	"errors"
+	"time"
+
 	flag "github.com/spf13/pflag"
 )
 
 type ConfigFetcher func() *Config
 
 type Config struct {
-	SegmentLimit int `koanf:"segment-limit" reload:"hot"`
+	SegmentLimit int        `koanf:"segment-limit" reload:"hot"`
+	Disk         DiskConfig `koanf:"disk"`
 }
 
 func AddOptions(prefix string, f *flag.FlagSet) {
 	f.Int(prefix+".segment-limit", DefaultConfig.SegmentLimit, "the maximum number of messages each segment within the backlog can contain")
+	DiskConfigAddOptions(prefix+".disk", f)
+}
+
+// DiskConfig configures where messages removed from the in-memory backlog once confirmed are kept, so
+// reconnecting clients can catch up from further back than fits in memory.
+type DiskConfig struct {
+	Enable             bool          `koanf:"enable"`
+	Directory          string        `koanf:"directory"`
+	Retention          time.Duration `koanf:"retention" reload:"hot"`
+	MaxCatchupMessages uint64        `koanf:"max-catchup-messages"`
+}
+
+func DiskConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultDiskConfig.Enable, "keep messages removed from the in-memory backlog on disk, to send to clients catching up from further back")
+	f.String(prefix+".directory", DefaultDiskConfig.Directory, "directory of the on-disk backlog database")
+	f.Duration(prefix+".retention", DefaultDiskConfig.Retention, "how long messages are kept in the on-disk backlog")
+	f.Uint64(prefix+".max-catchup-messages", DefaultDiskConfig.MaxCatchupMessages, "the maximum number of messages sent to a connecting client from the on-disk backlog")
+}
+
+func (c *DiskConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.Directory == "" {
+		return errors.New("the on-disk feed backlog requires a directory")
+	}
+	if c.Retention <= 0 {
+		return errors.New("the on-disk feed backlog retention must be positive")
+	}
+	return nil
 }
 
 var (
+	DefaultDiskConfig = DiskConfig{
+		Enable:             false,
+		Directory:          "",
+		Retention:          24 * time.Hour,
+		MaxCatchupMessages: 1_000_000,
+	}
 	DefaultConfig = Config{
 		SegmentLimit: 240,
+		Disk:         DefaultDiskConfig,
 	}
 	DefaultTestConfig = Config{
 		SegmentLimit: 3,
+		Disk:         DefaultDiskConfig,
 	}
 )
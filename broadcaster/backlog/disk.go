@@ -0,0 +1,226 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package backlog
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	m "github.com/offchainlabs/nitro/broadcaster/message"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+var (
+	diskWrittenCounter = metrics.NewRegisteredCounter("arb/feed/backlog/disk/written", nil)
+	diskDroppedCounter = metrics.NewRegisteredCounter("arb/feed/backlog/disk/dropped", nil)
+	diskPrunedCounter  = metrics.NewRegisteredCounter("arb/feed/backlog/disk/pruned", nil)
+	diskFirstGauge     = metrics.NewRegisteredGauge("arb/feed/backlog/disk/first", nil)
+	diskLastGauge      = metrics.NewRegisteredGauge("arb/feed/backlog/disk/last", nil)
+)
+
+const (
+	diskPendingBatches = 1024
+	diskPruneInterval  = time.Minute
+	// bounds each prune's write batch, pruning again right away if more messages expired
+	diskMaxPrunePerRound = 10_000
+)
+
+var diskMessagePrefix = []byte("m")
+
+// Spill receives the messages removed from the backlog once confirmed.
+type Spill interface {
+	Spill(msgs []*m.BroadcastFeedMessage)
+}
+
+// DiskBacklog keeps the messages confirmed out of the in-memory backlog on disk for the configured retention.
+// Messages are written in the background, so spilling doesn't slow broadcasting down. Each is stored under
+// its sequence number, prefixed by the time it was written, which the retention is measured from.
+type DiskBacklog struct {
+	stopwaiter.StopWaiter
+	config  func() *DiskConfig
+	db      ethdb.Database
+	pending chan []*m.BroadcastFeedMessage
+}
+
+func NewDiskBacklog(config func() *DiskConfig) *DiskBacklog {
+	return &DiskBacklog{
+		config:  config,
+		pending: make(chan []*m.BroadcastFeedMessage, diskPendingBatches),
+	}
+}
+
+// MaxCatchupMessages is the most messages sent to a connecting client from the on-disk backlog.
+func (d *DiskBacklog) MaxCatchupMessages() uint64 {
+	return d.config().MaxCatchupMessages
+}
+
+// Open opens the database, which must be done before Start.
+func (d *DiskBacklog) Open() error {
+	if d.db != nil {
+		return errors.New("on-disk feed backlog already open")
+	}
+	db, err := rawdb.NewLevelDBDatabase(d.config().Directory, 16, 16, "feedbacklog", false)
+	if err != nil {
+		return fmt.Errorf("error opening the on-disk feed backlog: %w", err)
+	}
+	d.db = db
+	return nil
+}
+
+func (d *DiskBacklog) Start(ctx context.Context) {
+	d.StopWaiter.Start(ctx, d)
+	d.LaunchThread(d.writeLoop)
+	d.CallIteratively(d.prune)
+}
+
+func (d *DiskBacklog) StopAndWait() {
+	d.StopWaiter.StopAndWait()
+	if d.db != nil {
+		if err := d.db.Close(); err != nil {
+			log.Warn("error closing the on-disk feed backlog", "err", err)
+		}
+	}
+}
+
+// Spill queues the messages to be written, dropping them if the writer is too far behind.
+func (d *DiskBacklog) Spill(msgs []*m.BroadcastFeedMessage) {
+	if len(msgs) == 0 {
+		return
+	}
+	select {
+	case d.pending <- msgs:
+	default:
+		diskDroppedCounter.Inc(int64(len(msgs)))
+		log.Warn("on-disk feed backlog writer is behind, dropping messages", "first", msgs[0].SequenceNumber, "count", len(msgs))
+	}
+}
+
+func diskMessageKey(seqNum arbutil.MessageIndex) []byte {
+	return binary.BigEndian.AppendUint64(append([]byte{}, diskMessagePrefix...), uint64(seqNum))
+}
+
+func (d *DiskBacklog) writeLoop(ctx context.Context) {
+	for {
+		select {
+		case msgs := <-d.pending:
+			if err := d.write(msgs); err != nil {
+				log.Error("error writing to the on-disk feed backlog", "err", err)
+			}
+		case <-ctx.Done():
+			// write what was already spilled before stopping
+			for {
+				select {
+				case msgs := <-d.pending:
+					if err := d.write(msgs); err != nil {
+						log.Error("error writing to the on-disk feed backlog", "err", err)
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (d *DiskBacklog) write(msgs []*m.BroadcastFeedMessage) error {
+	now := uint64(time.Now().Unix())
+	batch := d.db.NewBatch()
+	for _, msg := range msgs {
+		encoded, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		value := binary.BigEndian.AppendUint64(make([]byte, 0, 8+len(encoded)), now)
+		if err := batch.Put(diskMessageKey(msg.SequenceNumber), append(value, encoded...)); err != nil {
+			return err
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	diskWrittenCounter.Inc(int64(len(msgs)))
+	diskLastGauge.Update(int64(msgs[len(msgs)-1].SequenceNumber))
+	return nil
+}
+
+// prune deletes the messages written longer than the retention ago.
+func (d *DiskBacklog) prune(ctx context.Context) time.Duration {
+	cutoff := uint64(time.Now().Add(-d.config().Retention).Unix())
+	iter := d.db.NewIterator(diskMessagePrefix, nil)
+	defer iter.Release()
+	batch := d.db.NewBatch()
+	pruned := 0
+	more := false
+	for iter.Next() {
+		value := iter.Value()
+		if len(value) >= 8 && binary.BigEndian.Uint64(value) >= cutoff {
+			diskFirstGauge.Update(int64(binary.BigEndian.Uint64(iter.Key()[len(diskMessagePrefix):])))
+			break
+		}
+		if pruned >= diskMaxPrunePerRound {
+			more = true
+			break
+		}
+		if err := batch.Delete(iter.Key()); err != nil {
+			log.Error("error pruning the on-disk feed backlog", "err", err)
+			return diskPruneInterval
+		}
+		pruned++
+	}
+	if err := iter.Error(); err != nil {
+		log.Error("error reading the on-disk feed backlog", "err", err)
+		return diskPruneInterval
+	}
+	if err := batch.Write(); err != nil {
+		log.Error("error pruning the on-disk feed backlog", "err", err)
+		return diskPruneInterval
+	}
+	diskPrunedCounter.Inc(int64(pruned))
+	if more {
+		return 0
+	}
+	return diskPruneInterval
+}
+
+// FeedMessages returns up to count consecutive messages starting at from, fewer if it doesn't have them.
+// This lets the broadcast server fill the gap before the in-memory backlog for clients catching up.
+func (d *DiskBacklog) FeedMessages(ctx context.Context, from arbutil.MessageIndex, count uint64) ([]*m.BroadcastFeedMessage, error) {
+	iter := d.db.NewIterator(diskMessagePrefix, diskMessageKey(from)[len(diskMessagePrefix):])
+	defer iter.Release()
+	var msgs []*m.BroadcastFeedMessage
+	for next := from; uint64(len(msgs)) < count && iter.Next(); next++ {
+		key := iter.Key()
+		if len(key) != len(diskMessagePrefix)+8 || binary.BigEndian.Uint64(key[len(diskMessagePrefix):]) != uint64(next) {
+			// messages may be missing if they were dropped or the in-memory backlog was reset
+			break
+		}
+		value := iter.Value()
+		if len(value) < 8 {
+			return nil, fmt.Errorf("invalid on-disk feed backlog entry for message %v", next)
+		}
+		msg := &m.BroadcastFeedMessage{}
+		if err := json.Unmarshal(value[8:], msg); err != nil {
+			return nil, fmt.Errorf("error decoding on-disk feed backlog message %v: %w", next, err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, iter.Error()
+}
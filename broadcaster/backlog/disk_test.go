@@ -0,0 +1,103 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package backlog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	m "github.com/offchainlabs/nitro/broadcaster/message"
+)
+
+type testSpill struct {
+	spilled []*m.BroadcastFeedMessage
+}
+
+func (s *testSpill) Spill(msgs []*m.BroadcastFeedMessage) {
+	s.spilled = append(s.spilled, msgs...)
+}
+
+func TestBacklogSpillsConfirmedMessages(t *testing.T) {
+	spill := &testSpill{}
+	b := NewBacklogWithSpill(func() *Config { return &DefaultTestConfig }, spill)
+	if err := b.Append(m.CreateDummyBroadcastMessage([]arbutil.MessageIndex{40, 41, 42, 43, 44, 45, 46})); err != nil {
+		t.Fatal(err)
+	}
+	confirm := func(seqNum arbutil.MessageIndex) {
+		t.Helper()
+		err := b.Append(&m.BroadcastMessage{ConfirmedSequenceNumberMessage: &m.ConfirmedSequenceNumberMessage{SequenceNumber: seqNum}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	confirm(43)
+	confirm(44)
+	// past the end, so all of the backlog is removed
+	confirm(50)
+	if len(spill.spilled) != 7 {
+		t.Fatalf("expected 7 messages spilled, got %v", len(spill.spilled))
+	}
+	for i, msg := range spill.spilled {
+		if msg.SequenceNumber != arbutil.MessageIndex(40+i) {
+			t.Fatalf("expected message %v spilled, got %v", 40+i, msg.SequenceNumber)
+		}
+	}
+}
+
+func TestDiskBacklog(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config := DefaultDiskConfig
+	config.Enable = true
+	config.Directory = t.TempDir()
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	disk := NewDiskBacklog(func() *DiskConfig { return &config })
+	if err := disk.Open(); err != nil {
+		t.Fatal(err)
+	}
+	disk.Start(ctx)
+	defer disk.StopAndWait()
+
+	disk.Spill(m.CreateDummyBroadcastMessages([]arbutil.MessageIndex{10, 11, 12, 13}))
+	// a gap, as if messages had been dropped
+	disk.Spill(m.CreateDummyBroadcastMessages([]arbutil.MessageIndex{15, 16}))
+
+	checkMessages := func(from arbutil.MessageIndex, count uint64, expected int) {
+		t.Helper()
+		var msgs []*m.BroadcastFeedMessage
+		for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+			var err error
+			msgs, err = disk.FeedMessages(ctx, from, count)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(msgs) == expected {
+				break
+			}
+		}
+		if len(msgs) != expected {
+			t.Fatalf("expected %v messages from %v, got %v", expected, from, len(msgs))
+		}
+		for i, msg := range msgs {
+			if msg.SequenceNumber != from+arbutil.MessageIndex(i) {
+				t.Fatalf("expected message %v, got %v", from+arbutil.MessageIndex(i), msg.SequenceNumber)
+			}
+		}
+	}
+	checkMessages(15, 10, 2)
+	checkMessages(11, 2, 2)
+	// stops at the gap
+	checkMessages(10, 10, 4)
+	checkMessages(14, 10, 0)
+
+	// once past the retention, messages are pruned
+	config.Retention = -time.Hour
+	disk.prune(ctx)
+	checkMessages(10, 10, 0)
+}
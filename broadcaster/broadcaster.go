@@ -23,17 +23,32 @@ import (
 )
 
 type Broadcaster struct {
+	config     wsbroadcastserver.BroadcasterConfigFetcher
 	server     *wsbroadcastserver.WSBroadcastServer
 	backlog    backlog.Backlog
+	disk       *backlog.DiskBacklog
 	chainId    uint64
 	dataSigner signature.DataSignerFunc
+
+	gapFiller          wsbroadcastserver.GapFiller
+	maxGapFillMessages uint64
 }
 
 func NewBroadcaster(config wsbroadcastserver.BroadcasterConfigFetcher, chainId uint64, feedErrChan chan error, dataSigner signature.DataSignerFunc) *Broadcaster {
-	bklg := backlog.NewBacklog(func() *backlog.Config { return &config().Backlog })
+	backlogConfig := func() *backlog.Config { return &config().Backlog }
+	var disk *backlog.DiskBacklog
+	var bklg backlog.Backlog
+	if config().Backlog.Disk.Enable {
+		disk = backlog.NewDiskBacklog(func() *backlog.DiskConfig { return &config().Backlog.Disk })
+		bklg = backlog.NewBacklogWithSpill(backlogConfig, disk)
+	} else {
+		bklg = backlog.NewBacklog(backlogConfig)
+	}
 	return &Broadcaster{
+		config:     config,
 		server:     wsbroadcastserver.NewWSBroadcastServer(config, bklg, chainId, feedErrChan),
 		backlog:    bklg,
+		disk:       disk,
 		chainId:    chainId,
 		dataSigner: dataSigner,
 	}
@@ -45,6 +60,7 @@ func (b *Broadcaster) NewBroadcastFeedMessage(
 	blockHash *common.Hash,
 ) (*m.BroadcastFeedMessage, error) {
 	var messageSignature []byte
+	var keyId uint64
 	if b.dataSigner != nil {
 		hash, err := message.Hash(sequenceNumber, b.chainId)
 		if err != nil {
@@ -54,6 +70,7 @@ func (b *Broadcaster) NewBroadcastFeedMessage(
 		if err != nil {
 			return nil, err
 		}
+		keyId = b.config().SigningKeyId
 	}
 
 	return &m.BroadcastFeedMessage{
@@ -61,6 +78,7 @@ func (b *Broadcaster) NewBroadcastFeedMessage(
 		Message:        message,
 		BlockHash:      blockHash,
 		Signature:      messageSignature,
+		KeyId:          keyId,
 	}, nil
 }
 
@@ -148,20 +166,96 @@ func (b *Broadcaster) GetCachedMessageCount() int {
 	return int(b.backlog.Count())
 }
 
+// SetGapFiller sets where the messages clients request from before the backlog are read from. With the
+// on-disk backlog enabled, they're read from it first, and from the gap filler if it doesn't have them.
+func (b *Broadcaster) SetGapFiller(gapFiller wsbroadcastserver.GapFiller, maxMessages uint64) {
+	b.gapFiller = gapFiller
+	b.maxGapFillMessages = maxMessages
+	b.server.SetGapFiller(gapFiller, maxMessages)
+}
+
+// FeedMessages returns up to count consecutive messages starting at from, fewer if it doesn't have them.
+// Messages in the backlog are read from it, and those preceding it from where gaps are filled.
+func (b *Broadcaster) FeedMessages(ctx context.Context, from arbutil.MessageIndex, count uint64) ([]*m.BroadcastFeedMessage, error) {
+	if count == 0 {
+		return nil, nil
+	}
+	if head := b.backlog.Head(); !backlog.IsBacklogSegmentNil(head) && uint64(from) >= head.Start() {
+		last := head.Start() + b.backlog.Count() - 1
+		if uint64(from) > last {
+			return nil, nil
+		}
+		bm, err := b.backlog.Get(uint64(from), min(uint64(from)+count-1, last))
+		if err != nil {
+			return nil, err
+		}
+		return bm.Messages, nil
+	}
+	var gapFiller wsbroadcastserver.GapFiller = b.gapFiller
+	if b.disk != nil {
+		gapFiller = &diskGapFiller{disk: b.disk, next: b.gapFiller}
+	}
+	if gapFiller == nil {
+		return nil, nil
+	}
+	return gapFiller.FeedMessages(ctx, from, count)
+}
+
 func (b *Broadcaster) Initialize() error {
+	if b.disk != nil {
+		if err := b.disk.Open(); err != nil {
+			return err
+		}
+		maxMessages := max(b.disk.MaxCatchupMessages(), b.maxGapFillMessages)
+		b.server.SetGapFiller(&diskGapFiller{disk: b.disk, next: b.gapFiller}, maxMessages)
+	}
 	return b.server.Initialize()
 }
 
 func (b *Broadcaster) Start(ctx context.Context) error {
+	if b.disk != nil {
+		b.disk.Start(ctx)
+	}
 	return b.server.Start(ctx)
 }
 
 func (b *Broadcaster) StartWithHeader(ctx context.Context, header ws.HandshakeHeader) error {
+	if b.disk != nil {
+		b.disk.Start(ctx)
+	}
 	return b.server.StartWithHeader(ctx, header)
 }
 
+// StartOn starts broadcasting this broadcaster's chain through the listener of host, which serves another chain.
+func (b *Broadcaster) StartOn(ctx context.Context, host *Broadcaster) error {
+	if b.disk != nil {
+		b.disk.Start(ctx)
+	}
+	return b.server.StartOn(ctx, host.server)
+}
+
 func (b *Broadcaster) StopAndWait() {
 	b.server.StopAndWait()
+	if b.disk != nil {
+		b.disk.StopAndWait()
+	}
+}
+
+// diskGapFiller fills gaps from the on-disk backlog, and from next what it doesn't have.
+type diskGapFiller struct {
+	disk *backlog.DiskBacklog
+	next wsbroadcastserver.GapFiller
+}
+
+func (g *diskGapFiller) FeedMessages(ctx context.Context, from arbutil.MessageIndex, count uint64) ([]*m.BroadcastFeedMessage, error) {
+	msgs, err := g.disk.FeedMessages(ctx, from, count)
+	if err != nil {
+		log.Warn("error reading the on-disk feed backlog", "from", from, "err", err)
+	}
+	if len(msgs) > 0 || g.next == nil {
+		return msgs, nil
+	}
+	return g.next.FeedMessages(ctx, from, count)
 }
 
 func (b *Broadcaster) Started() bool {
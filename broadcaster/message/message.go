@@ -36,6 +36,9 @@ type BroadcastFeedMessage struct {
 	Message        arbostypes.MessageWithMetadata `json:"message"`
 	BlockHash      *common.Hash                   `json:"blockHash,omitempty"`
 	Signature      []byte                         `json:"signature"`
+	// KeyId identifies the key the message was signed with, letting clients follow key rotations.
+	// Zero if the broadcaster wasn't configured with one.
+	KeyId uint64 `json:"keyId,omitempty"`
 
 	CumulativeSumMsgSize uint64 `json:"-"`
 }
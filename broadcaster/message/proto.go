@@ -0,0 +1,259 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package message
+
+import (
+	"fmt"
+	"math/big"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+)
+
+// EncodeProto encodes the message in the protobuf encoding described by feed.proto.
+func EncodeProto(bm *BroadcastMessage) []byte {
+	var b []byte
+	b = appendProtoUint(b, 1, uint64(bm.Version))
+	for _, msg := range bm.Messages {
+		if msg == nil {
+			continue
+		}
+		b = appendProtoBytes(b, 2, appendProtoFeedMessage(nil, msg))
+	}
+	if bm.ConfirmedSequenceNumberMessage != nil {
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(bm.ConfirmedSequenceNumberMessage.SequenceNumber))
+	}
+	return b
+}
+
+func appendProtoFeedMessage(b []byte, msg *BroadcastFeedMessage) []byte {
+	b = appendProtoUint(b, 1, uint64(msg.SequenceNumber))
+	var inner []byte
+	if msg.Message.Message != nil {
+		inner = appendProtoBytes(inner, 1, appendProtoIncomingMessage(nil, msg.Message.Message))
+	}
+	inner = appendProtoUint(inner, 2, msg.Message.DelayedMessagesRead)
+	b = appendProtoBytes(b, 2, inner)
+	if msg.BlockHash != nil {
+		b = appendProtoBytes(b, 3, msg.BlockHash.Bytes())
+	}
+	if len(msg.Signature) > 0 {
+		b = appendProtoBytes(b, 4, msg.Signature)
+	}
+	b = appendProtoUint(b, 5, msg.KeyId)
+	return b
+}
+
+func appendProtoIncomingMessage(b []byte, msg *arbostypes.L1IncomingMessage) []byte {
+	if header := msg.Header; header != nil {
+		var h []byte
+		h = appendProtoUint(h, 1, uint64(header.Kind))
+		h = appendProtoBytes(h, 2, header.Poster.Bytes())
+		h = appendProtoUint(h, 3, header.BlockNumber)
+		h = appendProtoUint(h, 4, header.Timestamp)
+		if header.RequestId != nil {
+			h = appendProtoBytes(h, 5, header.RequestId.Bytes())
+		}
+		if header.L1BaseFee != nil {
+			h = appendProtoBytes(h, 6, header.L1BaseFee.Bytes())
+		}
+		b = appendProtoBytes(b, 1, h)
+	}
+	if len(msg.L2msg) > 0 {
+		b = appendProtoBytes(b, 2, msg.L2msg)
+	}
+	if msg.BatchGasCost != nil {
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		b = protowire.AppendVarint(b, *msg.BatchGasCost)
+	}
+	return b
+}
+
+// appendProtoUint appends a non-optional integer field, which is omitted when zero.
+func appendProtoUint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendProtoBytes(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+// DecodeProto decodes a message in the protobuf encoding described by feed.proto.
+func DecodeProto(data []byte) (*BroadcastMessage, error) {
+	bm := &BroadcastMessage{}
+	err := readProtoFields(data, func(f *protoField) error {
+		switch f.num {
+		case 1:
+			bm.Version = int(f.varint)
+		case 2:
+			msg, err := decodeProtoFeedMessage(f.bytes)
+			if err != nil {
+				return err
+			}
+			bm.Messages = append(bm.Messages, msg)
+		case 3:
+			bm.ConfirmedSequenceNumberMessage = &ConfirmedSequenceNumberMessage{SequenceNumber: arbutil.MessageIndex(f.varint)}
+		}
+		return nil
+	}, broadcastMessageProtoFields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid protobuf feed message: %w", err)
+	}
+	return bm, nil
+}
+
+func decodeProtoFeedMessage(data []byte) (*BroadcastFeedMessage, error) {
+	msg := &BroadcastFeedMessage{}
+	err := readProtoFields(data, func(f *protoField) error {
+		switch f.num {
+		case 1:
+			msg.SequenceNumber = arbutil.MessageIndex(f.varint)
+		case 2:
+			return readProtoFields(f.bytes, func(f *protoField) error {
+				switch f.num {
+				case 1:
+					incoming, err := decodeProtoIncomingMessage(f.bytes)
+					if err != nil {
+						return err
+					}
+					msg.Message.Message = incoming
+				case 2:
+					msg.Message.DelayedMessagesRead = f.varint
+				}
+				return nil
+			}, messageWithMetadataProtoFields)
+		case 3:
+			hash, err := protoHash(f.bytes)
+			if err != nil {
+				return err
+			}
+			msg.BlockHash = &hash
+		case 4:
+			msg.Signature = f.bytes
+		case 5:
+			msg.KeyId = f.varint
+		}
+		return nil
+	}, feedMessageProtoFields)
+	return msg, err
+}
+
+func decodeProtoIncomingMessage(data []byte) (*arbostypes.L1IncomingMessage, error) {
+	msg := &arbostypes.L1IncomingMessage{}
+	err := readProtoFields(data, func(f *protoField) error {
+		switch f.num {
+		case 1:
+			header := &arbostypes.L1IncomingMessageHeader{}
+			msg.Header = header
+			return readProtoFields(f.bytes, func(f *protoField) error {
+				switch f.num {
+				case 1:
+					if f.varint > 0xff {
+						return fmt.Errorf("message kind %v out of range", f.varint)
+					}
+					header.Kind = uint8(f.varint)
+				case 2:
+					if len(f.bytes) != common.AddressLength {
+						return fmt.Errorf("poster is %v bytes", len(f.bytes))
+					}
+					header.Poster = common.BytesToAddress(f.bytes)
+				case 3:
+					header.BlockNumber = f.varint
+				case 4:
+					header.Timestamp = f.varint
+				case 5:
+					requestId, err := protoHash(f.bytes)
+					if err != nil {
+						return err
+					}
+					header.RequestId = &requestId
+				case 6:
+					header.L1BaseFee = new(big.Int).SetBytes(f.bytes)
+				}
+				return nil
+			}, incomingMessageHeaderProtoFields)
+		case 2:
+			msg.L2msg = f.bytes
+		case 3:
+			batchGasCost := f.varint
+			msg.BatchGasCost = &batchGasCost
+		}
+		return nil
+	}, incomingMessageProtoFields)
+	return msg, err
+}
+
+func protoHash(data []byte) (common.Hash, error) {
+	if len(data) != common.HashLength {
+		return common.Hash{}, fmt.Errorf("hash is %v bytes", len(data))
+	}
+	return common.BytesToHash(data), nil
+}
+
+type protoField struct {
+	num    protowire.Number
+	varint uint64
+	bytes  []byte
+}
+
+// protoFieldTypes are the wire types of a message's known fields.
+type protoFieldTypes map[protowire.Number]protowire.Type
+
+var (
+	broadcastMessageProtoFields      = protoFieldTypes{1: protowire.VarintType, 2: protowire.BytesType, 3: protowire.VarintType}
+	feedMessageProtoFields           = protoFieldTypes{1: protowire.VarintType, 2: protowire.BytesType, 3: protowire.BytesType, 4: protowire.BytesType, 5: protowire.VarintType}
+	messageWithMetadataProtoFields   = protoFieldTypes{1: protowire.BytesType, 2: protowire.VarintType}
+	incomingMessageProtoFields       = protoFieldTypes{1: protowire.BytesType, 2: protowire.BytesType, 3: protowire.VarintType}
+	incomingMessageHeaderProtoFields = protoFieldTypes{
+		1: protowire.VarintType, 2: protowire.BytesType, 3: protowire.VarintType,
+		4: protowire.VarintType, 5: protowire.BytesType, 6: protowire.BytesType,
+	}
+)
+
+// readProtoFields calls handle with each known field of the message, skipping unknown fields.
+func readProtoFields(data []byte, handle func(f *protoField) error, types protoFieldTypes) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		expected, known := types[num]
+		if !known {
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+			continue
+		}
+		if typ != expected {
+			return fmt.Errorf("field %v has wire type %v, expected %v", num, typ, expected)
+		}
+		field := protoField{num: num}
+		if typ == protowire.VarintType {
+			field.varint, n = protowire.ConsumeVarint(data)
+		} else {
+			field.bytes, n = protowire.ConsumeBytes(data)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		if err := handle(&field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package message
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+)
+
+func TestProtoRoundTrip(t *testing.T) {
+	requestId := common.Hash{0: 0x01, 31: 0x02}
+	batchGasCost := uint64(123456)
+	bm := &BroadcastMessage{
+		Version: V1,
+		Messages: []*BroadcastFeedMessage{
+			{
+				SequenceNumber: 12345,
+				Message: arbostypes.MessageWithMetadata{
+					Message: &arbostypes.L1IncomingMessage{
+						Header: &arbostypes.L1IncomingMessageHeader{
+							Kind:        arbostypes.L1MessageType_BatchPostingReport,
+							Poster:      common.Address{19: 0xaa},
+							BlockNumber: 99,
+							Timestamp:   1700000000,
+							RequestId:   &requestId,
+							L1BaseFee:   big.NewInt(0),
+						},
+						L2msg:        []byte{0xde, 0xad, 0xbe, 0xef},
+						BatchGasCost: &batchGasCost,
+					},
+					DelayedMessagesRead: 3333,
+				},
+				BlockHash: &common.Hash{0: 0xff},
+				Signature: []byte{1, 2, 3},
+				KeyId:     7,
+			},
+			{
+				SequenceNumber: 12346,
+				Message: arbostypes.MessageWithMetadata{
+					Message: &arbostypes.L1IncomingMessage{
+						Header: &arbostypes.L1IncomingMessageHeader{
+							Kind:      arbostypes.L1MessageType_L2Message,
+							L1BaseFee: big.NewInt(1_000_000_000_000),
+						},
+						L2msg: []byte{0x01},
+					},
+				},
+			},
+		},
+		ConfirmedSequenceNumberMessage: &ConfirmedSequenceNumberMessage{SequenceNumber: 0},
+	}
+
+	decoded, err := DecodeProto(EncodeProto(bm))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := json.Marshal(bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(expected) != string(actual) {
+		t.Fatalf("protobuf round trip changed the message\nexpected: %s\nactual:   %s", expected, actual)
+	}
+
+	// fields added in later versions of the schema are skipped
+	extended := protowire.AppendTag(EncodeProto(bm), 100, protowire.BytesType)
+	extended = protowire.AppendBytes(extended, []byte("unknown"))
+	if _, err := DecodeProto(extended); err != nil {
+		t.Fatal("unknown field not skipped:", err)
+	}
+
+	// a truncated message is rejected
+	encoded := EncodeProto(bm)
+	if _, err := DecodeProto(encoded[:len(encoded)-5]); err == nil {
+		t.Fatal("truncated message decoded")
+	}
+}
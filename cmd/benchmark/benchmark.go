@@ -0,0 +1,373 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+// benchmark drives a local devnet with a reproducible load profile and reports the sequencer's throughput,
+// transaction latency, batch sizes and validator throughput, so runs against different releases can be compared.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/offchainlabs/nitro/arbnode"
+	"github.com/offchainlabs/nitro/cmd/genericconf"
+	"github.com/offchainlabs/nitro/cmd/util/confighelpers"
+	"github.com/offchainlabs/nitro/staker"
+)
+
+type BenchmarkConfig struct {
+	L2URL      string `koanf:"l2-url"`
+	PrivateKey string `koanf:"private-key"`
+	// optional, to measure batches
+	L1URL          string `koanf:"l1-url"`
+	SequencerInbox string `koanf:"sequencer-inbox"`
+	// optional, to measure validation
+	ValidatorURL string `koanf:"validator-url"`
+
+	Profile             string        `koanf:"profile"`
+	Accounts            int           `koanf:"accounts"`
+	Rate                float64       `koanf:"rate"`
+	Duration            time.Duration `koanf:"duration"`
+	CalldataSize        int           `koanf:"calldata-size"`
+	Seed                int64         `koanf:"seed"`
+	FundingTxs          uint64        `koanf:"funding-txs"`
+	ReceiptPollInterval time.Duration `koanf:"receipt-poll-interval"`
+	SettleTime          time.Duration `koanf:"settle-time"`
+
+	Label               string  `koanf:"label"`
+	Output              string  `koanf:"output"`
+	Baseline            string  `koanf:"baseline"`
+	RegressionThreshold float64 `koanf:"regression-threshold"`
+
+	Conf     genericconf.ConfConfig `koanf:"conf"`
+	LogLevel string                 `koanf:"log-level"`
+	LogType  string                 `koanf:"log-type"`
+}
+
+var DefaultBenchmarkConfig = BenchmarkConfig{
+	L2URL:               "http://localhost:8547",
+	Profile:             profileTransfer,
+	Accounts:            16,
+	Duration:            time.Minute,
+	CalldataSize:        10_000,
+	Seed:                1,
+	FundingTxs:          100_000,
+	ReceiptPollInterval: 10 * time.Millisecond,
+	SettleTime:          time.Minute,
+	RegressionThreshold: 10,
+	Conf:                genericconf.ConfConfigDefault,
+	LogLevel:            "INFO",
+	LogType:             "plaintext",
+}
+
+func main() {
+	if err := startup(); err != nil {
+		log.Error("benchmark failed", "err", err)
+		os.Exit(1)
+	}
+}
+
+func parseBenchmark(args []string) (*BenchmarkConfig, error) {
+	f := flag.NewFlagSet("benchmark", flag.ContinueOnError)
+	f.String("l2-url", DefaultBenchmarkConfig.L2URL, "RPC URL of the devnet's sequencer")
+	f.String("private-key", DefaultBenchmarkConfig.PrivateKey, "hex private key of a funded devnet account to fund the load generating accounts from")
+	f.String("l1-url", DefaultBenchmarkConfig.L1URL, "RPC URL of the devnet's parent chain, to measure the batches posted during the run")
+	f.String("sequencer-inbox", DefaultBenchmarkConfig.SequencerInbox, "address of the devnet's sequencer inbox on the parent chain, to measure the batches posted during the run")
+	f.String("validator-url", DefaultBenchmarkConfig.ValidatorURL, "RPC URL of a devnet node running the block validator, with the arb namespace enabled, to measure validation throughput")
+	f.String("profile", DefaultBenchmarkConfig.Profile, "load profile, one of "+strings.Join(profiles, ", "))
+	f.Int("accounts", DefaultBenchmarkConfig.Accounts, "number of accounts sending transactions concurrently, each waiting for its last transaction's receipt before sending the next")
+	f.Float64("rate", DefaultBenchmarkConfig.Rate, "transactions per second to send across all accounts (0 = as fast as the accounts can)")
+	f.Duration("duration", DefaultBenchmarkConfig.Duration, "how long to generate load for")
+	f.Int("calldata-size", DefaultBenchmarkConfig.CalldataSize, "bytes of random calldata in each transaction of the calldata profile")
+	f.Int64("seed", DefaultBenchmarkConfig.Seed, "seed of the load generating accounts, recipients and calldata, so runs are reproducible")
+	f.Uint64("funding-txs", DefaultBenchmarkConfig.FundingTxs, "how many transactions to fund each load generating account for")
+	f.Duration("receipt-poll-interval", DefaultBenchmarkConfig.ReceiptPollInterval, "how often to poll for receipts, which bounds the latency measurement's resolution")
+	f.Duration("settle-time", DefaultBenchmarkConfig.SettleTime, "how long to wait after the load for batches to be posted and blocks to be validated before measuring them")
+	f.String("label", DefaultBenchmarkConfig.Label, "label of this run in the report, such as the release under test")
+	f.String("output", DefaultBenchmarkConfig.Output, "file to write the JSON report to (empty = stdout)")
+	f.String("baseline", DefaultBenchmarkConfig.Baseline, "JSON report of an earlier run to compare against, failing if any metric regressed")
+	f.Float64("regression-threshold", DefaultBenchmarkConfig.RegressionThreshold, "percent a metric may be worse than the baseline's before it's a regression")
+	f.String("log-level", DefaultBenchmarkConfig.LogLevel, "log level, valid values are CRIT, ERROR, WARN, INFO, DEBUG, TRACE")
+	f.String("log-type", DefaultBenchmarkConfig.LogType, "log type (plaintext or json)")
+	genericconf.ConfConfigAddOptions("conf", f)
+
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+	var config BenchmarkConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (c *BenchmarkConfig) Validate() error {
+	if !slices.Contains(profiles, c.Profile) {
+		return fmt.Errorf("invalid profile %q, must be one of %v", c.Profile, strings.Join(profiles, ", "))
+	}
+	if c.PrivateKey == "" {
+		return errors.New("private-key is required to fund the load generating accounts")
+	}
+	if c.Accounts < 1 || c.Duration <= 0 || c.ReceiptPollInterval <= 0 || c.FundingTxs == 0 {
+		return errors.New("accounts, duration, receipt-poll-interval and funding-txs must be positive")
+	}
+	if c.Rate < 0 || c.CalldataSize < 0 {
+		return errors.New("rate and calldata-size must not be negative")
+	}
+	if (c.L1URL == "") != (c.SequencerInbox == "") {
+		return errors.New("measuring batches requires both l1-url and sequencer-inbox")
+	}
+	if c.SequencerInbox != "" && !common.IsHexAddress(c.SequencerInbox) {
+		return fmt.Errorf("invalid sequencer-inbox address %q", c.SequencerInbox)
+	}
+	return nil
+}
+
+func startup() error {
+	config, err := parseBenchmark(os.Args[1:])
+	if err != nil {
+		confighelpers.PrintErrorAndExit(err, func(string) {
+			fmt.Printf("\nSample usage: %s --l2-url <url> --private-key <hex> --profile transfer --duration 5m\n", os.Args[0])
+		})
+	}
+	if err := genericconf.InitLog(config.LogType, config.LogLevel, &genericconf.FileLoggingConfig{}, nil); err != nil {
+		return fmt.Errorf("error initializing logging: %w", err)
+	}
+	funder, err := crypto.HexToECDSA(strings.TrimPrefix(config.PrivateKey, "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid private-key: %w", err)
+	}
+	ctx := context.Background()
+
+	l2, err := ethclient.DialContext(ctx, config.L2URL)
+	if err != nil {
+		return err
+	}
+	defer l2.Close()
+	var l1 *ethclient.Client
+	if config.L1URL != "" {
+		l1, err = ethclient.DialContext(ctx, config.L1URL)
+		if err != nil {
+			return err
+		}
+		defer l1.Close()
+	}
+	var validator *ethclient.Client
+	if config.ValidatorURL != "" {
+		validator, err = ethclient.DialContext(ctx, config.ValidatorURL)
+		if err != nil {
+			return err
+		}
+		defer validator.Close()
+	}
+
+	generator, err := newLoadGenerator(ctx, l2, config)
+	if err != nil {
+		return err
+	}
+	keys, err := generator.setup(ctx, funder)
+	if err != nil {
+		return err
+	}
+
+	report := &Report{
+		Label:    config.Label,
+		Started:  time.Now().UTC(),
+		Profile:  config.Profile,
+		Accounts: config.Accounts,
+		Rate:     config.Rate,
+		Seed:     config.Seed,
+	}
+	if config.Profile == profileCalldata {
+		report.CalldataSize = config.CalldataSize
+	}
+	startBlock, err := l2.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	var startL1Block uint64
+	if l1 != nil {
+		if startL1Block, err = l1.BlockNumber(ctx); err != nil {
+			return err
+		}
+	}
+	var startValidated uint64
+	if validator != nil {
+		if startValidated, err = latestValidatedBlock(ctx, validator, l2); err != nil {
+			return err
+		}
+	}
+
+	log.Info("generating load", "profile", config.Profile, "accounts", config.Accounts, "rate", config.Rate, "duration", config.Duration)
+	start := time.Now()
+	runCtx, cancel := context.WithTimeout(ctx, config.Duration)
+	results, err := generator.run(runCtx, keys)
+	cancel()
+	elapsed := time.Since(start)
+	if err != nil {
+		return err
+	}
+	report.Duration = elapsed.Seconds()
+	report.addResults(results)
+	endBlock, err := l2.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	report.Sequencer, err = sequencerStats(ctx, l2, startBlock+1, endBlock, elapsed)
+	if err != nil {
+		return err
+	}
+	log.Info("load done", "sent", report.Sent, "failed", report.Failed, "tps", report.Sequencer.TPS, "latencyP50Ms", report.Latency.P50)
+
+	if l1 != nil || validator != nil {
+		log.Info("waiting for batches and validation to catch up", "settleTime", config.SettleTime)
+		time.Sleep(config.SettleTime)
+	}
+	if l1 != nil {
+		endL1Block, err := l1.BlockNumber(ctx)
+		if err != nil {
+			return err
+		}
+		report.Batches, err = batchStats(ctx, l1, common.HexToAddress(config.SequencerInbox), startL1Block+1, endL1Block)
+		if err != nil {
+			return err
+		}
+	}
+	if validator != nil {
+		endValidated, err := latestValidatedBlock(ctx, validator, l2)
+		if err != nil {
+			return err
+		}
+		validated := endValidated - min(startValidated, endValidated)
+		report.Validator = &ValidatorStats{
+			ValidatedBlocks: validated,
+			BlocksPerSecond: float64(validated) / (elapsed + config.SettleTime).Seconds(),
+			LagBlocks:       endBlock - min(endBlock, endValidated),
+		}
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if config.Output != "" {
+		err = os.WriteFile(config.Output, append(data, '\n'), 0600)
+	} else {
+		_, err = fmt.Println(string(data))
+	}
+	if err != nil {
+		return err
+	}
+
+	if config.Baseline != "" {
+		baseline, err := readReport(config.Baseline)
+		if err != nil {
+			return err
+		}
+		regressions, err := compareReports(baseline, report, config.RegressionThreshold)
+		if err != nil {
+			return err
+		}
+		for _, regression := range regressions {
+			log.Error("benchmark regressed", "metric", regression.Metric, "baseline", regression.Baseline, "current", regression.Current, "changePercent", regression.Change)
+		}
+		if len(regressions) > 0 {
+			return fmt.Errorf("%v metrics regressed from baseline %v by more than %v%%", len(regressions), baseline.Label, config.RegressionThreshold)
+		}
+		log.Info("no regressions from baseline", "baseline", baseline.Label)
+	}
+	return nil
+}
+
+// sequencerStats measures the blocks the sequencer produced during the run, not counting internal transactions.
+func sequencerStats(ctx context.Context, client *ethclient.Client, from uint64, to uint64, elapsed time.Duration) (SequencerStats, error) {
+	var stats SequencerStats
+	var gasUsed uint64
+	var perBlock []uint64
+	for number := from; number <= to; number++ {
+		block, err := client.BlockByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return stats, err
+		}
+		var txs uint64
+		for _, tx := range block.Transactions() {
+			if tx.Type() != types.ArbitrumInternalTxType {
+				txs++
+			}
+		}
+		stats.Blocks++
+		stats.Transactions += txs
+		gasUsed += block.GasUsed()
+		perBlock = append(perBlock, txs)
+	}
+	if len(perBlock) > 0 {
+		sort.Slice(perBlock, func(i, j int) bool { return perBlock[i] < perBlock[j] })
+		stats.TxsPerBlockMedian = perBlock[len(perBlock)/2]
+	}
+	stats.TPS = float64(stats.Transactions) / elapsed.Seconds()
+	stats.GasPerSecond = float64(gasUsed) / elapsed.Seconds()
+	return stats, nil
+}
+
+// batchStats measures the batches posted to the sequencer inbox in the parent chain block range.
+func batchStats(ctx context.Context, client *ethclient.Client, inboxAddr common.Address, from uint64, to uint64) (*BatchStats, error) {
+	inbox, err := arbnode.NewSequencerInbox(client, inboxAddr, int64(from))
+	if err != nil {
+		return nil, err
+	}
+	stats := &BatchStats{}
+	if from > to {
+		return stats, nil
+	}
+	batches, err := inbox.LookupBatchesInRange(ctx, new(big.Int).SetUint64(from), new(big.Int).SetUint64(to))
+	if err != nil {
+		return nil, err
+	}
+	for _, batch := range batches {
+		data, err := batch.Serialize(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		size := uint64(len(data))
+		stats.Count++
+		stats.TotalBytes += size
+		stats.MaxBytes = max(stats.MaxBytes, size)
+	}
+	if stats.Count > 0 {
+		stats.MeanBytes = float64(stats.TotalBytes) / float64(stats.Count)
+	}
+	return stats, nil
+}
+
+// latestValidatedBlock returns the number of the latest block the validator has validated.
+func latestValidatedBlock(ctx context.Context, validator *ethclient.Client, l2 *ethclient.Client) (uint64, error) {
+	var info staker.GlobalStateValidatedInfo
+	if err := validator.Client().CallContext(ctx, &info, "arb_latestValidated"); err != nil {
+		return 0, fmt.Errorf("failed to get the latest validated block: %w", err)
+	}
+	header, err := l2.HeaderByHash(ctx, info.GlobalState.BlockHash)
+	if err != nil {
+		return 0, err
+	}
+	return header.Number.Uint64(), nil
+}
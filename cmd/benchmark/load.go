@@ -0,0 +1,317 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	profileTransfer = "transfer"
+	profileERC20    = "erc20"
+	profileCalldata = "calldata"
+)
+
+var profiles = []string{profileTransfer, profileERC20, profileCalldata}
+
+// tokenTransferTopic is the ERC-20 Transfer(address,address,uint256) event topic.
+var tokenTransferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// tokenRuntimeCode moves balances and logs a Transfer event like an ERC-20 transfer(address,uint256) call, storing
+// each balance at the slot of its holder's address. Balances wrap rather than revert, so no minting is needed.
+func tokenRuntimeCode() []byte {
+	code := []byte{
+		byte(vm.PUSH1), 0x24, byte(vm.CALLDATALOAD), // amount
+		byte(vm.DUP1), byte(vm.CALLER), byte(vm.SLOAD), byte(vm.SUB), byte(vm.CALLER), byte(vm.SSTORE),
+		byte(vm.PUSH1), 0x04, byte(vm.CALLDATALOAD), // amount, to
+		byte(vm.DUP2), byte(vm.DUP2), byte(vm.SLOAD), byte(vm.ADD), byte(vm.DUP2), byte(vm.SSTORE),
+		byte(vm.DUP2), byte(vm.PUSH1), 0x00, byte(vm.MSTORE),
+		byte(vm.CALLER), byte(vm.PUSH32),
+	}
+	code = append(code, tokenTransferTopic.Bytes()...)
+	return append(code, byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x00, byte(vm.LOG3), byte(vm.STOP))
+}
+
+// tokenInitCode deploys tokenRuntimeCode.
+func tokenInitCode() []byte {
+	runtime := tokenRuntimeCode()
+	size := byte(len(runtime))
+	init := []byte{
+		byte(vm.PUSH1), size, byte(vm.PUSH1), 12, byte(vm.PUSH1), 0x00, byte(vm.CODECOPY),
+		byte(vm.PUSH1), size, byte(vm.PUSH1), 0x00, byte(vm.RETURN),
+	}
+	return append(init, runtime...)
+}
+
+func tokenTransferCalldata(to common.Address, amount uint64) []byte {
+	data := crypto.Keccak256([]byte("transfer(address,uint256)"))[:4]
+	data = append(data, common.LeftPadBytes(to.Bytes(), 32)...)
+	return append(data, common.LeftPadBytes(new(big.Int).SetUint64(amount).Bytes(), 32)...)
+}
+
+// workerKey derives the key of the ith load generating account from the seed, so reruns use the same accounts.
+func workerKey(seed int64, i int) (*ecdsa.PrivateKey, error) {
+	material := binary.BigEndian.AppendUint64([]byte("nitro-benchmark"), uint64(seed))
+	material = binary.BigEndian.AppendUint64(material, uint64(i))
+	return crypto.ToECDSA(crypto.Keccak256(material))
+}
+
+// txResult is the outcome of one load transaction.
+type txResult struct {
+	latency  time.Duration
+	gasUsed  uint64
+	calldata int
+	err      error
+}
+
+type loadGenerator struct {
+	client   *ethclient.Client
+	chainId  *big.Int
+	config   *BenchmarkConfig
+	signer   types.Signer
+	gasPrice *big.Int
+	token    common.Address
+	// gas limits from estimates with headroom, as L2 gas also pays for posting the transaction to the parent chain
+	fundingGas uint64
+	loadGas    uint64
+}
+
+func newLoadGenerator(ctx context.Context, client *ethclient.Client, config *BenchmarkConfig) (*loadGenerator, error) {
+	chainId, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// headroom so the load itself raising the base fee doesn't strand transactions
+	gasPrice.Mul(gasPrice, big.NewInt(4))
+	return &loadGenerator{
+		client:   client,
+		chainId:  chainId,
+		config:   config,
+		signer:   types.LatestSignerForChainID(chainId),
+		gasPrice: gasPrice,
+	}, nil
+}
+
+func (g *loadGenerator) send(ctx context.Context, key *ecdsa.PrivateKey, nonce uint64, to *common.Address, value *big.Int, data []byte, gas uint64) (*types.Transaction, error) {
+	tx, err := types.SignNewTx(key, g.signer, &types.DynamicFeeTx{
+		ChainID:   g.chainId,
+		Nonce:     nonce,
+		GasTipCap: common.Big0,
+		GasFeeCap: g.gasPrice,
+		Gas:       gas,
+		To:        to,
+		Value:     value,
+		Data:      data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tx, g.client.SendTransaction(ctx, tx)
+}
+
+// waitForReceipt polls for the transaction's receipt, as the benchmark measures latency more finely than the
+// default polling of bind.WaitMined.
+func (g *loadGenerator) waitForReceipt(ctx context.Context, tx *types.Transaction) (*types.Receipt, error) {
+	ticker := time.NewTicker(g.config.ReceiptPollInterval)
+	defer ticker.Stop()
+	for {
+		receipt, err := g.client.TransactionReceipt(ctx, tx.Hash())
+		if err == nil {
+			return receipt, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (g *loadGenerator) sendAndWait(ctx context.Context, key *ecdsa.PrivateKey, nonce uint64, to *common.Address, value *big.Int, data []byte, gas uint64) (*types.Receipt, error) {
+	tx, err := g.send(ctx, key, nonce, to, value, data, gas)
+	if err != nil {
+		return nil, err
+	}
+	receipt, err := g.waitForReceipt(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, fmt.Errorf("transaction %v failed", tx.Hash())
+	}
+	return receipt, nil
+}
+
+func (g *loadGenerator) estimateGas(ctx context.Context, from common.Address, to *common.Address, value *big.Int, data []byte) (uint64, error) {
+	gas, err := g.client.EstimateGas(ctx, ethereum.CallMsg{From: from, To: to, GasFeeCap: g.gasPrice, Value: value, Data: data})
+	if err != nil {
+		return 0, err
+	}
+	return gas * 3 / 2, nil
+}
+
+// setup funds the load generating accounts from the funder, and deploys the token for the erc20 profile.
+func (g *loadGenerator) setup(ctx context.Context, funder *ecdsa.PrivateKey) ([]*ecdsa.PrivateKey, error) {
+	funderAddr := crypto.PubkeyToAddress(funder.PublicKey)
+	nonce, err := g.client.PendingNonceAt(ctx, funderAddr)
+	if err != nil {
+		return nil, err
+	}
+	if g.config.Profile == profileERC20 {
+		gas, err := g.estimateGas(ctx, funderAddr, nil, common.Big0, tokenInitCode())
+		if err != nil {
+			return nil, err
+		}
+		receipt, err := g.sendAndWait(ctx, funder, nonce, nil, common.Big0, tokenInitCode(), gas)
+		if err != nil {
+			return nil, fmt.Errorf("failed to deploy the benchmark token: %w", err)
+		}
+		g.token = receipt.ContractAddress
+		nonce++
+		log.Info("deployed benchmark token", "address", g.token)
+	}
+	// the sample recipient is a fresh address, as most of the load's are
+	sample := common.HexToAddress("0x00000000000000000000000000000000000b3c4")
+	to, value, data := g.profileTx(rand.New(rand.NewSource(g.config.Seed)), sample)
+	g.loadGas, err = g.estimateGas(ctx, funderAddr, to, value, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate the %v profile's gas: %w", g.config.Profile, err)
+	}
+	g.fundingGas, err = g.estimateGas(ctx, funderAddr, &sample, common.Big1, nil)
+	if err != nil {
+		return nil, err
+	}
+	// enough for the gas and value of the transactions each account is funded for
+	funding := new(big.Int).Mul(g.gasPrice, new(big.Int).SetUint64(g.loadGas))
+	funding.Add(funding, big.NewInt(1_000_000))
+	funding.Mul(funding, new(big.Int).SetUint64(g.config.FundingTxs))
+	var keys []*ecdsa.PrivateKey
+	var last *types.Transaction
+	for i := 0; i < g.config.Accounts; i++ {
+		key, err := workerKey(g.config.Seed, i)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		last, err = g.send(ctx, funder, nonce, &addr, funding, nil, g.fundingGas)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fund benchmark account %v: %w", addr, err)
+		}
+		nonce++
+	}
+	if last != nil {
+		if _, err := g.waitForReceipt(ctx, last); err != nil {
+			return nil, err
+		}
+	}
+	log.Info("funded benchmark accounts", "accounts", len(keys), "each", funding)
+	return keys, nil
+}
+
+// run generates the profile's load from each account until the context is done, at up to the configured rate.
+func (g *loadGenerator) run(ctx context.Context, keys []*ecdsa.PrivateKey) ([]txResult, error) {
+	var tokens <-chan time.Time
+	if g.config.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / g.config.Rate))
+		defer ticker.Stop()
+		tokens = ticker.C
+	}
+	var mutex sync.Mutex
+	var results []txResult
+	var wg sync.WaitGroup
+	errs := make(chan error, len(keys))
+	for i, key := range keys {
+		wg.Add(1)
+		go func(worker int, key *ecdsa.PrivateKey) {
+			defer wg.Done()
+			// each account gets its own deterministic sequence of recipients and calldata
+			random := rand.New(rand.NewSource(g.config.Seed + int64(worker)))
+			nonce, err := g.client.PendingNonceAt(ctx, crypto.PubkeyToAddress(key.PublicKey))
+			if err != nil {
+				errs <- err
+				return
+			}
+			for {
+				if tokens != nil {
+					select {
+					case <-tokens:
+					case <-ctx.Done():
+						return
+					}
+				} else if ctx.Err() != nil {
+					return
+				}
+				result := g.next(ctx, random, key, nonce)
+				if ctx.Err() != nil {
+					// the run ended while waiting, so the transaction's latency isn't known
+					return
+				}
+				if result.err == nil {
+					nonce++
+				} else if nonce, err = g.client.PendingNonceAt(ctx, crypto.PubkeyToAddress(key.PublicKey)); err != nil {
+					errs <- err
+					return
+				}
+				mutex.Lock()
+				results = append(results, result)
+				mutex.Unlock()
+			}
+		}(i, key)
+	}
+	wg.Wait()
+	close(errs)
+	var err error
+	for workerErr := range errs {
+		err = errors.Join(err, workerErr)
+	}
+	return results, err
+}
+
+// profileTx returns the profile's next transaction to the recipient.
+func (g *loadGenerator) profileTx(random *rand.Rand, recipient common.Address) (*common.Address, *big.Int, []byte) {
+	switch g.config.Profile {
+	case profileERC20:
+		return &g.token, common.Big0, tokenTransferCalldata(recipient, uint64(1+random.Int63n(1_000_000)))
+	case profileCalldata:
+		data := make([]byte, g.config.CalldataSize)
+		random.Read(data)
+		return &recipient, common.Big0, data
+	default:
+		return &recipient, big.NewInt(1 + random.Int63n(1_000_000)), nil
+	}
+}
+
+func (g *loadGenerator) next(ctx context.Context, random *rand.Rand, key *ecdsa.PrivateKey, nonce uint64) txResult {
+	var recipient common.Address
+	random.Read(recipient[:])
+	to, value, data := g.profileTx(random, recipient)
+	start := time.Now()
+	receipt, err := g.sendAndWait(ctx, key, nonce, to, value, data, g.loadGas)
+	result := txResult{latency: time.Since(start), calldata: len(data), err: err}
+	if receipt != nil {
+		result.gasUsed = receipt.GasUsedForL2()
+	}
+	return result
+}
@@ -0,0 +1,176 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// Report is the outcome of a benchmark run, written as JSON so runs against different releases can be compared.
+type Report struct {
+	Label    string    `json:"label"`
+	Started  time.Time `json:"started"`
+	Profile  string    `json:"profile"`
+	Accounts int       `json:"accounts"`
+	Rate     float64   `json:"rate"`
+	Seed     int64     `json:"seed"`
+	Duration float64   `json:"durationSeconds"`
+	// only set for the calldata profile
+	CalldataSize int `json:"calldataSize,omitempty"`
+
+	Sent    uint64 `json:"sent"`
+	Failed  uint64 `json:"failed"`
+	GasUsed uint64 `json:"gasUsed"`
+
+	Sequencer SequencerStats  `json:"sequencer"`
+	Latency   LatencyStats    `json:"latency"`
+	Batches   *BatchStats     `json:"batches,omitempty"`
+	Validator *ValidatorStats `json:"validator,omitempty"`
+}
+
+type SequencerStats struct {
+	Blocks            uint64  `json:"blocks"`
+	Transactions      uint64  `json:"transactions"`
+	TPS               float64 `json:"tps"`
+	GasPerSecond      float64 `json:"gasPerSecond"`
+	TxsPerBlockMedian uint64  `json:"txsPerBlockMedian"`
+}
+
+// LatencyStats are in milliseconds from sending a transaction to getting its receipt.
+type LatencyStats struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+type BatchStats struct {
+	Count      uint64  `json:"count"`
+	TotalBytes uint64  `json:"totalBytes"`
+	MeanBytes  float64 `json:"meanBytes"`
+	MaxBytes   uint64  `json:"maxBytes"`
+}
+
+type ValidatorStats struct {
+	ValidatedBlocks uint64  `json:"validatedBlocks"`
+	BlocksPerSecond float64 `json:"blocksPerSecond"`
+	// how far validation was behind the last block of the run when the report was made
+	LagBlocks uint64 `json:"lagBlocks"`
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// percentile returns the pth percentile of sorted durations, by the nearest rank.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	} else if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+func summarizeLatency(results []txResult) LatencyStats {
+	var latencies []time.Duration
+	var total time.Duration
+	for _, result := range results {
+		if result.err == nil {
+			latencies = append(latencies, result.latency)
+			total += result.latency
+		}
+	}
+	if len(latencies) == 0 {
+		return LatencyStats{}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return LatencyStats{
+		Mean: millis(total) / float64(len(latencies)),
+		P50:  millis(percentile(latencies, 50)),
+		P90:  millis(percentile(latencies, 90)),
+		P99:  millis(percentile(latencies, 99)),
+		Max:  millis(latencies[len(latencies)-1]),
+	}
+}
+
+func (r *Report) addResults(results []txResult) {
+	for _, result := range results {
+		r.Sent++
+		if result.err != nil {
+			r.Failed++
+			continue
+		}
+		r.GasUsed += result.gasUsed
+	}
+	r.Latency = summarizeLatency(results)
+}
+
+func readReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("invalid benchmark report %v: %w", path, err)
+	}
+	return &report, nil
+}
+
+// Regression is a metric that got worse than the baseline by more than the threshold.
+type Regression struct {
+	Metric   string
+	Baseline float64
+	Current  float64
+	// relative change for the worse, in percent
+	Change float64
+}
+
+// compareReports returns the metrics of the current report that regressed from the baseline by more than
+// threshold percent. Metrics missing from either report are skipped.
+func compareReports(baseline *Report, current *Report, threshold float64) ([]Regression, error) {
+	if baseline.Profile != current.Profile || baseline.Accounts != current.Accounts || baseline.Rate != current.Rate || baseline.CalldataSize != current.CalldataSize {
+		return nil, fmt.Errorf("baseline %v ran a different load (%v with %v accounts at rate %v) than this run (%v with %v accounts at rate %v)",
+			baseline.Label, baseline.Profile, baseline.Accounts, baseline.Rate, current.Profile, current.Accounts, current.Rate)
+	}
+	type metric struct {
+		name           string
+		baseline       float64
+		current        float64
+		higherIsBetter bool
+	}
+	metrics := []metric{
+		{"sequencer tps", baseline.Sequencer.TPS, current.Sequencer.TPS, true},
+		{"sequencer gas per second", baseline.Sequencer.GasPerSecond, current.Sequencer.GasPerSecond, true},
+		{"latency p50 ms", baseline.Latency.P50, current.Latency.P50, false},
+		{"latency p99 ms", baseline.Latency.P99, current.Latency.P99, false},
+	}
+	if baseline.Validator != nil && current.Validator != nil {
+		metrics = append(metrics, metric{"validator blocks per second", baseline.Validator.BlocksPerSecond, current.Validator.BlocksPerSecond, true})
+	}
+	var regressions []Regression
+	for _, m := range metrics {
+		if m.baseline == 0 {
+			continue
+		}
+		change := (m.current - m.baseline) / m.baseline * 100
+		if m.higherIsBetter {
+			change = -change
+		}
+		if change > threshold {
+			regressions = append(regressions, Regression{Metric: m.name, Baseline: m.baseline, Current: m.current, Change: change})
+		}
+	}
+	return regressions, nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSummarizeLatency(t *testing.T) {
+	var results []txResult
+	for i := 1; i <= 100; i++ {
+		results = append(results, txResult{latency: time.Duration(i) * time.Millisecond})
+	}
+	results = append(results, txResult{latency: time.Hour, err: errors.New("failed")})
+	report := &Report{}
+	report.addResults(results)
+	if report.Sent != 101 || report.Failed != 1 {
+		t.Errorf("unexpected counts, sent %v failed %v", report.Sent, report.Failed)
+	}
+	expected := LatencyStats{Mean: 50.5, P50: 50, P90: 90, P99: 99, Max: 100}
+	if report.Latency != expected {
+		t.Errorf("expected latency %+v, got %+v", expected, report.Latency)
+	}
+}
+
+func TestCompareReports(t *testing.T) {
+	baseline := &Report{
+		Label:     "v1",
+		Profile:   profileTransfer,
+		Accounts:  16,
+		Sequencer: SequencerStats{TPS: 1000, GasPerSecond: 1e7},
+		Latency:   LatencyStats{P50: 100, P99: 400},
+	}
+	current := *baseline
+	current.Sequencer.TPS = 950
+	current.Latency.P99 = 500
+	regressions, err := compareReports(baseline, &current, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regressions) != 1 || regressions[0].Metric != "latency p99 ms" || regressions[0].Change != 25 {
+		t.Errorf("expected only the p99 latency to regress, got %+v", regressions)
+	}
+
+	current.Profile = profileERC20
+	if _, err := compareReports(baseline, &current, 10); err == nil {
+		t.Error("compared runs of different profiles")
+	}
+}
+
+func TestTokenInitCode(t *testing.T) {
+	init := tokenInitCode()
+	// the init code copies the runtime code from right after itself
+	if !bytes.Equal(init[12:], tokenRuntimeCode()) || int(init[1]) != len(tokenRuntimeCode()) {
+		t.Errorf("token init code doesn't deploy the runtime code")
+	}
+}
@@ -0,0 +1,306 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+// capacity-planner simulates how the L2 base fee and gas backlog evolve under recorded or synthetic traffic,
+// so chain owners can try out speed limit, gas limit and pricing inertia changes before making them on-chain.
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/offchainlabs/nitro/arbos/l2pricing"
+	"github.com/offchainlabs/nitro/cmd/genericconf"
+	"github.com/offchainlabs/nitro/cmd/util/confighelpers"
+	"github.com/offchainlabs/nitro/solgen/go/precompilesgen"
+	"github.com/offchainlabs/nitro/util/arbmath"
+)
+
+type CapacityPlannerConfig struct {
+	// where to read the current parameters and recorded traffic from
+	ChainURL  string `koanf:"chain-url"`
+	FromBlock uint64 `koanf:"from-block"`
+	ToBlock   uint64 `koanf:"to-block"`
+	// CSV of timestamp,gas rows, as an alternative to recording traffic from the chain
+	TrafficFile string           `koanf:"traffic-file"`
+	Synthetic   SyntheticProfile `koanf:"synthetic"`
+
+	// parameter overrides; 0 keeps the chain's value, or ArbOS' initial value without a chain
+	SpeedLimitPerSecond uint64  `koanf:"speed-limit"`
+	PerBlockGasLimit    uint64  `koanf:"block-gas-limit"`
+	PricingInertia      uint64  `koanf:"pricing-inertia"`
+	BacklogTolerance    uint64  `koanf:"backlog-tolerance"`
+	MinBaseFeeGwei      float64 `koanf:"min-base-fee-gwei"`
+
+	Output  string        `koanf:"output"`
+	Timeout time.Duration `koanf:"timeout"`
+
+	Conf     genericconf.ConfConfig `koanf:"conf"`
+	LogLevel string                 `koanf:"log-level"`
+	LogType  string                 `koanf:"log-type"`
+}
+
+var DefaultCapacityPlannerConfig = CapacityPlannerConfig{
+	Synthetic: SyntheticProfile{
+		Duration:        3600,
+		BlockTimeMillis: 250,
+		BurstMultiplier: 1,
+	},
+	Timeout:  time.Hour,
+	Conf:     genericconf.ConfConfigDefault,
+	LogLevel: "INFO",
+	LogType:  "plaintext",
+}
+
+func main() {
+	if err := startup(); err != nil {
+		log.Error("capacity planning failed", "err", err)
+		os.Exit(1)
+	}
+}
+
+func parseCapacityPlanner(args []string) (*CapacityPlannerConfig, error) {
+	f := flag.NewFlagSet("capacity-planner", flag.ContinueOnError)
+	f.String("chain-url", DefaultCapacityPlannerConfig.ChainURL, "RPC URL of the chain to read the current pricing parameters and recorded traffic from")
+	f.Uint64("from-block", DefaultCapacityPlannerConfig.FromBlock, "first block of recorded traffic to replay from the chain")
+	f.Uint64("to-block", DefaultCapacityPlannerConfig.ToBlock, "last block of recorded traffic to replay from the chain (0 = don't replay recorded traffic)")
+	f.String("traffic-file", DefaultCapacityPlannerConfig.TrafficFile, "CSV file of timestamp,gas rows, one per block, to replay")
+	f.Uint64("synthetic.duration", DefaultCapacityPlannerConfig.Synthetic.Duration, "seconds of synthetic traffic to generate when no recorded traffic is given")
+	f.Uint64("synthetic.block-time-millis", DefaultCapacityPlannerConfig.Synthetic.BlockTimeMillis, "time between synthetic blocks in milliseconds")
+	f.Uint64("synthetic.gas-per-second", DefaultCapacityPlannerConfig.Synthetic.GasPerSecond, "gas demanded per second by the synthetic traffic (0 = the speed limit)")
+	f.Uint64("synthetic.burst-start", DefaultCapacityPlannerConfig.Synthetic.BurstStart, "second at which the synthetic traffic bursts")
+	f.Uint64("synthetic.burst-duration", DefaultCapacityPlannerConfig.Synthetic.BurstDuration, "how many seconds the synthetic burst lasts")
+	f.Float64("synthetic.burst-multiplier", DefaultCapacityPlannerConfig.Synthetic.BurstMultiplier, "how much the synthetic demand is multiplied by during the burst")
+	f.Uint64("speed-limit", DefaultCapacityPlannerConfig.SpeedLimitPerSecond, "speed limit in gas per second to simulate")
+	f.Uint64("block-gas-limit", DefaultCapacityPlannerConfig.PerBlockGasLimit, "per block gas limit to simulate")
+	f.Uint64("pricing-inertia", DefaultCapacityPlannerConfig.PricingInertia, "pricing inertia to simulate")
+	f.Uint64("backlog-tolerance", DefaultCapacityPlannerConfig.BacklogTolerance, "backlog tolerance in seconds of the speed limit to simulate")
+	f.Float64("min-base-fee-gwei", DefaultCapacityPlannerConfig.MinBaseFeeGwei, "minimum base fee in gwei to simulate")
+	f.String("output", DefaultCapacityPlannerConfig.Output, "file to write the simulated blocks to as CSV (empty = stdout)")
+	f.Duration("timeout", DefaultCapacityPlannerConfig.Timeout, "timeout for reading from the chain")
+	f.String("log-level", DefaultCapacityPlannerConfig.LogLevel, "log level, valid values are CRIT, ERROR, WARN, INFO, DEBUG, TRACE")
+	f.String("log-type", DefaultCapacityPlannerConfig.LogType, "log type (plaintext or json)")
+	genericconf.ConfConfigAddOptions("conf", f)
+
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+	var config CapacityPlannerConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	if config.ToBlock != 0 {
+		if config.ChainURL == "" {
+			return nil, errors.New("replaying recorded traffic requires chain-url")
+		}
+		if config.FromBlock > config.ToBlock {
+			return nil, errors.New("from-block must not be after to-block")
+		}
+		if config.TrafficFile != "" {
+			return nil, errors.New("traffic-file and recorded traffic from the chain can't be used together")
+		}
+	}
+	return &config, nil
+}
+
+func startup() error {
+	config, err := parseCapacityPlanner(os.Args[1:])
+	if err != nil {
+		confighelpers.PrintErrorAndExit(err, func(string) {
+			fmt.Printf("\nSample usage: %s --chain-url <url> --from-block <n> --to-block <m> --speed-limit <gas per second>\n", os.Args[0])
+		})
+	}
+	if err := genericconf.InitLog(config.LogType, config.LogLevel, &genericconf.FileLoggingConfig{}, nil); err != nil {
+		return fmt.Errorf("error initializing logging: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+
+	params := &PricingParams{
+		SpeedLimitPerSecond: l2pricing.InitialSpeedLimitPerSecondV6,
+		PerBlockGasLimit:    l2pricing.InitialPerBlockGasLimitV6,
+		PricingInertia:      l2pricing.InitialPricingInertia,
+		BacklogTolerance:    l2pricing.InitialBacklogTolerance,
+		MinBaseFee:          big.NewInt(l2pricing.InitialMinimumBaseFeeWei),
+	}
+	var client *ethclient.Client
+	if config.ChainURL != "" {
+		client, err = ethclient.DialContext(ctx, config.ChainURL)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		if err := readChainParams(ctx, client, params); err != nil {
+			return fmt.Errorf("failed to read the chain's pricing parameters: %w", err)
+		}
+		log.Info("read the chain's pricing parameters", "speedLimit", params.SpeedLimitPerSecond, "blockGasLimit", params.PerBlockGasLimit, "inertia", params.PricingInertia, "tolerance", params.BacklogTolerance, "minBaseFee", params.MinBaseFee, "backlog", params.InitialBacklog)
+	}
+	if config.SpeedLimitPerSecond != 0 {
+		params.SpeedLimitPerSecond = config.SpeedLimitPerSecond
+	}
+	if config.PerBlockGasLimit != 0 {
+		params.PerBlockGasLimit = config.PerBlockGasLimit
+	}
+	if config.PricingInertia != 0 {
+		params.PricingInertia = config.PricingInertia
+	}
+	if config.BacklogTolerance != 0 {
+		params.BacklogTolerance = config.BacklogTolerance
+	}
+	if config.MinBaseFeeGwei != 0 {
+		params.MinBaseFee = arbmath.FloatToBig(config.MinBaseFeeGwei * 1e9)
+	}
+
+	var traffic []TrafficBlock
+	switch {
+	case config.ToBlock != 0:
+		traffic, err = recordedTraffic(ctx, client, config.FromBlock, config.ToBlock)
+	case config.TrafficFile != "":
+		traffic, err = trafficFromFile(config.TrafficFile)
+	default:
+		profile := config.Synthetic
+		if profile.GasPerSecond == 0 {
+			profile.GasPerSecond = params.SpeedLimitPerSecond
+		}
+		traffic, err = profile.Traffic()
+	}
+	if err != nil {
+		return err
+	}
+
+	out := io.Writer(os.Stdout)
+	if config.Output != "" {
+		file, err := os.Create(config.Output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	writer := csv.NewWriter(out)
+	if err := writer.Write([]string{"timestamp", "demand_gas", "included_gas", "queued_gas", "gas_backlog", "base_fee_wei"}); err != nil {
+		return err
+	}
+	summary, err := Simulate(params, traffic, func(block *SimulatedBlock) error {
+		return writer.Write([]string{
+			strconv.FormatUint(block.Timestamp, 10),
+			strconv.FormatUint(block.DemandGas, 10),
+			strconv.FormatUint(block.IncludedGas, 10),
+			strconv.FormatUint(block.QueuedGas, 10),
+			strconv.FormatUint(block.GasBacklog, 10),
+			block.BaseFee.String(),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	log.Info(
+		"simulation complete",
+		"blocks", summary.Blocks,
+		"seconds", summary.Seconds,
+		"demandGas", summary.DemandGas,
+		"includedGas", summary.IncludedGas,
+		"maxQueuedGas", summary.MaxQueuedGas,
+		"maxQueueDelaySeconds", summary.MaxQueueDelaySeconds,
+		"maxGasBacklog", summary.MaxGasBacklog,
+		"maxBaseFee", summary.MaxBaseFee,
+		"meanBaseFee", summary.MeanBaseFee,
+		"secondsAboveMinBaseFee", summary.SecondsAboveMin,
+		"finalBaseFee", summary.FinalBaseFee,
+	)
+	return nil
+}
+
+func readChainParams(ctx context.Context, client *ethclient.Client, params *PricingParams) error {
+	arbGasInfo, err := precompilesgen.NewArbGasInfo(types.ArbGasInfoAddress, client)
+	if err != nil {
+		return err
+	}
+	callOpts := &bind.CallOpts{Context: ctx}
+	speedLimit, blockGasLimit, _, err := arbGasInfo.GetGasAccountingParams(callOpts)
+	if err != nil {
+		return err
+	}
+	params.SpeedLimitPerSecond = speedLimit.Uint64()
+	params.PerBlockGasLimit = blockGasLimit.Uint64()
+	if params.PricingInertia, err = arbGasInfo.GetPricingInertia(callOpts); err != nil {
+		return err
+	}
+	if params.BacklogTolerance, err = arbGasInfo.GetGasBacklogTolerance(callOpts); err != nil {
+		return err
+	}
+	if params.MinBaseFee, err = arbGasInfo.GetMinimumGasPrice(callOpts); err != nil {
+		return err
+	}
+	params.InitialBacklog, err = arbGasInfo.GetGasBacklog(callOpts)
+	return err
+}
+
+func recordedTraffic(ctx context.Context, client *ethclient.Client, from, to uint64) ([]TrafficBlock, error) {
+	traffic := make([]TrafficBlock, 0, to-from+1)
+	for number := from; number <= to; number++ {
+		header, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read block %v: %w", number, err)
+		}
+		traffic = append(traffic, TrafficBlock{Timestamp: header.Time, Gas: header.GasUsed})
+		if (number-from)%10_000 == 0 {
+			log.Info("reading recorded traffic", "block", number, "to", to)
+		}
+	}
+	return traffic, nil
+}
+
+func trafficFromFile(path string) ([]TrafficBlock, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 2
+	reader.Comment = '#'
+	var traffic []TrafficBlock
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		timestamp, err := strconv.ParseUint(record[0], 10, 64)
+		if err != nil {
+			if line == 1 {
+				// header row
+				continue
+			}
+			return nil, fmt.Errorf("invalid timestamp on line %v: %w", line, err)
+		}
+		gas, err := strconv.ParseUint(record[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gas on line %v: %w", line, err)
+		}
+		traffic = append(traffic, TrafficBlock{Timestamp: timestamp, Gas: gas})
+	}
+	return traffic, nil
+}
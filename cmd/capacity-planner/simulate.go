@@ -0,0 +1,207 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/offchainlabs/nitro/arbos/burn"
+	"github.com/offchainlabs/nitro/arbos/l2pricing"
+	"github.com/offchainlabs/nitro/arbos/storage"
+	"github.com/offchainlabs/nitro/util/arbmath"
+)
+
+// PricingParams are the chain owner tunable parameters of the L2 pricing model.
+type PricingParams struct {
+	SpeedLimitPerSecond uint64
+	PerBlockGasLimit    uint64
+	PricingInertia      uint64
+	BacklogTolerance    uint64
+	MinBaseFee          *big.Int
+	// the gas backlog at the start of the simulation
+	InitialBacklog uint64
+}
+
+func (p *PricingParams) Validate() error {
+	if p.SpeedLimitPerSecond == 0 || p.PerBlockGasLimit == 0 || p.PricingInertia == 0 {
+		return errors.New("speed limit, per block gas limit and pricing inertia must be positive")
+	}
+	if p.MinBaseFee == nil || p.MinBaseFee.Sign() <= 0 {
+		return errors.New("minimum base fee must be positive")
+	}
+	return nil
+}
+
+// TrafficBlock is the gas demanded in one block of traffic.
+type TrafficBlock struct {
+	Timestamp uint64
+	Gas       uint64
+}
+
+// SimulatedBlock is the state of the chain after a simulated block.
+type SimulatedBlock struct {
+	Timestamp uint64
+	// gas demanded by the traffic in this block
+	DemandGas uint64
+	// gas actually included, which the per block gas limit may cap
+	IncludedGas uint64
+	// demanded gas still waiting to be included
+	QueuedGas  uint64
+	GasBacklog uint64
+	BaseFee    *big.Int
+}
+
+type SimulationSummary struct {
+	Blocks               uint64
+	Seconds              uint64
+	DemandGas            uint64
+	IncludedGas          uint64
+	MaxQueuedGas         uint64
+	MaxGasBacklog        uint64
+	MaxBaseFee           *big.Int
+	MeanBaseFee          *big.Int
+	SecondsAboveMin      uint64
+	FinalQueuedGas       uint64
+	FinalBaseFee         *big.Int
+	FinalGasBacklog      uint64
+	MaxQueueDelaySeconds uint64
+}
+
+// Simulate runs the traffic through ArbOS' L2 pricing model under the given parameters, calling
+// record with each block. Demand that doesn't fit in a block is carried over to the next ones.
+func Simulate(params *PricingParams, traffic []TrafficBlock, record func(*SimulatedBlock) error) (*SimulationSummary, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	sto := storage.NewMemoryBacked(burn.NewSystemBurner(nil, false))
+	if err := l2pricing.InitializeL2PricingState(sto); err != nil {
+		return nil, err
+	}
+	pricing := l2pricing.OpenL2PricingState(sto)
+	for _, err := range []error{
+		pricing.SetSpeedLimitPerSecond(params.SpeedLimitPerSecond),
+		pricing.SetMaxPerBlockGasLimit(params.PerBlockGasLimit),
+		pricing.SetPricingInertia(params.PricingInertia),
+		pricing.SetBacklogTolerance(params.BacklogTolerance),
+		pricing.SetMinBaseFeeWei(params.MinBaseFee),
+		pricing.SetBaseFeeWei(params.MinBaseFee),
+		pricing.SetGasBacklog(params.InitialBacklog),
+	} {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	summary := &SimulationSummary{
+		MaxBaseFee:  new(big.Int),
+		MeanBaseFee: new(big.Int),
+	}
+	if len(traffic) == 0 {
+		summary.FinalBaseFee = params.MinBaseFee
+		return summary, nil
+	}
+	baseFeeTimeSum := new(big.Int)
+	lastTimestamp := traffic[0].Timestamp
+	var queued uint64
+	// when the oldest queued gas was demanded
+	var queuedSince uint64
+	for _, block := range traffic {
+		timePassed := arbmath.SaturatingUSub(block.Timestamp, lastTimestamp)
+		baseFee, err := pricing.BaseFeeWei()
+		if err != nil {
+			return nil, err
+		}
+		// the base fee applies over the time since the last block
+		baseFeeTimeSum.Add(baseFeeTimeSum, arbmath.BigMulByUint(baseFee, timePassed))
+		if baseFee.Cmp(params.MinBaseFee) > 0 {
+			summary.SecondsAboveMin += timePassed
+		}
+		lastTimestamp = arbmath.MaxInt(lastTimestamp, block.Timestamp)
+
+		// ArbOS updates the base fee at the start of each block, and charges the block's gas to the backlog
+		pricing.UpdatePricingModel(baseFee, timePassed, false)
+		baseFee, err = pricing.BaseFeeWei()
+		if err != nil {
+			return nil, err
+		}
+		if queued == 0 {
+			queuedSince = block.Timestamp
+		}
+		queued = arbmath.SaturatingUAdd(queued, block.Gas)
+		included := arbmath.MinInt(queued, params.PerBlockGasLimit)
+		queued -= included
+		if queued == 0 {
+			queuedSince = block.Timestamp
+		}
+		if err := pricing.AddToGasPool(-arbmath.SaturatingCast[int64](included)); err != nil {
+			return nil, err
+		}
+		backlog, err := pricing.GasBacklog()
+		if err != nil {
+			return nil, err
+		}
+
+		summary.Blocks++
+		summary.DemandGas = arbmath.SaturatingUAdd(summary.DemandGas, block.Gas)
+		summary.IncludedGas = arbmath.SaturatingUAdd(summary.IncludedGas, included)
+		summary.MaxQueuedGas = arbmath.MaxInt(summary.MaxQueuedGas, queued)
+		summary.MaxGasBacklog = arbmath.MaxInt(summary.MaxGasBacklog, backlog)
+		summary.MaxQueueDelaySeconds = arbmath.MaxInt(summary.MaxQueueDelaySeconds, block.Timestamp-queuedSince)
+		if baseFee.Cmp(summary.MaxBaseFee) > 0 {
+			summary.MaxBaseFee = baseFee
+		}
+		summary.FinalQueuedGas = queued
+		summary.FinalBaseFee = baseFee
+		summary.FinalGasBacklog = backlog
+		if record != nil {
+			err := record(&SimulatedBlock{
+				Timestamp:   block.Timestamp,
+				DemandGas:   block.Gas,
+				IncludedGas: included,
+				QueuedGas:   queued,
+				GasBacklog:  backlog,
+				BaseFee:     baseFee,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	summary.Seconds = lastTimestamp - traffic[0].Timestamp
+	if summary.Seconds > 0 {
+		summary.MeanBaseFee = arbmath.BigDivByUint(baseFeeTimeSum, summary.Seconds)
+	} else {
+		summary.MeanBaseFee = summary.FinalBaseFee
+	}
+	return summary, nil
+}
+
+// SyntheticProfile generates traffic demanding a constant rate of gas, multiplied during a burst.
+type SyntheticProfile struct {
+	Duration        uint64  `koanf:"duration"`
+	BlockTimeMillis uint64  `koanf:"block-time-millis"`
+	GasPerSecond    uint64  `koanf:"gas-per-second"`
+	BurstStart      uint64  `koanf:"burst-start"`
+	BurstDuration   uint64  `koanf:"burst-duration"`
+	BurstMultiplier float64 `koanf:"burst-multiplier"`
+}
+
+func (p *SyntheticProfile) Traffic() ([]TrafficBlock, error) {
+	if p.BlockTimeMillis == 0 {
+		return nil, errors.New("synthetic block time must be positive")
+	}
+	blocks := p.Duration * 1000 / p.BlockTimeMillis
+	traffic := make([]TrafficBlock, 0, blocks)
+	for i := uint64(0); i < blocks; i++ {
+		millis := i * p.BlockTimeMillis
+		seconds := millis / 1000
+		gas := p.GasPerSecond * p.BlockTimeMillis / 1000
+		if seconds >= p.BurstStart && seconds < p.BurstStart+p.BurstDuration {
+			gas = uint64(float64(gas) * p.BurstMultiplier)
+		}
+		traffic = append(traffic, TrafficBlock{Timestamp: seconds, Gas: gas})
+	}
+	return traffic, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/offchainlabs/nitro/arbos/l2pricing"
+)
+
+func testPricingParams() *PricingParams {
+	return &PricingParams{
+		SpeedLimitPerSecond: l2pricing.InitialSpeedLimitPerSecondV6,
+		PerBlockGasLimit:    l2pricing.InitialPerBlockGasLimitV6,
+		PricingInertia:      l2pricing.InitialPricingInertia,
+		BacklogTolerance:    l2pricing.InitialBacklogTolerance,
+		MinBaseFee:          big.NewInt(l2pricing.InitialMinimumBaseFeeWei),
+	}
+}
+
+func TestSimulateSpeedLimit(t *testing.T) {
+	params := testPricingParams()
+	profile := SyntheticProfile{
+		Duration:        600,
+		BlockTimeMillis: 250,
+		GasPerSecond:    params.SpeedLimitPerSecond,
+		BurstMultiplier: 1,
+	}
+	traffic, err := profile.Traffic()
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary, err := Simulate(params, traffic, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.MaxBaseFee.Cmp(params.MinBaseFee) != 0 || summary.SecondsAboveMin != 0 {
+		t.Errorf("running at the speed limit raised the base fee: %+v", summary)
+	}
+
+	// half the speed limit, tripling it for a few minutes, builds a backlog that raises the base fee
+	profile.GasPerSecond = params.SpeedLimitPerSecond / 2
+	profile.BurstStart = 60
+	profile.BurstDuration = 300
+	profile.BurstMultiplier = 6
+	traffic, err = profile.Traffic()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var blocks uint64
+	summary, err = Simulate(params, traffic, func(*SimulatedBlock) error {
+		blocks++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blocks != uint64(len(traffic)) || summary.Blocks != blocks {
+		t.Errorf("recorded %v blocks of %v", blocks, len(traffic))
+	}
+	if summary.MaxBaseFee.Cmp(params.MinBaseFee) <= 0 || summary.MaxGasBacklog == 0 {
+		t.Errorf("a burst over the speed limit didn't raise the base fee: %+v", summary)
+	}
+	if summary.FinalGasBacklog >= summary.MaxGasBacklog {
+		t.Errorf("the backlog didn't drain after the burst: %+v", summary)
+	}
+
+	// a lower block gas limit can't fit the burst, so demand queues up
+	params.PerBlockGasLimit = params.SpeedLimitPerSecond / 2
+	summary, err = Simulate(params, traffic, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.MaxQueuedGas == 0 || summary.MaxQueueDelaySeconds == 0 {
+		t.Errorf("expected demand to queue up behind the block gas limit: %+v", summary)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package chaininfo
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Descriptor describes a chain as a node running it knows it, for publishing so third parties can configure nodes
+// and SDKs from it. It's a superset of ChainInfo, so a list of descriptors can be used as a chain info file.
+type Descriptor struct {
+	ChainInfo
+	DasRestUrls    []string    `json:"das-rest-urls,omitempty"`
+	ArbOSVersion   uint64      `json:"arbos-version"`
+	WasmModuleRoot common.Hash `json:"wasm-module-root"`
+}
+
+// RedactURLs strips the userinfo and query strings from the descriptor's URLs, as the ones a node is configured with
+// may contain credentials that mustn't be published.
+func (d *Descriptor) RedactURLs() {
+	d.SequencerUrl = redactURLList(d.SequencerUrl)
+	d.FeedUrl = redactURLList(d.FeedUrl)
+	d.SecondaryFeedUrl = redactURLList(d.SecondaryFeedUrl)
+	d.DasIndexUrl = redactURL(d.DasIndexUrl)
+	if d.DasRestUrls != nil {
+		redacted := make([]string, 0, len(d.DasRestUrls))
+		for _, rawURL := range d.DasRestUrls {
+			if rawURL = redactURL(rawURL); rawURL != "" {
+				redacted = append(redacted, rawURL)
+			}
+		}
+		d.DasRestUrls = redacted
+	}
+}
+
+// redactURLList redacts each URL of a comma separated list.
+func redactURLList(rawURLs string) string {
+	if rawURLs == "" {
+		return ""
+	}
+	var redacted []string
+	for _, rawURL := range strings.Split(rawURLs, ",") {
+		if rawURL = redactURL(rawURL); rawURL != "" {
+			redacted = append(redacted, rawURL)
+		}
+	}
+	return strings.Join(redacted, ",")
+}
+
+// redactURL strips the userinfo, query string and fragment from a URL. A URL that can't be parsed is dropped, as
+// it can't be told what part of it is sensitive.
+func redactURL(rawURL string) string {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+	parsed.User = nil
+	parsed.RawQuery = ""
+	parsed.ForceQuery = false
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+	return parsed.String()
+}
+
+// CanonicalJSON encodes the descriptor compactly with object keys sorted, so descriptors of the same chain are
+// byte for byte identical and can be compared or signed.
+func (d *Descriptor) CanonicalJSON() ([]byte, error) {
+	return canonicalJSON(d)
+}
+
+func canonicalJSON(v any) ([]byte, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	// decoding into generic values turns objects into maps, which encoding/json writes with sorted keys
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var generic any
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(generic); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n")), nil
+}
+
+// CanonicalJSONList encodes descriptors as a canonical chain info file.
+func CanonicalJSONList(descriptors []*Descriptor) ([]byte, error) {
+	return canonicalJSON(descriptors)
+}
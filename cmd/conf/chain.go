@@ -39,6 +39,7 @@ var DefaultL1WalletConfig = genericconf.WalletConfig{
 	PrivateKey:    genericconf.WalletConfigDefault.PrivateKey,
 	Account:       genericconf.WalletConfigDefault.Account,
 	OnlyCreateKey: genericconf.WalletConfigDefault.OnlyCreateKey,
+	KMS:           genericconf.WalletConfigDefault.KMS,
 }
 
 func L1ConfigAddOptions(prefix string, f *flag.FlagSet) {
@@ -1,6 +1,7 @@
 package conf
 
 import (
+	"errors"
 	"fmt"
 	"runtime"
 	"strings"
@@ -35,6 +36,10 @@ type InitConfig struct {
 	ReorgToBatch             int64         `koanf:"reorg-to-batch"`
 	ReorgToMessageBatch      int64         `koanf:"reorg-to-message-batch"`
 	ReorgToBlockBatch        int64         `koanf:"reorg-to-block-batch"`
+	ForceReinitWithWipe      bool          `koanf:"force-reinit-with-wipe"`
+	ReinitDryRun             bool          `koanf:"reinit-dry-run"`
+	ExportMessageArchive     string        `koanf:"export-message-archive"`
+	ImportMessageArchive     string        `koanf:"import-message-archive"`
 }
 
 var InitConfigDefault = InitConfig{
@@ -61,6 +66,10 @@ var InitConfigDefault = InitConfig{
 	ReorgToBatch:             -1,
 	ReorgToMessageBatch:      -1,
 	ReorgToBlockBatch:        -1,
+	ForceReinitWithWipe:      false,
+	ReinitDryRun:             false,
+	ExportMessageArchive:     "",
+	ImportMessageArchive:     "",
 }
 
 func InitConfigAddOptions(prefix string, f *pflag.FlagSet) {
@@ -87,6 +96,10 @@ func InitConfigAddOptions(prefix string, f *pflag.FlagSet) {
 	f.Int64(prefix+".reorg-to-batch", InitConfigDefault.ReorgToBatch, "rolls back the blockchain to a specified batch number")
 	f.Int64(prefix+".reorg-to-message-batch", InitConfigDefault.ReorgToMessageBatch, "rolls back the blockchain to the first batch at or before a given message index")
 	f.Int64(prefix+".reorg-to-block-batch", InitConfigDefault.ReorgToBlockBatch, "rolls back the blockchain to the first batch at or before a given block number")
+	f.Bool(prefix+".force-reinit-with-wipe", InitConfigDefault.ForceReinitWithWipe, "if the existing database doesn't match the configured chain (chain ID, genesis, rollup address or initial ArbOS version), DELETE it and initialize from scratch instead of refusing to start")
+	f.Bool(prefix+".reinit-dry-run", InitConfigDefault.ReinitDryRun, "check the existing database against the configured chain, report what force-reinit-with-wipe would delete, then quit without changing anything")
+	f.String(prefix+".export-message-archive", InitConfigDefault.ExportMessageArchive, "path to export all of the node's messages to as a message archive, then quit")
+	f.String(prefix+".import-message-archive", InitConfigDefault.ImportMessageArchive, "path of a message archive to import the messages of before starting, instead of deriving them from the parent chain (its batches are checked against the parent chain)")
 }
 
 func (c *InitConfig) Validate() error {
@@ -102,6 +115,12 @@ func (c *InitConfig) Validate() error {
 	if c.PruneTrieCleanCache < 0 {
 		return fmt.Errorf("invalid trie clean cache size: %d, has to be greater or equal 0", c.PruneTrieCleanCache)
 	}
+	if c.ForceReinitWithWipe && c.Force {
+		return errors.New("init.force-reinit-with-wipe can't be combined with init.force")
+	}
+	if c.ExportMessageArchive != "" && c.ImportMessageArchive != "" {
+		return errors.New("init.export-message-archive can't be combined with init.import-message-archive")
+	}
 	numReorgOptionsSpecified := 0
 	for _, reorgOption := range []int64{c.ReorgToBatch, c.ReorgToMessageBatch, c.ReorgToBlockBatch} {
 		if reorgOption >= 0 {
@@ -0,0 +1,117 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+// export-chain-info fetches the chain descriptor from a running node and writes it as a canonical chain info file,
+// ready to publish for third parties to configure nodes and SDKs with.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/cmd/chaininfo"
+	"github.com/offchainlabs/nitro/cmd/genericconf"
+	"github.com/offchainlabs/nitro/cmd/util/confighelpers"
+)
+
+type ExportChainInfoConfig struct {
+	NodeURL string        `koanf:"node-url"`
+	Output  string        `koanf:"output"`
+	Timeout time.Duration `koanf:"timeout"`
+
+	Conf     genericconf.ConfConfig `koanf:"conf"`
+	LogLevel string                 `koanf:"log-level"`
+	LogType  string                 `koanf:"log-type"`
+}
+
+var DefaultExportChainInfoConfig = ExportChainInfoConfig{
+	NodeURL:  "http://localhost:8547",
+	Output:   "",
+	Timeout:  time.Minute,
+	Conf:     genericconf.ConfConfigDefault,
+	LogLevel: "INFO",
+	LogType:  "plaintext",
+}
+
+func main() {
+	if err := startup(); err != nil {
+		log.Error("exporting chain info failed", "err", err)
+		os.Exit(1)
+	}
+}
+
+func parseExportChainInfo(args []string) (*ExportChainInfoConfig, error) {
+	f := flag.NewFlagSet("export-chain-info", flag.ContinueOnError)
+	f.String("node-url", DefaultExportChainInfoConfig.NodeURL, "RPC URL of the node to read the chain descriptor from")
+	f.String("output", DefaultExportChainInfoConfig.Output, "file to write the chain info to (empty = standard output)")
+	f.Duration("timeout", DefaultExportChainInfoConfig.Timeout, "timeout for reading the chain descriptor")
+	f.String("log-level", DefaultExportChainInfoConfig.LogLevel, "log level, valid values are CRIT, ERROR, WARN, INFO, DEBUG, TRACE")
+	f.String("log-type", DefaultExportChainInfoConfig.LogType, "log type (plaintext or json)")
+	genericconf.ConfConfigAddOptions("conf", f)
+
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+	var config ExportChainInfoConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	if config.NodeURL == "" {
+		return nil, errors.New("node-url is required")
+	}
+	return &config, nil
+}
+
+func startup() error {
+	config, err := parseExportChainInfo(os.Args[1:])
+	if err != nil {
+		confighelpers.PrintErrorAndExit(err, func(string) {
+			fmt.Printf("\nSample usage: %s --node-url <url> --output chain-info.json\n", os.Args[0])
+		})
+	}
+	if err := genericconf.InitLog(config.LogType, config.LogLevel, &genericconf.FileLoggingConfig{}, nil); err != nil {
+		return fmt.Errorf("error initializing logging: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+
+	client, err := rpc.DialContext(ctx, config.NodeURL)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	var raw json.RawMessage
+	if err := client.CallContext(ctx, &raw, "arb_chainInfo"); err != nil {
+		return fmt.Errorf("error reading chain descriptor: %w", err)
+	}
+	var descriptor chaininfo.Descriptor
+	if err := json.Unmarshal(raw, &descriptor); err != nil {
+		return fmt.Errorf("error parsing chain descriptor: %w", err)
+	}
+	if descriptor.ChainConfig == nil || descriptor.ChainConfig.ChainID == nil {
+		return errors.New("chain descriptor has no chain config")
+	}
+	output, err := chaininfo.CanonicalJSONList([]*chaininfo.Descriptor{&descriptor})
+	if err != nil {
+		return err
+	}
+	if config.Output == "" {
+		fmt.Println(string(output))
+		return nil
+	}
+	if err := os.WriteFile(config.Output, append(output, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write chain info: %w", err)
+	}
+	log.Info("wrote chain info", "chainId", descriptor.ChainConfig.ChainID, "arbosVersion", descriptor.ArbOSVersion, "wasmModuleRoot", descriptor.WasmModuleRoot, "file", config.Output)
+	return nil
+}
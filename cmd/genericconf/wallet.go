@@ -8,16 +8,19 @@ import (
 	"path/filepath"
 
 	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/util/kms"
 )
 
 const PASSWORD_NOT_SET = "PASSWORD_NOT_SET"
 
 type WalletConfig struct {
-	Pathname      string `koanf:"pathname"`
-	Password      string `koanf:"password"`
-	PrivateKey    string `koanf:"private-key"`
-	Account       string `koanf:"account"`
-	OnlyCreateKey bool   `koanf:"only-create-key"`
+	Pathname      string     `koanf:"pathname"`
+	Password      string     `koanf:"password"`
+	PrivateKey    string     `koanf:"private-key"`
+	Account       string     `koanf:"account"`
+	OnlyCreateKey bool       `koanf:"only-create-key"`
+	KMS           kms.Config `koanf:"kms"`
 }
 
 func (w *WalletConfig) Pwd() *string {
@@ -33,6 +36,7 @@ var WalletConfigDefault = WalletConfig{
 	PrivateKey:    "",
 	Account:       "",
 	OnlyCreateKey: false,
+	KMS:           kms.DefaultConfig,
 }
 
 func WalletConfigAddOptions(prefix string, f *flag.FlagSet, defaultPathname string) {
@@ -41,6 +45,7 @@ func WalletConfigAddOptions(prefix string, f *flag.FlagSet, defaultPathname stri
 	f.String(prefix+".private-key", WalletConfigDefault.PrivateKey, "private key for wallet")
 	f.String(prefix+".account", WalletConfigDefault.Account, "account to use (default is first account in keystore)")
 	f.Bool(prefix+".only-create-key", WalletConfigDefault.OnlyCreateKey, "if true, creates new key then exits")
+	kms.ConfigAddOptions(prefix+".kms", f)
 }
 
 func (w *WalletConfig) ResolveDirectoryNames(chain string) {
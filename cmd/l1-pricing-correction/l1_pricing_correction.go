@@ -0,0 +1,156 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+// l1-pricing-correction reports how far a chain's L1 fee collection is from what it owes batch posters, and lets
+// its owner apply a bounded correction to bring the surplus or deficit back toward zero.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/cmd/genericconf"
+	"github.com/offchainlabs/nitro/cmd/util"
+	"github.com/offchainlabs/nitro/cmd/util/confighelpers"
+	"github.com/offchainlabs/nitro/execution/gethexec"
+	"github.com/offchainlabs/nitro/solgen/go/precompilesgen"
+)
+
+type L1PricingCorrectionConfig struct {
+	ChainURL string `koanf:"chain-url"`
+	// wei to add to the L1 fees recognized as available, negative to correct a surplus; empty only reports
+	Correction string                   `koanf:"correction"`
+	Wallet     genericconf.WalletConfig `koanf:"wallet"`
+	Timeout    time.Duration            `koanf:"timeout"`
+
+	Conf     genericconf.ConfConfig `koanf:"conf"`
+	LogLevel string                 `koanf:"log-level"`
+	LogType  string                 `koanf:"log-type"`
+}
+
+var DefaultL1PricingCorrectionConfig = L1PricingCorrectionConfig{
+	Wallet:   genericconf.WalletConfigDefault,
+	Timeout:  5 * time.Minute,
+	Conf:     genericconf.ConfConfigDefault,
+	LogLevel: "INFO",
+	LogType:  "plaintext",
+}
+
+func main() {
+	if err := startup(); err != nil {
+		log.Error("l1 pricing correction failed", "err", err)
+		os.Exit(1)
+	}
+}
+
+func parseL1PricingCorrection(args []string) (*L1PricingCorrectionConfig, error) {
+	f := flag.NewFlagSet("l1-pricing-correction", flag.ContinueOnError)
+	f.String("chain-url", DefaultL1PricingCorrectionConfig.ChainURL, "RPC URL of the chain, which must serve the arbdebug namespace")
+	f.String("correction", DefaultL1PricingCorrectionConfig.Correction, "wei to add to the L1 fees recognized as available, negative to correct a surplus and positive a deficit (empty = only report)")
+	genericconf.WalletConfigAddOptions("wallet", f, DefaultL1PricingCorrectionConfig.Wallet.Pathname)
+	f.Duration("timeout", DefaultL1PricingCorrectionConfig.Timeout, "timeout for reading from and sending the correction to the chain")
+	f.String("log-level", DefaultL1PricingCorrectionConfig.LogLevel, "log level, valid values are CRIT, ERROR, WARN, INFO, DEBUG, TRACE")
+	f.String("log-type", DefaultL1PricingCorrectionConfig.LogType, "log type (plaintext or json)")
+	genericconf.ConfConfigAddOptions("conf", f)
+
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+	var config L1PricingCorrectionConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	if config.ChainURL == "" {
+		return nil, errors.New("chain-url is required")
+	}
+	return &config, nil
+}
+
+func report(ctx context.Context, client *ethclient.Client) (*gethexec.L1PricingEquilibration, error) {
+	var equilibration gethexec.L1PricingEquilibration
+	if err := client.Client().CallContext(ctx, &equilibration, "arbdebug_l1PricingEquilibration", rpc.LatestBlockNumber); err != nil {
+		return nil, err
+	}
+	out, err := json.MarshalIndent(&equilibration, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	fmt.Println(string(out))
+	return &equilibration, nil
+}
+
+func startup() error {
+	config, err := parseL1PricingCorrection(os.Args[1:])
+	if err != nil {
+		confighelpers.PrintErrorAndExit(err, func(string) {
+			fmt.Printf("\nSample usage: %s --chain-url <url> [--correction <wei> --wallet.private-key <chain owner key>]\n", os.Args[0])
+		})
+	}
+	if err := genericconf.InitLog(config.LogType, config.LogLevel, &genericconf.FileLoggingConfig{}, nil); err != nil {
+		return fmt.Errorf("error initializing logging: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+
+	client, err := ethclient.DialContext(ctx, config.ChainURL)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	equilibration, err := report(ctx, client)
+	if err != nil {
+		return fmt.Errorf("failed to read the L1 pricing equilibration: %w", err)
+	}
+	if config.Correction == "" {
+		return nil
+	}
+	correction, ok := new(big.Int).SetString(config.Correction, 10)
+	if !ok {
+		return fmt.Errorf("invalid correction %v", config.Correction)
+	}
+	if correction.Sign() == equilibration.Surplus.Sign() || correction.CmpAbs(equilibration.Surplus) > 0 {
+		return fmt.Errorf("correction %v would move surplus %v away from or past zero", correction, equilibration.Surplus)
+	}
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return err
+	}
+	opts, _, err := util.OpenWallet("l1-pricing-correction", &config.Wallet, chainID)
+	if err != nil {
+		return err
+	}
+	opts.Context = ctx
+	arbOwner, err := precompilesgen.NewArbOwner(types.ArbOwnerAddress, client)
+	if err != nil {
+		return err
+	}
+	tx, err := arbOwner.CorrectL1PricingSurplus(opts, correction)
+	if err != nil {
+		return err
+	}
+	log.Info("sent L1 pricing correction", "correction", correction, "tx", tx.Hash())
+	receipt, err := bind.WaitMined(ctx, client, tx)
+	if err != nil {
+		return err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return fmt.Errorf("correction transaction %v failed", tx.Hash())
+	}
+	_, err = report(ctx, client)
+	return err
+}
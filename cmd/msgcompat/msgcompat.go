@@ -0,0 +1,304 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+// msgcompat captures message streams from a sequencer feed, records the blocks this build produces
+// from them, and verifies other builds produce the same blocks.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/broadcastclient"
+	m "github.com/offchainlabs/nitro/broadcaster/message"
+	"github.com/offchainlabs/nitro/cmd/chaininfo"
+	"github.com/offchainlabs/nitro/cmd/util/confighelpers"
+	"github.com/offchainlabs/nitro/util/msgcompat"
+)
+
+func main() {
+	args := os.Args
+	if len(args) < 2 {
+		fmt.Fprintln(os.Stderr, "Usage: msgcompat [capture|record|verify] ...")
+		os.Exit(1)
+	}
+	var err error
+	switch strings.ToLower(args[1]) {
+	case "capture":
+		err = startCapture(args[2:])
+	case "record":
+		err = startRecord(args[2:])
+	case "verify":
+		err = startVerify(args[2:])
+	default:
+		err = fmt.Errorf("unknown command '%s', valid commands are 'capture', 'record' and 'verify'", args[1])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func recordedBy() string {
+	vcsRevision, _, vcsTime := confighelpers.GetVersion()
+	return fmt.Sprintf("nitro %v (%v)", vcsRevision, vcsTime)
+}
+
+// msgcompat capture
+
+type CaptureConfig struct {
+	Feed                broadcastclient.Config `koanf:"feed"`
+	ChainID             uint64                 `koanf:"chain-id"`
+	ChainName           string                 `koanf:"chain-name"`
+	ChainInfoFiles      []string               `koanf:"chain-info-files"`
+	InitialArbOSVersion uint64                 `koanf:"initial-arbos-version"`
+	Count               uint64                 `koanf:"count"`
+	Description         string                 `koanf:"description"`
+	Output              string                 `koanf:"output"`
+	Record              bool                   `koanf:"record"`
+	Timeout             time.Duration          `koanf:"timeout"`
+}
+
+func parseCaptureConfig(args []string) (*CaptureConfig, error) {
+	f := flag.NewFlagSet("msgcompat capture", flag.ContinueOnError)
+	broadcastclient.ConfigAddOptions("feed", f)
+	f.Uint64("chain-id", 0, "chain id of the feed, used to look up the chain config")
+	f.String("chain-name", "", "chain name of the feed, used to look up the chain config")
+	f.StringSlice("chain-info-files", []string{}, "chain info files to look the chain config up in, in addition to the built-in ones")
+	f.Uint64("initial-arbos-version", 0, "ArbOS version to start the replayed chain at instead of the chain config's (0 = don't override)")
+	f.Uint64("count", 1000, "number of feed messages to capture")
+	f.String("description", "", "description to store in the fixture")
+	f.String("output", "", "file to write the fixture to, gzipped if it ends in .gz")
+	f.Bool("record", true, "record the blocks this build produces from the captured messages")
+	f.Duration("timeout", time.Hour, "how long to wait for the messages to arrive")
+
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+	var config CaptureConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	if len(config.Feed.URL) == 0 {
+		return nil, errors.New("--feed.url must be set")
+	}
+	if config.Output == "" {
+		return nil, errors.New("--output must be set")
+	}
+	if config.Count == 0 {
+		return nil, errors.New("--count must be positive")
+	}
+	return &config, nil
+}
+
+// feedCollector gathers feed messages until it has enough of them.
+type feedCollector struct {
+	mutex    sync.Mutex
+	count    uint64
+	next     arbutil.MessageIndex
+	messages []arbostypes.MessageWithMetadata
+	done     chan struct{}
+}
+
+func (c *feedCollector) AddBroadcastMessages(feedMessages []*m.BroadcastFeedMessage) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, feedMessage := range feedMessages {
+		if uint64(len(c.messages)) >= c.count {
+			return nil
+		}
+		if len(c.messages) > 0 && feedMessage.SequenceNumber != c.next {
+			if feedMessage.SequenceNumber < c.next {
+				// the feed resent a message we already have
+				continue
+			}
+			return fmt.Errorf("gap in feed: expected message %v but got %v", c.next, feedMessage.SequenceNumber)
+		}
+		c.messages = append(c.messages, feedMessage.Message)
+		c.next = feedMessage.SequenceNumber + 1
+		if uint64(len(c.messages)) == c.count {
+			close(c.done)
+		}
+	}
+	return nil
+}
+
+func startCapture(args []string) error {
+	config, err := parseCaptureConfig(args)
+	if err != nil {
+		return err
+	}
+	chainConfig, err := chaininfo.GetChainConfig(new(big.Int).SetUint64(config.ChainID), config.ChainName, 0, config.ChainInfoFiles, "")
+	if err != nil {
+		return err
+	}
+	if config.InitialArbOSVersion != 0 {
+		chainConfig.ArbitrumChainParams.InitialArbOSVersion = config.InitialArbOSVersion
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+	collector := &feedCollector{count: config.Count, done: make(chan struct{})}
+	fatalErrChan := make(chan error, 1)
+	feedConfig := config.Feed
+	client, err := broadcastclient.NewBroadcastClient(
+		func() *broadcastclient.Config { return &feedConfig },
+		config.Feed.URL[0],
+		chainConfig.ChainID.Uint64(),
+		0,
+		collector,
+		nil,
+		fatalErrChan,
+		nil,
+		nil,
+		func(int32) {},
+	)
+	if err != nil {
+		return err
+	}
+	client.Start(ctx)
+	select {
+	case <-collector.done:
+	case err := <-fatalErrChan:
+		client.StopAndWait()
+		return err
+	case <-ctx.Done():
+		client.StopAndWait()
+		collector.mutex.Lock()
+		defer collector.mutex.Unlock()
+		return fmt.Errorf("timed out after capturing %v of %v messages", len(collector.messages), config.Count)
+	}
+	client.StopAndWait()
+	log.Info("captured feed messages", "count", len(collector.messages), "last", collector.next-1)
+
+	fixture := &msgcompat.Fixture{
+		Version:          msgcompat.FixtureVersion,
+		Description:      config.Description,
+		ChainConfig:      chainConfig,
+		InitialL1BaseFee: arbostypes.DefaultInitialL1BaseFee,
+		Messages:         collector.messages,
+	}
+	if config.Record {
+		if err := msgcompat.Record(fixture, recordedBy()); err != nil {
+			return err
+		}
+	}
+	return msgcompat.WriteFixture(config.Output, fixture)
+}
+
+// msgcompat record
+
+type RecordConfig struct {
+	Input  string `koanf:"input"`
+	Output string `koanf:"output"`
+}
+
+func parseRecordConfig(args []string) (*RecordConfig, error) {
+	f := flag.NewFlagSet("msgcompat record", flag.ContinueOnError)
+	f.String("input", "", "fixture whose messages should be executed")
+	f.String("output", "", "file to write the fixture with this build's blocks to (defaults to overwriting the input)")
+
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+	var config RecordConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	if config.Input == "" {
+		return nil, errors.New("--input must be set")
+	}
+	if config.Output == "" {
+		config.Output = config.Input
+	}
+	return &config, nil
+}
+
+func startRecord(args []string) error {
+	config, err := parseRecordConfig(args)
+	if err != nil {
+		return err
+	}
+	fixture, err := msgcompat.ReadFixture(config.Input)
+	if err != nil {
+		return err
+	}
+	if err := msgcompat.Record(fixture, recordedBy()); err != nil {
+		return err
+	}
+	return msgcompat.WriteFixture(config.Output, fixture)
+}
+
+// msgcompat verify
+
+type VerifyConfig struct {
+	Fixtures  []string `koanf:"fixtures"`
+	CorpusDir string   `koanf:"corpus-dir"`
+}
+
+func parseVerifyConfig(args []string) (*VerifyConfig, error) {
+	f := flag.NewFlagSet("msgcompat verify", flag.ContinueOnError)
+	f.StringSlice("fixtures", []string{}, "fixtures to verify")
+	f.String("corpus-dir", "", "directory of fixtures to verify")
+
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+	var config VerifyConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	if len(config.Fixtures) == 0 && config.CorpusDir == "" {
+		return nil, errors.New("--fixtures or --corpus-dir must be set")
+	}
+	return &config, nil
+}
+
+func startVerify(args []string) error {
+	config, err := parseVerifyConfig(args)
+	if err != nil {
+		return err
+	}
+	paths := config.Fixtures
+	if config.CorpusDir != "" {
+		corpus, err := msgcompat.FixturePaths(config.CorpusDir)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, corpus...)
+	}
+	failed := 0
+	for _, path := range paths {
+		fixture, err := msgcompat.ReadFixture(path)
+		if err != nil {
+			log.Error("failed to read fixture", "fixture", path, "err", err)
+			failed++
+			continue
+		}
+		if err := msgcompat.Verify(fixture); err != nil {
+			log.Error("fixture failed verification", "fixture", path, "recordedBy", fixture.RecordedBy, "err", err)
+			failed++
+			continue
+		}
+		log.Info("fixture verified", "fixture", path, "messages", len(fixture.Messages), "recordedBy", fixture.RecordedBy)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%v of %v fixtures failed verification", failed, len(paths))
+	}
+	return nil
+}
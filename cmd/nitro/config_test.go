@@ -78,6 +78,51 @@ func TestInvalidArchiveConfig(t *testing.T) {
 	}
 }
 
+func TestGethFlags(t *testing.T) {
+	args := strings.Split(validatorArgs+" --cache=4096 --gcmode archive --txpool.pricelimit 1", " ")
+	_, _, err := ParseNode(context.Background(), args)
+	if err == nil {
+		Fail(t, "accepted geth options")
+	}
+	for _, expected := range []string{"--cache is a geth option", "--execution.caching.archive", "--txpool.pricelimit is a geth option"} {
+		if !strings.Contains(err.Error(), expected) {
+			Fail(t, "expected error to mention", expected, "got", err)
+		}
+	}
+}
+
+func TestInvalidCachingConfig(t *testing.T) {
+	args := strings.Split(validatorArgs+" --execution.caching.max-number-of-blocks-to-skip-state-saving 16", " ")
+	_, _, err := ParseNode(context.Background(), args)
+	if err == nil || !strings.Contains(err.Error(), "can only be set when archive is set") {
+		Fail(t, "failed to detect skipping state saving on a non-archive node, got", err)
+	}
+	args = strings.Split(validatorArgs+" --execution.caching.trie-clean-cache -1", " ")
+	_, _, err = ParseNode(context.Background(), args)
+	if err == nil || !strings.Contains(err.Error(), "cannot be negative") {
+		Fail(t, "failed to detect a negative cache size, got", err)
+	}
+}
+
+func TestNodeModeConflicts(t *testing.T) {
+	seqArgs := "--persistent.chain /tmp/data --init.dev-init --node.parent-chain-reader.enable=false --parent-chain.id 5 --chain.id 421613 --node.batch-poster.parent-chain-wallet.pathname /l1keystore --node.batch-poster.parent-chain-wallet.password passphrase --http.addr 0.0.0.0 --ws.addr 0.0.0.0"
+	for _, test := range []struct {
+		args     string
+		conflict string
+	}{
+		{seqArgs + " --node.sequencer", "--execution.sequencer.enable"},
+		{seqArgs + " --node.sequencer --execution.sequencer.enable --node.seq-coordinator.enable --node.seq-coordinator.redis-url redis://localhost:6379", "--node.seq-coordinator.my-url"},
+		{seqArgs + " --node.sequencer --execution.sequencer.enable --node.inbox-reader.hard-reorg", "--node.inbox-reader.hard-reorg"},
+		{validatorArgs + " --node.dangerous.no-l1-listener", "--node.dangerous.no-l1-listener"},
+		{validatorArgs + " --execution.caching.archive --init.prune full", "--init.prune"},
+	} {
+		_, _, err := ParseNode(context.Background(), strings.Split(test.args, " "))
+		if err == nil || !strings.Contains(err.Error(), "unsupported combination of node roles") || !strings.Contains(err.Error(), test.conflict) {
+			Fail(t, "expected conflict mentioning", test.conflict, "for", test.args, "got", err)
+		}
+	}
+}
+
 func TestAggregatorConfig(t *testing.T) {
 	args := strings.Split("--persistent.chain /tmp/data --init.dev-init --node.parent-chain-reader.enable=false --parent-chain.id 5 --chain.id 421613 --node.batch-poster.parent-chain-wallet.pathname /l1keystore --node.batch-poster.parent-chain-wallet.password passphrase --http.addr 0.0.0.0 --ws.addr 0.0.0.0 --node.sequencer --execution.sequencer.enable --node.feed.output.enable --node.feed.output.port 9642 --node.data-availability.enable --node.data-availability.rpc-aggregator.backends [{\"url\":\"http://localhost:8547\",\"pubkey\":\"abc==\"}]", " ")
 	_, _, err := ParseNode(context.Background(), args)
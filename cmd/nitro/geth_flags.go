@@ -0,0 +1,77 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+)
+
+// gethFlag is a geth command line option operators commonly carry over to nitro, along with
+// what to use instead. Nitro configures the underlying geth itself, so these would otherwise
+// only be rejected as unknown flags.
+type gethFlag struct {
+	name string
+	// whether name is a prefix of a family of options, like txpool.
+	prefix     bool
+	suggestion string
+}
+
+var gethFlags = []gethFlag{
+	{name: "cache", suggestion: "set the caches individually with --execution.caching.database-cache, --execution.caching.trie-clean-cache, --execution.caching.trie-dirty-cache and --execution.caching.snapshot-cache"},
+	{name: "cache.database", suggestion: "use --execution.caching.database-cache"},
+	{name: "cache.trie", suggestion: "use --execution.caching.trie-clean-cache"},
+	{name: "cache.gc", suggestion: "use --execution.caching.trie-dirty-cache"},
+	{name: "cache.snapshot", suggestion: "use --execution.caching.snapshot-cache"},
+	{name: "cache.preimages", suggestion: "use --execution.caching.preimages"},
+	{name: "snapshot", suggestion: "use --execution.caching.snapshot-cache 0 to disable snapshots"},
+	{name: "gcmode", suggestion: "use --execution.caching.archive for --gcmode archive"},
+	{name: "state.scheme", suggestion: "use --execution.caching.state-scheme"},
+	{name: "history.state", suggestion: "use --execution.caching.state-history"},
+	{name: "history.transactions", suggestion: "use --execution.tx-lookup-limit"},
+	{name: "txlookuplimit", suggestion: "use --execution.tx-lookup-limit"},
+	{name: "datadir", suggestion: "use --persistent.chain"},
+	{name: "networkid", suggestion: "use --chain.id"},
+	{name: "syncmode", suggestion: "nitro derives the chain from its parent chain, use --init.url to start from a snapshot instead"},
+	{name: "txpool.", prefix: true, suggestion: "nitro has no mempool, transactions are forwarded to the sequencer or ordered by it as they arrive"},
+	{name: "miner.", prefix: true, suggestion: "blocks are only produced by the sequencer, see --execution.sequencer"},
+	{name: "mine", suggestion: "blocks are only produced by the sequencer, see --execution.sequencer.enable"},
+	{name: "bootnodes", suggestion: "nitro nodes don't peer with each other, they follow the sequencer feed with --node.feed.input.url"},
+	{name: "maxpeers", suggestion: "nitro nodes don't peer with each other, they follow the sequencer feed with --node.feed.input.url"},
+	{name: "nodiscover", suggestion: "nitro nodes don't peer with each other, they follow the sequencer feed with --node.feed.input.url"},
+}
+
+func lookupGethFlag(name string) *gethFlag {
+	for i := range gethFlags {
+		if gethFlags[i].name == name || (gethFlags[i].prefix && strings.HasPrefix(name, gethFlags[i].name)) {
+			return &gethFlags[i]
+		}
+	}
+	return nil
+}
+
+// checkGethFlags rejects geth options nitro doesn't accept before parsing, reporting what to use
+// for every one of them instead of only the first unknown flag.
+func checkGethFlags(f *flag.FlagSet, args []string) error {
+	var problems []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name := strings.TrimLeft(arg, "-")
+		name, _, _ = strings.Cut(name, "=")
+		if name == "" || f.Lookup(name) != nil {
+			continue
+		}
+		if gethFlag := lookupGethFlag(name); gethFlag != nil {
+			problems = append(problems, fmt.Sprintf("--%v is a geth option (%v)", name, gethFlag.suggestion))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("unsupported geth options:\n  - %v", strings.Join(problems, "\n  - "))
+}
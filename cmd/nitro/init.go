@@ -406,7 +406,61 @@ func isLeveldbNotExistError(err error) bool {
 	return os.IsNotExist(err)
 }
 
+// readInitMessage reads the chain's init message from the parent chain's delayed inbox, or makes one from the
+// chain config if the parent chain reader is disabled.
+func readInitMessage(ctx context.Context, config *NodeConfig, chainConfig *params.ChainConfig, chainId *big.Int, l1Client arbutil.L1Interface, rollupAddrs chaininfo.RollupAddresses) (*arbostypes.ParsedInitMessage, error) {
+	if config.Node.ParentChainReader.Enable {
+		delayedBridge, err := arbnode.NewDelayedBridge(l1Client, rollupAddrs.Bridge, rollupAddrs.DeployedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed creating delayed bridge while attempting to get serialized chain config from init message: %w", err)
+		}
+		deployedAt := new(big.Int).SetUint64(rollupAddrs.DeployedAt)
+		delayedMessages, err := delayedBridge.LookupMessagesInRange(ctx, deployedAt, deployedAt, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed getting delayed messages while attempting to get serialized chain config from init message: %w", err)
+		}
+		var initMessage *arbostypes.L1IncomingMessage
+		for _, msg := range delayedMessages {
+			if msg.Message.Header.Kind == arbostypes.L1MessageType_Initialize {
+				initMessage = msg.Message
+				break
+			}
+		}
+		if initMessage == nil {
+			return nil, fmt.Errorf("failed to get init message while attempting to get serialized chain config")
+		}
+		parsedInitMessage, err := initMessage.ParseInitMessage()
+		if err != nil {
+			return nil, err
+		}
+		if parsedInitMessage.ChainId.Cmp(chainId) != 0 {
+			return nil, fmt.Errorf("expected L2 chain ID %v but read L2 chain ID %v from init message in L1 inbox", chainId, parsedInitMessage.ChainId)
+		}
+		if parsedInitMessage.ChainConfig != nil {
+			if err := parsedInitMessage.ChainConfig.CheckCompatible(chainConfig, chainConfig.ArbitrumChainParams.GenesisBlockNum, 0); err != nil {
+				return nil, fmt.Errorf("incompatible chain config read from init message in L1 inbox: %w", err)
+			}
+		}
+		log.Info("Read serialized chain config from init message", "json", string(parsedInitMessage.SerializedChainConfig))
+		return parsedInitMessage, nil
+	}
+	serializedChainConfig, err := json.Marshal(chainConfig)
+	if err != nil {
+		return nil, err
+	}
+	log.Warn("Created fake init message as L1Reader is disabled and serialized chain config from init message is not available", "json", string(serializedChainConfig))
+	return &arbostypes.ParsedInitMessage{
+		ChainId:               chainConfig.ChainID,
+		InitialL1BaseFee:      arbostypes.DefaultInitialL1BaseFee,
+		ChainConfig:           chainConfig,
+		SerializedChainConfig: serializedChainConfig,
+	}, nil
+}
+
 func openInitializeChainDb(ctx context.Context, stack *node.Node, config *NodeConfig, chainId *big.Int, cacheConfig *core.CacheConfig, persistentConfig *conf.PersistentConfig, l1Client arbutil.L1Interface, rollupAddrs chaininfo.RollupAddresses) (ethdb.Database, *core.BlockChain, error) {
+	if err := checkReinitSafety(ctx, stack, config, chainId, l1Client, rollupAddrs); err != nil {
+		return nil, nil, err
+	}
 	if !config.Init.Force {
 		if readOnlyDb, err := stack.OpenDatabaseWithFreezerWithExtraOptions("l2chaindata", 0, 0, config.Persistent.Ancient, "l2chaindata/", true, persistentConfig.Pebble.ExtraOptions("l2chaindata")); err == nil {
 			if chainConfig := gethexec.TryReadStoredChainConfig(readOnlyDb); chainConfig != nil {
@@ -439,6 +493,9 @@ func openInitializeChainDb(ctx context.Context, stack *node.Node, config *NodeCo
 				if err != nil {
 					return chainDb, l2BlockChain, err
 				}
+				if err := ensureInitIdentity(chainDb, chainConfig, rollupAddrs); err != nil {
+					return chainDb, l2BlockChain, err
+				}
 				if config.Init.RecreateMissingStateFrom > 0 {
 					err = staterecovery.RecreateMissingStates(chainDb, l2BlockChain, cacheConfig, config.Init.RecreateMissingStateFrom)
 					if err != nil {
@@ -633,52 +690,9 @@ func openInitializeChainDb(ctx context.Context, stack *node.Node, config *NodeCo
 		if config.Init.ThenQuit {
 			cacheConfig.SnapshotWait = true
 		}
-		var parsedInitMessage *arbostypes.ParsedInitMessage
-		if config.Node.ParentChainReader.Enable {
-			delayedBridge, err := arbnode.NewDelayedBridge(l1Client, rollupAddrs.Bridge, rollupAddrs.DeployedAt)
-			if err != nil {
-				return chainDb, nil, fmt.Errorf("failed creating delayed bridge while attempting to get serialized chain config from init message: %w", err)
-			}
-			deployedAt := new(big.Int).SetUint64(rollupAddrs.DeployedAt)
-			delayedMessages, err := delayedBridge.LookupMessagesInRange(ctx, deployedAt, deployedAt, nil)
-			if err != nil {
-				return chainDb, nil, fmt.Errorf("failed getting delayed messages while attempting to get serialized chain config from init message: %w", err)
-			}
-			var initMessage *arbostypes.L1IncomingMessage
-			for _, msg := range delayedMessages {
-				if msg.Message.Header.Kind == arbostypes.L1MessageType_Initialize {
-					initMessage = msg.Message
-					break
-				}
-			}
-			if initMessage == nil {
-				return chainDb, nil, fmt.Errorf("failed to get init message while attempting to get serialized chain config")
-			}
-			parsedInitMessage, err = initMessage.ParseInitMessage()
-			if err != nil {
-				return chainDb, nil, err
-			}
-			if parsedInitMessage.ChainId.Cmp(chainId) != 0 {
-				return chainDb, nil, fmt.Errorf("expected L2 chain ID %v but read L2 chain ID %v from init message in L1 inbox", chainId, parsedInitMessage.ChainId)
-			}
-			if parsedInitMessage.ChainConfig != nil {
-				if err := parsedInitMessage.ChainConfig.CheckCompatible(chainConfig, chainConfig.ArbitrumChainParams.GenesisBlockNum, 0); err != nil {
-					return chainDb, nil, fmt.Errorf("incompatible chain config read from init message in L1 inbox: %w", err)
-				}
-			}
-			log.Info("Read serialized chain config from init message", "json", string(parsedInitMessage.SerializedChainConfig))
-		} else {
-			serializedChainConfig, err := json.Marshal(chainConfig)
-			if err != nil {
-				return chainDb, nil, err
-			}
-			parsedInitMessage = &arbostypes.ParsedInitMessage{
-				ChainId:               chainConfig.ChainID,
-				InitialL1BaseFee:      arbostypes.DefaultInitialL1BaseFee,
-				ChainConfig:           chainConfig,
-				SerializedChainConfig: serializedChainConfig,
-			}
-			log.Warn("Created fake init message as L1Reader is disabled and serialized chain config from init message is not available", "json", string(serializedChainConfig))
+		parsedInitMessage, err := readInitMessage(ctx, config, chainConfig, chainId, l1Client, rollupAddrs)
+		if err != nil {
+			return chainDb, nil, err
 		}
 
 		emptyBlockChain := rawdb.ReadHeadHeader(chainDb) == nil
@@ -705,6 +719,9 @@ func openInitializeChainDb(ctx context.Context, stack *node.Node, config *NodeCo
 	if err != nil {
 		return chainDb, l2BlockChain, err
 	}
+	if err := ensureInitIdentity(chainDb, chainConfig, rollupAddrs); err != nil {
+		return chainDb, l2BlockChain, err
+	}
 
 	return chainDb, l2BlockChain, nil
 }
@@ -0,0 +1,256 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/cmd/chaininfo"
+	"github.com/offchainlabs/nitro/execution/gethexec"
+	"github.com/offchainlabs/nitro/statetransfer"
+	"github.com/offchainlabs/nitro/util/arbmath"
+)
+
+// errReinitDryRun is returned once init.reinit-dry-run has reported on the existing database.
+var errReinitDryRun = errors.New("reinit dry run complete")
+
+// initIdentityKey maps to the identity of the chain the database was initialized for.
+var initIdentityKey = []byte("_nitroInitIdentity")
+
+// initIdentity records what a database was initialized for, beyond what the stored chain config has.
+type initIdentity struct {
+	ChainId         *big.Int       `json:"chainId"`
+	GenesisBlockNum uint64         `json:"genesisBlockNum"`
+	Rollup          common.Address `json:"rollup"`
+}
+
+func readInitIdentity(db ethdb.KeyValueReader) (*initIdentity, error) {
+	// databases initialized before the identity was recorded don't have one
+	if has, err := db.Has(initIdentityKey); err != nil || !has {
+		return nil, err
+	}
+	data, err := db.Get(initIdentityKey)
+	if err != nil {
+		return nil, err
+	}
+	var identity initIdentity
+	if err := json.Unmarshal(data, &identity); err != nil {
+		return nil, fmt.Errorf("failed to decode database init identity: %w", err)
+	}
+	return &identity, nil
+}
+
+// ensureInitIdentity records the database's identity if it doesn't have one yet.
+func ensureInitIdentity(db ethdb.Database, chainConfig *params.ChainConfig, rollupAddrs chaininfo.RollupAddresses) error {
+	existing, err := readInitIdentity(db)
+	if err != nil || existing != nil {
+		return err
+	}
+	data, err := json.Marshal(&initIdentity{
+		ChainId:         chainConfig.ChainID,
+		GenesisBlockNum: chainConfig.ArbitrumChainParams.GenesisBlockNum,
+		Rollup:          rollupAddrs.Rollup,
+	})
+	if err != nil {
+		return err
+	}
+	return db.Put(initIdentityKey, data)
+}
+
+// expectedGenesisHash builds block 0 of a chain without genesis state, which every database for the chain must have.
+func expectedGenesisHash(chainConfig *params.ChainConfig, initMessage *arbostypes.ParsedInitMessage) (common.Hash, error) {
+	db := rawdb.NewMemoryDatabase()
+	defer db.Close()
+	initData := statetransfer.NewMemoryInitDataReader(&statetransfer.ArbosInitializationInfo{})
+	if err := gethexec.WriteOrTestGenblock(db, core.DefaultCacheConfigWithScheme(rawdb.HashScheme), initData, chainConfig, initMessage, 0); err != nil {
+		return common.Hash{}, err
+	}
+	return rawdb.ReadCanonicalHash(db, 0), nil
+}
+
+// initMismatches compares an existing database against the configured chain. expectedConfig is the
+// chain config from the chain info, and expectedGenesis the genesis block hash it implies, if known;
+// rollup addresses are only compared if configured.
+func initMismatches(db ethdb.Reader, storedConfig *params.ChainConfig, chainId *big.Int, expectedConfig *params.ChainConfig, expectedGenesis common.Hash, rollupAddrs chaininfo.RollupAddresses) ([]string, error) {
+	var mismatches []string
+	if !arbmath.BigEquals(storedConfig.ChainID, chainId) {
+		mismatches = append(mismatches, fmt.Sprintf("database has chain ID %v but config has chain ID %v", storedConfig.ChainID, chainId))
+	}
+	storedParams := storedConfig.ArbitrumChainParams
+	if expectedConfig != nil {
+		expectedVersion := expectedConfig.ArbitrumChainParams.InitialArbOSVersion
+		if storedParams.InitialArbOSVersion != expectedVersion {
+			mismatches = append(mismatches, fmt.Sprintf("database was initialized with ArbOS version %v but chain info has initial ArbOS version %v", storedParams.InitialArbOSVersion, expectedVersion))
+		}
+	}
+	if expectedGenesis != (common.Hash{}) {
+		genesisHash := rawdb.ReadCanonicalHash(db, 0)
+		if genesisHash != expectedGenesis {
+			mismatches = append(mismatches, fmt.Sprintf("database has genesis block hash %v but the configured chain's genesis block hash is %v", genesisHash, expectedGenesis))
+		}
+	}
+	identity, err := readInitIdentity(db)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil && identity.Rollup != (common.Address{}) && rollupAddrs.Rollup != (common.Address{}) && identity.Rollup != rollupAddrs.Rollup {
+		mismatches = append(mismatches, fmt.Sprintf("database was initialized for rollup %v but config has rollup %v", identity.Rollup, rollupAddrs.Rollup))
+	}
+	return mismatches, nil
+}
+
+// databasePaths returns the paths force-reinit-with-wipe deletes.
+func databasePaths(stack *node.Node, config *NodeConfig) []string {
+	paths := []string{}
+	for _, name := range []string{"l2chaindata", "arbitrumdata", "wasm", "classic-msg"} {
+		paths = append(paths, filepath.Join(stack.InstanceDir(), name))
+	}
+	ancient := config.Persistent.Ancient
+	if filepath.IsAbs(ancient) && !strings.HasPrefix(ancient, stack.InstanceDir()+string(filepath.Separator)) {
+		paths = append(paths, ancient)
+	}
+	return paths
+}
+
+func pathUsage(root string) (int, int64, error) {
+	var files int
+	var size int64
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		files++
+		size += info.Size()
+		return nil
+	})
+	return files, size, err
+}
+
+// expectedGenesisFromChain builds the configured chain's genesis block from the init message on the parent chain.
+// It returns an empty hash, so the genesis isn't checked, if the parent chain reader is disabled: the init message
+// readInitMessage makes up then needn't match the one the database was initialized from.
+func expectedGenesisFromChain(ctx context.Context, config *NodeConfig, chainConfig *params.ChainConfig, chainId *big.Int, l1Client arbutil.L1Interface, rollupAddrs chaininfo.RollupAddresses) (common.Hash, error) {
+	if !config.Node.ParentChainReader.Enable {
+		log.Info("parent chain reader disabled, so not checking the existing database's genesis block against the chain's init message")
+		return common.Hash{}, nil
+	}
+	initMessage, err := readInitMessage(ctx, config, chainConfig, chainId, l1Client, rollupAddrs)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return expectedGenesisHash(chainConfig, initMessage)
+}
+
+// checkReinitSafety refuses to use an existing database that doesn't match the configured chain.
+// With init.force-reinit-with-wipe the database is deleted instead, so it's initialized from scratch;
+// with init.reinit-dry-run it only reports what would be deleted.
+func checkReinitSafety(ctx context.Context, stack *node.Node, config *NodeConfig, chainId *big.Int, l1Client arbutil.L1Interface, rollupAddrs chaininfo.RollupAddresses) error {
+	initConfig := &config.Init
+	readOnlyDb, err := stack.OpenDatabaseWithFreezerWithExtraOptions("l2chaindata", 0, 0, config.Persistent.Ancient, "l2chaindata/", true, config.Persistent.Pebble.ExtraOptions("l2chaindata"))
+	if err != nil {
+		if isLeveldbNotExistError(err) || isPebbleNotExistError(err) {
+			if initConfig.ReinitDryRun {
+				log.Info("reinit dry run: no existing database found, nothing would be deleted")
+				return errReinitDryRun
+			}
+			return nil
+		}
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	storedConfig := gethexec.TryReadStoredChainConfig(readOnlyDb)
+	if storedConfig == nil {
+		readOnlyDb.Close()
+		if initConfig.ReinitDryRun {
+			log.Info("reinit dry run: database has no stored chain config, so it would be initialized as usual")
+			return errReinitDryRun
+		}
+		return nil
+	}
+	combinedL2ChainInfoFiles := aggregateL2ChainInfoFiles(ctx, config.Chain.InfoFiles, config.Chain.InfoIpfsUrl, config.Chain.InfoIpfsDownloadPath)
+	var expectedConfig *params.ChainConfig
+	var expectedGenesis common.Hash
+	chainInfo, err := chaininfo.ProcessChainInfo(chainId.Uint64(), config.Chain.Name, combinedL2ChainInfoFiles, config.Chain.InfoJson)
+	if err != nil || chainInfo.ChainConfig == nil {
+		// chains not in the chain info rely on the stored chain config
+		log.Info("no chain info to check the existing database against", "err", err)
+	} else {
+		expectedConfig = chainInfo.ChainConfig
+		// A chain without genesis state has its genesis built from its config and init message, unless this
+		// node initializes its own genesis state. Chains with genesis state are imported, so aren't rebuilt.
+		if !chainInfo.HasGenesisState && storedConfig.ArbitrumChainParams.GenesisBlockNum == 0 && !config.Init.DevInit && config.Init.ImportFile == "" {
+			expectedGenesis, err = expectedGenesisFromChain(ctx, config, expectedConfig, chainId, l1Client, rollupAddrs)
+			if err != nil {
+				log.Warn("couldn't build the configured chain's genesis to check the existing database against", "err", err)
+			}
+		}
+	}
+	mismatches, err := initMismatches(readOnlyDb, storedConfig, chainId, expectedConfig, expectedGenesis, rollupAddrs)
+	readOnlyDb.Close()
+	if err != nil {
+		return err
+	}
+	for _, mismatch := range mismatches {
+		log.Error("existing database doesn't match the configured chain", "mismatch", mismatch)
+	}
+
+	if initConfig.ReinitDryRun {
+		if len(mismatches) == 0 {
+			log.Info("reinit dry run: existing database matches the configured chain, force-reinit-with-wipe would keep it")
+			return errReinitDryRun
+		}
+		for _, path := range databasePaths(stack, config) {
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			files, size, err := pathUsage(path)
+			if err != nil {
+				log.Warn("reinit dry run: failed to measure path", "path", path, "err", err)
+				continue
+			}
+			log.Warn("reinit dry run: force-reinit-with-wipe would delete", "path", path, "files", files, "size", fmt.Sprintf("%dMB", size/1024/1024))
+		}
+		return errReinitDryRun
+	}
+	if len(mismatches) == 0 {
+		if initConfig.ForceReinitWithWipe {
+			log.Info("existing database matches the configured chain, ignoring init.force-reinit-with-wipe")
+		}
+		return nil
+	}
+	if !initConfig.ForceReinitWithWipe {
+		return fmt.Errorf("existing database in %s doesn't match the configured chain: %s (check the config; to delete the database and initialize from scratch, use --init.force-reinit-with-wipe, after checking what it deletes with --init.reinit-dry-run)", stack.InstanceDir(), strings.Join(mismatches, "; "))
+	}
+	for _, path := range databasePaths(stack, config) {
+		log.Warn("wiping database for reinit", "path", path)
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("failed to wipe %s: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
@@ -20,13 +21,18 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/offchainlabs/nitro/arbnode"
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
 	"github.com/offchainlabs/nitro/cmd/chaininfo"
 	"github.com/offchainlabs/nitro/cmd/conf"
 	"github.com/offchainlabs/nitro/execution/gethexec"
+	"github.com/offchainlabs/nitro/statetransfer"
 	"github.com/offchainlabs/nitro/util/testhelpers"
 )
 
@@ -436,3 +442,88 @@ func TestOpenInitializeChainDbIncompatibleStateScheme(t *testing.T) {
 		t.Fatalf("Failed to detect incompatible state scheme")
 	}
 }
+
+func TestInitMismatches(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	chainConfig := params.ArbitrumDevTestChainConfig()
+	serializedChainConfig, err := json.Marshal(chainConfig)
+	Require(t, err)
+	initMessage := &arbostypes.ParsedInitMessage{
+		ChainId:               chainConfig.ChainID,
+		InitialL1BaseFee:      arbostypes.DefaultInitialL1BaseFee,
+		ChainConfig:           chainConfig,
+		SerializedChainConfig: serializedChainConfig,
+	}
+	initData := statetransfer.NewMemoryInitDataReader(&statetransfer.ArbosInitializationInfo{})
+	Require(t, gethexec.WriteOrTestGenblock(db, core.DefaultCacheConfigWithScheme(rawdb.HashScheme), initData, chainConfig, initMessage, 0))
+	rollupAddrs := chaininfo.RollupAddresses{Rollup: common.HexToAddress("0x1234")}
+	Require(t, ensureInitIdentity(db, chainConfig, rollupAddrs))
+
+	expectedGenesis, err := expectedGenesisHash(chainConfig, initMessage)
+	Require(t, err)
+	mismatches, err := initMismatches(db, chainConfig, chainConfig.ChainID, chainConfig, expectedGenesis, rollupAddrs)
+	Require(t, err)
+	if len(mismatches) != 0 {
+		t.Fatalf("unexpected mismatches %v", mismatches)
+	}
+
+	// a chain initialized by a different init message on the parent chain has a different genesis
+	otherInitMessage := *initMessage
+	otherInitMessage.InitialL1BaseFee = new(big.Int).Add(initMessage.InitialL1BaseFee, common.Big1)
+	otherGenesis, err := expectedGenesisHash(chainConfig, &otherInitMessage)
+	Require(t, err)
+	otherConfig := *chainConfig
+	otherConfig.ArbitrumChainParams.InitialArbOSVersion++
+	otherRollup := chaininfo.RollupAddresses{Rollup: common.HexToAddress("0x5678")}
+	mismatches, err = initMismatches(db, chainConfig, big.NewInt(1), &otherConfig, otherGenesis, otherRollup)
+	Require(t, err)
+	for _, want := range []string{"chain ID", "ArbOS version", "genesis block hash", "rollup"} {
+		found := false
+		for _, mismatch := range mismatches {
+			if strings.Contains(mismatch, want) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected a %v mismatch in %v", want, mismatches)
+		}
+	}
+}
+
+func TestExpectedGenesisReaderDisabled(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	chainConfig := params.ArbitrumDevTestChainConfig()
+	serializedChainConfig, err := json.Marshal(chainConfig)
+	Require(t, err)
+	// the database was initialized from the init message on the parent chain, with a base fee other than the default
+	initMessage := &arbostypes.ParsedInitMessage{
+		ChainId:               chainConfig.ChainID,
+		InitialL1BaseFee:      new(big.Int).Add(arbostypes.DefaultInitialL1BaseFee, common.Big1),
+		ChainConfig:           chainConfig,
+		SerializedChainConfig: serializedChainConfig,
+	}
+	initData := statetransfer.NewMemoryInitDataReader(&statetransfer.ArbosInitializationInfo{})
+	Require(t, gethexec.WriteOrTestGenblock(db, core.DefaultCacheConfigWithScheme(rawdb.HashScheme), initData, chainConfig, initMessage, 0))
+
+	// without the parent chain reader, the init message made up instead builds a different genesis
+	config := NodeConfigDefault
+	config.Node.ParentChainReader.Enable = false
+	madeUpMessage, err := readInitMessage(context.Background(), &config, chainConfig, chainConfig.ChainID, nil, chaininfo.RollupAddresses{})
+	Require(t, err)
+	madeUpGenesis, err := expectedGenesisHash(chainConfig, madeUpMessage)
+	Require(t, err)
+	if madeUpGenesis == rawdb.ReadCanonicalHash(db, 0) {
+		t.Fatal("made up init message unexpectedly builds the database's genesis")
+	}
+	// so the genesis isn't checked
+	expectedGenesis, err := expectedGenesisFromChain(context.Background(), &config, chainConfig, chainConfig.ChainID, nil, chaininfo.RollupAddresses{})
+	Require(t, err)
+	if expectedGenesis != (common.Hash{}) {
+		t.Fatal("expected no genesis to check without the parent chain reader, got", expectedGenesis)
+	}
+	mismatches, err := initMismatches(db, chainConfig, chainConfig.ChainID, chainConfig, expectedGenesis, chaininfo.RollupAddresses{})
+	Require(t, err)
+	if len(mismatches) != 0 {
+		t.Fatalf("unexpected mismatches %v", mismatches)
+	}
+}
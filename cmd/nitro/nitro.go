@@ -36,12 +36,14 @@ import (
 	_ "github.com/ethereum/go-ethereum/eth/tracers/js"
 	_ "github.com/ethereum/go-ethereum/eth/tracers/native"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/graphql"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/metrics/exp"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
 
 	"github.com/offchainlabs/nitro/arbnode"
 	"github.com/offchainlabs/nitro/arbnode/resourcemanager"
@@ -66,6 +68,7 @@ import (
 	"github.com/offchainlabs/nitro/util/iostat"
 	"github.com/offchainlabs/nitro/util/rpcclient"
 	"github.com/offchainlabs/nitro/util/signature"
+	"github.com/offchainlabs/nitro/util/telemetry"
 	"github.com/offchainlabs/nitro/validator/server_common"
 	"github.com/offchainlabs/nitro/validator/valnode"
 )
@@ -75,6 +78,9 @@ func printSampleUsage(name string) {
 	fmt.Printf("Options:\n")
 	fmt.Printf("  --help\n")
 	fmt.Printf("  --dev: Start a default L2-only dev chain\n")
+	fmt.Printf("\nCommands:\n")
+	fmt.Printf("  %s: Gather a redacted support bundle for filing issues (see %s %s --help)\n", supportBundleCommand, name, supportBundleCommand)
+	fmt.Printf("  %s: Verify the binary's version and the replay machines against a release's checksums (see %s %s --help)\n", verifyReleaseCommand, name, verifyReleaseCommand)
 }
 
 func addUnlockWallet(accountManager *accounts.Manager, walletConf *genericconf.WalletConfig) (common.Address, error) {
@@ -169,6 +175,12 @@ func mainImpl() int {
 	defer cancelFunc()
 
 	args := os.Args[1:]
+	if len(args) > 0 && args[0] == supportBundleCommand {
+		return supportBundleMain(ctx, args[1:])
+	}
+	if len(args) > 0 && args[0] == verifyReleaseCommand {
+		return verifyReleaseMain(ctx, args[1:])
+	}
 	nodeConfig, l2DevWallet, err := ParseNode(ctx, args)
 	if err != nil {
 		confighelpers.PrintErrorAndExit(err, printSampleUsage)
@@ -230,14 +242,6 @@ func mainImpl() int {
 		nodeConfig.Node.ParentChainReader.Enable = true
 	}
 
-	if nodeConfig.Execution.Sequencer.Enable && nodeConfig.Node.ParentChainReader.Enable && nodeConfig.Node.InboxReader.HardReorg {
-		flag.Usage()
-		log.Crit("hard reorgs cannot safely be enabled with sequencer mode enabled")
-	}
-	if nodeConfig.Execution.Sequencer.Enable != nodeConfig.Node.Sequencer {
-		log.Error("consensus and execution must agree if sequencing is enabled or not", "Execution.Sequencer.Enable", nodeConfig.Execution.Sequencer.Enable, "Node.Sequencer", nodeConfig.Node.Sequencer)
-	}
-
 	var dataSigner signature.DataSignerFunc
 	var l1TransactionOptsValidator *bind.TransactOpts
 	var l1TransactionOptsBatchPoster *bind.TransactOpts
@@ -283,10 +287,6 @@ func mainImpl() int {
 	combinedL2ChainInfoFile := aggregateL2ChainInfoFiles(ctx, nodeConfig.Chain.InfoFiles, nodeConfig.Chain.InfoIpfsUrl, nodeConfig.Chain.InfoIpfsDownloadPath)
 
 	if nodeConfig.Node.Staker.Enable {
-		if !nodeConfig.Node.ParentChainReader.Enable {
-			flag.Usage()
-			log.Crit("validator must have the parent chain reader enabled")
-		}
 		strategy, err := nodeConfig.Node.Staker.ParseStrategy()
 		if err != nil {
 			log.Crit("couldn't parse staker strategy", "err", err)
@@ -481,6 +481,10 @@ func mainImpl() int {
 		deferFuncs = append(deferFuncs, func() { l2BlockChain.Stop() })
 	}
 	deferFuncs = append(deferFuncs, func() { closeDb(chainDb, "chainDb") })
+	if errors.Is(err, errReinitDryRun) {
+		log.Info("quitting after reinit dry run")
+		return 0
+	}
 	if err != nil {
 		flag.Usage()
 		log.Error("error initializing database", "err", err)
@@ -495,6 +499,14 @@ func mainImpl() int {
 		return 1
 	}
 
+	if nodeConfig.Init.ExportMessageArchive != "" {
+		if err := exportMessageArchive(ctx, arbDb, nodeConfig.Chain.ID, nodeConfig.Init.ExportMessageArchive); err != nil {
+			log.Error("error exporting message archive", "err", err)
+			return 1
+		}
+		return 0
+	}
+
 	fatalErrChan := make(chan error, 10)
 
 	var blocksReExecutor *blocksreexecutor.BlocksReExecutor
@@ -638,6 +650,38 @@ func mainImpl() int {
 			}
 		}
 	}
+	if nodeConfig.Init.ImportMessageArchive != "" {
+		if err := importMessageArchive(ctx, currentNode, nodeConfig.Init.ImportMessageArchive); err != nil {
+			log.Error("error importing message archive", "err", err)
+			return 1
+		}
+	}
+	telemetryBeacon := telemetry.NewBeacon(func() *telemetry.Config { return &liveNodeConfig.Get().Telemetry }, telemetry.Node{
+		Version: strippedRevision,
+		Roles:   nodeRoles(nodeConfig),
+		ChainID: nodeConfig.Chain.ID,
+		DataDir: stack.InstanceDir(),
+		Sync: func() telemetry.SyncStatus {
+			status := telemetry.SyncStatus{Synced: currentNode.SyncMonitor.Synced()}
+			msgCount, err := currentNode.TxStreamer.GetMessageCount()
+			if target := currentNode.SyncMonitor.SyncTargetMessageCount(); err == nil && target > msgCount {
+				status.MessagesBehind = uint64(target - msgCount)
+			}
+			return status
+		},
+	})
+	stack.RegisterAPIs([]rpc.API{{
+		Namespace: "arbdebug",
+		Version:   "1.0",
+		Service:   telemetry.NewAPI(telemetryBeacon),
+		Public:    false,
+	}, {
+		Namespace: "arb",
+		Version:   "1.0",
+		Service:   arbnode.NewChainInfoAPI(currentNode, chainDescriptorBase(chainInfo, l2BlockChain.Config(), nodeConfig)),
+		Public:    false,
+	}})
+
 	gqlConf := nodeConfig.GraphQL
 	if gqlConf.Enable {
 		if err := graphql.New(stack, execNode.Backend.APIBackend(), execNode.FilterSystem, gqlConf.CORSDomain, gqlConf.VHosts); err != nil {
@@ -662,6 +706,10 @@ func mainImpl() int {
 		// remove previous deferFuncs, StopAndWait closes database and blockchain.
 		deferFuncs = []func(){func() { currentNode.StopAndWait() }}
 	}
+	if err == nil && nodeConfig.Telemetry.Enable {
+		telemetryBeacon.Start(ctx)
+		deferFuncs = append(deferFuncs, func() { telemetryBeacon.StopAndWait() })
+	}
 	if blocksReExecutor != nil && !nodeConfig.Init.ThenQuit {
 		blocksReExecutor.Start(ctx, nil)
 		deferFuncs = append(deferFuncs, func() { blocksReExecutor.StopAndWait() })
@@ -723,6 +771,7 @@ type NodeConfig struct {
 	Init             conf.InitConfig                 `koanf:"init"`
 	Rpc              genericconf.RpcConfig           `koanf:"rpc"`
 	BlocksReExecutor blocksreexecutor.Config         `koanf:"blocks-reexecutor"`
+	Telemetry        telemetry.Config                `koanf:"telemetry" reload:"hot"`
 }
 
 var NodeConfigDefault = NodeConfig{
@@ -748,6 +797,7 @@ var NodeConfigDefault = NodeConfig{
 	PProf:            false,
 	PprofCfg:         genericconf.PProfDefault,
 	BlocksReExecutor: blocksreexecutor.DefaultConfig,
+	Telemetry:        telemetry.DefaultConfig,
 }
 
 func NodeConfigAddOptions(f *flag.FlagSet) {
@@ -774,6 +824,7 @@ func NodeConfigAddOptions(f *flag.FlagSet) {
 	conf.InitConfigAddOptions("init", f)
 	genericconf.RpcConfigAddOptions("rpc", f)
 	blocksreexecutor.ConfigAddOptions("blocks-reexecutor", f)
+	telemetry.ConfigAddOptions("telemetry", f)
 }
 
 func (c *NodeConfig) ResolveDirectoryNames() error {
@@ -825,6 +876,9 @@ func (c *NodeConfig) CanReload(new *NodeConfig) error {
 }
 
 func (c *NodeConfig) Validate() error {
+	if err := validateNodeModes(c); err != nil {
+		return err
+	}
 	if c.Init.RecreateMissingStateFrom > 0 && !c.Execution.Caching.Archive {
 		return errors.New("recreate-missing-state-from enabled for a non-archive node")
 	}
@@ -843,6 +897,9 @@ func (c *NodeConfig) Validate() error {
 	if err := c.BlocksReExecutor.Validate(); err != nil {
 		return err
 	}
+	if err := c.Telemetry.Validate(); err != nil {
+		return err
+	}
 	if c.Node.ValidatorRequired() && (c.Execution.Caching.StateScheme == rawdb.PathScheme) {
 		return errors.New("path cannot be used as execution.caching.state-scheme when validator is required")
 	}
@@ -858,6 +915,10 @@ func ParseNode(ctx context.Context, args []string) (*NodeConfig, *genericconf.Wa
 
 	NodeConfigAddOptions(f)
 
+	if err := checkGethFlags(f, args); err != nil {
+		return nil, nil, err
+	}
+
 	k, err := confighelpers.BeginCommonParse(f, args)
 	if err != nil {
 		return nil, nil, err
@@ -1009,6 +1070,51 @@ func applyChainParameters(ctx context.Context, k *koanf.Koanf, chainId uint64, c
 	return nil
 }
 
+// chainDescriptorBase is the chain info the node was started with, with the chain config it's running and the
+// endpoints it's configured to forward transactions to in place of the chain info's.
+func chainDescriptorBase(chainInfo *chaininfo.ChainInfo, chainConfig *params.ChainConfig, nodeConfig *NodeConfig) *chaininfo.ChainInfo {
+	base := *chainInfo
+	base.ChainConfig = chainConfig
+	if target := nodeConfig.Execution.ForwardingTarget; target != "" && target != "null" {
+		base.SequencerUrl = target
+	}
+	if len(nodeConfig.Execution.SecondaryForwardingTarget) > 0 {
+		base.SecondaryForwardingTarget = strings.Join(nodeConfig.Execution.SecondaryForwardingTarget, ",")
+	}
+	return &base
+}
+
+func exportMessageArchive(ctx context.Context, arbDb ethdb.Database, chainId uint64, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	count, err := arbnode.ExportMessageArchive(ctx, arbDb, file, chainId, 0, 0)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	log.Info("exported message archive", "path", path, "messages", count)
+	return nil
+}
+
+func importMessageArchive(ctx context.Context, node *arbnode.Node, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	count, err := node.ImportMessageArchive(ctx, file)
+	if err != nil {
+		return err
+	}
+	log.Info("imported message archive", "path", path, "messageCount", count)
+	return nil
+}
+
 func initReorg(initConfig conf.InitConfig, chainConfig *params.ChainConfig, inboxTracker *arbnode.InboxTracker) error {
 	var batchCount uint64
 	if initConfig.ReorgToBatch >= 0 {
@@ -0,0 +1,106 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/offchainlabs/nitro/util/redisutil"
+)
+
+// nodeModeRule rejects a combination of node roles that isn't supported, and would otherwise
+// only show up as an obscure error or a node silently doing nothing once running.
+type nodeModeRule struct {
+	conflicts  func(c *NodeConfig) bool
+	problem    string
+	suggestion string
+}
+
+var nodeModeRules = []nodeModeRule{
+	{
+		conflicts: func(c *NodeConfig) bool {
+			return c.Node.Sequencer != c.Execution.Sequencer.Enable
+		},
+		problem:    "consensus and execution disagree on whether this node sequences",
+		suggestion: "set both --node.sequencer and --execution.sequencer.enable for a sequencer, or neither",
+	},
+	{
+		conflicts: func(c *NodeConfig) bool {
+			return c.Node.Sequencer && c.Node.SeqCoordinator.Enable && c.Node.SeqCoordinator.MyUrl == redisutil.INVALID_URL
+		},
+		problem:    "a sequencer with a coordinator but no url can never take the lockout, so it never sequences",
+		suggestion: "set --node.seq-coordinator.my-url to the url other sequencers forward to this one at",
+	},
+	{
+		conflicts: func(c *NodeConfig) bool {
+			return c.Node.Sequencer && c.Node.InboxReader.HardReorg && !c.Node.Dangerous.NoL1Listener
+		},
+		problem:    "hard reorgs would erase messages the sequencer already sequenced",
+		suggestion: "unset --node.inbox-reader.hard-reorg on sequencers",
+	},
+	{
+		conflicts: func(c *NodeConfig) bool {
+			return c.Node.Staker.Enable && c.Node.Dangerous.NoL1Listener
+		},
+		problem:    "a validator must read the parent chain to see assertions",
+		suggestion: "unset --node.dangerous.no-l1-listener, or use --node.block-validator.enable without --node.staker.enable to validate locally",
+	},
+	{
+		conflicts: func(c *NodeConfig) bool {
+			return c.Node.BlockValidator.Enable && c.Node.Dangerous.NoL1Listener
+		},
+		problem:    "the block validator only validates batches read from the parent chain, so without it never makes progress",
+		suggestion: "unset --node.dangerous.no-l1-listener, or unset --node.block-validator.enable",
+	},
+	{
+		conflicts: func(c *NodeConfig) bool {
+			return c.Execution.Caching.Archive && c.Init.Prune != ""
+		},
+		problem:    "pruning on init deletes the historical state an archive node keeps",
+		suggestion: "unset --init.prune for an archive node, or unset --execution.caching.archive for a pruned one",
+	},
+}
+
+// validateNodeModes checks the roles the node is configured for can run together,
+// reporting every unsupported combination along with how to fix it.
+func validateNodeModes(c *NodeConfig) error {
+	var conflicts []string
+	for _, rule := range nodeModeRules {
+		if rule.conflicts(c) {
+			conflicts = append(conflicts, fmt.Sprintf("%v (%v)", rule.problem, rule.suggestion))
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	return fmt.Errorf("unsupported combination of node roles:\n  - %v", strings.Join(conflicts, "\n  - "))
+}
+
+// nodeRoles names the roles the node is configured for, as reported by telemetry.
+func nodeRoles(c *NodeConfig) []string {
+	var roles []string
+	if c.Node.Sequencer {
+		roles = append(roles, "sequencer")
+	}
+	if c.Node.BatchPoster.Enable {
+		roles = append(roles, "batch-poster")
+	}
+	if c.Node.Staker.Enable {
+		roles = append(roles, "staker")
+	}
+	if c.Node.BlockValidator.Enable {
+		roles = append(roles, "block-validator")
+	}
+	if c.Node.Feed.Output.Enable {
+		roles = append(roles, "feed-output")
+	}
+	if c.Execution.Caching.Archive {
+		roles = append(roles, "archive")
+	}
+	if len(roles) == 0 {
+		roles = append(roles, "full-node")
+	}
+	return roles
+}
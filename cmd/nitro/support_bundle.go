@@ -0,0 +1,445 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/cmd/util/confighelpers"
+)
+
+const supportBundleCommand = "support-bundle"
+
+const redactedValue = "<redacted>"
+
+// defaultSupportBundleAllowlist is the config whose string values are included in support bundles. Other strings
+// (urls, keys, passwords, paths to secrets) are redacted, while booleans, numbers and durations are always kept.
+var defaultSupportBundleAllowlist = []string{
+	"chain.name",
+	"log-level",
+	"log-type",
+	"persistent.db-engine",
+	"execution.caching.state-scheme",
+	"node.staker.strategy",
+	"node.feed.input.encoding",
+	"node.data-availability.*.mode",
+	"validation.wasm.allowed-wasm-module-roots",
+}
+
+type SupportBundleConfig struct {
+	Output     string        `koanf:"output"`
+	NodeURL    string        `koanf:"node-url"`
+	MetricsURL string        `koanf:"metrics-url"`
+	LogLines   int           `koanf:"log-lines"`
+	Allow      []string      `koanf:"allow"`
+	Timeout    time.Duration `koanf:"timeout"`
+}
+
+var DefaultSupportBundleConfig = SupportBundleConfig{
+	Output:     "",
+	NodeURL:    "http://localhost:8547",
+	MetricsURL: "http://localhost:6070/debug/metrics",
+	LogLines:   5000,
+	Allow:      []string{},
+	Timeout:    10 * time.Second,
+}
+
+func supportBundleAddOptions(f *flag.FlagSet) {
+	f.String("output", DefaultSupportBundleConfig.Output, "file to write the support bundle to (default nitro-support-bundle-<time>.tar.gz)")
+	f.String("node-url", DefaultSupportBundleConfig.NodeURL, "RPC URL of the running node to read the sync status from (empty to skip)")
+	f.String("metrics-url", DefaultSupportBundleConfig.MetricsURL, "URL of the running node's metrics server to snapshot (empty to skip)")
+	f.Int("log-lines", DefaultSupportBundleConfig.LogLines, "number of the most recent log lines to include")
+	f.StringSlice("allow", DefaultSupportBundleConfig.Allow, "additional config keys whose values are included unredacted, as dotted paths where * matches one key and a trailing ** any number of keys")
+	f.Duration("timeout", DefaultSupportBundleConfig.Timeout, "timeout of each request to the running node")
+}
+
+// configRedactor redacts the config values that aren't allowlisted. Patterns are dotted config paths in which
+// * matches a single key, and a trailing ** matches any number of keys.
+type configRedactor struct {
+	allow [][]string
+}
+
+func newConfigRedactor(patterns []string) *configRedactor {
+	r := &configRedactor{}
+	for _, pattern := range patterns {
+		r.allow = append(r.allow, strings.Split(pattern, "."))
+	}
+	return r
+}
+
+func matchConfigPath(pattern, key []string) bool {
+	for i, part := range pattern {
+		if part == "**" && i == len(pattern)-1 {
+			return true
+		}
+		if i >= len(key) || (part != "*" && part != key[i]) {
+			return false
+		}
+	}
+	return len(pattern) == len(key)
+}
+
+func (r *configRedactor) allowed(key string) bool {
+	path := strings.Split(key, ".")
+	for _, pattern := range r.allow {
+		if matchConfigPath(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact returns the flattened config with the values that aren't allowlisted replaced, and the redacted strings.
+func (r *configRedactor) redact(config map[string]interface{}) (map[string]interface{}, []string) {
+	redacted := make(map[string]interface{}, len(config))
+	var secrets []string
+	for key, value := range config {
+		switch v := value.(type) {
+		case nil, bool, int, int32, int64, uint, uint32, uint64, float32, float64, time.Duration:
+			redacted[key] = value
+			continue
+		case string:
+			if r.allowed(key) || v == "" {
+				redacted[key] = value
+				continue
+			}
+			if _, err := time.ParseDuration(v); err == nil {
+				redacted[key] = value
+				continue
+			}
+			secrets = append(secrets, v)
+		case []string:
+			if r.allowed(key) || len(v) == 0 {
+				redacted[key] = value
+				continue
+			}
+			secrets = append(secrets, v...)
+		default:
+			if r.allowed(key) {
+				redacted[key] = value
+				continue
+			}
+		}
+		redacted[key] = redactedValue
+	}
+	return redacted, secrets
+}
+
+// redactLine replaces the redacted config values that appear in a log line.
+func redactLine(line string, secrets []string) string {
+	for _, secret := range secrets {
+		// short values, such as "full" or "1", are too common to be secrets
+		if len(secret) >= 8 {
+			line = strings.ReplaceAll(line, secret, redactedValue)
+		}
+	}
+	return line
+}
+
+// supportBundle is the archive of a support bundle, to which each section is written as a file. A section that
+// fails is recorded in the manifest instead, so a bundle can be gathered from a node that isn't running.
+type supportBundle struct {
+	tar      *tar.Writer
+	manifest map[string]string
+}
+
+func (b *supportBundle) add(name string, data []byte) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := b.tar.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := b.tar.Write(data)
+	return err
+}
+
+func (b *supportBundle) addSection(name string, gather func() ([]byte, error)) error {
+	data, err := gather()
+	if err != nil {
+		b.manifest[name] = err.Error()
+		return nil
+	}
+	b.manifest[name] = "ok"
+	return b.add(name, data)
+}
+
+func marshalSection(value interface{}) ([]byte, error) {
+	return json.MarshalIndent(value, "", "  ")
+}
+
+// tailLines returns up to count of the last lines of the file.
+func tailLines(path string, count int) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > 2*count {
+			lines = append(lines[:0], lines[len(lines)-count:]...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) > count {
+		lines = lines[len(lines)-count:]
+	}
+	return lines, nil
+}
+
+// directorySizes returns the size on disk of each database directory in the chain directory.
+func directorySizes(chainDir string) (map[string]int64, error) {
+	entries, err := os.ReadDir(chainDir)
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[string]int64)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		var size int64
+		err := filepath.WalkDir(filepath.Join(chainDir, entry.Name()), func(_ string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Type().IsRegular() {
+				info, err := d.Info()
+				if err != nil {
+					return err
+				}
+				size += info.Size()
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sizes[entry.Name()] = size
+	}
+	return sizes, nil
+}
+
+func syncStatus(ctx context.Context, nodeURL string) (map[string]interface{}, error) {
+	client, err := rpc.DialContext(ctx, nodeURL)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	status := make(map[string]interface{})
+	for _, method := range []string{"eth_syncing", "eth_blockNumber", "eth_chainId", "web3_clientVersion"} {
+		var result interface{}
+		if err := client.CallContext(ctx, &result, method); err != nil {
+			status[method] = map[string]string{"error": err.Error()}
+		} else {
+			status[method] = result
+		}
+	}
+	return status, nil
+}
+
+func fetchMetrics(ctx context.Context, metricsURL string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, metricsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metrics server returned status %v", response.StatusCode)
+	}
+	return io.ReadAll(response.Body)
+}
+
+func printSupportBundleUsage(progname string) {
+	fmt.Printf("\n")
+	fmt.Printf("Sample usage:                  %s %s [bundle options] -- [node options, e.g. --conf.file node.json]\n", progname, supportBundleCommand)
+}
+
+// supportBundleMain gathers the node's redacted config, recent logs, sync status, metrics, database sizes and
+// versions into an archive to attach when filing an issue. Options before "--" configure the bundle, and
+// those after it are the node's own options, so the same config is read as when running the node.
+func supportBundleMain(ctx context.Context, args []string) int {
+	bundleArgs, nodeArgs := args, []string{}
+	for i, arg := range args {
+		if arg == "--" {
+			bundleArgs, nodeArgs = args[:i], args[i+1:]
+			break
+		}
+	}
+	f := flag.NewFlagSet(supportBundleCommand, flag.ContinueOnError)
+	supportBundleAddOptions(f)
+	k, err := confighelpers.BeginCommonParse(f, bundleArgs)
+	if err != nil {
+		confighelpers.PrintErrorAndExit(err, printSupportBundleUsage)
+	}
+	var config SupportBundleConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		confighelpers.PrintErrorAndExit(err, printSupportBundleUsage)
+	}
+	if err := writeSupportBundle(ctx, &config, nodeArgs); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing support bundle: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func writeSupportBundle(ctx context.Context, config *SupportBundleConfig, nodeArgs []string) error {
+	nodeFlags := flag.NewFlagSet("", flag.ContinueOnError)
+	NodeConfigAddOptions(nodeFlags)
+	nodeKoanf, err := confighelpers.BeginCommonParse(nodeFlags, nodeArgs)
+	if err != nil {
+		return fmt.Errorf("error reading the node config: %w", err)
+	}
+	var nodeConfig NodeConfig
+	if err := confighelpers.EndCommonParse(nodeKoanf, &nodeConfig); err != nil {
+		return fmt.Errorf("error reading the node config: %w", err)
+	}
+	if err := nodeConfig.Persistent.ResolveDirectoryNames(); err != nil {
+		return err
+	}
+	redactor := newConfigRedactor(append(append([]string{}, defaultSupportBundleAllowlist...), config.Allow...))
+	redactedConfig, secrets := redactor.redact(nodeKoanf.All())
+	// replace longer secrets first, so one containing another is fully redacted
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+
+	output := config.Output
+	if output == "" {
+		output = fmt.Sprintf("nitro-support-bundle-%s.tar.gz", time.Now().UTC().Format("20060102-150405"))
+	}
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gzipWriter := gzip.NewWriter(file)
+	bundle := &supportBundle{tar: tar.NewWriter(gzipWriter), manifest: make(map[string]string)}
+
+	vcsRevision, strippedRevision, vcsTime := confighelpers.GetVersion()
+	sections := []struct {
+		name   string
+		gather func() ([]byte, error)
+	}{
+		{"versions.json", func() ([]byte, error) {
+			return marshalSection(map[string]string{
+				"revision":  vcsRevision,
+				"version":   strippedRevision,
+				"vcs-time":  vcsTime,
+				"go":        runtime.Version(),
+				"os":        runtime.GOOS,
+				"arch":      runtime.GOARCH,
+				"generated": time.Now().UTC().Format(time.RFC3339),
+			})
+		}},
+		{"config.json", func() ([]byte, error) {
+			return marshalSection(redactedConfig)
+		}},
+		{"logs.txt", func() ([]byte, error) {
+			if !nodeConfig.FileLogging.Enable {
+				return nil, errors.New("file logging is disabled")
+			}
+			logFile := nodeConfig.FileLogging.File
+			if !filepath.IsAbs(logFile) {
+				logFile = filepath.Join(nodeConfig.Persistent.LogDir, logFile)
+			}
+			lines, err := tailLines(logFile, config.LogLines)
+			if err != nil {
+				return nil, err
+			}
+			var builder strings.Builder
+			for _, line := range lines {
+				builder.WriteString(redactLine(line, secrets))
+				builder.WriteByte('\n')
+			}
+			return []byte(builder.String()), nil
+		}},
+		{"sync-status.json", func() ([]byte, error) {
+			if config.NodeURL == "" {
+				return nil, errors.New("no node url")
+			}
+			ctx, cancel := context.WithTimeout(ctx, config.Timeout)
+			defer cancel()
+			status, err := syncStatus(ctx, config.NodeURL)
+			if err != nil {
+				return nil, err
+			}
+			return marshalSection(status)
+		}},
+		{"metrics.json", func() ([]byte, error) {
+			if config.MetricsURL == "" {
+				return nil, errors.New("no metrics url")
+			}
+			ctx, cancel := context.WithTimeout(ctx, config.Timeout)
+			defer cancel()
+			return fetchMetrics(ctx, config.MetricsURL)
+		}},
+		{"db-stats.json", func() ([]byte, error) {
+			sizes, err := directorySizes(nodeConfig.Persistent.Chain)
+			if err != nil {
+				return nil, err
+			}
+			return marshalSection(map[string]interface{}{
+				"db-engine":         nodeConfig.Persistent.DBEngine,
+				"directory-bytes":   sizes,
+				"ancient-directory": nodeConfig.Persistent.Ancient != "",
+			})
+		}},
+	}
+	for _, section := range sections {
+		if err := bundle.addSection(section.name, section.gather); err != nil {
+			return err
+		}
+	}
+	manifest, err := marshalSection(bundle.manifest)
+	if err != nil {
+		return err
+	}
+	if err := bundle.add("manifest.json", manifest); err != nil {
+		return err
+	}
+	if err := bundle.tar.Close(); err != nil {
+		return err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote support bundle to %s\n", output)
+	for _, section := range sections {
+		if status := bundle.manifest[section.name]; status != "ok" {
+			fmt.Printf("  skipped %s: %s\n", section.name, status)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/cmd/util/confighelpers"
+	"github.com/offchainlabs/nitro/validator/server_common"
+)
+
+const verifyReleaseCommand = "verify-release"
+
+// the replay machine artifacts of each module root, as published with releases
+var releaseMachineFiles = []string{"machine.wavm.br", "replay.wasm"}
+
+type VerifyReleaseConfig struct {
+	ExpectedVersion string        `koanf:"expected-version"`
+	Checksums       string        `koanf:"checksums"`
+	RootPath        string        `koanf:"root-path"`
+	Prover          string        `koanf:"prover"`
+	Timeout         time.Duration `koanf:"timeout"`
+}
+
+var DefaultVerifyReleaseConfig = VerifyReleaseConfig{
+	ExpectedVersion: "",
+	Checksums:       "",
+	RootPath:        "",
+	Prover:          "",
+	Timeout:         time.Minute,
+}
+
+func verifyReleaseAddOptions(f *flag.FlagSet) {
+	f.String("expected-version", DefaultVerifyReleaseConfig.ExpectedVersion, "version the binary should have been built from (empty to only check it was built from an unmodified tree)")
+	f.String("checksums", DefaultVerifyReleaseConfig.Checksums, "path or URL of the release's published checksums, in sha256sum format with artifacts named <module root>/<file> (empty to skip checking artifact hashes)")
+	f.String("root-path", DefaultVerifyReleaseConfig.RootPath, "path to the machine folders, as in --validation.wasm.root-path (empty to search the default locations)")
+	f.String("prover", DefaultVerifyReleaseConfig.Prover, "path to the prover binary, used to recompute the module root of each machine (empty to skip)")
+	f.Duration("timeout", DefaultVerifyReleaseConfig.Timeout, "timeout for fetching the checksums and for each module root computation")
+}
+
+// releaseCheck is the outcome of verifying one property of the release.
+type releaseCheck struct {
+	name   string
+	ok     bool
+	detail string
+	// unchecked is set when there was nothing to check against, which is reported but isn't a failure
+	unchecked bool
+}
+
+// parseChecksums reads checksums in sha256sum format, keyed by artifact name.
+func parseChecksums(r io.Reader) (map[string]string, error) {
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid checksum line %q", line)
+		}
+		sum := strings.ToLower(fields[0])
+		if decoded, err := hex.DecodeString(sum); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("invalid sha256 checksum %q", fields[0])
+		}
+		// sha256sum marks files read in binary mode with a leading *
+		name := filepath.ToSlash(strings.TrimPrefix(fields[1], "*"))
+		checksums[name] = sum
+	}
+	return checksums, scanner.Err()
+}
+
+func readChecksums(ctx context.Context, source string, timeout time.Duration) (map[string]string, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		file, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return parseChecksums(file)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("checksums request returned status %v", response.StatusCode)
+	}
+	return parseChecksums(response.Body)
+}
+
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func proverModuleRoot(ctx context.Context, prover string, machineDir string, timeout time.Duration) (common.Hash, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	printRoot := exec.CommandContext(ctx, prover, "machine.wavm.br", "--print-wasmmoduleroot")
+	printRoot.Dir = machineDir
+	out, err := printRoot.Output()
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to compute module root: %w", err)
+	}
+	root := strings.TrimSpace(string(out))
+	if len(common.FromHex(root)) != common.HashLength {
+		return common.Hash{}, fmt.Errorf("prover printed an invalid module root %q", root)
+	}
+	return common.HexToHash(root), nil
+}
+
+// checkVersion verifies the binary was built from an unmodified tree, at the expected version if given.
+func checkVersion(expected, vcsRevision, strippedRevision, vcsTime string) releaseCheck {
+	check := releaseCheck{name: "version", detail: fmt.Sprintf("%v built %v", vcsRevision, vcsTime)}
+	switch {
+	case vcsRevision == "development" || vcsRevision == "unknown":
+		check.detail += ", built without version information"
+	case strings.HasSuffix(vcsRevision, "-modified"):
+		check.detail += ", built from a modified tree"
+	case expected != "" && strings.TrimPrefix(expected, "v") != strippedRevision:
+		check.detail += ", expected " + expected
+	default:
+		check.ok = true
+	}
+	return check
+}
+
+// checkMachines verifies the artifacts of each machine against the checksums, if any, and recomputes each
+// machine's module root with the prover, if given.
+func checkMachines(ctx context.Context, config *VerifyReleaseConfig, checksums map[string]string) ([]releaseCheck, error) {
+	locator, err := server_common.NewMachineLocator(config.RootPath)
+	if err != nil {
+		return nil, err
+	}
+	roots := locator.ModuleRoots()
+	if len(roots) == 0 {
+		return nil, errors.New("no machines found")
+	}
+	var checks []releaseCheck
+	for _, root := range roots {
+		machineDir := locator.GetMachinePath(root)
+		for _, file := range releaseMachineFiles {
+			name := root.Hex() + "/" + file
+			check := releaseCheck{name: name}
+			sum, err := fileSHA256(filepath.Join(machineDir, file))
+			if _, listed := checksums[name]; errors.Is(err, os.ErrNotExist) && file == "replay.wasm" && !listed {
+				// older releases didn't publish the replay binary
+				continue
+			}
+			if err != nil {
+				check.detail = err.Error()
+				checks = append(checks, check)
+				continue
+			}
+			check.detail = "sha256 " + sum
+			if checksums == nil {
+				check.unchecked = true
+				check.detail += ", no published checksums given"
+			} else if expected, listed := checksums[name]; !listed {
+				check.detail += ", not in the published checksums"
+			} else if expected != sum {
+				check.detail += ", published " + expected
+			} else {
+				check.ok = true
+			}
+			checks = append(checks, check)
+		}
+		if config.Prover != "" {
+			check := releaseCheck{name: root.Hex() + " module root"}
+			computed, err := proverModuleRoot(ctx, config.Prover, machineDir, config.Timeout)
+			if err != nil {
+				check.detail = err.Error()
+			} else if computed != root {
+				check.detail = "computed " + computed.Hex()
+			} else {
+				check.ok = true
+				check.detail = "matches"
+			}
+			checks = append(checks, check)
+		}
+	}
+	return checks, nil
+}
+
+func printVerifyReleaseUsage(progname string) {
+	fmt.Printf("\n")
+	fmt.Printf("Sample usage:                  %s %s --expected-version v3.2.1 --checksums sha256sums.txt --prover target/bin/prover\n", progname, verifyReleaseCommand)
+}
+
+// verifyReleaseMain checks the binary's embedded version and the replay machine artifacts validators run
+// against the checksums published with the release, reporting every mismatch. To rebuild the replay binary
+// itself and check its module root, use the verify-replay tool.
+func verifyReleaseMain(ctx context.Context, args []string) int {
+	f := flag.NewFlagSet(verifyReleaseCommand, flag.ContinueOnError)
+	verifyReleaseAddOptions(f)
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		confighelpers.PrintErrorAndExit(err, printVerifyReleaseUsage)
+	}
+	var config VerifyReleaseConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		confighelpers.PrintErrorAndExit(err, printVerifyReleaseUsage)
+	}
+
+	vcsRevision, strippedRevision, vcsTime := confighelpers.GetVersion()
+	checks := []releaseCheck{checkVersion(config.ExpectedVersion, vcsRevision, strippedRevision, vcsTime)}
+	var checksums map[string]string
+	if config.Checksums != "" {
+		checksums, err = readChecksums(ctx, config.Checksums, config.Timeout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading checksums: %v\n", err)
+			return 1
+		}
+	}
+	machineChecks, err := checkMachines(ctx, &config, checksums)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error checking machines: %v\n", err)
+		return 1
+	}
+	checks = append(checks, machineChecks...)
+
+	failed, unchecked := 0, 0
+	for _, check := range checks {
+		status := "ok"
+		if check.unchecked {
+			status = "UNCHECKED"
+			unchecked++
+		} else if !check.ok {
+			status = "MISMATCH"
+			failed++
+		}
+		fmt.Printf("%-9s %s: %s\n", status, check.name, check.detail)
+	}
+	if failed > 0 {
+		fmt.Printf("\n%d of %d checks failed\n", failed, len(checks))
+		return 1
+	}
+	if unchecked > 0 {
+		fmt.Printf("\n%d of %d checks passed, %d unchecked without published checksums\n", len(checks)-unchecked, len(checks), unchecked)
+		return 0
+	}
+	fmt.Printf("\nall %d checks passed\n", len(checks))
+	return 0
+}
@@ -0,0 +1,98 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestParseChecksums(t *testing.T) {
+	sum := strings.Repeat("ab", sha256.Size)
+	checksums, err := parseChecksums(strings.NewReader("# release checksums\n" + sum + "  0x01/machine.wavm.br\n" + sum + " *0x01/replay.wasm\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checksums) != 2 || checksums["0x01/machine.wavm.br"] != sum || checksums["0x01/replay.wasm"] != sum {
+		t.Fatal("unexpected checksums", checksums)
+	}
+	if _, err := parseChecksums(strings.NewReader("abcd  machine.wavm.br\n")); err == nil {
+		t.Fatal("parsed a truncated checksum")
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	if check := checkVersion("v3.2.1", "v3.2.1", "3.2.1", "now"); !check.ok {
+		t.Fatal("rejected the expected version:", check.detail)
+	}
+	if check := checkVersion("v3.2.1", "v3.2.0", "3.2.0", "now"); check.ok {
+		t.Fatal("accepted an unexpected version")
+	}
+	if check := checkVersion("", "abcdef1-modified", "abcdef1-modified", "now"); check.ok {
+		t.Fatal("accepted a build from a modified tree")
+	}
+	if check := checkVersion("", "development", "development", "development"); check.ok {
+		t.Fatal("accepted a build without version information")
+	}
+}
+
+func TestCheckMachines(t *testing.T) {
+	rootPath := t.TempDir()
+	root := common.HexToHash("0x1234")
+	machineDir := filepath.Join(rootPath, root.Hex())
+	if err := os.Mkdir(machineDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(machineDir, "module-root.txt"), []byte(root.Hex()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	machine := []byte("machine")
+	if err := os.WriteFile(filepath.Join(machineDir, "machine.wavm.br"), machine, 0600); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(machine)
+	config := &VerifyReleaseConfig{RootPath: rootPath}
+
+	check := func(checksums map[string]string, expectOk bool) {
+		t.Helper()
+		checks, err := checkMachines(context.Background(), config, checksums)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the replay binary isn't required unless it was published
+		if len(checks) != 1 || checks[0].ok != expectOk || checks[0].unchecked {
+			t.Fatal("unexpected checks", fmt.Sprintf("%+v", checks))
+		}
+	}
+	// without checksums, the artifacts are reported but not passed
+	checks, err := checkMachines(context.Background(), config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checks) != 1 || checks[0].ok || !checks[0].unchecked {
+		t.Fatal("expected the machine unchecked without checksums", fmt.Sprintf("%+v", checks))
+	}
+	check(map[string]string{root.Hex() + "/machine.wavm.br": hex.EncodeToString(sum[:])}, true)
+	check(map[string]string{root.Hex() + "/machine.wavm.br": strings.Repeat("00", sha256.Size)}, false)
+	check(map[string]string{}, false)
+
+	checks, err = checkMachines(context.Background(), config, map[string]string{
+		root.Hex() + "/machine.wavm.br": hex.EncodeToString(sum[:]),
+		root.Hex() + "/replay.wasm":     hex.EncodeToString(sum[:]),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checks) != 2 || checks[1].ok {
+		t.Fatal("a missing published replay binary wasn't reported", fmt.Sprintf("%+v", checks))
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package etcdcoordinator
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/offchainlabs/nitro/util/etcdutil"
+	"github.com/offchainlabs/nitro/util/redisutil"
+)
+
+// EtcdCoordinator builds upon EtcdCoordinator of etcdutil with additional functionality
+type EtcdCoordinator struct {
+	*etcdutil.EtcdCoordinator
+}
+
+// UpdatePriorities updates the priority list of sequencers
+func (ec *EtcdCoordinator) UpdatePriorities(ctx context.Context, priorities []string) error {
+	if len(priorities) == 0 {
+		return ec.Client.Delete(ctx, redisutil.PRIORITIES_KEY)
+	}
+	return ec.Client.Put(ctx, redisutil.PRIORITIES_KEY, []byte(strings.Join(priorities, ",")), clientv3.NoLease)
+}
+
+// UpdatePriorityPolicy stores the policy refining the priorities, or removes it if nil
+func (ec *EtcdCoordinator) UpdatePriorityPolicy(ctx context.Context, policy *redisutil.PriorityPolicy) error {
+	if policy == nil {
+		return ec.Client.Delete(ctx, redisutil.POLICY_KEY)
+	}
+	policyBytes, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	// make sure the sequencers will be able to use it
+	if _, err := redisutil.ParsePriorityPolicy(policyBytes); err != nil {
+		return err
+	}
+	return ec.Client.Put(ctx, redisutil.POLICY_KEY, policyBytes, clientv3.NoLease)
+}
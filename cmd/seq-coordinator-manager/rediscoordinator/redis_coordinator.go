@@ -2,6 +2,7 @@ package rediscoordinator
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"strings"
 
@@ -17,10 +18,10 @@ type RedisCoordinator struct {
 // UpdatePriorities updates the priority list of sequencers
 func (rc *RedisCoordinator) UpdatePriorities(ctx context.Context, priorities []string) error {
 	if len(priorities) == 0 {
-		return rc.Client.Del(ctx, redisutil.PRIORITIES_KEY).Err()
+		return rc.Client.Del(ctx, rc.Key(redisutil.PRIORITIES_KEY)).Err()
 	}
 	prioritiesString := strings.Join(priorities, ",")
-	err := rc.Client.Set(ctx, redisutil.PRIORITIES_KEY, prioritiesString, 0).Err()
+	err := rc.Client.Set(ctx, rc.Key(redisutil.PRIORITIES_KEY), prioritiesString, 0).Err()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			err = errors.New("sequencer priorities unset")
@@ -29,3 +30,19 @@ func (rc *RedisCoordinator) UpdatePriorities(ctx context.Context, priorities []s
 	}
 	return nil
 }
+
+// UpdatePriorityPolicy stores the policy refining the priorities, or removes it if nil
+func (rc *RedisCoordinator) UpdatePriorityPolicy(ctx context.Context, policy *redisutil.PriorityPolicy) error {
+	if policy == nil {
+		return rc.Client.Del(ctx, rc.Key(redisutil.POLICY_KEY)).Err()
+	}
+	policyBytes, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	// make sure the sequencers will be able to use it
+	if _, err := redisutil.ParsePriorityPolicy(policyBytes); err != nil {
+		return err
+	}
+	return rc.Client.Set(ctx, rc.Key(redisutil.POLICY_KEY), string(policyBytes), 0).Err()
+}
@@ -5,11 +5,14 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/enescakir/emoji"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/gdamore/tcell/v2"
+	"github.com/offchainlabs/nitro/cmd/seq-coordinator-manager/etcdcoordinator"
 	"github.com/offchainlabs/nitro/cmd/seq-coordinator-manager/rediscoordinator"
+	"github.com/offchainlabs/nitro/util/etcdutil"
 	"github.com/offchainlabs/nitro/util/redisutil"
 	"github.com/rivo/tview"
 )
@@ -27,14 +30,42 @@ var addSeqForm = tview.NewForm()
 var priorityForm = tview.NewForm()
 var nonPriorityForm = tview.NewForm()
 
+// coordinator is the backend the sequencers coordinate through, Redis or etcd
+type coordinator interface {
+	CurrentChosenSequencer(ctx context.Context) (string, error)
+	GetPriorities(ctx context.Context) ([]string, error)
+	GetLiveliness(ctx context.Context) ([]string, error)
+	UpdatePriorities(ctx context.Context, priorities []string) error
+}
+
 // Sequencer coordinator management UI data store
 type manager struct {
-	redisCoordinator *rediscoordinator.RedisCoordinator
-	prioritiesSet    map[string]bool
-	livelinessSet    map[string]bool
-	priorityList     []string
-	nonPriorityList  []string
-	maxURLSize       int
+	coordinator     coordinator
+	prioritiesSet   map[string]bool
+	livelinessSet   map[string]bool
+	priorityList    []string
+	nonPriorityList []string
+	maxURLSize      int
+}
+
+// newCoordinator connects to etcd for etcd:// urls, and otherwise to Redis
+func newCoordinator(url string) (coordinator, error) {
+	if strings.HasPrefix(url, "etcd://") {
+		config, err := etcdutil.ConfigFromURL(url)
+		if err != nil {
+			return nil, err
+		}
+		etcdutilCoordinator, err := etcdutil.NewEtcdCoordinator(config)
+		if err != nil {
+			return nil, err
+		}
+		return &etcdcoordinator.EtcdCoordinator{EtcdCoordinator: etcdutilCoordinator}, nil
+	}
+	redisutilCoordinator, err := redisutil.NewRedisCoordinator(url)
+	if err != nil {
+		return nil, err
+	}
+	return &rediscoordinator.RedisCoordinator{RedisCoordinator: redisutilCoordinator}, nil
 }
 
 func main() {
@@ -43,19 +74,16 @@ func main() {
 
 	args := os.Args[1:]
 	if len(args) != 1 {
-		fmt.Fprintf(os.Stderr, "Usage: seq-coordinator-manager [redis-url]\n")
+		fmt.Fprintf(os.Stderr, "Usage: seq-coordinator-manager [redis-url | etcd://[user:password@]host:port[,host:port...][/key-prefix]]\n")
 		os.Exit(1)
 	}
-	redisURL := args[0]
-	redisutilCoordinator, err := redisutil.NewRedisCoordinator(redisURL)
+	backend, err := newCoordinator(args[0])
 	if err != nil {
 		panic(err)
 	}
 
 	seqManager := &manager{
-		redisCoordinator: &rediscoordinator.RedisCoordinator{
-			RedisCoordinator: redisutilCoordinator,
-		},
+		coordinator:   backend,
 		prioritiesSet: make(map[string]bool),
 		livelinessSet: make(map[string]bool),
 		// maxURLSize dictates the allowed max length for sequencer urls
@@ -235,7 +263,7 @@ func (sm *manager) updatePriorityList(ctx context.Context, index int, target int
 // populateLists populates seq's in priority list and seq's that are online but not in priority
 func (sm *manager) populateLists(ctx context.Context) {
 	prioritySeqList.Clear()
-	chosen, err := sm.redisCoordinator.CurrentChosenSequencer(ctx)
+	chosen, err := sm.coordinator.CurrentChosenSequencer(ctx)
 	if err != nil {
 		panic(err)
 	}
@@ -290,9 +318,9 @@ func (sm *manager) addSeqPriorityForm(ctx context.Context) *tview.Form {
 	return addSeqForm
 }
 
-// pushUpdates pushes the local changes to the redis server
+// pushUpdates pushes the local changes to the coordination backend
 func (sm *manager) pushUpdates(ctx context.Context) {
-	err := sm.redisCoordinator.UpdatePriorities(ctx, sm.priorityList)
+	err := sm.coordinator.UpdatePriorities(ctx, sm.priorityList)
 	if err != nil {
 		log.Warn("Failed to push local changes to the priority list")
 	}
@@ -301,14 +329,14 @@ func (sm *manager) pushUpdates(ctx context.Context) {
 
 // refreshAllLists gets the current status of all the lists displayed in the UI
 func (sm *manager) refreshAllLists(ctx context.Context) {
-	priorityList, err := sm.redisCoordinator.GetPriorities(ctx)
+	priorityList, err := sm.coordinator.GetPriorities(ctx)
 	if err != nil {
 		panic(err)
 	}
 	sm.priorityList = priorityList
 	sm.prioritiesSet = getMapfromlist(priorityList)
 
-	livelinessList, err := sm.redisCoordinator.GetLiveliness(ctx)
+	livelinessList, err := sm.coordinator.GetLiveliness(ctx)
 	if err != nil {
 		panic(err)
 	}
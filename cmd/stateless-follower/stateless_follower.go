@@ -0,0 +1,148 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+// stateless-follower follows a chain by applying block witnesses from a node serving arb_blockWitness, and serves
+// reads of the state recent blocks touched, without keeping the chain's state.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/cmd/chaininfo"
+	"github.com/offchainlabs/nitro/cmd/conf"
+	"github.com/offchainlabs/nitro/cmd/genericconf"
+	"github.com/offchainlabs/nitro/cmd/util/confighelpers"
+	"github.com/offchainlabs/nitro/execution/gethexec"
+)
+
+type Config struct {
+	Conf     genericconf.ConfConfig           `koanf:"conf"`
+	Chain    conf.L2Config                    `koanf:"chain"`
+	LogLevel string                           `koanf:"log-level"`
+	LogType  string                           `koanf:"log-type"`
+	HTTP     genericconf.HTTPConfig           `koanf:"http"`
+	Follower gethexec.StatelessFollowerConfig `koanf:"follower"`
+}
+
+var ConfigDefault = Config{
+	Conf:     genericconf.ConfConfigDefault,
+	Chain:    conf.L2ConfigDefault,
+	LogLevel: "INFO",
+	LogType:  "plaintext",
+	HTTP:     genericconf.HTTPConfigDefault,
+	Follower: gethexec.DefaultStatelessFollowerConfig,
+}
+
+func ConfigAddOptions(f *flag.FlagSet) {
+	genericconf.ConfConfigAddOptions("conf", f)
+	conf.L2ConfigAddOptions("chain", f)
+	f.String("log-level", ConfigDefault.LogLevel, "log level, valid values are CRIT, ERROR, WARN, INFO, DEBUG, TRACE")
+	f.String("log-type", ConfigDefault.LogType, "log type (plaintext or json)")
+	genericconf.HTTPConfigAddOptions("http", f)
+	gethexec.StatelessFollowerConfigAddOptions("follower", f)
+}
+
+func parseConfig(args []string) (*Config, error) {
+	f := flag.NewFlagSet("", flag.ContinueOnError)
+	ConfigAddOptions(f)
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+	var config Config
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	if config.Conf.Dump {
+		if err := confighelpers.DumpConfig(k, map[string]interface{}{}); err != nil {
+			return nil, err
+		}
+	}
+	return &config, config.Follower.Validate()
+}
+
+func printSampleUsage(progname string) {
+	fmt.Printf("\n")
+	fmt.Printf("Sample usage:                  %s --chain.id=<L2 chain id> --follower.source.url=<node serving arb_blockWitness> \n", progname)
+}
+
+func main() {
+	if err := startup(); err != nil {
+		log.Error("Error running stateless follower", "err", err)
+		os.Exit(1)
+	}
+}
+
+func startup() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config, err := parseConfig(os.Args[1:])
+	if err != nil || (config.Chain.ID == 0 && config.Chain.Name == "") {
+		confighelpers.PrintErrorAndExit(err, printSampleUsage)
+	}
+	handler, err := genericconf.HandlerFromLogType(config.LogType, io.Writer(os.Stderr))
+	if err != nil {
+		return fmt.Errorf("error parsing log type when creating handler: %w", err)
+	}
+	logLevel, err := genericconf.ToSlogLevel(config.LogLevel)
+	if err != nil {
+		confighelpers.PrintErrorAndExit(err, printSampleUsage)
+	}
+	glogger := log.NewGlogHandler(handler)
+	glogger.Verbosity(logLevel)
+	log.SetDefault(log.NewLogger(glogger))
+
+	// the chain info's genesis block number is used as is, since the follower has no database to read it from
+	chainInfo, err := chaininfo.ProcessChainInfo(config.Chain.ID, config.Chain.Name, config.Chain.InfoFiles, config.Chain.InfoJson)
+	if err != nil {
+		return err
+	}
+	if chainInfo.ChainConfig == nil {
+		return fmt.Errorf("missing chain config for chain %v", chainInfo.ChainName)
+	}
+
+	stackConf := node.DefaultConfig
+	stackConf.DataDir = ""
+	stackConf.P2P.ListenAddr = ""
+	stackConf.P2P.NoDial = true
+	stackConf.P2P.NoDiscovery = true
+	config.HTTP.Apply(&stackConf)
+	stack, err := node.New(&stackConf)
+	if err != nil {
+		return err
+	}
+	defer stack.Close()
+
+	follower := gethexec.NewStatelessFollower(&config.Follower, chainInfo.ChainConfig)
+	stack.RegisterAPIs([]rpc.API{{
+		Namespace: "eth",
+		Version:   "1.0",
+		Service:   gethexec.NewStatelessFollowerAPI(follower),
+		Public:    true,
+	}})
+	if err := follower.Start(ctx); err != nil {
+		return err
+	}
+	defer follower.StopAndWait()
+	if err := stack.Start(); err != nil {
+		return fmt.Errorf("error starting the rpc server: %w", err)
+	}
+
+	sigint := make(chan os.Signal, 1)
+	signal.Notify(sigint, os.Interrupt, syscall.SIGTERM)
+	<-sigint
+	log.Info("shutting down because of sigint")
+	return nil
+}
@@ -112,6 +112,8 @@ var envvarsToSplitOnComma map[string]any = map[string]any{
 	"node.feed.input.secondary-url":                                  struct{}{},
 	"node.feed.input.url":                                            struct{}{},
 	"node.feed.input.verify.allowed-addresses":                       struct{}{},
+	"node.seq-coordinator.encryption.fallback-decryption-keys":       struct{}{},
+	"node.seq-coordinator.etcd.endpoints":                            struct{}{},
 	"node.seq-coordinator.signer.ecdsa.allowed-addresses":            struct{}{},
 	"node.staker.batch-poster.data-poster.blob-tx-replacement-times": time.Duration(0),
 	"node.staker.batch-poster.data-poster.replacement-times":         time.Duration(0),
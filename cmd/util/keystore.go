@@ -4,6 +4,7 @@
 package util
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 	"strings"
@@ -19,10 +20,14 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 
 	"github.com/offchainlabs/nitro/cmd/genericconf"
+	"github.com/offchainlabs/nitro/util/kms"
 	"github.com/offchainlabs/nitro/util/signature"
 )
 
 func OpenWallet(description string, walletConfig *genericconf.WalletConfig, chainId *big.Int) (*bind.TransactOpts, signature.DataSignerFunc, error) {
+	if walletConfig.KMS.Enabled() {
+		return openKMSWallet(description, &walletConfig.KMS, chainId)
+	}
 	if walletConfig.PrivateKey != "" {
 		privateKey, err := crypto.HexToECDSA(walletConfig.PrivateKey)
 		if err != nil {
@@ -71,6 +76,21 @@ func OpenWallet(description string, walletConfig *genericconf.WalletConfig, chai
 	return txOpts, signer, nil
 }
 
+func openKMSWallet(description string, config *kms.Config, chainId *big.Int) (*bind.TransactOpts, signature.DataSignerFunc, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+	signer, err := kms.NewSigner(ctx, config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening %s kms wallet: %w", description, err)
+	}
+	log.Info("using kms wallet", "wallet", description, "provider", config.Provider, "address", signer.Address())
+	var txOpts *bind.TransactOpts
+	if chainId != nil {
+		txOpts = signer.TransactOpts(chainId)
+	}
+	return txOpts, signer.DataSigner(), nil
+}
+
 func openKeystore(ks *keystore.KeyStore, description string, walletConfig *genericconf.WalletConfig, getPassword func() (string, error)) (*accounts.Account, error) {
 	creatingNew := len(ks.Accounts()) == 0
 	if creatingNew && !walletConfig.OnlyCreateKey {
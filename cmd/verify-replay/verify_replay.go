@@ -0,0 +1,326 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+// verify-replay builds the replay binary, computes its wasm module root, and checks it against the
+// root deployed on the parent chain and against builds made on other machines.
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/offchainlabs/nitro/cmd/genericconf"
+	"github.com/offchainlabs/nitro/cmd/util/confighelpers"
+	"github.com/offchainlabs/nitro/solgen/go/rollupgen"
+)
+
+type VerifyReplayConfig struct {
+	SourceDir          string        `koanf:"source-dir"`
+	OutputDir          string        `koanf:"output-dir"`
+	Prover             string        `koanf:"prover"`
+	WasmLibsDir        string        `koanf:"wasm-libs-dir"`
+	SkipBuild          bool          `koanf:"skip-build"`
+	MaxReplaySize      uint64        `koanf:"max-replay-size"`
+	ExpectedModuleRoot string        `koanf:"expected-module-root"`
+	ParentChainURL     string        `koanf:"parent-chain-url"`
+	RollupAddress      string        `koanf:"rollup-address"`
+	ReportFile         string        `koanf:"report-file"`
+	CompareReports     []string      `koanf:"compare-reports"`
+	Timeout            time.Duration `koanf:"timeout"`
+
+	Conf     genericconf.ConfConfig `koanf:"conf"`
+	LogLevel string                 `koanf:"log-level"`
+	LogType  string                 `koanf:"log-type"`
+}
+
+var DefaultVerifyReplayConfig = VerifyReplayConfig{
+	SourceDir:   ".",
+	OutputDir:   "target/verify-replay",
+	Prover:      "target/bin/prover",
+	WasmLibsDir: "target/machines/latest",
+	Timeout:     30 * time.Minute,
+	Conf:        genericconf.ConfConfigDefault,
+	LogLevel:    "INFO",
+	LogType:     "plaintext",
+}
+
+// the wasm libraries linked into the replay machine, as in the Makefile
+var replayWasmLibs = []string{"forward", "soft-float", "wasi_stub", "host_io", "user_host", "arbcompress", "program_exec"}
+
+// BuildReport describes one build of the replay binary, so builds from different machines can be compared.
+type BuildReport struct {
+	Host          string      `json:"host"`
+	Platform      string      `json:"platform"`
+	GoVersion     string      `json:"goVersion"`
+	ReplaySHA256  common.Hash `json:"replaySha256"`
+	ReplaySize    uint64      `json:"replaySize"`
+	ModuleRoot    common.Hash `json:"moduleRoot"`
+	Deterministic bool        `json:"deterministic"`
+}
+
+func main() {
+	if err := startup(); err != nil {
+		log.Error("replay verification failed", "err", err)
+		os.Exit(1)
+	}
+}
+
+func parseVerifyReplay(args []string) (*VerifyReplayConfig, error) {
+	f := flag.NewFlagSet("verify-replay", flag.ContinueOnError)
+	f.String("source-dir", DefaultVerifyReplayConfig.SourceDir, "root of the nitro source tree to build the replay binary from")
+	f.String("output-dir", DefaultVerifyReplayConfig.OutputDir, "directory to write the replay binary and machine to")
+	f.String("prover", DefaultVerifyReplayConfig.Prover, "path to the prover binary")
+	f.String("wasm-libs-dir", DefaultVerifyReplayConfig.WasmLibsDir, "directory containing the wasm libraries linked into the replay machine")
+	f.Bool("skip-build", DefaultVerifyReplayConfig.SkipBuild, "use the replay binary already in the output directory instead of building it")
+	f.Uint64("max-replay-size", DefaultVerifyReplayConfig.MaxReplaySize, "fail if the replay binary is larger than this many bytes (0 = no limit)")
+	f.String("expected-module-root", DefaultVerifyReplayConfig.ExpectedModuleRoot, "fail if the computed wasm module root differs from this one")
+	f.String("parent-chain-url", DefaultVerifyReplayConfig.ParentChainURL, "parent chain RPC URL used to read the deployed wasm module root")
+	f.String("rollup-address", DefaultVerifyReplayConfig.RollupAddress, "address of the rollup contract to read the deployed wasm module root from")
+	f.String("report-file", DefaultVerifyReplayConfig.ReportFile, "file to write this build's report to, for comparison on other machines")
+	f.StringSlice("compare-reports", DefaultVerifyReplayConfig.CompareReports, "build reports from other machines that must match this build")
+	f.Duration("timeout", DefaultVerifyReplayConfig.Timeout, "timeout for the whole verification")
+	f.String("log-level", DefaultVerifyReplayConfig.LogLevel, "log level, valid values are CRIT, ERROR, WARN, INFO, DEBUG, TRACE")
+	f.String("log-type", DefaultVerifyReplayConfig.LogType, "log type (plaintext or json)")
+	genericconf.ConfConfigAddOptions("conf", f)
+
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+	var config VerifyReplayConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	if (config.ParentChainURL == "") != (config.RollupAddress == "") {
+		return nil, errors.New("parent-chain-url and rollup-address must be set together")
+	}
+	if config.RollupAddress != "" && !common.IsHexAddress(config.RollupAddress) {
+		return nil, fmt.Errorf("invalid rollup address %v", config.RollupAddress)
+	}
+	if config.ExpectedModuleRoot != "" && len(common.FromHex(config.ExpectedModuleRoot)) != common.HashLength {
+		return nil, fmt.Errorf("invalid expected module root %v", config.ExpectedModuleRoot)
+	}
+	return &config, nil
+}
+
+func startup() error {
+	config, err := parseVerifyReplay(os.Args[1:])
+	if err != nil {
+		confighelpers.PrintErrorAndExit(err, func(string) {
+			fmt.Printf("\nSample usage: %s --parent-chain-url <url> --rollup-address <address>\n", os.Args[0])
+		})
+	}
+	if err := genericconf.InitLog(config.LogType, config.LogLevel, &genericconf.FileLoggingConfig{}, nil); err != nil {
+		return fmt.Errorf("error initializing logging: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+
+	report, err := buildAndMeasure(ctx, config)
+	if err != nil {
+		return err
+	}
+	reportJson, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(reportJson))
+	if config.ReportFile != "" {
+		if err := os.WriteFile(config.ReportFile, reportJson, 0o600); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+
+	var failures []string
+	if !report.Deterministic {
+		failures = append(failures, "building the replay binary twice produced different binaries")
+	}
+	if config.MaxReplaySize != 0 && report.ReplaySize > config.MaxReplaySize {
+		failures = append(failures, fmt.Sprintf("replay binary is %v bytes, more than the limit of %v", report.ReplaySize, config.MaxReplaySize))
+	}
+	if config.ExpectedModuleRoot != "" && report.ModuleRoot != common.HexToHash(config.ExpectedModuleRoot) {
+		failures = append(failures, fmt.Sprintf("module root %v doesn't match expected %v", report.ModuleRoot, config.ExpectedModuleRoot))
+	}
+	if config.ParentChainURL != "" {
+		deployed, err := deployedModuleRoot(ctx, config.ParentChainURL, common.HexToAddress(config.RollupAddress))
+		if err != nil {
+			return err
+		}
+		if deployed != report.ModuleRoot {
+			failures = append(failures, fmt.Sprintf("module root %v doesn't match the on-chain root %v", report.ModuleRoot, deployed))
+		} else {
+			log.Info("module root matches the on-chain root", "root", deployed)
+		}
+	}
+	for _, path := range config.CompareReports {
+		failures = append(failures, compareReport(report, path)...)
+	}
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "; "))
+	}
+	log.Info("replay binary verified", "moduleRoot", report.ModuleRoot)
+	return nil
+}
+
+// buildAndMeasure builds the replay binary twice to check the build is reproducible on this machine,
+// then generates the replay machine and reads its module root.
+func buildAndMeasure(ctx context.Context, config *VerifyReplayConfig) (*BuildReport, error) {
+	if err := os.MkdirAll(config.OutputDir, 0o755); err != nil {
+		return nil, err
+	}
+	replayPath, err := filepath.Abs(filepath.Join(config.OutputDir, "replay.wasm"))
+	if err != nil {
+		return nil, err
+	}
+	deterministic := true
+	if !config.SkipBuild {
+		secondPath := filepath.Join(config.OutputDir, "replay.second.wasm")
+		if err := buildReplay(ctx, config.SourceDir, replayPath); err != nil {
+			return nil, err
+		}
+		if err := buildReplay(ctx, config.SourceDir, secondPath); err != nil {
+			return nil, err
+		}
+		first, err := os.ReadFile(replayPath)
+		if err != nil {
+			return nil, err
+		}
+		second, err := os.ReadFile(secondPath)
+		if err != nil {
+			return nil, err
+		}
+		deterministic = bytes.Equal(first, second)
+		_ = os.Remove(secondPath)
+	}
+	replay, err := os.ReadFile(replayPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replay binary: %w", err)
+	}
+	moduleRoot, err := computeModuleRoot(ctx, config, replayPath)
+	if err != nil {
+		return nil, err
+	}
+	host, _ := os.Hostname()
+	return &BuildReport{
+		Host:          host,
+		Platform:      runtime.GOOS + "/" + runtime.GOARCH,
+		GoVersion:     runtime.Version(),
+		ReplaySHA256:  sha256.Sum256(replay),
+		ReplaySize:    uint64(len(replay)),
+		ModuleRoot:    moduleRoot,
+		Deterministic: deterministic,
+	}, nil
+}
+
+// releaseSourceDir is where the Dockerfile builds released replay binaries. The binary embeds its source paths,
+// so only a build from the same directory can reproduce a released module root.
+const releaseSourceDir = "/workspace"
+
+// buildReplay builds the replay binary exactly as the Makefile's $(replay_wasm) target does, as any other
+// flag (such as -trimpath) changes the binary and so its module root.
+func buildReplay(ctx context.Context, sourceDir string, output string) error {
+	if absSourceDir, err := filepath.Abs(sourceDir); err == nil && absSourceDir != releaseSourceDir {
+		log.Warn("building the replay binary outside of the release source directory, so its module root won't match a release's", "sourceDir", absSourceDir, "releaseSourceDir", releaseSourceDir)
+	}
+	cmd := exec.CommandContext(ctx, "go", "build", "-o", output, "./cmd/replay/...")
+	cmd.Dir = sourceDir
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	cmd.Stderr = os.Stderr
+	log.Info("building replay binary", "output", output)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to build replay binary: %w", err)
+	}
+	return nil
+}
+
+func computeModuleRoot(ctx context.Context, config *VerifyReplayConfig, replayPath string) (common.Hash, error) {
+	prover, err := filepath.Abs(config.Prover)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	outputDir, err := filepath.Abs(config.OutputDir)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	args := []string{replayPath, "--generate-binaries", outputDir}
+	for _, lib := range replayWasmLibs {
+		args = append(args, "-l", filepath.Join(config.WasmLibsDir, lib+".wasm"))
+	}
+	generate := exec.CommandContext(ctx, prover, args...)
+	generate.Stderr = os.Stderr
+	if err := generate.Run(); err != nil {
+		return common.Hash{}, fmt.Errorf("failed to generate replay machine: %w", err)
+	}
+	printRoot := exec.CommandContext(ctx, prover, "machine.wavm.br", "--print-wasmmoduleroot")
+	printRoot.Dir = outputDir
+	printRoot.Stderr = os.Stderr
+	out, err := printRoot.Output()
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to read module root: %w", err)
+	}
+	root := strings.TrimSpace(string(out))
+	if len(common.FromHex(root)) != common.HashLength {
+		return common.Hash{}, fmt.Errorf("prover printed an invalid module root %q", root)
+	}
+	return common.HexToHash(root), nil
+}
+
+func deployedModuleRoot(ctx context.Context, url string, rollupAddress common.Address) (common.Hash, error) {
+	client, err := ethclient.DialContext(ctx, url)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to connect to parent chain: %w", err)
+	}
+	defer client.Close()
+	rollup, err := rollupgen.NewRollupUserLogic(rollupAddress, client)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	root, err := rollup.WasmModuleRoot(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to get on-chain wasm module root: %w", err)
+	}
+	return root, nil
+}
+
+// compareReport checks a build from another machine produced the same replay binary and module root.
+func compareReport(report *BuildReport, path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []string{fmt.Sprintf("failed to read report %v: %v", path, err)}
+	}
+	var other BuildReport
+	if err := json.Unmarshal(data, &other); err != nil {
+		return []string{fmt.Sprintf("failed to parse report %v: %v", path, err)}
+	}
+	var failures []string
+	if other.ModuleRoot != report.ModuleRoot {
+		failures = append(failures, fmt.Sprintf("module root %v from %v (%v) differs from %v", other.ModuleRoot, other.Host, other.Platform, report.ModuleRoot))
+	}
+	if other.ReplaySHA256 != report.ReplaySHA256 {
+		failures = append(failures, fmt.Sprintf("replay binary from %v (%v, %v) differs from this build (%v)", other.Host, other.Platform, other.GoVersion, report.GoVersion))
+	}
+	if !other.Deterministic {
+		failures = append(failures, fmt.Sprintf("build on %v (%v) wasn't reproducible", other.Host, other.Platform))
+	}
+	if len(failures) == 0 {
+		log.Info("build matches report", "host", other.Host, "platform", other.Platform)
+	}
+	return failures
+}
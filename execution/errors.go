@@ -0,0 +1,164 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package execution
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrorCode is a stable, machine-readable reason a transaction or request was refused.
+// It's returned in the data field of JSON-RPC errors, so clients don't have to match on error messages.
+type ErrorCode string
+
+const (
+	// the sequencer's queue is full and the transaction couldn't be queued in time
+	ErrorCodeSequencerFull ErrorCode = "SEQUENCER_FULL"
+	// this node isn't the chosen sequencer and has nowhere to forward the transaction to
+	ErrorCodeCoordinatorNotChosen ErrorCode = "COORDINATOR_NOT_CHOSEN"
+	// the transaction couldn't be forwarded to any sequencer
+	ErrorCodeSequencerUnavailable ErrorCode = "SEQUENCER_UNAVAILABLE"
+	// the sequencer can't sequence because it doesn't have a recent parent chain block
+	ErrorCodeParentChainUnavailable ErrorCode = "PARENT_CHAIN_UNAVAILABLE"
+	// the transaction's max fee per gas is below the current base fee
+	ErrorCodeBelowBaseFee ErrorCode = "BELOW_BASEFEE"
+	// the conditions of a conditional transaction weren't met
+	ErrorCodeConditionalCheckFailed ErrorCode = "CONDITIONAL_CHECK_FAILED"
+	// the retryable doesn't exist anymore, either because it expired or was already redeemed
+	ErrorCodeRetryableExpired ErrorCode = "RETRYABLE_EXPIRED"
+	// the transaction's valid-until bound (the timestampMax or blockNumberMax of its conditional options)
+	// passed before it could be sequenced
+	ErrorCodeTransactionExpired ErrorCode = "TRANSACTION_EXPIRED"
+)
+
+var knownErrorCodes = map[ErrorCode]bool{
+	ErrorCodeSequencerFull:          true,
+	ErrorCodeCoordinatorNotChosen:   true,
+	ErrorCodeSequencerUnavailable:   true,
+	ErrorCodeParentChainUnavailable: true,
+	ErrorCodeBelowBaseFee:           true,
+	ErrorCodeConditionalCheckFailed: true,
+	ErrorCodeRetryableExpired:       true,
+	ErrorCodeTransactionExpired:     true,
+}
+
+// geth's JSON-RPC server uses this code for errors that don't specify one
+const defaultJsonRpcErrorCode = -32000
+
+// CodedErrorData is the data field of the JSON-RPC error a CodedError is returned as.
+type CodedErrorData struct {
+	Code ErrorCode `json:"code"`
+	// the data of the underlying error, if it had any (e.g. revert data)
+	Data interface{} `json:"data,omitempty"`
+}
+
+// A CodedError attaches an ErrorCode to an error. It keeps the message and JSON-RPC error code of the
+// error it wraps, and carries the ErrorCode in the JSON-RPC error's data field.
+type CodedError struct {
+	code ErrorCode
+	err  error
+}
+
+// NewCodedError creates an error with the given code, e.g. to use as a sentinel error.
+func NewCodedError(code ErrorCode, message string) *CodedError {
+	return &CodedError{code: code, err: errors.New(message)}
+}
+
+// WithErrorCode attaches code to err, or returns nil if err is nil.
+func WithErrorCode(code ErrorCode, err error) error {
+	if err == nil {
+		return nil
+	}
+	var coded *CodedError
+	if errors.As(err, &coded) && coded.code == code {
+		return err
+	}
+	return &CodedError{code: code, err: err}
+}
+
+func (e *CodedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *CodedError) Unwrap() error {
+	return e.err
+}
+
+func (e *CodedError) Code() ErrorCode {
+	return e.code
+}
+
+func (e *CodedError) ErrorCode() int {
+	var rpcErr interface{ ErrorCode() int }
+	if errors.As(e.err, &rpcErr) {
+		return rpcErr.ErrorCode()
+	}
+	return defaultJsonRpcErrorCode
+}
+
+func (e *CodedError) ErrorData() interface{} {
+	data := CodedErrorData{Code: e.code}
+	var dataErr interface{ ErrorData() interface{} }
+	if errors.As(e.err, &dataErr) {
+		data.Data = dataErr.ErrorData()
+	}
+	return data
+}
+
+// ErrorCodeOf returns the code attached to err, if there is one.
+func ErrorCodeOf(err error) (ErrorCode, bool) {
+	var coded *CodedError
+	if !errors.As(err, &coded) {
+		return "", false
+	}
+	return coded.code, true
+}
+
+// RestoreErrorCode re-attaches the code of an error returned by another node's JSON-RPC server,
+// so forwarded errors can be told apart the same way as local ones.
+func RestoreErrorCode(err error) error {
+	var dataErr interface{ ErrorData() interface{} }
+	if err == nil || !errors.As(err, &dataErr) {
+		return err
+	}
+	if _, ok := ErrorCodeOf(err); ok {
+		return err
+	}
+	raw, jsonErr := json.Marshal(dataErr.ErrorData())
+	if jsonErr != nil {
+		return err
+	}
+	var data CodedErrorData
+	if json.Unmarshal(raw, &data) != nil || !knownErrorCodes[data.Code] {
+		return err
+	}
+	return &CodedError{code: data.Code, err: &remoteError{err: err, data: data.Data}}
+}
+
+// remoteError keeps a forwarded error's message and JSON-RPC error code, with the code stripped from its data,
+// so it isn't nested when the CodedError wrapping it is serialized again.
+type remoteError struct {
+	err  error
+	data interface{}
+}
+
+func (e *remoteError) Error() string {
+	return e.err.Error()
+}
+
+func (e *remoteError) Unwrap() error {
+	return e.err
+}
+
+func (e *remoteError) ErrorCode() int {
+	var rpcErr interface{ ErrorCode() int }
+	if errors.As(e.err, &rpcErr) {
+		return rpcErr.ErrorCode()
+	}
+	return defaultJsonRpcErrorCode
+}
+
+func (e *remoteError) ErrorData() interface{} {
+	return e.data
+}
@@ -0,0 +1,89 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package execution
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// testRpcError mimics the errors geth's JSON-RPC client returns.
+type testRpcError struct {
+	message string
+	code    int
+	data    interface{}
+}
+
+func (e *testRpcError) Error() string          { return e.message }
+func (e *testRpcError) ErrorCode() int         { return e.code }
+func (e *testRpcError) ErrorData() interface{} { return e.data }
+
+func TestCodedErrorKeepsUnderlyingError(t *testing.T) {
+	inner := &testRpcError{message: "execution reverted", code: 3, data: "0x01"}
+	err := WithErrorCode(ErrorCodeRetryableExpired, fmt.Errorf("wrapped: %w", inner))
+	code, ok := ErrorCodeOf(err)
+	if !ok || code != ErrorCodeRetryableExpired {
+		t.Fatalf("unexpected error code %v", code)
+	}
+	var coded *CodedError
+	if !errors.As(err, &coded) {
+		t.Fatal("expected a coded error")
+	}
+	if coded.ErrorCode() != 3 || coded.Error() != "wrapped: execution reverted" {
+		t.Errorf("coded error changed the underlying error: code %v message %q", coded.ErrorCode(), coded.Error())
+	}
+	data, ok := coded.ErrorData().(CodedErrorData)
+	if !ok || data.Code != ErrorCodeRetryableExpired || data.Data != "0x01" {
+		t.Errorf("unexpected error data %+v", coded.ErrorData())
+	}
+	if !errors.Is(err, inner) {
+		t.Error("expected the coded error to unwrap to the underlying error")
+	}
+	if WithErrorCode(ErrorCodeSequencerFull, nil) != nil {
+		t.Error("expected no error to stay nil")
+	}
+
+	plain := NewCodedError(ErrorCodeSequencerFull, "queue full")
+	if plain.ErrorCode() != defaultJsonRpcErrorCode {
+		t.Errorf("unexpected JSON-RPC code %v", plain.ErrorCode())
+	}
+}
+
+func TestRestoreErrorCode(t *testing.T) {
+	local := WithErrorCode(ErrorCodeConditionalCheckFailed, &testRpcError{message: "rejected", code: -32003, data: "0x02"})
+	var coded *CodedError
+	if !errors.As(local, &coded) {
+		t.Fatal("expected a coded error")
+	}
+	// round trip the error data through JSON, the way the forwarding target's server and our client would
+	encoded, err := json.Marshal(coded.ErrorData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	remote := &testRpcError{message: "rejected", code: -32003, data: decoded}
+
+	restored := RestoreErrorCode(remote)
+	code, ok := ErrorCodeOf(restored)
+	if !ok || code != ErrorCodeConditionalCheckFailed {
+		t.Fatalf("expected the remote error code to be restored, got %v", code)
+	}
+	if !errors.As(restored, &coded) {
+		t.Fatal("expected a coded error")
+	}
+	data, ok := coded.ErrorData().(CodedErrorData)
+	if coded.ErrorCode() != -32003 || !ok || data.Code != ErrorCodeConditionalCheckFailed || data.Data != "0x02" {
+		t.Errorf("restored error doesn't match the original: code %v data %+v", coded.ErrorCode(), coded.ErrorData())
+	}
+
+	unknown := &testRpcError{message: "other", code: -32000, data: map[string]interface{}{"code": "SOMETHING_ELSE"}}
+	if RestoreErrorCode(unknown) != error(unknown) {
+		t.Error("expected an error with an unknown code to be left alone")
+	}
+}
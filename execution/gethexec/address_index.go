@@ -0,0 +1,340 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/offchainlabs/nitro/util/dbutil"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+var addressIndexBlockGauge = metrics.NewRegisteredGauge("arb/addressindex/block", nil)
+
+var (
+	addressIndexPrefix      = []byte("nitro-address-index-")
+	addressIndexProgressKey = []byte("nitro-address-index-progress")
+)
+
+type AddressIndexConfig struct {
+	Enable             bool          `koanf:"enable"`
+	PollInterval       time.Duration `koanf:"poll-interval" reload:"hot"`
+	MaxBlocksPerUpdate uint64        `koanf:"max-blocks-per-update" reload:"hot"`
+	MaxPageSize        uint64        `koanf:"max-page-size" reload:"hot"`
+}
+
+type AddressIndexConfigFetcher func() *AddressIndexConfig
+
+var DefaultAddressIndexConfig = AddressIndexConfig{
+	Enable:             false,
+	PollInterval:       time.Second,
+	MaxBlocksPerUpdate: 1000,
+	MaxPageSize:        1000,
+}
+
+func AddressIndexConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultAddressIndexConfig.Enable, "index the transactions sent from or to each address, and serve them through arb_getAddressTransactions")
+	f.Duration(prefix+".poll-interval", DefaultAddressIndexConfig.PollInterval, "how often to check for new blocks to index")
+	f.Uint64(prefix+".max-blocks-per-update", DefaultAddressIndexConfig.MaxBlocksPerUpdate, "maximum number of blocks to index before yielding")
+	f.Uint64(prefix+".max-page-size", DefaultAddressIndexConfig.MaxPageSize, "maximum number of transactions returned by a single arb_getAddressTransactions call")
+}
+
+func (c *AddressIndexConfig) Validate() error {
+	if c.Enable && (c.MaxBlocksPerUpdate == 0 || c.MaxPageSize == 0) {
+		return errors.New("address index max blocks per update and max page size must be positive")
+	}
+	return nil
+}
+
+type addressIndexProgress struct {
+	// the next block to index
+	NextBlock uint64
+	// the hash of block NextBlock-1, to detect reorgs
+	LastHash common.Hash
+}
+
+type addressIndexEntry struct {
+	TxHash    common.Hash
+	BlockHash common.Hash
+}
+
+// keys are ordered newest first, so pages can be served by iterating forwards
+func addressIndexKey(addr common.Address, blockNumber uint64, txIndex uint32) []byte {
+	key := make([]byte, 0, len(addressIndexPrefix)+common.AddressLength+12)
+	key = append(key, addressIndexPrefix...)
+	key = append(key, addr.Bytes()...)
+	key = binary.BigEndian.AppendUint64(key, ^blockNumber)
+	key = binary.BigEndian.AppendUint32(key, ^txIndex)
+	return key
+}
+
+type addressIndexChain interface {
+	CurrentBlock() *types.Header
+	GetBlockByNumber(number uint64) *types.Block
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+}
+
+// AddressIndex maintains an index of the transactions touching each address, following the chain head.
+// Besides a transaction's sender and recipient, an address is touched by a transaction that creates
+// it, and by the ArbOS transactions that pay it: retryables' beneficiaries and fee refund addresses,
+// and retries' refund addresses.
+// Entries of blocks that were reorged out are left in place, and skipped when the index is read.
+type AddressIndex struct {
+	stopwaiter.StopWaiter
+	db     ethdb.Database
+	chain  addressIndexChain
+	signer types.Signer
+	config AddressIndexConfigFetcher
+
+	mutex    sync.Mutex
+	progress addressIndexProgress
+}
+
+func NewAddressIndex(db ethdb.Database, chain addressIndexChain, chainConfig *params.ChainConfig, config AddressIndexConfigFetcher) (*AddressIndex, error) {
+	var progress addressIndexProgress
+	data, err := db.Get(addressIndexProgressKey)
+	if err == nil {
+		if err := rlp.DecodeBytes(data, &progress); err != nil {
+			return nil, fmt.Errorf("failed to decode address index progress: %w", err)
+		}
+	} else if !dbutil.IsErrNotFound(err) {
+		return nil, err
+	}
+	return &AddressIndex{
+		db:       db,
+		chain:    chain,
+		signer:   types.LatestSigner(chainConfig),
+		config:   config,
+		progress: progress,
+	}, nil
+}
+
+func (x *AddressIndex) Start(ctxIn context.Context) {
+	x.StopWaiter.Start(ctxIn, x)
+	x.CallIteratively(func(ctx context.Context) time.Duration {
+		caughtUp, err := x.update(ctx)
+		if err != nil {
+			log.Error("failed to update address index", "err", err)
+			return x.config().PollInterval
+		}
+		if caughtUp {
+			return x.config().PollInterval
+		}
+		return 0
+	})
+}
+
+func (x *AddressIndex) indexedBlocks() uint64 {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	return x.progress.NextBlock
+}
+
+// rewind moves the progress back to the last indexed block that's still canonical.
+func (x *AddressIndex) rewind(progress addressIndexProgress) (addressIndexProgress, error) {
+	for progress.NextBlock > 0 {
+		number := progress.NextBlock - 1
+		if rawdb.ReadCanonicalHash(x.db, number) == progress.LastHash {
+			break
+		}
+		header := rawdb.ReadHeader(x.db, progress.LastHash, number)
+		if header == nil {
+			return progress, fmt.Errorf("indexed block %v %v reorged out and its header is gone", number, progress.LastHash)
+		}
+		progress = addressIndexProgress{NextBlock: number, LastHash: header.ParentHash}
+	}
+	return progress, nil
+}
+
+func (x *AddressIndex) touchedAddresses(tx *types.Transaction, receipt *types.Receipt) []common.Address {
+	var addresses []common.Address
+	if sender, err := types.Sender(x.signer, tx); err == nil {
+		addresses = append(addresses, sender)
+	}
+	if to := tx.To(); to != nil {
+		addresses = append(addresses, *to)
+	}
+	if receipt != nil && receipt.ContractAddress != (common.Address{}) {
+		addresses = append(addresses, receipt.ContractAddress)
+	}
+	switch inner := tx.GetInner().(type) {
+	case *types.ArbitrumSubmitRetryableTx:
+		addresses = append(addresses, inner.Beneficiary, inner.FeeRefundAddr)
+		if inner.RetryTo != nil {
+			addresses = append(addresses, *inner.RetryTo)
+		}
+	case *types.ArbitrumRetryTx:
+		addresses = append(addresses, inner.RefundTo)
+	}
+	return addresses
+}
+
+// update indexes up to max-blocks-per-update new blocks, and returns whether it caught up with the chain head.
+func (x *AddressIndex) update(ctx context.Context) (bool, error) {
+	progress, err := x.rewind(x.progress)
+	if err != nil {
+		return false, err
+	}
+	head := x.chain.CurrentBlock().Number.Uint64()
+	batch := x.db.NewBatch()
+	for i := uint64(0); i < x.config().MaxBlocksPerUpdate && progress.NextBlock <= head; i++ {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		block := x.chain.GetBlockByNumber(progress.NextBlock)
+		if block == nil {
+			return false, fmt.Errorf("block %v not found", progress.NextBlock)
+		}
+		receipts := x.chain.GetReceiptsByHash(block.Hash())
+		for txIndex, tx := range block.Transactions() {
+			var receipt *types.Receipt
+			if txIndex < len(receipts) {
+				receipt = receipts[txIndex]
+			}
+			entry, err := rlp.EncodeToBytes(addressIndexEntry{TxHash: tx.Hash(), BlockHash: block.Hash()})
+			if err != nil {
+				return false, err
+			}
+			seen := make(map[common.Address]bool)
+			for _, addr := range x.touchedAddresses(tx, receipt) {
+				if seen[addr] {
+					continue
+				}
+				seen[addr] = true
+				if err := batch.Put(addressIndexKey(addr, block.NumberU64(), uint32(txIndex)), entry); err != nil {
+					return false, err
+				}
+			}
+		}
+		progress = addressIndexProgress{NextBlock: block.NumberU64() + 1, LastHash: block.Hash()}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := x.writeProgress(batch, progress); err != nil {
+				return false, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := x.writeProgress(batch, progress); err != nil {
+		return false, err
+	}
+	return progress.NextBlock > head, nil
+}
+
+func (x *AddressIndex) writeProgress(batch ethdb.Batch, progress addressIndexProgress) error {
+	encoded, err := rlp.EncodeToBytes(progress)
+	if err != nil {
+		return err
+	}
+	if err := batch.Put(addressIndexProgressKey, encoded); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	x.mutex.Lock()
+	x.progress = progress
+	x.mutex.Unlock()
+	addressIndexBlockGauge.Update(int64(progress.NextBlock))
+	return nil
+}
+
+type AddressTransaction struct {
+	Hash             common.Hash    `json:"hash"`
+	BlockHash        common.Hash    `json:"blockHash"`
+	BlockNumber      hexutil.Uint64 `json:"blockNumber"`
+	TransactionIndex hexutil.Uint   `json:"transactionIndex"`
+}
+
+type AddressTransactionsPage struct {
+	Transactions []AddressTransaction `json:"transactions"`
+	// pass this as the cursor to get the next page, nil if there are no more transactions
+	NextCursor *hexutil.Bytes `json:"nextCursor"`
+	// the number of blocks indexed so far; transactions in later blocks aren't included yet
+	IndexedBlocks hexutil.Uint64 `json:"indexedBlocks"`
+}
+
+// Transactions returns up to limit transactions touching addr, newest first, starting after the cursor
+// returned with the previous page.
+func (x *AddressIndex) Transactions(addr common.Address, cursor []byte, limit uint64) (*AddressTransactionsPage, error) {
+	if limit == 0 || limit > x.config().MaxPageSize {
+		limit = x.config().MaxPageSize
+	}
+	if len(cursor) != 0 && len(cursor) != 12 {
+		return nil, errors.New("invalid cursor")
+	}
+	prefix := addressIndexKey(addr, 0, 0)[:len(addressIndexPrefix)+common.AddressLength]
+	page := &AddressTransactionsPage{
+		Transactions:  []AddressTransaction{},
+		IndexedBlocks: hexutil.Uint64(x.indexedBlocks()),
+	}
+	iter := x.db.NewIterator(prefix, cursor)
+	defer iter.Release()
+	for iter.Next() {
+		suffix := iter.Key()[len(prefix):]
+		if len(suffix) != 12 || bytes.Equal(suffix, cursor) {
+			continue
+		}
+		if uint64(len(page.Transactions)) >= limit {
+			next := hexutil.Bytes(common.CopyBytes(cursor))
+			page.NextCursor = &next
+			break
+		}
+		var entry addressIndexEntry
+		if err := rlp.DecodeBytes(iter.Value(), &entry); err != nil {
+			return nil, err
+		}
+		blockNumber := ^binary.BigEndian.Uint64(suffix[:8])
+		if rawdb.ReadCanonicalHash(x.db, blockNumber) != entry.BlockHash {
+			// left behind by a reorg
+			continue
+		}
+		page.Transactions = append(page.Transactions, AddressTransaction{
+			Hash:             entry.TxHash,
+			BlockHash:        entry.BlockHash,
+			BlockNumber:      hexutil.Uint64(blockNumber),
+			TransactionIndex: hexutil.Uint(^binary.BigEndian.Uint32(suffix[8:])),
+		})
+		cursor = common.CopyBytes(suffix)
+	}
+	return page, iter.Error()
+}
+
+type AddressIndexAPI struct {
+	index *AddressIndex
+}
+
+func NewAddressIndexAPI(index *AddressIndex) *AddressIndexAPI {
+	return &AddressIndexAPI{index}
+}
+
+// GetAddressTransactions pages through the transactions touching an address, newest first.
+func (api *AddressIndexAPI) GetAddressTransactions(addr common.Address, cursor *hexutil.Bytes, limit *hexutil.Uint64) (*AddressTransactionsPage, error) {
+	var cursorBytes []byte
+	if cursor != nil {
+		cursorBytes = *cursor
+	}
+	var pageLimit uint64
+	if limit != nil {
+		pageLimit = uint64(*limit)
+	}
+	return api.index.Transactions(addr, cursorBytes, pageLimit)
+}
@@ -0,0 +1,137 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+type testIndexChain struct {
+	db     ethdb.Database
+	blocks map[common.Hash]*types.Block
+	head   *types.Block
+}
+
+func (c *testIndexChain) CurrentBlock() *types.Header {
+	return c.head.Header()
+}
+
+func (c *testIndexChain) GetBlockByNumber(number uint64) *types.Block {
+	return c.blocks[rawdb.ReadCanonicalHash(c.db, number)]
+}
+
+func (c *testIndexChain) GetReceiptsByHash(hash common.Hash) types.Receipts {
+	return nil
+}
+
+// addBlock makes a canonical block with the given transactions on top of parent.
+func (c *testIndexChain) addBlock(parent *types.Block, txs []*types.Transaction) *types.Block {
+	header := &types.Header{Number: common.Big0, Difficulty: common.Big1, Extra: []byte{byte(len(c.blocks))}}
+	if parent != nil {
+		header.Number = new(big.Int).Add(parent.Number(), common.Big1)
+		header.ParentHash = parent.Hash()
+	}
+	block := types.NewBlock(header, txs, nil, nil, trie.NewStackTrie(nil))
+	c.blocks[block.Hash()] = block
+	rawdb.WriteHeader(c.db, block.Header())
+	rawdb.WriteCanonicalHash(c.db, block.Hash(), block.NumberU64())
+	c.head = block
+	return block
+}
+
+func TestAddressIndexTransactions(t *testing.T) {
+	ctx := context.Background()
+	chainConfig := params.TestChainConfig
+	signer := types.LatestSigner(chainConfig)
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	recipient := common.HexToAddress("0x1234")
+	other := common.HexToAddress("0x5678")
+	nonce := uint64(0)
+	transfer := func(to common.Address) *types.Transaction {
+		tx, err := types.SignNewTx(key, signer, &types.DynamicFeeTx{ChainID: chainConfig.ChainID, Nonce: nonce, To: &to, Gas: 21000, GasFeeCap: common.Big1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonce++
+		return tx
+	}
+
+	db := rawdb.NewMemoryDatabase()
+	chain := &testIndexChain{db: db, blocks: make(map[common.Hash]*types.Block)}
+	genesis := chain.addBlock(nil, nil)
+	block1 := chain.addBlock(genesis, []*types.Transaction{transfer(recipient), transfer(other)})
+	chain.addBlock(block1, []*types.Transaction{transfer(recipient)})
+
+	config := DefaultAddressIndexConfig
+	config.Enable = true
+	config.MaxPageSize = 2
+	index, err := NewAddressIndex(db, chain, chainConfig, func() *AddressIndexConfig { return &config })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := index.update(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := index.Transactions(sender, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Transactions) != 2 || page.NextCursor == nil || page.IndexedBlocks != 3 {
+		t.Fatalf("unexpected first page %+v", page)
+	}
+	if page.Transactions[0].BlockNumber != 2 || page.Transactions[1].BlockNumber != 1 || page.Transactions[1].TransactionIndex != 1 {
+		t.Errorf("expected transactions newest first, got %+v", page.Transactions)
+	}
+	page, err = index.Transactions(sender, *page.NextCursor, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Transactions) != 1 || page.NextCursor != nil || page.Transactions[0].TransactionIndex != 0 {
+		t.Fatalf("unexpected last page %+v", page)
+	}
+	page, err = index.Transactions(recipient, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Transactions) != 2 {
+		t.Errorf("expected 2 transactions to the recipient, got %+v", page.Transactions)
+	}
+
+	// reorg out block 2, replacing it with a block sending to another address
+	reorged := chain.addBlock(block1, []*types.Transaction{transfer(other)})
+	if _, err := index.update(ctx); err != nil {
+		t.Fatal(err)
+	}
+	limit := hexutil.Uint64(10)
+	page, err = NewAddressIndexAPI(index).GetAddressTransactions(recipient, nil, &limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Transactions) != 1 || page.Transactions[0].BlockNumber != 1 {
+		t.Errorf("expected the reorged out transaction to be skipped, got %+v", page.Transactions)
+	}
+	page, err = index.Transactions(other, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Transactions) != 2 || page.Transactions[0].BlockHash != reorged.Hash() {
+		t.Errorf("expected the reorged in transaction to be indexed, got %+v", page.Transactions)
+	}
+}
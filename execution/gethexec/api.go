@@ -15,10 +15,12 @@ import (
 
 	"github.com/ethereum/go-ethereum/arbitrum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/offchainlabs/nitro/arbos/arbosState"
+	"github.com/offchainlabs/nitro/arbos/l1pricing"
 	"github.com/offchainlabs/nitro/arbos/retryables"
 	"github.com/offchainlabs/nitro/util/arbmath"
 )
@@ -35,6 +37,23 @@ func (a *ArbAPI) CheckPublisherHealth(ctx context.Context) error {
 	return a.txPublisher.CheckHealth(ctx)
 }
 
+type RetentionAPI struct {
+	retention *RetentionManager
+}
+
+func NewRetentionAPI(retention *RetentionManager) *RetentionAPI {
+	return &RetentionAPI{retention}
+}
+
+func (api *RetentionAPI) RetentionStatus() (*RetentionStatus, error) {
+	return api.retention.Status()
+}
+
+// ArchivedBlock serves deep-history blocks, including ones pruned from the local freezer.
+func (api *RetentionAPI) ArchivedBlock(ctx context.Context, number hexutil.Uint64) (*ArchivedBlock, error) {
+	return api.retention.ArchivedBlock(ctx, uint64(number))
+}
+
 type ArbDebugAPI struct {
 	blockchain        *core.BlockChain
 	blockRangeBound   uint64
@@ -177,6 +196,60 @@ func (api *ArbDebugAPI) PricingModel(ctx context.Context, start, end rpc.BlockNu
 	return history, nil
 }
 
+// L1PricingEquilibration is how far the L1 fees collected are from what's owed to batch posters and as rewards.
+type L1PricingEquilibration struct {
+	BlockNumber        uint64   `json:"blockNumber"`
+	L1FeesAvailable    *big.Int `json:"l1FeesAvailable"`
+	FundsDue           *big.Int `json:"fundsDue"`
+	FundsDueForRewards *big.Int `json:"fundsDueForRewards"`
+	Surplus            *big.Int `json:"surplus"`
+	LastSurplus        *big.Int `json:"lastSurplus"`
+	PoolBalance        *big.Int `json:"poolBalance"`
+	PricePerUnit       *big.Int `json:"pricePerUnit"`
+	EquilibrationUnits *big.Int `json:"equilibrationUnits"`
+}
+
+func (api *ArbDebugAPI) L1PricingEquilibration(ctx context.Context, blockNum rpc.BlockNumber) (L1PricingEquilibration, error) {
+	blockNum, _ = api.blockchain.ClipToPostNitroGenesis(blockNum)
+	header := api.blockchain.GetHeaderByNumber(uint64(blockNum))
+	if header == nil {
+		return L1PricingEquilibration{}, fmt.Errorf("block %v not found", blockNum)
+	}
+	statedb, err := api.blockchain.StateAt(header.Root)
+	if err != nil {
+		return L1PricingEquilibration{}, err
+	}
+	state, err := arbosState.OpenSystemArbosState(statedb, nil, true)
+	if err != nil {
+		return L1PricingEquilibration{}, err
+	}
+	l1Pricing := state.L1PricingState()
+	result := L1PricingEquilibration{
+		BlockNumber: header.Number.Uint64(),
+		PoolBalance: statedb.GetBalance(l1pricing.L1PricerFundsPoolAddress).ToBig(),
+	}
+	if result.L1FeesAvailable, err = l1Pricing.L1FeesAvailable(); err != nil {
+		return result, err
+	}
+	if result.FundsDue, err = l1Pricing.BatchPosterTable().TotalFundsDue(); err != nil {
+		return result, err
+	}
+	if result.FundsDueForRewards, err = l1Pricing.FundsDueForRewards(); err != nil {
+		return result, err
+	}
+	if result.Surplus, err = l1Pricing.GetL1PricingSurplus(); err != nil {
+		return result, err
+	}
+	if result.LastSurplus, err = l1Pricing.LastSurplus(); err != nil {
+		return result, err
+	}
+	if result.PricePerUnit, err = l1Pricing.PricePerUnit(); err != nil {
+		return result, err
+	}
+	result.EquilibrationUnits, err = l1Pricing.EquilibrationUnits()
+	return result, err
+}
+
 type TimeoutQueueHistory struct {
 	Start uint64   `json:"start"`
 	End   uint64   `json:"end"`
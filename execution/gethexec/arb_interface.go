@@ -21,9 +21,10 @@ type TransactionPublisher interface {
 }
 
 type ArbInterface struct {
-	blockchain  *core.BlockChain
-	node        *ExecutionNode
-	txPublisher TransactionPublisher
+	blockchain    *core.BlockChain
+	node          *ExecutionNode
+	txPublisher   TransactionPublisher
+	txPoolTracker *TxPoolTracker
 }
 
 func NewArbInterface(blockchain *core.BlockChain, txPublisher TransactionPublisher) (*ArbInterface, error) {
@@ -37,7 +38,16 @@ func (a *ArbInterface) Initialize(node *ExecutionNode) {
 	a.node = node
 }
 
+// SetTxPoolTracker makes the transactions published through this interface visible in the txpool namespace.
+// It must be called before the interface is used.
+func (a *ArbInterface) SetTxPoolTracker(tracker *TxPoolTracker) {
+	a.txPoolTracker = tracker
+}
+
 func (a *ArbInterface) PublishTransaction(ctx context.Context, tx *types.Transaction, options *arbitrum_types.ConditionalOptions) error {
+	if a.txPoolTracker != nil {
+		defer a.txPoolTracker.track(tx)()
+	}
 	return a.txPublisher.PublishTransaction(ctx, tx, options)
 }
 
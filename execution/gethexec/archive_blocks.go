@@ -0,0 +1,131 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ArchiveBlockAPI is registered in the eth namespace after geth's own APIs, so blocks pruned from the local
+// freezer are still served by eth_getBlockByNumber and eth_getBlockByHash, read from the archive store.
+type ArchiveBlockAPI struct {
+	retention *RetentionManager
+	config    *params.ChainConfig
+	// calls geth's own eth API
+	geth *rpc.Client
+}
+
+func NewArchiveBlockAPI(retention *RetentionManager, config *params.ChainConfig, geth *rpc.Client) *ArchiveBlockAPI {
+	return &ArchiveBlockAPI{retention, config, geth}
+}
+
+func (api *ArchiveBlockAPI) GetBlockByNumber(ctx context.Context, number rpc.BlockNumber, fullTx bool) (json.RawMessage, error) {
+	var result json.RawMessage
+	if err := api.geth.CallContext(ctx, &result, "eth_getBlockByNumber", number, fullTx); err != nil {
+		return nil, err
+	}
+	if string(result) != "null" || number < 0 {
+		return result, nil
+	}
+	pruned, err := api.retention.Pruned(uint64(number))
+	if err != nil || !pruned {
+		return result, err
+	}
+	return api.archivedBlock(ctx, uint64(number), fullTx)
+}
+
+func (api *ArchiveBlockAPI) GetBlockByHash(ctx context.Context, hash common.Hash, fullTx bool) (json.RawMessage, error) {
+	var result json.RawMessage
+	if err := api.geth.CallContext(ctx, &result, "eth_getBlockByHash", hash, fullTx); err != nil {
+		return nil, err
+	}
+	if string(result) != "null" {
+		return result, nil
+	}
+	number, pruned, err := api.retention.PrunedBlockNumber(hash)
+	if err != nil || !pruned {
+		return result, err
+	}
+	return api.archivedBlock(ctx, number, fullTx)
+}
+
+func (api *ArchiveBlockAPI) archivedBlock(ctx context.Context, number uint64, fullTx bool) (json.RawMessage, error) {
+	archived, err := api.retention.ArchivedBlock(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	var body types.Body
+	if err := rlp.DecodeBytes(archived.Body, &body); err != nil {
+		return nil, fmt.Errorf("failed to decode body of block %v: %w", number, err)
+	}
+	fields, err := marshalArchivedBlock(api.config, archived.Header, &body, fullTx)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// marshalArchivedBlock formats a block with the same fields as geth's eth_getBlockByNumber, which can't
+// serve it once it's been pruned. The header and transactions use their own JSON encodings, so only the
+// fields geth's API adds to them are filled in here.
+func marshalArchivedBlock(config *params.ChainConfig, header *types.Header, body *types.Body, fullTx bool) (map[string]interface{}, error) {
+	encodedHeader, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(encodedHeader, &fields); err != nil {
+		return nil, err
+	}
+	if config.IsArbitrumNitro(header.Number) {
+		info := types.DeserializeHeaderExtraInformation(header)
+		fields["l1BlockNumber"] = hexutil.Uint64(info.L1BlockNumber)
+		fields["sendCount"] = hexutil.Uint64(info.SendCount)
+		fields["sendRoot"] = info.SendRoot
+	}
+	block := types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Uncles)
+	fields["size"] = hexutil.Uint64(block.Size())
+
+	signer := types.MakeSigner(config, header.Number, header.Time)
+	transactions := make([]interface{}, 0, len(body.Transactions))
+	for i, tx := range body.Transactions {
+		if !fullTx {
+			transactions = append(transactions, tx.Hash())
+			continue
+		}
+		encodedTx, err := json.Marshal(tx)
+		if err != nil {
+			return nil, err
+		}
+		var txFields map[string]interface{}
+		if err := json.Unmarshal(encodedTx, &txFields); err != nil {
+			return nil, err
+		}
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return nil, err
+		}
+		txFields["from"] = from
+		txFields["blockHash"] = header.Hash()
+		txFields["blockNumber"] = (*hexutil.Big)(header.Number)
+		txFields["transactionIndex"] = hexutil.Uint64(i)
+		transactions = append(transactions, txFields)
+	}
+	fields["transactions"] = transactions
+	uncles := make([]common.Hash, 0, len(body.Uncles))
+	for _, uncle := range body.Uncles {
+		uncles = append(uncles, uncle.Hash())
+	}
+	fields["uncles"] = uncles
+	return fields, nil
+}
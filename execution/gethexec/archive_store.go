@@ -0,0 +1,159 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	flag "github.com/spf13/pflag"
+)
+
+// An ArchiveStore holds chain history segments that have been moved out of the local database.
+type ArchiveStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	String() string
+}
+
+type ArchiveStoreConfig struct {
+	Directory string               `koanf:"directory"`
+	S3        S3ArchiveStoreConfig `koanf:"s3"`
+}
+
+var DefaultArchiveStoreConfig = ArchiveStoreConfig{}
+
+func ArchiveStoreConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.String(prefix+".directory", DefaultArchiveStoreConfig.Directory, "directory to upload archived segments to")
+	S3ArchiveStoreConfigAddOptions(prefix+".s3", f)
+}
+
+func (c *ArchiveStoreConfig) Validate() error {
+	if c.Directory != "" && c.S3.Enable {
+		return errors.New("only one of directory and s3 can be used as the archive store")
+	}
+	if c.Directory == "" && !c.S3.Enable {
+		return errors.New("an archive store directory or s3 bucket must be configured")
+	}
+	if c.S3.Enable && c.S3.Bucket == "" {
+		return errors.New("archive store s3 bucket must be set")
+	}
+	return nil
+}
+
+func NewArchiveStore(config *ArchiveStoreConfig) (ArchiveStore, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.S3.Enable {
+		return NewS3ArchiveStore(&config.S3)
+	}
+	return NewDirectoryArchiveStore(config.Directory)
+}
+
+type DirectoryArchiveStore struct {
+	dir string
+}
+
+func NewDirectoryArchiveStore(dir string) (*DirectoryArchiveStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DirectoryArchiveStore{dir: dir}, nil
+}
+
+func (s *DirectoryArchiveStore) Put(ctx context.Context, key string, data []byte) error {
+	path := filepath.Join(s.dir, key)
+	// write to a temporary file first so a crash never leaves a partial segment behind
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *DirectoryArchiveStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(s.dir, key))
+}
+
+func (s *DirectoryArchiveStore) String() string {
+	return fmt.Sprintf("DirectoryArchiveStore(%v)", s.dir)
+}
+
+type S3ArchiveStoreConfig struct {
+	Enable       bool   `koanf:"enable"`
+	AccessKey    string `koanf:"access-key"`
+	Bucket       string `koanf:"bucket"`
+	ObjectPrefix string `koanf:"object-prefix"`
+	Region       string `koanf:"region"`
+	SecretKey    string `koanf:"secret-key"`
+}
+
+var DefaultS3ArchiveStoreConfig = S3ArchiveStoreConfig{}
+
+func S3ArchiveStoreConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultS3ArchiveStoreConfig.Enable, "upload archived segments to an AWS S3 bucket")
+	f.String(prefix+".access-key", DefaultS3ArchiveStoreConfig.AccessKey, "S3 access key")
+	f.String(prefix+".bucket", DefaultS3ArchiveStoreConfig.Bucket, "S3 bucket")
+	f.String(prefix+".object-prefix", DefaultS3ArchiveStoreConfig.ObjectPrefix, "prefix to add to S3 objects")
+	f.String(prefix+".region", DefaultS3ArchiveStoreConfig.Region, "S3 region")
+	f.String(prefix+".secret-key", DefaultS3ArchiveStoreConfig.SecretKey, "S3 secret key")
+}
+
+type S3ArchiveStore struct {
+	bucket       string
+	objectPrefix string
+	uploader     *manager.Uploader
+	downloader   *manager.Downloader
+}
+
+func NewS3ArchiveStore(config *S3ArchiveStoreConfig) (*S3ArchiveStore, error) {
+	cfg, err := awsConfig.LoadDefaultConfig(context.TODO(), awsConfig.WithRegion(config.Region), func(options *awsConfig.LoadOptions) error {
+		if config.AccessKey != "" && config.SecretKey != "" {
+			options.Credentials = credentials.NewStaticCredentialsProvider(config.AccessKey, config.SecretKey, "")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	client := s3.NewFromConfig(cfg)
+	return &S3ArchiveStore{
+		bucket:       config.Bucket,
+		objectPrefix: config.ObjectPrefix,
+		uploader:     manager.NewUploader(client),
+		downloader:   manager.NewDownloader(client),
+	}, nil
+}
+
+func (s *S3ArchiveStore) Put(ctx context.Context, key string, data []byte) error {
+	_, err := s.uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectPrefix + key),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+func (s *S3ArchiveStore) Get(ctx context.Context, key string) ([]byte, error) {
+	buf := manager.NewWriteAtBuffer([]byte{})
+	_, err := s.downloader.Download(ctx, buf, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectPrefix + key),
+	})
+	return buf.Bytes(), err
+}
+
+func (s *S3ArchiveStore) String() string {
+	return fmt.Sprintf("S3ArchiveStore(%v/%v)", s.bucket, s.objectPrefix)
+}
@@ -38,8 +38,10 @@ type CachingConfig struct {
 	MaxNumberOfBlocksToSkipStateSaving uint32        `koanf:"max-number-of-blocks-to-skip-state-saving"`
 	MaxAmountOfGasToSkipStateSaving    uint64        `koanf:"max-amount-of-gas-to-skip-state-saving"`
 	StylusLRUCache                     uint32        `koanf:"stylus-lru-cache"`
+	MessageResultCache                 int           `koanf:"message-result-cache"`
 	StateScheme                        string        `koanf:"state-scheme"`
 	StateHistory                       uint64        `koanf:"state-history"`
+	Preimages                          bool          `koanf:"preimages"`
 }
 
 func CachingConfigAddOptions(prefix string, f *flag.FlagSet) {
@@ -55,8 +57,10 @@ func CachingConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Uint32(prefix+".max-number-of-blocks-to-skip-state-saving", DefaultCachingConfig.MaxNumberOfBlocksToSkipStateSaving, "maximum number of blocks to skip state saving to persistent storage (archive node only) -- warning: this option seems to cause issues")
 	f.Uint64(prefix+".max-amount-of-gas-to-skip-state-saving", DefaultCachingConfig.MaxAmountOfGasToSkipStateSaving, "maximum amount of gas in blocks to skip saving state to Persistent storage (archive node only) -- warning: this option seems to cause issues")
 	f.Uint32(prefix+".stylus-lru-cache", DefaultCachingConfig.StylusLRUCache, "initialized stylus programs to keep in LRU cache")
+	f.Int(prefix+".message-result-cache", DefaultCachingConfig.MessageResultCache, "number of recent message results (block hash and send root) to keep in memory (0 = disabled)")
 	f.String(prefix+".state-scheme", DefaultCachingConfig.StateScheme, "scheme to use for state trie storage (hash, path)")
 	f.Uint64(prefix+".state-history", DefaultCachingConfig.StateHistory, "number of recent blocks to retain state history for (path state-scheme only)")
+	f.Bool(prefix+".preimages", DefaultCachingConfig.Preimages, "record the preimages of trie keys (always enabled for archive nodes)")
 }
 
 func getStateHistory(maxBlockSpeed time.Duration) uint64 {
@@ -76,8 +80,10 @@ var DefaultCachingConfig = CachingConfig{
 	MaxNumberOfBlocksToSkipStateSaving: 0,
 	MaxAmountOfGasToSkipStateSaving:    0,
 	StylusLRUCache:                     256,
+	MessageResultCache:                 1024,
 	StateScheme:                        rawdb.HashScheme,
 	StateHistory:                       getStateHistory(DefaultSequencerConfig.MaxBlockSpeed),
+	Preimages:                          false,
 }
 
 // TODO remove stack from parameters as it is no longer needed here
@@ -96,7 +102,7 @@ func DefaultCacheConfigFor(stack *node.Node, cachingConfig *CachingConfig) *core
 		TriesInMemory:                      cachingConfig.BlockCount,
 		TrieRetention:                      cachingConfig.BlockAge,
 		SnapshotLimit:                      cachingConfig.SnapshotCache,
-		Preimages:                          baseConf.Preimages,
+		Preimages:                          baseConf.Preimages || cachingConfig.Preimages,
 		SnapshotRestoreMaxGas:              cachingConfig.SnapshotRestoreGasLimit,
 		MaxNumberOfBlocksToSkipStateSaving: cachingConfig.MaxNumberOfBlocksToSkipStateSaving,
 		MaxAmountOfGasToSkipStateSaving:    cachingConfig.MaxAmountOfGasToSkipStateSaving,
@@ -119,7 +125,25 @@ func (c *CachingConfig) validateStateScheme() error {
 }
 
 func (c *CachingConfig) Validate() error {
-	return c.validateStateScheme()
+	if err := c.validateStateScheme(); err != nil {
+		return err
+	}
+	if c.TrieDirtyCache < 0 || c.TrieCleanCache < 0 || c.SnapshotCache < 0 || c.DatabaseCache < 0 || c.MessageResultCache < 0 {
+		return errors.New("caching sizes cannot be negative")
+	}
+	if c.TrieTimeLimit < 0 || c.BlockAge < 0 {
+		return errors.New("trie-time-limit and block-age cannot be negative")
+	}
+	if !c.Archive {
+		// geth keeps the state of at least one recent block in memory when not every block's state is written
+		if c.BlockCount == 0 {
+			return errors.New("block-count must be positive unless archive is set")
+		}
+		if c.MaxNumberOfBlocksToSkipStateSaving != 0 || c.MaxAmountOfGasToSkipStateSaving != 0 {
+			return errors.New("max-number-of-blocks-to-skip-state-saving and max-amount-of-gas-to-skip-state-saving can only be set when archive is set")
+		}
+	}
+	return nil
 }
 
 func WriteOrTestGenblock(chainDb ethdb.Database, cacheConfig *core.CacheConfig, initData statetransfer.InitDataReader, chainConfig *params.ChainConfig, initMessage *arbostypes.ParsedInitMessage, accountsPerSync uint) error {
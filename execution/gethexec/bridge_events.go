@@ -0,0 +1,393 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/offchainlabs/nitro/arbos/arbosState"
+	"github.com/offchainlabs/nitro/arbos/retryables"
+	"github.com/offchainlabs/nitro/solgen/go/precompilesgen"
+	"github.com/offchainlabs/nitro/util/dbutil"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+	"github.com/offchainlabs/nitro/util/webhook"
+)
+
+// Bridge events emitted from the L2 chain.
+const (
+	BridgeEventDepositSequenced    = "deposit.sequenced"
+	BridgeEventRetryableCreated    = "retryable.created"
+	BridgeEventRetryableRedeemed   = "retryable.redeemed"
+	BridgeEventRetryableExpired    = "retryable.expired"
+	BridgeEventWithdrawalInitiated = "withdrawal.initiated"
+)
+
+var (
+	bridgeEventsProgressKey = []byte("nitro-bridge-events-progress")
+	// pending retryables, mapped to their last known timeout
+	bridgeEventsRetryablePrefix = []byte("nitro-bridge-events-retryable-")
+)
+
+var arbSysL2ToL1TxID = func() common.Hash {
+	parsed, err := precompilesgen.ArbSysMetaData.GetAbi()
+	if err != nil {
+		panic(err)
+	}
+	return parsed.Events["L2ToL1Tx"].ID
+}()
+
+type BridgeEventsConfig struct {
+	Enable             bool           `koanf:"enable"`
+	PollInterval       time.Duration  `koanf:"poll-interval" reload:"hot"`
+	MaxBlocksPerUpdate uint64         `koanf:"max-blocks-per-update" reload:"hot"`
+	Webhook            webhook.Config `koanf:"webhook" reload:"hot"`
+}
+
+type BridgeEventsConfigFetcher func() *BridgeEventsConfig
+
+var DefaultBridgeEventsConfig = BridgeEventsConfig{
+	Enable:             false,
+	PollInterval:       time.Second,
+	MaxBlocksPerUpdate: 1000,
+	Webhook:            webhook.DefaultConfig,
+}
+
+func BridgeEventsConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultBridgeEventsConfig.Enable, "send webhook notifications of deposits being sequenced, retryables being created, redeemed and expiring, and withdrawals being initiated")
+	f.Duration(prefix+".poll-interval", DefaultBridgeEventsConfig.PollInterval, "how often to check for new blocks")
+	f.Uint64(prefix+".max-blocks-per-update", DefaultBridgeEventsConfig.MaxBlocksPerUpdate, "maximum number of blocks to process before yielding")
+	webhook.ConfigAddOptions(prefix+".webhook", f)
+}
+
+func (c *BridgeEventsConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.MaxBlocksPerUpdate == 0 {
+		return errors.New("bridge events max blocks per update must be positive")
+	}
+	return c.Webhook.Validate()
+}
+
+type DepositSequencedEvent struct {
+	RequestId   common.Hash     `json:"requestId"`
+	TxHash      common.Hash     `json:"txHash"`
+	From        common.Address  `json:"from"`
+	To          *common.Address `json:"to"`
+	Value       *hexutil.Big    `json:"value"`
+	BlockNumber hexutil.Uint64  `json:"blockNumber"`
+	BlockHash   common.Hash     `json:"blockHash"`
+}
+
+type RetryableCreatedEvent struct {
+	TicketId    common.Hash     `json:"ticketId"`
+	RequestId   common.Hash     `json:"requestId"`
+	From        common.Address  `json:"from"`
+	To          *common.Address `json:"to"`
+	CallValue   *hexutil.Big    `json:"callValue"`
+	Beneficiary common.Address  `json:"beneficiary"`
+	Timeout     hexutil.Uint64  `json:"timeout"`
+	BlockNumber hexutil.Uint64  `json:"blockNumber"`
+	BlockHash   common.Hash     `json:"blockHash"`
+}
+
+type RetryableRedeemedEvent struct {
+	TicketId common.Hash    `json:"ticketId"`
+	RetryTx  common.Hash    `json:"retryTxHash"`
+	Attempt  hexutil.Uint64 `json:"attempt"`
+	// whether this is the auto-redeem scheduled when the retryable was created
+	Auto        bool           `json:"auto"`
+	Success     bool           `json:"success"`
+	GasUsed     hexutil.Uint64 `json:"gasUsed"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+}
+
+type RetryableExpiredEvent struct {
+	TicketId common.Hash    `json:"ticketId"`
+	Timeout  hexutil.Uint64 `json:"timeout"`
+}
+
+type WithdrawalInitiatedEvent struct {
+	Position    *hexutil.Big   `json:"position"`
+	Hash        *hexutil.Big   `json:"hash"`
+	Caller      common.Address `json:"caller"`
+	Destination common.Address `json:"destination"`
+	CallValue   *hexutil.Big   `json:"callValue"`
+	Data        hexutil.Bytes  `json:"data"`
+	L1Block     *hexutil.Big   `json:"l1BlockNumber"`
+	TxHash      common.Hash    `json:"txHash"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+}
+
+// BridgeEventWatcher follows the chain head, and sends webhook notifications of the L2 side of bridge
+// messages' lifecycle. Retryables that weren't redeemed are tracked until they expire.
+// Notifications of blocks that are later reorged out aren't retracted.
+type BridgeEventWatcher struct {
+	stopwaiter.StopWaiter
+	db         ethdb.Database
+	blockchain *core.BlockChain
+	config     BridgeEventsConfigFetcher
+	notifier   *webhook.Notifier
+	arbSys     *precompilesgen.ArbSysFilterer
+
+	nextBlock uint64
+	// pending retryables, mapped to their last known timeout
+	pending map[common.Hash]uint64
+}
+
+func NewBridgeEventWatcher(db ethdb.Database, blockchain *core.BlockChain, config BridgeEventsConfigFetcher) (*BridgeEventWatcher, error) {
+	arbSys, err := precompilesgen.NewArbSysFilterer(types.ArbSysAddress, nil)
+	if err != nil {
+		return nil, err
+	}
+	w := &BridgeEventWatcher{
+		db:         db,
+		blockchain: blockchain,
+		config:     config,
+		notifier:   webhook.NewNotifier(func() *webhook.Config { return &config().Webhook }),
+		arbSys:     arbSys,
+		pending:    make(map[common.Hash]uint64),
+	}
+	data, err := db.Get(bridgeEventsProgressKey)
+	if err == nil && len(data) == 8 {
+		w.nextBlock = binary.BigEndian.Uint64(data)
+	} else if err != nil && !dbutil.IsErrNotFound(err) {
+		return nil, err
+	} else {
+		// start from the current head rather than notifying the whole history
+		w.nextBlock = blockchain.CurrentBlock().Number.Uint64() + 1
+	}
+	iter := db.NewIterator(bridgeEventsRetryablePrefix, nil)
+	defer iter.Release()
+	for iter.Next() {
+		if len(iter.Key()) != len(bridgeEventsRetryablePrefix)+common.HashLength || len(iter.Value()) != 8 {
+			continue
+		}
+		w.pending[common.BytesToHash(iter.Key()[len(bridgeEventsRetryablePrefix):])] = binary.BigEndian.Uint64(iter.Value())
+	}
+	return w, iter.Error()
+}
+
+func (w *BridgeEventWatcher) Start(ctxIn context.Context) {
+	w.StopWaiter.Start(ctxIn, w)
+	w.notifier.Start(w.GetContext())
+	w.CallIteratively(func(ctx context.Context) time.Duration {
+		caughtUp, err := w.update(ctx)
+		if err != nil {
+			log.Error("failed to update bridge events", "err", err)
+			return w.config().PollInterval
+		}
+		if caughtUp {
+			return w.config().PollInterval
+		}
+		return 0
+	})
+}
+
+func (w *BridgeEventWatcher) StopAndWait() {
+	w.StopWaiter.StopAndWait()
+	w.notifier.StopAndWait()
+}
+
+func bridgeEventsRetryableKey(ticketId common.Hash) []byte {
+	return append(common.CopyBytes(bridgeEventsRetryablePrefix), ticketId.Bytes()...)
+}
+
+func (w *BridgeEventWatcher) trackRetryable(batch ethdb.Batch, ticketId common.Hash, timeout uint64) error {
+	w.pending[ticketId] = timeout
+	return batch.Put(bridgeEventsRetryableKey(ticketId), binary.BigEndian.AppendUint64(nil, timeout))
+}
+
+func (w *BridgeEventWatcher) untrackRetryable(batch ethdb.Batch, ticketId common.Hash) error {
+	delete(w.pending, ticketId)
+	return batch.Delete(bridgeEventsRetryableKey(ticketId))
+}
+
+func (w *BridgeEventWatcher) processBlock(batch ethdb.Batch, block *types.Block) error {
+	receipts := w.blockchain.GetReceiptsByHash(block.Hash())
+	blockNumber := hexutil.Uint64(block.NumberU64())
+	for txIndex, tx := range block.Transactions() {
+		if txIndex >= len(receipts) {
+			return fmt.Errorf("missing receipt of transaction %v in block %v", txIndex, block.NumberU64())
+		}
+		receipt := receipts[txIndex]
+		success := receipt.Status == types.ReceiptStatusSuccessful
+		switch inner := tx.GetInner().(type) {
+		case *types.ArbitrumDepositTx:
+			w.notifier.Notify(BridgeEventDepositSequenced, &DepositSequencedEvent{
+				RequestId:   inner.L1RequestId,
+				TxHash:      tx.Hash(),
+				From:        inner.From,
+				To:          &inner.To,
+				Value:       (*hexutil.Big)(inner.Value),
+				BlockNumber: blockNumber,
+				BlockHash:   block.Hash(),
+			})
+		case *types.ArbitrumSubmitRetryableTx:
+			w.notifier.Notify(BridgeEventDepositSequenced, &DepositSequencedEvent{
+				RequestId:   inner.RequestId,
+				TxHash:      tx.Hash(),
+				From:        inner.From,
+				To:          inner.RetryTo,
+				Value:       (*hexutil.Big)(inner.DepositValue),
+				BlockNumber: blockNumber,
+				BlockHash:   block.Hash(),
+			})
+			if !success {
+				// the submission couldn't be paid for, so no retryable was created
+				continue
+			}
+			timeout := block.Time() + retryables.RetryableLifetimeSeconds
+			if err := w.trackRetryable(batch, tx.Hash(), timeout); err != nil {
+				return err
+			}
+			w.notifier.Notify(BridgeEventRetryableCreated, &RetryableCreatedEvent{
+				TicketId:    tx.Hash(),
+				RequestId:   inner.RequestId,
+				From:        inner.From,
+				To:          inner.RetryTo,
+				CallValue:   (*hexutil.Big)(inner.RetryValue),
+				Beneficiary: inner.Beneficiary,
+				Timeout:     hexutil.Uint64(timeout),
+				BlockNumber: blockNumber,
+				BlockHash:   block.Hash(),
+			})
+		case *types.ArbitrumRetryTx:
+			if success {
+				// a successful redeem deletes the retryable
+				if err := w.untrackRetryable(batch, inner.TicketId); err != nil {
+					return err
+				}
+			}
+			w.notifier.Notify(BridgeEventRetryableRedeemed, &RetryableRedeemedEvent{
+				TicketId:    inner.TicketId,
+				RetryTx:     tx.Hash(),
+				Attempt:     hexutil.Uint64(inner.Nonce),
+				Auto:        inner.Nonce == 0,
+				Success:     success,
+				GasUsed:     hexutil.Uint64(receipt.GasUsed),
+				BlockNumber: blockNumber,
+				BlockHash:   block.Hash(),
+			})
+		}
+		for _, l := range receipt.Logs {
+			if l.Address != types.ArbSysAddress || len(l.Topics) == 0 || l.Topics[0] != arbSysL2ToL1TxID {
+				continue
+			}
+			withdrawal, err := w.arbSys.ParseL2ToL1Tx(*l)
+			if err != nil {
+				log.Warn("failed to parse L2ToL1Tx event", "tx", tx.Hash(), "err", err)
+				continue
+			}
+			w.notifier.Notify(BridgeEventWithdrawalInitiated, &WithdrawalInitiatedEvent{
+				Position:    (*hexutil.Big)(withdrawal.Position),
+				Hash:        (*hexutil.Big)(withdrawal.Hash),
+				Caller:      withdrawal.Caller,
+				Destination: withdrawal.Destination,
+				CallValue:   (*hexutil.Big)(withdrawal.Callvalue),
+				Data:        withdrawal.Data,
+				L1Block:     (*hexutil.Big)(withdrawal.EthBlockNum),
+				TxHash:      tx.Hash(),
+				BlockNumber: blockNumber,
+				BlockHash:   block.Hash(),
+			})
+		}
+	}
+	return nil
+}
+
+// checkExpiries notifies of the pending retryables that timed out as of the given header.
+// A retryable's timeout is extended when it's kept alive, so candidates are checked against the state.
+func (w *BridgeEventWatcher) checkExpiries(batch ethdb.Batch, header *types.Header) error {
+	var candidates []common.Hash
+	for ticketId, timeout := range w.pending {
+		if timeout < header.Time {
+			candidates = append(candidates, ticketId)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	statedb, err := w.blockchain.StateAt(header.Root)
+	if err != nil {
+		return err
+	}
+	arbState, err := arbosState.OpenSystemArbosState(statedb, nil, true)
+	if err != nil {
+		return err
+	}
+	retryableState := arbState.RetryableState()
+	for _, ticketId := range candidates {
+		retryable, err := retryableState.OpenRetryable(ticketId, header.Time)
+		if err != nil {
+			return err
+		}
+		if retryable != nil {
+			timeout, err := retryable.CalculateTimeout()
+			if err != nil {
+				return err
+			}
+			if err := w.trackRetryable(batch, ticketId, timeout); err != nil {
+				return err
+			}
+			continue
+		}
+		w.notifier.Notify(BridgeEventRetryableExpired, &RetryableExpiredEvent{
+			TicketId: ticketId,
+			Timeout:  hexutil.Uint64(w.pending[ticketId]),
+		})
+		if err := w.untrackRetryable(batch, ticketId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// update processes up to max-blocks-per-update new blocks, and returns whether it caught up with the chain head.
+func (w *BridgeEventWatcher) update(ctx context.Context) (bool, error) {
+	head := w.blockchain.CurrentBlock()
+	batch := w.db.NewBatch()
+	for i := uint64(0); i < w.config().MaxBlocksPerUpdate && w.nextBlock <= head.Number.Uint64(); i++ {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		block := w.blockchain.GetBlockByNumber(w.nextBlock)
+		if block == nil {
+			return false, fmt.Errorf("block %v not found", w.nextBlock)
+		}
+		if err := w.processBlock(batch, block); err != nil {
+			return false, err
+		}
+		w.nextBlock++
+	}
+	caughtUp := w.nextBlock > head.Number.Uint64()
+	if caughtUp {
+		// retryables redeemed in blocks not processed yet might look expired
+		if err := w.checkExpiries(batch, head); err != nil {
+			return false, err
+		}
+	}
+	if err := batch.Put(bridgeEventsProgressKey, binary.BigEndian.AppendUint64(nil, w.nextBlock)); err != nil {
+		return false, err
+	}
+	if err := batch.Write(); err != nil {
+		return false, err
+	}
+	return caughtUp, nil
+}
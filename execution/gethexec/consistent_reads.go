@@ -0,0 +1,350 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/arbitrum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var consistentReadsRejectedCounter = metrics.NewRegisteredCounter("arb/rpc/consistentreads/rejected", nil)
+
+var ErrBlockNotPublished = errors.New("block not yet published by the chosen sequencer")
+
+type ConsistentReadsConfig struct {
+	Enable bool `koanf:"enable"`
+}
+
+var DefaultConsistentReadsConfig = ConsistentReadsConfig{
+	Enable: false,
+}
+
+func ConsistentReadsConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultConsistentReadsConfig.Enable, "serve eth namespace reads of blocks, transactions, logs and state, including eth_call, as of the last block the chosen sequencer published through the sequencer coordinator, so standby sequencers can serve reads that are never ahead of the chosen sequencer")
+}
+
+// ConsistentReadAPI serves reads as of the last block the chosen sequencer has published, rather than this
+// node's head. A standby sequencer's head can be ahead of that, with messages it sequenced while chosen that
+// never made it to the coordinator, and which will be reorged out once it catches up. Like StateReaderAPI,
+// it's registered in the eth namespace after geth's own APIs, so its methods take precedence.
+//
+// Account and storage reads and calls are served by the state reader. The other reads of a block are forwarded to
+// geth's own eth API once their block is resolved, and blocks, transactions and receipts looked up by hash
+// are hidden while they're past the published head. Methods without a block, like eth_gasPrice or
+// eth_chainId, and filters and subscriptions, which follow the node's head, aren't covered.
+type ConsistentReadAPI struct {
+	reader *StateReader
+	caller *StateCaller
+	// the last published block this node has built
+	head func(ctx context.Context) (uint64, error)
+	// calls geth's own eth API
+	geth *rpc.Client
+}
+
+func NewConsistentReadAPI(reader *StateReader, caller *StateCaller, head func(ctx context.Context) (uint64, error), geth *rpc.Client) *ConsistentReadAPI {
+	return &ConsistentReadAPI{reader, caller, head, geth}
+}
+
+// resolve pins the latest and pending tags to the published head, and rejects blocks past it.
+func (api *ConsistentReadAPI) resolve(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (rpc.BlockNumberOrHash, error) {
+	head, err := api.head(ctx)
+	if err != nil {
+		return rpc.BlockNumberOrHash{}, err
+	}
+	number, isNumber := blockNrOrHash.Number()
+	if !isNumber {
+		header, err := api.reader.backend.HeaderByNumberOrHash(ctx, blockNrOrHash)
+		if err != nil {
+			return rpc.BlockNumberOrHash{}, err
+		}
+		if header != nil && header.Number.Uint64() > head {
+			consistentReadsRejectedCounter.Inc(1)
+			return rpc.BlockNumberOrHash{}, fmt.Errorf("%w: block %v is past %v", ErrBlockNotPublished, header.Number, head)
+		}
+		return blockNrOrHash, nil
+	}
+	switch number {
+	case rpc.LatestBlockNumber, rpc.PendingBlockNumber:
+		return rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(head)), nil
+	case rpc.SafeBlockNumber, rpc.FinalizedBlockNumber, rpc.EarliestBlockNumber:
+		// these are already behind what the chosen sequencer published
+		return blockNrOrHash, nil
+	}
+	if number < 0 || uint64(number) > head {
+		consistentReadsRejectedCounter.Inc(1)
+		return rpc.BlockNumberOrHash{}, fmt.Errorf("%w: block %v is past %v", ErrBlockNotPublished, number, head)
+	}
+	return blockNrOrHash, nil
+}
+
+func (api *ConsistentReadAPI) BlockNumber(ctx context.Context) (hexutil.Uint64, error) {
+	head, err := api.head(ctx)
+	return hexutil.Uint64(head), err
+}
+
+func (api *ConsistentReadAPI) GetBalance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error) {
+	blockNrOrHash, err := api.resolve(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := api.reader.Balance(ctx, address, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(balance), nil
+}
+
+func (api *ConsistentReadAPI) GetTransactionCount(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Uint64, error) {
+	blockNrOrHash, err := api.resolve(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := api.reader.Nonce(ctx, address, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Uint64)(&nonce), nil
+}
+
+func (api *ConsistentReadAPI) GetCode(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	blockNrOrHash, err := api.resolve(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return api.reader.Code(ctx, address, blockNrOrHash)
+}
+
+func (api *ConsistentReadAPI) GetStorageAt(ctx context.Context, address common.Address, hexKey string, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	key, err := decodeStorageKey(hexKey)
+	if err != nil {
+		return nil, err
+	}
+	blockNrOrHash, err = api.resolve(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	value, err := api.reader.Storage(ctx, address, key, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return value.Bytes(), nil
+}
+
+func (api *ConsistentReadAPI) resolveNumber(ctx context.Context, number rpc.BlockNumber) (interface{}, error) {
+	return api.resolveParam(ctx, rpc.BlockNumberOrHashWithNumber(number))
+}
+
+// resolveParam resolves the block, and returns it as a parameter for geth's API.
+func (api *ConsistentReadAPI) resolveParam(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (interface{}, error) {
+	blockNrOrHash, err := api.resolve(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return blockParam(blockNrOrHash), nil
+}
+
+// published returns the block, transaction or receipt looked up by hash, or null if it's in a block past the
+// published head. The field holds its block number, which pending transactions don't have.
+func (api *ConsistentReadAPI) published(ctx context.Context, result json.RawMessage, field string) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(result, &fields); err != nil || fields == nil {
+		return result, err
+	}
+	raw, ok := fields[field]
+	if !ok || string(raw) == "null" {
+		return result, nil
+	}
+	var number hexutil.Uint64
+	if err := json.Unmarshal(raw, &number); err != nil {
+		return nil, err
+	}
+	head, err := api.head(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(number) > head {
+		consistentReadsRejectedCounter.Inc(1)
+		return nil, nil
+	}
+	return result, nil
+}
+
+func (api *ConsistentReadAPI) Call(ctx context.Context, args arbitrum.TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash, overrides *json.RawMessage, blockOverrides *json.RawMessage) (hexutil.Bytes, error) {
+	block, err := api.resolve(ctx, optionalBlock(blockNrOrHash))
+	if err != nil {
+		return nil, err
+	}
+	return callOrForward(ctx, api.caller, api.geth, args, block, overrides, blockOverrides)
+}
+
+func (api *ConsistentReadAPI) EstimateGas(ctx context.Context, args json.RawMessage, blockNrOrHash *rpc.BlockNumberOrHash, overrides *json.RawMessage) (hexutil.Uint64, error) {
+	block, err := api.resolveParam(ctx, optionalBlock(blockNrOrHash))
+	if err != nil {
+		return 0, err
+	}
+	var result hexutil.Uint64
+	err = api.geth.CallContext(ctx, &result, "eth_estimateGas", args, block, overrides)
+	return result, err
+}
+
+func (api *ConsistentReadAPI) CreateAccessList(ctx context.Context, args json.RawMessage, blockNrOrHash *rpc.BlockNumberOrHash) (json.RawMessage, error) {
+	block, err := api.resolveParam(ctx, optionalBlock(blockNrOrHash))
+	if err != nil {
+		return nil, err
+	}
+	var result json.RawMessage
+	err = api.geth.CallContext(ctx, &result, "eth_createAccessList", args, block)
+	return result, err
+}
+
+func (api *ConsistentReadAPI) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (json.RawMessage, error) {
+	block, err := api.resolveParam(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	var result json.RawMessage
+	err = api.geth.CallContext(ctx, &result, "eth_getProof", address, storageKeys, block)
+	return result, err
+}
+
+func (api *ConsistentReadAPI) GetBlockByNumber(ctx context.Context, number rpc.BlockNumber, fullTx bool) (json.RawMessage, error) {
+	block, err := api.resolveNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	var result json.RawMessage
+	err = api.geth.CallContext(ctx, &result, "eth_getBlockByNumber", block, fullTx)
+	return result, err
+}
+
+func (api *ConsistentReadAPI) GetHeaderByNumber(ctx context.Context, number rpc.BlockNumber) (json.RawMessage, error) {
+	block, err := api.resolveNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	var result json.RawMessage
+	err = api.geth.CallContext(ctx, &result, "eth_getHeaderByNumber", block)
+	return result, err
+}
+
+func (api *ConsistentReadAPI) GetBlockTransactionCountByNumber(ctx context.Context, number rpc.BlockNumber) (json.RawMessage, error) {
+	block, err := api.resolveNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	var result json.RawMessage
+	err = api.geth.CallContext(ctx, &result, "eth_getBlockTransactionCountByNumber", block)
+	return result, err
+}
+
+func (api *ConsistentReadAPI) GetTransactionByBlockNumberAndIndex(ctx context.Context, number rpc.BlockNumber, index hexutil.Uint) (json.RawMessage, error) {
+	block, err := api.resolveNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	var result json.RawMessage
+	err = api.geth.CallContext(ctx, &result, "eth_getTransactionByBlockNumberAndIndex", block, index)
+	return result, err
+}
+
+func (api *ConsistentReadAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (json.RawMessage, error) {
+	block, err := api.resolveParam(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	var result json.RawMessage
+	err = api.geth.CallContext(ctx, &result, "eth_getBlockReceipts", block)
+	return result, err
+}
+
+func (api *ConsistentReadAPI) GetBlockByHash(ctx context.Context, hash common.Hash, fullTx bool) (json.RawMessage, error) {
+	var result json.RawMessage
+	if err := api.geth.CallContext(ctx, &result, "eth_getBlockByHash", hash, fullTx); err != nil {
+		return nil, err
+	}
+	return api.published(ctx, result, "number")
+}
+
+func (api *ConsistentReadAPI) GetHeaderByHash(ctx context.Context, hash common.Hash) (json.RawMessage, error) {
+	var result json.RawMessage
+	if err := api.geth.CallContext(ctx, &result, "eth_getHeaderByHash", hash); err != nil {
+		return nil, err
+	}
+	return api.published(ctx, result, "number")
+}
+
+func (api *ConsistentReadAPI) GetTransactionByHash(ctx context.Context, hash common.Hash) (json.RawMessage, error) {
+	var result json.RawMessage
+	if err := api.geth.CallContext(ctx, &result, "eth_getTransactionByHash", hash); err != nil {
+		return nil, err
+	}
+	return api.published(ctx, result, "blockNumber")
+}
+
+func (api *ConsistentReadAPI) GetTransactionReceipt(ctx context.Context, hash common.Hash) (json.RawMessage, error) {
+	var result json.RawMessage
+	if err := api.geth.CallContext(ctx, &result, "eth_getTransactionReceipt", hash); err != nil {
+		return nil, err
+	}
+	return api.published(ctx, result, "blockNumber")
+}
+
+// GetLogs serves a log filter with its range ending at the published head, returning no logs if it starts
+// past it.
+func (api *ConsistentReadAPI) GetLogs(ctx context.Context, criteria map[string]json.RawMessage) (json.RawMessage, error) {
+	if criteria == nil {
+		criteria = make(map[string]json.RawMessage)
+	}
+	if raw, ok := criteria["blockHash"]; ok && string(raw) != "null" {
+		var hash common.Hash
+		if err := json.Unmarshal(raw, &hash); err != nil {
+			return nil, err
+		}
+		if _, err := api.resolve(ctx, rpc.BlockNumberOrHashWithHash(hash, false)); err != nil {
+			return nil, err
+		}
+	} else {
+		head, err := api.head(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, field := range []string{"fromBlock", "toBlock"} {
+			number := rpc.LatestBlockNumber
+			if raw, ok := criteria[field]; ok && string(raw) != "null" {
+				if err := json.Unmarshal(raw, &number); err != nil {
+					return nil, err
+				}
+			}
+			past := number >= 0 && uint64(number) > head
+			if past && field == "fromBlock" {
+				return json.RawMessage("[]"), nil
+			}
+			var param interface{} = number.String()
+			if number >= 0 {
+				param = hexutil.Uint64(number)
+			}
+			if past || number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+				param = hexutil.Uint64(head)
+			}
+			encoded, err := json.Marshal(param)
+			if err != nil {
+				return nil, err
+			}
+			criteria[field] = encoded
+		}
+	}
+	var result json.RawMessage
+	err := api.geth.CallContext(ctx, &result, "eth_getLogs", criteria)
+	return result, err
+}
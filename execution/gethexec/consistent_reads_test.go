@@ -0,0 +1,153 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arbitrum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestConsistentReadsResolve(t *testing.T) {
+	ctx := context.Background()
+	reader, addresses := newTestStateReader(t, 4, true)
+	head := uint64(1)
+	api := NewConsistentReadAPI(reader, nil, func(context.Context) (uint64, error) { return head, nil }, nil)
+
+	resolved, err := api.resolve(ctx, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if number, _ := resolved.Number(); number != 1 {
+		t.Fatal("latest resolved to", number, "rather than the published head")
+	}
+	balance, err := api.GetBalance(ctx, addresses[0], rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.ToInt().Uint64() != 1 {
+		t.Fatal("unexpected balance", balance)
+	}
+	byHash := rpc.BlockNumberOrHashWithHash(common.Hash{1}, false)
+	if _, err := api.resolve(ctx, byHash); err != nil {
+		t.Fatal(err)
+	}
+
+	// the test backend's only block is now past the published head
+	head = 0
+	if _, err := api.resolve(ctx, byHash); !errors.Is(err, ErrBlockNotPublished) {
+		t.Fatal("read a block by hash past the published head, got", err)
+	}
+	if _, err := api.GetBalance(ctx, addresses[0], rpc.BlockNumberOrHashWithNumber(1)); !errors.Is(err, ErrBlockNotPublished) {
+		t.Fatal("read a block by number past the published head, got", err)
+	}
+	blockNumber, err := api.BlockNumber(ctx)
+	if err != nil || blockNumber != 0 {
+		t.Fatal("unexpected block number", blockNumber, err)
+	}
+}
+
+// testGethEthAPI stands in for geth's eth API, recording the block each call was forwarded with.
+type testGethEthAPI struct {
+	block *rpc.BlockNumberOrHash
+}
+
+func (api *testGethEthAPI) Call(args map[string]interface{}, block *rpc.BlockNumberOrHash, overrides, blockOverrides *json.RawMessage) hexutil.Bytes {
+	api.block = block
+	return hexutil.Bytes{1}
+}
+
+func (api *testGethEthAPI) GetBlockByHash(hash common.Hash, fullTx bool) map[string]interface{} {
+	return map[string]interface{}{"hash": hash, "number": hexutil.Uint64(1)}
+}
+
+func (api *testGethEthAPI) GetTransactionByHash(hash common.Hash) map[string]interface{} {
+	if hash == (common.Hash{}) {
+		// pending
+		return map[string]interface{}{"hash": hash, "blockNumber": nil}
+	}
+	return map[string]interface{}{"hash": hash, "blockNumber": hexutil.Uint64(1)}
+}
+
+func (api *testGethEthAPI) GetLogs(criteria map[string]json.RawMessage) map[string]json.RawMessage {
+	return criteria
+}
+
+func isNull(result json.RawMessage) bool {
+	return len(result) == 0 || string(result) == "null"
+}
+
+func TestConsistentReadsForwarded(t *testing.T) {
+	ctx := context.Background()
+	reader, _ := newTestStateReader(t, 1, false)
+	geth := &testGethEthAPI{}
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", geth); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client := rpc.DialInProc(server)
+	defer client.Close()
+	head := uint64(1)
+	api := NewConsistentReadAPI(reader, nil, func(context.Context) (uint64, error) { return head, nil }, client)
+
+	// an omitted block is the published head
+	if _, err := api.Call(ctx, arbitrum.TransactionArgs{}, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if number, ok := geth.block.Number(); !ok || number != 1 {
+		t.Fatal("call was forwarded with", geth.block, "rather than the published head")
+	}
+	past := rpc.BlockNumberOrHashWithNumber(2)
+	if _, err := api.Call(ctx, arbitrum.TransactionArgs{}, &past, nil, nil); !errors.Is(err, ErrBlockNotPublished) {
+		t.Fatal("called a block past the published head, got", err)
+	}
+
+	block, err := api.GetBlockByHash(ctx, common.Hash{1}, false)
+	if err != nil || isNull(block) {
+		t.Fatal("published block hidden", string(block), err)
+	}
+	tx, err := api.GetTransactionByHash(ctx, common.Hash{1})
+	if err != nil || isNull(tx) {
+		t.Fatal("published transaction hidden", string(tx), err)
+	}
+
+	// the logs of blocks past the published head aren't returned
+	var criteria map[string]json.RawMessage
+	result, err := api.GetLogs(ctx, map[string]json.RawMessage{"fromBlock": json.RawMessage(`"finalized"`), "toBlock": json.RawMessage(`"0x5"`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(result, &criteria); err != nil {
+		t.Fatal(err)
+	}
+	if string(criteria["fromBlock"]) != `"finalized"` || string(criteria["toBlock"]) != `"0x1"` {
+		t.Fatal("log filter forwarded as", criteria)
+	}
+	result, err = api.GetLogs(ctx, map[string]json.RawMessage{"fromBlock": json.RawMessage(`"0x5"`)})
+	if err != nil || string(result) != "[]" {
+		t.Fatal("expected no logs from past the published head, got", string(result), err)
+	}
+
+	// once the block is past the published head, what's looked up by hash in it is hidden
+	head = 0
+	block, err = api.GetBlockByHash(ctx, common.Hash{1}, false)
+	if err != nil || !isNull(block) {
+		t.Fatal("unpublished block returned", string(block), err)
+	}
+	tx, err = api.GetTransactionByHash(ctx, common.Hash{1})
+	if err != nil || !isNull(tx) {
+		t.Fatal("unpublished transaction returned", string(tx), err)
+	}
+	tx, err = api.GetTransactionByHash(ctx, common.Hash{})
+	if err != nil || isNull(tx) {
+		t.Fatal("pending transaction hidden", string(tx), err)
+	}
+}
@@ -89,6 +89,8 @@ type ExecutionEngine struct {
 	prefetchBlock bool
 
 	cachedL1PriceData *L1PriceData
+
+	resultCache *messageResultCache
 }
 
 func NewL1PriceData() *L1PriceData {
@@ -103,6 +105,7 @@ func NewExecutionEngine(bc *core.BlockChain) (*ExecutionEngine, error) {
 		resequenceChan:    make(chan []*arbostypes.MessageWithMetadata),
 		newBlockNotifier:  make(chan struct{}, 1),
 		cachedL1PriceData: NewL1PriceData(),
+		resultCache:       newMessageResultCache(0),
 	}, nil
 }
 
@@ -185,6 +188,13 @@ func (s *ExecutionEngine) EnablePrefetchBlock() {
 	s.prefetchBlock = true
 }
 
+func (s *ExecutionEngine) EnableResultCache(size int) {
+	if s.Started() {
+		panic("trying to enable result cache after start")
+	}
+	s.resultCache = newMessageResultCache(size)
+}
+
 func (s *ExecutionEngine) SetConsensus(consensus execution.FullConsensusClient) {
 	if s.Started() {
 		panic("trying to set transaction consensus after start")
@@ -228,6 +238,7 @@ func (s *ExecutionEngine) Reorg(count arbutil.MessageIndex, newMessages []arbost
 	if err != nil {
 		return nil, err
 	}
+	s.resultCache.invalidateFrom(count)
 
 	newMessagesResults := make([]*execution.MessageResult, 0, len(oldMessages))
 	for i := range newMessages {
@@ -523,6 +534,7 @@ func (s *ExecutionEngine) sequenceTransactionsWithBlockMutex(header *arbostypes.
 		return nil, err
 	}
 	s.cacheL1PriceDataOfMsg(pos, receipts, block, false)
+	s.resultCache.add(pos, msgResult, s.resultCache.currentGeneration())
 
 	return block, nil
 }
@@ -578,6 +590,7 @@ func (s *ExecutionEngine) sequenceDelayedMessageWithBlockMutex(message *arbostyp
 		return nil, err
 	}
 	s.cacheL1PriceDataOfMsg(pos, receipts, block, true)
+	s.resultCache.add(pos, msgResult, s.resultCache.currentGeneration())
 
 	log.Info("ExecutionEngine: Added DelayedMessages", "pos", pos, "delayed", delayedSeqNum, "block-header", block.Header())
 
@@ -676,7 +689,16 @@ func (s *ExecutionEngine) resultFromHeader(header *types.Header) (*execution.Mes
 }
 
 func (s *ExecutionEngine) ResultAtPos(pos arbutil.MessageIndex) (*execution.MessageResult, error) {
-	return s.resultFromHeader(s.bc.GetHeaderByNumber(s.MessageIndexToBlockNumber(pos)))
+	cached, generation := s.resultCache.get(pos)
+	if cached != nil {
+		return cached, nil
+	}
+	result, err := s.resultFromHeader(s.bc.GetHeaderByNumber(s.MessageIndexToBlockNumber(pos)))
+	if err != nil {
+		return nil, err
+	}
+	s.resultCache.add(pos, result, generation)
+	return result, nil
 }
 
 func (s *ExecutionEngine) updateL1GasPriceEstimateMetric() {
@@ -700,16 +722,24 @@ func (s *ExecutionEngine) updateL1GasPriceEstimateMetric() {
 	l1GasPriceEstimateGauge.Update(l2EstimateL1GasPrice.Int64())
 }
 
-func (s *ExecutionEngine) getL1PricingSurplus() (int64, error) {
+func (s *ExecutionEngine) latestArbosState() (*arbosState.ArbosState, error) {
 	bc := s.bc
 	latestHeader := bc.CurrentBlock()
 	latestState, err := bc.StateAt(latestHeader.Root)
 	if err != nil {
-		return 0, errors.New("error getting latest statedb while fetching current L1 pricing surplus")
+		return nil, errors.New("error getting latest statedb while fetching current L1 pricing state")
 	}
 	arbState, err := arbosState.OpenSystemArbosState(latestState, nil, true)
 	if err != nil {
-		return 0, errors.New("error opening system arbos state while fetching current L1 pricing surplus")
+		return nil, errors.New("error opening system arbos state while fetching current L1 pricing state")
+	}
+	return arbState, nil
+}
+
+func (s *ExecutionEngine) getL1PricingSurplus() (int64, error) {
+	arbState, err := s.latestArbosState()
+	if err != nil {
+		return 0, err
 	}
 	surplus, err := arbState.L1PricingState().GetL1PricingSurplus()
 	if err != nil {
@@ -718,6 +748,28 @@ func (s *ExecutionEngine) getL1PricingSurplus() (int64, error) {
 	return surplus.Int64(), nil
 }
 
+// getL1PricingFunds returns the L1 fees collected and available, and the funds due to batch posters and as rewards.
+func (s *ExecutionEngine) getL1PricingFunds() (int64, int64, error) {
+	arbState, err := s.latestArbosState()
+	if err != nil {
+		return 0, 0, err
+	}
+	l1Pricing := arbState.L1PricingState()
+	available, err := l1Pricing.L1FeesAvailable()
+	if err != nil {
+		return 0, 0, err
+	}
+	fundsDue, err := l1Pricing.BatchPosterTable().TotalFundsDue()
+	if err != nil {
+		return 0, 0, err
+	}
+	fundsDueForRewards, err := l1Pricing.FundsDueForRewards()
+	if err != nil {
+		return 0, 0, err
+	}
+	return available.Int64(), arbmath.BigAdd(fundsDue, fundsDueForRewards).Int64(), nil
+}
+
 func (s *ExecutionEngine) cacheL1PriceDataOfMsg(seqNum arbutil.MessageIndex, receipts types.Receipts, block *types.Block, blockBuiltUsingDelayedMessage bool) {
 	var gasUsedForL1 uint64
 	var callDataUnits uint64
@@ -875,6 +927,7 @@ func (s *ExecutionEngine) digestMessageWithBlockMutex(num arbutil.MessageIndex,
 	if err != nil {
 		return nil, err
 	}
+	s.resultCache.add(num, msgResult, s.resultCache.currentGeneration())
 	return msgResult, nil
 }
 
@@ -0,0 +1,116 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+
+	"github.com/offchainlabs/nitro/util/arbmath"
+)
+
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+// FeeTokenAPI serves gas prices and transaction fees both in the fee token's smallest unit, as the chain
+// denominates them, and converted to wei at the fee token's exchange rate, for tools which assume fees are in wei.
+type FeeTokenAPI struct {
+	blockchain *core.BlockChain
+	chainDb    ethdb.Database
+}
+
+func NewFeeTokenAPI(blockchain *core.BlockChain, chainDb ethdb.Database) *FeeTokenAPI {
+	return &FeeTokenAPI{blockchain, chainDb}
+}
+
+type GasPriceSuggestion struct {
+	Decimals           hexutil.Uint64 `json:"decimals"`
+	WeiPerToken        *hexutil.Big   `json:"weiPerToken"`
+	GasPrice           *hexutil.Big   `json:"gasPrice"`
+	NormalizedGasPrice *hexutil.Big   `json:"normalizedGasPrice"`
+}
+
+type TransactionFees struct {
+	Decimals          hexutil.Uint64 `json:"decimals"`
+	WeiPerToken       *hexutil.Big   `json:"weiPerToken"`
+	GasUsed           hexutil.Uint64 `json:"gasUsed"`
+	GasUsedForL1      hexutil.Uint64 `json:"gasUsedForL1"`
+	EffectiveGasPrice *hexutil.Big   `json:"effectiveGasPrice"`
+	Fee               *hexutil.Big   `json:"fee"`
+	L1Fee             *hexutil.Big   `json:"l1Fee"`
+	NormalizedFee     *hexutil.Big   `json:"normalizedFee"`
+	NormalizedL1Fee   *hexutil.Big   `json:"normalizedL1Fee"`
+}
+
+// feeToken returns the fee token as of the given block.
+func (api *FeeTokenAPI) feeToken(blockNumber uint64) (arbmath.FeeToken, error) {
+	state, _, err := stateAndHeader(api.blockchain, blockNumber)
+	if err != nil {
+		return arbmath.EtherFeeToken, err
+	}
+	return state.FeeToken()
+}
+
+func normalize(amount *big.Int, feeToken arbmath.FeeToken) *hexutil.Big {
+	return (*hexutil.Big)(feeToken.ToWei(amount))
+}
+
+// FeeTokenDecimals returns how many decimals the chain's fee token has.
+func (api *FeeTokenAPI) FeeTokenDecimals(ctx context.Context) (hexutil.Uint64, error) {
+	feeToken, err := api.feeToken(api.blockchain.CurrentBlock().Number.Uint64())
+	return hexutil.Uint64(feeToken.Decimals), err
+}
+
+// SuggestGasPrice returns the current base fee, which is what transactions pay per gas, in the fee token's smallest
+// unit and in wei.
+func (api *FeeTokenAPI) SuggestGasPrice(ctx context.Context) (*GasPriceSuggestion, error) {
+	header := api.blockchain.CurrentBlock()
+	feeToken, err := api.feeToken(header.Number.Uint64())
+	if err != nil {
+		return nil, err
+	}
+	return &GasPriceSuggestion{
+		Decimals:           hexutil.Uint64(feeToken.Decimals),
+		WeiPerToken:        (*hexutil.Big)(feeToken.WeiPerToken),
+		GasPrice:           (*hexutil.Big)(header.BaseFee),
+		NormalizedGasPrice: normalize(header.BaseFee, feeToken),
+	}, nil
+}
+
+// TransactionFees returns what the transaction paid in total and for its L1 costs, converted to wei at the
+// exchange rate of the block it was included in.
+func (api *FeeTokenAPI) TransactionFees(ctx context.Context, hash common.Hash) (*TransactionFees, error) {
+	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(api.chainDb, hash)
+	if tx == nil {
+		return nil, ErrTransactionNotFound
+	}
+	receipts := api.blockchain.GetReceiptsByHash(blockHash)
+	if index >= uint64(len(receipts)) {
+		return nil, ErrTransactionNotFound
+	}
+	receipt := receipts[index]
+	feeToken, err := api.feeToken(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	fee := arbmath.BigMulByUint(receipt.EffectiveGasPrice, receipt.GasUsed)
+	l1Fee := arbmath.BigMulByUint(receipt.EffectiveGasPrice, receipt.GasUsedForL1)
+	return &TransactionFees{
+		Decimals:          hexutil.Uint64(feeToken.Decimals),
+		WeiPerToken:       (*hexutil.Big)(feeToken.WeiPerToken),
+		GasUsed:           hexutil.Uint64(receipt.GasUsed),
+		GasUsedForL1:      hexutil.Uint64(receipt.GasUsedForL1),
+		EffectiveGasPrice: (*hexutil.Big)(receipt.EffectiveGasPrice),
+		Fee:               (*hexutil.Big)(fee),
+		L1Fee:             (*hexutil.Big)(l1Fee),
+		NormalizedFee:     normalize(fee, feeToken),
+		NormalizedL1Fee:   normalize(l1Fee, feeToken),
+	}, nil
+}
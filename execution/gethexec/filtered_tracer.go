@@ -0,0 +1,279 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+// FilteredTracerName is the name to pass as the tracer of debug_traceTransaction and friends.
+const FilteredTracerName = "filteredTracer"
+
+type FilteredTracerConfig struct {
+	MaxResultSize uint64 `koanf:"max-result-size" reload:"hot"`
+	MaxStackItems uint64 `koanf:"max-stack-items" reload:"hot"`
+}
+
+type FilteredTracerConfigFetcher func() *FilteredTracerConfig
+
+var DefaultFilteredTracerConfig = FilteredTracerConfig{
+	MaxResultSize: 32 * 1024 * 1024,
+	MaxStackItems: 16,
+}
+
+func FilteredTracerConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Uint64(prefix+".max-result-size", DefaultFilteredTracerConfig.MaxResultSize, "maximum size in bytes of a single "+FilteredTracerName+" result; larger traces are returned in pages")
+	f.Uint64(prefix+".max-stack-items", DefaultFilteredTracerConfig.MaxStackItems, "maximum number of stack items "+FilteredTracerName+" can capture per opcode")
+}
+
+// the tracer directory constructs tracers without access to the node, so the node publishes its config here
+var filteredTracerConfig atomic.Pointer[FilteredTracerConfigFetcher]
+
+func setFilteredTracerConfig(config FilteredTracerConfigFetcher) {
+	filteredTracerConfig.Store(&config)
+}
+
+func init() {
+	tracers.DefaultDirectory.Register(FilteredTracerName, newFilteredTracer, false)
+}
+
+// filteredTracerOptions select what the tracer captures. Without opcodes and calls, nothing is captured.
+type filteredTracerOptions struct {
+	// opcodes to capture, e.g. SSTORE or CALL
+	Opcodes []string `json:"opcodes"`
+	// whether to capture call frames being entered and exited
+	Calls bool `json:"calls"`
+	// only capture inside these contracts, if set
+	Addresses []common.Address `json:"addresses"`
+	// only capture up to this call depth, if set
+	MaxDepth int `json:"maxDepth"`
+	// how many of the top stack items to capture for each opcode
+	StackItems uint64 `json:"stackItems"`
+	// skip this many matching entries, to continue from a previous page
+	Offset uint64 `json:"offset"`
+	// return at most this many entries
+	Limit uint64 `json:"limit"`
+}
+
+type filteredTraceEntry struct {
+	// the position of the entry among all the transaction's matching entries
+	Index   uint64          `json:"index"`
+	Type    string          `json:"type"`
+	Depth   int             `json:"depth"`
+	Address *common.Address `json:"address,omitempty"`
+	Pc      *uint64         `json:"pc,omitempty"`
+	Op      string          `json:"op,omitempty"`
+	Gas     hexutil.Uint64  `json:"gas"`
+	GasCost *hexutil.Uint64 `json:"gasCost,omitempty"`
+	Stack   []*hexutil.Big  `json:"stack,omitempty"`
+	From    *common.Address `json:"from,omitempty"`
+	To      *common.Address `json:"to,omitempty"`
+	Input   hexutil.Bytes   `json:"input,omitempty"`
+	Value   *hexutil.Big    `json:"value,omitempty"`
+	Output  hexutil.Bytes   `json:"output,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+type filteredTraceResult struct {
+	Entries []json.RawMessage `json:"entries"`
+	// the number of matching entries in the whole transaction
+	Total uint64 `json:"total"`
+	// pass this as the offset to get the next page, nil if this is the last page
+	NextOffset *uint64 `json:"nextOffset"`
+}
+
+type filteredTracer struct {
+	options   filteredTracerOptions
+	opcodes   map[vm.OpCode]bool
+	addresses map[common.Address]bool
+	maxSize   uint64
+	maxStack  uint64
+
+	// the contract executing at each depth, with the transaction's entry point at depth 1
+	frames  []common.Address
+	matched uint64
+	size    uint64
+	full    bool
+	result  filteredTraceResult
+	err     error
+	stopped atomic.Bool
+}
+
+func newFilteredTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	t := &filteredTracer{
+		opcodes:   make(map[vm.OpCode]bool),
+		addresses: make(map[common.Address]bool),
+		maxSize:   DefaultFilteredTracerConfig.MaxResultSize,
+		maxStack:  DefaultFilteredTracerConfig.MaxStackItems,
+		result:    filteredTraceResult{Entries: []json.RawMessage{}},
+	}
+	if fetcher := filteredTracerConfig.Load(); fetcher != nil {
+		config := (*fetcher)()
+		t.maxSize = config.MaxResultSize
+		t.maxStack = config.MaxStackItems
+	}
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &t.options); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range t.options.Opcodes {
+		op := vm.StringToOp(name)
+		if op.String() != name {
+			return nil, fmt.Errorf("unknown opcode %v", name)
+		}
+		t.opcodes[op] = true
+	}
+	if len(t.opcodes) == 0 && !t.options.Calls {
+		return nil, errors.New("no opcodes or calls to capture")
+	}
+	for _, addr := range t.options.Addresses {
+		t.addresses[addr] = true
+	}
+	if t.options.StackItems > t.maxStack {
+		return nil, fmt.Errorf("can capture at most %v stack items", t.maxStack)
+	}
+	return t, nil
+}
+
+func (t *filteredTracer) inScope(depth int) bool {
+	if t.options.MaxDepth > 0 && depth > t.options.MaxDepth {
+		return false
+	}
+	if len(t.addresses) == 0 {
+		return true
+	}
+	return depth > 0 && depth <= len(t.frames) && t.addresses[t.frames[depth-1]]
+}
+
+// record adds a matching entry to the page being built, if it's within the requested window and size.
+func (t *filteredTracer) record(entry *filteredTraceEntry) {
+	index := t.matched
+	t.matched++
+	if t.full || index < t.options.Offset {
+		return
+	}
+	if t.options.Limit != 0 && uint64(len(t.result.Entries)) >= t.options.Limit {
+		t.full = true
+		return
+	}
+	entry.Index = index
+	encoded, err := json.Marshal(entry)
+	if err != nil {
+		t.err = err
+		t.full = true
+		return
+	}
+	if t.maxSize != 0 && t.size+uint64(len(encoded)) > t.maxSize {
+		if len(t.result.Entries) == 0 {
+			t.err = fmt.Errorf("trace entry %v alone exceeds the maximum result size of %v bytes", index, t.maxSize)
+		}
+		t.full = true
+		return
+	}
+	t.size += uint64(len(encoded))
+	t.result.Entries = append(t.result.Entries, encoded)
+}
+
+func (t *filteredTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (t *filteredTracer) CaptureTxEnd(restGas uint64) {}
+
+func (t *filteredTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.frames = append(t.frames[:0], to)
+	if t.options.Calls && t.inScope(1) {
+		t.record(&filteredTraceEntry{Type: "enter", Depth: 1, From: &from, To: &to, Input: input, Gas: hexutil.Uint64(gas), Value: (*hexutil.Big)(value)})
+	}
+}
+
+func (t *filteredTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	if t.options.Calls && t.inScope(1) {
+		t.record(&filteredTraceEntry{Type: "exit", Depth: 1, Output: output, Gas: hexutil.Uint64(gasUsed), Error: errorString(err)})
+	}
+}
+
+func (t *filteredTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if t.stopped.Load() {
+		return
+	}
+	t.frames = append(t.frames, to)
+	depth := len(t.frames)
+	if t.options.Calls && t.inScope(depth) {
+		t.record(&filteredTraceEntry{Type: "enter", Op: typ.String(), Depth: depth, From: &from, To: &to, Input: input, Gas: hexutil.Uint64(gas), Value: (*hexutil.Big)(value)})
+	}
+}
+
+func (t *filteredTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if t.stopped.Load() {
+		return
+	}
+	depth := len(t.frames)
+	if t.options.Calls && t.inScope(depth) {
+		t.record(&filteredTraceEntry{Type: "exit", Depth: depth, Output: output, Gas: hexutil.Uint64(gasUsed), Error: errorString(err)})
+	}
+	if len(t.frames) > 1 {
+		t.frames = t.frames[:len(t.frames)-1]
+	}
+}
+
+func (t *filteredTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if t.stopped.Load() || !t.opcodes[op] || !t.inScope(depth) {
+		return
+	}
+	address := scope.Contract.Address()
+	gasCost := hexutil.Uint64(cost)
+	entry := &filteredTraceEntry{Type: "opcode", Depth: depth, Address: &address, Pc: &pc, Op: op.String(), Gas: hexutil.Uint64(gas), GasCost: &gasCost, Error: errorString(err)}
+	stack := scope.Stack.Data()
+	for i := uint64(0); i < t.options.StackItems && i < uint64(len(stack)); i++ {
+		entry.Stack = append(entry.Stack, (*hexutil.Big)(stack[len(stack)-1-int(i)].ToBig()))
+	}
+	t.record(entry)
+}
+
+func (t *filteredTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+func (t *filteredTracer) CaptureArbitrumTransfer(env *vm.EVM, from, to *common.Address, value *big.Int, before bool, purpose string) {
+}
+
+func (t *filteredTracer) CaptureArbitrumStorageGet(key common.Hash, depth int, before bool) {}
+
+func (t *filteredTracer) CaptureArbitrumStorageSet(key, value common.Hash, depth int, before bool) {}
+
+func (t *filteredTracer) CaptureStylusHostio(name string, args, outs []byte, startInk, endInk uint64) {
+}
+
+func (t *filteredTracer) GetResult() (json.RawMessage, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	t.result.Total = t.matched
+	if next := t.options.Offset + uint64(len(t.result.Entries)); next < t.matched {
+		t.result.NextOffset = &next
+	}
+	return json.Marshal(&t.result)
+}
+
+func (t *filteredTracer) Stop(err error) {
+	t.err = err
+	t.stopped.Store(true)
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
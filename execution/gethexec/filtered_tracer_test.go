@@ -0,0 +1,81 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+// runFilteredTracer feeds the tracer a transaction calling into a contract that makes calls to each of the callees.
+func runFilteredTracer(t *testing.T, options string, callees []common.Address) *filteredTraceResult {
+	t.Helper()
+	tracer, err := newFilteredTracer(&tracers.Context{}, json.RawMessage(options))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := common.HexToAddress("0x1")
+	contract := common.HexToAddress("0x2")
+	tracer.CaptureTxStart(1_000_000)
+	tracer.CaptureStart(nil, sender, contract, false, nil, 1_000_000, big.NewInt(0))
+	for _, callee := range callees {
+		tracer.CaptureEnter(vm.CALL, contract, callee, []byte{1}, 10_000, big.NewInt(0))
+		tracer.CaptureExit(nil, 100, nil)
+	}
+	tracer.CaptureEnd(nil, 50_000, nil)
+	tracer.CaptureTxEnd(0)
+	encoded, err := tracer.GetResult()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result filteredTraceResult
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		t.Fatal(err)
+	}
+	return &result
+}
+
+func TestFilteredTracerPaging(t *testing.T) {
+	callees := []common.Address{common.HexToAddress("0x3"), common.HexToAddress("0x4"), common.HexToAddress("0x5")}
+	result := runFilteredTracer(t, `{"calls":true,"maxDepth":2,"limit":3}`, callees)
+	if result.Total != 8 || len(result.Entries) != 3 || result.NextOffset == nil || *result.NextOffset != 3 {
+		t.Fatalf("unexpected first page: total %v entries %v next %v", result.Total, len(result.Entries), result.NextOffset)
+	}
+	result = runFilteredTracer(t, `{"calls":true,"offset":6,"limit":3}`, callees)
+	if len(result.Entries) != 2 || result.NextOffset != nil {
+		t.Fatalf("unexpected last page: entries %v next %v", len(result.Entries), result.NextOffset)
+	}
+	var last filteredTraceEntry
+	if err := json.Unmarshal(result.Entries[1], &last); err != nil {
+		t.Fatal(err)
+	}
+	if last.Index != 7 || last.Type != "exit" || last.Depth != 1 {
+		t.Errorf("unexpected last entry %+v", last)
+	}
+
+	// only the outer frame is within depth 1
+	result = runFilteredTracer(t, `{"calls":true,"maxDepth":1}`, callees)
+	if result.Total != 2 {
+		t.Errorf("expected only the outer frame, got %v entries", result.Total)
+	}
+
+	// the size cap splits the trace into pages
+	setFilteredTracerConfig(func() *FilteredTracerConfig { return &FilteredTracerConfig{MaxResultSize: 400, MaxStackItems: 1} })
+	defer setFilteredTracerConfig(func() *FilteredTracerConfig { return &DefaultFilteredTracerConfig })
+	result = runFilteredTracer(t, `{"calls":true}`, callees)
+	if len(result.Entries) == 0 || len(result.Entries) == 8 || result.NextOffset == nil {
+		t.Errorf("expected the size cap to truncate the page, got %v entries", len(result.Entries))
+	}
+	if _, err := newFilteredTracer(&tracers.Context{}, json.RawMessage(`{"opcodes":["SSTORE"],"stackItems":2}`)); err == nil {
+		t.Error("expected capturing more stack items than allowed to fail")
+	}
+	if _, err := newFilteredTracer(&tracers.Context{}, json.RawMessage(`{"opcodes":["NOTANOPCODE"]}`)); err == nil {
+		t.Error("expected an unknown opcode to be rejected")
+	}
+}
@@ -14,6 +14,8 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/offchainlabs/nitro/execution"
+	"github.com/offchainlabs/nitro/util/etcdutil"
 	"github.com/offchainlabs/nitro/util/redisutil"
 	"github.com/offchainlabs/nitro/util/stopwaiter"
 	flag "github.com/spf13/pflag"
@@ -27,12 +29,13 @@ import (
 )
 
 type ForwarderConfig struct {
-	ConnectionTimeout     time.Duration `koanf:"connection-timeout"`
-	IdleConnectionTimeout time.Duration `koanf:"idle-connection-timeout"`
-	MaxIdleConnections    int           `koanf:"max-idle-connections"`
-	RedisUrl              string        `koanf:"redis-url"`
-	UpdateInterval        time.Duration `koanf:"update-interval"`
-	RetryInterval         time.Duration `koanf:"retry-interval"`
+	ConnectionTimeout     time.Duration   `koanf:"connection-timeout"`
+	IdleConnectionTimeout time.Duration   `koanf:"idle-connection-timeout"`
+	MaxIdleConnections    int             `koanf:"max-idle-connections"`
+	RedisUrl              string          `koanf:"redis-url"`
+	Etcd                  etcdutil.Config `koanf:"etcd"`
+	UpdateInterval        time.Duration   `koanf:"update-interval"`
+	RetryInterval         time.Duration   `koanf:"retry-interval"`
 }
 
 var DefaultNodeForwarderConfig = ForwarderConfig{
@@ -40,6 +43,7 @@ var DefaultNodeForwarderConfig = ForwarderConfig{
 	IdleConnectionTimeout: 15 * time.Second,
 	MaxIdleConnections:    1,
 	RedisUrl:              "",
+	Etcd:                  etcdutil.DefaultConfig,
 	UpdateInterval:        time.Second,
 	RetryInterval:         100 * time.Millisecond,
 }
@@ -49,6 +53,7 @@ var DefaultSequencerForwarderConfig = ForwarderConfig{
 	IdleConnectionTimeout: 60 * time.Second,
 	MaxIdleConnections:    100,
 	RedisUrl:              "",
+	Etcd:                  etcdutil.DefaultConfig,
 	UpdateInterval:        time.Second,
 	RetryInterval:         100 * time.Millisecond,
 }
@@ -66,6 +71,7 @@ func AddOptionsForForwarderConfigImpl(prefix string, defaultConfig *ForwarderCon
 	f.Duration(prefix+".idle-connection-timeout", defaultConfig.IdleConnectionTimeout, "time until idle connections are closed")
 	f.Int(prefix+".max-idle-connections", defaultConfig.MaxIdleConnections, "maximum number of idle connections to keep open")
 	f.String(prefix+".redis-url", defaultConfig.RedisUrl, "the Redis URL to recomend target via")
+	etcdutil.ConfigAddOptions(prefix+".etcd", f)
 	f.Duration(prefix+".update-interval", defaultConfig.UpdateInterval, "forwarding target update interval")
 	f.Duration(prefix+".retry-interval", defaultConfig.RetryInterval, "minimal time between update retries")
 }
@@ -140,11 +146,11 @@ func (f *TxForwarder) PublishTransaction(inctx context.Context, tx *types.Transa
 			err = arbitrum.SendConditionalTransactionRPC(ctx, rpcClient, tx, options)
 		}
 		if err == nil || !f.tryNewForwarderErrors.MatchString(err.Error()) {
-			return err
+			return execution.RestoreErrorCode(err)
 		}
 		log.Warn("error forwarding transaction to a backup target", "target", f.targets[pos], "err", err)
 	}
-	return errors.New("failed to publish transaction to any of the forwarding targets")
+	return execution.NewCodedError(execution.ErrorCodeSequencerUnavailable, "failed to publish transaction to any of the forwarding targets")
 }
 
 const cacheUpstreamHealth = 2 * time.Second
@@ -257,15 +263,24 @@ func (f *TxDropper) Started() bool {
 	return true
 }
 
+// forwardingCoordinator finds the sequencer to forward to from the sequencer coordination keys.
+type forwardingCoordinator interface {
+	CurrentChosenSequencer(ctx context.Context) (string, error)
+	RecommendSequencerWantingLockout(ctx context.Context) (string, error)
+}
+
+// RedisTxForwarder forwards to the sequencer chosen through the sequencer coordinator, via Redis or,
+// if its endpoints are configured, etcd.
 type RedisTxForwarder struct {
 	stopwaiter.StopWaiterSafe
 
 	config         *ForwarderConfig
 	fallbackTarget string
 
-	errors           int
-	currentTarget    string
-	redisCoordinator *redisutil.RedisCoordinator
+	errors        int
+	currentTarget string
+	coordinator   forwardingCoordinator
+	etcd          *etcdutil.EtcdCoordinator
 
 	mtx       sync.RWMutex
 	forwarder *TxForwarder
@@ -296,10 +311,18 @@ func (f *RedisTxForwarder) CheckHealth(ctx context.Context) error {
 
 // not thread safe vs update and itself
 func (f *RedisTxForwarder) Initialize(ctx context.Context) error {
-	var err error
-	f.redisCoordinator, err = redisutil.NewRedisCoordinator(f.config.RedisUrl)
-	if err != nil {
-		return fmt.Errorf("unable to create redis coordinator: %w", err)
+	if f.config.Etcd.Enabled() {
+		etcdCoordinator, err := etcdutil.NewEtcdCoordinator(&f.config.Etcd)
+		if err != nil {
+			return fmt.Errorf("unable to create etcd coordinator: %w", err)
+		}
+		f.coordinator, f.etcd = etcdCoordinator, etcdCoordinator
+	} else {
+		redisCoordinator, err := redisutil.NewRedisCoordinator(f.config.RedisUrl)
+		if err != nil {
+			return fmt.Errorf("unable to create redis coordinator: %w", err)
+		}
+		f.coordinator = redisCoordinator
 	}
 	f.update(ctx)
 	return nil
@@ -345,11 +368,11 @@ func (f *RedisTxForwarder) update(ctx context.Context) time.Duration {
 	nextUpdateIn := f.noError
 	var newSequencerUrl string
 	var redisErr error
-	if f.redisCoordinator != nil {
-		newSequencerUrl, redisErr = f.redisCoordinator.CurrentChosenSequencer(ctx)
+	if f.coordinator != nil {
+		newSequencerUrl, redisErr = f.coordinator.CurrentChosenSequencer(ctx)
 		if redisErr == nil && newSequencerUrl == "" {
 			log.Info("no sequencer is currently chosen, using recommended sequencer instead")
-			newSequencerUrl, redisErr = f.redisCoordinator.RecommendSequencerWantingLockout(ctx)
+			newSequencerUrl, redisErr = f.coordinator.RecommendSequencerWantingLockout(ctx)
 		}
 		if redisErr != nil || newSequencerUrl == "" {
 			if f.shouldFallbackToStatic() && f.fallbackTarget != "" {
@@ -363,11 +386,11 @@ func (f *RedisTxForwarder) update(ctx context.Context) time.Duration {
 		}
 	} else {
 		if f.fallbackTarget != "" {
-			log.Warn("redis coordinator not initialized, falling back to static url", "fallback", f.fallbackTarget)
+			log.Warn("coordinator not initialized, falling back to static url", "fallback", f.fallbackTarget)
 			newSequencerUrl = f.fallbackTarget
 		} else {
 			// TODO panic? - there is no way to recover from this point
-			log.Error("redis coordinator not initilized, no fallback available")
+			log.Error("coordinator not initialized, no fallback available")
 			return f.retryAfterError()
 		}
 	}
@@ -414,6 +437,11 @@ func (f *RedisTxForwarder) StopAndWait() {
 	if oldForwarder != nil {
 		oldForwarder.StopAndWait()
 	}
+	if f.etcd != nil {
+		if err := f.etcd.Close(); err != nil {
+			log.Warn("Failed to close forwarder's etcd client", "err", err)
+		}
+	}
 }
 
 func (f *RedisTxForwarder) Started() bool {
@@ -38,7 +38,16 @@ type Config struct {
 	RPC                       arbitrum.Config                  `koanf:"rpc"`
 	TxLookupLimit             uint64                           `koanf:"tx-lookup-limit"`
 	EnablePrefetchBlock       bool                             `koanf:"enable-prefetch-block"`
+	EnableTxPoolAPI           bool                             `koanf:"enable-txpool-api"`
 	SyncMonitor               SyncMonitorConfig                `koanf:"sync-monitor"`
+	Retention                 RetentionConfig                  `koanf:"retention"`
+	StateReader               StateReaderConfig                `koanf:"state-reader"`
+	ConsistentReads           ConsistentReadsConfig            `koanf:"consistent-reads"`
+	AddressIndex              AddressIndexConfig               `koanf:"address-index" reload:"hot"`
+	BridgeEvents              BridgeEventsConfig               `koanf:"bridge-events" reload:"hot"`
+	FilteredTracer            FilteredTracerConfig             `koanf:"filtered-tracer" reload:"hot"`
+	SendRawTransactionSync    SendRawTransactionSyncConfig     `koanf:"send-raw-transaction-sync"`
+	PersistentFilters         PersistentFiltersConfig          `koanf:"persistent-filters" reload:"hot"`
 
 	forwardingTarget string
 }
@@ -50,6 +59,24 @@ func (c *Config) Validate() error {
 	if err := c.Sequencer.Validate(); err != nil {
 		return err
 	}
+	if err := c.Retention.Validate(); err != nil {
+		return err
+	}
+	if err := c.StateReader.Validate(); err != nil {
+		return err
+	}
+	if err := c.AddressIndex.Validate(); err != nil {
+		return err
+	}
+	if err := c.BridgeEvents.Validate(); err != nil {
+		return err
+	}
+	if err := c.SendRawTransactionSync.Validate(); err != nil {
+		return err
+	}
+	if err := c.PersistentFilters.Validate(); err != nil {
+		return err
+	}
 	if !c.Sequencer.Enable && c.ForwardingTarget == "" {
 		return errors.New("ForwardingTarget not set and not sequencer (can use \"null\")")
 	}
@@ -75,8 +102,17 @@ func ConfigAddOptions(prefix string, f *flag.FlagSet) {
 	TxPreCheckerConfigAddOptions(prefix+".tx-pre-checker", f)
 	CachingConfigAddOptions(prefix+".caching", f)
 	SyncMonitorConfigAddOptions(prefix+".sync-monitor", f)
+	RetentionConfigAddOptions(prefix+".retention", f)
+	StateReaderConfigAddOptions(prefix+".state-reader", f)
+	ConsistentReadsConfigAddOptions(prefix+".consistent-reads", f)
+	AddressIndexConfigAddOptions(prefix+".address-index", f)
+	BridgeEventsConfigAddOptions(prefix+".bridge-events", f)
+	FilteredTracerConfigAddOptions(prefix+".filtered-tracer", f)
+	SendRawTransactionSyncConfigAddOptions(prefix+".send-raw-transaction-sync", f)
+	PersistentFiltersConfigAddOptions(prefix+".persistent-filters", f)
 	f.Uint64(prefix+".tx-lookup-limit", ConfigDefault.TxLookupLimit, "retain the ability to lookup transactions by hash for the past N blocks (0 = all blocks)")
 	f.Bool(prefix+".enable-prefetch-block", ConfigDefault.EnablePrefetchBlock, "enable prefetching of blocks")
+	f.Bool(prefix+".enable-txpool-api", ConfigDefault.EnableTxPoolAPI, "serve the transactions waiting to be sequenced or forwarded through the txpool namespace")
 }
 
 var ConfigDefault = Config{
@@ -91,6 +127,15 @@ var ConfigDefault = Config{
 	Caching:                   DefaultCachingConfig,
 	Forwarder:                 DefaultNodeForwarderConfig,
 	EnablePrefetchBlock:       true,
+	EnableTxPoolAPI:           true,
+	Retention:                 DefaultRetentionConfig,
+	StateReader:               DefaultStateReaderConfig,
+	ConsistentReads:           DefaultConsistentReadsConfig,
+	AddressIndex:              DefaultAddressIndexConfig,
+	BridgeEvents:              DefaultBridgeEventsConfig,
+	FilteredTracer:            DefaultFilteredTracerConfig,
+	SendRawTransactionSync:    DefaultSendRawTransactionSyncConfig,
+	PersistentFilters:         DefaultPersistentFiltersConfig,
 }
 
 type ConfigFetcher func() *Config
@@ -108,7 +153,13 @@ type ExecutionNode struct {
 	SyncMonitor       *SyncMonitor
 	ParentChainReader *headerreader.HeaderReader
 	ClassicOutbox     *ClassicOutboxRetriever
-	started           atomic.Bool
+	Retention         *RetentionManager
+	AddressIndex      *AddressIndex
+	BridgeEvents      *BridgeEventWatcher
+	PersistentFilters *PersistentFilters
+	// serves the node's eth namespace, for APIs registered after the node's to extend its methods
+	EthClient *rpc.Client
+	started   atomic.Bool
 }
 
 func CreateExecutionNode(
@@ -127,6 +178,9 @@ func CreateExecutionNode(
 	if err != nil {
 		return nil, err
 	}
+	if config.Caching.MessageResultCache > 0 {
+		execEngine.EnableResultCache(config.Caching.MessageResultCache)
+	}
 	recorder := NewBlockRecorder(&config.RecordingDatabase, execEngine, chainDB)
 	var txPublisher TransactionPublisher
 	var sequencer *Sequencer
@@ -150,7 +204,7 @@ func CreateExecutionNode(
 		}
 		txPublisher = sequencer
 	} else {
-		if config.Forwarder.RedisUrl != "" {
+		if config.Forwarder.RedisUrl != "" || config.Forwarder.Etcd.Enabled() {
 			txPublisher = NewRedisTxForwarder(config.forwardingTarget, &config.Forwarder)
 		} else if config.forwardingTarget == "" {
 			txPublisher = NewTxDropper()
@@ -167,6 +221,11 @@ func CreateExecutionNode(
 	if err != nil {
 		return nil, err
 	}
+	var txPoolTracker *TxPoolTracker
+	if config.EnableTxPoolAPI {
+		txPoolTracker = NewTxPoolTracker(l2BlockChain.Config())
+		arbInterface.SetTxPoolTracker(txPoolTracker)
+	}
 	filterConfig := filters.Config{
 		LogCacheSize: config.RPC.FilterLogCacheSize,
 		Timeout:      config.RPC.FilterTimeout,
@@ -190,12 +249,56 @@ func CreateExecutionNode(
 		}
 	}
 
+	var retention *RetentionManager
+	if config.Retention.Enable {
+		store, err := NewArchiveStore(&config.Retention.Store)
+		if err != nil {
+			return nil, err
+		}
+		retention, err = NewRetentionManager(chainDB, store, func() *RetentionConfig { return &configFetcher().Retention })
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var addressIndex *AddressIndex
+	if config.AddressIndex.Enable {
+		addressIndex, err = NewAddressIndex(chainDB, l2BlockChain, l2BlockChain.Config(), func() *AddressIndexConfig { return &configFetcher().AddressIndex })
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	setFilteredTracerConfig(func() *FilteredTracerConfig { return &configFetcher().FilteredTracer })
+
+	var bridgeEvents *BridgeEventWatcher
+	if config.BridgeEvents.Enable {
+		bridgeEvents, err = NewBridgeEventWatcher(chainDB, l2BlockChain, func() *BridgeEventsConfig { return &configFetcher().BridgeEvents })
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var persistentFilters *PersistentFilters
+	if config.PersistentFilters.Enable {
+		persistentFilters, err = NewPersistentFilters(chainDB, l2BlockChain, FilterSystemLogs(filterSystem), func() *PersistentFiltersConfig { return &configFetcher().PersistentFilters })
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	apis := []rpc.API{{
 		Namespace: "arb",
 		Version:   "1.0",
 		Service:   NewArbAPI(txPublisher),
 		Public:    false,
 	}}
+	apis = append(apis, rpc.API{
+		Namespace: "arb",
+		Version:   "1.0",
+		Service:   NewFeeTokenAPI(l2BlockChain, chainDB),
+		Public:    false,
+	})
 	apis = append(apis, rpc.API{
 		Namespace: "arbdebug",
 		Version:   "1.0",
@@ -221,8 +324,110 @@ func CreateExecutionNode(
 		Public:    false,
 	})
 
+	apis = append(apis, rpc.API{
+		Namespace: "arb",
+		Version:   "1.0",
+		Service:   NewRetryableRedeemAPI(l2BlockChain, config.RPC.RPCGasCap),
+		Public:    false,
+	})
+
+	if txPoolTracker != nil {
+		// registered after the backend's APIs, so these take over geth's txpool namespace
+		apis = append(apis, rpc.API{
+			Namespace: "txpool",
+			Version:   "1.0",
+			Service:   NewTxPoolAPI(txPoolTracker, l2BlockChain),
+			Public:    true,
+		})
+	}
+	gethEth, err := gethEthClient(backend, filterSystem)
+	if err != nil {
+		return nil, err
+	}
+	if retention != nil {
+		// registered after the backend's APIs, so pruned blocks are read from the archive
+		archiveBlocks := NewArchiveBlockAPI(retention, l2BlockChain.Config(), gethEth)
+		apis = append(apis, rpc.API{
+			Namespace: "eth",
+			Version:   "1.0",
+			Service:   archiveBlocks,
+			Public:    true,
+		})
+		// and the block reads other APIs forward to geth fall back to the archive too
+		gethEth, err = gethEthClient(backend, filterSystem, archiveBlocks)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.ConsistentReads.Enable || config.StateReader.Enable {
+		stateReader := NewStateReader(backend.APIBackend(), l2BlockChain.Snapshots(), l2BlockChain.StateCache(), &config.StateReader)
+		stateCaller := NewStateCaller(stateReader, backend.APIBackend(), l2BlockChain, config.RPC.RPCGasCap, config.RPC.RPCEVMTimeout)
+		// registered after the backend's APIs, so these take over geth's eth namespace state reads. Consistent
+		// reads are served through the state reader whether or not it's enabled on its own.
+		service := interface{}(NewStateReaderAPI(stateReader, stateCaller, gethEth))
+		if config.ConsistentReads.Enable {
+			service = NewConsistentReadAPI(stateReader, stateCaller, syncMon.PublishedBlockNumber, gethEth)
+		}
+		apis = append(apis, rpc.API{
+			Namespace: "eth",
+			Version:   "1.0",
+			Service:   service,
+			Public:    true,
+		})
+	}
+	if config.SendRawTransactionSync.Enable {
+		apis = append(apis, rpc.API{
+			Namespace: "eth",
+			Version:   "1.0",
+			Service:   NewSendRawTransactionSyncAPI(txPublisher, l2BlockChain, chainDB, &config.SendRawTransactionSync),
+			Public:    true,
+		})
+	}
+	if retention != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "arb",
+			Version:   "1.0",
+			Service:   NewRetentionAPI(retention),
+			Public:    false,
+		})
+	}
+	if addressIndex != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "arb",
+			Version:   "1.0",
+			Service:   NewAddressIndexAPI(addressIndex),
+			Public:    false,
+		})
+	}
+	if persistentFilters != nil {
+		// registered after the backend's APIs, so these take over geth's eth namespace polling filters
+		apis = append(apis, rpc.API{
+			Namespace: "eth",
+			Version:   "1.0",
+			Service:   NewPersistentFilterAPI(persistentFilters, filterSystem),
+			Public:    true,
+		})
+		apis = append(apis, rpc.API{
+			Namespace: "arb",
+			Version:   "1.0",
+			Service:   NewPersistentFilterArbAPI(persistentFilters),
+			Public:    false,
+		})
+	}
+
 	stack.RegisterAPIs(apis)
 
+	var ethOverrides []interface{}
+	for _, api := range apis {
+		if api.Namespace == "eth" {
+			ethOverrides = append(ethOverrides, api.Service)
+		}
+	}
+	ethClient, err := gethEthClient(backend, filterSystem, ethOverrides...)
+	if err != nil {
+		return nil, err
+	}
+
 	return &ExecutionNode{
 		ChainDB:           chainDB,
 		Backend:           backend,
@@ -236,6 +441,11 @@ func CreateExecutionNode(
 		SyncMonitor:       syncMon,
 		ParentChainReader: parentChainReader,
 		ClassicOutbox:     classicOutbox,
+		Retention:         retention,
+		AddressIndex:      addressIndex,
+		BridgeEvents:      bridgeEvents,
+		PersistentFilters: persistentFilters,
+		EthClient:         ethClient,
 	}, nil
 
 }
@@ -280,6 +490,18 @@ func (n *ExecutionNode) Start(ctx context.Context) error {
 	if n.ParentChainReader != nil {
 		n.ParentChainReader.Start(ctx)
 	}
+	if n.Retention != nil {
+		n.Retention.Start(ctx)
+	}
+	if n.AddressIndex != nil {
+		n.AddressIndex.Start(ctx)
+	}
+	if n.BridgeEvents != nil {
+		n.BridgeEvents.Start(ctx)
+	}
+	if n.PersistentFilters != nil {
+		n.PersistentFilters.Start(ctx)
+	}
 	return nil
 }
 
@@ -293,6 +515,18 @@ func (n *ExecutionNode) StopAndWait() {
 		n.TxPublisher.StopAndWait()
 	}
 	n.Recorder.OrderlyShutdown()
+	if n.Retention != nil && n.Retention.Started() {
+		n.Retention.StopAndWait()
+	}
+	if n.AddressIndex != nil && n.AddressIndex.Started() {
+		n.AddressIndex.StopAndWait()
+	}
+	if n.BridgeEvents != nil && n.BridgeEvents.Started() {
+		n.BridgeEvents.StopAndWait()
+	}
+	if n.PersistentFilters != nil && n.PersistentFilters.Started() {
+		n.PersistentFilters.StopAndWait()
+	}
 	if n.ParentChainReader != nil && n.ParentChainReader.Started() {
 		n.ParentChainReader.StopAndWait()
 	}
@@ -388,3 +622,24 @@ func (n *ExecutionNode) Synced() bool {
 func (n *ExecutionNode) FullSyncProgressMap() map[string]interface{} {
 	return n.SyncMonitor.FullSyncProgressMap()
 }
+
+// gethEthClient returns a client of a separate server with only geth's own eth API, for APIs taking over eth
+// namespace methods to forward the calls they don't serve themselves, which the node's server would route
+// back to them. The given overrides are registered on top of geth's API.
+func gethEthClient(backend *arbitrum.Backend, filterSystem *filters.FilterSystem, overrides ...interface{}) (*rpc.Client, error) {
+	server := rpc.NewServer()
+	for _, api := range backend.APIBackend().GetAPIs(filterSystem) {
+		if api.Namespace != "eth" {
+			continue
+		}
+		if err := server.RegisterName(api.Namespace, api.Service); err != nil {
+			return nil, err
+		}
+	}
+	for _, service := range overrides {
+		if err := server.RegisterName("eth", service); err != nil {
+			return nil, err
+		}
+	}
+	return rpc.DialInProc(server), nil
+}
@@ -0,0 +1,455 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+var (
+	persistentFiltersGauge          = metrics.NewRegisteredGauge("arb/filters/persistent/active", nil)
+	persistentFiltersExpiredCounter = metrics.NewRegisteredCounter("arb/filters/persistent/expired", nil)
+)
+
+var persistentFilterPrefix = []byte("nitro-filter-")
+
+const persistentFilterPruneInterval = time.Minute
+
+type PersistentFiltersConfig struct {
+	Enable           bool          `koanf:"enable"`
+	TTL              time.Duration `koanf:"ttl" reload:"hot"`
+	MaxFilters       int           `koanf:"max-filters" reload:"hot"`
+	MaxBlocksPerPoll uint64        `koanf:"max-blocks-per-poll" reload:"hot"`
+}
+
+type PersistentFiltersConfigFetcher func() *PersistentFiltersConfig
+
+var DefaultPersistentFiltersConfig = PersistentFiltersConfig{
+	Enable:           false,
+	TTL:              24 * time.Hour,
+	MaxFilters:       10_000,
+	MaxBlocksPerPoll: 100_000,
+}
+
+func PersistentFiltersConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultPersistentFiltersConfig.Enable, "keep the filters installed with eth_newFilter and eth_newBlockFilter in the database, so they survive restarts, and list them through arb_activeFilters")
+	f.Duration(prefix+".ttl", DefaultPersistentFiltersConfig.TTL, "uninstall filters that haven't been polled for this long")
+	f.Int(prefix+".max-filters", DefaultPersistentFiltersConfig.MaxFilters, "maximum number of installed filters")
+	f.Uint64(prefix+".max-blocks-per-poll", DefaultPersistentFiltersConfig.MaxBlocksPerPoll, "maximum number of blocks whose changes are returned by a single eth_getFilterChanges call, the rest are returned by the following calls")
+}
+
+func (c *PersistentFiltersConfig) Validate() error {
+	if c.Enable && (c.TTL <= 0 || c.MaxFilters <= 0 || c.MaxBlocksPerPoll == 0) {
+		return errors.New("persistent filters ttl, max filters and max blocks per poll must be positive")
+	}
+	return nil
+}
+
+type persistentFilterKind string
+
+const (
+	persistentLogsFilter   persistentFilterKind = "logs"
+	persistentBlocksFilter persistentFilterKind = "blocks"
+)
+
+type persistentFilter struct {
+	Kind persistentFilterKind `json:"kind"`
+	// the criteria of logs filters, with block numbers as rpc.BlockNumber values
+	FromBlock int64            `json:"fromBlock"`
+	ToBlock   int64            `json:"toBlock"`
+	Addresses []common.Address `json:"addresses,omitempty"`
+	Topics    [][]common.Hash  `json:"topics,omitempty"`
+	// the last block whose changes were returned, and its hash
+	Cursor     uint64      `json:"cursor"`
+	CursorHash common.Hash `json:"cursorHash"`
+	// unix time of the filter's installation or last poll, which its expiry is measured from
+	LastPolled int64 `json:"lastPolled"`
+}
+
+type persistentFiltersChain interface {
+	CurrentBlock() *types.Header
+}
+
+// PersistentFilterLogsFunc returns the logs matching the criteria in the block range.
+type PersistentFilterLogsFunc func(ctx context.Context, begin, end int64, addresses []common.Address, topics [][]common.Hash) ([]*types.Log, error)
+
+// PersistentFilters keeps polling filters in the database, so that integrations polling them for
+// changes don't lose them when the node restarts. Each filter records the last block whose changes
+// were returned, and the next poll returns the changes in the blocks after it.
+// Unlike geth's filters, changes are computed from the chain when polled rather than collected as
+// blocks are added. When blocks a filter returned are reorged out, the next poll returns the changes of the
+// blocks that replaced them, but the logs of the reorged out blocks aren't returned as removed.
+type PersistentFilters struct {
+	stopwaiter.StopWaiter
+	db     ethdb.Database
+	chain  persistentFiltersChain
+	logs   PersistentFilterLogsFunc
+	config PersistentFiltersConfigFetcher
+
+	mutex   sync.Mutex
+	filters map[rpc.ID]*persistentFilter
+}
+
+func NewPersistentFilters(db ethdb.Database, chain persistentFiltersChain, logs PersistentFilterLogsFunc, config PersistentFiltersConfigFetcher) (*PersistentFilters, error) {
+	installed := make(map[rpc.ID]*persistentFilter)
+	iter := db.NewIterator(persistentFilterPrefix, nil)
+	defer iter.Release()
+	for iter.Next() {
+		filter := &persistentFilter{}
+		if err := json.Unmarshal(iter.Value(), filter); err != nil {
+			return nil, fmt.Errorf("failed to decode persistent filter %s: %w", iter.Key(), err)
+		}
+		installed[rpc.ID(iter.Key()[len(persistentFilterPrefix):])] = filter
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	persistentFiltersGauge.Update(int64(len(installed)))
+	return &PersistentFilters{
+		db:      db,
+		chain:   chain,
+		logs:    logs,
+		config:  config,
+		filters: installed,
+	}, nil
+}
+
+// FilterSystemLogs retrieves logs through geth's filter system.
+func FilterSystemLogs(filterSystem *filters.FilterSystem) PersistentFilterLogsFunc {
+	return func(ctx context.Context, begin, end int64, addresses []common.Address, topics [][]common.Hash) ([]*types.Log, error) {
+		return filterSystem.NewRangeFilter(begin, end, addresses, topics).Logs(ctx)
+	}
+}
+
+func (p *PersistentFilters) Start(ctxIn context.Context) {
+	p.StopWaiter.Start(ctxIn, p)
+	p.CallIteratively(func(ctx context.Context) time.Duration {
+		if err := p.prune(time.Now()); err != nil {
+			log.Error("failed to prune persistent filters", "err", err)
+		}
+		return persistentFilterPruneInterval
+	})
+}
+
+func persistentFilterKey(id rpc.ID) []byte {
+	return append(append([]byte{}, persistentFilterPrefix...), id...)
+}
+
+// write must be called with the mutex held.
+func (p *PersistentFilters) write(id rpc.ID, filter *persistentFilter) error {
+	encoded, err := json.Marshal(filter)
+	if err != nil {
+		return err
+	}
+	return p.db.Put(persistentFilterKey(id), encoded)
+}
+
+// prune uninstalls the filters that weren't polled within the ttl.
+func (p *PersistentFilters) prune(now time.Time) error {
+	cutoff := now.Add(-p.config().TTL).Unix()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	batch := p.db.NewBatch()
+	var expired []rpc.ID
+	for id, filter := range p.filters {
+		if filter.LastPolled < cutoff {
+			if err := batch.Delete(persistentFilterKey(id)); err != nil {
+				return err
+			}
+			expired = append(expired, id)
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	for _, id := range expired {
+		delete(p.filters, id)
+	}
+	persistentFiltersExpiredCounter.Inc(int64(len(expired)))
+	persistentFiltersGauge.Update(int64(len(p.filters)))
+	return nil
+}
+
+func (p *PersistentFilters) install(filter *persistentFilter) (rpc.ID, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if len(p.filters) >= p.config().MaxFilters {
+		return "", errors.New("too many filters installed")
+	}
+	head := p.chain.CurrentBlock()
+	filter.Cursor, filter.CursorHash = head.Number.Uint64(), head.Hash()
+	filter.LastPolled = time.Now().Unix()
+	id := rpc.NewID()
+	if err := p.write(id, filter); err != nil {
+		return "", err
+	}
+	p.filters[id] = filter
+	persistentFiltersGauge.Update(int64(len(p.filters)))
+	return id, nil
+}
+
+func (p *PersistentFilters) NewLogsFilter(crit filters.FilterCriteria) (rpc.ID, error) {
+	if crit.BlockHash != nil {
+		return "", errors.New("filters by block hash can't be installed, use eth_getLogs instead")
+	}
+	if len(crit.Topics) > 4 {
+		return "", errors.New("filter has more than 4 topics")
+	}
+	filter := &persistentFilter{
+		Kind:      persistentLogsFilter,
+		FromBlock: rpc.LatestBlockNumber.Int64(),
+		ToBlock:   rpc.LatestBlockNumber.Int64(),
+		Addresses: crit.Addresses,
+		Topics:    crit.Topics,
+	}
+	if crit.FromBlock != nil {
+		filter.FromBlock = crit.FromBlock.Int64()
+	}
+	if crit.ToBlock != nil {
+		filter.ToBlock = crit.ToBlock.Int64()
+	}
+	return p.install(filter)
+}
+
+func (p *PersistentFilters) NewBlocksFilter() (rpc.ID, error) {
+	return p.install(&persistentFilter{Kind: persistentBlocksFilter})
+}
+
+func (p *PersistentFilters) Uninstall(id rpc.ID) (bool, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if _, ok := p.filters[id]; !ok {
+		return false, nil
+	}
+	if err := p.db.Delete(persistentFilterKey(id)); err != nil {
+		return false, err
+	}
+	delete(p.filters, id)
+	persistentFiltersGauge.Update(int64(len(p.filters)))
+	return true, nil
+}
+
+// rewindToCanonical returns the last block the filter returned whose hash is still canonical, walking back
+// the chain the filter followed, so that the blocks which replaced the rest in a reorg have their changes
+// returned too. Filters persisted before the cursor's hash was recorded are assumed to be on the canonical chain.
+func (p *PersistentFilters) rewindToCanonical(cursor uint64, hash common.Hash) uint64 {
+	if hash == (common.Hash{}) {
+		return cursor
+	}
+	limit := p.config().MaxBlocksPerPoll
+	for steps := uint64(0); cursor > 0 && rawdb.ReadCanonicalHash(p.db, cursor) != hash; steps++ {
+		header := rawdb.ReadHeader(p.db, hash, cursor)
+		if header == nil || steps >= limit {
+			// the reorged chain is unknown from here on, so this block was replaced at the latest
+			return cursor - 1
+		}
+		hash = header.ParentHash
+		cursor--
+	}
+	return cursor
+}
+
+var errPolledConcurrently = errors.New("filter was polled concurrently, poll it again")
+
+// Changes returns the changes since the filter's last poll, or false if it isn't installed.
+// The mutex isn't held while the changes are read, so that polling a filter across many blocks doesn't block
+// the others; if the filter is polled again meanwhile, only the first poll to finish moves its cursor.
+func (p *PersistentFilters) Changes(ctx context.Context, id rpc.ID) (interface{}, bool, error) {
+	p.mutex.Lock()
+	filter, ok := p.filters[id]
+	p.mutex.Unlock()
+	if !ok {
+		return nil, false, nil
+	}
+	updated := *filter
+	updated.LastPolled = time.Now().Unix()
+	cursor := p.rewindToCanonical(filter.Cursor, filter.CursorHash)
+	if cursor != filter.Cursor {
+		log.Info("persistent filter's blocks were reorged, returning the changes of their replacements", "id", id, "cursor", filter.Cursor, "rewoundTo", cursor)
+	}
+	head := p.chain.CurrentBlock().Number.Uint64()
+	if head > cursor+p.config().MaxBlocksPerPoll {
+		head = cursor + p.config().MaxBlocksPerPoll
+	}
+	if head < cursor {
+		head = cursor
+	}
+	var changes interface{}
+	switch filter.Kind {
+	case persistentBlocksFilter:
+		hashes := []common.Hash{}
+		for number := cursor + 1; number <= head; number++ {
+			hashes = append(hashes, rawdb.ReadCanonicalHash(p.db, number))
+		}
+		changes = hashes
+	case persistentLogsFilter:
+		logs := []*types.Log{}
+		begin, end := int64(cursor+1), int64(head)
+		if filter.FromBlock >= 0 && filter.FromBlock > begin {
+			begin = filter.FromBlock
+		}
+		if filter.ToBlock >= 0 && filter.ToBlock < end {
+			end = filter.ToBlock
+		}
+		if begin <= end {
+			found, err := p.logs(ctx, begin, end, filter.Addresses, filter.Topics)
+			if err != nil {
+				return nil, true, err
+			}
+			logs = append(logs, found...)
+		}
+		changes = logs
+	default:
+		return nil, true, fmt.Errorf("unknown filter kind %v", filter.Kind)
+	}
+	updated.Cursor = head
+	updated.CursorHash = rawdb.ReadCanonicalHash(p.db, head)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if current, ok := p.filters[id]; !ok {
+		return nil, false, nil
+	} else if current != filter {
+		return nil, true, errPolledConcurrently
+	}
+	if err := p.write(id, &updated); err != nil {
+		return nil, true, err
+	}
+	p.filters[id] = &updated
+	return changes, true, nil
+}
+
+// FilterLogs returns all the logs matching a logs filter, or false if it isn't installed.
+func (p *PersistentFilters) FilterLogs(ctx context.Context, id rpc.ID) ([]*types.Log, bool, error) {
+	p.mutex.Lock()
+	filter, ok := p.filters[id]
+	p.mutex.Unlock()
+	if !ok {
+		return nil, false, nil
+	}
+	if filter.Kind != persistentLogsFilter {
+		return nil, true, errors.New("filter is not a logs filter")
+	}
+	logs, err := p.logs(ctx, filter.FromBlock, filter.ToBlock, filter.Addresses, filter.Topics)
+	return logs, true, err
+}
+
+type ActiveFilter struct {
+	ID        rpc.ID               `json:"id"`
+	Kind      persistentFilterKind `json:"kind"`
+	FromBlock *rpc.BlockNumber     `json:"fromBlock,omitempty"`
+	ToBlock   *rpc.BlockNumber     `json:"toBlock,omitempty"`
+	Addresses []common.Address     `json:"addresses,omitempty"`
+	Topics    [][]common.Hash      `json:"topics,omitempty"`
+	// the last block whose changes were returned
+	LastBlock hexutil.Uint64 `json:"lastBlock"`
+	// unix time after which the filter is uninstalled unless it's polled
+	Expires hexutil.Uint64 `json:"expires"`
+}
+
+func (p *PersistentFilters) Active() []ActiveFilter {
+	ttl := int64(p.config().TTL / time.Second)
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	active := make([]ActiveFilter, 0, len(p.filters))
+	for id, filter := range p.filters {
+		entry := ActiveFilter{
+			ID:        id,
+			Kind:      filter.Kind,
+			Addresses: filter.Addresses,
+			Topics:    filter.Topics,
+			LastBlock: hexutil.Uint64(filter.Cursor),
+			Expires:   hexutil.Uint64(filter.LastPolled + ttl),
+		}
+		if filter.Kind == persistentLogsFilter {
+			from, to := rpc.BlockNumber(filter.FromBlock), rpc.BlockNumber(filter.ToBlock)
+			entry.FromBlock, entry.ToBlock = &from, &to
+		}
+		active = append(active, entry)
+	}
+	return active
+}
+
+// PersistentFilterAPI takes over geth's polling filter methods in the eth namespace.
+// Pending transaction filters, which can't outlive the node anyway, are still served by geth's filters.
+type PersistentFilterAPI struct {
+	filters *PersistentFilters
+	geth    *filters.FilterAPI
+}
+
+func NewPersistentFilterAPI(persistentFilters *PersistentFilters, filterSystem *filters.FilterSystem) *PersistentFilterAPI {
+	return &PersistentFilterAPI{
+		filters: persistentFilters,
+		geth:    filters.NewFilterAPI(filterSystem, false),
+	}
+}
+
+func (api *PersistentFilterAPI) NewFilter(crit filters.FilterCriteria) (rpc.ID, error) {
+	return api.filters.NewLogsFilter(crit)
+}
+
+func (api *PersistentFilterAPI) NewBlockFilter() (rpc.ID, error) {
+	return api.filters.NewBlocksFilter()
+}
+
+// NewPendingTransactionFilter is served by geth, through the same instance polled for it below.
+func (api *PersistentFilterAPI) NewPendingTransactionFilter(fullTx *bool) rpc.ID {
+	return api.geth.NewPendingTransactionFilter(fullTx)
+}
+
+func (api *PersistentFilterAPI) GetFilterChanges(ctx context.Context, id rpc.ID) (interface{}, error) {
+	changes, found, err := api.filters.Changes(ctx, id)
+	if found {
+		return changes, err
+	}
+	return api.geth.GetFilterChanges(id)
+}
+
+func (api *PersistentFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*types.Log, error) {
+	logs, found, err := api.filters.FilterLogs(ctx, id)
+	if found {
+		return logs, err
+	}
+	return api.geth.GetFilterLogs(ctx, id)
+}
+
+func (api *PersistentFilterAPI) UninstallFilter(id rpc.ID) (bool, error) {
+	uninstalled, err := api.filters.Uninstall(id)
+	if uninstalled || err != nil {
+		return uninstalled, err
+	}
+	return api.geth.UninstallFilter(id), nil
+}
+
+type PersistentFilterArbAPI struct {
+	filters *PersistentFilters
+}
+
+func NewPersistentFilterArbAPI(persistentFilters *PersistentFilters) *PersistentFilterArbAPI {
+	return &PersistentFilterArbAPI{persistentFilters}
+}
+
+// ActiveFilters lists the installed logs and block filters.
+func (api *PersistentFilterArbAPI) ActiveFilters() []ActiveFilter {
+	return api.filters.Active()
+}
@@ -0,0 +1,254 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestPersistentFilters(t *testing.T) {
+	ctx := context.Background()
+	db := rawdb.NewMemoryDatabase()
+	chain := &testIndexChain{db: db, blocks: make(map[common.Hash]*types.Block)}
+	head := chain.addBlock(nil, nil)
+	head = chain.addBlock(head, nil)
+
+	type logsQuery struct {
+		begin, end int64
+		addresses  []common.Address
+	}
+	var queries []logsQuery
+	logs := func(ctx context.Context, begin, end int64, addresses []common.Address, topics [][]common.Hash) ([]*types.Log, error) {
+		queries = append(queries, logsQuery{begin, end, addresses})
+		return []*types.Log{{BlockNumber: uint64(end)}}, nil
+	}
+	config := DefaultPersistentFiltersConfig
+	config.Enable = true
+	config.MaxFilters = 3
+	config.MaxBlocksPerPoll = 2
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	open := func() *PersistentFilters {
+		t.Helper()
+		persistentFilters, err := NewPersistentFilters(db, chain, logs, func() *PersistentFiltersConfig { return &config })
+		if err != nil {
+			t.Fatal(err)
+		}
+		return persistentFilters
+	}
+	persistentFilters := open()
+
+	blocksFilter, err := persistentFilters.NewBlocksFilter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := common.HexToAddress("0x1234")
+	logsFilter, err := persistentFilters.NewLogsFilter(filters.FilterCriteria{Addresses: []common.Address{addr}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	toBlock := big.NewInt(4)
+	boundedFilter, err := persistentFilters.NewLogsFilter(filters.FilterCriteria{ToBlock: toBlock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := persistentFilters.NewBlocksFilter(); err == nil {
+		t.Fatal("installed more than the maximum number of filters")
+	}
+
+	var hashes []common.Hash
+	for i := 0; i < 3; i++ {
+		head = chain.addBlock(head, nil)
+		hashes = append(hashes, head.Hash())
+	}
+
+	// the filters survive reopening
+	persistentFilters = open()
+	if active := persistentFilters.Active(); len(active) != 3 {
+		t.Fatalf("expected 3 active filters, got %v", active)
+	}
+
+	changes := func(id rpc.ID) interface{} {
+		t.Helper()
+		result, found, err := persistentFilters.Changes(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !found {
+			t.Fatal("filter not found", id)
+		}
+		return result
+	}
+	checkHashes := func(expected []common.Hash) {
+		t.Helper()
+		result := changes(blocksFilter).([]common.Hash)
+		if len(result) != len(expected) {
+			t.Fatalf("expected block hashes %v, got %v", expected, result)
+		}
+		for i := range result {
+			if result[i] != expected[i] {
+				t.Fatalf("expected block hashes %v, got %v", expected, result)
+			}
+		}
+	}
+	// changes are returned at most two blocks at a time
+	checkHashes(hashes[:2])
+	checkHashes(hashes[2:])
+	checkHashes(nil)
+
+	changes(logsFilter)
+	changes(logsFilter)
+	chain.addBlock(head, nil)
+	changes(boundedFilter)
+	changes(boundedFilter)
+	// past its to block, the bounded filter has no more changes to look for
+	changes(boundedFilter)
+	if len(queries) != 4 {
+		t.Fatalf("expected 4 log queries, got %v", queries)
+	}
+	if queries[0].begin != 2 || queries[0].end != 3 || len(queries[0].addresses) != 1 || queries[0].addresses[0] != addr {
+		t.Fatalf("unexpected first logs query %v", queries[0])
+	}
+	if queries[1].begin != 4 || queries[1].end != 4 {
+		t.Fatalf("unexpected second logs query %v", queries[1])
+	}
+	if queries[2].begin != 2 || queries[2].end != 3 || queries[3].begin != 4 || queries[3].end != 4 {
+		t.Fatalf("unexpected bounded filter logs queries %v", queries[2:])
+	}
+
+	uninstalled, err := persistentFilters.Uninstall(logsFilter)
+	if err != nil || !uninstalled {
+		t.Fatal("failed to uninstall filter", err)
+	}
+	if _, found, _ := persistentFilters.Changes(ctx, logsFilter); found {
+		t.Fatal("uninstalled filter found")
+	}
+
+	// filters not polled within the ttl expire
+	if err := persistentFilters.prune(time.Now().Add(config.TTL + time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if active := open().Active(); len(active) != 0 {
+		t.Fatalf("expected expired filters uninstalled, got %v", active)
+	}
+}
+
+func TestPersistentFiltersReorg(t *testing.T) {
+	ctx := context.Background()
+	db := rawdb.NewMemoryDatabase()
+	chain := &testIndexChain{db: db, blocks: make(map[common.Hash]*types.Block)}
+	genesis := chain.addBlock(nil, nil)
+	var queries [][2]int64
+	logs := func(ctx context.Context, begin, end int64, addresses []common.Address, topics [][]common.Hash) ([]*types.Log, error) {
+		queries = append(queries, [2]int64{begin, end})
+		return nil, nil
+	}
+	config := DefaultPersistentFiltersConfig
+	config.Enable = true
+	persistentFilters, err := NewPersistentFilters(db, chain, logs, func() *PersistentFiltersConfig { return &config })
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocksFilter, err := persistentFilters.NewBlocksFilter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logsFilter, err := persistentFilters.NewLogsFilter(filters.FilterCriteria{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := func(id rpc.ID) interface{} {
+		t.Helper()
+		result, found, err := persistentFilters.Changes(ctx, id)
+		if err != nil || !found {
+			t.Fatal("failed polling filter", id, found, err)
+		}
+		return result
+	}
+
+	common1 := chain.addBlock(genesis, nil)
+	old := chain.addBlock(chain.addBlock(common1, nil), nil)
+	if hashes := changes(blocksFilter).([]common.Hash); len(hashes) != 3 || hashes[2] != old.Hash() {
+		t.Fatal("unexpected block hashes", hashes)
+	}
+	changes(logsFilter)
+
+	// the blocks after the first are replaced by a longer fork, whose changes are all returned
+	replaced := chain.addBlock(common1, nil)
+	fork := []common.Hash{replaced.Hash()}
+	for i := 0; i < 2; i++ {
+		replaced = chain.addBlock(replaced, nil)
+		fork = append(fork, replaced.Hash())
+	}
+	hashes := changes(blocksFilter).([]common.Hash)
+	if len(hashes) != len(fork) {
+		t.Fatalf("expected the fork's block hashes %v, got %v", fork, hashes)
+	}
+	for i := range fork {
+		if hashes[i] != fork[i] {
+			t.Fatalf("expected the fork's block hashes %v, got %v", fork, hashes)
+		}
+	}
+	changes(logsFilter)
+	if len(queries) != 2 || queries[0] != [2]int64{1, 3} || queries[1] != [2]int64{2, 4} {
+		t.Fatal("unexpected logs queries", queries)
+	}
+	if hashes := changes(blocksFilter).([]common.Hash); len(hashes) != 0 {
+		t.Fatal("unexpected block hashes after catching up", hashes)
+	}
+}
+
+func TestPersistentFiltersConcurrentPoll(t *testing.T) {
+	ctx := context.Background()
+	db := rawdb.NewMemoryDatabase()
+	chain := &testIndexChain{db: db, blocks: make(map[common.Hash]*types.Block)}
+	head := chain.addBlock(nil, nil)
+	var persistentFilters *PersistentFilters
+	var id rpc.ID
+	polling, otherPolled := false, false
+	logs := func(ctx context.Context, begin, end int64, addresses []common.Address, topics [][]common.Hash) ([]*types.Log, error) {
+		// filters can be installed and polled while a poll reads its logs
+		if !polling {
+			polling = true
+			if _, err := persistentFilters.NewBlocksFilter(); err != nil {
+				return nil, err
+			}
+			_, _, err := persistentFilters.Changes(ctx, id)
+			otherPolled = err == nil
+			return nil, err
+		}
+		return nil, nil
+	}
+	config := DefaultPersistentFiltersConfig
+	config.Enable = true
+	persistentFilters, err := NewPersistentFilters(db, chain, logs, func() *PersistentFiltersConfig { return &config })
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err = persistentFilters.NewLogsFilter(filters.FilterCriteria{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.addBlock(head, nil)
+	if _, _, err := persistentFilters.Changes(ctx, id); !errors.Is(err, errPolledConcurrently) {
+		t.Fatal("expected the slower of two concurrent polls to fail, got", err)
+	}
+	if !otherPolled {
+		t.Fatal("the concurrent poll failed")
+	}
+	if active := persistentFilters.Active(); len(active) != 2 || active[0].LastBlock != 1 && active[1].LastBlock != 1 {
+		t.Fatal("unexpected active filters", active)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/execution"
+	"github.com/offchainlabs/nitro/util/containers"
+)
+
+var (
+	resultCacheHitCounter         = metrics.NewRegisteredCounter("arb/execution/resultcache/hit", nil)
+	resultCacheMissCounter        = metrics.NewRegisteredCounter("arb/execution/resultcache/miss", nil)
+	resultCacheInvalidatedCounter = metrics.NewRegisteredCounter("arb/execution/resultcache/invalidated", nil)
+	resultCacheSizeGauge          = metrics.NewRegisteredGauge("arb/execution/resultcache/size", nil)
+)
+
+// messageResultCache keeps the results of recently executed messages, so
+// repeated lookups of the same recent message don't have to read the header
+// from the database. Entries at or after a reorg point are dropped.
+type messageResultCache struct {
+	mutex      sync.Mutex
+	cache      *containers.LruCache[arbutil.MessageIndex, execution.MessageResult]
+	highestPos arbutil.MessageIndex
+	// generation is bumped on every invalidation, so a lookup that raced with
+	// a reorg doesn't re-add a result from the old chain
+	generation uint64
+}
+
+func newMessageResultCache(size int) *messageResultCache {
+	return &messageResultCache{
+		cache: containers.NewLruCache[arbutil.MessageIndex, execution.MessageResult](size),
+	}
+}
+
+// get returns the cached result for pos, and the generation to pass to add on a miss.
+func (c *messageResultCache) get(pos arbutil.MessageIndex) (*execution.MessageResult, uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	result, ok := c.cache.Get(pos)
+	if !ok {
+		resultCacheMissCounter.Inc(1)
+		return nil, c.generation
+	}
+	resultCacheHitCounter.Inc(1)
+	return &result, c.generation
+}
+
+func (c *messageResultCache) add(pos arbutil.MessageIndex, result *execution.MessageResult, generation uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if generation != c.generation {
+		return
+	}
+	c.cache.Add(pos, *result)
+	if pos > c.highestPos {
+		c.highestPos = pos
+	}
+	resultCacheSizeGauge.Update(int64(c.cache.Len()))
+}
+
+// currentGeneration is used by callers that hold the block creation mutex and so can't race with a reorg.
+func (c *messageResultCache) currentGeneration() uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.generation
+}
+
+// invalidateFrom drops all results at or after pos.
+func (c *messageResultCache) invalidateFrom(pos arbutil.MessageIndex) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.generation++
+	if c.cache.Len() == 0 || pos > c.highestPos {
+		return
+	}
+	before := c.cache.Len()
+	if uint64(c.highestPos-pos) >= uint64(c.cache.Size()) {
+		c.cache.Clear()
+	} else {
+		for i := pos; i <= c.highestPos; i++ {
+			c.cache.Remove(i)
+		}
+	}
+	if pos > 0 {
+		c.highestPos = pos - 1
+	} else {
+		c.highestPos = 0
+	}
+	resultCacheInvalidatedCounter.Inc(int64(before - c.cache.Len()))
+	resultCacheSizeGauge.Update(int64(c.cache.Len()))
+}
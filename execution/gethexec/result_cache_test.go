@@ -0,0 +1,41 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/execution"
+)
+
+func TestMessageResultCacheInvalidation(t *testing.T) {
+	cache := newMessageResultCache(16)
+	for pos := arbutil.MessageIndex(0); pos < 10; pos++ {
+		cache.add(pos, &execution.MessageResult{BlockHash: common.BigToHash(common.Big1)}, cache.currentGeneration())
+	}
+	_, staleGeneration := cache.get(20)
+	cache.invalidateFrom(6)
+	for pos := arbutil.MessageIndex(0); pos < 10; pos++ {
+		result, _ := cache.get(pos)
+		if (result != nil) != (pos < 6) {
+			t.Errorf("unexpected cache presence for pos %d after reorg: %v", pos, result != nil)
+		}
+	}
+	cache.add(20, &execution.MessageResult{}, staleGeneration)
+	if result, _ := cache.get(20); result != nil {
+		t.Error("result looked up before reorg was cached after it")
+	}
+}
+
+func TestMessageResultCacheDisabled(t *testing.T) {
+	cache := newMessageResultCache(0)
+	cache.add(1, &execution.MessageResult{}, cache.currentGeneration())
+	if result, _ := cache.get(1); result != nil {
+		t.Error("disabled cache returned a result")
+	}
+	cache.invalidateFrom(0)
+}
@@ -0,0 +1,521 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+
+	"github.com/offchainlabs/nitro/util/arbmath"
+	"github.com/offchainlabs/nitro/util/containers"
+	"github.com/offchainlabs/nitro/util/dbutil"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+var (
+	retentionUploadedSegmentsCounter = metrics.NewRegisteredCounter("arb/retention/uploaded/segments", nil)
+	retentionUploadedBlocksGauge     = metrics.NewRegisteredGauge("arb/retention/uploaded/blocks", nil)
+	retentionFrozenBlocksGauge       = metrics.NewRegisteredGauge("arb/retention/frozen/blocks", nil)
+	retentionLocalTailGauge          = metrics.NewRegisteredGauge("arb/retention/local/tail", nil)
+	retentionArchiveFetchCounter     = metrics.NewRegisteredCounter("arb/retention/archive/fetch", nil)
+)
+
+var retentionProgressKey = []byte("nitro-retention-progress")
+
+// RetentionConfig configures the tiers chain history is kept in.
+// Recent blocks stay hot in the key-value database until geth's freezer migrates them to the ancients,
+// which it does once they're past its immutability threshold. When enabled, frozen blocks are
+// uploaded in fixed-size segments to an archive store, and may then be pruned from the local freezer,
+// keeping only the most recent local-ancient-blocks of them. Pruned blocks can still be fetched on
+// demand from the archive.
+type RetentionConfig struct {
+	Enable              bool               `koanf:"enable"`
+	CheckInterval       time.Duration      `koanf:"check-interval" reload:"hot"`
+	SegmentSize         uint64             `koanf:"segment-size"`
+	MaxSegmentsPerCheck uint64             `koanf:"max-segments-per-check" reload:"hot"`
+	Prune               bool               `koanf:"prune" reload:"hot"`
+	LocalAncientBlocks  uint64             `koanf:"local-ancient-blocks" reload:"hot"`
+	SegmentCacheSize    int                `koanf:"segment-cache-size"`
+	Store               ArchiveStoreConfig `koanf:"store"`
+}
+
+type RetentionConfigFetcher func() *RetentionConfig
+
+var DefaultRetentionConfig = RetentionConfig{
+	Enable:              false,
+	CheckInterval:       time.Minute,
+	SegmentSize:         10_000,
+	MaxSegmentsPerCheck: 10,
+	Prune:               false,
+	LocalAncientBlocks:  10_000_000,
+	SegmentCacheSize:    4,
+	Store:               DefaultArchiveStoreConfig,
+}
+
+func RetentionConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultRetentionConfig.Enable, "upload frozen chain history to an archive store")
+	f.Duration(prefix+".check-interval", DefaultRetentionConfig.CheckInterval, "how often to check for newly frozen blocks to upload and prune")
+	f.Uint64(prefix+".segment-size", DefaultRetentionConfig.SegmentSize, "number of blocks per archived segment (can't be changed once segments were uploaded)")
+	f.Uint64(prefix+".max-segments-per-check", DefaultRetentionConfig.MaxSegmentsPerCheck, "maximum number of segments to upload per check")
+	f.Bool(prefix+".prune", DefaultRetentionConfig.Prune, "prune uploaded blocks from the local freezer")
+	f.Uint64(prefix+".local-ancient-blocks", DefaultRetentionConfig.LocalAncientBlocks, "number of the most recent frozen blocks to keep locally when pruning")
+	f.Int(prefix+".segment-cache-size", DefaultRetentionConfig.SegmentCacheSize, "number of segments fetched from the archive to keep in memory")
+	ArchiveStoreConfigAddOptions(prefix+".store", f)
+}
+
+func (c *RetentionConfig) Validate() error {
+	if !c.Enable {
+		if c.Prune {
+			return errors.New("retention pruning requires uploading to be enabled")
+		}
+		return nil
+	}
+	if c.SegmentSize == 0 {
+		return errors.New("retention segment size must be positive")
+	}
+	if c.SegmentCacheSize <= 0 {
+		return errors.New("retention segment cache size must be positive")
+	}
+	return c.Store.Validate()
+}
+
+type retentionProgress struct {
+	SegmentSize    uint64
+	UploadedBlocks uint64
+}
+
+// archivedBlock holds a frozen block's items exactly as they're stored in the freezer.
+type archivedBlock struct {
+	Hash       common.Hash
+	Header     []byte
+	Body       []byte
+	Receipts   []byte
+	Difficulty []byte
+}
+
+// RetentionManager uploads frozen chain history to an archive store, prunes it locally once it's
+// uploaded, and serves archived blocks back on demand.
+type RetentionManager struct {
+	stopwaiter.StopWaiter
+	db     ethdb.Database
+	store  ArchiveStore
+	config RetentionConfigFetcher
+
+	// serializes uploads and pruning
+	updateMutex sync.Mutex
+	// guards progress and segments
+	mutex    sync.Mutex
+	progress retentionProgress
+	segments *containers.LruCache[uint64, []archivedBlock]
+}
+
+func NewRetentionManager(db ethdb.Database, store ArchiveStore, config RetentionConfigFetcher) (*RetentionManager, error) {
+	progress := retentionProgress{SegmentSize: config().SegmentSize}
+	data, err := db.Get(retentionProgressKey)
+	if err == nil {
+		if err := rlp.DecodeBytes(data, &progress); err != nil {
+			return nil, fmt.Errorf("failed to decode retention progress: %w", err)
+		}
+	} else if !dbutil.IsErrNotFound(err) {
+		return nil, err
+	}
+	if progress.UploadedBlocks > 0 && progress.SegmentSize != config().SegmentSize {
+		return nil, fmt.Errorf("retention segment size is %v but %v blocks were already uploaded in segments of %v", config().SegmentSize, progress.UploadedBlocks, progress.SegmentSize)
+	}
+	return &RetentionManager{
+		db:       db,
+		store:    store,
+		config:   config,
+		progress: progress,
+		segments: containers.NewLruCache[uint64, []archivedBlock](config().SegmentCacheSize),
+	}, nil
+}
+
+func (m *RetentionManager) Start(ctxIn context.Context) {
+	m.StopWaiter.Start(ctxIn, m)
+	m.CallIteratively(func(ctx context.Context) time.Duration {
+		if err := m.update(ctx); err != nil && ctx.Err() == nil {
+			log.Error("failed to update chain data retention", "err", err)
+		}
+		return m.config().CheckInterval
+	})
+}
+
+func (m *RetentionManager) update(ctx context.Context) error {
+	m.updateMutex.Lock()
+	defer m.updateMutex.Unlock()
+	if err := m.uploadSegments(ctx); err != nil {
+		return err
+	}
+	if m.config().Prune {
+		return m.prune()
+	}
+	return nil
+}
+
+func (m *RetentionManager) uploadedBlocks() uint64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.progress.UploadedBlocks
+}
+
+func segmentKey(start uint64) string {
+	return fmt.Sprintf("segment-%020d.rlp.gz", start)
+}
+
+func (m *RetentionManager) readFrozenBlock(number uint64) (archivedBlock, error) {
+	var block archivedBlock
+	hash, err := m.db.Ancient(rawdb.ChainFreezerHashTable, number)
+	if err != nil {
+		return block, err
+	}
+	block.Hash = common.BytesToHash(hash)
+	if block.Header, err = m.db.Ancient(rawdb.ChainFreezerHeaderTable, number); err != nil {
+		return block, err
+	}
+	if block.Body, err = m.db.Ancient(rawdb.ChainFreezerBodiesTable, number); err != nil {
+		return block, err
+	}
+	if block.Receipts, err = m.db.Ancient(rawdb.ChainFreezerReceiptTable, number); err != nil {
+		return block, err
+	}
+	if block.Difficulty, err = m.db.Ancient(rawdb.ChainFreezerDifficultyTable, number); err != nil {
+		return block, err
+	}
+	return block, nil
+}
+
+func encodeSegment(blocks []archivedBlock) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if err := rlp.Encode(writer, blocks); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeSegment(data []byte) ([]archivedBlock, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	var blocks []archivedBlock
+	if err := rlp.DecodeBytes(decompressed, &blocks); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// uploadSegments uploads every complete segment of frozen blocks that hasn't been uploaded yet.
+func (m *RetentionManager) uploadSegments(ctx context.Context) error {
+	frozen, err := m.db.Ancients()
+	if err != nil {
+		return err
+	}
+	tail, err := m.db.Tail()
+	if err != nil {
+		return err
+	}
+	retentionFrozenBlocksGauge.Update(int64(frozen))
+	retentionLocalTailGauge.Update(int64(tail))
+	config := m.config()
+	segmentSize := m.progress.SegmentSize
+	for i := uint64(0); i < config.MaxSegmentsPerCheck; i++ {
+		start := m.uploadedBlocks()
+		end := start + segmentSize
+		if end > frozen {
+			break
+		}
+		if start < tail {
+			return fmt.Errorf("blocks %v to %v were pruned from the freezer before they were uploaded", start, tail)
+		}
+		blocks := make([]archivedBlock, 0, segmentSize)
+		for number := start; number < end; number++ {
+			block, err := m.readFrozenBlock(number)
+			if err != nil {
+				return fmt.Errorf("failed to read frozen block %v: %w", number, err)
+			}
+			blocks = append(blocks, block)
+		}
+		data, err := encodeSegment(blocks)
+		if err != nil {
+			return err
+		}
+		if err := m.store.Put(ctx, segmentKey(start), data); err != nil {
+			return fmt.Errorf("failed to upload segment starting at block %v to %v: %w", start, m.store, err)
+		}
+		progress := retentionProgress{SegmentSize: segmentSize, UploadedBlocks: end}
+		encoded, err := rlp.EncodeToBytes(progress)
+		if err != nil {
+			return err
+		}
+		if err := m.db.Put(retentionProgressKey, encoded); err != nil {
+			return err
+		}
+		m.mutex.Lock()
+		m.progress = progress
+		m.mutex.Unlock()
+		retentionUploadedSegmentsCounter.Inc(1)
+		retentionUploadedBlocksGauge.Update(int64(end))
+		log.Info("uploaded chain history segment", "start", start, "end", end, "bytes", len(data), "store", m.store)
+	}
+	return nil
+}
+
+// preserveGenesis copies the genesis block back into the key-value store, as geth needs it to open
+// the chain after the freezer's tail no longer includes it.
+func (m *RetentionManager) preserveGenesis() error {
+	hash := rawdb.ReadCanonicalHash(m.db, 0)
+	if hash == (common.Hash{}) {
+		return errors.New("genesis block not found")
+	}
+	block := rawdb.ReadBlock(m.db, hash, 0)
+	if block == nil {
+		return errors.New("genesis block not found")
+	}
+	td := rawdb.ReadTd(m.db, hash, 0)
+	if td == nil {
+		return errors.New("genesis difficulty not found")
+	}
+	receipts := rawdb.ReadRawReceipts(m.db, hash, 0)
+	batch := m.db.NewBatch()
+	rawdb.WriteBlock(batch, block)
+	rawdb.WriteReceipts(batch, hash, 0, receipts)
+	rawdb.WriteTd(batch, hash, 0, td)
+	rawdb.WriteCanonicalHash(batch, hash, 0)
+	return batch.Write()
+}
+
+// prune drops uploaded blocks from the local freezer, keeping the most recent local-ancient-blocks of them.
+func (m *RetentionManager) prune() error {
+	frozen, err := m.db.Ancients()
+	if err != nil {
+		return err
+	}
+	tail, err := m.db.Tail()
+	if err != nil {
+		return err
+	}
+	target := arbmath.MinInt(m.uploadedBlocks(), arbmath.SaturatingUSub(frozen, m.config().LocalAncientBlocks))
+	if target <= tail {
+		return nil
+	}
+	if tail == 0 {
+		if err := m.preserveGenesis(); err != nil {
+			return fmt.Errorf("failed to preserve genesis block before pruning: %w", err)
+		}
+	}
+	if _, err := m.db.TruncateTail(target); err != nil {
+		return err
+	}
+	retentionLocalTailGauge.Update(int64(target))
+	log.Info("pruned archived chain history from the freezer", "oldTail", tail, "newTail", target)
+	return nil
+}
+
+func (m *RetentionManager) fetchSegment(ctx context.Context, start uint64) ([]archivedBlock, error) {
+	m.mutex.Lock()
+	blocks, ok := m.segments.Get(start)
+	m.mutex.Unlock()
+	if ok {
+		return blocks, nil
+	}
+	retentionArchiveFetchCounter.Inc(1)
+	data, err := m.store.Get(ctx, segmentKey(start))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch segment starting at block %v from %v: %w", start, m.store, err)
+	}
+	blocks, err = decodeSegment(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode segment starting at block %v: %w", start, err)
+	}
+	if uint64(len(blocks)) != m.progress.SegmentSize {
+		return nil, fmt.Errorf("segment starting at block %v has %v blocks but expected %v", start, len(blocks), m.progress.SegmentSize)
+	}
+	for i := range blocks {
+		if err := m.verifyArchivedBlock(start+uint64(i), &blocks[i]); err != nil {
+			return nil, fmt.Errorf("rejecting segment starting at block %v: %w", start, err)
+		}
+	}
+	m.mutex.Lock()
+	m.segments.Add(start, blocks)
+	m.mutex.Unlock()
+	return blocks, nil
+}
+
+// verifyArchivedBlock checks a block fetched from the archive store against what the node still has locally, so
+// a corrupted or tampered archive isn't served as the chain. Its hash must map back to its number, as the hash
+// to number mapping outlives pruning, and its body and receipts must match the header's roots.
+func (m *RetentionManager) verifyArchivedBlock(number uint64, block *archivedBlock) error {
+	if stored := rawdb.ReadHeaderNumber(m.db, block.Hash); stored == nil || *stored != number {
+		return fmt.Errorf("archived block %v has hash %v, which isn't the local chain's", number, block.Hash)
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(block.Header, header); err != nil {
+		return fmt.Errorf("failed to decode header of archived block %v: %w", number, err)
+	}
+	if header.Hash() != block.Hash {
+		return fmt.Errorf("archived header of block %v has hash %v but expected %v", number, header.Hash(), block.Hash)
+	}
+	var body types.Body
+	if err := rlp.DecodeBytes(block.Body, &body); err != nil {
+		return fmt.Errorf("failed to decode body of archived block %v: %w", number, err)
+	}
+	if txHash := types.DeriveSha(types.Transactions(body.Transactions), trie.NewStackTrie(nil)); txHash != header.TxHash {
+		return fmt.Errorf("archived transactions of block %v have root %v but the header has %v", number, txHash, header.TxHash)
+	}
+	if uncleHash := types.CalcUncleHash(body.Uncles); uncleHash != header.UncleHash {
+		return fmt.Errorf("archived uncles of block %v have hash %v but the header has %v", number, uncleHash, header.UncleHash)
+	}
+	var storedReceipts []*types.ReceiptForStorage
+	if err := rlp.DecodeBytes(block.Receipts, &storedReceipts); err != nil {
+		return fmt.Errorf("failed to decode receipts of archived block %v: %w", number, err)
+	}
+	if len(storedReceipts) != len(body.Transactions) {
+		return fmt.Errorf("archived block %v has %v receipts for %v transactions", number, len(storedReceipts), len(body.Transactions))
+	}
+	receipts := make(types.Receipts, len(storedReceipts))
+	for i, receipt := range storedReceipts {
+		receipts[i] = (*types.Receipt)(receipt)
+		// the type isn't stored with the receipt, but is part of the receipt root
+		receipts[i].Type = body.Transactions[i].Type()
+	}
+	if receiptHash := types.DeriveSha(receipts, trie.NewStackTrie(nil)); receiptHash != header.ReceiptHash {
+		return fmt.Errorf("archived receipts of block %v have root %v but the header has %v", number, receiptHash, header.ReceiptHash)
+	}
+	return nil
+}
+
+type ArchivedBlock struct {
+	Number hexutil.Uint64 `json:"number"`
+	Hash   common.Hash    `json:"hash"`
+	Header *types.Header  `json:"header"`
+	// the block body and receipts RLP, as stored in the freezer
+	Body     hexutil.Bytes `json:"body"`
+	Receipts hexutil.Bytes `json:"receipts"`
+	// where the block was read from: "freezer" or "archive"
+	Source string `json:"source"`
+}
+
+// ArchivedBlock returns a frozen block, reading it from the local freezer if it's still there
+// and from the archive store if it's been pruned.
+func (m *RetentionManager) ArchivedBlock(ctx context.Context, number uint64) (*ArchivedBlock, error) {
+	frozen, err := m.db.Ancients()
+	if err != nil {
+		return nil, err
+	}
+	tail, err := m.db.Tail()
+	if err != nil {
+		return nil, err
+	}
+	var block archivedBlock
+	var source string
+	if number >= tail && number < frozen {
+		block, err = m.readFrozenBlock(number)
+		if err != nil {
+			return nil, err
+		}
+		source = "freezer"
+	} else if number < m.uploadedBlocks() {
+		segmentSize := m.progress.SegmentSize
+		start := number - number%segmentSize
+		blocks, err := m.fetchSegment(ctx, start)
+		if err != nil {
+			return nil, err
+		}
+		block = blocks[number-start]
+		source = "archive"
+	} else {
+		return nil, fmt.Errorf("block %v isn't frozen yet", number)
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(block.Header, header); err != nil {
+		return nil, fmt.Errorf("failed to decode header of block %v: %w", number, err)
+	}
+	if header.Hash() != block.Hash {
+		return nil, fmt.Errorf("archived header of block %v has hash %v but expected %v", number, header.Hash(), block.Hash)
+	}
+	return &ArchivedBlock{
+		Number:   hexutil.Uint64(number),
+		Hash:     block.Hash,
+		Header:   header,
+		Body:     block.Body,
+		Receipts: block.Receipts,
+		Source:   source,
+	}, nil
+}
+
+// Pruned returns whether the block was pruned from the local freezer, so it can only be read from the archive.
+func (m *RetentionManager) Pruned(number uint64) (bool, error) {
+	tail, err := m.db.Tail()
+	if err != nil {
+		return false, err
+	}
+	return number < tail, nil
+}
+
+// PrunedBlockNumber returns the number of the block with the given hash if it was pruned from the local
+// freezer. The hash to number mapping isn't frozen, so it outlives the pruned block.
+func (m *RetentionManager) PrunedBlockNumber(hash common.Hash) (uint64, bool, error) {
+	number := rawdb.ReadHeaderNumber(m.db, hash)
+	if number == nil {
+		return 0, false, nil
+	}
+	pruned, err := m.Pruned(*number)
+	return *number, pruned, err
+}
+
+type RetentionStatus struct {
+	// blocks before this number are only kept in the freezer or the archive
+	FrozenBlocks hexutil.Uint64 `json:"frozenBlocks"`
+	// blocks before this number have been pruned locally and are only in the archive
+	LocalTail      hexutil.Uint64 `json:"localTail"`
+	UploadedBlocks hexutil.Uint64 `json:"uploadedBlocks"`
+	SegmentSize    hexutil.Uint64 `json:"segmentSize"`
+	Store          string         `json:"store"`
+}
+
+func (m *RetentionManager) Status() (*RetentionStatus, error) {
+	frozen, err := m.db.Ancients()
+	if err != nil {
+		return nil, err
+	}
+	tail, err := m.db.Tail()
+	if err != nil {
+		return nil, err
+	}
+	return &RetentionStatus{
+		FrozenBlocks:   hexutil.Uint64(frozen),
+		LocalTail:      hexutil.Uint64(tail),
+		UploadedBlocks: hexutil.Uint64(m.uploadedBlocks()),
+		SegmentSize:    hexutil.Uint64(m.progress.SegmentSize),
+		Store:          m.store.String(),
+	}, nil
+}
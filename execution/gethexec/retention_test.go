@@ -0,0 +1,265 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// newTestRetention freezes 10 blocks, the third of which has a transaction, and uploads them in segments of
+// 4 blocks, pruning all but the 3 most recent from the freezer.
+func newTestRetention(t *testing.T) (ethdb.Database, []*types.Block, *RetentionManager, ArchiveStore, *RetentionConfig) {
+	t.Helper()
+	ctx := context.Background()
+	db, err := rawdb.NewDatabaseWithFreezer(memorydb.New(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := types.LatestSigner(params.ArbitrumDevTestChainConfig())
+	tx := types.MustSignNewTx(key, signer, &types.LegacyTx{Gas: 21000, GasPrice: common.Big1, To: &common.Address{1}})
+	var blocks []*types.Block
+	var receipts []types.Receipts
+	parent := common.Hash{}
+	for i := int64(0); i < 10; i++ {
+		header := &types.Header{Number: big.NewInt(i), ParentHash: parent, Difficulty: common.Big1}
+		var txs []*types.Transaction
+		blockReceipts := types.Receipts{}
+		if i == 2 {
+			txs = []*types.Transaction{tx}
+			blockReceipts = types.Receipts{{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 21000, Logs: []*types.Log{}}}
+		}
+		block := types.NewBlock(header, txs, nil, blockReceipts, trie.NewStackTrie(nil))
+		blocks = append(blocks, block)
+		receipts = append(receipts, blockReceipts)
+		rawdb.WriteHeaderNumber(db, block.Hash(), block.NumberU64())
+		parent = block.Hash()
+	}
+	if _, err := rawdb.WriteAncientBlocks(db, blocks, receipts, big.NewInt(0)); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewDirectoryArchiveStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := DefaultRetentionConfig
+	config.Enable = true
+	config.SegmentSize = 4
+	config.Prune = true
+	config.LocalAncientBlocks = 3
+	retention, err := NewRetentionManager(db, store, func() *RetentionConfig { return &config })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := retention.update(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return db, blocks, retention, store, &config
+}
+
+func TestRetentionUploadAndPrune(t *testing.T) {
+	ctx := context.Background()
+	db, blocks, retention, store, config := newTestRetention(t)
+	status, err := retention.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the last two blocks don't fill a segment yet, and the three most recent blocks stay local
+	if status.UploadedBlocks != 8 || status.LocalTail != 7 || status.FrozenBlocks != 10 {
+		t.Fatalf("unexpected retention status %+v", status)
+	}
+	if rawdb.ReadCanonicalHash(db, 0) != blocks[0].Hash() {
+		t.Error("genesis block wasn't preserved after pruning")
+	}
+	for i, block := range blocks {
+		archived, err := retention.ArchivedBlock(ctx, uint64(i))
+		if err != nil {
+			t.Fatalf("failed to get archived block %d: %v", i, err)
+		}
+		expectedSource := "freezer"
+		if i < 7 {
+			expectedSource = "archive"
+		}
+		if archived.Hash != block.Hash() || archived.Source != expectedSource {
+			t.Errorf("unexpected archived block %d: hash %v from %v", i, archived.Hash, archived.Source)
+		}
+	}
+
+	// uploads resume from the stored progress
+	restarted, err := NewRetentionManager(db, store, func() *RetentionConfig { return config })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restarted.uploadedBlocks() != 8 {
+		t.Errorf("expected upload progress to be restored, got %d", restarted.uploadedBlocks())
+	}
+	config.SegmentSize = 5
+	if _, err := NewRetentionManager(db, store, func() *RetentionConfig { return config }); err == nil {
+		t.Error("expected changing the segment size after uploading to be rejected")
+	}
+}
+
+func TestRetentionRejectsTamperedSegment(t *testing.T) {
+	ctx := context.Background()
+	db, blocks, _, store, config := newTestRetention(t)
+	original, err := store.Get(ctx, segmentKey(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherTx := types.MustSignNewTx(otherKey, types.LatestSigner(params.ArbitrumDevTestChainConfig()), &types.LegacyTx{Gas: 21000, GasPrice: common.Big1, To: &common.Address{2}})
+	otherBlock := types.NewBlock(&types.Header{Number: big.NewInt(2), Difficulty: common.Big2}, nil, nil, nil, trie.NewStackTrie(nil))
+	encode := func(value interface{}) []byte {
+		t.Helper()
+		encoded, err := rlp.EncodeToBytes(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return encoded
+	}
+
+	for name, tamper := range map[string]func(block *archivedBlock){
+		"body": func(block *archivedBlock) {
+			block.Body = encode(&types.Body{Transactions: []*types.Transaction{otherTx}})
+		},
+		"receipts": func(block *archivedBlock) {
+			block.Receipts = encode([]*types.ReceiptForStorage{{Status: types.ReceiptStatusFailed, CumulativeGasUsed: 21000, Logs: []*types.Log{}}})
+		},
+		// a block that's consistent in itself, but isn't the local chain's
+		"block": func(block *archivedBlock) {
+			block.Hash = otherBlock.Hash()
+			block.Header = encode(otherBlock.Header())
+			block.Body = encode(otherBlock.Body())
+			block.Receipts = encode([]*types.ReceiptForStorage{})
+		},
+	} {
+		segment, err := decodeSegment(original)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tamper(&segment[2])
+		data, err := encodeSegment(segment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Put(ctx, segmentKey(0), data); err != nil {
+			t.Fatal(err)
+		}
+		// a fresh manager, so the segment isn't cached
+		retention, err := NewRetentionManager(db, store, func() *RetentionConfig { return config })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := retention.ArchivedBlock(ctx, 1); err == nil {
+			t.Errorf("served a block from a segment with a tampered %v", name)
+		}
+	}
+
+	if err := store.Put(ctx, segmentKey(0), original); err != nil {
+		t.Fatal(err)
+	}
+	retention, err := NewRetentionManager(db, store, func() *RetentionConfig { return config })
+	if err != nil {
+		t.Fatal(err)
+	}
+	archived, err := retention.ArchivedBlock(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if archived.Hash != blocks[2].Hash() {
+		t.Errorf("unexpected archived block hash %v", archived.Hash)
+	}
+}
+
+// testNullEthAPI stands in for geth's eth API, which has none of the pruned blocks.
+type testNullEthAPI struct{}
+
+func (api *testNullEthAPI) GetBlockByNumber(number rpc.BlockNumber, fullTx bool) map[string]interface{} {
+	return nil
+}
+
+func (api *testNullEthAPI) GetBlockByHash(hash common.Hash, fullTx bool) map[string]interface{} {
+	return nil
+}
+
+func TestArchiveBlockAPI(t *testing.T) {
+	ctx := context.Background()
+	_, blocks, retention, _, _ := newTestRetention(t)
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &testNullEthAPI{}); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client := rpc.DialInProc(server)
+	defer client.Close()
+	api := NewArchiveBlockAPI(retention, params.ArbitrumDevTestChainConfig(), client)
+
+	type rpcBlock struct {
+		Hash         common.Hash       `json:"hash"`
+		Number       hexutil.Uint64    `json:"number"`
+		Transactions []json.RawMessage `json:"transactions"`
+	}
+	decode := func(result json.RawMessage, err error) *rpcBlock {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var block *rpcBlock
+		if err := json.Unmarshal(result, &block); err != nil {
+			t.Fatal(err)
+		}
+		return block
+	}
+	block := decode(api.GetBlockByNumber(ctx, 2, true))
+	if block == nil || block.Hash != blocks[2].Hash() || block.Number != 2 || len(block.Transactions) != 1 {
+		t.Fatalf("unexpected pruned block %+v", block)
+	}
+	var tx struct {
+		Hash             common.Hash    `json:"hash"`
+		From             common.Address `json:"from"`
+		BlockHash        common.Hash    `json:"blockHash"`
+		TransactionIndex hexutil.Uint64 `json:"transactionIndex"`
+	}
+	if err := json.Unmarshal(block.Transactions[0], &tx); err != nil {
+		t.Fatal(err)
+	}
+	if tx.Hash != blocks[2].Transactions()[0].Hash() || tx.BlockHash != blocks[2].Hash() || tx.From == (common.Address{}) {
+		t.Errorf("unexpected transaction of a pruned block %+v", tx)
+	}
+	block = decode(api.GetBlockByHash(ctx, blocks[2].Hash(), false))
+	if block == nil || block.Number != 2 || string(block.Transactions[0]) != fmt.Sprintf("%q", blocks[2].Transactions()[0].Hash()) {
+		t.Fatalf("unexpected pruned block by hash %+v", block)
+	}
+	// blocks still in the freezer are geth's to serve
+	if block := decode(api.GetBlockByNumber(ctx, 8, false)); block != nil {
+		t.Error("served a block from the archive that wasn't pruned")
+	}
+	if block := decode(api.GetBlockByHash(ctx, common.Hash{1}, false)); block != nil {
+		t.Error("served an unknown block hash")
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/holiman/uint256"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/offchainlabs/nitro/arbos/arbosState"
+	"github.com/offchainlabs/nitro/execution"
+	"github.com/offchainlabs/nitro/solgen/go/precompilesgen"
+	"github.com/offchainlabs/nitro/util/arbmath"
+)
+
+var arbRetryableTxABI = func() *abi.ABI {
+	parsed, err := precompilesgen.ArbRetryableTxMetaData.GetAbi()
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// RetryableRedeemEstimate is the outcome of redeeming a retryable under the current state.
+type RetryableRedeemEstimate struct {
+	// the gas limit of the simulated redeem transaction: the least needed for the retry to succeed,
+	// unless a gas limit was requested or the retry fails regardless
+	RedeemGas hexutil.Uint64 `json:"redeemGas"`
+	// the gas the redeem donated to the retry
+	RetryGas     hexutil.Uint64 `json:"retryGas"`
+	RetryGasUsed hexutil.Uint64 `json:"retryGasUsed"`
+	GasPrice     *hexutil.Big   `json:"gasPrice"`
+	// what the redeemer is refunded for the retry's unused gas
+	ExpectedRefund *hexutil.Big  `json:"expectedRefund"`
+	Success        bool          `json:"success"`
+	ReturnData     hexutil.Bytes `json:"returnData"`
+	Error          string        `json:"error,omitempty"`
+}
+
+type RetryableRedeemAPI struct {
+	blockchain *core.BlockChain
+	gasCap     uint64
+}
+
+func NewRetryableRedeemAPI(blockchain *core.BlockChain, gasCap uint64) *RetryableRedeemAPI {
+	if gasCap == 0 {
+		gasCap = math.MaxUint64 / 2
+	}
+	return &RetryableRedeemAPI{blockchain, gasCap}
+}
+
+type redeemSimulation struct {
+	// set if the redeem transaction itself failed, e.g. for lack of gas
+	redeemErr error
+	retryTx   *types.Transaction
+	retry     *core.ExecutionResult
+}
+
+func (s *redeemSimulation) succeeded() bool {
+	return s.redeemErr == nil && s.retry.Err == nil
+}
+
+func (api *RetryableRedeemAPI) applyMessage(ctx context.Context, header *types.Header, statedb *state.StateDB, msg *core.Message) (*core.ExecutionResult, error) {
+	blockCtx := core.NewEVMBlockContext(header, api.blockchain, nil)
+	evm := vm.NewEVM(blockCtx, core.NewEVMTxContext(msg), statedb, api.blockchain.Config(), vm.Config{})
+	stop := context.AfterFunc(ctx, evm.Cancel)
+	defer stop()
+	core.ReadyEVMForL2(evm, msg)
+	gasPool := core.GasPool(math.MaxUint64)
+	result, err := core.ApplyMessage(evm, msg, &gasPool)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return result, statedb.Error()
+}
+
+// simulateRedeem runs a redeem transaction with the given gas limit followed by the retry it schedules,
+// the way they'd be executed in the next block.
+func (api *RetryableRedeemAPI) simulateRedeem(ctx context.Context, header *types.Header, statedb *state.StateDB, ticketId common.Hash, from common.Address, redeemGas uint64) (*redeemSimulation, error) {
+	statedb = statedb.Copy()
+	// the redeemer's balance doesn't affect the outcome, so make sure it can pay for the gas
+	statedb.AddBalance(from, uint256.MustFromBig(arbmath.BigMulByUint(header.BaseFee, redeemGas)))
+	data, err := arbRetryableTxABI.Pack("redeem", ticketId)
+	if err != nil {
+		return nil, err
+	}
+	msg := &core.Message{
+		From:              from,
+		To:                &types.ArbRetryableTxAddress,
+		Nonce:             statedb.GetNonce(from),
+		Value:             new(big.Int),
+		GasLimit:          redeemGas,
+		GasPrice:          header.BaseFee,
+		GasFeeCap:         header.BaseFee,
+		GasTipCap:         new(big.Int),
+		Data:              data,
+		SkipAccountChecks: true,
+		TxRunMode:         core.MessageGasEstimationMode,
+	}
+	statedb.SetTxContext(common.Hash{}, 0)
+	result, err := api.applyMessage(ctx, header, statedb, msg)
+	if err != nil {
+		return nil, err
+	}
+	if result.Err != nil {
+		return &redeemSimulation{redeemErr: result.Err}, nil
+	}
+	if len(result.ScheduledTxes) != 1 {
+		return nil, fmt.Errorf("redeem scheduled %v transactions instead of a retry", len(result.ScheduledTxes))
+	}
+	retryTx := result.ScheduledTxes[0]
+	retryMsg, err := core.TransactionToMessage(retryTx, types.NewArbitrumSigner(nil), header.BaseFee, core.MessageCommitMode)
+	if err != nil {
+		return nil, err
+	}
+	statedb.SetTxContext(retryTx.Hash(), 1)
+	retry, err := api.applyMessage(ctx, header, statedb, retryMsg)
+	if err != nil {
+		return nil, err
+	}
+	return &redeemSimulation{retryTx: retryTx, retry: retry}, nil
+}
+
+// EstimateRetryableRedeem simulates redeeming a retryable under the latest state, and returns the gas a redeem
+// transaction needs for the retry to succeed along with the retry's outcome and refund. If gas is given,
+// the redeem is simulated with that gas limit instead.
+func (api *RetryableRedeemAPI) EstimateRetryableRedeem(ctx context.Context, ticketId common.Hash, from *common.Address, gas *hexutil.Uint64) (*RetryableRedeemEstimate, error) {
+	header := api.blockchain.CurrentBlock()
+	if !api.blockchain.Config().IsArbitrumNitro(header.Number) {
+		return nil, types.ErrUseFallback
+	}
+	statedb, err := api.blockchain.StateAt(header.Root)
+	if err != nil {
+		return nil, err
+	}
+	arbState, err := arbosState.OpenSystemArbosState(statedb, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	retryable, err := arbState.RetryableState().OpenRetryable(ticketId, header.Time)
+	if err != nil {
+		return nil, err
+	}
+	if retryable == nil {
+		return nil, execution.WithErrorCode(execution.ErrorCodeRetryableExpired, fmt.Errorf("retryable %v not found", ticketId))
+	}
+	var redeemer common.Address
+	if from != nil {
+		redeemer = *from
+	}
+
+	redeemGas := api.gasCap
+	if gas != nil {
+		redeemGas = uint64(*gas)
+	}
+	sim, err := api.simulateRedeem(ctx, header, statedb, ticketId, redeemer, redeemGas)
+	if err != nil {
+		return nil, err
+	}
+	if gas == nil && sim.succeeded() {
+		// binary search for the least gas the retry succeeds with
+		lo, hi := params.TxGas-1, redeemGas
+		for lo+1 < hi {
+			mid := lo + (hi-lo)/2
+			midSim, err := api.simulateRedeem(ctx, header, statedb, ticketId, redeemer, mid)
+			if err != nil {
+				return nil, err
+			}
+			if midSim.succeeded() {
+				hi, sim = mid, midSim
+			} else {
+				lo = mid
+			}
+		}
+		redeemGas = hi
+	}
+
+	estimate := &RetryableRedeemEstimate{
+		RedeemGas:      hexutil.Uint64(redeemGas),
+		GasPrice:       (*hexutil.Big)(header.BaseFee),
+		ExpectedRefund: (*hexutil.Big)(new(big.Int)),
+		Success:        sim.succeeded(),
+	}
+	if sim.redeemErr != nil {
+		estimate.Error = fmt.Sprintf("redeem failed: %v", sim.redeemErr)
+		return estimate, nil
+	}
+	estimate.RetryGas = hexutil.Uint64(sim.retryTx.Gas())
+	estimate.RetryGasUsed = hexutil.Uint64(sim.retry.UsedGas)
+	estimate.ExpectedRefund = (*hexutil.Big)(arbmath.BigMulByUint(header.BaseFee, arbmath.SaturatingUSub(sim.retryTx.Gas(), sim.retry.UsedGas)))
+	estimate.ReturnData = sim.retry.Revert()
+	if sim.retry.Err != nil {
+		estimate.Error = sim.retry.Err.Error()
+		if errors.Is(sim.retry.Err, vm.ErrExecutionReverted) {
+			if reason, err := abi.UnpackRevert(sim.retry.Revert()); err == nil {
+				estimate.Error = fmt.Sprintf("%v: %v", sim.retry.Err, reason)
+			}
+		}
+	} else {
+		estimate.ReturnData = sim.retry.Return()
+	}
+	return estimate, nil
+}
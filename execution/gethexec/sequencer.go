@@ -4,6 +4,7 @@
 package gethexec
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -38,6 +39,7 @@ import (
 	"github.com/offchainlabs/nitro/arbos/arbosState"
 	"github.com/offchainlabs/nitro/arbos/arbostypes"
 	"github.com/offchainlabs/nitro/arbos/l1pricing"
+	"github.com/offchainlabs/nitro/solgen/go/precompilesgen"
 	"github.com/offchainlabs/nitro/util/stopwaiter"
 )
 
@@ -53,11 +55,14 @@ var (
 	successfulBlocksCounter                 = metrics.NewRegisteredCounter("arb/sequencer/block/successful", nil)
 	conditionalTxRejectedBySequencerCounter = metrics.NewRegisteredCounter("arb/sequencer/conditionaltx/rejected", nil)
 	conditionalTxAcceptedBySequencerCounter = metrics.NewRegisteredCounter("arb/sequencer/conditionaltx/accepted", nil)
+	expiredTxDroppedCounter                 = metrics.NewRegisteredCounter("arb/sequencer/expiredtx/dropped", nil)
 	l1GasPriceGauge                         = metrics.NewRegisteredGauge("arb/sequencer/l1gasprice", nil)
 	callDataUnitsBacklogGauge               = metrics.NewRegisteredGauge("arb/sequencer/calldataunitsbacklog", nil)
 	unusedL1GasChargeGauge                  = metrics.NewRegisteredGauge("arb/sequencer/unusedl1gascharge", nil)
 	currentSurplusGauge                     = metrics.NewRegisteredGauge("arb/sequencer/currentsurplus", nil)
 	expectedSurplusGauge                    = metrics.NewRegisteredGauge("arb/sequencer/expectedsurplus", nil)
+	l1FeesAvailableGauge                    = metrics.NewRegisteredGauge("arb/sequencer/l1pricing/feesavailable", nil)
+	l1FundsDueGauge                         = metrics.NewRegisteredGauge("arb/sequencer/l1pricing/fundsdue", nil)
 )
 
 type SequencerConfig struct {
@@ -114,7 +119,7 @@ type SequencerConfigFetcher func() *SequencerConfig
 var DefaultSequencerConfig = SequencerConfig{
 	Enable:                      false,
 	MaxBlockSpeed:               time.Millisecond * 250,
-	MaxRevertGasReject:          0,
+	MaxRevertGasReject:          params.TxGas + 10000,
 	MaxAcceptableTimestampDelta: time.Hour,
 	SenderWhitelist:             []string{},
 	Forwarder:                   DefaultSequencerForwarderConfig,
@@ -391,10 +396,11 @@ func (s *Sequencer) PublishTransaction(parentCtx context.Context, tx *types.Tran
 	// And hard threshold was enabled, this prevents spamming of read locks when not needed
 	if s.l1Reader != nil && config.ExpectedSurplusHardThreshold != "default" {
 		s.expectedSurplusMutex.RLock()
-		if s.expectedSurplusUpdated && s.expectedSurplus < int64(config.expectedSurplusHardThreshold) {
+		belowThreshold := s.expectedSurplusUpdated && s.expectedSurplus < int64(config.expectedSurplusHardThreshold)
+		s.expectedSurplusMutex.RUnlock()
+		if belowThreshold {
 			return errors.New("currently not accepting transactions due to expected surplus being below threshold")
 		}
-		s.expectedSurplusMutex.RUnlock()
 	}
 
 	sequencerBacklogGauge.Inc(1)
@@ -425,6 +431,16 @@ func (s *Sequencer) PublishTransaction(parentCtx context.Context, tx *types.Tran
 		return types.ErrTxTypeNotSupported
 	}
 
+	if s.l1Reader != nil {
+		s.L1BlockAndTimeMutex.Lock()
+		l1Block := s.l1BlockNumber.Load()
+		s.L1BlockAndTimeMutex.Unlock()
+		if transactionExpired(options, l1Block, uint64(time.Now().Unix())) {
+			expiredTxDroppedCounter.Inc(1)
+			return ErrTransactionExpired
+		}
+	}
+
 	txBytes, err := tx.MarshalBinary()
 	if err != nil {
 		return err
@@ -451,11 +467,19 @@ func (s *Sequencer) PublishTransaction(parentCtx context.Context, tx *types.Tran
 	select {
 	case s.txQueue <- queueItem:
 	case <-queueCtx.Done():
-		return queueCtx.Err()
+		err := queueCtx.Err()
+		if parentCtx.Err() == nil {
+			err = execution.WithErrorCode(execution.ErrorCodeSequencerFull, fmt.Errorf("sequencer queue is full: %w", err))
+		}
+		return err
 	}
 
 	select {
 	case res := <-resultChan:
+		if errors.Is(res, context.DeadlineExceeded) && parentCtx.Err() == nil && s.parentChainUnavailable() {
+			// the transaction waited in the retry queue for the sequencer to get a recent parent chain block
+			return execution.WithErrorCode(execution.ErrorCodeParentChainUnavailable, fmt.Errorf("sequencer has no recent parent chain block: %w", res))
+		}
 		return res
 	case <-abortCtx.Done():
 		// We use abortCtx here and not queueCtx, because the QueueTimeout only applies to the background queue.
@@ -482,7 +506,11 @@ func (s *Sequencer) preTxFilter(_ *params.ChainConfig, header *types.Header, sta
 		err := options.Check(l1Info.L1BlockNumber(), header.Time, statedb)
 		if err != nil {
 			conditionalTxRejectedBySequencerCounter.Inc(1)
-			return err
+			if transactionExpired(options, l1Info.L1BlockNumber(), header.Time) {
+				expiredTxDroppedCounter.Inc(1)
+				return ErrTransactionExpired
+			}
+			return execution.WithErrorCode(execution.ErrorCodeConditionalCheckFailed, err)
 		}
 		conditionalTxAcceptedBySequencerCounter.Inc(1)
 	}
@@ -491,7 +519,11 @@ func (s *Sequencer) preTxFilter(_ *params.ChainConfig, header *types.Header, sta
 
 func (s *Sequencer) postTxFilter(header *types.Header, _ *arbosState.ArbosState, tx *types.Transaction, sender common.Address, dataGas uint64, result *core.ExecutionResult) error {
 	if result.Err != nil && result.UsedGas > dataGas && result.UsedGas-dataGas <= s.config().MaxRevertGasReject {
-		return arbitrum.NewRevertReason(result)
+		err := arbitrum.NewRevertReason(result)
+		if isRetryableNotFoundRevert(result.Revert()) {
+			return execution.WithErrorCode(execution.ErrorCodeRetryableExpired, err)
+		}
+		return err
 	}
 	newNonce := tx.Nonce() + 1
 	s.nonceCache.Update(header, sender, newNonce)
@@ -587,7 +619,37 @@ func (s *Sequencer) Pause() {
 	}
 }
 
-var ErrNoSequencer = errors.New("sequencer temporarily not available")
+var ErrNoSequencer = execution.NewCodedError(execution.ErrorCodeCoordinatorNotChosen, "sequencer temporarily not available")
+
+// ErrTransactionExpired is returned for transactions that weren't sequenced before their valid-until bound,
+// given by the timestampMax or blockNumberMax (a parent chain block number) of their conditional options.
+var ErrTransactionExpired = execution.NewCodedError(execution.ErrorCodeTransactionExpired, "transaction expired before it could be sequenced")
+
+// transactionExpired returns whether a block with the given parent chain block number and timestamp
+// is past the upper bounds of the conditional options, so the transaction must not be sequenced anymore.
+func transactionExpired(options *arbitrum_types.ConditionalOptions, l1BlockNumber uint64, timestamp uint64) bool {
+	if options == nil {
+		return false
+	}
+	if options.TimestampMax != nil && timestamp > uint64(*options.TimestampMax) {
+		return true
+	}
+	return options.BlockNumberMax != nil && l1BlockNumber > uint64(*options.BlockNumberMax)
+}
+
+// dropExpired returns the expired queue items' results, and the rest of the queue items.
+func dropExpired(queueItems []txQueueItem, l1BlockNumber uint64, timestamp uint64) []txQueueItem {
+	remaining := queueItems[:0]
+	for _, queueItem := range queueItems {
+		if transactionExpired(queueItem.options, l1BlockNumber, timestamp) {
+			expiredTxDroppedCounter.Inc(1)
+			queueItem.returnResult(ErrTransactionExpired)
+			continue
+		}
+		remaining = append(remaining, queueItem)
+	}
+	return remaining
+}
 
 func (s *Sequencer) GetPauseAndForwarder() (chan struct{}, *TxForwarder) {
 	s.activeMutex.Lock()
@@ -850,14 +912,9 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 	s.nonceCache.Resize(config.NonceCacheSize) // Would probably be better in a config hook but this is basically free
 	s.nonceCache.BeginNewBlock()
 	queueItems = s.precheckNonces(queueItems, totalBlockSize)
-	txes := make([]*types.Transaction, len(queueItems))
-	hooks := s.makeSequencingHooks()
-	hooks.ConditionalOptionsForTx = make([]*arbitrum_types.ConditionalOptions, len(queueItems))
 	totalBlockSize = 0 // recompute the totalBlockSize to double check it
-	for i, queueItem := range queueItems {
-		txes[i] = queueItem.tx
+	for _, queueItem := range queueItems {
 		totalBlockSize = arbmath.SaturatingAdd(totalBlockSize, queueItem.txSize)
-		hooks.ConditionalOptionsForTx[i] = queueItem.options
 	}
 
 	if totalBlockSize > config.MaxTxDataSize {
@@ -896,6 +953,16 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 		return true
 	}
 
+	// transactions that waited in the queue past their valid-until bound must not land late
+	queueItems = dropExpired(queueItems, l1Block, uint64(timestamp))
+	txes := make([]*types.Transaction, len(queueItems))
+	hooks := s.makeSequencingHooks()
+	hooks.ConditionalOptionsForTx = make([]*arbitrum_types.ConditionalOptions, len(queueItems))
+	for i, queueItem := range queueItems {
+		txes[i] = queueItem.tx
+		hooks.ConditionalOptionsForTx[i] = queueItem.options
+	}
+
 	header := &arbostypes.L1IncomingMessageHeader{
 		Kind:        arbostypes.L1MessageType_L2Message,
 		Poster:      l1pricing.BatchPosterAddress,
@@ -978,6 +1045,9 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 			// Strip additional information, as it's incorrect due to L1 data gas.
 			err = core.ErrIntrinsicGas
 		}
+		if errors.Is(err, core.ErrFeeCapTooLow) {
+			err = execution.WithErrorCode(execution.ErrorCodeBelowBaseFee, err)
+		}
 		var nonceError NonceError
 		if errors.As(err, &nonceError) && nonceError.txNonce > nonceError.stateNonce {
 			s.nonceFailures.Add(nonceError, queueItem)
@@ -988,6 +1058,33 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 	return madeBlock
 }
 
+// parentChainUnavailable returns whether the sequencer is holding transactions back
+// because it doesn't know of a parent chain block close enough to the local time.
+func (s *Sequencer) parentChainUnavailable() bool {
+	if s.l1Reader == nil {
+		return false
+	}
+	s.L1BlockAndTimeMutex.Lock()
+	l1Block := s.l1BlockNumber.Load()
+	l1Timestamp := s.l1Timestamp
+	s.L1BlockAndTimeMutex.Unlock()
+	return l1Block == 0 || math.Abs(float64(l1Timestamp)-float64(time.Now().Unix())) > s.config().MaxAcceptableTimestampDelta.Seconds()
+}
+
+var retryableNotFoundSelector = func() []byte {
+	abi, err := precompilesgen.ArbRetryableTxMetaData.GetAbi()
+	if err != nil {
+		panic(err)
+	}
+	return abi.Errors["NoTicketWithID"].ID[:4]
+}()
+
+// isRetryableNotFoundRevert returns whether revert data is ArbRetryableTx's error for a retryable
+// that expired or was already redeemed.
+func isRetryableNotFoundRevert(revert []byte) bool {
+	return len(revert) >= 4 && bytes.Equal(revert[:4], retryableNotFoundSelector)
+}
+
 func (s *Sequencer) updateLatestParentChainBlock(header *types.Header) {
 	s.L1BlockAndTimeMutex.Lock()
 	defer s.L1BlockAndTimeMutex.Unlock()
@@ -1045,6 +1142,12 @@ func (s *Sequencer) updateExpectedSurplus(ctx context.Context) (int64, error) {
 	callDataUnitsBacklogGauge.Update(backlogCallDataUnits)
 	unusedL1GasChargeGauge.Update(backlogL1GasCharged)
 	currentSurplusGauge.Update(surplus)
+	if available, fundsDue, err := s.execEngine.getL1PricingFunds(); err == nil {
+		l1FeesAvailableGauge.Update(available)
+		l1FundsDueGauge.Update(fundsDue)
+	} else {
+		log.Warn("error getting l1 pricing funds", "err", err)
+	}
 	expectedSurplusGauge.Update(expectedSurplus)
 	config := s.config()
 	if config.ExpectedSurplusSoftThreshold != "default" && expectedSurplus < int64(config.expectedSurplusSoftThreshold) {
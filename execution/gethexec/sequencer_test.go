@@ -0,0 +1,51 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arbitrum_types"
+	"github.com/ethereum/go-ethereum/common/math"
+)
+
+func TestDropExpired(t *testing.T) {
+	timestampMax := math.HexOrDecimal64(100)
+	blockNumberMax := math.HexOrDecimal64(10)
+	options := []*arbitrum_types.ConditionalOptions{
+		nil,
+		{TimestampMax: &timestampMax},
+		{BlockNumberMax: &blockNumberMax},
+	}
+	var queueItems []txQueueItem
+	var results []chan error
+	for _, opts := range options {
+		resultChan := make(chan error, 1)
+		results = append(results, resultChan)
+		queueItems = append(queueItems, txQueueItem{options: opts, resultChan: resultChan, returnedResult: &atomic.Bool{}})
+	}
+
+	remaining := dropExpired(queueItems, 10, 100)
+	if len(remaining) != 3 {
+		t.Fatalf("dropped transactions still within their bounds, %v remaining", len(remaining))
+	}
+
+	remaining = dropExpired(remaining, 11, 100)
+	if len(remaining) != 2 || remaining[1].options != options[1] {
+		t.Fatalf("expected only the transaction bounded by parent chain block to expire, %v remaining", len(remaining))
+	}
+	if err := <-results[2]; !errors.Is(err, ErrTransactionExpired) {
+		t.Errorf("unexpected result for the expired transaction: %v", err)
+	}
+
+	remaining = dropExpired(remaining, 11, 101)
+	if len(remaining) != 1 || remaining[0].options != nil {
+		t.Fatalf("expected only the unbounded transaction to remain, %v remaining", len(remaining))
+	}
+	if err := <-results[1]; !errors.Is(err, ErrTransactionExpired) {
+		t.Errorf("unexpected result for the expired transaction: %v", err)
+	}
+}
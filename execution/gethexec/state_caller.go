@@ -0,0 +1,150 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/arbitrum"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// StateCaller serves eth_call on state from the state reader, so calls read through the snapshot layers of
+// recent blocks and share the state reader's pool of workers. It runs calls the way geth's eth_call does,
+// including NodeInterface's virtual contracts.
+type StateCaller struct {
+	reader  *StateReader
+	backend core.NodeInterfaceBackendAPI
+	chain   core.ChainContext
+	gasCap  uint64
+	timeout time.Duration
+}
+
+func NewStateCaller(reader *StateReader, backend core.NodeInterfaceBackendAPI, chain core.ChainContext, gasCap uint64, timeout time.Duration) *StateCaller {
+	return &StateCaller{
+		reader:  reader,
+		backend: backend,
+		chain:   chain,
+		gasCap:  gasCap,
+		timeout: timeout,
+	}
+}
+
+func (c *StateCaller) Call(ctx context.Context, args arbitrum.TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	statedb, header, release, err := c.reader.CallState(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	result, err := c.apply(ctx, args, statedb, header)
+	if err != nil {
+		return nil, err
+	}
+	if revert := result.Revert(); len(revert) > 0 {
+		return nil, newRevertError(revert)
+	}
+	return result.Return(), result.Err
+}
+
+func (c *StateCaller) apply(ctx context.Context, args arbitrum.TransactionArgs, statedb *state.StateDB, header *types.Header) (*core.ExecutionResult, error) {
+	var cancel context.CancelFunc
+	if c.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+	msg, err := args.ToMessage(c.gasCap, header, statedb, core.MessageEthcallMode)
+	if err != nil {
+		return nil, err
+	}
+	blockCtx := core.NewEVMBlockContext(header, c.chain, nil)
+	msg, result, err := core.InterceptRPCMessage(msg, ctx, statedb, header, c.backend, &blockCtx)
+	if err != nil || result != nil {
+		return result, err
+	}
+	evm := c.backend.GetEVM(ctx, msg, statedb, header, &vm.Config{NoBaseFee: true}, &blockCtx)
+	stop := context.AfterFunc(ctx, evm.Cancel)
+	defer stop()
+	core.ReadyEVMForL2(evm, msg)
+	gasPool := core.GasPool(math.MaxUint64)
+	result, err = core.ApplyMessage(evm, msg, &gasPool)
+	if err := statedb.Error(); err != nil {
+		return nil, err
+	}
+	if evm.Cancelled() {
+		return nil, fmt.Errorf("execution aborted (timeout = %v)", c.timeout)
+	}
+	if err != nil {
+		return result, fmt.Errorf("err: %w (supplied gas %d)", err, msg.GasLimit)
+	}
+	return result, nil
+}
+
+// revertError reports a reverted call the way geth's eth_call does, with the revert data as its error data.
+type revertError struct {
+	message string
+	data    string
+}
+
+func newRevertError(revert []byte) *revertError {
+	message := vm.ErrExecutionReverted.Error()
+	if reason, err := abi.UnpackRevert(revert); err == nil {
+		message = fmt.Sprintf("%v: %v", message, reason)
+	}
+	return &revertError{message, hexutil.Encode(revert)}
+}
+
+func (e *revertError) Error() string {
+	return e.message
+}
+
+func (e *revertError) ErrorCode() int {
+	return 3
+}
+
+func (e *revertError) ErrorData() interface{} {
+	return e.data
+}
+
+// callOrForward serves a call from the state caller, unless it overrides the state or block, which is
+// forwarded to geth's own eth_call.
+func callOrForward(ctx context.Context, caller *StateCaller, geth *rpc.Client, args arbitrum.TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *json.RawMessage, blockOverrides *json.RawMessage) (hexutil.Bytes, error) {
+	if caller != nil && overrides == nil && blockOverrides == nil {
+		return caller.Call(ctx, args, blockNrOrHash)
+	}
+	var result hexutil.Bytes
+	err := geth.CallContext(ctx, &result, "eth_call", args, blockParam(blockNrOrHash), overrides, blockOverrides)
+	return result, err
+}
+
+// optionalBlock returns the block of a call, which is the latest if omitted.
+func optionalBlock(blockNrOrHash *rpc.BlockNumberOrHash) rpc.BlockNumberOrHash {
+	if blockNrOrHash == nil {
+		return rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	}
+	return *blockNrOrHash
+}
+
+// blockParam encodes the block as a parameter for geth's API.
+func blockParam(blockNrOrHash rpc.BlockNumberOrHash) interface{} {
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		return map[string]interface{}{"blockHash": hash, "requireCanonical": blockNrOrHash.RequireCanonical}
+	}
+	number, _ := blockNrOrHash.Number()
+	if number < 0 {
+		return number.String()
+	}
+	return hexutil.Uint64(number)
+}
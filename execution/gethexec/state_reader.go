@@ -0,0 +1,371 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"runtime"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/arbitrum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	stateReaderSnapshotCounter = metrics.NewRegisteredCounter("arb/rpc/statereader/snapshot", nil)
+	stateReaderFallbackCounter = metrics.NewRegisteredCounter("arb/rpc/statereader/fallback", nil)
+	stateReaderRejectedCounter = metrics.NewRegisteredCounter("arb/rpc/statereader/rejected", nil)
+	stateReaderQueueTimer      = metrics.NewRegisteredTimer("arb/rpc/statereader/queue", nil)
+)
+
+var ErrStateReaderBusy = errors.New("too many concurrent state reads")
+
+type StateReaderConfig struct {
+	Enable       bool          `koanf:"enable"`
+	Workers      int           `koanf:"workers"`
+	QueueTimeout time.Duration `koanf:"queue-timeout"`
+}
+
+var DefaultStateReaderConfig = StateReaderConfig{
+	Enable:       false,
+	Workers:      0,
+	QueueTimeout: 5 * time.Second,
+}
+
+func StateReaderConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultStateReaderConfig.Enable, "serve eth_call, eth_getBalance, eth_getTransactionCount, eth_getCode and eth_getStorageAt from state snapshot layers, the account and storage reads without building a state database per request")
+	f.Int(prefix+".workers", DefaultStateReaderConfig.Workers, "maximum number of state reads served concurrently (0 = twice the number of CPUs)")
+	f.Duration(prefix+".queue-timeout", DefaultStateReaderConfig.QueueTimeout, "how long a state read may wait for a free worker before it's rejected")
+}
+
+func (c *StateReaderConfig) Validate() error {
+	if c.Workers < 0 {
+		return errors.New("state reader workers can't be negative")
+	}
+	return nil
+}
+
+type stateReaderBackend interface {
+	HeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error)
+	StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error)
+}
+
+// StateReader serves account and storage reads from the snapshot layers of recent blocks.
+// Snapshot layers are immutable once created, so any number of reads can share them without
+// contending with block execution for the state trie. Reads of blocks that aren't covered by a
+// snapshot layer, or while the snapshot is being generated, fall back to a regular state database.
+// The number of reads served at once is bounded by a pool of workers.
+type StateReader struct {
+	backend       stateReaderBackend
+	snaps         *snapshot.Tree
+	stateDatabase state.Database
+	workers       chan struct{}
+	queueTimeout  time.Duration
+}
+
+func NewStateReader(backend stateReaderBackend, snaps *snapshot.Tree, stateDatabase state.Database, config *StateReaderConfig) *StateReader {
+	workers := config.Workers
+	if workers == 0 {
+		workers = 2 * runtime.NumCPU()
+	}
+	return &StateReader{
+		backend:       backend,
+		snaps:         snaps,
+		stateDatabase: stateDatabase,
+		workers:       make(chan struct{}, workers),
+		queueTimeout:  config.QueueTimeout,
+	}
+}
+
+func (r *StateReader) acquire(ctx context.Context) (func(), error) {
+	start := time.Now()
+	timer := time.NewTimer(r.queueTimeout)
+	defer timer.Stop()
+	select {
+	case r.workers <- struct{}{}:
+		stateReaderQueueTimer.UpdateSince(start)
+		return func() { <-r.workers }, nil
+	case <-timer.C:
+		stateReaderRejectedCounter.Inc(1)
+		return nil, ErrStateReaderBusy
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// stateView reads the state of a single block, from its snapshot layer if there is one.
+type stateView struct {
+	reader *StateReader
+	ctx    context.Context
+	header *types.Header
+	layer  snapshot.Snapshot
+	// opened lazily once the snapshot can't answer a read
+	statedb *state.StateDB
+}
+
+func (r *StateReader) view(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*stateView, error) {
+	header, err := r.backend.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errors.New("header not found")
+	}
+	view := &stateView{reader: r, ctx: ctx, header: header}
+	if r.snaps != nil {
+		view.layer = r.snaps.Snapshot(header.Root)
+	}
+	return view, nil
+}
+
+func (v *stateView) fallback() (*state.StateDB, error) {
+	stateReaderFallbackCounter.Inc(1)
+	if v.statedb != nil {
+		return v.statedb, nil
+	}
+	// look the block up by hash so a reorg since the header was read can't change the answer
+	statedb, _, err := v.reader.backend.StateAndHeaderByNumberOrHash(v.ctx, rpc.BlockNumberOrHashWithHash(v.header.Hash(), false))
+	if err != nil {
+		return nil, err
+	}
+	if statedb == nil {
+		return nil, fmt.Errorf("state of block %v not found", v.header.Number)
+	}
+	v.statedb = statedb
+	return statedb, nil
+}
+
+// callState returns a state database of the block to run calls on, which reads through the block's snapshot
+// layer if it has one.
+func (v *stateView) callState() (*state.StateDB, error) {
+	if v.layer != nil {
+		statedb, err := state.New(v.header.Root, v.reader.stateDatabase, v.reader.snaps)
+		if err == nil {
+			stateReaderSnapshotCounter.Inc(1)
+			return statedb, nil
+		}
+	}
+	return v.fallback()
+}
+
+// account returns the account at addr, or nil if it doesn't exist.
+func (v *stateView) account(addr common.Address) (*types.SlimAccount, error) {
+	if v.layer != nil {
+		account, err := v.layer.Account(crypto.Keccak256Hash(addr.Bytes()))
+		if err == nil {
+			stateReaderSnapshotCounter.Inc(1)
+			return account, nil
+		}
+		// the layer went stale or the snapshot is still being generated
+	}
+	statedb, err := v.fallback()
+	if err != nil {
+		return nil, err
+	}
+	if !statedb.Exist(addr) {
+		return nil, statedb.Error()
+	}
+	return &types.SlimAccount{
+		Nonce:    statedb.GetNonce(addr),
+		Balance:  statedb.GetBalance(addr),
+		CodeHash: statedb.GetCodeHash(addr).Bytes(),
+	}, statedb.Error()
+}
+
+func (v *stateView) storage(addr common.Address, key common.Hash) (common.Hash, error) {
+	if v.layer != nil {
+		enc, err := v.layer.Storage(crypto.Keccak256Hash(addr.Bytes()), crypto.Keccak256Hash(key.Bytes()))
+		if err == nil {
+			stateReaderSnapshotCounter.Inc(1)
+			if len(enc) == 0 {
+				return common.Hash{}, nil
+			}
+			_, content, _, err := rlp.Split(enc)
+			if err != nil {
+				return common.Hash{}, err
+			}
+			return common.BytesToHash(content), nil
+		}
+	}
+	statedb, err := v.fallback()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return statedb.GetState(addr, key), statedb.Error()
+}
+
+func (r *StateReader) Balance(ctx context.Context, addr common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*big.Int, error) {
+	release, err := r.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	view, err := r.view(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	account, err := view.account(addr)
+	if err != nil || account == nil {
+		return new(big.Int), err
+	}
+	return account.Balance.ToBig(), nil
+}
+
+func (r *StateReader) Nonce(ctx context.Context, addr common.Address, blockNrOrHash rpc.BlockNumberOrHash) (uint64, error) {
+	release, err := r.acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	view, err := r.view(ctx, blockNrOrHash)
+	if err != nil {
+		return 0, err
+	}
+	account, err := view.account(addr)
+	if err != nil || account == nil {
+		return 0, err
+	}
+	return account.Nonce, nil
+}
+
+func (r *StateReader) Code(ctx context.Context, addr common.Address, blockNrOrHash rpc.BlockNumberOrHash) ([]byte, error) {
+	release, err := r.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	view, err := r.view(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	account, err := view.account(addr)
+	if err != nil || account == nil || len(account.CodeHash) == 0 {
+		return nil, err
+	}
+	codeHash := common.BytesToHash(account.CodeHash)
+	if codeHash == types.EmptyCodeHash {
+		return nil, nil
+	}
+	code := rawdb.ReadCode(r.stateDatabase.DiskDB(), codeHash)
+	if len(code) == 0 {
+		return nil, fmt.Errorf("code %v of account %v not found", codeHash, addr)
+	}
+	return code, nil
+}
+
+func (r *StateReader) Storage(ctx context.Context, addr common.Address, key common.Hash, blockNrOrHash rpc.BlockNumberOrHash) (common.Hash, error) {
+	release, err := r.acquire(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	defer release()
+	view, err := r.view(ctx, blockNrOrHash)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return view.storage(addr, key)
+}
+
+// CallState returns a state database of the block to run a call on, along with the block's header, and a
+// function to release the worker serving the call once it's done.
+func (r *StateReader) CallState(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, func(), error) {
+	release, err := r.acquire(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	view, err := r.view(ctx, blockNrOrHash)
+	if err != nil {
+		release()
+		return nil, nil, nil, err
+	}
+	statedb, err := view.callState()
+	if err != nil {
+		release()
+		return nil, nil, nil, err
+	}
+	return statedb, view.header, release, nil
+}
+
+// StateReaderAPI is registered in the eth namespace after geth's own APIs, so its methods take
+// precedence over geth's implementations of the same methods.
+type StateReaderAPI struct {
+	reader *StateReader
+	caller *StateCaller
+	// calls geth's own eth API
+	geth *rpc.Client
+}
+
+func NewStateReaderAPI(reader *StateReader, caller *StateCaller, geth *rpc.Client) *StateReaderAPI {
+	return &StateReaderAPI{reader, caller, geth}
+}
+
+func (api *StateReaderAPI) Call(ctx context.Context, args arbitrum.TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash, overrides *json.RawMessage, blockOverrides *json.RawMessage) (hexutil.Bytes, error) {
+	return callOrForward(ctx, api.caller, api.geth, args, optionalBlock(blockNrOrHash), overrides, blockOverrides)
+}
+
+func (api *StateReaderAPI) GetBalance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error) {
+	balance, err := api.reader.Balance(ctx, address, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(balance), nil
+}
+
+func (api *StateReaderAPI) GetTransactionCount(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Uint64, error) {
+	nonce, err := api.reader.Nonce(ctx, address, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Uint64)(&nonce), nil
+}
+
+func (api *StateReaderAPI) GetCode(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	return api.reader.Code(ctx, address, blockNrOrHash)
+}
+
+func (api *StateReaderAPI) GetStorageAt(ctx context.Context, address common.Address, hexKey string, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	key, err := decodeStorageKey(hexKey)
+	if err != nil {
+		return nil, err
+	}
+	value, err := api.reader.Storage(ctx, address, key, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return value.Bytes(), nil
+}
+
+// decodeStorageKey parses a storage slot the way geth's eth_getStorageAt does.
+func decodeStorageKey(hexKey string) (common.Hash, error) {
+	if len(hexKey) >= 2 && hexKey[0] == '0' && (hexKey[1] == 'x' || hexKey[1] == 'X') {
+		hexKey = hexKey[2:]
+	}
+	if len(hexKey) > 64 {
+		return common.Hash{}, errors.New("hex string too long, want at most 32 bytes")
+	}
+	if len(hexKey)%2 == 1 {
+		hexKey = "0" + hexKey
+	}
+	b, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return common.Hash{}, errors.New("hex string invalid")
+	}
+	return common.BytesToHash(b), nil
+}
@@ -0,0 +1,198 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/holiman/uint256"
+)
+
+var testStorageSlot = common.Hash{1}
+
+type testStateBackend struct {
+	header        *types.Header
+	stateDatabase state.Database
+}
+
+func (b *testStateBackend) HeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
+	return b.header, nil
+}
+
+func (b *testStateBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	statedb, err := state.New(b.header.Root, b.stateDatabase, nil)
+	return statedb, b.header, err
+}
+
+func testAccountCode(i int) []byte {
+	if i%16 != 0 {
+		return nil
+	}
+	return []byte{0x60, byte(i), 0x60, 0x00, 0x55}
+}
+
+// newTestStateReader builds a state with the given number of accounts, each with a nonce, balance and
+// storage slot derived from its index, and a state reader for it with or without a snapshot.
+func newTestStateReader(tb testing.TB, accounts int, withSnapshot bool) (*StateReader, []common.Address) {
+	tb.Helper()
+	db := rawdb.NewMemoryDatabase()
+	trieDb := triedb.NewDatabase(db, triedb.HashDefaults)
+	stateDatabase := state.NewDatabaseWithNodeDB(db, trieDb)
+	statedb, err := state.New(types.EmptyRootHash, stateDatabase, nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	addresses := make([]common.Address, 0, accounts)
+	for i := 0; i < accounts; i++ {
+		addr := common.BigToAddress(big.NewInt(int64(i + 1)))
+		statedb.SetNonce(addr, uint64(i))
+		statedb.SetBalance(addr, uint256.NewInt(uint64(i+1)))
+		statedb.SetState(addr, testStorageSlot, common.BigToHash(big.NewInt(int64(i+1))))
+		if code := testAccountCode(i); code != nil {
+			statedb.SetCode(addr, code)
+		}
+		addresses = append(addresses, addr)
+	}
+	root, err := statedb.Commit(0, false)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := trieDb.Commit(root, false); err != nil {
+		tb.Fatal(err)
+	}
+	var snaps *snapshot.Tree
+	if withSnapshot {
+		snaps, err = snapshot.New(snapshot.Config{CacheSize: 16}, db, trieDb, root)
+		if err != nil {
+			tb.Fatal(err)
+		}
+	}
+	backend := &testStateBackend{
+		header:        &types.Header{Number: common.Big1, Root: root},
+		stateDatabase: stateDatabase,
+	}
+	config := DefaultStateReaderConfig
+	return NewStateReader(backend, snaps, stateDatabase, &config), addresses
+}
+
+func TestStateReaderMatchesState(t *testing.T) {
+	ctx := context.Background()
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	for _, withSnapshot := range []bool{true, false} {
+		reader, addresses := newTestStateReader(t, 64, withSnapshot)
+		for i, addr := range addresses {
+			balance, err := reader.Balance(ctx, addr, latest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			nonce, err := reader.Nonce(ctx, addr, latest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			value, err := reader.Storage(ctx, addr, testStorageSlot, latest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			code, err := reader.Code(ctx, addr, latest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if balance.Uint64() != uint64(i+1) || nonce != uint64(i) || value != common.BigToHash(big.NewInt(int64(i+1))) || !bytes.Equal(code, testAccountCode(i)) {
+				t.Errorf("snapshot %v: unexpected state of account %d: balance %v nonce %v storage %v code %x", withSnapshot, i, balance, nonce, value, code)
+			}
+		}
+		missing := common.HexToAddress("0xdead")
+		balance, err := reader.Balance(ctx, missing, latest)
+		if err != nil || balance.Sign() != 0 {
+			t.Errorf("snapshot %v: expected a missing account to have no balance, got %v (err %v)", withSnapshot, balance, err)
+		}
+		value, err := reader.Storage(ctx, missing, testStorageSlot, latest)
+		if err != nil || value != (common.Hash{}) {
+			t.Errorf("snapshot %v: expected a missing account to have empty storage, got %v (err %v)", withSnapshot, value, err)
+		}
+	}
+}
+
+func TestStateReaderCallState(t *testing.T) {
+	ctx := context.Background()
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	for _, withSnapshot := range []bool{true, false} {
+		reader, addresses := newTestStateReader(t, 32, withSnapshot)
+		statedb, header, release, err := reader.CallState(ctx, latest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Number.Uint64() != 1 {
+			t.Errorf("snapshot %v: call state of block %v", withSnapshot, header.Number)
+		}
+		if len(reader.workers) != 1 {
+			t.Errorf("snapshot %v: call state doesn't hold a worker", withSnapshot)
+		}
+		for i, addr := range addresses {
+			if statedb.GetBalance(addr).Uint64() != uint64(i+1) || statedb.GetNonce(addr) != uint64(i) || !bytes.Equal(statedb.GetCode(addr), testAccountCode(i)) {
+				t.Errorf("snapshot %v: unexpected call state of account %d", withSnapshot, i)
+			}
+		}
+		release()
+		if len(reader.workers) != 0 {
+			t.Errorf("snapshot %v: worker not released after the call", withSnapshot)
+		}
+	}
+}
+
+func TestDecodeStorageKey(t *testing.T) {
+	for input, expected := range map[string]common.Hash{
+		"0x1":  common.BigToHash(common.Big1),
+		"0x01": common.BigToHash(common.Big1),
+		"0x":   {},
+		"ff":   common.BigToHash(big.NewInt(255)),
+	} {
+		key, err := decodeStorageKey(input)
+		if err != nil || key != expected {
+			t.Errorf("decodeStorageKey(%q) = %v, %v; expected %v", input, key, err, expected)
+		}
+	}
+	if _, err := decodeStorageKey("0x" + common.Hash{}.Hex()[2:] + "00"); err == nil {
+		t.Error("expected a key longer than 32 bytes to be rejected")
+	}
+}
+
+// BenchmarkStateReads compares concurrent reads served from snapshot layers against
+// reads that open a state database per request.
+func BenchmarkStateReads(b *testing.B) {
+	ctx := context.Background()
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	for _, withSnapshot := range []bool{true, false} {
+		b.Run(fmt.Sprintf("snapshot=%v", withSnapshot), func(b *testing.B) {
+			reader, addresses := newTestStateReader(b, 10_000, withSnapshot)
+			var next atomic.Uint64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					addr := addresses[next.Add(1)%uint64(len(addresses))]
+					if _, err := reader.Storage(ctx, addr, testStorageSlot, latest); err != nil {
+						b.Error(err)
+						return
+					}
+					if _, err := reader.Balance(ctx, addr, latest); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,390 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"runtime"
+	"sync"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/arbos"
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/execution"
+	"github.com/offchainlabs/nitro/util/rpcclient"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+var (
+	statelessFollowerHeadGauge       = metrics.NewRegisteredGauge("arb/statelessfollower/head", nil)
+	statelessFollowerAppliedCounter  = metrics.NewRegisteredCounter("arb/statelessfollower/applied", nil)
+	statelessFollowerMismatchCounter = metrics.NewRegisteredCounter("arb/statelessfollower/mismatch", nil)
+)
+
+var ErrStateNotWitnessed = errors.New("state not covered by the retained block witnesses")
+
+type StatelessFollowerConfig struct {
+	Source        rpcclient.ClientConfig `koanf:"source" reload:"hot"`
+	PollInterval  time.Duration          `koanf:"poll-interval"`
+	RetainBlocks  int                    `koanf:"retain-blocks"`
+	BlocksPerPoll int                    `koanf:"blocks-per-poll"`
+}
+
+var DefaultStatelessFollowerConfig = StatelessFollowerConfig{
+	Source:        rpcclient.DefaultClientConfig,
+	PollInterval:  250 * time.Millisecond,
+	RetainBlocks:  128,
+	BlocksPerPoll: 64,
+}
+
+func StatelessFollowerConfigAddOptions(prefix string, f *flag.FlagSet) {
+	rpcclient.RPCClientAddOptions(prefix+".source", f, &DefaultStatelessFollowerConfig.Source)
+	f.Duration(prefix+".poll-interval", DefaultStatelessFollowerConfig.PollInterval, "how often to poll the source for new blocks once caught up")
+	f.Int(prefix+".retain-blocks", DefaultStatelessFollowerConfig.RetainBlocks, "number of recent blocks to keep the witnessed state of, and so serve reads of")
+	f.Int(prefix+".blocks-per-poll", DefaultStatelessFollowerConfig.BlocksPerPoll, "maximum number of blocks to apply per poll")
+}
+
+func (c *StatelessFollowerConfig) Validate() error {
+	if c.RetainBlocks < 1 {
+		return errors.New("stateless follower must retain at least one block")
+	}
+	if c.BlocksPerPoll < 1 {
+		return errors.New("stateless follower must apply at least one block per poll")
+	}
+	return c.Source.Validate()
+}
+
+// followedBlock is a block the follower applied, with the state its witness covered and applying it changed.
+type followedBlock struct {
+	header *types.Header
+	db     ethdb.Database
+}
+
+// StatelessFollower follows a chain by applying each block to the state in its witness, rather than to the full
+// state, and checking it gets the block hash, and so the state root, the source has. It starts from the source's
+// head, which it trusts, so it only ever has the state recent blocks touched. Witnesses are fetched from a node
+// serving arb_blockWitness, normally the sequencer or a node right behind it.
+type StatelessFollower struct {
+	stopwaiter.StopWaiter
+	config      *StatelessFollowerConfig
+	chainConfig *params.ChainConfig
+	client      *rpcclient.RpcClient
+
+	blocksMutex sync.RWMutex
+	blocks      []*followedBlock // oldest first, so the last is the head
+	byHash      map[common.Hash]*followedBlock
+}
+
+func NewStatelessFollower(config *StatelessFollowerConfig, chainConfig *params.ChainConfig) *StatelessFollower {
+	return &StatelessFollower{
+		config:      config,
+		chainConfig: chainConfig,
+		client:      rpcclient.NewRpcClient(func() *rpcclient.ClientConfig { return &config.Source }, nil),
+		byHash:      make(map[common.Hash]*followedBlock),
+	}
+}
+
+func (f *StatelessFollower) Start(ctxIn context.Context) error {
+	f.StopWaiter.Start(ctxIn, f)
+	ctx := f.GetContext()
+	if err := f.client.Start(ctx); err != nil {
+		return fmt.Errorf("failed to connect to the stateless follower source: %w", err)
+	}
+	if err := f.resync(ctx); err != nil {
+		return err
+	}
+	f.CallIteratively(f.poll)
+	return nil
+}
+
+func (f *StatelessFollower) StopAndWait() {
+	f.StopWaiter.StopAndWait()
+	f.client.Close()
+}
+
+func (f *StatelessFollower) sourceHeader(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	var header *types.Header
+	if err := f.client.CallContext(ctx, &header, "eth_getBlockByNumber", number, false); err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("source doesn't have block %v", number)
+	}
+	return header, nil
+}
+
+// resync drops the followed blocks and starts over from the source's head, whose state is then empty.
+func (f *StatelessFollower) resync(ctx context.Context) error {
+	header, err := f.sourceHeader(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return err
+	}
+	log.Info("stateless follower starting from the source's head", "block", header.Number, "hash", header.Hash())
+	f.blocksMutex.Lock()
+	defer f.blocksMutex.Unlock()
+	f.blocks = []*followedBlock{{header: header, db: rawdb.NewMemoryDatabase()}}
+	f.byHash = map[common.Hash]*followedBlock{header.Hash(): f.blocks[0]}
+	statelessFollowerHeadGauge.Update(header.Number.Int64())
+	return nil
+}
+
+func (f *StatelessFollower) head() *followedBlock {
+	f.blocksMutex.RLock()
+	defer f.blocksMutex.RUnlock()
+	return f.blocks[len(f.blocks)-1]
+}
+
+func (f *StatelessFollower) poll(ctx context.Context) time.Duration {
+	var sourceHead hexutil.Uint64
+	if err := f.client.CallContext(ctx, &sourceHead, "eth_blockNumber"); err != nil {
+		log.Warn("stateless follower failed reading the source's head", "err", err)
+		return f.config.PollInterval
+	}
+	for i := 0; i < f.config.BlocksPerPoll; i++ {
+		parent := f.head()
+		number := parent.header.Number.Uint64() + 1
+		if number > uint64(sourceHead) {
+			return f.config.PollInterval
+		}
+		pos := arbutil.BlockNumberToMessageCount(number, f.chainConfig.ArbitrumChainParams.GenesisBlockNum) - 1
+		var witness execution.BlockWitness
+		if err := f.client.CallContext(ctx, &witness, "arb_blockWitness", hexutil.Uint64(pos)); err != nil {
+			log.Warn("stateless follower failed fetching a block witness", "block", number, "err", err)
+			return f.config.PollInterval
+		}
+		block, err := f.apply(parent, &witness)
+		if err != nil {
+			// most likely the source reorged, so the witness wasn't for our head
+			log.Warn("stateless follower failed applying a block witness, resyncing", "block", number, "err", err)
+			if err := f.resync(ctx); err != nil {
+				log.Warn("stateless follower failed resyncing", "err", err)
+			}
+			return f.config.PollInterval
+		}
+		f.push(block)
+	}
+	return 0
+}
+
+// apply applies the witness's block on parent, using only the state in the witness.
+func (f *StatelessFollower) apply(parent *followedBlock, witness *execution.BlockWitness) (*followedBlock, error) {
+	if witness.Message == nil || witness.Message.Message == nil {
+		return nil, errors.New("witness has no message")
+	}
+	db := rawdb.NewMemoryDatabase()
+	preimages := make(map[common.Hash][]byte, len(witness.Preimages))
+	for _, preimage := range witness.Preimages {
+		hash := crypto.Keccak256Hash(preimage)
+		preimages[hash] = preimage
+		// preimages are both trie nodes, stored by hash, and code
+		if err := db.Put(hash.Bytes(), preimage); err != nil {
+			return nil, err
+		}
+		rawdb.WriteCode(db, hash, preimage)
+	}
+	if len(witness.UserWasms) > 0 && witness.StylusArch != runtime.GOARCH {
+		return nil, fmt.Errorf("witness has Stylus programs activated for %v, but this node runs on %v", witness.StylusArch, runtime.GOARCH)
+	}
+	// activated programs are read from the wasm store rather than the state, so they're written there too
+	for moduleHash, wasm := range witness.UserWasms {
+		rawdb.WriteActivation(db, moduleHash, wasm.Asm, wasm.Module)
+	}
+	stateDatabase := state.NewDatabase(db)
+	statedb, err := state.New(parent.header.Root, stateDatabase, nil)
+	if err != nil {
+		return nil, err
+	}
+	chainContext := &followerChainContext{follower: f, preimages: preimages}
+	block, _, err := arbos.ProduceBlock(witness.Message.Message, witness.Message.DelayedMessagesRead, parent.header, statedb, chainContext, f.chainConfig, false)
+	if err != nil {
+		return nil, err
+	}
+	if block.Hash() != witness.BlockHash {
+		statelessFollowerMismatchCounter.Inc(1)
+		return nil, fmt.Errorf("applying block %v got hash %v and root %v, but the source has hash %v", block.Number(), block.Hash(), block.Root(), witness.BlockHash)
+	}
+	root, err := statedb.Commit(block.NumberU64(), true)
+	if err != nil {
+		return nil, err
+	}
+	if err := stateDatabase.TrieDB().Commit(root, false); err != nil {
+		return nil, err
+	}
+	statelessFollowerAppliedCounter.Inc(1)
+	return &followedBlock{header: block.Header(), db: db}, nil
+}
+
+func (f *StatelessFollower) push(block *followedBlock) {
+	f.blocksMutex.Lock()
+	defer f.blocksMutex.Unlock()
+	f.blocks = append(f.blocks, block)
+	f.byHash[block.header.Hash()] = block
+	for len(f.blocks) > f.config.RetainBlocks {
+		delete(f.byHash, f.blocks[0].header.Hash())
+		f.blocks = f.blocks[1:]
+	}
+	statelessFollowerHeadGauge.Update(block.header.Number.Int64())
+}
+
+// StateAndHeader returns the state of a retained block. Trie nodes are addressed by their hash, so the state of
+// any block can be read from the nodes of every retained witness, but accounts and slots none of them touched
+// can't be read.
+func (f *StatelessFollower) StateAndHeader(blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	f.blocksMutex.RLock()
+	defer f.blocksMutex.RUnlock()
+	var block *followedBlock
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		block = f.byHash[hash]
+	} else if number, ok := blockNrOrHash.Number(); ok {
+		head := f.blocks[len(f.blocks)-1]
+		oldest := f.blocks[0].header.Number.Int64()
+		switch {
+		case number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber:
+			block = head
+		case number >= 0 && int64(number) >= oldest && int64(number) <= head.header.Number.Int64():
+			block = f.blocks[int64(number)-oldest]
+		}
+	}
+	if block == nil {
+		return nil, nil, fmt.Errorf("%w: block %v isn't retained", ErrStateNotWitnessed, blockNrOrHash)
+	}
+	layers := make([]ethdb.KeyValueReader, 0, len(f.blocks))
+	for i := len(f.blocks) - 1; i >= 0; i-- {
+		layers = append(layers, f.blocks[i].db)
+	}
+	statedb, err := state.New(block.header.Root, state.NewDatabase(&witnessedDb{Database: block.db, layers: layers}), nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrStateNotWitnessed, err)
+	}
+	return statedb, block.header, nil
+}
+
+// witnessedDb reads from the retained witnesses, newest first.
+type witnessedDb struct {
+	ethdb.Database
+	layers []ethdb.KeyValueReader
+}
+
+func (db *witnessedDb) Has(key []byte) (bool, error) {
+	for _, layer := range db.layers {
+		if has, err := layer.Has(key); err != nil || has {
+			return has, err
+		}
+	}
+	return false, nil
+}
+
+func (db *witnessedDb) Get(key []byte) ([]byte, error) {
+	for _, layer := range db.layers {
+		if has, _ := layer.Has(key); has {
+			return layer.Get(key)
+		}
+	}
+	return db.Database.Get(key)
+}
+
+// followerChainContext finds headers among the followed blocks and the witness, which has any the block reads.
+type followerChainContext struct {
+	follower  *StatelessFollower
+	preimages map[common.Hash][]byte
+}
+
+func (c *followerChainContext) Engine() consensus.Engine {
+	return arbos.Engine{IsSequencer: false}
+}
+
+func (c *followerChainContext) GetHeader(hash common.Hash, number uint64) *types.Header {
+	c.follower.blocksMutex.RLock()
+	block := c.follower.byHash[hash]
+	c.follower.blocksMutex.RUnlock()
+	if block != nil {
+		return block.header
+	}
+	enc, ok := c.preimages[hash]
+	if !ok {
+		return nil
+	}
+	var header types.Header
+	if err := rlp.DecodeBytes(enc, &header); err != nil || header.Number.Uint64() != number {
+		return nil
+	}
+	return &header
+}
+
+// StatelessFollowerAPI serves the eth namespace's state reads from a stateless follower.
+type StatelessFollowerAPI struct {
+	follower *StatelessFollower
+}
+
+func NewStatelessFollowerAPI(follower *StatelessFollower) *StatelessFollowerAPI {
+	return &StatelessFollowerAPI{follower}
+}
+
+// read runs fn on the state of the block, failing if the state it read wasn't witnessed.
+func (api *StatelessFollowerAPI) read(blockNrOrHash rpc.BlockNumberOrHash, fn func(*state.StateDB)) error {
+	statedb, _, err := api.follower.StateAndHeader(blockNrOrHash)
+	if err != nil {
+		return err
+	}
+	fn(statedb)
+	if err := statedb.Error(); err != nil {
+		return fmt.Errorf("%w: %w", ErrStateNotWitnessed, err)
+	}
+	return nil
+}
+
+func (api *StatelessFollowerAPI) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(api.follower.chainConfig.ChainID)
+}
+
+func (api *StatelessFollowerAPI) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(api.follower.head().header.Number.Uint64())
+}
+
+func (api *StatelessFollowerAPI) GetBalance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error) {
+	var balance *big.Int
+	err := api.read(blockNrOrHash, func(statedb *state.StateDB) { balance = statedb.GetBalance(address).ToBig() })
+	return (*hexutil.Big)(balance), err
+}
+
+func (api *StatelessFollowerAPI) GetTransactionCount(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Uint64, error) {
+	var nonce uint64
+	err := api.read(blockNrOrHash, func(statedb *state.StateDB) { nonce = statedb.GetNonce(address) })
+	return (*hexutil.Uint64)(&nonce), err
+}
+
+func (api *StatelessFollowerAPI) GetCode(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	var code []byte
+	err := api.read(blockNrOrHash, func(statedb *state.StateDB) { code = statedb.GetCode(address) })
+	return code, err
+}
+
+func (api *StatelessFollowerAPI) GetStorageAt(ctx context.Context, address common.Address, hexKey string, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	key, err := decodeStorageKey(hexKey)
+	if err != nil {
+		return nil, err
+	}
+	var value common.Hash
+	err = api.read(blockNrOrHash, func(statedb *state.StateDB) { value = statedb.GetState(address, key) })
+	return value.Bytes(), err
+}
@@ -0,0 +1,87 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/holiman/uint256"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/execution"
+)
+
+func TestStatelessFollowerReads(t *testing.T) {
+	ctx := context.Background()
+	config := DefaultStatelessFollowerConfig
+	config.RetainBlocks = 2
+	follower := NewStatelessFollower(&config, params.ArbitrumDevTestChainConfig())
+	api := NewStatelessFollowerAPI(follower)
+
+	// the first block's witness has an account, which the later blocks don't touch
+	db := rawdb.NewMemoryDatabase()
+	stateDatabase := state.NewDatabase(db)
+	statedb, err := state.New(types.EmptyRootHash, stateDatabase, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := common.Address{1}
+	statedb.SetBalance(addr, uint256.NewInt(100))
+	root, err := statedb.Commit(0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stateDatabase.TrieDB().Commit(root, false); err != nil {
+		t.Fatal(err)
+	}
+	follower.blocks = []*followedBlock{{header: &types.Header{Number: big.NewInt(1), Root: root}, db: db}}
+	follower.byHash = map[common.Hash]*followedBlock{}
+	follower.push(&followedBlock{header: &types.Header{Number: big.NewInt(2), Root: root}, db: rawdb.NewMemoryDatabase()})
+
+	// the state of the second block is read from the first's witness
+	balance, err := api.GetBalance(ctx, addr, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.ToInt().Uint64() != 100 {
+		t.Fatal("unexpected balance", balance)
+	}
+	if api.BlockNumber() != 2 {
+		t.Fatal("unexpected block number", api.BlockNumber())
+	}
+
+	// the first block is dropped once more are retained, and with it the state only it witnessed
+	follower.push(&followedBlock{header: &types.Header{Number: big.NewInt(3), Root: root}, db: rawdb.NewMemoryDatabase()})
+	if _, err := api.GetBalance(ctx, addr, rpc.BlockNumberOrHashWithNumber(1)); !errors.Is(err, ErrStateNotWitnessed) {
+		t.Fatal("read a block that's no longer retained, got", err)
+	}
+	if _, err := api.GetBalance(ctx, addr, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)); !errors.Is(err, ErrStateNotWitnessed) {
+		t.Fatal("read state no retained witness has, got", err)
+	}
+}
+
+func TestStatelessFollowerRejectsForeignStylusArch(t *testing.T) {
+	config := DefaultStatelessFollowerConfig
+	follower := NewStatelessFollower(&config, params.ArbitrumDevTestChainConfig())
+	parent := &followedBlock{header: &types.Header{Number: big.NewInt(1), Root: types.EmptyRootHash}, db: rawdb.NewMemoryDatabase()}
+	witness := &execution.BlockWitness{
+		Message:    &arbostypes.MessageWithMetadata{Message: &arbostypes.L1IncomingMessage{Header: &arbostypes.L1IncomingMessageHeader{}}},
+		StylusArch: "not-" + runtime.GOARCH,
+		UserWasms:  map[common.Hash]execution.WitnessWasm{{1}: {Asm: []byte{1}, Module: []byte{2}}},
+	}
+	if _, err := follower.apply(parent, witness); err == nil || !strings.Contains(err.Error(), "Stylus") {
+		t.Fatal("applied a witness with asm for another architecture, got", err)
+	}
+}
@@ -107,6 +107,30 @@ func (s *SyncMonitor) FinalizedBlockNumber(ctx context.Context) (uint64, error)
 	return block, nil
 }
 
+// PublishedBlockNumber returns the last block the chosen sequencer published through the sequencer
+// coordinator, or this node's head if it hasn't built that block yet.
+func (s *SyncMonitor) PublishedBlockNumber(ctx context.Context) (uint64, error) {
+	if s.consensus == nil {
+		return 0, errors.New("not set up for published block")
+	}
+	msg, err := s.consensus.GetPublishedMsgCount(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if msg == 0 {
+		return 0, errors.New("no messages published yet")
+	}
+	block := s.exec.MessageIndexToBlockNumber(msg - 1)
+	header, err := s.exec.getCurrentHeader()
+	if err != nil {
+		return 0, err
+	}
+	if header.Number.Uint64() < block {
+		block = header.Number.Uint64()
+	}
+	return block, nil
+}
+
 func (s *SyncMonitor) Synced() bool {
 	if s.consensus.Synced() {
 		built, err := s.exec.HeadMessageNumber()
@@ -0,0 +1,148 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	syncSendIncludedHistogram = metrics.NewRegisteredHistogram("arb/rpc/sendrawtransactionsync/included", nil, metrics.NewBoundedHistogramSample())
+	syncSendTimeoutCounter    = metrics.NewRegisteredCounter("arb/rpc/sendrawtransactionsync/timeout", nil)
+)
+
+var ErrSyncSendTimeout = errors.New("transaction submitted but not yet included in a block")
+
+type SendRawTransactionSyncConfig struct {
+	Enable         bool          `koanf:"enable"`
+	DefaultTimeout time.Duration `koanf:"default-timeout"`
+	MaxTimeout     time.Duration `koanf:"max-timeout"`
+}
+
+var DefaultSendRawTransactionSyncConfig = SendRawTransactionSyncConfig{
+	Enable:         false,
+	DefaultTimeout: 2 * time.Second,
+	MaxTimeout:     10 * time.Second,
+}
+
+func SendRawTransactionSyncConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultSendRawTransactionSyncConfig.Enable, "serve eth_sendRawTransactionSync, which only returns once the transaction is included in a block")
+	f.Duration(prefix+".default-timeout", DefaultSendRawTransactionSyncConfig.DefaultTimeout, "how long eth_sendRawTransactionSync waits for the transaction's block when the request doesn't give a timeout")
+	f.Duration(prefix+".max-timeout", DefaultSendRawTransactionSyncConfig.MaxTimeout, "the longest timeout a eth_sendRawTransactionSync request can ask for")
+}
+
+func (c *SendRawTransactionSyncConfig) Validate() error {
+	if c.DefaultTimeout <= 0 || c.MaxTimeout < c.DefaultTimeout {
+		return errors.New("send-raw-transaction-sync timeouts must be positive, and max-timeout at least default-timeout")
+	}
+	return nil
+}
+
+// SendRawTransactionSyncAPI submits transactions and waits for this node to have the block including them,
+// so latency sensitive clients don't have to poll for receipts. It's registered in the eth namespace.
+type SendRawTransactionSyncAPI struct {
+	publisher  TransactionPublisher
+	blockchain *core.BlockChain
+	chainDb    ethdb.Database
+	config     *SendRawTransactionSyncConfig
+}
+
+func NewSendRawTransactionSyncAPI(publisher TransactionPublisher, blockchain *core.BlockChain, chainDb ethdb.Database, config *SendRawTransactionSyncConfig) *SendRawTransactionSyncAPI {
+	return &SendRawTransactionSyncAPI{publisher, blockchain, chainDb, config}
+}
+
+type SyncTransactionResult struct {
+	TransactionHash   common.Hash     `json:"transactionHash"`
+	BlockHash         common.Hash     `json:"blockHash"`
+	BlockNumber       hexutil.Uint64  `json:"blockNumber"`
+	TransactionIndex  hexutil.Uint64  `json:"transactionIndex"`
+	Status            hexutil.Uint64  `json:"status"`
+	GasUsed           hexutil.Uint64  `json:"gasUsed"`
+	EffectiveGasPrice *hexutil.Big    `json:"effectiveGasPrice"`
+	ContractAddress   *common.Address `json:"contractAddress,omitempty"`
+}
+
+// SendRawTransactionSync submits the transaction like eth_sendRawTransaction, then waits up to timeout
+// milliseconds for it to be included in a block. A transaction the sequencer rejects returns its error.
+// If it isn't included in time, the error says so and the transaction may still be included later.
+func (api *SendRawTransactionSyncAPI) SendRawTransactionSync(ctx context.Context, input hexutil.Bytes, timeout *hexutil.Uint64) (*SyncTransactionResult, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return nil, err
+	}
+	wait := api.config.DefaultTimeout
+	if timeout != nil {
+		wait = min(time.Duration(*timeout)*time.Millisecond, api.config.MaxTimeout)
+	}
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+	start := time.Now()
+
+	// subscribe before submitting so the block including the transaction can't be missed
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := api.blockchain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	// a sequencer only returns once the transaction is in a block, and a forwarder once its sequencer has,
+	// so usually this node only needs to wait for the block if it's not the sequencer
+	if err := api.publisher.PublishTransaction(ctx, tx, nil); err != nil {
+		return nil, err
+	}
+	for {
+		result, err := api.lookup(tx.Hash())
+		if err != nil || result != nil {
+			if result != nil {
+				syncSendIncludedHistogram.Update(time.Since(start).Microseconds())
+			}
+			return result, err
+		}
+		select {
+		case <-heads:
+		case err := <-sub.Err():
+			return nil, err
+		case <-ctx.Done():
+			syncSendTimeoutCounter.Inc(1)
+			return nil, fmt.Errorf("%w: %v after %v", ErrSyncSendTimeout, tx.Hash(), wait)
+		}
+	}
+}
+
+// lookup returns the transaction's result, or nil if it's not in a block yet.
+func (api *SendRawTransactionSyncAPI) lookup(hash common.Hash) (*SyncTransactionResult, error) {
+	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(api.chainDb, hash)
+	if tx == nil {
+		return nil, nil
+	}
+	receipts := api.blockchain.GetReceiptsByHash(blockHash)
+	if index >= uint64(len(receipts)) {
+		return nil, fmt.Errorf("block %v is missing the receipt of transaction %v", blockHash, hash)
+	}
+	receipt := receipts[index]
+	result := &SyncTransactionResult{
+		TransactionHash:   hash,
+		BlockHash:         blockHash,
+		BlockNumber:       hexutil.Uint64(blockNumber),
+		TransactionIndex:  hexutil.Uint64(index),
+		Status:            hexutil.Uint64(receipt.Status),
+		GasUsed:           hexutil.Uint64(receipt.GasUsed),
+		EffectiveGasPrice: (*hexutil.Big)(receipt.EffectiveGasPrice),
+	}
+	if tx.To() == nil {
+		result.ContractAddress = &receipt.ContractAddress
+	}
+	return result, nil
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/offchainlabs/nitro/arbos/arbosState"
 	"github.com/offchainlabs/nitro/arbos/l1pricing"
+	"github.com/offchainlabs/nitro/execution"
 	"github.com/offchainlabs/nitro/util/arbmath"
 	"github.com/offchainlabs/nitro/util/headerreader"
 	flag "github.com/spf13/pflag"
@@ -133,7 +134,7 @@ func PreCheckTx(bc *core.BlockChain, chainConfig *params.ChainConfig, header *ty
 		}
 	}
 	if arbmath.BigLessThan(tx.GasFeeCap(), baseFee) {
-		return fmt.Errorf("%w: address %v, maxFeePerGas: %s baseFee: %s", core.ErrFeeCapTooLow, sender, tx.GasFeeCap(), header.BaseFee)
+		return execution.WithErrorCode(execution.ErrorCodeBelowBaseFee, fmt.Errorf("%w: address %v, maxFeePerGas: %s baseFee: %s", core.ErrFeeCapTooLow, sender, tx.GasFeeCap(), header.BaseFee))
 	}
 	stateNonce := statedb.GetNonce(sender)
 	if tx.Nonce() < stateNonce {
@@ -153,7 +154,7 @@ func PreCheckTx(bc *core.BlockChain, chainConfig *params.ChainConfig, header *ty
 	if options != nil {
 		if err := options.Check(extraInfo.L1BlockNumber, header.Time, statedb); err != nil {
 			conditionalTxRejectedByTxPreCheckerCurrentStateCounter.Inc(1)
-			return err
+			return execution.WithErrorCode(execution.ErrorCodeConditionalCheckFailed, err)
 		}
 		conditionalTxAcceptedByTxPreCheckerCurrentStateCounter.Inc(1)
 		if config.RequiredStateAge > 0 {
@@ -179,7 +180,7 @@ func PreCheckTx(bc *core.BlockChain, chainConfig *params.ChainConfig, header *ty
 				oldExtraInfo := types.DeserializeHeaderExtraInformation(oldHeader)
 				if err := options.Check(oldExtraInfo.L1BlockNumber, oldHeader.Time, secondOldStatedb); err != nil {
 					conditionalTxRejectedByTxPreCheckerOldStateCounter.Inc(1)
-					return arbitrum_types.WrapOptionsCheckError(err, "conditions check failed for old state")
+					return execution.WithErrorCode(execution.ErrorCodeConditionalCheckFailed, arbitrum_types.WrapOptionsCheckError(err, "conditions check failed for old state"))
 				}
 			}
 			conditionalTxAcceptedByTxPreCheckerOldStateCounter.Inc(1)
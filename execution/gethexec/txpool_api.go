@@ -0,0 +1,261 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/offchainlabs/nitro/util/arbmath"
+)
+
+type trackedTx struct {
+	tx     *types.Transaction
+	sender common.Address
+}
+
+// TxPoolTracker keeps track of the transactions the node is in the middle of publishing,
+// i.e. waiting in the sequencer's queues or being forwarded to the sequencer.
+// Nitro has no mempool, but these are the closest equivalent, and are served through the txpool namespace.
+type TxPoolTracker struct {
+	signer types.Signer
+
+	mutex sync.Mutex
+	txs   map[common.Hash]trackedTx
+}
+
+func NewTxPoolTracker(chainConfig *params.ChainConfig) *TxPoolTracker {
+	return &TxPoolTracker{
+		signer: types.LatestSigner(chainConfig),
+		txs:    make(map[common.Hash]trackedTx),
+	}
+}
+
+// track records a transaction until the returned function is called once it's been published.
+func (t *TxPoolTracker) track(tx *types.Transaction) func() {
+	sender, err := types.Sender(t.signer, tx)
+	if err != nil {
+		// leave rejecting the transaction to the publisher
+		return func() {}
+	}
+	hash := tx.Hash()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if _, duplicate := t.txs[hash]; duplicate {
+		return func() {}
+	}
+	t.txs[hash] = trackedTx{tx: tx, sender: sender}
+	return func() {
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+		delete(t.txs, hash)
+	}
+}
+
+// txPoolContents splits the tracked transactions into pending ones, which continue their sender's
+// nonce sequence, and queued ones, which come after a nonce gap. Transactions with a nonce below
+// the sender's current one are about to be rejected or already included, and are left out.
+type txPoolContents struct {
+	pending map[common.Address][]*types.Transaction
+	queued  map[common.Address][]*types.Transaction
+}
+
+func (t *TxPoolTracker) contents(nonceAt func(common.Address) uint64) txPoolContents {
+	t.mutex.Lock()
+	bySender := make(map[common.Address][]*types.Transaction)
+	for _, tracked := range t.txs {
+		bySender[tracked.sender] = append(bySender[tracked.sender], tracked.tx)
+	}
+	t.mutex.Unlock()
+
+	contents := txPoolContents{
+		pending: make(map[common.Address][]*types.Transaction),
+		queued:  make(map[common.Address][]*types.Transaction),
+	}
+	for sender, txs := range bySender {
+		sort.Slice(txs, func(i, j int) bool { return txs[i].Nonce() < txs[j].Nonce() })
+		nonce := nonceAt(sender)
+		for _, tx := range txs {
+			if tx.Nonce() < nonce {
+				continue
+			}
+			if tx.Nonce() == nonce {
+				contents.pending[sender] = append(contents.pending[sender], tx)
+				nonce++
+			} else {
+				contents.queued[sender] = append(contents.queued[sender], tx)
+			}
+		}
+	}
+	return contents
+}
+
+// TxPoolAPI emulates geth's txpool namespace over the transactions tracked by a TxPoolTracker,
+// so monitoring tools built for it work against nitro nodes.
+type TxPoolAPI struct {
+	tracker    *TxPoolTracker
+	blockchain *core.BlockChain
+}
+
+func NewTxPoolAPI(tracker *TxPoolTracker, blockchain *core.BlockChain) *TxPoolAPI {
+	return &TxPoolAPI{tracker, blockchain}
+}
+
+func (api *TxPoolAPI) contents() (txPoolContents, *types.Header, error) {
+	header := api.blockchain.CurrentBlock()
+	statedb, err := api.blockchain.StateAt(header.Root)
+	if err != nil {
+		return txPoolContents{}, nil, err
+	}
+	return api.tracker.contents(statedb.GetNonce), header, nil
+}
+
+func (api *TxPoolAPI) Status() (map[string]hexutil.Uint, error) {
+	contents, _, err := api.contents()
+	if err != nil {
+		return nil, err
+	}
+	count := func(txs map[common.Address][]*types.Transaction) hexutil.Uint {
+		total := 0
+		for _, senderTxs := range txs {
+			total += len(senderTxs)
+		}
+		return hexutil.Uint(total)
+	}
+	return map[string]hexutil.Uint{
+		"pending": count(contents.pending),
+		"queued":  count(contents.queued),
+	}, nil
+}
+
+// TxPoolTransaction is a pending transaction in the format of geth's txpool_content.
+type TxPoolTransaction struct {
+	BlockHash        *common.Hash      `json:"blockHash"`
+	BlockNumber      *hexutil.Big      `json:"blockNumber"`
+	From             common.Address    `json:"from"`
+	Gas              hexutil.Uint64    `json:"gas"`
+	GasPrice         *hexutil.Big      `json:"gasPrice"`
+	GasFeeCap        *hexutil.Big      `json:"maxFeePerGas,omitempty"`
+	GasTipCap        *hexutil.Big      `json:"maxPriorityFeePerGas,omitempty"`
+	Hash             common.Hash       `json:"hash"`
+	Input            hexutil.Bytes     `json:"input"`
+	Nonce            hexutil.Uint64    `json:"nonce"`
+	To               *common.Address   `json:"to"`
+	TransactionIndex *hexutil.Uint64   `json:"transactionIndex"`
+	Value            *hexutil.Big      `json:"value"`
+	Type             hexutil.Uint64    `json:"type"`
+	Accesses         *types.AccessList `json:"accessList,omitempty"`
+	ChainID          *hexutil.Big      `json:"chainId,omitempty"`
+	V                *hexutil.Big      `json:"v"`
+	R                *hexutil.Big      `json:"r"`
+	S                *hexutil.Big      `json:"s"`
+}
+
+func newTxPoolTransaction(tx *types.Transaction, sender common.Address, baseFee *big.Int) *TxPoolTransaction {
+	v, r, s := tx.RawSignatureValues()
+	result := &TxPoolTransaction{
+		From:     sender,
+		Gas:      hexutil.Uint64(tx.Gas()),
+		GasPrice: (*hexutil.Big)(tx.GasPrice()),
+		Hash:     tx.Hash(),
+		Input:    hexutil.Bytes(tx.Data()),
+		Nonce:    hexutil.Uint64(tx.Nonce()),
+		To:       tx.To(),
+		Value:    (*hexutil.Big)(tx.Value()),
+		Type:     hexutil.Uint64(tx.Type()),
+		V:        (*hexutil.Big)(v),
+		R:        (*hexutil.Big)(r),
+		S:        (*hexutil.Big)(s),
+	}
+	if tx.Type() != types.LegacyTxType {
+		accessList := tx.AccessList()
+		result.Accesses = &accessList
+		result.ChainID = (*hexutil.Big)(tx.ChainId())
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		result.GasFeeCap = (*hexutil.Big)(tx.GasFeeCap())
+		result.GasTipCap = (*hexutil.Big)(tx.GasTipCap())
+		// like geth, report the price the transaction would pay in the next block
+		if baseFee != nil {
+			result.GasPrice = (*hexutil.Big)(arbmath.BigMin(tx.GasFeeCap(), arbmath.BigAdd(baseFee, tx.GasTipCap())))
+		}
+	}
+	return result
+}
+
+func (api *TxPoolAPI) rpcTransactions(txs []*types.Transaction, header *types.Header) map[string]*TxPoolTransaction {
+	result := make(map[string]*TxPoolTransaction, len(txs))
+	for _, tx := range txs {
+		sender, _ := types.Sender(api.tracker.signer, tx)
+		result[fmt.Sprint(tx.Nonce())] = newTxPoolTransaction(tx, sender, header.BaseFee)
+	}
+	return result
+}
+
+func (api *TxPoolAPI) Content() (map[string]map[string]map[string]*TxPoolTransaction, error) {
+	contents, header, err := api.contents()
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]map[string]map[string]*TxPoolTransaction{
+		"pending": make(map[string]map[string]*TxPoolTransaction, len(contents.pending)),
+		"queued":  make(map[string]map[string]*TxPoolTransaction, len(contents.queued)),
+	}
+	for sender, txs := range contents.pending {
+		result["pending"][sender.Hex()] = api.rpcTransactions(txs, header)
+	}
+	for sender, txs := range contents.queued {
+		result["queued"][sender.Hex()] = api.rpcTransactions(txs, header)
+	}
+	return result, nil
+}
+
+func (api *TxPoolAPI) ContentFrom(addr common.Address) (map[string]map[string]*TxPoolTransaction, error) {
+	contents, header, err := api.contents()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]map[string]*TxPoolTransaction{
+		"pending": api.rpcTransactions(contents.pending[addr], header),
+		"queued":  api.rpcTransactions(contents.queued[addr], header),
+	}, nil
+}
+
+func inspectTransactions(txs []*types.Transaction) map[string]string {
+	result := make(map[string]string, len(txs))
+	for _, tx := range txs {
+		if to := tx.To(); to != nil {
+			result[fmt.Sprint(tx.Nonce())] = fmt.Sprintf("%s: %v wei + %v gas × %v wei", to.Hex(), tx.Value(), tx.Gas(), tx.GasFeeCap())
+		} else {
+			result[fmt.Sprint(tx.Nonce())] = fmt.Sprintf("contract creation: %v wei + %v gas × %v wei", tx.Value(), tx.Gas(), tx.GasFeeCap())
+		}
+	}
+	return result
+}
+
+func (api *TxPoolAPI) Inspect() (map[string]map[string]map[string]string, error) {
+	contents, _, err := api.contents()
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]map[string]map[string]string{
+		"pending": make(map[string]map[string]string, len(contents.pending)),
+		"queued":  make(map[string]map[string]string, len(contents.queued)),
+	}
+	for sender, txs := range contents.pending {
+		result["pending"][sender.Hex()] = inspectTransactions(txs)
+	}
+	for sender, txs := range contents.queued {
+		result["queued"][sender.Hex()] = inspectTransactions(txs)
+	}
+	return result, nil
+}
@@ -0,0 +1,56 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package gethexec
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestTxPoolTrackerContents(t *testing.T) {
+	chainConfig := params.ArbitrumDevTestChainConfig()
+	tracker := NewTxPoolTracker(chainConfig)
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	signer := types.LatestSigner(chainConfig)
+	var untrack []func()
+	// nonce 4 is below the state nonce, 5 and 6 continue it, and 8 comes after a gap
+	for _, nonce := range []uint64{4, 5, 6, 8} {
+		tx := types.MustSignNewTx(key, signer, &types.DynamicFeeTx{
+			ChainID:   chainConfig.ChainID,
+			Nonce:     nonce,
+			GasFeeCap: big.NewInt(params.GWei),
+			Gas:       21000,
+			To:        &common.Address{1},
+		})
+		untrack = append(untrack, tracker.track(tx))
+		// publishing the same transaction twice doesn't track it twice
+		tracker.track(tx)()
+	}
+	nonceAt := func(common.Address) uint64 { return 5 }
+
+	contents := tracker.contents(nonceAt)
+	if len(contents.pending[sender]) != 2 || contents.pending[sender][0].Nonce() != 5 || contents.pending[sender][1].Nonce() != 6 {
+		t.Errorf("unexpected pending transactions %v", contents.pending[sender])
+	}
+	if len(contents.queued[sender]) != 1 || contents.queued[sender][0].Nonce() != 8 {
+		t.Errorf("unexpected queued transactions %v", contents.queued[sender])
+	}
+
+	for _, done := range untrack {
+		done()
+	}
+	contents = tracker.contents(nonceAt)
+	if len(contents.pending) != 0 || len(contents.queued) != 0 {
+		t.Error("expected published transactions to stop being tracked")
+	}
+}
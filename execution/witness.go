@@ -0,0 +1,32 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package execution
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbutil"
+)
+
+// BlockWitness is what a stateless follower needs to apply a block without the full state: the block's
+// message, and every preimage recorded producing it, which includes the trie nodes and code it touched.
+// Preimages are keyed by their hash, so they're sent without keys. The Stylus programs the block calls are
+// sent as activated, keyed by module hash, since they're stored outside the state; their asm is only usable
+// on the source's StylusArch.
+type BlockWitness struct {
+	Pos        arbutil.MessageIndex            `json:"pos"`
+	BlockHash  common.Hash                     `json:"blockHash"`
+	Message    *arbostypes.MessageWithMetadata `json:"message"`
+	Preimages  []hexutil.Bytes                 `json:"preimages"`
+	StylusArch string                          `json:"stylusArch,omitempty"`
+	UserWasms  map[common.Hash]WitnessWasm     `json:"userWasms,omitempty"`
+}
+
+// WitnessWasm is an activated Stylus program, as recorded in state.UserWasms.
+type WitnessWasm struct {
+	Asm    hexutil.Bytes `json:"asm"`
+	Module hexutil.Bytes `json:"module"`
+}
@@ -10,12 +10,15 @@ import (
 	"fmt"
 	"math/big"
 
+	"github.com/offchainlabs/nitro/arbos/arbosState"
+	"github.com/offchainlabs/nitro/arbos/guardrails"
 	"github.com/offchainlabs/nitro/arbos/l1pricing"
 	"github.com/offchainlabs/nitro/arbos/programs"
 	"github.com/offchainlabs/nitro/util/arbmath"
 	am "github.com/offchainlabs/nitro/util/arbmath"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -27,12 +30,47 @@ type ArbOwner struct {
 	Address          addr // 0x70
 	OwnerActs        func(ctx, mech, bytes4, addr, []byte) error
 	OwnerActsGasCost func(bytes4, addr, []byte) (uint64, error)
+
+	EmergencyPauseChanged        func(ctx, mech, uint64) error
+	EmergencyPauseChangedGasCost func(uint64) (uint64, error)
 }
 
+// MaxEmergencyPauseSeconds bounds a single emergency pause; owners must renew longer pauses.
+const MaxEmergencyPauseSeconds = 7 * 24 * 60 * 60
+
+// MaxGuardrailBypassSeconds bounds how long guarded parameters can change without bounds at once.
+const MaxGuardrailBypassSeconds = 24 * 60 * 60
+
+// MaxFeeTokenDecimals bounds the decimals a fee token may have.
+const MaxFeeTokenDecimals = 36
+
 var (
 	ErrOutOfBounds = errors.New("value out of bounds")
+
+	ErrNotAPrecompile          = errors.New("not a precompile")
+	ErrPrecompileNotDisablable = errors.New("methods of ArbOwner and ArbosActs can't be disabled")
 )
 
+// guardParameterChange enforces the parameter's rate-of-change guardrail on the owner changing it to next.
+// The current value is only read from ArbOS 32, so earlier calls cost the same gas as before.
+func guardParameterChange(c ctx, evm mech, param guardrails.Parameter, current func() (*big.Int, error), next *big.Int) error {
+	if c.State.ArbOSVersion() < 32 {
+		return nil
+	}
+	value, err := current()
+	if err != nil {
+		return err
+	}
+	return c.State.ParameterGuardrails().CheckChange(param, value, next, evm.Context.Time)
+}
+
+func uintParameter(get func() (uint64, error)) func() (*big.Int, error) {
+	return func() (*big.Int, error) {
+		value, err := get()
+		return arbmath.UintToBig(value), err
+	}
+}
+
 // AddChainOwner adds account as a chain owner
 func (con ArbOwner) AddChainOwner(c ctx, evm mech, newOwner addr) error {
 	return c.State.ChainOwners().Add(newOwner)
@@ -59,7 +97,11 @@ func (con ArbOwner) GetAllChainOwners(c ctx, evm mech) ([]common.Address, error)
 
 // SetL1BaseFeeEstimateInertia sets how slowly ArbOS updates its estimate of the L1 basefee
 func (con ArbOwner) SetL1BaseFeeEstimateInertia(c ctx, evm mech, inertia uint64) error {
-	return c.State.L1PricingState().SetInertia(inertia)
+	l1p := c.State.L1PricingState()
+	if err := guardParameterChange(c, evm, guardrails.L1PricingInertia, uintParameter(l1p.Inertia), arbmath.UintToBig(inertia)); err != nil {
+		return err
+	}
+	return l1p.SetInertia(inertia)
 }
 
 // SetL2BaseFee sets the L2 gas price directly, bypassing the pool calculus
@@ -69,12 +111,20 @@ func (con ArbOwner) SetL2BaseFee(c ctx, evm mech, priceInWei huge) error {
 
 // SetMinimumL2BaseFee sets the minimum base fee needed for a transaction to succeed
 func (con ArbOwner) SetMinimumL2BaseFee(c ctx, evm mech, priceInWei huge) error {
-	return c.State.L2PricingState().SetMinBaseFeeWei(priceInWei)
+	l2p := c.State.L2PricingState()
+	if err := guardParameterChange(c, evm, guardrails.MinimumL2BaseFee, l2p.MinBaseFeeWei, priceInWei); err != nil {
+		return err
+	}
+	return l2p.SetMinBaseFeeWei(priceInWei)
 }
 
 // SetSpeedLimit sets the computational speed limit for the chain
 func (con ArbOwner) SetSpeedLimit(c ctx, evm mech, limit uint64) error {
-	return c.State.L2PricingState().SetSpeedLimitPerSecond(limit)
+	l2p := c.State.L2PricingState()
+	if err := guardParameterChange(c, evm, guardrails.SpeedLimit, uintParameter(l2p.SpeedLimitPerSecond), arbmath.UintToBig(limit)); err != nil {
+		return err
+	}
+	return l2p.SetSpeedLimitPerSecond(limit)
 }
 
 // SetMaxTxGasLimit sets the maximum size a tx (and block) can be
@@ -82,6 +132,34 @@ func (con ArbOwner) SetMaxTxGasLimit(c ctx, evm mech, limit uint64) error {
 	return c.State.L2PricingState().SetMaxPerBlockGasLimit(limit)
 }
 
+// ScheduleGasLimitRamp moves the max tx gas limit toward target by stepBips of its value every period seconds
+func (con ArbOwner) ScheduleGasLimitRamp(c ctx, evm mech, target, stepBips, period uint64) error {
+	return c.State.L2PricingState().ScheduleGasLimitRamp(target, stepBips, period, evm.Context.Time)
+}
+
+// CancelGasLimitRamp stops any scheduled gas limit ramp, leaving the max tx gas limit where it is
+func (con ArbOwner) CancelGasLimitRamp(c ctx, evm mech) error {
+	return c.State.L2PricingState().CancelGasLimitRamp()
+}
+
+// DisablePrecompileMethod makes calls to the precompile's method with the given selector revert, as if it
+// didn't exist. ArbOwner's methods can't be disabled, so the owner can't lock themselves out, nor can ArbosActs',
+// which the chain itself calls.
+func (con ArbOwner) DisablePrecompileMethod(c ctx, evm mech, precompile addr, method bytes4) error {
+	if _, ok := arbosState.PrecompileMinArbOSVersions[precompile]; !ok {
+		return ErrNotAPrecompile
+	}
+	if precompile == types.ArbOwnerAddress || precompile == types.ArbosAddress {
+		return ErrPrecompileNotDisablable
+	}
+	return c.State.DisabledPrecompileMethods().Disable(precompile, method)
+}
+
+// EnablePrecompileMethod enables a method disabled with DisablePrecompileMethod again
+func (con ArbOwner) EnablePrecompileMethod(c ctx, evm mech, precompile addr, method bytes4) error {
+	return c.State.DisabledPrecompileMethods().Enable(precompile, method)
+}
+
 // SetL2GasPricingInertia sets the L2 gas pricing inertia
 func (con ArbOwner) SetL2GasPricingInertia(c ctx, evm mech, sec uint64) error {
 	return c.State.L2PricingState().SetPricingInertia(sec)
@@ -117,12 +195,92 @@ func (con ArbOwner) ScheduleArbOSUpgrade(c ctx, evm mech, newVersion uint64, tim
 	return c.State.ScheduleArbOSUpgrade(newVersion, timestamp)
 }
 
+// SetEmergencyPause pauses user transactions for the given number of seconds, replacing any current pause.
+// Transactions from chain owners, and every transaction from a delayed message, including L2 transactions sent
+// through the delayed inbox, are still executed.
+func (con ArbOwner) SetEmergencyPause(c ctx, evm mech, seconds uint64) error {
+	if seconds == 0 || seconds > MaxEmergencyPauseSeconds {
+		return ErrOutOfBounds
+	}
+	expiry := evm.Context.Time + seconds
+	if err := c.State.SetEmergencyPauseExpiry(expiry); err != nil {
+		return err
+	}
+	return con.EmergencyPauseChanged(c, evm, expiry)
+}
+
+// ClearEmergencyPause resumes user transactions before the current pause expires
+func (con ArbOwner) ClearEmergencyPause(c ctx, evm mech) error {
+	if err := c.State.SetEmergencyPauseExpiry(0); err != nil {
+		return err
+	}
+	return con.EmergencyPauseChanged(c, evm, 0)
+}
+
+// SetAllowlistMode selects which allowlists are enforced: 1 for senders, 2 for deployers, 3 for both, or 0 for none
+func (con ArbOwner) SetAllowlistMode(c ctx, evm mech, mode uint64) error {
+	return c.State.SetAllowlistMode(mode)
+}
+
+// AddAllowedSender allows account to send transactions while the sender allowlist is enforced
+func (con ArbOwner) AddAllowedSender(c ctx, evm mech, account addr) error {
+	return c.State.AllowedSenders().Add(account)
+}
+
+// RemoveAllowedSender removes account from the sender allowlist
+func (con ArbOwner) RemoveAllowedSender(c ctx, evm mech, account addr) error {
+	member, err := c.State.AllowedSenders().IsMember(account)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return errors.New("tried to remove account not on the sender allowlist")
+	}
+	return c.State.AllowedSenders().Remove(account, c.State.ArbOSVersion())
+}
+
+// AddAllowedDeployer allows account to deploy contracts while the deployer allowlist is enforced
+func (con ArbOwner) AddAllowedDeployer(c ctx, evm mech, account addr) error {
+	return c.State.AllowedDeployers().Add(account)
+}
+
+// RemoveAllowedDeployer removes account from the deployer allowlist
+func (con ArbOwner) RemoveAllowedDeployer(c ctx, evm mech, account addr) error {
+	member, err := c.State.AllowedDeployers().IsMember(account)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return errors.New("tried to remove account not on the deployer allowlist")
+	}
+	return c.State.AllowedDeployers().Remove(account, c.State.ArbOSVersion())
+}
+
+// SetGasFreePair sets whether transactions from sender to target execute without paying L2 fees,
+// within the per-block gas-free budget
+func (con ArbOwner) SetGasFreePair(c ctx, evm mech, sender addr, target addr, gasFree bool) error {
+	return c.State.SetGasFreePair(sender, target, gasFree)
+}
+
+// SetGasFreeBudget sets how much gas gas-free transactions may use per block
+func (con ArbOwner) SetGasFreeBudget(c ctx, evm mech, gas uint64) error {
+	return c.State.SetGasFreeBudget(gas)
+}
+
 func (con ArbOwner) SetL1PricingEquilibrationUnits(c ctx, evm mech, equilibrationUnits huge) error {
-	return c.State.L1PricingState().SetEquilibrationUnits(equilibrationUnits)
+	l1p := c.State.L1PricingState()
+	if err := guardParameterChange(c, evm, guardrails.L1PricingEquilibrationUnits, l1p.EquilibrationUnits, equilibrationUnits); err != nil {
+		return err
+	}
+	return l1p.SetEquilibrationUnits(equilibrationUnits)
 }
 
 func (con ArbOwner) SetL1PricingInertia(c ctx, evm mech, inertia uint64) error {
-	return c.State.L1PricingState().SetInertia(inertia)
+	l1p := c.State.L1PricingState()
+	if err := guardParameterChange(c, evm, guardrails.L1PricingInertia, uintParameter(l1p.Inertia), arbmath.UintToBig(inertia)); err != nil {
+		return err
+	}
+	return l1p.SetInertia(inertia)
 }
 
 func (con ArbOwner) SetL1PricingRewardRecipient(c ctx, evm mech, recipient addr) error {
@@ -130,19 +288,62 @@ func (con ArbOwner) SetL1PricingRewardRecipient(c ctx, evm mech, recipient addr)
 }
 
 func (con ArbOwner) SetL1PricingRewardRate(c ctx, evm mech, weiPerUnit uint64) error {
-	return c.State.L1PricingState().SetPerUnitReward(weiPerUnit)
+	l1p := c.State.L1PricingState()
+	if err := guardParameterChange(c, evm, guardrails.L1PricingRewardRate, uintParameter(l1p.PerUnitReward), arbmath.UintToBig(weiPerUnit)); err != nil {
+		return err
+	}
+	return l1p.SetPerUnitReward(weiPerUnit)
 }
 
 func (con ArbOwner) SetL1PricePerUnit(c ctx, evm mech, pricePerUnit *big.Int) error {
-	return c.State.L1PricingState().SetPricePerUnit(pricePerUnit)
+	l1p := c.State.L1PricingState()
+	if err := guardParameterChange(c, evm, guardrails.L1PricePerUnit, l1p.PricePerUnit, pricePerUnit); err != nil {
+		return err
+	}
+	return l1p.SetPricePerUnit(pricePerUnit)
 }
 
 func (con ArbOwner) SetPerBatchGasCharge(c ctx, evm mech, cost int64) error {
-	return c.State.L1PricingState().SetPerBatchGasCost(cost)
+	l1p := c.State.L1PricingState()
+	current := func() (*big.Int, error) {
+		value, err := l1p.PerBatchGasCost()
+		return big.NewInt(value), err
+	}
+	if err := guardParameterChange(c, evm, guardrails.PerBatchGasCharge, current, big.NewInt(cost)); err != nil {
+		return err
+	}
+	return l1p.SetPerBatchGasCost(cost)
 }
 
 func (con ArbOwner) SetAmortizedCostCapBips(c ctx, evm mech, cap uint64) error {
-	return c.State.L1PricingState().SetAmortizedCostCapBips(cap)
+	l1p := c.State.L1PricingState()
+	if err := guardParameterChange(c, evm, guardrails.AmortizedCostCapBips, uintParameter(l1p.AmortizedCostCapBips), arbmath.UintToBig(cap)); err != nil {
+		return err
+	}
+	return l1p.SetAmortizedCostCapBips(cap)
+}
+
+// SetParameterGuardrails bounds how far the minimum base fee, speed limit and L1 pricing parameters can move,
+// in basis points of their value at the start of each window of the given seconds, with 0 bips disabling the
+// bounds. Loosening them requires opening the bypass first.
+func (con ArbOwner) SetParameterGuardrails(c ctx, evm mech, maxChangeBips uint64, windowSeconds uint64) error {
+	if maxChangeBips > uint64(arbmath.OneInBips) {
+		return ErrOutOfBounds
+	}
+	return c.State.ParameterGuardrails().SetConfig(maxChangeBips, windowSeconds, evm.Context.Time)
+}
+
+// SetParameterGuardrailBypass lets guarded parameters change without bounds for the given seconds, or closes
+// the bypass if 0. This is the escape hatch for deliberate large changes.
+func (con ArbOwner) SetParameterGuardrailBypass(c ctx, evm mech, seconds uint64) error {
+	if seconds > MaxGuardrailBypassSeconds {
+		return ErrOutOfBounds
+	}
+	expiry := uint64(0)
+	if seconds > 0 {
+		expiry = evm.Context.Time + seconds
+	}
+	return c.State.ParameterGuardrails().SetBypassExpiry(expiry)
 }
 
 func (con ArbOwner) SetBrotliCompressionLevel(c ctx, evm mech, level uint64) error {
@@ -169,6 +370,37 @@ func (con ArbOwner) ReleaseL1PricerSurplusFunds(c ctx, evm mech, maxWeiToRelease
 	return weiToTransfer, nil
 }
 
+// CorrectL1PricingSurplus adjusts the L1 fees recognized as available by the given wei, to bring an accumulated
+// surplus (with a negative correction) or deficit (with a positive one) back toward zero faster than the pricing
+// model would. Corrections can't overshoot zero, nor recognize more than the L1 pricer's funds pool holds.
+func (con ArbOwner) CorrectL1PricingSurplus(c ctx, evm mech, correction huge) error {
+	balance := evm.StateDB.GetBalance(l1pricing.L1PricerFundsPoolAddress)
+	err := c.State.L1PricingState().CorrectSurplus(correction, balance.ToBig(), c.State.ArbOSVersion())
+	if errors.Is(err, l1pricing.ErrSurplusCorrectionOutOfBounds) {
+		return ErrOutOfBounds
+	}
+	return err
+}
+
+// SetFeeTokenDecimals sets how many decimals the chain's fee token has, so that the L1 costs reimbursed to batch
+// posters are converted from wei into its smallest unit. The funds due to batch posters are rescaled, but other
+// balances and prices aren't.
+func (con ArbOwner) SetFeeTokenDecimals(c ctx, evm mech, decimals uint8) error {
+	if decimals == 0 || decimals > MaxFeeTokenDecimals {
+		return ErrOutOfBounds
+	}
+	return c.State.SetFeeTokenDecimals(uint64(decimals))
+}
+
+// SetFeeTokenExchangeRate sets how many wei one whole fee token is worth, which the L1 costs reimbursed to batch
+// posters are converted at
+func (con ArbOwner) SetFeeTokenExchangeRate(c ctx, evm mech, weiPerToken huge) error {
+	if weiPerToken.Sign() <= 0 {
+		return ErrOutOfBounds
+	}
+	return c.State.SetFeeTokenExchangeRate(weiPerToken)
+}
+
 // Sets the amount of ink 1 gas buys
 func (con ArbOwner) SetInkPrice(c ctx, evm mech, inkPrice uint32) error {
 	params, err := c.State.Programs().Params()
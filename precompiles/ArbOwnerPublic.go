@@ -14,6 +14,9 @@ type ArbOwnerPublic struct {
 	Address                    addr // 0x6b
 	ChainOwnerRectified        func(ctx, mech, addr) error
 	ChainOwnerRectifiedGasCost func(addr) (uint64, error)
+
+	GasFreeBudgetExhausted        func(ctx, mech, uint64, addr, addr) error
+	GasFreeBudgetExhaustedGasCost func(uint64, addr, addr) (uint64, error)
 }
 
 // GetAllChainOwners retrieves the list of chain owners
@@ -65,3 +68,77 @@ func (con ArbOwnerPublic) GetScheduledUpgrade(c ctx, evm mech) (uint64, uint64,
 	}
 	return version, timestamp, nil
 }
+
+// GetEmergencyPauseExpiry gets the timestamp until which user transactions are paused.
+// Returns 0 if the chain isn't paused.
+func (con ArbOwnerPublic) GetEmergencyPauseExpiry(c ctx, evm mech) (uint64, error) {
+	expiry, err := c.State.EmergencyPauseExpiry()
+	if err != nil || expiry <= evm.Context.Time {
+		return 0, err
+	}
+	return expiry, nil
+}
+
+// GetGasLimitRamp gets the scheduled gas limit ramp's target, step in basis points, period in seconds, and the
+// timestamp of its next step. Returns all zeros if no ramp is scheduled.
+func (con ArbOwnerPublic) GetGasLimitRamp(c ctx, evm mech) (uint64, uint64, uint64, uint64, error) {
+	return c.State.L2PricingState().GasLimitRamp()
+}
+
+// GetAllowlistMode gets which allowlists are enforced: 1 for senders, 2 for deployers, 3 for both, or 0 for none
+func (con ArbOwnerPublic) GetAllowlistMode(c ctx, evm mech) (uint64, error) {
+	return c.State.AllowlistMode()
+}
+
+// IsAllowedSender checks if the account is on the sender allowlist
+func (con ArbOwnerPublic) IsAllowedSender(c ctx, evm mech, account addr) (bool, error) {
+	return c.State.AllowedSenders().IsMember(account)
+}
+
+// IsAllowedDeployer checks if the account is on the deployer allowlist
+func (con ArbOwnerPublic) IsAllowedDeployer(c ctx, evm mech, account addr) (bool, error) {
+	return c.State.AllowedDeployers().IsMember(account)
+}
+
+// IsGasFreePair checks if transactions from sender to target execute without paying L2 fees
+func (con ArbOwnerPublic) IsGasFreePair(c ctx, evm mech, sender addr, target addr) (bool, error) {
+	return c.State.IsGasFreePair(sender, target)
+}
+
+// GetGasFreeBudget gets the per-block gas-free budget and how much of it has been used in the current block
+func (con ArbOwnerPublic) GetGasFreeBudget(c ctx, evm mech) (uint64, uint64, error) {
+	budget, err := c.State.GasFreeBudget()
+	if err != nil {
+		return 0, 0, err
+	}
+	used, err := c.State.GasFreeUsed(evm.Context.BlockNumber.Uint64())
+	return budget, used, err
+}
+
+// GetParameterGuardrails gets the most guarded parameters can move per window in basis points (0 if unbounded),
+// the window in seconds, and the timestamp until which the guardrails are bypassed
+func (con ArbOwnerPublic) GetParameterGuardrails(c ctx, evm mech) (uint64, uint64, uint64, error) {
+	return c.State.ParameterGuardrails().Config()
+}
+
+// GetFeeTokenDecimals gets how many decimals the chain's fee token has
+func (con ArbOwnerPublic) GetFeeTokenDecimals(c ctx, evm mech) (uint8, error) {
+	decimals, err := c.State.FeeTokenDecimals()
+	return uint8(decimals), err
+}
+
+// GetFeeTokenExchangeRate gets how many wei one whole fee token is worth
+func (con ArbOwnerPublic) GetFeeTokenExchangeRate(c ctx, evm mech) (huge, error) {
+	feeToken, err := c.State.FeeToken()
+	return feeToken.WeiPerToken, err
+}
+
+// GetDisabledPrecompileMethods gets the selectors of the precompile's methods the chain owner disabled
+func (con ArbOwnerPublic) GetDisabledPrecompileMethods(c ctx, evm mech, precompile addr) ([]bytes4, error) {
+	return c.State.DisabledPrecompileMethods().Disabled(precompile)
+}
+
+// IsPrecompileMethodDisabled checks if the chain owner disabled the precompile's method with the given selector
+func (con ArbOwnerPublic) IsPrecompileMethodDisabled(c ctx, evm mech, precompile addr, method bytes4) (bool, error) {
+	return c.State.DisabledPrecompileMethods().IsDisabled(precompile, method)
+}
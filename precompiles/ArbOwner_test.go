@@ -6,12 +6,14 @@ package precompiles
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"math/big"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/holiman/uint256"
@@ -228,3 +230,81 @@ func TestArbInfraFeeAccount(t *testing.T) {
 		t.Fatal()
 	}
 }
+
+func TestArbOwnerEmergencyPause(t *testing.T) {
+	evm := newMockEVMForTesting()
+	caller := common.BytesToAddress(crypto.Keccak256([]byte{})[:20])
+	callCtx := testContext(caller, evm)
+	var emitted []uint64
+	prec := &ArbOwner{
+		EmergencyPauseChanged: func(c ctx, evm mech, expiry uint64) error {
+			emitted = append(emitted, expiry)
+			return nil
+		},
+	}
+	precPublic := &ArbOwnerPublic{}
+
+	if err := prec.SetEmergencyPause(callCtx, evm, MaxEmergencyPauseSeconds+1); err == nil {
+		Fail(t, "pause longer than the maximum should be rejected")
+	}
+	Require(t, prec.SetEmergencyPause(callCtx, evm, 3600))
+	expiry, err := precPublic.GetEmergencyPauseExpiry(callCtx, evm)
+	Require(t, err)
+	if expiry != evm.Context.Time+3600 {
+		Fail(t, "unexpected pause expiry", expiry)
+	}
+
+	Require(t, prec.ClearEmergencyPause(callCtx, evm))
+	expiry, err = precPublic.GetEmergencyPauseExpiry(callCtx, evm)
+	Require(t, err)
+	if expiry != 0 {
+		Fail(t, "pause should be cleared", expiry)
+	}
+	if len(emitted) != 2 || emitted[0] != evm.Context.Time+3600 || emitted[1] != 0 {
+		Fail(t, "unexpected pause events", emitted)
+	}
+}
+
+func TestArbOwnerDisablePrecompileMethod(t *testing.T) {
+	evm := newMockEVMForTesting()
+	caller := common.BytesToAddress(crypto.Keccak256([]byte{})[:20])
+	callCtx := testContext(caller, evm)
+	prec := &ArbOwner{}
+	precPublic := &ArbOwnerPublic{}
+
+	contracts := Precompiles()
+	addressTable := types.ArbAddressTableAddress
+	size := contracts[addressTable].Precompile().GetMethodID("Size")
+	callSize := func() error {
+		_, _, err := contracts[addressTable].Call(size[:], addressTable, addressTable, caller, big.NewInt(0), true, 1_000_000, evm)
+		return err
+	}
+	Require(t, callSize())
+
+	Require(t, prec.DisablePrecompileMethod(callCtx, evm, addressTable, size))
+	if err := callSize(); !errors.Is(err, vm.ErrExecutionReverted) {
+		Fail(t, "disabled method was called, err:", err)
+	}
+	disabled, err := precPublic.IsPrecompileMethodDisabled(callCtx, evm, addressTable, size)
+	Require(t, err)
+	methods, err := precPublic.GetDisabledPrecompileMethods(callCtx, evm, addressTable)
+	Require(t, err)
+	if !disabled || len(methods) != 1 || methods[0] != size {
+		Fail(t, "unexpected disabled methods", disabled, methods)
+	}
+
+	if err := prec.DisablePrecompileMethod(callCtx, evm, types.ArbOwnerAddress, size); !errors.Is(err, ErrPrecompileNotDisablable) {
+		Fail(t, "disabled an ArbOwner method, err:", err)
+	}
+	if err := prec.DisablePrecompileMethod(callCtx, evm, common.Address{1}, size); !errors.Is(err, ErrNotAPrecompile) {
+		Fail(t, "disabled a method of a non-precompile, err:", err)
+	}
+
+	Require(t, prec.EnablePrecompileMethod(callCtx, evm, addressTable, size))
+	Require(t, callSize())
+	methods, err = precPublic.GetDisabledPrecompileMethods(callCtx, evm, addressTable)
+	Require(t, err)
+	if len(methods) != 0 {
+		Fail(t, "methods still disabled", methods)
+	}
+}
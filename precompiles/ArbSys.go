@@ -207,6 +207,19 @@ func (con ArbSys) WithdrawEth(c ctx, evm mech, value huge, destination addr) (hu
 	return con.SendTxToL1(c, evm, value, destination, []byte{})
 }
 
+// GetDelayedMessageBacklog gets the number of delayed messages sequenced as of the current block, the size
+// of the L1 delayed inbox when the last sequenced batch posting report was made, and that report's L1 timestamp.
+// Batch posting reports are added to the delayed inbox every time a batch is posted, so a report timestamp that
+// falls far behind the block timestamp means the sequencer isn't including delayed messages.
+func (con ArbSys) GetDelayedMessageBacklog(c ctx, evm mech) (uint64, uint64, uint64, error) {
+	sequenced, err := c.State.DelayedMessagesRead()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	reported, reportTimestamp, err := c.State.LastBatchPostingReport()
+	return sequenced, reported, reportTimestamp, err
+}
+
 func (con ArbSys) isTopLevel(c ctx, evm mech) bool {
 	depth := evm.Depth()
 	return depth < 2 || evm.Origin == c.txProcessor.Contracts[depth-2].Caller()
@@ -557,6 +557,22 @@ func Precompiles() map[addr]ArbosPrecompile {
 	ArbOwnerPublic.methodsByName["RectifyChainOwner"].arbosVersion = 11
 	ArbOwnerPublic.methodsByName["GetBrotliCompressionLevel"].arbosVersion = 20
 	ArbOwnerPublic.methodsByName["GetScheduledUpgrade"].arbosVersion = 20
+	ArbOwnerPublic.methodsByName["GetEmergencyPauseExpiry"].arbosVersion = 32
+	ArbOwnerPublic.methodsByName["GetAllowlistMode"].arbosVersion = 32
+	ArbOwnerPublic.methodsByName["IsAllowedSender"].arbosVersion = 32
+	ArbOwnerPublic.methodsByName["IsAllowedDeployer"].arbosVersion = 32
+	ArbOwnerPublic.methodsByName["IsGasFreePair"].arbosVersion = 32
+	ArbOwnerPublic.methodsByName["GetGasFreeBudget"].arbosVersion = 32
+	ArbOwnerPublic.methodsByName["GetParameterGuardrails"].arbosVersion = 32
+	ArbOwnerPublic.methodsByName["GetFeeTokenDecimals"].arbosVersion = 32
+	ArbOwnerPublic.methodsByName["GetFeeTokenExchangeRate"].arbosVersion = 32
+	ArbOwnerPublic.methodsByName["GetGasLimitRamp"].arbosVersion = 32
+	ArbOwnerPublic.methodsByName["GetDisabledPrecompileMethods"].arbosVersion = 32
+	ArbOwnerPublic.methodsByName["IsPrecompileMethodDisabled"].arbosVersion = 32
+	arbos.EmitGasFreeBudgetExhaustedEvent = func(evm mech, blockNumber uint64, sender, target addr) error {
+		context := eventCtx(ArbOwnerPublicImpl.GasFreeBudgetExhaustedGasCost(blockNumber, sender, target))
+		return ArbOwnerPublicImpl.GasFreeBudgetExhausted(context, evm, blockNumber, sender, target)
+	}
 
 	ArbWasmImpl := &ArbWasm{Address: types.ArbWasmAddress}
 	ArbWasm := insert(MakePrecompile(pgen.ArbWasmMetaData, ArbWasmImpl))
@@ -603,6 +619,7 @@ func Precompiles() map[addr]ArbosPrecompile {
 	arbos.ArbSysAddress = ArbSys.address
 	arbos.L2ToL1TransactionEventID = ArbSys.events["L2ToL1Transaction"].template.ID
 	arbos.L2ToL1TxEventID = ArbSys.events["L2ToL1Tx"].template.ID
+	ArbSys.methodsByName["GetDelayedMessageBacklog"].arbosVersion = 32
 
 	ArbOwnerImpl := &ArbOwner{Address: types.ArbOwnerAddress}
 	emitOwnerActs := func(evm mech, method bytes4, owner addr, data []byte) error {
@@ -615,6 +632,25 @@ func Precompiles() map[addr]ArbosPrecompile {
 	ArbOwner.methodsByName["ReleaseL1PricerSurplusFunds"].arbosVersion = 10
 	ArbOwner.methodsByName["SetChainConfig"].arbosVersion = 11
 	ArbOwner.methodsByName["SetBrotliCompressionLevel"].arbosVersion = 20
+	ArbOwner.methodsByName["SetEmergencyPause"].arbosVersion = 32
+	ArbOwner.methodsByName["ClearEmergencyPause"].arbosVersion = 32
+	allowlistMethods := []string{
+		"SetAllowlistMode", "AddAllowedSender", "RemoveAllowedSender", "AddAllowedDeployer", "RemoveAllowedDeployer",
+	}
+	for _, method := range allowlistMethods {
+		ArbOwner.methodsByName[method].arbosVersion = 32
+	}
+	ArbOwner.methodsByName["SetGasFreePair"].arbosVersion = 32
+	ArbOwner.methodsByName["SetGasFreeBudget"].arbosVersion = 32
+	ArbOwner.methodsByName["SetParameterGuardrails"].arbosVersion = 32
+	ArbOwner.methodsByName["SetParameterGuardrailBypass"].arbosVersion = 32
+	ArbOwner.methodsByName["CorrectL1PricingSurplus"].arbosVersion = 32
+	ArbOwner.methodsByName["SetFeeTokenDecimals"].arbosVersion = 32
+	ArbOwner.methodsByName["SetFeeTokenExchangeRate"].arbosVersion = 32
+	ArbOwner.methodsByName["ScheduleGasLimitRamp"].arbosVersion = 32
+	ArbOwner.methodsByName["CancelGasLimitRamp"].arbosVersion = 32
+	ArbOwner.methodsByName["DisablePrecompileMethod"].arbosVersion = 32
+	ArbOwner.methodsByName["EnablePrecompileMethod"].arbosVersion = 32
 	stylusMethods := []string{
 		"SetInkPrice", "SetWasmMaxStackDepth", "SetWasmFreePages", "SetWasmPageGas",
 		"SetWasmPageLimit", "SetWasmMinInitGas", "SetWasmInitCostScalar",
@@ -689,6 +725,17 @@ func (p *Precompile) Call(
 		return nil, 0, vm.ErrExecutionReverted
 	}
 
+	if arbosVersion >= 32 {
+		disabled, err := arbosState.PrecompileMethodDisabled(evm.StateDB, p.address, id)
+		if err != nil {
+			return nil, 0, err
+		}
+		if disabled {
+			// the chain owner disabled the method, so treat it as if it doesn't exist
+			return nil, 0, vm.ErrExecutionReverted
+		}
+	}
+
 	if method.purity >= view && actingAsAddress != precompileAddress {
 		// should not access precompile superpowers when not acting as the precompile
 		return nil, 0, vm.ErrExecutionReverted
@@ -195,6 +195,7 @@ func TestPrecompilesPerArbosVersion(t *testing.T) {
 		20: 8,
 		30: 38,
 		31: 1,
+		32: 31,
 	}
 
 	precompiles := Precompiles()
@@ -0,0 +1,140 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	m "github.com/offchainlabs/nitro/broadcaster/message"
+	"github.com/offchainlabs/nitro/wsbroadcastserver"
+)
+
+var (
+	backfillRequestsCounter = metrics.NewRegisteredCounter("arb/relay/backfill/requests", nil)
+	backfillMessagesCounter = metrics.NewRegisteredCounter("arb/relay/backfill/messages", nil)
+)
+
+// BackfillPath is where the relay serves ranges of feed messages, given the first sequence number as the
+// "from" query parameter and the number of messages as "count".
+const BackfillPath = "/feed/messages"
+
+type BackfillConfig struct {
+	Enable      bool   `koanf:"enable"`
+	Addr        string `koanf:"addr"`
+	Port        string `koanf:"port"`
+	MaxMessages uint64 `koanf:"max-messages"`
+}
+
+var BackfillConfigDefault = BackfillConfig{
+	Enable:      false,
+	Addr:        "127.0.0.1",
+	Port:        "9643",
+	MaxMessages: 10_000,
+}
+
+func BackfillConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", BackfillConfigDefault.Enable, "serve ranges of feed messages over http, for clients to fill the gaps in the feed they find after reconnecting")
+	f.String(prefix+".addr", BackfillConfigDefault.Addr, "address to serve feed message ranges on")
+	f.String(prefix+".port", BackfillConfigDefault.Port, "port to serve feed message ranges on")
+	f.Uint64(prefix+".max-messages", BackfillConfigDefault.MaxMessages, "maximum number of messages to return for a single request")
+}
+
+func (c *BackfillConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.MaxMessages == 0 {
+		return errors.New("backfill.max-messages must be positive")
+	}
+	return nil
+}
+
+// feedMessageSource reads a range of feed messages, fewer if it doesn't have them all.
+type feedMessageSource interface {
+	FeedMessages(ctx context.Context, from arbutil.MessageIndex, count uint64) ([]*m.BroadcastFeedMessage, error)
+}
+
+// backfillHandler serves ranges of feed messages from the relay's backlog, or from the on-disk backlog and
+// gap filler for those preceding it, encoded as the feed encodes them.
+type backfillHandler struct {
+	source      feedMessageSource
+	maxMessages uint64
+	// the feed's api key authentication, which also applies to backfill requests
+	auth *wsbroadcastserver.AuthConfig
+}
+
+func (h *backfillHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.auth != nil && h.auth.Enable {
+		if _, err := wsbroadcastserver.AuthenticateRequest(h.auth, r); err != nil {
+			http.Error(w, "invalid or missing api key", http.StatusUnauthorized)
+			return
+		}
+	}
+	backfillRequestsCounter.Inc(1)
+	query := r.URL.Query()
+	from, err := strconv.ParseUint(query.Get("from"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid from sequence number", http.StatusBadRequest)
+		return
+	}
+	count := h.maxMessages
+	if query.Has("count") {
+		count, err = strconv.ParseUint(query.Get("count"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid message count", http.StatusBadRequest)
+			return
+		}
+		count = min(count, h.maxMessages)
+	}
+	msgs, err := h.source.FeedMessages(r.Context(), arbutil.MessageIndex(from), count)
+	if err != nil {
+		log.Warn("error reading feed messages to backfill", "from", from, "count", count, "err", err)
+		http.Error(w, "error reading feed messages", http.StatusInternalServerError)
+		return
+	}
+	backfillMessagesCounter.Inc(int64(len(msgs)))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&m.BroadcastMessage{Version: 1, Messages: msgs}); err != nil {
+		log.Debug("error writing backfilled feed messages", "err", err)
+	}
+}
+
+func (r *Relay) launchBackfillServer(ctx context.Context) {
+	mux := http.NewServeMux()
+	mux.Handle(BackfillPath, &backfillHandler{source: r.broadcaster, maxMessages: r.backfill.MaxMessages, auth: r.feedAuth})
+	server := &http.Server{
+		Addr:              fmt.Sprintf("%s:%s", r.backfill.Addr, r.backfill.Port),
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		err := server.Shutdown(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+			log.Warn("error shutting down feed backfill server", "err", err)
+		}
+	}()
+
+	err := server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Warn("error serving feed backfill server", "err", err)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	m "github.com/offchainlabs/nitro/broadcaster/message"
+	"github.com/offchainlabs/nitro/wsbroadcastserver"
+)
+
+type fakeFeedMessageSource struct {
+	msgCount uint64
+}
+
+func (s *fakeFeedMessageSource) FeedMessages(_ context.Context, from arbutil.MessageIndex, count uint64) ([]*m.BroadcastFeedMessage, error) {
+	var seqNums []arbutil.MessageIndex
+	for pos := uint64(from); pos < min(uint64(from)+count, s.msgCount); pos++ {
+		seqNums = append(seqNums, arbutil.MessageIndex(pos))
+	}
+	return m.CreateDummyBroadcastMessages(seqNums), nil
+}
+
+func TestBackfillHandler(t *testing.T) {
+	server := httptest.NewServer(&backfillHandler{source: &fakeFeedMessageSource{msgCount: 100}, maxMessages: 10})
+	defer server.Close()
+
+	get := func(query string) (int, []*m.BroadcastFeedMessage) {
+		t.Helper()
+		response, err := http.Get(server.URL + BackfillPath + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return response.StatusCode, nil
+		}
+		var res m.BroadcastMessage
+		if err := json.NewDecoder(response.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		return response.StatusCode, res.Messages
+	}
+	checkRange := func(from, count uint64, expected int) {
+		t.Helper()
+		status, msgs := get(fmt.Sprintf("?from=%d&count=%d", from, count))
+		if status != http.StatusOK || len(msgs) != expected {
+			t.Fatalf("requested %v messages from %v, expected %v, got status %v with %v messages", count, from, expected, status, len(msgs))
+		}
+		for i, msg := range msgs {
+			if msg.SequenceNumber != arbutil.MessageIndex(from)+arbutil.MessageIndex(i) {
+				t.Fatal("unexpected message", msg.SequenceNumber, "at", i)
+			}
+		}
+	}
+	checkRange(5, 3, 3)
+	// requests are capped to the maximum number of messages
+	checkRange(20, 50, 10)
+	checkRange(95, 10, 5)
+	checkRange(100, 10, 0)
+
+	if status, _ := get("?from=abc"); status != http.StatusBadRequest {
+		t.Fatal("expected a bad request for an invalid sequence number, got", status)
+	}
+
+	// with the feed's authentication enabled, requests need one of its api keys
+	auth := wsbroadcastserver.AuthConfig{Enable: true, Keys: []string{"client:secret:1"}}
+	authServer := httptest.NewServer(&backfillHandler{source: &fakeFeedMessageSource{msgCount: 100}, maxMessages: 10, auth: &auth})
+	defer authServer.Close()
+	request := func(query string, key string) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, authServer.URL+BackfillPath+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		response, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+		return response.StatusCode
+	}
+	if status := request("?from=5", ""); status != http.StatusUnauthorized {
+		t.Fatal("expected a request without an api key to be unauthorized, got", status)
+	}
+	if status := request("?from=5", "wrong"); status != http.StatusUnauthorized {
+		t.Fatal("expected a request with an unknown api key to be unauthorized, got", status)
+	}
+	if status := request("?from=5", "secret"); status != http.StatusOK {
+		t.Fatal("expected a request with a valid api key to succeed, got", status)
+	}
+	if status := request("?from=5&"+wsbroadcastserver.AuthKeyQueryParam+"=secret", ""); status != http.StatusOK {
+		t.Fatal("expected a request with a valid api key in the query to succeed, got", status)
+	}
+}
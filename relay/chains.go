@@ -0,0 +1,186 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package relay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/broadcastclient"
+	"github.com/offchainlabs/nitro/broadcastclients"
+	"github.com/offchainlabs/nitro/broadcaster"
+	m "github.com/offchainlabs/nitro/broadcaster/message"
+	"github.com/offchainlabs/nitro/util/signature"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+	"github.com/offchainlabs/nitro/wsbroadcastserver"
+)
+
+// chainFeed relays the feed of one of the additional chains, serving it through the primary chain's listener
+// at wsbroadcastserver.ChainPathPrefix followed by the chain id.
+type chainFeed struct {
+	stopwaiter.StopWaiter
+	chainId                     uint64
+	broadcastClients            *broadcastclients.BroadcastClients
+	broadcaster                 *broadcaster.Broadcaster
+	confirmedSequenceNumberChan chan arbutil.MessageIndex
+	messageChan                 chan m.BroadcastFeedMessage
+}
+
+// parseChains groups the additional chains, each given as <chain id>@<feed url>, into the feed urls of each
+// chain, returning the chain ids in the order they were first given.
+func parseChains(chains []string, primaryChainId uint64) ([]uint64, map[uint64][]string, error) {
+	var chainIds []uint64
+	urls := make(map[uint64][]string)
+	for _, chain := range chains {
+		idString, url, found := strings.Cut(chain, "@")
+		if !found || url == "" {
+			return nil, nil, fmt.Errorf("chain %q must be given as <chain id>@<feed url>", chain)
+		}
+		chainId, err := strconv.ParseUint(idString, 10, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid chain id in %q: %w", chain, err)
+		}
+		if chainId == primaryChainId {
+			return nil, nil, fmt.Errorf("chain %v is the relay's primary chain", chainId)
+		}
+		if _, exists := urls[chainId]; !exists {
+			chainIds = append(chainIds, chainId)
+		}
+		urls[chainId] = append(urls[chainId], url)
+	}
+	return chainIds, urls, nil
+}
+
+// parseChainSigners groups the feed signers of the additional chains, each given as <chain id>@<address>, by chain.
+func parseChainSigners(signers []string, chainIds []uint64) (map[uint64][]string, error) {
+	chainSigners := make(map[uint64][]string)
+	for _, signer := range signers {
+		idString, address, found := strings.Cut(signer, "@")
+		if !found || !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("chain signer %q must be given as <chain id>@<address>", signer)
+		}
+		chainId, err := strconv.ParseUint(idString, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chain id in %q: %w", signer, err)
+		}
+		if !slices.Contains(chainIds, chainId) {
+			return nil, fmt.Errorf("chain signer %q is for chain %v, which isn't one of the additional chains", signer, chainId)
+		}
+		chainSigners[chainId] = append(chainSigners[chainId], address)
+	}
+	return chainSigners, nil
+}
+
+// chainVerifierConfig returns how to verify the signatures of an additional chain's feed messages: only the
+// chain's own signers are accepted, as the relay can't look up its sequencer. Without signers, messages are
+// passed on unverified, which must have been explicitly allowed.
+func chainVerifierConfig(config *Config, chainId uint64, signers []string) (signature.VerifierConfig, error) {
+	if len(signers) > 0 {
+		return signature.VerifierConfig{
+			AllowedAddresses: signers,
+			AcceptSequencer:  false,
+			Dangerous: signature.DangerousVerifierConfig{
+				AcceptMissing: false,
+			},
+		}, nil
+	}
+	if !config.ChainsAcceptUnverified {
+		return signature.VerifierConfig{}, fmt.Errorf("no signers given for chain %v in chain-signers, set chains-accept-unverified to relay its feed without verifying it", chainId)
+	}
+	log.Warn("relaying the feed of a chain without verifying its signatures", "chainId", chainId)
+	return signature.DefultFeedVerifierConfig, nil
+}
+
+// newChainFeed creates the relay of an additional chain with the primary chain's feed settings, except that
+// messages are verified with the chain's own signers, the primary feed's api key isn't sent, and the feed
+// cache and on-disk backlog are kept in a subdirectory for the chain.
+func newChainFeed(config *Config, chainId uint64, urls []string, verify signature.VerifierConfig, feedErrChan chan error) (*chainFeed, error) {
+	input := config.Node.Feed.Input
+	input.URL = urls
+	input.SecondaryURL = []string{}
+	input.Verify = verify
+	input.SigningKeys = broadcastclient.DefaultSigningKeysConfig
+	input.Backfill = broadcastclient.DefaultBackfillConfig
+	input.APIKey = ""
+	chainDirectory := func(directory string) string {
+		return filepath.Join(directory, "chain-"+strconv.FormatUint(chainId, 10))
+	}
+	if input.Cache.Enable {
+		input.Cache.Directory = chainDirectory(input.Cache.Directory)
+	}
+	output := config.Node.Feed.Output
+	if output.Backlog.Disk.Enable {
+		output.Backlog.Disk.Directory = chainDirectory(output.Backlog.Disk.Directory)
+	}
+
+	q := MessageQueue{make(chan m.BroadcastFeedMessage, config.Queue)}
+	confirmedSequenceNumberListener := make(chan arbutil.MessageIndex, config.Queue)
+	clients, err := broadcastclients.NewBroadcastClients(
+		func() *broadcastclient.Config { return &input },
+		chainId,
+		0,
+		&q,
+		confirmedSequenceNumberListener,
+		feedErrChan,
+		nil,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the feed clients of chain %v: %w", chainId, err)
+	}
+	if clients == nil {
+		return nil, fmt.Errorf("no feed servers found for chain %v", chainId)
+	}
+	dataSignerErr := func([]byte) ([]byte, error) {
+		return nil, errors.New("relay attempted to sign feed message")
+	}
+	return &chainFeed{
+		chainId:                     chainId,
+		broadcastClients:            clients,
+		broadcaster:                 broadcaster.NewBroadcaster(func() *wsbroadcastserver.BroadcasterConfig { return &output }, chainId, feedErrChan, dataSignerErr),
+		confirmedSequenceNumberChan: confirmedSequenceNumberListener,
+		messageChan:                 q.queue,
+	}, nil
+}
+
+func (c *chainFeed) Start(ctx context.Context, host *broadcaster.Broadcaster) error {
+	c.StopWaiter.Start(ctx, c)
+	if err := c.broadcaster.Initialize(); err != nil {
+		return fmt.Errorf("broadcast of chain %v unable to initialize: %w", c.chainId, err)
+	}
+	if err := c.broadcaster.StartOn(ctx, host); err != nil {
+		return fmt.Errorf("broadcast of chain %v unable to start: %w", c.chainId, err)
+	}
+	c.broadcastClients.Start(ctx)
+	c.LaunchThread(func(ctx context.Context) {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-c.messageChan:
+				c.broadcaster.BroadcastSingleFeedMessage(&msg)
+			case cs := <-c.confirmedSequenceNumberChan:
+				c.broadcaster.Confirm(cs)
+			}
+		}
+	})
+	return nil
+}
+
+func (c *chainFeed) StopAndWait() {
+	c.StopWaiter.StopAndWait()
+	c.broadcastClients.StopAndWait()
+	if c.broadcaster.Started() {
+		c.broadcaster.StopAndWait()
+	}
+}
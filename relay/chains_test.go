@@ -0,0 +1,61 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package relay
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseChains(t *testing.T) {
+	chainIds, urls, err := parseChains([]string{"42170@wss://a/feed", "660279@wss://b/feed", "42170@wss://c/feed"}, 42161)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(chainIds, []uint64{42170, 660279}) {
+		t.Fatal("unexpected chains", chainIds)
+	}
+	if !slices.Equal(urls[42170], []string{"wss://a/feed", "wss://c/feed"}) || !slices.Equal(urls[660279], []string{"wss://b/feed"}) {
+		t.Fatal("unexpected feed urls", urls)
+	}
+
+	for _, invalid := range []string{"42170", "42170@", "nova@wss://a/feed", "42161@wss://a/feed"} {
+		if _, _, err := parseChains([]string{invalid}, 42161); err == nil {
+			t.Fatal("expected an error parsing", invalid)
+		}
+	}
+}
+
+func TestChainVerifierConfig(t *testing.T) {
+	chainIds := []uint64{42170, 660279}
+	signers, err := parseChainSigners([]string{"42170@0x0000000000000000000000000000000000000001", "42170@0x0000000000000000000000000000000000000002"}, chainIds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, invalid := range []string{"42170", "42170@0x01", "nova@0x0000000000000000000000000000000000000001", "42161@0x0000000000000000000000000000000000000001"} {
+		if _, err := parseChainSigners([]string{invalid}, chainIds); err == nil {
+			t.Fatal("expected an error parsing", invalid)
+		}
+	}
+
+	config := ConfigDefault
+	verify, err := chainVerifierConfig(&config, 42170, signers[42170])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(verify.AllowedAddresses) != 2 || verify.AcceptSequencer || verify.Dangerous.AcceptMissing {
+		t.Fatal("expected only the chain's signers accepted", verify)
+	}
+	if _, err := chainVerifierConfig(&config, 660279, signers[660279]); err == nil {
+		t.Fatal("expected a chain without signers to be refused")
+	}
+	config.ChainsAcceptUnverified = true
+	verify, err = chainVerifierConfig(&config, 660279, signers[660279])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verify.Dangerous.AcceptMissing {
+		t.Fatal("expected a chain without signers to be relayed unverified once allowed")
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package relay
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	m "github.com/offchainlabs/nitro/broadcaster/message"
+	"github.com/offchainlabs/nitro/util/containers"
+	"github.com/offchainlabs/nitro/util/rpcclient"
+)
+
+type GapFillConfig struct {
+	Enable      bool                   `koanf:"enable"`
+	RPC         rpcclient.ClientConfig `koanf:"rpc"`
+	MaxMessages uint64                 `koanf:"max-messages"`
+	CacheChunks int                    `koanf:"cache-chunks"`
+}
+
+var GapFillConfigDefault = GapFillConfig{
+	Enable: false,
+	RPC: rpcclient.ClientConfig{
+		URL:                       "",
+		Retries:                   rpcclient.DefaultClientConfig.Retries,
+		RetryErrors:               rpcclient.DefaultClientConfig.RetryErrors,
+		ArgLogLimit:               rpcclient.DefaultClientConfig.ArgLogLimit,
+		WebsocketMessageSizeLimit: rpcclient.DefaultClientConfig.WebsocketMessageSizeLimit,
+	},
+	MaxMessages: 100_000,
+	CacheChunks: 1024,
+}
+
+func GapFillConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", GapFillConfigDefault.Enable, "send clients the messages they request that precede the backlog, read from a node next to the relay, rather than making them read the messages from the parent chain")
+	rpcclient.RPCClientAddOptions(prefix+".rpc", f, &GapFillConfigDefault.RPC)
+	f.Uint64(prefix+".max-messages", GapFillConfigDefault.MaxMessages, "maximum number of messages preceding the backlog to send a client")
+	f.Int(prefix+".cache-chunks", GapFillConfigDefault.CacheChunks, "number of chunks of 256 messages read from the node to cache, shared by all clients")
+}
+
+func (c *GapFillConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.RPC.URL == "" {
+		return errors.New("node.gap-fill.rpc.url must be set to the node's authenticated rpc endpoint")
+	}
+	if c.CacheChunks <= 0 {
+		return errors.New("node.gap-fill.cache-chunks must be positive")
+	}
+	return c.RPC.Validate()
+}
+
+const gapFillChunkMessages = 256
+
+// nodeGapFiller reads the messages preceding the relay's backlog from a node's arb_feedMessages.
+// After a restart, many clients request roughly the same messages at once, so the messages are read
+// from the node in aligned chunks, one request at a time, and the complete chunks are cached.
+type nodeGapFiller struct {
+	client *rpcclient.RpcClient
+
+	mutex  sync.Mutex
+	chunks *containers.LruCache[uint64, []*m.BroadcastFeedMessage]
+}
+
+func newNodeGapFiller(config *GapFillConfig) *nodeGapFiller {
+	return &nodeGapFiller{
+		client: rpcclient.NewRpcClient(func() *rpcclient.ClientConfig { return &config.RPC }, nil),
+		chunks: containers.NewLruCache[uint64, []*m.BroadcastFeedMessage](config.CacheChunks),
+	}
+}
+
+func (f *nodeGapFiller) Start(ctx context.Context) error {
+	return f.client.Start(ctx)
+}
+
+func (f *nodeGapFiller) Close() {
+	f.client.Close()
+}
+
+func (f *nodeGapFiller) FeedMessages(ctx context.Context, from arbutil.MessageIndex, count uint64) ([]*m.BroadcastFeedMessage, error) {
+	chunkStart := uint64(from) - uint64(from)%gapFillChunkMessages
+	chunk, err := f.chunk(ctx, chunkStart)
+	if err != nil {
+		return nil, err
+	}
+	offset := uint64(from) - chunkStart
+	if offset >= uint64(len(chunk)) {
+		return nil, nil
+	}
+	return chunk[offset:min(uint64(len(chunk)), offset+count)], nil
+}
+
+func (f *nodeGapFiller) chunk(ctx context.Context, start uint64) ([]*m.BroadcastFeedMessage, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if chunk, ok := f.chunks.Get(start); ok {
+		return chunk, nil
+	}
+	var chunk []*m.BroadcastFeedMessage
+	err := f.client.CallContext(ctx, &chunk, "arb_feedMessages", hexutil.Uint64(start), hexutil.Uint64(gapFillChunkMessages))
+	if err != nil {
+		return nil, err
+	}
+	for i, msg := range chunk {
+		if uint64(msg.SequenceNumber) != start+uint64(i) {
+			return nil, errors.New("node returned feed messages out of order")
+		}
+	}
+	// the node may not have the rest of a partial chunk yet
+	if len(chunk) == gapFillChunkMessages {
+		f.chunks.Add(start, chunk)
+	}
+	return chunk, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package relay
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	m "github.com/offchainlabs/nitro/broadcaster/message"
+)
+
+type fakeFeedMessagesAPI struct {
+	msgCount atomic.Uint64
+	calls    atomic.Int64
+}
+
+func (a *fakeFeedMessagesAPI) FeedMessages(from hexutil.Uint64, count hexutil.Uint64) []*m.BroadcastFeedMessage {
+	a.calls.Add(1)
+	messages := []*m.BroadcastFeedMessage{}
+	for pos := uint64(from); pos < min(uint64(from+count), a.msgCount.Load()); pos++ {
+		messages = append(messages, &m.BroadcastFeedMessage{SequenceNumber: arbutil.MessageIndex(pos)})
+	}
+	return messages
+}
+
+func TestNodeGapFiller(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	api := &fakeFeedMessagesAPI{}
+	api.msgCount.Store(600)
+	server := rpc.NewServer()
+	if err := server.RegisterName("arb", api); err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	config := GapFillConfigDefault
+	config.Enable = true
+	config.RPC.URL = httpServer.URL
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	filler := newNodeGapFiller(&config)
+	if err := filler.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer filler.Close()
+
+	checkMessages := func(from uint64, count uint64, expected int) {
+		t.Helper()
+		msgs, err := filler.FeedMessages(ctx, arbutil.MessageIndex(from), count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(msgs) != expected {
+			t.Fatalf("expected %v messages from %v, got %v", expected, from, len(msgs))
+		}
+		for i, msg := range msgs {
+			if uint64(msg.SequenceNumber) != from+uint64(i) {
+				t.Fatalf("expected message %v, got %v", from+uint64(i), msg.SequenceNumber)
+			}
+		}
+	}
+
+	// ends at the end of the chunk
+	checkMessages(100, 1000, 156)
+	checkMessages(10, 20, 20)
+	if api.calls.Load() != 1 {
+		t.Fatalf("expected complete chunk to be cached, node was called %v times", api.calls.Load())
+	}
+	// the partial last chunk isn't cached, so later messages are found
+	checkMessages(520, 100, 80)
+	api.msgCount.Store(700)
+	checkMessages(520, 100, 100)
+	if api.calls.Load() != 3 {
+		t.Fatalf("expected partial chunk to be read again, node was called %v times", api.calls.Load())
+	}
+	checkMessages(800, 10, 0)
+}
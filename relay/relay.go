@@ -6,10 +6,13 @@ package relay
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
 
 	flag "github.com/spf13/pflag"
 
+	"github.com/ethereum/go-ethereum/log"
+
 	"github.com/offchainlabs/nitro/arbutil"
 	"github.com/offchainlabs/nitro/broadcastclient"
 	"github.com/offchainlabs/nitro/broadcastclients"
@@ -28,6 +31,10 @@ type Relay struct {
 	broadcaster                 *broadcaster.Broadcaster
 	confirmedSequenceNumberChan chan arbutil.MessageIndex
 	messageChan                 chan m.BroadcastFeedMessage
+	gapFiller                   *nodeGapFiller
+	backfill                    *BackfillConfig
+	feedAuth                    *wsbroadcastserver.AuthConfig
+	chains                      []*chainFeed
 }
 
 type MessageQueue struct {
@@ -43,6 +50,26 @@ func (q *MessageQueue) AddBroadcastMessages(feedMessages []*m.BroadcastFeedMessa
 }
 
 func NewRelay(config *Config, feedErrChan chan error) (*Relay, error) {
+	if err := config.Node.GapFill.Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.Node.Feed.Input.Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.Backfill.Validate(); err != nil {
+		return nil, err
+	}
+	chainIds, chainUrls, err := parseChains(config.Chains, config.Chain.ID)
+	if err != nil {
+		return nil, err
+	}
+	chainSigners, err := parseChainSigners(config.ChainSigners, chainIds)
+	if err != nil {
+		return nil, err
+	}
+	if config.Node.Feed.Input.SigningKeys.RegistryAddress != "" {
+		log.Warn("relay has no parent chain connection, ignoring the feed signing key registry")
+	}
 
 	q := MessageQueue{make(chan m.BroadcastFeedMessage, config.Queue)}
 
@@ -56,6 +83,7 @@ func NewRelay(config *Config, feedErrChan chan error) (*Relay, error) {
 		confirmedSequenceNumberListener,
 		feedErrChan,
 		nil,
+		nil,
 	)
 	if err != nil {
 		return nil, err
@@ -67,16 +95,43 @@ func NewRelay(config *Config, feedErrChan chan error) (*Relay, error) {
 	dataSignerErr := func([]byte) ([]byte, error) {
 		return nil, errors.New("relay attempted to sign feed message")
 	}
+	bcast := broadcaster.NewBroadcaster(func() *wsbroadcastserver.BroadcasterConfig { return &config.Node.Feed.Output }, config.Chain.ID, feedErrChan, dataSignerErr)
+	var gapFiller *nodeGapFiller
+	if config.Node.GapFill.Enable {
+		gapFiller = newNodeGapFiller(&config.Node.GapFill)
+		bcast.SetGapFiller(gapFiller, config.Node.GapFill.MaxMessages)
+	}
+	var chains []*chainFeed
+	for _, chainId := range chainIds {
+		verify, err := chainVerifierConfig(config, chainId, chainSigners[chainId])
+		if err != nil {
+			return nil, err
+		}
+		chain, err := newChainFeed(config, chainId, chainUrls[chainId], verify, feedErrChan)
+		if err != nil {
+			return nil, err
+		}
+		chains = append(chains, chain)
+	}
 	return &Relay{
-		broadcaster:                 broadcaster.NewBroadcaster(func() *wsbroadcastserver.BroadcasterConfig { return &config.Node.Feed.Output }, config.Chain.ID, feedErrChan, dataSignerErr),
+		broadcaster:                 bcast,
 		broadcastClients:            clients,
 		confirmedSequenceNumberChan: confirmedSequenceNumberListener,
 		messageChan:                 q.queue,
+		gapFiller:                   gapFiller,
+		backfill:                    &config.Backfill,
+		feedAuth:                    &config.Node.Feed.Output.Auth,
+		chains:                      chains,
 	}, nil
 }
 
 func (r *Relay) Start(ctx context.Context) error {
 	r.StopWaiter.Start(ctx, r)
+	if r.gapFiller != nil {
+		if err := r.gapFiller.Start(ctx); err != nil {
+			return fmt.Errorf("unable to connect to node to fill gaps: %w", err)
+		}
+	}
 	err := r.broadcaster.Initialize()
 	if err != nil {
 		return errors.New("broadcast unable to initialize")
@@ -85,9 +140,18 @@ func (r *Relay) Start(ctx context.Context) error {
 	if err != nil {
 		return errors.New("broadcast unable to start")
 	}
+	for _, chain := range r.chains {
+		if err := chain.Start(ctx, r.broadcaster); err != nil {
+			return err
+		}
+	}
 
 	r.broadcastClients.Start(ctx)
 
+	if r.backfill.Enable {
+		r.LaunchThread(r.launchBackfillServer)
+	}
+
 	r.LaunchThread(func(ctx context.Context) {
 		for {
 			select {
@@ -111,34 +175,48 @@ func (r *Relay) GetListenerAddr() net.Addr {
 
 func (r *Relay) StopAndWait() {
 	r.StopWaiter.StopAndWait()
+	for _, chain := range r.chains {
+		chain.StopAndWait()
+	}
 	r.broadcastClients.StopAndWait()
 	r.broadcaster.StopAndWait()
+	if r.gapFiller != nil {
+		r.gapFiller.Close()
+	}
 }
 
 type Config struct {
-	Conf          genericconf.ConfConfig          `koanf:"conf"`
-	Chain         L2Config                        `koanf:"chain"`
-	LogLevel      string                          `koanf:"log-level"`
-	LogType       string                          `koanf:"log-type"`
-	Metrics       bool                            `koanf:"metrics"`
-	MetricsServer genericconf.MetricsServerConfig `koanf:"metrics-server"`
-	PProf         bool                            `koanf:"pprof"`
-	PprofCfg      genericconf.PProf               `koanf:"pprof-cfg"`
-	Node          NodeConfig                      `koanf:"node"`
-	Queue         int                             `koanf:"queue"`
+	Conf                   genericconf.ConfConfig          `koanf:"conf"`
+	Chain                  L2Config                        `koanf:"chain"`
+	LogLevel               string                          `koanf:"log-level"`
+	LogType                string                          `koanf:"log-type"`
+	Metrics                bool                            `koanf:"metrics"`
+	MetricsServer          genericconf.MetricsServerConfig `koanf:"metrics-server"`
+	PProf                  bool                            `koanf:"pprof"`
+	PprofCfg               genericconf.PProf               `koanf:"pprof-cfg"`
+	Node                   NodeConfig                      `koanf:"node"`
+	Queue                  int                             `koanf:"queue"`
+	Backfill               BackfillConfig                  `koanf:"backfill"`
+	Chains                 []string                        `koanf:"chains"`
+	ChainSigners           []string                        `koanf:"chain-signers"`
+	ChainsAcceptUnverified bool                            `koanf:"chains-accept-unverified"`
 }
 
 var ConfigDefault = Config{
-	Conf:          genericconf.ConfConfigDefault,
-	Chain:         L2ConfigDefault,
-	LogLevel:      "INFO",
-	LogType:       "plaintext",
-	Metrics:       false,
-	MetricsServer: genericconf.MetricsServerConfigDefault,
-	PProf:         false,
-	PprofCfg:      genericconf.PProfDefault,
-	Node:          NodeConfigDefault,
-	Queue:         1024,
+	Conf:                   genericconf.ConfConfigDefault,
+	Chain:                  L2ConfigDefault,
+	LogLevel:               "INFO",
+	LogType:                "plaintext",
+	Metrics:                false,
+	MetricsServer:          genericconf.MetricsServerConfigDefault,
+	PProf:                  false,
+	PprofCfg:               genericconf.PProfDefault,
+	Node:                   NodeConfigDefault,
+	Queue:                  1024,
+	Backfill:               BackfillConfigDefault,
+	Chains:                 []string{},
+	ChainSigners:           []string{},
+	ChainsAcceptUnverified: false,
 }
 
 func ConfigAddOptions(f *flag.FlagSet) {
@@ -152,18 +230,25 @@ func ConfigAddOptions(f *flag.FlagSet) {
 	genericconf.PProfAddOptions("pprof-cfg", f)
 	NodeConfigAddOptions("node", f)
 	f.Int("queue", ConfigDefault.Queue, "queue for incoming messages from sequencer")
+	BackfillConfigAddOptions("backfill", f)
+	f.StringSlice("chains", ConfigDefault.Chains, "additional chains to relay alongside chain.id, each given as <chain id>@<feed url> (repeat a chain for several feed urls), sharing the node.feed settings and served on the same port at the path /chain/<chain id>")
+	f.StringSlice("chain-signers", ConfigDefault.ChainSigners, "feed signers to accept for the additional chains, each given as <chain id>@<address> (repeat a chain for several signers)")
+	f.Bool("chains-accept-unverified", ConfigDefault.ChainsAcceptUnverified, "DANGEROUS! relay the feeds of additional chains without chain-signers without verifying their signatures")
 }
 
 type NodeConfig struct {
-	Feed broadcastclient.FeedConfig `koanf:"feed"`
+	Feed    broadcastclient.FeedConfig `koanf:"feed"`
+	GapFill GapFillConfig              `koanf:"gap-fill"`
 }
 
 var NodeConfigDefault = NodeConfig{
-	Feed: broadcastclient.FeedConfigDefault,
+	Feed:    broadcastclient.FeedConfigDefault,
+	GapFill: GapFillConfigDefault,
 }
 
 func NodeConfigAddOptions(prefix string, f *flag.FlagSet) {
 	broadcastclient.FeedConfigAddOptions(prefix+".feed", f, true, true)
+	GapFillConfigAddOptions(prefix+".gap-fill", f)
 }
 
 type L2Config struct {
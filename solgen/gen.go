@@ -61,6 +61,67 @@ func (m *moduleInfo) exportABIs(dest string) {
 	}
 }
 
+// addAbiFragments appends the abi entries in each <module>/<contract>.json file under dir to the contract of the
+// same name. They declare the methods and events of nitro's own precompile extensions, which the pinned
+// contracts don't have yet. An entry the contract already declares is fatal, so fragments must be removed
+// once the contracts declare them.
+func addAbiFragments(modules map[string]*moduleInfo, dir string) {
+	filePaths, err := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, path := range filePaths {
+		moduleDir, file := filepath.Split(path)
+		_, module := filepath.Split(moduleDir[:len(moduleDir)-1])
+		module = strings.ReplaceAll(module, "-", "_") + "gen"
+		name := file[:len(file)-5]
+
+		modInfo := modules[module]
+		if modInfo == nil {
+			log.Fatal("abi fragment ", path, " is for unknown module ", module)
+		}
+		index := -1
+		for i, contractName := range modInfo.contractNames {
+			if contractName == name {
+				index = i
+			}
+		}
+		if index < 0 {
+			log.Fatal("abi fragment ", path, " is for unknown contract ", name)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatal("could not read abi fragment ", path, err)
+		}
+		var fragment []map[string]interface{}
+		if err := json.Unmarshal(data, &fragment); err != nil {
+			log.Fatal("failed to parse abi fragment ", path, err)
+		}
+		var entries []map[string]interface{}
+		if err := json.Unmarshal([]byte(modInfo.abis[index]), &entries); err != nil {
+			log.Fatal(err)
+		}
+		declared := make(map[string]bool)
+		for _, entry := range entries {
+			declared[fmt.Sprint(entry["type"], " ", entry["name"])] = true
+		}
+		for _, entry := range fragment {
+			key := fmt.Sprint(entry["type"], " ", entry["name"])
+			if declared[key] {
+				log.Fatal("contract ", name, " already declares the ", key, " of abi fragment ", path, ", so the fragment entry should be removed")
+			}
+			declared[key] = true
+			entries = append(entries, entry)
+		}
+		abi, err := json.Marshal(entries)
+		if err != nil {
+			log.Fatal(err)
+		}
+		modInfo.abis[index] = string(abi)
+	}
+}
+
 func main() {
 	_, filename, _, ok := runtime.Caller(0)
 	if !ok {
@@ -167,6 +228,8 @@ func main() {
 		modInfo.addArtifact(artifact)
 	}
 
+	addAbiFragments(modules, filepath.Join(root, "abi"))
+
 	for module, info := range modules {
 
 		code, err := bind.Bind(
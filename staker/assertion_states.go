@@ -0,0 +1,188 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/validator"
+)
+
+// MaxAssertionStatesPerQuery bounds how many block states a single AssertionBlockStates call returns.
+const MaxAssertionStatesPerQuery = 1024
+
+type AssertionSideInfo struct {
+	GlobalState    validator.GoGlobalState `json:"globalState"`
+	MachineStatus  validator.MachineStatus `json:"machineStatus"`
+	BlockStateHash common.Hash             `json:"blockStateHash"`
+}
+
+// AssertionInfo describes a posted assertion along with the data needed to
+// recompute its execution hash and check it against the local chain.
+type AssertionInfo struct {
+	NodeNum         uint64            `json:"nodeNum"`
+	NodeHash        common.Hash       `json:"nodeHash"`
+	WasmModuleRoot  common.Hash       `json:"wasmModuleRoot"`
+	NumBlocks       uint64            `json:"numBlocks"`
+	ExecutionHash   common.Hash       `json:"executionHash"`
+	MaxBatchesRead  uint64            `json:"maxBatchesRead"`
+	Before          AssertionSideInfo `json:"before"`
+	After           AssertionSideInfo `json:"after"`
+	LocalAfterHash  *common.Hash      `json:"localAfterHash,omitempty"`
+	MatchesLocalRun *bool             `json:"matchesLocalRun,omitempty"`
+}
+
+// AssertionBlockState is the block challenge state at a single step of an assertion.
+type AssertionBlockState struct {
+	Step          uint64                  `json:"step"`
+	MessageCount  arbutil.MessageIndex    `json:"messageCount"`
+	GlobalState   validator.GoGlobalState `json:"globalState"`
+	MachineStatus validator.MachineStatus `json:"machineStatus"`
+	Hash          common.Hash             `json:"hash"`
+}
+
+func newAssertionSideInfo(state *validator.ExecutionState) AssertionSideInfo {
+	return AssertionSideInfo{
+		GlobalState:    state.GlobalState,
+		MachineStatus:  state.MachineStatus,
+		BlockStateHash: state.BlockStateHash(),
+	}
+}
+
+func (v *L1Validator) assertionBackend(node *NodeInfo) (*BlockChallengeBackend, error) {
+	return newBlockChallengeBackendFromStates(
+		node.Assertion.BeforeState.GlobalState,
+		node.Assertion.AfterState.GlobalState,
+		node.Assertion.AfterState.RequiredBatches(),
+		v.txStreamer,
+		v.inboxTracker,
+	)
+}
+
+// AssertionInfo looks up the assertion posted as nodeNum. If the local chain has
+// caught up to the assertion, the block state hash this node computes for the
+// assertion's last block is included, so callers can see whether they agree.
+func (v *L1Validator) AssertionInfo(ctx context.Context, nodeNum uint64) (*AssertionInfo, error) {
+	if nodeNum == 0 {
+		return nil, errors.New("node 0 has no assertion")
+	}
+	node, err := v.rollup.LookupNode(ctx, nodeNum)
+	if err != nil {
+		return nil, err
+	}
+	info := &AssertionInfo{
+		NodeNum:        node.NodeNum,
+		NodeHash:       node.NodeHash,
+		WasmModuleRoot: node.WasmModuleRoot,
+		NumBlocks:      node.Assertion.NumBlocks,
+		ExecutionHash:  node.Assertion.ExecutionHash(),
+		MaxBatchesRead: node.Assertion.AfterState.RequiredBatches(),
+		Before:         newAssertionSideInfo(node.Assertion.BeforeState),
+		After:          newAssertionSideInfo(node.Assertion.AfterState),
+	}
+	batchCount, err := v.inboxTracker.GetBatchCount()
+	if err != nil {
+		return nil, err
+	}
+	if batchCount < info.MaxBatchesRead {
+		return info, nil
+	}
+	backend, err := v.assertionBackend(node)
+	if err != nil {
+		return nil, err
+	}
+	processed, err := v.txStreamer.GetProcessedMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	if processed < backend.GetMessageCountAtStep(info.NumBlocks) {
+		return info, nil
+	}
+	localHash, err := backend.GetHashAtStep(ctx, info.NumBlocks)
+	if err != nil {
+		return nil, err
+	}
+	matches := localHash == info.After.BlockStateHash
+	info.LocalAfterHash = &localHash
+	info.MatchesLocalRun = &matches
+	return info, nil
+}
+
+// AssertionBlockStates returns the per-block states backing the assertion posted
+// as nodeNum, as they would be bisected over in a block challenge. Step 0 is the
+// assertion's start state and step NumBlocks is its end state.
+func (v *L1Validator) AssertionBlockStates(ctx context.Context, nodeNum uint64, start uint64, count uint64) ([]AssertionBlockState, error) {
+	if nodeNum == 0 {
+		return nil, errors.New("node 0 has no assertion")
+	}
+	if count > MaxAssertionStatesPerQuery {
+		return nil, fmt.Errorf("requested %v states, at most %v can be queried at once", count, MaxAssertionStatesPerQuery)
+	}
+	node, err := v.rollup.LookupNode(ctx, nodeNum)
+	if err != nil {
+		return nil, err
+	}
+	numBlocks := node.Assertion.NumBlocks
+	if start > numBlocks {
+		return nil, fmt.Errorf("start step %v is past the assertion's %v blocks", start, numBlocks)
+	}
+	if count > numBlocks-start+1 {
+		count = numBlocks - start + 1
+	}
+	backend, err := v.assertionBackend(node)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, nil
+	}
+	lastMsgCount := backend.GetMessageCountAtStep(start + count - 1)
+	streamerCount, err := v.txStreamer.GetProcessedMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	if lastMsgCount > streamerCount {
+		return nil, fmt.Errorf("local chain has only processed %v messages, need %v", streamerCount, lastMsgCount)
+	}
+	states := make([]AssertionBlockState, 0, count)
+	for step := start; step < start+count; step++ {
+		gs, status, err := backend.GetInfoAtStep(step)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get assertion %v state at step %v: %w", nodeNum, step, err)
+		}
+		hash, err := backend.GetHashAtStep(ctx, step)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, AssertionBlockState{
+			Step:          step,
+			MessageCount:  backend.GetMessageCountAtStep(step),
+			GlobalState:   gs,
+			MachineStatus: validator.MachineStatus(status),
+			Hash:          hash,
+		})
+	}
+	return states, nil
+}
+
+// LatestStakedAssertion returns the assertion this validator's wallet is currently staked on.
+func (v *L1Validator) LatestStakedAssertion(ctx context.Context) (*AssertionInfo, error) {
+	walletAddr := v.wallet.Address()
+	if walletAddr == nil {
+		return nil, errors.New("validator has no wallet address")
+	}
+	info, err := v.rollup.StakerInfo(ctx, *walletAddr)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return nil, fmt.Errorf("validator %v is not staked", *walletAddr)
+	}
+	return v.AssertionInfo(ctx, info.LatestStakedNode)
+}
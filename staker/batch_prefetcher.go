@@ -0,0 +1,124 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/util/containers"
+)
+
+var (
+	validatorBatchPrefetchHitCounter  = metrics.NewRegisteredCounter("arb/validator/batchprefetch/hit", nil)
+	validatorBatchPrefetchMissCounter = metrics.NewRegisteredCounter("arb/validator/batchprefetch/miss", nil)
+	validatorBatchPrefetchInFlight    = metrics.NewRegisteredGauge("arb/validator/batchprefetch/inflight", nil)
+)
+
+type fetchedBatch struct {
+	found     bool
+	data      []byte
+	blockHash common.Hash
+	msgCount  arbutil.MessageIndex
+}
+
+type batchReader func(ctx context.Context, batchNum uint64) (bool, []byte, common.Hash, arbutil.MessageIndex, error)
+
+// batchPrefetcher reads the batches following the one validation entries are
+// currently being created from, so that fetching batch data from the parent
+// chain or a DA provider overlaps with validating the current batch.
+type batchPrefetcher struct {
+	read   batchReader
+	launch func(func(ctx context.Context))
+
+	mutex   sync.Mutex
+	fetches map[uint64]*containers.Promise[fetchedBatch]
+}
+
+func newBatchPrefetcher(read batchReader, launch func(func(ctx context.Context))) *batchPrefetcher {
+	return &batchPrefetcher{
+		read:    read,
+		launch:  launch,
+		fetches: make(map[uint64]*containers.Promise[fetchedBatch]),
+	}
+}
+
+// prefetch starts reading batches in [from, from+window) that aren't being read already.
+func (p *batchPrefetcher) prefetch(from uint64, window uint64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for batchNum := from; batchNum < from+window; batchNum++ {
+		if existing, ok := p.fetches[batchNum]; ok {
+			res, err := existing.Current()
+			if errors.Is(err, containers.ErrNotReady) || (err == nil && res.found) {
+				continue
+			}
+			// the batch wasn't posted yet or reading it failed, try again
+		}
+		promise := containers.NewPromise[fetchedBatch](nil)
+		p.fetches[batchNum] = &promise
+		validatorBatchPrefetchInFlight.Inc(1)
+		batchNum := batchNum
+		p.launch(func(ctx context.Context) {
+			defer validatorBatchPrefetchInFlight.Dec(1)
+			found, data, blockHash, msgCount, err := p.read(ctx, batchNum)
+			if err != nil {
+				log.Debug("error prefetching batch", "batch", batchNum, "err", err)
+				promise.ProduceError(err)
+				return
+			}
+			promise.Produce(fetchedBatch{
+				found:     found,
+				data:      data,
+				blockHash: blockHash,
+				msgCount:  msgCount,
+			})
+		})
+	}
+}
+
+// get returns the batch, using the prefetched result if there is one.
+// Failed or not-yet-posted prefetches are retried with a direct read.
+func (p *batchPrefetcher) get(ctx context.Context, batchNum uint64) (bool, []byte, common.Hash, arbutil.MessageIndex, error) {
+	p.mutex.Lock()
+	promise, ok := p.fetches[batchNum]
+	delete(p.fetches, batchNum)
+	p.mutex.Unlock()
+	if ok {
+		res, err := promise.Await(ctx)
+		if err == nil && res.found {
+			validatorBatchPrefetchHitCounter.Inc(1)
+			return true, res.data, res.blockHash, res.msgCount, nil
+		}
+		if ctx.Err() != nil {
+			return false, nil, common.Hash{}, 0, ctx.Err()
+		}
+	}
+	validatorBatchPrefetchMissCounter.Inc(1)
+	return p.read(ctx, batchNum)
+}
+
+// reset drops all prefetched batches, e.g. after a batch reorg.
+func (p *batchPrefetcher) reset() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.fetches = make(map[uint64]*containers.Promise[fetchedBatch])
+}
+
+// pruneBelow drops prefetched batches before batchNum, which will no longer be read.
+func (p *batchPrefetcher) pruneBelow(batchNum uint64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for fetched := range p.fetches {
+		if fetched < batchNum {
+			delete(p.fetches, fetched)
+		}
+	}
+}
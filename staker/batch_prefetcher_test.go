@@ -0,0 +1,65 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/arbutil"
+)
+
+func TestBatchPrefetcher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mutex sync.Mutex
+	reads := make(map[uint64]int)
+	posted := uint64(3)
+	read := func(_ context.Context, batchNum uint64) (bool, []byte, common.Hash, arbutil.MessageIndex, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		reads[batchNum]++
+		if batchNum >= posted {
+			return false, nil, common.Hash{}, 0, nil
+		}
+		return true, []byte{byte(batchNum)}, common.Hash{}, arbutil.MessageIndex(batchNum * 10), nil
+	}
+	var wg sync.WaitGroup
+	launch := func(f func(context.Context)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f(ctx)
+		}()
+	}
+	prefetcher := newBatchPrefetcher(read, launch)
+
+	prefetcher.prefetch(1, 4)
+	wg.Wait()
+	found, data, _, count, err := prefetcher.get(ctx, 1)
+	Require(t, err)
+	if !found || data[0] != 1 || count != 10 {
+		Fail(t, "unexpected prefetched batch", found, data, count)
+	}
+	// batch 3 wasn't posted when prefetched, so it must be read again once it is
+	mutex.Lock()
+	posted = 5
+	mutex.Unlock()
+	found, _, _, _, err = prefetcher.get(ctx, 3)
+	Require(t, err)
+	if !found {
+		Fail(t, "batch posted after prefetch not found")
+	}
+	prefetcher.prefetch(2, 3)
+	wg.Wait()
+	mutex.Lock()
+	if reads[1] != 1 || reads[2] != 1 || reads[4] != 2 {
+		Fail(t, "unexpected batch reads", reads)
+	}
+	mutex.Unlock()
+}
@@ -38,8 +38,22 @@ func NewBlockChallengeBackend(
 	streamer TransactionStreamerInterface,
 	inboxTracker InboxTrackerInterface,
 ) (*BlockChallengeBackend, error) {
-	startGs := validator.GoGlobalStateFromSolidity(initialState.StartState)
+	return newBlockChallengeBackendFromStates(
+		validator.GoGlobalStateFromSolidity(initialState.StartState),
+		validator.GoGlobalStateFromSolidity(initialState.EndState),
+		maxBatchesRead,
+		streamer,
+		inboxTracker,
+	)
+}
 
+func newBlockChallengeBackendFromStates(
+	startGs validator.GoGlobalState,
+	endGs validator.GoGlobalState,
+	maxBatchesRead uint64,
+	streamer TransactionStreamerInterface,
+	inboxTracker InboxTrackerInterface,
+) (*BlockChallengeBackend, error) {
 	var startMsgCount arbutil.MessageIndex
 	if startGs.Batch > 0 {
 		var err error
@@ -65,7 +79,7 @@ func NewBlockChallengeBackend(
 		startGs:                startGs,
 		startPosition:          0,
 		endPosition:            math.MaxUint64,
-		endGs:                  validator.GoGlobalStateFromSolidity(initialState.EndState),
+		endGs:                  endGs,
 		inboxTracker:           inboxTracker,
 		tooFarStartsAtPosition: uint64(endMsgCount - startMsgCount + 1),
 	}, nil
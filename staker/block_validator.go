@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"regexp"
 	"runtime"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -95,6 +96,10 @@ type BlockValidator struct {
 
 	fatalErr chan<- error
 
+	pool *ValidatorPool
+
+	batchPrefetcher *batchPrefetcher
+
 	MemoryFreeLimitChecker resourcemanager.LimitChecker
 }
 
@@ -106,12 +111,14 @@ type BlockValidatorConfig struct {
 	ValidationPoll              time.Duration                 `koanf:"validation-poll" reload:"hot"`
 	PrerecordedBlocks           uint64                        `koanf:"prerecorded-blocks" reload:"hot"`
 	ForwardBlocks               uint64                        `koanf:"forward-blocks" reload:"hot"`
+	BatchPrefetchWindow         uint64                        `koanf:"batch-prefetch-window" reload:"hot"`
 	CurrentModuleRoot           string                        `koanf:"current-module-root"`         // TODO(magic) requires reinitialization on hot reload
 	PendingUpgradeModuleRoot    string                        `koanf:"pending-upgrade-module-root"` // TODO(magic) requires StatelessBlockValidator recreation on hot reload
 	FailureIsFatal              bool                          `koanf:"failure-is-fatal" reload:"hot"`
 	Dangerous                   BlockValidatorDangerousConfig `koanf:"dangerous"`
 	MemoryFreeLimit             string                        `koanf:"memory-free-limit" reload:"hot"`
 	ValidationServerConfigsList string                        `koanf:"validation-server-configs-list"`
+	Pool                        ValidatorPoolConfig           `koanf:"pool"`
 
 	memoryFreeLimit int
 }
@@ -152,7 +159,7 @@ func (c *BlockValidatorConfig) Validate() error {
 			}
 		}
 	}
-	return nil
+	return c.Pool.Validate()
 }
 
 type BlockValidatorDangerousConfig struct {
@@ -168,12 +175,14 @@ func BlockValidatorConfigAddOptions(prefix string, f *pflag.FlagSet) {
 	f.String(prefix+".validation-server-configs-list", DefaultBlockValidatorConfig.ValidationServerConfigsList, "array of execution rpc configs given as a json string. time duration should be supplied in number indicating nanoseconds")
 	f.Duration(prefix+".validation-poll", DefaultBlockValidatorConfig.ValidationPoll, "poll time to check validations")
 	f.Uint64(prefix+".forward-blocks", DefaultBlockValidatorConfig.ForwardBlocks, "prepare entries for up to that many blocks ahead of validation (small footprint)")
+	f.Uint64(prefix+".batch-prefetch-window", DefaultBlockValidatorConfig.BatchPrefetchWindow, "number of upcoming batches to fetch concurrently while preparing validation entries (0 = fetch batches when needed)")
 	f.Uint64(prefix+".prerecorded-blocks", DefaultBlockValidatorConfig.PrerecordedBlocks, "record that many blocks ahead of validation (larger footprint)")
 	f.String(prefix+".current-module-root", DefaultBlockValidatorConfig.CurrentModuleRoot, "current wasm module root ('current' read from chain, 'latest' from machines/latest dir, or provide hash)")
 	f.String(prefix+".pending-upgrade-module-root", DefaultBlockValidatorConfig.PendingUpgradeModuleRoot, "pending upgrade wasm module root to additionally validate (hash, 'latest' or empty)")
 	f.Bool(prefix+".failure-is-fatal", DefaultBlockValidatorConfig.FailureIsFatal, "failing a validation is treated as a fatal error")
 	BlockValidatorDangerousConfigAddOptions(prefix+".dangerous", f)
 	f.String(prefix+".memory-free-limit", DefaultBlockValidatorConfig.MemoryFreeLimit, "minimum free-memory limit after reaching which the blockvalidator pauses validation. Enabled by default as 1GB, to disable provide empty string")
+	ValidatorPoolConfigAddOptions(prefix+".pool", f)
 }
 
 func BlockValidatorDangerousConfigAddOptions(prefix string, f *pflag.FlagSet) {
@@ -187,12 +196,14 @@ var DefaultBlockValidatorConfig = BlockValidatorConfig{
 	RedisValidationClientConfig: redis.DefaultValidationClientConfig,
 	ValidationPoll:              time.Second,
 	ForwardBlocks:               1024,
+	BatchPrefetchWindow:         4,
 	PrerecordedBlocks:           uint64(2 * runtime.NumCPU()),
 	CurrentModuleRoot:           "current",
 	PendingUpgradeModuleRoot:    "latest",
 	FailureIsFatal:              true,
 	Dangerous:                   DefaultBlockValidatorDangerousConfig,
 	MemoryFreeLimit:             "default",
+	Pool:                        DefaultValidatorPoolConfig,
 }
 
 var TestBlockValidatorConfig = BlockValidatorConfig{
@@ -202,12 +213,14 @@ var TestBlockValidatorConfig = BlockValidatorConfig{
 	RedisValidationClientConfig: redis.TestValidationClientConfig,
 	ValidationPoll:              100 * time.Millisecond,
 	ForwardBlocks:               128,
+	BatchPrefetchWindow:         2,
 	PrerecordedBlocks:           uint64(2 * runtime.NumCPU()),
 	CurrentModuleRoot:           "latest",
 	PendingUpgradeModuleRoot:    "latest",
 	FailureIsFatal:              true,
 	Dangerous:                   DefaultBlockValidatorDangerousConfig,
 	MemoryFreeLimit:             "default",
+	Pool:                        TestValidatorPoolConfig,
 }
 
 var DefaultBlockValidatorDangerousConfig = BlockValidatorDangerousConfig{
@@ -264,6 +277,7 @@ func NewBlockValidator(
 		config:                  config,
 		fatalErr:                fatalErr,
 	}
+	ret.batchPrefetcher = newBatchPrefetcher(ret.readBatch, ret.LaunchThread)
 	if !config().Dangerous.ResetBlockValidation {
 		validated, err := ret.ReadLastValidatedInfo()
 		if err != nil {
@@ -292,6 +306,13 @@ func NewBlockValidator(
 			PosInBatch: 0,
 		}
 	}
+	if config().Pool.Enable {
+		pool, err := NewValidatorPool(func() *ValidatorPoolConfig { return &config().Pool })
+		if err != nil {
+			return nil, err
+		}
+		ret.pool = pool
+	}
 	streamer.SetBlockValidator(ret)
 	inbox.SetBlockValidator(ret)
 	if config().MemoryFreeLimit != "" {
@@ -562,10 +583,15 @@ func (v *BlockValidator) createNextValidationEntry(ctx context.Context) (bool, e
 	}
 	if v.nextCreateStartGS.PosInBatch == 0 || v.nextCreateBatchReread {
 		// new batch
-		found, batch, batchBlockHash, count, err := v.readBatch(ctx, v.nextCreateStartGS.Batch)
+		if v.nextCreateBatchReread {
+			v.batchPrefetcher.reset()
+		}
+		found, batch, batchBlockHash, count, err := v.batchPrefetcher.get(ctx, v.nextCreateStartGS.Batch)
 		if !found {
 			return false, err
 		}
+		v.batchPrefetcher.pruneBelow(v.nextCreateStartGS.Batch)
+		v.batchPrefetcher.prefetch(v.nextCreateStartGS.Batch+1, v.config().BatchPrefetchWindow)
 		v.nextCreateBatch = batch
 		v.nextCreateBatchBlockHash = batchBlockHash
 		v.nextCreateBatchMsgCount = count
@@ -785,21 +811,38 @@ validationsLoop:
 				}
 				validatorValidValidationsCounter.Inc(1)
 			}
-			err := v.writeLastValidated(validationStatus.Entry.End, wasmRoots)
-			if err != nil {
-				log.Error("failed writing new validated to database", "pos", pos, "err", err)
-			}
-			go v.recorder.MarkValid(pos, v.lastValidGS.BlockHash)
-			atomicStorePos(&v.validatedA, pos+1, validatorMsgCountValidatedGauge)
-			v.validations.Delete(pos)
-			nonBlockingTrigger(v.createNodesChan)
-			nonBlockingTrigger(v.sendRecordChan)
-			if v.testingProgressMadeChan != nil {
-				nonBlockingTrigger(v.testingProgressMadeChan)
+			v.markValidated(pos, validationStatus.Entry.End, wasmRoots)
+			if v.pool != nil {
+				result := GlobalStateValidatedInfo{
+					GlobalState: validationStatus.Entry.End,
+					WasmRoots:   wasmRoots,
+				}
+				v.LaunchUntrackedThread(func() {
+					if err := v.pool.PublishResult(ctx, pos, &result); err != nil {
+						log.Warn("failed publishing validation result to pool", "pos", pos, "err", err)
+					}
+				})
 			}
-			log.Trace("result validated", "count", v.validated(), "blockHash", v.lastValidGS.BlockHash)
 			continue
 		}
+		if currentStatus == Prepared && v.pool != nil && !v.pool.IsAssigned(pos) {
+			if pos == v.validated() {
+				accepted, err := v.acceptPoolResult(ctx, pos, validationStatus, wasmRoots)
+				if err != nil {
+					log.Warn("failed reading validation result from pool", "pos", pos, "err", err)
+				}
+				if accepted {
+					continue
+				}
+			}
+			waited := time.Duration(time.Now().UnixMilli()-validationStatus.profileTS) * time.Millisecond
+			if waited < v.config().Pool.TakeoverTimeout {
+				// another pool member is responsible for this one
+				continue
+			}
+			log.Warn("validating message assigned to other pool members", "pos", pos, "waited", waited)
+			validatorPoolTakeoverCounter.Inc(1)
+		}
 		for _, moduleRoot := range wasmRoots {
 			spawner := v.chosenValidator[moduleRoot]
 			if spawner == nil {
@@ -866,6 +909,53 @@ validationsLoop:
 	}
 }
 
+// must be called holding reorg-read from the validation thread
+func (v *BlockValidator) markValidated(pos arbutil.MessageIndex, end validator.GoGlobalState, wasmRoots []common.Hash) {
+	err := v.writeLastValidated(end, wasmRoots)
+	if err != nil {
+		log.Error("failed writing new validated to database", "pos", pos, "err", err)
+	}
+	go v.recorder.MarkValid(pos, v.lastValidGS.BlockHash)
+	atomicStorePos(&v.validatedA, pos+1, validatorMsgCountValidatedGauge)
+	v.validations.Delete(pos)
+	nonBlockingTrigger(v.createNodesChan)
+	nonBlockingTrigger(v.sendRecordChan)
+	if v.testingProgressMadeChan != nil {
+		nonBlockingTrigger(v.testingProgressMadeChan)
+	}
+	log.Trace("result validated", "count", v.validated(), "blockHash", v.lastValidGS.BlockHash)
+}
+
+// acceptPoolResult advances validation past pos if another pool member
+// published an end state matching our own execution, validated against
+// every module root we validate against.
+func (v *BlockValidator) acceptPoolResult(ctx context.Context, pos arbutil.MessageIndex, validationStatus *validationStatus, wasmRoots []common.Hash) (bool, error) {
+	if validationStatus.Entry.Start != v.lastValidGS {
+		return false, nil
+	}
+	result, err := v.pool.GetResult(ctx, pos)
+	if err != nil || result == nil {
+		return false, err
+	}
+	if result.GlobalState != validationStatus.Entry.End {
+		validatorPoolMismatchCounter.Inc(1)
+		log.Error("validation pool result does not match local execution, validating locally", "pos", pos, "pool", result.GlobalState, "local", validationStatus.Entry.End)
+		// make sure we don't wait for takeover
+		validationStatus.profileTS = 0
+		return false, nil
+	}
+	for _, moduleRoot := range wasmRoots {
+		if !slices.Contains(result.WasmRoots, moduleRoot) {
+			log.Warn("validation pool result not validated against all module roots, validating locally", "pos", pos, "moduleRoot", moduleRoot, "poolRoots", result.WasmRoots)
+			validationStatus.profileTS = 0
+			return false, nil
+		}
+	}
+	validatorPoolAcceptedCounter.Inc(1)
+	v.markValidated(pos, result.GlobalState, result.WasmRoots)
+	return true, nil
+}
+
 func (v *BlockValidator) iterativeValidationProgress(ctx context.Context, ignored struct{}) time.Duration {
 	reorg, err := v.advanceValidations(ctx)
 	if err != nil {
@@ -1285,6 +1375,9 @@ func (v *BlockValidator) LaunchWorkthreadsWhenCaughtUp(ctx context.Context) {
 
 func (v *BlockValidator) Start(ctxIn context.Context) error {
 	v.StopWaiter.Start(ctxIn, v)
+	if v.pool != nil {
+		v.pool.Start(ctxIn)
+	}
 	v.LaunchThread(v.LaunchWorkthreadsWhenCaughtUp)
 	v.CallIteratively(v.iterativeValidationPrint)
 	return nil
@@ -1292,6 +1385,9 @@ func (v *BlockValidator) Start(ctxIn context.Context) error {
 
 func (v *BlockValidator) StopAndWait() {
 	v.StopWaiter.StopAndWait()
+	if v.pool != nil {
+		v.pool.StopAndWait()
+	}
 }
 
 // WaitForPos can only be used from One thread
@@ -0,0 +1,332 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	flag "github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/staker/txbuilder"
+	"github.com/offchainlabs/nitro/util/arbmath"
+)
+
+var (
+	stakeTokenBalanceGauge    = metrics.NewRegisteredGaugeFloat64("arb/staker/staketoken/balance", nil)
+	stakeTokenAllowanceGauge  = metrics.NewRegisteredGaugeFloat64("arb/staker/staketoken/allowance", nil)
+	stakeTokenApprovalCounter = metrics.NewRegisteredCounter("arb/staker/staketoken/approval", nil)
+	stakeTokenTopUpCounter    = metrics.NewRegisteredCounter("arb/staker/staketoken/topup", nil)
+	stakeTokenAlertCounter    = metrics.NewRegisteredCounter("arb/staker/staketoken/alert", nil)
+)
+
+// Only the subset of ERC20 used to manage the stake token.
+const stakeTokenABI = `[
+	{"type":"function","name":"balanceOf","stateMutability":"view","inputs":[{"name":"account","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"allowance","stateMutability":"view","inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"approve","stateMutability":"nonpayable","inputs":[{"name":"spender","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"transferFrom","stateMutability":"nonpayable","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]}
+]`
+
+type StakeTokenConfig struct {
+	Enable            bool          `koanf:"enable"`
+	Address           string        `koanf:"address"`
+	AutoApprove       bool          `koanf:"auto-approve"`
+	ApprovalAmount    string        `koanf:"approval-amount"`
+	MinBalance        string        `koanf:"min-balance"`
+	TopUpAmount       string        `koanf:"top-up-amount"`
+	FundingAddress    string        `koanf:"funding-address"`
+	MaxTopUpPerWindow string        `koanf:"max-top-up-per-window"`
+	TopUpWindow       time.Duration `koanf:"top-up-window"`
+
+	address           common.Address
+	approvalAmount    *big.Int
+	minBalance        *big.Int
+	topUpAmount       *big.Int
+	fundingAddress    common.Address
+	maxTopUpPerWindow *big.Int
+}
+
+var DefaultStakeTokenConfig = StakeTokenConfig{
+	Enable:            false,
+	Address:           "",
+	AutoApprove:       true,
+	ApprovalAmount:    "",
+	MinBalance:        "0",
+	TopUpAmount:       "0",
+	FundingAddress:    "",
+	MaxTopUpPerWindow: "0",
+	TopUpWindow:       24 * time.Hour,
+}
+
+func StakeTokenConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultStakeTokenConfig.Enable, "manage the allowance and balance of the rollup's ERC20 stake token")
+	f.String(prefix+".address", DefaultStakeTokenConfig.Address, "stake token address (if unset, read from the rollup contract)")
+	f.Bool(prefix+".auto-approve", DefaultStakeTokenConfig.AutoApprove, "automatically approve the rollup to spend the stake token when the allowance is below the required stake")
+	f.String(prefix+".approval-amount", DefaultStakeTokenConfig.ApprovalAmount, "amount of stake token (in base units) to approve; if empty, approves the maximum uint256")
+	f.String(prefix+".min-balance", DefaultStakeTokenConfig.MinBalance, "minimum stake token balance (in base units) to maintain in the validator wallet")
+	f.String(prefix+".top-up-amount", DefaultStakeTokenConfig.TopUpAmount, "amount of stake token (in base units) to pull from the funding address when the balance is below min-balance")
+	f.String(prefix+".funding-address", DefaultStakeTokenConfig.FundingAddress, "address to pull stake token top-ups from (must have approved the validator wallet)")
+	f.String(prefix+".max-top-up-per-window", DefaultStakeTokenConfig.MaxTopUpPerWindow, "maximum amount of stake token (in base units) to pull from the funding address per top-up window (0 = unlimited)")
+	f.Duration(prefix+".top-up-window", DefaultStakeTokenConfig.TopUpWindow, "length of the window max-top-up-per-window is enforced over")
+}
+
+func parseStakeTokenAmount(name string, value string) (*big.Int, error) {
+	if value == "" {
+		return new(big.Int), nil
+	}
+	amount, ok := new(big.Int).SetString(value, 0)
+	if !ok || amount.Sign() < 0 {
+		return nil, fmt.Errorf("invalid stake token %s \"%v\"", name, value)
+	}
+	return amount, nil
+}
+
+func (c *StakeTokenConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.Address != "" && !common.IsHexAddress(c.Address) {
+		return errors.New("invalid stake token address")
+	}
+	c.address = common.HexToAddress(c.Address)
+	if c.FundingAddress != "" && !common.IsHexAddress(c.FundingAddress) {
+		return errors.New("invalid stake token funding address")
+	}
+	c.fundingAddress = common.HexToAddress(c.FundingAddress)
+	var err error
+	if c.ApprovalAmount == "" {
+		c.approvalAmount = abi.MaxUint256
+	} else if c.approvalAmount, err = parseStakeTokenAmount("approval amount", c.ApprovalAmount); err != nil {
+		return err
+	}
+	if c.minBalance, err = parseStakeTokenAmount("min balance", c.MinBalance); err != nil {
+		return err
+	}
+	if c.topUpAmount, err = parseStakeTokenAmount("top up amount", c.TopUpAmount); err != nil {
+		return err
+	}
+	if c.maxTopUpPerWindow, err = parseStakeTokenAmount("max top up per window", c.MaxTopUpPerWindow); err != nil {
+		return err
+	}
+	if c.minBalance.Sign() > 0 {
+		if c.fundingAddress == (common.Address{}) {
+			return errors.New("stake token min balance requires a funding address")
+		}
+		if c.topUpAmount.Sign() <= 0 {
+			return errors.New("stake token min balance requires a positive top up amount")
+		}
+	}
+	if c.maxTopUpPerWindow.Sign() > 0 && c.TopUpWindow <= 0 {
+		return errors.New("stake token top up window must be positive")
+	}
+	return nil
+}
+
+type stakeTokenTopUp struct {
+	time   time.Time
+	amount *big.Int
+}
+
+// topUpPolicy limits how much stake token can be pulled from the funding
+// address within a sliding window.
+type topUpPolicy struct {
+	mutex   sync.Mutex
+	max     *big.Int
+	window  time.Duration
+	history []stakeTokenTopUp
+}
+
+func (p *topUpPolicy) prune(now time.Time) {
+	cutoff := now.Add(-p.window)
+	for len(p.history) > 0 && !p.history[0].time.After(cutoff) {
+		p.history = p.history[1:]
+	}
+}
+
+// allowed returns how much of the requested amount may be pulled right now.
+func (p *topUpPolicy) allowed(now time.Time, requested *big.Int) *big.Int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.max == nil || p.max.Sign() == 0 {
+		return new(big.Int).Set(requested)
+	}
+	p.prune(now)
+	remaining := new(big.Int).Set(p.max)
+	for _, topUp := range p.history {
+		remaining.Sub(remaining, topUp.amount)
+	}
+	if remaining.Sign() <= 0 {
+		return new(big.Int)
+	}
+	return arbmath.BigMin(remaining, requested)
+}
+
+func (p *topUpPolicy) record(now time.Time, amount *big.Int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.history = append(p.history, stakeTokenTopUp{time: now, amount: new(big.Int).Set(amount)})
+}
+
+// StakeTokenManager keeps the validator wallet able to place stakes on
+// rollups using a custom ERC20 stake token.
+type StakeTokenManager struct {
+	config   *StakeTokenConfig
+	abi      abi.ABI
+	rollup   *RollupWatcher
+	builder  *txbuilder.Builder
+	token    *bind.BoundContract
+	address  common.Address
+	policy   *topUpPolicy
+	canBatch bool
+}
+
+func NewStakeTokenManager(config *StakeTokenConfig, rollup *RollupWatcher, builder *txbuilder.Builder, canBatch bool) (*StakeTokenManager, error) {
+	parsed, err := abi.JSON(strings.NewReader(stakeTokenABI))
+	if err != nil {
+		return nil, err
+	}
+	return &StakeTokenManager{
+		config:   config,
+		abi:      parsed,
+		rollup:   rollup,
+		builder:  builder,
+		canBatch: canBatch,
+		policy: &topUpPolicy{
+			max:    config.maxTopUpPerWindow,
+			window: config.TopUpWindow,
+		},
+	}, nil
+}
+
+func (m *StakeTokenManager) Initialize(ctx context.Context) error {
+	address := m.config.address
+	if address == (common.Address{}) {
+		var err error
+		address, err = m.rollup.StakeToken(m.rollup.getCallOpts(ctx))
+		if err != nil {
+			return fmt.Errorf("error getting rollup stake token: %w", err)
+		}
+	}
+	if address == (common.Address{}) {
+		log.Warn("stake token management enabled but rollup uses native currency for stakes")
+	}
+	m.address = address
+	// The builder is used as the transactor so that approvals and top-ups
+	// are queued alongside the rest of the staker's transactions.
+	m.token = bind.NewBoundContract(address, m.abi, m.builder, m.builder, m.builder)
+	return nil
+}
+
+func (m *StakeTokenManager) callUint(callOpts *bind.CallOpts, method string, params ...interface{}) (*big.Int, error) {
+	var out []interface{}
+	if err := m.token.Call(callOpts, &out, method, params...); err != nil {
+		return nil, err
+	}
+	if len(out) != 1 {
+		return nil, fmt.Errorf("unexpected %v result length %v", method, len(out))
+	}
+	value, ok := out[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("unexpected %v result type %T", method, out[0])
+	}
+	return value, nil
+}
+
+func stakeTokenAlert(msg string, ctx ...interface{}) {
+	stakeTokenAlertCounter.Inc(1)
+	log.Error(msg, ctx...)
+}
+
+// Update queues an approval and/or a top-up transaction on the builder if the
+// wallet's allowance or balance falls short of what's needed to stake the
+// required amount. It returns true if any transaction was queued.
+func (m *StakeTokenManager) Update(ctx context.Context, wallet common.Address, requiredStake *big.Int) (bool, error) {
+	if m.address == (common.Address{}) {
+		return false, nil
+	}
+	callOpts := m.rollup.getCallOpts(ctx)
+	balance, err := m.callUint(callOpts, "balanceOf", wallet)
+	if err != nil {
+		return false, fmt.Errorf("error getting stake token balance: %w", err)
+	}
+	stakeTokenBalanceGauge.Update(arbmath.BalancePerEther(balance))
+	allowance, err := m.callUint(callOpts, "allowance", wallet, m.rollup.address)
+	if err != nil {
+		return false, fmt.Errorf("error getting stake token allowance: %w", err)
+	}
+	stakeTokenAllowanceGauge.Update(arbmath.BalancePerEther(allowance))
+
+	queued := false
+	if allowance.Cmp(requiredStake) < 0 {
+		if !m.config.AutoApprove {
+			stakeTokenAlert("stake token allowance below required stake and auto-approve disabled", "allowance", allowance, "requiredStake", requiredStake)
+		} else {
+			amount := arbmath.BigMax(m.config.approvalAmount, requiredStake)
+			auth, err := m.builder.Auth(ctx)
+			if err != nil {
+				return false, err
+			}
+			if _, err := m.token.Transact(auth, "approve", m.rollup.address, amount); err != nil {
+				return false, fmt.Errorf("error approving stake token: %w", err)
+			}
+			log.Info("approving rollup to spend stake token", "token", m.address, "amount", amount)
+			stakeTokenApprovalCounter.Inc(1)
+			queued = true
+		}
+	}
+	if queued && !m.canBatch {
+		// Only the first transaction will be executed, so leave the top-up for the next round
+		return queued, nil
+	}
+
+	target := arbmath.BigMax(m.config.minBalance, requiredStake)
+	if balance.Cmp(target) >= 0 || m.config.fundingAddress == (common.Address{}) {
+		if balance.Cmp(requiredStake) < 0 {
+			stakeTokenAlert("stake token balance below required stake", "balance", balance, "requiredStake", requiredStake)
+		}
+		return queued, nil
+	}
+	requested := arbmath.BigMax(m.config.topUpAmount, arbmath.BigSub(target, balance))
+	now := time.Now()
+	amount := m.policy.allowed(now, requested)
+	if amount.Sign() == 0 {
+		stakeTokenAlert("stake token top up limit reached for current window", "balance", balance, "target", target, "window", m.config.TopUpWindow)
+		return queued, nil
+	}
+	fundingAllowance, err := m.callUint(callOpts, "allowance", m.config.fundingAddress, wallet)
+	if err != nil {
+		return queued, fmt.Errorf("error getting stake token funding allowance: %w", err)
+	}
+	fundingBalance, err := m.callUint(callOpts, "balanceOf", m.config.fundingAddress)
+	if err != nil {
+		return queued, fmt.Errorf("error getting stake token funding balance: %w", err)
+	}
+	amount = arbmath.BigMin(amount, arbmath.BigMin(fundingAllowance, fundingBalance))
+	if amount.Sign() == 0 {
+		stakeTokenAlert("stake token funding address cannot cover top up", "funding", m.config.fundingAddress, "fundingBalance", fundingBalance, "fundingAllowance", fundingAllowance)
+		return queued, nil
+	}
+	auth, err := m.builder.Auth(ctx)
+	if err != nil {
+		return queued, err
+	}
+	if _, err := m.token.Transact(auth, "transferFrom", m.config.fundingAddress, wallet, amount); err != nil {
+		return queued, fmt.Errorf("error topping up stake token balance: %w", err)
+	}
+	m.policy.record(now, amount)
+	log.Info("topping up stake token balance", "token", m.address, "from", m.config.fundingAddress, "amount", amount, "balance", balance)
+	stakeTokenTopUpCounter.Inc(1)
+	return true, nil
+}
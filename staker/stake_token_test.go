@@ -0,0 +1,54 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestStakeTokenTopUpPolicy(t *testing.T) {
+	policy := &topUpPolicy{
+		max:    big.NewInt(100),
+		window: time.Hour,
+	}
+	start := time.Unix(1_000_000, 0)
+	if allowed := policy.allowed(start, big.NewInt(60)); allowed.Int64() != 60 {
+		Fail(t, "expected full top up to be allowed, got", allowed)
+	}
+	policy.record(start, big.NewInt(60))
+	if allowed := policy.allowed(start.Add(time.Minute), big.NewInt(60)); allowed.Int64() != 40 {
+		Fail(t, "expected top up to be capped at 40, got", allowed)
+	}
+	policy.record(start.Add(time.Minute), big.NewInt(40))
+	if allowed := policy.allowed(start.Add(2*time.Minute), big.NewInt(1)); allowed.Sign() != 0 {
+		Fail(t, "expected no top up to be allowed, got", allowed)
+	}
+	if allowed := policy.allowed(start.Add(time.Hour), big.NewInt(100)); allowed.Int64() != 60 {
+		Fail(t, "expected first top up to have left the window, got", allowed)
+	}
+	if allowed := policy.allowed(start.Add(2*time.Hour), big.NewInt(100)); allowed.Int64() != 100 {
+		Fail(t, "expected window to be empty, got", allowed)
+	}
+}
+
+func TestStakeTokenConfigValidate(t *testing.T) {
+	config := DefaultStakeTokenConfig
+	config.Enable = true
+	config.MinBalance = "1000"
+	if err := config.Validate(); err == nil {
+		Fail(t, "expected min balance without funding address to be rejected")
+	}
+	config.FundingAddress = "0x0000000000000000000000000000000000001234"
+	config.TopUpAmount = "500"
+	Require(t, config.Validate())
+	if config.approvalAmount.BitLen() != 256 {
+		Fail(t, "expected default approval amount to be max uint256")
+	}
+	config.TopUpAmount = "-1"
+	if err := config.Validate(); err == nil {
+		Fail(t, "expected negative top up amount to be rejected")
+	}
+}
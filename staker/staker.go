@@ -94,6 +94,7 @@ type L1ValidatorConfig struct {
 	EnableFastConfirmation    bool                        `koanf:"enable-fast-confirmation"`
 	FastConfirmSafeAddress    string                      `koanf:"fast-confirm-safe-address"`
 	LogQueryBatchSize         uint64                      `koanf:"log-query-batch-size" reload:"hot"`
+	StakeToken                StakeTokenConfig            `koanf:"stake-token"`
 
 	strategy    StakerStrategy
 	gasRefunder common.Address
@@ -139,7 +140,7 @@ func (c *L1ValidatorConfig) Validate() error {
 		return errors.New("invalid validator gas refunder address")
 	}
 	c.gasRefunder = common.HexToAddress(c.GasRefunderAddress)
-	return nil
+	return c.StakeToken.Validate()
 }
 
 var DefaultL1ValidatorConfig = L1ValidatorConfig{
@@ -163,6 +164,7 @@ var DefaultL1ValidatorConfig = L1ValidatorConfig{
 	EnableFastConfirmation:    false,
 	FastConfirmSafeAddress:    "",
 	LogQueryBatchSize:         0,
+	StakeToken:                DefaultStakeTokenConfig,
 }
 
 var TestL1ValidatorConfig = L1ValidatorConfig{
@@ -186,6 +188,7 @@ var TestL1ValidatorConfig = L1ValidatorConfig{
 	EnableFastConfirmation:    false,
 	FastConfirmSafeAddress:    "",
 	LogQueryBatchSize:         0,
+	StakeToken:                DefaultStakeTokenConfig,
 }
 
 var DefaultValidatorL1WalletConfig = genericconf.WalletConfig{
@@ -194,6 +197,7 @@ var DefaultValidatorL1WalletConfig = genericconf.WalletConfig{
 	PrivateKey:    genericconf.WalletConfigDefault.PrivateKey,
 	Account:       genericconf.WalletConfigDefault.Account,
 	OnlyCreateKey: genericconf.WalletConfigDefault.OnlyCreateKey,
+	KMS:           genericconf.WalletConfigDefault.KMS,
 }
 
 func L1ValidatorConfigAddOptions(prefix string, f *flag.FlagSet) {
@@ -217,6 +221,7 @@ func L1ValidatorConfigAddOptions(prefix string, f *flag.FlagSet) {
 	genericconf.WalletConfigAddOptions(prefix+".parent-chain-wallet", f, DefaultL1ValidatorConfig.ParentChainWallet.Pathname)
 	f.Bool(prefix+".enable-fast-confirmation", DefaultL1ValidatorConfig.EnableFastConfirmation, "enable fast confirmation")
 	f.String(prefix+".fast-confirm-safe-address", DefaultL1ValidatorConfig.FastConfirmSafeAddress, "safe address for fast confirmation")
+	StakeTokenConfigAddOptions(prefix+".stake-token", f)
 }
 
 type DangerousConfig struct {
@@ -270,6 +275,7 @@ type Staker struct {
 	statelessBlockValidator *StatelessBlockValidator
 	fatalErr                chan<- error
 	fastConfirmSafe         *FastConfirmSafe
+	stakeToken              *StakeTokenManager
 }
 
 type ValidatorWalletInterface interface {
@@ -333,6 +339,13 @@ func NewStaker(
 			return nil, err
 		}
 	}
+	var stakeToken *StakeTokenManager
+	if config.StakeToken.Enable {
+		stakeToken, err = NewStakeTokenManager(&config.StakeToken, val.rollup, val.builder, wallet.CanBatchTxs())
+		if err != nil {
+			return nil, err
+		}
+	}
 	inactiveValidatedNodes := btree.NewG(2, func(a, b validatedNode) bool {
 		return a.number < b.number || (a.number == b.number && a.hash.Cmp(b.hash) < 0)
 	})
@@ -350,6 +363,7 @@ func NewStaker(
 		fatalErr:                fatalErr,
 		fastConfirmSafe:         fastConfirmSafe,
 		inactiveValidatedNodes:  inactiveValidatedNodes,
+		stakeToken:              stakeToken,
 	}, nil
 }
 
@@ -362,6 +376,11 @@ func (s *Staker) Initialize(ctx context.Context) error {
 	if walletAddressOrZero != (common.Address{}) {
 		s.updateStakerBalanceMetric(ctx)
 	}
+	if s.stakeToken != nil {
+		if err := s.stakeToken.Initialize(ctx); err != nil {
+			return err
+		}
+	}
 	if s.blockValidator != nil && s.config.StartValidationFromStaked {
 		latestStaked, _, err := s.validatorUtils.LatestStaked(&s.baseCallOpts, s.rollupAddress, walletAddressOrZero)
 		if err != nil {
@@ -875,6 +894,22 @@ func (s *Staker) Act(ctx context.Context) (*types.Transaction, error) {
 		}
 	}
 
+	if s.stakeToken != nil && rawInfo == nil && effectiveStrategy >= StakeLatestStrategy &&
+		walletAddressOrZero != (common.Address{}) && canActFurther() {
+		requiredStake, err := s.rollup.CurrentRequiredStake(callOpts)
+		if err != nil {
+			return nil, fmt.Errorf("error getting current required stake: %w", err)
+		}
+		queued, err := s.stakeToken.Update(ctx, walletAddressOrZero, requiredStake)
+		if err != nil {
+			return nil, fmt.Errorf("error managing stake token: %w", err)
+		}
+		if queued && !s.wallet.CanBatchTxs() {
+			// The stake can't be placed until the approval or top-up lands
+			return s.wallet.ExecuteTransactions(ctx, s.builder, s.config.gasRefunder)
+		}
+	}
+
 	// Don't attempt to create a new stake if we're resolving a node and the stake is elevated,
 	// as that might affect the current required stake.
 	if (rawInfo != nil || !resolvingNode || !requiredStakeElevated) && canActFurther() {
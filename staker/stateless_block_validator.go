@@ -21,6 +21,7 @@ import (
 	"github.com/offchainlabs/nitro/arbos/arbostypes"
 	"github.com/offchainlabs/nitro/arbutil"
 	"github.com/offchainlabs/nitro/execution"
+	"github.com/offchainlabs/nitro/util/containers"
 	"github.com/offchainlabs/nitro/util/rpcclient"
 	"github.com/offchainlabs/nitro/validator"
 	"github.com/offchainlabs/nitro/validator/client/redis"
@@ -270,6 +271,18 @@ func (v *StatelessBlockValidator) ValidationEntryRecord(ctx context.Context, e *
 		return fmt.Errorf("validation entry should be ReadyForRecord, is: %v", e.Stage)
 	}
 	e.Preimages = make(map[arbutil.PreimageType]map[common.Hash][]byte)
+	// read the delayed message while the block is being recorded
+	delayedMsgPromise := containers.NewPromise[[]byte](nil)
+	if e.HasDelayedMsg {
+		go func() {
+			delayedMsg, err := v.inboxTracker.GetDelayedMessageBytes(ctx, e.DelayedMsgNr)
+			if err != nil {
+				delayedMsgPromise.ProduceError(err)
+				return
+			}
+			delayedMsgPromise.Produce(delayedMsg)
+		}()
+	}
 	if e.Pos != 0 {
 		recording, err := v.recorder.RecordBlockCreation(ctx, e.Pos, e.msg)
 		if err != nil {
@@ -306,7 +319,7 @@ func (v *StatelessBlockValidator) ValidationEntryRecord(ctx context.Context, e *
 		e.UserWasms = recording.UserWasms
 	}
 	if e.HasDelayedMsg {
-		delayedMsg, err := v.inboxTracker.GetDelayedMessageBytes(ctx, e.DelayedMsgNr)
+		delayedMsg, err := delayedMsgPromise.Await(ctx)
 		if err != nil {
 			log.Error(
 				"error while trying to read delayed msg for proving",
@@ -0,0 +1,261 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/go-redis/redis/v8"
+	"github.com/spf13/pflag"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/util/redisutil"
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+var (
+	validatorPoolMembersGauge        = metrics.NewRegisteredGauge("arb/validator/pool/members", nil)
+	validatorPoolAcceptedCounter     = metrics.NewRegisteredCounter("arb/validator/pool/accepted", nil)
+	validatorPoolPublishedCounter    = metrics.NewRegisteredCounter("arb/validator/pool/published", nil)
+	validatorPoolTakeoverCounter     = metrics.NewRegisteredCounter("arb/validator/pool/takeover", nil)
+	validatorPoolMismatchCounter     = metrics.NewRegisteredCounter("arb/validator/pool/mismatch", nil)
+	validatorPoolRedisFailureCounter = metrics.NewRegisteredCounter("arb/validator/pool/redis_failure", nil)
+)
+
+type ValidatorPoolConfig struct {
+	Enable            bool          `koanf:"enable"`
+	RedisUrl          string        `koanf:"redis-url"`
+	Name              string        `koanf:"name"`
+	MyId              string        `koanf:"my-id"`
+	RangeSize         uint64        `koanf:"range-size" reload:"hot"`
+	Overlap           uint64        `koanf:"overlap" reload:"hot"`
+	HeartbeatInterval time.Duration `koanf:"heartbeat-interval" reload:"hot"`
+	MemberTimeout     time.Duration `koanf:"member-timeout" reload:"hot"`
+	TakeoverTimeout   time.Duration `koanf:"takeover-timeout" reload:"hot"`
+	ResultRetention   time.Duration `koanf:"result-retention" reload:"hot"`
+}
+
+var DefaultValidatorPoolConfig = ValidatorPoolConfig{
+	Enable:            false,
+	RedisUrl:          "",
+	Name:              "default",
+	MyId:              "",
+	RangeSize:         64,
+	Overlap:           1,
+	HeartbeatInterval: 5 * time.Second,
+	MemberTimeout:     30 * time.Second,
+	TakeoverTimeout:   10 * time.Minute,
+	ResultRetention:   24 * time.Hour,
+}
+
+var TestValidatorPoolConfig = ValidatorPoolConfig{
+	Enable:            false,
+	RedisUrl:          "",
+	Name:              "test",
+	MyId:              "",
+	RangeSize:         4,
+	Overlap:           1,
+	HeartbeatInterval: 50 * time.Millisecond,
+	MemberTimeout:     time.Second,
+	TakeoverTimeout:   5 * time.Second,
+	ResultRetention:   time.Hour,
+}
+
+func ValidatorPoolConfigAddOptions(prefix string, f *pflag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultValidatorPoolConfig.Enable, "split block validation ranges among a pool of validators coordinating through redis")
+	f.String(prefix+".redis-url", DefaultValidatorPoolConfig.RedisUrl, "redis url used to coordinate the validator pool")
+	f.String(prefix+".name", DefaultValidatorPoolConfig.Name, "name of the validator pool (all members must use the same name)")
+	f.String(prefix+".my-id", DefaultValidatorPoolConfig.MyId, "this validator's id prefix within the pool (optional)")
+	f.Uint64(prefix+".range-size", DefaultValidatorPoolConfig.RangeSize, "number of messages in each range assigned to pool members")
+	f.Uint64(prefix+".overlap", DefaultValidatorPoolConfig.Overlap, "number of pool members that validate each range")
+	f.Duration(prefix+".heartbeat-interval", DefaultValidatorPoolConfig.HeartbeatInterval, "how often to refresh pool membership")
+	f.Duration(prefix+".member-timeout", DefaultValidatorPoolConfig.MemberTimeout, "how long after its last heartbeat a member is dropped and its ranges reassigned")
+	f.Duration(prefix+".takeover-timeout", DefaultValidatorPoolConfig.TakeoverTimeout, "how long to wait for another member's result before validating an unassigned message locally")
+	f.Duration(prefix+".result-retention", DefaultValidatorPoolConfig.ResultRetention, "how long published validation results are kept in redis")
+}
+
+func (c *ValidatorPoolConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.RedisUrl == "" {
+		return errors.New("validator pool requires a redis url")
+	}
+	if c.Name == "" || strings.Contains(c.Name, ":") {
+		return fmt.Errorf("invalid validator pool name \"%v\"", c.Name)
+	}
+	if c.RangeSize == 0 {
+		return errors.New("validator pool range-size must be positive")
+	}
+	if c.Overlap == 0 {
+		return errors.New("validator pool overlap must be positive")
+	}
+	if c.MemberTimeout <= c.HeartbeatInterval {
+		return errors.New("validator pool member-timeout must be greater than heartbeat-interval")
+	}
+	return nil
+}
+
+type ValidatorPoolConfigFetcher func() *ValidatorPoolConfig
+
+// ValidatorPool divides block validation among a set of validators. Every
+// message index belongs to a range of RangeSize messages, and each range is
+// assigned to Overlap consecutive members of the sorted list of live members.
+// Members publish the end state of the messages they validated, and the other
+// members accept those results instead of validating the messages themselves.
+// If no result shows up within TakeoverTimeout, a member validates the
+// message locally, so coverage never depends on any single member.
+type ValidatorPool struct {
+	stopwaiter.StopWaiter
+	client redis.UniversalClient
+	config ValidatorPoolConfigFetcher
+	myId   string
+
+	membersMutex sync.RWMutex
+	members      []string
+}
+
+func NewValidatorPool(config ValidatorPoolConfigFetcher) (*ValidatorPool, error) {
+	client, err := redisutil.RedisClientFromURL(config().RedisUrl)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, errors.New("validator pool redis url not set")
+	}
+	randBig, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
+	if err != nil {
+		return nil, err
+	}
+	return &ValidatorPool{
+		client: client,
+		config: config,
+		myId:   config().MyId + "-" + strconv.FormatInt(randBig.Int64(), 16), // unique even if config is not
+	}, nil
+}
+
+func (p *ValidatorPool) memberPrefix() string {
+	return "validator-pool:" + p.config().Name + ":member:"
+}
+
+func (p *ValidatorPool) resultKey(pos arbutil.MessageIndex) string {
+	return "validator-pool:" + p.config().Name + ":result:" + strconv.FormatUint(uint64(pos), 10)
+}
+
+func (p *ValidatorPool) heartbeat(ctx context.Context) time.Duration {
+	config := p.config()
+	err := p.client.Set(ctx, p.memberPrefix()+p.myId, time.Now().UnixMilli(), config.MemberTimeout).Err()
+	if err != nil {
+		validatorPoolRedisFailureCounter.Inc(1)
+		log.Warn("validator pool heartbeat failed", "err", err)
+		return config.HeartbeatInterval
+	}
+	var members []string
+	iter := p.client.Scan(ctx, 0, p.memberPrefix()+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		members = append(members, strings.TrimPrefix(iter.Val(), p.memberPrefix()))
+	}
+	if err := iter.Err(); err != nil {
+		validatorPoolRedisFailureCounter.Inc(1)
+		log.Warn("validator pool failed listing members", "err", err)
+		return config.HeartbeatInterval
+	}
+	sort.Strings(members)
+	p.membersMutex.Lock()
+	changed := len(members) != len(p.members)
+	for i := 0; !changed && i < len(members); i++ {
+		changed = members[i] != p.members[i]
+	}
+	p.members = members
+	p.membersMutex.Unlock()
+	if changed {
+		log.Info("validator pool membership changed", "members", len(members), "myId", p.myId)
+	}
+	validatorPoolMembersGauge.Update(int64(len(members)))
+	return config.HeartbeatInterval
+}
+
+func (p *ValidatorPool) Start(ctxIn context.Context) {
+	p.StopWaiter.Start(ctxIn, p)
+	p.CallIteratively(p.heartbeat)
+}
+
+func (p *ValidatorPool) StopAndWait() {
+	p.StopWaiter.StopAndWait()
+	// Leave the pool so our ranges are reassigned without waiting for the timeout
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := p.client.Del(ctx, p.memberPrefix()+p.myId).Err(); err != nil {
+		log.Warn("validator pool failed to leave pool", "err", err)
+	}
+}
+
+func isAssigned(members []string, myId string, rangeSize, overlap uint64, pos arbutil.MessageIndex) bool {
+	myIndex := sort.SearchStrings(members, myId)
+	if myIndex >= len(members) || members[myIndex] != myId {
+		// Until we know we're a member, we can't rely on anyone else
+		return true
+	}
+	count := uint64(len(members))
+	if overlap >= count {
+		return true
+	}
+	first := (uint64(pos) / rangeSize) % count
+	offset := (uint64(myIndex) + count - first) % count
+	return offset < overlap
+}
+
+// IsAssigned returns true if this member is responsible for validating pos.
+func (p *ValidatorPool) IsAssigned(pos arbutil.MessageIndex) bool {
+	config := p.config()
+	p.membersMutex.RLock()
+	defer p.membersMutex.RUnlock()
+	return isAssigned(p.members, p.myId, config.RangeSize, config.Overlap, pos)
+}
+
+// PublishResult makes the validated end state of pos, and the module roots it was validated against,
+// available to the rest of the pool.
+func (p *ValidatorPool) PublishResult(ctx context.Context, pos arbutil.MessageIndex, result *GlobalStateValidatedInfo) error {
+	encoded, err := rlp.EncodeToBytes(result)
+	if err != nil {
+		return err
+	}
+	err = p.client.Set(ctx, p.resultKey(pos), encoded, p.config().ResultRetention).Err()
+	if err != nil {
+		validatorPoolRedisFailureCounter.Inc(1)
+		return err
+	}
+	validatorPoolPublishedCounter.Inc(1)
+	return nil
+}
+
+// GetResult returns the end state another member validated for pos, or nil if none was published yet.
+func (p *ValidatorPool) GetResult(ctx context.Context, pos arbutil.MessageIndex) (*GlobalStateValidatedInfo, error) {
+	encoded, err := p.client.Get(ctx, p.resultKey(pos)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		validatorPoolRedisFailureCounter.Inc(1)
+		return nil, err
+	}
+	var result GlobalStateValidatedInfo
+	if err := rlp.DecodeBytes(encoded, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package staker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/util/redisutil"
+	"github.com/offchainlabs/nitro/validator"
+)
+
+func TestValidatorPoolAssignmentCoverage(t *testing.T) {
+	members := []string{"a", "b", "c", "d", "e"}
+	for _, overlap := range []uint64{1, 2, 3, 5, 7} {
+		expected := overlap
+		if expected > uint64(len(members)) {
+			expected = uint64(len(members))
+		}
+		for pos := arbutil.MessageIndex(0); pos < 200; pos++ {
+			var assigned uint64
+			for _, member := range members {
+				if isAssigned(members, member, 8, overlap, pos) {
+					assigned++
+				}
+			}
+			if assigned != expected {
+				Fail(t, "pos", pos, "overlap", overlap, "assigned to", assigned, "members, expected", expected)
+			}
+		}
+	}
+}
+
+func TestValidatorPoolAssignmentRanges(t *testing.T) {
+	members := []string{"a", "b", "c"}
+	for pos := arbutil.MessageIndex(0); pos < 16; pos++ {
+		expectA := pos < 4 || (pos >= 12 && pos < 16)
+		if isAssigned(members, "a", 4, 1, pos) != expectA {
+			Fail(t, "unexpected assignment for pos", pos)
+		}
+	}
+}
+
+func TestValidatorPoolUnknownMemberValidatesEverything(t *testing.T) {
+	members := []string{"a", "b", "c"}
+	for pos := arbutil.MessageIndex(0); pos < 16; pos++ {
+		if !isAssigned(members, "z", 4, 1, pos) {
+			Fail(t, "non-member should validate pos", pos)
+		}
+		if !isAssigned(nil, "a", 4, 1, pos) {
+			Fail(t, "member of empty pool should validate pos", pos)
+		}
+	}
+}
+
+func TestValidatorPoolResultKeepsModuleRoots(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	config := TestValidatorPoolConfig
+	config.RedisUrl = redisutil.CreateTestRedis(ctx, t)
+	pool, err := NewValidatorPool(func() *ValidatorPoolConfig { return &config })
+	Require(t, err)
+
+	result := &GlobalStateValidatedInfo{
+		GlobalState: validator.GoGlobalState{BlockHash: common.Hash{1}, Batch: 2, PosInBatch: 3},
+		WasmRoots:   []common.Hash{{4}, {5}},
+	}
+	Require(t, pool.PublishResult(ctx, 7, result))
+	read, err := pool.GetResult(ctx, 7)
+	Require(t, err)
+	if read == nil || read.GlobalState != result.GlobalState || len(read.WasmRoots) != 2 || read.WasmRoots[0] != result.WasmRoots[0] || read.WasmRoots[1] != result.WasmRoots[1] {
+		Fail(t, "unexpected pool result", read)
+	}
+	read, err = pool.GetResult(ctx, 8)
+	Require(t, err)
+	if read != nil {
+		Fail(t, "unexpected result for a message not validated", read)
+	}
+}
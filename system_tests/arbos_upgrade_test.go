@@ -0,0 +1,233 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbtest
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/offchainlabs/nitro/arbos/arbosState"
+	"github.com/offchainlabs/nitro/arbos/l2pricing"
+	"github.com/offchainlabs/nitro/solgen/go/mocksgen"
+	"github.com/offchainlabs/nitro/solgen/go/precompilesgen"
+	"github.com/offchainlabs/nitro/util/arbmath"
+)
+
+// The ArbOS versions chains may still be running, each of which must upgrade to the latest version cleanly.
+
+func TestArbOSUpgradeFrom11(t *testing.T) {
+	testArbOSUpgrade(t, 11)
+}
+
+func TestArbOSUpgradeFrom20(t *testing.T) {
+	testArbOSUpgrade(t, 20)
+}
+
+func TestArbOSUpgradeFromStylus(t *testing.T) {
+	testArbOSUpgrade(t, params.ArbosVersion_Stylus)
+}
+
+func TestArbOSUpgradeFromStylusFixes(t *testing.T) {
+	testArbOSUpgrade(t, params.ArbosVersion_StylusFixes)
+}
+
+// testArbOSUpgrade boots a chain at the given version, sends traffic across an owner upgrade to the
+// latest version, and checks the state, pending retryables, and pricing carry over, and that a
+// second node following the chain computes the same blocks.
+func testArbOSUpgrade(t *testing.T, fromVersion uint64) {
+	t.Parallel()
+	builder, delayedInbox, lookupL2Tx, ctx, teardown := retryableSetup(t, func(builder *NodeBuilder) {
+		builder.WithArbOSVersion(fromVersion)
+	})
+	defer teardown()
+
+	statedb, err := builder.L2.ExecNode.Backend.ArbInterface().BlockChain().State()
+	Require(t, err)
+	arbState, err := arbosState.OpenSystemArbosState(statedb, nil, true)
+	Require(t, err)
+	toVersion := arbState.MaxArbosVersionSupported()
+	if toVersion <= fromVersion {
+		Fatal(t, "nothing to upgrade to from version", fromVersion)
+	}
+
+	callOpts := &bind.CallOpts{Context: ctx}
+	ownerTxOpts := builder.L2Info.GetDefaultTransactOpts("Owner", ctx)
+	arbSys, err := precompilesgen.NewArbSys(types.ArbSysAddress, builder.L2.Client)
+	Require(t, err)
+	arbOwner, err := precompilesgen.NewArbOwner(types.ArbOwnerAddress, builder.L2.Client)
+	Require(t, err)
+	arbGasInfo, err := precompilesgen.NewArbGasInfo(types.ArbGasInfoAddress, builder.L2.Client)
+	Require(t, err)
+	arbRetryableTx, err := precompilesgen.NewArbRetryableTx(types.ArbRetryableTxAddress, builder.L2.Client)
+	Require(t, err)
+
+	checkVersion := func(expected uint64) {
+		t.Helper()
+		version, err := arbSys.ArbOSVersion(callOpts)
+		Require(t, err)
+		// ArbSys reports versions offset by 55, as Nitro started at version 56
+		if version.Uint64() != 55+expected {
+			Fatal(t, "expected ArbOS version", expected, "got", version.Uint64()-55)
+		}
+	}
+	checkVersion(fromVersion)
+
+	// the pricing parameters the upgrade must leave untouched
+	pricing := func() map[string]string {
+		t.Helper()
+		values := make(map[string]string)
+		record := func(name string, value interface{}, err error) {
+			t.Helper()
+			Require(t, err, "failed to read", name)
+			values[name] = fmt.Sprint(value)
+		}
+		minimumGasPrice, err := arbGasInfo.GetMinimumGasPrice(callOpts)
+		record("minimum gas price", minimumGasPrice, err)
+		speedLimit, gasPoolMax, maxTxGasLimit, err := arbGasInfo.GetGasAccountingParams(callOpts)
+		record("gas accounting params", []*big.Int{speedLimit, gasPoolMax, maxTxGasLimit}, err)
+		inertia, err := arbGasInfo.GetPricingInertia(callOpts)
+		record("pricing inertia", inertia, err)
+		tolerance, err := arbGasInfo.GetGasBacklogTolerance(callOpts)
+		record("backlog tolerance", tolerance, err)
+		perBatchGasCharge, err := arbGasInfo.GetPerBatchGasCharge(callOpts)
+		record("per batch gas charge", perBatchGasCharge, err)
+		amortizedCostCap, err := arbGasInfo.GetAmortizedCostCapBips(callOpts)
+		record("amortized cost cap", amortizedCostCap, err)
+		rewardRate, err := arbGasInfo.GetL1RewardRate(callOpts)
+		record("L1 reward rate", rewardRate, err)
+		rewardRecipient, err := arbGasInfo.GetL1RewardRecipient(callOpts)
+		record("L1 reward recipient", rewardRecipient, err)
+		return values
+	}
+
+	// representative traffic before the upgrade
+	builder.L2Info.GenerateAccount("User3")
+	builder.L2.TransferBalance(t, "Owner", "User3", big.NewInt(1e12), builder.L2Info)
+	simpleAddr, simple := builder.L2.DeploySimple(t, ownerTxOpts)
+	increment := func() {
+		t.Helper()
+		tx, err := simple.Increment(&ownerTxOpts)
+		Require(t, err)
+		_, err = builder.L2.EnsureTxSucceeded(tx)
+		Require(t, err)
+	}
+	increment()
+
+	// a retryable whose auto-redeem fails, left pending across the upgrade
+	simpleABI, err := mocksgen.SimpleMetaData.GetAbi()
+	Require(t, err)
+	usertxopts := builder.L1Info.GetDefaultTransactOpts("Faucet", ctx)
+	usertxopts.Value = arbmath.BigMul(big.NewInt(1e12), big.NewInt(1e12))
+	beneficiaryAddress := builder.L2Info.GetAddress("Beneficiary")
+	l1tx, err := delayedInbox.CreateRetryableTicket(
+		&usertxopts,
+		simpleAddr,
+		common.Big0,
+		big.NewInt(1e16),
+		beneficiaryAddress,
+		beneficiaryAddress,
+		// send enough L2 gas for intrinsic but not compute
+		big.NewInt(int64(params.TxGas+params.TxDataNonZeroGasEIP2028*4)),
+		big.NewInt(l2pricing.InitialBaseFeeWei*2),
+		simpleABI.Methods["incrementRedeem"].ID,
+	)
+	Require(t, err)
+	l1Receipt, err := builder.L1.EnsureTxSucceeded(l1tx)
+	Require(t, err)
+	waitForL1DelayBlocks(t, builder)
+	receipt, err := builder.L2.EnsureTxSucceeded(lookupL2Tx(l1Receipt))
+	Require(t, err)
+	if len(receipt.Logs) != 2 {
+		Fatal(t, "unexpected retryable submission log count", len(receipt.Logs))
+	}
+	ticketId := receipt.Logs[0].Topics[1]
+	receipt, err = WaitForTx(ctx, builder.L2.Client, receipt.Logs[1].Topics[2], time.Second*5)
+	Require(t, err)
+	if receipt.Status != types.ReceiptStatusFailed {
+		Fatal(t, "auto-redeem unexpectedly succeeded")
+	}
+	ticketTimeout, err := arbRetryableTx.GetTimeout(callOpts, ticketId)
+	Require(t, err)
+
+	pricingBefore := pricing()
+	baseFeeBefore := builder.L2.GetBaseFee(t)
+	user3Balance := builder.L2.GetBalance(t, builder.L2Info.GetAddress("User3"))
+
+	// the upgrade happens with the next block once scheduled for the past
+	tx, err := arbOwner.ScheduleArbOSUpgrade(&ownerTxOpts, toVersion, 0)
+	Require(t, err)
+	receipt, err = builder.L2.EnsureTxSucceeded(tx)
+	Require(t, err)
+	upgradeBlock := receipt.BlockNumber.Uint64()
+	builder.L2.TransferBalance(t, "Owner", "Owner", common.Big1, builder.L2Info)
+	checkVersion(toVersion)
+
+	// the state from before the upgrade is intact
+	counter, err := simple.Counter(callOpts)
+	Require(t, err)
+	if counter != 1 {
+		Fatal(t, "unexpected counter after upgrade", counter)
+	}
+	if balance := builder.L2.GetBalance(t, builder.L2Info.GetAddress("User3")); !arbmath.BigEquals(balance, user3Balance) {
+		Fatal(t, "balance changed across upgrade from", user3Balance, "to", balance)
+	}
+
+	// pricing continues where it left off
+	pricingAfter := pricing()
+	for name, before := range pricingBefore {
+		if pricingAfter[name] != before {
+			Fatal(t, name, "changed across upgrade from", before, "to", pricingAfter[name])
+		}
+	}
+	if baseFee := builder.L2.GetBaseFee(t); baseFee.Cmp(baseFeeBefore) > 0 {
+		Fatal(t, "base fee jumped across upgrade from", baseFeeBefore, "to", baseFee)
+	}
+
+	// the pending retryable survives and can still be redeemed
+	timeout, err := arbRetryableTx.GetTimeout(callOpts, ticketId)
+	Require(t, err)
+	if !arbmath.BigEquals(timeout, ticketTimeout) {
+		Fatal(t, "retryable timeout changed across upgrade from", ticketTimeout, "to", timeout)
+	}
+	tx, err = arbRetryableTx.Redeem(&ownerTxOpts, ticketId)
+	Require(t, err)
+	receipt, err = builder.L2.EnsureTxSucceeded(tx)
+	Require(t, err)
+	receipt, err = WaitForTx(ctx, builder.L2.Client, receipt.Logs[0].Topics[2], time.Second*5)
+	Require(t, err)
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		Fatal(t, "retryable redeem failed after upgrade")
+	}
+
+	// traffic continues after the upgrade
+	increment()
+	counter, err = simple.Counter(callOpts)
+	Require(t, err)
+	if counter != 3 {
+		Fatal(t, "unexpected counter after redeem and increment", counter)
+	}
+	lastTx, _ := builder.L2.TransferBalance(t, "Owner", "User3", big.NewInt(1e12), builder.L2Info)
+
+	// a node following the chain computes the same blocks across the upgrade
+	testClientB, cleanupB := builder.Build2ndNode(t, &SecondNodeParams{})
+	defer cleanupB()
+	receipt, err = WaitForTx(ctx, testClientB.Client, lastTx.Hash(), time.Second*30)
+	Require(t, err)
+	for number := upgradeBlock - 1; number <= receipt.BlockNumber.Uint64(); number++ {
+		expected, err := builder.L2.Client.HeaderByNumber(ctx, arbmath.UintToBig(number))
+		Require(t, err)
+		actual, err := testClientB.Client.HeaderByNumber(ctx, arbmath.UintToBig(number))
+		Require(t, err)
+		if expected.Hash() != actual.Hash() {
+			Fatal(t, "block", number, "differs between nodes:", expected.Hash(), actual.Hash())
+		}
+	}
+}
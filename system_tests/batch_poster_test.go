@@ -24,14 +24,25 @@ import (
 	"github.com/offchainlabs/nitro/solgen/go/bridgegen"
 	"github.com/offchainlabs/nitro/solgen/go/upgrade_executorgen"
 	"github.com/offchainlabs/nitro/util/redisutil"
+	"github.com/offchainlabs/nitro/util/testhelpers"
 )
 
 func TestBatchPosterParallel(t *testing.T) {
-	testBatchPosterParallel(t, false)
+	testBatchPosterParallel(t, false, 1)
 }
 
 func TestRedisBatchPosterParallel(t *testing.T) {
-	testBatchPosterParallel(t, true)
+	testBatchPosterParallel(t, true, 1)
+}
+
+func TestBatchPosterPipelined(t *testing.T) {
+	testBatchPosterParallel(t, false, 4)
+}
+
+// TestRedisBatchPosterPipelined has several batch posters sharing a data poster queue, each building and
+// posting pipelines of batches whenever it holds the queue's lock.
+func TestRedisBatchPosterPipelined(t *testing.T) {
+	testBatchPosterParallel(t, true, 4)
 }
 
 func addNewBatchPoster(ctx context.Context, t *testing.T, builder *NodeBuilder, address common.Address) {
@@ -76,9 +87,10 @@ func externalSignerTestCfg(addr common.Address, url string) (*dataposter.Externa
 	}, nil
 }
 
-func testBatchPosterParallel(t *testing.T, useRedis bool) {
+func testBatchPosterParallel(t *testing.T, useRedis bool, maxPipelinedBatches int) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	logHandler := testhelpers.InitTestLog(t, log.LvlTrace)
 	srv := externalsignertest.NewServer(t)
 	go func() {
 		if err := srv.Start(); err != nil {
@@ -139,6 +151,7 @@ func testBatchPosterParallel(t *testing.T, useRedis bool) {
 	seqTxOpts := builder.L1Info.GetDefaultTransactOpts("Sequencer", ctx)
 	builder.nodeConfig.BatchPoster.Enable = true
 	builder.nodeConfig.BatchPoster.MaxSize = len(firstTxData) * 2
+	builder.nodeConfig.BatchPoster.MaxPipelinedBatches = maxPipelinedBatches
 	startL1Block, err := builder.L1.Client.BlockNumber(ctx)
 	Require(t, err)
 	parentChainID, err := builder.L1.Client.ChainID(ctx)
@@ -217,6 +230,41 @@ func testBatchPosterParallel(t *testing.T, useRedis bool) {
 	if l2balance.Sign() == 0 {
 		Fatal(t, "Unexpected zero balance")
 	}
+
+	if maxPipelinedBatches > 1 {
+		// the backlog of small batches was worked through with several batches in flight at once
+		if !logHandler.WasLogged(arbnode.PostedPipelinedBatchesLogMsg) {
+			Fatal(t, "no batches were pipelined")
+		}
+		// batch posters sharing a queue may legitimately abandon a pipeline when another takes over the queue
+		if !useRedis && logHandler.WasLogged(arbnode.AbandonedPipelinedBatchesLogMsg) {
+			Fatal(t, "pipelined batches were abandoned")
+		}
+		// and still landed in order, without any gaps or duplicates
+		endL1Block, err := builder.L1.Client.BlockNumber(ctx)
+		Require(t, err)
+		seqInbox, err := arbnode.NewSequencerInbox(builder.L1.Client, builder.L2.ConsensusNode.DeployInfo.SequencerInbox, 0)
+		Require(t, err)
+		batches, err := seqInbox.LookupBatchesInRange(ctx, new(big.Int).SetUint64(startL1Block), new(big.Int).SetUint64(endL1Block))
+		Require(t, err)
+		if len(batches) < maxPipelinedBatches {
+			Fatal(t, "expected a backlog of batches, got", len(batches))
+		}
+		for i := 1; i < len(batches); i++ {
+			if batches[i].SequenceNumber != batches[i-1].SequenceNumber+1 {
+				Fatal(t, "batch", batches[i].SequenceNumber, "was posted after batch", batches[i-1].SequenceNumber)
+			}
+		}
+		for _, tx := range txs {
+			receiptA, err := builder.L2.Client.TransactionReceipt(ctx, tx.Hash())
+			Require(t, err)
+			receiptB, err := testClientB.Client.TransactionReceipt(ctx, tx.Hash())
+			Require(t, err)
+			if receiptA.BlockHash != receiptB.BlockHash {
+				Fatal(t, "transaction", tx.Hash(), "is in block", receiptB.BlockHash, "from the batches, but", receiptA.BlockHash, "from the sequencer")
+			}
+		}
+	}
 }
 
 func TestBatchPosterLargeTx(t *testing.T) {
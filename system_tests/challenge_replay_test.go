@@ -0,0 +1,63 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+//go:build challengetest
+// +build challengetest
+
+package arbtest
+
+import (
+	"os"
+	"testing"
+)
+
+// TestChallengeReplayRecordedDivergences replays every recorded divergence under
+// testdata/challenge_replays (or $CHALLENGE_REPLAY_DIR) with real machines, and checks the honest party wins.
+func TestChallengeReplayRecordedDivergences(t *testing.T) {
+	dir := os.Getenv(challengeReplayDirEnv)
+	if dir == "" {
+		dir = "testdata/challenge_replays"
+	}
+	scenarios := loadChallengeScenarios(t, dir)
+	if len(scenarios) == 0 {
+		Fatal(t, "no recorded challenge scenarios in", dir)
+	}
+	defaultWasmRootDir := ""
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.Name, func(t *testing.T) {
+			runChallengeScenario(t, scenario, false, defaultWasmRootDir).checkWinner(t)
+		})
+	}
+}
+
+// TestChallengeReplaySynthesizedDivergences corrupts each message position in turn, for both an honest and a
+// dishonest asserter, and checks each game, played against stubs, bisects down to the corrupted message.
+func TestChallengeReplaySynthesizedDivergences(t *testing.T) {
+	var scores []*challengeScore
+	for _, asserterIsCorrect := range []bool{true, false} {
+		for msgIdx := int64(1); msgIdx <= makeBatch_MsgsPerBatch*3; msgIdx++ {
+			scenario := &challengeScenario{
+				AsserterIsCorrect: asserterIsCorrect,
+				DivergedMsgIdx:    msgIdx,
+			}
+			scores = append(scores, runChallengeScenario(t, scenario, true, ""))
+		}
+	}
+	for _, score := range scores {
+		score.check(t)
+	}
+}
+
+// TestChallengeReplaySynthesizedDivergencesFull plays synthesized divergences out with real machines, for both
+// an honest and a dishonest asserter, and checks the honest party wins.
+func TestChallengeReplaySynthesizedDivergencesFull(t *testing.T) {
+	defaultWasmRootDir := ""
+	for _, asserterIsCorrect := range []bool{true, false} {
+		scenario := &challengeScenario{
+			AsserterIsCorrect: asserterIsCorrect,
+			DivergedMsgIdx:    makeBatch_MsgsPerBatch + 2,
+		}
+		runChallengeScenario(t, scenario, false, defaultWasmRootDir).checkWinner(t)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// challengeScenario describes an assertion divergence between an asserter and a challenger.
+// Batches hold the raw signed L2 transactions each party's inbox contains. Scenarios can be
+// synthesized by corrupting a single message, or replayed from a recorded JSON fixture.
+type challengeScenario struct {
+	Name              string            `json:"name"`
+	AsserterIsCorrect bool              `json:"asserterIsCorrect"`
+	DivergedMsgIdx    int64             `json:"divergedMsgIdx"`
+	AsserterBatches   [][]hexutil.Bytes `json:"asserterBatches"`
+	ChallengerBatches [][]hexutil.Bytes `json:"challengerBatches"`
+}
+
+// challengeReplayRecordDirEnv, when set, makes synthesized scenarios get written out as fixtures.
+const challengeReplayRecordDirEnv = "CHALLENGE_REPLAY_RECORD_DIR"
+
+// challengeReplayDirEnv overrides the directory recorded scenarios are replayed from.
+const challengeReplayDirEnv = "CHALLENGE_REPLAY_DIR"
+
+func (s *challengeScenario) synthesize(t *testing.T, asserterL2Info, challengerL2Info *BlockchainTestInfo) {
+	t.Helper()
+	if s.Name == "" {
+		s.Name = fmt.Sprintf("synthetic-msg%d-asserter-correct-%v", s.DivergedMsgIdx, s.AsserterIsCorrect)
+	}
+	s.AsserterBatches = nil
+	s.ChallengerBatches = nil
+	for batch := int64(0); batch < 3; batch++ {
+		s.AsserterBatches = append(s.AsserterBatches, makeBatchTxs(t, asserterL2Info, -1))
+		s.ChallengerBatches = append(s.ChallengerBatches, makeBatchTxs(t, challengerL2Info, s.DivergedMsgIdx-makeBatch_MsgsPerBatch*batch-1))
+	}
+}
+
+func (s *challengeScenario) maybeRecord(t *testing.T) {
+	t.Helper()
+	dir := os.Getenv(challengeReplayRecordDirEnv)
+	if dir == "" {
+		return
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	Require(t, err)
+	path := filepath.Join(dir, s.Name+".json")
+	// #nosec G306
+	Require(t, os.WriteFile(path, data, 0o644))
+	t.Log("recorded challenge scenario to", path)
+}
+
+func loadChallengeScenarios(t *testing.T, dir string) []*challengeScenario {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	Require(t, err)
+	var scenarios []*challengeScenario
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		Require(t, err)
+		var scenario challengeScenario
+		Require(t, json.Unmarshal(data, &scenario), "failed to parse challenge scenario", path)
+		if scenario.Name == "" {
+			scenario.Name = filepath.Base(path)
+		}
+		scenarios = append(scenarios, &scenario)
+	}
+	return scenarios
+}
+
+// challengeScore summarizes how a challenge game played out.
+type challengeScore struct {
+	Scenario       string
+	DivergedMsgIdx int64
+	ExpectedWinner common.Address
+	// the party that won, or that the other was unable to move against, unset if the game didn't finish
+	Winner common.Address
+	// the message the game bisected down to, if it ended spawning an execution run the stubs can't play
+	SpawnedMsgIdx int64
+	Reason        string
+	// indexed by party: 0 is the challenger, 1 is the asserter
+	Moves    [2]int
+	GasUsed  [2]uint64
+	Duration time.Duration
+}
+
+func (s *challengeScore) log(t *testing.T) {
+	t.Helper()
+	t.Logf(
+		"challenge scenario %q (diverged at msg %d) won by %v after %v: challenger moves %d gas %d, asserter moves %d gas %d (%s)",
+		s.Scenario, s.DivergedMsgIdx, s.Winner, s.Duration, s.Moves[0], s.GasUsed[0], s.Moves[1], s.GasUsed[1], s.Reason,
+	)
+}
+
+// check fails the test unless the correct party won the game, or, for a game played against stubs, unless
+// it bisected down to the message the parties diverged at.
+func (s *challengeScore) check(t *testing.T) {
+	t.Helper()
+	if s.SpawnedMsgIdx != 0 {
+		if s.SpawnedMsgIdx != s.DivergedMsgIdx {
+			Fatal(t, "scenario", s.Scenario, "spawned an execution run at msg", s.SpawnedMsgIdx, "expected", s.DivergedMsgIdx)
+		}
+		return
+	}
+	s.checkWinner(t)
+}
+
+// checkWinner fails the test unless the game was played out to the end, with real machines, and the correct
+// party won it.
+func (s *challengeScore) checkWinner(t *testing.T) {
+	t.Helper()
+	if s.SpawnedMsgIdx != 0 {
+		Fatal(t, "scenario", s.Scenario, "ended spawning an execution run at msg", s.SpawnedMsgIdx, "instead of being won")
+	}
+	if s.Winner != s.ExpectedWinner {
+		Fatal(t, "scenario", s.Scenario, "won by", s.Winner, "expected", s.ExpectedWinner, "reason:", s.Reason)
+	}
+}
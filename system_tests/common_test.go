@@ -166,6 +166,7 @@ var TestCachingConfig = gethexec.CachingConfig{
 	MaxNumberOfBlocksToSkipStateSaving: 0,
 	MaxAmountOfGasToSkipStateSaving:    0,
 	StylusLRUCache:                     0,
+	MessageResultCache:                 128,
 	StateScheme:                        env.GetTestStateScheme(),
 }
 
@@ -15,6 +15,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
@@ -132,26 +133,44 @@ func writeTxToBatch(writer io.Writer, tx *types.Transaction) error {
 	if err != nil {
 		return err
 	}
+	return writeTxDataToBatch(writer, txData)
+}
+
+func writeTxDataToBatch(writer io.Writer, txData []byte) error {
 	var segment []byte
 	segment = append(segment, arbstate.BatchSegmentKindL2Message)
 	segment = append(segment, arbos.L2MessageKind_SignedTx)
 	segment = append(segment, txData...)
-	err = rlp.Encode(writer, segment)
-	return err
+	return rlp.Encode(writer, segment)
 }
 
 const makeBatch_MsgsPerBatch = int64(5)
 
 func makeBatch(t *testing.T, l2Node *arbnode.Node, l2Info *BlockchainTestInfo, backend *ethclient.Client, sequencer *bind.TransactOpts, seqInbox *mocksgen.SequencerInboxStub, seqInboxAddr common.Address, modStep int64) {
-	ctx := context.Background()
+	postBatch(t, l2Node, backend, sequencer, seqInbox, seqInboxAddr, makeBatchTxs(t, l2Info, modStep))
+}
 
-	batchBuffer := bytes.NewBuffer([]byte{})
+// makeBatchTxs creates the signed transactions for a batch, corrupting the value of the modStep'th one
+func makeBatchTxs(t *testing.T, l2Info *BlockchainTestInfo, modStep int64) []hexutil.Bytes {
+	var txs []hexutil.Bytes
 	for i := int64(0); i < makeBatch_MsgsPerBatch; i++ {
 		value := i
 		if i == modStep {
 			value++
 		}
-		err := writeTxToBatch(batchBuffer, l2Info.PrepareTx("Owner", "Destination", 1000000, big.NewInt(value), []byte{}))
+		txData, err := l2Info.PrepareTx("Owner", "Destination", 1000000, big.NewInt(value), []byte{}).MarshalBinary()
+		Require(t, err)
+		txs = append(txs, txData)
+	}
+	return txs
+}
+
+func postBatch(t *testing.T, l2Node *arbnode.Node, backend *ethclient.Client, sequencer *bind.TransactOpts, seqInbox *mocksgen.SequencerInboxStub, seqInboxAddr common.Address, txs []hexutil.Bytes) {
+	ctx := context.Background()
+
+	batchBuffer := bytes.NewBuffer([]byte{})
+	for _, txData := range txs {
+		err := writeTxDataToBatch(batchBuffer, txData)
 		Require(t, err)
 	}
 	compressed, err := arbcompress.CompressWell(batchBuffer.Bytes())
@@ -235,6 +254,17 @@ func setupSequencerInboxStub(ctx context.Context, t *testing.T, l1Info *Blockcha
 }
 
 func RunChallengeTest(t *testing.T, asserterIsCorrect bool, useStubs bool, challengeMsgIdx int64, wasmRootDir string) {
+	runChallengeScenario(t, &challengeScenario{
+		AsserterIsCorrect: asserterIsCorrect,
+		DivergedMsgIdx:    challengeMsgIdx,
+	}, useStubs, wasmRootDir).check(t)
+}
+
+// runChallengeScenario plays a full challenge game between an asserter and a challenger whose
+// inboxes diverge as described by the scenario, and scores how the game played out.
+func runChallengeScenario(t *testing.T, scenario *challengeScenario, useStubs bool, wasmRootDir string) *challengeScore {
+	asserterIsCorrect := scenario.AsserterIsCorrect
+	challengeMsgIdx := scenario.DivergedMsgIdx
 	glogger := log.NewGlogHandler(
 		log.NewTerminalHandler(io.Writer(os.Stderr), false))
 	glogger.Verbosity(log.LvlInfo)
@@ -306,21 +336,21 @@ func RunChallengeTest(t *testing.T, asserterIsCorrect bool, useStubs bool, chall
 	asserterL2Info.GenerateAccount("Destination")
 	challengerL2Info.SetFullAccountInfo("Destination", asserterL2Info.GetInfoWithPrivKey("Destination"))
 
+	if scenario.AsserterBatches == nil {
+		scenario.synthesize(t, asserterL2Info, challengerL2Info)
+	}
+	if len(scenario.AsserterBatches) != 3 || len(scenario.ChallengerBatches) != 3 {
+		Fatal(t, "challenge scenario must have 3 batches per party")
+	}
 	if challengeMsgIdx < 1 || challengeMsgIdx > 3*makeBatch_MsgsPerBatch {
 		Fatal(t, "challengeMsgIdx illegal")
 	}
+	scenario.maybeRecord(t)
 
-	// seqNum := common.Big2
-	makeBatch(t, asserterL2, asserterL2Info, l1Backend, &sequencerTxOpts, asserterSeqInbox, asserterSeqInboxAddr, -1)
-	makeBatch(t, challengerL2, challengerL2Info, l1Backend, &sequencerTxOpts, challengerSeqInbox, challengerSeqInboxAddr, challengeMsgIdx-1)
-
-	// seqNum.Add(seqNum, common.Big1)
-	makeBatch(t, asserterL2, asserterL2Info, l1Backend, &sequencerTxOpts, asserterSeqInbox, asserterSeqInboxAddr, -1)
-	makeBatch(t, challengerL2, challengerL2Info, l1Backend, &sequencerTxOpts, challengerSeqInbox, challengerSeqInboxAddr, challengeMsgIdx-makeBatch_MsgsPerBatch-1)
-
-	// seqNum.Add(seqNum, common.Big1)
-	makeBatch(t, asserterL2, asserterL2Info, l1Backend, &sequencerTxOpts, asserterSeqInbox, asserterSeqInboxAddr, -1)
-	makeBatch(t, challengerL2, challengerL2Info, l1Backend, &sequencerTxOpts, challengerSeqInbox, challengerSeqInboxAddr, challengeMsgIdx-makeBatch_MsgsPerBatch*2-1)
+	for i := range scenario.AsserterBatches {
+		postBatch(t, asserterL2, l1Backend, &sequencerTxOpts, asserterSeqInbox, asserterSeqInboxAddr, scenario.AsserterBatches[i])
+		postBatch(t, challengerL2, l1Backend, &sequencerTxOpts, challengerSeqInbox, challengerSeqInboxAddr, scenario.ChallengerBatches[i])
+	}
 
 	trueSeqInboxAddr := challengerSeqInboxAddr
 	trueDelayedBridge := challengerBridgeAddr
@@ -422,9 +452,22 @@ func RunChallengeTest(t *testing.T, asserterIsCorrect bool, useStubs bool, chall
 
 	confirmLatestBlock(ctx, t, l1Info, l1Backend)
 
+	score := &challengeScore{
+		Scenario:       scenario.Name,
+		DivergedMsgIdx: challengeMsgIdx,
+		ExpectedWinner: expectedWinner,
+	}
+	// indexed by party, like the score
+	parties := [2]common.Address{l1Info.GetAddress("challenger"), l1Info.GetAddress("asserter")}
+	start := time.Now()
+	finish := func(reason string) *challengeScore {
+		score.Duration = time.Since(start)
+		score.Reason = reason
+		score.log(t)
+		return score
+	}
 	for i := 0; i < 100; i++ {
 		var tx *types.Transaction
-		var currentCorrect bool
 		// Gas cost is slightly reduced if done in the same timestamp or block as previous call.
 		// This might make gas estimation undersestimate next move.
 		// Invoke a new L1 block, with a new timestamp, before estimating.
@@ -433,19 +476,20 @@ func RunChallengeTest(t *testing.T, asserterIsCorrect bool, useStubs bool, chall
 			l1Info.PrepareTx("Faucet", "User", 30000, big.NewInt(1e12), nil),
 		})
 
-		if i%2 == 0 {
-			currentCorrect = !asserterIsCorrect
+		party := i % 2
+		if party == 0 {
 			tx, err = challengerManager.Act(ctx)
 		} else {
-			currentCorrect = asserterIsCorrect
 			tx, err = asserterManager.Act(ctx)
 		}
 		if err != nil {
-			if !currentCorrect && (strings.Contains(err.Error(), "lost challenge") ||
+			if strings.Contains(err.Error(), "lost challenge") ||
 				strings.Contains(err.Error(), "SAME_OSP_END") ||
-				strings.Contains(err.Error(), "BAD_SEQINBOX_MESSAGE")) {
-				t.Log("challenge completed! asserter hit expected error:", err)
-				return
+				strings.Contains(err.Error(), "BAD_SEQINBOX_MESSAGE") {
+				t.Log("challenge completed! party", party, "unable to move:", err)
+				// the party unable to make a move loses
+				score.Winner = parties[1-party]
+				return finish(err.Error())
 			}
 			Fatal(t, "challenge step", i, "hit error:", err)
 		}
@@ -458,21 +502,23 @@ func RunChallengeTest(t *testing.T, asserterIsCorrect bool, useStubs bool, chall
 				if len(mockSpawn.ExecSpawned) != 1 {
 					Fatal(t, "bad number of spawned execRuns: ", len(mockSpawn.ExecSpawned))
 				}
-				if mockSpawn.ExecSpawned[0] != uint64(challengeMsgIdx) {
-					Fatal(t, "wrong spawned execRuns: ", mockSpawn.ExecSpawned[0], " expected: ", challengeMsgIdx)
-				}
-				return
+				// the game bisected down to a single message, which the stubs can't execute
+				score.SpawnedMsgIdx = int64(mockSpawn.ExecSpawned[0])
+				return finish("execution run spawned")
 			}
 		}
 
-		_, err = EnsureTxSucceeded(ctx, l1Backend, tx)
+		score.Moves[party]++
+		receipt, err := EnsureTxSucceeded(ctx, l1Backend, tx)
 		if err != nil {
-			if !currentCorrect && strings.Contains(err.Error(), "BAD_SEQINBOX_MESSAGE") {
-				t.Log("challenge complete! Tx failed as expected:", err)
-				return
+			if strings.Contains(err.Error(), "BAD_SEQINBOX_MESSAGE") {
+				t.Log("challenge complete! party", party, "move failed:", err)
+				score.Winner = parties[1-party]
+				return finish(err.Error())
 			}
 			Fatal(t, err)
 		}
+		score.GasUsed[party] += receipt.GasUsed
 
 		confirmLatestBlock(ctx, t, l1Info, l1Backend)
 
@@ -483,10 +529,10 @@ func RunChallengeTest(t *testing.T, asserterIsCorrect bool, useStubs bool, chall
 		if winner == (common.Address{}) {
 			continue
 		}
-		if winner != expectedWinner {
-			Fatal(t, "wrong party won challenge")
-		}
+		score.Winner = winner
+		return finish("winner declared on chain")
 	}
 
 	Fatal(t, "challenge timed out without winner")
+	return nil
 }
@@ -0,0 +1,50 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbtest
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/offchainlabs/nitro/execution/gethexec"
+)
+
+func TestSendRawTransactionSync(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	builder := NewNodeBuilder(ctx).DefaultConfig(t, false)
+	builder.execConfig.SendRawTransactionSync.Enable = true
+	cleanup := builder.Build(t)
+	defer cleanup()
+
+	builder.L2Info.GenerateAccount("User2")
+	tx := builder.L2Info.PrepareTx("Owner", "User2", builder.L2Info.TransferGas, big.NewInt(1e12), nil)
+	data, err := tx.MarshalBinary()
+	Require(t, err)
+
+	l2rpc := builder.L2.Stack.Attach()
+	var result gethexec.SyncTransactionResult
+	err = l2rpc.CallContext(ctx, &result, "eth_sendRawTransactionSync", hexutil.Bytes(data), nil)
+	Require(t, err)
+	if result.TransactionHash != tx.Hash() || result.Status != hexutil.Uint64(types.ReceiptStatusSuccessful) {
+		Fatal(t, "unexpected result", result)
+	}
+	// the block is already there once the call returns
+	receipt, err := builder.L2.Client.TransactionReceipt(ctx, tx.Hash())
+	Require(t, err)
+	if receipt.BlockNumber.Uint64() != uint64(result.BlockNumber) || receipt.BlockHash != result.BlockHash {
+		Fatal(t, "result block", result.BlockNumber, "differs from receipt block", receipt.BlockNumber)
+	}
+
+	// a transaction the sequencer rejects returns its error
+	err = l2rpc.CallContext(ctx, &result, "eth_sendRawTransactionSync", hexutil.Bytes(data), nil)
+	if err == nil || !strings.Contains(err.Error(), "nonce too low") {
+		Fatal(t, "expected the replayed transaction to be rejected, got", err)
+	}
+}
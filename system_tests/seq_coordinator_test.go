@@ -90,7 +90,7 @@ func TestRedisSeqCoordinatorPriorities(t *testing.T) {
 			},
 			DelayedMessagesRead: 1,
 		}
-		err = node.SeqCoordinator.SequencingMessage(curMsgs, &emptyMessage)
+		_, err = node.SeqCoordinator.SequencingMessage(curMsgs, &emptyMessage)
 		if errors.Is(err, execution.ErrRetrySequencer) {
 			return false
 		}
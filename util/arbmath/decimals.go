@@ -0,0 +1,70 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbmath
+
+import (
+	"math/big"
+)
+
+// EtherDecimals is how many decimals ether, and so wei-denominated amounts, have.
+const EtherDecimals = 18
+
+func decimalsFactor(decimals uint64) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), new(big.Int).SetUint64(decimals), nil)
+}
+
+// ScaleDecimals converts an amount of a token with fromDecimals decimals to one with toDecimals, rounding down.
+func ScaleDecimals(amount *big.Int, fromDecimals, toDecimals uint64) *big.Int {
+	if fromDecimals == toDecimals {
+		return new(big.Int).Set(amount)
+	}
+	if fromDecimals < toDecimals {
+		return new(big.Int).Mul(amount, decimalsFactor(toDecimals-fromDecimals))
+	}
+	return new(big.Int).Div(amount, decimalsFactor(fromDecimals-toDecimals))
+}
+
+// ScaleDecimalsUp converts an amount of a token with fromDecimals decimals to one with toDecimals, rounding up.
+func ScaleDecimalsUp(amount *big.Int, fromDecimals, toDecimals uint64) *big.Int {
+	if fromDecimals <= toDecimals {
+		return ScaleDecimals(amount, fromDecimals, toDecimals)
+	}
+	factor := decimalsFactor(fromDecimals - toDecimals)
+	quotient, remainder := new(big.Int).QuoRem(amount, factor, new(big.Int))
+	if remainder.Sign() > 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	return quotient
+}
+
+// FeeToken is the token a chain pays fees in, which balances, gas prices and fees are denominated in the
+// smallest unit of.
+type FeeToken struct {
+	Decimals uint64
+	// how many wei one whole token is worth
+	WeiPerToken *big.Int
+}
+
+// EtherFeeToken is ether itself, the fee token of chains that didn't set another.
+var EtherFeeToken = FeeToken{Decimals: EtherDecimals, WeiPerToken: decimalsFactor(EtherDecimals)}
+
+// IsEther returns whether amounts of the token are amounts of wei.
+func (t FeeToken) IsEther() bool {
+	return t.Decimals == EtherDecimals && t.WeiPerToken.Cmp(EtherFeeToken.WeiPerToken) == 0
+}
+
+// FromWei converts an amount of wei to the token's smallest unit, rounding up if roundUp is set.
+func (t FeeToken) FromWei(wei *big.Int, roundUp bool) *big.Int {
+	numerator := new(big.Int).Mul(wei, decimalsFactor(t.Decimals))
+	quotient, remainder := new(big.Int).QuoRem(numerator, t.WeiPerToken, new(big.Int))
+	if roundUp && remainder.Sign() > 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	return quotient
+}
+
+// ToWei converts an amount in the token's smallest unit to wei, rounding down.
+func (t FeeToken) ToWei(amount *big.Int) *big.Int {
+	return new(big.Int).Quo(new(big.Int).Mul(amount, t.WeiPerToken), decimalsFactor(t.Decimals))
+}
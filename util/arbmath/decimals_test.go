@@ -0,0 +1,70 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package arbmath
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestScaleDecimals(t *testing.T) {
+	for _, tc := range []struct {
+		amount   int64
+		from, to uint64
+		down, up int64
+	}{
+		{1_500_000_000_000, EtherDecimals, 6, 1, 2},
+		{2_000_000_000_000, EtherDecimals, 6, 2, 2},
+		{999, EtherDecimals, 6, 0, 1},
+		{0, EtherDecimals, 6, 0, 0},
+		{3, 6, EtherDecimals, 3_000_000_000_000, 3_000_000_000_000},
+		{42, 6, 6, 42, 42},
+	} {
+		amount := big.NewInt(tc.amount)
+		if down := ScaleDecimals(amount, tc.from, tc.to); down.Int64() != tc.down {
+			Fail(t, "scaling", tc.amount, "from", tc.from, "to", tc.to, "decimals rounding down got", down, "expected", tc.down)
+		}
+		if up := ScaleDecimalsUp(amount, tc.from, tc.to); up.Int64() != tc.up {
+			Fail(t, "scaling", tc.amount, "from", tc.from, "to", tc.to, "decimals rounding up got", up, "expected", tc.up)
+		}
+		if amount.Int64() != tc.amount {
+			Fail(t, "scaling modified its input")
+		}
+	}
+}
+
+func TestFeeToken(t *testing.T) {
+	// a token with 6 decimals worth half an ether
+	token := FeeToken{Decimals: 6, WeiPerToken: big.NewInt(500_000_000_000_000_000)}
+	if token.IsEther() || !EtherFeeToken.IsEther() {
+		Fail(t, "wrong tokens taken for ether")
+	}
+	for _, tc := range []struct {
+		wei      int64
+		down, up int64
+	}{
+		{1_000_000_000_000, 2, 2},
+		{1_200_000_000_000, 2, 3},
+		{1, 0, 1},
+		{0, 0, 0},
+	} {
+		wei := big.NewInt(tc.wei)
+		if down := token.FromWei(wei, false); down.Int64() != tc.down {
+			Fail(t, "converting", tc.wei, "wei rounding down got", down, "expected", tc.down)
+		}
+		if up := token.FromWei(wei, true); up.Int64() != tc.up {
+			Fail(t, "converting", tc.wei, "wei rounding up got", up, "expected", tc.up)
+		}
+	}
+	if wei := token.ToWei(big.NewInt(3)); wei.Int64() != 1_500_000_000_000 {
+		Fail(t, "converting to wei got", wei)
+	}
+	amount := big.NewInt(12345)
+	if wei := EtherFeeToken.ToWei(amount); wei.Cmp(amount) != 0 {
+		Fail(t, "ether converted to a different amount of wei", wei)
+	}
+	if units := EtherFeeToken.FromWei(amount, true); units.Cmp(amount) != 0 {
+		Fail(t, "wei converted to a different amount of ether", units)
+	}
+}
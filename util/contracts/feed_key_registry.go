@@ -0,0 +1,64 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package contracts
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const feedKeyRegistryABI = `[
+	{"type":"function","name":"getFeedSigningKeys","stateMutability":"view","inputs":[],"outputs":[{"name":"keyIds","type":"uint64[]"},{"name":"signers","type":"address[]"}]}
+]`
+
+// FeedKeyRegistry reads the currently valid feed signing keys from a registry contract,
+// which returns each key's id along with the address signing with it.
+type FeedKeyRegistry struct {
+	contract *bind.BoundContract
+}
+
+func NewFeedKeyRegistry(address common.Address, caller bind.ContractCaller) (*FeedKeyRegistry, error) {
+	parsed, err := abi.JSON(strings.NewReader(feedKeyRegistryABI))
+	if err != nil {
+		return nil, err
+	}
+	return &FeedKeyRegistry{
+		contract: bind.NewBoundContract(address, parsed, caller, nil, nil),
+	}, nil
+}
+
+func (r *FeedKeyRegistry) FeedSigningKeys(ctx context.Context) (map[uint64]common.Address, error) {
+	var out []interface{}
+	if err := r.contract.Call(&bind.CallOpts{Context: ctx}, &out, "getFeedSigningKeys"); err != nil {
+		return nil, err
+	}
+	if len(out) != 2 {
+		return nil, fmt.Errorf("unexpected getFeedSigningKeys result length %v", len(out))
+	}
+	keyIds, ok := out[0].([]uint64)
+	if !ok {
+		return nil, fmt.Errorf("unexpected getFeedSigningKeys key ids type %T", out[0])
+	}
+	signers, ok := out[1].([]common.Address)
+	if !ok {
+		return nil, fmt.Errorf("unexpected getFeedSigningKeys signers type %T", out[1])
+	}
+	if len(keyIds) != len(signers) {
+		return nil, fmt.Errorf("feed key registry returned %v key ids but %v signers", len(keyIds), len(signers))
+	}
+	keys := make(map[uint64]common.Address, len(keyIds))
+	for i, keyId := range keyIds {
+		keys[keyId] = signers[i]
+	}
+	return keys, nil
+}
+
+type FeedKeyRegistryInterface interface {
+	FeedSigningKeys(ctx context.Context) (map[uint64]common.Address, error)
+}
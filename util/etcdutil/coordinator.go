@@ -0,0 +1,138 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package etcdutil
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/offchainlabs/nitro/util/redisutil"
+)
+
+// EtcdCoordinator reads the sequencer coordination keys from etcd, which has the same keys as Redis.
+type EtcdCoordinator struct {
+	Client *Client
+}
+
+func NewEtcdCoordinator(config *Config) (*EtcdCoordinator, error) {
+	client, err := NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	return &EtcdCoordinator{Client: client}, nil
+}
+
+func (c *EtcdCoordinator) getString(ctx context.Context, key string) (string, error) {
+	kv, err := c.Client.Get(ctx, key)
+	if err != nil || kv == nil {
+		return "", err
+	}
+	return string(kv.Value), nil
+}
+
+// RecommendSequencerWantingLockout returns the sequencer the lockout was handed off to if it wants the
+// lockout, and otherwise the top priority sequencer wanting the lockout.
+func (c *EtcdCoordinator) RecommendSequencerWantingLockout(ctx context.Context) (string, error) {
+	handoffTarget, err := c.HandoffTarget(ctx)
+	if err != nil {
+		return "", err
+	}
+	if handoffTarget != "" {
+		wants, err := c.Client.Get(ctx, redisutil.WantsLockoutKeyFor(handoffTarget))
+		if err != nil {
+			return "", err
+		}
+		if wants != nil {
+			return handoffTarget, nil
+		}
+	}
+	priorities, err := c.GetPriorities(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(priorities) == 0 {
+		return "", errors.New("sequencer priorities unset")
+	}
+	policyString, err := c.getString(ctx, redisutil.POLICY_KEY)
+	if err != nil {
+		return "", err
+	}
+	if policyString != "" {
+		policy, err := redisutil.ParsePriorityPolicy([]byte(policyString))
+		if err != nil {
+			// a broken policy mustn't prevent failover, so fall back to the plain priorities
+			log.Error("invalid sequencer priority policy on etcd, ignoring it", "err", err)
+		} else {
+			var current string
+			if policy.Stickiness != 0 {
+				current, err = c.CurrentChosenSequencer(ctx)
+				if err != nil {
+					return "", err
+				}
+			}
+			priorities = policy.Rank(priorities, current, time.Now())
+		}
+	}
+	wanting, err := c.GetLiveliness(ctx)
+	if err != nil {
+		return "", err
+	}
+	wantsLockout := make(map[string]bool, len(wanting))
+	for _, url := range wanting {
+		wantsLockout[url] = true
+	}
+	for _, url := range priorities {
+		if wantsLockout[url] {
+			return url, nil
+		}
+	}
+	log.Error("no sequencer appears to want the lockout on etcd", "priorities", priorities)
+	return "", nil
+}
+
+// HandoffTarget returns the sequencer the lockout was last handed off to, or "" if none was
+func (c *EtcdCoordinator) HandoffTarget(ctx context.Context) (string, error) {
+	return c.getString(ctx, redisutil.HANDOFF_KEY)
+}
+
+// CurrentChosenSequencer retrieves the current chosen sequencer holding the lock
+func (c *EtcdCoordinator) CurrentChosenSequencer(ctx context.Context) (string, error) {
+	return c.getString(ctx, redisutil.CHOSENSEQ_KEY)
+}
+
+// CurrentChosenSyncUrl returns the url the chosen sequencer serves its messages at, if it streams them
+// to standbys rather than writing them to etcd.
+func (c *EtcdCoordinator) CurrentChosenSyncUrl(ctx context.Context) (string, error) {
+	return c.getString(ctx, redisutil.SYNC_URL_KEY)
+}
+
+// GetPriorities returns the priority list of sequencers
+func (c *EtcdCoordinator) GetPriorities(ctx context.Context) ([]string, error) {
+	prioritiesString, err := c.getString(ctx, redisutil.PRIORITIES_KEY)
+	if err != nil || prioritiesString == "" {
+		return []string{}, err
+	}
+	return strings.Split(prioritiesString, ","), nil
+}
+
+// GetLiveliness returns a list of sequencers that want the lockout
+func (c *EtcdCoordinator) GetLiveliness(ctx context.Context) ([]string, error) {
+	wanting, err := c.Client.GetPrefix(ctx, redisutil.WANTS_LOCKOUT_KEY_PREFIX)
+	if err != nil {
+		return []string{}, err
+	}
+	livelinessList := make([]string, 0, len(wanting))
+	for _, kv := range wanting {
+		livelinessList = append(livelinessList, strings.TrimPrefix(kv.Key, redisutil.WANTS_LOCKOUT_KEY_PREFIX))
+	}
+	return livelinessList, nil
+}
+
+func (c *EtcdCoordinator) Close() error {
+	return c.Client.Close()
+}
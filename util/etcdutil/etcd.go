@@ -0,0 +1,205 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+// Package etcdutil wraps etcd's v3 client, keeping all keys under a configured prefix.
+package etcdutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/namespace"
+	"go.uber.org/zap"
+)
+
+type Config struct {
+	Endpoints      []string      `koanf:"endpoints"`
+	Username       string        `koanf:"username"`
+	Password       string        `koanf:"password"`
+	KeyPrefix      string        `koanf:"key-prefix"`
+	RequestTimeout time.Duration `koanf:"request-timeout"`
+}
+
+var DefaultConfig = Config{
+	Endpoints:      []string{},
+	Username:       "",
+	Password:       "",
+	KeyPrefix:      "nitro/",
+	RequestTimeout: 5 * time.Second,
+}
+
+func ConfigAddOptions(prefix string, f *pflag.FlagSet) {
+	f.StringSlice(prefix+".endpoints", DefaultConfig.Endpoints, "etcd client URLs (e.g. http://etcd-0:2379), balanced between by the client")
+	f.String(prefix+".username", DefaultConfig.Username, "etcd username, if authentication is enabled")
+	f.String(prefix+".password", DefaultConfig.Password, "etcd password, if authentication is enabled")
+	f.String(prefix+".key-prefix", DefaultConfig.KeyPrefix, "prefix for all keys, so several chains can share an etcd cluster")
+	f.Duration(prefix+".request-timeout", DefaultConfig.RequestTimeout, "timeout of a single etcd request")
+}
+
+func (c *Config) Enabled() bool {
+	return len(c.Endpoints) > 0
+}
+
+func (c *Config) Validate() error {
+	if len(c.Endpoints) == 0 {
+		return errors.New("no etcd endpoints configured")
+	}
+	if c.RequestTimeout <= 0 {
+		return errors.New("etcd request timeout must be positive")
+	}
+	return nil
+}
+
+// ConfigFromURL parses an etcd://[user:password@]host:port[,host:port...][/key-prefix] URL,
+// for tools taking a single coordination URL.
+func ConfigFromURL(etcdURL string) (*Config, error) {
+	u, err := url.Parse(etcdURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "etcd" {
+		return nil, fmt.Errorf("etcd url %v must have the etcd:// scheme", u.Redacted())
+	}
+	config := DefaultConfig
+	config.Endpoints = nil
+	for _, host := range strings.Split(u.Host, ",") {
+		if host != "" {
+			config.Endpoints = append(config.Endpoints, "http://"+host)
+		}
+	}
+	if u.User != nil {
+		config.Username = u.User.Username()
+		config.Password, _ = u.User.Password()
+	}
+	if path := strings.TrimPrefix(u.Path, "/"); path != "" {
+		config.KeyPrefix = path
+	}
+	return &config, config.Validate()
+}
+
+// Client is an etcd client whose keys are all under the key prefix, and whose requests each time out
+// after the request timeout. It doesn't retry requests which may have been applied, so a failed Txn
+// may or may not have been applied.
+type Client struct {
+	config *Config
+	client *clientv3.Client
+	kv     clientv3.KV
+	lease  clientv3.Lease
+}
+
+func NewClient(config *Config) (*Client, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   config.Endpoints,
+		Username:    config.Username,
+		Password:    config.Password,
+		DialTimeout: config.RequestTimeout,
+		Logger:      zap.NewNop(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		config: config,
+		client: client,
+		kv:     namespace.NewKV(client.KV, config.KeyPrefix),
+		lease:  namespace.NewLease(client.Lease, config.KeyPrefix),
+	}, nil
+}
+
+func (c *Client) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, c.config.RequestTimeout)
+}
+
+// KeyValue is a stored key, with the key prefix removed.
+type KeyValue struct {
+	Key         string
+	Value       []byte
+	ModRevision int64
+	Lease       int64
+}
+
+// Get returns the key, or nil if it doesn't exist.
+func (c *Client) Get(ctx context.Context, key string) (*KeyValue, error) {
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+	response, err := c.kv.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Kvs) == 0 {
+		return nil, nil
+	}
+	kv := response.Kvs[0]
+	return &KeyValue{Key: string(kv.Key), Value: kv.Value, ModRevision: kv.ModRevision, Lease: kv.Lease}, nil
+}
+
+// GetPrefix returns all keys starting with the prefix.
+func (c *Client) GetPrefix(ctx context.Context, prefix string) ([]*KeyValue, error) {
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+	response, err := c.kv.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	kvs := make([]*KeyValue, 0, len(response.Kvs))
+	for _, kv := range response.Kvs {
+		kvs = append(kvs, &KeyValue{Key: string(kv.Key), Value: kv.Value, ModRevision: kv.ModRevision, Lease: kv.Lease})
+	}
+	return kvs, nil
+}
+
+// Put stores the value, attached to the lease if it's non-zero, so it's deleted when the lease expires.
+func (c *Client) Put(ctx context.Context, key string, value []byte, lease clientv3.LeaseID) error {
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+	var opts []clientv3.OpOption
+	if lease != clientv3.NoLease {
+		opts = append(opts, clientv3.WithLease(lease))
+	}
+	_, err := c.kv.Put(ctx, key, string(value), opts...)
+	return err
+}
+
+func (c *Client) Delete(ctx context.Context, key string) error {
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+	_, err := c.kv.Delete(ctx, key)
+	return err
+}
+
+// Grant creates a lease for the duration, rounded up to whole seconds, returning its id.
+func (c *Client) Grant(ctx context.Context, ttl time.Duration) (clientv3.LeaseID, error) {
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+	seconds := int64((ttl + time.Second - 1) / time.Second)
+	response, err := c.lease.Grant(ctx, seconds)
+	if err != nil {
+		return clientv3.NoLease, err
+	}
+	return response.ID, nil
+}
+
+// Txn applies the operations if all comparisons hold, returning whether they did.
+// An error doesn't mean the transaction wasn't applied, so it mustn't be blindly retried.
+func (c *Client) Txn(ctx context.Context, compares []clientv3.Cmp, ops []clientv3.Op) (bool, error) {
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+	response, err := c.kv.Txn(ctx).If(compares...).Then(ops...).Commit()
+	if err != nil {
+		return false, err
+	}
+	return response.Succeeded, nil
+}
+
+func (c *Client) Close() error {
+	return c.client.Close()
+}
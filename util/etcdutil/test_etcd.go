@@ -0,0 +1,39 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package etcdutil
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// CreateTestEtcd runs a single member etcd server until the test ends, returning a config to reach it with.
+func CreateTestEtcd(t *testing.T) *Config {
+	t.Helper()
+	serverConfig := embed.NewConfig()
+	serverConfig.Dir = t.TempDir()
+	serverConfig.LogLevel = "error"
+	clientURL, _ := url.Parse("http://127.0.0.1:0")
+	peerURL, _ := url.Parse("http://127.0.0.1:0")
+	serverConfig.ListenClientUrls, serverConfig.AdvertiseClientUrls = []url.URL{*clientURL}, []url.URL{*clientURL}
+	serverConfig.ListenPeerUrls, serverConfig.AdvertisePeerUrls = []url.URL{*peerURL}, []url.URL{*peerURL}
+	serverConfig.InitialCluster = serverConfig.InitialClusterFromName(serverConfig.Name)
+	server, err := embed.StartEtcd(serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Close)
+	select {
+	case <-server.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		t.Fatal("etcd server didn't start")
+	}
+	config := DefaultConfig
+	config.Endpoints = []string{"http://" + server.Clients[0].Addr().String()}
+	config.KeyPrefix = "test/"
+	return &config
+}
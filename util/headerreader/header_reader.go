@@ -17,12 +17,19 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/offchainlabs/nitro/arbutil"
 	"github.com/offchainlabs/nitro/util/stopwaiter"
 	flag "github.com/spf13/pflag"
 )
 
+var (
+	finalityCacheHitCounter  = metrics.NewRegisteredCounter("arb/headerreader/finality/cache/hit", nil)
+	finalityCacheMissCounter = metrics.NewRegisteredCounter("arb/headerreader/finality/cache/miss", nil)
+	reorgCounter             = metrics.NewRegisteredCounter("arb/headerreader/reorgs", nil)
+)
+
 // A regexp matching "execution reverted" errors returned from the parent chain RPC.
 var ExecutionRevertedRegexp = regexp.MustCompile(`(?i)execution reverted|VM execution error\.?`)
 
@@ -46,6 +53,8 @@ type HeaderReader struct {
 	lastBroadcastErr           error
 	lastPendingCallBlockNr     uint64
 	requiresPendingCallUpdates int
+	// how many times a new head hasn't extended the previous one
+	reorgs uint64
 
 	safe      cachedHeader
 	finalized cachedHeader
@@ -57,6 +66,9 @@ type cachedHeader struct {
 	rpcBlockNum    *big.Int
 	headWhenCached *types.Header
 	header         *types.Header
+	cachedAt       time.Time
+	// the reader's reorg count from before the header was read
+	reorgsWhenCached uint64
 }
 
 type Config struct {
@@ -68,6 +80,7 @@ type Config struct {
 	TxTimeout            time.Duration   `koanf:"tx-timeout" reload:"hot"`
 	OldHeaderTimeout     time.Duration   `koanf:"old-header-timeout" reload:"hot"`
 	UseFinalityData      bool            `koanf:"use-finality-data" reload:"hot"`
+	FinalityCacheTTL     time.Duration   `koanf:"finality-cache-ttl" reload:"hot"`
 	Dangerous            DangerousConfig `koanf:"dangerous"`
 }
 
@@ -86,6 +99,7 @@ var DefaultConfig = Config{
 	TxTimeout:            5 * time.Minute,
 	OldHeaderTimeout:     5 * time.Minute,
 	UseFinalityData:      true,
+	FinalityCacheTTL:     2 * time.Second,
 	Dangerous: DangerousConfig{
 		WaitForTxApprovalSafePoll: 0,
 	},
@@ -95,6 +109,7 @@ func AddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".enable", DefaultConfig.Enable, "enable reader connection")
 	f.Bool(prefix+".poll-only", DefaultConfig.PollOnly, "do not attempt to subscribe to header events")
 	f.Bool(prefix+".use-finality-data", DefaultConfig.UseFinalityData, "use l1 data about finalized/safe blocks")
+	f.Duration(prefix+".finality-cache-ttl", DefaultConfig.FinalityCacheTTL, "keep using the finalized/safe blocks read as of an earlier l1 head for up to this long while l1 moves forward without reorging (0 = read them again for each new head)")
 	f.Duration(prefix+".poll-interval", DefaultConfig.PollInterval, "interval when polling endpoint")
 	f.Duration(prefix+".poll-timeout", DefaultConfig.PollTimeout, "timeout when polling endpoint")
 	f.Duration(prefix+".subscribe-err-interval", DefaultConfig.SubscribeErrInterval, "interval for subscribe error")
@@ -211,6 +226,11 @@ func (s *HeaderReader) possiblyBroadcast(h *types.Header) {
 	broadcastThis := false
 
 	if headerHash != s.lastBroadcastHash {
+		if last := s.lastBroadcastHeader; last != nil && !extendsHeader(last, h) {
+			s.reorgs++
+			reorgCounter.Inc(1)
+			log.Info("parent chain head didn't extend the previous one", "previous", last.Number, "previousHash", last.Hash(), "head", h.Number, "hash", headerHash, "minDepth", reorgDepth(last, h))
+		}
 		broadcastThis = true
 		s.lastBroadcastHash = headerHash
 		s.lastBroadcastHeader = h
@@ -250,6 +270,26 @@ func (s *HeaderReader) possiblyBroadcast(h *types.Header) {
 	}
 }
 
+// extendsHeader returns whether head could be on the same chain as, and after, last. Heads can be skipped when
+// polling, so a head more than one block after last is assumed to extend it.
+func extendsHeader(last, head *types.Header) bool {
+	if head.Number.Cmp(last.Number) <= 0 {
+		return false
+	}
+	if head.Number.Uint64() == last.Number.Uint64()+1 {
+		return head.ParentHash == last.Hash()
+	}
+	return true
+}
+
+// reorgDepth returns the fewest blocks a reorg from last to head could have replaced.
+func reorgDepth(last, head *types.Header) uint64 {
+	if head.Number.Cmp(last.Number) > 0 {
+		return 1
+	}
+	return last.Number.Uint64() - head.Number.Uint64() + 1
+}
+
 func (s *HeaderReader) getPendingCallBlockNumber() (*big.Int, error) {
 	if s.isParentChainArbitrum {
 		return s.arbSys.ArbBlockNumber(&bind.CallOpts{Context: s.GetContext(), Pending: true})
@@ -475,6 +515,14 @@ func (s *HeaderReader) getCached(ctx context.Context, c *cachedHeader) (*types.H
 	if !s.config().UseFinalityData || !HeaderIndicatesFinalitySupport(currentHead) {
 		return nil, ErrBlockNumberNotSupported
 	}
+	if s.cacheStillValid(c, currentHead) {
+		finalityCacheHitCounter.Inc(1)
+		return c.header, nil
+	}
+	finalityCacheMissCounter.Inc(1)
+	s.chanMutex.RLock()
+	reorgs := s.reorgs
+	s.chanMutex.RUnlock()
 	header, err := s.client.HeaderByNumber(ctx, c.rpcBlockNum)
 	if err != nil {
 		if !errors.Is(err, context.Canceled) {
@@ -486,9 +534,27 @@ func (s *HeaderReader) getCached(ctx context.Context, c *cachedHeader) (*types.H
 	}
 	c.header = header
 	c.headWhenCached = currentHead
+	c.cachedAt = time.Now()
+	c.reorgsWhenCached = reorgs
 	return c.header, nil
 }
 
+// cacheStillValid returns whether the header cached as of an earlier head can still be used as of the current one,
+// which it can for a while as long as the parent chain has only moved forward since. Any reorg invalidates it, as
+// even one which doesn't reach it may have moved the safe block.
+func (s *HeaderReader) cacheStillValid(c *cachedHeader, currentHead *types.Header) bool {
+	ttl := s.config().FinalityCacheTTL
+	if ttl <= 0 || c.header == nil || c.headWhenCached == nil || time.Since(c.cachedAt) >= ttl {
+		return false
+	}
+	if currentHead.Number.Cmp(c.headWhenCached.Number) <= 0 {
+		return false
+	}
+	s.chanMutex.RLock()
+	defer s.chanMutex.RUnlock()
+	return s.reorgs == c.reorgsWhenCached
+}
+
 func (s *HeaderReader) LatestSafeBlockHeader(ctx context.Context) (*types.Header, error) {
 	header, err := s.getCached(ctx, &s.safe)
 	if errors.Is(err, ErrBlockNumberNotSupported) {
@@ -0,0 +1,82 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package headerreader
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/offchainlabs/nitro/arbutil"
+)
+
+type safeBlockClient struct {
+	arbutil.L1Interface
+	reads int
+}
+
+func (c *safeBlockClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	c.reads++
+	return &types.Header{Number: big.NewInt(int64(c.reads)), Difficulty: common.Big0}, nil
+}
+
+func childHeader(parent *types.Header) *types.Header {
+	return &types.Header{Number: new(big.Int).Add(parent.Number, common.Big1), ParentHash: parent.Hash(), Difficulty: common.Big0}
+}
+
+func TestFinalityCache(t *testing.T) {
+	config := TestConfig
+	config.UseFinalityData = true
+	config.FinalityCacheTTL = time.Hour
+	client := &safeBlockClient{}
+	reader := &HeaderReader{
+		config:            func() *Config { return &config },
+		client:            client,
+		outChannels:       make(map[chan<- *types.Header]struct{}),
+		outChannelsBehind: make(map[chan<- *types.Header]struct{}),
+		safe:              cachedHeader{blockTag: "safe", rpcBlockNum: big.NewInt(rpc.SafeBlockNumber.Int64())},
+	}
+	ctx := context.Background()
+	expectReads := func(reads int) {
+		t.Helper()
+		if _, err := reader.LatestSafeBlockHeader(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if client.reads != reads {
+			t.Fatalf("expected %v reads of the safe block, got %v", reads, client.reads)
+		}
+	}
+
+	head := &types.Header{Number: big.NewInt(100), Difficulty: common.Big0}
+	reader.possiblyBroadcast(head)
+	expectReads(1)
+	head = childHeader(head)
+	reader.possiblyBroadcast(head)
+	expectReads(1)
+
+	// a second head at the same height is a reorg
+	sibling := childHeader(head)
+	head = childHeader(head)
+	head.Extra = []byte{1}
+	reader.possiblyBroadcast(sibling)
+	expectReads(1)
+	reader.possiblyBroadcast(head)
+	expectReads(2)
+
+	// the cache expires even while the parent chain moves forward
+	reader.safe.cachedAt = time.Now().Add(-2 * time.Hour)
+	head = childHeader(head)
+	reader.possiblyBroadcast(head)
+	expectReads(3)
+
+	config.FinalityCacheTTL = 0
+	head = childHeader(head)
+	reader.possiblyBroadcast(head)
+	expectReads(4)
+}
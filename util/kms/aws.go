@@ -0,0 +1,140 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+)
+
+const maxResponseSize = 1 << 20
+
+// awsBackend calls the AWS KMS JSON API directly, signing requests with the credentials found in the environment.
+type awsBackend struct {
+	keyID       string
+	region      string
+	endpoint    string
+	credentials aws.CredentialsProvider
+	signer      *v4.Signer
+	client      *http.Client
+}
+
+func newAWSBackend(ctx context.Context, config *Config) (*awsBackend, error) {
+	var options []func(*awsConfig.LoadOptions) error
+	if config.Region != "" {
+		options = append(options, awsConfig.WithRegion(config.Region))
+	}
+	cfg, err := awsConfig.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("error loading aws config: %w", err)
+	}
+	if cfg.Region == "" {
+		return nil, errors.New("no aws region configured for kms")
+	}
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://kms.%s.amazonaws.com", cfg.Region)
+	}
+	return &awsBackend{
+		keyID:       config.KeyID,
+		region:      cfg.Region,
+		endpoint:    strings.TrimSuffix(endpoint, "/"),
+		credentials: cfg.Credentials,
+		signer:      v4.NewSigner(),
+		client:      &http.Client{},
+	}, nil
+}
+
+type awsError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+func (b *awsBackend) call(ctx context.Context, action string, request any, response any) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+	credentials, err := b.credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("error retrieving aws credentials: %w", err)
+	}
+	payloadHash := sha256.Sum256(body)
+	if err := b.signer.SignHTTP(ctx, credentials, req, hex.EncodeToString(payloadHash[:]), "kms", b.region, time.Now()); err != nil {
+		return err
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return &retryableError{fmt.Errorf("aws kms %v request failed: %w", action, err)}
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return &retryableError{err}
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr awsError
+		_ = json.Unmarshal(data, &apiErr)
+		err := fmt.Errorf("aws kms %v failed with status %v: %v: %v", action, resp.Status, apiErr.Type, apiErr.Message)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 || strings.HasSuffix(apiErr.Type, "ThrottlingException") {
+			return &retryableError{err}
+		}
+		return err
+	}
+	return json.Unmarshal(data, response)
+}
+
+func (b *awsBackend) publicKey(ctx context.Context) ([]byte, error) {
+	var response struct {
+		PublicKey []byte
+		KeySpec   string
+	}
+	if err := b.call(ctx, "GetPublicKey", map[string]string{"KeyId": b.keyID}, &response); err != nil {
+		return nil, err
+	}
+	if response.KeySpec != "ECC_SECG_P256K1" {
+		return nil, fmt.Errorf("aws kms key %v has key spec %v, expected ECC_SECG_P256K1", b.keyID, response.KeySpec)
+	}
+	return response.PublicKey, nil
+}
+
+func (b *awsBackend) sign(ctx context.Context, digest []byte) ([]byte, error) {
+	request := struct {
+		KeyId            string
+		Message          []byte
+		MessageType      string
+		SigningAlgorithm string
+	}{
+		KeyId:            b.keyID,
+		Message:          digest,
+		MessageType:      "DIGEST",
+		SigningAlgorithm: "ECDSA_SHA_256",
+	}
+	var response struct {
+		Signature []byte
+	}
+	if err := b.call(ctx, "Sign", request, &response); err != nil {
+		return nil, err
+	}
+	return response.Signature, nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package kms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jwt"
+)
+
+const (
+	gcpDefaultEndpoint = "https://cloudkms.googleapis.com"
+	gcpScope           = "https://www.googleapis.com/auth/cloudkms"
+	gcpTokenURL        = "https://oauth2.googleapis.com/token"
+	gcpMetadataToken   = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// gcpBackend calls the Cloud KMS REST API, authenticating as a service account, either from a key file or the
+// instance's own.
+type gcpBackend struct {
+	keyVersion string
+	endpoint   string
+	client     *http.Client
+}
+
+func newGCPBackend(config *Config) (*gcpBackend, error) {
+	var tokens oauth2.TokenSource
+	if config.CredentialsFile != "" {
+		var err error
+		tokens, err = serviceAccountTokenSource(config.CredentialsFile)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		tokens = oauth2.ReuseTokenSource(nil, metadataTokenSource{client: &http.Client{Timeout: 10 * time.Second}})
+	}
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = gcpDefaultEndpoint
+	}
+	return &gcpBackend{
+		keyVersion: config.KeyID,
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		// the token source outlives whatever context the signer is created with
+		client: oauth2.NewClient(context.Background(), tokens),
+	}, nil
+}
+
+func serviceAccountTokenSource(path string) (oauth2.TokenSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading gcp credentials file: %w", err)
+	}
+	var key struct {
+		ClientEmail  string `json:"client_email"`
+		PrivateKey   string `json:"private_key"`
+		PrivateKeyID string `json:"private_key_id"`
+		TokenURI     string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, fmt.Errorf("error parsing gcp credentials file: %w", err)
+	}
+	if key.ClientEmail == "" || key.PrivateKey == "" {
+		return nil, errors.New("gcp credentials file isn't a service account key")
+	}
+	tokenURL := key.TokenURI
+	if tokenURL == "" {
+		tokenURL = gcpTokenURL
+	}
+	config := &jwt.Config{
+		Email:        key.ClientEmail,
+		PrivateKey:   []byte(key.PrivateKey),
+		PrivateKeyID: key.PrivateKeyID,
+		TokenURL:     tokenURL,
+		Scopes:       []string{gcpScope},
+	}
+	return config.TokenSource(context.Background()), nil
+}
+
+// metadataTokenSource gets access tokens for the instance's service account from the metadata server.
+type metadataTokenSource struct {
+	client *http.Client
+}
+
+func (s metadataTokenSource) Token() (*oauth2.Token, error) {
+	req, err := http.NewRequest(http.MethodGet, gcpMetadataToken, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error getting gcp access token from the metadata server: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gcp metadata server responded with status %v", resp.Status)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+		TokenType   string `json:"token_type"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&token); err != nil {
+		return nil, err
+	}
+	return &oauth2.Token{
+		AccessToken: token.AccessToken,
+		TokenType:   token.TokenType,
+		Expiry:      time.Now().Add(time.Duration(token.ExpiresIn) * time.Second),
+	}, nil
+}
+
+type gcpError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+func (b *gcpBackend) call(ctx context.Context, method string, url string, request any, response any) error {
+	var body io.Reader
+	if request != nil {
+		data, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	if request != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return &retryableError{fmt.Errorf("gcp kms request failed: %w", err)}
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return &retryableError{err}
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr gcpError
+		_ = json.Unmarshal(data, &apiErr)
+		err := fmt.Errorf("gcp kms request failed with status %v: %v: %v", resp.Status, apiErr.Error.Status, apiErr.Error.Message)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return &retryableError{err}
+		}
+		return err
+	}
+	return json.Unmarshal(data, response)
+}
+
+func (b *gcpBackend) publicKey(ctx context.Context) ([]byte, error) {
+	var response struct {
+		Pem       string `json:"pem"`
+		Algorithm string `json:"algorithm"`
+	}
+	if err := b.call(ctx, http.MethodGet, fmt.Sprintf("%s/v1/%s/publicKey", b.endpoint, b.keyVersion), nil, &response); err != nil {
+		return nil, err
+	}
+	if response.Algorithm != "EC_SIGN_SECP256K1_SHA256" {
+		return nil, fmt.Errorf("gcp kms key %v has algorithm %v, expected EC_SIGN_SECP256K1_SHA256", b.keyVersion, response.Algorithm)
+	}
+	block, _ := pem.Decode([]byte(response.Pem))
+	if block == nil {
+		return nil, errors.New("gcp kms public key isn't pem encoded")
+	}
+	return block.Bytes, nil
+}
+
+func (b *gcpBackend) sign(ctx context.Context, digest []byte) ([]byte, error) {
+	// the key's algorithm names sha256, but any 32 byte digest is signed as is
+	request := map[string]map[string][]byte{"digest": {"sha256": digest}}
+	var response struct {
+		Signature []byte `json:"signature"`
+	}
+	if err := b.call(ctx, http.MethodPost, fmt.Sprintf("%s/v1/%s:asymmetricSign", b.endpoint, b.keyVersion), request, &response); err != nil {
+		return nil, err
+	}
+	return response.Signature, nil
+}
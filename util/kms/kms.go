@@ -0,0 +1,278 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+// Package kms signs with secp256k1 keys held in AWS KMS or GCP Cloud KMS, so wallets don't need keys on disk.
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/util/signature"
+)
+
+var (
+	signCounter  = metrics.NewRegisteredCounter("arb/kms/sign", nil)
+	retryCounter = metrics.NewRegisteredCounter("arb/kms/retry", nil)
+)
+
+const (
+	ProviderAWS = "aws"
+	ProviderGCP = "gcp"
+)
+
+type Config struct {
+	Provider        string        `koanf:"provider"`
+	KeyID           string        `koanf:"key-id"`
+	Region          string        `koanf:"region"`
+	Endpoint        string        `koanf:"endpoint"`
+	CredentialsFile string        `koanf:"credentials-file"`
+	Timeout         time.Duration `koanf:"timeout"`
+	MaxRetries      int           `koanf:"max-retries"`
+	RetryBackoff    time.Duration `koanf:"retry-backoff"`
+}
+
+var DefaultConfig = Config{
+	Provider:        "",
+	KeyID:           "",
+	Region:          "",
+	Endpoint:        "",
+	CredentialsFile: "",
+	Timeout:         30 * time.Second,
+	MaxRetries:      5,
+	RetryBackoff:    200 * time.Millisecond,
+}
+
+func ConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.String(prefix+".provider", DefaultConfig.Provider, "sign with a secp256k1 key held in this KMS instead of a local key, \"aws\" or \"gcp\" (empty = use a local key)")
+	f.String(prefix+".key-id", DefaultConfig.KeyID, "the key's id or ARN for aws, or its full key version resource name for gcp")
+	f.String(prefix+".region", DefaultConfig.Region, "aws region of the key (empty = the region configured in the environment)")
+	f.String(prefix+".endpoint", DefaultConfig.Endpoint, "KMS endpoint to use instead of the provider's default one")
+	f.String(prefix+".credentials-file", DefaultConfig.CredentialsFile, "gcp service account key file (empty = use the instance's service account)")
+	f.Duration(prefix+".timeout", DefaultConfig.Timeout, "timeout for each signature, including retries")
+	f.Int(prefix+".max-retries", DefaultConfig.MaxRetries, "how many times to retry a throttled or failed KMS request")
+	f.Duration(prefix+".retry-backoff", DefaultConfig.RetryBackoff, "delay before the first retry of a KMS request, doubling with each retry")
+}
+
+func (c *Config) Enabled() bool {
+	return c.Provider != ""
+}
+
+func (c *Config) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+	if c.Provider != ProviderAWS && c.Provider != ProviderGCP {
+		return fmt.Errorf("invalid kms provider \"%v\", expected \"%v\" or \"%v\"", c.Provider, ProviderAWS, ProviderGCP)
+	}
+	if c.KeyID == "" {
+		return errors.New("kms key id is required")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("invalid kms timeout %v", c.Timeout)
+	}
+	if c.MaxRetries < 0 {
+		return fmt.Errorf("invalid kms max retries %v", c.MaxRetries)
+	}
+	return nil
+}
+
+// backend is a KMS holding the key.
+type backend interface {
+	// publicKey returns the key's DER encoded SubjectPublicKeyInfo.
+	publicKey(ctx context.Context) ([]byte, error)
+	// sign returns the DER encoded ECDSA signature of the digest.
+	sign(ctx context.Context, digest []byte) ([]byte, error)
+}
+
+// retryableError is a KMS error worth retrying, like being throttled or the KMS being unavailable.
+type retryableError struct {
+	error
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.error
+}
+
+type Signer struct {
+	config    *Config
+	backend   backend
+	publicKey []byte
+	address   common.Address
+}
+
+// NewSigner connects to the KMS and reads the key's public key, deriving the address it signs as once.
+func NewSigner(ctx context.Context, config *Config) (*Signer, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	var b backend
+	var err error
+	if config.Provider == ProviderAWS {
+		b, err = newAWSBackend(ctx, config)
+	} else {
+		b, err = newGCPBackend(config)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newSigner(ctx, config, b)
+}
+
+func newSigner(ctx context.Context, config *Config, b backend) (*Signer, error) {
+	der, err := withRetry(ctx, config, b.publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("error reading kms public key: %w", err)
+	}
+	publicKey, err := parsePublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	pubkey, err := crypto.UnmarshalPubkey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{
+		config:    config,
+		backend:   b,
+		publicKey: publicKey,
+		address:   crypto.PubkeyToAddress(*pubkey),
+	}, nil
+}
+
+func (s *Signer) Address() common.Address {
+	return s.address
+}
+
+// SignHash signs the 32 byte hash, returning a 65 byte [R || S || V] signature as crypto.Sign does.
+func (s *Signer) SignHash(ctx context.Context, hash []byte) ([]byte, error) {
+	if len(hash) != common.HashLength {
+		return nil, fmt.Errorf("hash is %v bytes long, expected %v", len(hash), common.HashLength)
+	}
+	der, err := withRetry(ctx, s.config, func(ctx context.Context) ([]byte, error) {
+		return s.backend.sign(ctx, hash)
+	})
+	if err != nil {
+		return nil, err
+	}
+	signCounter.Inc(1)
+	return toEthereumSignature(hash, der, s.publicKey)
+}
+
+func (s *Signer) signHashWithTimeout(hash []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+	return s.SignHash(ctx, hash)
+}
+
+// TransactOpts returns transaction options signing transactions for the chain with the key.
+func (s *Signer) TransactOpts(chainID *big.Int) *bind.TransactOpts {
+	txSigner := types.LatestSignerForChainID(chainID)
+	return &bind.TransactOpts{
+		From: s.address,
+		Signer: func(address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != s.address {
+				return nil, bind.ErrNotAuthorized
+			}
+			sig, err := s.signHashWithTimeout(txSigner.Hash(tx).Bytes())
+			if err != nil {
+				return nil, err
+			}
+			return tx.WithSignature(txSigner, sig)
+		},
+		Context: context.Background(),
+	}
+}
+
+// DataSigner returns a function signing hashes with the key.
+func (s *Signer) DataSigner() signature.DataSignerFunc {
+	return s.signHashWithTimeout
+}
+
+func withRetry[T any](ctx context.Context, config *Config, fn func(context.Context) (T, error)) (T, error) {
+	for attempt := 0; ; attempt++ {
+		result, err := fn(ctx)
+		var retryable *retryableError
+		if err == nil || !errors.As(err, &retryable) || attempt >= config.MaxRetries {
+			return result, err
+		}
+		retryCounter.Inc(1)
+		// back off exponentially, with jitter so signers sharing a rate limit don't retry in lockstep
+		delay := config.RetryBackoff << attempt
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		log.Warn("kms request failed, retrying", "attempt", attempt+1, "delay", delay, "err", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		}
+	}
+}
+
+// parsePublicKey returns the uncompressed secp256k1 public key in a DER encoded SubjectPublicKeyInfo.
+func parsePublicKey(der []byte) ([]byte, error) {
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if rest, err := asn1.Unmarshal(der, &spki); err != nil {
+		return nil, fmt.Errorf("error parsing kms public key: %w", err)
+	} else if len(rest) > 0 {
+		return nil, errors.New("kms public key has trailing data")
+	}
+	if _, err := crypto.UnmarshalPubkey(spki.PublicKey.Bytes); err != nil {
+		return nil, fmt.Errorf("kms key isn't a secp256k1 key: %w", err)
+	}
+	return spki.PublicKey.Bytes, nil
+}
+
+var secp256k1HalfN = new(big.Int).Rsh(crypto.S256().Params().N, 1)
+
+// toEthereumSignature converts a DER encoded ECDSA signature of the hash to [R || S || V], with S in the lower half
+// of the curve order as Ethereum requires, and V found by which recovery id gives back the public key.
+func toEthereumSignature(hash []byte, der []byte, publicKey []byte) ([]byte, error) {
+	var parsed struct {
+		R, S *big.Int
+	}
+	if rest, err := asn1.Unmarshal(der, &parsed); err != nil {
+		return nil, fmt.Errorf("error parsing kms signature: %w", err)
+	} else if len(rest) > 0 {
+		return nil, errors.New("kms signature has trailing data")
+	}
+	if parsed.R.Sign() <= 0 || parsed.S.Sign() <= 0 || parsed.R.BitLen() > 256 || parsed.S.BitLen() > 256 {
+		return nil, errors.New("kms signature is out of range")
+	}
+	s := parsed.S
+	if s.Cmp(secp256k1HalfN) > 0 {
+		s = new(big.Int).Sub(crypto.S256().Params().N, s)
+	}
+	sig := make([]byte, crypto.SignatureLength)
+	parsed.R.FillBytes(sig[:32])
+	s.FillBytes(sig[32:64])
+	for v := byte(0); v < 2; v++ {
+		sig[64] = v
+		recovered, err := crypto.Ecrecover(hash, sig)
+		if err == nil && bytes.Equal(recovered, publicKey) {
+			return sig, nil
+		}
+	}
+	return nil, errors.New("kms signature doesn't recover to the key's public key")
+}
@@ -0,0 +1,169 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package kms
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidSecp256k1      = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+)
+
+// localBackend signs with a local key, encoding its results as a KMS would.
+type localBackend struct {
+	key      *ecdsa.PrivateKey
+	throttle int
+	highS    bool
+	signs    int
+}
+
+func (b *localBackend) publicKey(ctx context.Context) ([]byte, error) {
+	params, err := asn1.Marshal(oidSecp256k1)
+	if err != nil {
+		return nil, err
+	}
+	pubkey := crypto.FromECDSAPub(&b.key.PublicKey)
+	return asn1.Marshal(struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: params}},
+		PublicKey: asn1.BitString{Bytes: pubkey, BitLength: len(pubkey) * 8},
+	})
+}
+
+func (b *localBackend) sign(ctx context.Context, digest []byte) ([]byte, error) {
+	b.signs++
+	if b.throttle > 0 {
+		b.throttle--
+		return nil, &retryableError{errors.New("throttled")}
+	}
+	sig, err := crypto.Sign(digest, b.key)
+	if err != nil {
+		return nil, err
+	}
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:64])
+	if b.highS {
+		// KMSes don't normalize s, so either half of the curve order can come back
+		s.Sub(crypto.S256().Params().N, s)
+	}
+	return asn1.Marshal(struct{ R, S *big.Int }{r, s})
+}
+
+func testSigner(t *testing.T, b *localBackend) *Signer {
+	t.Helper()
+	config := DefaultConfig
+	config.RetryBackoff = time.Millisecond
+	signer, err := newSigner(context.Background(), &config, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer.Address() != crypto.PubkeyToAddress(b.key.PublicKey) {
+		t.Fatalf("signer address %v, expected %v", signer.Address(), crypto.PubkeyToAddress(b.key.PublicKey))
+	}
+	return signer
+}
+
+func TestSignHash(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, highS := range []bool{false, true} {
+		signer := testSigner(t, &localBackend{key: key, highS: highS})
+		for i := 0; i < 8; i++ {
+			hash := make([]byte, 32)
+			if _, err := rand.Read(hash); err != nil {
+				t.Fatal(err)
+			}
+			sig, err := signer.DataSigner()(hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !crypto.ValidateSignatureValues(sig[64], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64]), true) {
+				t.Fatal("signature isn't in Ethereum's canonical form")
+			}
+			recovered, err := crypto.SigToPub(hash, sig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if crypto.PubkeyToAddress(*recovered) != signer.Address() {
+				t.Fatalf("signature recovers to %v, expected %v", crypto.PubkeyToAddress(*recovered), signer.Address())
+			}
+		}
+	}
+}
+
+func TestSignHashRetries(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := &localBackend{key: key, throttle: DefaultConfig.MaxRetries}
+	signer := testSigner(t, backend)
+	if _, err := signer.SignHash(context.Background(), make([]byte, 32)); err != nil {
+		t.Fatal(err)
+	}
+	if backend.signs != DefaultConfig.MaxRetries+1 {
+		t.Fatalf("expected %v attempts, got %v", DefaultConfig.MaxRetries+1, backend.signs)
+	}
+
+	backend.signs = 0
+	backend.throttle = DefaultConfig.MaxRetries + 1
+	if _, err := signer.SignHash(context.Background(), make([]byte, 32)); err == nil {
+		t.Fatal("expected an error once retries ran out")
+	}
+	if backend.signs != DefaultConfig.MaxRetries+1 {
+		t.Fatalf("expected %v attempts, got %v", DefaultConfig.MaxRetries+1, backend.signs)
+	}
+}
+
+func TestToEthereumSignatureRejectsOtherKeys(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := make([]byte, 32)
+	der, err := (&localBackend{key: other}).sign(context.Background(), hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := toEthereumSignature(hash, der, crypto.FromECDSAPub(&key.PublicKey)); err == nil {
+		t.Fatal("expected a signature by another key to be rejected")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	config := DefaultConfig
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	config.Provider = "azure"
+	config.KeyID = "key"
+	if err := config.Validate(); err == nil {
+		t.Fatal("expected an unknown provider to be rejected")
+	}
+	config.Provider = ProviderGCP
+	config.KeyID = ""
+	if err := config.Validate(); err == nil {
+		t.Fatal("expected a missing key id to be rejected")
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+// Package msgcompat records message streams together with the blocks the current build produces
+// from them, and replays those recordings to catch accidental consensus changes between builds.
+package msgcompat
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+)
+
+// FixtureVersion is the version of the fixture format written by this build.
+// Older builds must still be able to read fixtures written by newer ones, so fields may only be added.
+const FixtureVersion = 1
+
+// A Fixture is a message stream executed on top of a freshly initialized chain.
+// Results holds the blocks produced by the build named in RecordedBy, one per message.
+type Fixture struct {
+	Version          uint64                           `json:"version"`
+	Description      string                           `json:"description,omitempty"`
+	RecordedBy       string                           `json:"recordedBy,omitempty"`
+	ChainConfig      *params.ChainConfig              `json:"chainConfig"`
+	InitialL1BaseFee *big.Int                         `json:"initialL1BaseFee"`
+	Messages         []arbostypes.MessageWithMetadata `json:"messages"`
+	Results          []Result                         `json:"results,omitempty"`
+}
+
+type Result struct {
+	BlockHash common.Hash `json:"blockHash"`
+	StateRoot common.Hash `json:"stateRoot"`
+	SendRoot  common.Hash `json:"sendRoot"`
+}
+
+func (f *Fixture) Validate() error {
+	if f.Version == 0 || f.Version > FixtureVersion {
+		return fmt.Errorf("unsupported fixture version %v (this build supports up to %v)", f.Version, FixtureVersion)
+	}
+	if f.ChainConfig == nil || f.ChainConfig.ChainID == nil {
+		return fmt.Errorf("fixture is missing its chain config")
+	}
+	if f.InitialL1BaseFee == nil {
+		return fmt.Errorf("fixture is missing its initial L1 base fee")
+	}
+	for i, msg := range f.Messages {
+		if msg.Message == nil || msg.Message.Header == nil {
+			return fmt.Errorf("fixture message %v is missing its header", i)
+		}
+	}
+	if len(f.Results) != 0 && len(f.Results) != len(f.Messages) {
+		return fmt.Errorf("fixture has %v results for %v messages", len(f.Results), len(f.Messages))
+	}
+	return nil
+}
+
+// ReadFixture reads a fixture from a json file, which is gzipped if its name ends in ".gz".
+func ReadFixture(path string) (*Fixture, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var reader io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress fixture %v: %w", path, err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+	var fixture Fixture
+	if err := json.NewDecoder(reader).Decode(&fixture); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture %v: %w", path, err)
+	}
+	if err := fixture.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid fixture %v: %w", path, err)
+	}
+	return &fixture, nil
+}
+
+// WriteFixture writes a fixture as json, gzipped if the file name ends in ".gz".
+func WriteFixture(path string, fixture *Fixture) (err error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	var writer io.Writer = file
+	if strings.HasSuffix(path, ".gz") {
+		gzipWriter := gzip.NewWriter(file)
+		defer func() {
+			if closeErr := gzipWriter.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		writer = gzipWriter
+	}
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(fixture)
+}
+
+// FixturePaths lists the fixtures in a corpus directory in a stable order.
+func FixturePaths(dir string) ([]string, error) {
+	var paths []string
+	for _, pattern := range []string{"*.json", "*.json.gz"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
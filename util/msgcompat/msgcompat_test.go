@@ -0,0 +1,148 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package msgcompat
+
+import (
+	"errors"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/offchainlabs/nitro/arbos"
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/arbos/l1pricing"
+	"github.com/offchainlabs/nitro/arbos/l2pricing"
+	"github.com/offchainlabs/nitro/util/testhelpers"
+)
+
+// corpusDir holds captured traffic that every build must replay to the recorded blocks.
+const corpusDir = "testdata"
+
+// testKey signs the generated fixtures' transactions, so they're the same on every run.
+const testKey = "b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291"
+
+// initCode deploys a contract returning 42.
+var initCode = common.FromHex("600a600c600039600a6000f3602a60005260206000f3")
+
+func testMessage(kind uint8, blockNumber uint64, requestId *common.Hash, l2msg []byte) arbostypes.MessageWithMetadata {
+	poster := l1pricing.BatchPosterAddress
+	if requestId != nil {
+		poster = common.Address{2}
+	}
+	return arbostypes.MessageWithMetadata{
+		Message: &arbostypes.L1IncomingMessage{
+			Header: &arbostypes.L1IncomingMessageHeader{
+				Kind:        kind,
+				Poster:      poster,
+				BlockNumber: blockNumber,
+				Timestamp:   blockNumber,
+				RequestId:   requestId,
+				L1BaseFee:   big.NewInt(0),
+			},
+			L2msg: l2msg,
+		},
+		DelayedMessagesRead: 1,
+	}
+}
+
+// testFixture is a deposit followed by transfers, a contract deployment and a call to it, all signed with testKey.
+func testFixture(t *testing.T) *Fixture {
+	t.Helper()
+	chainConfig := params.ArbitrumDevTestChainConfig()
+	key, err := crypto.HexToECDSA(testKey)
+	Require(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+
+	requestId := common.Hash{1}
+	deposit := append(sender.Bytes(), common.BigToHash(big.NewInt(params.Ether)).Bytes()...)
+	messages := []arbostypes.MessageWithMetadata{testMessage(arbostypes.L1MessageType_EthDeposit, 1, &requestId, deposit)}
+	signer := types.LatestSignerForChainID(chainConfig.ChainID)
+	contract := crypto.CreateAddress(sender, 3)
+	txs := []*types.DynamicFeeTx{
+		{To: &common.Address{3}, Value: big.NewInt(1)},
+		{To: &common.Address{3}, Value: big.NewInt(2)},
+		{To: &common.Address{4}, Value: big.NewInt(3)},
+		{Data: initCode},
+		{To: &contract},
+	}
+	for nonce, tx := range txs {
+		tx.ChainID = chainConfig.ChainID
+		tx.Nonce = uint64(nonce)
+		tx.GasTipCap = common.Big0
+		tx.GasFeeCap = big.NewInt(l2pricing.InitialBaseFeeWei * 2)
+		tx.Gas = 1000000
+		txBytes, err := types.MustSignNewTx(key, signer, tx).MarshalBinary()
+		Require(t, err)
+		l2msg := append([]byte{arbos.L2MessageKind_SignedTx}, txBytes...)
+		messages = append(messages, testMessage(arbostypes.L1MessageType_L2Message, 2+uint64(nonce), nil, l2msg))
+	}
+	return &Fixture{
+		Version:          FixtureVersion,
+		Description:      "generated by the msgcompat tests",
+		ChainConfig:      chainConfig,
+		InitialL1BaseFee: arbostypes.DefaultInitialL1BaseFee,
+		Messages:         messages,
+	}
+}
+
+func TestRecordAndVerify(t *testing.T) {
+	fixture := testFixture(t)
+	Require(t, Record(fixture, "test"))
+	if len(fixture.Results) != len(fixture.Messages) {
+		Fail(t, "expected a result per message, got", len(fixture.Results))
+	}
+
+	path := filepath.Join(t.TempDir(), "fixture.json.gz")
+	Require(t, WriteFixture(path, fixture))
+	readBack, err := ReadFixture(path)
+	Require(t, err)
+	Require(t, Verify(readBack))
+
+	readBack.Results[2].StateRoot = common.Hash{0xff}
+	var mismatch *MismatchError
+	if err := Verify(readBack); !errors.As(err, &mismatch) || mismatch.Index != 2 {
+		Fail(t, "expected a mismatch at message 2, got", err)
+	}
+
+	readBack.Version = FixtureVersion + 1
+	if _, err := Execute(readBack, nil); err == nil {
+		Fail(t, "expected a fixture from an unknown format version to be rejected")
+	}
+}
+
+// TestFixtureCorpus replays the captured fixtures, and a generated one recorded with this build and read
+// back from disk, so the replay is checked to be deterministic even without captured traffic.
+func TestFixtureCorpus(t *testing.T) {
+	paths, err := FixturePaths(corpusDir)
+	Require(t, err)
+	generated := testFixture(t)
+	Require(t, Record(generated, "test"))
+	generatedPath := filepath.Join(t.TempDir(), "generated.json")
+	Require(t, WriteFixture(generatedPath, generated))
+	paths = append(paths, generatedPath)
+	for _, path := range paths {
+		path := path
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			t.Parallel()
+			fixture, err := ReadFixture(path)
+			Require(t, err)
+			Require(t, Verify(fixture))
+		})
+	}
+}
+
+func Require(t *testing.T, err error, printables ...interface{}) {
+	t.Helper()
+	testhelpers.RequireImpl(t, err, printables...)
+}
+
+func Fail(t *testing.T, printables ...interface{}) {
+	t.Helper()
+	testhelpers.FailImpl(t, printables...)
+}
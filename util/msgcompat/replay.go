@@ -0,0 +1,155 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package msgcompat
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/offchainlabs/nitro/arbos"
+	"github.com/offchainlabs/nitro/arbos/arbosState"
+	"github.com/offchainlabs/nitro/arbos/arbostypes"
+	"github.com/offchainlabs/nitro/statetransfer"
+)
+
+// A MismatchError reports the first message whose block differs from the recorded one.
+type MismatchError struct {
+	Index    int
+	Expected Result
+	Got      Result
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf(
+		"message %v produced block %v (state root %v, send root %v) but the fixture recorded block %v (state root %v, send root %v)",
+		e.Index, e.Got.BlockHash, e.Got.StateRoot, e.Got.SendRoot, e.Expected.BlockHash, e.Expected.StateRoot, e.Expected.SendRoot,
+	)
+}
+
+// headerChain lets blocks look up the headers produced earlier in the replay, e.g. for BLOCKHASH.
+type headerChain struct {
+	headers map[common.Hash]*types.Header
+}
+
+func (c *headerChain) Engine() consensus.Engine {
+	return nil
+}
+
+func (c *headerChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	header := c.headers[hash]
+	if header == nil || header.Number.Uint64() != number {
+		return nil
+	}
+	return header
+}
+
+// Execute initializes a fresh chain from the fixture's chain config and produces one block per message,
+// the same way a node does. If a callback is given, it's called after each block and may stop the
+// replay early by returning an error.
+func Execute(fixture *Fixture, onBlock func(index int, result Result) error) ([]Result, error) {
+	if err := fixture.Validate(); err != nil {
+		return nil, err
+	}
+	chainConfig := fixture.ChainConfig
+	serializedChainConfig, err := json.Marshal(chainConfig)
+	if err != nil {
+		return nil, err
+	}
+	initMessage := &arbostypes.ParsedInitMessage{
+		ChainId:               chainConfig.ChainID,
+		InitialL1BaseFee:      fixture.InitialL1BaseFee,
+		ChainConfig:           chainConfig,
+		SerializedChainConfig: serializedChainConfig,
+	}
+	chainDb := rawdb.NewMemoryDatabase()
+	defer chainDb.Close()
+	cacheConfig := core.DefaultCacheConfigWithScheme(rawdb.HashScheme)
+	stateRoot, err := arbosState.InitializeArbosInDatabase(
+		chainDb,
+		cacheConfig,
+		statetransfer.NewMemoryInitDataReader(&statetransfer.ArbosInitializationInfo{}),
+		chainConfig,
+		initMessage,
+		0,
+		0,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ArbOS: %w", err)
+	}
+	stateDatabase := state.NewDatabaseWithConfig(chainDb, cacheConfig.TriedbConfig())
+	defer stateDatabase.TrieDB().Close()
+
+	genesis := arbosState.MakeGenesisBlock(common.Hash{}, chainConfig.ArbitrumChainParams.GenesisBlockNum, 0, stateRoot, chainConfig)
+	lastHeader := genesis.Header()
+	chain := &headerChain{headers: map[common.Hash]*types.Header{lastHeader.Hash(): lastHeader}}
+
+	results := make([]Result, 0, len(fixture.Messages))
+	for i := range fixture.Messages {
+		statedb, err := state.New(lastHeader.Root, stateDatabase, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open state for message %v: %w", i, err)
+		}
+		msg := fixture.Messages[i]
+		block, _, err := arbos.ProduceBlock(msg.Message, msg.DelayedMessagesRead, lastHeader, statedb, chain, chainConfig, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to produce block for message %v: %w", i, err)
+		}
+		root, err := statedb.Commit(block.NumberU64(), true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to commit state for message %v: %w", i, err)
+		}
+		if root != block.Root() {
+			return nil, fmt.Errorf("message %v committed state root %v but its block has root %v", i, root, block.Root())
+		}
+		lastHeader = block.Header()
+		chain.headers[block.Hash()] = lastHeader
+		result := Result{
+			BlockHash: block.Hash(),
+			StateRoot: block.Root(),
+			SendRoot:  types.DeserializeHeaderExtraInformation(lastHeader).SendRoot,
+		}
+		results = append(results, result)
+		if onBlock != nil {
+			if err := onBlock(i, result); err != nil {
+				return results, err
+			}
+		}
+	}
+	return results, nil
+}
+
+// Record executes the fixture's messages with this build and stores the resulting blocks in it.
+func Record(fixture *Fixture, recordedBy string) error {
+	fixture.Version = FixtureVersion
+	fixture.Results = nil
+	results, err := Execute(fixture, nil)
+	if err != nil {
+		return err
+	}
+	fixture.Results = results
+	fixture.RecordedBy = recordedBy
+	return nil
+}
+
+// Verify executes the fixture's messages with this build and checks they produce the recorded blocks.
+// It stops at the first mismatch, which is returned as a *MismatchError.
+func Verify(fixture *Fixture) error {
+	if len(fixture.Results) != len(fixture.Messages) {
+		return fmt.Errorf("fixture has %v recorded results for %v messages", len(fixture.Results), len(fixture.Messages))
+	}
+	_, err := Execute(fixture, func(index int, result Result) error {
+		if result != fixture.Results[index] {
+			return &MismatchError{Index: index, Expected: fixture.Results[index], Got: result}
+		}
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,179 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+// Package profiling summarizes pprof captures by the component label that
+// stopwaiter sets on the threads it launches.
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/pprof/profile"
+
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+const MaxCaptureDuration = time.Minute
+
+// UnlabeledComponent groups samples from goroutines not launched by a StopWaiter.
+const UnlabeledComponent = "other"
+
+// only one CPU profile can run at a time
+var captureMutex sync.Mutex
+
+type ComponentUsage struct {
+	Component  string  `json:"component"`
+	CPUTime    string  `json:"cpuTime"`
+	CPUPercent float64 `json:"cpuPercent"`
+	Goroutines int64   `json:"goroutines"`
+}
+
+type MemorySummary struct {
+	HeapAllocBytes uint64 `json:"heapAllocBytes"`
+	HeapInuseBytes uint64 `json:"heapInuseBytes"`
+	SysBytes       uint64 `json:"sysBytes"`
+	NumGC          uint32 `json:"numGC"`
+	GCPauseTotal   string `json:"gcPauseTotal"`
+}
+
+// ComponentProfile is the aggregated result of a short capture. Heap profiles
+// don't carry goroutine labels, so memory is only reported for the whole process.
+type ComponentProfile struct {
+	Duration     string           `json:"duration"`
+	TotalCPUTime string           `json:"totalCpuTime"`
+	Components   []ComponentUsage `json:"components"`
+	Memory       MemorySummary    `json:"memory"`
+}
+
+func componentOf(sample *profile.Sample) string {
+	if values := sample.Label[stopwaiter.ComponentLabel]; len(values) > 0 {
+		return values[0]
+	}
+	return UnlabeledComponent
+}
+
+func sumByComponent(prof *profile.Profile, valueIndex int) map[string]int64 {
+	sums := make(map[string]int64)
+	for _, sample := range prof.Sample {
+		if valueIndex < len(sample.Value) {
+			sums[componentOf(sample)] += sample.Value[valueIndex]
+		}
+	}
+	return sums
+}
+
+func readMemorySummary() MemorySummary {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return MemorySummary{
+		HeapAllocBytes: stats.HeapAlloc,
+		HeapInuseBytes: stats.HeapInuse,
+		SysBytes:       stats.Sys,
+		NumGC:          stats.NumGC,
+		GCPauseTotal:   time.Duration(stats.PauseTotalNs).String(),
+	}
+}
+
+func captureGoroutines() (map[string]int64, error) {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 0); err != nil {
+		return nil, err
+	}
+	prof, err := profile.Parse(&buf)
+	if err != nil {
+		return nil, err
+	}
+	return sumByComponent(prof, 0), nil
+}
+
+// CaptureComponentProfile records a CPU profile for the given duration and
+// returns CPU time and goroutine counts per component, largest CPU user first.
+func CaptureComponentProfile(ctx context.Context, duration time.Duration) (*ComponentProfile, error) {
+	if duration <= 0 || duration > MaxCaptureDuration {
+		return nil, fmt.Errorf("capture duration must be positive and at most %v", MaxCaptureDuration)
+	}
+	if !captureMutex.TryLock() {
+		return nil, errors.New("another component profile capture is in progress")
+	}
+	defer captureMutex.Unlock()
+
+	var cpuBuf bytes.Buffer
+	if err := pprof.StartCPUProfile(&cpuBuf); err != nil {
+		return nil, fmt.Errorf("failed to start cpu profile: %w", err)
+	}
+	start := time.Now()
+	timer := time.NewTimer(duration)
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		timer.Stop()
+	}
+	pprof.StopCPUProfile()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	elapsed := time.Since(start)
+
+	cpuProf, err := profile.Parse(&cpuBuf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cpu profile: %w", err)
+	}
+	// CPU profiles record samples/count and cpu/nanoseconds
+	cpuIndex := len(cpuProf.SampleType) - 1
+	cpuByComponent := sumByComponent(cpuProf, cpuIndex)
+	goroutinesByComponent, err := captureGoroutines()
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture goroutine profile: %w", err)
+	}
+
+	var totalCPU int64
+	for _, nanos := range cpuByComponent {
+		totalCPU += nanos
+	}
+	components := make(map[string]*ComponentUsage)
+	usageFor := func(name string) *ComponentUsage {
+		usage, ok := components[name]
+		if !ok {
+			usage = &ComponentUsage{Component: name}
+			components[name] = usage
+		}
+		return usage
+	}
+	for name, nanos := range cpuByComponent {
+		usage := usageFor(name)
+		usage.CPUTime = time.Duration(nanos).String()
+		if totalCPU > 0 {
+			usage.CPUPercent = float64(nanos) * 100 / float64(totalCPU)
+		}
+	}
+	for name, count := range goroutinesByComponent {
+		usageFor(name).Goroutines = count
+	}
+	result := &ComponentProfile{
+		Duration:     elapsed.String(),
+		TotalCPUTime: time.Duration(totalCPU).String(),
+		Memory:       readMemorySummary(),
+	}
+	for _, usage := range components {
+		if usage.CPUTime == "" {
+			usage.CPUTime = time.Duration(0).String()
+		}
+		result.Components = append(result.Components, *usage)
+	}
+	sort.Slice(result.Components, func(i, j int) bool {
+		a, b := result.Components[i], result.Components[j]
+		if a.CPUPercent != b.CPUPercent {
+			return a.CPUPercent > b.CPUPercent
+		}
+		return a.Component < b.Component
+	})
+	return result, nil
+}
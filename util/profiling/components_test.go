@@ -0,0 +1,60 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package profiling
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+func TestSumByComponent(t *testing.T) {
+	prof := &profile.Profile{
+		Sample: []*profile.Sample{
+			{Value: []int64{1, 100}, Label: map[string][]string{stopwaiter.ComponentLabel: {"arbnode.BatchPoster"}}},
+			{Value: []int64{2, 200}, Label: map[string][]string{stopwaiter.ComponentLabel: {"arbnode.BatchPoster"}}},
+			{Value: []int64{3, 300}},
+		},
+	}
+	sums := sumByComponent(prof, 1)
+	if sums["arbnode.BatchPoster"] != 300 || sums[UnlabeledComponent] != 300 || len(sums) != 2 {
+		t.Errorf("unexpected sums %v", sums)
+	}
+}
+
+type busyComponent struct {
+	stopwaiter.StopWaiter
+}
+
+func TestCaptureComponentProfile(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	busy := &busyComponent{}
+	busy.Start(ctx, busy)
+	defer busy.StopAndWait()
+	busy.LaunchThread(func(ctx context.Context) {
+		for ctx.Err() == nil {
+		}
+	})
+	result, err := CaptureComponentProfile(ctx, 500*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, usage := range result.Components {
+		if usage.Component == "profiling.busyComponent" {
+			found = usage.Goroutines > 0
+		}
+	}
+	if !found {
+		t.Errorf("busy component missing from profile %+v", result.Components)
+	}
+	if _, err := CaptureComponentProfile(ctx, 2*MaxCaptureDuration); err == nil {
+		t.Error("expected overly long capture to be rejected")
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package redisutil
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/offchainlabs/nitro/util/signature"
+)
+
+// Encrypted payloads are laid out as the version byte, the key id, the nonce, and the sealed payload.
+// Plaintext payloads are JSON or INVALID_VAL, so they never start with the version byte.
+const payloadEncryptionVersion byte = 1
+const payloadKeyIdLength = 4
+
+type PayloadEncryptionConfig struct {
+	Key                    string   `koanf:"key"`
+	FallbackDecryptionKeys []string `koanf:"fallback-decryption-keys"`
+	RequireEncryption      bool     `koanf:"require-encryption"`
+}
+
+func PayloadEncryptionConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.String(prefix+".key", DefaultPayloadEncryptionConfig.Key, "a 32-byte (64-character) hex string used to encrypt message payloads stored in Redis, or a path to a file containing it (e.g. as written by a KMS agent); if empty, payloads are stored unencrypted")
+	f.StringSlice(prefix+".fallback-decryption-keys", DefaultPayloadEncryptionConfig.FallbackDecryptionKeys, "previous encryption keys still accepted for decrypting payloads, for key rotation")
+	f.Bool(prefix+".require-encryption", DefaultPayloadEncryptionConfig.RequireEncryption, "reject unencrypted payloads (other than invalidated messages), once all sequencers encrypt them")
+}
+
+var DefaultPayloadEncryptionConfig = PayloadEncryptionConfig{
+	Key:                    "",
+	FallbackDecryptionKeys: []string{},
+	RequireEncryption:      false,
+}
+
+type payloadKey struct {
+	id   [payloadKeyIdLength]byte
+	aead cipher.AEAD
+}
+
+func newPayloadKey(key *common.Hash) (*payloadKey, error) {
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// the id identifies the key a payload was encrypted with, without revealing the key
+	var id [payloadKeyIdLength]byte
+	copy(id[:], crypto.Keccak256([]byte("nitro redis payload key id"), key[:]))
+	return &payloadKey{id: id, aead: aead}, nil
+}
+
+// PayloadEncryptor encrypts the payloads sequencers share through Redis, so the contents of
+// soon to be published messages don't leak from a compromised Redis instance.
+type PayloadEncryptor struct {
+	// nil if payloads are written unencrypted
+	key     *payloadKey
+	keys    map[[payloadKeyIdLength]byte]*payloadKey
+	require bool
+}
+
+func NewPayloadEncryptor(config *PayloadEncryptionConfig) (*PayloadEncryptor, error) {
+	e := &PayloadEncryptor{
+		keys:    make(map[[payloadKeyIdLength]byte]*payloadKey),
+		require: config.RequireEncryption,
+	}
+	keyHash, err := signature.LoadSigningKey(config.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load payload encryption key: %w", err)
+	}
+	if keyHash != nil {
+		e.key, err = newPayloadKey(keyHash)
+		if err != nil {
+			return nil, err
+		}
+		e.keys[e.key.id] = e.key
+	}
+	for _, fallback := range config.FallbackDecryptionKeys {
+		keyHash, err := signature.LoadSigningKey(fallback)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load payload decryption key: %w", err)
+		}
+		if keyHash == nil {
+			continue
+		}
+		key, err := newPayloadKey(keyHash)
+		if err != nil {
+			return nil, err
+		}
+		e.keys[key.id] = key
+	}
+	if e.key == nil && len(e.keys) > 0 {
+		return nil, errors.New("cannot have fallback-decryption-keys without an encryption key")
+	}
+	if e.key == nil && e.require {
+		return nil, errors.New("payload encryption is required but no key is present")
+	}
+	return e, nil
+}
+
+// Encrypt seals the payload, binding it to the associated data (e.g. the message index), so it can't be
+// replayed elsewhere. Without an encryption key, it returns the payload unchanged.
+func (e *PayloadEncryptor) Encrypt(payload []byte, associatedData []byte) ([]byte, error) {
+	if e.key == nil {
+		return payload, nil
+	}
+	nonceSize := e.key.aead.NonceSize()
+	out := make([]byte, 1+payloadKeyIdLength+nonceSize, 1+payloadKeyIdLength+nonceSize+len(payload)+e.key.aead.Overhead())
+	out[0] = payloadEncryptionVersion
+	copy(out[1:], e.key.id[:])
+	nonce := out[1+payloadKeyIdLength:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return e.key.aead.Seal(out, nonce, payload, associatedData), nil
+}
+
+// Decrypt opens a payload sealed with the encryption key or one of the fallback keys.
+// Unencrypted payloads are returned as is, unless encryption is required.
+func (e *PayloadEncryptor) Decrypt(data []byte, associatedData []byte) ([]byte, error) {
+	if len(data) == 0 || data[0] != payloadEncryptionVersion {
+		if e.require && string(data) != INVALID_VAL {
+			return nil, errors.New("payload isn't encrypted, but encryption is required")
+		}
+		return data, nil
+	}
+	if len(data) < 1+payloadKeyIdLength {
+		return nil, errors.New("encrypted payload too short")
+	}
+	var id [payloadKeyIdLength]byte
+	copy(id[:], data[1:])
+	key, ok := e.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("payload encrypted with unknown key %x", id)
+	}
+	nonceSize := key.aead.NonceSize()
+	if len(data) < 1+payloadKeyIdLength+nonceSize {
+		return nil, errors.New("encrypted payload too short")
+	}
+	nonce := data[1+payloadKeyIdLength : 1+payloadKeyIdLength+nonceSize]
+	return key.aead.Open(nil, nonce, data[1+payloadKeyIdLength+nonceSize:], associatedData)
+}
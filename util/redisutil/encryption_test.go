@@ -0,0 +1,77 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package redisutil
+
+import (
+	"bytes"
+	"testing"
+)
+
+const (
+	testOldKey = "0x6f6c646b65796f6c646b65796f6c646b65796f6c646b65796f6c646b65796f6c"
+	testNewKey = "0x6e65776b65796e65776b65796e65776b65796e65776b65796e65776b65796e65"
+)
+
+func TestPayloadEncryptionRotation(t *testing.T) {
+	plaintext, err := NewPayloadEncryptor(&DefaultPayloadEncryptionConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldWriter, err := NewPayloadEncryptor(&PayloadEncryptionConfig{Key: testOldKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := NewPayloadEncryptor(&PayloadEncryptionConfig{Key: testNewKey, FallbackDecryptionKeys: []string{testOldKey}, RequireEncryption: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := []byte(`{"message":{}}`)
+	index := []byte{0, 0, 0, 0, 0, 0, 0, 7}
+
+	unencrypted, err := plaintext.Encrypt(payload, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(unencrypted, payload) {
+		t.Fatal("payload encrypted without a key")
+	}
+	// payloads written before encryption was enabled can still be read
+	decoded, err := oldWriter.Decrypt(unencrypted, index)
+	if err != nil || !bytes.Equal(decoded, payload) {
+		t.Fatal("failed to read an unencrypted payload", err)
+	}
+	if _, err := rotated.Decrypt(unencrypted, index); err == nil {
+		t.Error("read an unencrypted payload although encryption is required")
+	}
+	if _, err := rotated.Decrypt([]byte(INVALID_VAL), index); err != nil {
+		t.Error("failed to read an invalidated message", err)
+	}
+
+	encrypted, err := oldWriter.Encrypt(payload, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(encrypted, payload) {
+		t.Fatal("encrypted payload contains the plaintext")
+	}
+	decoded, err = rotated.Decrypt(encrypted, index)
+	if err != nil || !bytes.Equal(decoded, payload) {
+		t.Fatal("failed to read a payload encrypted with the previous key", err)
+	}
+	if _, err := rotated.Decrypt(encrypted, []byte{0, 0, 0, 0, 0, 0, 0, 8}); err == nil {
+		t.Error("read a payload moved to another message index")
+	}
+
+	encrypted, err = rotated.Encrypt(payload, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := oldWriter.Decrypt(encrypted, index); err == nil {
+		t.Error("read a payload encrypted with an unknown key")
+	}
+
+	if _, err := NewPayloadEncryptor(&PayloadEncryptionConfig{RequireEncryption: true}); err == nil {
+		t.Error("expected requiring encryption without a key to fail")
+	}
+}
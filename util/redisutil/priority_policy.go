@@ -0,0 +1,142 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package redisutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// PriorityPolicy refines the order of the priorities list when picking the sequencer to recommend.
+// It's stored as JSON under POLICY_KEY, so all sequencers apply the same policy, and changes take effect
+// on their next update. Sequencers not on the priorities list are never recommended.
+type PriorityPolicy struct {
+	// Base weights by sequencer URL; the highest total weight is preferred. Sequencers without a weight
+	// get one from their position on the priorities list, which is lower than any configured weight.
+	Weights map[string]int64 `json:"weights,omitempty"`
+	// Time windows during which sequencers get extra weight, e.g. to follow the sun.
+	Windows []PriorityWindow `json:"windows,omitempty"`
+	// Extra weight for the currently chosen sequencer, so the lockout is only handed over to
+	// a sequencer preferred by more than this.
+	Stickiness int64 `json:"stickiness,omitempty"`
+}
+
+// PriorityWindow adds Bonus to the weight of the sequencer at Url between Start and End (HH:MM, in
+// the Timezone) on the given Days (0 is Sunday, all days if empty). An End before Start wraps past midnight.
+type PriorityWindow struct {
+	Url      string         `json:"url"`
+	Days     []time.Weekday `json:"days,omitempty"`
+	Start    string         `json:"start"`
+	End      string         `json:"end"`
+	Timezone string         `json:"timezone,omitempty"`
+	Bonus    int64          `json:"bonus"`
+
+	location *time.Location
+	start    time.Duration
+	end      time.Duration
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q: %w", s, err)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// ParsePriorityPolicy parses and validates a policy stored under POLICY_KEY.
+func ParsePriorityPolicy(data []byte) (*PriorityPolicy, error) {
+	var policy PriorityPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, err
+	}
+	for i := range policy.Windows {
+		window := &policy.Windows[i]
+		var err error
+		window.location, err = time.LoadLocation(window.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("window %v: %w", i, err)
+		}
+		if window.start, err = parseTimeOfDay(window.Start); err != nil {
+			return nil, fmt.Errorf("window %v: %w", i, err)
+		}
+		if window.end, err = parseTimeOfDay(window.End); err != nil {
+			return nil, fmt.Errorf("window %v: %w", i, err)
+		}
+		for _, day := range window.Days {
+			if day < time.Sunday || day > time.Saturday {
+				return nil, fmt.Errorf("window %v: invalid day %v", i, day)
+			}
+		}
+	}
+	return &policy, nil
+}
+
+func (w *PriorityWindow) active(now time.Time) bool {
+	local := now.In(w.location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, w.location)
+	sinceMidnight := local.Sub(midnight)
+	day := local.Weekday()
+	if w.end < w.start && sinceMidnight < w.end {
+		// in the part of a window that started the day before
+		day = (day + 6) % 7
+		sinceMidnight += 24 * time.Hour
+	}
+	if len(w.Days) > 0 {
+		found := false
+		for _, d := range w.Days {
+			if d == day {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	end := w.end
+	if end < w.start {
+		end += 24 * time.Hour
+	}
+	return sinceMidnight >= w.start && sinceMidnight < end
+}
+
+// Weight returns the sequencer's total weight at the given time.
+func (p *PriorityPolicy) Weight(url string, priorityIndex int, current string, now time.Time) int64 {
+	weight, ok := p.Weights[url]
+	if !ok {
+		// below any configured weight, preserving the order of the priorities list
+		var minWeight int64
+		for _, w := range p.Weights {
+			if w < minWeight {
+				minWeight = w
+			}
+		}
+		weight = minWeight - 1 - int64(priorityIndex)
+	}
+	for i := range p.Windows {
+		if p.Windows[i].Url == url && p.Windows[i].active(now) {
+			weight += p.Windows[i].Bonus
+		}
+	}
+	if url == current {
+		weight += p.Stickiness
+	}
+	return weight
+}
+
+// Rank orders the priorities list by the policy, with ties kept in list order.
+func (p *PriorityPolicy) Rank(priorities []string, current string, now time.Time) []string {
+	weights := make(map[string]int64, len(priorities))
+	for i, url := range priorities {
+		weights[url] = p.Weight(url, i, current, now)
+	}
+	ranked := append([]string{}, priorities...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return weights[ranked[i]] > weights[ranked[j]]
+	})
+	return ranked
+}
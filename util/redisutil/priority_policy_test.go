@@ -0,0 +1,58 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package redisutil
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPriorityPolicyRank(t *testing.T) {
+	priorities := []string{"us", "eu", "asia"}
+	policy, err := ParsePriorityPolicy([]byte(`{
+		"weights": {"eu": 10},
+		"windows": [
+			{"url": "asia", "days": [1, 2, 3, 4, 5], "start": "09:00", "end": "17:00", "timezone": "Asia/Tokyo", "bonus": 100},
+			{"url": "us", "start": "22:00", "end": "02:00", "timezone": "UTC", "bonus": 100}
+		],
+		"stickiness": 50
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(now time.Time, current string, expected string) {
+		t.Helper()
+		ranked := strings.Join(policy.Rank(priorities, current, now), ",")
+		if ranked != expected {
+			t.Errorf("at %v with %q chosen, ranked %v, expected %v", now, current, ranked, expected)
+		}
+	}
+
+	// Monday 12:00 UTC is outside every window, so the weighted sequencer comes first,
+	// and the rest keep their priority order
+	monday := time.Date(2024, time.March, 4, 12, 0, 0, 0, time.UTC)
+	check(monday, "", "eu,us,asia")
+	// Monday 14:00 in Tokyo
+	check(monday.Add(-7*time.Hour), "", "asia,eu,us")
+	// Saturday 14:00 in Tokyo
+	check(monday.Add(-7*time.Hour+5*24*time.Hour), "", "eu,us,asia")
+	// the window wrapping midnight is active before and after it, here overlapping with Tokyo's
+	check(monday.Add(11*time.Hour), "", "us,eu,asia")
+	check(monday.Add(13*time.Hour), "", "us,asia,eu")
+	check(monday.Add(15*time.Hour), "", "asia,eu,us")
+	// the chosen sequencer keeps the lockout unless another is preferred by more than the stickiness
+	check(monday, "us", "us,eu,asia")
+	check(monday.Add(-7*time.Hour), "eu", "asia,eu,us")
+
+	for _, invalid := range []string{
+		`{"windows": [{"url": "us", "start": "25:00", "end": "02:00"}]}`,
+		`{"windows": [{"url": "us", "start": "01:00", "end": "02:00", "timezone": "Mars/Olympus"}]}`,
+		`{"windows": [{"url": "us", "days": [7], "start": "01:00", "end": "02:00"}]}`,
+	} {
+		if _, err := ParsePriorityPolicy([]byte(invalid)); err == nil {
+			t.Errorf("accepted invalid policy %v", invalid)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 
@@ -15,7 +16,10 @@ import (
 
 const CHOSENSEQ_KEY string = "coordinator.chosen"                 // Never overwritten. Expires or released only
 const MSG_COUNT_KEY string = "coordinator.msgCount"               // Only written by sequencer holding CHOSEN key
+const SYNC_URL_KEY string = "coordinator.syncUrl"                 // Optional. Written by sequencer holding CHOSEN key, expires with it
 const PRIORITIES_KEY string = "coordinator.priorities"            // Read only
+const POLICY_KEY string = "coordinator.policy"                    // Read only. Optional PriorityPolicy JSON
+const HANDOFF_KEY string = "coordinator.handoff"                  // Optional. Sequencer handed off to, preferred over the priorities
 const WANTS_LOCKOUT_KEY_PREFIX string = "coordinator.liveliness." // Per server. Only written by self
 const MESSAGE_KEY_PREFIX string = "coordinator.msg."              // Per Message. Only written by sequencer holding CHOSEN
 const SIGNATURE_KEY_PREFIX string = "coordinator.msg.sig."        // Per Message. Only written by sequencer holding CHOSEN
@@ -23,12 +27,43 @@ const WANTS_LOCKOUT_VAL string = "OK"
 const INVALID_VAL string = "INVALID"
 const INVALID_URL string = "<?INVALID-URL?>"
 
+// CLUSTER_HASH_TAG replaces the "coordinator" prefix of keys in a Redis Cluster, so they all hash
+// to the same slot, as transactions watching or writing several of them require.
+const CLUSTER_HASH_TAG string = "{coordinator}"
+
 type RedisCoordinator struct {
-	Client redis.UniversalClient
+	Client  redis.UniversalClient
+	cluster bool
 }
 
 func WantsLockoutKeyFor(url string) string { return WANTS_LOCKOUT_KEY_PREFIX + url }
 
+// ClusterKey returns the key as stored in a Redis Cluster.
+func ClusterKey(key string) string {
+	return CLUSTER_HASH_TAG + strings.TrimPrefix(key, "coordinator")
+}
+
+// KeyFor returns the key as stored through the client.
+func KeyFor(client redis.UniversalClient, key string) string {
+	if IsCluster(client) {
+		return ClusterKey(key)
+	}
+	return key
+}
+
+// Key returns the key as stored through the coordinator's client.
+func (c *RedisCoordinator) Key(key string) string {
+	if c.cluster {
+		return ClusterKey(key)
+	}
+	return key
+}
+
+// ServerIdentity returns the run id of the server holding the coordinator's keys.
+func (c *RedisCoordinator) ServerIdentity(ctx context.Context) (string, error) {
+	return ServerIdentity(ctx, c.Client, c.Key(CHOSENSEQ_KEY))
+}
+
 func NewRedisCoordinator(redisUrl string) (*RedisCoordinator, error) {
 	redisClient, err := RedisClientFromURL(redisUrl)
 	if err != nil {
@@ -36,13 +71,28 @@ func NewRedisCoordinator(redisUrl string) (*RedisCoordinator, error) {
 	}
 
 	return &RedisCoordinator{
-		Client: redisClient,
+		Client:  redisClient,
+		cluster: IsCluster(redisClient),
 	}, nil
 }
 
-// RecommendSequencerWantingLockout returns the top priority sequencer wanting the lockout
+// RecommendSequencerWantingLockout returns the sequencer the lockout was handed off to if it wants the lockout,
+// and otherwise the top priority sequencer wanting the lockout
 func (c *RedisCoordinator) RecommendSequencerWantingLockout(ctx context.Context) (string, error) {
-	prioritiesString, err := c.Client.Get(ctx, PRIORITIES_KEY).Result()
+	handoffTarget, err := c.HandoffTarget(ctx)
+	if err != nil {
+		return "", err
+	}
+	if handoffTarget != "" {
+		err := c.Client.Get(ctx, c.Key(WantsLockoutKeyFor(handoffTarget))).Err()
+		if err == nil {
+			return handoffTarget, nil
+		}
+		if !errors.Is(err, redis.Nil) {
+			return "", err
+		}
+	}
+	prioritiesString, err := c.Client.Get(ctx, c.Key(PRIORITIES_KEY)).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			err = errors.New("sequencer priorities unset")
@@ -50,8 +100,12 @@ func (c *RedisCoordinator) RecommendSequencerWantingLockout(ctx context.Context)
 		return "", err
 	}
 	priorities := strings.Split(prioritiesString, ",")
+	priorities, err = c.applyPriorityPolicy(ctx, priorities)
+	if err != nil {
+		return "", err
+	}
 	for _, url := range priorities {
-		err := c.Client.Get(ctx, WantsLockoutKeyFor(url)).Err()
+		err := c.Client.Get(ctx, c.Key(WantsLockoutKeyFor(url))).Err()
 		if errors.Is(err, redis.Nil) { // wants lockout not set
 			continue
 		}
@@ -64,9 +118,63 @@ func (c *RedisCoordinator) RecommendSequencerWantingLockout(ctx context.Context)
 	return "", nil
 }
 
+// HandoffTarget returns the sequencer the lockout was last handed off to, or "" if none was
+func (c *RedisCoordinator) HandoffTarget(ctx context.Context) (string, error) {
+	target, err := c.Client.Get(ctx, c.Key(HANDOFF_KEY)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return target, err
+}
+
+// SetHandoffTarget makes url the recommended sequencer whenever it wants the lockout, until the ttl passes,
+// another handoff, or until cleared by passing an empty url
+func (c *RedisCoordinator) SetHandoffTarget(ctx context.Context, url string, ttl time.Duration) error {
+	if url == "" {
+		return c.Client.Del(ctx, c.Key(HANDOFF_KEY)).Err()
+	}
+	return c.Client.Set(ctx, c.Key(HANDOFF_KEY), url, ttl).Err()
+}
+
+// GetPriorityPolicy returns the policy refining the priorities, or nil if there is none
+func (c *RedisCoordinator) GetPriorityPolicy(ctx context.Context) (*PriorityPolicy, error) {
+	policyString, err := c.Client.Get(ctx, c.Key(POLICY_KEY)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParsePriorityPolicy([]byte(policyString))
+}
+
+func (c *RedisCoordinator) applyPriorityPolicy(ctx context.Context, priorities []string) ([]string, error) {
+	policyString, err := c.Client.Get(ctx, c.Key(POLICY_KEY)).Result()
+	if errors.Is(err, redis.Nil) {
+		return priorities, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	policy, err := ParsePriorityPolicy([]byte(policyString))
+	if err != nil {
+		// a broken policy mustn't prevent failover, so fall back to the plain priorities
+		log.Error("invalid sequencer priority policy on redis, ignoring it", "err", err)
+		return priorities, nil
+	}
+	var current string
+	if policy.Stickiness != 0 {
+		current, err = c.CurrentChosenSequencer(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return policy.Rank(priorities, current, time.Now()), nil
+}
+
 // CurrentChosenSequencer retrieves the current chosen sequencer holding the lock
 func (c *RedisCoordinator) CurrentChosenSequencer(ctx context.Context) (string, error) {
-	current, err := c.Client.Get(ctx, CHOSENSEQ_KEY).Result()
+	current, err := c.Client.Get(ctx, c.Key(CHOSENSEQ_KEY)).Result()
 	if errors.Is(err, redis.Nil) {
 		return "", nil
 	}
@@ -76,9 +184,22 @@ func (c *RedisCoordinator) CurrentChosenSequencer(ctx context.Context) (string,
 	return current, nil
 }
 
+// CurrentChosenSyncUrl returns the url the chosen sequencer serves its messages at, if it streams them
+// to standbys rather than writing them to Redis.
+func (c *RedisCoordinator) CurrentChosenSyncUrl(ctx context.Context) (string, error) {
+	url, err := c.Client.Get(ctx, c.Key(SYNC_URL_KEY)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return url, nil
+}
+
 // GetPriorities returns the priority list of sequencers
 func (rc *RedisCoordinator) GetPriorities(ctx context.Context) ([]string, error) {
-	prioritiesString, err := rc.Client.Get(ctx, PRIORITIES_KEY).Result()
+	prioritiesString, err := rc.Client.Get(ctx, rc.Key(PRIORITIES_KEY)).Result()
 	if errors.Is(err, redis.Nil) {
 		return []string{}, nil
 	}
@@ -92,9 +213,19 @@ func (rc *RedisCoordinator) GetPriorities(ctx context.Context) ([]string, error)
 // GetLiveliness returns a list of sequencers that have their liveliness set to OK
 func (rc *RedisCoordinator) GetLiveliness(ctx context.Context) ([]string, error) {
 	var livelinessList []string
+	scanner := redis.Cmdable(rc.Client)
+	if cluster, ok := rc.Client.(*redis.ClusterClient); ok {
+		// all keys are on the master for their shared slot
+		master, err := cluster.MasterForKey(ctx, rc.Key(CHOSENSEQ_KEY))
+		if err != nil {
+			return []string{}, err
+		}
+		scanner = master
+	}
+	prefix := rc.Key(WANTS_LOCKOUT_KEY_PREFIX)
 	cursor := uint64(0)
 	for {
-		keySlice, cursor, err := rc.Client.Scan(ctx, cursor, WANTS_LOCKOUT_KEY_PREFIX+"*", 0).Result()
+		keySlice, cursor, err := scanner.Scan(ctx, cursor, prefix+"*", 0).Result()
 		if err != nil {
 			return []string{}, err
 		}
@@ -104,7 +235,7 @@ func (rc *RedisCoordinator) GetLiveliness(ctx context.Context) ([]string, error)
 		}
 	}
 	for i, elem := range livelinessList {
-		url := strings.TrimPrefix(elem, WANTS_LOCKOUT_KEY_PREFIX)
+		url := strings.TrimPrefix(elem, prefix)
 		livelinessList[i] = url
 	}
 	return livelinessList, nil
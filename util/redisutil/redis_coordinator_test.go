@@ -0,0 +1,74 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package redisutil
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRecommendHandoffTarget(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	coordinator, err := NewRedisCoordinator(CreateTestRedis(ctx, t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := coordinator.Client
+	if err := client.Set(ctx, PRIORITIES_KEY, "primary,standby1,standby2", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	for _, url := range []string{"primary", "standby1"} {
+		if err := client.Set(ctx, WantsLockoutKeyFor(url), WANTS_LOCKOUT_VAL, time.Minute).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(expected string) {
+		t.Helper()
+		recommended, err := coordinator.RecommendSequencerWantingLockout(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if recommended != expected {
+			t.Errorf("recommended %v, expected %v", recommended, expected)
+		}
+	}
+	check("primary")
+
+	if err := coordinator.SetHandoffTarget(ctx, "standby1", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	check("standby1")
+	// an interrupted handoff doesn't pin the lockout to its target for good
+	ttl, err := client.TTL(ctx, coordinator.Key(HANDOFF_KEY)).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl <= 0 || ttl > time.Minute {
+		t.Errorf("handoff target expires in %v, expected within a minute", ttl)
+	}
+
+	// a target not wanting the lockout falls back to the priorities
+	if err := coordinator.SetHandoffTarget(ctx, "standby2", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	check("primary")
+	if err := client.Set(ctx, WantsLockoutKeyFor("standby2"), WANTS_LOCKOUT_VAL, time.Minute).Err(); err != nil {
+		t.Fatal(err)
+	}
+	check("standby2")
+
+	if err := coordinator.SetHandoffTarget(ctx, "", 0); err != nil {
+		t.Fatal(err)
+	}
+	target, err := coordinator.HandoffTarget(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != "" {
+		t.Errorf("handoff target %v not cleared", target)
+	}
+	check("primary")
+}
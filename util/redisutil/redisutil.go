@@ -1,14 +1,177 @@
 package redisutil
 
-import "github.com/go-redis/redis/v8"
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
 
-func RedisClientFromURL(url string) (redis.UniversalClient, error) {
-	if url == "" {
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisClientFromURL creates a client for the url, or returns nil if it's empty.
+// Besides redis:// and rediss:// urls of a single server, it accepts
+//
+//	redis-sentinel://[[user]:password@]host:port[,host:port...]/master[?db=N&sentinel-password=P]
+//	redis-cluster://[[user]:password@]host:port[,host:port...]
+//
+// (or rediss-sentinel:// and rediss-cluster:// for TLS), which fail over to the new master when one fails.
+func RedisClientFromURL(redisUrl string) (redis.UniversalClient, error) {
+	if redisUrl == "" {
 		return nil, nil
 	}
-	redisOptions, err := redis.ParseURL(url)
+	if strings.HasPrefix(redisUrl, "redis-sentinel://") || strings.HasPrefix(redisUrl, "rediss-sentinel://") {
+		failoverOptions, err := parseSentinelURL(redisUrl)
+		if err != nil {
+			return nil, err
+		}
+		return redis.NewFailoverClient(failoverOptions), nil
+	}
+	if strings.HasPrefix(redisUrl, "redis-cluster://") || strings.HasPrefix(redisUrl, "rediss-cluster://") {
+		clusterOptions, err := parseClusterURL(redisUrl)
+		if err != nil {
+			return nil, err
+		}
+		return redis.NewClusterClient(clusterOptions), nil
+	}
+	redisOptions, err := redis.ParseURL(redisUrl)
 	if err != nil {
 		return nil, err
 	}
 	return redis.NewClient(redisOptions), nil
 }
+
+// topologyURL holds the parts sentinel and cluster urls have in common.
+type topologyURL struct {
+	addrs    []string
+	username string
+	password string
+	tls      *tls.Config
+	path     string
+	query    url.Values
+}
+
+func parseTopologyURL(redisUrl string) (*topologyURL, error) {
+	// url.Parse rejects lists of hosts unless the last has a port, so split them off first
+	scheme, rest, _ := strings.Cut(redisUrl, "://")
+	authority, pathAndQuery := rest, ""
+	if end := strings.IndexAny(rest, "/?"); end >= 0 {
+		authority, pathAndQuery = rest[:end], rest[end:]
+	}
+	var hosts string
+	if at := strings.LastIndex(authority, "@"); at >= 0 {
+		hosts = authority[at+1:]
+		authority = authority[:at+1] + "hosts"
+	} else {
+		hosts = authority
+		authority = "hosts"
+	}
+	u, err := url.Parse(scheme + "://" + authority + pathAndQuery)
+	if err != nil {
+		return nil, err
+	}
+	result := &topologyURL{
+		path:  strings.Trim(u.Path, "/"),
+		query: u.Query(),
+	}
+	for _, addr := range strings.Split(hosts, ",") {
+		if addr == "" {
+			continue
+		}
+		if !strings.Contains(addr, ":") {
+			addr += ":6379"
+		}
+		result.addrs = append(result.addrs, addr)
+	}
+	if len(result.addrs) == 0 {
+		return nil, fmt.Errorf("no redis addresses in %v url", scheme)
+	}
+	if u.User != nil {
+		result.username = u.User.Username()
+		result.password, _ = u.User.Password()
+	}
+	if strings.HasPrefix(u.Scheme, "rediss") {
+		result.tls = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			ServerName: strings.Split(result.addrs[0], ":")[0],
+		}
+	}
+	return result, nil
+}
+
+func parseSentinelURL(redisUrl string) (*redis.FailoverOptions, error) {
+	parsed, err := parseTopologyURL(redisUrl)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.path == "" || strings.Contains(parsed.path, "/") {
+		return nil, errors.New("redis sentinel url must name the master as its path")
+	}
+	options := &redis.FailoverOptions{
+		MasterName:       parsed.path,
+		SentinelAddrs:    parsed.addrs,
+		SentinelPassword: parsed.query.Get("sentinel-password"),
+		Username:         parsed.username,
+		Password:         parsed.password,
+		TLSConfig:        parsed.tls,
+	}
+	if db := parsed.query.Get("db"); db != "" {
+		options.DB, err = strconv.Atoi(db)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis database number %q: %w", db, err)
+		}
+	}
+	return options, nil
+}
+
+func parseClusterURL(redisUrl string) (*redis.ClusterOptions, error) {
+	parsed, err := parseTopologyURL(redisUrl)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.path != "" {
+		// cluster mode only has database 0
+		return nil, errors.New("redis cluster url can't select a database")
+	}
+	return &redis.ClusterOptions{
+		Addrs:     parsed.addrs,
+		Username:  parsed.username,
+		Password:  parsed.password,
+		TLSConfig: parsed.tls,
+	}, nil
+}
+
+// IsCluster returns whether the client is for a Redis Cluster, where keys used together must share a hash slot.
+func IsCluster(client redis.UniversalClient) bool {
+	_, ok := client.(*redis.ClusterClient)
+	return ok
+}
+
+// ServerIdentity returns the run id of the server holding key, which changes when the server
+// fails over to another or restarts, and so might have lost recent writes.
+func ServerIdentity(ctx context.Context, client redis.UniversalClient, key string) (string, error) {
+	var info string
+	var err error
+	if cluster, ok := client.(*redis.ClusterClient); ok {
+		var master *redis.Client
+		master, err = cluster.MasterForKey(ctx, key)
+		if err != nil {
+			return "", err
+		}
+		info, err = master.Info(ctx, "server").Result()
+	} else {
+		info, err = client.Info(ctx, "server").Result()
+	}
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(info, "\n") {
+		if runId, found := strings.CutPrefix(strings.TrimSpace(line), "run_id:"); found {
+			return runId, nil
+		}
+	}
+	return "", errors.New("redis server info has no run_id")
+}
@@ -0,0 +1,87 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package rpcclient
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var failoverCounter = metrics.NewRegisteredCounter("arb/rpcclient/failover", nil)
+
+// FailoverClient sends each request to one of several endpoints serving the same chain, sticking with the
+// current one until it fails to answer, and then failing over to the next. An endpoint answering with an error
+// is working, so only errors reaching it cause failover.
+type FailoverClient struct {
+	clients []rpc.ClientInterface
+	current atomic.Uint32
+}
+
+func NewFailoverClient(clients ...rpc.ClientInterface) *FailoverClient {
+	return &FailoverClient{clients: clients}
+}
+
+// Clients returns the endpoints in order of preference, as for checking state on each of them.
+func (c *FailoverClient) Clients() []rpc.ClientInterface {
+	return c.clients
+}
+
+func isEndpointError(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	var rpcErr rpc.Error
+	return !errors.As(err, &rpcErr)
+}
+
+func (c *FailoverClient) try(ctx context.Context, fn func(rpc.ClientInterface) error) error {
+	start := c.current.Load()
+	var err error
+	for i := 0; i < len(c.clients); i++ {
+		index := (start + uint32(i)) % uint32(len(c.clients))
+		err = fn(c.clients[index])
+		if !isEndpointError(ctx, err) {
+			if index != start && c.current.CompareAndSwap(start, index) {
+				failoverCounter.Inc(1)
+				log.Warn("failed over to another endpoint", "index", index, "previous", start)
+			}
+			return err
+		}
+		log.Debug("endpoint failed, trying the next one", "index", index, "err", err)
+	}
+	return err
+}
+
+func (c *FailoverClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return c.try(ctx, func(client rpc.ClientInterface) error {
+		return client.CallContext(ctx, result, method, args...)
+	})
+}
+
+func (c *FailoverClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	return c.try(ctx, func(client rpc.ClientInterface) error {
+		return client.BatchCallContext(ctx, b)
+	})
+}
+
+func (c *FailoverClient) EthSubscribe(ctx context.Context, channel interface{}, args ...interface{}) (*rpc.ClientSubscription, error) {
+	var sub *rpc.ClientSubscription
+	err := c.try(ctx, func(client rpc.ClientInterface) error {
+		var err error
+		sub, err = client.EthSubscribe(ctx, channel, args...)
+		return err
+	})
+	return sub, err
+}
+
+func (c *FailoverClient) Close() {
+	for _, client := range c.clients {
+		client.Close()
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package rpcclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type stubEndpoint struct {
+	err   error
+	calls int
+}
+
+type stubRpcError struct{}
+
+func (stubRpcError) Error() string  { return "execution reverted" }
+func (stubRpcError) ErrorCode() int { return 3 }
+
+func (s *stubEndpoint) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	s.calls++
+	return s.err
+}
+
+func (s *stubEndpoint) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	s.calls++
+	return s.err
+}
+
+func (s *stubEndpoint) EthSubscribe(ctx context.Context, channel interface{}, args ...interface{}) (*rpc.ClientSubscription, error) {
+	s.calls++
+	return nil, s.err
+}
+
+func (s *stubEndpoint) Close() {}
+
+func TestFailoverClient(t *testing.T) {
+	ctx := context.Background()
+	primary := &stubEndpoint{err: errors.New("connection refused")}
+	fallback := &stubEndpoint{}
+	client := NewFailoverClient(primary, fallback)
+
+	Require(t, client.CallContext(ctx, nil, "eth_blockNumber"))
+	if primary.calls != 1 || fallback.calls != 1 {
+		Fail(t, "didn't fail over, calls", primary.calls, fallback.calls)
+	}
+	// sticks with the endpoint that worked
+	Require(t, client.CallContext(ctx, nil, "eth_blockNumber"))
+	if primary.calls != 1 || fallback.calls != 2 {
+		Fail(t, "didn't stick with the working endpoint, calls", primary.calls, fallback.calls)
+	}
+	// an error answered by the endpoint doesn't fail over
+	fallback.err = stubRpcError{}
+	primary.err = nil
+	if err := client.CallContext(ctx, nil, "eth_call"); !errors.Is(err, stubRpcError{}) {
+		Fail(t, "unexpected error", err)
+	}
+	if primary.calls != 1 {
+		Fail(t, "failed over on an error answered by the endpoint")
+	}
+	// with every endpoint failing, the last error is returned
+	fallback.err = errors.New("timeout")
+	primary.err = errors.New("connection refused")
+	if err := client.CallContext(ctx, nil, "eth_blockNumber"); err == nil || err.Error() != "connection refused" {
+		Fail(t, "unexpected error", err)
+	}
+}
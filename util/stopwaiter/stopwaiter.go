@@ -8,6 +8,7 @@ import (
 	"errors"
 	"reflect"
 	"runtime"
+	"runtime/pprof"
 	"strings"
 	"sync"
 	"time"
@@ -18,6 +19,10 @@ import (
 
 const stopDelayWarningTimeout = 30 * time.Second
 
+// ComponentLabel is the pprof label set on every thread launched by a StopWaiter,
+// with the name of the StopWaiter's parent as its value.
+const ComponentLabel = "component"
+
 type StopWaiterSafe struct {
 	mutex     sync.Mutex // protects started, stopped, ctx, parentCtx, stopFunc
 	started   bool
@@ -87,7 +92,8 @@ func (s *StopWaiterSafe) Start(ctx context.Context, parent any) error {
 	s.started = true
 	s.name = getParentName(parent)
 	s.parentCtx = ctx
-	s.ctx, s.stopFunc = context.WithCancel(s.parentCtx)
+	labeledCtx := pprof.WithLabels(s.parentCtx, pprof.Labels(ComponentLabel, s.name))
+	s.ctx, s.stopFunc = context.WithCancel(labeledCtx)
 	if s.stopped {
 		s.stopFunc()
 	}
@@ -177,6 +183,7 @@ func (s *StopWaiterSafe) LaunchThreadSafe(foo func(context.Context)) error {
 	}
 	s.wg.Add(1)
 	go func() {
+		pprof.SetGoroutineLabels(ctx)
 		foo(ctx)
 		s.wg.Done()
 	}()
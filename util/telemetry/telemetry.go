@@ -0,0 +1,228 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+// Package telemetry periodically reports what version of the node is running, in which roles, and whether it's
+// synced, for operators who opt in to help coordinate network upgrades.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+var (
+	sentCounter   = metrics.NewRegisteredCounter("arb/telemetry/sent", nil)
+	failedCounter = metrics.NewRegisteredCounter("arb/telemetry/failed", nil)
+)
+
+// NodeIDFile is the name of the file in the node's data directory holding its random telemetry identity.
+const NodeIDFile = "telemetry-node-id"
+
+type Config struct {
+	Enable   bool          `koanf:"enable"`
+	URL      string        `koanf:"url" reload:"hot"`
+	Interval time.Duration `koanf:"interval" reload:"hot"`
+	Timeout  time.Duration `koanf:"timeout" reload:"hot"`
+}
+
+type ConfigFetcher func() *Config
+
+var DefaultConfig = Config{
+	Enable:   false,
+	URL:      "",
+	Interval: time.Hour,
+	Timeout:  10 * time.Second,
+}
+
+func ConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultConfig.Enable, "opt in to periodically reporting the node's version, roles, chain id and sync status, identified only by a random id (see arbdebug_telemetryPreview for exactly what is sent)")
+	f.String(prefix+".url", DefaultConfig.URL, "URL to POST telemetry reports to")
+	f.Duration(prefix+".interval", DefaultConfig.Interval, "how often to send a telemetry report")
+	f.Duration(prefix+".timeout", DefaultConfig.Timeout, "timeout of a single telemetry report request")
+}
+
+func (c *Config) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.URL == "" {
+		return errors.New("telemetry is enabled but no url is configured")
+	}
+	if !strings.HasPrefix(c.URL, "http://") && !strings.HasPrefix(c.URL, "https://") {
+		return fmt.Errorf("invalid telemetry url %v, expected http or https", c.URL)
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("invalid telemetry interval %v", c.Interval)
+	}
+	return nil
+}
+
+// Report is everything a telemetry report contains. It deliberately holds nothing identifying the operator: the
+// node id is random, and neither addresses nor endpoints are included.
+type Report struct {
+	NodeID         string    `json:"nodeId"`
+	Version        string    `json:"version"`
+	Roles          []string  `json:"roles"`
+	ChainID        uint64    `json:"chainId"`
+	Synced         bool      `json:"synced"`
+	MessagesBehind uint64    `json:"messagesBehind"`
+	Time           time.Time `json:"time"`
+}
+
+// SyncStatus is the part of a report that changes as the node runs.
+type SyncStatus struct {
+	Synced         bool
+	MessagesBehind uint64
+}
+
+// Node describes the node being reported on.
+type Node struct {
+	Version string
+	Roles   []string
+	ChainID uint64
+	// DataDir is where the node id is kept, so it's the same across restarts.
+	DataDir string
+	Sync    func() SyncStatus
+}
+
+type Beacon struct {
+	stopwaiter.StopWaiter
+	config     ConfigFetcher
+	node       Node
+	httpClient *http.Client
+
+	nodeIDLock sync.Mutex
+	nodeID     string
+}
+
+func NewBeacon(config ConfigFetcher, node Node) *Beacon {
+	return &Beacon{
+		config:     config,
+		node:       node,
+		httpClient: &http.Client{},
+	}
+}
+
+func (b *Beacon) loadNodeID() (string, error) {
+	b.nodeIDLock.Lock()
+	defer b.nodeIDLock.Unlock()
+	if b.nodeID != "" {
+		return b.nodeID, nil
+	}
+	path := filepath.Join(b.node.DataDir, NodeIDFile)
+	data, err := os.ReadFile(path)
+	if err == nil {
+		b.nodeID = strings.TrimSpace(string(data))
+		return b.nodeID, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(id)+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("error saving telemetry node id: %w", err)
+	}
+	b.nodeID = hex.EncodeToString(id)
+	return b.nodeID, nil
+}
+
+// Report builds the report that would be sent now.
+func (b *Beacon) Report() (*Report, error) {
+	nodeID, err := b.loadNodeID()
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{
+		NodeID:  nodeID,
+		Version: b.node.Version,
+		Roles:   b.node.Roles,
+		ChainID: b.node.ChainID,
+		Time:    time.Now().UTC().Truncate(time.Second),
+	}
+	if b.node.Sync != nil {
+		status := b.node.Sync()
+		report.Synced = status.Synced
+		report.MessagesBehind = status.MessagesBehind
+	}
+	return report, nil
+}
+
+func (b *Beacon) Start(ctxIn context.Context) {
+	b.StopWaiter.Start(ctxIn, b)
+	b.CallIteratively(func(ctx context.Context) time.Duration {
+		if err := b.send(ctx); err != nil {
+			failedCounter.Inc(1)
+			log.Warn("failed to send telemetry report", "err", err)
+		} else {
+			sentCounter.Inc(1)
+		}
+		return b.config().Interval
+	})
+}
+
+func (b *Beacon) send(ctx context.Context) error {
+	config := b.config()
+	report, err := b.Report()
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	if config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry endpoint responded with status %v", resp.Status)
+	}
+	return nil
+}
+
+// API lets operators preview exactly what is, or would be if enabled, sent.
+type API struct {
+	beacon *Beacon
+}
+
+func NewAPI(beacon *Beacon) *API {
+	return &API{beacon}
+}
+
+func (a *API) TelemetryPreview(ctx context.Context) (*Report, error) {
+	return a.beacon.Report()
+}
@@ -0,0 +1,78 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBeaconSendsPreview(t *testing.T) {
+	received := make(chan Report, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var report Report
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			t.Errorf("decoding report: %v", err)
+		}
+		received <- report
+	}))
+	defer server.Close()
+
+	config := DefaultConfig
+	config.Enable = true
+	config.URL = server.URL
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	node := Node{
+		Version: "v1.2.3",
+		Roles:   []string{"sequencer"},
+		ChainID: 412346,
+		DataDir: t.TempDir(),
+		Sync:    func() SyncStatus { return SyncStatus{Synced: false, MessagesBehind: 7} },
+	}
+	beacon := NewBeacon(func() *Config { return &config }, node)
+	preview, err := NewAPI(beacon).TelemetryPreview(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	beacon.Start(context.Background())
+	defer beacon.StopAndWait()
+
+	var report Report
+	select {
+	case report = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a telemetry report")
+	}
+	if report.NodeID != preview.NodeID || report.Version != preview.Version || report.ChainID != preview.ChainID ||
+		report.MessagesBehind != preview.MessagesBehind || len(report.Roles) != 1 || report.Roles[0] != "sequencer" {
+		t.Fatalf("sent %+v, but previewed %+v", report, preview)
+	}
+
+	// the node id survives restarts
+	restarted, err := NewBeacon(func() *Config { return &config }, node).Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restarted.NodeID != report.NodeID {
+		t.Fatalf("node id changed from %v to %v", report.NodeID, restarted.NodeID)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	config := DefaultConfig
+	config.Enable = true
+	if err := config.Validate(); err == nil {
+		t.Fatal("expected enabling telemetry without a url to be rejected")
+	}
+	config.URL = "ftp://example.com"
+	if err := config.Validate(); err == nil {
+		t.Fatal("expected a non-http url to be rejected")
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+// Package webhook delivers event notifications as JSON POST requests to configured URLs.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/util/stopwaiter"
+)
+
+var (
+	deliveredCounter = metrics.NewRegisteredCounter("arb/webhook/delivered", nil)
+	failedCounter    = metrics.NewRegisteredCounter("arb/webhook/failed", nil)
+	droppedCounter   = metrics.NewRegisteredCounter("arb/webhook/dropped", nil)
+)
+
+// SignatureHeader holds the hex encoded HMAC-SHA256 of the request body, keyed with the configured secret.
+const SignatureHeader = "X-Nitro-Signature"
+
+type Config struct {
+	URLs       []string      `koanf:"urls"`
+	Secret     string        `koanf:"secret"`
+	Events     []string      `koanf:"events" reload:"hot"`
+	Timeout    time.Duration `koanf:"timeout" reload:"hot"`
+	Retries    int           `koanf:"retries" reload:"hot"`
+	RetryDelay time.Duration `koanf:"retry-delay" reload:"hot"`
+	QueueSize  int           `koanf:"queue-size"`
+}
+
+type ConfigFetcher func() *Config
+
+var DefaultConfig = Config{
+	URLs:       []string{},
+	Secret:     "",
+	Events:     []string{},
+	Timeout:    10 * time.Second,
+	Retries:    3,
+	RetryDelay: time.Second,
+	QueueSize:  1024,
+}
+
+func ConfigAddOptions(prefix string, f *pflag.FlagSet) {
+	f.StringSlice(prefix+".urls", DefaultConfig.URLs, "URLs to POST event notifications to")
+	f.String(prefix+".secret", DefaultConfig.Secret, "if set, sign notifications with an HMAC-SHA256 of their body using this secret, in the "+SignatureHeader+" header")
+	f.StringSlice(prefix+".events", DefaultConfig.Events, "only send notifications of these event types (empty = all)")
+	f.Duration(prefix+".timeout", DefaultConfig.Timeout, "timeout of a single notification request")
+	f.Int(prefix+".retries", DefaultConfig.Retries, "number of times to retry a failed notification")
+	f.Duration(prefix+".retry-delay", DefaultConfig.RetryDelay, "delay between retries of a failed notification")
+	f.Int(prefix+".queue-size", DefaultConfig.QueueSize, "maximum number of notifications waiting to be delivered, further ones are dropped")
+}
+
+func (c *Config) Validate() error {
+	if len(c.URLs) == 0 {
+		return errors.New("no webhook urls configured")
+	}
+	if c.QueueSize <= 0 {
+		return errors.New("webhook queue size must be positive")
+	}
+	if c.Retries < 0 {
+		return errors.New("webhook retries can't be negative")
+	}
+	return nil
+}
+
+// Event is the body of a notification.
+type Event struct {
+	Type string      `json:"type"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data"`
+}
+
+// Notifier delivers events in the order they're emitted, without blocking the emitter.
+type Notifier struct {
+	stopwaiter.StopWaiter
+	config     ConfigFetcher
+	httpClient *http.Client
+	queue      chan Event
+}
+
+func NewNotifier(config ConfigFetcher) *Notifier {
+	return &Notifier{
+		config:     config,
+		httpClient: &http.Client{},
+		queue:      make(chan Event, config().QueueSize),
+	}
+}
+
+func (n *Notifier) enabled(eventType string) bool {
+	events := n.config().Events
+	if len(events) == 0 {
+		return true
+	}
+	for _, enabled := range events {
+		if enabled == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Notify queues an event for delivery, or drops it if the queue is full.
+func (n *Notifier) Notify(eventType string, data interface{}) {
+	if !n.enabled(eventType) {
+		return
+	}
+	select {
+	case n.queue <- Event{Type: eventType, Time: time.Now().UTC(), Data: data}:
+	default:
+		droppedCounter.Inc(1)
+		log.Warn("webhook queue full, dropping event", "type", eventType)
+	}
+}
+
+func (n *Notifier) Start(ctxIn context.Context) {
+	n.StopWaiter.Start(ctxIn, n)
+	n.LaunchThread(func(ctx context.Context) {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-n.queue:
+				body, err := json.Marshal(event)
+				if err != nil {
+					log.Error("failed to encode webhook event", "type", event.Type, "err", err)
+					continue
+				}
+				for _, url := range n.config().URLs {
+					n.deliver(ctx, url, event.Type, body)
+				}
+			}
+		}
+	})
+}
+
+func (n *Notifier) deliver(ctx context.Context, url string, eventType string, body []byte) {
+	config := n.config()
+	var err error
+	for attempt := 0; attempt <= config.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(config.RetryDelay):
+			}
+		}
+		if err = n.post(ctx, url, body); err == nil {
+			deliveredCounter.Inc(1)
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+	failedCounter.Inc(1)
+	log.Warn("failed to deliver webhook event", "type", eventType, "url", url, "err", err)
+}
+
+func (n *Notifier) post(ctx context.Context, url string, body []byte) error {
+	config := n.config()
+	if config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if config.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(config.Secret))
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %v", resp.Status)
+	}
+	return nil
+}
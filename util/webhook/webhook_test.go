@@ -0,0 +1,74 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotifierDelivery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan Event, 4)
+	failures := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(body)
+		if r.Header.Get(SignatureHeader) != hex.EncodeToString(mac.Sum(nil)) {
+			t.Error("notification has an invalid signature")
+		}
+		var event Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Error(err)
+			return
+		}
+		received <- event
+	}))
+	defer server.Close()
+
+	config := DefaultConfig
+	config.URLs = []string{server.URL}
+	config.Secret = "secret"
+	config.Events = []string{"wanted"}
+	config.RetryDelay = time.Millisecond
+	notifier := NewNotifier(func() *Config { return &config })
+	notifier.Start(ctx)
+	defer notifier.StopAndWait()
+
+	notifier.Notify("unwanted", 1)
+	notifier.Notify("wanted", 2)
+	select {
+	case event := <-received:
+		if event.Type != "wanted" || event.Data != float64(2) {
+			t.Errorf("unexpected event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the notification to be retried")
+	}
+	select {
+	case event := <-received:
+		t.Errorf("unexpected extra event %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
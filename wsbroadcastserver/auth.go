@@ -0,0 +1,246 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package wsbroadcastserver
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	clientsUnauthorizedCounter = metrics.NewRegisteredCounter("arb/feed/clients/auth/unauthorized", nil)
+)
+
+var errTooManyKeyConnections = errors.New("too many connections for api key")
+
+// AuthKeyQueryParam is the query parameter clients without control of their handshake headers may pass their
+// key in, instead of the Authorization header.
+const AuthKeyQueryParam = "api-key"
+
+type AuthConfig struct {
+	Enable         bool     `koanf:"enable" reload:"hot"`
+	Keys           []string `koanf:"keys" reload:"hot"`
+	AllowAnonymous bool     `koanf:"allow-anonymous" reload:"hot"`
+}
+
+var DefaultAuthConfig = AuthConfig{
+	Enable:         false,
+	Keys:           []string{},
+	AllowAnonymous: false,
+}
+
+func AuthConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultAuthConfig.Enable, "require clients to authenticate with an api key, passed as a bearer token in the Authorization header or in the "+AuthKeyQueryParam+" query parameter")
+	f.StringSlice(prefix+".keys", DefaultAuthConfig.Keys, "api keys, each given as <name>:<key>[:<max connections>], where the name labels the key's metrics and a max of 0 or none is unlimited")
+	f.Bool(prefix+".allow-anonymous", DefaultAuthConfig.AllowAnonymous, "also accept clients without an api key, limited only by the connection limits")
+}
+
+// authKey is an api key a client may connect with.
+type authKey struct {
+	name           string
+	key            string
+	maxConnections int
+}
+
+func parseAuthKeys(keys []string) ([]authKey, error) {
+	var parsed []authKey
+	names := make(map[string]bool)
+	for _, entry := range keys {
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+			return nil, errors.New("feed api keys must be given as <name>:<key>[:<max connections>]")
+		}
+		key := authKey{name: parts[0], key: parts[1]}
+		if len(parts) == 3 {
+			maxConnections, err := strconv.Atoi(parts[2])
+			if err != nil || maxConnections < 0 {
+				return nil, fmt.Errorf("invalid max connections for feed api key %v", key.name)
+			}
+			key.maxConnections = maxConnections
+		}
+		if names[key.name] {
+			return nil, fmt.Errorf("duplicate feed api key name %v", key.name)
+		}
+		names[key.name] = true
+		parsed = append(parsed, key)
+	}
+	return parsed, nil
+}
+
+func (c *AuthConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	keys, err := parseAuthKeys(c.Keys)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 && !c.AllowAnonymous {
+		return errors.New("feed authentication is enabled without any api keys")
+	}
+	return nil
+}
+
+// authKeyFromRequest returns the api key passed in a request uri's query parameters, if any.
+func authKeyFromRequest(uri string) string {
+	_, query, found := strings.Cut(uri, "?")
+	if !found {
+		return ""
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return ""
+	}
+	return values.Get(AuthKeyQueryParam)
+}
+
+// authKeyFromHeader returns the api key passed as a bearer token in an Authorization header.
+func authKeyFromHeader(value string) string {
+	scheme, token, found := strings.Cut(value, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+type AuthConfigFetcher func() *AuthConfig
+
+// Authenticator checks the api keys of connecting clients, limits the connections of each key, and meters
+// the connections and bandwidth of each key.
+type Authenticator struct {
+	mutex       sync.Mutex
+	connections map[string]int
+	config      AuthConfigFetcher
+}
+
+func NewAuthenticator(configFetcher AuthConfigFetcher) *Authenticator {
+	return &Authenticator{
+		connections: make(map[string]int),
+		config:      configFetcher,
+	}
+}
+
+func authKeyMetricPrefix(name string) string {
+	return "arb/feed/clients/auth/" + name
+}
+
+// matchAuthKey returns the configured key matching a client's, or nil for an anonymous client if they're allowed.
+func matchAuthKey(config *AuthConfig, key string) (*authKey, error) {
+	if key == "" {
+		if config.AllowAnonymous {
+			return nil, nil
+		}
+		clientsUnauthorizedCounter.Inc(1)
+		return nil, errors.New("missing api key")
+	}
+	keys, err := parseAuthKeys(config.Keys)
+	if err != nil {
+		return nil, err
+	}
+	var match *authKey
+	for i := range keys {
+		// compare against every key so the time taken doesn't reveal which were close
+		if subtle.ConstantTimeCompare([]byte(keys[i].key), []byte(key)) == 1 {
+			match = &keys[i]
+		}
+	}
+	if match == nil {
+		clientsUnauthorizedCounter.Inc(1)
+		return nil, errors.New("invalid api key")
+	}
+	return match, nil
+}
+
+// Authenticate returns the name of the client's api key, or an empty name for an anonymous client. It fails if
+// the key isn't known, or has as many connections as it's allowed.
+func (a *Authenticator) Authenticate(key string) (string, error) {
+	match, err := matchAuthKey(a.config(), key)
+	if err != nil || match == nil {
+		return "", err
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if match.maxConnections > 0 && a.connections[match.name] >= match.maxConnections {
+		metrics.GetOrRegisterCounter(authKeyMetricPrefix(match.name)+"/limited", nil).Inc(1)
+		return "", errTooManyKeyConnections
+	}
+	return match.name, nil
+}
+
+// AuthenticateRequest returns the name of the api key an http request passes, the same ways a feed client
+// does, or an empty name for an anonymous request. Connection limits don't apply, as the request isn't a
+// feed connection.
+func AuthenticateRequest(config *AuthConfig, r *http.Request) (string, error) {
+	key := authKeyFromHeader(r.Header.Get("Authorization"))
+	if key == "" {
+		key = r.URL.Query().Get(AuthKeyQueryParam)
+	}
+	match, err := matchAuthKey(config, key)
+	if err != nil || match == nil {
+		return "", err
+	}
+	return match.name, nil
+}
+
+// Register counts a connection of the named key, returning false if the key is already at its limit.
+func (a *Authenticator) Register(name string) bool {
+	if name == "" {
+		return true
+	}
+	keys, err := parseAuthKeys(a.config().Keys)
+	if err != nil {
+		log.Warn("error parsing feed api keys", "err", err)
+		return false
+	}
+	maxConnections := -1
+	for _, key := range keys {
+		if key.name == name {
+			maxConnections = key.maxConnections
+		}
+	}
+	if maxConnections < 0 {
+		// the key was removed since the client authenticated
+		return false
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if maxConnections > 0 && a.connections[name] >= maxConnections {
+		metrics.GetOrRegisterCounter(authKeyMetricPrefix(name)+"/limited", nil).Inc(1)
+		return false
+	}
+	a.connections[name]++
+	metrics.GetOrRegisterGauge(authKeyMetricPrefix(name)+"/connections", nil).Update(int64(a.connections[name]))
+	return true
+}
+
+func (a *Authenticator) Release(name string) {
+	if name == "" {
+		return
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.connections[name] > 0 {
+		a.connections[name]--
+	}
+	metrics.GetOrRegisterGauge(authKeyMetricPrefix(name)+"/connections", nil).Update(int64(a.connections[name]))
+}
+
+// authKeyBandwidthCounter returns the counter of the bytes sent to clients of the named key.
+func authKeyBandwidthCounter(name string) metrics.Counter {
+	if name == "" {
+		return nil
+	}
+	return metrics.GetOrRegisterCounter(authKeyMetricPrefix(name)+"/bytes", nil)
+}
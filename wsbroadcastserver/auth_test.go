@@ -0,0 +1,100 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package wsbroadcastserver
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthenticator(t *testing.T) {
+	config := DefaultAuthConfig
+	config.Enable = true
+	config.Keys = []string{"gold:goldkey", "free:freekey:1"}
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	auth := NewAuthenticator(func() *AuthConfig { return &config })
+
+	if _, err := auth.Authenticate(""); err == nil {
+		t.Fatal("anonymous client authenticated")
+	}
+	if _, err := auth.Authenticate("wrongkey"); err == nil {
+		t.Fatal("client with an unknown key authenticated")
+	}
+	name, err := auth.Authenticate("freekey")
+	if err != nil || name != "free" {
+		t.Fatal("expected the free key, got", name, err)
+	}
+	if !auth.Register(name) {
+		t.Fatal("first connection of the free key not registered")
+	}
+	if _, err := auth.Authenticate("freekey"); !errors.Is(err, errTooManyKeyConnections) {
+		t.Fatal("expected the free key to be at its connection limit, got", err)
+	}
+	if auth.Register(name) {
+		t.Fatal("connection past the free key's limit registered")
+	}
+	auth.Release(name)
+	if _, err := auth.Authenticate("freekey"); err != nil {
+		t.Fatal("free key still limited after its connection was released", err)
+	}
+	for i := 0; i < 10; i++ {
+		if !auth.Register("gold") {
+			t.Fatal("unlimited key limited")
+		}
+	}
+
+	config.AllowAnonymous = true
+	if name, err := auth.Authenticate(""); err != nil || name != "" {
+		t.Fatal("anonymous client rejected", err)
+	}
+
+	for _, keys := range [][]string{{"nokey"}, {"name:key:-1"}, {"a:key1", "a:key2"}} {
+		invalid := AuthConfig{Enable: true, Keys: keys}
+		if invalid.Validate() == nil {
+			t.Fatal("expected invalid keys", keys)
+		}
+	}
+}
+
+func TestAuthKeyFromRequest(t *testing.T) {
+	if key := authKeyFromRequest("/chain/42170?api-key=abc&x=1"); key != "abc" {
+		t.Fatal("unexpected key from query", key)
+	}
+	if key := authKeyFromRequest("/"); key != "" {
+		t.Fatal("unexpected key without query", key)
+	}
+	if key := authKeyFromHeader("Bearer abc"); key != "abc" {
+		t.Fatal("unexpected key from header", key)
+	}
+	if key := authKeyFromHeader("Basic abc"); key != "" {
+		t.Fatal("unexpected key from basic auth", key)
+	}
+}
+
+func TestAuthenticateRequest(t *testing.T) {
+	config := AuthConfig{Enable: true, Keys: []string{"free:freekey:1"}}
+	request := httptest.NewRequest(http.MethodGet, "/backfill?api-key=freekey", nil)
+	if name, err := AuthenticateRequest(&config, request); err != nil || name != "free" {
+		t.Fatal("expected the free key from the query, got", name, err)
+	}
+	request = httptest.NewRequest(http.MethodGet, "/backfill", nil)
+	if _, err := AuthenticateRequest(&config, request); err == nil {
+		t.Fatal("request without a key authenticated")
+	}
+	request.Header.Set("Authorization", "Bearer freekey")
+	// connection limits don't apply to requests
+	for i := 0; i < 2; i++ {
+		if name, err := AuthenticateRequest(&config, request); err != nil || name != "free" {
+			t.Fatal("expected the free key from the header, got", name, err)
+		}
+	}
+	request.Header.Set("Authorization", "Bearer wrongkey")
+	if _, err := AuthenticateRequest(&config, request); err == nil {
+		t.Fatal("request with an unknown key authenticated")
+	}
+}
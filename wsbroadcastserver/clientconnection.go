@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/offchainlabs/nitro/arbutil"
 	"github.com/offchainlabs/nitro/broadcaster/backlog"
 	m "github.com/offchainlabs/nitro/broadcaster/message"
@@ -57,10 +58,19 @@ type ClientConnection struct {
 	registered    chan bool
 	backlogSent   bool
 
-	compression bool
-	flateReader *wsflate.Reader
+	compression       bool
+	compressionBudget *CompressionBudget
+	encoding          FeedEncoding
+	flateReader       *wsflate.Reader
 
 	delay time.Duration
+
+	gapFiller          GapFiller
+	maxGapFillMessages uint64
+
+	// the name of the api key the client authenticated with, and the counter of the bytes sent to its clients
+	authKeyName string
+	bytesSent   metrics.Counter
 }
 
 func NewClientConnection(
@@ -70,6 +80,7 @@ func NewClientConnection(
 	requestedSeqNum arbutil.MessageIndex,
 	connectingIP net.IP,
 	compression bool,
+	encoding FeedEncoding,
 	maxSendQueue int,
 	delay time.Duration,
 	bklg backlog.Backlog,
@@ -84,6 +95,7 @@ func NewClientConnection(
 		requestedSeqNum: requestedSeqNum,
 		out:             make(chan message, maxSendQueue),
 		compression:     compression,
+		encoding:        encoding,
 		flateReader:     NewFlateReader(),
 		delay:           delay,
 		backlog:         bklg,
@@ -102,6 +114,10 @@ func (cc *ClientConnection) Compression() bool {
 	return cc.compression
 }
 
+func (cc *ClientConnection) Encoding() FeedEncoding {
+	return cc.encoding
+}
+
 // Register sends the ClientConnection to be registered with the ClientManager.
 func (cc *ClientConnection) Register() {
 	cc.clientAction <- ClientConnectionAction{
@@ -165,15 +181,21 @@ func (cc *ClientConnection) writeBacklog(ctx context.Context, segment backlog.Ba
 }
 
 func (cc *ClientConnection) writeBroadcastMessage(bm *m.BroadcastMessage) error {
-	notCompressed, compressed, err := serializeMessage(bm, !cc.compression, cc.compression)
+	compress := cc.compression && cc.compressionBudget.Allow()
+	start := time.Now()
+	notCompressed, compressed, err := serializeMessage(bm, !compress, compress, cc.encoding)
 	if err != nil {
 		return err
 	}
 
 	var data []byte
-	if cc.compression {
+	if compress {
+		cc.compressionBudget.Spend(time.Since(start))
 		data = compressed.Bytes()
 	} else {
+		if cc.compression {
+			compressionSkippedCounter.Inc(1)
+		}
 		data = notCompressed.Bytes()
 	}
 	err = cc.writeRaw(data)
@@ -202,6 +224,9 @@ func (cc *ClientConnection) Start(parentCtx context.Context) {
 		// Send the current backlog before registering the ClientConnection in
 		// case the backlog is very large
 		segment := cc.backlog.Head()
+		if err := cc.fillGapBeforeBacklog(ctx, segment); err != nil {
+			logWarn(err, "error filling the gap before the backlog")
+		}
 		if !backlog.IsBacklogSegmentNil(segment) && segment.Start() < uint64(cc.requestedSeqNum) {
 			s, err := cc.backlog.Lookup(uint64(cc.requestedSeqNum))
 			if err != nil {
@@ -247,15 +272,22 @@ func (cc *ClientConnection) Start(parentCtx context.Context) {
 				if !cc.backlogSent && msg.sequenceNumber != nil && uint64(*msg.sequenceNumber) > expSeqNum {
 					catchupSeqNum := uint64(*msg.sequenceNumber) - 1
 					bm, err := cc.backlog.Get(expSeqNum, catchupSeqNum)
-					if err != nil {
-						logWarn(err, fmt.Sprintf("error reading messages %d to %d from backlog", expSeqNum, catchupSeqNum))
-						return
+					if err != nil && cc.gapFiller != nil {
+						// e.g. the backlog was empty when the client connected
+						next, fillErr := cc.fillGap(ctx, expSeqNum, catchupSeqNum+1)
+						if fillErr == nil && next > catchupSeqNum {
+							err = nil
+						}
+					} else if err == nil {
+						err = cc.writeBroadcastMessage(bm)
+						if err != nil {
+							logWarn(err, fmt.Sprintf("error writing messages %d to %d from backlog", expSeqNum, catchupSeqNum))
+							cc.Remove()
+							return
+						}
 					}
-
-					err = cc.writeBroadcastMessage(bm)
 					if err != nil {
-						logWarn(err, fmt.Sprintf("error writing messages %d to %d from backlog", expSeqNum, catchupSeqNum))
-						cc.Remove()
+						logWarn(err, fmt.Sprintf("error reading messages %d to %d from backlog", expSeqNum, catchupSeqNum))
 						return
 					}
 				}
@@ -324,7 +356,10 @@ func (cc *ClientConnection) writeRaw(p []byte) error {
 	cc.ioMutex.Lock()
 	defer cc.ioMutex.Unlock()
 
-	_, err := cc.conn.Write(p)
+	n, err := cc.conn.Write(p)
+	if cc.bytesSent != nil {
+		cc.bytesSent.Inc(int64(n))
+	}
 
 	return err
 }
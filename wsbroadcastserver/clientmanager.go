@@ -53,6 +53,8 @@ type ClientManager struct {
 	backlog       backlog.Backlog
 
 	connectionLimiter *ConnectionLimiter
+	authenticator     *Authenticator
+	compressionBudget *CompressionBudget
 }
 
 func NewClientManager(poller netpoll.Poller, configFetcher BroadcasterConfigFetcher, bklg backlog.Backlog) *ClientManager {
@@ -66,6 +68,8 @@ func NewClientManager(poller netpoll.Poller, configFetcher BroadcasterConfigFetc
 		config:            configFetcher,
 		backlog:           bklg,
 		connectionLimiter: NewConnectionLimiter(func() *ConnectionLimiterConfig { return &configFetcher().ConnectionLimits }),
+		authenticator:     NewAuthenticator(func() *AuthConfig { return &configFetcher().Auth }),
+		compressionBudget: NewCompressionBudget(func() *CompressionBudgetConfig { return &configFetcher().CompressionBudget }),
 	}
 }
 
@@ -78,7 +82,11 @@ func (cm *ClientManager) registerClient(ctx context.Context, clientConnection *C
 
 	// TODO:(clamb) the clientsTotalFailedRegisterCounter was deleted after backlog logic moved to ClientConnection. Should this metric be reintroduced or will it be ok to just delete completely given the behaviour has changed, ask Lee
 
+	if !cm.authenticator.Register(clientConnection.authKeyName) {
+		return fmt.Errorf("Connection limited for api key %s", clientConnection.authKeyName)
+	}
 	if cm.config().ConnectionLimits.Enable && !cm.connectionLimiter.Register(clientConnection.clientIp) {
+		cm.authenticator.Release(clientConnection.authKeyName)
 		return fmt.Errorf("Connection limited %s", clientConnection.clientIp)
 	}
 
@@ -132,6 +140,7 @@ func (cm *ClientManager) removeClient(clientConnection *ClientConnection) {
 	if cm.config().ConnectionLimits.Enable {
 		cm.connectionLimiter.Release(clientConnection.clientIp)
 	}
+	cm.authenticator.Release(clientConnection.authKeyName)
 
 	delete(cm.clientPtrMap, clientConnection)
 }
@@ -156,22 +165,48 @@ func (cm *ClientManager) doBroadcast(bm *m.BroadcastMessage) ([]*ClientConnectio
 		return nil, err
 	}
 	config := cm.config()
-	//                                        /-> wsutil.Writer -> not compressed msg buffer
-	// bm -> json.Encoder -> io.MultiWriter -|
-	//                                        \-> flateWriter -> wsutil.Writer -> compressed msg buffer
-
-	notCompressed, compressed, err := serializeMessage(bm, !config.RequireCompression, config.EnableCompression)
-	if err != nil {
-		return nil, err
+	// clients that negotiated compression are sent uncompressed messages once the compression budget is spent
+	compress := config.EnableCompression && cm.compressionBudget.Allow()
+	//                                                       /-> wsutil.Writer -> not compressed msg buffer
+	// bm -> json.Encoder or EncodeProto -> io.MultiWriter -|
+	//                                                       \-> flateWriter -> wsutil.Writer -> compressed msg buffer
+
+	// each encoding is only serialized if a client uses it
+	type serialized struct {
+		notCompressed, compressed bytes.Buffer
+	}
+	serializedByEncoding := make(map[FeedEncoding]*serialized, 2)
+	serialize := func(encoding FeedEncoding) (*serialized, error) {
+		if s, ok := serializedByEncoding[encoding]; ok {
+			return s, nil
+		}
+		start := time.Now()
+		notCompressed, compressed, err := serializeMessage(bm, !config.RequireCompression || !compress, compress, encoding)
+		if err != nil {
+			return nil, err
+		}
+		if compress {
+			cm.compressionBudget.Spend(time.Since(start))
+		}
+		s := &serialized{notCompressed, compressed}
+		serializedByEncoding[encoding] = s
+		return s, nil
 	}
 
 	sendQueueTooLargeCount := 0
 	clientDeleteList := make([]*ClientConnection, 0, len(cm.clientPtrMap))
 	for client := range cm.clientPtrMap {
+		s, err := serialize(client.Encoding())
+		if err != nil {
+			return nil, err
+		}
 		var data []byte
 		if client.Compression() {
-			if config.EnableCompression {
-				data = compressed.Bytes()
+			if compress {
+				data = s.compressed.Bytes()
+			} else if config.EnableCompression {
+				data = s.notCompressed.Bytes()
+				compressionSkippedCounter.Inc(1)
 			} else {
 				log.Warn("disconnecting because client has enabled compression, but compression support is disabled", "client", client.Name)
 				clientDeleteList = append(clientDeleteList, client)
@@ -179,7 +214,7 @@ func (cm *ClientManager) doBroadcast(bm *m.BroadcastMessage) ([]*ClientConnectio
 			}
 		} else {
 			if !config.RequireCompression {
-				data = notCompressed.Bytes()
+				data = s.notCompressed.Bytes()
 			} else {
 				log.Warn("disconnecting because client has disabled compression, but compression support is required", "client", client.Name)
 				clientDeleteList = append(clientDeleteList, client)
@@ -221,7 +256,7 @@ func (cm *ClientManager) doBroadcast(bm *m.BroadcastMessage) ([]*ClientConnectio
 	return clientDeleteList, nil
 }
 
-func serializeMessage(bm *m.BroadcastMessage, enableNonCompressedOutput, enableCompressedOutput bool) (bytes.Buffer, bytes.Buffer, error) {
+func serializeMessage(bm *m.BroadcastMessage, enableNonCompressedOutput, enableCompressedOutput bool, encoding FeedEncoding) (bytes.Buffer, bytes.Buffer, error) {
 	flateWriter, err := flate.NewWriterDict(nil, DeflateCompressionLevel, GetStaticCompressorDictionary())
 	if err != nil {
 		return bytes.Buffer{}, bytes.Buffer{}, fmt.Errorf("unable to create flate writer: %w", err)
@@ -232,12 +267,16 @@ func serializeMessage(bm *m.BroadcastMessage, enableNonCompressedOutput, enableC
 	writers := []io.Writer{}
 	var notCompressedWriter *wsutil.Writer
 	var compressedWriter *wsutil.Writer
+	op := ws.OpText
+	if encoding == FeedEncodingProtobuf {
+		op = ws.OpBinary
+	}
 	if enableNonCompressedOutput {
-		notCompressedWriter = wsutil.NewWriter(&notCompressed, ws.StateServerSide, ws.OpText)
+		notCompressedWriter = wsutil.NewWriter(&notCompressed, ws.StateServerSide, op)
 		writers = append(writers, notCompressedWriter)
 	}
 	if enableCompressedOutput {
-		compressedWriter = wsutil.NewWriter(&compressed, ws.StateServerSide|ws.StateExtended, ws.OpText)
+		compressedWriter = wsutil.NewWriter(&compressed, ws.StateServerSide|ws.StateExtended, op)
 		var msg wsflate.MessageState
 		msg.SetCompressed(true)
 		compressedWriter.SetExtensions(&msg)
@@ -246,9 +285,15 @@ func serializeMessage(bm *m.BroadcastMessage, enableNonCompressedOutput, enableC
 	}
 
 	multiWriter := io.MultiWriter(writers...)
-	encoder := json.NewEncoder(multiWriter)
-	if err := encoder.Encode(bm); err != nil {
-		return bytes.Buffer{}, bytes.Buffer{}, fmt.Errorf("unable to encode message: %w", err)
+	if encoding == FeedEncodingProtobuf {
+		if _, err := multiWriter.Write(m.EncodeProto(bm)); err != nil {
+			return bytes.Buffer{}, bytes.Buffer{}, fmt.Errorf("unable to encode message: %w", err)
+		}
+	} else {
+		encoder := json.NewEncoder(multiWriter)
+		if err := encoder.Encode(bm); err != nil {
+			return bytes.Buffer{}, bytes.Buffer{}, fmt.Errorf("unable to encode message: %w", err)
+		}
 	}
 	if notCompressedWriter != nil {
 		if err := notCompressedWriter.Flush(); err != nil {
@@ -0,0 +1,106 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package wsbroadcastserver
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	compressionTimeCounter      = metrics.NewRegisteredCounter("arb/feed/compression/microseconds", nil)
+	compressionSkippedCounter   = metrics.NewRegisteredCounter("arb/feed/compression/skipped", nil)
+	compressionExhaustedCounter = metrics.NewRegisteredCounter("arb/feed/compression/exhausted", nil)
+)
+
+// CompressionBudgetConfig bounds the time spent compressing messages. Once a window's budget is spent,
+// messages are sent uncompressed, even to clients that negotiated compression, until the next window.
+type CompressionBudgetConfig struct {
+	Enable  bool          `koanf:"enable" reload:"hot"`
+	MaxTime time.Duration `koanf:"max-time" reload:"hot"`
+	Window  time.Duration `koanf:"window" reload:"hot"`
+}
+
+var DefaultCompressionBudgetConfig = CompressionBudgetConfig{
+	Enable:  false,
+	MaxTime: 500 * time.Millisecond,
+	Window:  time.Second,
+}
+
+func CompressionBudgetConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultCompressionBudgetConfig.Enable, "bound the time spent compressing messages, sending them uncompressed once the budget is spent")
+	f.Duration(prefix+".max-time", DefaultCompressionBudgetConfig.MaxTime, "time that may be spent compressing messages in each window")
+	f.Duration(prefix+".window", DefaultCompressionBudgetConfig.Window, "length of the windows the compression budget is spent over")
+}
+
+func (c *CompressionBudgetConfig) Validate() error {
+	if c.Enable && (c.MaxTime <= 0 || c.Window <= 0) {
+		return errors.New("the compression budget max-time and window must be positive")
+	}
+	return nil
+}
+
+type CompressionBudgetConfigFetcher func() *CompressionBudgetConfig
+
+// CompressionBudget tracks the time spent compressing messages in the current window.
+type CompressionBudget struct {
+	mutex       sync.Mutex
+	windowStart time.Time
+	spent       time.Duration
+	exhausted   bool
+	config      CompressionBudgetConfigFetcher
+}
+
+func NewCompressionBudget(configFetcher CompressionBudgetConfigFetcher) *CompressionBudget {
+	return &CompressionBudget{
+		config: configFetcher,
+	}
+}
+
+// Allow returns whether messages may be compressed, which they always may be if the budget is disabled.
+func (b *CompressionBudget) Allow() bool {
+	if b == nil {
+		return true
+	}
+	config := b.config()
+	if !config.Enable {
+		return true
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if now := time.Now(); now.Sub(b.windowStart) >= config.Window {
+		if b.exhausted {
+			log.Debug("feed compression budget replenished, compressing messages again")
+		}
+		b.windowStart = now
+		b.spent = 0
+		b.exhausted = false
+	}
+	if b.spent < config.MaxTime {
+		return true
+	}
+	if !b.exhausted {
+		b.exhausted = true
+		compressionExhaustedCounter.Inc(1)
+		log.Warn("feed compression budget exhausted, sending messages uncompressed until the window ends", "maxTime", config.MaxTime, "window", config.Window)
+	}
+	return false
+}
+
+// Spend records time spent compressing messages.
+func (b *CompressionBudget) Spend(duration time.Duration) {
+	compressionTimeCounter.Inc(duration.Microseconds())
+	if b == nil {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.spent += duration
+}
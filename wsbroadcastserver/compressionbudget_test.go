@@ -0,0 +1,47 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package wsbroadcastserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompressionBudget(t *testing.T) {
+	config := CompressionBudgetConfig{
+		Enable:  true,
+		MaxTime: 10 * time.Millisecond,
+		Window:  200 * time.Millisecond,
+	}
+	budget := NewCompressionBudget(func() *CompressionBudgetConfig { return &config })
+
+	if !budget.Allow() {
+		t.Fatal("compression wasn't allowed with an unspent budget")
+	}
+	budget.Spend(6 * time.Millisecond)
+	if !budget.Allow() {
+		t.Fatal("compression wasn't allowed with budget left")
+	}
+	budget.Spend(6 * time.Millisecond)
+	if budget.Allow() {
+		t.Fatal("compression was allowed with the budget spent")
+	}
+
+	time.Sleep(config.Window)
+	if !budget.Allow() {
+		t.Fatal("compression wasn't allowed in the next window")
+	}
+
+	budget.Spend(time.Second)
+	config.Enable = false
+	if !budget.Allow() {
+		t.Fatal("compression wasn't allowed with the budget disabled")
+	}
+
+	var unbounded *CompressionBudget
+	unbounded.Spend(time.Second)
+	if !unbounded.Allow() {
+		t.Fatal("compression wasn't allowed without a budget")
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2024, Offchain Labs, Inc.
+// For license information, see https://github.com/nitro/blob/master/LICENSE
+
+package wsbroadcastserver
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/offchainlabs/nitro/arbutil"
+	"github.com/offchainlabs/nitro/broadcaster/backlog"
+	m "github.com/offchainlabs/nitro/broadcaster/message"
+)
+
+var gapFilledMessagesCounter = metrics.NewRegisteredCounter("arb/feed/gapfill/messages", nil)
+
+const gapFillChunkMessages = 256
+
+// GapFiller provides the messages that are no longer, or not yet, in the backlog, such as after a restart,
+// so clients requesting them are still sent them rather than having to read them from the parent chain.
+type GapFiller interface {
+	// FeedMessages returns up to count messages starting at from, fewer if it doesn't have them.
+	FeedMessages(ctx context.Context, from arbutil.MessageIndex, count uint64) ([]*m.BroadcastFeedMessage, error)
+}
+
+// fillGap sends the client the messages from the gap filler from `from` up to until, or up to the server's
+// limit, returning the sequence number after the last message sent.
+func (cc *ClientConnection) fillGap(ctx context.Context, from uint64, until uint64) (uint64, error) {
+	if until-from > cc.maxGapFillMessages {
+		until = from + cc.maxGapFillMessages
+	}
+	next := from
+	for next < until {
+		msgs, err := cc.gapFiller.FeedMessages(ctx, arbutil.MessageIndex(next), min(until-next, gapFillChunkMessages))
+		if err != nil {
+			return next, err
+		}
+		if len(msgs) == 0 {
+			break
+		}
+		if uint64(msgs[0].SequenceNumber) != next {
+			return next, fmt.Errorf("gap filler returned message %v rather than %v", msgs[0].SequenceNumber, next)
+		}
+		if last := uint64(msgs[len(msgs)-1].SequenceNumber); last >= until {
+			msgs = msgs[:until-next]
+		}
+		err = cc.writeBroadcastMessage(&m.BroadcastMessage{
+			Version:  m.V1,
+			Messages: msgs,
+		})
+		if err != nil {
+			return next, err
+		}
+		next = uint64(msgs[len(msgs)-1].SequenceNumber) + 1
+		cc.LastSentSeqNum.Store(next - 1)
+		gapFilledMessagesCounter.Inc(int64(len(msgs)))
+	}
+	if next > from {
+		log.Debug("filled gap before backlog for client", "client", cc.Name, "from", from, "sentCount", next-from)
+	}
+	return next, nil
+}
+
+// fillGapBeforeBacklog sends the client the messages it requested that precede the backlog.
+func (cc *ClientConnection) fillGapBeforeBacklog(ctx context.Context, head backlog.BacklogSegment) error {
+	if cc.gapFiller == nil || cc.requestedSeqNum == 0 {
+		return nil
+	}
+	// with an empty backlog, send whatever the gap filler has, as the backlog will continue from there
+	until := uint64(math.MaxUint64)
+	if !backlog.IsBacklogSegmentNil(head) {
+		until = head.Start()
+	}
+	if uint64(cc.requestedSeqNum) >= until {
+		return nil
+	}
+	_, err := cc.fillGap(ctx, uint64(cc.requestedSeqNum), until)
+	return err
+}
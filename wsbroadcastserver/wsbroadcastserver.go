@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/textproto"
@@ -27,6 +28,7 @@ import (
 	"github.com/offchainlabs/nitro/arbutil"
 	"github.com/offchainlabs/nitro/broadcaster/backlog"
 	m "github.com/offchainlabs/nitro/broadcaster/message"
+	"github.com/offchainlabs/nitro/util/containers"
 )
 
 var (
@@ -35,6 +37,8 @@ var (
 	HTTPHeaderFeedClientVersion       = textproto.CanonicalMIMEHeaderKey("Arbitrum-Feed-Client-Version")
 	HTTPHeaderRequestedSequenceNumber = textproto.CanonicalMIMEHeaderKey("Arbitrum-Requested-Sequence-Number")
 	HTTPHeaderChainId                 = textproto.CanonicalMIMEHeaderKey("Arbitrum-Chain-Id")
+	HTTPHeaderFeedMessageVersion      = textproto.CanonicalMIMEHeaderKey("Arbitrum-Feed-Message-Version")
+	HTTPHeaderFeedEncoding            = textproto.CanonicalMIMEHeaderKey("Arbitrum-Feed-Encoding")
 	upgradeToWSTimer                  = metrics.NewRegisteredTimer("arb/feed/clients/upgrade/duration", nil)
 	startWithHeaderTimer              = metrics.NewRegisteredTimer("arb/feed/clients/start/duration", nil)
 )
@@ -42,12 +46,54 @@ var (
 const (
 	FeedServerVersion = 2
 	FeedClientVersion = 2
-	LivenessProbeURI  = "livenessprobe"
+	// FeedMessageVersion is advertised separately from the server version so older clients keep connecting.
+	// Since version 2, feed messages carry the hash of the block they produced on the sequencer.
+	FeedMessageVersion = 2
+	LivenessProbeURI   = "livenessprobe"
+	// ChainPathPrefix followed by a chain id is the path of that chain's feed on a server relaying several chains.
+	// Clients may instead request the chain with the HTTPHeaderChainId header; without either, they're sent the
+	// feed of the server's own chain.
+	ChainPathPrefix = "/chain/"
 )
 
+// FeedEncoding is the encoding of the messages sent to a client, negotiated with the HTTPHeaderFeedEncoding
+// handshake header: a client asks for an encoding, and the server echoes it if accepted, otherwise using JSON.
+type FeedEncoding uint8
+
+const (
+	FeedEncodingJSON FeedEncoding = iota
+	// FeedEncodingProtobuf messages are sent as binary frames, encoded as described by broadcaster/message/feed.proto
+	FeedEncodingProtobuf
+)
+
+const (
+	FeedEncodingJSONName     = "json"
+	FeedEncodingProtobufName = "protobuf-v1"
+)
+
+func (e FeedEncoding) String() string {
+	if e == FeedEncodingProtobuf {
+		return FeedEncodingProtobufName
+	}
+	return FeedEncodingJSONName
+}
+
+// ParseFeedEncoding returns the encoding named by a HTTPHeaderFeedEncoding header value.
+func ParseFeedEncoding(name string) (FeedEncoding, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case FeedEncodingJSONName:
+		return FeedEncodingJSON, nil
+	case FeedEncodingProtobufName:
+		return FeedEncodingProtobuf, nil
+	default:
+		return FeedEncodingJSON, fmt.Errorf("unknown feed encoding %q", name)
+	}
+}
+
 type BroadcasterConfig struct {
 	Enable             bool                    `koanf:"enable"`
 	Signed             bool                    `koanf:"signed"`
+	SigningKeyId       uint64                  `koanf:"signing-key-id"`
 	Addr               string                  `koanf:"addr"`
 	ReadTimeout        time.Duration           `koanf:"read-timeout" reload:"hot"`      // reloaded value will affect all clients (next time the timeout is checked)
 	WriteTimeout       time.Duration           `koanf:"write-timeout" reload:"hot"`     // reloading will affect only new connections
@@ -64,9 +110,12 @@ type BroadcasterConfig struct {
 	LogDisconnect      bool                    `koanf:"log-disconnect"`
 	EnableCompression  bool                    `koanf:"enable-compression" reload:"hot"`  // if reloaded to false will cause disconnection of clients with enabled compression on next broadcast
 	RequireCompression bool                    `koanf:"require-compression" reload:"hot"` // if reloaded to true will cause disconnection of clients with disabled compression on next broadcast
+	EnableProtobuf     bool                    `koanf:"enable-protobuf" reload:"hot"`     // reloaded value will affect only future upgrades to websocket
 	LimitCatchup       bool                    `koanf:"limit-catchup" reload:"hot"`
 	MaxCatchup         int                     `koanf:"max-catchup" reload:"hot"`
 	ConnectionLimits   ConnectionLimiterConfig `koanf:"connection-limits" reload:"hot"`
+	Auth               AuthConfig              `koanf:"auth" reload:"hot"`
+	CompressionBudget  CompressionBudgetConfig `koanf:"compression-budget" reload:"hot"`
 	ClientDelay        time.Duration           `koanf:"client-delay" reload:"hot"`
 	Backlog            backlog.Config          `koanf:"backlog" reload:"hot"`
 }
@@ -75,7 +124,13 @@ func (bc *BroadcasterConfig) Validate() error {
 	if !bc.EnableCompression && bc.RequireCompression {
 		return errors.New("require-compression cannot be true while enable-compression is false")
 	}
-	return nil
+	if err := bc.CompressionBudget.Validate(); err != nil {
+		return err
+	}
+	if err := bc.Backlog.Disk.Validate(); err != nil {
+		return err
+	}
+	return bc.Auth.Validate()
 }
 
 type BroadcasterConfigFetcher func() *BroadcasterConfig
@@ -83,6 +138,7 @@ type BroadcasterConfigFetcher func() *BroadcasterConfig
 func BroadcasterConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".enable", DefaultBroadcasterConfig.Enable, "enable broadcaster")
 	f.Bool(prefix+".signed", DefaultBroadcasterConfig.Signed, "sign broadcast messages")
+	f.Uint64(prefix+".signing-key-id", DefaultBroadcasterConfig.SigningKeyId, "identifier of the key signing broadcast messages, included in each signed message so clients can follow key rotations (0 means none)")
 	f.String(prefix+".addr", DefaultBroadcasterConfig.Addr, "address to bind the relay feed output to")
 	f.Duration(prefix+".read-timeout", DefaultBroadcasterConfig.ReadTimeout, "duration to wait before timing out reading data (i.e. pings) from clients")
 	f.Duration(prefix+".write-timeout", DefaultBroadcasterConfig.WriteTimeout, "duration to wait before timing out writing data to clients")
@@ -99,9 +155,12 @@ func BroadcasterConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".log-disconnect", DefaultBroadcasterConfig.LogDisconnect, "log every client disconnect")
 	f.Bool(prefix+".enable-compression", DefaultBroadcasterConfig.EnableCompression, "enable per message deflate compression support")
 	f.Bool(prefix+".require-compression", DefaultBroadcasterConfig.RequireCompression, "require clients to use compression")
+	CompressionBudgetConfigAddOptions(prefix+".compression-budget", f)
+	f.Bool(prefix+".enable-protobuf", DefaultBroadcasterConfig.EnableProtobuf, "send messages in the protobuf encoding to clients that request it, instead of JSON")
 	f.Bool(prefix+".limit-catchup", DefaultBroadcasterConfig.LimitCatchup, "only supply catchup buffer if requested sequence number is reasonable")
 	f.Int(prefix+".max-catchup", DefaultBroadcasterConfig.MaxCatchup, "the maximum size of the catchup buffer (-1 means unlimited)")
 	ConnectionLimiterConfigAddOptions(prefix+".connection-limits", f)
+	AuthConfigAddOptions(prefix+".auth", f)
 	f.Duration(prefix+".client-delay", DefaultBroadcasterConfig.ClientDelay, "delay the first messages sent to each client by this amount")
 	backlog.AddOptions(prefix+".backlog", f)
 }
@@ -109,6 +168,7 @@ func BroadcasterConfigAddOptions(prefix string, f *flag.FlagSet) {
 var DefaultBroadcasterConfig = BroadcasterConfig{
 	Enable:             false,
 	Signed:             false,
+	SigningKeyId:       0,
 	Addr:               "",
 	ReadTimeout:        time.Second,
 	WriteTimeout:       2 * time.Second,
@@ -125,9 +185,12 @@ var DefaultBroadcasterConfig = BroadcasterConfig{
 	LogDisconnect:      false,
 	EnableCompression:  false,
 	RequireCompression: false,
+	CompressionBudget:  DefaultCompressionBudgetConfig,
+	EnableProtobuf:     false,
 	LimitCatchup:       false,
 	MaxCatchup:         -1,
 	ConnectionLimits:   DefaultConnectionLimiterConfig,
+	Auth:               DefaultAuthConfig,
 	ClientDelay:        0,
 	Backlog:            backlog.DefaultConfig,
 }
@@ -135,6 +198,7 @@ var DefaultBroadcasterConfig = BroadcasterConfig{
 var DefaultTestBroadcasterConfig = BroadcasterConfig{
 	Enable:             false,
 	Signed:             false,
+	SigningKeyId:       0,
 	Addr:               "0.0.0.0",
 	ReadTimeout:        2 * time.Second,
 	WriteTimeout:       2 * time.Second,
@@ -151,9 +215,12 @@ var DefaultTestBroadcasterConfig = BroadcasterConfig{
 	LogDisconnect:      false,
 	EnableCompression:  true,
 	RequireCompression: false,
+	CompressionBudget:  DefaultCompressionBudgetConfig,
+	EnableProtobuf:     true,
 	LimitCatchup:       false,
 	MaxCatchup:         -1,
 	ConnectionLimits:   DefaultConnectionLimiterConfig,
+	Auth:               DefaultAuthConfig,
 	ClientDelay:        0,
 	Backlog:            backlog.DefaultTestConfig,
 }
@@ -172,6 +239,16 @@ type WSBroadcastServer struct {
 	backlog       backlog.Backlog
 	chainId       uint64
 	fatalErrChan  chan error
+
+	gapFiller          GapFiller
+	maxGapFillMessages uint64
+
+	// the handshake header sent to clients of this server's chain
+	header ws.HandshakeHeader
+	// the servers of other chains whose clients connect through this server's listener, by chain id
+	chains containers.SyncMap[uint64, *WSBroadcastServer]
+	// the server whose listener this server's clients connect through, if not its own
+	host *WSBroadcastServer
 }
 
 func NewWSBroadcastServer(config BroadcasterConfigFetcher, bklg backlog.Backlog, chainId uint64, fatalErrChan chan error) *WSBroadcastServer {
@@ -184,6 +261,13 @@ func NewWSBroadcastServer(config BroadcasterConfigFetcher, bklg backlog.Backlog,
 	}
 }
 
+// SetGapFiller lets clients be sent up to maxMessages messages preceding the backlog from the gap filler.
+// It must be called before the server is started.
+func (s *WSBroadcastServer) SetGapFiller(gapFiller GapFiller, maxMessages uint64) {
+	s.gapFiller = gapFiller
+	s.maxGapFillMessages = maxMessages
+}
+
 func (s *WSBroadcastServer) Initialize() error {
 	if s.poller != nil {
 		return errors.New("broadcast server already initialized")
@@ -203,20 +287,71 @@ func (s *WSBroadcastServer) Initialize() error {
 	return nil
 }
 
-func (s *WSBroadcastServer) Start(ctx context.Context) error {
+func (s *WSBroadcastServer) defaultHeader() ws.HandshakeHeader {
 	// Prepare handshake header writer from http.Header mapping.
-	header := ws.HandshakeHeaderHTTP(http.Header{
-		HTTPHeaderFeedServerVersion: []string{strconv.Itoa(FeedServerVersion)},
-		HTTPHeaderChainId:           []string{strconv.FormatUint(s.chainId, 10)},
+	return ws.HandshakeHeaderHTTP(http.Header{
+		HTTPHeaderFeedServerVersion:  []string{strconv.Itoa(FeedServerVersion)},
+		HTTPHeaderFeedMessageVersion: []string{strconv.Itoa(FeedMessageVersion)},
+		HTTPHeaderChainId:            []string{strconv.FormatUint(s.chainId, 10)},
 	})
+}
 
+func (s *WSBroadcastServer) Start(ctx context.Context) error {
 	startTime := time.Now()
-	err := s.StartWithHeader(ctx, header)
+	err := s.StartWithHeader(ctx, s.defaultHeader())
 	elapsed := time.Since(startTime)
 	startWithHeaderTimer.Update(elapsed)
 	return err
 }
 
+// StartOn starts serving this server's chain through the listener of host, which must serve a different chain,
+// to clients requesting the chain by its path or header. The chains share host's connection limits.
+func (s *WSBroadcastServer) StartOn(ctx context.Context, host *WSBroadcastServer) error {
+	s.startMutex.Lock()
+	defer s.startMutex.Unlock()
+	if s.started {
+		return errors.New("broadcast server already started")
+	}
+	if host.chainId == s.chainId {
+		return fmt.Errorf("chain %v is already served by the host broadcast server", s.chainId)
+	}
+	if _, exists := host.chains.Load(s.chainId); exists {
+		return fmt.Errorf("chain %v is already served by the host broadcast server", s.chainId)
+	}
+	s.header = s.defaultHeader()
+	s.host = host
+	s.clientManager.connectionLimiter = host.clientManager.connectionLimiter
+	s.clientManager.authenticator = host.clientManager.authenticator
+	s.clientManager.Start(ctx)
+	host.chains.Store(s.chainId, s)
+	s.started = true
+	log.Info("arbitrum websocket broadcast server is serving chain on shared listener", "chainId", s.chainId, "path", ChainPathPrefix+strconv.FormatUint(s.chainId, 10))
+	return nil
+}
+
+// chainServer returns the server of the chain requested by a client, or this server's if none was requested.
+func (s *WSBroadcastServer) chainServer(chainId uint64, requested bool) (*WSBroadcastServer, bool) {
+	if !requested || chainId == s.chainId {
+		return s, true
+	}
+	return s.chains.Load(chainId)
+}
+
+// parseChainPath returns the chain id in a request uri starting with ChainPathPrefix.
+func parseChainPath(uri string) (uint64, bool, error) {
+	path, _, _ := strings.Cut(uri, "?")
+	rest, found := strings.CutPrefix(path, ChainPathPrefix)
+	if !found {
+		return 0, false, nil
+	}
+	rest, _, _ = strings.Cut(rest, "/")
+	chainId, err := strconv.ParseUint(rest, 10, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	return chainId, true, nil
+}
+
 func (s *WSBroadcastServer) StartWithHeader(ctx context.Context, header ws.HandshakeHeader) error {
 	s.startMutex.Lock()
 	defer s.startMutex.Unlock()
@@ -225,6 +360,7 @@ func (s *WSBroadcastServer) StartWithHeader(ctx context.Context, header ws.Hands
 	}
 
 	s.clientManager.Start(ctx)
+	s.header = header
 
 	// handle incoming connection requests.
 	// It upgrades TCP connection to WebSocket, registers netpoll listener on
@@ -258,6 +394,11 @@ func (s *WSBroadcastServer) StartWithHeader(ctx context.Context, header ws.Hands
 		var feedClientVersionSeen bool
 		var connectingIP net.IP
 		var requestedSeqNum arbutil.MessageIndex
+		var requestedChainId uint64
+		var chainRequested bool
+		var authKey, authKeyName string
+		target := s
+		encoding := FeedEncodingJSON
 		upgrader := ws.Upgrader{
 			OnRequest: func(uri []byte) error {
 				if strings.Contains(string(uri), LivenessProbeURI) {
@@ -265,6 +406,17 @@ func (s *WSBroadcastServer) StartWithHeader(ctx context.Context, header ws.Hands
 						ws.RejectionStatus(http.StatusOK),
 					)
 				}
+				chainId, found, err := parseChainPath(string(uri))
+				if err != nil {
+					return ws.RejectConnectionError(
+						ws.RejectionStatus(http.StatusBadRequest),
+						ws.RejectionReason("Malformed chain id in path"),
+					)
+				}
+				if found {
+					requestedChainId, chainRequested = chainId, true
+				}
+				authKey = authKeyFromRequest(string(uri))
 				return nil
 			},
 			OnHeader: func(key []byte, value []byte) error {
@@ -293,6 +445,25 @@ func (s *WSBroadcastServer) StartWithHeader(ctx context.Context, header ws.Hands
 						)
 					}
 					requestedSeqNum = arbutil.MessageIndex(num)
+				} else if headerName == HTTPHeaderFeedEncoding {
+					// unknown encodings are ignored, falling back to JSON, so clients can ask for newer ones
+					requestedEncoding, err := ParseFeedEncoding(string(value))
+					if err == nil && (requestedEncoding != FeedEncodingProtobuf || config.EnableProtobuf) {
+						encoding = requestedEncoding
+					}
+				} else if headerName == HTTPHeaderChainId && !chainRequested {
+					chainId, err := strconv.ParseUint(string(value), 0, 64)
+					if err != nil {
+						return ws.RejectConnectionError(
+							ws.RejectionStatus(http.StatusBadRequest),
+							ws.RejectionReason(fmt.Sprintf("Malformed HTTP header %s", HTTPHeaderChainId)),
+						)
+					}
+					requestedChainId, chainRequested = chainId, true
+				} else if headerName == "Authorization" {
+					if key := authKeyFromHeader(string(value)); key != "" {
+						authKey = key
+					}
 				} else if headerName == HTTPHeaderCloudflareConnectingIP {
 					connectingIP = net.ParseIP(string(value))
 					log.Trace("Client IP parsed from header", "ip", connectingIP, "header", headerName, "value", string(value))
@@ -307,6 +478,14 @@ func (s *WSBroadcastServer) StartWithHeader(ctx context.Context, header ws.Hands
 						ws.RejectionReason(fmt.Sprintf("Missing HTTP header %s", HTTPHeaderFeedClientVersion)),
 					)
 				}
+				var served bool
+				target, served = s.chainServer(requestedChainId, chainRequested)
+				if !served {
+					return nil, ws.RejectConnectionError(
+						ws.RejectionStatus(http.StatusNotFound),
+						ws.RejectionReason(fmt.Sprintf("Chain %d is not served", requestedChainId)),
+					)
+				}
 				if connectingIP == nil {
 					if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
 						connectingIP = addr.IP
@@ -316,6 +495,22 @@ func (s *WSBroadcastServer) StartWithHeader(ctx context.Context, header ws.Hands
 					}
 				}
 
+				if config.Auth.Enable {
+					var err error
+					authKeyName, err = s.clientManager.authenticator.Authenticate(authKey)
+					if errors.Is(err, errTooManyKeyConnections) {
+						return nil, ws.RejectConnectionError(
+							ws.RejectionStatus(http.StatusTooManyRequests),
+							ws.RejectionReason("Too many open feed connections for api key."),
+						)
+					} else if err != nil {
+						return nil, ws.RejectConnectionError(
+							ws.RejectionStatus(http.StatusUnauthorized),
+							ws.RejectionReason("Invalid or missing api key."),
+						)
+					}
+				}
+
 				if config.ConnectionLimits.Enable && !s.clientManager.connectionLimiter.IsAllowed(connectingIP) {
 					return nil, ws.RejectConnectionError(
 						ws.RejectionStatus(http.StatusTooManyRequests),
@@ -323,7 +518,12 @@ func (s *WSBroadcastServer) StartWithHeader(ctx context.Context, header ws.Hands
 					)
 				}
 
-				return header, nil
+				if encoding != FeedEncodingJSON {
+					return handshakeHeaders{target.header, ws.HandshakeHeaderHTTP(http.Header{
+						HTTPHeaderFeedEncoding: []string{encoding.String()},
+					})}, nil
+				}
+				return target.header, nil
 			},
 			Negotiate: negotiate,
 		}
@@ -378,11 +578,16 @@ func (s *WSBroadcastServer) StartWithHeader(ctx context.Context, header ws.Hands
 		// Register incoming client in clientManager.
 		safeConn := writeDeadliner{conn, config.WriteTimeout}
 
-		client := NewClientConnection(safeConn, desc, s.clientManager.clientAction, requestedSeqNum, connectingIP, compressionAccepted, s.config().MaxSendQueue, s.config().ClientDelay, s.backlog)
+		client := NewClientConnection(safeConn, desc, target.clientManager.clientAction, requestedSeqNum, connectingIP, compressionAccepted, encoding, target.config().MaxSendQueue, target.config().ClientDelay, target.backlog)
+		client.gapFiller = target.gapFiller
+		client.maxGapFillMessages = target.maxGapFillMessages
+		client.authKeyName = authKeyName
+		client.bytesSent = authKeyBandwidthCounter(authKeyName)
+		client.compressionBudget = target.clientManager.compressionBudget
 		client.Start(ctx)
 
 		// Subscribe to events about conn.
-		err = s.poller.Start(desc, func(ev netpoll.Event) {
+		err = target.poller.Start(desc, func(ev netpoll.Event) {
 			if ev&(netpoll.EventReadHup|netpoll.EventHup) != 0 {
 				// ReadHup or Hup received, means the client has close the connection
 				// remove it from the clientManager registry.
@@ -396,9 +601,9 @@ func (s *WSBroadcastServer) StartWithHeader(ctx context.Context, header ws.Hands
 			}
 
 			// receive client messages, close on error
-			s.clientManager.pool.Schedule(func() {
+			target.clientManager.pool.Schedule(func() {
 				// Ignore any messages sent from client, close on any error
-				if _, _, err := client.Receive(ctx, s.config().ReadTimeout); err != nil {
+				if _, _, err := client.Receive(ctx, target.config().ReadTimeout); err != nil {
 					client.Remove()
 					return
 				}
@@ -504,10 +709,20 @@ func (s *WSBroadcastServer) StartWithHeader(ctx context.Context, header ws.Hands
 }
 
 func (s *WSBroadcastServer) ListenerAddr() net.Addr {
+	if s.host != nil {
+		return s.host.ListenerAddr()
+	}
 	return s.listener.Addr()
 }
 
 func (s *WSBroadcastServer) StopAndWait() {
+	if s.host != nil {
+		s.host.chains.Delete(s.chainId)
+		s.clientManager.StopAndWait()
+		s.closePoller()
+		s.started = false
+		return
+	}
 	err := s.listener.Close()
 	if err != nil {
 		log.Warn("error in listener.Close", "err", err)
@@ -527,9 +742,22 @@ func (s *WSBroadcastServer) StopAndWait() {
 	}
 
 	s.clientManager.StopAndWait()
+	s.closePoller()
 	s.started = false
 }
 
+// closePoller releases the poller's epoll instance and its goroutine, which stopping observing the
+// descriptors doesn't.
+func (s *WSBroadcastServer) closePoller() {
+	closer, ok := s.poller.(io.Closer)
+	if !ok {
+		return
+	}
+	if err := closer.Close(); err != nil {
+		log.Warn("error in poller.Close", "err", err)
+	}
+}
+
 func (s *WSBroadcastServer) Started() bool {
 	return s.started
 }
@@ -556,3 +784,21 @@ func (d writeDeadliner) Write(p []byte) (int, error) {
 	}
 	return d.Conn.Write(p)
 }
+
+// handshakeHeaders writes several handshake headers one after another.
+type handshakeHeaders []ws.HandshakeHeader
+
+func (h handshakeHeaders) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	for _, header := range h {
+		if header == nil {
+			continue
+		}
+		n, err := header.WriteTo(w)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}